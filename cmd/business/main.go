@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	_ "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator/builtin"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/app"
@@ -50,6 +51,12 @@ func run(args []string) int {
 	if len(args) > 0 && strings.EqualFold(args[0], "gate") {
 		return runGate(args[1:])
 	}
+	if len(args) > 0 && strings.EqualFold(args[0], "encrypt-secrets") {
+		return runEncryptSecrets(context.Background(), args[1:])
+	}
+	if len(args) > 0 && strings.EqualFold(args[0], "migrate") {
+		return runMigrate(context.Background(), args[1:])
+	}
 
 	if err := runServer(context.Background(), args); err != nil {
 		log.WithError(err).Error("command failed")
@@ -103,6 +110,97 @@ func runGateCopilotDualRun(args []string) int {
 	return exitCodeGateBlocked
 }
 
+// runEncryptSecrets encrypts plaintext auth content and provider API keys already in the database.
+func runEncryptSecrets(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("encrypt-secrets", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	cfgPath := fs.String("config", "", "config file path (or env CONFIG_PATH)")
+	dryRun := fs.Bool("dry-run", false, "count rows that would be encrypted without writing")
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "parse encrypt-secrets arguments: %v\n", err)
+		return exitCodeError
+	}
+
+	appCfg, err := config.LoadFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load config: %v\n", err)
+		return exitCodeError
+	}
+	if strings.TrimSpace(*cfgPath) != "" {
+		appCfg.ConfigPath = config.ResolveConfigPath(*cfgPath)
+	}
+
+	result, err := app.EncryptSecrets(ctx, appCfg, *dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "encrypt secrets failed: %v\n", err)
+		return exitCodeError
+	}
+	fmt.Printf("encrypt_secrets dry_run=%t auths_scanned=%d auths_encrypted=%d provider_keys_scanned=%d provider_keys_updated=%d\n",
+		*dryRun, result.AuthsScanned, result.AuthsEncrypted, result.ProviderKeysScanned, result.ProviderKeysUpdated)
+	return exitCodeOK
+}
+
+// runMigrate reports, applies or reverts versioned schema migrations.
+func runMigrate(ctx context.Context, args []string) int {
+	action := "status"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = strings.ToLower(strings.TrimSpace(args[0])), args[1:]
+	}
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	cfgPath := fs.String("config", "", "config file path (or env CONFIG_PATH)")
+	to := fs.Int("to", -1, "target schema version (up: latest, down: previous by default)")
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "parse migrate arguments: %v\n", err)
+		return exitCodeError
+	}
+	if fs.NArg() != 0 {
+		fmt.Fprintf(os.Stderr, "unexpected positional args: %s\n", strings.Join(fs.Args(), " "))
+		return exitCodeError
+	}
+
+	appCfg, err := config.LoadFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load config: %v\n", err)
+		return exitCodeError
+	}
+	if strings.TrimSpace(*cfgPath) != "" {
+		appCfg.ConfigPath = config.ResolveConfigPath(*cfgPath)
+	}
+
+	switch action {
+	case "status":
+		states, errStatus := app.MigrationStatus(ctx, appCfg)
+		if errStatus != nil {
+			fmt.Fprintf(os.Stderr, "migration status failed: %v\n", errStatus)
+			return exitCodeError
+		}
+		for _, state := range states {
+			appliedAt := "-"
+			if state.AppliedAt != nil {
+				appliedAt = state.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			fmt.Printf("migration version=%d applied=%t applied_at=%s reversible=%t description=%q\n", state.Version, state.Applied, appliedAt, state.Reversible, state.Description)
+		}
+		return exitCodeOK
+	case "up", "down":
+		migrate := app.MigrateUp
+		if action == "down" {
+			migrate = app.MigrateDown
+		}
+		version, errMigrate := migrate(ctx, appCfg, *to)
+		if errMigrate != nil {
+			fmt.Fprintf(os.Stderr, "migrate %s failed: %v\n", action, errMigrate)
+			return exitCodeError
+		}
+		fmt.Printf("migrate action=%s schema_version=%d\n", action, version)
+		return exitCodeOK
+	default:
+		fmt.Fprintln(os.Stderr, "usage: cpab migrate [status|up|down] [--config <file>] [--to <version>]")
+		return exitCodeError
+	}
+}
+
 // runServer parses flags, loads config, and starts the init or main server.
 func runServer(ctx context.Context, args []string) error {
 	fs := flag.NewFlagSet("app", flag.ContinueOnError)
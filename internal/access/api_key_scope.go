@@ -0,0 +1,266 @@
+package access
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ErrAPIKeyScope indicates the request falls outside the API key's configured scope.
+var ErrAPIKeyScope = errors.New("api key scope violation")
+
+// KeyScope holds the request restrictions configured on an API key.
+type KeyScope struct {
+	Models    []string
+	Providers []string
+	MaxTokens int
+}
+
+// ScopeOf returns the normalized scope of an API key.
+func ScopeOf(apiKey *models.APIKey) KeyScope {
+	if apiKey == nil {
+		return KeyScope{}
+	}
+	scope := KeyScope{
+		Models:    ParseScopeList(apiKey.AllowedModels),
+		Providers: ParseScopeList(apiKey.AllowedProviders),
+	}
+	if apiKey.MaxTokensPerRequest != nil && *apiKey.MaxTokensPerRequest > 0 {
+		scope.MaxTokens = *apiKey.MaxTokensPerRequest
+	}
+	return scope
+}
+
+// IsZero reports whether the scope places no restriction on requests.
+func (s KeyScope) IsZero() bool {
+	return len(s.Models) == 0 && len(s.Providers) == 0 && s.MaxTokens <= 0
+}
+
+// AllowsModel reports whether the model is permitted; matching ignores case.
+func (s KeyScope) AllowsModel(model string) bool {
+	return len(s.Models) == 0 || containsFold(s.Models, model)
+}
+
+// AllowsAnyProvider reports whether at least one of the providers is permitted.
+func (s KeyScope) AllowsAnyProvider(providers []string) bool {
+	if len(s.Providers) == 0 {
+		return true
+	}
+	for _, provider := range providers {
+		if containsFold(s.Providers, provider) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseScopeList decodes a JSON string list, trimming blanks and duplicates.
+func ParseScopeList(raw datatypes.JSON) []string {
+	if len(raw) == 0 {
+		return nil
+	}
+	var items []string
+	if errUnmarshal := json.Unmarshal(raw, &items); errUnmarshal != nil {
+		return nil
+	}
+	return NormalizeScopeList(items)
+}
+
+// NormalizeScopeList trims entries and drops blanks and case-insensitive duplicates.
+func NormalizeScopeList(items []string) []string {
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" || containsFold(out, item) {
+			continue
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+func containsFold(items []string, value string) bool {
+	value = strings.TrimSpace(value)
+	for _, item := range items {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// scopedRequest is the part of a proxy request a key scope is checked against.
+type scopedRequest struct {
+	Model     string
+	MaxTokens int
+	HasLimit  bool
+}
+
+// enforceKeyScope checks the request against the key scope and caps the output token limit
+// when the request does not set one. The request body is restored for downstream handlers.
+func enforceKeyScope(ctx context.Context, db *gorm.DB, r *http.Request, scope KeyScope) error {
+	if scope.IsZero() || r == nil || r.Method != http.MethodPost {
+		return nil
+	}
+	raw, errRead := readRequestBody(r)
+	if errRead != nil {
+		return errRead
+	}
+	req := parseScopedRequest(r, raw)
+	if req.Model != "" {
+		if !scope.AllowsModel(req.Model) {
+			return fmt.Errorf("%w: model %q is not allowed for this api key", ErrAPIKeyScope, req.Model)
+		}
+		if len(scope.Providers) > 0 {
+			providers, errProviders := modelProviders(ctx, db, req.Model)
+			if errProviders != nil {
+				return errProviders
+			}
+			if !scope.AllowsAnyProvider(providers) {
+				return fmt.Errorf("%w: model %q is not served by an allowed provider", ErrAPIKeyScope, req.Model)
+			}
+		}
+	}
+	if scope.MaxTokens > 0 {
+		if req.HasLimit && req.MaxTokens > scope.MaxTokens {
+			return fmt.Errorf("%w: max tokens %d exceeds the api key limit of %d", ErrAPIKeyScope, req.MaxTokens, scope.MaxTokens)
+		}
+		if !req.HasLimit {
+			if capped, ok := capRequestTokens(r.URL.Path, raw, scope.MaxTokens); ok {
+				raw = capped
+			}
+		}
+	}
+	r.Body = io.NopCloser(bytes.NewReader(raw))
+	r.ContentLength = int64(len(raw))
+	return nil
+}
+
+// RequestModel returns the model a proxy request asks for, leaving the body readable.
+func RequestModel(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	raw, errRead := readRequestBody(r)
+	if errRead != nil {
+		return ""
+	}
+	return parseScopedRequest(r, raw).Model
+}
+
+// readRequestBody drains the body and leaves an equivalent reader in its place.
+func readRequestBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	raw, errRead := io.ReadAll(r.Body)
+	_ = r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(raw))
+	if errRead != nil {
+		return nil, fmt.Errorf("read request body: %w", errRead)
+	}
+	return raw, nil
+}
+
+// parseScopedRequest extracts the model and output token limit from an OpenAI, Claude or
+// Gemini style request. Gemini carries the model in the path instead of the body.
+func parseScopedRequest(r *http.Request, raw []byte) scopedRequest {
+	var body struct {
+		Model               string `json:"model"`
+		MaxTokens           *int   `json:"max_tokens"`
+		MaxCompletionTokens *int   `json:"max_completion_tokens"`
+		MaxOutputTokens     *int   `json:"max_output_tokens"`
+		GenerationConfig    *struct {
+			MaxOutputTokens *int `json:"maxOutputTokens"`
+		} `json:"generationConfig"`
+	}
+	_ = json.Unmarshal(raw, &body)
+
+	out := scopedRequest{Model: strings.TrimSpace(body.Model)}
+	if out.Model == "" && r.URL != nil {
+		out.Model = geminiPathModel(r.URL.Path)
+	}
+	limits := []*int{body.MaxTokens, body.MaxCompletionTokens, body.MaxOutputTokens}
+	if body.GenerationConfig != nil {
+		limits = append(limits, body.GenerationConfig.MaxOutputTokens)
+	}
+	for _, limit := range limits {
+		if limit == nil {
+			continue
+		}
+		out.HasLimit = true
+		if *limit > out.MaxTokens {
+			out.MaxTokens = *limit
+		}
+	}
+	return out
+}
+
+// geminiPathModel returns the model from paths like /v1beta/models/{model}:generateContent.
+func geminiPathModel(path string) string {
+	const marker = "/models/"
+	idx := strings.Index(path, marker)
+	if idx < 0 || !hasPathPrefix(path, "/v1beta") {
+		return ""
+	}
+	model := path[idx+len(marker):]
+	if colon := strings.Index(model, ":"); colon >= 0 {
+		model = model[:colon]
+	}
+	return strings.TrimSpace(model)
+}
+
+// capRequestTokens sets the request's output token limit using the field its API format expects.
+func capRequestTokens(path string, raw []byte, limit int) ([]byte, bool) {
+	var body map[string]json.RawMessage
+	if errUnmarshal := json.Unmarshal(raw, &body); errUnmarshal != nil || body == nil {
+		return nil, false
+	}
+	encodedLimit, _ := json.Marshal(limit)
+	switch {
+	case hasPathPrefix(path, "/v1beta"):
+		config := map[string]json.RawMessage{}
+		if existing, ok := body["generationConfig"]; ok {
+			if errUnmarshal := json.Unmarshal(existing, &config); errUnmarshal != nil || config == nil {
+				return nil, false
+			}
+		}
+		config["maxOutputTokens"] = encodedLimit
+		encodedConfig, errMarshal := json.Marshal(config)
+		if errMarshal != nil {
+			return nil, false
+		}
+		body["generationConfig"] = encodedConfig
+	case hasPathPrefix(path, "/v1/responses"):
+		body["max_output_tokens"] = encodedLimit
+	default:
+		body["max_tokens"] = encodedLimit
+	}
+	out, errMarshal := json.Marshal(body)
+	if errMarshal != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+// modelProviders returns the providers with an enabled mapping exposing the model.
+func modelProviders(ctx context.Context, db *gorm.DB, model string) ([]string, error) {
+	var providers []string
+	lowered := strings.ToLower(strings.TrimSpace(model))
+	if errFind := db.WithContext(ctx).Model(&models.ModelMapping{}).
+		Where("is_enabled = ? AND (LOWER(new_model_name) = ? OR LOWER(model_name) = ?)", true, lowered, lowered).
+		Distinct().
+		Pluck("provider", &providers).Error; errFind != nil {
+		return nil, fmt.Errorf("resolve model providers: %w", errFind)
+	}
+	return providers, nil
+}
@@ -0,0 +1,108 @@
+package access
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+)
+
+func TestEnforceKeyScopeRejectsDisallowedModel(t *testing.T) {
+	scope := KeyScope{Models: []string{"gpt-4o-mini"}}
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o"}`))
+
+	errScope := enforceKeyScope(context.Background(), nil, req, scope)
+
+	if !errors.Is(errScope, ErrAPIKeyScope) {
+		t.Fatalf("expected scope violation, got %v", errScope)
+	}
+}
+
+func TestEnforceKeyScopeAllowsModelAndRestoresBody(t *testing.T) {
+	scope := KeyScope{Models: []string{"GPT-4o-mini"}}
+	payload := `{"model":"gpt-4o-mini","max_tokens":100}`
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(payload))
+
+	if errScope := enforceKeyScope(context.Background(), nil, req, scope); errScope != nil {
+		t.Fatalf("expected request allowed, got %v", errScope)
+	}
+	raw, _ := io.ReadAll(req.Body)
+	if string(raw) != payload {
+		t.Fatalf("expected body restored, got %s", raw)
+	}
+}
+
+func TestEnforceKeyScopeUsesGeminiPathModel(t *testing.T) {
+	scope := KeyScope{Models: []string{"gemini-2.5-flash"}}
+	req := httptest.NewRequest("POST", "/v1beta/models/gemini-2.5-pro:generateContent", strings.NewReader(`{}`))
+
+	if errScope := enforceKeyScope(context.Background(), nil, req, scope); !errors.Is(errScope, ErrAPIKeyScope) {
+		t.Fatalf("expected scope violation, got %v", errScope)
+	}
+}
+
+func TestEnforceKeyScopeMaxTokens(t *testing.T) {
+	scope := KeyScope{MaxTokens: 512}
+
+	over := httptest.NewRequest("POST", "/v1/responses", strings.NewReader(`{"model":"m","max_output_tokens":1024}`))
+	if errScope := enforceKeyScope(context.Background(), nil, over, scope); !errors.Is(errScope, ErrAPIKeyScope) {
+		t.Fatalf("expected scope violation, got %v", errScope)
+	}
+
+	missing := httptest.NewRequest("POST", "/v1beta/models/m:generateContent", strings.NewReader(`{"generationConfig":{"temperature":0.5}}`))
+	if errScope := enforceKeyScope(context.Background(), nil, missing, scope); errScope != nil {
+		t.Fatalf("expected request allowed, got %v", errScope)
+	}
+	var body struct {
+		GenerationConfig map[string]any `json:"generationConfig"`
+	}
+	raw, _ := io.ReadAll(missing.Body)
+	if errUnmarshal := json.Unmarshal(raw, &body); errUnmarshal != nil {
+		t.Fatalf("decode body: %v", errUnmarshal)
+	}
+	if body.GenerationConfig["maxOutputTokens"] != float64(512) || body.GenerationConfig["temperature"] != 0.5 {
+		t.Fatalf("expected capped generation config, got %v", body.GenerationConfig)
+	}
+}
+
+func TestEnforceKeyScopeAllowedProviders(t *testing.T) {
+	conn := openDBAPIKeyProviderTestDB(t)
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate: %v", errMigrate)
+	}
+	if errCreate := conn.Create(&models.ModelMapping{Provider: "openai", ModelName: "gpt-4o-mini", NewModelName: "mini", IsEnabled: true}).Error; errCreate != nil {
+		t.Fatalf("create mapping: %v", errCreate)
+	}
+
+	allowed := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model":"mini"}`))
+	if errScope := enforceKeyScope(context.Background(), conn, allowed, KeyScope{Providers: []string{"OpenAI"}}); errScope != nil {
+		t.Fatalf("expected request allowed, got %v", errScope)
+	}
+	denied := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model":"mini"}`))
+	if errScope := enforceKeyScope(context.Background(), conn, denied, KeyScope{Providers: []string{"claude"}}); !errors.Is(errScope, ErrAPIKeyScope) {
+		t.Fatalf("expected scope violation, got %v", errScope)
+	}
+}
+
+func TestScopeOfParsesKeyColumns(t *testing.T) {
+	maxTokens := 256
+	scope := ScopeOf(&models.APIKey{
+		AllowedModels:       datatypes.JSON(`[" gpt-4o-mini ","GPT-4o-mini",""]`),
+		AllowedProviders:    datatypes.JSON(`[]`),
+		MaxTokensPerRequest: &maxTokens,
+	})
+
+	if len(scope.Models) != 1 || scope.Models[0] != "gpt-4o-mini" {
+		t.Fatalf("unexpected models %v", scope.Models)
+	}
+	if len(scope.Providers) != 0 || scope.MaxTokens != 256 || scope.IsZero() {
+		t.Fatalf("unexpected scope %+v", scope)
+	}
+}
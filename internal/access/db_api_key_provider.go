@@ -8,7 +8,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/ipallow"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/team"
 
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
 	"gorm.io/gorm"
@@ -23,6 +27,9 @@ var ErrInsufficientBalance = errors.New("insufficient balance")
 // ErrDailyMaxUsageExceeded indicates the user exceeded daily prepaid spending limit.
 var ErrDailyMaxUsageExceeded = errors.New("daily max usage exceeded")
 
+// ErrIPNotAllowed indicates the request source address is outside the API key's allowlist.
+var ErrIPNotAllowed = errors.New("source ip not allowed for this api key")
+
 // DBAPIKeyProvider authenticates requests using API keys stored in the database.
 type DBAPIKeyProvider struct {
 	db *gorm.DB
@@ -85,6 +92,7 @@ func (p *DBAPIKeyProvider) Authenticate(ctx context.Context, r *http.Request) (*
 	err := p.db.WithContext(ctx).
 		Preload("User").
 		Where("api_key = ? AND active = ? AND revoked_at IS NULL", token, true).
+		Where("(expires_at IS NULL OR expires_at > ?)", time.Now().UTC()).
 		First(&apiKey).Error
 	switch {
 	case err == nil:
@@ -94,12 +102,49 @@ func (p *DBAPIKeyProvider) Authenticate(ctx context.Context, r *http.Request) (*
 		return nil, sdkaccess.NewInternalAuthError("db api key provider query failed", err)
 	}
 
+	if allowed := ipallow.FromJSON(apiKey.AllowedIPs); len(allowed) > 0 {
+		clientIP := ipallow.ClientIP(r, ipallow.TrustedProxies())
+		if !allowed.Contains(clientIP) {
+			events.PublishIPRejected(ctx, "api_key:"+strconv.FormatUint(apiKey.ID, 10), clientIP.String(), path)
+			authErr := sdkaccess.NewInternalAuthError(ErrIPNotAllowed.Error(), ErrIPNotAllowed)
+			authErr.StatusCode = http.StatusForbidden
+			return nil, authErr
+		}
+	}
+
+	// Team keys spend from the team owner's pool; the issuing member stays on the usage row.
+	payerID := apiKey.UserID
+	if apiKey.TeamID != nil {
+		ownerID, errPayer := team.Payer(ctx, p.db, &apiKey)
+		switch {
+		case errors.Is(errPayer, team.ErrUnavailable):
+			return nil, sdkaccess.NewInvalidCredentialError()
+		case errPayer != nil:
+			return nil, sdkaccess.NewInternalAuthError("db api key provider team lookup failed", errPayer)
+		}
+		payerID = &ownerID
+	}
+
 	if apiKey.User != nil {
 		if apiKey.User.Disabled {
 			return nil, sdkaccess.NewInvalidCredentialError()
 		}
-		if apiKey.UserID != nil {
-			ok, errBalance := hasValidBillOrPrepaidBalance(ctx, p.db, *apiKey.UserID)
+		if payerID != nil {
+			spend, errSpend := billing.LoadSpendLimitState(ctx, p.db, *payerID, time.Now())
+			if errSpend != nil {
+				return nil, sdkaccess.NewInternalAuthError("db api key provider spend limit check failed", errSpend)
+			}
+			if errLimit := spend.Err(); errLimit != nil {
+				authErr := sdkaccess.NewInternalAuthError(errLimit.Error(), errLimit)
+				authErr.StatusCode = http.StatusTooManyRequests
+				return nil, authErr
+			}
+			ok, errBalance := hasValidBillOrPrepaidBalance(ctx, p.db, *payerID)
+			if errors.Is(errBalance, billing.ErrDailyBillQuotaReached) {
+				authErr := sdkaccess.NewInternalAuthError(errBalance.Error(), errBalance)
+				authErr.StatusCode = http.StatusTooManyRequests
+				return nil, authErr
+			}
 			if errBalance != nil {
 				return nil, sdkaccess.NewInternalAuthError("db api key provider balance check failed", errBalance)
 			}
@@ -109,6 +154,15 @@ func (p *DBAPIKeyProvider) Authenticate(ctx context.Context, r *http.Request) (*
 		}
 	}
 
+	if errScope := enforceKeyScope(ctx, p.db, r, ScopeOf(&apiKey)); errScope != nil {
+		if !errors.Is(errScope, ErrAPIKeyScope) {
+			return nil, sdkaccess.NewInternalAuthError("db api key provider scope check failed", errScope)
+		}
+		authErr := sdkaccess.NewInternalAuthError(errScope.Error(), errScope)
+		authErr.StatusCode = http.StatusForbidden
+		return nil, authErr
+	}
+
 	now := time.Now().UTC()
 	_ = p.db.WithContext(ctx).Model(&models.APIKey{}).
 		Where("id = ?", apiKey.ID).
@@ -119,8 +173,12 @@ func (p *DBAPIKeyProvider) Authenticate(ctx context.Context, r *http.Request) (*
 		"api_key_name": apiKey.Name,
 		"is_admin":     strconv.FormatBool(apiKey.IsAdmin),
 	}
-	if apiKey.UserID != nil {
-		meta["user_id"] = strconv.FormatUint(*apiKey.UserID, 10)
+	if payerID != nil {
+		meta["user_id"] = strconv.FormatUint(*payerID, 10)
+	}
+	if apiKey.TeamID != nil {
+		meta["team_id"] = strconv.FormatUint(*apiKey.TeamID, 10)
+		meta["team_member_id"] = strconv.FormatUint(*apiKey.UserID, 10)
 	}
 
 	return &sdkaccess.Result{
@@ -130,6 +188,14 @@ func (p *DBAPIKeyProvider) Authenticate(ctx context.Context, r *http.Request) (*
 	}, nil
 }
 
+// ExtractAPIKey returns the API key presented by a proxy request, using the same rules as DBAPIKeyProvider.
+func ExtractAPIKey(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	return extractToken(r, "Authorization", "Bearer", true)
+}
+
 // extractToken extracts an API key token from headers or query parameters.
 func extractToken(r *http.Request, header string, scheme string, allowXAPIKey bool) string {
 	header = strings.TrimSpace(header)
@@ -163,6 +229,11 @@ func extractToken(r *http.Request, header string, scheme string, allowXAPIKey bo
 	return ""
 }
 
+// RequiresAPIKey reports whether proxy requests on path must present a database API key.
+func RequiresAPIKey(path string) bool {
+	return requiresDBAPIKeyAuth(path)
+}
+
 // requiresDBAPIKeyAuth determines whether DB API key auth should be enforced.
 func requiresDBAPIKeyAuth(path string) bool {
 	if hasPathPrefix(path, "/v1") {
@@ -272,58 +343,23 @@ func loadTodayPrepaidUsageAmount(ctx context.Context, db *gorm.DB, userID uint64
 }
 
 // hasValidBillQuota checks if the user has paid bill quota remaining.
+// A reached daily quota is reported as a billing.DailyLimitError so the request is blocked
+// instead of silently falling back to prepaid balance.
 func hasValidBillQuota(ctx context.Context, db *gorm.DB, userID uint64) (bool, error) {
 	if db == nil {
 		return false, errors.New("nil db")
 	}
-	now := time.Now().UTC()
-	var summary struct {
-		LeftQuota      float64 `gorm:"column:left_quota"`      // Total remaining quota across bills.
-		DailyQuota     float64 `gorm:"column:daily_quota"`     // Sum of daily quotas for limited plans.
-		UnlimitedDaily int64   `gorm:"column:unlimited_daily"` // Count of unlimited daily plans.
-	}
-	if errSummary := db.WithContext(ctx).
-		Model(&models.Bill{}).
-		Select(`
-			COALESCE(SUM(left_quota), 0) AS left_quota,
-			COALESCE(SUM(CASE WHEN daily_quota > 0 THEN daily_quota ELSE 0 END), 0) AS daily_quota,
-			COALESCE(SUM(CASE WHEN daily_quota <= 0 THEN 1 ELSE 0 END), 0) AS unlimited_daily
-		`).
-		Where("user_id = ? AND is_enabled = ? AND status = ? AND left_quota > 0", userID, true, models.BillStatusPaid).
-		Where("period_start <= ? AND period_end >= ?", now, now).
-		Scan(&summary).Error; errSummary != nil {
-		return false, errSummary
-	}
-	if summary.LeftQuota <= 0 {
-		return false, nil
-	}
-	if summary.UnlimitedDaily > 0 || summary.DailyQuota <= 0 {
-		return true, nil
+	state, errState := billing.LoadDailyBillState(ctx, db, userID, time.Now())
+	if errState != nil {
+		return false, errState
 	}
-	usedToday, errUsage := loadTodayUsageAmount(ctx, db, userID, now)
-	if errUsage != nil {
-		return false, errUsage
-	}
-	return usedToday < summary.DailyQuota, nil
-}
-
-// loadTodayUsageAmount calculates today's usage cost for the user in local time.
-func loadTodayUsageAmount(ctx context.Context, db *gorm.DB, userID uint64, now time.Time) (float64, error) {
-	if db == nil {
-		return 0, errors.New("nil db")
+	if !state.HasActiveBill {
+		return false, nil
 	}
-	loc := time.Local
-	localNow := now.In(loc)
-	todayStart := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, loc)
-	var costMicros int64
-	if errSum := db.WithContext(ctx).
-		Model(&models.Usage{}).
-		Where("user_id = ? AND requested_at >= ?", userID, todayStart).
-		Select("COALESCE(SUM(cost_micros), 0)").
-		Scan(&costMicros).Error; errSum != nil {
-		return 0, errSum
+	if errLimit := state.Err(); errLimit != nil {
+		return false, errLimit
 	}
-	return float64(costMicros) / 1_000_000, nil
+	return true, nil
 }
 
 // hasValidPrepaidBalance checks if the user has redeemable prepaid card balance.
@@ -2,13 +2,18 @@ package access
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/glebarez/sqlite"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"gorm.io/gorm"
 )
 
@@ -72,3 +77,85 @@ func TestDBAPIKeyProviderAuthenticateRequiresAuthOnCLIProxyPath(t *testing.T) {
 		t.Fatalf("expected no_credentials auth error, got %v", authErr)
 	}
 }
+
+func TestDBAPIKeyProviderAuthenticateRejectsReachedSpendLimit(t *testing.T) {
+	provider := newDBAPIKeyProviderForPathTest(t)
+	if errMigrate := db.Migrate(provider.db); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	user := models.User{Username: "capped", Email: "capped@example.com", Password: "x", DailySpendLimit: 1}
+	if errCreate := provider.db.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+	userID := user.ID
+	key := models.APIKey{Name: "capped", APIKey: "sk-capped", UserID: &userID, Active: true}
+	if errCreate := provider.db.Create(&key).Error; errCreate != nil {
+		t.Fatalf("create api key: %v", errCreate)
+	}
+	usage := models.Usage{Provider: "openai", Model: "gpt-4o", UserID: &userID, CostMicros: 1_000_000, RequestedAt: time.Now().UTC()}
+	if errCreate := provider.db.Create(&usage).Error; errCreate != nil {
+		t.Fatalf("create usage: %v", errCreate)
+	}
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	req.Header.Set("Authorization", "Bearer sk-capped")
+	_, authErr := provider.Authenticate(context.Background(), req)
+
+	if authErr == nil || authErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 auth error, got %v", authErr)
+	}
+	if !errors.Is(authErr.Cause, billing.ErrSpendLimitReached) {
+		t.Fatalf("expected spend limit cause, got %v", authErr.Cause)
+	}
+}
+
+func TestDBAPIKeyProviderAuthenticateRejectsReachedDailyBillQuota(t *testing.T) {
+	provider := newDBAPIKeyProviderForPathTest(t)
+	if errMigrate := db.Migrate(provider.db); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	now := time.Now().UTC()
+	user := models.User{Username: "daily", Email: "daily@example.com", Password: "x"}
+	if errCreate := provider.db.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+	plan := models.Plan{Name: "Daily", MonthPrice: 10}
+	if errCreate := provider.db.Create(&plan).Error; errCreate != nil {
+		t.Fatalf("create plan: %v", errCreate)
+	}
+	bill := models.Bill{
+		PlanID:      plan.ID,
+		UserID:      user.ID,
+		PeriodType:  models.BillPeriodTypeMonthly,
+		PeriodStart: now.Add(-24 * time.Hour),
+		PeriodEnd:   now.Add(24 * time.Hour),
+		TotalQuota:  100,
+		DailyQuota:  1,
+		LeftQuota:   100,
+		IsEnabled:   true,
+		Status:      models.BillStatusPaid,
+	}
+	if errCreate := provider.db.Create(&bill).Error; errCreate != nil {
+		t.Fatalf("create bill: %v", errCreate)
+	}
+	userID := user.ID
+	key := models.APIKey{Name: "daily", APIKey: "sk-daily", UserID: &userID, Active: true}
+	if errCreate := provider.db.Create(&key).Error; errCreate != nil {
+		t.Fatalf("create api key: %v", errCreate)
+	}
+	usage := models.Usage{Provider: "openai", Model: "gpt-4o", UserID: &userID, CostMicros: 1_200_000, ChargedTo: "bill", RequestedAt: now}
+	if errCreate := provider.db.Create(&usage).Error; errCreate != nil {
+		t.Fatalf("create usage: %v", errCreate)
+	}
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	req.Header.Set("Authorization", "Bearer sk-daily")
+	_, authErr := provider.Authenticate(context.Background(), req)
+
+	if authErr == nil || authErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 auth error, got %v", authErr)
+	}
+	if !errors.Is(authErr.Cause, billing.ErrDailyBillQuotaReached) {
+		t.Fatalf("expected daily bill quota cause, got %v", authErr.Cause)
+	}
+}
@@ -0,0 +1,404 @@
+// Package anomaly watches per-user and per-API-key usage for spend spikes, comparing the
+// most recent window against each subject's own history and raising usage.anomaly events
+// that reach webhooks, email and the admin notification table.
+package anomaly
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/currency"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultIntervalMinutes = 15
+	defaultWindowMinutes   = 60
+	defaultBaselineDays    = 7
+	defaultMultiplier      = 10
+	defaultMinCost         = 1
+	defaultMinRequests     = 100
+	defaultMinTokens       = 100_000
+	defaultCooldownMinutes = 360
+	// minHistory is how much usage a subject needs before its baseline is trusted.
+	minHistory = 24 * time.Hour
+	// disabledRecheck is how often a disabled detector rereads its setting.
+	disabledRecheck = 5 * time.Minute
+)
+
+// Config mirrors the USAGE_ANOMALY_DETECTION setting.
+type Config struct {
+	Enabled         bool    `json:"enabled"`          // Whether the detector runs.
+	IntervalMinutes int     `json:"interval_minutes"` // How often usage is checked.
+	WindowMinutes   int     `json:"window_minutes"`   // Recent window compared against the baseline.
+	BaselineDays    int     `json:"baseline_days"`    // History averaged into the baseline.
+	Multiplier      float64 `json:"multiplier"`       // Spike factor over baseline that raises an alert.
+	MinCost         float64 `json:"min_cost"`         // Window cost in USD below which cost spikes are ignored.
+	MinRequests     int64   `json:"min_requests"`     // Window requests below which request spikes are ignored.
+	MinTokens       int64   `json:"min_tokens"`       // Window tokens below which token spikes are ignored.
+	CooldownMinutes int     `json:"cooldown_minutes"` // Quiet period before the same subject alerts again.
+}
+
+// LoadConfig reads USAGE_ANOMALY_DETECTION and fills defaults; invalid values disable detection.
+func LoadConfig() Config {
+	var cfg Config
+	raw, ok := internalsettings.DBConfigValue(internalsettings.UsageAnomalyDetectionKey)
+	if ok && len(bytes.TrimSpace(raw)) > 0 {
+		if errUnmarshal := json.Unmarshal(raw, &cfg); errUnmarshal != nil {
+			log.WithError(errUnmarshal).Warn("anomaly: invalid detection setting")
+			cfg = Config{}
+		}
+	}
+	return cfg.withDefaults()
+}
+
+func (c Config) withDefaults() Config {
+	if c.IntervalMinutes <= 0 {
+		c.IntervalMinutes = defaultIntervalMinutes
+	}
+	if c.WindowMinutes <= 0 {
+		c.WindowMinutes = defaultWindowMinutes
+	}
+	if c.BaselineDays <= 0 {
+		c.BaselineDays = defaultBaselineDays
+	}
+	if c.Multiplier <= 1 {
+		c.Multiplier = defaultMultiplier
+	}
+	if c.MinCost <= 0 {
+		c.MinCost = defaultMinCost
+	}
+	if c.MinRequests <= 0 {
+		c.MinRequests = defaultMinRequests
+	}
+	if c.MinTokens <= 0 {
+		c.MinTokens = defaultMinTokens
+	}
+	if c.CooldownMinutes <= 0 {
+		c.CooldownMinutes = defaultCooldownMinutes
+	}
+	return c
+}
+
+// Subject kinds checked by the detector.
+const (
+	SubjectUser   = "user"
+	SubjectAPIKey = "api_key"
+)
+
+// Metrics compared against the baseline.
+const (
+	MetricRequests = "requests"
+	MetricTokens   = "tokens"
+	MetricCost     = "cost_micros"
+)
+
+// Spike describes one metric running above its baseline.
+type Spike struct {
+	Metric   string  `json:"metric"`   // requests, tokens or cost_micros.
+	Current  int64   `json:"current"`  // Value in the recent window.
+	Baseline float64 `json:"baseline"` // Average value per window over the baseline period.
+	Ratio    float64 `json:"ratio"`    // Current over baseline; 0 when the baseline is empty.
+}
+
+// Anomaly is one subject whose recent usage spiked.
+type Anomaly struct {
+	SubjectType string          `json:"subject_type"` // user or api_key.
+	SubjectID   uint64          `json:"subject_id"`   // User or API key id.
+	WindowStart time.Time       `json:"window_start"` // Start of the recent window.
+	WindowEnd   time.Time       `json:"window_end"`   // End of the recent window.
+	Spikes      []Spike         `json:"spikes"`       // Metrics above their baseline.
+	Severity    events.Severity `json:"severity"`     // Critical when a spike doubles the multiplier or has no baseline.
+}
+
+// Subject returns the event subject, e.g. "api_key:12".
+func (a Anomaly) Subject() string {
+	return a.SubjectType + ":" + strconv.FormatUint(a.SubjectID, 10)
+}
+
+// usageTotals holds usage aggregated per subject.
+type usageTotals struct {
+	SubjectID   uint64
+	Requests    int64
+	Tokens      int64
+	CostMicros  int64
+	FirstSeenAt time.Time `gorm:"-"`
+}
+
+// Detect compares the window ending at now against each active subject's baseline.
+func Detect(ctx context.Context, db *gorm.DB, cfg Config, now time.Time) ([]Anomaly, error) {
+	cfg = cfg.withDefaults()
+	out := make([]Anomaly, 0)
+	for _, subject := range []struct {
+		kind   string
+		column string
+	}{{SubjectAPIKey, "api_key_id"}, {SubjectUser, "user_id"}} {
+		found, errDetect := detectSubjects(ctx, db, cfg, now, subject.kind, subject.column)
+		if errDetect != nil {
+			return nil, errDetect
+		}
+		out = append(out, found...)
+	}
+	return out, nil
+}
+
+func detectSubjects(ctx context.Context, db *gorm.DB, cfg Config, now time.Time, kind, column string) ([]Anomaly, error) {
+	window := time.Duration(cfg.WindowMinutes) * time.Minute
+	windowStart := now.Add(-window)
+	baselineStart := windowStart.AddDate(0, 0, -cfg.BaselineDays)
+
+	var current []usageTotals
+	if errScan := db.WithContext(ctx).Model(&models.Usage{}).
+		Select(column+" AS subject_id, COUNT(*) AS requests, COALESCE(SUM(total_tokens), 0) AS tokens, COALESCE(SUM(cost_micros), 0) AS cost_micros").
+		Where(column+" IS NOT NULL AND requested_at >= ? AND requested_at < ?", windowStart, now).
+		Group(column).
+		Scan(&current).Error; errScan != nil {
+		return nil, fmt.Errorf("anomaly: aggregate %s window: %w", kind, errScan)
+	}
+	minCostMicros := int64(math.Round(cfg.MinCost * 1_000_000))
+	candidates := make([]uint64, 0, len(current))
+	for _, row := range current {
+		if row.Requests >= cfg.MinRequests || row.Tokens >= cfg.MinTokens || row.CostMicros >= minCostMicros {
+			candidates = append(candidates, row.SubjectID)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	var history []usageTotals
+	if errScan := db.WithContext(ctx).Model(&models.Usage{}).
+		Select(column+" AS subject_id, COUNT(*) AS requests, COALESCE(SUM(total_tokens), 0) AS tokens, COALESCE(SUM(cost_micros), 0) AS cost_micros").
+		Where(column+" IN ? AND requested_at >= ? AND requested_at < ?", candidates, baselineStart, windowStart).
+		Group(column).
+		Scan(&history).Error; errScan != nil {
+		return nil, fmt.Errorf("anomaly: aggregate %s baseline: %w", kind, errScan)
+	}
+	baselines := make(map[uint64]usageTotals, len(history))
+	for _, row := range history {
+		var firstSeen []time.Time
+		if errPluck := db.WithContext(ctx).Model(&models.Usage{}).
+			Where(column+" = ? AND requested_at >= ?", row.SubjectID, baselineStart).
+			Order("requested_at ASC").
+			Limit(1).
+			Pluck("requested_at", &firstSeen).Error; errPluck != nil {
+			return nil, fmt.Errorf("anomaly: first %s usage: %w", kind, errPluck)
+		}
+		if len(firstSeen) == 0 {
+			continue
+		}
+		row.FirstSeenAt = firstSeen[0]
+		baselines[row.SubjectID] = row
+	}
+
+	out := make([]Anomaly, 0)
+	for _, row := range current {
+		base, ok := baselines[row.SubjectID]
+		if !ok {
+			continue
+		}
+		// Subjects younger than the baseline period are averaged over their own lifetime.
+		historyStart := base.FirstSeenAt
+		if historyStart.Before(baselineStart) {
+			historyStart = baselineStart
+		}
+		span := windowStart.Sub(historyStart)
+		if span < minHistory {
+			continue
+		}
+		windows := float64(span) / float64(window)
+		spikes := make([]Spike, 0, 3)
+		for _, metric := range []struct {
+			name    string
+			current int64
+			total   int64
+			floor   int64
+		}{
+			{MetricCost, row.CostMicros, base.CostMicros, minCostMicros},
+			{MetricTokens, row.Tokens, base.Tokens, cfg.MinTokens},
+			{MetricRequests, row.Requests, base.Requests, cfg.MinRequests},
+		} {
+			if metric.current < metric.floor {
+				continue
+			}
+			baseline := float64(metric.total) / windows
+			if float64(metric.current) < baseline*cfg.Multiplier {
+				continue
+			}
+			spike := Spike{Metric: metric.name, Current: metric.current, Baseline: math.Round(baseline*100) / 100}
+			if baseline > 0 {
+				spike.Ratio = math.Round(float64(metric.current)/baseline*10) / 10
+			}
+			spikes = append(spikes, spike)
+		}
+		if len(spikes) == 0 {
+			continue
+		}
+		anomaly := Anomaly{
+			SubjectType: kind,
+			SubjectID:   row.SubjectID,
+			WindowStart: windowStart,
+			WindowEnd:   now,
+			Spikes:      spikes,
+			Severity:    events.SeverityWarning,
+		}
+		for _, spike := range spikes {
+			if spike.Ratio == 0 || spike.Ratio >= 2*cfg.Multiplier {
+				anomaly.Severity = events.SeverityCritical
+			}
+		}
+		out = append(out, anomaly)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].SubjectID < out[j].SubjectID })
+	return out, nil
+}
+
+// Detector periodically runs Detect and publishes new anomalies.
+type Detector struct {
+	db *gorm.DB
+}
+
+// NewDetector constructs a detector; returns nil when db is nil.
+func NewDetector(db *gorm.DB) *Detector {
+	if db == nil {
+		return nil
+	}
+	return &Detector{db: db}
+}
+
+// Start launches the detection loop in a background goroutine.
+func (d *Detector) Start(ctx context.Context) {
+	if d == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go d.run(ctx)
+	log.Info("usage anomaly detector started")
+}
+
+func (d *Detector) run(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		cfg := LoadConfig()
+		wait := disabledRecheck
+		if cfg.Enabled {
+			wait = time.Duration(cfg.IntervalMinutes) * time.Minute
+			if raised, errRun := d.RunOnce(ctx, cfg, time.Now().UTC()); errRun != nil {
+				log.WithError(errRun).Warn("usage anomaly detector: run failed")
+			} else if raised > 0 {
+				log.Infof("usage anomaly detector: raised %d alerts", raised)
+			}
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			if !timer.Stop() {
+				<-timer.C
+			}
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// RunOnce detects anomalies ending at now and publishes those whose subject has not alerted
+// within the cooldown, judged by the admin notifications the bus records for each alert.
+// Returns the number of alerts raised.
+func (d *Detector) RunOnce(ctx context.Context, cfg Config, now time.Time) (int, error) {
+	if d == nil || d.db == nil {
+		return 0, nil
+	}
+	cfg = cfg.withDefaults()
+	anomalies, errDetect := Detect(ctx, d.db, cfg, now)
+	if errDetect != nil {
+		return 0, errDetect
+	}
+	cooldownStart := now.Add(-time.Duration(cfg.CooldownMinutes) * time.Minute)
+	display := currency.Display(ctx, d.db)
+	raised := 0
+	for _, anomaly := range anomalies {
+		var recent int64
+		if errCount := d.db.WithContext(ctx).Model(&models.AdminNotification{}).
+			Where("event_type = ? AND subject = ? AND occurred_at >= ?", string(events.TypeUsageAnomaly), anomaly.Subject(), cooldownStart).
+			Count(&recent).Error; errCount != nil {
+			return raised, fmt.Errorf("anomaly: check cooldown: %w", errCount)
+		}
+		if recent > 0 {
+			continue
+		}
+		events.Publish(ctx, d.buildEvent(ctx, anomaly, display))
+		raised++
+	}
+	return raised, nil
+}
+
+func (d *Detector) buildEvent(ctx context.Context, anomaly Anomaly, display currency.Converter) events.Event {
+	data := map[string]any{
+		"subject_type": anomaly.SubjectType,
+		"subject_id":   anomaly.SubjectID,
+		"window_start": anomaly.WindowStart,
+		"window_end":   anomaly.WindowEnd,
+		"spikes":       anomaly.Spikes,
+	}
+	label := "user " + strconv.FormatUint(anomaly.SubjectID, 10)
+	switch anomaly.SubjectType {
+	case SubjectAPIKey:
+		var key models.APIKey
+		if errFind := d.db.WithContext(ctx).Select("id", "user_id", "name").First(&key, anomaly.SubjectID).Error; errFind == nil {
+			data["api_key_name"] = key.Name
+			if key.UserID != nil {
+				data["user_id"] = *key.UserID
+			}
+			label = fmt.Sprintf("API key %q (%d)", key.Name, key.ID)
+		} else {
+			label = "API key " + strconv.FormatUint(anomaly.SubjectID, 10)
+		}
+	case SubjectUser:
+		var user models.User
+		if errFind := d.db.WithContext(ctx).Select("id", "username").First(&user, anomaly.SubjectID).Error; errFind == nil {
+			data["username"] = user.Username
+			label = fmt.Sprintf("user %s (%d)", user.Username, user.ID)
+		}
+	}
+
+	top := anomaly.Spikes[0]
+	window := anomaly.WindowEnd.Sub(anomaly.WindowStart).Round(time.Minute)
+	var message string
+	switch top.Metric {
+	case MetricCost:
+		message = fmt.Sprintf("%s spent %s in the last %s", label, display.Format(top.Current, 2), window)
+		if top.Ratio > 0 {
+			message += fmt.Sprintf(", %.1fx its usual %s", top.Ratio, display.Format(int64(math.Round(top.Baseline)), 2))
+		}
+	default:
+		message = fmt.Sprintf("%s used %d %s in the last %s", label, top.Current, top.Metric, window)
+		if top.Ratio > 0 {
+			message += fmt.Sprintf(", %.1fx its usual %.0f", top.Ratio, top.Baseline)
+		}
+	}
+	if top.Ratio == 0 {
+		message += " with no prior usage in the baseline period"
+	}
+	return events.Event{
+		Type:       events.TypeUsageAnomaly,
+		Severity:   anomaly.Severity,
+		Subject:    anomaly.Subject(),
+		Message:    message,
+		Data:       data,
+		OccurredAt: anomaly.WindowEnd,
+	}
+}
@@ -0,0 +1,119 @@
+package anomaly
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func setupAnomalyDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:anomaly_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+// seedUsage inserts perHour requests for every hour in [from, to).
+func seedUsage(t *testing.T, conn *gorm.DB, userID, apiKeyID uint64, from, to time.Time, perHour int, costMicros int64) {
+	t.Helper()
+	rows := make([]models.Usage, 0)
+	for at := from; at.Before(to); at = at.Add(time.Hour) {
+		for i := 0; i < perHour; i++ {
+			user, key := userID, apiKeyID
+			rows = append(rows, models.Usage{
+				Provider:    "claude",
+				Model:       "claude-sonnet",
+				UserID:      &user,
+				APIKeyID:    &key,
+				RequestedAt: at.Add(time.Duration(i) * time.Second),
+				TotalTokens: 10,
+				CostMicros:  costMicros,
+			})
+		}
+	}
+	if errCreate := conn.CreateInBatches(rows, 500).Error; errCreate != nil {
+		t.Fatalf("seed usage: %v", errCreate)
+	}
+}
+
+func TestDetectFlagsSpikesAgainstBaseline(t *testing.T) {
+	conn := setupAnomalyDB(t)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	windowStart := now.Add(-time.Hour)
+	history := windowStart.Add(-72 * time.Hour)
+
+	// API key 1 normally sends one request an hour, then bursts.
+	seedUsage(t, conn, 1, 1, history, windowStart, 1, 1000)
+	seedUsage(t, conn, 1, 1, windowStart, now, 60, 1000)
+	// User 2 is busy but steady.
+	seedUsage(t, conn, 2, 2, history, windowStart, 10, 1000)
+	seedUsage(t, conn, 2, 2, windowStart, now, 10, 1000)
+	// User 3 only started two hours ago, too recently to have a baseline.
+	seedUsage(t, conn, 3, 3, windowStart.Add(-2*time.Hour), windowStart, 1, 1000)
+	seedUsage(t, conn, 3, 3, windowStart, now, 60, 1000)
+
+	cfg := Config{MinRequests: 5, MinTokens: 1_000_000, MinCost: 100}
+	anomalies, errDetect := Detect(context.Background(), conn, cfg, now)
+	if errDetect != nil {
+		t.Fatalf("Detect: %v", errDetect)
+	}
+	if len(anomalies) != 2 {
+		t.Fatalf("expected api key 1 and user 1 flagged, got %+v", anomalies)
+	}
+	for i, want := range []string{"api_key:1", "user:1"} {
+		got := anomalies[i]
+		if got.Subject() != want {
+			t.Fatalf("anomaly %d subject = %s, want %s", i, got.Subject(), want)
+		}
+		if len(got.Spikes) != 1 || got.Spikes[0].Metric != MetricRequests || got.Spikes[0].Current != 60 {
+			t.Fatalf("unexpected spikes for %s: %+v", want, got.Spikes)
+		}
+		if got.Spikes[0].Baseline != 1 || got.Spikes[0].Ratio != 60 {
+			t.Fatalf("unexpected baseline for %s: %+v", want, got.Spikes[0])
+		}
+		if got.Severity != events.SeverityCritical {
+			t.Fatalf("expected critical severity for a 60x spike, got %s", got.Severity)
+		}
+	}
+}
+
+func TestRunOnceHonoursCooldown(t *testing.T) {
+	conn := setupAnomalyDB(t)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	windowStart := now.Add(-time.Hour)
+	seedUsage(t, conn, 1, 1, windowStart.Add(-48*time.Hour), windowStart, 1, 1000)
+	seedUsage(t, conn, 1, 1, windowStart, now, 30, 1000)
+
+	recent := models.AdminNotification{
+		EventID:    "evt-1",
+		EventType:  string(events.TypeUsageAnomaly),
+		Severity:   string(events.SeverityWarning),
+		Subject:    "api_key:1",
+		OccurredAt: now.Add(-30 * time.Minute),
+	}
+	if errCreate := conn.Create(&recent).Error; errCreate != nil {
+		t.Fatalf("create notification: %v", errCreate)
+	}
+
+	cfg := Config{MinRequests: 5, MinTokens: 1_000_000, MinCost: 100, CooldownMinutes: 60}
+	raised, errRun := NewDetector(conn).RunOnce(context.Background(), cfg, now)
+	if errRun != nil {
+		t.Fatalf("RunOnce: %v", errRun)
+	}
+	if raised != 1 {
+		t.Fatalf("expected only user:1 raised while api_key:1 cools down, got %d", raised)
+	}
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/fs"
 	"net/http"
@@ -14,21 +15,48 @@ import (
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/access"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/anomaly"
 	internalauth "github.com/router-for-me/CLIProxyAPIBusiness/internal/auth"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/authcooldown"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/authschedule"
 	internalbilling "github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/bulkdelete"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/chaos"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/cluster"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/coop"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/costreplay"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/currency"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/environments"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/healthprobe"
 	relayhttp "github.com/router-for-me/CLIProxyAPIBusiness/internal/http"
 	internalhttp "github.com/router-for-me/CLIProxyAPIBusiness/internal/http/api/admin"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/http/api/front"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/kpisnapshot"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/lifecycle"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/logging"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/mail"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/mfasession"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelreference"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelregistry"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/proxyhealth"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/quota"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/ratelimit"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/requestlog"
 	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/shutdown"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/slo"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/standby"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/startupcheck"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/stats"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/store"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/tierupgrade"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/tracing"
 	internalusage "github.com/router-for-me/CLIProxyAPIBusiness/internal/usage"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/watcher"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/webhook"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/webui"
 
 	"github.com/gin-gonic/gin"
@@ -65,6 +93,8 @@ func Migrate(ctx context.Context, cfg config.AppConfig) error {
 
 // RunServer boots the API relay server with database-backed components.
 func RunServer(ctx context.Context, cfg config.AppConfig, defaultPort int) error {
+	ctx, cancelRun := context.WithCancel(ctx)
+	defer cancelRun()
 	configPath := config.ResolveConfigPath(cfg.ConfigPath)
 	dsn, err := config.LoadDatabaseDSN(configPath)
 	if err != nil {
@@ -74,6 +104,24 @@ func RunServer(ctx context.Context, cfg config.AppConfig, defaultPort int) error
 	if errLoad != nil {
 		return errLoad
 	}
+	cipher, errEncryption := configureEncryption(configPath)
+	if errEncryption != nil {
+		return errEncryption
+	}
+	shutdownTracing, errTracing := configureTracing(configPath)
+	if errTracing != nil {
+		return errTracing
+	}
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if errShutdown := shutdownTracing(flushCtx); errShutdown != nil {
+			log.WithError(errShutdown).Warn("flush tracing spans on shutdown failed")
+		}
+	}()
+	if errPayloadStore := configurePayloadStore(configPath); errPayloadStore != nil {
+		return errPayloadStore
+	}
 	conn, err := db.Open(dsn)
 	if err != nil {
 		return err
@@ -81,6 +129,9 @@ func RunServer(ctx context.Context, cfg config.AppConfig, defaultPort int) error
 	if errMigrate := db.Migrate(conn); errMigrate != nil {
 		return errMigrate
 	}
+	if errChaos := chaos.RegisterDBLatency(conn); errChaos != nil {
+		return fmt.Errorf("register chaos hooks: %w", errChaos)
+	}
 	if errRefreshSettings := internalsettings.RefreshDBConfigSnapshot(ctx, conn); errRefreshSettings != nil {
 		return fmt.Errorf("refresh settings snapshot: %w", errRefreshSettings)
 	}
@@ -102,7 +153,44 @@ func RunServer(ctx context.Context, cfg config.AppConfig, defaultPort int) error
 
 	jwtConfig, _ := config.LoadJWTConfig(configPath)
 
+	startupReport := startupcheck.Run(ctx, conn, startupcheck.Input{
+		ConfigPath:    configPath,
+		JWTSecret:     jwtConfig.Secret,
+		EncryptionKey: cipher != nil,
+	})
+	if errStartup := startupReport.Err(); errStartup != nil {
+		return errStartup
+	}
+	for _, issue := range startupReport.Issues {
+		log.Warnf("startup check %s: %s (admin writes disabled)", issue.Code, issue.Message)
+	}
+	startupcheck.SetCurrent(startupReport)
+
+	consistencyCfg, errConsistency := config.LoadConsistencyConfig(configPath)
+	if errConsistency != nil {
+		return errConsistency
+	}
+	runConsistencyCheck(ctx, conn, configPath, consistencyCfg)
+
+	envCfg, errEnv := config.LoadEnvironmentsConfig(configPath)
+	if errEnv != nil {
+		return errEnv
+	}
+	usageArchiveCfg, errUsageArchive := config.LoadUsageArchiveConfig(configPath)
+	if errUsageArchive != nil {
+		return errUsageArchive
+	}
+	usageWriterCfg, errUsageWriter := config.LoadUsageWriterConfig(configPath)
+	if errUsageWriter != nil {
+		return errUsageWriter
+	}
+	standbyCfg, errStandby := config.LoadStandbyConfig(configPath)
+	if errStandby != nil {
+		return errStandby
+	}
+
 	authStore := store.NewGormAuthStore(conn)
+	authStore.SetEnvironment(envCfg.Current)
 	sdkAuth.RegisterTokenStore(authStore)
 
 	// Respect config.yaml when commercial-mode is explicitly set.
@@ -132,7 +220,23 @@ func RunServer(ctx context.Context, cfg config.AppConfig, defaultPort int) error
 
 	serverAccessMgr := sdkaccess.NewManager()
 
-	coreManager := coreauth.NewManager(authStore, internalauth.NewSelector(conn), internalauth.NewStatusCodeHook())
+	selector := internalauth.NewSelector(conn)
+	coreManager := coreauth.NewManager(authStore, selector, internalauth.NewStatusCodeHook())
+
+	standbyRegistry := standby.NewRegistry()
+	standbyRegistry.Register("rate_limit", selector.RateLimiter())
+	standbyRegistry.Register("auth_selector", selector)
+	if standbyCfg.Dir != "" {
+		snap, restored, errRestore := standby.Restore(standbyRegistry, standbyCfg.Dir, standbyCfg.MaxAge, time.Now())
+		switch {
+		case errors.Is(errRestore, standby.ErrStale):
+			log.Infof("standby: ignoring snapshot from %s exported at %s", snap.Instance, snap.ExportedAt.Format(time.RFC3339))
+		case errRestore != nil:
+			log.WithError(errRestore).Warn("standby: restore runtime state failed")
+		case len(restored) > 0:
+			log.Infof("standby: restored %v from %s", restored, snap.Instance)
+		}
+	}
 
 	if errLog := logging.ConfigureLogOutput(coreCfg); errLog != nil {
 		return fmt.Errorf("configure logging: %w", errLog)
@@ -149,7 +253,9 @@ func RunServer(ctx context.Context, cfg config.AppConfig, defaultPort int) error
 		WithServerOptions(
 			sdkapi.WithMiddleware(
 				logging.GinLogrusRecovery(),
+				tracing.GinMiddleware(),
 				logging.GinLogrusLogger(),
+				shutdown.Default().Middleware(),
 				corsMiddleware(),
 				func(c *gin.Context) {
 					if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
@@ -166,6 +272,12 @@ func RunServer(ctx context.Context, cfg config.AppConfig, defaultPort int) error
 				},
 				webUIRootMiddleware(webBundle.IndexHTML),
 				relayhttp.CLIProxyModelsMiddleware(conn, modelStore),
+				relayhttp.DebugRouteMiddleware(conn),
+				relayhttp.APIKeyRateLimitMiddleware(conn, ratelimit.Default()),
+				relayhttp.ConcurrencyLimitMiddleware(conn, ratelimit.DefaultInFlight()),
+				relayhttp.ModelConcurrencyMiddleware(ratelimit.DefaultModelGate()),
+				relayhttp.RequestLogMiddleware(conn),
+				relayhttp.RetryAfterMiddleware(),
 			),
 			sdkapi.WithRouterConfigurator(func(engine *gin.Engine, baseHandler *sdkhandlers.BaseAPIHandler, cfg *sdkconfig.Config) {
 				internalhttp.RegisterAdminRoutes(engine, conn, jwtConfig, configPath, cfg, baseHandler)
@@ -271,16 +383,101 @@ func RunServer(ctx context.Context, cfg config.AppConfig, defaultPort int) error
 	if err != nil {
 		return err
 	}
-	service.RegisterUsagePlugin(internalusage.NewGormUsagePlugin(conn))
+	events.RegisterDefaultSubscribers(ctx, events.Default(), conn)
+	webhookDispatcher := webhook.NewDispatcher(conn)
+	webhookDispatcher.Start(ctx)
+	webhook.SetDefault(webhookDispatcher)
+	if mfaStore := mfasession.NewDBStore(conn); mfaStore != nil {
+		mfasession.SetDefault(mfaStore)
+		mfasession.NewJanitor(mfaStore).Start(ctx)
+	}
+	standby.NewExporter(standbyRegistry, standbyCfg.Dir, standbyCfg.Interval).Start(ctx)
+	if webhookSubscriber := webhook.NewSubscriber(conn, webhookDispatcher); webhookSubscriber != nil {
+		events.Default().Subscribe(webhookSubscriber)
+	}
+	if lowBalanceNotifier := mail.NewLowBalanceNotifier(conn, mail.New()); lowBalanceNotifier != nil {
+		events.Default().Subscribe(lowBalanceNotifier, mail.LowBalanceTypes...)
+	}
+	if lifecycleNotifier := mail.NewLifecycleNotifier(conn, mail.New()); lifecycleNotifier != nil {
+		events.Default().Subscribe(lifecycleNotifier, mail.LifecycleTypes...)
+	}
+	events.Default().Start(ctx)
+	usagePlugin := internalusage.NewGormUsagePlugin(conn)
+	usageWriterOpts := internalusage.DefaultAsyncOptions()
+	usageWriterOpts.OutboxPath = usageWriterCfg.OutboxPath
+	usagePlugin.StartAsync(usageWriterOpts)
+	service.RegisterUsagePlugin(usagePlugin)
 	if cleaner := internalusage.NewUsagesRetentionCleaner(conn); cleaner != nil {
+		cleaner.SetArchiveDir(usageArchiveCfg.Dir)
 		cleaner.Start(ctx)
 	}
-	if quotaPoller := quota.NewPoller(conn, coreManager); quotaPoller != nil {
+	if requestLogCleaner := requestlog.NewCleaner(conn); requestLogCleaner != nil {
+		requestLogCleaner.Start(ctx)
+	}
+	quotaPoller := quota.NewPoller(conn, coreManager)
+	if quotaPoller != nil {
 		quotaPoller.Start(ctx)
 	}
 	if modelSyncer := modelreference.NewSyncer(conn); modelSyncer != nil {
 		modelSyncer.Start(ctx)
 	}
+	if tierEvaluator := tierupgrade.NewEvaluator(conn); tierEvaluator != nil {
+		tierEvaluator.Start(ctx)
+	}
+	if groupMigrations := internalbilling.NewGroupMigrationScheduler(conn); groupMigrations != nil {
+		groupMigrations.Start(ctx)
+	}
+	if billRenewals := internalbilling.NewBillRenewalScheduler(conn); billRenewals != nil {
+		billRenewals.Start(ctx)
+	}
+	if coopSettler := coop.NewSettler(conn); coopSettler != nil {
+		coopSettler.Start(ctx)
+	}
+	if bulkDeleteRunner := bulkdelete.NewRunner(conn); bulkDeleteRunner != nil {
+		bulkDeleteRunner.Start(ctx)
+	}
+	if ruleSnapshotter := costreplay.NewSnapshotter(conn); ruleSnapshotter != nil {
+		ruleSnapshotter.Start(ctx)
+	}
+	if costReplayRunner := costreplay.NewRunner(conn); costReplayRunner != nil {
+		costReplayRunner.Start(ctx)
+	}
+	if rateFetcher := currency.NewFetcher(conn); rateFetcher != nil {
+		rateFetcher.Start(ctx)
+	}
+	if anomalyDetector := anomaly.NewDetector(conn); anomalyDetector != nil {
+		anomalyDetector.Start(ctx)
+	}
+	if sloEvaluator := slo.NewEvaluator(conn); sloEvaluator != nil {
+		sloEvaluator.Start(ctx)
+	}
+	if kpiSnapshotter := kpisnapshot.NewSnapshotter(conn); kpiSnapshotter != nil {
+		kpiSnapshotter.Start(ctx)
+	}
+	if statsAggregator := stats.NewAggregator(conn); statsAggregator != nil {
+		statsAggregator.Start(ctx)
+	}
+	if healthProber := healthprobe.NewProber(conn); healthProber != nil {
+		healthProber.Start(ctx)
+	}
+	if authCooldowns := authcooldown.NewScheduler(conn); authCooldowns != nil {
+		authCooldowns.Start(ctx)
+	}
+	if userLifecycle := lifecycle.NewScheduler(conn); userLifecycle != nil {
+		userLifecycle.Start(ctx)
+	}
+	if proxyHealth := proxyhealth.NewChecker(conn); proxyHealth != nil {
+		proxyHealth.Start(ctx)
+	}
+	if coordinator := cluster.NewCoordinator(conn, envCfg.Current); coordinator != nil {
+		coordinator.Start(ctx)
+	}
+	if authSchedules := authschedule.NewEvaluator(conn); authSchedules != nil {
+		authSchedules.Start(ctx)
+	}
+	if envSyncer := environments.NewSyncer(conn, envCfg); envSyncer != nil {
+		envSyncer.Start(ctx)
+	}
 	go func() {
 		if errAutoImport := internalbilling.AutoImportDefaultGroupOnce(ctx, conn, 60*time.Second, 2*time.Second); errAutoImport != nil {
 			log.WithError(errAutoImport).Warn("billing rules auto import on startup failed")
@@ -289,8 +486,12 @@ func RunServer(ctx context.Context, cfg config.AppConfig, defaultPort int) error
 
 	// serverAccessMgr.SetProviders(nil)
 
+	graceful := startGracefulShutdown(ctx, shutdown.Default(), cancelRun)
 	log.Infof("starting relay with config=%s", cfg.ConfigPath)
-	return service.Run(ctx)
+	errRun := service.Run(ctx)
+	cancelRun()
+	graceful.finish(quotaPoller, usagePlugin)
+	return errRun
 }
 
 // isCommercialModeProvided reports whether config.yaml explicitly sets the top-level
@@ -348,6 +549,12 @@ func isAPIRoute(requestPath string) bool {
 	if requestPath == "/healthz" || strings.HasPrefix(requestPath, "/healthz/") {
 		return true
 	}
+	if requestPath == "/readyz" {
+		return true
+	}
+	if requestPath == "/metrics" {
+		return true
+	}
 	apiPrefixes := []string{"/v0", "/v1", "/v1beta"}
 	for _, prefix := range apiPrefixes {
 		if requestPath == prefix || strings.HasPrefix(requestPath, prefix+"/") {
@@ -0,0 +1,35 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/consistency"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// runConsistencyCheck cross-checks the database against the config file, logs every problem,
+// repairs the fixable ones when auto-repair is enabled and publishes the report. A failed
+// check is logged and never blocks startup.
+func runConsistencyCheck(ctx context.Context, conn *gorm.DB, configPath string, cfg config.ConsistencyConfig) {
+	report, errCheck := consistency.Check(ctx, conn, configPath, time.Now())
+	if errCheck != nil {
+		log.WithError(errCheck).Warn("consistency check failed")
+		return
+	}
+	if cfg.AutoRepair {
+		if errRepair := consistency.Repair(ctx, conn, &report); errRepair != nil {
+			log.WithError(errRepair).Warn("consistency auto-repair failed")
+		}
+	}
+	for _, issue := range report.Issues {
+		if issue.Repaired {
+			log.Infof("consistency check %s: %s (repaired)", issue.Code, issue.Message)
+			continue
+		}
+		log.Warnf("consistency check %s: %s", issue.Code, issue.Message)
+	}
+	consistency.SetCurrent(report)
+}
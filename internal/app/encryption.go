@@ -0,0 +1,69 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/crypto"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	log "github.com/sirupsen/logrus"
+)
+
+// configureEncryption builds the secret cipher from config and installs it for model hooks.
+// It returns nil when no key is configured.
+func configureEncryption(configPath string) (*crypto.Cipher, error) {
+	encCfg, errLoad := config.LoadEncryptionConfig(configPath)
+	if errLoad != nil {
+		return nil, errLoad
+	}
+	if encCfg.Key == "" {
+		crypto.SetDefault(nil)
+		return nil, nil
+	}
+	keys := make(map[string][]byte, len(encCfg.PreviousKeys)+1)
+	for id, raw := range encCfg.PreviousKeys {
+		key, errParse := crypto.ParseKey(raw)
+		if errParse != nil {
+			return nil, fmt.Errorf("encryption previous key %q: %w", id, errParse)
+		}
+		keys[id] = key
+	}
+	key, errParse := crypto.ParseKey(encCfg.Key)
+	if errParse != nil {
+		return nil, fmt.Errorf("encryption key: %w", errParse)
+	}
+	keys[encCfg.KeyID] = key
+	wrapper, errWrapper := crypto.NewLocalKeyWrapper(encCfg.KeyID, keys)
+	if errWrapper != nil {
+		return nil, errWrapper
+	}
+	cipher := crypto.NewCipher(wrapper, encCfg.Enabled)
+	crypto.SetDefault(cipher)
+	log.Infof("secret encryption configured (enabled=%t key_id=%s)", cipher.Enabled(), encCfg.KeyID)
+	return cipher, nil
+}
+
+// EncryptSecrets encrypts existing plaintext secrets using the configured encryption key.
+func EncryptSecrets(ctx context.Context, cfg config.AppConfig, dryRun bool) (db.EncryptSecretsResult, error) {
+	configPath := config.ResolveConfigPath(cfg.ConfigPath)
+	cipher, errCipher := configureEncryption(configPath)
+	if errCipher != nil {
+		return db.EncryptSecretsResult{}, errCipher
+	}
+	if !cipher.Enabled() {
+		return db.EncryptSecretsResult{}, fmt.Errorf("encryption is not enabled (set `encryption.enabled` or %s)", config.EnvEncryptionEnabled)
+	}
+	dsn, err := config.LoadDatabaseDSN(configPath)
+	if err != nil {
+		return db.EncryptSecretsResult{}, err
+	}
+	conn, err := db.Open(dsn)
+	if err != nil {
+		return db.EncryptSecretsResult{}, err
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		return db.EncryptSecretsResult{}, errMigrate
+	}
+	return db.EncryptSecrets(ctx, conn, cipher, dryRun)
+}
@@ -20,6 +20,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // InitRequest contains parameters for initial system setup.
@@ -287,7 +288,7 @@ func upsertSiteNameSetting(conn *gorm.DB, siteName string) error {
 	value := json.RawMessage(payload)
 
 	now := time.Now().UTC()
-	res := conn.Model(&models.Setting{}).Where("key = ?", internalsettings.SiteNameKey).
+	res := conn.Model(&models.Setting{}).Where("? = ?", clause.Column{Name: "key"}, internalsettings.SiteNameKey).
 		Updates(map[string]any{
 			"value":      value,
 			"updated_at": now,
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"gorm.io/gorm"
+)
+
+// MigrationStatus opens the database and lists the schema migrations with their applied state.
+func MigrationStatus(ctx context.Context, cfg config.AppConfig) ([]db.MigrationState, error) {
+	conn, err := openConfiguredDatabase(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return db.MigrationStatus(conn.WithContext(ctx))
+}
+
+// MigrateUp applies pending migrations up to target; a negative target applies all of them.
+func MigrateUp(ctx context.Context, cfg config.AppConfig, target int) (int, error) {
+	conn, err := openConfiguredDatabase(cfg)
+	if err != nil {
+		return 0, err
+	}
+	conn = conn.WithContext(ctx)
+	if target < 0 {
+		target = db.LatestSchemaVersion()
+	}
+	current, errVersion := db.SchemaVersion(conn)
+	if errVersion != nil {
+		return 0, errVersion
+	}
+	if current <= db.LatestSchemaVersion() && target < current {
+		return current, fmt.Errorf("schema is at version %d, use migrate down to revert to %d", current, target)
+	}
+	if errMigrate := db.MigrateTo(conn, target); errMigrate != nil {
+		return current, errMigrate
+	}
+	return db.SchemaVersion(conn)
+}
+
+// MigrateDown reverts migrations down to target; a negative target reverts the latest applied one.
+func MigrateDown(ctx context.Context, cfg config.AppConfig, target int) (int, error) {
+	conn, err := openConfiguredDatabase(cfg)
+	if err != nil {
+		return 0, err
+	}
+	conn = conn.WithContext(ctx)
+	current, errVersion := db.SchemaVersion(conn)
+	if errVersion != nil {
+		return 0, errVersion
+	}
+	if target < 0 {
+		target = current - 1
+	}
+	if target < 0 || target > current {
+		return current, fmt.Errorf("schema is at version %d, cannot revert to %d", current, target)
+	}
+	if errMigrate := db.MigrateTo(conn, target); errMigrate != nil {
+		return current, errMigrate
+	}
+	return db.SchemaVersion(conn)
+}
+
+// openConfiguredDatabase opens the database named by the config without migrating it.
+func openConfiguredDatabase(cfg config.AppConfig) (*gorm.DB, error) {
+	dsn, err := config.LoadDatabaseDSN(config.ResolveConfigPath(cfg.ConfigPath))
+	if err != nil {
+		return nil, err
+	}
+	return db.Open(dsn)
+}
@@ -0,0 +1,35 @@
+package app
+
+import (
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/payloadstore"
+	log "github.com/sirupsen/logrus"
+)
+
+// configurePayloadStore installs the object store for captured request bodies. Without one
+// the bodies stay in the database.
+func configurePayloadStore(configPath string) error {
+	storeCfg, errLoad := config.LoadPayloadStoreConfig(configPath)
+	if errLoad != nil {
+		return errLoad
+	}
+	store, errStore := payloadstore.New(payloadstore.Options{
+		Backend:         storeCfg.Backend,
+		Dir:             storeCfg.Dir,
+		Bucket:          storeCfg.Bucket,
+		Prefix:          storeCfg.Prefix,
+		Endpoint:        storeCfg.Endpoint,
+		Region:          storeCfg.Region,
+		AccessKeyID:     storeCfg.AccessKeyID,
+		SecretAccessKey: storeCfg.SecretAccessKey,
+		PathStyle:       storeCfg.PathStyle,
+	})
+	if errStore != nil {
+		return errStore
+	}
+	payloadstore.SetDefault(store)
+	if store != nil {
+		log.Infof("payload store configured (backend=%s)", store.Backend())
+	}
+	return nil
+}
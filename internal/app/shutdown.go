@@ -0,0 +1,103 @@
+package app
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/quota"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/shutdown"
+	internalusage "github.com/router-for-me/CLIProxyAPIBusiness/internal/usage"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// shutdownDrainTimeout bounds how long in-flight requests may run after a stop signal.
+	shutdownDrainTimeout = 30 * time.Second
+	// shutdownFlushTimeout bounds stopping the quota poller and flushing queued usage.
+	shutdownFlushTimeout = 30 * time.Second
+)
+
+// gracefulShutdown stops the main server in order: on SIGINT or SIGTERM it turns new
+// requests away and waits for in-flight ones, then cancels the server context; finish then
+// waits for the quota poller and flushes queued usage records with their balance deductions.
+type gracefulShutdown struct {
+	drainer *shutdown.Drainer
+	signals chan os.Signal
+	done    chan struct{}
+	started time.Time
+	summary shutdown.Summary
+}
+
+// startGracefulShutdown installs the signal handler; stop cancels the server context.
+func startGracefulShutdown(ctx context.Context, drainer *shutdown.Drainer, stop context.CancelFunc) *gracefulShutdown {
+	g := &gracefulShutdown{
+		drainer: drainer,
+		signals: make(chan os.Signal, 1),
+		done:    make(chan struct{}),
+	}
+	signal.Notify(g.signals, os.Interrupt, syscall.SIGTERM)
+	go g.watch(ctx, stop)
+	return g
+}
+
+func (g *gracefulShutdown) watch(ctx context.Context, stop context.CancelFunc) {
+	defer close(g.done)
+	select {
+	case sig := <-g.signals:
+		g.summary.Signal = sig.String()
+	case <-ctx.Done():
+		signal.Stop(g.signals)
+		return
+	}
+	// Restore the default handler so a second signal kills the process.
+	signal.Stop(g.signals)
+	g.started = time.Now()
+	g.summary.DrainedRequests = g.drainer.InFlight()
+	log.Infof("shutdown: %s received, draining %d in-flight requests", g.summary.Signal, g.summary.DrainedRequests)
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), shutdownDrainTimeout)
+	g.summary.AbandonedRequests = g.drainer.Drain(drainCtx)
+	cancel()
+	if g.summary.AbandonedRequests > 0 {
+		log.Warnf("shutdown: drain timed out with %d requests still in flight", g.summary.AbandonedRequests)
+	}
+	stop()
+}
+
+// finish runs once the server has stopped and the server context is cancelled. It waits for
+// the quota poller, flushes queued usage records and logs the shutdown summary.
+func (g *gracefulShutdown) finish(poller *quota.Poller, usagePlugin *internalusage.GormUsagePlugin) {
+	<-g.done
+	if g.started.IsZero() {
+		g.started = time.Now()
+		g.summary.Signal = "server stopped"
+	}
+	flushCtx, cancel := context.WithTimeout(context.Background(), shutdownFlushTimeout)
+	defer cancel()
+
+	if errWait := poller.Wait(flushCtx); errWait != nil {
+		log.WithError(errWait).Warn("shutdown: quota poller did not stop in time")
+	} else {
+		g.summary.QuotaPollerOK = true
+	}
+	g.summary.QueuedUsage = usagePlugin.QueueLength()
+	if errClose := usagePlugin.Close(flushCtx); errClose != nil {
+		log.WithError(errClose).Warn("flush queued usage records on shutdown failed")
+	} else {
+		g.summary.UsageFlushed = true
+	}
+	g.summary.Duration = time.Since(g.started)
+
+	log.WithFields(log.Fields{
+		"reason":             g.summary.Signal,
+		"drained_requests":   g.summary.DrainedRequests,
+		"abandoned_requests": g.summary.AbandonedRequests,
+		"queued_usage":       g.summary.QueuedUsage,
+		"usage_flushed":      g.summary.UsageFlushed,
+		"quota_poller_ok":    g.summary.QuotaPollerOK,
+		"duration":           g.summary.Duration.Round(time.Millisecond).String(),
+	}).Info("shutdown complete")
+}
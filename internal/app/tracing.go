@@ -0,0 +1,34 @@
+package app
+
+import (
+	"context"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/tracing"
+	log "github.com/sirupsen/logrus"
+)
+
+// configureTracing enables span export when tracing is configured and returns a shutdown
+// function that flushes pending spans. The returned function is never nil.
+func configureTracing(configPath string) (func(context.Context) error, error) {
+	noop := func(context.Context) error { return nil }
+	tracingCfg, errLoad := config.LoadTracingConfig(configPath)
+	if errLoad != nil {
+		return noop, errLoad
+	}
+	if !tracingCfg.Enabled {
+		tracing.Disable()
+		return noop, nil
+	}
+	shutdown, errConfigure := tracing.Configure(tracing.Options{
+		Endpoint:    tracingCfg.Endpoint,
+		ServiceName: tracingCfg.ServiceName,
+		Headers:     tracingCfg.Headers,
+		SampleRatio: tracingCfg.SampleRatio,
+	})
+	if errConfigure != nil {
+		return noop, errConfigure
+	}
+	log.Infof("tracing configured (endpoint=%s service=%s)", tracingCfg.Endpoint, tracingCfg.ServiceName)
+	return shutdown, nil
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// debugRouteContextKey stores the admin debug routing override on the gin context.
+const debugRouteContextKey = "debugRoute"
+
+// DebugRoute forces a request onto an explicit upstream model and/or credential.
+type DebugRoute struct {
+	Model   string // Upstream model name sent as-is, skipping model mapping aliases.
+	AuthKey string // Auth key (file name) to route through, skipping selector rules.
+}
+
+// SetDebugRoute attaches a debug routing override to the request.
+func SetDebugRoute(c *gin.Context, route DebugRoute) {
+	if c == nil {
+		return
+	}
+	route.Model = strings.TrimSpace(route.Model)
+	route.AuthKey = strings.TrimSpace(route.AuthKey)
+	if route.Model == "" && route.AuthKey == "" {
+		return
+	}
+	c.Set(debugRouteContextKey, route)
+}
+
+// debugRouteFromContext returns the debug routing override for the request, if any.
+func debugRouteFromContext(ctx context.Context) (DebugRoute, bool) {
+	if ctx == nil {
+		return DebugRoute{}, false
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil {
+		return DebugRoute{}, false
+	}
+	v, exists := ginCtx.Get(debugRouteContextKey)
+	if !exists {
+		return DebugRoute{}, false
+	}
+	route, ok := v.(DebugRoute)
+	return route, ok
+}
+
+// modelMappingBypassed reports whether the request names an explicit upstream model, so model
+// mapping settings keyed by the model must not apply to it.
+func modelMappingBypassed(ctx context.Context) bool {
+	route, ok := debugRouteFromContext(ctx)
+	return ok && route.Model != ""
+}
+
+// pickDebugRouteAuth returns the pinned auth, ignoring cooldowns, group filters and rate limits.
+func pickDebugRouteAuth(auths []*coreauth.Auth, authKey, provider, model string) (*coreauth.Auth, error) {
+	for _, candidate := range auths {
+		if candidate == nil || candidate.Disabled {
+			continue
+		}
+		if strings.TrimSpace(candidate.ID) == authKey {
+			return candidate, nil
+		}
+	}
+	return nil, newModelNotFoundError(provider, model)
+}
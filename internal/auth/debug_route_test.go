@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelmapping"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+)
+
+func TestSelectorDebugRoutePinsAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Request = httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	SetDebugRoute(ginCtx, DebugRoute{AuthKey: "auth-b"})
+	ctx := context.WithValue(context.Background(), "gin", ginCtx)
+
+	selector := &Selector{}
+	auths := []*coreauth.Auth{
+		{ID: "auth-a", Status: coreauth.StatusActive},
+		{ID: "auth-b", Status: coreauth.StatusActive, Metadata: map[string]any{"_sys_token_invalid": true}},
+	}
+
+	selected, errPick := selector.Pick(ctx, "codex", "gpt-5", cliproxyexecutor.Options{}, auths)
+	if errPick != nil {
+		t.Fatalf("expected pick ok, got %v", errPick)
+	}
+	if selected == nil || selected.ID != "auth-b" {
+		t.Fatalf("expected pinned auth selected, got %+v", selected)
+	}
+
+	SetDebugRoute(ginCtx, DebugRoute{AuthKey: "missing"})
+	if _, errMissing := selector.Pick(ctx, "codex", "gpt-5", cliproxyexecutor.Options{}, auths); errMissing == nil {
+		t.Fatalf("expected error for unknown pinned auth")
+	}
+}
+
+func TestSelectorDebugRouteModelBypassesModelMapping(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	conn, errOpen := db.Open(":memory:")
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	now := time.Now().UTC()
+
+	groupAllowed := models.UserGroup{Name: "allowed", CreatedAt: now, UpdatedAt: now}
+	groupDenied := models.UserGroup{Name: "denied", CreatedAt: now, UpdatedAt: now}
+	if errCreate := conn.Create(&groupAllowed).Error; errCreate != nil {
+		t.Fatalf("create user group: %v", errCreate)
+	}
+	if errCreate := conn.Create(&groupDenied).Error; errCreate != nil {
+		t.Fatalf("create user group: %v", errCreate)
+	}
+	user := models.User{Username: "admin-debug", Password: "hashed", UserGroupID: models.UserGroupIDs{&groupAllowed.ID}, CreatedAt: now, UpdatedAt: now}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+	authGroup := models.AuthGroup{Name: "ag", CreatedAt: now, UpdatedAt: now}
+	if errCreate := conn.Create(&authGroup).Error; errCreate != nil {
+		t.Fatalf("create auth group: %v", errCreate)
+	}
+	authRecord := models.Auth{
+		Key:         "auth-1",
+		AuthGroupID: models.AuthGroupIDs{&authGroup.ID},
+		Content:     datatypes.JSON([]byte(`{"type":"codex"}`)),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if errCreate := conn.Create(&authRecord).Error; errCreate != nil {
+		t.Fatalf("create auth record: %v", errCreate)
+	}
+	// The alias gpt-5 maps onto gpt-5-codex, pins fill-first routing and is closed to the user.
+	mapping := models.ModelMapping{
+		ID:           1,
+		Provider:     "codex",
+		ModelName:    "gpt-5-codex",
+		NewModelName: "gpt-5",
+		Selector:     modelMappingSelectorFillFirst,
+		IsEnabled:    true,
+		UserGroupID:  models.UserGroupIDs{&groupDenied.ID},
+	}
+	modelmapping.StoreModelMappings(now, []models.ModelMapping{mapping})
+	defer modelmapping.StoreModelMappings(now, nil)
+
+	selector := NewSelector(conn)
+	selector.rateLimiter = nil
+	selector.resolveRateLimit = nil
+	auths := []*coreauth.Auth{{ID: authRecord.Key, Status: coreauth.StatusActive}}
+
+	ctx, _ := buildTestGinContext("/v1/chat/completions", user.ID)
+	if mappingID, mode := selector.loadModelMappingSelector(ctx, "codex", "gpt-5-codex"); mappingID != mapping.ID || mode != modelMappingSelectorFillFirst {
+		t.Fatalf("expected the mapping to apply without a debug route, got id=%d selector=%d", mappingID, mode)
+	}
+	if _, errPick := selector.Pick(ctx, "codex", "gpt-5-codex", cliproxyexecutor.Options{}, auths); errPick == nil {
+		t.Fatal("expected the mapping user groups to block the pick without a debug route")
+	}
+
+	debugCtx, ginCtx := buildTestGinContext("/v1/chat/completions", user.ID)
+	SetDebugRoute(ginCtx, DebugRoute{Model: "gpt-5-codex"})
+	if mappingID, mode := selector.loadModelMappingSelector(debugCtx, "codex", "gpt-5-codex"); mappingID != 0 || mode != modelMappingSelectorRoundRobin {
+		t.Fatalf("expected the debug model to bypass the mapping, got id=%d selector=%d", mappingID, mode)
+	}
+	selected, errPick := selector.Pick(debugCtx, "codex", "gpt-5-codex", cliproxyexecutor.Options{}, auths)
+	if errPick != nil {
+		t.Fatalf("expected the debug model to skip mapping user groups, got %v", errPick)
+	}
+	if selected == nil || selected.ID != authRecord.Key {
+		t.Fatalf("unexpected selection %+v", selected)
+	}
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/headertemplate"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/logging"
+)
+
+// headerVarsContextKey stores the request's header template values on the gin context, so
+// retries reuse the same {{uuid}} and {{request_id}}.
+const headerVarsContextKey = "headerTemplateVars"
+
+// renderHeaderTemplates returns a copy of selected with templated header attributes resolved
+// for the request, or selected itself when none of its headers are templated.
+func renderHeaderTemplates(ctx context.Context, selected *coreauth.Auth) *coreauth.Auth {
+	if selected == nil || !hasHeaderTemplates(selected.Attributes) {
+		return selected
+	}
+	vars := headerVarsFromContext(ctx)
+	rendered := selected.Clone()
+	for key, value := range rendered.Attributes {
+		if strings.HasPrefix(key, "header:") && headertemplate.IsTemplate(value) {
+			rendered.Attributes[key] = headertemplate.Render(value, vars)
+		}
+	}
+	return rendered
+}
+
+// hasHeaderTemplates reports whether any header attribute holds placeholders.
+func hasHeaderTemplates(attrs map[string]string) bool {
+	for key, value := range attrs {
+		if strings.HasPrefix(key, "header:") && headertemplate.IsTemplate(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerVarsFromContext returns the request's template values, creating them on first use.
+// The timestamp placeholders always reflect the current attempt.
+func headerVarsFromContext(ctx context.Context) headertemplate.Vars {
+	now := time.Now()
+	var ginCtx *gin.Context
+	if ctx != nil {
+		ginCtx, _ = ctx.Value("gin").(*gin.Context)
+	}
+	if ginCtx == nil {
+		return headertemplate.NewVars(now, logging.GetRequestID(ctx))
+	}
+	if v, exists := ginCtx.Get(headerVarsContextKey); exists {
+		if vars, ok := v.(headertemplate.Vars); ok {
+			vars.Now = now
+			return vars
+		}
+	}
+	vars := headertemplate.NewVars(now, logging.GetGinRequestID(ginCtx))
+	ginCtx.Set(headerVarsContextKey, vars)
+	return vars
+}
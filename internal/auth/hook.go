@@ -4,6 +4,7 @@ import (
 	"context"
 
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/retrypolicy"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -17,8 +18,15 @@ func NewStatusCodeHook() *StatusCodeHook {
 	return &StatusCodeHook{}
 }
 
-// OnResult logs request outcomes with severity derived from HTTP status codes.
+// OnResult logs request outcomes with severity derived from HTTP status codes and reports
+// them to the retry policy deciding whether the request may fail over.
 func (h *StatusCodeHook) OnResult(ctx context.Context, result coreauth.Result) {
+	failedStatus := 0
+	if result.Error != nil {
+		failedStatus = result.Error.HTTPStatus
+	}
+	retrypolicy.RecordResult(ctx, result.Provider, result.Success, failedStatus)
+
 	entry := log.WithFields(log.Fields{
 		"auth_id":  result.AuthID,
 		"provider": result.Provider,
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"math/rand/v2"
 	"net/http"
 	"sort"
 	"strconv"
@@ -18,9 +19,12 @@ import (
 	"github.com/gin-gonic/gin"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/canary"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/chaos"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelmapping"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/ratelimit"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/retrypolicy"
 	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -52,6 +56,35 @@ func NewSelector(db *gorm.DB) *Selector {
 	}
 }
 
+// RateLimiter returns the manager enforcing per-user and per-mapping rate limits.
+func (s *Selector) RateLimiter() *ratelimit.Manager {
+	if s == nil {
+		return nil
+	}
+	return s.rateLimiter
+}
+
+// selectorState is the selector runtime state shared with a standby instance. Sticky
+// bindings live in user_model_auth_bindings and need no export.
+type selectorState struct {
+	RoundRobinCursor uint64 `json:"round_robin_cursor"`
+}
+
+// ExportState snapshots the round-robin cursor.
+func (s *Selector) ExportState(_ time.Time) (json.RawMessage, error) {
+	return json.Marshal(selectorState{RoundRobinCursor: s.roundRobinCursor.Load()})
+}
+
+// ImportState resumes round-robin rotation where another instance left off.
+func (s *Selector) ImportState(raw json.RawMessage, _ time.Time) error {
+	var state selectorState
+	if errUnmarshal := json.Unmarshal(raw, &state); errUnmarshal != nil {
+		return errUnmarshal
+	}
+	s.roundRobinCursor.Store(state.RoundRobinCursor)
+	return nil
+}
+
 // Pick implements coreauth.Selector.
 func (s *Selector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*coreauth.Auth) (*coreauth.Auth, error) {
 	_ = opts
@@ -59,6 +92,17 @@ func (s *Selector) Pick(ctx context.Context, provider, model string, opts clipro
 		ctx = context.Background()
 	}
 
+	if route, ok := debugRouteFromContext(ctx); ok && route.AuthKey != "" {
+		return pickDebugRouteAuth(auths, route.AuthKey, provider, model)
+	}
+
+	if errFault := chaos.ProviderFault(provider, model); errFault != nil {
+		return nil, errFault
+	}
+	if errRetry := retrypolicy.Before(ctx, provider); errRetry != nil {
+		return nil, errRetry
+	}
+
 	now := time.Now()
 	available, errAvailable := getAvailableAuths(auths, provider, model, now)
 	if errAvailable != nil {
@@ -78,7 +122,7 @@ func (s *Selector) Pick(ctx context.Context, provider, model string, opts clipro
 				return nil, newModelNotFoundError(provider, model)
 			}
 
-			if mappingUserGroupIDs, okMapping := modelmapping.LookupUserGroupIDs(provider, model); okMapping {
+			if mappingUserGroupIDs, okMapping := lookupMappingUserGroupIDs(ctx, provider, model); okMapping {
 				mappingUserGroupIDs = mappingUserGroupIDs.Clean()
 				if len(mappingUserGroupIDs) > 0 {
 					selectedUserGroupID = selectFirstAllowedUserGroupID(mappingUserGroupIDs, userGroupIDs, billUserGroupIDs)
@@ -101,6 +145,8 @@ func (s *Selector) Pick(ctx context.Context, provider, model string, opts clipro
 		}
 	}
 
+	available = splitCanary(available)
+
 	mappingID, selector := s.loadModelMappingSelector(ctx, provider, model)
 	var selected *coreauth.Auth
 	var errPick error
@@ -139,7 +185,7 @@ func (s *Selector) Pick(ctx context.Context, provider, model string, opts clipro
 		}
 		applyBillingUserGroupIDToContext(ctx, billingUserGroupID)
 	}
-	return selected, nil
+	return renderHeaderTemplates(ctx, selected), nil
 }
 
 func newModelNotFoundError(_ string, _ string) error {
@@ -198,7 +244,7 @@ func (s *Selector) filterAuthsByAuthGroupUserGroups(
 	if errFind := s.db.WithContext(ctx).
 		Model(&models.Auth{}).
 		Select("key", "auth_group_id").
-		Where("key IN ?", keys).
+		Where("? IN ?", clause.Column{Name: "key"}, keys).
 		Find(&rows).Error; errFind != nil {
 		return nil, nil, nil, errFind
 	}
@@ -412,7 +458,7 @@ func (s *Selector) pickFillFirst(ctx context.Context, available []*coreauth.Auth
 	if errFind := s.db.WithContext(ctx).
 		Model(&models.Auth{}).
 		Select("id", "key").
-		Where("key IN ?", keys).
+		Where("? IN ?", clause.Column{Name: "key"}, keys).
 		Find(&rows).Error; errFind != nil {
 		return available[0]
 	}
@@ -595,10 +641,12 @@ func (s *Selector) selectLeastUsedAuth(ctx context.Context, userID uint64, provi
 	return best, bestIndex, nil
 }
 
-func (s *Selector) loadModelMappingSelector(_ context.Context, provider, model string) (uint64, int) {
+// loadModelMappingSelector returns the mapping and selector configured for provider + model.
+// A debug route naming an upstream model bypasses mappings and always gets round robin.
+func (s *Selector) loadModelMappingSelector(ctx context.Context, provider, model string) (uint64, int) {
 	provider = strings.TrimSpace(provider)
 	model = strings.TrimSpace(model)
-	if provider == "" || model == "" || s == nil {
+	if provider == "" || model == "" || s == nil || modelMappingBypassed(ctx) {
 		return 0, modelMappingSelectorRoundRobin
 	}
 
@@ -609,6 +657,15 @@ func (s *Selector) loadModelMappingSelector(_ context.Context, provider, model s
 	return mappingID, normalizeModelMappingSelector(selector)
 }
 
+// lookupMappingUserGroupIDs returns the user groups a model mapping restricts provider + model
+// to, unless a debug route bypasses mappings.
+func lookupMappingUserGroupIDs(ctx context.Context, provider, model string) (models.UserGroupIDs, bool) {
+	if modelMappingBypassed(ctx) {
+		return nil, false
+	}
+	return modelmapping.LookupUserGroupIDs(provider, model)
+}
+
 func normalizeModelMappingSelector(value int) int {
 	switch value {
 	case modelMappingSelectorRoundRobin, modelMappingSelectorFillFirst, modelMappingSelectorStick:
@@ -799,6 +856,26 @@ func (e *rateLimitError) Headers() http.Header {
 	return headers
 }
 
+// splitCanary narrows available to the provider keys in canary or to the remaining auths,
+// per the canary percent. Retries after a canary key fails fall through to the other keys.
+func splitCanary(available []*coreauth.Auth) []*coreauth.Auth {
+	apiKeys := make([]string, len(available))
+	for i, auth := range available {
+		if auth != nil && auth.Attributes != nil {
+			apiKeys[i] = auth.Attributes["api_key"]
+		}
+	}
+	chosen := canary.Choose(apiKeys, rand.IntN)
+	if chosen == nil {
+		return available
+	}
+	out := make([]*coreauth.Auth, 0, len(chosen))
+	for _, i := range chosen {
+		out = append(out, available[i])
+	}
+	return out
+}
+
 func collectAvailable(auths []*coreauth.Auth, model string, now time.Time) (available []*coreauth.Auth, cooldownCount int, earliest time.Time) {
 	available = make([]*coreauth.Auth, 0, len(auths))
 	for i := 0; i < len(auths); i++ {
@@ -0,0 +1,306 @@
+// Package authcooldown takes auth files that upstream providers rate limit out of routing
+// for a back-off window and puts them back once the window passes. Windows come from the
+// quota and rate-limit hints stored with failed usage rows, falling back to a per-provider
+// default. Availability changes go through auths.is_available, so the db watcher syncs
+// them to the SDK like manual toggles.
+package authcooldown
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/retryafter"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultIntervalSeconds = 30
+	defaultLookbackMinutes = 15
+	defaultMinSeconds      = 30
+	defaultMaxSeconds      = 24 * 60 * 60
+	defaultBackoffSeconds  = 60
+	// disabledRecheck is how often a disabled scheduler rereads its setting.
+	disabledRecheck = 5 * time.Minute
+	// maxReasonLength bounds the stored cooldown reason.
+	maxReasonLength = 500
+)
+
+// Config mirrors the AUTH_COOLDOWN setting.
+type Config struct {
+	Enabled         bool           `json:"enabled"`          // Whether the scheduler runs.
+	IntervalSeconds int            `json:"interval_seconds"` // How often usage and expired cooldowns are checked.
+	LookbackMinutes int            `json:"lookback_minutes"` // How far back 429 responses are considered.
+	MinSeconds      int            `json:"min_seconds"`      // Shortest cooldown window.
+	MaxSeconds      int            `json:"max_seconds"`      // Longest cooldown window.
+	BackoffSeconds  map[string]int `json:"backoff_seconds"`  // Window per provider when upstream gives no hint; "default" applies to the rest.
+}
+
+// LoadConfig reads AUTH_COOLDOWN and fills defaults; invalid values disable the scheduler.
+func LoadConfig() Config {
+	var cfg Config
+	raw, ok := internalsettings.DBConfigValue(internalsettings.AuthCooldownKey)
+	if ok && len(bytes.TrimSpace(raw)) > 0 {
+		if errUnmarshal := json.Unmarshal(raw, &cfg); errUnmarshal != nil {
+			log.WithError(errUnmarshal).Warn("auth cooldown: invalid setting")
+			cfg = Config{}
+		}
+	}
+	return cfg.withDefaults()
+}
+
+func (c Config) withDefaults() Config {
+	if c.IntervalSeconds <= 0 {
+		c.IntervalSeconds = defaultIntervalSeconds
+	}
+	if c.LookbackMinutes <= 0 {
+		c.LookbackMinutes = defaultLookbackMinutes
+	}
+	if c.MinSeconds <= 0 {
+		c.MinSeconds = defaultMinSeconds
+	}
+	if c.MaxSeconds < c.MinSeconds {
+		c.MaxSeconds = defaultMaxSeconds
+		if c.MaxSeconds < c.MinSeconds {
+			c.MaxSeconds = c.MinSeconds
+		}
+	}
+	return c
+}
+
+// backoff returns the fallback window for provider.
+func (c Config) backoff(provider string) time.Duration {
+	if seconds, ok := c.BackoffSeconds[strings.ToLower(strings.TrimSpace(provider))]; ok && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if seconds, ok := c.BackoffSeconds["default"]; ok && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return defaultBackoffSeconds * time.Second
+}
+
+// storedErrorDetail is the part of usages.error_detail the scheduler reads.
+type storedErrorDetail struct {
+	Message           string            `json:"message"`
+	RetryAfterSeconds int               `json:"retry_after_seconds"`
+	ResponseBody      json.RawMessage   `json:"response_body"`
+	RateLimitHeaders  map[string]string `json:"rate_limit_headers"`
+}
+
+func decodeErrorDetail(raw []byte) storedErrorDetail {
+	var detail storedErrorDetail
+	if len(bytes.TrimSpace(raw)) > 0 {
+		_ = json.Unmarshal(raw, &detail)
+	}
+	return detail
+}
+
+// Window returns how long an auth stays out of routing after a 429 at failedAt. Exhausted
+// limits in the stored headers and back-off hints in the upstream body take precedence,
+// then the Retry-After sent to the client, then the provider default.
+func Window(cfg Config, provider string, errorDetail []byte, failedAt time.Time) time.Duration {
+	cfg = cfg.withDefaults()
+	detail := decodeErrorDetail(errorDetail)
+
+	var (
+		wait  time.Duration
+		found bool
+	)
+	if reset, ok := retryafter.FromResetHeaders(detail.RateLimitHeaders, failedAt); ok {
+		wait, found = reset, true
+	}
+	if hint, ok := retryafter.FromBody(detail.ResponseBody); ok && (!found || hint > wait) {
+		wait, found = hint, true
+	}
+	if !found && detail.RetryAfterSeconds > 0 {
+		wait, found = time.Duration(detail.RetryAfterSeconds)*time.Second, true
+	}
+	if !found {
+		wait = cfg.backoff(provider)
+	}
+
+	minWait := time.Duration(cfg.MinSeconds) * time.Second
+	maxWait := time.Duration(cfg.MaxSeconds) * time.Second
+	if wait < minWait {
+		return minWait
+	}
+	if wait > maxWait {
+		return maxWait
+	}
+	return wait
+}
+
+// Scheduler periodically cools down rate-limited auths and releases expired cooldowns.
+type Scheduler struct {
+	db *gorm.DB
+}
+
+// NewScheduler constructs a cooldown scheduler; returns nil when db is nil.
+func NewScheduler(db *gorm.DB) *Scheduler {
+	if db == nil {
+		return nil
+	}
+	return &Scheduler{db: db}
+}
+
+// Start launches the scheduling loop in a background goroutine.
+func (s *Scheduler) Start(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go s.run(ctx)
+	log.Info("auth cooldown scheduler started")
+}
+
+func (s *Scheduler) run(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		cfg := LoadConfig()
+		wait := disabledRecheck
+		if cfg.Enabled {
+			wait = time.Duration(cfg.IntervalSeconds) * time.Second
+			if result, errRun := s.RunOnce(ctx, cfg, time.Now().UTC()); errRun != nil {
+				log.WithError(errRun).Warn("auth cooldown scheduler: run failed")
+			} else if result.Started > 0 || result.Released > 0 {
+				log.Infof("auth cooldown scheduler: cooled down %d auths, released %d", result.Started, result.Released)
+			}
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			if !timer.Stop() {
+				<-timer.C
+			}
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// Result counts the auths changed by one run.
+type Result struct {
+	Started  int // Auths taken out of routing.
+	Released int // Auths put back into routing.
+}
+
+// failureRow is the latest rate-limited request of an auth.
+type failureRow struct {
+	AuthID      uint64
+	Provider    string
+	RequestedAt time.Time
+	ErrorDetail datatypes.JSON
+}
+
+// RunOnce releases cooldowns that ended by now, then cools down available auths that were
+// rate limited within the lookback window. Only 429s after an auth's last cooldown count,
+// so the failures that started a cooldown do not start another once it is released.
+func (s *Scheduler) RunOnce(ctx context.Context, cfg Config, now time.Time) (Result, error) {
+	var result Result
+	if s == nil || s.db == nil {
+		return result, nil
+	}
+	cfg = cfg.withDefaults()
+
+	released, errRelease := Release(ctx, s.db, now)
+	if errRelease != nil {
+		return result, errRelease
+	}
+	result.Released = released
+
+	var failures []failureRow
+	if errFind := s.db.WithContext(ctx).
+		Table("usages").
+		Select("usages.auth_id, usages.provider, usages.requested_at, usages.error_detail").
+		Joins("JOIN auths ON auths.id = usages.auth_id").
+		Where("usages.error_status_code = ? AND usages.requested_at >= ?", http.StatusTooManyRequests, now.Add(-time.Duration(cfg.LookbackMinutes)*time.Minute)).
+		Where("auths.is_available = ? AND auths.pending_approval = ?", true, false).
+		Where("auths.cooldown_until IS NULL OR usages.requested_at > auths.cooldown_until").
+		Order("usages.requested_at DESC").
+		Find(&failures).Error; errFind != nil {
+		return result, fmt.Errorf("auth cooldown: load rate-limited requests: %w", errFind)
+	}
+
+	seen := make(map[uint64]struct{}, len(failures))
+	for _, failure := range failures {
+		if _, done := seen[failure.AuthID]; done {
+			continue
+		}
+		seen[failure.AuthID] = struct{}{}
+		until := failure.RequestedAt.Add(Window(cfg, failure.Provider, failure.ErrorDetail, failure.RequestedAt)).UTC()
+		if !until.After(now) {
+			continue
+		}
+		res := s.db.WithContext(ctx).Model(&models.Auth{}).
+			Where("id = ? AND is_available = ?", failure.AuthID, true).
+			Updates(map[string]any{
+				"is_available":    false,
+				"cooling_down":    true,
+				"cooldown_until":  until,
+				"cooldown_reason": cooldownReason(failure.ErrorDetail),
+				"updated_at":      now,
+			})
+		if res.Error != nil {
+			return result, fmt.Errorf("auth cooldown: cool down auth %d: %w", failure.AuthID, res.Error)
+		}
+		if res.RowsAffected > 0 {
+			result.Started++
+			log.Infof("auth cooldown: auth %d rate limited by %s, unavailable until %s", failure.AuthID, failure.Provider, until.Format(time.RFC3339))
+		}
+	}
+	return result, nil
+}
+
+// Release puts auths whose cooldown ended by now back into routing; auths that were sent
+// for approval in the meantime stay unavailable. Returns the number of auths re-enabled.
+func Release(ctx context.Context, db *gorm.DB, now time.Time) (int, error) {
+	if db == nil {
+		return 0, nil
+	}
+	var released int
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.Auth{}).
+			Where("cooling_down = ? AND cooldown_until <= ? AND pending_approval = ?", true, now, false).
+			Updates(map[string]any{"is_available": true, "cooling_down": false, "updated_at": now})
+		if res.Error != nil {
+			return res.Error
+		}
+		released = int(res.RowsAffected)
+		return tx.Model(&models.Auth{}).
+			Where("cooling_down = ? AND cooldown_until <= ?", true, now).
+			Updates(map[string]any{"cooling_down": false, "updated_at": now}).Error
+	})
+	if errTx != nil {
+		return 0, fmt.Errorf("auth cooldown: release: %w", errTx)
+	}
+	return released, nil
+}
+
+// ManualOverride returns the column updates applied when an admin toggles availability by
+// hand: the cooldown no longer owns the auth, and earlier 429s are treated as handled.
+func ManualOverride(now time.Time) map[string]any {
+	return map[string]any{"cooling_down": false, "cooldown_until": now}
+}
+
+func cooldownReason(errorDetail []byte) string {
+	reason := strings.TrimSpace(decodeErrorDetail(errorDetail).Message)
+	if reason == "" {
+		reason = http.StatusText(http.StatusTooManyRequests)
+	}
+	if runes := []rune(reason); len(runes) > maxReasonLength {
+		reason = string(runes[:maxReasonLength])
+	}
+	return reason
+}
@@ -0,0 +1,163 @@
+package authcooldown
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func setupCooldownDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:authcooldown_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func seedRateLimited(t *testing.T, conn *gorm.DB, authID uint64, provider string, at time.Time, detail string) {
+	t.Helper()
+	status := http.StatusTooManyRequests
+	row := models.Usage{
+		Provider:        provider,
+		Model:           "model",
+		AuthID:          &authID,
+		RequestedAt:     at,
+		Failed:          true,
+		ErrorStatusCode: &status,
+		ErrorDetail:     datatypes.JSON(detail),
+	}
+	if errCreate := conn.Create(&row).Error; errCreate != nil {
+		t.Fatalf("seed usage: %v", errCreate)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	failedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	cfg := Config{BackoffSeconds: map[string]int{"codex": 300, "default": 90}}
+	cases := []struct {
+		name     string
+		provider string
+		detail   string
+		want     time.Duration
+	}{
+		{name: "exhausted header", provider: "codex", detail: `{"rate_limit_headers":{"x-ratelimit-remaining-tokens":"0","x-ratelimit-reset-tokens":"10m0s"}}`, want: 10 * time.Minute},
+		{name: "body hint beyond client cap", provider: "codex", detail: `{"retry_after_seconds":3600,"response_body":{"error":{"resets_in_seconds":7200}}}`, want: 2 * time.Hour},
+		{name: "client retry after", provider: "claude", detail: `{"retry_after_seconds":120}`, want: 2 * time.Minute},
+		{name: "provider default", provider: "codex", detail: `{"message":"slow down"}`, want: 5 * time.Minute},
+		{name: "fallback default", provider: "gemini", detail: `{}`, want: 90 * time.Second},
+		{name: "clamped to minimum", provider: "claude", detail: `{"retry_after_seconds":1}`, want: defaultMinSeconds * time.Second},
+		{name: "clamped to maximum", provider: "codex", detail: `{"response_body":{"resets_in_seconds":604800}}`, want: defaultMaxSeconds * time.Second},
+	}
+	for _, tc := range cases {
+		if got := Window(cfg, tc.provider, []byte(tc.detail), failedAt); got != tc.want {
+			t.Fatalf("%s: Window = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRunOnceCoolsDownAndReleases(t *testing.T) {
+	conn := setupCooldownDB(t)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	limited := models.Auth{Key: "limited", Content: datatypes.JSON(`{"type":"codex"}`), IsAvailable: true}
+	healthy := models.Auth{Key: "healthy", Content: datatypes.JSON(`{"type":"codex"}`), IsAvailable: true}
+	for _, auth := range []*models.Auth{&limited, &healthy} {
+		if errCreate := conn.Create(auth).Error; errCreate != nil {
+			t.Fatalf("create auth: %v", errCreate)
+		}
+	}
+	seedRateLimited(t, conn, limited.ID, "codex", now.Add(-time.Minute), `{"message":"usage limit reached","retry_after_seconds":600}`)
+	seedRateLimited(t, conn, limited.ID, "codex", now.Add(-2*time.Minute), `{"message":"older"}`)
+
+	scheduler := NewScheduler(conn)
+	cfg := Config{Enabled: true}
+	result, errRun := scheduler.RunOnce(ctx, cfg, now)
+	if errRun != nil {
+		t.Fatalf("run once: %v", errRun)
+	}
+	if result.Started != 1 || result.Released != 0 {
+		t.Fatalf("expected one cooldown, got %+v", result)
+	}
+
+	var got models.Auth
+	if errFind := conn.First(&got, limited.ID).Error; errFind != nil {
+		t.Fatalf("load auth: %v", errFind)
+	}
+	wantUntil := now.Add(9 * time.Minute)
+	if got.IsAvailable || !got.CoolingDown || got.CooldownUntil == nil || !got.CooldownUntil.Equal(wantUntil) {
+		t.Fatalf("expected cooldown until %s, got available=%v cooling=%v until=%v", wantUntil, got.IsAvailable, got.CoolingDown, got.CooldownUntil)
+	}
+	if got.CooldownReason != "usage limit reached" {
+		t.Fatalf("expected reason from latest failure, got %q", got.CooldownReason)
+	}
+
+	if result, errRun = scheduler.RunOnce(ctx, cfg, now.Add(5*time.Minute)); errRun != nil || result.Released != 0 {
+		t.Fatalf("expected cooldown to hold, got %+v err=%v", result, errRun)
+	}
+
+	result, errRun = scheduler.RunOnce(ctx, cfg, now.Add(10*time.Minute))
+	if errRun != nil {
+		t.Fatalf("run once: %v", errRun)
+	}
+	if result.Released != 1 || result.Started != 0 {
+		t.Fatalf("expected release without a new cooldown, got %+v", result)
+	}
+	if errFind := conn.First(&got, limited.ID).Error; errFind != nil {
+		t.Fatalf("load auth: %v", errFind)
+	}
+	if !got.IsAvailable || got.CoolingDown {
+		t.Fatalf("expected auth back in routing, got available=%v cooling=%v", got.IsAvailable, got.CoolingDown)
+	}
+
+	var untouched models.Auth
+	if errFind := conn.First(&untouched, healthy.ID).Error; errFind != nil {
+		t.Fatalf("load auth: %v", errFind)
+	}
+	if !untouched.IsAvailable || untouched.CoolingDown {
+		t.Fatalf("expected healthy auth to stay available")
+	}
+}
+
+func TestReleaseSkipsManualOverride(t *testing.T) {
+	conn := setupCooldownDB(t)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	auth := models.Auth{Key: "manual", Content: datatypes.JSON(`{"type":"claude"}`), IsAvailable: true}
+	if errCreate := conn.Create(&auth).Error; errCreate != nil {
+		t.Fatalf("create auth: %v", errCreate)
+	}
+	seedRateLimited(t, conn, auth.ID, "claude", now.Add(-time.Minute), `{"retry_after_seconds":120}`)
+	if _, errRun := NewScheduler(conn).RunOnce(ctx, Config{Enabled: true}, now); errRun != nil {
+		t.Fatalf("run once: %v", errRun)
+	}
+
+	// An admin disables the auth by hand while it cools down.
+	updates := ManualOverride(now)
+	updates["is_available"] = false
+	if errUpdate := conn.Model(&models.Auth{}).Where("id = ?", auth.ID).Updates(updates).Error; errUpdate != nil {
+		t.Fatalf("manual disable: %v", errUpdate)
+	}
+
+	released, errRelease := Release(ctx, conn, now.Add(time.Hour))
+	if errRelease != nil {
+		t.Fatalf("release: %v", errRelease)
+	}
+	if released != 0 {
+		t.Fatalf("expected manually disabled auth to stay unavailable")
+	}
+}
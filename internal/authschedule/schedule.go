@@ -0,0 +1,344 @@
+// Package authschedule routes auth groups by time of day. A group with a schedule only
+// serves requests inside its windows; a background evaluator flips auth_groups.off_schedule
+// and touches the member auths so the db watcher drops or restores them like other
+// availability changes.
+package authschedule
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// evaluateEvery is how often schedules are evaluated; windows have minute precision.
+const evaluateEvery = 30 * time.Second
+
+// Window is a daily time range. End before Start wraps past midnight, and End equal to Start
+// covers the whole day. Days lists the weekdays the window starts on; empty means every day.
+type Window struct {
+	Days  []string `json:"days,omitempty"` // "mon" to "sun".
+	Start string   `json:"start"`          // "HH:MM", inclusive.
+	End   string   `json:"end"`            // "HH:MM", exclusive.
+}
+
+// Schedule lists the windows an auth group routes in, evaluated in Timezone.
+type Schedule struct {
+	Timezone string   `json:"timezone,omitempty"` // IANA name; defaults to UTC.
+	Windows  []Window `json:"windows"`
+}
+
+// parsedWindow is a validated window in minutes since midnight.
+type parsedWindow struct {
+	days  map[time.Weekday]struct{}
+	start int
+	end   int
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Parse decodes a stored schedule; an empty value or one without windows yields nil, which
+// routes at all times.
+func Parse(raw []byte) (*Schedule, error) {
+	if len(bytes.TrimSpace(raw)) == 0 || string(bytes.TrimSpace(raw)) == "null" {
+		return nil, nil
+	}
+	var schedule Schedule
+	if errUnmarshal := json.Unmarshal(raw, &schedule); errUnmarshal != nil {
+		return nil, fmt.Errorf("invalid schedule: %w", errUnmarshal)
+	}
+	if len(schedule.Windows) == 0 {
+		return nil, nil
+	}
+	if _, errValidate := schedule.compile(); errValidate != nil {
+		return nil, errValidate
+	}
+	return &schedule, nil
+}
+
+// Normalize validates raw and returns it in canonical form for storage; nil clears the schedule.
+func Normalize(raw []byte) ([]byte, error) {
+	schedule, errParse := Parse(raw)
+	if errParse != nil || schedule == nil {
+		return nil, errParse
+	}
+	schedule.Timezone = strings.TrimSpace(schedule.Timezone)
+	for i := range schedule.Windows {
+		window := &schedule.Windows[i]
+		window.Start, window.End = strings.TrimSpace(window.Start), strings.TrimSpace(window.End)
+		for j, day := range window.Days {
+			window.Days[j] = strings.ToLower(strings.TrimSpace(day))[:3]
+		}
+	}
+	return json.Marshal(schedule)
+}
+
+func (s *Schedule) location() (*time.Location, error) {
+	name := strings.TrimSpace(s.Timezone)
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, errLoad := time.LoadLocation(name)
+	if errLoad != nil {
+		return nil, fmt.Errorf("invalid schedule timezone %q", name)
+	}
+	return loc, nil
+}
+
+func (s *Schedule) compile() ([]parsedWindow, error) {
+	if _, errLoc := s.location(); errLoc != nil {
+		return nil, errLoc
+	}
+	out := make([]parsedWindow, 0, len(s.Windows))
+	for _, window := range s.Windows {
+		start, errStart := parseClock(window.Start)
+		if errStart != nil {
+			return nil, errStart
+		}
+		end, errEnd := parseClock(window.End)
+		if errEnd != nil {
+			return nil, errEnd
+		}
+		parsed := parsedWindow{start: start, end: end}
+		if len(window.Days) > 0 {
+			parsed.days = make(map[time.Weekday]struct{}, len(window.Days))
+			for _, day := range window.Days {
+				key := strings.ToLower(strings.TrimSpace(day))
+				if len(key) >= 3 {
+					key = key[:3]
+				}
+				weekday, ok := weekdays[key]
+				if !ok {
+					return nil, fmt.Errorf("invalid schedule day %q", day)
+				}
+				parsed.days[weekday] = struct{}{}
+			}
+		}
+		out = append(out, parsed)
+	}
+	return out, nil
+}
+
+// parseClock converts "HH:MM" to minutes since midnight; "24:00" is accepted as an end.
+func parseClock(value string) (int, error) {
+	hours, minutes, ok := strings.Cut(strings.TrimSpace(value), ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid schedule time %q", value)
+	}
+	h, errHours := strconv.Atoi(hours)
+	m, errMinutes := strconv.Atoi(minutes)
+	if errHours != nil || errMinutes != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid schedule time %q", value)
+	}
+	return h*60 + m, nil
+}
+
+// Active reports whether the schedule routes at now; a nil schedule always does.
+func (s *Schedule) Active(now time.Time) bool {
+	if s == nil || len(s.Windows) == 0 {
+		return true
+	}
+	loc, errLoc := s.location()
+	if errLoc != nil {
+		return true
+	}
+	windows, errCompile := s.compile()
+	if errCompile != nil {
+		return true
+	}
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	today := local.Weekday()
+	yesterday := (today + 6) % 7
+	for _, window := range windows {
+		switch {
+		case window.start < window.end:
+			if window.startsOn(today) && minute >= window.start && minute < window.end {
+				return true
+			}
+		case window.start == window.end:
+			if window.startsOn(today) {
+				return true
+			}
+		default:
+			// Wraps past midnight: the evening part belongs to today, the morning part to yesterday.
+			if window.startsOn(today) && minute >= window.start {
+				return true
+			}
+			if window.startsOn(yesterday) && minute < window.end {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (w parsedWindow) startsOn(day time.Weekday) bool {
+	if len(w.days) == 0 {
+		return true
+	}
+	_, ok := w.days[day]
+	return ok
+}
+
+// Evaluator keeps auth_groups.off_schedule in step with each group's schedule.
+type Evaluator struct {
+	db *gorm.DB
+}
+
+// NewEvaluator constructs a schedule evaluator; returns nil when db is nil.
+func NewEvaluator(db *gorm.DB) *Evaluator {
+	if db == nil {
+		return nil
+	}
+	return &Evaluator{db: db}
+}
+
+// Start launches the evaluation loop in a background goroutine.
+func (e *Evaluator) Start(ctx context.Context) {
+	if e == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go e.run(ctx)
+	log.Info("auth group schedule evaluator started")
+}
+
+func (e *Evaluator) run(ctx context.Context) {
+	ticker := time.NewTicker(evaluateEvery)
+	defer ticker.Stop()
+	for {
+		if result, errRun := e.RunOnce(ctx, time.Now().UTC()); errRun != nil {
+			log.WithError(errRun).Warn("auth group schedule evaluator: run failed")
+		} else if result.Enabled > 0 || result.Disabled > 0 {
+			log.Infof("auth group schedule evaluator: enabled %d groups, disabled %d", result.Enabled, result.Disabled)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Result counts the groups changed by one run.
+type Result struct {
+	Enabled  int // Groups that entered a window.
+	Disabled int // Groups that left their windows.
+}
+
+// RunOnce evaluates every group's schedule at now and updates the groups whose state changed.
+// Groups with an invalid schedule stay routable.
+func (e *Evaluator) RunOnce(ctx context.Context, now time.Time) (Result, error) {
+	var result Result
+	if e == nil || e.db == nil {
+		return result, nil
+	}
+	var groups []models.AuthGroup
+	if errFind := e.db.WithContext(ctx).
+		Select("id", "schedule", "off_schedule").
+		Find(&groups).Error; errFind != nil {
+		return result, fmt.Errorf("auth schedule: load groups: %w", errFind)
+	}
+	for _, group := range groups {
+		schedule, errParse := Parse(group.Schedule)
+		if errParse != nil {
+			log.WithError(errParse).Warnf("auth schedule: group %d has an invalid schedule", group.ID)
+		}
+		off := !schedule.Active(now)
+		if off == group.OffSchedule {
+			continue
+		}
+		if errSet := SetOffSchedule(ctx, e.db, group.ID, off, now); errSet != nil {
+			return result, errSet
+		}
+		if off {
+			result.Disabled++
+		} else {
+			result.Enabled++
+		}
+	}
+	return result, nil
+}
+
+// SetOffSchedule stores a group's schedule state and touches its member auths so the db
+// watcher re-syncs them.
+func SetOffSchedule(ctx context.Context, db *gorm.DB, groupID uint64, off bool, now time.Time) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if errGroup := tx.Model(&models.AuthGroup{}).Where("id = ?", groupID).
+			Updates(map[string]any{"off_schedule": off, "updated_at": now}).Error; errGroup != nil {
+			return fmt.Errorf("auth schedule: update group %d: %w", groupID, errGroup)
+		}
+		var auths []models.Auth
+		if errFind := tx.Select("id", "auth_group_id").Find(&auths).Error; errFind != nil {
+			return fmt.Errorf("auth schedule: load auths: %w", errFind)
+		}
+		ids := make([]uint64, 0)
+		for _, auth := range auths {
+			if primary := auth.AuthGroupID.Primary(); primary != nil && *primary == groupID {
+				ids = append(ids, auth.ID)
+			}
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if errTouch := tx.Model(&models.Auth{}).Where("id IN ?", ids).
+			Update("updated_at", now).Error; errTouch != nil {
+			return fmt.Errorf("auth schedule: touch auths of group %d: %w", groupID, errTouch)
+		}
+		return nil
+	})
+}
+
+// OffScheduleGroupIDs returns the groups currently outside their schedule.
+func OffScheduleGroupIDs(ctx context.Context, db *gorm.DB) (map[uint64]struct{}, error) {
+	var ids []uint64
+	if errFind := db.WithContext(ctx).Model(&models.AuthGroup{}).
+		Where("off_schedule = ?", true).
+		Pluck("id", &ids).Error; errFind != nil {
+		return nil, errFind
+	}
+	out := make(map[uint64]struct{}, len(ids))
+	for _, id := range ids {
+		out[id] = struct{}{}
+	}
+	return out, nil
+}
+
+// FilterAuths drops auth records whose primary group is outside its schedule. When the group
+// states cannot be loaded every record is kept, so a lookup failure never empties routing.
+func FilterAuths(ctx context.Context, db *gorm.DB, rows []models.Auth) []models.Auth {
+	if db == nil || len(rows) == 0 {
+		return rows
+	}
+	off, errOff := OffScheduleGroupIDs(ctx, db)
+	if errOff != nil {
+		log.WithError(errOff).Debug("auth schedule: load off-schedule groups failed")
+		return rows
+	}
+	if len(off) == 0 {
+		return rows
+	}
+	out := make([]models.Auth, 0, len(rows))
+	for i := range rows {
+		if primary := rows[i].AuthGroupID.Primary(); primary != nil {
+			if _, hidden := off[*primary]; hidden {
+				continue
+			}
+		}
+		out = append(out, rows[i])
+	}
+	return out
+}
@@ -0,0 +1,115 @@
+package authschedule
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func setupScheduleDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:authschedule_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func TestScheduleActive(t *testing.T) {
+	offPeak, errParse := Parse([]byte(`{"windows":[{"start":"22:00","end":"06:00"}]}`))
+	if errParse != nil {
+		t.Fatalf("parse: %v", errParse)
+	}
+	weekend, errWeekend := Parse([]byte(`{"timezone":"Asia/Shanghai","windows":[{"days":["sat","Sunday"],"start":"00:00","end":"00:00"}]}`))
+	if errWeekend != nil {
+		t.Fatalf("parse weekend: %v", errWeekend)
+	}
+	cases := []struct {
+		name     string
+		schedule *Schedule
+		at       time.Time
+		want     bool
+	}{
+		{name: "nil routes always", schedule: nil, at: time.Date(2026, 5, 6, 12, 0, 0, 0, time.UTC), want: true},
+		{name: "evening part", schedule: offPeak, at: time.Date(2026, 5, 6, 23, 30, 0, 0, time.UTC), want: true},
+		{name: "morning part", schedule: offPeak, at: time.Date(2026, 5, 7, 5, 59, 0, 0, time.UTC), want: true},
+		{name: "end is exclusive", schedule: offPeak, at: time.Date(2026, 5, 7, 6, 0, 0, 0, time.UTC), want: false},
+		{name: "midday", schedule: offPeak, at: time.Date(2026, 5, 7, 12, 0, 0, 0, time.UTC), want: false},
+		// Friday 17:00 UTC is Saturday 01:00 in Shanghai.
+		{name: "weekend in timezone", schedule: weekend, at: time.Date(2026, 5, 8, 17, 0, 0, 0, time.UTC), want: true},
+		{name: "weekday in timezone", schedule: weekend, at: time.Date(2026, 5, 8, 15, 0, 0, 0, time.UTC), want: false},
+	}
+	for _, tc := range cases {
+		if got := tc.schedule.Active(tc.at); got != tc.want {
+			t.Errorf("%s: Active = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	for _, raw := range []string{
+		`{"windows":[{"start":"25:00","end":"06:00"}]}`,
+		`{"windows":[{"days":["xyz"],"start":"01:00","end":"06:00"}]}`,
+		`{"timezone":"Nowhere/City","windows":[{"start":"01:00","end":"06:00"}]}`,
+	} {
+		if _, errInvalid := Normalize([]byte(raw)); errInvalid == nil {
+			t.Errorf("expected %s to be rejected", raw)
+		}
+	}
+}
+
+func TestRunOnceHidesOffScheduleGroups(t *testing.T) {
+	conn := setupScheduleDB(t)
+	ctx := context.Background()
+	group := models.AuthGroup{Name: "night", Schedule: datatypes.JSON(`{"windows":[{"start":"00:00","end":"08:00"}]}`)}
+	if errCreate := conn.Create(&group).Error; errCreate != nil {
+		t.Fatalf("create group: %v", errCreate)
+	}
+	created := time.Date(2026, 5, 6, 9, 0, 0, 0, time.UTC)
+	auths := []models.Auth{
+		{Key: "night-auth", Content: datatypes.JSON(`{}`), IsAvailable: true, AuthGroupID: models.AuthGroupIDs{&group.ID}, CreatedAt: created, UpdatedAt: created},
+		{Key: "plain-auth", Content: datatypes.JSON(`{}`), IsAvailable: true, CreatedAt: created, UpdatedAt: created},
+	}
+	for i := range auths {
+		if errCreate := conn.Create(&auths[i]).Error; errCreate != nil {
+			t.Fatalf("create auth %q: %v", auths[i].Key, errCreate)
+		}
+	}
+
+	evaluator := NewEvaluator(conn)
+	noon := time.Date(2026, 5, 6, 12, 0, 0, 0, time.UTC)
+	result, errRun := evaluator.RunOnce(ctx, noon)
+	if errRun != nil || result.Disabled != 1 || result.Enabled != 0 {
+		t.Fatalf("noon run: result %+v err %v", result, errRun)
+	}
+	var rows []models.Auth
+	if errFind := conn.Order("id ASC").Find(&rows).Error; errFind != nil {
+		t.Fatalf("load auths: %v", errFind)
+	}
+	if !rows[0].UpdatedAt.Equal(noon) || rows[1].UpdatedAt.Equal(noon) {
+		t.Fatalf("expected only the member auth to be touched, got %v and %v", rows[0].UpdatedAt, rows[1].UpdatedAt)
+	}
+	if visible := FilterAuths(ctx, conn, rows); len(visible) != 1 || visible[0].Key != "plain-auth" {
+		t.Fatalf("unexpected visible auths %+v", visible)
+	}
+
+	if again, _ := evaluator.RunOnce(ctx, noon.Add(time.Minute)); again.Disabled != 0 || again.Enabled != 0 {
+		t.Fatalf("expected no change on rerun, got %+v", again)
+	}
+	night := time.Date(2026, 5, 7, 1, 0, 0, 0, time.UTC)
+	if result, _ = evaluator.RunOnce(ctx, night); result.Enabled != 1 {
+		t.Fatalf("night run: %+v", result)
+	}
+	if visible := FilterAuths(ctx, conn, rows); len(visible) != 2 {
+		t.Fatalf("expected both auths visible at night, got %d", len(visible))
+	}
+}
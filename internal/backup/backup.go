@@ -0,0 +1,209 @@
+// Package backup exports and restores the configuration held in the database: provider keys,
+// auth files, users, groups, billing rules and settings. Archives are sealed with a key
+// derived from an admin supplied passphrase, so they stay portable across deployments with
+// different encryption keys.
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/buildinfo"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"golang.org/x/crypto/scrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// Format identifies sealed backup files.
+	Format = "cpab-backup"
+	// FormatVersion is the version of the archive layout.
+	FormatVersion = 1
+	// MinPassphraseLength is the shortest accepted passphrase.
+	MinPassphraseLength = 12
+
+	kdfName  = "scrypt"
+	kdfN     = 1 << 15
+	kdfR     = 8
+	kdfP     = 1
+	keySize  = 32
+	saltSize = 16
+)
+
+// Errors returned when sealing or opening archives.
+var (
+	ErrWeakPassphrase = fmt.Errorf("backup: passphrase must be at least %d characters", MinPassphraseLength)
+	ErrBadPassphrase  = errors.New("backup: wrong passphrase or corrupted archive")
+	ErrUnsupported    = errors.New("backup: unsupported archive format")
+)
+
+// Archive is the plaintext content of a backup.
+type Archive struct {
+	Version    int       `json:"version"`     // Archive layout version.
+	CreatedAt  time.Time `json:"created_at"`  // When the backup was taken.
+	AppVersion string    `json:"app_version"` // Build that produced the backup.
+
+	ProviderAPIKeys []models.ProviderAPIKey `json:"provider_api_keys"`
+	AuthGroups      []models.AuthGroup      `json:"auth_groups"`
+	Auths           []models.Auth           `json:"auths"`
+	UserGroups      []models.UserGroup      `json:"user_groups"`
+	Users           []models.User           `json:"users"`
+	BillingRules    []models.BillingRule    `json:"billing_rules"`
+	Settings        []models.Setting        `json:"settings"`
+}
+
+// Counts returns the number of rows per section.
+func (a *Archive) Counts() map[string]int {
+	return map[string]int{
+		"provider_api_keys": len(a.ProviderAPIKeys),
+		"auth_groups":       len(a.AuthGroups),
+		"auths":             len(a.Auths),
+		"user_groups":       len(a.UserGroups),
+		"users":             len(a.Users),
+		"billing_rules":     len(a.BillingRules),
+		"settings":          len(a.Settings),
+	}
+}
+
+// Build reads every backed up table. Encrypted columns are decrypted by the model hooks, so
+// the archive holds plaintext secrets and must only leave the process sealed.
+func Build(ctx context.Context, db *gorm.DB, now time.Time) (*Archive, error) {
+	if db == nil {
+		return nil, errors.New("backup: nil db")
+	}
+	archive := &Archive{Version: FormatVersion, CreatedAt: now.UTC(), AppVersion: buildinfo.Version}
+	conn := db.WithContext(ctx)
+	for _, section := range []struct {
+		name string
+		dest any
+	}{
+		{"provider api keys", &archive.ProviderAPIKeys},
+		{"auth groups", &archive.AuthGroups},
+		{"auths", &archive.Auths},
+		{"user groups", &archive.UserGroups},
+		{"users", &archive.Users},
+		{"billing rules", &archive.BillingRules},
+	} {
+		if errFind := conn.Order("id ASC").Find(section.dest).Error; errFind != nil {
+			return nil, fmt.Errorf("backup: read %s: %w", section.name, errFind)
+		}
+	}
+	if errFind := conn.Order(clause.OrderByColumn{Column: clause.Column{Name: "key"}}).Find(&archive.Settings).Error; errFind != nil {
+		return nil, fmt.Errorf("backup: read settings: %w", errFind)
+	}
+	return archive, nil
+}
+
+// sealedFile is the on-disk layout of a backup: a readable header and the sealed archive.
+type sealedFile struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	KDF       string    `json:"kdf"`
+	N         int       `json:"n"`
+	R         int       `json:"r"`
+	P         int       `json:"p"`
+	Salt      []byte    `json:"salt"`
+	Data      []byte    `json:"data"` // Nonce followed by the AES-256-GCM sealed, gzipped archive JSON.
+}
+
+// Seal compresses and encrypts the archive with a key derived from passphrase.
+func Seal(archive *Archive, passphrase string) ([]byte, error) {
+	if archive == nil {
+		return nil, errors.New("backup: nil archive")
+	}
+	if len(strings.TrimSpace(passphrase)) < MinPassphraseLength {
+		return nil, ErrWeakPassphrase
+	}
+	var plain bytes.Buffer
+	zw := gzip.NewWriter(&plain)
+	if errEncode := json.NewEncoder(zw).Encode(archive); errEncode != nil {
+		return nil, fmt.Errorf("backup: encode archive: %w", errEncode)
+	}
+	if errClose := zw.Close(); errClose != nil {
+		return nil, fmt.Errorf("backup: compress archive: %w", errClose)
+	}
+
+	file := sealedFile{Format: Format, Version: FormatVersion, CreatedAt: archive.CreatedAt, KDF: kdfName, N: kdfN, R: kdfR, P: kdfP, Salt: make([]byte, saltSize)}
+	if _, errRand := io.ReadFull(rand.Reader, file.Salt); errRand != nil {
+		return nil, fmt.Errorf("backup: generate salt: %w", errRand)
+	}
+	aead, errAEAD := file.aead(passphrase)
+	if errAEAD != nil {
+		return nil, errAEAD
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, errRand := io.ReadFull(rand.Reader, nonce); errRand != nil {
+		return nil, fmt.Errorf("backup: generate nonce: %w", errRand)
+	}
+	file.Data = aead.Seal(nonce, nonce, plain.Bytes(), file.header())
+	return json.Marshal(file)
+}
+
+// Open decrypts and decodes an archive produced by Seal.
+func Open(data []byte, passphrase string) (*Archive, error) {
+	var file sealedFile
+	if errUnmarshal := json.Unmarshal(data, &file); errUnmarshal != nil || file.Format != Format {
+		return nil, ErrUnsupported
+	}
+	if file.Version != FormatVersion || file.KDF != kdfName {
+		return nil, fmt.Errorf("%w: version %d, kdf %q", ErrUnsupported, file.Version, file.KDF)
+	}
+	if file.N <= 1 || file.N > 1<<20 || file.R <= 0 || file.R > 32 || file.P <= 0 || file.P > 16 {
+		return nil, fmt.Errorf("%w: kdf parameters out of range", ErrUnsupported)
+	}
+	aead, errAEAD := file.aead(passphrase)
+	if errAEAD != nil {
+		return nil, errAEAD
+	}
+	if len(file.Data) < aead.NonceSize() {
+		return nil, ErrBadPassphrase
+	}
+	nonce, body := file.Data[:aead.NonceSize()], file.Data[aead.NonceSize():]
+	plain, errOpen := aead.Open(nil, nonce, body, file.header())
+	if errOpen != nil {
+		return nil, ErrBadPassphrase
+	}
+	zr, errGzip := gzip.NewReader(bytes.NewReader(plain))
+	if errGzip != nil {
+		return nil, fmt.Errorf("backup: decompress archive: %w", errGzip)
+	}
+	defer func() { _ = zr.Close() }()
+	var archive Archive
+	if errDecode := json.NewDecoder(zr).Decode(&archive); errDecode != nil {
+		return nil, fmt.Errorf("backup: decode archive: %w", errDecode)
+	}
+	if archive.Version != FormatVersion {
+		return nil, fmt.Errorf("%w: archive version %d", ErrUnsupported, archive.Version)
+	}
+	return &archive, nil
+}
+
+// aead derives the archive key from passphrase.
+func (f sealedFile) aead(passphrase string) (cipher.AEAD, error) {
+	key, errKey := scrypt.Key([]byte(passphrase), f.Salt, f.N, f.R, f.P, keySize)
+	if errKey != nil {
+		return nil, fmt.Errorf("backup: derive key: %w", errKey)
+	}
+	block, errBlock := aes.NewCipher(key)
+	if errBlock != nil {
+		return nil, errBlock
+	}
+	return cipher.NewGCM(block)
+}
+
+// header binds the readable header fields to the ciphertext.
+func (f sealedFile) header() []byte {
+	return []byte(fmt.Sprintf("%s|%d|%s|%s|%d|%d|%d", f.Format, f.Version, f.CreatedAt.UTC().Format(time.RFC3339Nano), f.KDF, f.N, f.R, f.P))
+}
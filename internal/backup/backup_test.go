@@ -0,0 +1,184 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const testPassphrase = "correct horse battery"
+
+func setupBackupDB(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:backup_%s_%d?mode=memory&cache=shared", name, time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func mustCreate(t *testing.T, conn *gorm.DB, value any) {
+	t.Helper()
+	if errCreate := conn.Create(value).Error; errCreate != nil {
+		t.Fatalf("create %T: %v", value, errCreate)
+	}
+}
+
+func seedSource(t *testing.T, conn *gorm.DB) {
+	t.Helper()
+	// Offset IDs so the restore has to remap group references.
+	mustCreate(t, conn, &models.UserGroup{Name: "filler-1"})
+	mustCreate(t, conn, &models.UserGroup{Name: "filler-2"})
+	team := models.UserGroup{Name: "team", RPMLimit: 60}
+	mustCreate(t, conn, &team)
+	pool := models.AuthGroup{Name: "pool", RateLimit: 5, UserGroupID: models.UserGroupIDs{&team.ID}}
+	mustCreate(t, conn, &pool)
+	user := models.User{Username: "alice", Email: "alice@example.com", Password: "hash", UserGroupID: models.UserGroupIDs{&team.ID}}
+	mustCreate(t, conn, &user)
+	mustCreate(t, conn, &models.Auth{Key: "codex-alice.json", Name: "alice", Content: datatypes.JSON(`{"type":"codex","refresh_token":"rt"}`), AuthGroupID: models.AuthGroupIDs{&pool.ID}, ContributedByUserID: &user.ID})
+	mustCreate(t, conn, &models.ProviderAPIKey{Provider: "openai", Name: "primary", APIKey: "sk-live-1", Priority: 3})
+	price := 2.5
+	mustCreate(t, conn, &models.BillingRule{AuthGroupID: pool.ID, UserGroupID: team.ID, Provider: "openai", Model: "gpt-5", BillingType: models.BillingTypePerRequest, PricePerRequest: &price})
+	mustCreate(t, conn, &models.Setting{Key: "SITE_NAME", Value: json.RawMessage(`"Source"`)})
+}
+
+func roundTrip(t *testing.T, conn *gorm.DB) *Archive {
+	t.Helper()
+	archive, errBuild := Build(context.Background(), conn, time.Now())
+	if errBuild != nil {
+		t.Fatalf("build: %v", errBuild)
+	}
+	sealed, errSeal := Seal(archive, testPassphrase)
+	if errSeal != nil {
+		t.Fatalf("seal: %v", errSeal)
+	}
+	opened, errOpen := Open(sealed, testPassphrase)
+	if errOpen != nil {
+		t.Fatalf("open: %v", errOpen)
+	}
+	return opened
+}
+
+func TestSealRejectsWeakAndWrongPassphrases(t *testing.T) {
+	archive := &Archive{Version: FormatVersion, CreatedAt: time.Now().UTC()}
+	if _, errSeal := Seal(archive, "short"); !errors.Is(errSeal, ErrWeakPassphrase) {
+		t.Fatalf("expected ErrWeakPassphrase, got %v", errSeal)
+	}
+	sealed, errSeal := Seal(archive, testPassphrase)
+	if errSeal != nil {
+		t.Fatalf("seal: %v", errSeal)
+	}
+	if _, errOpen := Open(sealed, "another passphrase"); !errors.Is(errOpen, ErrBadPassphrase) {
+		t.Fatalf("expected ErrBadPassphrase, got %v", errOpen)
+	}
+	if _, errOpen := Open([]byte(`{"format":"other"}`), testPassphrase); !errors.Is(errOpen, ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", errOpen)
+	}
+}
+
+func TestRestoreIntoEmptyDatabaseRemapsReferences(t *testing.T) {
+	source := setupBackupDB(t, "source")
+	seedSource(t, source)
+	archive := roundTrip(t, source)
+
+	target := setupBackupDB(t, "target")
+	result, errRestore := Restore(context.Background(), target, archive, StrategySkip)
+	if errRestore != nil {
+		t.Fatalf("restore: %v", errRestore)
+	}
+	if got := result.Sections["auths"]; got == nil || got.Created != 1 {
+		t.Fatalf("expected one auth created, got %+v", got)
+	}
+
+	var team models.UserGroup
+	var pool models.AuthGroup
+	var user models.User
+	var auth models.Auth
+	var rule models.BillingRule
+	var key models.ProviderAPIKey
+	target.Where("name = ?", "team").First(&team)
+	target.Where("name = ?", "pool").First(&pool)
+	target.Where("username = ?", "alice").First(&user)
+	target.Where("? = ?", clause.Column{Name: "key"}, "codex-alice.json").First(&auth)
+	target.First(&rule)
+	target.First(&key)
+
+	if team.RPMLimit != 60 || len(pool.UserGroupID) != 1 || *pool.UserGroupID[0] != team.ID {
+		t.Fatalf("auth group not linked to restored user group: team=%+v pool=%+v", team, pool)
+	}
+	if len(user.UserGroupID) != 1 || *user.UserGroupID[0] != team.ID || user.Password != "hash" {
+		t.Fatalf("unexpected restored user: %+v", user)
+	}
+	if len(auth.AuthGroupID) != 1 || *auth.AuthGroupID[0] != pool.ID || auth.ContributedByUserID == nil || *auth.ContributedByUserID != user.ID {
+		t.Fatalf("unexpected restored auth: %+v", auth)
+	}
+	if rule.AuthGroupID != pool.ID || rule.UserGroupID != team.ID || rule.PricePerRequest == nil || *rule.PricePerRequest != 2.5 {
+		t.Fatalf("unexpected restored billing rule: %+v", rule)
+	}
+	if key.APIKey != "sk-live-1" || key.Priority != 3 {
+		t.Fatalf("unexpected restored provider key: %+v", key)
+	}
+}
+
+func TestRestoreConflictStrategies(t *testing.T) {
+	source := setupBackupDB(t, "strategies")
+	seedSource(t, source)
+	archive := roundTrip(t, source)
+
+	for _, tc := range []struct {
+		strategy     Strategy
+		wantPriority int
+		wantName     string
+	}{
+		{StrategySkip, 9, "renamed"},
+		{StrategyOverwrite, 3, "primary"},
+		{StrategyMerge, 3, "primary"},
+	} {
+		target := setupBackupDB(t, string(tc.strategy))
+		existing := models.ProviderAPIKey{Provider: "openai", Name: "renamed", APIKey: "sk-live-1", Priority: 9, BaseURL: "https://proxy.internal"}
+		mustCreate(t, target, &existing)
+
+		result, errRestore := Restore(context.Background(), target, archive, tc.strategy)
+		if errRestore != nil {
+			t.Fatalf("%s: restore: %v", tc.strategy, errRestore)
+		}
+		var keys []models.ProviderAPIKey
+		target.Find(&keys)
+		if len(keys) != 1 || keys[0].ID != existing.ID {
+			t.Fatalf("%s: expected the existing key to be matched, got %+v", tc.strategy, keys)
+		}
+		if keys[0].Priority != tc.wantPriority || keys[0].Name != tc.wantName {
+			t.Fatalf("%s: unexpected key after restore: %+v", tc.strategy, keys[0])
+		}
+		// Merge keeps fields the archive leaves empty; overwrite replaces them.
+		wantBaseURL := "https://proxy.internal"
+		if tc.strategy == StrategyOverwrite {
+			wantBaseURL = ""
+		}
+		if keys[0].BaseURL != wantBaseURL {
+			t.Fatalf("%s: expected base url %q, got %q", tc.strategy, wantBaseURL, keys[0].BaseURL)
+		}
+		counts := result.Sections["provider_api_keys"]
+		if tc.strategy == StrategySkip && counts.Skipped != 1 || tc.strategy != StrategySkip && counts.Updated != 1 {
+			t.Fatalf("%s: unexpected counts %+v", tc.strategy, counts)
+		}
+	}
+
+	if _, errParse := ParseStrategy("replace"); errParse == nil {
+		t.Fatal("expected unknown strategy to fail")
+	}
+}
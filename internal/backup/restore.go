@@ -0,0 +1,382 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Strategy decides what happens to archived rows that match an existing row.
+type Strategy string
+
+// Supported conflict strategies.
+const (
+	// StrategySkip keeps existing rows untouched.
+	StrategySkip Strategy = "skip"
+	// StrategyOverwrite replaces existing rows with the archived values.
+	StrategyOverwrite Strategy = "overwrite"
+	// StrategyMerge applies the non-empty archived values on top of existing rows.
+	StrategyMerge Strategy = "merge"
+)
+
+// ParseStrategy validates a strategy name; empty selects skip.
+func ParseStrategy(raw string) (Strategy, error) {
+	switch Strategy(strings.ToLower(strings.TrimSpace(raw))) {
+	case "", StrategySkip:
+		return StrategySkip, nil
+	case StrategyOverwrite:
+		return StrategyOverwrite, nil
+	case StrategyMerge:
+		return StrategyMerge, nil
+	default:
+		return "", fmt.Errorf("backup: unknown strategy %q (use skip, overwrite or merge)", raw)
+	}
+}
+
+// SectionResult counts the outcome of one restored table.
+type SectionResult struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+}
+
+// Result reports the outcome of a restore per section.
+type Result struct {
+	Strategy Strategy                  `json:"strategy"`
+	Sections map[string]*SectionResult `json:"sections"`
+}
+
+func (r *Result) section(name string) *SectionResult {
+	if r.Sections[name] == nil {
+		r.Sections[name] = &SectionResult{}
+	}
+	return r.Sections[name]
+}
+
+// Restore writes the archive into db in one transaction. Rows are matched on natural keys
+// (group names, usernames, auth keys, setting keys, provider key secrets and billing rule
+// scopes); group, user and parent references are remapped to the IDs in the target database.
+func Restore(ctx context.Context, db *gorm.DB, archive *Archive, strategy Strategy) (*Result, error) {
+	if db == nil {
+		return nil, errors.New("backup: nil db")
+	}
+	if archive == nil {
+		return nil, errors.New("backup: nil archive")
+	}
+	r := &restorer{
+		strategy:   strategy,
+		result:     &Result{Strategy: strategy, Sections: map[string]*SectionResult{}},
+		userGroups: map[uint64]uint64{},
+		authGroups: map[uint64]uint64{},
+		users:      map[uint64]uint64{},
+	}
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, step := range []func(*gorm.DB, *Archive) error{
+			r.restoreUserGroups,
+			r.restoreAuthGroups,
+			r.restoreProviderAPIKeys,
+			r.restoreUsers,
+			r.restoreAuths,
+			r.restoreBillingRules,
+			r.restoreSettings,
+		} {
+			if errStep := step(tx, archive); errStep != nil {
+				return errStep
+			}
+		}
+		return nil
+	})
+	if errTx != nil {
+		return nil, errTx
+	}
+	return r.result, nil
+}
+
+// restorer carries the strategy and the archived to restored ID mappings.
+type restorer struct {
+	strategy   Strategy
+	result     *Result
+	userGroups map[uint64]uint64 // Archived user group ID to target ID.
+	authGroups map[uint64]uint64 // Archived auth group ID to target ID.
+	users      map[uint64]uint64 // Archived user ID to target ID.
+}
+
+// apply creates row, or resolves the conflict with existing per the strategy. It returns the
+// stored row.
+func apply[T any](r *restorer, tx *gorm.DB, section string, existing, row *T) (*T, error) {
+	counts := r.result.section(section)
+	if existing == nil {
+		setField(row, "ID", uint64(0))
+		if errCreate := tx.Omit(clause.Associations).Create(row).Error; errCreate != nil {
+			return nil, fmt.Errorf("backup: restore %s: %w", section, errCreate)
+		}
+		counts.Created++
+		return row, nil
+	}
+	switch r.strategy {
+	case StrategyOverwrite:
+		copyField(row, existing, "ID")
+		copyField(row, existing, "CreatedAt")
+	case StrategyMerge:
+		merged := *existing
+		overlayNonZero(&merged, row)
+		copyField(&merged, existing, "ID")
+		copyField(&merged, existing, "CreatedAt")
+		row = &merged
+	default:
+		counts.Skipped++
+		return existing, nil
+	}
+	if errSave := tx.Omit(clause.Associations).Save(row).Error; errSave != nil {
+		return nil, fmt.Errorf("backup: restore %s: %w", section, errSave)
+	}
+	counts.Updated++
+	return row, nil
+}
+
+func (r *restorer) restoreUserGroups(tx *gorm.DB, archive *Archive) error {
+	parents := map[uint64]*uint64{} // Target ID to archived parent ID.
+	for i := range archive.UserGroups {
+		row := archive.UserGroups[i]
+		archivedID, archivedParent := row.ID, row.ParentID
+		var existing *models.UserGroup
+		var found models.UserGroup
+		if errFind := tx.Where("name = ?", row.Name).Limit(1).Find(&found).Error; errFind != nil {
+			return errFind
+		}
+		if found.ID != 0 {
+			existing = &found
+		}
+		row.ParentID = nil
+		if existing != nil {
+			row.ParentID = existing.ParentID
+		}
+		stored, errApply := apply(r, tx, "user_groups", existing, &row)
+		if errApply != nil {
+			return errApply
+		}
+		r.userGroups[archivedID] = stored.ID
+		if existing == nil || r.strategy == StrategyOverwrite || (r.strategy == StrategyMerge && archivedParent != nil) {
+			parents[stored.ID] = archivedParent
+		}
+	}
+	// Parents are linked once every group has its target ID.
+	for id, archivedParent := range parents {
+		var parentID *uint64
+		if archivedParent != nil {
+			if mapped, ok := r.userGroups[*archivedParent]; ok {
+				parentID = &mapped
+			}
+		}
+		if errUpdate := tx.Model(&models.UserGroup{}).Where("id = ?", id).Update("parent_id", parentID).Error; errUpdate != nil {
+			return fmt.Errorf("backup: link user group parent: %w", errUpdate)
+		}
+	}
+	return nil
+}
+
+func (r *restorer) restoreAuthGroups(tx *gorm.DB, archive *Archive) error {
+	for i := range archive.AuthGroups {
+		row := archive.AuthGroups[i]
+		archivedID := row.ID
+		row.UserGroupID = models.UserGroupIDs(remapIDs(row.UserGroupID, r.userGroups))
+		var found models.AuthGroup
+		if errFind := tx.Where("name = ?", row.Name).Limit(1).Find(&found).Error; errFind != nil {
+			return errFind
+		}
+		stored, errApply := apply(r, tx, "auth_groups", existingOrNil(&found, found.ID), &row)
+		if errApply != nil {
+			return errApply
+		}
+		r.authGroups[archivedID] = stored.ID
+	}
+	return nil
+}
+
+func (r *restorer) restoreProviderAPIKeys(tx *gorm.DB, archive *Archive) error {
+	// Keys may be encrypted at rest, so matching happens on the decrypted rows.
+	var current []models.ProviderAPIKey
+	if errFind := tx.Find(&current).Error; errFind != nil {
+		return errFind
+	}
+	byIdentity := make(map[string]*models.ProviderAPIKey, len(current))
+	for i := range current {
+		byIdentity[providerKeyIdentity(&current[i])] = &current[i]
+	}
+	for i := range archive.ProviderAPIKeys {
+		row := archive.ProviderAPIKeys[i]
+		identity := providerKeyIdentity(&row)
+		stored, errApply := apply(r, tx, "provider_api_keys", byIdentity[identity], &row)
+		if errApply != nil {
+			return errApply
+		}
+		byIdentity[identity] = stored
+	}
+	return nil
+}
+
+// providerKeyIdentity matches provider keys on their secret, or on name and base URL for
+// keys that only carry nested entries.
+func providerKeyIdentity(key *models.ProviderAPIKey) string {
+	provider := strings.ToLower(strings.TrimSpace(key.Provider))
+	if secret := strings.TrimSpace(key.APIKey); secret != "" {
+		return provider + "\x00key\x00" + secret
+	}
+	return provider + "\x00name\x00" + strings.TrimSpace(key.Name) + "\x00" + strings.TrimSpace(key.BaseURL)
+}
+
+func (r *restorer) restoreUsers(tx *gorm.DB, archive *Archive) error {
+	for i := range archive.Users {
+		row := archive.Users[i]
+		archivedID := row.ID
+		row.UserGroupID = models.UserGroupIDs(remapIDs(row.UserGroupID, r.userGroups))
+		row.BillUserGroupID = models.UserGroupIDs(remapIDs(row.BillUserGroupID, r.userGroups))
+		row.Plan, row.APIKeys, row.UserGroup = nil, nil, nil
+		// Plans are not part of the backup; keep the reference only when the plan exists.
+		if row.PlanID != nil {
+			var count int64
+			if errCount := tx.Model(&models.Plan{}).Where("id = ?", *row.PlanID).Count(&count).Error; errCount != nil {
+				return errCount
+			}
+			if count == 0 {
+				row.PlanID = nil
+			}
+		}
+		var found models.User
+		if errFind := tx.Where("username = ?", row.Username).Limit(1).Find(&found).Error; errFind != nil {
+			return errFind
+		}
+		stored, errApply := apply(r, tx, "users", existingOrNil(&found, found.ID), &row)
+		if errApply != nil {
+			return errApply
+		}
+		r.users[archivedID] = stored.ID
+	}
+	return nil
+}
+
+func (r *restorer) restoreAuths(tx *gorm.DB, archive *Archive) error {
+	for i := range archive.Auths {
+		row := archive.Auths[i]
+		row.AuthGroupID = models.AuthGroupIDs(remapIDs(row.AuthGroupID, r.authGroups))
+		row.AuthGroup = nil
+		if row.ContributedByUserID != nil {
+			if mapped, ok := r.users[*row.ContributedByUserID]; ok {
+				row.ContributedByUserID = &mapped
+			} else {
+				row.ContributedByUserID = nil
+			}
+		}
+		var found models.Auth
+		if errFind := tx.Where("? = ?", clause.Column{Name: "key"}, row.Key).Limit(1).Find(&found).Error; errFind != nil {
+			return errFind
+		}
+		if _, errApply := apply(r, tx, "auths", existingOrNil(&found, found.ID), &row); errApply != nil {
+			return errApply
+		}
+	}
+	return nil
+}
+
+func (r *restorer) restoreBillingRules(tx *gorm.DB, archive *Archive) error {
+	for i := range archive.BillingRules {
+		row := archive.BillingRules[i]
+		row.AuthGroup, row.UserGroup = models.AuthGroup{}, models.UserGroup{}
+		authGroupID, okAuth := remapID(row.AuthGroupID, r.authGroups)
+		userGroupID, okUser := remapID(row.UserGroupID, r.userGroups)
+		if !okAuth || !okUser {
+			r.result.section("billing_rules").Skipped++
+			continue
+		}
+		row.AuthGroupID, row.UserGroupID = authGroupID, userGroupID
+		var found models.BillingRule
+		if errFind := tx.Where("auth_group_id = ? AND user_group_id = ? AND provider = ? AND model = ?", row.AuthGroupID, row.UserGroupID, row.Provider, row.Model).
+			Limit(1).Find(&found).Error; errFind != nil {
+			return errFind
+		}
+		if _, errApply := apply(r, tx, "billing_rules", existingOrNil(&found, found.ID), &row); errApply != nil {
+			return errApply
+		}
+	}
+	return nil
+}
+
+func (r *restorer) restoreSettings(tx *gorm.DB, archive *Archive) error {
+	for i := range archive.Settings {
+		row := archive.Settings[i]
+		var found []models.Setting
+		if errFind := tx.Where("? = ?", clause.Column{Name: "key"}, row.Key).Limit(1).Find(&found).Error; errFind != nil {
+			return errFind
+		}
+		var existing *models.Setting
+		if len(found) > 0 {
+			existing = &found[0]
+		}
+		if _, errApply := apply(r, tx, "settings", existing, &row); errApply != nil {
+			return errApply
+		}
+	}
+	return nil
+}
+
+// existingOrNil returns found when the lookup matched a row.
+func existingOrNil[T any](found *T, id uint64) *T {
+	if id == 0 {
+		return nil
+	}
+	return found
+}
+
+// remapID translates an archived ID; zero stays zero as it scopes to every group.
+func remapID(id uint64, mapping map[uint64]uint64) (uint64, bool) {
+	if id == 0 {
+		return 0, true
+	}
+	mapped, ok := mapping[id]
+	return mapped, ok
+}
+
+// remapIDs translates archived ID lists, dropping IDs without a restored counterpart.
+func remapIDs(ids []*uint64, mapping map[uint64]uint64) []*uint64 {
+	out := make([]*uint64, 0, len(ids))
+	for _, id := range ids {
+		if id == nil {
+			continue
+		}
+		if mapped, ok := mapping[*id]; ok {
+			out = append(out, &mapped)
+		}
+	}
+	return out
+}
+
+// overlayNonZero copies every non-zero exported field of src onto dst.
+func overlayNonZero[T any](dst, src *T) {
+	dv, sv := reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem()
+	for i := 0; i < sv.NumField(); i++ {
+		if !sv.Type().Field(i).IsExported() || sv.Field(i).IsZero() {
+			continue
+		}
+		dv.Field(i).Set(sv.Field(i))
+	}
+}
+
+// copyField copies the named field from src onto dst when it exists.
+func copyField[T any](dst, src *T, name string) {
+	if field := reflect.ValueOf(src).Elem().FieldByName(name); field.IsValid() {
+		reflect.ValueOf(dst).Elem().FieldByName(name).Set(field)
+	}
+}
+
+// setField sets the named field when it exists.
+func setField[T any](dst *T, name string, value any) {
+	if field := reflect.ValueOf(dst).Elem().FieldByName(name); field.IsValid() && field.CanSet() {
+		field.Set(reflect.ValueOf(value))
+	}
+}
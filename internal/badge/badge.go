@@ -0,0 +1,210 @@
+// Package badge serves public usage badges: a token-addressed summary of one user's or
+// project's requests this month and their success rate, rendered as JSON or SVG. Results
+// are cached and lookups are rate limited because the endpoint is unauthenticated.
+package badge
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/stats"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultCacheTTL is how long a computed badge is served before it is recomputed.
+	defaultCacheTTL = 5 * time.Minute
+	// maxCacheEntries bounds the cache; expired entries are pruned once it is reached.
+	maxCacheEntries = 4096
+	// tokenBytes is the amount of randomness in a badge token.
+	tokenBytes = 20
+	// maxLabelLength bounds the custom label drawn on the badge.
+	maxLabelLength = 32
+	// maxBadgesPerUser bounds how many badges one user may create.
+	maxBadgesPerUser = 20
+)
+
+var (
+	// ErrNotFound is returned when no badge matches a token.
+	ErrNotFound = errors.New("badge: not found")
+	// ErrLimitReached is returned when a user already owns the maximum number of badges.
+	ErrLimitReached = errors.New("badge: badge limit reached")
+)
+
+// Stats summarizes the usage shown on a badge.
+type Stats struct {
+	Label          string    `json:"label"`
+	Project        string    `json:"project,omitempty"`
+	MonthStart     time.Time `json:"month_start"`
+	Requests       int64     `json:"requests"`
+	FailedRequests int64     `json:"failed_requests"`
+	Uptime         float64   `json:"uptime"` // Successful requests as a percentage; 100 without traffic.
+	GeneratedAt    time.Time `json:"generated_at"`
+}
+
+// MonthStart returns the local midnight that starts the month containing t.
+func MonthStart(t time.Time) time.Time {
+	local := t.In(time.Local)
+	return time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, time.Local)
+}
+
+// Create stores a new badge for the user with a fresh random token.
+func Create(ctx context.Context, db *gorm.DB, userID uint64, project, label string) (*models.UsageBadge, error) {
+	project = strings.TrimSpace(project)
+	label = strings.TrimSpace(label)
+	if len([]rune(label)) > maxLabelLength {
+		label = string([]rune(label)[:maxLabelLength])
+	}
+	var count int64
+	if errCount := db.WithContext(ctx).Model(&models.UsageBadge{}).Where("user_id = ?", userID).Count(&count).Error; errCount != nil {
+		return nil, fmt.Errorf("badge: count badges: %w", errCount)
+	}
+	if count >= maxBadgesPerUser {
+		return nil, ErrLimitReached
+	}
+	buf := make([]byte, tokenBytes)
+	if _, errRead := rand.Read(buf); errRead != nil {
+		return nil, fmt.Errorf("badge: random: %w", errRead)
+	}
+	row := models.UsageBadge{
+		UserID:  userID,
+		Token:   hex.EncodeToString(buf),
+		Project: project,
+		Label:   label,
+	}
+	if errCreate := db.WithContext(ctx).Create(&row).Error; errCreate != nil {
+		return nil, fmt.Errorf("badge: create: %w", errCreate)
+	}
+	return &row, nil
+}
+
+// Compute returns the badge's usage for the month containing now. User badges read the
+// hourly aggregates; project badges filter raw usages by source, which the aggregates do
+// not keep.
+func Compute(ctx context.Context, db *gorm.DB, row *models.UsageBadge, now time.Time) (Stats, error) {
+	from := MonthStart(now)
+	out := Stats{Label: row.Label, Project: row.Project, MonthStart: from, GeneratedAt: now.UTC()}
+	if row.Project == "" {
+		requests, failed, errQuery := stats.QueryUserRequests(ctx, db, row.UserID, from, now)
+		if errQuery != nil {
+			return Stats{}, errQuery
+		}
+		out.Requests, out.FailedRequests = requests, failed
+	} else {
+		var counts struct {
+			Requests       int64
+			FailedRequests int64
+		}
+		if errScan := db.WithContext(ctx).
+			Model(&models.Usage{}).
+			Where("user_id = ? AND source = ? AND requested_at >= ? AND requested_at < ?", row.UserID, row.Project, from.UTC(), now.UTC()).
+			Select("COUNT(*) AS requests, COALESCE(SUM(CASE WHEN failed THEN 1 ELSE 0 END), 0) AS failed_requests").
+			Scan(&counts).Error; errScan != nil {
+			return Stats{}, fmt.Errorf("badge: query project requests: %w", errScan)
+		}
+		out.Requests, out.FailedRequests = counts.Requests, counts.FailedRequests
+	}
+	out.Uptime = 100
+	if out.Requests > 0 {
+		out.Uptime = float64(out.Requests-out.FailedRequests) / float64(out.Requests) * 100
+	}
+	return out, nil
+}
+
+type cacheEntry struct {
+	stats   Stats
+	expires time.Time
+}
+
+// Server resolves badge tokens to cached stats.
+type Server struct {
+	db  *gorm.DB
+	ttl time.Duration
+	now func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+// NewServer constructs a badge server; returns nil when db is nil.
+func NewServer(db *gorm.DB) *Server {
+	if db == nil {
+		return nil
+	}
+	return &Server{db: db, ttl: defaultCacheTTL, now: time.Now, cache: make(map[string]cacheEntry)}
+}
+
+// Stats returns the cached stats for a badge token, recomputing them once the cache entry
+// expires. Unknown tokens return ErrNotFound and are cached too so probing stays cheap.
+func (s *Server) Stats(ctx context.Context, token string) (Stats, error) {
+	if s == nil || s.db == nil {
+		return Stats{}, ErrNotFound
+	}
+	token = strings.TrimSpace(token)
+	if token == "" || len(token) > tokenBytes*2 {
+		return Stats{}, ErrNotFound
+	}
+	now := s.now()
+	s.mu.Lock()
+	entry, ok := s.cache[token]
+	s.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		if entry.stats.GeneratedAt.IsZero() {
+			return Stats{}, ErrNotFound
+		}
+		return entry.stats, nil
+	}
+
+	var row models.UsageBadge
+	var out Stats
+	errFind := s.db.WithContext(ctx).Where("token = ?", token).First(&row).Error
+	switch {
+	case errors.Is(errFind, gorm.ErrRecordNotFound):
+	case errFind != nil:
+		return Stats{}, fmt.Errorf("badge: load: %w", errFind)
+	default:
+		computed, errCompute := Compute(ctx, s.db, &row, now)
+		if errCompute != nil {
+			return Stats{}, errCompute
+		}
+		out = computed
+	}
+	s.store(token, cacheEntry{stats: out, expires: now.Add(s.ttl)}, now)
+	if out.GeneratedAt.IsZero() {
+		return Stats{}, ErrNotFound
+	}
+	return out, nil
+}
+
+// Forget drops a token from the cache, e.g. after its badge is deleted.
+func (s *Server) Forget(token string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	delete(s.cache, token)
+	s.mu.Unlock()
+}
+
+func (s *Server) store(token string, entry cacheEntry, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.cache) >= maxCacheEntries {
+		for key, cached := range s.cache {
+			if !now.Before(cached.expires) {
+				delete(s.cache, key)
+			}
+		}
+		if len(s.cache) >= maxCacheEntries {
+			return
+		}
+	}
+	s.cache[token] = entry
+}
@@ -0,0 +1,135 @@
+package badge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func setupBadgeDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:badge_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func TestServerComputesAndCachesMonthlyStats(t *testing.T) {
+	conn := setupBadgeDB(t)
+	ctx := context.Background()
+	monthStart := MonthStart(time.Now())
+	now := monthStart.Add(10 * 24 * time.Hour)
+
+	userID, otherID := uint64(7), uint64(8)
+	rows := []models.Usage{
+		{Provider: "codex", Model: "gpt-5", UserID: &userID, Source: "cli", RequestedAt: monthStart.Add(time.Minute)},
+		{Provider: "codex", Model: "gpt-5", UserID: &userID, Source: "cli", RequestedAt: monthStart.Add(2 * time.Minute), Failed: true},
+		{Provider: "codex", Model: "gpt-5", UserID: &userID, Source: "ide", RequestedAt: monthStart.Add(3 * time.Minute)},
+		{Provider: "codex", Model: "gpt-5", UserID: &userID, Source: "cli", RequestedAt: monthStart.Add(-time.Hour)},
+		{Provider: "codex", Model: "gpt-5", UserID: &otherID, Source: "cli", RequestedAt: monthStart.Add(time.Minute)},
+	}
+	if errCreate := conn.Create(&rows).Error; errCreate != nil {
+		t.Fatalf("create usage: %v", errCreate)
+	}
+
+	userBadge, errCreate := Create(ctx, conn, userID, "", "")
+	if errCreate != nil {
+		t.Fatalf("Create: %v", errCreate)
+	}
+	projectBadge, errCreate := Create(ctx, conn, userID, " cli ", "my cli")
+	if errCreate != nil {
+		t.Fatalf("Create project: %v", errCreate)
+	}
+	if len(userBadge.Token) != tokenBytes*2 || userBadge.Token == projectBadge.Token || projectBadge.Project != "cli" {
+		t.Fatalf("unexpected badges %+v %+v", userBadge, projectBadge)
+	}
+
+	server := NewServer(conn)
+	server.now = func() time.Time { return now }
+	got, errStats := server.Stats(ctx, userBadge.Token)
+	if errStats != nil {
+		t.Fatalf("Stats: %v", errStats)
+	}
+	if got.Requests != 3 || got.FailedRequests != 1 {
+		t.Fatalf("unexpected user stats %+v", got)
+	}
+	got, errStats = server.Stats(ctx, projectBadge.Token)
+	if errStats != nil {
+		t.Fatalf("Stats project: %v", errStats)
+	}
+	if got.Requests != 2 || got.Uptime != 50 || got.Label != "my cli" {
+		t.Fatalf("unexpected project stats %+v", got)
+	}
+
+	// Cached results ignore new usage until the entry expires.
+	late := models.Usage{Provider: "codex", Model: "gpt-5", UserID: &userID, Source: "cli", RequestedAt: monthStart.Add(4 * time.Minute)}
+	if errCreate := conn.Create(&late).Error; errCreate != nil {
+		t.Fatalf("create usage: %v", errCreate)
+	}
+	if got, _ = server.Stats(ctx, projectBadge.Token); got.Requests != 2 {
+		t.Fatalf("expected cached project stats, got %+v", got)
+	}
+	server.now = func() time.Time { return now.Add(defaultCacheTTL + time.Second) }
+	if got, _ = server.Stats(ctx, projectBadge.Token); got.Requests != 3 {
+		t.Fatalf("expected recomputed project stats, got %+v", got)
+	}
+
+	if _, errStats = server.Stats(ctx, "missing"); !errors.Is(errStats, ErrNotFound) {
+		t.Fatalf("Stats(missing) error = %v, want ErrNotFound", errStats)
+	}
+}
+
+func TestLimiterAllowsPerMinute(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 1, 12, 0, 10, 0, time.UTC)
+	limiter := NewLimiter(2)
+	limiter.now = func() time.Time { return now }
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.Allow("1.2.3.4"); !ok {
+			t.Fatalf("request %d should be allowed", i)
+		}
+	}
+	if ok, reset := limiter.Allow("1.2.3.4"); ok || !reset.Equal(now.Truncate(time.Minute).Add(time.Minute)) {
+		t.Fatalf("expected third request limited until next minute, ok=%v reset=%s", ok, reset)
+	}
+	if ok, _ := limiter.Allow("5.6.7.8"); !ok {
+		t.Fatal("other clients keep their own window")
+	}
+	now = now.Add(time.Minute)
+	if ok, _ := limiter.Allow("1.2.3.4"); !ok {
+		t.Fatal("expected the limit to reset in the next window")
+	}
+}
+
+func TestRenderSVG(t *testing.T) {
+	t.Parallel()
+
+	if got := FormatCount(1234); got != "1.2k" {
+		t.Fatalf("FormatCount(1234) = %q", got)
+	}
+	if got := FormatCount(250_000_000); got != "250M" {
+		t.Fatalf("FormatCount(250M) = %q", got)
+	}
+	label, message, color := Stats{Requests: 1500, Uptime: 99.5}.Text(MetricUptime)
+	if label != "uptime" || message != "99.50%" || color != colorGreen {
+		t.Fatalf("unexpected uptime text %q %q %q", label, message, color)
+	}
+	svg := string(RenderSVG("a<b", "1.5k/month", colorBlue))
+	if !strings.HasPrefix(svg, "<svg") || !strings.Contains(svg, "a&lt;b") || strings.Contains(svg, "a<b") {
+		t.Fatalf("unexpected svg %s", svg)
+	}
+}
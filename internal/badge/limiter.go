@@ -0,0 +1,71 @@
+package badge
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultClientLimit is how many badge requests one client IP may make per minute.
+	DefaultClientLimit = 30
+	// maxLimiterKeys bounds the limiter; stale windows are pruned once it is reached.
+	maxLimiterKeys = 8192
+)
+
+type limiterWindow struct {
+	start time.Time
+	count int
+}
+
+// Limiter is a fixed one-minute window limiter keyed by client.
+type Limiter struct {
+	limit int
+	now   func() time.Time
+
+	mu      sync.Mutex
+	windows map[string]*limiterWindow
+}
+
+// NewLimiter constructs a limiter allowing limit requests per key per minute.
+func NewLimiter(limit int) *Limiter {
+	if limit <= 0 {
+		limit = DefaultClientLimit
+	}
+	return &Limiter{limit: limit, now: time.Now, windows: make(map[string]*limiterWindow)}
+}
+
+// Allow records a request for key and reports whether it is within the limit, along with
+// when the current window resets.
+func (l *Limiter) Allow(key string) (bool, time.Time) {
+	now := l.now()
+	start := now.Truncate(time.Minute)
+	reset := start.Add(time.Minute)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	window := l.windows[key]
+	if window == nil {
+		if len(l.windows) >= maxLimiterKeys {
+			for k, w := range l.windows {
+				if w.start.Before(start) {
+					delete(l.windows, k)
+				}
+			}
+			// Fail closed when every tracked client is active in this window.
+			if len(l.windows) >= maxLimiterKeys {
+				return false, reset
+			}
+		}
+		window = &limiterWindow{start: start}
+		l.windows[key] = window
+	}
+	if !window.start.Equal(start) {
+		window.start = start
+		window.count = 0
+	}
+	if window.count >= l.limit {
+		return false, reset
+	}
+	window.count++
+	return true, reset
+}
@@ -0,0 +1,85 @@
+package badge
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+)
+
+// Badge colors, matching the common flat README badge palette.
+const (
+	colorBlue   = "#007ec6"
+	colorGreen  = "#4c1"
+	colorYellow = "#dfb317"
+	colorRed    = "#e05d44"
+	colorLabel  = "#555"
+)
+
+// Metrics a badge can display.
+const (
+	MetricRequests = "requests"
+	MetricUptime   = "uptime"
+)
+
+// FormatCount abbreviates a count, e.g. 1234 -> "1.2k".
+func FormatCount(n int64) string {
+	switch {
+	case n >= 1_000_000_000:
+		return trimDecimal(float64(n)/1_000_000_000) + "B"
+	case n >= 1_000_000:
+		return trimDecimal(float64(n)/1_000_000) + "M"
+	case n >= 1_000:
+		return trimDecimal(float64(n)/1_000) + "k"
+	default:
+		return strconv.FormatInt(n, 10)
+	}
+}
+
+func trimDecimal(v float64) string {
+	if v >= 100 {
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(v, 'f', 1, 64)
+}
+
+// Text returns the label, message and color drawn for metric.
+func (s Stats) Text(metric string) (string, string, string) {
+	label := s.Label
+	if metric == MetricUptime {
+		if label == "" {
+			label = "uptime"
+		}
+		color := colorRed
+		switch {
+		case s.Uptime >= 99:
+			color = colorGreen
+		case s.Uptime >= 95:
+			color = colorYellow
+		}
+		return label, strconv.FormatFloat(s.Uptime, 'f', 2, 64) + "%", color
+	}
+	if label == "" {
+		label = "requests"
+	}
+	return label, FormatCount(s.Requests) + "/month", colorBlue
+}
+
+// textWidth estimates the rendered width of s in the 11px Verdana used by the badge.
+func textWidth(s string) int {
+	return len([]rune(s))*7 + 10
+}
+
+// RenderSVG draws a flat two-part badge.
+func RenderSVG(label, message, color string) []byte {
+	labelWidth, messageWidth := textWidth(label), textWidth(message)
+	width := labelWidth + messageWidth
+	label, message = html.EscapeString(label), html.EscapeString(message)
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">`+
+		`<title>%[4]s: %[5]s</title>`+
+		`<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`+
+		`<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>`+
+		`<g clip-path="url(#r)"><rect width="%[2]d" height="20" fill="%[7]s"/><rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/><rect width="%[1]d" height="20" fill="url(#s)"/></g>`+
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+
+		`<text x="%[8]d" y="14">%[4]s</text><text x="%[9]d" y="14">%[5]s</text></g></svg>`,
+		width, labelWidth, messageWidth, label, message, color, colorLabel, labelWidth/2, labelWidth+messageWidth/2))
+}
@@ -0,0 +1,511 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/currency"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/usergroup"
+	"gorm.io/gorm"
+)
+
+// ErrExchangeRateMissing is returned when the matched rule prices in a currency that has no
+// exchange rate. The request is left unpriced instead of being billed at zero.
+var ErrExchangeRateMissing = errors.New("billing: no exchange rate for rule currency")
+
+// Rule match levels reported by ExplainCost, from most to least specific.
+const (
+	MatchExactModel           = "exact_model"
+	MatchGroupWildcard        = "group_wildcard"
+	MatchDefaultExactModel    = "default_exact_model"
+	MatchDefaultGroupWildcard = "default_group_wildcard"
+	MatchNone                 = "none"
+)
+
+// CostInput holds the request attributes that drive billing rule matching and pricing.
+type CostInput struct {
+	Provider        string  // Provider name.
+	Model           string  // Model name after mapping.
+	APIKeyID        *uint64 // Related API key ID.
+	UserID          *uint64 // Related user ID.
+	AuthID          *uint64 // Related auth ID.
+	UserGroupID     *uint64 // Billing user group ID, when already known.
+	Failed          bool    // Whether the request failed.
+	InputTokens     int64   // Input token count.
+	OutputTokens    int64   // Output token count.
+	ReasoningTokens int64   // Reasoning token count.
+	CachedTokens    int64   // Cached token count.
+
+	CacheCreationTokens int64 // Cache-write token count.
+
+	RequestedAt time.Time // Request time positioning tiered volume; zero means now.
+}
+
+// CostTokens describes how reported token counts translate into billable tokens.
+type CostTokens struct {
+	Input         int64 `json:"input"`          // Reported input tokens.
+	Cached        int64 `json:"cached"`         // Reported cached (cache read) tokens.
+	BillableInput int64 `json:"billable_input"` // Input tokens charged at the input price.
+	Output        int64 `json:"output"`         // Reported output tokens.
+	Reasoning     int64 `json:"reasoning"`      // Reported reasoning tokens, billed as part of output.
+	CacheCreate   int64 `json:"cache_create"`   // Reported cache-write tokens, billed at the cache create price.
+	TierVolume    int64 `json:"tier_volume"`    // Tokens already billed this month under a tiered rule.
+}
+
+// CostComponent is one priced line of a cost calculation.
+type CostComponent struct {
+	Name            string  `json:"name"`             // input, output, cache_read, cache_create, request or minimum.
+	Quantity        int64   `json:"quantity"`         // Tokens or requests charged.
+	UnitPrice       float64 `json:"unit_price"`       // Price per million tokens, or per request.
+	CostMicros      float64 `json:"cost_micros"`      // Unrounded line cost in micros of the rule currency.
+	PriceConfigured bool    `json:"price_configured"` // Whether the rule sets this price.
+}
+
+// CostRule summarizes the billing rule a request matched.
+type CostRule struct {
+	ID                    uint64             `json:"id"`
+	AuthGroupID           uint64             `json:"auth_group_id"`
+	UserGroupID           uint64             `json:"user_group_id"`
+	Provider              string             `json:"provider"`
+	Model                 string             `json:"model"`
+	BillingType           models.BillingType `json:"billing_type"`
+	PricePerRequest       *float64           `json:"price_per_request"`
+	PriceInputToken       *float64           `json:"price_input_token"`
+	PriceOutputToken      *float64           `json:"price_output_token"`
+	PriceCacheCreateToken *float64           `json:"price_cache_create_token"`
+	PriceCacheReadToken   *float64           `json:"price_cache_read_token"`
+	Currency              string             `json:"currency"`
+
+	TokenTiers    []models.BillingTokenTier `json:"token_tiers,omitempty"`
+	MinimumCharge *float64                  `json:"minimum_charge"`
+
+	EnergyWhPerMillionTokens *float64 `json:"energy_wh_per_million_tokens"`
+	CarbonGramsPerKWh        *float64 `json:"carbon_grams_per_kwh"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CostMultiplier records a factor applied on top of the rule price.
+type CostMultiplier struct {
+	Name   string  `json:"name"`   // Multiplier name.
+	Factor float64 `json:"factor"` // Factor applied to the subtotal.
+}
+
+// CostExplanation is a step by step account of how a request cost was derived.
+type CostExplanation struct {
+	Provider           string           `json:"provider"`
+	Model              string           `json:"model"`
+	AuthGroupID        *uint64          `json:"auth_group_id"`         // Auth group resolved from the auth.
+	UserGroupID        *uint64          `json:"user_group_id"`         // User group used for matching.
+	DefaultAuthGroupID *uint64          `json:"default_auth_group_id"` // Fallback auth group.
+	DefaultUserGroupID *uint64          `json:"default_user_group_id"` // Fallback user group.
+	MatchLevel         string           `json:"match_level"`           // How specific the matched rule is.
+	Rule               *CostRule        `json:"rule"`                  // Matched rule, if any.
+	Tokens             CostTokens       `json:"tokens"`
+	Components         []CostComponent  `json:"components"`
+	Tiers              []CostTier       `json:"tiers,omitempty"` // Volume bands a tiered rule priced.
+	Multipliers        []CostMultiplier `json:"multipliers"`
+	ExchangeRate       float64          `json:"exchange_rate"`    // Rule currency units per USD used to convert the total.
+	TotalMicros        int64            `json:"total_micros"`     // Final rounded cost in USD micros.
+	Footprint          Footprint        `json:"footprint"`        // Estimated energy and emissions.
+	Reason             string           `json:"reason,omitempty"` // Why the cost is zero, when it is.
+}
+
+// ExplainCost resolves the billing rule for a request and breaks down its cost.
+// The returned TotalMicros is the amount the usage pipeline charges.
+func ExplainCost(ctx context.Context, db *gorm.DB, in CostInput) (*CostExplanation, error) {
+	return explainCost(ctx, db, in, nil)
+}
+
+// ExplainCostWithRules prices a request against set instead of the live billing rules.
+// Groups are still resolved from db.
+func ExplainCostWithRules(ctx context.Context, db *gorm.DB, in CostInput, set *RuleSet) (*CostExplanation, error) {
+	if set == nil {
+		return ExplainCost(ctx, db, in)
+	}
+	return explainCost(ctx, db, in, set)
+}
+
+func explainCost(ctx context.Context, db *gorm.DB, in CostInput, set *RuleSet) (*CostExplanation, error) {
+	out := &CostExplanation{
+		Provider:    strings.TrimSpace(in.Provider),
+		Model:       strings.TrimSpace(in.Model),
+		MatchLevel:  MatchNone,
+		Tokens:      explainTokens(in),
+		Components:  []CostComponent{},
+		Multipliers: []CostMultiplier{},
+	}
+	if db == nil {
+		out.Reason = "database unavailable"
+		return out, nil
+	}
+	if in.Failed {
+		out.Reason = "failed requests are not charged"
+		return out, nil
+	}
+	if out.Provider == "" || out.Model == "" {
+		out.Reason = "provider or model missing"
+		return out, nil
+	}
+	providerLower := strings.ToLower(out.Provider)
+	requestedAt := in.RequestedAt
+	if requestedAt.IsZero() {
+		requestedAt = time.Now()
+	}
+
+	if in.AuthID != nil {
+		if set != nil {
+			out.AuthGroupID = set.authGroupID(ctx, db, *in.AuthID)
+		} else {
+			var auth models.Auth
+			if errFindAuth := db.WithContext(ctx).Select("auth_group_id").First(&auth, *in.AuthID).Error; errFindAuth == nil {
+				out.AuthGroupID = auth.AuthGroupID.Primary()
+			}
+		}
+	}
+
+	userGroupID := in.UserGroupID
+	if userGroupID == nil && in.APIKeyID != nil {
+		var apiKey models.APIKey
+		if errFindAPIKey := db.WithContext(ctx).Select("user_id").First(&apiKey, *in.APIKeyID).Error; errFindAPIKey == nil && apiKey.UserID != nil {
+			var user models.User
+			if errFindUser := db.WithContext(ctx).Select("user_group_id").First(&user, *apiKey.UserID).Error; errFindUser == nil {
+				userGroupID = user.UserGroupID.Primary()
+			}
+		}
+	}
+	if userGroupID == nil && in.UserID != nil {
+		var user models.User
+		if errFindUser := db.WithContext(ctx).Select("user_group_id").First(&user, *in.UserID).Error; errFindUser == nil {
+			userGroupID = user.UserGroupID.Primary()
+		}
+	}
+	out.UserGroupID = userGroupID
+
+	loadCandidateRules := func(primaryAuthGroupID, primaryUserGroupID, defaultAuthGroupID, defaultUserGroupID uint64) ([]models.BillingRule, error) {
+		if set != nil {
+			// SelectBillingRule applies the same group, provider and model filters in memory.
+			return set.rules, nil
+		}
+		q := db.WithContext(ctx).Model(&models.BillingRule{}).Where("is_enabled = true")
+		if defaultAuthGroupID != 0 && defaultUserGroupID != 0 && (defaultAuthGroupID != primaryAuthGroupID || defaultUserGroupID != primaryUserGroupID) {
+			q = q.Where("(auth_group_id = ? AND user_group_id = ?) OR (auth_group_id = ? AND user_group_id = ?)", primaryAuthGroupID, primaryUserGroupID, defaultAuthGroupID, defaultUserGroupID)
+		} else {
+			q = q.Where("auth_group_id = ? AND user_group_id = ?", primaryAuthGroupID, primaryUserGroupID)
+		}
+		q = q.Where("((LOWER(provider) = ? AND model = ?) OR (provider = '' AND model = ''))", providerLower, out.Model)
+
+		var rules []models.BillingRule
+		if errFindRules := q.Find(&rules).Error; errFindRules != nil {
+			return nil, errFindRules
+		}
+		return rules, nil
+	}
+
+	if out.AuthGroupID != nil && userGroupID != nil {
+		// The user group's own rules win, then those of its billing rule group and its parents.
+		var ruleGroupIDs []uint64
+		var errRuleGroups error
+		if set != nil {
+			ruleGroupIDs, errRuleGroups = set.ruleUserGroupIDs(ctx, db, *userGroupID)
+		} else {
+			ruleGroupIDs, errRuleGroups = resolveRuleUserGroupIDs(ctx, db, *userGroupID)
+		}
+		if errRuleGroups != nil {
+			return nil, errRuleGroups
+		}
+		for _, ruleGroupID := range ruleGroupIDs {
+			rulesPrimary, errPrimary := loadCandidateRules(*out.AuthGroupID, ruleGroupID, 0, 0)
+			if errPrimary != nil {
+				return nil, errPrimary
+			}
+			if rule := SelectBillingRule(rulesPrimary, *out.AuthGroupID, ruleGroupID, 0, 0, out.Provider, out.Model); rule != nil {
+				if errPrice := out.price(ctx, db, set, rule, in.UserID, requestedAt, *out.AuthGroupID, ruleGroupID); errPrice != nil {
+					return nil, errPrice
+				}
+				return out, nil
+			}
+		}
+	}
+
+	var defaultAuthGroupID, defaultUserGroupID *uint64
+	if set != nil {
+		var errDefaults error
+		defaultAuthGroupID, defaultUserGroupID, errDefaults = set.defaultGroupIDs(ctx, db)
+		if errDefaults != nil {
+			return nil, errDefaults
+		}
+	} else {
+		var errDefaultAuthGroup, errDefaultUserGroup error
+		defaultAuthGroupID, errDefaultAuthGroup = ResolveDefaultAuthGroupID(ctx, db)
+		if errDefaultAuthGroup != nil {
+			return nil, errDefaultAuthGroup
+		}
+		defaultUserGroupID, errDefaultUserGroup = ResolveDefaultUserGroupID(ctx, db)
+		if errDefaultUserGroup != nil {
+			return nil, errDefaultUserGroup
+		}
+	}
+	out.DefaultAuthGroupID = defaultAuthGroupID
+	out.DefaultUserGroupID = defaultUserGroupID
+
+	primaryAuthGroupID := out.AuthGroupID
+	if primaryAuthGroupID == nil {
+		primaryAuthGroupID = defaultAuthGroupID
+	}
+	primaryUserGroupID := userGroupID
+	if primaryUserGroupID == nil {
+		primaryUserGroupID = defaultUserGroupID
+	}
+	if primaryAuthGroupID == nil || primaryUserGroupID == nil {
+		out.Reason = "no auth group or user group to match rules against"
+		return out, nil
+	}
+
+	var defaultAuthGroupIDValue uint64
+	if defaultAuthGroupID != nil {
+		defaultAuthGroupIDValue = *defaultAuthGroupID
+	}
+	var defaultUserGroupIDValue uint64
+	if defaultUserGroupID != nil {
+		defaultUserGroupIDValue = *defaultUserGroupID
+	}
+
+	rules, errRules := loadCandidateRules(*primaryAuthGroupID, *primaryUserGroupID, defaultAuthGroupIDValue, defaultUserGroupIDValue)
+	if errRules != nil {
+		return nil, errRules
+	}
+	rule := SelectBillingRule(rules, *primaryAuthGroupID, *primaryUserGroupID, defaultAuthGroupIDValue, defaultUserGroupIDValue, out.Provider, out.Model)
+	if rule == nil {
+		out.Reason = "no enabled billing rule matched"
+		return out, nil
+	}
+	if errPrice := out.price(ctx, db, set, rule, in.UserID, requestedAt, *primaryAuthGroupID, *primaryUserGroupID); errPrice != nil {
+		return nil, errPrice
+	}
+	return out, nil
+}
+
+// resolveRuleUserGroupIDs lists the user groups whose billing rules may price members of
+// userGroupID, in order of precedence; the group itself comes first.
+func resolveRuleUserGroupIDs(ctx context.Context, db *gorm.DB, userGroupID uint64) ([]uint64, error) {
+	policy, errPolicy := usergroup.Resolve(ctx, db, userGroupID)
+	if errPolicy != nil {
+		return nil, errPolicy
+	}
+	if len(policy.BillingRuleGroupIDs) == 0 {
+		return []uint64{userGroupID}, nil
+	}
+	return policy.BillingRuleGroupIDs, nil
+}
+
+// price positions the request in the rule's tiers, resolves the rule currency and applies the rule.
+func (e *CostExplanation) price(ctx context.Context, db *gorm.DB, set *RuleSet, rule *models.BillingRule, userID *uint64, at time.Time, authGroupID, userGroupID uint64) error {
+	if errVolume := e.resolveTierVolume(ctx, db, set, rule, userID, at); errVolume != nil {
+		return errVolume
+	}
+	rate, errRate := ruleExchangeRate(ctx, db, rule)
+	if errRate != nil {
+		return errRate
+	}
+	e.applyRule(rule, authGroupID, userGroupID, rate)
+	return nil
+}
+
+// ruleExchangeRate returns the rule currency units per USD, or ErrExchangeRateMissing.
+func ruleExchangeRate(ctx context.Context, db *gorm.DB, rule *models.BillingRule) (float64, error) {
+	code, errCode := currency.Normalize(rule.Currency)
+	if errCode != nil || code == currency.Base {
+		return 1, nil
+	}
+	table, errLoad := currency.Cached(ctx, db)
+	if errLoad != nil {
+		return 0, errLoad
+	}
+	rate, errRate := table.Rate(code)
+	if errRate != nil {
+		return 0, fmt.Errorf("%w %s", ErrExchangeRateMissing, code)
+	}
+	return rate, nil
+}
+
+// resolveTierVolume positions the request within a tiered rule's bands, from the usage table
+// or, when pricing against a rule set, from the volume the set has priced so far.
+func (e *CostExplanation) resolveTierVolume(ctx context.Context, db *gorm.DB, set *RuleSet, rule *models.BillingRule, userID *uint64, now time.Time) error {
+	if set == nil {
+		return e.loadTierVolume(ctx, db, rule, userID, now)
+	}
+	if rule.BillingType == models.BillingTypeTiered && userID != nil {
+		e.Tokens.TierVolume = set.consumeTierVolume(rule.ID, *userID, now, e.Tokens.Input+e.Tokens.Output)
+	}
+	return nil
+}
+
+func explainTokens(in CostInput) CostTokens {
+	// Many upstream providers (e.g. OpenAI usage format) report CachedTokens as a subset of
+	// InputTokens (input_tokens_details.cached_tokens). If we charge InputTokens in full AND
+	// charge CachedTokens again as cache-read, cache hit tokens would be double-charged.
+	//
+	// To make per-token billing consistent across providers, we treat billable input tokens as:
+	//   billableInput = max(InputTokens - CachedTokens, 0)
+	// and charge CachedTokens separately via PriceCacheReadToken.
+	//
+	// Cache-write tokens are reported outside of InputTokens (Anthropic's
+	// cache_creation_input_tokens), so they are charged as-is via PriceCacheCreateToken.
+	billableInput := in.InputTokens
+	if in.CachedTokens > 0 && in.CachedTokens <= billableInput {
+		billableInput -= in.CachedTokens
+	}
+	return CostTokens{
+		Input:         in.InputTokens,
+		Cached:        in.CachedTokens,
+		BillableInput: billableInput,
+		Output:        in.OutputTokens,
+		Reasoning:     in.ReasoningTokens,
+		CacheCreate:   in.CacheCreationTokens,
+	}
+}
+
+// applyRule records the matched rule and prices the request with it. Rule prices are in the
+// rule currency; rate (units per USD, always positive) converts the total to USD.
+func (e *CostExplanation) applyRule(rule *models.BillingRule, authGroupID, userGroupID uint64, rate float64) {
+	e.Rule = &CostRule{
+		ID:                    rule.ID,
+		AuthGroupID:           rule.AuthGroupID,
+		UserGroupID:           rule.UserGroupID,
+		Provider:              rule.Provider,
+		Model:                 rule.Model,
+		BillingType:           rule.BillingType,
+		PricePerRequest:       rule.PricePerRequest,
+		PriceInputToken:       rule.PriceInputToken,
+		PriceOutputToken:      rule.PriceOutputToken,
+		PriceCacheCreateToken: rule.PriceCacheCreateToken,
+		PriceCacheReadToken:   rule.PriceCacheReadToken,
+		Currency:              ruleCurrency(rule),
+		MinimumCharge:         rule.MinimumCharge,
+
+		EnergyWhPerMillionTokens: rule.EnergyWhPerMillionTokens,
+		CarbonGramsPerKWh:        rule.CarbonGramsPerKWh,
+
+		UpdatedAt: rule.UpdatedAt,
+	}
+	// Reasoning tokens are reported as part of output, so input plus output is the
+	// processed token count for footprint purposes.
+	e.Footprint = EstimateFootprint(rule.EnergyWhPerMillionTokens, rule.CarbonGramsPerKWh, e.Tokens.Input+e.Tokens.Output)
+	e.MatchLevel = ruleMatchLevel(rule, authGroupID, userGroupID, e.Provider, e.Model)
+
+	var total float64
+	switch rule.BillingType {
+	case models.BillingTypePerRequest:
+		line := CostComponent{Name: "request", Quantity: 1, PriceConfigured: rule.PricePerRequest != nil}
+		if rule.PricePerRequest != nil {
+			line.UnitPrice = *rule.PricePerRequest
+			line.CostMicros = *rule.PricePerRequest * 1_000_000
+		}
+		e.Components = append(e.Components, line)
+		total = line.CostMicros
+	case models.BillingTypePerToken:
+		// Token prices are per 1,000,000 tokens, so micros = price_per_million * tokens.
+		lines := []struct {
+			name     string
+			quantity int64
+			price    *float64
+		}{
+			{"input", e.Tokens.BillableInput, rule.PriceInputToken},
+			{"output", e.Tokens.Output, rule.PriceOutputToken},
+			{"cache_create", e.Tokens.CacheCreate, rule.PriceCacheCreateToken},
+			{"cache_read", e.Tokens.Cached, rule.PriceCacheReadToken},
+		}
+		for _, l := range lines {
+			line := CostComponent{Name: l.name, Quantity: l.quantity, PriceConfigured: l.price != nil}
+			if l.price != nil {
+				line.UnitPrice = *l.price
+				line.CostMicros = float64(l.quantity) * (*l.price)
+			}
+			total += line.CostMicros
+			e.Components = append(e.Components, line)
+		}
+	case models.BillingTypeTiered:
+		tiers, errTiers := ParseTokenTiers(rule.TokenTiers)
+		if errTiers != nil || len(tiers) == 0 {
+			e.Reason = "invalid token tiers"
+			break
+		}
+		e.Rule.TokenTiers = tiers
+		e.Tiers = allocateTiers(tiers, e.Tokens.TierVolume, e.Tokens.Input+e.Tokens.Output)
+		// Each line is charged at its price blended across the bands the request spans.
+		lines := []struct {
+			name     string
+			quantity int64
+			price    func(models.BillingTokenTier) *float64
+		}{
+			{"input", e.Tokens.BillableInput, func(t models.BillingTokenTier) *float64 { return t.PriceInputToken }},
+			{"output", e.Tokens.Output, func(t models.BillingTokenTier) *float64 { return t.PriceOutputToken }},
+			{"cache_create", e.Tokens.CacheCreate, func(t models.BillingTokenTier) *float64 { return t.PriceCacheCreateToken }},
+			{"cache_read", e.Tokens.Cached, func(t models.BillingTokenTier) *float64 { return t.PriceCacheReadToken }},
+		}
+		for _, l := range lines {
+			price, configured := blendedTierPrice(tiers, e.Tiers, l.price)
+			line := CostComponent{Name: l.name, Quantity: l.quantity, UnitPrice: price, PriceConfigured: configured}
+			line.CostMicros = float64(l.quantity) * price
+			total += line.CostMicros
+			e.Components = append(e.Components, line)
+		}
+	default:
+		e.Reason = "unsupported billing type"
+	}
+	for _, m := range e.Multipliers {
+		total *= m.Factor
+	}
+	if rule.MinimumCharge != nil && e.Reason == "" {
+		minimum := *rule.MinimumCharge * 1_000_000
+		if total < minimum {
+			e.Components = append(e.Components, CostComponent{
+				Name:            "minimum",
+				Quantity:        1,
+				UnitPrice:       *rule.MinimumCharge,
+				CostMicros:      minimum - total,
+				PriceConfigured: true,
+			})
+			total = minimum
+		}
+	}
+	e.ExchangeRate = rate
+	if rate != 1 {
+		total /= rate
+	}
+	e.TotalMicros = int64(math.Round(total))
+	if e.TotalMicros == 0 && e.Reason == "" {
+		e.Reason = "matched rule prices this request at zero"
+	}
+}
+
+// ruleCurrency returns the rule's normalized currency, treating empty or invalid codes as USD.
+func ruleCurrency(rule *models.BillingRule) string {
+	code, errCode := currency.Normalize(rule.Currency)
+	if errCode != nil {
+		return currency.Base
+	}
+	return code
+}
+
+func ruleMatchLevel(rule *models.BillingRule, authGroupID, userGroupID uint64, provider, model string) string {
+	exact := strings.EqualFold(strings.TrimSpace(rule.Provider), strings.TrimSpace(provider)) &&
+		strings.TrimSpace(rule.Model) == strings.TrimSpace(model) && strings.TrimSpace(rule.Model) != ""
+	primary := rule.AuthGroupID == authGroupID && rule.UserGroupID == userGroupID
+	switch {
+	case primary && exact:
+		return MatchExactModel
+	case primary:
+		return MatchGroupWildcard
+	case exact:
+		return MatchDefaultExactModel
+	default:
+		return MatchDefaultGroupWildcard
+	}
+}
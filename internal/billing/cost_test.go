@@ -0,0 +1,186 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/currency"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func TestExplainCost_PerTokenBreakdown(t *testing.T) {
+	dsn := fmt.Sprintf("file:billing_explain_cost_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	ctx := context.Background()
+
+	authGroup := models.AuthGroup{Name: "explain-auth-group"}
+	if errCreate := conn.Create(&authGroup).Error; errCreate != nil {
+		t.Fatalf("create auth group: %v", errCreate)
+	}
+	userGroup := models.UserGroup{Name: "explain-user-group"}
+	if errCreate := conn.Create(&userGroup).Error; errCreate != nil {
+		t.Fatalf("create user group: %v", errCreate)
+	}
+	authGroupID := authGroup.ID
+	auth := models.Auth{Key: "explain-auth", Content: datatypes.JSON(`{"type":"codex"}`), AuthGroupID: models.AuthGroupIDs{&authGroupID}}
+	if errCreate := conn.Create(&auth).Error; errCreate != nil {
+		t.Fatalf("create auth: %v", errCreate)
+	}
+
+	inputPrice, outputPrice, cacheReadPrice := 2.0, 8.0, 0.5
+	wildcard := models.BillingRule{
+		AuthGroupID:      authGroup.ID,
+		UserGroupID:      userGroup.ID,
+		BillingType:      models.BillingTypePerToken,
+		PriceInputToken:  &outputPrice,
+		PriceOutputToken: &outputPrice,
+		IsEnabled:        true,
+	}
+	exact := models.BillingRule{
+		AuthGroupID:         authGroup.ID,
+		UserGroupID:         userGroup.ID,
+		Provider:            "openai",
+		Model:               "gpt-5",
+		BillingType:         models.BillingTypePerToken,
+		PriceInputToken:     &inputPrice,
+		PriceOutputToken:    &outputPrice,
+		PriceCacheReadToken: &cacheReadPrice,
+		IsEnabled:           true,
+	}
+	for _, rule := range []*models.BillingRule{&wildcard, &exact} {
+		if errCreate := conn.Create(rule).Error; errCreate != nil {
+			t.Fatalf("create billing rule: %v", errCreate)
+		}
+	}
+
+	userGroupID := userGroup.ID
+	out, errExplain := ExplainCost(ctx, conn, CostInput{
+		Provider:     "OpenAI",
+		Model:        "gpt-5",
+		AuthID:       &auth.ID,
+		UserGroupID:  &userGroupID,
+		InputTokens:  1000,
+		CachedTokens: 400,
+		OutputTokens: 200,
+	})
+	if errExplain != nil {
+		t.Fatalf("explain cost: %v", errExplain)
+	}
+	if out.Rule == nil || out.Rule.ID != exact.ID {
+		t.Fatalf("expected exact rule %d, got %+v", exact.ID, out.Rule)
+	}
+	if out.MatchLevel != MatchExactModel {
+		t.Fatalf("expected match level %q, got %q", MatchExactModel, out.MatchLevel)
+	}
+	if out.Tokens.BillableInput != 600 {
+		t.Fatalf("expected billable input 600, got %d", out.Tokens.BillableInput)
+	}
+	// 600*2 + 200*8 + 400*0.5 = 1200 + 1600 + 200.
+	if out.TotalMicros != 3000 {
+		t.Fatalf("expected total 3000 micros, got %d", out.TotalMicros)
+	}
+	if len(out.Components) != 4 {
+		t.Fatalf("expected 4 components, got %d", len(out.Components))
+	}
+
+	failed, errFailed := ExplainCost(ctx, conn, CostInput{Provider: "openai", Model: "gpt-5", AuthID: &auth.ID, UserGroupID: &userGroupID, Failed: true, InputTokens: 10})
+	if errFailed != nil {
+		t.Fatalf("explain failed cost: %v", errFailed)
+	}
+	if failed.TotalMicros != 0 || failed.Rule != nil || failed.Reason == "" {
+		t.Fatalf("expected failed request to be free with a reason, got %+v", failed)
+	}
+}
+
+func TestApplyRule_ChargesCacheCreationTokens(t *testing.T) {
+	inputPrice, outputPrice, cacheCreatePrice, cacheReadPrice := 3.0, 15.0, 3.75, 0.3
+	rule := &models.BillingRule{
+		BillingType:           models.BillingTypePerToken,
+		PriceInputToken:       &inputPrice,
+		PriceOutputToken:      &outputPrice,
+		PriceCacheCreateToken: &cacheCreatePrice,
+		PriceCacheReadToken:   &cacheReadPrice,
+	}
+	out := &CostExplanation{Tokens: explainTokens(CostInput{InputTokens: 100, OutputTokens: 10, CacheCreationTokens: 1000})}
+	out.applyRule(rule, 1, 1, 1)
+
+	var cacheCreate *CostComponent
+	for i := range out.Components {
+		if out.Components[i].Name == "cache_create" {
+			cacheCreate = &out.Components[i]
+		}
+	}
+	if cacheCreate == nil || cacheCreate.Quantity != 1000 || cacheCreate.CostMicros != 3750 {
+		t.Fatalf("expected 1000 cache-write tokens charged at 3.75/M, got %+v", cacheCreate)
+	}
+	if out.Tokens.BillableInput != 100 {
+		t.Fatalf("expected cache-write tokens to leave billable input alone, got %d", out.Tokens.BillableInput)
+	}
+	if out.TotalMicros != 300+150+3750 {
+		t.Fatalf("unexpected total %d", out.TotalMicros)
+	}
+}
+
+func TestExplainCost_MissingExchangeRateIsAnError(t *testing.T) {
+	dsn := fmt.Sprintf("file:billing_missing_rate_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	ctx := context.Background()
+	currency.Invalidate()
+	t.Cleanup(currency.Invalidate)
+
+	authGroup := models.AuthGroup{Name: "rate-auth-group"}
+	if errCreate := conn.Create(&authGroup).Error; errCreate != nil {
+		t.Fatalf("create auth group: %v", errCreate)
+	}
+	userGroup := models.UserGroup{Name: "rate-user-group"}
+	if errCreate := conn.Create(&userGroup).Error; errCreate != nil {
+		t.Fatalf("create user group: %v", errCreate)
+	}
+	authGroupID := authGroup.ID
+	auth := models.Auth{Key: "rate-auth", Content: datatypes.JSON(`{"type":"codex"}`), AuthGroupID: models.AuthGroupIDs{&authGroupID}}
+	if errCreate := conn.Create(&auth).Error; errCreate != nil {
+		t.Fatalf("create auth: %v", errCreate)
+	}
+	price := 300.0
+	rule := models.BillingRule{AuthGroupID: authGroup.ID, UserGroupID: userGroup.ID, BillingType: models.BillingTypePerToken, PriceInputToken: &price, Currency: "JPY", IsEnabled: true}
+	if errCreate := conn.Create(&rule).Error; errCreate != nil {
+		t.Fatalf("create billing rule: %v", errCreate)
+	}
+
+	userGroupID := userGroup.ID
+	input := CostInput{Provider: "openai", Model: "gpt-5", AuthID: &auth.ID, UserGroupID: &userGroupID, InputTokens: 1000}
+	if _, errExplain := ExplainCost(ctx, conn, input); !errors.Is(errExplain, ErrExchangeRateMissing) {
+		t.Fatalf("explain without a JPY rate error = %v, want ErrExchangeRateMissing", errExplain)
+	}
+
+	if _, errSet := currency.SetRate(ctx, conn, "JPY", 150, models.ExchangeRateSourceManual); errSet != nil {
+		t.Fatalf("set rate: %v", errSet)
+	}
+	out, errExplain := ExplainCost(ctx, conn, input)
+	if errExplain != nil {
+		t.Fatalf("explain with a JPY rate: %v", errExplain)
+	}
+	// 1000*300 JPY micros at 150 JPY per USD.
+	if out.TotalMicros != 2000 || out.ExchangeRate != 150 {
+		t.Fatalf("expected 2000 micros at rate 150, got %d at %v", out.TotalMicros, out.ExchangeRate)
+	}
+}
@@ -0,0 +1,113 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// dailyQuotaEpsilon defines a tolerance for daily quota comparisons.
+const dailyQuotaEpsilon = 0.000001
+
+// ErrDailyBillQuotaReached indicates the user's active bills hit their aggregated daily quota.
+var ErrDailyBillQuotaReached = errors.New("daily bill quota reached")
+
+// DailyLimitError reports a reached daily bill quota together with the next reset time.
+type DailyLimitError struct {
+	ResetsAt time.Time // Next local midnight, when billed requests resume.
+}
+
+// Error implements error.
+func (e *DailyLimitError) Error() string {
+	if e == nil {
+		return ErrDailyBillQuotaReached.Error()
+	}
+	return fmt.Sprintf("%s, resets at %s", ErrDailyBillQuotaReached.Error(), e.ResetsAt.Format(time.RFC3339))
+}
+
+// Unwrap returns ErrDailyBillQuotaReached so callers can match with errors.Is.
+func (e *DailyLimitError) Unwrap() error { return ErrDailyBillQuotaReached }
+
+// DailyBillState summarizes a user's active paid bills against their aggregated daily quota.
+type DailyBillState struct {
+	HasActiveBill  bool      `json:"has_active_bill"` // Whether any paid, enabled bill with quota left covers now.
+	LeftQuota      float64   `json:"left_quota"`      // Remaining quota across active bills.
+	DailyQuota     float64   `json:"daily_quota"`     // Aggregated daily quota; zero when unlimited.
+	UnlimitedDaily bool      `json:"unlimited_daily"` // Whether any active bill has no daily quota.
+	UsedToday      float64   `json:"used_today"`      // Usage cost since local midnight.
+	LimitReached   bool      `json:"limit_reached"`   // Whether billed requests are blocked until ResetsAt.
+	ResetsAt       time.Time `json:"resets_at"`       // Next local midnight.
+}
+
+// Err returns a DailyLimitError when the daily limit is reached, otherwise nil.
+func (s DailyBillState) Err() error {
+	if !s.LimitReached {
+		return nil
+	}
+	return &DailyLimitError{ResetsAt: s.ResetsAt}
+}
+
+// NextDailyReset returns the next local midnight after now.
+func NextDailyReset(now time.Time) time.Time {
+	localNow := now.In(time.Local)
+	todayStart := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, time.Local)
+	return todayStart.AddDate(0, 0, 1)
+}
+
+// LoadDailyBillState aggregates the user's active bills and today's usage.
+func LoadDailyBillState(ctx context.Context, db *gorm.DB, userID uint64, now time.Time) (DailyBillState, error) {
+	state := DailyBillState{ResetsAt: NextDailyReset(now)}
+	if db == nil {
+		return state, errors.New("nil db")
+	}
+	if userID == 0 {
+		return state, nil
+	}
+
+	var summary struct {
+		ActiveBills    int64   `gorm:"column:active_bills"`    // Count of active bills.
+		LeftQuota      float64 `gorm:"column:left_quota"`      // Total remaining quota across bills.
+		DailyQuota     float64 `gorm:"column:daily_quota"`     // Sum of daily quotas for limited plans.
+		UnlimitedDaily int64   `gorm:"column:unlimited_daily"` // Count of unlimited daily plans.
+	}
+	nowUTC := now.UTC()
+	if errSummary := db.WithContext(ctx).
+		Model(&models.Bill{}).
+		Select(`
+			COUNT(*) AS active_bills,
+			COALESCE(SUM(left_quota), 0) AS left_quota,
+			COALESCE(SUM(CASE WHEN daily_quota > 0 THEN daily_quota ELSE 0 END), 0) AS daily_quota,
+			COALESCE(SUM(CASE WHEN daily_quota <= 0 THEN 1 ELSE 0 END), 0) AS unlimited_daily
+		`).
+		Where("user_id = ? AND is_enabled = ? AND status = ? AND left_quota > 0", userID, true, models.BillStatusPaid).
+		Where("period_start <= ? AND period_end >= ?", nowUTC, nowUTC).
+		Scan(&summary).Error; errSummary != nil {
+		return state, errSummary
+	}
+	if summary.ActiveBills == 0 || summary.LeftQuota <= 0 {
+		return state, nil
+	}
+	state.HasActiveBill = true
+	state.LeftQuota = summary.LeftQuota
+	state.UnlimitedDaily = summary.UnlimitedDaily > 0 || summary.DailyQuota <= 0
+	if !state.UnlimitedDaily {
+		state.DailyQuota = summary.DailyQuota
+	}
+
+	todayStart := state.ResetsAt.AddDate(0, 0, -1)
+	var costMicros int64
+	if errSum := db.WithContext(ctx).
+		Model(&models.Usage{}).
+		Where("user_id = ? AND requested_at >= ?", userID, todayStart).
+		Select("COALESCE(SUM(cost_micros), 0)").
+		Scan(&costMicros).Error; errSum != nil {
+		return state, errSum
+	}
+	state.UsedToday = float64(costMicros) / 1_000_000
+	state.LimitReached = !state.UnlimitedDaily && state.UsedToday+dailyQuotaEpsilon >= state.DailyQuota
+	return state, nil
+}
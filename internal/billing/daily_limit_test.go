@@ -0,0 +1,94 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestLoadDailyBillState_LimitReached(t *testing.T) {
+	dsn := fmt.Sprintf("file:billing_daily_limit_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	ctx := context.Background()
+	now := time.Now()
+
+	user := models.User{Username: "daily-user", Email: "daily-user@example.com", Password: "x"}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+	plan := models.Plan{Name: "Daily", MonthPrice: 10}
+	if errCreate := conn.Create(&plan).Error; errCreate != nil {
+		t.Fatalf("create plan: %v", errCreate)
+	}
+	bill := models.Bill{
+		PlanID:      plan.ID,
+		UserID:      user.ID,
+		PeriodType:  models.BillPeriodTypeMonthly,
+		PeriodStart: now.UTC().Add(-24 * time.Hour),
+		PeriodEnd:   now.UTC().Add(24 * time.Hour),
+		TotalQuota:  100,
+		DailyQuota:  1,
+		LeftQuota:   100,
+		IsEnabled:   true,
+		Status:      models.BillStatusPaid,
+	}
+	if errCreate := conn.Create(&bill).Error; errCreate != nil {
+		t.Fatalf("create bill: %v", errCreate)
+	}
+
+	state, errState := LoadDailyBillState(ctx, conn, user.ID, now)
+	if errState != nil {
+		t.Fatalf("load state: %v", errState)
+	}
+	if !state.HasActiveBill || state.LimitReached || state.Err() != nil {
+		t.Fatalf("expected active bill under limit, got %+v", state)
+	}
+
+	userID := user.ID
+	usage := models.Usage{
+		Provider:    "codex",
+		Model:       "gpt-5",
+		UserID:      &userID,
+		RequestedAt: now.UTC(),
+		CostMicros:  1_200_000,
+		ChargedTo:   "bill",
+	}
+	if errCreate := conn.Create(&usage).Error; errCreate != nil {
+		t.Fatalf("create usage: %v", errCreate)
+	}
+
+	state, errState = LoadDailyBillState(ctx, conn, user.ID, now)
+	if errState != nil {
+		t.Fatalf("load state: %v", errState)
+	}
+	if !state.LimitReached {
+		t.Fatalf("expected daily limit reached, got %+v", state)
+	}
+	if !errors.Is(state.Err(), ErrDailyBillQuotaReached) {
+		t.Fatalf("expected ErrDailyBillQuotaReached, got %v", state.Err())
+	}
+	if !state.ResetsAt.Equal(NextDailyReset(now)) || !state.ResetsAt.After(now) {
+		t.Fatalf("unexpected reset time %s", state.ResetsAt)
+	}
+}
+
+func TestNextDailyReset(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 30, 0, 0, time.Local)
+	want := time.Date(2026, 3, 11, 0, 0, 0, 0, time.Local)
+	if got := NextDailyReset(now); !got.Equal(want) {
+		t.Fatalf("NextDailyReset(%s) = %s, want %s", now, got, want)
+	}
+}
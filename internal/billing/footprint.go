@@ -0,0 +1,26 @@
+package billing
+
+import "math"
+
+// Footprint is the estimated energy use and emissions of a request.
+type Footprint struct {
+	EnergyMilliWh    int64 `json:"energy_milli_wh"`   // Estimated energy in milliwatt-hours.
+	CarbonMilligrams int64 `json:"carbon_milligrams"` // Estimated emissions in milligrams CO2e.
+}
+
+// EstimateFootprint applies a rule's footprint factors to a token count. Energy is only
+// estimated when the rule sets an energy factor; emissions additionally need a carbon
+// intensity.
+func EstimateFootprint(energyWhPerMillionTokens, carbonGramsPerKWh *float64, tokens int64) Footprint {
+	if energyWhPerMillionTokens == nil || *energyWhPerMillionTokens <= 0 || tokens <= 0 {
+		return Footprint{}
+	}
+	// Wh per 1M tokens equals mWh per 1K tokens.
+	energyMilliWh := float64(tokens) * (*energyWhPerMillionTokens) / 1_000
+	out := Footprint{EnergyMilliWh: int64(math.Round(energyMilliWh))}
+	if carbonGramsPerKWh != nil && *carbonGramsPerKWh > 0 {
+		// mWh / 1e6 = kWh and g * 1e3 = mg, so mg = mWh * g/kWh / 1e3.
+		out.CarbonMilligrams = int64(math.Round(energyMilliWh * (*carbonGramsPerKWh) / 1_000))
+	}
+	return out
+}
@@ -0,0 +1,29 @@
+package billing
+
+import "testing"
+
+func TestEstimateFootprint(t *testing.T) {
+	t.Parallel()
+	energy := 300.0 // Wh per 1M tokens.
+	carbon := 400.0 // gCO2e per kWh.
+	zero := 0.0
+
+	cases := []struct {
+		name   string
+		energy *float64
+		carbon *float64
+		tokens int64
+		want   Footprint
+	}{
+		{name: "energy and carbon", energy: &energy, carbon: &carbon, tokens: 10_000, want: Footprint{EnergyMilliWh: 3000, CarbonMilligrams: 1200}},
+		{name: "energy only", energy: &energy, tokens: 10_000, want: Footprint{EnergyMilliWh: 3000}},
+		{name: "no energy factor", carbon: &carbon, tokens: 10_000, want: Footprint{}},
+		{name: "zero factor", energy: &zero, carbon: &carbon, tokens: 10_000, want: Footprint{}},
+		{name: "no tokens", energy: &energy, carbon: &carbon, want: Footprint{}},
+	}
+	for _, tc := range cases {
+		if got := EstimateFootprint(tc.energy, tc.carbon, tc.tokens); got != tc.want {
+			t.Fatalf("%s: expected %+v, got %+v", tc.name, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,518 @@
+package billing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const defaultGroupMigrationInterval = time.Minute
+
+// Group migration boundaries accepted by ResolveGroupMigrationBoundary.
+const (
+	// GroupMigrationBoundaryNow applies the migration immediately.
+	GroupMigrationBoundaryNow = "now"
+	// GroupMigrationBoundaryNextDay applies the migration at the next UTC midnight.
+	GroupMigrationBoundaryNextDay = "next_day"
+	// GroupMigrationBoundaryPeriodEnd applies the migration when the earliest active bill ends.
+	GroupMigrationBoundaryPeriodEnd = "period_end"
+)
+
+// Bill change actions recorded in a migration result.
+const (
+	billActionClosed   = "closed"
+	billActionProrated = "prorated"
+	billActionRetagged = "retagged"
+	billActionDisabled = "disabled"
+)
+
+// ErrGroupMigrationNotScheduled is returned when acting on a migration that is no longer scheduled.
+var ErrGroupMigrationNotScheduled = errors.New("group migration: not scheduled")
+
+// GroupMigrationBillChange describes what a migration does, or did, to one bill.
+type GroupMigrationBillChange struct {
+	BillID          uint64              `json:"bill_id"`                   // Affected bill ID.
+	Action          string              `json:"action"`                    // closed, prorated, retagged or disabled.
+	PeriodEnd       time.Time           `json:"period_end"`                // Period end after the change.
+	UserGroupID     models.UserGroupIDs `json:"user_group_id"`             // Bill user groups after the change.
+	NewBillID       uint64              `json:"new_bill_id,omitempty"`     // Bill created for the remainder.
+	CarriedQuota    float64             `json:"carried_quota,omitempty"`   // Quota moved to the new bill.
+	CarriedAmount   float64             `json:"carried_amount,omitempty"`  // Amount moved to the new bill.
+	RemainingRatio  float64             `json:"remaining_ratio,omitempty"` // Share of the period after the boundary.
+	OriginalGroupID models.UserGroupIDs `json:"original_user_group_id"`    // Bill user groups before the change.
+}
+
+// ParseGroupMigrationBillStrategy normalizes a strategy name; empty defaults to prorate.
+func ParseGroupMigrationBillStrategy(raw string) (models.GroupMigrationBillStrategy, bool) {
+	switch models.GroupMigrationBillStrategy(strings.ToLower(strings.TrimSpace(raw))) {
+	case "", models.GroupMigrationBillStrategyProrate:
+		return models.GroupMigrationBillStrategyProrate, true
+	case models.GroupMigrationBillStrategyClose:
+		return models.GroupMigrationBillStrategyClose, true
+	case models.GroupMigrationBillStrategyRetag:
+		return models.GroupMigrationBillStrategyRetag, true
+	default:
+		return "", false
+	}
+}
+
+// ResolveGroupMigrationBoundary converts a boundary name into an effective time.
+// An explicit effectiveAt takes precedence over the boundary name.
+func ResolveGroupMigrationBoundary(ctx context.Context, db *gorm.DB, userID uint64, boundary string, effectiveAt *time.Time, now time.Time) (time.Time, error) {
+	now = now.UTC()
+	if effectiveAt != nil && !effectiveAt.IsZero() {
+		if effectiveAt.Before(now) {
+			return now, nil
+		}
+		return effectiveAt.UTC(), nil
+	}
+	switch strings.ToLower(strings.TrimSpace(boundary)) {
+	case "", GroupMigrationBoundaryNow:
+		return now, nil
+	case GroupMigrationBoundaryNextDay:
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1), nil
+	case GroupMigrationBoundaryPeriodEnd:
+		var bill models.Bill
+		errFind := db.WithContext(ctx).
+			Select("id", "period_end").
+			Where("user_id = ? AND is_enabled = ? AND status = ?", userID, true, models.BillStatusPaid).
+			Where("period_start <= ? AND period_end > ?", now, now).
+			Order("period_end ASC").
+			First(&bill).Error
+		if errFind != nil {
+			if errors.Is(errFind, gorm.ErrRecordNotFound) {
+				return now, nil
+			}
+			return time.Time{}, errFind
+		}
+		return bill.PeriodEnd.UTC(), nil
+	default:
+		return time.Time{}, fmt.Errorf("group migration: unknown boundary %q", boundary)
+	}
+}
+
+// PlanGroupMigration previews the bill changes a migration would make at the boundary.
+func PlanGroupMigration(ctx context.Context, db *gorm.DB, migration *models.UserGroupMigration) ([]GroupMigrationBillChange, error) {
+	if db == nil || migration == nil {
+		return nil, errors.New("group migration: nil input")
+	}
+	bills, errBills := loadMigratableBills(db.WithContext(ctx), migration)
+	if errBills != nil {
+		return nil, errBills
+	}
+	changes := make([]GroupMigrationBillChange, 0, len(bills))
+	for i := range bills {
+		change, _, _ := planBillChange(&bills[i], migration)
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// ScheduleGroupMigration stores a migration and applies it right away when it is already due.
+func ScheduleGroupMigration(ctx context.Context, db *gorm.DB, migration *models.UserGroupMigration) error {
+	if db == nil || migration == nil {
+		return errors.New("group migration: nil input")
+	}
+	now := time.Now().UTC()
+	migration.Status = models.GroupMigrationStatusScheduled
+	migration.CreatedAt = now
+	migration.UpdatedAt = now
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Only one scheduled migration per user; a newer schedule replaces the older one.
+		if errCancel := tx.Model(&models.UserGroupMigration{}).
+			Where("user_id = ? AND status = ?", migration.UserID, models.GroupMigrationStatusScheduled).
+			Updates(map[string]any{
+				"status":     models.GroupMigrationStatusCancelled,
+				"updated_at": now,
+			}).Error; errCancel != nil {
+			return errCancel
+		}
+		return tx.Create(migration).Error
+	})
+	if errTx != nil {
+		return fmt.Errorf("group migration: schedule: %w", errTx)
+	}
+	if migration.EffectiveAt.After(now) {
+		return nil
+	}
+	applied, errApply := ApplyGroupMigration(ctx, db, migration.ID)
+	if applied != nil {
+		*migration = *applied
+	}
+	return errApply
+}
+
+// CancelGroupMigration cancels a scheduled migration.
+func CancelGroupMigration(ctx context.Context, db *gorm.DB, migrationID uint64) error {
+	res := db.WithContext(ctx).
+		Model(&models.UserGroupMigration{}).
+		Where("id = ? AND status = ?", migrationID, models.GroupMigrationStatusScheduled).
+		Updates(map[string]any{
+			"status":     models.GroupMigrationStatusCancelled,
+			"updated_at": time.Now().UTC(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrGroupMigrationNotScheduled
+	}
+	return nil
+}
+
+// ApplyGroupMigration closes, prorates or retags the user's bills and switches the user
+// groups in a single transaction. A failed apply is recorded on the migration row.
+func ApplyGroupMigration(ctx context.Context, db *gorm.DB, migrationID uint64) (*models.UserGroupMigration, error) {
+	var migration models.UserGroupMigration
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if errFind := tx.Where("id = ?", migrationID).First(&migration).Error; errFind != nil {
+			return errFind
+		}
+		if migration.Status != models.GroupMigrationStatusScheduled {
+			return ErrGroupMigrationNotScheduled
+		}
+
+		bills, errBills := loadMigratableBills(tx, &migration)
+		if errBills != nil {
+			return errBills
+		}
+		changes := make([]GroupMigrationBillChange, 0, len(bills))
+		for i := range bills {
+			change, errApply := applyBillChange(tx, &bills[i], &migration)
+			if errApply != nil {
+				return errApply
+			}
+			changes = append(changes, change)
+		}
+
+		now := time.Now().UTC()
+		if errUser := tx.Model(&models.User{}).
+			Where("id = ?", migration.UserID).
+			Updates(map[string]any{
+				"user_group_id": migration.ToUserGroupID.Clean(),
+				"updated_at":    now,
+			}).Error; errUser != nil {
+			return fmt.Errorf("update user: %w", errUser)
+		}
+		if errRefresh := refreshBillUserGroupIDs(ctx, tx, migration.UserID); errRefresh != nil {
+			return fmt.Errorf("refresh bill user group ids: %w", errRefresh)
+		}
+
+		result, errMarshal := json.Marshal(changes)
+		if errMarshal != nil {
+			return errMarshal
+		}
+		migration.Status = models.GroupMigrationStatusApplied
+		migration.Result = datatypes.JSON(result)
+		migration.LastError = ""
+		migration.AppliedAt = &now
+		return tx.Model(&models.UserGroupMigration{}).
+			Where("id = ?", migration.ID).
+			Updates(map[string]any{
+				"status":     migration.Status,
+				"result":     migration.Result,
+				"last_error": "",
+				"applied_at": now,
+				"updated_at": now,
+			}).Error
+	})
+	if errTx != nil {
+		if migration.ID != 0 && !errors.Is(errTx, ErrGroupMigrationNotScheduled) {
+			if errMark := db.WithContext(ctx).
+				Model(&models.UserGroupMigration{}).
+				Where("id = ? AND status = ?", migration.ID, models.GroupMigrationStatusScheduled).
+				Updates(map[string]any{
+					"status":     models.GroupMigrationStatusFailed,
+					"last_error": errTx.Error(),
+					"updated_at": time.Now().UTC(),
+				}).Error; errMark != nil {
+				log.WithError(errMark).Warnf("group migration: mark %d failed", migration.ID)
+			}
+		}
+		return nil, errTx
+	}
+	return &migration, nil
+}
+
+// ApplyDueGroupMigrations applies every scheduled migration whose effective time has passed.
+func ApplyDueGroupMigrations(ctx context.Context, db *gorm.DB, now time.Time) (int, error) {
+	var ids []uint64
+	if errFind := db.WithContext(ctx).
+		Model(&models.UserGroupMigration{}).
+		Where("status = ? AND effective_at <= ?", models.GroupMigrationStatusScheduled, now.UTC()).
+		Order("effective_at ASC, id ASC").
+		Pluck("id", &ids).Error; errFind != nil {
+		return 0, errFind
+	}
+	applied := 0
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return applied, ctx.Err()
+		}
+		if _, errApply := ApplyGroupMigration(ctx, db, id); errApply != nil {
+			log.WithError(errApply).Warnf("group migration: apply %d failed", id)
+			continue
+		}
+		applied++
+	}
+	return applied, nil
+}
+
+// GroupMigrationScheduler applies scheduled group migrations once they become due.
+type GroupMigrationScheduler struct {
+	db       *gorm.DB
+	interval time.Duration
+}
+
+// NewGroupMigrationScheduler constructs a scheduler; returns nil when db is nil.
+func NewGroupMigrationScheduler(db *gorm.DB) *GroupMigrationScheduler {
+	if db == nil {
+		return nil
+	}
+	return &GroupMigrationScheduler{db: db, interval: defaultGroupMigrationInterval}
+}
+
+// Start launches the scheduler loop in a background goroutine.
+func (s *GroupMigrationScheduler) Start(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go s.run(ctx)
+	log.Infof("group migration scheduler started (interval=%s)", s.interval)
+}
+
+func (s *GroupMigrationScheduler) run(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, errApply := ApplyDueGroupMigrations(ctx, s.db, time.Now().UTC()); errApply != nil {
+			log.WithError(errApply).Warn("group migration scheduler: apply failed")
+		}
+		timer := time.NewTimer(s.interval)
+		select {
+		case <-ctx.Done():
+			if !timer.Stop() {
+				<-timer.C
+			}
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// loadMigratableBills returns paid, enabled bills still running at the boundary that grant one of the old groups.
+func loadMigratableBills(tx *gorm.DB, migration *models.UserGroupMigration) ([]models.Bill, error) {
+	var bills []models.Bill
+	if errFind := tx.
+		Where("user_id = ? AND is_enabled = ? AND status = ?", migration.UserID, true, models.BillStatusPaid).
+		Where("period_end > ?", migration.EffectiveAt).
+		Order("id ASC").
+		Find(&bills).Error; errFind != nil {
+		return nil, fmt.Errorf("load bills: %w", errFind)
+	}
+	from := groupSet(migration.FromUserGroupID)
+	out := bills[:0]
+	for _, bill := range bills {
+		groups := bill.UserGroupID.Clean()
+		if len(groups) == 0 {
+			out = append(out, bill)
+			continue
+		}
+		for _, gid := range groups {
+			if _, ok := from[*gid]; ok {
+				out = append(out, bill)
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// planBillChange computes the change for one bill without touching the database.
+// It returns the change plus the updated old bill and, for prorate, the remainder bill.
+func planBillChange(bill *models.Bill, migration *models.UserGroupMigration) (GroupMigrationBillChange, models.Bill, *models.Bill) {
+	boundary := migration.EffectiveAt.UTC()
+	newGroups := replaceGroups(bill.UserGroupID, migration.FromUserGroupID, migration.ToUserGroupID)
+	change := GroupMigrationBillChange{
+		BillID:          bill.ID,
+		PeriodEnd:       bill.PeriodEnd,
+		UserGroupID:     bill.UserGroupID.Clean(),
+		OriginalGroupID: bill.UserGroupID.Clean(),
+	}
+	updated := *bill
+
+	// Bills that have not started yet are never split; they either move or stop.
+	notStarted := !bill.PeriodStart.Before(boundary)
+
+	switch migration.BillStrategy {
+	case models.GroupMigrationBillStrategyRetag:
+		change.Action = billActionRetagged
+		change.UserGroupID = newGroups
+		updated.UserGroupID = newGroups
+		return change, updated, nil
+	case models.GroupMigrationBillStrategyClose:
+		if notStarted {
+			change.Action = billActionDisabled
+			updated.IsEnabled = false
+			return change, updated, nil
+		}
+		change.Action = billActionClosed
+		change.PeriodEnd = boundary
+		updated.PeriodEnd = boundary
+		return change, updated, nil
+	}
+
+	if notStarted {
+		change.Action = billActionRetagged
+		change.UserGroupID = newGroups
+		updated.UserGroupID = newGroups
+		return change, updated, nil
+	}
+
+	total := bill.PeriodEnd.Sub(bill.PeriodStart)
+	ratio := 0.0
+	if total > 0 {
+		ratio = float64(bill.PeriodEnd.Sub(boundary)) / float64(total)
+	}
+	ratio = math.Min(math.Max(ratio, 0), 1)
+
+	// Carry the time-proportional share of the total quota, capped by what is actually left.
+	carriedQuota := math.Min(math.Max(bill.LeftQuota, 0), bill.TotalQuota*ratio)
+	carriedAmount := math.Round(bill.Amount*ratio*100) / 100
+
+	updated.PeriodEnd = boundary
+	updated.TotalQuota = bill.TotalQuota - carriedQuota
+	updated.LeftQuota = math.Max(bill.LeftQuota-carriedQuota, 0)
+	updated.Amount = bill.Amount - carriedAmount
+
+	remainder := models.Bill{
+		PlanID:      bill.PlanID,
+		UserID:      bill.UserID,
+		UserGroupID: newGroups,
+		PeriodType:  bill.PeriodType,
+		Amount:      carriedAmount,
+		PeriodStart: boundary,
+		PeriodEnd:   bill.PeriodEnd,
+		TotalQuota:  carriedQuota,
+		DailyQuota:  bill.DailyQuota,
+		LeftQuota:   carriedQuota,
+		RateLimit:   bill.RateLimit,
+		IsEnabled:   true,
+		Status:      bill.Status,
+	}
+
+	change.Action = billActionProrated
+	change.PeriodEnd = boundary
+	change.CarriedQuota = carriedQuota
+	change.CarriedAmount = carriedAmount
+	change.RemainingRatio = ratio
+	return change, updated, &remainder
+}
+
+func applyBillChange(tx *gorm.DB, bill *models.Bill, migration *models.UserGroupMigration) (GroupMigrationBillChange, error) {
+	change, updated, remainder := planBillChange(bill, migration)
+	now := time.Now().UTC()
+	if errUpdate := tx.Model(&models.Bill{}).
+		Where("id = ?", bill.ID).
+		Updates(map[string]any{
+			"user_group_id": updated.UserGroupID.Clean(),
+			"period_end":    updated.PeriodEnd,
+			"total_quota":   updated.TotalQuota,
+			"left_quota":    updated.LeftQuota,
+			"amount":        updated.Amount,
+			"is_enabled":    updated.IsEnabled,
+			"updated_at":    now,
+		}).Error; errUpdate != nil {
+		return change, fmt.Errorf("update bill %d: %w", bill.ID, errUpdate)
+	}
+	if remainder != nil {
+		remainder.CreatedAt = now
+		remainder.UpdatedAt = now
+		if errCreate := tx.Create(remainder).Error; errCreate != nil {
+			return change, fmt.Errorf("create remainder for bill %d: %w", bill.ID, errCreate)
+		}
+		change.NewBillID = remainder.ID
+	}
+	return change, nil
+}
+
+// replaceGroups drops the old groups from ids and appends the new ones, keeping other groups.
+func replaceGroups(ids, from, to models.UserGroupIDs) models.UserGroupIDs {
+	drop := groupSet(from)
+	for gid := range groupSet(to) {
+		delete(drop, gid)
+	}
+	seen := make(map[uint64]struct{})
+	out := make(models.UserGroupIDs, 0, len(ids)+len(to))
+	for _, list := range []models.UserGroupIDs{ids, to} {
+		for _, gid := range list.Clean() {
+			if _, skip := drop[*gid]; skip {
+				continue
+			}
+			if _, ok := seen[*gid]; ok {
+				continue
+			}
+			seen[*gid] = struct{}{}
+			idCopy := *gid
+			out = append(out, &idCopy)
+		}
+	}
+	return out.Clean()
+}
+
+func groupSet(ids models.UserGroupIDs) map[uint64]struct{} {
+	set := make(map[uint64]struct{}, len(ids))
+	for _, gid := range ids.Clean() {
+		set[*gid] = struct{}{}
+	}
+	return set
+}
+
+// refreshBillUserGroupIDs mirrors the usage and front handlers helper so bill_user_group_id
+// matches the bills that are active after the migration.
+func refreshBillUserGroupIDs(ctx context.Context, tx *gorm.DB, userID uint64) error {
+	now := time.Now().UTC()
+	var bills []models.Bill
+	if errFind := tx.WithContext(ctx).
+		Model(&models.Bill{}).
+		Select("user_group_id").
+		Where("user_id = ? AND is_enabled = ? AND status = ? AND left_quota > 0", userID, true, models.BillStatusPaid).
+		Where("period_start <= ? AND period_end >= ?", now, now).
+		Find(&bills).Error; errFind != nil {
+		return errFind
+	}
+
+	seen := make(map[uint64]struct{})
+	merged := make(models.UserGroupIDs, 0)
+	for _, bill := range bills {
+		for _, gid := range bill.UserGroupID.Clean() {
+			if gid == nil || *gid == 0 {
+				continue
+			}
+			if _, ok := seen[*gid]; ok {
+				continue
+			}
+			seen[*gid] = struct{}{}
+			idCopy := *gid
+			merged = append(merged, &idCopy)
+		}
+	}
+
+	return tx.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ?", userID).
+		Update("bill_user_group_id", merged.Clean()).Error
+}
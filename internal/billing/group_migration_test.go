@@ -0,0 +1,168 @@
+package billing
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func setupGroupMigrationDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:billing_group_migration_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func groupIDs(ids ...uint64) models.UserGroupIDs {
+	out := make(models.UserGroupIDs, 0, len(ids))
+	for i := range ids {
+		id := ids[i]
+		out = append(out, &id)
+	}
+	return out
+}
+
+func seedMigrationUserAndBill(t *testing.T, conn *gorm.DB, start, end time.Time) (models.User, models.Bill) {
+	t.Helper()
+	user := models.User{
+		Username:    "migrate-user",
+		Email:       "migrate-user@example.com",
+		Password:    "x",
+		UserGroupID: groupIDs(1),
+	}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+	plan := models.Plan{Name: "Pro", MonthPrice: 30, UserGroupID: groupIDs(1)}
+	if errCreate := conn.Create(&plan).Error; errCreate != nil {
+		t.Fatalf("create plan: %v", errCreate)
+	}
+	bill := models.Bill{
+		PlanID:      plan.ID,
+		UserID:      user.ID,
+		UserGroupID: groupIDs(1),
+		PeriodType:  models.BillPeriodTypeMonthly,
+		Amount:      30,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		TotalQuota:  300,
+		UsedQuota:   50,
+		LeftQuota:   250,
+		IsEnabled:   true,
+		Status:      models.BillStatusPaid,
+	}
+	if errCreate := conn.Create(&bill).Error; errCreate != nil {
+		t.Fatalf("create bill: %v", errCreate)
+	}
+	return user, bill
+}
+
+func TestApplyGroupMigration_ProrateSplitsBill(t *testing.T) {
+	conn := setupGroupMigrationDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	start := now.AddDate(0, 0, -10)
+	end := now.AddDate(0, 0, 20)
+	user, bill := seedMigrationUserAndBill(t, conn, start, end)
+
+	migration := models.UserGroupMigration{
+		UserID:          user.ID,
+		FromUserGroupID: groupIDs(1),
+		ToUserGroupID:   groupIDs(2),
+		BillStrategy:    models.GroupMigrationBillStrategyProrate,
+		EffectiveAt:     now,
+	}
+	if errSchedule := ScheduleGroupMigration(ctx, conn, &migration); errSchedule != nil {
+		t.Fatalf("schedule: %v", errSchedule)
+	}
+	if migration.Status != models.GroupMigrationStatusApplied {
+		t.Fatalf("expected applied status, got %q (%s)", migration.Status, migration.LastError)
+	}
+
+	var oldBill models.Bill
+	if errFind := conn.First(&oldBill, bill.ID).Error; errFind != nil {
+		t.Fatalf("load old bill: %v", errFind)
+	}
+	if !oldBill.PeriodEnd.Equal(now) {
+		t.Fatalf("expected old bill to end at boundary, got %s", oldBill.PeriodEnd)
+	}
+
+	var remainder models.Bill
+	if errFind := conn.Where("user_id = ? AND id <> ?", user.ID, bill.ID).First(&remainder).Error; errFind != nil {
+		t.Fatalf("load remainder bill: %v", errFind)
+	}
+	if got := remainder.UserGroupID.Values(); len(got) != 1 || got[0] != 2 {
+		t.Fatalf("expected remainder bill group [2], got %v", got)
+	}
+	if math.Abs(remainder.LeftQuota-200) > 0.01 || math.Abs(remainder.Amount-20) > 0.01 {
+		t.Fatalf("unexpected remainder quota/amount: %v/%v", remainder.LeftQuota, remainder.Amount)
+	}
+	if math.Abs(oldBill.LeftQuota+remainder.LeftQuota-bill.LeftQuota) > 0.01 {
+		t.Fatalf("left quota not conserved: %v + %v", oldBill.LeftQuota, remainder.LeftQuota)
+	}
+
+	var updated models.User
+	if errFind := conn.First(&updated, user.ID).Error; errFind != nil {
+		t.Fatalf("load user: %v", errFind)
+	}
+	if got := updated.UserGroupID.Values(); len(got) != 1 || got[0] != 2 {
+		t.Fatalf("expected user group [2], got %v", got)
+	}
+	if got := updated.BillUserGroupID.Values(); len(got) != 1 || got[0] != 2 {
+		t.Fatalf("expected bill user group [2], got %v", got)
+	}
+}
+
+func TestScheduleGroupMigration_FutureBoundaryWaits(t *testing.T) {
+	conn := setupGroupMigrationDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	user, bill := seedMigrationUserAndBill(t, conn, now.AddDate(0, 0, -1), now.AddDate(0, 0, 29))
+
+	migration := models.UserGroupMigration{
+		UserID:          user.ID,
+		FromUserGroupID: groupIDs(1),
+		ToUserGroupID:   groupIDs(3),
+		BillStrategy:    models.GroupMigrationBillStrategyClose,
+		EffectiveAt:     now.Add(time.Hour),
+	}
+	if errSchedule := ScheduleGroupMigration(ctx, conn, &migration); errSchedule != nil {
+		t.Fatalf("schedule: %v", errSchedule)
+	}
+	if migration.Status != models.GroupMigrationStatusScheduled {
+		t.Fatalf("expected scheduled status, got %q", migration.Status)
+	}
+
+	applied, errApply := ApplyDueGroupMigrations(ctx, conn, now)
+	if errApply != nil || applied != 0 {
+		t.Fatalf("expected nothing due, got %d (%v)", applied, errApply)
+	}
+	applied, errApply = ApplyDueGroupMigrations(ctx, conn, now.Add(2*time.Hour))
+	if errApply != nil || applied != 1 {
+		t.Fatalf("expected one applied, got %d (%v)", applied, errApply)
+	}
+
+	var closed models.Bill
+	if errFind := conn.First(&closed, bill.ID).Error; errFind != nil {
+		t.Fatalf("load bill: %v", errFind)
+	}
+	if !closed.PeriodEnd.Equal(migration.EffectiveAt) {
+		t.Fatalf("expected bill closed at %s, got %s", migration.EffectiveAt, closed.PeriodEnd)
+	}
+	if errCancel := CancelGroupMigration(ctx, conn, migration.ID); errCancel != ErrGroupMigrationNotScheduled {
+		t.Fatalf("expected not scheduled error, got %v", errCancel)
+	}
+}
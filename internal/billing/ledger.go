@@ -0,0 +1,222 @@
+package billing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LedgerActorSystem marks ledger entries written by automatic deductions.
+const LedgerActorSystem = "system"
+
+var (
+	// ErrInvalidAdjustment is returned when an adjustment request is malformed.
+	ErrInvalidAdjustment = errors.New("invalid balance adjustment")
+	// ErrInsufficientBillQuota is returned when a debit exceeds a bill's remaining quota.
+	ErrInsufficientBillQuota = errors.New("insufficient bill quota")
+)
+
+// LedgerRef describes the cause of a balance change recorded in the balance_transactions ledger.
+type LedgerRef struct {
+	Kind    models.BalanceTransactionKind // Reason category.
+	BillID  *uint64                       // Related bill, e.g. the bill a prepaid payment bought.
+	UsageID *uint64                       // Usage row that caused the change.
+	Reason  string                        // Free-form explanation.
+	Actor   string                        // "system" or "admin:<username>"; empty means system.
+}
+
+// RecordPrepaidTransaction writes a ledger entry for a change to a prepaid card balance.
+func RecordPrepaidTransaction(ctx context.Context, tx *gorm.DB, userID, cardID uint64, amount, balanceAfter float64, ref LedgerRef) (*models.BalanceTransaction, error) {
+	entry := newLedgerEntry(userID, models.BalanceTransactionTargetPrepaid, amount, balanceAfter, ref)
+	entry.PrepaidCardID = &cardID
+	if errCreate := tx.WithContext(ctx).Create(&entry).Error; errCreate != nil {
+		return nil, errCreate
+	}
+	return &entry, nil
+}
+
+// RecordBillTransaction writes a ledger entry for a change to a bill's remaining quota.
+func RecordBillTransaction(ctx context.Context, tx *gorm.DB, userID, billID uint64, amount, leftAfter float64, ref LedgerRef) (*models.BalanceTransaction, error) {
+	entry := newLedgerEntry(userID, models.BalanceTransactionTargetBill, amount, leftAfter, ref)
+	entry.BillID = &billID
+	if errCreate := tx.WithContext(ctx).Create(&entry).Error; errCreate != nil {
+		return nil, errCreate
+	}
+	return &entry, nil
+}
+
+func newLedgerEntry(userID uint64, target models.BalanceTransactionTarget, amount, balanceAfter float64, ref LedgerRef) models.BalanceTransaction {
+	actor := strings.TrimSpace(ref.Actor)
+	if actor == "" {
+		actor = LedgerActorSystem
+	}
+	return models.BalanceTransaction{
+		UserID:       userID,
+		Target:       target,
+		Kind:         ref.Kind,
+		Amount:       amount,
+		BalanceAfter: balanceAfter,
+		BillID:       ref.BillID,
+		UsageID:      ref.UsageID,
+		Reason:       strings.TrimSpace(ref.Reason),
+		Actor:        actor,
+	}
+}
+
+// Adjustment describes a manual credit or debit of a user's prepaid balance or bill quota.
+type Adjustment struct {
+	UserID        uint64                          // User whose balance changes.
+	Target        models.BalanceTransactionTarget // Prepaid balance or bill quota.
+	Kind          models.BalanceTransactionKind   // Adjustment or refund.
+	Amount        float64                         // Signed change; positive credits, negative debits.
+	BillID        uint64                          // Bill to adjust; required for bill adjustments.
+	PrepaidCardID uint64                          // Card to adjust; optional for prepaid adjustments.
+	Reason        string                          // Required explanation.
+	Actor         string                          // Admin performing the change.
+}
+
+// AdjustBalance applies a manual adjustment and records it in the ledger. Prepaid credits go to
+// the given card or, without one, to a new redeemed card; prepaid debits without a card spend
+// cards in the usual order. Bill adjustments move the bill's total and remaining quota together.
+func AdjustBalance(ctx context.Context, db *gorm.DB, adj Adjustment, now time.Time) ([]models.BalanceTransaction, error) {
+	if adj.UserID == 0 || adj.Amount == 0 || math.IsNaN(adj.Amount) || math.IsInf(adj.Amount, 0) || strings.TrimSpace(adj.Reason) == "" {
+		return nil, ErrInvalidAdjustment
+	}
+	if adj.Kind != models.BalanceTransactionKindAdjustment && adj.Kind != models.BalanceTransactionKindRefund {
+		return nil, ErrInvalidAdjustment
+	}
+	ref := LedgerRef{Kind: adj.Kind, Reason: adj.Reason, Actor: adj.Actor}
+
+	var entries []models.BalanceTransaction
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if errUser := tx.Select("id").First(&models.User{}, adj.UserID).Error; errUser != nil {
+			return errUser
+		}
+		var errAdjust error
+		switch adj.Target {
+		case models.BalanceTransactionTargetPrepaid:
+			entries, errAdjust = adjustPrepaid(ctx, tx, adj, ref, now)
+		case models.BalanceTransactionTargetBill:
+			entries, errAdjust = adjustBill(ctx, tx, adj, ref, now)
+		default:
+			errAdjust = ErrInvalidAdjustment
+		}
+		return errAdjust
+	})
+	if errTx != nil {
+		return nil, errTx
+	}
+	return entries, nil
+}
+
+func adjustPrepaid(ctx context.Context, tx *gorm.DB, adj Adjustment, ref LedgerRef, now time.Time) ([]models.BalanceTransaction, error) {
+	if adj.PrepaidCardID == 0 {
+		if adj.Amount < 0 {
+			return deductPrepaid(ctx, tx, adj.UserID, -adj.Amount, now, ref)
+		}
+		password, errPassword := ledgerRandomHex(8)
+		if errPassword != nil {
+			return nil, errPassword
+		}
+		name := "Balance adjustment"
+		if adj.Kind == models.BalanceTransactionKindRefund {
+			name = "Refund"
+		}
+		userID := adj.UserID
+		redeemedAt := now
+		card := models.PrepaidCard{
+			Name:           name,
+			CardSN:         fmt.Sprintf("ADJ-%d-%d", adj.UserID, now.UnixNano()),
+			Password:       password,
+			Amount:         adj.Amount,
+			Balance:        adj.Amount,
+			IsEnabled:      true,
+			RedeemedUserID: &userID,
+			CreatedAt:      now,
+			RedeemedAt:     &redeemedAt,
+		}
+		if errCreate := tx.WithContext(ctx).Create(&card).Error; errCreate != nil {
+			return nil, errCreate
+		}
+		return recordOne(RecordPrepaidTransaction(ctx, tx, adj.UserID, card.ID, adj.Amount, card.Balance, ref))
+	}
+
+	var card models.PrepaidCard
+	if errFind := tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND redeemed_user_id = ?", adj.PrepaidCardID, adj.UserID).
+		First(&card).Error; errFind != nil {
+		return nil, errFind
+	}
+	if card.Balance+adj.Amount < -prepaidBalanceEpsilon {
+		return nil, ErrInsufficientPrepaidBalance
+	}
+	balanceAfter := math.Max(card.Balance+adj.Amount, 0)
+	if errUpdate := tx.WithContext(ctx).
+		Model(&models.PrepaidCard{}).
+		Where("id = ?", card.ID).
+		Update("balance", balanceAfter).Error; errUpdate != nil {
+		return nil, errUpdate
+	}
+	return recordOne(RecordPrepaidTransaction(ctx, tx, adj.UserID, card.ID, balanceAfter-card.Balance, balanceAfter, ref))
+}
+
+func adjustBill(ctx context.Context, tx *gorm.DB, adj Adjustment, ref LedgerRef, now time.Time) ([]models.BalanceTransaction, error) {
+	if adj.BillID == 0 {
+		return nil, ErrInvalidAdjustment
+	}
+	var bill models.Bill
+	if errFind := tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND user_id = ?", adj.BillID, adj.UserID).
+		First(&bill).Error; errFind != nil {
+		return nil, errFind
+	}
+	leftAfter := bill.LeftQuota + adj.Amount
+	if leftAfter < 0 {
+		return nil, ErrInsufficientBillQuota
+	}
+	if errUpdate := tx.WithContext(ctx).
+		Model(&models.Bill{}).
+		Where("id = ?", bill.ID).
+		Updates(map[string]any{
+			"total_quota": math.Max(bill.TotalQuota+adj.Amount, 0),
+			"left_quota":  leftAfter,
+			"updated_at":  now,
+		}).Error; errUpdate != nil {
+		return nil, errUpdate
+	}
+	entry, errRecord := RecordBillTransaction(ctx, tx, adj.UserID, bill.ID, adj.Amount, leftAfter, ref)
+	if errRecord != nil {
+		return nil, errRecord
+	}
+	// Crossing zero remaining quota changes which user groups the bill grants.
+	if errRefresh := refreshBillUserGroupIDs(ctx, tx, adj.UserID); errRefresh != nil {
+		return nil, errRefresh
+	}
+	return []models.BalanceTransaction{*entry}, nil
+}
+
+func recordOne(entry *models.BalanceTransaction, err error) ([]models.BalanceTransaction, error) {
+	if err != nil {
+		return nil, err
+	}
+	return []models.BalanceTransaction{*entry}, nil
+}
+
+func ledgerRandomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, errRead := rand.Read(buf); errRead != nil {
+		return "", fmt.Errorf("billing: random: %w", errRead)
+	}
+	return hex.EncodeToString(buf), nil
+}
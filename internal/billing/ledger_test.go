@@ -0,0 +1,64 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+)
+
+func TestAdjustBalance(t *testing.T) {
+	conn := setupRenewalDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	_, bill := seedRenewalBill(t, conn, "ledger", models.BillRenewalModeManual, now.Add(time.Hour))
+
+	if _, errAdjust := AdjustBalance(ctx, conn, Adjustment{UserID: bill.UserID, Target: models.BalanceTransactionTargetPrepaid, Kind: models.BalanceTransactionKindAdjustment, Amount: 5}, now); !errors.Is(errAdjust, ErrInvalidAdjustment) {
+		t.Fatalf("expected reason to be required, got %v", errAdjust)
+	}
+
+	credit, errCredit := AdjustBalance(ctx, conn, Adjustment{
+		UserID: bill.UserID, Target: models.BalanceTransactionTargetPrepaid, Kind: models.BalanceTransactionKindRefund,
+		Amount: 8, Reason: "outage refund", Actor: "admin:root",
+	}, now)
+	if errCredit != nil || len(credit) != 1 || credit[0].Amount != 8 || credit[0].Actor != "admin:root" || credit[0].PrepaidCardID == nil {
+		t.Fatalf("unexpected credit %+v err=%v", credit, errCredit)
+	}
+
+	debit, errDebit := AdjustBalance(ctx, conn, Adjustment{
+		UserID: bill.UserID, Target: models.BalanceTransactionTargetPrepaid, Kind: models.BalanceTransactionKindAdjustment,
+		Amount: -3, Reason: "correction",
+	}, now)
+	if errDebit != nil || len(debit) != 1 || debit[0].Amount != -3 || math.Abs(debit[0].BalanceAfter-5) > 1e-9 {
+		t.Fatalf("unexpected debit %+v err=%v", debit, errDebit)
+	}
+	if _, errOver := AdjustBalance(ctx, conn, Adjustment{
+		UserID: bill.UserID, Target: models.BalanceTransactionTargetPrepaid, Kind: models.BalanceTransactionKindAdjustment,
+		Amount: -50, Reason: "too much",
+	}, now); !errors.Is(errOver, ErrInsufficientPrepaidBalance) {
+		t.Fatalf("expected ErrInsufficientPrepaidBalance, got %v", errOver)
+	}
+
+	quota, errQuota := AdjustBalance(ctx, conn, Adjustment{
+		UserID: bill.UserID, Target: models.BalanceTransactionTargetBill, Kind: models.BalanceTransactionKindAdjustment,
+		Amount: 25, BillID: bill.ID, Reason: "goodwill",
+	}, now)
+	if errQuota != nil || len(quota) != 1 || quota[0].BillID == nil || *quota[0].BillID != bill.ID || quota[0].BalanceAfter != bill.LeftQuota+25 {
+		t.Fatalf("unexpected bill adjustment %+v err=%v", quota, errQuota)
+	}
+	if _, errOver := AdjustBalance(ctx, conn, Adjustment{
+		UserID: bill.UserID, Target: models.BalanceTransactionTargetBill, Kind: models.BalanceTransactionKindAdjustment,
+		Amount: -1000, BillID: bill.ID, Reason: "too much",
+	}, now); !errors.Is(errOver, ErrInsufficientBillQuota) {
+		t.Fatalf("expected ErrInsufficientBillQuota, got %v", errOver)
+	}
+
+	var count int64
+	conn.Model(&models.BalanceTransaction{}).Where("user_id = ?", bill.UserID).Count(&count)
+	if count != 3 {
+		t.Fatalf("expected 3 ledger entries, got %d", count)
+	}
+}
@@ -0,0 +1,155 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// BurnRateWindowDays is how many trailing days of spend the balance projection averages.
+const BurnRateWindowDays = 7
+
+// BillBalance is the remaining quota of one active bill.
+type BillBalance struct {
+	ID          uint64    `json:"id"`
+	PlanID      uint64    `json:"plan_id"`
+	PlanName    string    `json:"plan_name"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	TotalQuota  float64   `json:"total_quota"`
+	UsedQuota   float64   `json:"used_quota"`
+	LeftQuota   float64   `json:"left_quota"`
+	DailyQuota  float64   `json:"daily_quota"` // Zero when the bill has no daily cap.
+	AutoRenew   bool      `json:"auto_renew"`
+}
+
+// PrepaidBalance is the remaining balance of one redeemed prepaid card.
+type PrepaidBalance struct {
+	ID         uint64     `json:"id"`
+	Name       string     `json:"name"`
+	CardSN     string     `json:"card_sn"`
+	Balance    float64    `json:"balance"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RedeemedAt *time.Time `json:"redeemed_at"`
+}
+
+// BalanceOverview answers how much a user can still spend and for how long.
+type BalanceOverview struct {
+	Bills          []BillBalance    `json:"bills"`           // Paid, enabled bills whose period covers now.
+	PrepaidCards   []PrepaidBalance `json:"prepaid_cards"`   // Redeemed, unexpired cards with balance, expiring first.
+	Daily          DailyBillState   `json:"daily"`           // Aggregated daily cap of the active bills.
+	BillLeftQuota  float64          `json:"bill_left_quota"` // Quota left across active bills.
+	PrepaidBalance float64          `json:"prepaid_balance"` // Balance left across prepaid cards.
+	TotalRemaining float64          `json:"total_remaining"` // Bill quota plus prepaid balance.
+	TodaySpend     float64          `json:"today_spend"`     // Usage cost since local midnight.
+
+	BurnRatePerDay         float64    `json:"burn_rate_per_day"`        // Average daily spend over the trailing window.
+	BurnRateWindowDays     int        `json:"burn_rate_window_days"`    // Length of the trailing window.
+	ProjectedDaysRemaining *float64   `json:"projected_days_remaining"` // Nil when nothing was spent in the window.
+	ProjectedDepletionAt   *time.Time `json:"projected_depletion_at"`   // Nil when nothing was spent in the window.
+}
+
+// LoadBalanceOverview gathers the bills, prepaid cards and recent spend of a user and
+// projects when the remaining balance runs out at the trailing average burn rate. The
+// projection ignores bill period ends and daily caps.
+func LoadBalanceOverview(ctx context.Context, db *gorm.DB, userID uint64, now time.Time) (*BalanceOverview, error) {
+	if db == nil {
+		return nil, errors.New("nil db")
+	}
+	nowUTC := now.UTC()
+	overview := &BalanceOverview{
+		Bills:              make([]BillBalance, 0),
+		PrepaidCards:       make([]PrepaidBalance, 0),
+		BurnRateWindowDays: BurnRateWindowDays,
+	}
+
+	daily, errDaily := LoadDailyBillState(ctx, db, userID, now)
+	if errDaily != nil {
+		return nil, errDaily
+	}
+	overview.Daily = daily
+
+	var bills []models.Bill
+	if errBills := db.WithContext(ctx).
+		Preload("Plan").
+		Where("user_id = ? AND is_enabled = ? AND status = ?", userID, true, models.BillStatusPaid).
+		Where("period_start <= ? AND period_end >= ?", nowUTC, nowUTC).
+		Order("period_end ASC, id ASC").
+		Find(&bills).Error; errBills != nil {
+		return nil, errBills
+	}
+	for _, bill := range bills {
+		overview.Bills = append(overview.Bills, BillBalance{
+			ID:          bill.ID,
+			PlanID:      bill.PlanID,
+			PlanName:    bill.Plan.Name,
+			PeriodStart: bill.PeriodStart,
+			PeriodEnd:   bill.PeriodEnd,
+			TotalQuota:  bill.TotalQuota,
+			UsedQuota:   bill.UsedQuota,
+			LeftQuota:   bill.LeftQuota,
+			DailyQuota:  bill.DailyQuota,
+			AutoRenew:   bill.AutoRenew,
+		})
+		if bill.LeftQuota > 0 {
+			overview.BillLeftQuota += bill.LeftQuota
+		}
+	}
+
+	var cards []models.PrepaidCard
+	if errCards := db.WithContext(ctx).
+		Where("redeemed_user_id = ? AND is_enabled = ? AND balance > 0 AND redeemed_at IS NOT NULL", userID, true).
+		Where("(expires_at IS NULL OR expires_at >= ?)", nowUTC).
+		Order(dbutil.NullsLastOrder(db, "expires_at", "ASC")).
+		Order("id ASC").
+		Find(&cards).Error; errCards != nil {
+		return nil, errCards
+	}
+	for _, card := range cards {
+		overview.PrepaidCards = append(overview.PrepaidCards, PrepaidBalance{
+			ID:         card.ID,
+			Name:       card.Name,
+			CardSN:     card.CardSN,
+			Balance:    card.Balance,
+			ExpiresAt:  card.ExpiresAt,
+			RedeemedAt: card.RedeemedAt,
+		})
+		overview.PrepaidBalance += card.Balance
+	}
+	overview.TotalRemaining = overview.BillLeftQuota + overview.PrepaidBalance
+
+	todayStart := NextDailyReset(now).AddDate(0, 0, -1)
+	windowStart := nowUTC.AddDate(0, 0, -BurnRateWindowDays)
+	var spend struct {
+		Today  int64 `gorm:"column:today"`
+		Window int64 `gorm:"column:window_total"`
+	}
+	if errSpend := db.WithContext(ctx).
+		Model(&models.Usage{}).
+		Select("COALESCE(SUM(CASE WHEN requested_at >= ? THEN cost_micros ELSE 0 END), 0) AS today, COALESCE(SUM(CASE WHEN requested_at >= ? THEN cost_micros ELSE 0 END), 0) AS window_total", todayStart, windowStart).
+		Where("user_id = ? AND requested_at >= ?", userID, earliest(todayStart, windowStart)).
+		Scan(&spend).Error; errSpend != nil {
+		return nil, errSpend
+	}
+	overview.TodaySpend = float64(spend.Today) / 1_000_000
+	overview.BurnRatePerDay = float64(spend.Window) / 1_000_000 / BurnRateWindowDays
+	if overview.BurnRatePerDay > 0 {
+		days := overview.TotalRemaining / overview.BurnRatePerDay
+		depletesAt := nowUTC.Add(time.Duration(days * float64(24*time.Hour)))
+		overview.ProjectedDaysRemaining = &days
+		overview.ProjectedDepletionAt = &depletesAt
+	}
+	return overview, nil
+}
+
+// earliest returns the earlier of two instants.
+func earliest(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
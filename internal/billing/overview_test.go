@@ -0,0 +1,97 @@
+package billing
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestLoadBalanceOverview(t *testing.T) {
+	dsn := fmt.Sprintf("file:billing_overview_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	ctx := context.Background()
+	now := time.Now()
+
+	user := models.User{Username: "overview-user", Email: "overview-user@example.com", Password: "x"}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+	plan := models.Plan{Name: "Pro", MonthPrice: 10}
+	if errCreate := conn.Create(&plan).Error; errCreate != nil {
+		t.Fatalf("create plan: %v", errCreate)
+	}
+	for _, bill := range []models.Bill{
+		{PlanID: plan.ID, UserID: user.ID, PeriodType: models.BillPeriodTypeMonthly, PeriodStart: now.UTC().AddDate(0, 0, -10), PeriodEnd: now.UTC().AddDate(0, 0, 20), TotalQuota: 50, UsedQuota: 30, LeftQuota: 20, IsEnabled: true, Status: models.BillStatusPaid},
+		{PlanID: plan.ID, UserID: user.ID, PeriodType: models.BillPeriodTypeMonthly, PeriodStart: now.UTC().AddDate(0, 0, -40), PeriodEnd: now.UTC().AddDate(0, 0, -10), TotalQuota: 50, LeftQuota: 50, IsEnabled: true, Status: models.BillStatusPaid},
+		{PlanID: plan.ID, UserID: user.ID, PeriodType: models.BillPeriodTypeMonthly, PeriodStart: now.UTC().AddDate(0, 0, -1), PeriodEnd: now.UTC().AddDate(0, 0, 29), TotalQuota: 50, LeftQuota: 50, IsEnabled: true, Status: models.BillStatusPending},
+	} {
+		if errCreate := conn.Create(&bill).Error; errCreate != nil {
+			t.Fatalf("create bill: %v", errCreate)
+		}
+	}
+	redeemedAt := now.UTC().AddDate(0, 0, -3)
+	expired := now.UTC().AddDate(0, 0, -1)
+	for _, card := range []models.PrepaidCard{
+		{Name: "Card", CardSN: "OV-1", Password: "x", Amount: 10, Balance: 8, IsEnabled: true, RedeemedUserID: &user.ID, RedeemedAt: &redeemedAt},
+		{Name: "Old", CardSN: "OV-2", Password: "x", Amount: 10, Balance: 10, IsEnabled: true, RedeemedUserID: &user.ID, RedeemedAt: &redeemedAt, ExpiresAt: &expired},
+	} {
+		if errCreate := conn.Create(&card).Error; errCreate != nil {
+			t.Fatalf("create card: %v", errCreate)
+		}
+	}
+	userID := user.ID
+	for _, usage := range []models.Usage{
+		{Provider: "codex", Model: "gpt-5", UserID: &userID, RequestedAt: now.UTC(), CostMicros: 1_000_000},
+		{Provider: "codex", Model: "gpt-5", UserID: &userID, RequestedAt: now.UTC().AddDate(0, 0, -3), CostMicros: 6_000_000},
+		{Provider: "codex", Model: "gpt-5", UserID: &userID, RequestedAt: now.UTC().AddDate(0, 0, -30), CostMicros: 90_000_000},
+	} {
+		if errCreate := conn.Create(&usage).Error; errCreate != nil {
+			t.Fatalf("create usage: %v", errCreate)
+		}
+	}
+
+	overview, errOverview := LoadBalanceOverview(ctx, conn, user.ID, now)
+	if errOverview != nil {
+		t.Fatalf("load overview: %v", errOverview)
+	}
+	if len(overview.Bills) != 1 || overview.Bills[0].PlanName != "Pro" || overview.BillLeftQuota != 20 {
+		t.Fatalf("unexpected bills: %+v left=%v", overview.Bills, overview.BillLeftQuota)
+	}
+	if len(overview.PrepaidCards) != 1 || overview.PrepaidBalance != 8 {
+		t.Fatalf("unexpected prepaid cards: %+v balance=%v", overview.PrepaidCards, overview.PrepaidBalance)
+	}
+	if overview.TotalRemaining != 28 || overview.TodaySpend != 1 {
+		t.Fatalf("remaining=%v today=%v, want 28 and 1", overview.TotalRemaining, overview.TodaySpend)
+	}
+	if overview.BurnRatePerDay != 1 {
+		t.Fatalf("burn rate = %v, want 1", overview.BurnRatePerDay)
+	}
+	if overview.ProjectedDaysRemaining == nil || math.Abs(*overview.ProjectedDaysRemaining-28) > 1e-9 {
+		t.Fatalf("projected days = %v, want 28", overview.ProjectedDaysRemaining)
+	}
+
+	idle := models.User{Username: "overview-idle", Email: "overview-idle@example.com", Password: "x"}
+	if errCreate := conn.Create(&idle).Error; errCreate != nil {
+		t.Fatalf("create idle user: %v", errCreate)
+	}
+	overview, errOverview = LoadBalanceOverview(ctx, conn, idle.ID, now)
+	if errOverview != nil {
+		t.Fatalf("load idle overview: %v", errOverview)
+	}
+	if overview.ProjectedDaysRemaining != nil || len(overview.Bills) != 0 || len(overview.PrepaidCards) != 0 {
+		t.Fatalf("unexpected idle overview: %+v", overview)
+	}
+}
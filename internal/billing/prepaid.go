@@ -0,0 +1,77 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// prepaidBalanceEpsilon tolerates rounding when comparing balances against prices.
+const prepaidBalanceEpsilon = 0.01
+
+// ErrInsufficientPrepaidBalance is returned when redeemed prepaid cards cannot cover an amount.
+var ErrInsufficientPrepaidBalance = errors.New("insufficient prepaid card balance")
+
+// DeductPrepaidBalance charges amount against a user's redeemed prepaid cards, spending the
+// cards that expire first, and records each card debit in the ledger under ref. It must run
+// inside a transaction so the card rows stay locked.
+func DeductPrepaidBalance(ctx context.Context, tx *gorm.DB, userID uint64, amount float64, now time.Time, ref LedgerRef) error {
+	_, errDeduct := deductPrepaid(ctx, tx, userID, amount, now, ref)
+	return errDeduct
+}
+
+func deductPrepaid(ctx context.Context, tx *gorm.DB, userID uint64, amount float64, now time.Time, ref LedgerRef) ([]models.BalanceTransaction, error) {
+	var cards []models.PrepaidCard
+	if errCards := tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("redeemed_user_id = ? AND is_enabled = ? AND balance > 0 AND redeemed_at IS NOT NULL", userID, true).
+		Where("(expires_at IS NULL OR expires_at >= ?)", now).
+		Order(dbutil.NullsLastOrder(tx, "expires_at", "ASC")).
+		Order(dbutil.NullsLastOrder(tx, "redeemed_at", "ASC")).
+		Order("id ASC").
+		Find(&cards).Error; errCards != nil {
+		return nil, errCards
+	}
+
+	totalBalance := 0.0
+	for _, card := range cards {
+		totalBalance += card.Balance
+	}
+	if totalBalance+prepaidBalanceEpsilon < amount {
+		return nil, ErrInsufficientPrepaidBalance
+	}
+
+	var entries []models.BalanceTransaction
+	remaining := amount
+	for _, card := range cards {
+		if remaining <= 0 {
+			break
+		}
+		if card.Balance <= 0 {
+			continue
+		}
+		deduct := card.Balance
+		if deduct > remaining {
+			deduct = remaining
+		}
+		res := tx.WithContext(ctx).
+			Model(&models.PrepaidCard{}).
+			Where("id = ?", card.ID).
+			Update("balance", gorm.Expr("balance - ?", deduct))
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		entry, errRecord := RecordPrepaidTransaction(ctx, tx, userID, card.ID, -deduct, card.Balance-deduct, ref)
+		if errRecord != nil {
+			return nil, errRecord
+		}
+		entries = append(entries, *entry)
+		remaining -= deduct
+	}
+	return entries, nil
+}
@@ -0,0 +1,249 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/currency"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultBillRenewalInterval = 5 * time.Minute
+	// defaultBillRenewalLeadTime is how long before period_end the next bill is created.
+	defaultBillRenewalLeadTime = 24 * time.Hour
+)
+
+var (
+	// ErrBillAlreadyRenewed is returned when the bill was renewed already or no longer auto-renews.
+	ErrBillAlreadyRenewed = errors.New("bill renewal: already renewed")
+	// ErrRenewalPlanUnavailable is returned when the bill's plan is deleted or disabled; auto-renewal is switched off.
+	ErrRenewalPlanUnavailable = errors.New("bill renewal: plan is not available")
+)
+
+// RenewBill creates the next period's bill for an auto-renewing bill. Prepaid renewals are
+// paid from the user's prepaid cards when the balance covers the amount; otherwise, and for
+// manual renewals, the new bill is created pending. The new bill keeps auto-renewing.
+func RenewBill(ctx context.Context, db *gorm.DB, billID uint64, now time.Time) (*models.Bill, error) {
+	var renewed models.Bill
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var bill models.Bill
+		if errFind := tx.First(&bill, billID).Error; errFind != nil {
+			return errFind
+		}
+		if !bill.AutoRenew || bill.RenewedBillID != nil {
+			return ErrBillAlreadyRenewed
+		}
+
+		var plan models.Plan
+		if errPlan := tx.Where("id = ? AND is_enabled = ?", bill.PlanID, true).First(&plan).Error; errPlan != nil {
+			if !errors.Is(errPlan, gorm.ErrRecordNotFound) {
+				return errPlan
+			}
+			return ErrRenewalPlanUnavailable
+		}
+
+		status := models.BillStatusPending
+		if bill.RenewalMode == models.BillRenewalModePrepaid {
+			// Prepaid balances are in USD; a bill priced in a currency without a rate stays pending.
+			amount, errAmount := billAmountUSD(ctx, tx, &bill)
+			if errAmount != nil && !errors.Is(errAmount, currency.ErrUnknownRate) {
+				return errAmount
+			}
+			errDeduct := ErrInsufficientPrepaidBalance
+			if errAmount == nil {
+				errDeduct = DeductPrepaidBalance(ctx, tx, bill.UserID, amount, now, LedgerRef{
+					Kind:   models.BalanceTransactionKindBillPayment,
+					Reason: fmt.Sprintf("auto-renewal of bill %d", bill.ID),
+				})
+			}
+			switch {
+			case errDeduct == nil:
+				status = models.BillStatusPaid
+			case !errors.Is(errDeduct, ErrInsufficientPrepaidBalance):
+				return errDeduct
+			}
+		}
+
+		periodStart := bill.PeriodEnd
+		periodEnd := periodStart.AddDate(0, 1, 0)
+		if bill.PeriodType == models.BillPeriodTypeYearly {
+			periodEnd = periodStart.AddDate(1, 0, 0)
+		}
+		renewed = models.Bill{
+			PlanID:      plan.ID,
+			UserID:      bill.UserID,
+			UserGroupID: plan.UserGroupID.Clean(),
+			PeriodType:  bill.PeriodType,
+			Amount:      bill.Amount,
+			Currency:    bill.Currency,
+			PeriodStart: periodStart,
+			PeriodEnd:   periodEnd,
+			TotalQuota:  plan.TotalQuota,
+			DailyQuota:  plan.DailyQuota,
+			LeftQuota:   plan.TotalQuota,
+			RateLimit:   plan.RateLimit,
+			IsEnabled:   true,
+			Status:      status,
+			AutoRenew:   true,
+			RenewalMode: bill.RenewalMode,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if errCreate := tx.Create(&renewed).Error; errCreate != nil {
+			return errCreate
+		}
+		// Claim the renewal on the old bill; a concurrent renewal rolls this one back.
+		res := tx.Model(&models.Bill{}).
+			Where("id = ? AND renewed_bill_id IS NULL", bill.ID).
+			Updates(map[string]any{"renewed_bill_id": renewed.ID, "updated_at": now})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrBillAlreadyRenewed
+		}
+		return nil
+	})
+	if errors.Is(errTx, ErrRenewalPlanUnavailable) {
+		// The plan is gone or retired: stop renewing instead of retrying forever.
+		if errStop := db.WithContext(ctx).Model(&models.Bill{}).Where("id = ?", billID).
+			Updates(map[string]any{"auto_renew": false, "updated_at": now}).Error; errStop != nil {
+			return nil, errStop
+		}
+	}
+	if errTx != nil {
+		return nil, errTx
+	}
+	publishBillRenewed(ctx, billID, &renewed)
+	if renewed.RenewalMode == models.BillRenewalModePrepaid && renewed.Status == models.BillStatusPending {
+		events.PublishBalanceInsufficient(ctx, renewed.UserID,
+			fmt.Sprintf("prepaid balance could not cover the %s renewal", currency.FormatAmount(renewed.Amount, renewed.Currency, 2)),
+			map[string]any{"bill_id": renewed.ID, "amount": renewed.Amount, "currency": renewed.Currency})
+	}
+	return &renewed, nil
+}
+
+// RenewDueBills renews every auto-renewing bill whose period ends within the lead time.
+func RenewDueBills(ctx context.Context, db *gorm.DB, now time.Time, leadTime time.Duration) (int, error) {
+	var ids []uint64
+	if errFind := db.WithContext(ctx).
+		Model(&models.Bill{}).
+		Where("auto_renew = ? AND renewed_bill_id IS NULL AND is_enabled = ? AND status = ?", true, true, models.BillStatusPaid).
+		Where("period_end > ? AND period_end <= ?", now.UTC(), now.UTC().Add(leadTime)).
+		Order("period_end ASC, id ASC").
+		Pluck("id", &ids).Error; errFind != nil {
+		return 0, errFind
+	}
+	renewed := 0
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return renewed, ctx.Err()
+		}
+		if _, errRenew := RenewBill(ctx, db, id, now.UTC()); errRenew != nil {
+			if !errors.Is(errRenew, ErrBillAlreadyRenewed) {
+				log.WithError(errRenew).Warnf("bill renewal: renew %d failed", id)
+			}
+			continue
+		}
+		renewed++
+	}
+	return renewed, nil
+}
+
+// publishBillRenewed notifies subscribers that a bill was renewed; pending bills are raised
+// as warnings so the user is told to pay before access lapses.
+func publishBillRenewed(ctx context.Context, previousBillID uint64, bill *models.Bill) {
+	severity := events.SeverityInfo
+	message := fmt.Sprintf("bill renewed until %s", bill.PeriodEnd.UTC().Format(time.RFC3339))
+	if bill.Status == models.BillStatusPending {
+		severity = events.SeverityWarning
+		message = fmt.Sprintf("bill renewed until %s and awaiting payment of %s", bill.PeriodEnd.UTC().Format(time.RFC3339), currency.FormatAmount(bill.Amount, bill.Currency, 2))
+	}
+	events.Publish(ctx, events.Event{
+		Type:     events.TypeBillRenewed,
+		Severity: severity,
+		Subject:  "user:" + strconv.FormatUint(bill.UserID, 10),
+		Message:  message,
+		Data: map[string]any{
+			"user_id":          bill.UserID,
+			"bill_id":          bill.ID,
+			"previous_bill_id": previousBillID,
+			"plan_id":          bill.PlanID,
+			"amount":           bill.Amount,
+			"currency":         bill.Currency,
+			"status":           bill.Status,
+			"period_start":     bill.PeriodStart,
+			"period_end":       bill.PeriodEnd,
+		},
+	})
+}
+
+// BillRenewalScheduler renews auto-renewing bills shortly before they expire.
+type BillRenewalScheduler struct {
+	db       *gorm.DB
+	interval time.Duration
+	leadTime time.Duration
+}
+
+// NewBillRenewalScheduler constructs a scheduler; returns nil when db is nil.
+func NewBillRenewalScheduler(db *gorm.DB) *BillRenewalScheduler {
+	if db == nil {
+		return nil
+	}
+	return &BillRenewalScheduler{db: db, interval: defaultBillRenewalInterval, leadTime: defaultBillRenewalLeadTime}
+}
+
+// Start launches the scheduler loop in a background goroutine.
+func (s *BillRenewalScheduler) Start(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go s.run(ctx)
+	log.Infof("bill renewal scheduler started (interval=%s, lead=%s)", s.interval, s.leadTime)
+}
+
+func (s *BillRenewalScheduler) run(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, errRenew := RenewDueBills(ctx, s.db, time.Now().UTC(), s.leadTime); errRenew != nil {
+			log.WithError(errRenew).Warn("bill renewal scheduler: renew failed")
+		}
+		timer := time.NewTimer(s.interval)
+		select {
+		case <-ctx.Done():
+			if !timer.Stop() {
+				<-timer.C
+			}
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// billAmountUSD converts a bill's amount from its currency into USD.
+func billAmountUSD(ctx context.Context, db *gorm.DB, bill *models.Bill) (float64, error) {
+	code, errCode := currency.Normalize(bill.Currency)
+	if errCode != nil {
+		return 0, errCode
+	}
+	if code == currency.Base {
+		return bill.Amount, nil
+	}
+	table, errLoad := currency.Cached(ctx, db)
+	if errLoad != nil {
+		return 0, errLoad
+	}
+	return table.Convert(bill.Amount, code, currency.Base)
+}
@@ -0,0 +1,136 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func setupRenewalDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:billing_renewal_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func seedRenewalBill(t *testing.T, conn *gorm.DB, username string, mode models.BillRenewalMode, periodEnd time.Time) (models.Plan, models.Bill) {
+	t.Helper()
+	user := models.User{Username: username, Email: username + "@example.com", Password: "x"}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+	plan := models.Plan{Name: username + "-plan", MonthPrice: 10, TotalQuota: 100, DailyQuota: 5, RateLimit: 3, UserGroupID: groupIDs(7), IsEnabled: true}
+	if errCreate := conn.Create(&plan).Error; errCreate != nil {
+		t.Fatalf("create plan: %v", errCreate)
+	}
+	bill := models.Bill{
+		PlanID:      plan.ID,
+		UserID:      user.ID,
+		UserGroupID: plan.UserGroupID,
+		PeriodType:  models.BillPeriodTypeMonthly,
+		Amount:      10,
+		PeriodStart: periodEnd.AddDate(0, -1, 0),
+		PeriodEnd:   periodEnd,
+		TotalQuota:  100,
+		LeftQuota:   20,
+		IsEnabled:   true,
+		Status:      models.BillStatusPaid,
+		AutoRenew:   true,
+		RenewalMode: mode,
+	}
+	if errCreate := conn.Create(&bill).Error; errCreate != nil {
+		t.Fatalf("create bill: %v", errCreate)
+	}
+	return plan, bill
+}
+
+func TestRenewDueBillsCreatesNextPeriod(t *testing.T) {
+	conn := setupRenewalDB(t)
+	ctx := context.Background()
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	_, prepaid := seedRenewalBill(t, conn, "renew-prepaid", models.BillRenewalModePrepaid, now.Add(6*time.Hour))
+	_, manual := seedRenewalBill(t, conn, "renew-manual", models.BillRenewalModeManual, now.Add(12*time.Hour))
+	_, later := seedRenewalBill(t, conn, "renew-later", models.BillRenewalModeManual, now.Add(72*time.Hour))
+
+	redeemedAt := now.Add(-time.Hour)
+	card := models.PrepaidCard{Name: "card", CardSN: "SN-RENEW", Password: "p", Amount: 15, Balance: 15, IsEnabled: true, RedeemedUserID: &prepaid.UserID, RedeemedAt: &redeemedAt}
+	if errCreate := conn.Create(&card).Error; errCreate != nil {
+		t.Fatalf("create card: %v", errCreate)
+	}
+
+	renewed, errRenew := RenewDueBills(ctx, conn, now, defaultBillRenewalLeadTime)
+	if errRenew != nil || renewed != 2 {
+		t.Fatalf("expected 2 renewals, got %d err=%v", renewed, errRenew)
+	}
+
+	var next models.Bill
+	if errFind := conn.Where("user_id = ? AND id <> ?", prepaid.UserID, prepaid.ID).First(&next).Error; errFind != nil {
+		t.Fatalf("find renewed bill: %v", errFind)
+	}
+	if next.Status != models.BillStatusPaid || !next.AutoRenew || next.LeftQuota != 100 || next.RateLimit != 3 ||
+		!next.PeriodStart.Equal(prepaid.PeriodEnd) || !next.PeriodEnd.Equal(prepaid.PeriodEnd.AddDate(0, 1, 0)) {
+		t.Fatalf("unexpected renewed prepaid bill: %+v", next)
+	}
+	if errReload := conn.First(&card, card.ID).Error; errReload != nil || math.Abs(card.Balance-5) > 1e-9 {
+		t.Fatalf("expected card balance 5 after renewal, got %v err=%v", card.Balance, errReload)
+	}
+
+	var manualNext models.Bill
+	if errFind := conn.Where("user_id = ? AND id <> ?", manual.UserID, manual.ID).First(&manualNext).Error; errFind != nil {
+		t.Fatalf("find renewed manual bill: %v", errFind)
+	}
+	if manualNext.Status != models.BillStatusPending {
+		t.Fatalf("expected manual renewal to be pending, got %v", manualNext.Status)
+	}
+
+	var count int64
+	conn.Model(&models.Bill{}).Where("user_id = ?", later.UserID).Count(&count)
+	if count != 1 {
+		t.Fatalf("expected bill outside the lead time to stay unrenewed, got %d bills", count)
+	}
+
+	if again, errAgain := RenewDueBills(ctx, conn, now, defaultBillRenewalLeadTime); errAgain != nil || again != 0 {
+		t.Fatalf("expected renewals to be idempotent, got %d err=%v", again, errAgain)
+	}
+	if _, errRenew := RenewBill(ctx, conn, prepaid.ID, now); !errors.Is(errRenew, ErrBillAlreadyRenewed) {
+		t.Fatalf("expected ErrBillAlreadyRenewed, got %v", errRenew)
+	}
+}
+
+func TestRenewBillFallsBackToPendingAndStopsForRetiredPlans(t *testing.T) {
+	conn := setupRenewalDB(t)
+	ctx := context.Background()
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	_, broke := seedRenewalBill(t, conn, "renew-broke", models.BillRenewalModePrepaid, now.Add(time.Hour))
+	next, errRenew := RenewBill(ctx, conn, broke.ID, now)
+	if errRenew != nil || next.Status != models.BillStatusPending {
+		t.Fatalf("expected pending renewal without balance, got %+v err=%v", next, errRenew)
+	}
+
+	plan, retired := seedRenewalBill(t, conn, "renew-retired", models.BillRenewalModeManual, now.Add(time.Hour))
+	if errUpdate := conn.Model(&plan).Update("is_enabled", false).Error; errUpdate != nil {
+		t.Fatalf("disable plan: %v", errUpdate)
+	}
+	if _, errRenew = RenewBill(ctx, conn, retired.ID, now); !errors.Is(errRenew, ErrRenewalPlanUnavailable) {
+		t.Fatalf("expected ErrRenewalPlanUnavailable, got %v", errRenew)
+	}
+	if errReload := conn.First(&retired, retired.ID).Error; errReload != nil || retired.AutoRenew {
+		t.Fatalf("expected auto_renew to be switched off, got %v err=%v", retired.AutoRenew, errReload)
+	}
+}
@@ -0,0 +1,90 @@
+package billing
+
+import (
+	"context"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// RuleSet holds a fixed list of billing rules, such as a snapshot of past rules, for pricing
+// requests with ExplainCostWithRules. It caches group lookups and tracks the tiered volume it
+// has priced, so requests must be priced in chronological order. A RuleSet is not safe for
+// concurrent use.
+type RuleSet struct {
+	rules []models.BillingRule
+
+	authGroups map[uint64]*uint64      // Primary auth group per auth ID.
+	ruleGroups map[uint64][]uint64     // User groups whose rules price each user group, in order.
+	volumes    map[tierVolumeKey]int64 // Tokens priced per user, tiered rule and month.
+
+	defaultsLoaded     bool
+	defaultAuthGroupID *uint64
+	defaultUserGroupID *uint64
+}
+
+type tierVolumeKey struct {
+	userID     uint64
+	ruleID     uint64
+	monthReset int64
+}
+
+// NewRuleSet constructs a rule set pricing against rules.
+func NewRuleSet(rules []models.BillingRule) *RuleSet {
+	return &RuleSet{
+		rules:      rules,
+		authGroups: make(map[uint64]*uint64),
+		ruleGroups: make(map[uint64][]uint64),
+		volumes:    make(map[tierVolumeKey]int64),
+	}
+}
+
+func (s *RuleSet) authGroupID(ctx context.Context, db *gorm.DB, authID uint64) *uint64 {
+	if groupID, ok := s.authGroups[authID]; ok {
+		return groupID
+	}
+	var groupID *uint64
+	var auth models.Auth
+	if errFindAuth := db.WithContext(ctx).Select("auth_group_id").First(&auth, authID).Error; errFindAuth == nil {
+		groupID = auth.AuthGroupID.Primary()
+	}
+	s.authGroups[authID] = groupID
+	return groupID
+}
+
+func (s *RuleSet) ruleUserGroupIDs(ctx context.Context, db *gorm.DB, userGroupID uint64) ([]uint64, error) {
+	if groupIDs, ok := s.ruleGroups[userGroupID]; ok {
+		return groupIDs, nil
+	}
+	groupIDs, errGroups := resolveRuleUserGroupIDs(ctx, db, userGroupID)
+	if errGroups != nil {
+		return nil, errGroups
+	}
+	s.ruleGroups[userGroupID] = groupIDs
+	return groupIDs, nil
+}
+
+func (s *RuleSet) defaultGroupIDs(ctx context.Context, db *gorm.DB) (*uint64, *uint64, error) {
+	if s.defaultsLoaded {
+		return s.defaultAuthGroupID, s.defaultUserGroupID, nil
+	}
+	authGroupID, errAuthGroup := ResolveDefaultAuthGroupID(ctx, db)
+	if errAuthGroup != nil {
+		return nil, nil, errAuthGroup
+	}
+	userGroupID, errUserGroup := ResolveDefaultUserGroupID(ctx, db)
+	if errUserGroup != nil {
+		return nil, nil, errUserGroup
+	}
+	s.defaultAuthGroupID, s.defaultUserGroupID, s.defaultsLoaded = authGroupID, userGroupID, true
+	return authGroupID, userGroupID, nil
+}
+
+// consumeTierVolume returns the month-to-date volume before this request and adds its tokens.
+func (s *RuleSet) consumeTierVolume(ruleID, userID uint64, at time.Time, tokens int64) int64 {
+	key := tierVolumeKey{userID: userID, ruleID: ruleID, monthReset: NextMonthlyReset(at).Unix()}
+	prior := s.volumes[key]
+	s.volumes[key] = prior + tokens
+	return prior
+}
@@ -0,0 +1,155 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/usergroup"
+	"gorm.io/gorm"
+)
+
+// ErrSpendLimitReached indicates the user's cumulative cost hit a configured spend limit.
+var ErrSpendLimitReached = errors.New("spend limit reached")
+
+// Spend limit periods.
+const (
+	SpendPeriodDaily   = "daily"
+	SpendPeriodMonthly = "monthly"
+)
+
+// SpendLimitError reports a reached spend limit together with the time requests resume.
+type SpendLimitError struct {
+	Period   string    // SpendPeriodDaily or SpendPeriodMonthly.
+	Limit    float64   // The limit that was reached.
+	ResetsAt time.Time // Start of the next period.
+}
+
+// Error implements error.
+func (e *SpendLimitError) Error() string {
+	if e == nil {
+		return ErrSpendLimitReached.Error()
+	}
+	return fmt.Sprintf("%s %s of %.2f, resets at %s", e.Period, ErrSpendLimitReached.Error(), e.Limit, e.ResetsAt.Format(time.RFC3339))
+}
+
+// Unwrap returns ErrSpendLimitReached so callers can match with errors.Is.
+func (e *SpendLimitError) Unwrap() error { return ErrSpendLimitReached }
+
+// SpendLimitState summarizes a user's spend against the effective daily and monthly limits.
+// A zero limit means the period is unlimited and its remaining budget is not reported.
+type SpendLimitState struct {
+	DailyLimit       float64   `json:"daily_limit"`       // Effective daily limit; zero when unlimited.
+	DailySpent       float64   `json:"daily_spent"`       // Cost since local midnight.
+	DailyRemaining   float64   `json:"daily_remaining"`   // Budget left today; zero when unlimited or exhausted.
+	DailyResetsAt    time.Time `json:"daily_resets_at"`   // Next local midnight.
+	MonthlyLimit     float64   `json:"monthly_limit"`     // Effective monthly limit; zero when unlimited.
+	MonthlySpent     float64   `json:"monthly_spent"`     // Cost since the start of the local month.
+	MonthlyRemaining float64   `json:"monthly_remaining"` // Budget left this month; zero when unlimited or exhausted.
+	MonthlyResetsAt  time.Time `json:"monthly_resets_at"` // Start of the next local month.
+	LimitReached     bool      `json:"limit_reached"`     // Whether requests are blocked until a reset.
+}
+
+// Err returns a SpendLimitError for the reached limit that blocks the longest, otherwise nil.
+func (s SpendLimitState) Err() error {
+	if s.MonthlyLimit > 0 && s.MonthlySpent+dailyQuotaEpsilon >= s.MonthlyLimit {
+		return &SpendLimitError{Period: SpendPeriodMonthly, Limit: s.MonthlyLimit, ResetsAt: s.MonthlyResetsAt}
+	}
+	if s.DailyLimit > 0 && s.DailySpent+dailyQuotaEpsilon >= s.DailyLimit {
+		return &SpendLimitError{Period: SpendPeriodDaily, Limit: s.DailyLimit, ResetsAt: s.DailyResetsAt}
+	}
+	return nil
+}
+
+// NextMonthlyReset returns the first local midnight of the month after now.
+func NextMonthlyReset(now time.Time) time.Time {
+	localNow := now.In(time.Local)
+	return time.Date(localNow.Year(), localNow.Month()+1, 1, 0, 0, 0, 0, time.Local)
+}
+
+// LoadSpendLimitState resolves the user's effective spend limits and current spend.
+// A positive limit on the user wins; otherwise the strictest positive limit among the
+// user's groups applies, each group inheriting the limits it leaves unset from its parents.
+func LoadSpendLimitState(ctx context.Context, db *gorm.DB, userID uint64, now time.Time) (SpendLimitState, error) {
+	state := SpendLimitState{DailyResetsAt: NextDailyReset(now), MonthlyResetsAt: NextMonthlyReset(now)}
+	if db == nil {
+		return state, errors.New("nil db")
+	}
+	if userID == 0 {
+		return state, nil
+	}
+
+	var user models.User
+	if errFind := db.WithContext(ctx).
+		Select("id", "user_group_id", "bill_user_group_id", "daily_spend_limit", "monthly_spend_limit").
+		First(&user, userID).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return state, nil
+		}
+		return state, errFind
+	}
+	state.DailyLimit = user.DailySpendLimit
+	state.MonthlyLimit = user.MonthlySpendLimit
+
+	if state.DailyLimit <= 0 || state.MonthlyLimit <= 0 {
+		groupIDs := append(user.UserGroupID.Values(), user.BillUserGroupID.Values()...)
+		dailyFromUser, monthlyFromUser := state.DailyLimit > 0, state.MonthlyLimit > 0
+		seen := make(map[uint64]struct{}, len(groupIDs))
+		for _, groupID := range groupIDs {
+			if _, ok := seen[groupID]; ok {
+				continue
+			}
+			seen[groupID] = struct{}{}
+			policy, errPolicy := usergroup.Resolve(ctx, db, groupID)
+			if errPolicy != nil {
+				return state, errPolicy
+			}
+			if !dailyFromUser {
+				state.DailyLimit = strictestLimit(state.DailyLimit, policy.DailySpendLimit)
+			}
+			if !monthlyFromUser {
+				state.MonthlyLimit = strictestLimit(state.MonthlyLimit, policy.MonthlySpendLimit)
+			}
+		}
+	}
+	if state.DailyLimit <= 0 && state.MonthlyLimit <= 0 {
+		return state, nil
+	}
+
+	monthStart := state.MonthlyResetsAt.AddDate(0, -1, 0)
+	dayStart := state.DailyResetsAt.AddDate(0, 0, -1)
+	var spent struct {
+		Daily   int64 `gorm:"column:daily"`   // Cost micros since local midnight.
+		Monthly int64 `gorm:"column:monthly"` // Cost micros since the start of the month.
+	}
+	if errSum := db.WithContext(ctx).
+		Model(&models.Usage{}).
+		Select("COALESCE(SUM(CASE WHEN requested_at >= ? THEN cost_micros ELSE 0 END), 0) AS daily, COALESCE(SUM(cost_micros), 0) AS monthly", dayStart).
+		Where("user_id = ? AND requested_at >= ?", userID, monthStart).
+		Scan(&spent).Error; errSum != nil {
+		return state, errSum
+	}
+	state.DailySpent = float64(spent.Daily) / 1_000_000
+	state.MonthlySpent = float64(spent.Monthly) / 1_000_000
+	if state.DailyLimit > 0 && state.DailySpent < state.DailyLimit {
+		state.DailyRemaining = state.DailyLimit - state.DailySpent
+	}
+	if state.MonthlyLimit > 0 && state.MonthlySpent < state.MonthlyLimit {
+		state.MonthlyRemaining = state.MonthlyLimit - state.MonthlySpent
+	}
+	state.LimitReached = state.Err() != nil
+	return state, nil
+}
+
+// strictestLimit returns the lower of two limits, ignoring non-positive (unlimited) values.
+func strictestLimit(current, candidate float64) float64 {
+	if candidate <= 0 {
+		return current
+	}
+	if current <= 0 || candidate < current {
+		return candidate
+	}
+	return current
+}
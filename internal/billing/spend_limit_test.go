@@ -0,0 +1,96 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestLoadSpendLimitState_UserAndGroupLimits(t *testing.T) {
+	dsn := fmt.Sprintf("file:billing_spend_limit_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	ctx := context.Background()
+	now := time.Now()
+
+	loose := models.UserGroup{Name: "loose", DailySpendLimit: 50, MonthlySpendLimit: 500}
+	strict := models.UserGroup{Name: "strict", DailySpendLimit: 5}
+	if errCreate := conn.Create(&[]*models.UserGroup{&loose, &strict}).Error; errCreate != nil {
+		t.Fatalf("create groups: %v", errCreate)
+	}
+	user := models.User{
+		Username:    "spend-user",
+		Email:       "spend-user@example.com",
+		Password:    "x",
+		UserGroupID: models.UserGroupIDs{&loose.ID, &strict.ID},
+	}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+
+	state, errState := LoadSpendLimitState(ctx, conn, user.ID, now)
+	if errState != nil {
+		t.Fatalf("load state: %v", errState)
+	}
+	if state.DailyLimit != 5 || state.MonthlyLimit != 500 || state.DailyRemaining != 5 || state.LimitReached {
+		t.Fatalf("expected strictest group limits with full budget, got %+v", state)
+	}
+
+	userID := user.ID
+	usage := models.Usage{
+		Provider:    "openai",
+		Model:       "gpt-4o",
+		UserID:      &userID,
+		CostMicros:  5_000_000,
+		RequestedAt: now.UTC(),
+	}
+	if errCreate := conn.Create(&usage).Error; errCreate != nil {
+		t.Fatalf("create usage: %v", errCreate)
+	}
+
+	state, errState = LoadSpendLimitState(ctx, conn, user.ID, now)
+	if errState != nil {
+		t.Fatalf("load state: %v", errState)
+	}
+	var limitErr *SpendLimitError
+	if !state.LimitReached || !errors.As(state.Err(), &limitErr) || limitErr.Period != SpendPeriodDaily {
+		t.Fatalf("expected daily spend limit reached, got %+v", state)
+	}
+	if !errors.Is(state.Err(), ErrSpendLimitReached) || !limitErr.ResetsAt.Equal(NextDailyReset(now)) {
+		t.Fatalf("unexpected spend limit error: %v", state.Err())
+	}
+	if state.MonthlyRemaining != 495 {
+		t.Fatalf("expected 495 left this month, got %+v", state)
+	}
+
+	if errUpdate := conn.Model(&models.User{}).Where("id = ?", user.ID).Update("daily_spend_limit", 20).Error; errUpdate != nil {
+		t.Fatalf("update user limit: %v", errUpdate)
+	}
+	state, errState = LoadSpendLimitState(ctx, conn, user.ID, now)
+	if errState != nil {
+		t.Fatalf("load state: %v", errState)
+	}
+	if state.DailyLimit != 20 || state.DailyRemaining != 15 || state.LimitReached {
+		t.Fatalf("expected user limit to override groups, got %+v", state)
+	}
+}
+
+func TestNextMonthlyReset(t *testing.T) {
+	now := time.Date(2025, time.December, 31, 23, 0, 0, 0, time.Local)
+	want := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.Local)
+	if got := NextMonthlyReset(now); !got.Equal(want) {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,128 @@
+package billing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidTokenTiers indicates a tiered rule's tier table cannot be used for pricing.
+var ErrInvalidTokenTiers = errors.New("invalid token tiers")
+
+// CostTier shows how a request's tokens were spread over one band of a tiered rule.
+type CostTier struct {
+	Index      int   `json:"index"`        // Zero-based band index.
+	FromTokens int64 `json:"from_tokens"`  // Cumulative volume where the band starts.
+	UpToTokens int64 `json:"up_to_tokens"` // Cumulative volume where the band ends; zero when unbounded.
+	Tokens     int64 `json:"tokens"`       // Request tokens billed within the band.
+}
+
+// ParseTokenTiers decodes a tier table and checks that bands are ascending, that only the
+// last band is unbounded and that no price is negative. An empty table returns no tiers.
+func ParseTokenTiers(raw datatypes.JSON) ([]models.BillingTokenTier, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var tiers []models.BillingTokenTier
+	if errUnmarshal := json.Unmarshal(raw, &tiers); errUnmarshal != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTokenTiers, errUnmarshal)
+	}
+	var previous int64
+	for i, tier := range tiers {
+		switch {
+		case tier.UpToTokens < 0:
+			return nil, fmt.Errorf("%w: tier %d has a negative bound", ErrInvalidTokenTiers, i)
+		case tier.UpToTokens == 0 && i != len(tiers)-1:
+			return nil, fmt.Errorf("%w: only the last tier may be unbounded", ErrInvalidTokenTiers)
+		case tier.UpToTokens != 0 && tier.UpToTokens <= previous:
+			return nil, fmt.Errorf("%w: tier bounds must be ascending", ErrInvalidTokenTiers)
+		}
+		for _, price := range []*float64{tier.PriceInputToken, tier.PriceOutputToken, tier.PriceCacheCreateToken, tier.PriceCacheReadToken} {
+			if price != nil && *price < 0 {
+				return nil, fmt.Errorf("%w: tier %d has a negative price", ErrInvalidTokenTiers, i)
+			}
+		}
+		previous = tier.UpToTokens
+	}
+	return tiers, nil
+}
+
+// allocateTiers spreads tokens consumed on top of prior volume across the bands. Volume past
+// the last bounded band stays in that band. A request without tokens is placed in the band
+// containing prior so its prices can still be reported.
+func allocateTiers(tiers []models.BillingTokenTier, prior, tokens int64) []CostTier {
+	out := make([]CostTier, 0, len(tiers))
+	var from int64
+	for i, tier := range tiers {
+		last := i == len(tiers)-1
+		band := CostTier{Index: i, FromTokens: from, UpToTokens: tier.UpToTokens}
+		from = tier.UpToTokens
+		if !last && prior >= tier.UpToTokens {
+			continue
+		}
+		if tokens == 0 {
+			return append(out, band)
+		}
+		take := tokens
+		if !last && prior+take > tier.UpToTokens {
+			take = tier.UpToTokens - prior
+		}
+		band.Tokens = take
+		out = append(out, band)
+		prior += take
+		tokens -= take
+		if tokens == 0 {
+			break
+		}
+	}
+	return out
+}
+
+// blendedTierPrice returns the token-weighted price across the allocated bands and whether
+// any of those bands configures it.
+func blendedTierPrice(tiers []models.BillingTokenTier, allocated []CostTier, price func(models.BillingTokenTier) *float64) (float64, bool) {
+	var total int64
+	for _, band := range allocated {
+		total += band.Tokens
+	}
+	var blended float64
+	configured := false
+	for _, band := range allocated {
+		value := price(tiers[band.Index])
+		if value == nil {
+			continue
+		}
+		configured = true
+		weight := 1.0
+		if total > 0 {
+			weight = float64(band.Tokens) / float64(total)
+		}
+		blended += *value * weight
+	}
+	return blended, configured
+}
+
+// loadTierVolume records the user's month-to-date volume under a tiered rule, which
+// positions the request within the rule's bands.
+func (e *CostExplanation) loadTierVolume(ctx context.Context, db *gorm.DB, rule *models.BillingRule, userID *uint64, now time.Time) error {
+	if rule == nil || rule.BillingType != models.BillingTypeTiered || userID == nil {
+		return nil
+	}
+	monthStart := NextMonthlyReset(now).AddDate(0, -1, 0)
+	var volume int64
+	if errSum := db.WithContext(ctx).
+		Model(&models.Usage{}).
+		Where("user_id = ? AND billing_rule_id = ? AND requested_at >= ?", *userID, rule.ID, monthStart).
+		Select("COALESCE(SUM(input_tokens + output_tokens), 0)").
+		Scan(&volume).Error; errSum != nil {
+		return errSum
+	}
+	e.Tokens.TierVolume = volume
+	return nil
+}
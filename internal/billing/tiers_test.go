@@ -0,0 +1,131 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func TestExplainCost_TieredVolumeAndMinimumCharge(t *testing.T) {
+	dsn := fmt.Sprintf("file:billing_tiers_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	ctx := context.Background()
+
+	authGroup := models.AuthGroup{Name: "tier-auth-group"}
+	if errCreate := conn.Create(&authGroup).Error; errCreate != nil {
+		t.Fatalf("create auth group: %v", errCreate)
+	}
+	userGroup := models.UserGroup{Name: "tier-user-group"}
+	if errCreate := conn.Create(&userGroup).Error; errCreate != nil {
+		t.Fatalf("create user group: %v", errCreate)
+	}
+	authGroupID := authGroup.ID
+	auth := models.Auth{Key: "tier-auth", Content: datatypes.JSON(`{"type":"codex"}`), AuthGroupID: models.AuthGroupIDs{&authGroupID}}
+	if errCreate := conn.Create(&auth).Error; errCreate != nil {
+		t.Fatalf("create auth: %v", errCreate)
+	}
+	user := models.User{Username: "tier-user", Email: "tier-user@example.com", Password: "x"}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+
+	minimum := 0.01
+	rule := models.BillingRule{
+		AuthGroupID:   authGroup.ID,
+		UserGroupID:   userGroup.ID,
+		Provider:      "openai",
+		Model:         "gpt-5",
+		BillingType:   models.BillingTypeTiered,
+		TokenTiers:    datatypes.JSON(`[{"up_to_tokens":1000,"price_input_token":4,"price_output_token":4},{"up_to_tokens":0,"price_input_token":2,"price_output_token":2}]`),
+		MinimumCharge: &minimum,
+		IsEnabled:     true,
+	}
+	if errCreate := conn.Create(&rule).Error; errCreate != nil {
+		t.Fatalf("create billing rule: %v", errCreate)
+	}
+	userID := user.ID
+	prior := models.Usage{Provider: "openai", Model: "gpt-5", UserID: &userID, BillingRuleID: &rule.ID, InputTokens: 800, RequestedAt: time.Now().UTC()}
+	if errCreate := conn.Create(&prior).Error; errCreate != nil {
+		t.Fatalf("create prior usage: %v", errCreate)
+	}
+
+	userGroupID := userGroup.ID
+	out, errExplain := ExplainCost(ctx, conn, CostInput{
+		Provider:    "openai",
+		Model:       "gpt-5",
+		AuthID:      &auth.ID,
+		UserID:      &userID,
+		UserGroupID: &userGroupID,
+		InputTokens: 400,
+	})
+	if errExplain != nil {
+		t.Fatalf("explain cost: %v", errExplain)
+	}
+	if out.Tokens.TierVolume != 800 {
+		t.Fatalf("expected prior volume 800, got %d", out.Tokens.TierVolume)
+	}
+	wantTiers := []CostTier{
+		{Index: 0, FromTokens: 0, UpToTokens: 1000, Tokens: 200},
+		{Index: 1, FromTokens: 1000, UpToTokens: 0, Tokens: 200},
+	}
+	if !reflect.DeepEqual(out.Tiers, wantTiers) {
+		t.Fatalf("unexpected tier allocation: %+v", out.Tiers)
+	}
+	if input := out.Components[0]; input.Name != "input" || input.UnitPrice != 3 || input.CostMicros != 1200 {
+		t.Fatalf("expected input blended at 3/M, got %+v", input)
+	}
+	last := out.Components[len(out.Components)-1]
+	if last.Name != "minimum" || last.CostMicros != 8800 || out.TotalMicros != 10_000 {
+		t.Fatalf("expected minimum charge to lift total to 10000 micros, got %+v total=%d", last, out.TotalMicros)
+	}
+}
+
+func TestAllocateTiers(t *testing.T) {
+	tiers := []models.BillingTokenTier{{UpToTokens: 100}, {UpToTokens: 300}}
+	cases := []struct {
+		prior, tokens int64
+		want          []CostTier
+	}{
+		{prior: 0, tokens: 50, want: []CostTier{{Index: 0, UpToTokens: 100, Tokens: 50}}},
+		{prior: 50, tokens: 300, want: []CostTier{{Index: 0, UpToTokens: 100, Tokens: 50}, {Index: 1, FromTokens: 100, UpToTokens: 300, Tokens: 250}}},
+		{prior: 500, tokens: 10, want: []CostTier{{Index: 1, FromTokens: 100, UpToTokens: 300, Tokens: 10}}},
+		{prior: 150, tokens: 0, want: []CostTier{{Index: 1, FromTokens: 100, UpToTokens: 300}}},
+	}
+	for _, tc := range cases {
+		if got := allocateTiers(tiers, tc.prior, tc.tokens); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("allocateTiers(%d, %d) = %+v, want %+v", tc.prior, tc.tokens, got, tc.want)
+		}
+	}
+}
+
+func TestParseTokenTiersRejectsInvalidTables(t *testing.T) {
+	for _, raw := range []string{
+		`[{"up_to_tokens":0},{"up_to_tokens":100}]`,
+		`[{"up_to_tokens":100},{"up_to_tokens":100}]`,
+		`[{"up_to_tokens":100,"price_input_token":-1}]`,
+		`{"up_to_tokens":100}`,
+	} {
+		if _, errParse := ParseTokenTiers(datatypes.JSON(raw)); !errors.Is(errParse, ErrInvalidTokenTiers) {
+			t.Fatalf("expected ErrInvalidTokenTiers for %s, got %v", raw, errParse)
+		}
+	}
+	tiers, errParse := ParseTokenTiers(datatypes.JSON(`[{"up_to_tokens":1000000,"price_input_token":3},{"up_to_tokens":0,"price_input_token":2}]`))
+	if errParse != nil || len(tiers) != 2 {
+		t.Fatalf("expected two valid tiers, got %+v err=%v", tiers, errParse)
+	}
+}
@@ -0,0 +1,382 @@
+// Package bulkdelete runs admin-requested bulk deletes in the background, in batches, with
+// progress tracking and cancellation.
+package bulkdelete
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultRunnerInterval = 5 * time.Second
+	// staleRunningAfter lets another runner resume a job whose runner stopped reporting progress.
+	staleRunningAfter = 5 * time.Minute
+)
+
+// Batch size limits.
+const (
+	DefaultBatchSize = 500
+	MaxBatchSize     = 5000
+)
+
+// Supported entities.
+const (
+	EntityUsages       = "usages"
+	EntityPrepaidCards = "prepaid_cards"
+	EntityAuths        = "auths"
+)
+
+var (
+	// ErrUnknownEntity is returned for entities that cannot be bulk deleted.
+	ErrUnknownEntity = errors.New("bulk delete: unknown entity")
+	// ErrInvalidCriteria is returned when the criteria are malformed or too broad.
+	ErrInvalidCriteria = errors.New("bulk delete: invalid criteria")
+	// ErrNotCancellable is returned when cancelling a job that already finished.
+	ErrNotCancellable = errors.New("bulk delete: job already finished")
+
+	errJobCancelled = errors.New("bulk delete: job cancelled")
+)
+
+// Criteria selects the rows a job deletes. Before is required for every entity so a job
+// can never wipe a whole table by accident.
+type Criteria struct {
+	Before *time.Time `json:"before"` // usages: requested_at; prepaid_cards: expires_at; auths: updated_at.
+
+	UserID     *uint64 `json:"user_id,omitempty"`     // usages only.
+	Provider   string  `json:"provider,omitempty"`    // usages only.
+	Model      string  `json:"model,omitempty"`       // usages only.
+	FailedOnly bool    `json:"failed_only,omitempty"` // usages only.
+
+	UnredeemedOnly bool `json:"unredeemed_only,omitempty"` // prepaid_cards only.
+
+	UnavailableOnly  bool `json:"unavailable_only,omitempty"`   // auths only.
+	TokenInvalidOnly bool `json:"token_invalid_only,omitempty"` // auths only.
+}
+
+// NormalizeEntity lowercases an entity name and checks it is supported.
+func NormalizeEntity(raw string) (string, error) {
+	entity := strings.ToLower(strings.TrimSpace(raw))
+	switch entity {
+	case EntityUsages, EntityPrepaidCards, EntityAuths:
+		return entity, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownEntity, raw)
+	}
+}
+
+// Validate checks that the criteria only use filters the entity supports.
+func (c Criteria) Validate(entity string) error {
+	if c.Before == nil || c.Before.IsZero() {
+		return fmt.Errorf("%w: before is required", ErrInvalidCriteria)
+	}
+	usageFilters := c.UserID != nil || strings.TrimSpace(c.Provider) != "" || strings.TrimSpace(c.Model) != "" || c.FailedOnly
+	switch entity {
+	case EntityUsages:
+		if c.UnredeemedOnly || c.UnavailableOnly || c.TokenInvalidOnly {
+			return fmt.Errorf("%w: filter not supported for usages", ErrInvalidCriteria)
+		}
+	case EntityPrepaidCards:
+		if usageFilters || c.UnavailableOnly || c.TokenInvalidOnly {
+			return fmt.Errorf("%w: filter not supported for prepaid_cards", ErrInvalidCriteria)
+		}
+	case EntityAuths:
+		if usageFilters || c.UnredeemedOnly {
+			return fmt.Errorf("%w: filter not supported for auths", ErrInvalidCriteria)
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownEntity, entity)
+	}
+	return nil
+}
+
+// scope builds the query selecting the rows matched by the criteria.
+func scope(db *gorm.DB, entity string, c Criteria) (*gorm.DB, error) {
+	if errValidate := c.Validate(entity); errValidate != nil {
+		return nil, errValidate
+	}
+	before := c.Before.UTC()
+	switch entity {
+	case EntityUsages:
+		q := db.Model(&models.Usage{}).Where("requested_at < ?", before)
+		if c.UserID != nil {
+			q = q.Where("user_id = ?", *c.UserID)
+		}
+		if provider := strings.TrimSpace(c.Provider); provider != "" {
+			q = q.Where("provider = ?", provider)
+		}
+		if model := strings.TrimSpace(c.Model); model != "" {
+			q = q.Where("model = ?", model)
+		}
+		if c.FailedOnly {
+			q = q.Where("failed = ?", true)
+		}
+		return q, nil
+	case EntityPrepaidCards:
+		q := db.Model(&models.PrepaidCard{}).Where("expires_at IS NOT NULL AND expires_at < ?", before)
+		if c.UnredeemedOnly {
+			q = q.Where("redeemed_user_id IS NULL")
+		}
+		return q, nil
+	default:
+		q := db.Model(&models.Auth{}).Where("updated_at < ?", before)
+		if c.UnavailableOnly {
+			q = q.Where("is_available = ?", false)
+		}
+		if c.TokenInvalidOnly {
+			q = q.Where("token_invalid = ?", true)
+		}
+		return q, nil
+	}
+}
+
+// Count returns how many rows the criteria currently match.
+func Count(ctx context.Context, db *gorm.DB, entity string, c Criteria) (int64, error) {
+	q, errScope := scope(db.WithContext(ctx), entity, c)
+	if errScope != nil {
+		return 0, errScope
+	}
+	var count int64
+	if errCount := q.Count(&count).Error; errCount != nil {
+		return 0, errCount
+	}
+	return count, nil
+}
+
+// Cancel stops a pending or running job. The batch in flight is rolled back.
+func Cancel(ctx context.Context, db *gorm.DB, id uint64) error {
+	now := time.Now().UTC()
+	res := db.WithContext(ctx).Model(&models.BulkDeleteJob{}).
+		Where("id = ? AND status IN ?", id, []models.BulkDeleteStatus{models.BulkDeleteStatusPending, models.BulkDeleteStatusRunning}).
+		Updates(map[string]any{"status": models.BulkDeleteStatusCancelled, "finished_at": now, "updated_at": now})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		var count int64
+		if errCount := db.WithContext(ctx).Model(&models.BulkDeleteJob{}).Where("id = ?", id).Count(&count).Error; errCount != nil {
+			return errCount
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return ErrNotCancellable
+	}
+	return nil
+}
+
+// RunNext claims the oldest runnable job and deletes its rows batch by batch. It reports
+// whether a job was claimed.
+func RunNext(ctx context.Context, db *gorm.DB, now time.Time) (bool, error) {
+	job, errClaim := claimJob(ctx, db, now)
+	if errClaim != nil || job == nil {
+		return false, errClaim
+	}
+	if errRun := runJob(ctx, db, job); errRun != nil {
+		if errors.Is(errRun, errJobCancelled) {
+			log.Infof("bulk delete: job %d cancelled after %d rows", job.ID, job.Deleted)
+			return true, nil
+		}
+		if ctx.Err() != nil {
+			// Shutting down: leave the job running so it resumes once it goes stale.
+			return true, ctx.Err()
+		}
+		finished := time.Now().UTC()
+		db.WithContext(ctx).Model(&models.BulkDeleteJob{}).
+			Where("id = ? AND status = ?", job.ID, models.BulkDeleteStatusRunning).
+			Updates(map[string]any{"status": models.BulkDeleteStatusFailed, "last_error": errRun.Error(), "finished_at": finished, "updated_at": finished})
+		return true, errRun
+	}
+	return true, nil
+}
+
+// claimJob moves the oldest pending job, or a running job nobody has advanced recently, to running.
+func claimJob(ctx context.Context, db *gorm.DB, now time.Time) (*models.BulkDeleteJob, error) {
+	staleBefore := now.Add(-staleRunningAfter)
+	var candidates []models.BulkDeleteJob
+	if errFind := db.WithContext(ctx).
+		Where("status = ? OR (status = ? AND updated_at < ?)", models.BulkDeleteStatusPending, models.BulkDeleteStatusRunning, staleBefore).
+		Order("id ASC").
+		Limit(5).
+		Find(&candidates).Error; errFind != nil {
+		return nil, errFind
+	}
+	for i := range candidates {
+		job := &candidates[i]
+		updates := map[string]any{"status": models.BulkDeleteStatusRunning, "updated_at": now}
+		// The status guard makes the claim a compare-and-set, so concurrent runners claim a job once.
+		claim := db.WithContext(ctx).Model(&models.BulkDeleteJob{}).Where("id = ? AND status = ?", job.ID, job.Status)
+		if job.Status == models.BulkDeleteStatusPending {
+			var criteria Criteria
+			errCriteria := json.Unmarshal(job.Criteria, &criteria)
+			var total int64
+			if errCriteria == nil {
+				total, errCriteria = Count(ctx, db, job.Entity, criteria)
+			}
+			if errCriteria != nil {
+				db.WithContext(ctx).Model(&models.BulkDeleteJob{}).
+					Where("id = ? AND status = ?", job.ID, models.BulkDeleteStatusPending).
+					Updates(map[string]any{"status": models.BulkDeleteStatusFailed, "last_error": errCriteria.Error(), "finished_at": now, "updated_at": now})
+				log.WithError(errCriteria).Warnf("bulk delete: job %d cannot start", job.ID)
+				continue
+			}
+			updates["total"] = total
+			updates["started_at"] = now
+			job.Total = total
+		} else {
+			claim = claim.Where("updated_at < ?", staleBefore)
+		}
+		res := claim.Updates(updates)
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected == 1 {
+			job.Status = models.BulkDeleteStatusRunning
+			return job, nil
+		}
+	}
+	return nil, nil
+}
+
+// runJob deletes matching rows in batches until none remain or the job is cancelled.
+func runJob(ctx context.Context, db *gorm.DB, job *models.BulkDeleteJob) error {
+	var criteria Criteria
+	if errUnmarshal := json.Unmarshal(job.Criteria, &criteria); errUnmarshal != nil {
+		return errUnmarshal
+	}
+	batchSize := job.BatchSize
+	if batchSize <= 0 || batchSize > MaxBatchSize {
+		batchSize = DefaultBatchSize
+	}
+	for {
+		if errCtx := ctx.Err(); errCtx != nil {
+			return errCtx
+		}
+		deleted, errBatch := deleteBatch(ctx, db, job, criteria, batchSize)
+		if errBatch != nil {
+			return errBatch
+		}
+		if deleted == 0 {
+			finished := time.Now().UTC()
+			res := db.WithContext(ctx).Model(&models.BulkDeleteJob{}).
+				Where("id = ? AND status = ?", job.ID, models.BulkDeleteStatusRunning).
+				Updates(map[string]any{"status": models.BulkDeleteStatusCompleted, "finished_at": finished, "updated_at": finished})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return errJobCancelled
+			}
+			log.Infof("bulk delete: job %d deleted %d %s", job.ID, job.Deleted, job.Entity)
+			return nil
+		}
+		job.Deleted += deleted
+	}
+}
+
+// deleteBatch deletes one batch and records progress in the same transaction, so a
+// cancellation that lands mid-batch rolls the batch back.
+func deleteBatch(ctx context.Context, db *gorm.DB, job *models.BulkDeleteJob, criteria Criteria, batchSize int) (int64, error) {
+	var deleted int64
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		q, errScope := scope(tx, job.Entity, criteria)
+		if errScope != nil {
+			return errScope
+		}
+		var ids []uint64
+		if errPluck := q.Order("id ASC").Limit(batchSize).Pluck("id", &ids).Error; errPluck != nil {
+			return errPluck
+		}
+		if len(ids) > 0 {
+			res := tx.Table(tableName(job.Entity)).Where("id IN ?", ids).Delete(nil)
+			if res.Error != nil {
+				return res.Error
+			}
+			deleted = res.RowsAffected
+		}
+		progress := tx.Model(&models.BulkDeleteJob{}).
+			Where("id = ? AND status = ?", job.ID, models.BulkDeleteStatusRunning).
+			Updates(map[string]any{"deleted": gorm.Expr("deleted + ?", deleted), "updated_at": time.Now().UTC()})
+		if progress.Error != nil {
+			return progress.Error
+		}
+		if progress.RowsAffected == 0 {
+			return errJobCancelled
+		}
+		return nil
+	})
+	if errTx != nil {
+		return 0, errTx
+	}
+	return deleted, nil
+}
+
+// tableName maps an entity to its table.
+func tableName(entity string) string {
+	switch entity {
+	case EntityPrepaidCards:
+		return "prepaid_cards"
+	case EntityAuths:
+		return "auths"
+	default:
+		return "usages"
+	}
+}
+
+// Runner executes queued bulk delete jobs.
+type Runner struct {
+	db       *gorm.DB
+	interval time.Duration
+}
+
+// NewRunner constructs a runner; returns nil when db is nil.
+func NewRunner(db *gorm.DB) *Runner {
+	if db == nil {
+		return nil
+	}
+	return &Runner{db: db, interval: defaultRunnerInterval}
+}
+
+// Start launches the runner loop in a background goroutine.
+func (r *Runner) Start(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go r.run(ctx)
+	log.Infof("bulk delete runner started (interval=%s)", r.interval)
+}
+
+func (r *Runner) run(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		claimed, errRun := RunNext(ctx, r.db, time.Now().UTC())
+		if errRun != nil && ctx.Err() == nil {
+			log.WithError(errRun).Warn("bulk delete runner: job failed")
+		}
+		if claimed {
+			// Drain the queue before sleeping.
+			continue
+		}
+		timer := time.NewTimer(r.interval)
+		select {
+		case <-ctx.Done():
+			if !timer.Stop() {
+				<-timer.C
+			}
+			return
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package bulkdelete
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func setupBulkDeleteDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:bulkdelete_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func createJob(t *testing.T, conn *gorm.DB, entity string, criteria Criteria, batchSize int) models.BulkDeleteJob {
+	t.Helper()
+	raw, errMarshal := json.Marshal(criteria)
+	if errMarshal != nil {
+		t.Fatalf("marshal criteria: %v", errMarshal)
+	}
+	job := models.BulkDeleteJob{Entity: entity, Criteria: raw, BatchSize: batchSize, Status: models.BulkDeleteStatusPending}
+	if errCreate := conn.Create(&job).Error; errCreate != nil {
+		t.Fatalf("create job: %v", errCreate)
+	}
+	return job
+}
+
+func TestRunNextDeletesMatchingUsagesInBatches(t *testing.T) {
+	conn := setupBulkDeleteDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	for i := 0; i < 5; i++ {
+		old := models.Usage{Provider: "openai", Model: "gpt-5", RequestedAt: now.AddDate(0, 0, -40), Failed: i%2 == 0}
+		if errCreate := conn.Create(&old).Error; errCreate != nil {
+			t.Fatalf("create usage: %v", errCreate)
+		}
+	}
+	recent := models.Usage{Provider: "openai", Model: "gpt-5", RequestedAt: now, Failed: true}
+	if errCreate := conn.Create(&recent).Error; errCreate != nil {
+		t.Fatalf("create usage: %v", errCreate)
+	}
+
+	before := now.AddDate(0, 0, -30)
+	criteria := Criteria{Before: &before, FailedOnly: true}
+	count, errCount := Count(ctx, conn, EntityUsages, criteria)
+	if errCount != nil || count != 3 {
+		t.Fatalf("expected dry-run count 3, got %d err=%v", count, errCount)
+	}
+
+	job := createJob(t, conn, EntityUsages, criteria, 2)
+	claimed, errRun := RunNext(ctx, conn, now)
+	if errRun != nil || !claimed {
+		t.Fatalf("expected job to run, claimed=%v err=%v", claimed, errRun)
+	}
+
+	var stored models.BulkDeleteJob
+	if errFind := conn.First(&stored, job.ID).Error; errFind != nil {
+		t.Fatalf("load job: %v", errFind)
+	}
+	if stored.Status != models.BulkDeleteStatusCompleted || stored.Total != 3 || stored.Deleted != 3 || stored.FinishedAt == nil {
+		t.Fatalf("unexpected job state: %+v", stored)
+	}
+	var remaining int64
+	conn.Model(&models.Usage{}).Count(&remaining)
+	if remaining != 3 {
+		t.Fatalf("expected 3 usages to remain, got %d", remaining)
+	}
+
+	if claimed, _ = RunNext(ctx, conn, now); claimed {
+		t.Fatalf("expected no further jobs")
+	}
+}
+
+func TestCancelStopsPendingJob(t *testing.T) {
+	conn := setupBulkDeleteDB(t)
+	ctx := context.Background()
+	before := time.Now().UTC()
+
+	job := createJob(t, conn, EntityAuths, Criteria{Before: &before, TokenInvalidOnly: true}, 0)
+	if errCancel := Cancel(ctx, conn, job.ID); errCancel != nil {
+		t.Fatalf("cancel job: %v", errCancel)
+	}
+	if errCancel := Cancel(ctx, conn, job.ID); !errors.Is(errCancel, ErrNotCancellable) {
+		t.Fatalf("expected ErrNotCancellable on second cancel, got %v", errCancel)
+	}
+	if errCancel := Cancel(ctx, conn, job.ID+100); !errors.Is(errCancel, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected not found for unknown job, got %v", errCancel)
+	}
+	if claimed, errRun := RunNext(ctx, conn, time.Now().UTC()); claimed || errRun != nil {
+		t.Fatalf("expected cancelled job to be skipped, claimed=%v err=%v", claimed, errRun)
+	}
+}
+
+func TestCriteriaValidate(t *testing.T) {
+	before := time.Now().UTC()
+	userID := uint64(1)
+	cases := []struct {
+		entity   string
+		criteria Criteria
+	}{
+		{entity: EntityUsages, criteria: Criteria{}},
+		{entity: EntityUsages, criteria: Criteria{Before: &before, UnredeemedOnly: true}},
+		{entity: EntityPrepaidCards, criteria: Criteria{Before: &before, UserID: &userID}},
+		{entity: EntityAuths, criteria: Criteria{Before: &before, Provider: "openai"}},
+	}
+	for _, tc := range cases {
+		if errValidate := tc.criteria.Validate(tc.entity); !errors.Is(errValidate, ErrInvalidCriteria) {
+			t.Fatalf("expected ErrInvalidCriteria for %s %+v, got %v", tc.entity, tc.criteria, errValidate)
+		}
+	}
+	if _, errEntity := NormalizeEntity("bills"); !errors.Is(errEntity, ErrUnknownEntity) {
+		t.Fatalf("expected ErrUnknownEntity, got %v", errEntity)
+	}
+}
@@ -0,0 +1,135 @@
+// Package canary trials new provider keys on a share of live traffic. The watcher publishes
+// the provider keys and the SDK auths synthesized from them; the auth selector splits each
+// provider's traffic between canary keys and the remaining keys, and usage rows record the
+// provider key that served them so both sides can be compared.
+package canary
+
+import (
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+)
+
+// Key is the routing view of a provider key.
+type Key struct {
+	ID       uint64 // Provider key ID.
+	Provider string // Provider name of the key.
+	Percent  int    // Share of traffic routed to the key while in canary; zero outside a canary.
+}
+
+var (
+	keysByAPIKey  atomic.Pointer[map[string]Key]
+	apiKeysByAuth atomic.Pointer[map[string]string]
+)
+
+// StoreKeys publishes the provider keys serving this instance. Keys of openai-compatible
+// providers are published once per API key entry.
+func StoreKeys(rows []models.ProviderAPIKey) {
+	next := make(map[string]Key, len(rows))
+	for _, row := range rows {
+		if !row.IsEnabled {
+			continue
+		}
+		key := Key{ID: row.ID, Provider: row.Provider, Percent: clampPercent(row.CanaryPercent)}
+		for _, apiKey := range rowAPIKeys(row) {
+			next[apiKey] = key
+		}
+	}
+	keysByAPIKey.Store(&next)
+}
+
+// StoreAuths publishes the API key of every SDK auth synthesized from provider keys, by auth ID.
+func StoreAuths(apiKeyByAuthID map[string]string) {
+	next := make(map[string]string, len(apiKeyByAuthID))
+	for authID, apiKey := range apiKeyByAuthID {
+		if apiKey = strings.TrimSpace(apiKey); authID != "" && apiKey != "" {
+			next[authID] = apiKey
+		}
+	}
+	apiKeysByAuth.Store(&next)
+}
+
+// Lookup returns the provider key owning apiKey.
+func Lookup(apiKey string) (Key, bool) {
+	current := keysByAPIKey.Load()
+	apiKey = strings.TrimSpace(apiKey)
+	if current == nil || apiKey == "" {
+		return Key{}, false
+	}
+	key, ok := (*current)[apiKey]
+	return key, ok
+}
+
+// KeyIDForAuth returns the provider key behind an SDK auth, or nil for auth files.
+func KeyIDForAuth(authID string) *uint64 {
+	current := apiKeysByAuth.Load()
+	if current == nil {
+		return nil
+	}
+	apiKey, ok := (*current)[strings.TrimSpace(authID)]
+	if !ok {
+		return nil
+	}
+	key, ok := Lookup(apiKey)
+	if !ok {
+		return nil
+	}
+	id := key.ID
+	return &id
+}
+
+// Choose splits one request between canary keys and the rest. Given the API keys of the
+// candidates (empty for auth files), it returns the indexes the request may use: the canary
+// candidates with the highest canary percent's probability, the others otherwise. It returns
+// nil when no split applies, that is when candidates are all canaries or none are. roll
+// returns a number in [0, n).
+func Choose(apiKeys []string, roll func(n int) int) []int {
+	percent := 0
+	var canaries, others []int
+	for i, apiKey := range apiKeys {
+		key, ok := Lookup(apiKey)
+		if ok && key.Percent > 0 {
+			canaries = append(canaries, i)
+			percent = max(percent, key.Percent)
+			continue
+		}
+		others = append(others, i)
+	}
+	if len(canaries) == 0 || len(others) == 0 {
+		return nil
+	}
+	if roll(100) < percent {
+		return canaries
+	}
+	return others
+}
+
+// rowAPIKeys returns the API keys configured on a provider key row.
+func rowAPIKeys(row models.ProviderAPIKey) []string {
+	var out []string
+	if apiKey := strings.TrimSpace(row.APIKey); apiKey != "" {
+		out = append(out, apiKey)
+	}
+	if len(row.APIKeyEntries) == 0 {
+		return out
+	}
+	var entries []struct {
+		APIKey string `json:"api_key"`
+	}
+	if errUnmarshal := json.Unmarshal(row.APIKeyEntries, &entries); errUnmarshal != nil {
+		return out
+	}
+	for _, entry := range entries {
+		if apiKey := strings.TrimSpace(entry.APIKey); apiKey != "" {
+			out = append(out, apiKey)
+		}
+	}
+	return out
+}
+
+// clampPercent bounds a canary percent to [0, 100].
+func clampPercent(percent int) int {
+	return min(max(percent, 0), 100)
+}
@@ -0,0 +1,150 @@
+package canary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func setupCanaryDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:canary_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func TestChooseSplitsTrafficAndAttributesAuths(t *testing.T) {
+	StoreKeys([]models.ProviderAPIKey{
+		{ID: 1, Provider: "claude", APIKey: "sk-old", IsEnabled: true},
+		{ID: 2, Provider: "claude", APIKey: "sk-new", IsEnabled: true, CanaryPercent: 10},
+		{ID: 3, Provider: "openai", IsEnabled: true, APIKeyEntries: datatypes.JSON(`[{"api_key":"sk-compat"}]`)},
+		{ID: 4, Provider: "claude", APIKey: "sk-off", CanaryPercent: 50},
+	})
+	StoreAuths(map[string]string{"claude:apikey:aaa": "sk-new", "openai:apikey:bbb": "sk-compat"})
+	t.Cleanup(func() {
+		StoreKeys(nil)
+		StoreAuths(nil)
+	})
+
+	candidates := []string{"sk-old", "", "sk-new"}
+	if got := Choose(candidates, func(int) int { return 9 }); !slices.Equal(got, []int{2}) {
+		t.Fatalf("expected rolls under the percent to pick the canary, got %v", got)
+	}
+	if got := Choose(candidates, func(int) int { return 10 }); !slices.Equal(got, []int{0, 1}) {
+		t.Fatalf("expected other rolls to pick the remaining auths, got %v", got)
+	}
+	if got := Choose([]string{"sk-new"}, func(int) int { return 99 }); got != nil {
+		t.Fatalf("expected no split when only canaries remain, got %v", got)
+	}
+	if got := Choose([]string{"sk-old", "sk-off"}, func(int) int { return 0 }); got != nil {
+		t.Fatalf("expected disabled keys to be ignored, got %v", got)
+	}
+
+	if id := KeyIDForAuth("claude:apikey:aaa"); id == nil || *id != 2 {
+		t.Fatalf("expected auth to resolve to key 2, got %v", id)
+	}
+	if id := KeyIDForAuth("openai:apikey:bbb"); id == nil || *id != 3 {
+		t.Fatalf("expected compat entry to resolve to key 3, got %v", id)
+	}
+	if id := KeyIDForAuth("codex-auth-file.json"); id != nil {
+		t.Fatalf("expected auth files to have no provider key, got %d", *id)
+	}
+}
+
+func TestCanaryLifecycleAndReport(t *testing.T) {
+	conn := setupCanaryDB(t)
+	ctx := context.Background()
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	oldKey := models.ProviderAPIKey{Provider: "claude", Name: "old", APIKey: "sk-old", IsEnabled: true}
+	newKey := models.ProviderAPIKey{Provider: "claude", Name: "new", APIKey: "sk-new", IsEnabled: true}
+	otherEnv := models.ProviderAPIKey{Provider: "claude", Name: "staging", APIKey: "sk-stg", IsEnabled: true, Environments: datatypes.JSON(`["staging"]`)}
+	for _, key := range []*models.ProviderAPIKey{&oldKey, &newKey, &otherEnv} {
+		if errCreate := conn.Create(key).Error; errCreate != nil {
+			t.Fatalf("create key: %v", errCreate)
+		}
+	}
+	conn.Model(&newKey).Update("environments", datatypes.JSON(`["prod"]`))
+	newKey.Environments = datatypes.JSON(`["prod"]`)
+
+	if _, errStart := Start(ctx, conn, newKey.ID, 100, now); !errors.Is(errStart, ErrInvalidPercent) {
+		t.Fatalf("expected ErrInvalidPercent, got %v", errStart)
+	}
+	if errRollback := Rollback(ctx, conn, newKey.ID, now); !errors.Is(errRollback, ErrNotCanary) {
+		t.Fatalf("expected ErrNotCanary, got %v", errRollback)
+	}
+	started, errStart := Start(ctx, conn, newKey.ID, 20, now)
+	if errStart != nil || started.CanaryPercent != 20 {
+		t.Fatalf("Start: %+v %v", started, errStart)
+	}
+
+	rows := []models.Usage{
+		{Provider: "claude", Model: "m", ProviderAPIKeyID: &newKey.ID, RequestedAt: now, CreatedAt: now.Add(400 * time.Millisecond)},
+		{Provider: "claude", Model: "m", ProviderAPIKeyID: &newKey.ID, RequestedAt: now, CreatedAt: now.Add(5 * time.Second), Failed: true},
+		{Provider: "claude", Model: "m", ProviderAPIKeyID: &oldKey.ID, RequestedAt: now, CreatedAt: now.Add(200 * time.Millisecond)},
+		{Provider: "claude", Model: "m", ProviderAPIKeyID: &oldKey.ID, RequestedAt: now, CreatedAt: now.Add(200 * time.Millisecond)},
+		{Provider: "claude", Model: "m", RequestedAt: now, CreatedAt: now.Add(time.Second)},
+	}
+	if errCreate := conn.Create(&rows).Error; errCreate != nil {
+		t.Fatalf("create usage: %v", errCreate)
+	}
+	report, errReport := Report(ctx, conn, started, now.Add(-time.Minute), now.Add(time.Minute))
+	if errReport != nil {
+		t.Fatalf("Report: %v", errReport)
+	}
+	if report.Canary.Requests != 2 || report.Canary.ErrorRate != 50 || report.Canary.AvgLatencyMillis != 400 {
+		t.Fatalf("unexpected canary arm: %+v", report.Canary)
+	}
+	if report.Baseline.Requests != 2 || report.Baseline.ErrorRate != 0 || report.Baseline.AvgLatencyMillis != 200 {
+		t.Fatalf("unexpected baseline arm: %+v", report.Baseline)
+	}
+	if report.ErrorRateDelta != 50 || report.LatencyDeltaMillis != 200 {
+		t.Fatalf("unexpected deltas: %+v", report)
+	}
+
+	retired, errPromote := Promote(ctx, conn, newKey.ID, true, now)
+	if errPromote != nil || retired != 1 {
+		t.Fatalf("expected one retired key, got %d (%v)", retired, errPromote)
+	}
+	var promoted, retiredKey, untouched models.ProviderAPIKey
+	conn.First(&promoted, newKey.ID)
+	conn.First(&retiredKey, oldKey.ID)
+	conn.First(&untouched, otherEnv.ID)
+	if promoted.CanaryPercent != 0 || promoted.CanaryStartedAt != nil || !promoted.IsEnabled {
+		t.Fatalf("expected the promoted key to serve normally, got %+v", promoted)
+	}
+	if retiredKey.IsEnabled || !untouched.IsEnabled {
+		t.Fatalf("expected only keys sharing an environment to be retired")
+	}
+
+	if _, errStart := Start(ctx, conn, newKey.ID, 5, now); !errors.Is(errStart, ErrNoBaseline) {
+		t.Fatalf("expected ErrNoBaseline once the peers are retired, got %v", errStart)
+	}
+	conn.Model(&retiredKey).Update("is_enabled", true)
+	if _, errStart := Start(ctx, conn, newKey.ID, 5, now); errStart != nil {
+		t.Fatalf("Start: %v", errStart)
+	}
+	if errRollback := Rollback(ctx, conn, newKey.ID, now); errRollback != nil {
+		t.Fatalf("Rollback: %v", errRollback)
+	}
+	var rolledBack models.ProviderAPIKey
+	conn.First(&rolledBack, newKey.ID)
+	if rolledBack.IsEnabled || rolledBack.CanaryPercent != 0 {
+		t.Fatalf("expected the rolled back key to be disabled, got %+v", rolledBack)
+	}
+}
@@ -0,0 +1,244 @@
+package canary
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"time"
+
+	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/environments"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidPercent is returned when a canary percent is outside 1-99.
+	ErrInvalidPercent = errors.New("canary: percent must be between 1 and 99")
+	// ErrNotCanary is returned when promoting or rolling back a key outside a canary.
+	ErrNotCanary = errors.New("canary: key is not in canary")
+	// ErrNoBaseline is returned when no other enabled key of the provider serves the same
+	// environments, so there is no traffic to split.
+	ErrNoBaseline = errors.New("canary: provider has no other enabled key to compare against")
+)
+
+// Start puts the key in canary, routing percent of its provider's traffic to it. The key is
+// enabled if it was not; restarting a running canary changes its percent and restarts its
+// report window.
+func Start(ctx context.Context, db *gorm.DB, keyID uint64, percent int, now time.Time) (*models.ProviderAPIKey, error) {
+	if percent < 1 || percent > 99 {
+		return nil, ErrInvalidPercent
+	}
+	var key models.ProviderAPIKey
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if errFind := tx.First(&key, keyID).Error; errFind != nil {
+			return errFind
+		}
+		peers, errPeers := peerKeys(tx, &key)
+		if errPeers != nil {
+			return errPeers
+		}
+		if len(peers) == 0 {
+			return ErrNoBaseline
+		}
+		key.CanaryPercent = percent
+		key.CanaryStartedAt = &now
+		key.IsEnabled = true
+		key.UpdatedAt = now
+		return tx.Model(&key).Updates(map[string]any{
+			"canary_percent":    percent,
+			"canary_started_at": now,
+			"is_enabled":        true,
+			"updated_at":        now,
+		}).Error
+	})
+	if errTx != nil {
+		return nil, errTx
+	}
+	return &key, nil
+}
+
+// Promote ends the key's canary so it serves traffic like any other key. With retireOthers
+// the provider's other enabled keys serving the same environments are disabled, completing a
+// key swap. It returns the number of retired keys.
+func Promote(ctx context.Context, db *gorm.DB, keyID uint64, retireOthers bool, now time.Time) (int, error) {
+	retired := 0
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		key, errKey := loadCanary(tx, keyID)
+		if errKey != nil {
+			return errKey
+		}
+		if errEnd := endCanary(tx, key.ID, map[string]any{}, now); errEnd != nil {
+			return errEnd
+		}
+		if !retireOthers {
+			return nil
+		}
+		peers, errPeers := peerKeys(tx, key)
+		if errPeers != nil {
+			return errPeers
+		}
+		if len(peers) == 0 {
+			return nil
+		}
+		retired = len(peers)
+		return tx.Model(&models.ProviderAPIKey{}).
+			Where("id IN ?", peers).
+			Updates(map[string]any{"is_enabled": false, "updated_at": now}).Error
+	})
+	if errTx != nil {
+		return 0, errTx
+	}
+	return retired, nil
+}
+
+// Rollback ends the key's canary and disables the key, returning all traffic to the others.
+func Rollback(ctx context.Context, db *gorm.DB, keyID uint64, now time.Time) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		key, errKey := loadCanary(tx, keyID)
+		if errKey != nil {
+			return errKey
+		}
+		return endCanary(tx, key.ID, map[string]any{"is_enabled": false}, now)
+	})
+}
+
+// loadCanary loads a key that is in canary.
+func loadCanary(tx *gorm.DB, keyID uint64) (*models.ProviderAPIKey, error) {
+	var key models.ProviderAPIKey
+	if errFind := tx.First(&key, keyID).Error; errFind != nil {
+		return nil, errFind
+	}
+	if key.CanaryPercent <= 0 {
+		return nil, ErrNotCanary
+	}
+	return &key, nil
+}
+
+// endCanary clears the key's canary fields along with the extra updates.
+func endCanary(tx *gorm.DB, keyID uint64, updates map[string]any, now time.Time) error {
+	updates["canary_percent"] = 0
+	updates["canary_started_at"] = nil
+	updates["updated_at"] = now
+	return tx.Model(&models.ProviderAPIKey{}).Where("id = ?", keyID).Updates(updates).Error
+}
+
+// peerKeys returns the IDs of the provider's other enabled keys that serve an environment
+// the key serves.
+func peerKeys(tx *gorm.DB, key *models.ProviderAPIKey) ([]uint64, error) {
+	var rows []models.ProviderAPIKey
+	if errFind := tx.Select("id", "environments").
+		Where("provider = ? AND id <> ? AND is_enabled = ?", key.Provider, key.ID, true).
+		Find(&rows).Error; errFind != nil {
+		return nil, errFind
+	}
+	tags := environments.Decode(key.Environments)
+	ids := make([]uint64, 0, len(rows))
+	for _, row := range rows {
+		if sharesEnvironment(tags, environments.Decode(row.Environments)) {
+			ids = append(ids, row.ID)
+		}
+	}
+	return ids, nil
+}
+
+// sharesEnvironment reports whether two keys serve a common environment; untagged keys serve
+// every environment.
+func sharesEnvironment(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	for _, tag := range a {
+		if slices.Contains(b, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Arm summarizes the requests served by one side of a canary.
+type Arm struct {
+	Requests         int64   `json:"requests"`           // Requests served.
+	Failed           int64   `json:"failed"`             // Failed requests.
+	ErrorRate        float64 `json:"error_rate"`         // Failed share of requests, in percent.
+	AvgLatencyMillis float64 `json:"avg_latency_millis"` // Mean latency of successful requests.
+	MaxLatencyMillis float64 `json:"max_latency_millis"` // Slowest successful request.
+}
+
+// Comparison contrasts a canary key with the provider's other keys over the same window.
+type Comparison struct {
+	KeyID    uint64    `json:"key_id"`   // Canary key ID.
+	Provider string    `json:"provider"` // Provider of the key.
+	Percent  int       `json:"percent"`  // Configured canary share.
+	From     time.Time `json:"from"`     // Window start.
+	To       time.Time `json:"to"`       // Window end.
+	Canary   Arm       `json:"canary"`   // Requests served by the canary key.
+	Baseline Arm       `json:"baseline"` // Requests served by the provider's other keys.
+	// ErrorRateDelta is the canary error rate minus the baseline's, in percentage points.
+	ErrorRateDelta float64 `json:"error_rate_delta"`
+	// LatencyDeltaMillis is the canary mean latency minus the baseline's.
+	LatencyDeltaMillis float64 `json:"latency_delta_millis"`
+}
+
+// Report compares the key with the provider's other keys over [from, to).
+func Report(ctx context.Context, db *gorm.DB, key *models.ProviderAPIKey, from, to time.Time) (*Comparison, error) {
+	canaryArm, errCanary := armStats(ctx, db, db.Where("provider_api_key_id = ?", key.ID), from, to)
+	if errCanary != nil {
+		return nil, errCanary
+	}
+	others := db.Model(&models.ProviderAPIKey{}).Select("id").Where("provider = ? AND id <> ?", key.Provider, key.ID)
+	baselineArm, errBaseline := armStats(ctx, db, db.Where("provider_api_key_id IN (?)", others), from, to)
+	if errBaseline != nil {
+		return nil, errBaseline
+	}
+	out := &Comparison{
+		KeyID:    key.ID,
+		Provider: key.Provider,
+		Percent:  key.CanaryPercent,
+		From:     from.UTC(),
+		To:       to.UTC(),
+		Canary:   canaryArm,
+		Baseline: baselineArm,
+	}
+	if canaryArm.Requests > 0 && baselineArm.Requests > 0 {
+		out.ErrorRateDelta = round(canaryArm.ErrorRate - baselineArm.ErrorRate)
+		out.LatencyDeltaMillis = round(canaryArm.AvgLatencyMillis - baselineArm.AvgLatencyMillis)
+	}
+	return out, nil
+}
+
+// armStats aggregates the usage rows matching scope in [from, to).
+func armStats(ctx context.Context, db *gorm.DB, scope *gorm.DB, from, to time.Time) (Arm, error) {
+	latency := dbutil.DurationMillisExpr(db, "requested_at", "created_at")
+	var row struct {
+		Requests   int64
+		Failed     int64
+		LatencySum float64
+		LatencyMax float64
+		Successes  int64
+	}
+	if errScan := db.WithContext(ctx).Model(&models.Usage{}).
+		Where(scope).
+		Where("requested_at >= ? AND requested_at < ?", from.UTC(), to.UTC()).
+		Select(`COUNT(*) AS requests,
+			COALESCE(SUM(CASE WHEN failed THEN 1 ELSE 0 END), 0) AS failed,
+			COALESCE(SUM(CASE WHEN failed THEN 0 ELSE ` + latency + ` END), 0) AS latency_sum,
+			COALESCE(MAX(CASE WHEN failed THEN 0 ELSE ` + latency + ` END), 0) AS latency_max,
+			COALESCE(SUM(CASE WHEN failed THEN 0 ELSE 1 END), 0) AS successes`).
+		Scan(&row).Error; errScan != nil {
+		return Arm{}, errScan
+	}
+	arm := Arm{Requests: row.Requests, Failed: row.Failed, MaxLatencyMillis: round(row.LatencyMax)}
+	if row.Requests > 0 {
+		arm.ErrorRate = round(float64(row.Failed) * 100 / float64(row.Requests))
+	}
+	if row.Successes > 0 {
+		arm.AvgLatencyMillis = round(row.LatencySum / float64(row.Successes))
+	}
+	return arm, nil
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
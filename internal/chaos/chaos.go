@@ -0,0 +1,340 @@
+// Package chaos injects admin-triggered faults (provider error bursts, database latency and
+// config sync failures) so operators can verify alerting, failover and billing under failure
+// conditions. Faults are held in memory on the node that received them, always expire, and
+// only fire while the CHAOS_TESTING setting is enabled.
+package chaos
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	mathrand "math/rand/v2"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+)
+
+// Kind identifies what a fault breaks.
+type Kind string
+
+const (
+	// KindProviderError makes the selector fail picks with an upstream-style status code.
+	KindProviderError Kind = "provider_error"
+	// KindDBLatency delays every database statement.
+	KindDBLatency Kind = "db_latency"
+	// KindConfigSyncFailure makes the config watcher skip its database polls.
+	KindConfigSyncFailure Kind = "config_sync_failure"
+)
+
+const (
+	// MaxDuration caps how long a single fault may stay armed.
+	MaxDuration = time.Hour
+	// MaxLatency caps injected database latency per statement.
+	MaxLatency = 30 * time.Second
+)
+
+var (
+	// ErrDisabled is returned when faults are injected while CHAOS_TESTING is off.
+	ErrDisabled = errors.New("chaos: chaos testing is disabled")
+	// ErrInvalidFault is returned when a fault definition is malformed.
+	ErrInvalidFault = errors.New("chaos: invalid fault")
+	// ErrNotFound is returned when clearing an unknown fault.
+	ErrNotFound = errors.New("chaos: fault not found")
+)
+
+// Fault is one armed failure.
+type Fault struct {
+	ID         string        `json:"id"`                    // Random identifier.
+	Kind       Kind          `json:"kind"`                  // What the fault breaks.
+	Provider   string        `json:"provider,omitempty"`    // Provider filter for provider_error; empty matches all.
+	Model      string        `json:"model,omitempty"`       // Model filter for provider_error; empty matches all.
+	StatusCode int           `json:"status_code,omitempty"` // 429 or 5xx returned by provider_error.
+	Rate       float64       `json:"rate"`                  // Probability in (0,1] that a matching call fails.
+	Latency    time.Duration `json:"-"`                     // Delay added by db_latency.
+	LatencyMs  int64         `json:"latency_ms,omitempty"`  // Latency in milliseconds, for responses.
+	Remaining  int           `json:"remaining,omitempty"`   // Failures left before the fault disarms; 0 means unlimited.
+	Triggered  int           `json:"triggered"`             // Times the fault fired.
+	CreatedBy  string        `json:"created_by"`            // Admin who injected the fault.
+	CreatedAt  time.Time     `json:"created_at"`            // Injection time.
+	ExpiresAt  time.Time     `json:"expires_at"`            // Time the fault disarms on its own.
+}
+
+// Spec describes a fault to inject.
+type Spec struct {
+	Kind       Kind          // What the fault breaks.
+	Provider   string        // Provider filter for provider_error.
+	Model      string        // Model filter for provider_error.
+	StatusCode int           // 429 or 5xx; defaults to 500.
+	Rate       float64       // Failure probability; defaults to 1.
+	Latency    time.Duration // Delay for db_latency.
+	Count      int           // Failures before disarming; 0 means until expiry.
+	Duration   time.Duration // How long the fault stays armed.
+	CreatedBy  string        // Admin injecting the fault.
+}
+
+// registry holds the faults armed on this node.
+type registry struct {
+	mu     sync.Mutex
+	faults map[string]*Fault
+	roll   func() float64
+}
+
+var faults = &registry{faults: make(map[string]*Fault), roll: mathrand.Float64}
+
+// Enabled reports whether the CHAOS_TESTING setting is on.
+func Enabled() bool {
+	raw, ok := internalsettings.DBConfigValue(internalsettings.ChaosTestingKey)
+	if !ok {
+		return internalsettings.DefaultChaosTesting
+	}
+	raw = bytes.TrimSpace(raw)
+	var enabled bool
+	if errUnmarshal := json.Unmarshal(raw, &enabled); errUnmarshal == nil {
+		return enabled
+	}
+	var text string
+	if errUnmarshal := json.Unmarshal(raw, &text); errUnmarshal == nil {
+		text = strings.TrimSpace(text)
+		return strings.EqualFold(text, "true") || text == "1"
+	}
+	return false
+}
+
+// Inject validates and arms a fault.
+func Inject(spec Spec, now time.Time) (Fault, error) {
+	if !Enabled() {
+		return Fault{}, ErrDisabled
+	}
+	fault, errValidate := newFault(spec, now)
+	if errValidate != nil {
+		return Fault{}, errValidate
+	}
+	faults.mu.Lock()
+	defer faults.mu.Unlock()
+	faults.pruneLocked(now)
+	faults.faults[fault.ID] = fault
+	return *fault, nil
+}
+
+func newFault(spec Spec, now time.Time) (*Fault, error) {
+	fault := &Fault{
+		Kind:      spec.Kind,
+		Rate:      spec.Rate,
+		Remaining: spec.Count,
+		CreatedBy: strings.TrimSpace(spec.CreatedBy),
+		CreatedAt: now,
+	}
+	if fault.Rate == 0 {
+		fault.Rate = 1
+	}
+	if fault.Rate < 0 || fault.Rate > 1 {
+		return nil, fmt.Errorf("%w: rate must be within (0,1]", ErrInvalidFault)
+	}
+	if spec.Count < 0 {
+		return nil, fmt.Errorf("%w: count must not be negative", ErrInvalidFault)
+	}
+	if spec.Duration <= 0 || spec.Duration > MaxDuration {
+		return nil, fmt.Errorf("%w: duration must be within (0,%s]", ErrInvalidFault, MaxDuration)
+	}
+	fault.ExpiresAt = now.Add(spec.Duration)
+
+	switch spec.Kind {
+	case KindProviderError:
+		fault.Provider = strings.ToLower(strings.TrimSpace(spec.Provider))
+		fault.Model = strings.TrimSpace(spec.Model)
+		fault.StatusCode = spec.StatusCode
+		if fault.StatusCode == 0 {
+			fault.StatusCode = http.StatusInternalServerError
+		}
+		if fault.StatusCode != http.StatusTooManyRequests && (fault.StatusCode < 500 || fault.StatusCode > 599) {
+			return nil, fmt.Errorf("%w: status_code must be 429 or 5xx", ErrInvalidFault)
+		}
+	case KindDBLatency:
+		if spec.Latency <= 0 || spec.Latency > MaxLatency {
+			return nil, fmt.Errorf("%w: latency must be within (0,%s]", ErrInvalidFault, MaxLatency)
+		}
+		fault.Latency = spec.Latency
+		fault.LatencyMs = spec.Latency.Milliseconds()
+	case KindConfigSyncFailure:
+	default:
+		return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalidFault, spec.Kind)
+	}
+
+	id, errID := randomID()
+	if errID != nil {
+		return nil, errID
+	}
+	fault.ID = id
+	return fault, nil
+}
+
+// List returns the armed faults, oldest first.
+func List(now time.Time) []Fault {
+	faults.mu.Lock()
+	defer faults.mu.Unlock()
+	faults.pruneLocked(now)
+	out := make([]Fault, 0, len(faults.faults))
+	for _, fault := range faults.faults {
+		out = append(out, *fault)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].ID < out[j].ID
+		}
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	return out
+}
+
+// Clear disarms one fault.
+func Clear(id string) error {
+	faults.mu.Lock()
+	defer faults.mu.Unlock()
+	if _, ok := faults.faults[id]; !ok {
+		return ErrNotFound
+	}
+	delete(faults.faults, id)
+	return nil
+}
+
+// ClearAll disarms every fault and returns how many were armed.
+func ClearAll() int {
+	faults.mu.Lock()
+	defer faults.mu.Unlock()
+	n := len(faults.faults)
+	faults.faults = make(map[string]*Fault)
+	return n
+}
+
+// ProviderError is the error returned to callers while a provider_error fault fires.
+type ProviderError struct {
+	FaultID    string // Fault that produced the error.
+	statusCode int
+}
+
+// Error implements error with the same JSON shape as real upstream failures.
+func (e *ProviderError) Error() string {
+	if e.statusCode == http.StatusTooManyRequests {
+		return `{"error":"rate limit exceeded (chaos fault ` + e.FaultID + `)"}`
+	}
+	return `{"error":"upstream error (chaos fault ` + e.FaultID + `)"}`
+}
+
+// StatusCode returns the injected HTTP status.
+func (e *ProviderError) StatusCode() int {
+	return e.statusCode
+}
+
+// Headers returns response headers for the injected error.
+func (e *ProviderError) Headers() http.Header {
+	headers := make(http.Header)
+	headers.Set("Content-Type", "application/json")
+	if e.statusCode == http.StatusTooManyRequests {
+		headers.Set("Retry-After", strconv.Itoa(1))
+	}
+	return headers
+}
+
+// ProviderFault returns an injected error when a provider_error fault fires for provider and model.
+func ProviderFault(provider, model string) error {
+	fault, ok := faults.fire(time.Now(), func(f *Fault) bool {
+		if f.Kind != KindProviderError {
+			return false
+		}
+		if f.Provider != "" && !strings.EqualFold(f.Provider, strings.TrimSpace(provider)) {
+			return false
+		}
+		return f.Model == "" || f.Model == strings.TrimSpace(model)
+	})
+	if !ok {
+		return nil
+	}
+	return &ProviderError{FaultID: fault.ID, statusCode: fault.StatusCode}
+}
+
+// DBLatency returns the delay to add to the next database statement.
+func DBLatency() time.Duration {
+	fault, ok := faults.fire(time.Now(), func(f *Fault) bool { return f.Kind == KindDBLatency })
+	if !ok {
+		return 0
+	}
+	return fault.Latency
+}
+
+// ConfigSyncFault reports whether the next config sync should fail.
+func ConfigSyncFault() bool {
+	_, ok := faults.fire(time.Now(), func(f *Fault) bool { return f.Kind == KindConfigSyncFailure })
+	return ok
+}
+
+// fire finds the first armed fault matching match, rolls its rate and records the trigger.
+func (r *registry) fire(now time.Time, match func(*Fault) bool) (Fault, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.faults) == 0 || !Enabled() {
+		return Fault{}, false
+	}
+	r.pruneLocked(now)
+	var picked *Fault
+	for _, fault := range r.faults {
+		if !match(fault) {
+			continue
+		}
+		if picked == nil || fault.CreatedAt.Before(picked.CreatedAt) {
+			picked = fault
+		}
+	}
+	if picked == nil || (picked.Rate < 1 && r.roll() >= picked.Rate) {
+		return Fault{}, false
+	}
+	picked.Triggered++
+	out := *picked
+	if picked.Remaining > 0 {
+		picked.Remaining--
+		if picked.Remaining == 0 {
+			delete(r.faults, picked.ID)
+		}
+	}
+	return out, true
+}
+
+func (r *registry) pruneLocked(now time.Time) {
+	for id, fault := range r.faults {
+		if !now.Before(fault.ExpiresAt) {
+			delete(r.faults, id)
+		}
+	}
+}
+
+// Sleep waits for d or until ctx is done.
+func Sleep(ctx context.Context, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+func randomID() (string, error) {
+	buf := make([]byte, 8)
+	if _, errRead := rand.Read(buf); errRead != nil {
+		return "", fmt.Errorf("chaos: random: %w", errRead)
+	}
+	return hex.EncodeToString(buf), nil
+}
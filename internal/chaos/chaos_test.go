@@ -0,0 +1,154 @@
+package chaos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"gorm.io/gorm"
+)
+
+func setChaosTesting(t *testing.T, enabled bool) {
+	t.Helper()
+	internalsettings.StoreDBConfig(time.Now(), map[string]json.RawMessage{
+		internalsettings.ChaosTestingKey: json.RawMessage(fmt.Sprintf("%t", enabled)),
+	})
+	t.Cleanup(func() {
+		internalsettings.StoreDBConfig(time.Now(), map[string]json.RawMessage{})
+		ClearAll()
+	})
+}
+
+func TestInjectRequiresSetting(t *testing.T) {
+	setChaosTesting(t, false)
+
+	_, errInject := Inject(Spec{Kind: KindConfigSyncFailure, Duration: time.Minute}, time.Now())
+	if !errors.Is(errInject, ErrDisabled) {
+		t.Fatalf("Inject() error = %v, want ErrDisabled", errInject)
+	}
+}
+
+func TestInjectValidatesSpec(t *testing.T) {
+	setChaosTesting(t, true)
+
+	cases := []Spec{
+		{Kind: "disk_full", Duration: time.Minute},
+		{Kind: KindProviderError, Duration: 0},
+		{Kind: KindProviderError, Duration: 2 * MaxDuration},
+		{Kind: KindProviderError, StatusCode: http.StatusBadRequest, Duration: time.Minute},
+		{Kind: KindProviderError, Rate: 1.5, Duration: time.Minute},
+		{Kind: KindDBLatency, Duration: time.Minute},
+		{Kind: KindConfigSyncFailure, Count: -1, Duration: time.Minute},
+	}
+	for i, spec := range cases {
+		if _, errInject := Inject(spec, time.Now()); !errors.Is(errInject, ErrInvalidFault) {
+			t.Fatalf("case %d: Inject() error = %v, want ErrInvalidFault", i, errInject)
+		}
+	}
+}
+
+func TestProviderFaultBurst(t *testing.T) {
+	setChaosTesting(t, true)
+
+	fault, errInject := Inject(Spec{
+		Kind:       KindProviderError,
+		Provider:   "Claude",
+		StatusCode: http.StatusTooManyRequests,
+		Count:      2,
+		Duration:   time.Minute,
+		CreatedBy:  "admin:root",
+	}, time.Now())
+	if errInject != nil {
+		t.Fatalf("Inject() error = %v", errInject)
+	}
+
+	if errFault := ProviderFault("gemini", "gemini-2.5-pro"); errFault != nil {
+		t.Fatalf("ProviderFault(gemini) = %v, want nil", errFault)
+	}
+	for i := 0; i < 2; i++ {
+		errFault := ProviderFault("claude", "claude-sonnet-4")
+		var providerErr *ProviderError
+		if !errors.As(errFault, &providerErr) {
+			t.Fatalf("call %d: ProviderFault() = %v, want *ProviderError", i, errFault)
+		}
+		if providerErr.StatusCode() != http.StatusTooManyRequests || providerErr.FaultID != fault.ID {
+			t.Fatalf("call %d: status=%d fault=%s", i, providerErr.StatusCode(), providerErr.FaultID)
+		}
+		if providerErr.Headers().Get("Retry-After") == "" {
+			t.Fatalf("call %d: missing Retry-After header", i)
+		}
+	}
+	if errFault := ProviderFault("claude", "claude-sonnet-4"); errFault != nil {
+		t.Fatalf("ProviderFault() after burst = %v, want nil", errFault)
+	}
+	if armed := List(time.Now()); len(armed) != 0 {
+		t.Fatalf("List() = %d faults, want 0 after burst", len(armed))
+	}
+}
+
+func TestFaultsStopWhenDisabledOrExpired(t *testing.T) {
+	setChaosTesting(t, true)
+
+	now := time.Now()
+	if _, errInject := Inject(Spec{Kind: KindConfigSyncFailure, Duration: time.Minute}, now.Add(-2*time.Minute)); errInject != nil {
+		t.Fatalf("Inject() error = %v", errInject)
+	}
+	if ConfigSyncFault() {
+		t.Fatal("ConfigSyncFault() = true for expired fault")
+	}
+
+	fault, errInject := Inject(Spec{Kind: KindConfigSyncFailure, Duration: time.Minute}, now)
+	if errInject != nil {
+		t.Fatalf("Inject() error = %v", errInject)
+	}
+	if !ConfigSyncFault() {
+		t.Fatal("ConfigSyncFault() = false, want true")
+	}
+	internalsettings.StoreDBConfig(time.Now(), map[string]json.RawMessage{
+		internalsettings.ChaosTestingKey: json.RawMessage("false"),
+	})
+	if ConfigSyncFault() {
+		t.Fatal("ConfigSyncFault() = true after CHAOS_TESTING was disabled")
+	}
+	if errClear := Clear(fault.ID); errClear != nil {
+		t.Fatalf("Clear() error = %v", errClear)
+	}
+	if errClear := Clear(fault.ID); !errors.Is(errClear, ErrNotFound) {
+		t.Fatalf("Clear() again error = %v, want ErrNotFound", errClear)
+	}
+}
+
+func TestRegisterDBLatencyDelaysStatements(t *testing.T) {
+	setChaosTesting(t, true)
+
+	dsn := fmt.Sprintf("file:chaos_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := conn.AutoMigrate(&models.Setting{}); errMigrate != nil {
+		t.Fatalf("migrate: %v", errMigrate)
+	}
+	if errRegister := RegisterDBLatency(conn); errRegister != nil {
+		t.Fatalf("RegisterDBLatency() error = %v", errRegister)
+	}
+
+	latency := 50 * time.Millisecond
+	if _, errInject := Inject(Spec{Kind: KindDBLatency, Latency: latency, Count: 1, Duration: time.Minute}, time.Now()); errInject != nil {
+		t.Fatalf("Inject() error = %v", errInject)
+	}
+	start := time.Now()
+	var count int64
+	if errCount := conn.Model(&models.Setting{}).Count(&count).Error; errCount != nil {
+		t.Fatalf("count: %v", errCount)
+	}
+	if elapsed := time.Since(start); elapsed < latency {
+		t.Fatalf("query took %s, want at least %s", elapsed, latency)
+	}
+}
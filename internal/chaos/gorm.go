@@ -0,0 +1,38 @@
+package chaos
+
+import (
+	"gorm.io/gorm"
+)
+
+// latencyCallbackName names the callback registered before each gorm operation.
+const latencyCallbackName = "chaos:db_latency"
+
+// RegisterDBLatency hooks db so armed db_latency faults delay every statement.
+func RegisterDBLatency(db *gorm.DB) error {
+	if db == nil {
+		return nil
+	}
+	cb := db.Callback()
+	if errRegister := cb.Create().Before("gorm:create").Register(latencyCallbackName, injectLatency); errRegister != nil {
+		return errRegister
+	}
+	if errRegister := cb.Query().Before("gorm:query").Register(latencyCallbackName, injectLatency); errRegister != nil {
+		return errRegister
+	}
+	if errRegister := cb.Update().Before("gorm:update").Register(latencyCallbackName, injectLatency); errRegister != nil {
+		return errRegister
+	}
+	if errRegister := cb.Delete().Before("gorm:delete").Register(latencyCallbackName, injectLatency); errRegister != nil {
+		return errRegister
+	}
+	if errRegister := cb.Row().Before("gorm:row").Register(latencyCallbackName, injectLatency); errRegister != nil {
+		return errRegister
+	}
+	return cb.Raw().Before("gorm:raw").Register(latencyCallbackName, injectLatency)
+}
+
+func injectLatency(tx *gorm.DB) {
+	if delay := DBLatency(); delay > 0 {
+		Sleep(tx.Statement.Context, delay)
+	}
+}
@@ -0,0 +1,140 @@
+// Package cluster coordinates servers that share one database. Instances broadcast change
+// notifications over Redis pub/sub so peers resync their in-memory SDK config right away
+// instead of waiting for the next db watcher poll, and register themselves in
+// cluster_instances for visibility.
+package cluster
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Event kinds broadcast between instances.
+const (
+	// KindConfigChanged reports a change to provider keys, auths, mappings, payload rules or
+	// proxies that feeds the SDK config.
+	KindConfigChanged = "config_changed"
+	// KindSettingsChanged reports a change to DB-backed settings.
+	KindSettingsChanged = "settings_changed"
+)
+
+// Event is one change notification.
+type Event struct {
+	Kind       string    `json:"kind"`        // Event kind.
+	InstanceID string    `json:"instance_id"` // Instance that made the change.
+	At         time.Time `json:"at"`          // When the change was published.
+}
+
+var (
+	instanceID = newInstanceID()
+	startedAt  = time.Now().UTC()
+
+	handlersMu  sync.RWMutex
+	handlers    = map[uint64]func(Event){}
+	nextHandler uint64
+
+	publisherMu sync.RWMutex
+	publisher   func(context.Context, Event) error // Sends to the bus; nil while disconnected.
+)
+
+// InstanceID returns the identifier of this process.
+func InstanceID() string {
+	return instanceID
+}
+
+// Subscribe registers handler for events published locally or by peers and returns a
+// function that removes it. Handlers run synchronously and must not block.
+func Subscribe(handler func(Event)) func() {
+	if handler == nil {
+		return func() {}
+	}
+	handlersMu.Lock()
+	nextHandler++
+	id := nextHandler
+	handlers[id] = handler
+	handlersMu.Unlock()
+	return func() {
+		handlersMu.Lock()
+		delete(handlers, id)
+		handlersMu.Unlock()
+	}
+}
+
+// Publish notifies local subscribers and, while the bus is connected, every other instance.
+func Publish(ctx context.Context, kind string) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	event := Event{Kind: kind, InstanceID: instanceID, At: time.Now().UTC()}
+	dispatch(event)
+
+	publisherMu.RLock()
+	send := publisher
+	publisherMu.RUnlock()
+	if send == nil {
+		return
+	}
+	if errSend := send(ctx, event); errSend != nil {
+		log.WithError(errSend).Warnf("cluster: publish %s failed", kind)
+	}
+}
+
+// setPublisher swaps the function that sends events to the bus.
+func setPublisher(send func(context.Context, Event) error) {
+	publisherMu.Lock()
+	publisher = send
+	publisherMu.Unlock()
+}
+
+// receive dispatches a bus message, skipping events this instance published itself.
+func receive(payload string) {
+	var event Event
+	if errUnmarshal := json.Unmarshal([]byte(payload), &event); errUnmarshal != nil {
+		log.WithError(errUnmarshal).Debug("cluster: ignore malformed message")
+		return
+	}
+	if event.Kind == "" || event.InstanceID == instanceID {
+		return
+	}
+	dispatch(event)
+}
+
+func dispatch(event Event) {
+	handlersMu.RLock()
+	current := make([]func(Event), 0, len(handlers))
+	for _, handler := range handlers {
+		current = append(current, handler)
+	}
+	handlersMu.RUnlock()
+	for _, handler := range current {
+		handler(event)
+	}
+}
+
+// newInstanceID combines the short hostname with a random suffix.
+func newInstanceID() string {
+	host, _ := os.Hostname()
+	host = strings.TrimSpace(host)
+	if idx := strings.Index(host, "."); idx > 0 {
+		host = host[:idx]
+	}
+	if len(host) > 40 {
+		host = host[:40]
+	}
+	if host == "" {
+		host = "instance"
+	}
+	suffix := make([]byte, 4)
+	if _, errRand := rand.Read(suffix); errRand != nil {
+		return host + "-" + time.Now().UTC().Format("150405.000")
+	}
+	return host + "-" + hex.EncodeToString(suffix)
+}
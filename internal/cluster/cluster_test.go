@@ -0,0 +1,124 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"gorm.io/gorm"
+)
+
+func setupClusterDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:cluster_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func storeSettings(t *testing.T, values map[string]string) {
+	t.Helper()
+	raw := make(map[string]json.RawMessage, len(values))
+	for key, value := range values {
+		raw[key] = json.RawMessage(value)
+	}
+	internalsettings.StoreDBConfig(time.Now(), raw)
+	t.Cleanup(func() {
+		internalsettings.StoreDBConfig(time.Now(), map[string]json.RawMessage{})
+	})
+}
+
+func TestLoadConfigDefaultsAndRateLimitRedisFallback(t *testing.T) {
+	storeSettings(t, map[string]string{
+		internalsettings.ClusterBusKey:         `{"enabled":true,"heartbeat_seconds":1}`,
+		internalsettings.RateLimitRedisAddrKey: `"redis.internal:6379"`,
+	})
+
+	cfg := LoadConfig()
+	if !cfg.Enabled || cfg.RedisAddr != "redis.internal:6379" {
+		t.Fatalf("expected rate limit redis fallback, got %+v", cfg)
+	}
+	if cfg.Channel != DefaultChannel || cfg.HeartbeatSeconds != minHeartbeatSeconds {
+		t.Fatalf("unexpected defaults: %+v", cfg)
+	}
+	if cfg.busKey() == "" {
+		t.Fatal("expected enabled config to need a connection")
+	}
+
+	storeSettings(t, map[string]string{internalsettings.ClusterBusKey: `{"redis_addr":"bus:6379"}`})
+	if cfg = LoadConfig(); cfg.busKey() != "" || cfg.HeartbeatSeconds != DefaultHeartbeatSeconds {
+		t.Fatalf("expected disabled bus with default heartbeat, got %+v", cfg)
+	}
+}
+
+func TestPublishDispatchesLocallyAndReceiveSkipsOwnEvents(t *testing.T) {
+	var got []Event
+	unsubscribe := Subscribe(func(event Event) { got = append(got, event) })
+	defer unsubscribe()
+
+	Publish(context.Background(), KindSettingsChanged)
+	if len(got) != 1 || got[0].Kind != KindSettingsChanged || got[0].InstanceID != InstanceID() {
+		t.Fatalf("expected local settings event, got %+v", got)
+	}
+
+	own, _ := json.Marshal(Event{Kind: KindConfigChanged, InstanceID: InstanceID()})
+	receive(string(own))
+	receive("not json")
+	peer, _ := json.Marshal(Event{Kind: KindConfigChanged, InstanceID: "peer-1"})
+	receive(string(peer))
+	if len(got) != 2 || got[1].InstanceID != "peer-1" {
+		t.Fatalf("expected only the peer event to be dispatched, got %+v", got)
+	}
+
+	unsubscribe()
+	Publish(context.Background(), KindConfigChanged)
+	if len(got) != 2 {
+		t.Fatalf("expected no dispatch after unsubscribe, got %+v", got)
+	}
+}
+
+func TestHeartbeatRegistersAndListsInstances(t *testing.T) {
+	conn := setupClusterDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	peer := models.ClusterInstance{InstanceID: "peer-1", StartedAt: now.Add(-time.Hour), LastSeenAt: now.Add(-10 * time.Minute)}
+	gone := models.ClusterInstance{InstanceID: "peer-2", StartedAt: now.Add(-72 * time.Hour), LastSeenAt: now.Add(-48 * time.Hour)}
+	if errCreate := conn.Create(&[]models.ClusterInstance{peer, gone}).Error; errCreate != nil {
+		t.Fatalf("seed instances: %v", errCreate)
+	}
+
+	coordinator := NewCoordinator(conn, "prod")
+	if errBeat := coordinator.Heartbeat(ctx, now.Add(-time.Minute)); errBeat != nil {
+		t.Fatalf("first heartbeat: %v", errBeat)
+	}
+	if errBeat := coordinator.Heartbeat(ctx, now); errBeat != nil {
+		t.Fatalf("second heartbeat: %v", errBeat)
+	}
+
+	instances, errList := ListInstances(ctx, conn, Config{}, now)
+	if errList != nil {
+		t.Fatalf("list instances: %v", errList)
+	}
+	if len(instances) != 2 {
+		t.Fatalf("expected self and peer-1 after pruning, got %+v", instances)
+	}
+	self := instances[0]
+	if !self.Self || !self.Online || self.InstanceID != InstanceID() || self.Environment != "prod" || !self.LastSeenAt.Equal(now) {
+		t.Fatalf("unexpected self row: %+v", self)
+	}
+	if instances[1].InstanceID != "peer-1" || instances[1].Online || instances[1].Self {
+		t.Fatalf("expected stale peer-1 offline, got %+v", instances[1])
+	}
+}
@@ -0,0 +1,309 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/buildinfo"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/ratelimit"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// DefaultChannel is the Redis channel used when none is configured.
+	DefaultChannel = "cpab:cluster"
+	// DefaultHeartbeatSeconds is how often instances refresh their registry row.
+	DefaultHeartbeatSeconds = 30
+	minHeartbeatSeconds     = 5
+	maxHeartbeatSeconds     = 300
+	// staleAfter is how long rows of stopped instances stay listed before they are pruned.
+	staleAfter = 24 * time.Hour
+	// connectTimeout bounds the Redis ping and subscribe handshake.
+	connectTimeout = 5 * time.Second
+)
+
+// Config mirrors the CLUSTER_BUS setting.
+type Config struct {
+	Enabled          bool   `json:"enabled"`           // Whether change events go over Redis.
+	RedisAddr        string `json:"redis_addr"`        // Redis address; empty reuses the rate limit Redis.
+	RedisPassword    string `json:"redis_password"`    // Redis password.
+	RedisDB          int    `json:"redis_db"`          // Redis database index.
+	Channel          string `json:"channel"`           // Pub/sub channel shared by the instances.
+	HeartbeatSeconds int    `json:"heartbeat_seconds"` // How often the registry row is refreshed.
+}
+
+// LoadConfig reads CLUSTER_BUS and fills defaults. Without its own address the bus reuses the
+// Redis configured for rate limiting.
+func LoadConfig() Config {
+	var cfg Config
+	raw, ok := internalsettings.DBConfigValue(internalsettings.ClusterBusKey)
+	if ok && len(bytes.TrimSpace(raw)) > 0 {
+		if errUnmarshal := json.Unmarshal(raw, &cfg); errUnmarshal != nil {
+			log.WithError(errUnmarshal).Warn("cluster: invalid setting")
+			cfg = Config{}
+		}
+	}
+	if strings.TrimSpace(cfg.RedisAddr) == "" {
+		limits := ratelimit.LoadSettingsConfig()
+		cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB = limits.RedisAddr, limits.RedisPassword, limits.RedisDB
+	}
+	return cfg.withDefaults()
+}
+
+func (c Config) withDefaults() Config {
+	c.RedisAddr = strings.TrimSpace(c.RedisAddr)
+	c.Channel = strings.TrimSpace(c.Channel)
+	if c.Channel == "" {
+		c.Channel = DefaultChannel
+	}
+	if c.RedisDB < 0 {
+		c.RedisDB = 0
+	}
+	switch {
+	case c.HeartbeatSeconds <= 0:
+		c.HeartbeatSeconds = DefaultHeartbeatSeconds
+	case c.HeartbeatSeconds < minHeartbeatSeconds:
+		c.HeartbeatSeconds = minHeartbeatSeconds
+	case c.HeartbeatSeconds > maxHeartbeatSeconds:
+		c.HeartbeatSeconds = maxHeartbeatSeconds
+	}
+	return c
+}
+
+// heartbeat returns the heartbeat interval.
+func (c Config) heartbeat() time.Duration {
+	return time.Duration(c.HeartbeatSeconds) * time.Second
+}
+
+// busKey identifies the connection settings so changes trigger a reconnect.
+func (c Config) busKey() string {
+	if !c.Enabled || c.RedisAddr == "" {
+		return ""
+	}
+	return strings.Join([]string{c.RedisAddr, c.RedisPassword, strconv.Itoa(c.RedisDB), c.Channel}, "\x00")
+}
+
+// Coordinator keeps the Redis subscription and the registry row of this instance current.
+type Coordinator struct {
+	db          *gorm.DB
+	environment string
+	newClient   func(*redis.Options) *redis.Client
+
+	mu        sync.Mutex
+	key       string             // busKey of the open connection.
+	client    *redis.Client      // Open Redis client, if any.
+	stopWatch context.CancelFunc // Stops the subscription loop.
+	connected bool               // Whether the subscription is live.
+}
+
+var (
+	activeMu sync.RWMutex
+	active   *Coordinator
+)
+
+// NewCoordinator constructs a coordinator for an instance serving environment; returns nil
+// when db is nil.
+func NewCoordinator(db *gorm.DB, environment string) *Coordinator {
+	if db == nil {
+		return nil
+	}
+	return &Coordinator{db: db, environment: strings.TrimSpace(environment), newClient: redis.NewClient}
+}
+
+// Start launches the heartbeat and bus loop in a background goroutine.
+func (c *Coordinator) Start(ctx context.Context) {
+	if c == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	activeMu.Lock()
+	active = c
+	activeMu.Unlock()
+	go c.run(ctx)
+	log.Infof("cluster coordinator started (instance=%s)", instanceID)
+}
+
+// Connected reports whether the running coordinator is subscribed to the bus.
+func Connected() bool {
+	activeMu.RLock()
+	c := active
+	activeMu.RUnlock()
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.connected
+}
+
+func (c *Coordinator) run(ctx context.Context) {
+	for {
+		cfg := LoadConfig()
+		c.syncBus(ctx, cfg)
+		if errBeat := c.Heartbeat(ctx, time.Now().UTC()); errBeat != nil {
+			log.WithError(errBeat).Warn("cluster: heartbeat failed")
+		}
+		select {
+		case <-ctx.Done():
+			c.closeBus()
+			leaveCtx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+			if errLeave := c.db.WithContext(leaveCtx).Where("instance_id = ?", instanceID).Delete(&models.ClusterInstance{}).Error; errLeave != nil {
+				log.WithError(errLeave).Warn("cluster: remove registry row failed")
+			}
+			cancel()
+			return
+		case <-time.After(cfg.heartbeat()):
+		}
+	}
+}
+
+// syncBus opens, replaces or closes the Redis subscription to match cfg.
+func (c *Coordinator) syncBus(ctx context.Context, cfg Config) {
+	key := cfg.busKey()
+	c.mu.Lock()
+	unchanged := key == c.key && (key == "" || c.connected)
+	c.mu.Unlock()
+	if unchanged {
+		return
+	}
+	c.closeBus()
+	if key == "" {
+		return
+	}
+
+	client := c.newClient(&redis.Options{Addr: cfg.RedisAddr, Password: cfg.RedisPassword, DB: cfg.RedisDB})
+	connectCtx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+	if errPing := client.Ping(connectCtx).Err(); errPing != nil {
+		log.WithError(errPing).Warn("cluster: redis unavailable, peers sync on their poll interval")
+		_ = client.Close()
+		return
+	}
+	sub := client.Subscribe(ctx, cfg.Channel)
+	if _, errReceive := sub.Receive(connectCtx); errReceive != nil {
+		log.WithError(errReceive).Warn("cluster: subscribe failed")
+		_ = sub.Close()
+		_ = client.Close()
+		return
+	}
+
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	c.mu.Lock()
+	c.key, c.client, c.stopWatch, c.connected = key, client, stopWatch, true
+	c.mu.Unlock()
+	channel := cfg.Channel
+	setPublisher(func(ctx context.Context, event Event) error {
+		payload, errMarshal := json.Marshal(event)
+		if errMarshal != nil {
+			return errMarshal
+		}
+		return client.Publish(ctx, channel, payload).Err()
+	})
+	go c.watch(watchCtx, sub)
+	log.Infof("cluster: subscribed to %s", channel)
+}
+
+// watch forwards bus messages until the subscription closes.
+func (c *Coordinator) watch(ctx context.Context, sub *redis.PubSub) {
+	defer func() { _ = sub.Close() }()
+	messages := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				c.mu.Lock()
+				c.connected = false
+				c.mu.Unlock()
+				return
+			}
+			receive(msg.Payload)
+		}
+	}
+}
+
+// closeBus drops the subscription and the Redis client.
+func (c *Coordinator) closeBus() {
+	c.mu.Lock()
+	client, stopWatch := c.client, c.stopWatch
+	c.key, c.client, c.stopWatch, c.connected = "", nil, nil, false
+	c.mu.Unlock()
+	setPublisher(nil)
+	if stopWatch != nil {
+		stopWatch()
+	}
+	if client != nil {
+		_ = client.Close()
+	}
+}
+
+// Heartbeat upserts the registry row of this instance and prunes rows of instances that
+// stopped long ago.
+func (c *Coordinator) Heartbeat(ctx context.Context, now time.Time) error {
+	if c == nil || c.db == nil {
+		return nil
+	}
+	c.mu.Lock()
+	connected := c.connected
+	c.mu.Unlock()
+	hostname, _ := os.Hostname()
+	row := models.ClusterInstance{
+		InstanceID:   instanceID,
+		Hostname:     strings.TrimSpace(hostname),
+		Version:      buildinfo.Version,
+		Environment:  c.environment,
+		BusConnected: connected,
+		StartedAt:    startedAt,
+		LastSeenAt:   now,
+	}
+	if errUpsert := c.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "instance_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"hostname", "version", "environment", "bus_connected", "last_seen_at"}),
+	}).Create(&row).Error; errUpsert != nil {
+		return errUpsert
+	}
+	return c.db.WithContext(ctx).Where("last_seen_at < ?", now.Add(-staleAfter)).Delete(&models.ClusterInstance{}).Error
+}
+
+// InstanceStatus is one registry row with its liveness.
+type InstanceStatus struct {
+	models.ClusterInstance
+	Online bool `json:"online"` // Heartbeat seen within three intervals.
+	Self   bool `json:"self"`   // Row of the instance answering the request.
+}
+
+// ListInstances returns registered instances, most recently seen first.
+func ListInstances(ctx context.Context, db *gorm.DB, cfg Config, now time.Time) ([]InstanceStatus, error) {
+	if db == nil {
+		return nil, errors.New("cluster: nil db")
+	}
+	var rows []models.ClusterInstance
+	if errFind := db.WithContext(ctx).Order("last_seen_at DESC").Order("id ASC").Find(&rows).Error; errFind != nil {
+		return nil, errFind
+	}
+	onlineAfter := now.Add(-3 * cfg.withDefaults().heartbeat())
+	out := make([]InstanceStatus, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, InstanceStatus{
+			ClusterInstance: row,
+			Online:          row.LastSeenAt.After(onlineAfter),
+			Self:            row.InstanceID == instanceID,
+		})
+	}
+	return out, nil
+}
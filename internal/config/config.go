@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,6 +17,25 @@ const (
 	EnvDBConnection = "DB_CONNECTION"
 	EnvJWTSecret    = "JWT_SECRET"
 	EnvJWTExpiry    = "JWT_EXPIRY"
+
+	EnvEncryptionEnabled = "ENCRYPTION_ENABLED"
+	EnvEncryptionKey     = "ENCRYPTION_KEY"
+	EnvEncryptionKeyID   = "ENCRYPTION_KEY_ID"
+
+	EnvTracingEnabled    = "TRACING_ENABLED"
+	EnvOTLPEndpoint      = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	EnvOTLPHeaders       = "OTEL_EXPORTER_OTLP_HEADERS"
+	EnvOTelServiceName   = "OTEL_SERVICE_NAME"
+	EnvTracingSampleRate = "TRACING_SAMPLE_RATIO"
+
+	EnvEnvironment = "CPAB_ENVIRONMENT"
+
+	EnvUsageArchiveDir = "USAGE_ARCHIVE_DIR"
+	EnvUsageOutboxPath = "USAGE_OUTBOX_PATH"
+
+	EnvStandbyDir = "STANDBY_STATE_DIR"
+
+	EnvConsistencyAutoRepair = "CONSISTENCY_AUTO_REPAIR"
 )
 
 // AppConfig holds resolved application configuration values.
@@ -116,3 +136,349 @@ func LoadJWTConfig(configPath string) (JWTConfig, error) {
 	}
 	return result, nil
 }
+
+// defaultEncryptionKeyID names the master key when the config does not.
+const defaultEncryptionKeyID = "primary"
+
+// EncryptionConfig holds settings for encrypting secrets stored in the database.
+type EncryptionConfig struct {
+	Enabled      bool              `yaml:"enabled"`       // Encrypt new writes of auth content and provider keys.
+	KeyID        string            `yaml:"key-id"`        // Identifier of the current master key.
+	Key          string            `yaml:"key"`           // Current master key (base64 or hex, 32 bytes).
+	PreviousKeys map[string]string `yaml:"previous-keys"` // Retired master keys kept for decryption.
+}
+
+// LoadEncryptionConfig loads encryption settings from the YAML config file and environment.
+func LoadEncryptionConfig(configPath string) (EncryptionConfig, error) {
+	// fileConfig maps the YAML fields needed for encryption settings.
+	type fileConfig struct {
+		Encryption EncryptionConfig `yaml:"encryption"`
+	}
+
+	var result EncryptionConfig
+	data, errRead := os.ReadFile(configPath)
+	if errRead == nil {
+		var cfg fileConfig
+		if errUnmarshal := yaml.Unmarshal(data, &cfg); errUnmarshal != nil {
+			return EncryptionConfig{}, fmt.Errorf("parse config file: %w", errUnmarshal)
+		}
+		result = cfg.Encryption
+	}
+
+	if key := strings.TrimSpace(os.Getenv(EnvEncryptionKey)); key != "" {
+		result.Key = key
+	}
+	if keyID := strings.TrimSpace(os.Getenv(EnvEncryptionKeyID)); keyID != "" {
+		result.KeyID = keyID
+	}
+	if enabledRaw := strings.TrimSpace(os.Getenv(EnvEncryptionEnabled)); enabledRaw != "" {
+		enabled, errParse := strconv.ParseBool(enabledRaw)
+		if errParse != nil {
+			return EncryptionConfig{}, fmt.Errorf("parse %s: %w", EnvEncryptionEnabled, errParse)
+		}
+		result.Enabled = enabled
+	}
+
+	result.Key = strings.TrimSpace(result.Key)
+	result.KeyID = strings.TrimSpace(result.KeyID)
+	if result.KeyID == "" {
+		result.KeyID = defaultEncryptionKeyID
+	}
+	if result.Enabled && result.Key == "" {
+		return EncryptionConfig{}, errors.New("encryption enabled but no key configured (set `encryption.key` or ENCRYPTION_KEY)")
+	}
+	return result, nil
+}
+
+// defaultTracingServiceName is reported as service.name when the config does not set one.
+const defaultTracingServiceName = "cpab"
+
+// TracingConfig holds OpenTelemetry tracing and OTLP/HTTP exporter settings.
+type TracingConfig struct {
+	Enabled     bool              `yaml:"enabled"`      // Record spans and export them.
+	Endpoint    string            `yaml:"endpoint"`     // OTLP/HTTP collector base URL, e.g. http://127.0.0.1:4318.
+	ServiceName string            `yaml:"service-name"` // service.name resource attribute.
+	Headers     map[string]string `yaml:"headers"`      // Extra exporter request headers.
+	SampleRatio float64           `yaml:"sample-ratio"` // Fraction of new traces to record; 0 means all.
+}
+
+// LoadTracingConfig loads tracing settings from the YAML config file and environment.
+func LoadTracingConfig(configPath string) (TracingConfig, error) {
+	// fileConfig maps the YAML fields needed for tracing settings.
+	type fileConfig struct {
+		Tracing TracingConfig `yaml:"tracing"`
+	}
+
+	var result TracingConfig
+	data, errRead := os.ReadFile(configPath)
+	if errRead == nil {
+		var cfg fileConfig
+		if errUnmarshal := yaml.Unmarshal(data, &cfg); errUnmarshal != nil {
+			return TracingConfig{}, fmt.Errorf("parse config file: %w", errUnmarshal)
+		}
+		result = cfg.Tracing
+	}
+
+	if endpoint := strings.TrimSpace(os.Getenv(EnvOTLPEndpoint)); endpoint != "" {
+		result.Endpoint = endpoint
+	}
+	if serviceName := strings.TrimSpace(os.Getenv(EnvOTelServiceName)); serviceName != "" {
+		result.ServiceName = serviceName
+	}
+	if headersRaw := strings.TrimSpace(os.Getenv(EnvOTLPHeaders)); headersRaw != "" {
+		if result.Headers == nil {
+			result.Headers = make(map[string]string)
+		}
+		for _, pair := range strings.Split(headersRaw, ",") {
+			key, value, ok := strings.Cut(pair, "=")
+			if !ok || strings.TrimSpace(key) == "" {
+				return TracingConfig{}, fmt.Errorf("parse %s: invalid header %q", EnvOTLPHeaders, pair)
+			}
+			result.Headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	if ratioRaw := strings.TrimSpace(os.Getenv(EnvTracingSampleRate)); ratioRaw != "" {
+		ratio, errParse := strconv.ParseFloat(ratioRaw, 64)
+		if errParse != nil {
+			return TracingConfig{}, fmt.Errorf("parse %s: %w", EnvTracingSampleRate, errParse)
+		}
+		result.SampleRatio = ratio
+	}
+	if enabledRaw := strings.TrimSpace(os.Getenv(EnvTracingEnabled)); enabledRaw != "" {
+		enabled, errParse := strconv.ParseBool(enabledRaw)
+		if errParse != nil {
+			return TracingConfig{}, fmt.Errorf("parse %s: %w", EnvTracingEnabled, errParse)
+		}
+		result.Enabled = enabled
+	}
+
+	result.Endpoint = strings.TrimSpace(result.Endpoint)
+	result.ServiceName = strings.TrimSpace(result.ServiceName)
+	if result.ServiceName == "" {
+		result.ServiceName = defaultTracingServiceName
+	}
+	if result.SampleRatio < 0 || result.SampleRatio > 1 {
+		return TracingConfig{}, fmt.Errorf("tracing sample-ratio must be between 0 and 1, got %v", result.SampleRatio)
+	}
+	if result.Enabled && result.Endpoint == "" {
+		return TracingConfig{}, errors.New("tracing enabled but no endpoint configured (set `tracing.endpoint` or OTEL_EXPORTER_OTLP_ENDPOINT)")
+	}
+	return result, nil
+}
+
+// EnvironmentTarget is another CLIProxyAPI instance whose credentials are managed from this panel.
+type EnvironmentTarget struct {
+	Name       string `yaml:"name"`        // Environment tag, e.g. "staging".
+	ConfigPath string `yaml:"config-path"` // Config file that receives the environment's provider keys.
+	AuthDir    string `yaml:"auth-dir"`    // Optional directory that receives the environment's auth files.
+}
+
+// EnvironmentsConfig holds the environment served by this instance and the extra sync targets.
+type EnvironmentsConfig struct {
+	Current string              `yaml:"current"` // Environment tag of this instance; empty serves every credential.
+	Targets []EnvironmentTarget `yaml:"targets"` // Additional environments kept in sync.
+}
+
+// LoadEnvironmentsConfig loads environment tagging settings from the YAML config file and environment.
+func LoadEnvironmentsConfig(configPath string) (EnvironmentsConfig, error) {
+	// fileConfig maps the YAML fields needed for environment settings.
+	type fileConfig struct {
+		Environments EnvironmentsConfig `yaml:"environments"`
+	}
+
+	var result EnvironmentsConfig
+	data, errRead := os.ReadFile(configPath)
+	if errRead == nil {
+		var cfg fileConfig
+		if errUnmarshal := yaml.Unmarshal(data, &cfg); errUnmarshal != nil {
+			return EnvironmentsConfig{}, fmt.Errorf("parse config file: %w", errUnmarshal)
+		}
+		result = cfg.Environments
+	}
+	if current := strings.TrimSpace(os.Getenv(EnvEnvironment)); current != "" {
+		result.Current = current
+	}
+
+	result.Current = strings.ToLower(strings.TrimSpace(result.Current))
+	seen := map[string]struct{}{}
+	if result.Current != "" {
+		seen[result.Current] = struct{}{}
+	}
+	targets := make([]EnvironmentTarget, 0, len(result.Targets))
+	for _, target := range result.Targets {
+		target.Name = strings.ToLower(strings.TrimSpace(target.Name))
+		target.ConfigPath = strings.TrimSpace(target.ConfigPath)
+		target.AuthDir = strings.TrimSpace(target.AuthDir)
+		if target.Name == "" {
+			return EnvironmentsConfig{}, errors.New("environment target is missing `name`")
+		}
+		if _, exists := seen[target.Name]; exists {
+			return EnvironmentsConfig{}, fmt.Errorf("duplicate environment %q", target.Name)
+		}
+		if target.ConfigPath == "" && target.AuthDir == "" {
+			return EnvironmentsConfig{}, fmt.Errorf("environment %q needs `config-path` or `auth-dir`", target.Name)
+		}
+		if target.ConfigPath != "" {
+			target.ConfigPath = ResolveConfigPath(target.ConfigPath)
+			if target.ConfigPath == ResolveConfigPath(configPath) {
+				return EnvironmentsConfig{}, fmt.Errorf("environment %q must not target this instance's config file", target.Name)
+			}
+		}
+		seen[target.Name] = struct{}{}
+		targets = append(targets, target)
+	}
+	result.Targets = targets
+	return result, nil
+}
+
+// UsageArchiveConfig controls where usages rows are exported before retention deletes them.
+type UsageArchiveConfig struct {
+	Dir string `yaml:"dir"` // Directory receiving gzip-compressed JSONL files; empty disables export.
+}
+
+// LoadUsageArchiveConfig loads usage archive settings from the YAML config file and environment.
+func LoadUsageArchiveConfig(configPath string) (UsageArchiveConfig, error) {
+	// fileConfig maps the YAML fields needed for usage archive settings.
+	type fileConfig struct {
+		UsageArchive UsageArchiveConfig `yaml:"usage-archive"`
+	}
+
+	var result UsageArchiveConfig
+	data, errRead := os.ReadFile(configPath)
+	if errRead == nil {
+		var cfg fileConfig
+		if errUnmarshal := yaml.Unmarshal(data, &cfg); errUnmarshal != nil {
+			return UsageArchiveConfig{}, fmt.Errorf("parse config file: %w", errUnmarshal)
+		}
+		result = cfg.UsageArchive
+	}
+	if dir := strings.TrimSpace(os.Getenv(EnvUsageArchiveDir)); dir != "" {
+		result.Dir = dir
+	}
+	result.Dir = strings.TrimSpace(result.Dir)
+	if result.Dir != "" {
+		if abs, errAbs := filepath.Abs(result.Dir); errAbs == nil {
+			result.Dir = abs
+		}
+	}
+	return result, nil
+}
+
+// defaultUsageOutboxPath is the usage writer journal used when none is configured.
+const defaultUsageOutboxPath = "./data/usage-outbox.jsonl"
+
+// UsageWriterConfig controls the asynchronous usage writer.
+type UsageWriterConfig struct {
+	OutboxPath string `yaml:"outbox-path"` // Journal of unpersisted usage records replayed on start.
+}
+
+// LoadUsageWriterConfig loads usage writer settings from the YAML config file and environment.
+func LoadUsageWriterConfig(configPath string) (UsageWriterConfig, error) {
+	// fileConfig maps the YAML fields needed for usage writer settings.
+	type fileConfig struct {
+		UsageWriter UsageWriterConfig `yaml:"usage-writer"`
+	}
+
+	var result UsageWriterConfig
+	data, errRead := os.ReadFile(configPath)
+	if errRead == nil {
+		var cfg fileConfig
+		if errUnmarshal := yaml.Unmarshal(data, &cfg); errUnmarshal != nil {
+			return UsageWriterConfig{}, fmt.Errorf("parse config file: %w", errUnmarshal)
+		}
+		result = cfg.UsageWriter
+	}
+	if path := strings.TrimSpace(os.Getenv(EnvUsageOutboxPath)); path != "" {
+		result.OutboxPath = path
+	}
+	result.OutboxPath = strings.TrimSpace(result.OutboxPath)
+	if result.OutboxPath == "" {
+		result.OutboxPath = defaultUsageOutboxPath
+	}
+	if abs, errAbs := filepath.Abs(result.OutboxPath); errAbs == nil {
+		result.OutboxPath = abs
+	}
+	return result, nil
+}
+
+// Default standby export cadence and the oldest snapshot restored on startup.
+const (
+	defaultStandbyInterval = 5 * time.Second
+	defaultStandbyMaxAge   = 2 * time.Minute
+)
+
+// StandbyConfig controls the runtime state snapshot shared with a warm standby instance.
+type StandbyConfig struct {
+	Dir      string        `yaml:"dir"`      // Shared directory holding the snapshot; empty disables export and restore.
+	Interval time.Duration `yaml:"interval"` // How often the snapshot is rewritten; defaults to 5s.
+	MaxAge   time.Duration `yaml:"max-age"`  // Older snapshots are ignored on startup; defaults to 2m.
+}
+
+// LoadStandbyConfig loads standby state export settings from the YAML config file and environment.
+func LoadStandbyConfig(configPath string) (StandbyConfig, error) {
+	// fileConfig maps the YAML fields needed for standby settings.
+	type fileConfig struct {
+		Standby StandbyConfig `yaml:"standby"`
+	}
+
+	var result StandbyConfig
+	data, errRead := os.ReadFile(configPath)
+	if errRead == nil {
+		var cfg fileConfig
+		if errUnmarshal := yaml.Unmarshal(data, &cfg); errUnmarshal != nil {
+			return StandbyConfig{}, fmt.Errorf("parse config file: %w", errUnmarshal)
+		}
+		result = cfg.Standby
+	}
+	if dir := strings.TrimSpace(os.Getenv(EnvStandbyDir)); dir != "" {
+		result.Dir = dir
+	}
+	result.Dir = strings.TrimSpace(result.Dir)
+	if result.Dir != "" {
+		if abs, errAbs := filepath.Abs(result.Dir); errAbs == nil {
+			result.Dir = abs
+		}
+	}
+	if result.Interval < 0 || result.MaxAge < 0 {
+		return StandbyConfig{}, errors.New("standby interval and max-age must not be negative")
+	}
+	if result.Interval == 0 {
+		result.Interval = defaultStandbyInterval
+	}
+	if result.MaxAge == 0 {
+		result.MaxAge = defaultStandbyMaxAge
+	}
+	return result, nil
+}
+
+// ConsistencyConfig controls the boot-time consistency check between the database and the
+// config file.
+type ConsistencyConfig struct {
+	AutoRepair bool `yaml:"auto-repair"` // Repair fixable problems at boot instead of only reporting them.
+}
+
+// LoadConsistencyConfig loads consistency check settings from the YAML config file and environment.
+func LoadConsistencyConfig(configPath string) (ConsistencyConfig, error) {
+	// fileConfig maps the YAML fields needed for consistency check settings.
+	type fileConfig struct {
+		Consistency ConsistencyConfig `yaml:"consistency-check"`
+	}
+
+	var result ConsistencyConfig
+	data, errRead := os.ReadFile(configPath)
+	if errRead == nil {
+		var cfg fileConfig
+		if errUnmarshal := yaml.Unmarshal(data, &cfg); errUnmarshal != nil {
+			return ConsistencyConfig{}, fmt.Errorf("parse config file: %w", errUnmarshal)
+		}
+		result = cfg.Consistency
+	}
+	if raw := strings.TrimSpace(os.Getenv(EnvConsistencyAutoRepair)); raw != "" {
+		autoRepair, errParse := strconv.ParseBool(raw)
+		if errParse != nil {
+			return ConsistencyConfig{}, fmt.Errorf("parse %s: %w", EnvConsistencyAutoRepair, errParse)
+		}
+		result.AutoRepair = autoRepair
+	}
+	return result, nil
+}
@@ -40,3 +40,143 @@ func TestLoadJWTConfig_EnvOverride(t *testing.T) {
 		t.Fatalf("expected expiry=%s, got %s", (2 * time.Hour).String(), cfg.Expiry.String())
 	}
 }
+
+func TestLoadEncryptionConfig_EnvOverride(t *testing.T) {
+	t.Setenv("ENCRYPTION_ENABLED", "true")
+	t.Setenv("ENCRYPTION_KEY", "env-key")
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	content := "encryption:\n  enabled: false\n  key-id: k2\n  key: file-key\n  previous-keys:\n    k1: old-key\n"
+	if err := os.WriteFile(configPath, []byte(content), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := LoadEncryptionConfig(configPath)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !cfg.Enabled || cfg.Key != "env-key" || cfg.KeyID != "k2" {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+	if cfg.PreviousKeys["k1"] != "old-key" {
+		t.Fatalf("expected previous key k1, got %+v", cfg.PreviousKeys)
+	}
+}
+
+func TestLoadEncryptionConfig_EnabledWithoutKey(t *testing.T) {
+	t.Setenv("ENCRYPTION_ENABLED", "1")
+	t.Setenv("ENCRYPTION_KEY", "")
+
+	if _, err := LoadEncryptionConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Fatalf("expected error when encryption is enabled without a key")
+	}
+}
+
+func TestLoadTracingConfig_EnvOverride(t *testing.T) {
+	t.Setenv("TRACING_ENABLED", "true")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "authorization=Bearer abc, x-tenant=cpab")
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	content := "tracing:\n  enabled: false\n  endpoint: http://file:4318\n  service-name: cpab-eu\n  sample-ratio: 0.25\n"
+	if err := os.WriteFile(configPath, []byte(content), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := LoadTracingConfig(configPath)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !cfg.Enabled || cfg.Endpoint != "http://collector:4318" || cfg.ServiceName != "cpab-eu" || cfg.SampleRatio != 0.25 {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+	if cfg.Headers["authorization"] != "Bearer abc" || cfg.Headers["x-tenant"] != "cpab" {
+		t.Fatalf("unexpected headers: %+v", cfg.Headers)
+	}
+}
+
+func TestLoadTracingConfig_EnabledWithoutEndpoint(t *testing.T) {
+	t.Setenv("TRACING_ENABLED", "1")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+
+	if _, err := LoadTracingConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Fatalf("expected error when tracing is enabled without an endpoint")
+	}
+}
+
+func TestLoadEnvironmentsConfig(t *testing.T) {
+	t.Setenv("CPAB_ENVIRONMENT", "")
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	content := "environments:\n  current: Prod\n  targets:\n    - name: Staging\n      config-path: " + filepath.Join(dir, "staging.yaml") + "\n      auth-dir: " + filepath.Join(dir, "staging-auths") + "\n"
+	if err := os.WriteFile(configPath, []byte(content), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := LoadEnvironmentsConfig(configPath)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.Current != "prod" || len(cfg.Targets) != 1 || cfg.Targets[0].Name != "staging" {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+
+	t.Setenv("CPAB_ENVIRONMENT", "staging")
+	if _, err := LoadEnvironmentsConfig(configPath); err == nil {
+		t.Fatalf("expected error when a target duplicates the current environment")
+	}
+}
+
+func TestLoadUsageArchiveConfig(t *testing.T) {
+	t.Setenv("USAGE_ARCHIVE_DIR", "")
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("usage-archive:\n  dir: \" "+filepath.Join(dir, "archive")+" \"\n"), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := LoadUsageArchiveConfig(configPath)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.Dir != filepath.Join(dir, "archive") {
+		t.Fatalf("unexpected archive dir: %q", cfg.Dir)
+	}
+
+	override := filepath.Join(dir, "override")
+	t.Setenv("USAGE_ARCHIVE_DIR", override)
+	cfg, err = LoadUsageArchiveConfig(configPath)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.Dir != override {
+		t.Fatalf("expected env override, got %q", cfg.Dir)
+	}
+}
+
+func TestLoadStandbyConfig(t *testing.T) {
+	t.Setenv("STANDBY_STATE_DIR", "")
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("standby:\n  dir: "+filepath.Join(dir, "state")+"\n  interval: 10s\n"), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := LoadStandbyConfig(configPath)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.Dir != filepath.Join(dir, "state") || cfg.Interval != 10*time.Second || cfg.MaxAge != defaultStandbyMaxAge {
+		t.Fatalf("unexpected standby config: %+v", cfg)
+	}
+
+	if err := os.WriteFile(configPath, []byte("standby:\n  interval: -1s\n"), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err := LoadStandbyConfig(configPath); err == nil {
+		t.Fatal("expected negative interval to be rejected")
+	}
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	EnvPayloadStoreBackend         = "PAYLOAD_STORE_BACKEND"
+	EnvPayloadStoreDir             = "PAYLOAD_STORE_DIR"
+	EnvPayloadStoreBucket          = "PAYLOAD_STORE_BUCKET"
+	EnvPayloadStoreAccessKeyID     = "PAYLOAD_STORE_ACCESS_KEY_ID"
+	EnvPayloadStoreSecretAccessKey = "PAYLOAD_STORE_SECRET_ACCESS_KEY"
+)
+
+// PayloadStoreConfig selects where captured request bodies are kept.
+type PayloadStoreConfig struct {
+	Backend         string `yaml:"backend"`           // database (default), local, s3 or gcs.
+	Dir             string `yaml:"dir"`               // Root directory of the local backend.
+	Bucket          string `yaml:"bucket"`            // Bucket of the s3 and gcs backends.
+	Prefix          string `yaml:"prefix"`            // Key prefix inside the directory or bucket.
+	Endpoint        string `yaml:"endpoint"`          // Service URL for S3-compatible services; optional.
+	Region          string `yaml:"region"`            // Signing region; optional.
+	AccessKeyID     string `yaml:"access-key-id"`     // Access key, or a GCS HMAC key ID.
+	SecretAccessKey string `yaml:"secret-access-key"` // Secret key, or a GCS HMAC secret.
+	PathStyle       bool   `yaml:"path-style"`        // Address the bucket in the URL path.
+}
+
+// LoadPayloadStoreConfig loads payload store settings from the YAML config file and environment.
+func LoadPayloadStoreConfig(configPath string) (PayloadStoreConfig, error) {
+	// fileConfig maps the YAML fields needed for payload store settings.
+	type fileConfig struct {
+		PayloadStore PayloadStoreConfig `yaml:"payload-store"`
+	}
+
+	var result PayloadStoreConfig
+	data, errRead := os.ReadFile(configPath)
+	if errRead == nil {
+		var cfg fileConfig
+		if errUnmarshal := yaml.Unmarshal(data, &cfg); errUnmarshal != nil {
+			return PayloadStoreConfig{}, fmt.Errorf("parse config file: %w", errUnmarshal)
+		}
+		result = cfg.PayloadStore
+	}
+	for env, target := range map[string]*string{
+		EnvPayloadStoreBackend:         &result.Backend,
+		EnvPayloadStoreDir:             &result.Dir,
+		EnvPayloadStoreBucket:          &result.Bucket,
+		EnvPayloadStoreAccessKeyID:     &result.AccessKeyID,
+		EnvPayloadStoreSecretAccessKey: &result.SecretAccessKey,
+	} {
+		if value := strings.TrimSpace(os.Getenv(env)); value != "" {
+			*target = value
+		}
+	}
+
+	result.Backend = strings.ToLower(strings.TrimSpace(result.Backend))
+	result.Dir = strings.TrimSpace(result.Dir)
+	result.Bucket = strings.TrimSpace(result.Bucket)
+	switch result.Backend {
+	case "", "database":
+	case "local":
+		if result.Dir == "" {
+			return PayloadStoreConfig{}, errors.New("payload-store: local backend requires dir")
+		}
+		if abs, errAbs := filepath.Abs(result.Dir); errAbs == nil {
+			result.Dir = abs
+		}
+	case "s3", "gcs":
+		if result.Bucket == "" {
+			return PayloadStoreConfig{}, fmt.Errorf("payload-store: %s backend requires bucket", result.Backend)
+		}
+		if strings.TrimSpace(result.AccessKeyID) == "" || strings.TrimSpace(result.SecretAccessKey) == "" {
+			return PayloadStoreConfig{}, fmt.Errorf("payload-store: %s backend requires access-key-id and secret-access-key", result.Backend)
+		}
+	default:
+		return PayloadStoreConfig{}, fmt.Errorf("payload-store: unknown backend %q", result.Backend)
+	}
+	return result, nil
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadPayloadStoreConfig(t *testing.T) {
+	for _, env := range []string{"PAYLOAD_STORE_BACKEND", "PAYLOAD_STORE_DIR", "PAYLOAD_STORE_BUCKET", "PAYLOAD_STORE_ACCESS_KEY_ID", "PAYLOAD_STORE_SECRET_ACCESS_KEY"} {
+		t.Setenv(env, "")
+	}
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("payload-store:\n  backend: GCS\n  bucket: captures\n  access-key-id: GOOG1KEY\n"), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err := LoadPayloadStoreConfig(configPath); err == nil {
+		t.Fatalf("expected error without a secret")
+	}
+
+	t.Setenv("PAYLOAD_STORE_SECRET_ACCESS_KEY", "secret")
+	cfg, err := LoadPayloadStoreConfig(configPath)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.Backend != "gcs" || cfg.Bucket != "captures" || cfg.SecretAccessKey != "secret" {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+
+	t.Setenv("PAYLOAD_STORE_BACKEND", "local")
+	if _, err := LoadPayloadStoreConfig(configPath); err == nil {
+		t.Fatalf("expected error for local backend without dir")
+	}
+	t.Setenv("PAYLOAD_STORE_DIR", filepath.Join(dir, "payloads"))
+	cfg, err = LoadPayloadStoreConfig(configPath)
+	if err != nil || cfg.Dir != filepath.Join(dir, "payloads") {
+		t.Fatalf("unexpected local config: %+v %v", cfg, err)
+	}
+}
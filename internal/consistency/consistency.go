@@ -0,0 +1,373 @@
+// Package consistency cross-checks database state against the config file at boot. It
+// reports keys listed in the config file that the database does not know or has disabled,
+// auth files that reference deleted auth groups, and billing rules that point at deleted
+// groups. Database-side problems can be repaired; config file problems are only reported
+// because the file is owned by the operator.
+package consistency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// Issue codes.
+const (
+	// IssueOrphanedAPIKey is a config api-keys entry without a database API key.
+	IssueOrphanedAPIKey = "orphaned_api_key"
+	// IssueDisabledAPIKey is a config api-keys entry whose database API key is inactive, revoked or expired.
+	IssueDisabledAPIKey = "disabled_api_key"
+	// IssueOrphanedProviderKey is a config provider key without a database provider key; the
+	// database copy replaces the config sections at runtime, so the entry is ignored.
+	IssueOrphanedProviderKey = "orphaned_provider_key"
+	// IssueDisabledProviderKey is a config provider key whose database row is disabled.
+	IssueDisabledProviderKey = "disabled_provider_key"
+	// IssueAuthMissingGroup is an auth file referencing a deleted auth group.
+	IssueAuthMissingGroup = "auth_missing_group"
+	// IssueBillingRuleMissingGroup is a billing rule pointing at a deleted auth or user group.
+	IssueBillingRuleMissingGroup = "billing_rule_missing_group"
+)
+
+// Issue describes one inconsistency.
+type Issue struct {
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	Table      string `json:"table,omitempty"`  // Table of the affected row.
+	RowID      uint64 `json:"row_id,omitempty"` // Affected row.
+	Repairable bool   `json:"repairable"`       // Whether Repair can fix it.
+	Repaired   bool   `json:"repaired"`         // Whether Repair fixed it.
+}
+
+// Report holds the outcome of one check.
+type Report struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Issues    []Issue   `json:"issues"`
+}
+
+// Repaired counts the repaired issues.
+func (r Report) Repaired() int {
+	n := 0
+	for _, issue := range r.Issues {
+		if issue.Repaired {
+			n++
+		}
+	}
+	return n
+}
+
+// fileKeys maps the config file sections holding keys.
+type fileKeys struct {
+	APIKeys    []string          `yaml:"api-keys"`
+	GeminiKeys []fileProviderKey `yaml:"gemini-api-key"`
+	CodexKeys  []fileProviderKey `yaml:"codex-api-key"`
+	ClaudeKeys []fileProviderKey `yaml:"claude-api-key"`
+	OpenAI     []struct {
+		Name          string            `yaml:"name"`
+		APIKeyEntries []fileProviderKey `yaml:"api-key-entries"`
+	} `yaml:"openai-compatibility"`
+}
+
+// fileProviderKey is one provider key entry of the config file.
+type fileProviderKey struct {
+	APIKey string `yaml:"api-key"`
+}
+
+// Check runs every check. A missing config file skips the config checks.
+func Check(ctx context.Context, db *gorm.DB, configPath string, now time.Time) (Report, error) {
+	report := Report{CheckedAt: now.UTC(), Issues: []Issue{}}
+	if db == nil {
+		return report, fmt.Errorf("consistency: nil db")
+	}
+	var keys fileKeys
+	if data, errRead := os.ReadFile(configPath); errRead == nil {
+		if errUnmarshal := yaml.Unmarshal(data, &keys); errUnmarshal != nil {
+			return report, fmt.Errorf("consistency: parse config file: %w", errUnmarshal)
+		}
+	}
+	for _, check := range []func(context.Context, *gorm.DB, fileKeys, time.Time) ([]Issue, error){
+		checkAPIKeys,
+		checkProviderKeys,
+		checkAuthGroups,
+		checkBillingRules,
+	} {
+		issues, errCheck := check(ctx, db, keys, now)
+		if errCheck != nil {
+			return report, errCheck
+		}
+		report.Issues = append(report.Issues, issues...)
+	}
+	return report, nil
+}
+
+// checkAPIKeys compares the config api-keys with the api_keys table.
+func checkAPIKeys(ctx context.Context, db *gorm.DB, keys fileKeys, now time.Time) ([]Issue, error) {
+	var issues []Issue
+	for _, key := range keys.APIKeys {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		var row models.APIKey
+		found := db.WithContext(ctx).Where("api_key = ?", key).Limit(1).Find(&row)
+		if found.Error != nil {
+			return nil, fmt.Errorf("consistency: query api key: %w", found.Error)
+		}
+		switch {
+		case found.RowsAffected == 0:
+			issues = append(issues, Issue{
+				Code:    IssueOrphanedAPIKey,
+				Message: fmt.Sprintf("config api-keys entry %s has no API key in the database", maskKey(key)),
+			})
+		case !row.Active || row.RevokedAt != nil || (row.ExpiresAt != nil && !row.ExpiresAt.After(now)):
+			issues = append(issues, Issue{
+				Code:    IssueDisabledAPIKey,
+				Message: fmt.Sprintf("config api-keys entry %s belongs to disabled API key %d", maskKey(key), row.ID),
+				Table:   "api_keys",
+				RowID:   row.ID,
+			})
+		}
+	}
+	return issues, nil
+}
+
+// checkProviderKeys compares the config provider sections with the provider_api_keys table.
+func checkProviderKeys(ctx context.Context, db *gorm.DB, keys fileKeys, _ time.Time) ([]Issue, error) {
+	if len(keys.GeminiKeys)+len(keys.CodexKeys)+len(keys.ClaudeKeys)+len(keys.OpenAI) == 0 {
+		return nil, nil
+	}
+	var rows []models.ProviderAPIKey
+	if errFind := db.WithContext(ctx).Order("id ASC").Find(&rows).Error; errFind != nil {
+		return nil, fmt.Errorf("consistency: list provider api keys: %w", errFind)
+	}
+	byKey := make(map[string]*models.ProviderAPIKey)
+	byName := make(map[string]*models.ProviderAPIKey)
+	remember := func(index map[string]*models.ProviderAPIKey, value string, row *models.ProviderAPIKey) {
+		// An enabled row wins over disabled duplicates.
+		if prev, ok := index[value]; value == "" || (ok && prev.IsEnabled) {
+			return
+		}
+		index[value] = row
+	}
+	for i := range rows {
+		row := &rows[i]
+		remember(byKey, strings.TrimSpace(row.APIKey), row)
+		remember(byName, strings.TrimSpace(row.Name), row)
+		var entries []struct {
+			APIKey string `json:"api_key"`
+		}
+		if len(row.APIKeyEntries) > 0 && json.Unmarshal(row.APIKeyEntries, &entries) == nil {
+			for _, entry := range entries {
+				remember(byKey, strings.TrimSpace(entry.APIKey), row)
+			}
+		}
+	}
+
+	var issues []Issue
+	report := func(section, label string, row *models.ProviderAPIKey) {
+		switch {
+		case row == nil:
+			issues = append(issues, Issue{
+				Code:    IssueOrphanedProviderKey,
+				Message: fmt.Sprintf("config %s entry %s has no provider key in the database and is ignored", section, label),
+			})
+		case !row.IsEnabled:
+			issues = append(issues, Issue{
+				Code:    IssueDisabledProviderKey,
+				Message: fmt.Sprintf("config %s entry %s belongs to disabled provider key %d", section, label, row.ID),
+				Table:   "provider_api_keys",
+				RowID:   row.ID,
+			})
+		}
+	}
+	for _, section := range []struct {
+		name    string
+		entries []fileProviderKey
+	}{
+		{"gemini-api-key", keys.GeminiKeys},
+		{"codex-api-key", keys.CodexKeys},
+		{"claude-api-key", keys.ClaudeKeys},
+	} {
+		for _, entry := range section.entries {
+			if key := strings.TrimSpace(entry.APIKey); key != "" {
+				report(section.name, maskKey(key), byKey[key])
+			}
+		}
+	}
+	for _, provider := range keys.OpenAI {
+		if name := strings.TrimSpace(provider.Name); name != "" {
+			report("openai-compatibility", fmt.Sprintf("%q", name), byName[name])
+		}
+	}
+	return issues, nil
+}
+
+// checkAuthGroups finds auth files referencing deleted auth groups.
+func checkAuthGroups(ctx context.Context, db *gorm.DB, _ fileKeys, _ time.Time) ([]Issue, error) {
+	groups, errGroups := existingIDs(ctx, db, &models.AuthGroup{})
+	if errGroups != nil {
+		return nil, errGroups
+	}
+	var auths []models.Auth
+	if errFind := db.WithContext(ctx).Select("id", "key", "auth_group_id").Order("id ASC").Find(&auths).Error; errFind != nil {
+		return nil, fmt.Errorf("consistency: list auths: %w", errFind)
+	}
+	var issues []Issue
+	for _, auth := range auths {
+		missing := missingIDs(auth.AuthGroupID.Values(), groups)
+		if len(missing) == 0 {
+			continue
+		}
+		issues = append(issues, Issue{
+			Code:       IssueAuthMissingGroup,
+			Message:    fmt.Sprintf("auth file %q references deleted auth groups %v", auth.Key, missing),
+			Table:      "auths",
+			RowID:      auth.ID,
+			Repairable: true,
+		})
+	}
+	return issues, nil
+}
+
+// checkBillingRules finds billing rules pointing at deleted auth or user groups.
+func checkBillingRules(ctx context.Context, db *gorm.DB, _ fileKeys, _ time.Time) ([]Issue, error) {
+	authGroups, errAuthGroups := existingIDs(ctx, db, &models.AuthGroup{})
+	if errAuthGroups != nil {
+		return nil, errAuthGroups
+	}
+	userGroups, errUserGroups := existingIDs(ctx, db, &models.UserGroup{})
+	if errUserGroups != nil {
+		return nil, errUserGroups
+	}
+	var rules []models.BillingRule
+	if errFind := db.WithContext(ctx).
+		Select("id", "auth_group_id", "user_group_id", "provider", "model", "is_enabled").
+		Order("id ASC").Find(&rules).Error; errFind != nil {
+		return nil, fmt.Errorf("consistency: list billing rules: %w", errFind)
+	}
+	var issues []Issue
+	for _, rule := range rules {
+		var missing []string
+		if _, ok := authGroups[rule.AuthGroupID]; !ok {
+			missing = append(missing, fmt.Sprintf("auth group %d", rule.AuthGroupID))
+		}
+		if _, ok := userGroups[rule.UserGroupID]; !ok {
+			missing = append(missing, fmt.Sprintf("user group %d", rule.UserGroupID))
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		issues = append(issues, Issue{
+			Code:       IssueBillingRuleMissingGroup,
+			Message:    fmt.Sprintf("billing rule %d (%s/%s) points at deleted %s", rule.ID, rule.Provider, rule.Model, strings.Join(missing, " and ")),
+			Table:      "billing_rules",
+			RowID:      rule.ID,
+			Repairable: rule.IsEnabled,
+		})
+	}
+	return issues, nil
+}
+
+// Repair fixes the repairable issues of report: deleted groups are removed from auth files
+// and billing rules pointing at deleted groups are disabled. Fixed issues are marked.
+func Repair(ctx context.Context, db *gorm.DB, report *Report) error {
+	if db == nil || report == nil {
+		return nil
+	}
+	authGroups, errGroups := existingIDs(ctx, db, &models.AuthGroup{})
+	if errGroups != nil {
+		return errGroups
+	}
+	for i := range report.Issues {
+		issue := &report.Issues[i]
+		if !issue.Repairable || issue.Repaired {
+			continue
+		}
+		var errRepair error
+		switch issue.Code {
+		case IssueAuthMissingGroup:
+			errRepair = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				var auth models.Auth
+				if errFind := tx.Select("id", "auth_group_id").First(&auth, issue.RowID).Error; errFind != nil {
+					return errFind
+				}
+				kept := make(models.AuthGroupIDs, 0, len(auth.AuthGroupID))
+				for _, id := range auth.AuthGroupID.Values() {
+					if _, ok := authGroups[id]; ok {
+						id := id
+						kept = append(kept, &id)
+					}
+				}
+				return tx.Model(&models.Auth{}).Where("id = ?", issue.RowID).Update("auth_group_id", kept).Error
+			})
+		case IssueBillingRuleMissingGroup:
+			errRepair = db.WithContext(ctx).Model(&models.BillingRule{}).
+				Where("id = ?", issue.RowID).
+				Update("is_enabled", false).Error
+		default:
+			continue
+		}
+		if errRepair != nil {
+			return fmt.Errorf("consistency: repair %s %d: %w", issue.Table, issue.RowID, errRepair)
+		}
+		issue.Repaired = true
+	}
+	return nil
+}
+
+// existingIDs returns the primary keys of model's table.
+func existingIDs(ctx context.Context, db *gorm.DB, model any) (map[uint64]struct{}, error) {
+	var ids []uint64
+	if errPluck := db.WithContext(ctx).Model(model).Pluck("id", &ids).Error; errPluck != nil {
+		return nil, fmt.Errorf("consistency: list ids: %w", errPluck)
+	}
+	out := make(map[uint64]struct{}, len(ids))
+	for _, id := range ids {
+		out[id] = struct{}{}
+	}
+	return out, nil
+}
+
+// missingIDs returns the sorted ids absent from existing.
+func missingIDs(ids []uint64, existing map[uint64]struct{}) []uint64 {
+	var missing []uint64
+	for _, id := range ids {
+		if _, ok := existing[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i] < missing[j] })
+	return missing
+}
+
+// maskKey keeps the first and last four characters of a key.
+func maskKey(key string) string {
+	runes := []rune(key)
+	if len(runes) <= 12 {
+		return "****"
+	}
+	return string(runes[:4]) + "..." + string(runes[len(runes)-4:])
+}
+
+var current atomic.Pointer[Report]
+
+// SetCurrent publishes the report served by the admin endpoint.
+func SetCurrent(report Report) {
+	current.Store(&report)
+}
+
+// Current returns the published report; an empty report when no check has run.
+func Current() Report {
+	if report := current.Load(); report != nil {
+		return *report
+	}
+	return Report{Issues: []Issue{}}
+}
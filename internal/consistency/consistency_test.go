@@ -0,0 +1,186 @@
+package consistency
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func setupConsistencyDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:consistency_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func mustCreate(t *testing.T, conn *gorm.DB, value any) {
+	t.Helper()
+	if errCreate := conn.Create(value).Error; errCreate != nil {
+		t.Fatalf("create %T: %v", value, errCreate)
+	}
+}
+
+func issueCodes(report Report) map[string]int {
+	codes := make(map[string]int)
+	for _, issue := range report.Issues {
+		codes[issue.Code]++
+	}
+	return codes
+}
+
+func TestCheckReportsConfigKeyProblems(t *testing.T) {
+	conn := setupConsistencyDB(t)
+	ctx := context.Background()
+
+	mustCreate(t, conn, &models.APIKey{Name: "live", APIKey: "sk-live-0000000000000001"})
+	disabled := models.APIKey{Name: "off", APIKey: "sk-off-00000000000000002"}
+	mustCreate(t, conn, &disabled)
+	conn.Model(&disabled).Update("active", false)
+
+	mustCreate(t, conn, &models.ProviderAPIKey{Provider: "gemini", APIKey: "AIza-enabled-000000000001"})
+	off := models.ProviderAPIKey{Provider: "claude", APIKey: "sk-ant-disabled-00000001"}
+	mustCreate(t, conn, &off)
+	conn.Model(&off).Update("is_enabled", false)
+	mustCreate(t, conn, &models.ProviderAPIKey{
+		Provider:      "openai-compatibility",
+		Name:          "openrouter",
+		APIKeyEntries: datatypes.JSON(`[{"api_key":"sk-or-entry-000000000001"}]`),
+	})
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	config := `
+api-keys:
+  - sk-live-0000000000000001
+  - sk-off-00000000000000002
+  - sk-missing-0000000000003
+gemini-api-key:
+  - api-key: AIza-enabled-000000000001
+codex-api-key:
+  - api-key: sk-codex-missing-0000001
+claude-api-key:
+  - api-key: sk-ant-disabled-00000001
+openai-compatibility:
+  - name: openrouter
+  - name: unknown
+`
+	if errWrite := os.WriteFile(configPath, []byte(config), 0o600); errWrite != nil {
+		t.Fatalf("write config: %v", errWrite)
+	}
+
+	report, errCheck := Check(ctx, conn, configPath, time.Now())
+	if errCheck != nil {
+		t.Fatalf("Check: %v", errCheck)
+	}
+	codes := issueCodes(report)
+	want := map[string]int{
+		IssueOrphanedAPIKey:      1,
+		IssueDisabledAPIKey:      1,
+		IssueOrphanedProviderKey: 2,
+		IssueDisabledProviderKey: 1,
+	}
+	for code, count := range want {
+		if codes[code] != count {
+			t.Fatalf("expected %d %s issues, got %+v", count, code, report.Issues)
+		}
+	}
+	for _, issue := range report.Issues {
+		if issue.Repairable {
+			t.Fatalf("config issues must not be repairable: %+v", issue)
+		}
+	}
+
+	if _, errMissing := Check(ctx, conn, filepath.Join(t.TempDir(), "missing.yaml"), time.Now()); errMissing != nil {
+		t.Fatalf("Check without config file: %v", errMissing)
+	}
+}
+
+func TestRepairFixesMissingGroups(t *testing.T) {
+	conn := setupConsistencyDB(t)
+	ctx := context.Background()
+
+	authGroup := models.AuthGroup{Name: "kept"}
+	mustCreate(t, conn, &authGroup)
+	deletedAuthGroup := models.AuthGroup{Name: "deleted"}
+	mustCreate(t, conn, &deletedAuthGroup)
+	userGroup := models.UserGroup{Name: "members"}
+	mustCreate(t, conn, &userGroup)
+
+	keptID, deletedID := authGroup.ID, deletedAuthGroup.ID
+	auth := models.Auth{
+		Key:         "auth-1",
+		Content:     datatypes.JSON(`{}`),
+		AuthGroupID: models.AuthGroupIDs{&keptID, &deletedID},
+	}
+	mustCreate(t, conn, &auth)
+	healthy := models.BillingRule{AuthGroupID: keptID, UserGroupID: userGroup.ID, BillingType: models.BillingTypePerRequest}
+	mustCreate(t, conn, &healthy)
+	orphan := models.BillingRule{AuthGroupID: deletedID, UserGroupID: userGroup.ID, BillingType: models.BillingTypePerRequest}
+	mustCreate(t, conn, &orphan)
+	// Foreign keys keep billing rules consistent on new databases; orphans come from data
+	// restored or edited without them.
+	sqlDB, errDB := conn.DB()
+	if errDB != nil {
+		t.Fatalf("sql db: %v", errDB)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if errPragma := conn.Exec("PRAGMA foreign_keys = OFF").Error; errPragma != nil {
+		t.Fatalf("disable foreign keys: %v", errPragma)
+	}
+	if errDelete := conn.Delete(&models.AuthGroup{}, deletedID).Error; errDelete != nil {
+		t.Fatalf("delete auth group: %v", errDelete)
+	}
+
+	report, errCheck := Check(ctx, conn, "", time.Now())
+	if errCheck != nil {
+		t.Fatalf("Check: %v", errCheck)
+	}
+	codes := issueCodes(report)
+	if len(report.Issues) != 2 || codes[IssueAuthMissingGroup] != 1 || codes[IssueBillingRuleMissingGroup] != 1 {
+		t.Fatalf("expected auth and billing rule issues, got %+v", report.Issues)
+	}
+
+	if errRepair := Repair(ctx, conn, &report); errRepair != nil {
+		t.Fatalf("Repair: %v", errRepair)
+	}
+	if report.Repaired() != 2 {
+		t.Fatalf("expected 2 repaired issues, got %+v", report.Issues)
+	}
+
+	var storedAuth models.Auth
+	if errFind := conn.First(&storedAuth, auth.ID).Error; errFind != nil {
+		t.Fatalf("load auth: %v", errFind)
+	}
+	if ids := storedAuth.AuthGroupID.Values(); len(ids) != 1 || ids[0] != keptID {
+		t.Fatalf("expected auth groups [%d], got %v", keptID, ids)
+	}
+	var storedRule models.BillingRule
+	if errFind := conn.First(&storedRule, orphan.ID).Error; errFind != nil {
+		t.Fatalf("load billing rule: %v", errFind)
+	}
+	if storedRule.IsEnabled {
+		t.Fatalf("expected orphan billing rule to be disabled")
+	}
+
+	again, errAgain := Check(ctx, conn, "", time.Now())
+	if errAgain != nil {
+		t.Fatalf("Check after repair: %v", errAgain)
+	}
+	if len(again.Issues) != 1 || again.Issues[0].Repairable {
+		t.Fatalf("expected only the disabled orphan rule to remain, got %+v", again.Issues)
+	}
+}
@@ -0,0 +1,270 @@
+// Package coop lets trusted front users contribute their own provider credentials to the
+// shared routing pool and earn prepaid credit for the traffic those credentials serve.
+package coop
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/authcooldown"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// approvalReason is recorded on contributed auths held for super-admin approval.
+const approvalReason = "co-op contribution"
+
+var (
+	// ErrPoolDisabled is returned when COOP_POOL is disabled.
+	ErrPoolDisabled = errors.New("coop: pool is disabled")
+	// ErrUserNotTrusted is returned when the user is not allowed to contribute.
+	ErrUserNotTrusted = errors.New("coop: user is not trusted to contribute")
+	// ErrInvalidCredential is returned when the credential content has no provider type.
+	ErrInvalidCredential = errors.New("coop: credential content must be a JSON object with a type")
+	// ErrNotActive is returned when acting on a contribution in the wrong state.
+	ErrNotActive = errors.New("coop: contribution is not in the required state")
+)
+
+// Policy mirrors the COOP_POOL setting.
+type Policy struct {
+	Enabled         bool     `json:"enabled"`          // Whether users may contribute credentials.
+	TrustedUsers    []string `json:"trusted_users"`    // Usernames allowed to contribute.
+	AuthGroupID     uint64   `json:"auth_group_id"`    // Auth group isolating contributed credentials; zero uses the default group.
+	CreditRate      float64  `json:"credit_rate"`      // Share of served usage cost credited back (0-1).
+	RequireApproval bool     `json:"require_approval"` // Hold contributions for super-admin approval before routing.
+}
+
+// LoadPolicy reads COOP_POOL; invalid values disable the pool.
+func LoadPolicy() Policy {
+	var policy Policy
+	raw, ok := internalsettings.DBConfigValue(internalsettings.CoopPoolKey)
+	if !ok || len(bytes.TrimSpace(raw)) == 0 {
+		return policy
+	}
+	if errUnmarshal := json.Unmarshal(raw, &policy); errUnmarshal != nil {
+		log.WithError(errUnmarshal).Warn("coop: invalid pool setting")
+		return Policy{}
+	}
+	if policy.CreditRate < 0 {
+		policy.CreditRate = 0
+	}
+	if policy.CreditRate > 1 {
+		policy.CreditRate = 1
+	}
+	return policy
+}
+
+// Trusts reports whether username may contribute credentials.
+func (p Policy) Trusts(username string) bool {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return false
+	}
+	for _, trusted := range p.TrustedUsers {
+		if strings.EqualFold(strings.TrimSpace(trusted), username) {
+			return true
+		}
+	}
+	return false
+}
+
+// Contribute stores a user's credential as an auth row attributed to them and records the
+// contribution. The auth joins the policy's isolation group and, when required, waits for
+// approval before it routes traffic.
+func Contribute(ctx context.Context, db *gorm.DB, policy Policy, user *models.User, name string, content map[string]any, now time.Time) (*models.CoopContribution, *models.Auth, error) {
+	if !policy.Enabled {
+		return nil, nil, ErrPoolDisabled
+	}
+	if user == nil || !policy.Trusts(user.Username) {
+		return nil, nil, ErrUserNotTrusted
+	}
+	provider, _ := content["type"].(string)
+	provider = strings.TrimSpace(provider)
+	if provider == "" {
+		return nil, nil, ErrInvalidCredential
+	}
+	contentJSON, errMarshal := json.Marshal(content)
+	if errMarshal != nil {
+		return nil, nil, ErrInvalidCredential
+	}
+	suffix, errSuffix := randomHex(6)
+	if errSuffix != nil {
+		return nil, nil, errSuffix
+	}
+	key := fmt.Sprintf("coop-%d-%s.json", user.ID, suffix)
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = key
+	}
+	if runes := []rune(name); len(runes) > 64 {
+		name = string(runes[:64])
+	}
+
+	var contribution models.CoopContribution
+	var auth models.Auth
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		groupIDs, errGroup := isolationGroup(tx, policy)
+		if errGroup != nil {
+			return errGroup
+		}
+		userID := user.ID
+		auth = models.Auth{
+			Key:                 key,
+			Name:                name,
+			AuthGroupID:         groupIDs,
+			Content:             datatypes.JSON(contentJSON),
+			IsAvailable:         !policy.RequireApproval,
+			PendingApproval:     policy.RequireApproval,
+			ImportedBy:          "user:" + user.Username,
+			ContributedByUserID: &userID,
+			CreatedAt:           now,
+			UpdatedAt:           now,
+		}
+		if policy.RequireApproval {
+			auth.ApprovalReason = approvalReason
+		}
+		if errCreate := tx.Create(&auth).Error; errCreate != nil {
+			return errCreate
+		}
+		if policy.RequireApproval {
+			// is_available defaults to true, so the zero value is not written on create.
+			if errHold := tx.Model(&auth).Update("is_available", false).Error; errHold != nil {
+				return errHold
+			}
+		}
+		contribution = models.CoopContribution{
+			UserID:       user.ID,
+			AuthID:       auth.ID,
+			AuthKey:      auth.Key,
+			Provider:     provider,
+			Status:       models.CoopContributionStatusActive,
+			CreditRate:   policy.CreditRate,
+			SettledUntil: now,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+		return tx.Create(&contribution).Error
+	})
+	if errTx != nil {
+		return nil, nil, errTx
+	}
+	return &contribution, &auth, nil
+}
+
+// isolationGroup returns the auth groups contributed credentials are placed in.
+func isolationGroup(tx *gorm.DB, policy Policy) (models.AuthGroupIDs, error) {
+	if policy.AuthGroupID != 0 {
+		id := policy.AuthGroupID
+		return models.AuthGroupIDs{&id}, nil
+	}
+	var defaultGroup models.AuthGroup
+	if errFind := tx.Where("is_default = ?", true).First(&defaultGroup).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return models.AuthGroupIDs{}, nil
+		}
+		return nil, errFind
+	}
+	id := defaultGroup.ID
+	return models.AuthGroupIDs{&id}, nil
+}
+
+// Revoke settles outstanding credit, deletes the contributed auth, and marks the contribution
+// revoked. A non-zero userID restricts the call to the contributor's own contributions.
+func Revoke(ctx context.Context, db *gorm.DB, contributionID, userID uint64, now time.Time) (*models.CoopContribution, error) {
+	contribution, errLoad := load(ctx, db, contributionID, userID)
+	if errLoad != nil {
+		return nil, errLoad
+	}
+	if contribution.Status == models.CoopContributionStatusRevoked {
+		return nil, ErrNotActive
+	}
+	if _, errSettle := settleContribution(ctx, db, contribution, now); errSettle != nil {
+		return nil, errSettle
+	}
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if errDelete := tx.Where("id = ? AND contributed_by_user_id = ?", contribution.AuthID, contribution.UserID).
+			Delete(&models.Auth{}).Error; errDelete != nil {
+			return errDelete
+		}
+		res := tx.Model(&models.CoopContribution{}).
+			Where("id = ? AND status <> ?", contribution.ID, models.CoopContributionStatusRevoked).
+			Updates(map[string]any{"status": models.CoopContributionStatusRevoked, "revoked_at": now, "updated_at": now})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotActive
+		}
+		return nil
+	})
+	if errTx != nil {
+		return nil, errTx
+	}
+	return load(ctx, db, contributionID, userID)
+}
+
+// SetSuspended takes a contribution out of routing (or puts it back) without deleting the
+// credential. Resuming a contribution still awaiting approval keeps it unavailable.
+func SetSuspended(ctx context.Context, db *gorm.DB, contributionID uint64, suspended bool, reason string, now time.Time) (*models.CoopContribution, error) {
+	from, to := models.CoopContributionStatusActive, models.CoopContributionStatusSuspended
+	if !suspended {
+		from, to = to, from
+		reason = ""
+	}
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var contribution models.CoopContribution
+		if errFind := tx.First(&contribution, contributionID).Error; errFind != nil {
+			return errFind
+		}
+		res := tx.Model(&models.CoopContribution{}).
+			Where("id = ? AND status = ?", contributionID, from).
+			Updates(map[string]any{"status": to, "suspend_reason": strings.TrimSpace(reason), "updated_at": now})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotActive
+		}
+		authUpdates := authcooldown.ManualOverride(now)
+		authUpdates["is_available"] = !suspended
+		authUpdates["updated_at"] = now
+		return tx.Model(&models.Auth{}).
+			Where("id = ? AND pending_approval = ?", contribution.AuthID, false).
+			Updates(authUpdates).Error
+	})
+	if errTx != nil {
+		return nil, errTx
+	}
+	return load(ctx, db, contributionID, 0)
+}
+
+// load fetches a contribution, optionally scoped to its owner.
+func load(ctx context.Context, db *gorm.DB, contributionID, userID uint64) (*models.CoopContribution, error) {
+	q := db.WithContext(ctx).Where("id = ?", contributionID)
+	if userID != 0 {
+		q = q.Where("user_id = ?", userID)
+	}
+	var contribution models.CoopContribution
+	if errFind := q.First(&contribution).Error; errFind != nil {
+		return nil, errFind
+	}
+	return &contribution, nil
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, errRead := rand.Read(buf); errRead != nil {
+		return "", fmt.Errorf("coop: random: %w", errRead)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,126 @@
+package coop
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"gorm.io/gorm"
+)
+
+func setupCoopDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:coop_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func createCoopUser(t *testing.T, conn *gorm.DB, username string) *models.User {
+	t.Helper()
+	user := models.User{Username: username, Email: username + "@example.com", Password: "x"}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+	return &user
+}
+
+func TestLoadPolicy(t *testing.T) {
+	internalsettings.StoreDBConfig(time.Now(), map[string]json.RawMessage{
+		internalsettings.CoopPoolKey: json.RawMessage(`{"enabled":true,"trusted_users":["Alice"],"credit_rate":1.5}`),
+	})
+	t.Cleanup(func() { internalsettings.StoreDBConfig(time.Now(), nil) })
+
+	policy := LoadPolicy()
+	if !policy.Enabled || policy.CreditRate != 1 || !policy.Trusts("alice") || policy.Trusts("bob") {
+		t.Fatalf("unexpected policy: %+v", policy)
+	}
+}
+
+func TestContributeSettleAndRevoke(t *testing.T) {
+	conn := setupCoopDB(t)
+	ctx := context.Background()
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	alice := createCoopUser(t, conn, "alice")
+	bob := createCoopUser(t, conn, "bob")
+	policy := Policy{Enabled: true, TrustedUsers: []string{"alice"}, AuthGroupID: 9, CreditRate: 0.5, RequireApproval: true}
+
+	if _, _, errContribute := Contribute(ctx, conn, policy, bob, "", map[string]any{"type": "codex"}, now); !errors.Is(errContribute, ErrUserNotTrusted) {
+		t.Fatalf("expected ErrUserNotTrusted, got %v", errContribute)
+	}
+	if _, _, errContribute := Contribute(ctx, conn, policy, alice, "", map[string]any{"token": "x"}, now); !errors.Is(errContribute, ErrInvalidCredential) {
+		t.Fatalf("expected ErrInvalidCredential, got %v", errContribute)
+	}
+	if _, _, errContribute := Contribute(ctx, conn, Policy{}, alice, "", map[string]any{"type": "codex"}, now); !errors.Is(errContribute, ErrPoolDisabled) {
+		t.Fatalf("expected ErrPoolDisabled, got %v", errContribute)
+	}
+
+	contribution, auth, errContribute := Contribute(ctx, conn, policy, alice, "alice codex", map[string]any{"type": "codex", "refresh_token": "rt"}, now)
+	if errContribute != nil {
+		t.Fatalf("contribute: %v", errContribute)
+	}
+	if auth.IsAvailable || !auth.PendingApproval || auth.ContributedByUserID == nil || *auth.ContributedByUserID != alice.ID ||
+		len(auth.AuthGroupID) != 1 || *auth.AuthGroupID[0] != 9 || contribution.Provider != "codex" {
+		t.Fatalf("unexpected contributed auth %+v / contribution %+v", auth, contribution)
+	}
+
+	authID := auth.ID
+	usages := []models.Usage{
+		{Provider: "codex", Model: "gpt-5", AuthID: &authID, UserID: &bob.ID, RequestedAt: now.Add(time.Minute), CostMicros: 2_000_000},
+		{Provider: "codex", Model: "gpt-5", AuthID: &authID, UserID: &bob.ID, RequestedAt: now.Add(2 * time.Minute), CostMicros: 1_000_000, Failed: true},
+		{Provider: "codex", Model: "gpt-5", AuthID: &authID, UserID: &alice.ID, RequestedAt: now.Add(3 * time.Minute), CostMicros: 5_000_000},
+		{Provider: "codex", Model: "gpt-5", AuthID: &authID, UserID: &bob.ID, RequestedAt: now.Add(-time.Minute), CostMicros: 7_000_000},
+	}
+	if errCreate := conn.Create(&usages).Error; errCreate != nil {
+		t.Fatalf("create usages: %v", errCreate)
+	}
+
+	settlements, errSettle := Settle(ctx, conn, now.Add(time.Hour))
+	if errSettle != nil || len(settlements) != 1 || settlements[0].Requests != 1 || math.Abs(settlements[0].Credit-1) > 1e-9 {
+		t.Fatalf("unexpected settlements %+v err=%v", settlements, errSettle)
+	}
+	if again, _ := Settle(ctx, conn, now.Add(time.Hour)); len(again) != 0 {
+		t.Fatalf("expected no double credit, got %+v", again)
+	}
+	var cards []models.PrepaidCard
+	conn.Where("redeemed_user_id = ?", alice.ID).Find(&cards)
+	if len(cards) != 1 || math.Abs(cards[0].Balance-1) > 1e-9 || cards[0].RedeemedAt == nil {
+		t.Fatalf("expected one redeemed credit card of 1.0, got %+v", cards)
+	}
+
+	if _, errSuspend := SetSuspended(ctx, conn, contribution.ID, true, "abuse review", now); errSuspend != nil {
+		t.Fatalf("suspend: %v", errSuspend)
+	}
+	if _, errSuspend := SetSuspended(ctx, conn, contribution.ID, true, "", now); !errors.Is(errSuspend, ErrNotActive) {
+		t.Fatalf("expected ErrNotActive on double suspend, got %v", errSuspend)
+	}
+	if _, errResume := SetSuspended(ctx, conn, contribution.ID, false, "", now); errResume != nil {
+		t.Fatalf("resume: %v", errResume)
+	}
+
+	if _, errRevoke := Revoke(ctx, conn, contribution.ID, bob.ID, now); !errors.Is(errRevoke, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected other users to be unable to revoke, got %v", errRevoke)
+	}
+	revoked, errRevoke := Revoke(ctx, conn, contribution.ID, alice.ID, now.Add(2*time.Hour))
+	if errRevoke != nil || revoked.Status != models.CoopContributionStatusRevoked || revoked.RevokedAt == nil {
+		t.Fatalf("unexpected revoke result %+v err=%v", revoked, errRevoke)
+	}
+	var remaining int64
+	conn.Model(&models.Auth{}).Where("id = ?", authID).Count(&remaining)
+	if remaining != 0 {
+		t.Fatal("expected contributed auth to be deleted on revoke")
+	}
+}
@@ -0,0 +1,166 @@
+package coop
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const defaultSettleInterval = time.Hour
+
+// creditCardName labels prepaid cards issued as co-op credit.
+const creditCardName = "Co-op credit"
+
+// Settlement summarizes the credit issued for one contribution.
+type Settlement struct {
+	ContributionID uint64  `json:"contribution_id"`
+	Requests       int64   `json:"requests"`
+	CostMicros     int64   `json:"cost_micros"`
+	Credit         float64 `json:"credit"`
+}
+
+// Settle credits every non-revoked contribution for the usage it served up to now.
+func Settle(ctx context.Context, db *gorm.DB, now time.Time) ([]Settlement, error) {
+	var contributions []models.CoopContribution
+	if errFind := db.WithContext(ctx).
+		Where("status <> ?", models.CoopContributionStatusRevoked).
+		Order("id ASC").
+		Find(&contributions).Error; errFind != nil {
+		return nil, errFind
+	}
+	out := make([]Settlement, 0, len(contributions))
+	for i := range contributions {
+		if ctx.Err() != nil {
+			return out, ctx.Err()
+		}
+		settlement, errSettle := settleContribution(ctx, db, &contributions[i], now)
+		if errSettle != nil {
+			log.WithError(errSettle).Warnf("coop: settle contribution %d failed", contributions[i].ID)
+			continue
+		}
+		if settlement.Requests > 0 {
+			out = append(out, settlement)
+		}
+	}
+	return out, nil
+}
+
+// settleContribution credits the successful usage other users sent through a contribution
+// since it was last settled. Credit is issued as a redeemed prepaid card so it spends like
+// any other balance. Advancing settled_until is guarded so concurrent settlements do not
+// credit the same window twice.
+func settleContribution(ctx context.Context, db *gorm.DB, contribution *models.CoopContribution, now time.Time) (Settlement, error) {
+	settlement := Settlement{ContributionID: contribution.ID}
+	if !now.After(contribution.SettledUntil) {
+		return settlement, nil
+	}
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var totals struct {
+			Requests   int64
+			CostMicros int64
+		}
+		if errSum := tx.Model(&models.Usage{}).
+			Select("COUNT(*) AS requests, COALESCE(SUM(cost_micros), 0) AS cost_micros").
+			Where("auth_id = ? AND failed = ?", contribution.AuthID, false).
+			Where("requested_at > ? AND requested_at <= ?", contribution.SettledUntil, now).
+			Where("(user_id IS NULL OR user_id <> ?)", contribution.UserID).
+			Scan(&totals).Error; errSum != nil {
+			return errSum
+		}
+		settlement.Requests = totals.Requests
+		settlement.CostMicros = totals.CostMicros
+		settlement.Credit = math.Round(float64(totals.CostMicros)/1_000_000*contribution.CreditRate*1e6) / 1e6
+
+		res := tx.Model(&models.CoopContribution{}).
+			Where("id = ? AND settled_until = ?", contribution.ID, contribution.SettledUntil).
+			Updates(map[string]any{
+				"settled_until":   now,
+				"credited_amount": gorm.Expr("credited_amount + ?", settlement.Credit),
+				"served_requests": gorm.Expr("served_requests + ?", settlement.Requests),
+				"updated_at":      now,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			settlement = Settlement{ContributionID: contribution.ID}
+			return nil
+		}
+		if settlement.Credit <= 0 {
+			return nil
+		}
+		password, errPassword := randomHex(8)
+		if errPassword != nil {
+			return errPassword
+		}
+		userID := contribution.UserID
+		redeemedAt := now
+		card := models.PrepaidCard{
+			Name:           creditCardName,
+			CardSN:         fmt.Sprintf("COOP-%d-%d", contribution.ID, now.UnixNano()),
+			Password:       password,
+			Amount:         settlement.Credit,
+			Balance:        settlement.Credit,
+			IsEnabled:      true,
+			RedeemedUserID: &userID,
+			CreatedAt:      now,
+			RedeemedAt:     &redeemedAt,
+		}
+		return tx.Create(&card).Error
+	})
+	if errTx != nil {
+		return Settlement{ContributionID: contribution.ID}, errTx
+	}
+	return settlement, nil
+}
+
+// Settler periodically credits contributors for the usage their credentials served.
+type Settler struct {
+	db       *gorm.DB
+	interval time.Duration
+}
+
+// NewSettler constructs a settler; returns nil when db is nil.
+func NewSettler(db *gorm.DB) *Settler {
+	if db == nil {
+		return nil
+	}
+	return &Settler{db: db, interval: defaultSettleInterval}
+}
+
+// Start launches the settlement loop in a background goroutine.
+func (s *Settler) Start(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go s.run(ctx)
+	log.Infof("coop settler started (interval=%s)", s.interval)
+}
+
+func (s *Settler) run(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, errSettle := Settle(ctx, s.db, time.Now().UTC()); errSettle != nil {
+			log.WithError(errSettle).Warn("coop settler: settle failed")
+		}
+		timer := time.NewTimer(s.interval)
+		select {
+		case <-ctx.Done():
+			if !timer.Stop() {
+				<-timer.C
+			}
+			return
+		case <-timer.C:
+		}
+	}
+}
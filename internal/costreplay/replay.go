@@ -0,0 +1,368 @@
+package costreplay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultRunnerInterval = 5 * time.Second
+	// staleRunningAfter lets another runner restart a job whose runner stopped reporting progress.
+	staleRunningAfter = 5 * time.Minute
+	// replayBatchSize is how many usage rows are repriced between progress updates.
+	replayBatchSize = 1000
+)
+
+var (
+	// ErrInvalidPeriod is returned when a job's period is empty or reversed.
+	ErrInvalidPeriod = errors.New("cost replay: period end must be after start")
+	// ErrNotCancellable is returned when cancelling a job that already finished.
+	ErrNotCancellable = errors.New("cost replay: job already finished")
+
+	errJobCancelled = errors.New("cost replay: job cancelled")
+)
+
+// ModelLine compares original and repriced cost for one provider and model.
+type ModelLine struct {
+	Provider       string `json:"provider"`
+	Model          string `json:"model"`
+	Requests       int64  `json:"requests"`
+	Unmatched      int64  `json:"unmatched"`       // Requests no snapshot rule priced.
+	OriginalMicros int64  `json:"original_micros"` // Cost charged at the time.
+	ReplayedMicros int64  `json:"replayed_micros"` // Cost under the snapshot.
+	DeltaMicros    int64  `json:"delta_micros"`    // Replayed minus original.
+}
+
+// Report is the result of a completed replay.
+type Report struct {
+	SnapshotID     uint64      `json:"snapshot_id"`
+	PeriodStart    time.Time   `json:"period_start"`
+	PeriodEnd      time.Time   `json:"period_end"`
+	Requests       int64       `json:"requests"`
+	Unmatched      int64       `json:"unmatched"`
+	OriginalMicros int64       `json:"original_micros"`
+	ReplayedMicros int64       `json:"replayed_micros"`
+	DeltaMicros    int64       `json:"delta_micros"`
+	Models         []ModelLine `json:"models"`
+}
+
+// CreateJob queues a replay of successful usage in [start, end) against a snapshot.
+func CreateJob(ctx context.Context, db *gorm.DB, snapshotID uint64, start, end time.Time, userID, createdBy *uint64) (*models.CostReplayJob, error) {
+	if !end.After(start) {
+		return nil, ErrInvalidPeriod
+	}
+	if errFind := db.WithContext(ctx).Select("id").First(&models.BillingRuleSnapshot{}, snapshotID).Error; errFind != nil {
+		return nil, errFind
+	}
+	job := models.CostReplayJob{
+		SnapshotID:  snapshotID,
+		PeriodStart: start.UTC(),
+		PeriodEnd:   end.UTC(),
+		UserID:      userID,
+		Status:      models.CostReplayStatusPending,
+		CreatedBy:   createdBy,
+	}
+	if errCreate := db.WithContext(ctx).Create(&job).Error; errCreate != nil {
+		return nil, errCreate
+	}
+	return &job, nil
+}
+
+// Cancel stops a pending or running job.
+func Cancel(ctx context.Context, db *gorm.DB, id uint64) error {
+	now := time.Now().UTC()
+	res := db.WithContext(ctx).Model(&models.CostReplayJob{}).
+		Where("id = ? AND status IN ?", id, []models.CostReplayStatus{models.CostReplayStatusPending, models.CostReplayStatusRunning}).
+		Updates(map[string]any{"status": models.CostReplayStatusCancelled, "finished_at": now, "updated_at": now})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		var count int64
+		if errCount := db.WithContext(ctx).Model(&models.CostReplayJob{}).Where("id = ?", id).Count(&count).Error; errCount != nil {
+			return errCount
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return ErrNotCancellable
+	}
+	return nil
+}
+
+// RunNext claims the oldest runnable job and reprices its usage. It reports whether a job was claimed.
+func RunNext(ctx context.Context, db *gorm.DB, now time.Time) (bool, error) {
+	job, errClaim := claimJob(ctx, db, now)
+	if errClaim != nil || job == nil {
+		return false, errClaim
+	}
+	if errRun := runJob(ctx, db, job); errRun != nil {
+		if errors.Is(errRun, errJobCancelled) {
+			log.Infof("cost replay: job %d cancelled after %d rows", job.ID, job.Processed)
+			return true, nil
+		}
+		if ctx.Err() != nil {
+			// Shutting down: leave the job running so it restarts once it goes stale.
+			return true, ctx.Err()
+		}
+		finished := time.Now().UTC()
+		db.WithContext(ctx).Model(&models.CostReplayJob{}).
+			Where("id = ? AND status = ?", job.ID, models.CostReplayStatusRunning).
+			Updates(map[string]any{"status": models.CostReplayStatusFailed, "last_error": errRun.Error(), "finished_at": finished, "updated_at": finished})
+		return true, errRun
+	}
+	return true, nil
+}
+
+// claimJob moves the oldest pending job, or a running job nobody has advanced recently, to
+// running. Reports are accumulated in memory, so a reclaimed job starts over.
+func claimJob(ctx context.Context, db *gorm.DB, now time.Time) (*models.CostReplayJob, error) {
+	staleBefore := now.Add(-staleRunningAfter)
+	var candidates []models.CostReplayJob
+	if errFind := db.WithContext(ctx).
+		Where("status = ? OR (status = ? AND updated_at < ?)", models.CostReplayStatusPending, models.CostReplayStatusRunning, staleBefore).
+		Order("id ASC").
+		Limit(5).
+		Find(&candidates).Error; errFind != nil {
+		return nil, errFind
+	}
+	for i := range candidates {
+		job := &candidates[i]
+		if errCount := scope(db.WithContext(ctx), job).Count(&job.Total).Error; errCount != nil {
+			return nil, errCount
+		}
+		// The status guard makes the claim a compare-and-set, so concurrent runners claim a job once.
+		claim := db.WithContext(ctx).Model(&models.CostReplayJob{}).Where("id = ? AND status = ?", job.ID, job.Status)
+		if job.Status == models.CostReplayStatusRunning {
+			claim = claim.Where("updated_at < ?", staleBefore)
+		}
+		res := claim.Updates(map[string]any{
+			"status":     models.CostReplayStatusRunning,
+			"total":      job.Total,
+			"processed":  0,
+			"started_at": now,
+			"updated_at": now,
+		})
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected == 1 {
+			job.Status = models.CostReplayStatusRunning
+			job.Processed = 0
+			return job, nil
+		}
+	}
+	return nil, nil
+}
+
+// scope selects the successful usage a job reprices.
+func scope(db *gorm.DB, job *models.CostReplayJob) *gorm.DB {
+	q := db.Model(&models.Usage{}).
+		Where("requested_at >= ? AND requested_at < ? AND failed = ?", job.PeriodStart, job.PeriodEnd, false)
+	if job.UserID != nil {
+		q = q.Where("user_id = ?", *job.UserID)
+	}
+	return q
+}
+
+// runJob reprices the job's usage in chronological batches, then stores the report.
+func runJob(ctx context.Context, db *gorm.DB, job *models.CostReplayJob) error {
+	var snapshot models.BillingRuleSnapshot
+	if errFind := db.WithContext(ctx).First(&snapshot, job.SnapshotID).Error; errFind != nil {
+		return fmt.Errorf("load snapshot: %w", errFind)
+	}
+	rules, errRules := SnapshotRules(&snapshot)
+	if errRules != nil {
+		return errRules
+	}
+	set := billing.NewRuleSet(rules)
+	acc := newAccumulator(job)
+
+	var (
+		lastAt time.Time
+		lastID uint64
+		first  = true
+	)
+	for {
+		if errCtx := ctx.Err(); errCtx != nil {
+			return errCtx
+		}
+		q := scope(db.WithContext(ctx), job)
+		if !first {
+			q = q.Where("(requested_at > ? OR (requested_at = ? AND id > ?))", lastAt, lastAt, lastID)
+		}
+		var rows []models.Usage
+		if errFind := q.Order("requested_at ASC, id ASC").Limit(replayBatchSize).Find(&rows).Error; errFind != nil {
+			return errFind
+		}
+		for i := range rows {
+			if errPrice := acc.add(ctx, db, set, &rows[i]); errPrice != nil {
+				return errPrice
+			}
+		}
+		if len(rows) > 0 {
+			first = false
+			lastAt, lastID = rows[len(rows)-1].RequestedAt, rows[len(rows)-1].ID
+		}
+		job.Processed += int64(len(rows))
+
+		updates := map[string]any{"processed": job.Processed, "updated_at": time.Now().UTC()}
+		done := len(rows) < replayBatchSize
+		if done {
+			report, errMarshal := json.Marshal(acc.report())
+			if errMarshal != nil {
+				return errMarshal
+			}
+			finished := time.Now().UTC()
+			updates["status"] = models.CostReplayStatusCompleted
+			updates["report"] = datatypes.JSON(report)
+			updates["finished_at"] = finished
+		}
+		res := db.WithContext(ctx).Model(&models.CostReplayJob{}).
+			Where("id = ? AND status = ?", job.ID, models.CostReplayStatusRunning).
+			Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errJobCancelled
+		}
+		if done {
+			log.Infof("cost replay: job %d repriced %d rows", job.ID, job.Processed)
+			return nil
+		}
+	}
+}
+
+type lineKey struct {
+	provider string
+	model    string
+}
+
+// accumulator sums original and repriced cost per provider and model.
+type accumulator struct {
+	base  Report
+	lines map[lineKey]*ModelLine
+}
+
+func newAccumulator(job *models.CostReplayJob) *accumulator {
+	return &accumulator{
+		base:  Report{SnapshotID: job.SnapshotID, PeriodStart: job.PeriodStart, PeriodEnd: job.PeriodEnd},
+		lines: make(map[lineKey]*ModelLine),
+	}
+}
+
+func (a *accumulator) add(ctx context.Context, db *gorm.DB, set *billing.RuleSet, row *models.Usage) error {
+	explanation, errExplain := billing.ExplainCostWithRules(ctx, db, billing.CostInput{
+		Provider:            row.Provider,
+		Model:               row.Model,
+		APIKeyID:            row.APIKeyID,
+		UserID:              row.UserID,
+		AuthID:              row.AuthID,
+		UserGroupID:         row.UserGroupID,
+		InputTokens:         row.InputTokens,
+		OutputTokens:        row.OutputTokens,
+		ReasoningTokens:     row.ReasoningTokens,
+		CachedTokens:        row.CachedTokens,
+		CacheCreationTokens: row.CacheCreationTokens,
+		RequestedAt:         row.RequestedAt,
+	}, set)
+	if errExplain != nil {
+		return errExplain
+	}
+	key := lineKey{provider: row.Provider, model: row.Model}
+	line := a.lines[key]
+	if line == nil {
+		line = &ModelLine{Provider: row.Provider, Model: row.Model}
+		a.lines[key] = line
+	}
+	line.Requests++
+	line.OriginalMicros += row.CostMicros
+	line.ReplayedMicros += explanation.TotalMicros
+	if explanation.Rule == nil {
+		line.Unmatched++
+	}
+	return nil
+}
+
+func (a *accumulator) report() Report {
+	out := a.base
+	out.Models = make([]ModelLine, 0, len(a.lines))
+	for _, line := range a.lines {
+		line.DeltaMicros = line.ReplayedMicros - line.OriginalMicros
+		out.Requests += line.Requests
+		out.Unmatched += line.Unmatched
+		out.OriginalMicros += line.OriginalMicros
+		out.ReplayedMicros += line.ReplayedMicros
+		out.Models = append(out.Models, *line)
+	}
+	out.DeltaMicros = out.ReplayedMicros - out.OriginalMicros
+	sort.Slice(out.Models, func(i, j int) bool {
+		if out.Models[i].Provider != out.Models[j].Provider {
+			return out.Models[i].Provider < out.Models[j].Provider
+		}
+		return out.Models[i].Model < out.Models[j].Model
+	})
+	return out
+}
+
+// Runner executes queued cost replay jobs.
+type Runner struct {
+	db       *gorm.DB
+	interval time.Duration
+}
+
+// NewRunner constructs a runner; returns nil when db is nil.
+func NewRunner(db *gorm.DB) *Runner {
+	if db == nil {
+		return nil
+	}
+	return &Runner{db: db, interval: defaultRunnerInterval}
+}
+
+// Start launches the runner loop in a background goroutine.
+func (r *Runner) Start(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go r.run(ctx)
+	log.Infof("cost replay runner started (interval=%s)", r.interval)
+}
+
+func (r *Runner) run(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		claimed, errRun := RunNext(ctx, r.db, time.Now().UTC())
+		if errRun != nil && ctx.Err() == nil {
+			log.WithError(errRun).Warn("cost replay runner: job failed")
+		}
+		if claimed {
+			// Drain the queue before sleeping.
+			continue
+		}
+		timer := time.NewTimer(r.interval)
+		select {
+		case <-ctx.Done():
+			if !timer.Stop() {
+				<-timer.C
+			}
+			return
+		case <-timer.C:
+		}
+	}
+}
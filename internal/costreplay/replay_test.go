@@ -0,0 +1,130 @@
+package costreplay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func TestReplayAgainstCapturedAndProposedSnapshots(t *testing.T) {
+	dsn := fmt.Sprintf("file:cost_replay_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	ctx := context.Background()
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	authGroup := models.AuthGroup{Name: "replay-auth-group"}
+	userGroup := models.UserGroup{Name: "replay-user-group"}
+	if errCreate := conn.Create(&authGroup).Error; errCreate != nil {
+		t.Fatalf("create auth group: %v", errCreate)
+	}
+	if errCreate := conn.Create(&userGroup).Error; errCreate != nil {
+		t.Fatalf("create user group: %v", errCreate)
+	}
+	authGroupID, userGroupID := authGroup.ID, userGroup.ID
+	auth := models.Auth{Key: "replay-auth", Content: datatypes.JSON(`{"type":"codex"}`), AuthGroupID: models.AuthGroupIDs{&authGroupID}}
+	if errCreate := conn.Create(&auth).Error; errCreate != nil {
+		t.Fatalf("create auth: %v", errCreate)
+	}
+	oldPrice := 1.0
+	rule := models.BillingRule{AuthGroupID: authGroupID, UserGroupID: userGroupID, BillingType: models.BillingTypePerToken, PriceInputToken: &oldPrice, IsEnabled: true}
+	if errCreate := conn.Create(&rule).Error; errCreate != nil {
+		t.Fatalf("create rule: %v", errCreate)
+	}
+
+	captured, errCapture := CaptureIfChanged(ctx, conn, now.AddDate(0, -1, 0))
+	if errCapture != nil || captured == nil || captured.RuleCount != 1 {
+		t.Fatalf("unexpected capture %+v err=%v", captured, errCapture)
+	}
+	if again, _ := CaptureIfChanged(ctx, conn, now.AddDate(0, -1, 0).Add(time.Hour)); again != nil {
+		t.Fatalf("expected unchanged rules not to be captured again, got %+v", again)
+	}
+
+	// Prices doubled after the snapshot; usage was charged at the new price.
+	newPrice := 2.0
+	if errUpdate := conn.Model(&rule).Update("price_input_token", newPrice).Error; errUpdate != nil {
+		t.Fatalf("update rule: %v", errUpdate)
+	}
+	authID := auth.ID
+	usages := []models.Usage{
+		{Provider: "openai", Model: "gpt-5", AuthID: &authID, UserGroupID: &userGroupID, RequestedAt: now.Add(-2 * time.Hour), InputTokens: 1000, CostMicros: 2000},
+		{Provider: "openai", Model: "gpt-5", AuthID: &authID, UserGroupID: &userGroupID, RequestedAt: now.Add(-time.Hour), InputTokens: 500, CostMicros: 1000},
+		{Provider: "openai", Model: "gpt-5", AuthID: &authID, UserGroupID: &userGroupID, RequestedAt: now.Add(-time.Hour), InputTokens: 500, Failed: true},
+		{Provider: "openai", Model: "gpt-5", AuthID: &authID, UserGroupID: &userGroupID, RequestedAt: now.AddDate(0, -2, 0), InputTokens: 500, CostMicros: 500},
+	}
+	if errCreate := conn.Create(&usages).Error; errCreate != nil {
+		t.Fatalf("create usages: %v", errCreate)
+	}
+
+	asOf, errAsOf := SnapshotAsOf(ctx, conn, now.AddDate(0, 0, -20))
+	if errAsOf != nil || asOf.ID != captured.ID {
+		t.Fatalf("expected as-of snapshot %d, got %+v err=%v", captured.ID, asOf, errAsOf)
+	}
+
+	start := now.AddDate(0, 0, -1)
+	if _, errPeriod := CreateJob(ctx, conn, captured.ID, now, start, nil, nil); !errors.Is(errPeriod, ErrInvalidPeriod) {
+		t.Fatalf("expected ErrInvalidPeriod, got %v", errPeriod)
+	}
+	job, errJob := CreateJob(ctx, conn, captured.ID, start, now, nil, nil)
+	if errJob != nil {
+		t.Fatalf("create job: %v", errJob)
+	}
+	report := runToCompletion(t, conn, job.ID, now)
+	if report.Requests != 2 || report.OriginalMicros != 3000 || report.ReplayedMicros != 1500 || report.DeltaMicros != -1500 || len(report.Models) != 1 {
+		t.Fatalf("unexpected captured replay report %+v", report)
+	}
+
+	tripled := 3.0
+	proposed, errProposed := CreateProposed(ctx, conn, "triple", []Rule{{AuthGroupID: authGroupID, UserGroupID: userGroupID, BillingType: models.BillingTypePerToken, PriceInputToken: &tripled, IsEnabled: true}}, nil, now)
+	if errProposed != nil || !proposed.Proposed {
+		t.Fatalf("create proposed snapshot: %+v err=%v", proposed, errProposed)
+	}
+	if _, errInvalid := CreateProposed(ctx, conn, "bad", []Rule{{BillingType: models.BillingTypePerToken}}, nil, now); !errors.Is(errInvalid, ErrInvalidRules) {
+		t.Fatalf("expected ErrInvalidRules, got %v", errInvalid)
+	}
+	proposedJob, errJob := CreateJob(ctx, conn, proposed.ID, start, now, nil, nil)
+	if errJob != nil {
+		t.Fatalf("create proposed job: %v", errJob)
+	}
+	if report := runToCompletion(t, conn, proposedJob.ID, now); report.ReplayedMicros != 4500 || report.Unmatched != 0 {
+		t.Fatalf("unexpected proposed replay report %+v", report)
+	}
+
+	if errCancel := Cancel(ctx, conn, proposedJob.ID); !errors.Is(errCancel, ErrNotCancellable) {
+		t.Fatalf("expected ErrNotCancellable, got %v", errCancel)
+	}
+}
+
+func runToCompletion(t *testing.T, conn *gorm.DB, jobID uint64, now time.Time) Report {
+	t.Helper()
+	claimed, errRun := RunNext(context.Background(), conn, now)
+	if errRun != nil || !claimed {
+		t.Fatalf("run job: claimed=%v err=%v", claimed, errRun)
+	}
+	var job models.CostReplayJob
+	if errFind := conn.First(&job, jobID).Error; errFind != nil {
+		t.Fatalf("load job: %v", errFind)
+	}
+	if job.Status != models.CostReplayStatusCompleted || job.Processed != job.Total {
+		t.Fatalf("unexpected job state %+v", job)
+	}
+	var report Report
+	if errUnmarshal := json.Unmarshal(job.Report, &report); errUnmarshal != nil {
+		t.Fatalf("decode report: %v", errUnmarshal)
+	}
+	return report
+}
@@ -0,0 +1,298 @@
+// Package costreplay reprices past usage against billing rule snapshots, so pricing changes
+// can be evaluated before rollout and past months compared under earlier rules.
+package costreplay
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/currency"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const defaultSnapshotInterval = time.Hour
+
+// ErrInvalidRules is returned when proposed snapshot rules cannot be used for pricing.
+var ErrInvalidRules = errors.New("cost replay: invalid rules")
+
+// Rule is the snapshot form of a billing rule. Proposed snapshots are supplied in this form.
+type Rule struct {
+	ID          uint64             `json:"id"`
+	AuthGroupID uint64             `json:"auth_group_id"`
+	UserGroupID uint64             `json:"user_group_id"`
+	Provider    string             `json:"provider"`
+	Model       string             `json:"model"`
+	BillingType models.BillingType `json:"billing_type"`
+
+	PricePerRequest       *float64 `json:"price_per_request,omitempty"`
+	PriceInputToken       *float64 `json:"price_input_token,omitempty"`
+	PriceOutputToken      *float64 `json:"price_output_token,omitempty"`
+	PriceCacheCreateToken *float64 `json:"price_cache_create_token,omitempty"`
+	PriceCacheReadToken   *float64 `json:"price_cache_read_token,omitempty"`
+	Currency              string   `json:"currency,omitempty"` // Price currency; empty means USD.
+
+	TokenTiers    []models.BillingTokenTier `json:"token_tiers,omitempty"`
+	MinimumCharge *float64                  `json:"minimum_charge,omitempty"`
+
+	IsEnabled bool      `json:"is_enabled"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func ruleFromModel(r models.BillingRule) Rule {
+	tiers, _ := billing.ParseTokenTiers(r.TokenTiers)
+	// USD is left implicit so snapshots taken before rules had a currency keep their fingerprint.
+	code, errCode := currency.Normalize(r.Currency)
+	if errCode != nil || code == currency.Base {
+		code = ""
+	}
+	return Rule{
+		ID:                    r.ID,
+		AuthGroupID:           r.AuthGroupID,
+		UserGroupID:           r.UserGroupID,
+		Provider:              r.Provider,
+		Model:                 r.Model,
+		BillingType:           r.BillingType,
+		PricePerRequest:       r.PricePerRequest,
+		PriceInputToken:       r.PriceInputToken,
+		PriceOutputToken:      r.PriceOutputToken,
+		PriceCacheCreateToken: r.PriceCacheCreateToken,
+		PriceCacheReadToken:   r.PriceCacheReadToken,
+		Currency:              code,
+		TokenTiers:            tiers,
+		MinimumCharge:         r.MinimumCharge,
+		IsEnabled:             r.IsEnabled,
+		UpdatedAt:             r.UpdatedAt.UTC(),
+	}
+}
+
+func (r Rule) toModel() (models.BillingRule, error) {
+	out := models.BillingRule{
+		ID:                    r.ID,
+		AuthGroupID:           r.AuthGroupID,
+		UserGroupID:           r.UserGroupID,
+		Provider:              r.Provider,
+		Model:                 r.Model,
+		BillingType:           r.BillingType,
+		PricePerRequest:       r.PricePerRequest,
+		PriceInputToken:       r.PriceInputToken,
+		PriceOutputToken:      r.PriceOutputToken,
+		PriceCacheCreateToken: r.PriceCacheCreateToken,
+		PriceCacheReadToken:   r.PriceCacheReadToken,
+		Currency:              currency.Base,
+		MinimumCharge:         r.MinimumCharge,
+		IsEnabled:             r.IsEnabled,
+		UpdatedAt:             r.UpdatedAt,
+	}
+	if r.Currency != "" {
+		out.Currency = strings.ToUpper(strings.TrimSpace(r.Currency))
+	}
+	if len(r.TokenTiers) > 0 {
+		raw, errMarshal := json.Marshal(r.TokenTiers)
+		if errMarshal != nil {
+			return out, errMarshal
+		}
+		out.TokenTiers = datatypes.JSON(raw)
+	}
+	return out, nil
+}
+
+// validate checks a proposed rule the way the billing rule handlers would.
+func (r Rule) validate() error {
+	if r.AuthGroupID == 0 || r.UserGroupID == 0 {
+		return fmt.Errorf("%w: rule %d needs auth_group_id and user_group_id", ErrInvalidRules, r.ID)
+	}
+	if _, errCode := currency.Normalize(r.Currency); errCode != nil {
+		return fmt.Errorf("%w: rule %d has invalid currency", ErrInvalidRules, r.ID)
+	}
+	switch r.BillingType {
+	case models.BillingTypePerRequest, models.BillingTypePerToken:
+	case models.BillingTypeTiered:
+		raw, _ := json.Marshal(r.TokenTiers)
+		if tiers, errTiers := billing.ParseTokenTiers(raw); errTiers != nil || len(tiers) == 0 {
+			return fmt.Errorf("%w: rule %d has invalid token tiers", ErrInvalidRules, r.ID)
+		}
+	default:
+		return fmt.Errorf("%w: rule %d has unsupported billing_type", ErrInvalidRules, r.ID)
+	}
+	return nil
+}
+
+// SnapshotRules decodes a snapshot's rules into billing rules for pricing.
+func SnapshotRules(snapshot *models.BillingRuleSnapshot) ([]models.BillingRule, error) {
+	var rules []Rule
+	if errUnmarshal := json.Unmarshal(snapshot.Rules, &rules); errUnmarshal != nil {
+		return nil, fmt.Errorf("cost replay: decode snapshot %d: %w", snapshot.ID, errUnmarshal)
+	}
+	out := make([]models.BillingRule, 0, len(rules))
+	for _, rule := range rules {
+		model, errModel := rule.toModel()
+		if errModel != nil {
+			return nil, errModel
+		}
+		out = append(out, model)
+	}
+	return out, nil
+}
+
+// Capture stores the live billing rules as a snapshot.
+func Capture(ctx context.Context, db *gorm.DB, name string, createdBy *uint64, now time.Time) (*models.BillingRuleSnapshot, error) {
+	rules, errRules := liveRules(ctx, db)
+	if errRules != nil {
+		return nil, errRules
+	}
+	return store(ctx, db, name, rules, false, createdBy, now)
+}
+
+// CreateProposed stores supplied rules as a snapshot, for pricing a change before rollout.
+// Rules without an ID are numbered so rule selection stays deterministic.
+func CreateProposed(ctx context.Context, db *gorm.DB, name string, rules []Rule, createdBy *uint64, now time.Time) (*models.BillingRuleSnapshot, error) {
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("%w: no rules", ErrInvalidRules)
+	}
+	for i := range rules {
+		if rules[i].ID == 0 {
+			rules[i].ID = uint64(i + 1)
+		}
+		if errValidate := rules[i].validate(); errValidate != nil {
+			return nil, errValidate
+		}
+		if rules[i].UpdatedAt.IsZero() {
+			rules[i].UpdatedAt = now.UTC()
+		}
+	}
+	return store(ctx, db, name, rules, true, createdBy, now)
+}
+
+// CaptureIfChanged stores the live rules unless they match the most recent captured snapshot.
+// It returns nil when nothing changed.
+func CaptureIfChanged(ctx context.Context, db *gorm.DB, now time.Time) (*models.BillingRuleSnapshot, error) {
+	rules, errRules := liveRules(ctx, db)
+	if errRules != nil {
+		return nil, errRules
+	}
+	var latest models.BillingRuleSnapshot
+	errLatest := db.WithContext(ctx).Where("proposed = ?", false).Order("created_at DESC, id DESC").First(&latest).Error
+	if errLatest != nil && !errors.Is(errLatest, gorm.ErrRecordNotFound) {
+		return nil, errLatest
+	}
+	if errLatest == nil && latest.Fingerprint == fingerprint(rules) {
+		return nil, nil
+	}
+	name := "Automatic " + now.In(time.Local).Format("2006-01-02 15:04")
+	return store(ctx, db, name, rules, false, nil, now)
+}
+
+// SnapshotAsOf returns the latest captured snapshot taken at or before at.
+func SnapshotAsOf(ctx context.Context, db *gorm.DB, at time.Time) (*models.BillingRuleSnapshot, error) {
+	var snapshot models.BillingRuleSnapshot
+	if errFind := db.WithContext(ctx).
+		Where("proposed = ? AND created_at <= ?", false, at).
+		Order("created_at DESC, id DESC").
+		First(&snapshot).Error; errFind != nil {
+		return nil, errFind
+	}
+	return &snapshot, nil
+}
+
+func liveRules(ctx context.Context, db *gorm.DB) ([]Rule, error) {
+	var rows []models.BillingRule
+	if errFind := db.WithContext(ctx).Order("id ASC").Find(&rows).Error; errFind != nil {
+		return nil, errFind
+	}
+	rules := make([]Rule, 0, len(rows))
+	for _, row := range rows {
+		rules = append(rules, ruleFromModel(row))
+	}
+	return rules, nil
+}
+
+func store(ctx context.Context, db *gorm.DB, name string, rules []Rule, proposed bool, createdBy *uint64, now time.Time) (*models.BillingRuleSnapshot, error) {
+	raw, errMarshal := json.Marshal(rules)
+	if errMarshal != nil {
+		return nil, errMarshal
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Snapshot " + now.In(time.Local).Format("2006-01-02 15:04")
+	}
+	snapshot := models.BillingRuleSnapshot{
+		Name:        name,
+		Rules:       datatypes.JSON(raw),
+		RuleCount:   len(rules),
+		Fingerprint: fingerprint(rules),
+		Proposed:    proposed,
+		CreatedBy:   createdBy,
+		CreatedAt:   now,
+	}
+	if errCreate := db.WithContext(ctx).Create(&snapshot).Error; errCreate != nil {
+		return nil, errCreate
+	}
+	return &snapshot, nil
+}
+
+// fingerprint hashes the rules in ID order.
+func fingerprint(rules []Rule) string {
+	sorted := append([]Rule(nil), rules...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	raw, _ := json.Marshal(sorted)
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// Snapshotter periodically captures the live billing rules when they change, so past months
+// can later be repriced under the rules that applied at the time.
+type Snapshotter struct {
+	db       *gorm.DB
+	interval time.Duration
+}
+
+// NewSnapshotter constructs a snapshotter; returns nil when db is nil.
+func NewSnapshotter(db *gorm.DB) *Snapshotter {
+	if db == nil {
+		return nil
+	}
+	return &Snapshotter{db: db, interval: defaultSnapshotInterval}
+}
+
+// Start launches the capture loop in a background goroutine.
+func (s *Snapshotter) Start(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go s.run(ctx)
+	log.Infof("billing rule snapshotter started (interval=%s)", s.interval)
+}
+
+func (s *Snapshotter) run(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, errCapture := CaptureIfChanged(ctx, s.db, time.Now().UTC()); errCapture != nil && ctx.Err() == nil {
+			log.WithError(errCapture).Warn("billing rule snapshotter: capture failed")
+		}
+		timer := time.NewTimer(s.interval)
+		select {
+		case <-ctx.Done():
+			if !timer.Stop() {
+				<-timer.C
+			}
+			return
+		case <-timer.C:
+		}
+	}
+}
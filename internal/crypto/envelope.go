@@ -0,0 +1,304 @@
+// Package crypto implements envelope encryption for secrets stored in the database.
+//
+// Every value is sealed with a fresh random data key (AES-256-GCM), and the data key is
+// wrapped by a KeyWrapper holding the master key. The local wrapper keeps master keys in
+// memory; a KMS-backed wrapper can satisfy the same interface.
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// tokenPrefix marks encrypted string values.
+	tokenPrefix = "enc:v1:"
+	// jsonEnvelopeField holds the encrypted payload inside a JSON envelope.
+	jsonEnvelopeField = "$enc"
+	// dataKeySize is the AES-256 data key length in bytes.
+	dataKeySize = 32
+)
+
+// Markers that identify encrypted values in stored data.
+const (
+	// EncryptedPrefix starts every encrypted string value.
+	EncryptedPrefix = tokenPrefix
+	// EnvelopeField is the key of a JSON encryption envelope.
+	EnvelopeField = jsonEnvelopeField
+)
+
+// Errors returned by the encryption layer.
+var (
+	ErrNoCipher     = errors.New("crypto: encrypted value found but no encryption key is configured")
+	ErrUnknownKeyID = errors.New("crypto: unknown key id")
+	ErrMalformed    = errors.New("crypto: malformed encrypted value")
+)
+
+// KeyWrapper wraps and unwraps per-value data keys with a master key.
+type KeyWrapper interface {
+	// KeyID returns the identifier of the key used for new wraps.
+	KeyID() string
+	// Wrap encrypts a data key with the current master key.
+	Wrap(dataKey []byte) ([]byte, error)
+	// Unwrap decrypts a data key wrapped by the master key identified by keyID.
+	Unwrap(keyID string, wrapped []byte) ([]byte, error)
+}
+
+// LocalKeyWrapper wraps data keys with in-memory AES-256 master keys.
+type LocalKeyWrapper struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// NewLocalKeyWrapper builds a wrapper from master keys indexed by key ID.
+// The primary key wraps new data keys; the others remain available for decryption during rotation.
+func NewLocalKeyWrapper(primary string, keys map[string][]byte) (*LocalKeyWrapper, error) {
+	primary = strings.TrimSpace(primary)
+	if primary == "" || strings.Contains(primary, ":") {
+		return nil, fmt.Errorf("crypto: invalid key id %q", primary)
+	}
+	if _, ok := keys[primary]; !ok {
+		return nil, fmt.Errorf("crypto: primary key %q not provided", primary)
+	}
+	w := &LocalKeyWrapper{primary: primary, keys: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		if strings.Contains(id, ":") {
+			return nil, fmt.Errorf("crypto: invalid key id %q", id)
+		}
+		aead, errAEAD := newAEAD(key)
+		if errAEAD != nil {
+			return nil, fmt.Errorf("crypto: key %q: %w", id, errAEAD)
+		}
+		w.keys[id] = aead
+	}
+	return w, nil
+}
+
+// KeyID returns the primary key ID.
+func (w *LocalKeyWrapper) KeyID() string { return w.primary }
+
+// Wrap encrypts the data key with the primary master key.
+func (w *LocalKeyWrapper) Wrap(dataKey []byte) ([]byte, error) {
+	return seal(w.keys[w.primary], dataKey)
+}
+
+// Unwrap decrypts the data key with the master key identified by keyID.
+func (w *LocalKeyWrapper) Unwrap(keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := w.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKeyID, keyID)
+	}
+	return open(aead, wrapped)
+}
+
+// ParseKey decodes a 32-byte master key given as base64 or hex.
+func ParseKey(raw string) ([]byte, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, errors.New("crypto: empty key")
+	}
+	if len(raw) == hex.EncodedLen(dataKeySize) {
+		if key, errHex := hex.DecodeString(raw); errHex == nil {
+			return key, nil
+		}
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if key, errDecode := enc.DecodeString(raw); errDecode == nil {
+			if len(key) != dataKeySize {
+				return nil, fmt.Errorf("crypto: key must be %d bytes, got %d", dataKeySize, len(key))
+			}
+			return key, nil
+		}
+	}
+	return nil, errors.New("crypto: key must be base64 or hex encoded")
+}
+
+// Cipher encrypts and decrypts values using envelope encryption.
+type Cipher struct {
+	wrapper KeyWrapper
+	enabled bool
+}
+
+// NewCipher constructs a cipher. When enabled is false the cipher only decrypts,
+// which keeps existing encrypted rows readable after encryption is switched off.
+func NewCipher(wrapper KeyWrapper, enabled bool) *Cipher {
+	return &Cipher{wrapper: wrapper, enabled: enabled && wrapper != nil}
+}
+
+// Enabled reports whether new writes are encrypted.
+func (c *Cipher) Enabled() bool {
+	return c != nil && c.enabled
+}
+
+// IsEncrypted reports whether a string value carries the encryption prefix.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, tokenPrefix)
+}
+
+// EncryptString seals a plaintext string. Already encrypted and empty values are returned as is.
+func (c *Cipher) EncryptString(plain string) (string, error) {
+	if !c.Enabled() || plain == "" || IsEncrypted(plain) {
+		return plain, nil
+	}
+	dataKey := make([]byte, dataKeySize)
+	if _, errRand := io.ReadFull(rand.Reader, dataKey); errRand != nil {
+		return "", fmt.Errorf("crypto: generate data key: %w", errRand)
+	}
+	aead, errAEAD := newAEAD(dataKey)
+	if errAEAD != nil {
+		return "", errAEAD
+	}
+	sealed, errSeal := seal(aead, []byte(plain))
+	if errSeal != nil {
+		return "", errSeal
+	}
+	wrapped, errWrap := c.wrapper.Wrap(dataKey)
+	if errWrap != nil {
+		return "", fmt.Errorf("crypto: wrap data key: %w", errWrap)
+	}
+	enc := base64.RawURLEncoding
+	return tokenPrefix + c.wrapper.KeyID() + ":" + enc.EncodeToString(wrapped) + ":" + enc.EncodeToString(sealed), nil
+}
+
+// DecryptString opens an encrypted string; plaintext values are returned unchanged.
+func (c *Cipher) DecryptString(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if c == nil || c.wrapper == nil {
+		return "", ErrNoCipher
+	}
+	parts := strings.Split(strings.TrimPrefix(value, tokenPrefix), ":")
+	if len(parts) != 3 {
+		return "", ErrMalformed
+	}
+	enc := base64.RawURLEncoding
+	wrapped, errWrapped := enc.DecodeString(parts[1])
+	sealed, errSealed := enc.DecodeString(parts[2])
+	if errWrapped != nil || errSealed != nil {
+		return "", ErrMalformed
+	}
+	dataKey, errUnwrap := c.wrapper.Unwrap(parts[0], wrapped)
+	if errUnwrap != nil {
+		return "", fmt.Errorf("crypto: unwrap data key: %w", errUnwrap)
+	}
+	aead, errAEAD := newAEAD(dataKey)
+	if errAEAD != nil {
+		return "", errAEAD
+	}
+	plain, errOpen := open(aead, sealed)
+	if errOpen != nil {
+		return "", errOpen
+	}
+	return string(plain), nil
+}
+
+// EncryptJSON seals a JSON document into an envelope object. The top-level "type" field is
+// kept in clear text so that SQL filters on the auth provider type keep working.
+func (c *Cipher) EncryptJSON(raw []byte) ([]byte, error) {
+	if !c.Enabled() || len(raw) == 0 || IsEncryptedJSON(raw) {
+		return raw, nil
+	}
+	token, errEncrypt := c.EncryptString(string(raw))
+	if errEncrypt != nil {
+		return nil, errEncrypt
+	}
+	envelope := map[string]any{jsonEnvelopeField: token}
+	var head struct {
+		Type any `json:"type"`
+	}
+	if errHead := json.Unmarshal(raw, &head); errHead == nil && head.Type != nil {
+		envelope["type"] = head.Type
+	}
+	return json.Marshal(envelope)
+}
+
+// DecryptJSON opens a JSON envelope; plaintext documents are returned unchanged.
+func (c *Cipher) DecryptJSON(raw []byte) ([]byte, error) {
+	token, ok := envelopeToken(raw)
+	if !ok {
+		return raw, nil
+	}
+	plain, errDecrypt := c.DecryptString(token)
+	if errDecrypt != nil {
+		return nil, errDecrypt
+	}
+	return []byte(plain), nil
+}
+
+// IsEncryptedJSON reports whether a JSON document is an encryption envelope.
+func IsEncryptedJSON(raw []byte) bool {
+	_, ok := envelopeToken(raw)
+	return ok
+}
+
+func envelopeToken(raw []byte) (string, bool) {
+	if len(raw) == 0 || !strings.Contains(string(raw), jsonEnvelopeField) {
+		return "", false
+	}
+	var envelope map[string]json.RawMessage
+	if errUnmarshal := json.Unmarshal(raw, &envelope); errUnmarshal != nil {
+		return "", false
+	}
+	field, ok := envelope[jsonEnvelopeField]
+	if !ok {
+		return "", false
+	}
+	var token string
+	if errToken := json.Unmarshal(field, &token); errToken != nil || !IsEncrypted(token) {
+		return "", false
+	}
+	return token, true
+}
+
+var defaultCipher atomic.Pointer[Cipher]
+
+// SetDefault installs the process-wide cipher used by model hooks.
+func SetDefault(c *Cipher) {
+	defaultCipher.Store(c)
+}
+
+// Default returns the process-wide cipher, or nil when none is configured.
+func Default() *Cipher {
+	return defaultCipher.Load()
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != dataKeySize {
+		return nil, fmt.Errorf("crypto: key must be %d bytes, got %d", dataKeySize, len(key))
+	}
+	block, errBlock := aes.NewCipher(key)
+	if errBlock != nil {
+		return nil, errBlock
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, plain []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, errRand := io.ReadFull(rand.Reader, nonce); errRand != nil {
+		return nil, fmt.Errorf("crypto: generate nonce: %w", errRand)
+	}
+	return aead.Seal(nonce, nonce, plain, nil), nil
+}
+
+func open(aead cipher.AEAD, sealed []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	nonce, body := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, errOpen := aead.Open(nil, nonce, body, nil)
+	if errOpen != nil {
+		return nil, fmt.Errorf("crypto: decrypt: %w", errOpen)
+	}
+	return plain, nil
+}
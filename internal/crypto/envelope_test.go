@@ -0,0 +1,70 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func testCipher(t *testing.T, enabled bool) *Cipher {
+	t.Helper()
+	wrapper, errWrapper := NewLocalKeyWrapper("k1", map[string][]byte{"k1": bytes.Repeat([]byte{7}, 32)})
+	if errWrapper != nil {
+		t.Fatalf("new wrapper: %v", errWrapper)
+	}
+	return NewCipher(wrapper, enabled)
+}
+
+func TestCipherStringRoundTrip(t *testing.T) {
+	c := testCipher(t, true)
+	token, errEncrypt := c.EncryptString("sk-secret")
+	if errEncrypt != nil {
+		t.Fatalf("encrypt: %v", errEncrypt)
+	}
+	if !IsEncrypted(token) || token == "sk-secret" {
+		t.Fatalf("expected encrypted token, got %q", token)
+	}
+	again, _ := c.EncryptString(token)
+	if again != token {
+		t.Fatalf("expected encrypted input to pass through")
+	}
+	plain, errDecrypt := c.DecryptString(token)
+	if errDecrypt != nil || plain != "sk-secret" {
+		t.Fatalf("decrypt: %q %v", plain, errDecrypt)
+	}
+
+	var missing *Cipher
+	if _, errMissing := missing.DecryptString(token); !errors.Is(errMissing, ErrNoCipher) {
+		t.Fatalf("expected ErrNoCipher, got %v", errMissing)
+	}
+}
+
+func TestCipherJSONKeepsType(t *testing.T) {
+	c := testCipher(t, true)
+	raw := []byte(`{"type":"codex","access_token":"abc"}`)
+	sealed, errEncrypt := c.EncryptJSON(raw)
+	if errEncrypt != nil {
+		t.Fatalf("encrypt: %v", errEncrypt)
+	}
+	if bytes.Contains(sealed, []byte("abc")) {
+		t.Fatalf("expected token to be hidden: %s", sealed)
+	}
+	var head map[string]any
+	if errUnmarshal := json.Unmarshal(sealed, &head); errUnmarshal != nil || head["type"] != "codex" {
+		t.Fatalf("expected clear type, got %s", sealed)
+	}
+	opened, errDecrypt := c.DecryptJSON(sealed)
+	if errDecrypt != nil || !bytes.Equal(opened, raw) {
+		t.Fatalf("decrypt: %s %v", opened, errDecrypt)
+	}
+
+	readOnly := testCipher(t, false)
+	passthrough, _ := readOnly.EncryptJSON(raw)
+	if !bytes.Equal(passthrough, raw) {
+		t.Fatalf("expected disabled cipher to keep plaintext")
+	}
+	if opened, errDecrypt = readOnly.DecryptJSON(sealed); errDecrypt != nil || !bytes.Equal(opened, raw) {
+		t.Fatalf("expected disabled cipher to decrypt: %v", errDecrypt)
+	}
+}
@@ -0,0 +1,293 @@
+// Package currency converts USD-denominated billing amounts into other currencies using
+// the admin-managed (or fetched) exchange rate table, and formats them for display.
+package currency
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Base is the currency every stored cost, balance and quota is denominated in.
+const Base = "USD"
+
+// cacheTTL bounds how stale the cached rate table may be on nodes that did not write it.
+const cacheTTL = time.Minute
+
+var (
+	// ErrInvalidCode is returned for codes that are not three ASCII letters.
+	ErrInvalidCode = errors.New("currency: invalid currency code")
+	// ErrUnknownRate is returned when no exchange rate exists for a currency.
+	ErrUnknownRate = errors.New("currency: no exchange rate")
+	// ErrInvalidRate is returned for non-positive or non-finite rates.
+	ErrInvalidRate = errors.New("currency: invalid exchange rate")
+	// ErrRateInUse is returned when deleting a rate still referenced by billing rules.
+	ErrRateInUse = errors.New("currency: exchange rate is used by billing rules")
+)
+
+// Normalize upper-cases and validates an ISO currency code; empty means Base.
+func Normalize(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return Base, nil
+	}
+	if len(code) != 3 {
+		return "", ErrInvalidCode
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return "", ErrInvalidCode
+		}
+	}
+	return code, nil
+}
+
+// Table maps currency codes to units per 1 USD. Base is always present at 1.
+type Table map[string]float64
+
+// Rate returns units of code per 1 USD.
+func (t Table) Rate(code string) (float64, error) {
+	code, errCode := Normalize(code)
+	if errCode != nil {
+		return 0, errCode
+	}
+	if code == Base {
+		return 1, nil
+	}
+	rate, ok := t[code]
+	if !ok || rate <= 0 {
+		return 0, fmt.Errorf("%w for %s", ErrUnknownRate, code)
+	}
+	return rate, nil
+}
+
+// Convert converts amount from one currency to another through USD.
+func (t Table) Convert(amount float64, from, to string) (float64, error) {
+	fromRate, errFrom := t.Rate(from)
+	if errFrom != nil {
+		return 0, errFrom
+	}
+	toRate, errTo := t.Rate(to)
+	if errTo != nil {
+		return 0, errTo
+	}
+	return amount / fromRate * toRate, nil
+}
+
+// Load reads the exchange rate table.
+func Load(ctx context.Context, db *gorm.DB) (Table, error) {
+	var rows []models.ExchangeRate
+	if errFind := db.WithContext(ctx).Find(&rows).Error; errFind != nil {
+		return nil, fmt.Errorf("currency: load rates: %w", errFind)
+	}
+	table := Table{Base: 1}
+	for _, row := range rows {
+		if row.Rate > 0 {
+			table[row.Currency] = row.Rate
+		}
+	}
+	return table, nil
+}
+
+// rateCache holds the last loaded table for hot paths such as cost calculation.
+type rateCache struct {
+	mu       sync.Mutex
+	table    Table
+	loadedAt time.Time
+}
+
+var cache rateCache
+
+// Cached returns the rate table, reloading it at most once per cacheTTL. A failed reload
+// keeps serving the previous table.
+func Cached(ctx context.Context, db *gorm.DB) (Table, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.table != nil && time.Since(cache.loadedAt) < cacheTTL {
+		return cache.table, nil
+	}
+	table, errLoad := Load(ctx, db)
+	if errLoad != nil {
+		if cache.table != nil {
+			return cache.table, nil
+		}
+		return nil, errLoad
+	}
+	cache.table = table
+	cache.loadedAt = time.Now()
+	return table, nil
+}
+
+// Invalidate drops the cached table so the next Cached call reloads it.
+func Invalidate() {
+	cache.mu.Lock()
+	cache.table = nil
+	cache.mu.Unlock()
+}
+
+// SetRate creates or replaces the rate for code.
+func SetRate(ctx context.Context, db *gorm.DB, code string, rate float64, source models.ExchangeRateSource) (*models.ExchangeRate, error) {
+	code, errCode := Normalize(code)
+	if errCode != nil {
+		return nil, errCode
+	}
+	if code == Base || rate <= 0 || math.IsNaN(rate) || math.IsInf(rate, 0) {
+		return nil, ErrInvalidRate
+	}
+	now := time.Now().UTC()
+	row := models.ExchangeRate{Currency: code, Rate: rate, Source: source, CreatedAt: now, UpdatedAt: now}
+	if errUpsert := db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "currency"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "updated_at"}),
+	}).Create(&row).Error; errUpsert != nil {
+		return nil, errUpsert
+	}
+	Invalidate()
+	var out models.ExchangeRate
+	if errFind := db.WithContext(ctx).Where("currency = ?", code).First(&out).Error; errFind != nil {
+		return nil, errFind
+	}
+	return &out, nil
+}
+
+// DeleteRate removes the rate for code unless billing rules are priced in it.
+func DeleteRate(ctx context.Context, db *gorm.DB, code string) error {
+	code, errCode := Normalize(code)
+	if errCode != nil {
+		return errCode
+	}
+	var inUse int64
+	if errCount := db.WithContext(ctx).Model(&models.BillingRule{}).
+		Where("currency = ?", code).
+		Count(&inUse).Error; errCount != nil {
+		return errCount
+	}
+	if inUse > 0 {
+		return ErrRateInUse
+	}
+	res := db.WithContext(ctx).Where("currency = ?", code).Delete(&models.ExchangeRate{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	Invalidate()
+	return nil
+}
+
+// DisplayCurrency returns the DISPLAY_CURRENCY setting, defaulting to Base.
+func DisplayCurrency() string {
+	raw, ok := internalsettings.DBConfigValue(internalsettings.DisplayCurrencyKey)
+	if !ok || len(bytes.TrimSpace(raw)) == 0 {
+		return internalsettings.DefaultDisplayCurrency
+	}
+	var text string
+	if errUnmarshal := json.Unmarshal(raw, &text); errUnmarshal != nil {
+		text = string(raw)
+	}
+	code, errCode := Normalize(text)
+	if errCode != nil {
+		return internalsettings.DefaultDisplayCurrency
+	}
+	return code
+}
+
+// Converter turns USD amounts into one target currency.
+type Converter struct {
+	Currency string  // Target currency code.
+	Rate     float64 // Units of Currency per 1 USD.
+}
+
+// USD is the identity converter.
+var USD = Converter{Currency: Base, Rate: 1}
+
+// NewConverter returns a converter into code using table.
+func NewConverter(table Table, code string) (Converter, error) {
+	code, errCode := Normalize(code)
+	if errCode != nil {
+		return Converter{}, errCode
+	}
+	rate, errRate := table.Rate(code)
+	if errRate != nil {
+		return Converter{}, errRate
+	}
+	return Converter{Currency: code, Rate: rate}, nil
+}
+
+// Display returns a converter into the display currency. When its rate is missing the
+// amounts stay in USD rather than being mislabelled.
+func Display(ctx context.Context, db *gorm.DB) Converter {
+	code := DisplayCurrency()
+	if code == Base || db == nil {
+		return USD
+	}
+	table, errLoad := Cached(ctx, db)
+	if errLoad != nil {
+		return USD
+	}
+	converter, errConverter := NewConverter(table, code)
+	if errConverter != nil {
+		return USD
+	}
+	return converter
+}
+
+// Amount converts a USD amount.
+func (c Converter) Amount(usd float64) float64 {
+	if c.Rate == 0 {
+		return usd
+	}
+	return usd * c.Rate
+}
+
+// Micros converts USD micros into micros of the target currency.
+func (c Converter) Micros(usdMicros int64) int64 {
+	if c.Rate == 0 || c.Rate == 1 {
+		return usdMicros
+	}
+	return int64(math.Round(float64(usdMicros) * c.Rate))
+}
+
+// Format renders USD micros in the target currency with the given number of decimals,
+// e.g. "$1.25", "€1.15" or "CHF 1.10".
+func (c Converter) Format(usdMicros int64, decimals int) string {
+	return FormatAmount(c.Amount(float64(usdMicros)/1_000_000), c.Currency, decimals)
+}
+
+// symbols lists prefixes for common currencies; others are prefixed with their code.
+var symbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+	"CNY": "¥",
+	"KRW": "₩",
+	"INR": "₹",
+}
+
+// FormatAmount renders amount in code with the given number of decimals.
+func FormatAmount(amount float64, code string, decimals int) string {
+	if code == "" {
+		code = Base
+	}
+	number := fmt.Sprintf("%.*f", decimals, amount)
+	if symbol, ok := symbols[code]; ok {
+		if strings.HasPrefix(number, "-") {
+			return "-" + symbol + number[1:]
+		}
+		return symbol + number
+	}
+	return code + " " + number
+}
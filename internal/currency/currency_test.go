@@ -0,0 +1,141 @@
+package currency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"gorm.io/gorm"
+)
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{"": Base, " eur ": "EUR", "JPY": "JPY"}
+	for in, want := range cases {
+		got, errNormalize := Normalize(in)
+		if errNormalize != nil || got != want {
+			t.Fatalf("Normalize(%q) = %q, %v; want %q", in, got, errNormalize, want)
+		}
+	}
+	for _, in := range []string{"EU", "EURO", "E1R"} {
+		if _, errNormalize := Normalize(in); !errors.Is(errNormalize, ErrInvalidCode) {
+			t.Fatalf("Normalize(%q) error = %v, want ErrInvalidCode", in, errNormalize)
+		}
+	}
+}
+
+func TestTableConvertAndFormat(t *testing.T) {
+	t.Parallel()
+
+	table := Table{Base: 1, "EUR": 0.9, "JPY": 150}
+	got, errConvert := table.Convert(9, "EUR", "JPY")
+	if errConvert != nil || math.Abs(got-1500) > 1e-9 {
+		t.Fatalf("Convert(9 EUR -> JPY) = %v, %v; want 1500", got, errConvert)
+	}
+	if _, errConvert = table.Convert(1, "GBP", Base); !errors.Is(errConvert, ErrUnknownRate) {
+		t.Fatalf("Convert from unknown currency error = %v, want ErrUnknownRate", errConvert)
+	}
+
+	converter, errConverter := NewConverter(table, "eur")
+	if errConverter != nil {
+		t.Fatalf("NewConverter: %v", errConverter)
+	}
+	if micros := converter.Micros(2_000_000); micros != 1_800_000 {
+		t.Fatalf("Micros = %d, want 1800000", micros)
+	}
+	if text := converter.Format(2_000_000, 2); text != "€1.80" {
+		t.Fatalf("Format = %q, want €1.80", text)
+	}
+	if text := FormatAmount(-1.5, "CHF", 2); text != "CHF -1.50" {
+		t.Fatalf("FormatAmount = %q, want CHF -1.50", text)
+	}
+	if text := USD.Format(-1_500_000, 2); text != "-$1.50" {
+		t.Fatalf("USD.Format = %q, want -$1.50", text)
+	}
+}
+
+func TestParseFeedRebasesToUSD(t *testing.T) {
+	t.Parallel()
+
+	table, errParse := ParseFeed([]byte(`{"base_code":"EUR","conversion_rates":{"EUR":1,"USD":1.25,"GBP":0.85,"X1Z":3}}`))
+	if errParse != nil {
+		t.Fatalf("ParseFeed: %v", errParse)
+	}
+	if table[Base] != 1 {
+		t.Fatalf("USD rate = %v, want 1", table[Base])
+	}
+	if math.Abs(table["EUR"]-0.8) > 1e-9 || math.Abs(table["GBP"]-0.68) > 1e-9 {
+		t.Fatalf("unexpected rebased table %+v", table)
+	}
+	if _, ok := table["X1Z"]; ok {
+		t.Fatalf("invalid code should be skipped: %+v", table)
+	}
+	if _, errParse = ParseFeed([]byte(`{"base":"EUR","rates":{"GBP":0.85}}`)); !errors.Is(errParse, ErrUnknownRate) {
+		t.Fatalf("feed without USD error = %v, want ErrUnknownRate", errParse)
+	}
+}
+
+func TestSetAndDeleteRate(t *testing.T) {
+	dsn := fmt.Sprintf("file:currency_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	ctx := context.Background()
+
+	if _, errSet := SetRate(ctx, conn, Base, 2, models.ExchangeRateSourceManual); !errors.Is(errSet, ErrInvalidRate) {
+		t.Fatalf("SetRate(USD) error = %v, want ErrInvalidRate", errSet)
+	}
+	if _, errSet := SetRate(ctx, conn, "eur", 0.9, models.ExchangeRateSourceFetched); errSet != nil {
+		t.Fatalf("SetRate: %v", errSet)
+	}
+	row, errSet := SetRate(ctx, conn, "EUR", 0.95, models.ExchangeRateSourceManual)
+	if errSet != nil || row.Currency != "EUR" || row.Rate != 0.95 || row.Source != models.ExchangeRateSourceManual {
+		t.Fatalf("unexpected upserted rate %+v err=%v", row, errSet)
+	}
+	var count int64
+	conn.Model(&models.ExchangeRate{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("rate rows = %d, want 1", count)
+	}
+
+	internalsettings.StoreDBConfig(time.Now(), map[string]json.RawMessage{
+		internalsettings.DisplayCurrencyKey: json.RawMessage(`"eur"`),
+	})
+	t.Cleanup(func() { internalsettings.StoreDBConfig(time.Now(), nil) })
+	if display := Display(ctx, conn); display.Currency != "EUR" || display.Rate != 0.95 {
+		t.Fatalf("unexpected display converter %+v", display)
+	}
+
+	rule := models.BillingRule{AuthGroupID: 1, UserGroupID: 1, BillingType: models.BillingTypePerToken, Currency: "EUR", IsEnabled: true}
+	if errCreate := conn.Create(&rule).Error; errCreate != nil {
+		t.Fatalf("create rule: %v", errCreate)
+	}
+	if errDelete := DeleteRate(ctx, conn, "EUR"); !errors.Is(errDelete, ErrRateInUse) {
+		t.Fatalf("DeleteRate in use error = %v, want ErrRateInUse", errDelete)
+	}
+	if errUpdate := conn.Model(&rule).Update("currency", Base).Error; errUpdate != nil {
+		t.Fatalf("update rule: %v", errUpdate)
+	}
+	if errDelete := DeleteRate(ctx, conn, "EUR"); errDelete != nil {
+		t.Fatalf("DeleteRate: %v", errDelete)
+	}
+	if errDelete := DeleteRate(ctx, conn, "EUR"); !errors.Is(errDelete, gorm.ErrRecordNotFound) {
+		t.Fatalf("DeleteRate missing error = %v, want ErrRecordNotFound", errDelete)
+	}
+	if display := Display(ctx, conn); display != USD {
+		t.Fatalf("display without rate = %+v, want USD", display)
+	}
+}
@@ -0,0 +1,225 @@
+package currency
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultFeedInterval = 6 * time.Hour
+	minFeedInterval     = 5 * time.Minute
+	feedTimeout         = 15 * time.Second
+	maxFeedBody         = 1 << 20
+)
+
+// ErrNoFeed is returned when a fetch is requested without a configured feed URL.
+var ErrNoFeed = errors.New("currency: exchange rate feed is not configured")
+
+// FeedConfig mirrors the EXCHANGE_RATE_FEED setting.
+type FeedConfig struct {
+	URL             string `json:"url"`              // JSON endpoint returning a base and a rates object.
+	IntervalMinutes int    `json:"interval_minutes"` // Fetch interval; defaults to six hours.
+}
+
+// LoadFeedConfig reads the EXCHANGE_RATE_FEED setting; an empty URL disables fetching.
+func LoadFeedConfig() FeedConfig {
+	var cfg FeedConfig
+	raw, ok := internalsettings.DBConfigValue(internalsettings.ExchangeRateFeedKey)
+	if !ok || len(bytes.TrimSpace(raw)) == 0 {
+		return cfg
+	}
+	if errUnmarshal := json.Unmarshal(raw, &cfg); errUnmarshal != nil {
+		log.WithError(errUnmarshal).Warn("currency: invalid exchange rate feed setting")
+		return FeedConfig{}
+	}
+	cfg.URL = strings.TrimSpace(cfg.URL)
+	return cfg
+}
+
+func (c FeedConfig) interval() time.Duration {
+	interval := time.Duration(c.IntervalMinutes) * time.Minute
+	if interval <= 0 {
+		return defaultFeedInterval
+	}
+	if interval < minFeedInterval {
+		return minFeedInterval
+	}
+	return interval
+}
+
+// feedPayload accepts the common rate feed shapes: {"base": ..., "rates": {...}} and
+// {"base_code": ..., "conversion_rates": {...}}.
+type feedPayload struct {
+	Base            string             `json:"base"`
+	BaseCode        string             `json:"base_code"`
+	Rates           map[string]float64 `json:"rates"`
+	ConversionRates map[string]float64 `json:"conversion_rates"`
+}
+
+// ParseFeed decodes a rate feed response into units per 1 USD, rebasing when the feed
+// quotes another base currency.
+func ParseFeed(body []byte) (Table, error) {
+	var payload feedPayload
+	if errUnmarshal := json.Unmarshal(body, &payload); errUnmarshal != nil {
+		return nil, fmt.Errorf("currency: decode feed: %w", errUnmarshal)
+	}
+	rates := payload.Rates
+	if len(rates) == 0 {
+		rates = payload.ConversionRates
+	}
+	base := payload.Base
+	if base == "" {
+		base = payload.BaseCode
+	}
+	base, errBase := Normalize(base)
+	if errBase != nil {
+		return nil, fmt.Errorf("currency: feed base: %w", errBase)
+	}
+	quoted := make(Table, len(rates)+1)
+	for code, rate := range rates {
+		normalized, errCode := Normalize(code)
+		if errCode != nil || rate <= 0 {
+			continue
+		}
+		quoted[normalized] = rate
+	}
+	quoted[base] = 1
+	usdRate, ok := quoted[Base]
+	if !ok {
+		return nil, fmt.Errorf("%w for %s in feed based on %s", ErrUnknownRate, Base, base)
+	}
+	out := make(Table, len(quoted))
+	for code, rate := range quoted {
+		out[code] = rate / usdRate
+	}
+	out[Base] = 1
+	return out, nil
+}
+
+// Fetch pulls the configured feed and stores its rates. Currencies with a manual rate
+// keep it. Returns the number of rates written.
+func Fetch(ctx context.Context, db *gorm.DB, client *http.Client, url string) (int, error) {
+	req, errReq := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if errReq != nil {
+		return 0, fmt.Errorf("currency: build feed request: %w", errReq)
+	}
+	resp, errDo := client.Do(req)
+	if errDo != nil {
+		return 0, fmt.Errorf("currency: fetch feed: %w", errDo)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("currency: fetch feed: status %d", resp.StatusCode)
+	}
+	body, errRead := io.ReadAll(io.LimitReader(resp.Body, maxFeedBody))
+	if errRead != nil {
+		return 0, fmt.Errorf("currency: read feed: %w", errRead)
+	}
+	table, errParse := ParseFeed(body)
+	if errParse != nil {
+		return 0, errParse
+	}
+
+	var manual []string
+	if errFind := db.WithContext(ctx).Model(&models.ExchangeRate{}).
+		Where("source = ?", models.ExchangeRateSourceManual).
+		Pluck("currency", &manual).Error; errFind != nil {
+		return 0, errFind
+	}
+	skip := make(map[string]struct{}, len(manual)+1)
+	for _, code := range manual {
+		skip[code] = struct{}{}
+	}
+	skip[Base] = struct{}{}
+
+	written := 0
+	for code, rate := range table {
+		if _, ok := skip[code]; ok {
+			continue
+		}
+		if _, errSet := SetRate(ctx, db, code, rate, models.ExchangeRateSourceFetched); errSet != nil {
+			return written, errSet
+		}
+		written++
+	}
+	return written, nil
+}
+
+// FetchConfigured fetches the feed named by the EXCHANGE_RATE_FEED setting once.
+func FetchConfigured(ctx context.Context, db *gorm.DB) (int, error) {
+	cfg := LoadFeedConfig()
+	if cfg.URL == "" {
+		return 0, ErrNoFeed
+	}
+	return Fetch(ctx, db, &http.Client{Timeout: feedTimeout}, cfg.URL)
+}
+
+// Fetcher periodically refreshes exchange rates from the configured feed.
+type Fetcher struct {
+	db     *gorm.DB
+	client *http.Client
+}
+
+// NewFetcher constructs a fetcher; returns nil when db is nil.
+func NewFetcher(db *gorm.DB) *Fetcher {
+	if db == nil {
+		return nil
+	}
+	return &Fetcher{db: db, client: &http.Client{Timeout: feedTimeout}}
+}
+
+// Start launches the fetch loop in a background goroutine.
+func (f *Fetcher) Start(ctx context.Context) {
+	if f == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go f.run(ctx)
+	log.Info("exchange rate fetcher started")
+}
+
+func (f *Fetcher) run(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		cfg := LoadFeedConfig()
+		if cfg.URL != "" {
+			if written, errFetch := Fetch(ctx, f.db, f.client, cfg.URL); errFetch != nil {
+				log.WithError(errFetch).Warn("exchange rate fetcher: fetch failed")
+			} else {
+				log.Debugf("exchange rate fetcher: stored %d rates", written)
+			}
+		}
+		wait := cfg.interval()
+		if cfg.URL == "" {
+			// Check again soon so a newly configured feed is picked up without a restart.
+			wait = minFeedInterval
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			if !timer.Stop() {
+				<-timer.C
+			}
+			return
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"embed"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// baselineSchemas holds the frozen DDL of migration 1 for each dialect. The files are never
+// edited: model changes ship as new migrations, so every deployment starts from the same
+// tables no matter which build created them.
+//
+//go:embed baseline/*.sql
+var baselineSchemas embed.FS
+
+// migrateBaseline brings the schema to the baseline of the versioned migrations. Fresh
+// databases get the frozen DDL of their dialect; databases created before schema versioning,
+// or left half-created by an interrupted baseline, are upgraded in place instead.
+func migrateBaseline(conn *gorm.DB) error {
+	if hasLegacySchema(conn) {
+		return upgradeLegacySchema(conn)
+	}
+	return createBaselineSchema(conn)
+}
+
+// hasLegacySchema reports whether any baseline table already exists.
+func hasLegacySchema(conn *gorm.DB) bool {
+	migrator := conn.Migrator()
+	if migrator.HasTable("recharge_cards") {
+		return true
+	}
+	for _, model := range migrationModels() {
+		if migrator.HasTable(model) {
+			return true
+		}
+	}
+	return false
+}
+
+// createBaselineSchema runs the frozen baseline DDL of the current dialect and seeds defaults.
+func createBaselineSchema(conn *gorm.DB) error {
+	dialect := DialectName(conn)
+	if dialect == "" {
+		dialect = DialectPostgres
+	}
+	statements, errLoad := baselineStatements(dialect)
+	if errLoad != nil {
+		return errLoad
+	}
+	for i, statement := range statements {
+		if errExec := conn.Exec(statement).Error; errExec != nil {
+			return fmt.Errorf("db: baseline statement %d: %w", i+1, errExec)
+		}
+	}
+	if dialect == DialectPostgres {
+		if errIndex := createSearchIndexesPostgres(conn); errIndex != nil {
+			return errIndex
+		}
+	}
+	return seedBaseline(conn)
+}
+
+// baselineStatements returns the frozen baseline DDL of dialect, one statement per entry.
+func baselineStatements(dialect string) ([]string, error) {
+	raw, errRead := baselineSchemas.ReadFile("baseline/" + dialect + ".sql")
+	if errRead != nil {
+		return nil, fmt.Errorf("db: no baseline schema for dialect %s: %w", dialect, errRead)
+	}
+	lines := make([]string, 0, 512)
+	for _, line := range strings.Split(string(raw), "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			lines = append(lines, line)
+		}
+	}
+	out := make([]string, 0, 256)
+	for _, statement := range strings.Split(strings.Join(lines, "\n"), ";\n") {
+		if statement = strings.TrimSpace(statement); statement != "" {
+			out = append(out, statement)
+		}
+	}
+	return out, nil
+}
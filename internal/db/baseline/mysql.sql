@@ -0,0 +1,148 @@
+-- Baseline schema (migration 1) for MySQL, applied to fresh databases.
+-- Frozen: do not edit. Change the schema with a new migration in versions.go.
+
+CREATE TABLE `admins` (`id` bigint unsigned AUTO_INCREMENT,`username` VARCHAR(255) NOT NULL,`password` TEXT NOT NULL,`active` boolean NOT NULL DEFAULT true,`is_super_admin` boolean NOT NULL DEFAULT false,`organization_id` bigint unsigned,`permissions` JSON NOT NULL DEFAULT ('[]'),`denied_permissions` JSON NOT NULL DEFAULT ('[]'),`allowed_ips` JSON NOT NULL DEFAULT ('[]'),`oidc_issuer` VARCHAR(255),`oidc_subject` VARCHAR(255),`totp_secret` TEXT,`passkey_id` BLOB,`passkey_public_key` BLOB,`passkey_sign_count` bigint,`passkey_backup_eligible` boolean,`passkey_backup_state` boolean,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),UNIQUE INDEX `idx_admins_username` (`username`),INDEX `idx_admins_organization_id` (`organization_id`),INDEX `idx_admins_oidc_identity` (`oidc_issuer`,`oidc_subject`));
+
+CREATE TABLE `plans` (`id` bigint unsigned AUTO_INCREMENT,`name` varchar(255) NOT NULL,`month_price` decimal(10,2) NOT NULL DEFAULT 0,`description` TEXT,`support_models` JSON NOT NULL DEFAULT ('[]'),`user_group_id` JSON NOT NULL DEFAULT ('[]'),`feature1` varchar(255),`feature2` varchar(255),`feature3` varchar(255),`feature4` varchar(255),`sort_order` bigint NOT NULL DEFAULT 0,`total_quota` decimal(20,10) NOT NULL DEFAULT 0,`daily_quota` decimal(20,10) NOT NULL DEFAULT 0,`rate_limit` bigint NOT NULL DEFAULT 0,`is_enabled` boolean NOT NULL DEFAULT true,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`));
+
+CREATE TABLE `user_groups` (`id` bigint unsigned AUTO_INCREMENT,`name` VARCHAR(255) NOT NULL,`is_default` boolean NOT NULL DEFAULT false,`rate_limit` bigint NOT NULL DEFAULT 0,`rpm_limit` bigint NOT NULL DEFAULT 0,`tpm_limit` bigint NOT NULL DEFAULT 0,`max_concurrent_requests` bigint NOT NULL DEFAULT 0,`daily_spend_limit` decimal(20,10) NOT NULL DEFAULT 0,`monthly_spend_limit` decimal(20,10) NOT NULL DEFAULT 0,`parent_id` bigint unsigned,`billing_rule_group_id` bigint unsigned,`request_log_enabled` boolean NOT NULL DEFAULT false,`organization_id` bigint unsigned,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),UNIQUE INDEX `idx_user_groups_name` (`name`),INDEX `idx_user_groups_parent_id` (`parent_id`),INDEX `idx_user_groups_organization_id` (`organization_id`));
+
+CREATE TABLE `auth_groups` (`id` bigint unsigned AUTO_INCREMENT,`name` VARCHAR(255) NOT NULL,`is_default` boolean NOT NULL DEFAULT false,`rate_limit` bigint NOT NULL DEFAULT 0,`user_group_id` JSON NOT NULL DEFAULT ('[]'),`schedule` JSON,`off_schedule` boolean NOT NULL DEFAULT false,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),UNIQUE INDEX `idx_auth_groups_name` (`name`));
+
+CREATE TABLE `users` (`id` bigint unsigned AUTO_INCREMENT,`username` VARCHAR(255) NOT NULL,`name` TEXT,`email` VARCHAR(255),`password` TEXT NOT NULL,`ldap_dn` VARCHAR(255),`email_verified_at` datetime(3) NULL,`organization_id` bigint unsigned,`user_group_id` JSON NOT NULL DEFAULT ('[]'),`bill_user_group_id` JSON NOT NULL DEFAULT ('[]'),`plan_id` bigint unsigned,`daily_max_usage` decimal(20,10) NOT NULL DEFAULT 0,`rate_limit` bigint NOT NULL DEFAULT 0,`max_concurrent_requests` bigint NOT NULL DEFAULT 0,`daily_spend_limit` decimal(20,10) NOT NULL DEFAULT 0,`monthly_spend_limit` decimal(20,10) NOT NULL DEFAULT 0,`active` boolean NOT NULL DEFAULT true,`disabled` boolean NOT NULL DEFAULT false,`totp_secret` TEXT,`passkey_id` BLOB,`passkey_public_key` BLOB,`passkey_sign_count` bigint,`passkey_backup_eligible` boolean,`passkey_backup_state` boolean,`sessions_revoked_at` datetime(3) NULL,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),UNIQUE INDEX `idx_users_username` (`username`),UNIQUE INDEX `idx_users_email` (`email`),INDEX `idx_users_ldapdn` (`ldap_dn`),INDEX `idx_users_organization_id` (`organization_id`),INDEX `idx_users_plan_id` (`plan_id`),CONSTRAINT `fk_users_plan` FOREIGN KEY (`plan_id`) REFERENCES `plans`(`id`));
+
+CREATE TABLE `auths` (`id` bigint unsigned AUTO_INCREMENT,`key` VARCHAR(255) NOT NULL,`name` varchar(64),`proxy_url` TEXT,`auth_group_id` JSON NOT NULL DEFAULT ('[]'),`content` JSON NOT NULL,`whitelist_enabled` boolean NOT NULL DEFAULT false,`allowed_models` JSON NOT NULL DEFAULT ('[]'),`excluded_models` JSON NOT NULL DEFAULT ('[]'),`is_available` boolean NOT NULL DEFAULT true,`rate_limit` bigint NOT NULL DEFAULT 0,`priority` bigint NOT NULL DEFAULT 0,`token_invalid` boolean NOT NULL DEFAULT false,`last_auth_check_at` DATETIME(3),`last_auth_error` TEXT,`cooling_down` boolean NOT NULL DEFAULT false,`cooldown_until` datetime(3) NULL,`cooldown_reason` TEXT,`pending_approval` boolean NOT NULL DEFAULT false,`approval_reason` TEXT,`imported_by` varchar(255),`contributed_by_user_id` bigint unsigned,`quota_poll_interval_seconds` bigint,`poll_enabled` boolean NOT NULL DEFAULT true,`environments` JSON NOT NULL DEFAULT ('[]'),`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),UNIQUE INDEX `idx_auths_key` (`key`),INDEX `idx_auths_priority` (`priority`),INDEX `idx_auths_cooling_down` (`cooling_down`),INDEX `idx_auths_cooldown_until` (`cooldown_until`),INDEX `idx_auths_pending_approval` (`pending_approval`),INDEX `idx_auths_contributed_by_user_id` (`contributed_by_user_id`));
+
+CREATE TABLE `quota` (`id` bigint unsigned AUTO_INCREMENT,`auth_id` bigint unsigned NOT NULL,`type` VARCHAR(255) NOT NULL,`data` JSON NOT NULL DEFAULT ('{}'),`plan` TEXT,`remaining_requests` bigint,`remaining_tokens` bigint,`remaining_fraction` double,`reset_at` DATETIME(3),`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_quota_auth_id` (`auth_id`),INDEX `idx_quota_type` (`type`),INDEX `idx_quota_reset_at` (`reset_at`));
+
+CREATE TABLE `api_keys` (`id` bigint unsigned AUTO_INCREMENT,`user_id` bigint unsigned,`team_id` bigint unsigned,`name` TEXT NOT NULL,`api_key` VARCHAR(255) NOT NULL,`is_admin` boolean NOT NULL DEFAULT false,`active` boolean NOT NULL DEFAULT true,`expires_at` datetime(3) NULL,`revoked_at` datetime(3) NULL,`last_used_at` datetime(3) NULL,`allowed_models` JSON NOT NULL DEFAULT ('[]'),`allowed_providers` JSON NOT NULL DEFAULT ('[]'),`max_tokens_per_request` bigint,`allowed_ips` JSON NOT NULL DEFAULT ('[]'),`rpm_limit` bigint NOT NULL DEFAULT 0,`tpm_limit` bigint NOT NULL DEFAULT 0,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_api_keys_user_id` (`user_id`),INDEX `idx_api_keys_team_id` (`team_id`),UNIQUE INDEX `idx_api_keys_api_key` (`api_key`),CONSTRAINT `fk_users_api_keys` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`));
+
+CREATE TABLE `usages` (`id` bigint unsigned AUTO_INCREMENT,`provider` VARCHAR(255) NOT NULL,`model` VARCHAR(255) NOT NULL,`user_id` bigint unsigned,`user_group_id` bigint unsigned,`api_key_id` bigint unsigned,`auth_id` bigint unsigned,`team_id` bigint unsigned,`team_member_id` bigint unsigned,`provider_api_key_id` bigint unsigned,`auth_key` VARCHAR(255),`auth_index` TEXT,`request_id` VARCHAR(255),`source` TEXT,`proxy_hash` varchar(16) NOT NULL DEFAULT '',`proxy_label` TEXT NOT NULL DEFAULT (''),`variant_origin` TEXT,`variant` TEXT,`requested_at` datetime(3) NOT NULL,`failed` boolean NOT NULL DEFAULT false,`error_status_code` bigint,`error_detail` JSON,`error_code` VARCHAR(255) NOT NULL DEFAULT '',`retry_after_seconds` bigint NOT NULL DEFAULT 0,`input_tokens` bigint NOT NULL DEFAULT 0,`output_tokens` bigint NOT NULL DEFAULT 0,`reasoning_tokens` bigint NOT NULL DEFAULT 0,`cached_tokens` bigint NOT NULL DEFAULT 0,`cache_creation_tokens` bigint NOT NULL DEFAULT 0,`total_tokens` bigint NOT NULL DEFAULT 0,`cost_micros` bigint NOT NULL DEFAULT 0,`billing_rule_id` bigint unsigned,`energy_milli_wh` bigint NOT NULL DEFAULT 0,`carbon_milligrams` bigint NOT NULL DEFAULT 0,`charged_to` VARCHAR(255) NOT NULL DEFAULT 'none',`created_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_usages_provider` (`provider`),INDEX `idx_usages_model` (`model`),INDEX `idx_usages_user_id` (`user_id`),INDEX `idx_usages_user_group_id` (`user_group_id`),INDEX `idx_usages_api_key_id` (`api_key_id`),INDEX `idx_usages_auth_id` (`auth_id`),INDEX `idx_usages_team_id` (`team_id`),INDEX `idx_usages_team_member_id` (`team_member_id`),INDEX `idx_usages_provider_api_key_id` (`provider_api_key_id`),INDEX `idx_usages_auth_key` (`auth_key`),INDEX `idx_usages_request_id` (`request_id`),INDEX `idx_usages_proxy_hash` (`proxy_hash`),INDEX `idx_usages_requested_at` (`requested_at`),INDEX `idx_usages_error_status_code` (`error_status_code`),INDEX `idx_usages_error_code` (`error_code`),INDEX `idx_usages_billing_rule_id` (`billing_rule_id`),INDEX `idx_usages_charged_to` (`charged_to`));
+
+CREATE TABLE `bills` (`id` bigint unsigned AUTO_INCREMENT,`plan_id` bigint unsigned NOT NULL,`user_id` bigint unsigned NOT NULL,`user_group_id` JSON NOT NULL DEFAULT ('[]'),`period_type` bigint NOT NULL,`amount` decimal(10,2) NOT NULL DEFAULT 0,`currency` varchar(8) NOT NULL DEFAULT 'USD',`period_start` datetime(3) NOT NULL,`period_end` datetime(3) NOT NULL,`total_quota` decimal(20,10) NOT NULL DEFAULT 0,`daily_quota` decimal(20,10) NOT NULL DEFAULT 0,`used_quota` decimal(20,10) NOT NULL DEFAULT 0,`left_quota` decimal(20,10) NOT NULL DEFAULT 0,`rate_limit` bigint NOT NULL DEFAULT 0,`used_count` bigint NOT NULL DEFAULT 0,`is_enabled` boolean NOT NULL DEFAULT true,`status` bigint NOT NULL DEFAULT 1,`auto_renew` boolean NOT NULL DEFAULT false,`renewal_mode` bigint NOT NULL DEFAULT 1,`renewed_bill_id` bigint unsigned,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_bills_plan_id` (`plan_id`),INDEX `idx_bills_user_id` (`user_id`),INDEX `idx_bills_renewed_bill_id` (`renewed_bill_id`),CONSTRAINT `fk_bills_plan` FOREIGN KEY (`plan_id`) REFERENCES `plans`(`id`),CONSTRAINT `fk_bills_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`));
+
+CREATE TABLE `billing_rules` (`id` bigint unsigned AUTO_INCREMENT,`auth_group_id` bigint unsigned NOT NULL,`user_group_id` bigint unsigned NOT NULL,`provider` varchar(191),`model` varchar(191),`billing_type` bigint NOT NULL,`price_per_request` decimal(20,10),`price_input_token` decimal(20,10),`price_output_token` decimal(20,10),`price_cache_create_token` decimal(20,10),`price_cache_read_token` decimal(20,10),`currency` varchar(8) NOT NULL DEFAULT 'USD',`token_tiers` JSON,`minimum_charge` decimal(20,10),`energy_wh_per_million_tokens` decimal(20,10),`carbon_grams_per_kwh` decimal(20,10),`is_enabled` boolean NOT NULL DEFAULT true,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_billing_rules_auth_group_id` (`auth_group_id`),INDEX `idx_billing_rules_user_group_id` (`user_group_id`),INDEX `idx_billing_rules_provider` (`provider`),INDEX `idx_billing_rules_model` (`model`),CONSTRAINT `fk_billing_rules_auth_group` FOREIGN KEY (`auth_group_id`) REFERENCES `auth_groups`(`id`),CONSTRAINT `fk_billing_rules_user_group` FOREIGN KEY (`user_group_id`) REFERENCES `user_groups`(`id`));
+
+CREATE TABLE `model_mappings` (`id` bigint unsigned AUTO_INCREMENT,`provider` varchar(255) NOT NULL,`model_name` varchar(255) NOT NULL,`new_model_name` varchar(255) NOT NULL,`fork` boolean NOT NULL DEFAULT false,`selector` bigint NOT NULL DEFAULT 0,`rate_limit` bigint NOT NULL DEFAULT 0,`user_group_id` JSON NOT NULL DEFAULT ('[]'),`is_enabled` boolean NOT NULL DEFAULT true,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_model_mappings_provider` (`provider`),INDEX `idx_model_mappings_model_name` (`model_name`));
+
+CREATE TABLE `models` (`provider_name` varchar(255) NOT NULL,`model_name` varchar(255) NOT NULL,`model_id` varchar(255),`context_limit` bigint NOT NULL DEFAULT 0,`output_limit` bigint NOT NULL DEFAULT 0,`input_price` decimal(20,10),`output_price` decimal(20,10),`cache_read_price` decimal(20,10),`cache_write_price` decimal(20,10),`context_over_200k_input_price` decimal(20,10),`context_over_200k_output_price` decimal(20,10),`context_over_200k_cache_read_price` decimal(20,10),`context_over_200k_cache_write_price` decimal(20,10),`extra` JSON NOT NULL DEFAULT ('{}'),`last_seen_at` datetime(3) NOT NULL,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`provider_name`,`model_name`),INDEX `idx_models_provider_name` (`provider_name`),INDEX `idx_models_model_name` (`model_name`),INDEX `idx_models_model_id` (`model_id`),INDEX `idx_models_last_seen_at` (`last_seen_at`));
+
+CREATE TABLE `user_model_auth_bindings` (`id` bigint unsigned AUTO_INCREMENT,`user_id` bigint unsigned NOT NULL,`model_mapping_id` bigint unsigned NOT NULL,`auth_index` varchar(64) NOT NULL,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),UNIQUE INDEX `idx_user_model_auth_bindings_user_model` (`user_id`,`model_mapping_id`),INDEX `idx_user_model_auth_bindings_model_mapping_id` (`model_mapping_id`));
+
+CREATE TABLE `model_payload_rules` (`id` bigint unsigned AUTO_INCREMENT,`model_mapping_id` bigint unsigned NOT NULL,`protocol` varchar(32),`params` JSON NOT NULL,`is_enabled` boolean NOT NULL DEFAULT true,`description` TEXT,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),UNIQUE INDEX `idx_model_payload_rules_mapping` (`model_mapping_id`),INDEX `idx_model_payload_rules_protocol` (`protocol`),INDEX `idx_model_payload_rules_is_enabled` (`is_enabled`),CONSTRAINT `fk_model_payload_rules_model_mapping` FOREIGN KEY (`model_mapping_id`) REFERENCES `model_mappings`(`id`) ON DELETE CASCADE);
+
+CREATE TABLE `provider_api_keys` (`id` bigint unsigned AUTO_INCREMENT,`provider` varchar(64) NOT NULL,`priority` bigint NOT NULL DEFAULT 0,`name` TEXT,`api_key` TEXT,`prefix` TEXT,`base_url` TEXT,`proxy_url` TEXT,`is_enabled` boolean NOT NULL DEFAULT true,`whitelist_enabled` boolean NOT NULL DEFAULT false,`headers` JSON,`models` JSON,`excluded_models` JSON,`api_key_entries` JSON,`environments` JSON NOT NULL DEFAULT ('[]'),`canary_percent` bigint NOT NULL DEFAULT 0,`canary_started_at` datetime(3) NULL,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_provider_api_keys_provider` (`provider`),INDEX `idx_provider_api_keys_priority` (`priority`),INDEX `idx_provider_api_keys_is_enabled` (`is_enabled`));
+
+CREATE TABLE `proxies` (`id` bigint unsigned AUTO_INCREMENT,`proxy_url` TEXT NOT NULL,`region` varchar(64) NOT NULL DEFAULT '',`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`));
+
+CREATE TABLE `prepaid_cards` (`id` bigint unsigned AUTO_INCREMENT,`name` TEXT NOT NULL,`card_sn` VARCHAR(255) NOT NULL,`password` TEXT NOT NULL,`amount` decimal(20,10) NOT NULL,`balance` decimal(20,10) NOT NULL DEFAULT 0,`valid_days` bigint NOT NULL DEFAULT 0,`expires_at` datetime(3) NULL,`is_enabled` boolean NOT NULL DEFAULT true,`redeemed_user_id` bigint unsigned,`user_group_id` bigint unsigned,`created_at` datetime(3) NOT NULL,`redeemed_at` datetime(3) NULL,PRIMARY KEY (`id`),UNIQUE INDEX `idx_prepaid_cards_card_sn` (`card_sn`),INDEX `idx_prepaid_cards_redeemed_user_id` (`redeemed_user_id`),INDEX `idx_prepaid_cards_user_group_id` (`user_group_id`),CONSTRAINT `fk_prepaid_cards_redeemed_user` FOREIGN KEY (`redeemed_user_id`) REFERENCES `users`(`id`));
+
+CREATE TABLE `settings` (`key` varchar(255),`value` JSON,`updated_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),PRIMARY KEY (`key`));
+
+CREATE TABLE `audit_logs` (`id` bigint unsigned AUTO_INCREMENT,`event_id` varchar(64) NOT NULL,`event_type` varchar(64) NOT NULL,`severity` varchar(16) NOT NULL DEFAULT 'info',`subject` VARCHAR(255) NOT NULL DEFAULT '',`message` TEXT NOT NULL DEFAULT (''),`data` JSON NOT NULL DEFAULT ('{}'),`occurred_at` datetime(3) NOT NULL,`created_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_audit_logs_event_id` (`event_id`),INDEX `idx_audit_logs_event_type` (`event_type`),INDEX `idx_audit_logs_subject` (`subject`),INDEX `idx_audit_logs_occurred_at` (`occurred_at`));
+
+CREATE TABLE `tier_upgrade_rules` (`id` bigint unsigned AUTO_INCREMENT,`name` TEXT NOT NULL,`from_user_group_id` bigint unsigned NOT NULL,`to_user_group_id` bigint unsigned NOT NULL,`window_days` bigint NOT NULL DEFAULT 7,`threshold_amount` decimal(20,10) NOT NULL DEFAULT 0,`require_confirmation` boolean NOT NULL,`is_enabled` boolean NOT NULL,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_tier_upgrade_rules_from_user_group_id` (`from_user_group_id`));
+
+CREATE TABLE `tier_upgrades` (`id` bigint unsigned AUTO_INCREMENT,`rule_id` bigint unsigned NOT NULL,`user_id` bigint unsigned NOT NULL,`from_user_group_id` bigint unsigned NOT NULL,`to_user_group_id` bigint unsigned NOT NULL,`usage_amount` decimal(20,10) NOT NULL DEFAULT 0,`status` varchar(16) NOT NULL,`decided_at` datetime(3) NULL,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_tier_upgrades_rule_id` (`rule_id`),INDEX `idx_tier_upgrades_user_id` (`user_id`),INDEX `idx_tier_upgrades_status` (`status`));
+
+CREATE TABLE `user_group_migrations` (`id` bigint unsigned AUTO_INCREMENT,`user_id` bigint unsigned NOT NULL,`from_user_group_id` JSON NOT NULL DEFAULT ('[]'),`to_user_group_id` JSON NOT NULL DEFAULT ('[]'),`bill_strategy` varchar(16) NOT NULL,`effective_at` datetime(3) NOT NULL,`status` varchar(16) NOT NULL,`result` JSON,`last_error` TEXT,`applied_at` datetime(3) NULL,`created_by` bigint unsigned,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_user_group_migrations_user_id` (`user_id`),INDEX `idx_user_group_migrations_effective_at` (`effective_at`),INDEX `idx_user_group_migrations_status` (`status`));
+
+CREATE TABLE `kpi_snapshots` (`id` bigint unsigned AUTO_INCREMENT,`day` datetime(3) NOT NULL,`requests` bigint NOT NULL DEFAULT 0,`failed_requests` bigint NOT NULL DEFAULT 0,`success_rate` double NOT NULL DEFAULT 0,`input_tokens` bigint NOT NULL DEFAULT 0,`output_tokens` bigint NOT NULL DEFAULT 0,`cached_tokens` bigint NOT NULL DEFAULT 0,`total_tokens` bigint NOT NULL DEFAULT 0,`cost_micros` bigint NOT NULL DEFAULT 0,`active_users` bigint NOT NULL DEFAULT 0,`active_api_keys` bigint NOT NULL DEFAULT 0,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),UNIQUE INDEX `idx_kpi_snapshots_day` (`day`));
+
+CREATE TABLE `nodes` (`id` bigint unsigned AUTO_INCREMENT,`name` varchar(64) NOT NULL,`token` VARCHAR(255) NOT NULL,`environment` varchar(32) NOT NULL DEFAULT '',`push_url` TEXT NOT NULL DEFAULT (''),`is_enabled` boolean NOT NULL DEFAULT true,`config_version` varchar(64) NOT NULL DEFAULT '',`applied_version` varchar(64) NOT NULL DEFAULT '',`agent_version` varchar(64) NOT NULL DEFAULT '',`sync_status` varchar(16) NOT NULL DEFAULT 'pending',`sync_error` TEXT NOT NULL DEFAULT (''),`last_seen_at` datetime(3) NULL,`last_sync_at` datetime(3) NULL,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),UNIQUE INDEX `idx_nodes_name` (`name`),UNIQUE INDEX `idx_nodes_token` (`token`));
+
+CREATE TABLE `model_displays` (`id` bigint unsigned AUTO_INCREMENT,`model_id` varchar(255) NOT NULL,`display_name` varchar(255) NOT NULL DEFAULT '',`category` varchar(64) NOT NULL DEFAULT '',`description` TEXT NOT NULL DEFAULT (''),`context_window` bigint NOT NULL DEFAULT 0,`capabilities` JSON NOT NULL DEFAULT ('[]'),`sort_order` bigint NOT NULL DEFAULT 0,`is_enabled` boolean NOT NULL DEFAULT true,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),UNIQUE INDEX `idx_model_displays_model_id` (`model_id`),INDEX `idx_model_displays_category` (`category`));
+
+CREATE TABLE `usage_daily` (`id` bigint unsigned AUTO_INCREMENT,`day` datetime(3) NOT NULL,`provider` varchar(255) NOT NULL,`model` varchar(255) NOT NULL,`user_id` bigint unsigned NOT NULL DEFAULT 0,`api_key_id` bigint unsigned NOT NULL DEFAULT 0,`requests` bigint NOT NULL DEFAULT 0,`failed_requests` bigint NOT NULL DEFAULT 0,`input_tokens` bigint NOT NULL DEFAULT 0,`output_tokens` bigint NOT NULL DEFAULT 0,`reasoning_tokens` bigint NOT NULL DEFAULT 0,`cached_tokens` bigint NOT NULL DEFAULT 0,`total_tokens` bigint NOT NULL DEFAULT 0,`cost_micros` bigint NOT NULL DEFAULT 0,`energy_milli_wh` bigint NOT NULL DEFAULT 0,`carbon_milligrams` bigint NOT NULL DEFAULT 0,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),UNIQUE INDEX `idx_usage_daily_bucket` (`day`,`provider`,`model`,`user_id`,`api_key_id`),INDEX `idx_usage_daily_day` (`day`),INDEX `idx_usage_daily_user_id` (`user_id`));
+
+CREATE TABLE `usage_hourly` (`id` bigint unsigned AUTO_INCREMENT,`hour` datetime(3) NOT NULL,`provider` varchar(255) NOT NULL,`model` varchar(255) NOT NULL,`user_id` bigint unsigned NOT NULL DEFAULT 0,`requests` bigint NOT NULL DEFAULT 0,`failed_requests` bigint NOT NULL DEFAULT 0,`total_tokens` bigint NOT NULL DEFAULT 0,`cached_tokens` bigint NOT NULL DEFAULT 0,`cost_micros` bigint NOT NULL DEFAULT 0,`energy_milli_wh` bigint NOT NULL DEFAULT 0,`carbon_milligrams` bigint NOT NULL DEFAULT 0,`duration_millis` bigint NOT NULL DEFAULT 0,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),UNIQUE INDEX `idx_usage_hourly_bucket` (`hour`,`provider`,`model`,`user_id`),INDEX `idx_usage_hourly_hour` (`hour`));
+
+CREATE TABLE `provider_health_checks` (`id` bigint unsigned AUTO_INCREMENT,`provider` varchar(255) NOT NULL,`source` varchar(32) NOT NULL,`target_id` bigint unsigned NOT NULL DEFAULT 0,`success` boolean NOT NULL DEFAULT false,`status_code` bigint NOT NULL DEFAULT 0,`latency_millis` bigint NOT NULL DEFAULT 0,`error` TEXT,`checked_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_provider_health_checks_provider` (`provider`),INDEX `idx_provider_health_checks_checked_at` (`checked_at`));
+
+CREATE TABLE `stats_cursors` (`name` varchar(64),`through` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`name`));
+
+CREATE TABLE `bulk_delete_jobs` (`id` bigint unsigned AUTO_INCREMENT,`entity` varchar(32) NOT NULL,`criteria` JSON NOT NULL,`batch_size` bigint NOT NULL,`status` varchar(16) NOT NULL,`total` bigint NOT NULL DEFAULT 0,`deleted` bigint NOT NULL DEFAULT 0,`last_error` TEXT,`created_by` bigint unsigned,`started_at` datetime(3) NULL,`finished_at` datetime(3) NULL,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_bulk_delete_jobs_status` (`status`));
+
+CREATE TABLE `invoices` (`id` bigint unsigned AUTO_INCREMENT,`number` varchar(64),`user_id` bigint unsigned,`user_group_id` bigint unsigned,`period_start` datetime(3) NOT NULL,`period_end` datetime(3) NOT NULL,`currency` varchar(8) NOT NULL,`exchange_rate` decimal(20,10) NOT NULL DEFAULT 1,`line_items` JSON NOT NULL,`subtotal` decimal(20,10) NOT NULL DEFAULT 0,`tax_rate` decimal(20,10) NOT NULL DEFAULT 0,`tax` decimal(20,10) NOT NULL DEFAULT 0,`total` decimal(20,10) NOT NULL DEFAULT 0,`status` varchar(16) NOT NULL,`finalized_at` datetime(3) NULL,`paid_at` datetime(3) NULL,`created_by` bigint unsigned,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_invoices_number` (`number`),INDEX `idx_invoices_user_id` (`user_id`),INDEX `idx_invoices_user_group_id` (`user_group_id`),INDEX `idx_invoices_period_start` (`period_start`),INDEX `idx_invoices_status` (`status`));
+
+CREATE TABLE `coop_contributions` (`id` bigint unsigned AUTO_INCREMENT,`user_id` bigint unsigned NOT NULL,`auth_id` bigint unsigned NOT NULL,`auth_key` TEXT NOT NULL,`provider` varchar(64) NOT NULL,`status` varchar(16) NOT NULL DEFAULT 'active',`credit_rate` decimal(10,4) NOT NULL DEFAULT 0,`settled_until` datetime(3) NOT NULL,`credited_amount` decimal(20,10) NOT NULL DEFAULT 0,`served_requests` bigint NOT NULL DEFAULT 0,`suspend_reason` TEXT,`revoked_at` datetime(3) NULL,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_coop_contributions_user_id` (`user_id`),INDEX `idx_coop_contributions_auth_id` (`auth_id`),INDEX `idx_coop_contributions_status` (`status`));
+
+CREATE TABLE `balance_transactions` (`id` bigint unsigned AUTO_INCREMENT,`user_id` bigint unsigned NOT NULL,`target` varchar(16) NOT NULL,`kind` varchar(32) NOT NULL,`amount` decimal(20,10) NOT NULL,`balance_after` decimal(20,10) NOT NULL,`prepaid_card_id` bigint unsigned,`bill_id` bigint unsigned,`usage_id` bigint unsigned,`reason` TEXT,`actor` varchar(128),`created_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_balance_transactions_user_id` (`user_id`),INDEX `idx_balance_transactions_target` (`target`),INDEX `idx_balance_transactions_kind` (`kind`),INDEX `idx_balance_transactions_prepaid_card_id` (`prepaid_card_id`),INDEX `idx_balance_transactions_bill_id` (`bill_id`),INDEX `idx_balance_transactions_usage_id` (`usage_id`),INDEX `idx_balance_transactions_created_at` (`created_at`));
+
+CREATE TABLE `billing_rule_snapshots` (`id` bigint unsigned AUTO_INCREMENT,`name` varchar(128) NOT NULL,`rules` JSON NOT NULL,`rule_count` bigint NOT NULL DEFAULT 0,`fingerprint` varchar(64),`proposed` boolean NOT NULL DEFAULT false,`created_by` bigint unsigned,`created_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_billing_rule_snapshots_fingerprint` (`fingerprint`),INDEX `idx_billing_rule_snapshots_created_at` (`created_at`));
+
+CREATE TABLE `cost_replay_jobs` (`id` bigint unsigned AUTO_INCREMENT,`snapshot_id` bigint unsigned NOT NULL,`period_start` datetime(3) NOT NULL,`period_end` datetime(3) NOT NULL,`user_id` bigint unsigned,`status` varchar(16) NOT NULL,`total` bigint NOT NULL DEFAULT 0,`processed` bigint NOT NULL DEFAULT 0,`report` JSON,`last_error` TEXT,`created_by` bigint unsigned,`started_at` datetime(3) NULL,`finished_at` datetime(3) NULL,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_cost_replay_jobs_snapshot_id` (`snapshot_id`),INDEX `idx_cost_replay_jobs_status` (`status`));
+
+CREATE TABLE `exchange_rates` (`id` bigint unsigned AUTO_INCREMENT,`currency` varchar(8) NOT NULL,`rate` decimal(20,10) NOT NULL,`source` varchar(16) NOT NULL,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),UNIQUE INDEX `idx_exchange_rates_currency` (`currency`));
+
+CREATE TABLE `auth_import_conflicts` (`id` bigint unsigned AUTO_INCREMENT,`auth_key` VARCHAR(255) NOT NULL,`auth_id` bigint unsigned NOT NULL,`source` TEXT,`proxy_url` TEXT,`auth_group_id` JSON NOT NULL DEFAULT ('[]'),`content` JSON NOT NULL,`imported_by` varchar(255),`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),UNIQUE INDEX `idx_auth_import_conflicts_auth_key` (`auth_key`),INDEX `idx_auth_import_conflicts_auth_id` (`auth_id`));
+
+CREATE TABLE `admin_notifications` (`id` bigint unsigned AUTO_INCREMENT,`event_id` varchar(64) NOT NULL,`event_type` varchar(64) NOT NULL,`severity` varchar(16) NOT NULL DEFAULT 'info',`subject` VARCHAR(255) NOT NULL DEFAULT '',`message` TEXT NOT NULL DEFAULT (''),`data` JSON NOT NULL DEFAULT ('{}'),`read_at` datetime(3) NULL,`read_by` varchar(255),`occurred_at` datetime(3) NOT NULL,`created_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_admin_notifications_event_id` (`event_id`),INDEX `idx_admin_notifications_event_type` (`event_type`),INDEX `idx_admin_notifications_subject` (`subject`),INDEX `idx_admin_notifications_read_at` (`read_at`),INDEX `idx_admin_notifications_occurred_at` (`occurred_at`));
+
+CREATE TABLE `usage_badges` (`id` bigint unsigned AUTO_INCREMENT,`user_id` bigint unsigned NOT NULL,`token` varchar(64) NOT NULL,`project` TEXT NOT NULL DEFAULT (''),`label` varchar(64) NOT NULL DEFAULT '',`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_usage_badges_user_id` (`user_id`),UNIQUE INDEX `idx_usage_badges_token` (`token`));
+
+CREATE TABLE `slos` (`id` bigint unsigned AUTO_INCREMENT,`name` varchar(255) NOT NULL,`description` TEXT,`kind` varchar(16) NOT NULL,`threshold_millis` bigint NOT NULL DEFAULT 0,`objective` decimal(7,4) NOT NULL,`window_days` bigint NOT NULL DEFAULT 30,`provider` varchar(255) NOT NULL DEFAULT '',`model` varchar(255) NOT NULL DEFAULT '',`is_enabled` boolean NOT NULL DEFAULT true,`last_evaluated_at` datetime(3) NULL,`last_state` varchar(16) NOT NULL DEFAULT '',`last_compliance` double NOT NULL DEFAULT 0,`last_budget_remaining` double NOT NULL DEFAULT 0,`last_alerted_at` datetime(3) NULL,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),UNIQUE INDEX `idx_slos_name` (`name`));
+
+CREATE TABLE `webhooks` (`id` bigint unsigned AUTO_INCREMENT,`name` varchar(255) NOT NULL,`url` TEXT NOT NULL,`secret` TEXT NOT NULL,`event_types` JSON NOT NULL DEFAULT ('[]'),`is_enabled` boolean NOT NULL DEFAULT true,`created_by` varchar(255) NOT NULL DEFAULT '',`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_webhooks_is_enabled` (`is_enabled`));
+
+CREATE TABLE `webhook_deliveries` (`id` bigint unsigned AUTO_INCREMENT,`webhook_id` bigint unsigned NOT NULL,`event_id` varchar(64) NOT NULL,`event_type` varchar(64) NOT NULL,`payload` TEXT NOT NULL,`status` varchar(16) NOT NULL DEFAULT 'pending',`attempts` bigint NOT NULL DEFAULT 0,`next_attempt_at` datetime(3) NULL,`last_attempt_at` datetime(3) NULL,`last_status_code` bigint NOT NULL DEFAULT 0,`last_error` TEXT,`delivered_at` datetime(3) NULL,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_webhook_deliveries_webhook_id` (`webhook_id`),INDEX `idx_webhook_deliveries_event_id` (`event_id`),INDEX `idx_webhook_deliveries_event_type` (`event_type`),INDEX `idx_webhook_deliveries_status` (`status`),INDEX `idx_webhook_deliveries_next_attempt_at` (`next_attempt_at`));
+
+CREATE TABLE `email_tokens` (`id` bigint unsigned AUTO_INCREMENT,`user_id` bigint unsigned NOT NULL,`purpose` varchar(32) NOT NULL,`token_hash` varchar(64) NOT NULL,`email` TEXT NOT NULL,`expires_at` datetime(3) NOT NULL,`used_at` datetime(3) NULL,`created_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_email_tokens_user_id` (`user_id`),INDEX `idx_email_tokens_purpose` (`purpose`),UNIQUE INDEX `idx_email_tokens_token_hash` (`token_hash`),INDEX `idx_email_tokens_expires_at` (`expires_at`));
+
+CREATE TABLE `mfa_recovery_codes` (`id` bigint unsigned AUTO_INCREMENT,`owner_type` varchar(16) NOT NULL,`owner_id` bigint unsigned NOT NULL,`code_hash` varchar(64) NOT NULL,`used_at` datetime(3) NULL,`created_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_mfa_recovery_codes_owner` (`owner_type`,`owner_id`),UNIQUE INDEX `idx_mfa_recovery_codes_code_hash` (`code_hash`));
+
+CREATE TABLE `mfa_sessions` (`id` bigint unsigned AUTO_INCREMENT,`scope` varchar(64) NOT NULL,`key` varchar(255) NOT NULL,`value` BLOB NOT NULL,`expires_at` datetime(3) NOT NULL,`created_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),UNIQUE INDEX `idx_mfa_sessions_scope_key` (`scope`,`key`),INDEX `idx_mfa_sessions_expires_at` (`expires_at`));
+
+CREATE TABLE `admin_roles` (`id` bigint unsigned AUTO_INCREMENT,`name` varchar(100) NOT NULL,`description` TEXT,`permissions` JSON NOT NULL DEFAULT ('[]'),`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),UNIQUE INDEX `idx_admin_roles_name` (`name`));
+
+CREATE TABLE `admin_role_assignments` (`admin_id` bigint unsigned,`role_id` bigint unsigned,`created_at` datetime(3) NOT NULL,PRIMARY KEY (`admin_id`,`role_id`),INDEX `idx_admin_role_assignments_role_id` (`role_id`));
+
+CREATE TABLE `user_identities` (`id` bigint unsigned AUTO_INCREMENT,`user_id` bigint unsigned NOT NULL,`provider` varchar(32) NOT NULL,`subject` varchar(255) NOT NULL,`login` TEXT,`email` TEXT,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_user_identities_user_id` (`user_id`),UNIQUE INDEX `idx_user_identities_subject` (`provider`,`subject`));
+
+CREATE TABLE `invitations` (`id` bigint unsigned AUTO_INCREMENT,`code` varchar(64) NOT NULL,`note` TEXT,`user_group_id` bigint unsigned,`plan_id` bigint unsigned,`plan_days` bigint NOT NULL DEFAULT 0,`max_uses` bigint NOT NULL DEFAULT 0,`used_count` bigint NOT NULL DEFAULT 0,`expires_at` datetime(3) NULL,`is_enabled` boolean NOT NULL DEFAULT true,`created_at` datetime(3) NOT NULL,`updated_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),UNIQUE INDEX `idx_invitations_code` (`code`),INDEX `idx_invitations_user_group_id` (`user_group_id`),INDEX `idx_invitations_plan_id` (`plan_id`));
+
+CREATE TABLE `invitation_redemptions` (`id` bigint unsigned AUTO_INCREMENT,`invitation_id` bigint unsigned NOT NULL,`user_id` bigint unsigned NOT NULL,`bill_id` bigint unsigned,`created_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_invitation_redemptions_invitation_id` (`invitation_id`),UNIQUE INDEX `idx_invitation_redemptions_user_id` (`user_id`));
+
+CREATE TABLE `request_logs` (`id` bigint unsigned AUTO_INCREMENT,`request_id` VARCHAR(255),`user_id` bigint unsigned,`api_key_id` bigint unsigned,`method` TEXT NOT NULL,`path` TEXT NOT NULL,`model` VARCHAR(255),`status_code` bigint NOT NULL DEFAULT 0,`duration_ms` bigint NOT NULL DEFAULT 0,`client_ip` TEXT NOT NULL DEFAULT (''),`request_body` TEXT,`response_body` TEXT,`request_truncated` boolean NOT NULL DEFAULT false,`response_truncated` boolean NOT NULL DEFAULT false,`payload_backend` varchar(16) NOT NULL DEFAULT '',`payload_key` TEXT,`payload_bytes` bigint NOT NULL DEFAULT 0,`expires_at` datetime(3) NULL,`redacted_at` datetime(3) NULL,`created_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),INDEX `idx_request_logs_request_id` (`request_id`),INDEX `idx_request_logs_user_id` (`user_id`),INDEX `idx_request_logs_api_key_id` (`api_key_id`),INDEX `idx_request_logs_model` (`model`),INDEX `idx_request_logs_created_at` (`created_at`),INDEX `idx_request_logs_expires_at` (`expires_at`));
+
+CREATE TABLE `cluster_instances` (`id` bigint unsigned AUTO_INCREMENT,`instance_id` varchar(64) NOT NULL,`hostname` varchar(255) NOT NULL DEFAULT '',`version` varchar(64) NOT NULL DEFAULT '',`environment` varchar(32) NOT NULL DEFAULT '',`bus_connected` boolean NOT NULL DEFAULT false,`started_at` datetime(3) NOT NULL,`last_seen_at` datetime(3) NOT NULL,PRIMARY KEY (`id`),UNIQUE INDEX `idx_cluster_instances_instance_id` (`instance_id`),INDEX `idx_cluster_instances_last_seen_at` (`last_seen_at`));
+
+CREATE INDEX idx_auths_updated_at_id ON auths (updated_at DESC, id DESC);
+
+CREATE INDEX idx_settings_updated_at_key ON settings (updated_at DESC, `key` DESC);
+
+CREATE INDEX idx_plans_sort_order_created_at ON plans (sort_order ASC, created_at DESC);
+
+CREATE INDEX idx_plans_is_enabled_sort_order_created_at ON plans (is_enabled, sort_order ASC, created_at DESC);
+
+CREATE INDEX idx_bills_user_id_created_at ON bills (user_id, created_at DESC);
+
+CREATE INDEX idx_bills_plan_id_created_at ON bills (plan_id, created_at DESC);
+
+CREATE INDEX idx_bills_status_created_at ON bills (status, created_at DESC);
+
+CREATE INDEX idx_bills_is_enabled_created_at ON bills (is_enabled, created_at DESC);
+
+CREATE INDEX idx_billing_rules_match ON billing_rules (auth_group_id, user_group_id, is_enabled, provider, model);
+
+CREATE UNIQUE INDEX idx_billing_rules_unique_key ON billing_rules (auth_group_id, user_group_id, provider, model);
+
+CREATE INDEX idx_model_mappings_provider_model_name_is_enabled ON model_mappings (provider, model_name, is_enabled);
+
+CREATE INDEX idx_model_mappings_provider_new_model_name_is_enabled ON model_mappings (provider, new_model_name, is_enabled);
+
+CREATE INDEX idx_prepaid_cards_redeemed_at ON prepaid_cards (redeemed_at);
+
+CREATE INDEX idx_prepaid_cards_redeemed_user_group ON prepaid_cards (redeemed_user_id, user_group_id);
+
+CREATE INDEX idx_api_keys_user_id_created_at ON api_keys (user_id, created_at DESC);
+
+CREATE INDEX idx_usages_user_id_requested_at ON usages (user_id, requested_at DESC);
+
+CREATE INDEX idx_usages_user_id_charged_to_requested_at ON usages (user_id, charged_to, requested_at DESC);
+
+CREATE INDEX idx_usages_api_key_id_requested_at ON usages (api_key_id, requested_at DESC);
+
+CREATE INDEX idx_usages_user_id_model ON usages (user_id, model);
+
+CREATE INDEX idx_usages_user_id_provider_model ON usages (user_id, provider, model);
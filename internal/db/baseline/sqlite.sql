@@ -0,0 +1,506 @@
+-- Baseline schema (migration 1) for SQLite, applied to fresh databases.
+-- Frozen: do not edit. Change the schema with a new migration in versions.go.
+
+CREATE TABLE `admins` (`id` integer PRIMARY KEY AUTOINCREMENT,`username` text NOT NULL,`password` text NOT NULL,`active` numeric NOT NULL DEFAULT true,`is_super_admin` numeric NOT NULL DEFAULT false,`organization_id` integer,`permissions` JSON NOT NULL DEFAULT '[]',`denied_permissions` JSON NOT NULL DEFAULT '[]',`allowed_ips` JSON NOT NULL DEFAULT '[]',`oidc_issuer` text,`oidc_subject` text,`totp_secret` text,`passkey_id` bytea,`passkey_public_key` bytea,`passkey_sign_count` bigint,`passkey_backup_eligible` boolean,`passkey_backup_state` boolean,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE INDEX `idx_admins_oidc_identity` ON `admins`(`oidc_issuer`,`oidc_subject`);
+
+CREATE INDEX `idx_admins_organization_id` ON `admins`(`organization_id`);
+
+CREATE UNIQUE INDEX `idx_admins_username` ON `admins`(`username`);
+
+CREATE TABLE `plans` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` varchar(255) NOT NULL,`month_price` decimal(10,2) NOT NULL DEFAULT 0,`description` text,`support_models` JSON NOT NULL DEFAULT '[]',`user_group_id` jsonb NOT NULL DEFAULT '[]',`feature1` varchar(255),`feature2` varchar(255),`feature3` varchar(255),`feature4` varchar(255),`sort_order` integer NOT NULL DEFAULT 0,`total_quota` decimal(20,10) NOT NULL DEFAULT 0,`daily_quota` decimal(20,10) NOT NULL DEFAULT 0,`rate_limit` integer NOT NULL DEFAULT 0,`is_enabled` numeric NOT NULL DEFAULT true,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE TABLE `user_groups` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` text NOT NULL,`is_default` numeric NOT NULL DEFAULT false,`rate_limit` integer NOT NULL DEFAULT 0,`rpm_limit` integer NOT NULL DEFAULT 0,`tpm_limit` integer NOT NULL DEFAULT 0,`max_concurrent_requests` integer NOT NULL DEFAULT 0,`daily_spend_limit` decimal(20,10) NOT NULL DEFAULT 0,`monthly_spend_limit` decimal(20,10) NOT NULL DEFAULT 0,`parent_id` integer,`billing_rule_group_id` integer,`request_log_enabled` numeric NOT NULL DEFAULT false,`organization_id` integer,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE INDEX `idx_user_groups_organization_id` ON `user_groups`(`organization_id`);
+
+CREATE INDEX `idx_user_groups_parent_id` ON `user_groups`(`parent_id`);
+
+CREATE UNIQUE INDEX `idx_user_groups_name` ON `user_groups`(`name`);
+
+CREATE TABLE `auth_groups` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` text NOT NULL,`is_default` numeric NOT NULL DEFAULT false,`rate_limit` integer NOT NULL DEFAULT 0,`user_group_id` jsonb NOT NULL DEFAULT '[]',`schedule` JSON,`off_schedule` numeric NOT NULL DEFAULT false,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE UNIQUE INDEX `idx_auth_groups_name` ON `auth_groups`(`name`);
+
+CREATE TABLE `users` (`id` integer PRIMARY KEY AUTOINCREMENT,`username` text NOT NULL,`name` text,`email` text,`password` text NOT NULL,`ldap_dn` text,`email_verified_at` datetime,`organization_id` integer,`user_group_id` jsonb NOT NULL DEFAULT '[]',`bill_user_group_id` jsonb NOT NULL DEFAULT '[]',`plan_id` integer,`daily_max_usage` decimal(20,10) NOT NULL DEFAULT 0,`rate_limit` integer NOT NULL DEFAULT 0,`max_concurrent_requests` integer NOT NULL DEFAULT 0,`daily_spend_limit` decimal(20,10) NOT NULL DEFAULT 0,`monthly_spend_limit` decimal(20,10) NOT NULL DEFAULT 0,`active` numeric NOT NULL DEFAULT true,`disabled` numeric NOT NULL DEFAULT false,`totp_secret` text,`passkey_id` bytea,`passkey_public_key` bytea,`passkey_sign_count` bigint,`passkey_backup_eligible` boolean,`passkey_backup_state` boolean,`sessions_revoked_at` datetime,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL,CONSTRAINT `fk_users_plan` FOREIGN KEY (`plan_id`) REFERENCES `plans`(`id`));
+
+CREATE INDEX `idx_users_plan_id` ON `users`(`plan_id`);
+
+CREATE INDEX `idx_users_organization_id` ON `users`(`organization_id`);
+
+CREATE INDEX `idx_users_ldapdn` ON `users`(`ldap_dn`);
+
+CREATE UNIQUE INDEX `idx_users_email` ON `users`(`email`);
+
+CREATE UNIQUE INDEX `idx_users_username` ON `users`(`username`);
+
+CREATE TABLE `auths` (`id` integer PRIMARY KEY AUTOINCREMENT,`key` text NOT NULL,`name` varchar(64),`proxy_url` text,`auth_group_id` jsonb NOT NULL DEFAULT '[]',`content` JSON NOT NULL,`whitelist_enabled` boolean NOT NULL DEFAULT false,`allowed_models` JSON NOT NULL DEFAULT '[]',`excluded_models` JSON NOT NULL DEFAULT '[]',`is_available` boolean NOT NULL DEFAULT true,`rate_limit` integer NOT NULL DEFAULT 0,`priority` integer NOT NULL DEFAULT 0,`token_invalid` boolean NOT NULL DEFAULT false,`last_auth_check_at` timestamptz,`last_auth_error` text,`cooling_down` boolean NOT NULL DEFAULT false,`cooldown_until` datetime,`cooldown_reason` text,`pending_approval` boolean NOT NULL DEFAULT false,`approval_reason` text,`imported_by` varchar(255),`contributed_by_user_id` integer,`quota_poll_interval_seconds` integer,`poll_enabled` boolean NOT NULL DEFAULT true,`environments` JSON NOT NULL DEFAULT '[]',`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE INDEX `idx_auths_contributed_by_user_id` ON `auths`(`contributed_by_user_id`);
+
+CREATE INDEX `idx_auths_pending_approval` ON `auths`(`pending_approval`);
+
+CREATE INDEX `idx_auths_cooldown_until` ON `auths`(`cooldown_until`);
+
+CREATE INDEX `idx_auths_cooling_down` ON `auths`(`cooling_down`);
+
+CREATE INDEX `idx_auths_priority` ON `auths`(`priority`);
+
+CREATE UNIQUE INDEX `idx_auths_key` ON `auths`(`key`);
+
+CREATE TABLE `quota` (`id` integer PRIMARY KEY AUTOINCREMENT,`auth_id` integer NOT NULL,`type` text NOT NULL,`data` JSON NOT NULL DEFAULT '{}',`plan` text,`remaining_requests` integer,`remaining_tokens` integer,`remaining_fraction` real,`reset_at` datetime,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE INDEX `idx_quota_reset_at` ON `quota`(`reset_at`);
+
+CREATE INDEX `idx_quota_type` ON `quota`(`type`);
+
+CREATE INDEX `idx_quota_auth_id` ON `quota`(`auth_id`);
+
+CREATE TABLE `api_keys` (`id` integer PRIMARY KEY AUTOINCREMENT,`user_id` integer,`team_id` integer,`name` text NOT NULL,`api_key` text NOT NULL,`is_admin` numeric NOT NULL DEFAULT false,`active` numeric NOT NULL DEFAULT true,`expires_at` datetime,`revoked_at` datetime,`last_used_at` datetime,`allowed_models` JSON NOT NULL DEFAULT '[]',`allowed_providers` JSON NOT NULL DEFAULT '[]',`max_tokens_per_request` integer,`allowed_ips` JSON NOT NULL DEFAULT '[]',`rpm_limit` integer NOT NULL DEFAULT 0,`tpm_limit` integer NOT NULL DEFAULT 0,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL,CONSTRAINT `fk_users_api_keys` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`));
+
+CREATE UNIQUE INDEX `idx_api_keys_api_key` ON `api_keys`(`api_key`);
+
+CREATE INDEX `idx_api_keys_team_id` ON `api_keys`(`team_id`);
+
+CREATE INDEX `idx_api_keys_user_id` ON `api_keys`(`user_id`);
+
+CREATE TABLE `usages` (`id` integer PRIMARY KEY AUTOINCREMENT,`provider` text NOT NULL,`model` text NOT NULL,`user_id` integer,`user_group_id` integer,`api_key_id` integer,`auth_id` integer,`team_id` integer,`team_member_id` integer,`provider_api_key_id` integer,`auth_key` text,`auth_index` text,`request_id` text,`source` text,`proxy_hash` varchar(16) NOT NULL DEFAULT "",`proxy_label` text NOT NULL DEFAULT "",`variant_origin` text,`variant` text,`requested_at` datetime NOT NULL,`failed` numeric NOT NULL DEFAULT false,`error_status_code` integer,`error_detail` JSON,`error_code` text NOT NULL DEFAULT "",`retry_after_seconds` integer NOT NULL DEFAULT 0,`input_tokens` integer NOT NULL DEFAULT 0,`output_tokens` integer NOT NULL DEFAULT 0,`reasoning_tokens` integer NOT NULL DEFAULT 0,`cached_tokens` integer NOT NULL DEFAULT 0,`cache_creation_tokens` integer NOT NULL DEFAULT 0,`total_tokens` integer NOT NULL DEFAULT 0,`cost_micros` integer NOT NULL DEFAULT 0,`billing_rule_id` integer,`energy_milli_wh` integer NOT NULL DEFAULT 0,`carbon_milligrams` integer NOT NULL DEFAULT 0,`charged_to` text NOT NULL DEFAULT "none",`created_at` datetime NOT NULL);
+
+CREATE INDEX `idx_usages_charged_to` ON `usages`(`charged_to`);
+
+CREATE INDEX `idx_usages_billing_rule_id` ON `usages`(`billing_rule_id`);
+
+CREATE INDEX `idx_usages_error_code` ON `usages`(`error_code`);
+
+CREATE INDEX `idx_usages_error_status_code` ON `usages`(`error_status_code`);
+
+CREATE INDEX `idx_usages_requested_at` ON `usages`(`requested_at`);
+
+CREATE INDEX `idx_usages_proxy_hash` ON `usages`(`proxy_hash`);
+
+CREATE INDEX `idx_usages_request_id` ON `usages`(`request_id`);
+
+CREATE INDEX `idx_usages_auth_key` ON `usages`(`auth_key`);
+
+CREATE INDEX `idx_usages_provider_api_key_id` ON `usages`(`provider_api_key_id`);
+
+CREATE INDEX `idx_usages_team_member_id` ON `usages`(`team_member_id`);
+
+CREATE INDEX `idx_usages_team_id` ON `usages`(`team_id`);
+
+CREATE INDEX `idx_usages_auth_id` ON `usages`(`auth_id`);
+
+CREATE INDEX `idx_usages_api_key_id` ON `usages`(`api_key_id`);
+
+CREATE INDEX `idx_usages_user_group_id` ON `usages`(`user_group_id`);
+
+CREATE INDEX `idx_usages_user_id` ON `usages`(`user_id`);
+
+CREATE INDEX `idx_usages_model` ON `usages`(`model`);
+
+CREATE INDEX `idx_usages_provider` ON `usages`(`provider`);
+
+CREATE TABLE `bills` (`id` integer PRIMARY KEY AUTOINCREMENT,`plan_id` integer NOT NULL,`user_id` integer NOT NULL,`user_group_id` jsonb NOT NULL DEFAULT '[]',`period_type` integer NOT NULL,`amount` decimal(10,2) NOT NULL DEFAULT 0,`currency` varchar(8) NOT NULL DEFAULT "USD",`period_start` datetime NOT NULL,`period_end` datetime NOT NULL,`total_quota` decimal(20,10) NOT NULL DEFAULT 0,`daily_quota` decimal(20,10) NOT NULL DEFAULT 0,`used_quota` decimal(20,10) NOT NULL DEFAULT 0,`left_quota` decimal(20,10) NOT NULL DEFAULT 0,`rate_limit` integer NOT NULL DEFAULT 0,`used_count` integer NOT NULL DEFAULT 0,`is_enabled` numeric NOT NULL DEFAULT true,`status` integer NOT NULL DEFAULT 1,`auto_renew` numeric NOT NULL DEFAULT false,`renewal_mode` integer NOT NULL DEFAULT 1,`renewed_bill_id` integer,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL,CONSTRAINT `fk_bills_plan` FOREIGN KEY (`plan_id`) REFERENCES `plans`(`id`),CONSTRAINT `fk_bills_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`));
+
+CREATE INDEX `idx_bills_renewed_bill_id` ON `bills`(`renewed_bill_id`);
+
+CREATE INDEX `idx_bills_user_id` ON `bills`(`user_id`);
+
+CREATE INDEX `idx_bills_plan_id` ON `bills`(`plan_id`);
+
+CREATE TABLE `billing_rules` (`id` integer PRIMARY KEY AUTOINCREMENT,`auth_group_id` integer NOT NULL,`user_group_id` integer NOT NULL,`provider` text,`model` text,`billing_type` integer NOT NULL,`price_per_request` decimal(20,10),`price_input_token` decimal(20,10),`price_output_token` decimal(20,10),`price_cache_create_token` decimal(20,10),`price_cache_read_token` decimal(20,10),`currency` varchar(8) NOT NULL DEFAULT "USD",`token_tiers` JSON,`minimum_charge` decimal(20,10),`energy_wh_per_million_tokens` decimal(20,10),`carbon_grams_per_kwh` decimal(20,10),`is_enabled` numeric NOT NULL DEFAULT true,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL,CONSTRAINT `fk_billing_rules_auth_group` FOREIGN KEY (`auth_group_id`) REFERENCES `auth_groups`(`id`),CONSTRAINT `fk_billing_rules_user_group` FOREIGN KEY (`user_group_id`) REFERENCES `user_groups`(`id`));
+
+CREATE INDEX `idx_billing_rules_model` ON `billing_rules`(`model`);
+
+CREATE INDEX `idx_billing_rules_provider` ON `billing_rules`(`provider`);
+
+CREATE INDEX `idx_billing_rules_user_group_id` ON `billing_rules`(`user_group_id`);
+
+CREATE INDEX `idx_billing_rules_auth_group_id` ON `billing_rules`(`auth_group_id`);
+
+CREATE TABLE `model_mappings` (`id` integer PRIMARY KEY AUTOINCREMENT,`provider` varchar(255) NOT NULL,`model_name` varchar(255) NOT NULL,`new_model_name` varchar(255) NOT NULL,`fork` numeric NOT NULL DEFAULT false,`selector` integer NOT NULL DEFAULT 0,`rate_limit` integer NOT NULL DEFAULT 0,`user_group_id` jsonb NOT NULL DEFAULT '[]',`is_enabled` numeric NOT NULL DEFAULT true,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE INDEX `idx_model_mappings_model_name` ON `model_mappings`(`model_name`);
+
+CREATE INDEX `idx_model_mappings_provider` ON `model_mappings`(`provider`);
+
+CREATE TABLE `models` (`provider_name` varchar(255) NOT NULL,`model_name` varchar(255) NOT NULL,`model_id` varchar(255),`context_limit` integer NOT NULL DEFAULT 0,`output_limit` integer NOT NULL DEFAULT 0,`input_price` decimal(20,10),`output_price` decimal(20,10),`cache_read_price` decimal(20,10),`cache_write_price` decimal(20,10),`context_over_200k_input_price` decimal(20,10),`context_over_200k_output_price` decimal(20,10),`context_over_200k_cache_read_price` decimal(20,10),`context_over_200k_cache_write_price` decimal(20,10),`extra` JSON NOT NULL DEFAULT '{}',`last_seen_at` datetime NOT NULL,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL,PRIMARY KEY (`provider_name`,`model_name`));
+
+CREATE INDEX `idx_models_last_seen_at` ON `models`(`last_seen_at`);
+
+CREATE INDEX `idx_models_model_id` ON `models`(`model_id`);
+
+CREATE INDEX `idx_models_model_name` ON `models`(`model_name`);
+
+CREATE INDEX `idx_models_provider_name` ON `models`(`provider_name`);
+
+CREATE TABLE `user_model_auth_bindings` (`id` integer PRIMARY KEY AUTOINCREMENT,`user_id` integer NOT NULL,`model_mapping_id` integer NOT NULL,`auth_index` varchar(64) NOT NULL,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE INDEX `idx_user_model_auth_bindings_model_mapping_id` ON `user_model_auth_bindings`(`model_mapping_id`);
+
+CREATE UNIQUE INDEX `idx_user_model_auth_bindings_user_model` ON `user_model_auth_bindings`(`user_id`,`model_mapping_id`);
+
+CREATE TABLE `model_payload_rules` (`id` integer PRIMARY KEY AUTOINCREMENT,`model_mapping_id` integer NOT NULL,`protocol` varchar(32),`params` JSON NOT NULL,`is_enabled` numeric NOT NULL DEFAULT true,`description` text,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL,CONSTRAINT `fk_model_payload_rules_model_mapping` FOREIGN KEY (`model_mapping_id`) REFERENCES `model_mappings`(`id`) ON DELETE CASCADE);
+
+CREATE INDEX `idx_model_payload_rules_is_enabled` ON `model_payload_rules`(`is_enabled`);
+
+CREATE INDEX `idx_model_payload_rules_protocol` ON `model_payload_rules`(`protocol`);
+
+CREATE UNIQUE INDEX `idx_model_payload_rules_mapping` ON `model_payload_rules`(`model_mapping_id`);
+
+CREATE TABLE `provider_api_keys` (`id` integer PRIMARY KEY AUTOINCREMENT,`provider` varchar(64) NOT NULL,`priority` integer NOT NULL DEFAULT 0,`name` text,`api_key` text,`prefix` text,`base_url` text,`proxy_url` text,`is_enabled` numeric NOT NULL DEFAULT true,`whitelist_enabled` numeric NOT NULL DEFAULT false,`headers` JSON,`models` JSON,`excluded_models` JSON,`api_key_entries` JSON,`environments` JSON NOT NULL DEFAULT '[]',`canary_percent` integer NOT NULL DEFAULT 0,`canary_started_at` datetime,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE INDEX `idx_provider_api_keys_is_enabled` ON `provider_api_keys`(`is_enabled`);
+
+CREATE INDEX `idx_provider_api_keys_priority` ON `provider_api_keys`(`priority`);
+
+CREATE INDEX `idx_provider_api_keys_provider` ON `provider_api_keys`(`provider`);
+
+CREATE TABLE `proxies` (`id` integer PRIMARY KEY AUTOINCREMENT,`proxy_url` text NOT NULL,`region` varchar(64) NOT NULL DEFAULT "",`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE TABLE `prepaid_cards` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` text NOT NULL,`card_sn` text NOT NULL,`password` text NOT NULL,`amount` decimal(20,10) NOT NULL,`balance` decimal(20,10) NOT NULL DEFAULT 0,`valid_days` integer NOT NULL DEFAULT 0,`expires_at` datetime,`is_enabled` numeric NOT NULL DEFAULT true,`redeemed_user_id` integer,`user_group_id` integer,`created_at` datetime NOT NULL,`redeemed_at` datetime,CONSTRAINT `fk_prepaid_cards_redeemed_user` FOREIGN KEY (`redeemed_user_id`) REFERENCES `users`(`id`));
+
+CREATE INDEX `idx_prepaid_cards_user_group_id` ON `prepaid_cards`(`user_group_id`);
+
+CREATE INDEX `idx_prepaid_cards_redeemed_user_id` ON `prepaid_cards`(`redeemed_user_id`);
+
+CREATE UNIQUE INDEX `idx_prepaid_cards_card_sn` ON `prepaid_cards`(`card_sn`);
+
+CREATE TABLE `settings` (`key` varchar(255),`value` jsonb,`updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,PRIMARY KEY (`key`));
+
+CREATE TABLE `audit_logs` (`id` integer PRIMARY KEY AUTOINCREMENT,`event_id` varchar(64) NOT NULL,`event_type` varchar(64) NOT NULL,`severity` varchar(16) NOT NULL DEFAULT "info",`subject` text NOT NULL DEFAULT "",`message` text NOT NULL DEFAULT "",`data` JSON NOT NULL DEFAULT '{}',`occurred_at` datetime NOT NULL,`created_at` datetime NOT NULL);
+
+CREATE INDEX `idx_audit_logs_occurred_at` ON `audit_logs`(`occurred_at`);
+
+CREATE INDEX `idx_audit_logs_subject` ON `audit_logs`(`subject`);
+
+CREATE INDEX `idx_audit_logs_event_type` ON `audit_logs`(`event_type`);
+
+CREATE INDEX `idx_audit_logs_event_id` ON `audit_logs`(`event_id`);
+
+CREATE TABLE `tier_upgrade_rules` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` text NOT NULL,`from_user_group_id` integer NOT NULL,`to_user_group_id` integer NOT NULL,`window_days` integer NOT NULL DEFAULT 7,`threshold_amount` decimal(20,10) NOT NULL DEFAULT 0,`require_confirmation` numeric NOT NULL,`is_enabled` numeric NOT NULL,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE INDEX `idx_tier_upgrade_rules_from_user_group_id` ON `tier_upgrade_rules`(`from_user_group_id`);
+
+CREATE TABLE `tier_upgrades` (`id` integer PRIMARY KEY AUTOINCREMENT,`rule_id` integer NOT NULL,`user_id` integer NOT NULL,`from_user_group_id` integer NOT NULL,`to_user_group_id` integer NOT NULL,`usage_amount` decimal(20,10) NOT NULL DEFAULT 0,`status` varchar(16) NOT NULL,`decided_at` datetime,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE INDEX `idx_tier_upgrades_status` ON `tier_upgrades`(`status`);
+
+CREATE INDEX `idx_tier_upgrades_user_id` ON `tier_upgrades`(`user_id`);
+
+CREATE INDEX `idx_tier_upgrades_rule_id` ON `tier_upgrades`(`rule_id`);
+
+CREATE TABLE `user_group_migrations` (`id` integer PRIMARY KEY AUTOINCREMENT,`user_id` integer NOT NULL,`from_user_group_id` jsonb NOT NULL DEFAULT '[]',`to_user_group_id` jsonb NOT NULL DEFAULT '[]',`bill_strategy` varchar(16) NOT NULL,`effective_at` datetime NOT NULL,`status` varchar(16) NOT NULL,`result` JSON,`last_error` text,`applied_at` datetime,`created_by` integer,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE INDEX `idx_user_group_migrations_status` ON `user_group_migrations`(`status`);
+
+CREATE INDEX `idx_user_group_migrations_effective_at` ON `user_group_migrations`(`effective_at`);
+
+CREATE INDEX `idx_user_group_migrations_user_id` ON `user_group_migrations`(`user_id`);
+
+CREATE TABLE `kpi_snapshots` (`id` integer PRIMARY KEY AUTOINCREMENT,`day` datetime NOT NULL,`requests` integer NOT NULL DEFAULT 0,`failed_requests` integer NOT NULL DEFAULT 0,`success_rate` real NOT NULL DEFAULT 0,`input_tokens` integer NOT NULL DEFAULT 0,`output_tokens` integer NOT NULL DEFAULT 0,`cached_tokens` integer NOT NULL DEFAULT 0,`total_tokens` integer NOT NULL DEFAULT 0,`cost_micros` integer NOT NULL DEFAULT 0,`active_users` integer NOT NULL DEFAULT 0,`active_api_keys` integer NOT NULL DEFAULT 0,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE UNIQUE INDEX `idx_kpi_snapshots_day` ON `kpi_snapshots`(`day`);
+
+CREATE TABLE `nodes` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` varchar(64) NOT NULL,`token` text NOT NULL,`environment` varchar(32) NOT NULL DEFAULT "",`push_url` text NOT NULL DEFAULT "",`is_enabled` numeric NOT NULL DEFAULT true,`config_version` varchar(64) NOT NULL DEFAULT "",`applied_version` varchar(64) NOT NULL DEFAULT "",`agent_version` varchar(64) NOT NULL DEFAULT "",`sync_status` varchar(16) NOT NULL DEFAULT "pending",`sync_error` text NOT NULL DEFAULT "",`last_seen_at` datetime,`last_sync_at` datetime,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE UNIQUE INDEX `idx_nodes_token` ON `nodes`(`token`);
+
+CREATE UNIQUE INDEX `idx_nodes_name` ON `nodes`(`name`);
+
+CREATE TABLE `model_displays` (`id` integer PRIMARY KEY AUTOINCREMENT,`model_id` varchar(255) NOT NULL,`display_name` varchar(255) NOT NULL DEFAULT "",`category` varchar(64) NOT NULL DEFAULT "",`description` text NOT NULL DEFAULT "",`context_window` integer NOT NULL DEFAULT 0,`capabilities` JSON NOT NULL DEFAULT '[]',`sort_order` integer NOT NULL DEFAULT 0,`is_enabled` numeric NOT NULL DEFAULT true,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE INDEX `idx_model_displays_category` ON `model_displays`(`category`);
+
+CREATE UNIQUE INDEX `idx_model_displays_model_id` ON `model_displays`(`model_id`);
+
+CREATE TABLE `usage_daily` (`id` integer PRIMARY KEY AUTOINCREMENT,`day` datetime NOT NULL,`provider` varchar(255) NOT NULL,`model` varchar(255) NOT NULL,`user_id` integer NOT NULL DEFAULT 0,`api_key_id` integer NOT NULL DEFAULT 0,`requests` integer NOT NULL DEFAULT 0,`failed_requests` integer NOT NULL DEFAULT 0,`input_tokens` integer NOT NULL DEFAULT 0,`output_tokens` integer NOT NULL DEFAULT 0,`reasoning_tokens` integer NOT NULL DEFAULT 0,`cached_tokens` integer NOT NULL DEFAULT 0,`total_tokens` integer NOT NULL DEFAULT 0,`cost_micros` integer NOT NULL DEFAULT 0,`energy_milli_wh` integer NOT NULL DEFAULT 0,`carbon_milligrams` integer NOT NULL DEFAULT 0,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE INDEX `idx_usage_daily_user_id` ON `usage_daily`(`user_id`);
+
+CREATE INDEX `idx_usage_daily_day` ON `usage_daily`(`day`);
+
+CREATE UNIQUE INDEX `idx_usage_daily_bucket` ON `usage_daily`(`day`,`provider`,`model`,`user_id`,`api_key_id`);
+
+CREATE TABLE `usage_hourly` (`id` integer PRIMARY KEY AUTOINCREMENT,`hour` datetime NOT NULL,`provider` varchar(255) NOT NULL,`model` varchar(255) NOT NULL,`user_id` integer NOT NULL DEFAULT 0,`requests` integer NOT NULL DEFAULT 0,`failed_requests` integer NOT NULL DEFAULT 0,`total_tokens` integer NOT NULL DEFAULT 0,`cached_tokens` integer NOT NULL DEFAULT 0,`cost_micros` integer NOT NULL DEFAULT 0,`energy_milli_wh` integer NOT NULL DEFAULT 0,`carbon_milligrams` integer NOT NULL DEFAULT 0,`duration_millis` integer NOT NULL DEFAULT 0,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE INDEX `idx_usage_hourly_hour` ON `usage_hourly`(`hour`);
+
+CREATE UNIQUE INDEX `idx_usage_hourly_bucket` ON `usage_hourly`(`hour`,`provider`,`model`,`user_id`);
+
+CREATE TABLE `provider_health_checks` (`id` integer PRIMARY KEY AUTOINCREMENT,`provider` varchar(255) NOT NULL,`source` varchar(32) NOT NULL,`target_id` integer NOT NULL DEFAULT 0,`success` numeric NOT NULL DEFAULT false,`status_code` integer NOT NULL DEFAULT 0,`latency_millis` integer NOT NULL DEFAULT 0,`error` text,`checked_at` datetime NOT NULL);
+
+CREATE INDEX `idx_provider_health_checks_checked_at` ON `provider_health_checks`(`checked_at`);
+
+CREATE INDEX `idx_provider_health_checks_provider` ON `provider_health_checks`(`provider`);
+
+CREATE TABLE `stats_cursors` (`name` varchar(64),`through` datetime NOT NULL,`updated_at` datetime NOT NULL,PRIMARY KEY (`name`));
+
+CREATE TABLE `bulk_delete_jobs` (`id` integer PRIMARY KEY AUTOINCREMENT,`entity` varchar(32) NOT NULL,`criteria` JSON NOT NULL,`batch_size` integer NOT NULL,`status` varchar(16) NOT NULL,`total` integer NOT NULL DEFAULT 0,`deleted` integer NOT NULL DEFAULT 0,`last_error` text,`created_by` integer,`started_at` datetime,`finished_at` datetime,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE INDEX `idx_bulk_delete_jobs_status` ON `bulk_delete_jobs`(`status`);
+
+CREATE TABLE `invoices` (`id` integer PRIMARY KEY AUTOINCREMENT,`number` varchar(64),`user_id` integer,`user_group_id` integer,`period_start` datetime NOT NULL,`period_end` datetime NOT NULL,`currency` varchar(8) NOT NULL,`exchange_rate` decimal(20,10) NOT NULL DEFAULT 1,`line_items` JSON NOT NULL,`subtotal` decimal(20,10) NOT NULL DEFAULT 0,`tax_rate` decimal(20,10) NOT NULL DEFAULT 0,`tax` decimal(20,10) NOT NULL DEFAULT 0,`total` decimal(20,10) NOT NULL DEFAULT 0,`status` varchar(16) NOT NULL,`finalized_at` datetime,`paid_at` datetime,`created_by` integer,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE INDEX `idx_invoices_status` ON `invoices`(`status`);
+
+CREATE INDEX `idx_invoices_period_start` ON `invoices`(`period_start`);
+
+CREATE INDEX `idx_invoices_user_group_id` ON `invoices`(`user_group_id`);
+
+CREATE INDEX `idx_invoices_user_id` ON `invoices`(`user_id`);
+
+CREATE INDEX `idx_invoices_number` ON `invoices`(`number`);
+
+CREATE TABLE `coop_contributions` (`id` integer PRIMARY KEY AUTOINCREMENT,`user_id` integer NOT NULL,`auth_id` integer NOT NULL,`auth_key` text NOT NULL,`provider` varchar(64) NOT NULL,`status` varchar(16) NOT NULL DEFAULT "active",`credit_rate` decimal(10,4) NOT NULL DEFAULT 0,`settled_until` datetime NOT NULL,`credited_amount` decimal(20,10) NOT NULL DEFAULT 0,`served_requests` integer NOT NULL DEFAULT 0,`suspend_reason` text,`revoked_at` datetime,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE INDEX `idx_coop_contributions_status` ON `coop_contributions`(`status`);
+
+CREATE INDEX `idx_coop_contributions_auth_id` ON `coop_contributions`(`auth_id`);
+
+CREATE INDEX `idx_coop_contributions_user_id` ON `coop_contributions`(`user_id`);
+
+CREATE TABLE `balance_transactions` (`id` integer PRIMARY KEY AUTOINCREMENT,`user_id` integer NOT NULL,`target` varchar(16) NOT NULL,`kind` varchar(32) NOT NULL,`amount` decimal(20,10) NOT NULL,`balance_after` decimal(20,10) NOT NULL,`prepaid_card_id` integer,`bill_id` integer,`usage_id` integer,`reason` text,`actor` varchar(128),`created_at` datetime NOT NULL);
+
+CREATE INDEX `idx_balance_transactions_created_at` ON `balance_transactions`(`created_at`);
+
+CREATE INDEX `idx_balance_transactions_usage_id` ON `balance_transactions`(`usage_id`);
+
+CREATE INDEX `idx_balance_transactions_bill_id` ON `balance_transactions`(`bill_id`);
+
+CREATE INDEX `idx_balance_transactions_prepaid_card_id` ON `balance_transactions`(`prepaid_card_id`);
+
+CREATE INDEX `idx_balance_transactions_kind` ON `balance_transactions`(`kind`);
+
+CREATE INDEX `idx_balance_transactions_target` ON `balance_transactions`(`target`);
+
+CREATE INDEX `idx_balance_transactions_user_id` ON `balance_transactions`(`user_id`);
+
+CREATE TABLE `billing_rule_snapshots` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` varchar(128) NOT NULL,`rules` JSON NOT NULL,`rule_count` integer NOT NULL DEFAULT 0,`fingerprint` varchar(64),`proposed` numeric NOT NULL DEFAULT false,`created_by` integer,`created_at` datetime NOT NULL);
+
+CREATE INDEX `idx_billing_rule_snapshots_created_at` ON `billing_rule_snapshots`(`created_at`);
+
+CREATE INDEX `idx_billing_rule_snapshots_fingerprint` ON `billing_rule_snapshots`(`fingerprint`);
+
+CREATE TABLE `cost_replay_jobs` (`id` integer PRIMARY KEY AUTOINCREMENT,`snapshot_id` integer NOT NULL,`period_start` datetime NOT NULL,`period_end` datetime NOT NULL,`user_id` integer,`status` varchar(16) NOT NULL,`total` integer NOT NULL DEFAULT 0,`processed` integer NOT NULL DEFAULT 0,`report` JSON,`last_error` text,`created_by` integer,`started_at` datetime,`finished_at` datetime,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE INDEX `idx_cost_replay_jobs_status` ON `cost_replay_jobs`(`status`);
+
+CREATE INDEX `idx_cost_replay_jobs_snapshot_id` ON `cost_replay_jobs`(`snapshot_id`);
+
+CREATE TABLE `exchange_rates` (`id` integer PRIMARY KEY AUTOINCREMENT,`currency` varchar(8) NOT NULL,`rate` decimal(20,10) NOT NULL,`source` varchar(16) NOT NULL,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE UNIQUE INDEX `idx_exchange_rates_currency` ON `exchange_rates`(`currency`);
+
+CREATE TABLE `auth_import_conflicts` (`id` integer PRIMARY KEY AUTOINCREMENT,`auth_key` text NOT NULL,`auth_id` integer NOT NULL,`source` text,`proxy_url` text,`auth_group_id` jsonb NOT NULL DEFAULT '[]',`content` JSON NOT NULL,`imported_by` varchar(255),`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE INDEX `idx_auth_import_conflicts_auth_id` ON `auth_import_conflicts`(`auth_id`);
+
+CREATE UNIQUE INDEX `idx_auth_import_conflicts_auth_key` ON `auth_import_conflicts`(`auth_key`);
+
+CREATE TABLE `admin_notifications` (`id` integer PRIMARY KEY AUTOINCREMENT,`event_id` varchar(64) NOT NULL,`event_type` varchar(64) NOT NULL,`severity` varchar(16) NOT NULL DEFAULT "info",`subject` text NOT NULL DEFAULT "",`message` text NOT NULL DEFAULT "",`data` JSON NOT NULL DEFAULT '{}',`read_at` datetime,`read_by` varchar(255),`occurred_at` datetime NOT NULL,`created_at` datetime NOT NULL);
+
+CREATE INDEX `idx_admin_notifications_occurred_at` ON `admin_notifications`(`occurred_at`);
+
+CREATE INDEX `idx_admin_notifications_read_at` ON `admin_notifications`(`read_at`);
+
+CREATE INDEX `idx_admin_notifications_subject` ON `admin_notifications`(`subject`);
+
+CREATE INDEX `idx_admin_notifications_event_type` ON `admin_notifications`(`event_type`);
+
+CREATE INDEX `idx_admin_notifications_event_id` ON `admin_notifications`(`event_id`);
+
+CREATE TABLE `usage_badges` (`id` integer PRIMARY KEY AUTOINCREMENT,`user_id` integer NOT NULL,`token` varchar(64) NOT NULL,`project` text NOT NULL DEFAULT "",`label` varchar(64) NOT NULL DEFAULT "",`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE UNIQUE INDEX `idx_usage_badges_token` ON `usage_badges`(`token`);
+
+CREATE INDEX `idx_usage_badges_user_id` ON `usage_badges`(`user_id`);
+
+CREATE TABLE `slos` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` varchar(255) NOT NULL,`description` text,`kind` varchar(16) NOT NULL,`threshold_millis` integer NOT NULL DEFAULT 0,`objective` decimal(7,4) NOT NULL,`window_days` integer NOT NULL DEFAULT 30,`provider` varchar(255) NOT NULL DEFAULT "",`model` varchar(255) NOT NULL DEFAULT "",`is_enabled` numeric NOT NULL DEFAULT true,`last_evaluated_at` datetime,`last_state` varchar(16) NOT NULL DEFAULT "",`last_compliance` real NOT NULL DEFAULT 0,`last_budget_remaining` real NOT NULL DEFAULT 0,`last_alerted_at` datetime,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE UNIQUE INDEX `idx_slos_name` ON `slos`(`name`);
+
+CREATE TABLE `webhooks` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` varchar(255) NOT NULL,`url` text NOT NULL,`secret` text NOT NULL,`event_types` JSON NOT NULL DEFAULT '[]',`is_enabled` numeric NOT NULL DEFAULT true,`created_by` varchar(255) NOT NULL DEFAULT "",`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE INDEX `idx_webhooks_is_enabled` ON `webhooks`(`is_enabled`);
+
+CREATE TABLE `webhook_deliveries` (`id` integer PRIMARY KEY AUTOINCREMENT,`webhook_id` integer NOT NULL,`event_id` varchar(64) NOT NULL,`event_type` varchar(64) NOT NULL,`payload` text NOT NULL,`status` varchar(16) NOT NULL DEFAULT "pending",`attempts` integer NOT NULL DEFAULT 0,`next_attempt_at` datetime,`last_attempt_at` datetime,`last_status_code` integer NOT NULL DEFAULT 0,`last_error` text,`delivered_at` datetime,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE INDEX `idx_webhook_deliveries_next_attempt_at` ON `webhook_deliveries`(`next_attempt_at`);
+
+CREATE INDEX `idx_webhook_deliveries_status` ON `webhook_deliveries`(`status`);
+
+CREATE INDEX `idx_webhook_deliveries_event_type` ON `webhook_deliveries`(`event_type`);
+
+CREATE INDEX `idx_webhook_deliveries_event_id` ON `webhook_deliveries`(`event_id`);
+
+CREATE INDEX `idx_webhook_deliveries_webhook_id` ON `webhook_deliveries`(`webhook_id`);
+
+CREATE TABLE `email_tokens` (`id` integer PRIMARY KEY AUTOINCREMENT,`user_id` integer NOT NULL,`purpose` varchar(32) NOT NULL,`token_hash` varchar(64) NOT NULL,`email` text NOT NULL,`expires_at` datetime NOT NULL,`used_at` datetime,`created_at` datetime NOT NULL);
+
+CREATE INDEX `idx_email_tokens_expires_at` ON `email_tokens`(`expires_at`);
+
+CREATE UNIQUE INDEX `idx_email_tokens_token_hash` ON `email_tokens`(`token_hash`);
+
+CREATE INDEX `idx_email_tokens_purpose` ON `email_tokens`(`purpose`);
+
+CREATE INDEX `idx_email_tokens_user_id` ON `email_tokens`(`user_id`);
+
+CREATE TABLE `mfa_recovery_codes` (`id` integer PRIMARY KEY AUTOINCREMENT,`owner_type` varchar(16) NOT NULL,`owner_id` integer NOT NULL,`code_hash` varchar(64) NOT NULL,`used_at` datetime,`created_at` datetime NOT NULL);
+
+CREATE UNIQUE INDEX `idx_mfa_recovery_codes_code_hash` ON `mfa_recovery_codes`(`code_hash`);
+
+CREATE INDEX `idx_mfa_recovery_codes_owner` ON `mfa_recovery_codes`(`owner_type`,`owner_id`);
+
+CREATE TABLE `mfa_sessions` (`id` integer PRIMARY KEY AUTOINCREMENT,`scope` varchar(64) NOT NULL,`key` varchar(255) NOT NULL,`value` bytea NOT NULL,`expires_at` datetime NOT NULL,`created_at` datetime NOT NULL);
+
+CREATE INDEX `idx_mfa_sessions_expires_at` ON `mfa_sessions`(`expires_at`);
+
+CREATE UNIQUE INDEX `idx_mfa_sessions_scope_key` ON `mfa_sessions`(`scope`,`key`);
+
+CREATE TABLE `admin_roles` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` varchar(100) NOT NULL,`description` text,`permissions` JSON NOT NULL DEFAULT '[]',`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE UNIQUE INDEX `idx_admin_roles_name` ON `admin_roles`(`name`);
+
+CREATE TABLE `admin_role_assignments` (`admin_id` integer,`role_id` integer,`created_at` datetime NOT NULL,PRIMARY KEY (`admin_id`,`role_id`));
+
+CREATE INDEX `idx_admin_role_assignments_role_id` ON `admin_role_assignments`(`role_id`);
+
+CREATE TABLE `user_identities` (`id` integer PRIMARY KEY AUTOINCREMENT,`user_id` integer NOT NULL,`provider` varchar(32) NOT NULL,`subject` varchar(255) NOT NULL,`login` text,`email` text,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE UNIQUE INDEX `idx_user_identities_subject` ON `user_identities`(`provider`,`subject`);
+
+CREATE INDEX `idx_user_identities_user_id` ON `user_identities`(`user_id`);
+
+CREATE TABLE `invitations` (`id` integer PRIMARY KEY AUTOINCREMENT,`code` varchar(64) NOT NULL,`note` text,`user_group_id` integer,`plan_id` integer,`plan_days` integer NOT NULL DEFAULT 0,`max_uses` integer NOT NULL DEFAULT 0,`used_count` integer NOT NULL DEFAULT 0,`expires_at` datetime,`is_enabled` numeric NOT NULL DEFAULT true,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL);
+
+CREATE INDEX `idx_invitations_plan_id` ON `invitations`(`plan_id`);
+
+CREATE INDEX `idx_invitations_user_group_id` ON `invitations`(`user_group_id`);
+
+CREATE UNIQUE INDEX `idx_invitations_code` ON `invitations`(`code`);
+
+CREATE TABLE `invitation_redemptions` (`id` integer PRIMARY KEY AUTOINCREMENT,`invitation_id` integer NOT NULL,`user_id` integer NOT NULL,`bill_id` integer,`created_at` datetime NOT NULL);
+
+CREATE UNIQUE INDEX `idx_invitation_redemptions_user_id` ON `invitation_redemptions`(`user_id`);
+
+CREATE INDEX `idx_invitation_redemptions_invitation_id` ON `invitation_redemptions`(`invitation_id`);
+
+CREATE TABLE `request_logs` (`id` integer PRIMARY KEY AUTOINCREMENT,`request_id` text,`user_id` integer,`api_key_id` integer,`method` text NOT NULL,`path` text NOT NULL,`model` text,`status_code` integer NOT NULL DEFAULT 0,`duration_ms` integer NOT NULL DEFAULT 0,`client_ip` text NOT NULL DEFAULT "",`request_body` text,`response_body` text,`request_truncated` numeric NOT NULL DEFAULT false,`response_truncated` numeric NOT NULL DEFAULT false,`payload_backend` varchar(16) NOT NULL DEFAULT "",`payload_key` text,`payload_bytes` integer NOT NULL DEFAULT 0,`expires_at` datetime,`redacted_at` datetime,`created_at` datetime NOT NULL);
+
+CREATE INDEX `idx_request_logs_created_at` ON `request_logs`(`created_at`);
+
+CREATE INDEX `idx_request_logs_expires_at` ON `request_logs`(`expires_at`);
+
+CREATE INDEX `idx_request_logs_model` ON `request_logs`(`model`);
+
+CREATE INDEX `idx_request_logs_api_key_id` ON `request_logs`(`api_key_id`);
+
+CREATE INDEX `idx_request_logs_user_id` ON `request_logs`(`user_id`);
+
+CREATE INDEX `idx_request_logs_request_id` ON `request_logs`(`request_id`);
+
+CREATE TABLE `cluster_instances` (`id` integer PRIMARY KEY AUTOINCREMENT,`instance_id` varchar(64) NOT NULL,`hostname` varchar(255) NOT NULL DEFAULT "",`version` varchar(64) NOT NULL DEFAULT "",`environment` varchar(32) NOT NULL DEFAULT "",`bus_connected` numeric NOT NULL DEFAULT false,`started_at` datetime NOT NULL,`last_seen_at` datetime NOT NULL);
+
+CREATE INDEX `idx_cluster_instances_last_seen_at` ON `cluster_instances`(`last_seen_at`);
+
+CREATE UNIQUE INDEX `idx_cluster_instances_instance_id` ON `cluster_instances`(`instance_id`);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_model_payload_rules_mapping
+	ON model_payload_rules (model_mapping_id);
+
+CREATE INDEX IF NOT EXISTS idx_prepaid_cards_redeemed_user_group ON prepaid_cards (redeemed_user_id, user_group_id);
+
+CREATE INDEX IF NOT EXISTS idx_auths_updated_at_id
+	ON auths (updated_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_settings_updated_at_key
+	ON settings (updated_at DESC, key DESC);
+
+CREATE INDEX IF NOT EXISTS idx_auths_available_id
+	ON auths (id)
+	WHERE is_available = true;
+
+CREATE INDEX IF NOT EXISTS idx_user_groups_default_true
+	ON user_groups (id)
+	WHERE is_default = true;
+
+CREATE INDEX IF NOT EXISTS idx_auth_groups_default_true
+	ON auth_groups (id)
+	WHERE is_default = true;
+
+CREATE INDEX IF NOT EXISTS idx_plans_sort_order_created_at
+	ON plans (sort_order ASC, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_plans_is_enabled_sort_order_created_at
+	ON plans (is_enabled, sort_order ASC, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_bills_user_id_created_at
+	ON bills (user_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_bills_plan_id_created_at
+	ON bills (plan_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_bills_status_created_at
+	ON bills (status, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_bills_is_enabled_created_at
+	ON bills (is_enabled, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_billing_rules_match
+	ON billing_rules (auth_group_id, user_group_id, is_enabled, provider, model);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_billing_rules_unique_key
+	ON billing_rules (auth_group_id, user_group_id, provider, model);
+
+CREATE INDEX IF NOT EXISTS idx_proxies_proxy_url
+	ON proxies (proxy_url);
+
+CREATE INDEX IF NOT EXISTS idx_model_mappings_provider_model_name_is_enabled
+	ON model_mappings (provider, model_name, is_enabled);
+
+CREATE INDEX IF NOT EXISTS idx_model_mappings_provider_new_model_name_is_enabled
+	ON model_mappings (provider, new_model_name, is_enabled);
+
+CREATE INDEX IF NOT EXISTS idx_prepaid_cards_redeemed_at
+	ON prepaid_cards (redeemed_at);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id_created_at
+	ON api_keys (user_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id_active_not_revoked
+	ON api_keys (user_id)
+	WHERE active = true AND revoked_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id_expires_at_active
+	ON api_keys (user_id, expires_at)
+	WHERE active = true AND revoked_at IS NULL AND expires_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id_revoked_at
+	ON api_keys (user_id, revoked_at)
+	WHERE revoked_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_usages_source
+	ON usages (source);
+
+CREATE INDEX IF NOT EXISTS idx_usages_user_id_requested_at
+	ON usages (user_id, requested_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_usages_user_id_charged_to_requested_at
+	ON usages (user_id, charged_to, requested_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_usages_api_key_id_requested_at
+	ON usages (api_key_id, requested_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_usages_user_id_model
+	ON usages (user_id, model);
+
+CREATE INDEX IF NOT EXISTS idx_usages_user_id_provider_model
+	ON usages (user_id, provider, model);
+
+CREATE INDEX IF NOT EXISTS idx_usages_user_id_source
+	ON usages (user_id, source);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_model_auth_bindings_user_model
+	ON user_model_auth_bindings (user_id, model_mapping_id);
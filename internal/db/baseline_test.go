@@ -0,0 +1,74 @@
+package db
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestBaselineSchemasCoverBaselineModels(t *testing.T) {
+	conn := openVersionsTestDB(t)
+	for _, dialect := range []string{DialectPostgres, DialectMySQL, DialectSQLite} {
+		statements, errLoad := baselineStatements(dialect)
+		if errLoad != nil {
+			t.Fatalf("load %s baseline: %v", dialect, errLoad)
+		}
+		ddl := strings.Join(statements, "\n")
+		for _, model := range migrationModels() {
+			table, errTable := tableNameForModel(conn, model)
+			if errTable != nil {
+				t.Fatalf("table of %T: %v", model, errTable)
+			}
+			quoted := "`" + table + "`"
+			if dialect == DialectPostgres {
+				quoted = `"` + table + `"`
+			}
+			if !strings.Contains(ddl, "CREATE TABLE "+quoted+" (") {
+				t.Fatalf("%s baseline has no table %s", dialect, table)
+			}
+		}
+	}
+}
+
+func TestFrozenBaselineMatchesLegacyUpgrade(t *testing.T) {
+	fresh := openVersionsTestDB(t)
+	if errMigrate := Migrate(fresh); errMigrate != nil {
+		t.Fatalf("migrate fresh database: %v", errMigrate)
+	}
+	legacy := openVersionsTestDB(t)
+	if errUpgrade := upgradeLegacySchema(legacy); errUpgrade != nil {
+		t.Fatalf("upgrade legacy database: %v", errUpgrade)
+	}
+	if errMigrate := Migrate(legacy); errMigrate != nil {
+		t.Fatalf("migrate legacy database: %v", errMigrate)
+	}
+
+	want, got := sqliteSchemaShape(t, legacy), sqliteSchemaShape(t, fresh)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("frozen baseline drifted from the legacy upgrade:\nfresh:  %v\nlegacy: %v", got, want)
+	}
+}
+
+// sqliteSchemaShape lists every table column and index of an SQLite database.
+func sqliteSchemaShape(t *testing.T, conn *gorm.DB) []string {
+	t.Helper()
+	var shape []string
+	if errColumns := conn.Raw(`
+		SELECT m.name || '.' || p.name
+		FROM sqlite_master m JOIN pragma_table_info(m.name) p
+		WHERE m.type = 'table' AND m.name NOT LIKE 'sqlite_%'
+	`).Scan(&shape).Error; errColumns != nil {
+		t.Fatalf("list columns: %v", errColumns)
+	}
+	var indexes []string
+	if errIndexes := conn.Raw(`
+		SELECT tbl_name || ' index ' || name FROM sqlite_master WHERE type = 'index' AND name NOT LIKE 'sqlite_%'
+	`).Scan(&indexes).Error; errIndexes != nil {
+		t.Fatalf("list indexes: %v", errIndexes)
+	}
+	shape = append(shape, indexes...)
+	sort.Strings(shape)
+	return shape
+}
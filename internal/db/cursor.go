@@ -0,0 +1,117 @@
+package db
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// cursorPrefix versions the opaque keyset cursor.
+const cursorPrefix = "k1|"
+
+// ErrInvalidCursor reports a cursor that is malformed or was issued for another ordering.
+var ErrInvalidCursor = errors.New("db: invalid cursor")
+
+// Keyset orders rows by a column with the primary key as tie breaker and resumes after the
+// last row of the previous page. Unlike OFFSET, the cost of a page does not grow with its
+// depth, so it suits large tables such as usages.
+type Keyset struct {
+	Column   string // Sort column, qualified when the query joins.
+	IDColumn string // Primary key column; "id" when empty.
+	Time     bool   // Column holds timestamps; cursor values are Unix nanoseconds.
+	Desc     bool   // Newest or largest first.
+}
+
+// RequestedAtKeyset orders usages by request time and ID, newest first when desc.
+func RequestedAtKeyset(desc bool) Keyset {
+	return Keyset{Column: "usages.requested_at", IDColumn: "usages.id", Time: true, Desc: desc}
+}
+
+// Cursor is the position of the last row of a page.
+type Cursor struct {
+	Value int64  // Sort value; Unix nanoseconds for time columns.
+	ID    uint64 // Primary key.
+}
+
+// key identifies the ordering a cursor belongs to.
+func (k Keyset) key() string {
+	direction := "asc"
+	if k.Desc {
+		direction = "desc"
+	}
+	return k.Column + " " + direction
+}
+
+func (k Keyset) idColumn() string {
+	if k.IDColumn == "" {
+		return "id"
+	}
+	return k.IDColumn
+}
+
+// Encode returns the opaque cursor for a row position.
+func (k Keyset) Encode(pos Cursor) string {
+	raw := cursorPrefix + k.key() + "|" + strconv.FormatInt(pos.Value, 10) + "|" + strconv.FormatUint(pos.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Decode parses a cursor issued by Encode for the same ordering.
+func (k Keyset) Decode(cursor string) (Cursor, error) {
+	var pos Cursor
+	raw, errDecode := base64.RawURLEncoding.DecodeString(strings.TrimSpace(cursor))
+	if errDecode != nil {
+		return pos, ErrInvalidCursor
+	}
+	rest, ok := strings.CutPrefix(string(raw), cursorPrefix)
+	if !ok {
+		return pos, ErrInvalidCursor
+	}
+	parts := strings.Split(rest, "|")
+	if len(parts) != 3 || parts[0] != k.key() {
+		return pos, fmt.Errorf("%w: issued for another ordering", ErrInvalidCursor)
+	}
+	value, errValue := strconv.ParseInt(parts[1], 10, 64)
+	id, errID := strconv.ParseUint(parts[2], 10, 64)
+	if errValue != nil || errID != nil {
+		return pos, ErrInvalidCursor
+	}
+	pos.Value, pos.ID = value, id
+	return pos, nil
+}
+
+// Apply orders query by the keyset and, when after is set, skips rows up to and including it.
+func (k Keyset) Apply(query *gorm.DB, after *Cursor) *gorm.DB {
+	direction, op := "ASC", ">"
+	if k.Desc {
+		direction, op = "DESC", "<"
+	}
+	if after != nil {
+		var value any = after.Value
+		if k.Time {
+			value = time.Unix(0, after.Value).UTC()
+		}
+		query = query.Where(
+			fmt.Sprintf("((%s %s ?) OR (%s = ? AND %s %s ?))", k.Column, op, k.Column, k.idColumn(), op),
+			value, value, after.ID,
+		)
+	}
+	return query.Order(k.Column + " " + direction).Order(k.idColumn() + " " + direction)
+}
+
+// TimeValue converts a timestamp to a cursor value.
+func TimeValue(t time.Time) int64 { return t.UnixNano() }
+
+// NextPage trims rows fetched with limit+1 to limit and returns the cursor of the next page,
+// or "" when rows held the last page. position reports the sort value and ID of a row.
+func NextPage[T any](k Keyset, rows []T, limit int, position func(T) Cursor) ([]T, string) {
+	if limit <= 0 || len(rows) <= limit {
+		return rows, ""
+	}
+	rows = rows[:limit]
+	return rows, k.Encode(position(rows[len(rows)-1]))
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+)
+
+func TestKeysetPagesThroughTiedTimestamps(t *testing.T) {
+	conn := openVersionsTestDB(t)
+	if errMigrate := Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate: %v", errMigrate)
+	}
+	base := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+	// Five rows, the middle three sharing one timestamp, so pages must break ties by ID.
+	for i, offset := range []time.Duration{0, time.Minute, time.Minute, time.Minute, 2 * time.Minute} {
+		row := models.Usage{Provider: "codex", Model: "gpt-5", RequestedAt: base.Add(offset), CostMicros: int64(i)}
+		if errCreate := conn.Create(&row).Error; errCreate != nil {
+			t.Fatalf("create usage: %v", errCreate)
+		}
+	}
+
+	keyset := RequestedAtKeyset(true)
+	position := func(row models.Usage) Cursor { return Cursor{Value: TimeValue(row.RequestedAt), ID: row.ID} }
+	var (
+		seen   []uint64
+		cursor string
+	)
+	for page := 0; page < 5; page++ {
+		var after *Cursor
+		if cursor != "" {
+			pos, errDecode := keyset.Decode(cursor)
+			if errDecode != nil {
+				t.Fatalf("decode cursor: %v", errDecode)
+			}
+			after = &pos
+		}
+		var rows []models.Usage
+		if errFind := keyset.Apply(conn.Model(&models.Usage{}), after).Limit(2 + 1).Find(&rows).Error; errFind != nil {
+			t.Fatalf("page %d: %v", page, errFind)
+		}
+		rows, cursor = NextPage(keyset, rows, 2, position)
+		for _, row := range rows {
+			seen = append(seen, row.ID)
+		}
+		if cursor == "" {
+			break
+		}
+	}
+	want := []uint64{5, 4, 3, 2, 1}
+	if len(seen) != len(want) {
+		t.Fatalf("seen %v, want %v", seen, want)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("seen %v, want %v", seen, want)
+		}
+	}
+}
+
+func TestKeysetRejectsCursorOfAnotherOrdering(t *testing.T) {
+	cursor := RequestedAtKeyset(true).Encode(Cursor{Value: 1, ID: 2})
+	if _, errDecode := RequestedAtKeyset(false).Decode(cursor); !errors.Is(errDecode, ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor, got %v", errDecode)
+	}
+	if _, errDecode := RequestedAtKeyset(true).Decode("garbage"); !errors.Is(errDecode, ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor for garbage, got %v", errDecode)
+	}
+	pos, errDecode := RequestedAtKeyset(true).Decode(cursor)
+	if errDecode != nil || pos.Value != 1 || pos.ID != 2 {
+		t.Fatalf("round trip = %+v %v", pos, errDecode)
+	}
+}
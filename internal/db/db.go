@@ -66,6 +66,8 @@ func Open(dsn string) (*gorm.DB, error) {
 		return openPostgres(trimmed)
 	case DialectSQLite:
 		return openSQLite(trimmed)
+	case DialectMySQL:
+		return openMySQL(trimmed)
 	default:
 		return nil, fmt.Errorf("db: unsupported dialect: %s", dialect)
 	}
@@ -75,6 +77,8 @@ func Open(dsn string) (*gorm.DB, error) {
 func detectDialectFromDSN(dsn string) (string, error) {
 	lower := strings.ToLower(strings.TrimSpace(dsn))
 	switch {
+	case strings.HasPrefix(lower, "mysql://") || strings.Contains(lower, "@tcp(") || strings.Contains(lower, "@unix("):
+		return DialectMySQL, nil
 	case strings.HasPrefix(lower, "postgres://") || strings.HasPrefix(lower, "postgresql://"):
 		return DialectPostgres, nil
 	case strings.Contains(lower, "host=") || strings.Contains(lower, "user=") || strings.Contains(lower, "dbname=") || strings.Contains(lower, "sslmode="):
@@ -2,6 +2,7 @@ package db
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"gorm.io/datatypes"
@@ -14,6 +15,8 @@ const (
 	DialectPostgres = "postgres"
 	// DialectSQLite is the SQLite dialect name.
 	DialectSQLite = "sqlite"
+	// DialectMySQL is the MySQL dialect name.
+	DialectMySQL = "mysql"
 )
 
 // DialectName returns the active database dialect name.
@@ -29,9 +32,14 @@ func IsSQLite(conn *gorm.DB) bool {
 	return DialectName(conn) == DialectSQLite
 }
 
+// IsMySQL reports whether the connection uses MySQL.
+func IsMySQL(conn *gorm.DB) bool {
+	return DialectName(conn) == DialectMySQL
+}
+
 // CaseInsensitiveLikeExpr returns a SQL expression for case-insensitive LIKE.
 func CaseInsensitiveLikeExpr(conn *gorm.DB, column string) string {
-	if IsSQLite(conn) {
+	if IsSQLite(conn) || IsMySQL(conn) {
 		return fmt.Sprintf("LOWER(%s) LIKE ?", column)
 	}
 	return fmt.Sprintf("%s ILIKE ?", column)
@@ -39,7 +47,7 @@ func CaseInsensitiveLikeExpr(conn *gorm.DB, column string) string {
 
 // NormalizeLikePattern normalizes a LIKE pattern for the current dialect.
 func NormalizeLikePattern(conn *gorm.DB, pattern string) string {
-	if IsSQLite(conn) {
+	if IsSQLite(conn) || IsMySQL(conn) {
 		return strings.ToLower(pattern)
 	}
 	return pattern
@@ -47,24 +55,65 @@ func NormalizeLikePattern(conn *gorm.DB, pattern string) string {
 
 // JSONExtractTextExpr returns a SQL expression to extract a JSON field as text.
 func JSONExtractTextExpr(conn *gorm.DB, column, key string) string {
-	if IsSQLite(conn) {
+	switch DialectName(conn) {
+	case DialectSQLite:
 		return fmt.Sprintf("json_extract(%s, '$.%s')", column, key)
+	case DialectMySQL:
+		return fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(%s, '$.%s'))", column, key)
+	default:
+		return fmt.Sprintf("%s->>'%s'", column, key)
 	}
-	return fmt.Sprintf("%s->>'%s'", column, key)
 }
 
 // JSONArrayContainsExpr returns a SQL expression to test JSON array containment.
 func JSONArrayContainsExpr(conn *gorm.DB, column string) string {
-	if IsSQLite(conn) {
+	switch DialectName(conn) {
+	case DialectSQLite:
 		return fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(%s) WHERE value = ?)", column)
+	case DialectMySQL:
+		return fmt.Sprintf("JSON_CONTAINS(%s, ?)", column)
+	default:
+		return fmt.Sprintf("%s @> ?", column)
 	}
-	return fmt.Sprintf("%s @> ?", column)
 }
 
 // JSONArrayContainsValue returns the bind value for JSON array containment checks.
 func JSONArrayContainsValue(conn *gorm.DB, value uint64) any {
-	if IsSQLite(conn) {
+	switch DialectName(conn) {
+	case DialectSQLite:
 		return value
+	case DialectMySQL:
+		return strconv.FormatUint(value, 10)
+	default:
+		return datatypes.JSON([]byte(fmt.Sprintf("[%d]", value)))
+	}
+}
+
+// DurationMillisExpr returns a SQL expression for the non-negative milliseconds between two timestamp columns.
+func DurationMillisExpr(conn *gorm.DB, fromColumn, toColumn string) string {
+	switch DialectName(conn) {
+	case DialectSQLite:
+		return fmt.Sprintf("MAX((julianday(%s) - julianday(%s)) * 86400000, 0)", toColumn, fromColumn)
+	case DialectMySQL:
+		return fmt.Sprintf("GREATEST(TIMESTAMPDIFF(MICROSECOND, %s, %s) / 1000, 0)", fromColumn, toColumn)
+	default:
+		return fmt.Sprintf("GREATEST(EXTRACT(EPOCH FROM (%s - %s)) * 1000, 0)", toColumn, fromColumn)
+	}
+}
+
+// NullsLastOrder returns an ORDER BY item sorting column in direction with NULLs after all
+// other values. MySQL has no NULLS LAST, so it sorts on the IS NULL flag first.
+func NullsLastOrder(conn *gorm.DB, column, direction string) string {
+	if IsMySQL(conn) {
+		return fmt.Sprintf("%s IS NULL, %s %s", column, column, direction)
+	}
+	return fmt.Sprintf("%s %s NULLS LAST", column, direction)
+}
+
+// CastTextExpr returns a SQL expression casting expr to a string.
+func CastTextExpr(conn *gorm.DB, expr string) string {
+	if IsMySQL(conn) {
+		return fmt.Sprintf("CAST(%s AS CHAR)", expr)
 	}
-	return datatypes.JSON([]byte(fmt.Sprintf("[%d]", value)))
+	return fmt.Sprintf("CAST(%s AS TEXT)", expr)
 }
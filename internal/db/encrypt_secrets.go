@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/crypto"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// encryptSecretsBatchSize bounds how many rows are loaded per batch.
+const encryptSecretsBatchSize = 200
+
+// EncryptSecretsResult counts rows touched by EncryptSecrets.
+type EncryptSecretsResult struct {
+	AuthsScanned        int // Auth rows inspected.
+	AuthsEncrypted      int // Auth rows whose content was encrypted.
+	ProviderKeysScanned int // Provider API key rows inspected.
+	ProviderKeysUpdated int // Provider API key rows with at least one encrypted field.
+}
+
+// EncryptSecrets encrypts plaintext auth content and provider API keys in place.
+// Rows are read and written through the table directly so model hooks do not interfere.
+// With dryRun set the rows are only counted.
+func EncryptSecrets(ctx context.Context, conn *gorm.DB, c *crypto.Cipher, dryRun bool) (EncryptSecretsResult, error) {
+	var result EncryptSecretsResult
+	if conn == nil {
+		return result, errors.New("db: nil connection")
+	}
+	if !c.Enabled() {
+		return result, errors.New("db: encryption is not enabled")
+	}
+
+	type authRow struct {
+		ID      uint64
+		Content datatypes.JSON
+	}
+	var lastAuthID uint64
+	for {
+		var rows []authRow
+		if errFind := conn.WithContext(ctx).Table("auths").
+			Select("id", "content").
+			Where("id > ?", lastAuthID).
+			Order("id ASC").
+			Limit(encryptSecretsBatchSize).
+			Scan(&rows).Error; errFind != nil {
+			return result, fmt.Errorf("db: load auths: %w", errFind)
+		}
+		if len(rows) == 0 {
+			break
+		}
+		for _, row := range rows {
+			lastAuthID = row.ID
+			result.AuthsScanned++
+			if len(row.Content) == 0 || crypto.IsEncryptedJSON(row.Content) {
+				continue
+			}
+			result.AuthsEncrypted++
+			if dryRun {
+				continue
+			}
+			sealed, errEncrypt := c.EncryptJSON(row.Content)
+			if errEncrypt != nil {
+				return result, fmt.Errorf("db: encrypt auth %d: %w", row.ID, errEncrypt)
+			}
+			if errUpdate := conn.WithContext(ctx).Table("auths").
+				Where("id = ?", row.ID).
+				Update("content", datatypes.JSON(sealed)).Error; errUpdate != nil {
+				return result, fmt.Errorf("db: update auth %d: %w", row.ID, errUpdate)
+			}
+		}
+	}
+
+	type providerKeyRow struct {
+		ID            uint64
+		APIKey        string
+		APIKeyEntries datatypes.JSON
+	}
+	var lastKeyID uint64
+	for {
+		var rows []providerKeyRow
+		if errFind := conn.WithContext(ctx).Table("provider_api_keys").
+			Select("id", "api_key", "api_key_entries").
+			Where("id > ?", lastKeyID).
+			Order("id ASC").
+			Limit(encryptSecretsBatchSize).
+			Scan(&rows).Error; errFind != nil {
+			return result, fmt.Errorf("db: load provider api keys: %w", errFind)
+		}
+		if len(rows) == 0 {
+			break
+		}
+		for _, row := range rows {
+			lastKeyID = row.ID
+			result.ProviderKeysScanned++
+			updates := map[string]any{}
+			if row.APIKey != "" && !crypto.IsEncrypted(row.APIKey) {
+				sealed, errEncrypt := c.EncryptString(row.APIKey)
+				if errEncrypt != nil {
+					return result, fmt.Errorf("db: encrypt provider api key %d: %w", row.ID, errEncrypt)
+				}
+				updates["api_key"] = sealed
+			}
+			if len(row.APIKeyEntries) > 0 && string(row.APIKeyEntries) != "null" && !crypto.IsEncryptedJSON(row.APIKeyEntries) {
+				sealed, errEncrypt := c.EncryptJSON(row.APIKeyEntries)
+				if errEncrypt != nil {
+					return result, fmt.Errorf("db: encrypt provider api key entries %d: %w", row.ID, errEncrypt)
+				}
+				updates["api_key_entries"] = datatypes.JSON(sealed)
+			}
+			if len(updates) == 0 {
+				continue
+			}
+			result.ProviderKeysUpdated++
+			if dryRun {
+				continue
+			}
+			if errUpdate := conn.WithContext(ctx).Table("provider_api_keys").
+				Where("id = ?", row.ID).
+				Updates(updates).Error; errUpdate != nil {
+				return result, fmt.Errorf("db: update provider api key %d: %w", row.ID, errUpdate)
+			}
+		}
+	}
+	return result, nil
+}
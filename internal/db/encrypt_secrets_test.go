@@ -0,0 +1,106 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/crypto"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func TestEncryptSecretsEncryptsExistingRows(t *testing.T) {
+	dsn := fmt.Sprintf("file:encrypt_secrets_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate: %v", errMigrate)
+	}
+	t.Cleanup(func() { crypto.SetDefault(nil) })
+
+	content := []byte(`{"type":"codex","access_token":"secret-token"}`)
+	auth := models.Auth{Key: "codex-1", Content: datatypes.JSON(content)}
+	if errCreate := conn.Create(&auth).Error; errCreate != nil {
+		t.Fatalf("create auth: %v", errCreate)
+	}
+	key := models.ProviderAPIKey{Provider: "openai", APIKey: "sk-plain"}
+	if errCreate := conn.Create(&key).Error; errCreate != nil {
+		t.Fatalf("create provider key: %v", errCreate)
+	}
+
+	wrapper, errWrapper := crypto.NewLocalKeyWrapper("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	if errWrapper != nil {
+		t.Fatalf("new wrapper: %v", errWrapper)
+	}
+	cipher := crypto.NewCipher(wrapper, true)
+	crypto.SetDefault(cipher)
+
+	result, errEncrypt := EncryptSecrets(context.Background(), conn, cipher, false)
+	if errEncrypt != nil {
+		t.Fatalf("encrypt secrets: %v", errEncrypt)
+	}
+	if result.AuthsEncrypted != 1 || result.ProviderKeysUpdated != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	var raw struct {
+		Content string
+	}
+	if errRaw := conn.Table("auths").Select("content").Where("id = ?", auth.ID).Scan(&raw).Error; errRaw != nil {
+		t.Fatalf("load raw auth: %v", errRaw)
+	}
+	if !crypto.IsEncryptedJSON([]byte(raw.Content)) {
+		t.Fatalf("expected stored content to be encrypted, got %s", raw.Content)
+	}
+
+	var loaded models.Auth
+	if errFind := conn.First(&loaded, auth.ID).Error; errFind != nil {
+		t.Fatalf("load auth: %v", errFind)
+	}
+	if !bytes.Equal(loaded.Content, content) {
+		t.Fatalf("expected decrypted content, got %s", loaded.Content)
+	}
+	var loadedKey models.ProviderAPIKey
+	if errFind := conn.First(&loadedKey, key.ID).Error; errFind != nil {
+		t.Fatalf("load provider key: %v", errFind)
+	}
+	if loadedKey.APIKey != "sk-plain" {
+		t.Fatalf("expected decrypted api key, got %q", loadedKey.APIKey)
+	}
+
+	// New writes through the model are encrypted by the hooks.
+	if errUpdate := conn.Model(&models.ProviderAPIKey{}).Where("id = ?", key.ID).
+		Updates(map[string]any{"api_key": "sk-rotated"}).Error; errUpdate != nil {
+		t.Fatalf("update provider key: %v", errUpdate)
+	}
+	var rawKey struct {
+		APIKey string
+	}
+	if errRaw := conn.Table("provider_api_keys").Select("api_key").Where("id = ?", key.ID).Scan(&rawKey).Error; errRaw != nil {
+		t.Fatalf("load raw provider key: %v", errRaw)
+	}
+	if !crypto.IsEncrypted(rawKey.APIKey) {
+		t.Fatalf("expected hook to encrypt api key, got %q", rawKey.APIKey)
+	}
+
+	created := models.Auth{Key: "codex-2", Content: datatypes.JSON(content)}
+	if errCreate := conn.Create(&created).Error; errCreate != nil {
+		t.Fatalf("create encrypted auth: %v", errCreate)
+	}
+	if !bytes.Equal(created.Content, content) {
+		t.Fatalf("expected in-memory content to stay plaintext, got %s", created.Content)
+	}
+	if errRaw := conn.Table("auths").Select("content").Where("id = ?", created.ID).Scan(&raw).Error; errRaw != nil {
+		t.Fatalf("load raw created auth: %v", errRaw)
+	}
+	if !crypto.IsEncryptedJSON([]byte(raw.Content)) {
+		t.Fatalf("expected created content to be encrypted, got %s", raw.Content)
+	}
+}
@@ -11,16 +11,18 @@ import (
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// Migrate runs database migrations for the current dialect.
-func Migrate(conn *gorm.DB) error {
-	if conn == nil {
-		return fmt.Errorf("db: nil connection")
-	}
+// upgradeLegacySchema brings a database created before schema versioning to the baseline of
+// the versioned migrations for the current dialect. It is idempotent, so databases already at
+// the baseline are left unchanged.
+func upgradeLegacySchema(conn *gorm.DB) error {
 	switch DialectName(conn) {
 	case DialectSQLite:
 		return migrateSQLite(conn)
+	case DialectMySQL:
+		return migrateMySQL(conn)
 	case DialectPostgres, "":
 		return migratePostgres(conn)
 	default:
@@ -28,6 +30,65 @@ func Migrate(conn *gorm.DB) error {
 	}
 }
 
+// migrationModels lists every model of the baseline schema, shared by all dialects.
+func migrationModels() []any {
+	return []any{
+		&models.Admin{},
+		&models.Plan{},
+		&models.UserGroup{},
+		&models.AuthGroup{},
+		&models.User{},
+		&models.Auth{},
+		&models.Quota{},
+		&models.APIKey{},
+		&models.Usage{},
+		&models.Bill{},
+		&models.BillingRule{},
+		&models.ModelMapping{},
+		&models.ModelReference{},
+		&models.UserModelAuthBinding{},
+		&models.ModelPayloadRule{},
+		&models.ProviderAPIKey{},
+		&models.Proxy{},
+		&models.PrepaidCard{},
+		&models.Setting{},
+		&models.AuditLog{},
+		&models.TierUpgradeRule{},
+		&models.TierUpgrade{},
+		&models.UserGroupMigration{},
+		&models.KPISnapshot{},
+		&models.Node{},
+		&models.ModelDisplay{},
+		&models.UsageDaily{},
+		&models.UsageHourly{},
+		&models.ProviderHealthCheck{},
+		&models.StatsCursor{},
+		&models.BulkDeleteJob{},
+		&models.Invoice{},
+		&models.CoopContribution{},
+		&models.BalanceTransaction{},
+		&models.BillingRuleSnapshot{},
+		&models.CostReplayJob{},
+		&models.ExchangeRate{},
+		&models.AuthImportConflict{},
+		&models.AdminNotification{},
+		&models.UsageBadge{},
+		&models.SLO{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.EmailToken{},
+		&models.MFARecoveryCode{},
+		&models.MFASession{},
+		&models.AdminRole{},
+		&models.AdminRoleAssignment{},
+		&models.UserIdentity{},
+		&models.Invitation{},
+		&models.InvitationRedemption{},
+		&models.RequestLog{},
+		&models.ClusterInstance{},
+	}
+}
+
 // migratePostgres applies PostgreSQL-specific schema updates and indexes.
 func migratePostgres(conn *gorm.DB) error {
 	if errRename := conn.Exec(`
@@ -48,27 +109,7 @@ func migratePostgres(conn *gorm.DB) error {
 		return errPreUserGroup
 	}
 
-	if errAutoMigrate := conn.AutoMigrate(
-		&models.Admin{},
-		&models.Plan{},
-		&models.UserGroup{},
-		&models.AuthGroup{},
-		&models.User{},
-		&models.Auth{},
-		&models.Quota{},
-		&models.APIKey{},
-		&models.Usage{},
-		&models.Bill{},
-		&models.BillingRule{},
-		&models.ModelMapping{},
-		&models.ModelReference{},
-		&models.UserModelAuthBinding{},
-		&models.ModelPayloadRule{},
-		&models.ProviderAPIKey{},
-		&models.Proxy{},
-		&models.PrepaidCard{},
-		&models.Setting{},
-	); errAutoMigrate != nil {
+	if errAutoMigrate := conn.AutoMigrate(migrationModels()...); errAutoMigrate != nil {
 		return fmt.Errorf("db: migrate: %w", errAutoMigrate)
 	}
 	if errTokenHealth := ensureAuthTokenHealthColumnsPostgres(conn); errTokenHealth != nil {
@@ -113,25 +154,7 @@ func migratePostgres(conn *gorm.DB) error {
 	`).Error; errUsageRequestID != nil {
 		return fmt.Errorf("db: add usage request_id: %w", errUsageRequestID)
 	}
-	if errSeed := ensureDefaultGroups(conn); errSeed != nil {
-		return errSeed
-	}
-	if errSeed := ensureOnlyMappedModelsSetting(conn); errSeed != nil {
-		return errSeed
-	}
-	if errSeed := ensureQuotaPollSettings(conn); errSeed != nil {
-		return errSeed
-	}
-	if errSeed := ensureAutoAssignProxySetting(conn); errSeed != nil {
-		return errSeed
-	}
-	if errSeed := ensureRateLimitSetting(conn); errSeed != nil {
-		return errSeed
-	}
-	if errSeed := ensureUsagesRetentionSetting(conn); errSeed != nil {
-		return errSeed
-	}
-	if errSeed := ensureOAuthCallbackHostSetting(conn); errSeed != nil {
+	if errSeed := seedBaseline(conn); errSeed != nil {
 		return errSeed
 	}
 	if errAuthGroup := migrateAuthGroupIDsPostgres(conn); errAuthGroup != nil {
@@ -364,8 +387,6 @@ func migratePostgres(conn *gorm.DB) error {
 		return fmt.Errorf("db: add models model_id: %w", errModelIDAdd)
 	}
 
-	_ = conn.Exec(`CREATE EXTENSION IF NOT EXISTS pg_trgm`).Error
-
 	// ddl defines an index or DDL statement to apply.
 	type ddl struct {
 		name string // Human-readable name for error reporting.
@@ -672,210 +693,7 @@ func migratePostgres(conn *gorm.DB) error {
 		}
 	}
 
-	// trgmIndex defines trigram and fallback index statements.
-	type trgmIndex struct {
-		name     string // Logical index name.
-		trgmSQL  string // Trigram index SQL.
-		lowerSQL string // Lowercase fallback index SQL.
-	}
-	trgmIndexes := []trgmIndex{
-		{
-			name: "idx_auths_key",
-			trgmSQL: `
-				CREATE INDEX IF NOT EXISTS idx_auths_key_trgm
-				ON auths USING gin (key gin_trgm_ops)
-			`,
-			lowerSQL: `
-				CREATE INDEX IF NOT EXISTS idx_auths_key_lower
-				ON auths (LOWER(key))
-			`,
-		},
-		{
-			name: "idx_users_username",
-			trgmSQL: `
-				CREATE INDEX IF NOT EXISTS idx_users_username_trgm
-				ON users USING gin (username gin_trgm_ops)
-			`,
-			lowerSQL: `
-				CREATE INDEX IF NOT EXISTS idx_users_username_lower
-				ON users (LOWER(username))
-			`,
-		},
-		{
-			name: "idx_users_email",
-			trgmSQL: `
-				CREATE INDEX IF NOT EXISTS idx_users_email_trgm
-				ON users USING gin (email gin_trgm_ops)
-			`,
-			lowerSQL: `
-				CREATE INDEX IF NOT EXISTS idx_users_email_lower
-				ON users (LOWER(email))
-			`,
-		},
-		{
-			name: "idx_admins_username",
-			trgmSQL: `
-				CREATE INDEX IF NOT EXISTS idx_admins_username_trgm
-				ON admins USING gin (username gin_trgm_ops)
-			`,
-			lowerSQL: `
-				CREATE INDEX IF NOT EXISTS idx_admins_username_lower
-				ON admins (LOWER(username))
-			`,
-		},
-		{
-			name: "idx_user_groups_name",
-			trgmSQL: `
-				CREATE INDEX IF NOT EXISTS idx_user_groups_name_trgm
-				ON user_groups USING gin (name gin_trgm_ops)
-			`,
-			lowerSQL: `
-				CREATE INDEX IF NOT EXISTS idx_user_groups_name_lower
-				ON user_groups (LOWER(name))
-			`,
-		},
-		{
-			name: "idx_auth_groups_name",
-			trgmSQL: `
-				CREATE INDEX IF NOT EXISTS idx_auth_groups_name_trgm
-				ON auth_groups USING gin (name gin_trgm_ops)
-			`,
-			lowerSQL: `
-				CREATE INDEX IF NOT EXISTS idx_auth_groups_name_lower
-				ON auth_groups (LOWER(name))
-			`,
-		},
-		{
-			name: "idx_models_provider_name",
-			trgmSQL: `
-				CREATE INDEX IF NOT EXISTS idx_models_provider_name_trgm
-				ON models USING gin (provider_name gin_trgm_ops)
-			`,
-			lowerSQL: `
-				CREATE INDEX IF NOT EXISTS idx_models_provider_name_lower
-				ON models (LOWER(provider_name))
-			`,
-		},
-		{
-			name: "idx_models_model_name",
-			trgmSQL: `
-				CREATE INDEX IF NOT EXISTS idx_models_model_name_trgm
-				ON models USING gin (model_name gin_trgm_ops)
-			`,
-			lowerSQL: `
-				CREATE INDEX IF NOT EXISTS idx_models_model_name_lower
-				ON models (LOWER(model_name))
-			`,
-		},
-		{
-			name: "idx_models_model_id",
-			trgmSQL: `
-				CREATE INDEX IF NOT EXISTS idx_models_model_id_trgm
-				ON models USING gin (model_id gin_trgm_ops)
-			`,
-			lowerSQL: `
-				CREATE INDEX IF NOT EXISTS idx_models_model_id_lower
-				ON models (LOWER(model_id))
-			`,
-		},
-		{
-			name: "idx_models_provider_model_id",
-			trgmSQL: `
-				CREATE INDEX IF NOT EXISTS idx_models_provider_model_id_trgm
-				ON models USING gin ((provider_name || ' ' || model_id) gin_trgm_ops)
-			`,
-			lowerSQL: `
-				CREATE INDEX IF NOT EXISTS idx_models_provider_model_id_lower
-				ON models (LOWER(provider_name), LOWER(model_id))
-			`,
-		},
-		{
-			name: "idx_prepaid_cards_name",
-			trgmSQL: `
-				CREATE INDEX IF NOT EXISTS idx_prepaid_cards_name_trgm
-				ON prepaid_cards USING gin (name gin_trgm_ops)
-			`,
-			lowerSQL: `
-				CREATE INDEX IF NOT EXISTS idx_prepaid_cards_name_lower
-				ON prepaid_cards (LOWER(name))
-			`,
-		},
-		{
-			name: "idx_prepaid_cards_card_sn",
-			trgmSQL: `
-				CREATE INDEX IF NOT EXISTS idx_prepaid_cards_card_sn_trgm
-				ON prepaid_cards USING gin (card_sn gin_trgm_ops)
-			`,
-			lowerSQL: `
-				CREATE INDEX IF NOT EXISTS idx_prepaid_cards_card_sn_lower
-				ON prepaid_cards (LOWER(card_sn))
-			`,
-		},
-		{
-			name: "idx_api_keys_name",
-			trgmSQL: `
-				CREATE INDEX IF NOT EXISTS idx_api_keys_name_trgm
-				ON api_keys USING gin (LOWER(name) gin_trgm_ops)
-			`,
-			lowerSQL: `
-				CREATE INDEX IF NOT EXISTS idx_api_keys_name_lower
-				ON api_keys (LOWER(name))
-			`,
-		},
-		{
-			name: "idx_api_keys_api_key",
-			trgmSQL: `
-				CREATE INDEX IF NOT EXISTS idx_api_keys_api_key_trgm
-				ON api_keys USING gin (LOWER(api_key) gin_trgm_ops)
-			`,
-			lowerSQL: `
-				CREATE INDEX IF NOT EXISTS idx_api_keys_api_key_lower
-				ON api_keys (LOWER(api_key))
-			`,
-		},
-		{
-			name: "idx_provider_api_keys_name",
-			trgmSQL: `
-				CREATE INDEX IF NOT EXISTS idx_provider_api_keys_name_trgm
-				ON provider_api_keys USING gin (LOWER(name) gin_trgm_ops)
-			`,
-			lowerSQL: `
-				CREATE INDEX IF NOT EXISTS idx_provider_api_keys_name_lower
-				ON provider_api_keys (LOWER(name))
-			`,
-		},
-		{
-			name: "idx_provider_api_keys_api_key",
-			trgmSQL: `
-				CREATE INDEX IF NOT EXISTS idx_provider_api_keys_api_key_trgm
-				ON provider_api_keys USING gin (LOWER(api_key) gin_trgm_ops)
-			`,
-			lowerSQL: `
-				CREATE INDEX IF NOT EXISTS idx_provider_api_keys_api_key_lower
-				ON provider_api_keys (LOWER(api_key))
-			`,
-		},
-		{
-			name: "idx_proxies_proxy_url",
-			trgmSQL: `
-				CREATE INDEX IF NOT EXISTS idx_proxies_proxy_url_trgm
-				ON proxies USING gin (LOWER(proxy_url) gin_trgm_ops)
-			`,
-			lowerSQL: `
-				CREATE INDEX IF NOT EXISTS idx_proxies_proxy_url_lower
-				ON proxies (LOWER(proxy_url))
-			`,
-		},
-	}
-	for _, item := range trgmIndexes {
-		if errIdx := conn.Exec(item.trgmSQL).Error; errIdx != nil {
-			if errLower := conn.Exec(item.lowerSQL).Error; errLower != nil {
-				return fmt.Errorf("db: create index %s: %w", item.name, errLower)
-			}
-		}
-	}
-
-	return nil
+	return createSearchIndexesPostgres(conn)
 }
 
 // migrateSQLite applies SQLite-specific schema updates and indexes.
@@ -884,31 +702,11 @@ func migrateSQLite(conn *gorm.DB) error {
 		return errFix
 	}
 
-	if errRename := renameTableIfNeeded(conn, "recharge_cards", "prepaid_cards"); errRename != nil {
-		return fmt.Errorf("db: rename recharge_cards: %w", errRename)
-	}
-
-	if errAutoMigrate := conn.AutoMigrate(
-		&models.Admin{},
-		&models.Plan{},
-		&models.UserGroup{},
-		&models.AuthGroup{},
-		&models.User{},
-		&models.Auth{},
-		&models.Quota{},
-		&models.APIKey{},
-		&models.Usage{},
-		&models.Bill{},
-		&models.BillingRule{},
-		&models.ModelMapping{},
-		&models.ModelReference{},
-		&models.UserModelAuthBinding{},
-		&models.ModelPayloadRule{},
-		&models.ProviderAPIKey{},
-		&models.Proxy{},
-		&models.PrepaidCard{},
-		&models.Setting{},
-	); errAutoMigrate != nil {
+	if errRename := renameTableIfNeeded(conn, "recharge_cards", "prepaid_cards"); errRename != nil {
+		return fmt.Errorf("db: rename recharge_cards: %w", errRename)
+	}
+
+	if errAutoMigrate := conn.AutoMigrate(migrationModels()...); errAutoMigrate != nil {
 		return fmt.Errorf("db: migrate: %w", errAutoMigrate)
 	}
 	migrator := conn.Migrator()
@@ -974,25 +772,7 @@ func migrateSQLite(conn *gorm.DB) error {
 			return fmt.Errorf("db: add usage request_id: %w", errUsageRequestID)
 		}
 	}
-	if errSeed := ensureDefaultGroups(conn); errSeed != nil {
-		return errSeed
-	}
-	if errSeed := ensureOnlyMappedModelsSetting(conn); errSeed != nil {
-		return errSeed
-	}
-	if errSeed := ensureQuotaPollSettings(conn); errSeed != nil {
-		return errSeed
-	}
-	if errSeed := ensureAutoAssignProxySetting(conn); errSeed != nil {
-		return errSeed
-	}
-	if errSeed := ensureRateLimitSetting(conn); errSeed != nil {
-		return errSeed
-	}
-	if errSeed := ensureUsagesRetentionSetting(conn); errSeed != nil {
-		return errSeed
-	}
-	if errSeed := ensureOAuthCallbackHostSetting(conn); errSeed != nil {
+	if errSeed := seedBaseline(conn); errSeed != nil {
 		return errSeed
 	}
 	if errAuthGroup := migrateAuthGroupIDsSQLite(conn); errAuthGroup != nil {
@@ -1292,6 +1072,296 @@ func migrateSQLite(conn *gorm.DB) error {
 	return nil
 }
 
+// createSearchIndexesPostgres creates the trigram indexes behind substring search, falling
+// back to lowercase indexes where the pg_trgm extension is unavailable.
+func createSearchIndexesPostgres(conn *gorm.DB) error {
+	_ = conn.Exec(`CREATE EXTENSION IF NOT EXISTS pg_trgm`).Error
+
+	// trgmIndex defines trigram and fallback index statements.
+	type trgmIndex struct {
+		name     string // Logical index name.
+		trgmSQL  string // Trigram index SQL.
+		lowerSQL string // Lowercase fallback index SQL.
+	}
+	trgmIndexes := []trgmIndex{
+		{
+			name: "idx_auths_key",
+			trgmSQL: `
+				CREATE INDEX IF NOT EXISTS idx_auths_key_trgm
+				ON auths USING gin (key gin_trgm_ops)
+			`,
+			lowerSQL: `
+				CREATE INDEX IF NOT EXISTS idx_auths_key_lower
+				ON auths (LOWER(key))
+			`,
+		},
+		{
+			name: "idx_users_username",
+			trgmSQL: `
+				CREATE INDEX IF NOT EXISTS idx_users_username_trgm
+				ON users USING gin (username gin_trgm_ops)
+			`,
+			lowerSQL: `
+				CREATE INDEX IF NOT EXISTS idx_users_username_lower
+				ON users (LOWER(username))
+			`,
+		},
+		{
+			name: "idx_users_email",
+			trgmSQL: `
+				CREATE INDEX IF NOT EXISTS idx_users_email_trgm
+				ON users USING gin (email gin_trgm_ops)
+			`,
+			lowerSQL: `
+				CREATE INDEX IF NOT EXISTS idx_users_email_lower
+				ON users (LOWER(email))
+			`,
+		},
+		{
+			name: "idx_admins_username",
+			trgmSQL: `
+				CREATE INDEX IF NOT EXISTS idx_admins_username_trgm
+				ON admins USING gin (username gin_trgm_ops)
+			`,
+			lowerSQL: `
+				CREATE INDEX IF NOT EXISTS idx_admins_username_lower
+				ON admins (LOWER(username))
+			`,
+		},
+		{
+			name: "idx_user_groups_name",
+			trgmSQL: `
+				CREATE INDEX IF NOT EXISTS idx_user_groups_name_trgm
+				ON user_groups USING gin (name gin_trgm_ops)
+			`,
+			lowerSQL: `
+				CREATE INDEX IF NOT EXISTS idx_user_groups_name_lower
+				ON user_groups (LOWER(name))
+			`,
+		},
+		{
+			name: "idx_auth_groups_name",
+			trgmSQL: `
+				CREATE INDEX IF NOT EXISTS idx_auth_groups_name_trgm
+				ON auth_groups USING gin (name gin_trgm_ops)
+			`,
+			lowerSQL: `
+				CREATE INDEX IF NOT EXISTS idx_auth_groups_name_lower
+				ON auth_groups (LOWER(name))
+			`,
+		},
+		{
+			name: "idx_models_provider_name",
+			trgmSQL: `
+				CREATE INDEX IF NOT EXISTS idx_models_provider_name_trgm
+				ON models USING gin (provider_name gin_trgm_ops)
+			`,
+			lowerSQL: `
+				CREATE INDEX IF NOT EXISTS idx_models_provider_name_lower
+				ON models (LOWER(provider_name))
+			`,
+		},
+		{
+			name: "idx_models_model_name",
+			trgmSQL: `
+				CREATE INDEX IF NOT EXISTS idx_models_model_name_trgm
+				ON models USING gin (model_name gin_trgm_ops)
+			`,
+			lowerSQL: `
+				CREATE INDEX IF NOT EXISTS idx_models_model_name_lower
+				ON models (LOWER(model_name))
+			`,
+		},
+		{
+			name: "idx_models_model_id",
+			trgmSQL: `
+				CREATE INDEX IF NOT EXISTS idx_models_model_id_trgm
+				ON models USING gin (model_id gin_trgm_ops)
+			`,
+			lowerSQL: `
+				CREATE INDEX IF NOT EXISTS idx_models_model_id_lower
+				ON models (LOWER(model_id))
+			`,
+		},
+		{
+			name: "idx_models_provider_model_id",
+			trgmSQL: `
+				CREATE INDEX IF NOT EXISTS idx_models_provider_model_id_trgm
+				ON models USING gin ((provider_name || ' ' || model_id) gin_trgm_ops)
+			`,
+			lowerSQL: `
+				CREATE INDEX IF NOT EXISTS idx_models_provider_model_id_lower
+				ON models (LOWER(provider_name), LOWER(model_id))
+			`,
+		},
+		{
+			name: "idx_prepaid_cards_name",
+			trgmSQL: `
+				CREATE INDEX IF NOT EXISTS idx_prepaid_cards_name_trgm
+				ON prepaid_cards USING gin (name gin_trgm_ops)
+			`,
+			lowerSQL: `
+				CREATE INDEX IF NOT EXISTS idx_prepaid_cards_name_lower
+				ON prepaid_cards (LOWER(name))
+			`,
+		},
+		{
+			name: "idx_prepaid_cards_card_sn",
+			trgmSQL: `
+				CREATE INDEX IF NOT EXISTS idx_prepaid_cards_card_sn_trgm
+				ON prepaid_cards USING gin (card_sn gin_trgm_ops)
+			`,
+			lowerSQL: `
+				CREATE INDEX IF NOT EXISTS idx_prepaid_cards_card_sn_lower
+				ON prepaid_cards (LOWER(card_sn))
+			`,
+		},
+		{
+			name: "idx_api_keys_name",
+			trgmSQL: `
+				CREATE INDEX IF NOT EXISTS idx_api_keys_name_trgm
+				ON api_keys USING gin (LOWER(name) gin_trgm_ops)
+			`,
+			lowerSQL: `
+				CREATE INDEX IF NOT EXISTS idx_api_keys_name_lower
+				ON api_keys (LOWER(name))
+			`,
+		},
+		{
+			name: "idx_api_keys_api_key",
+			trgmSQL: `
+				CREATE INDEX IF NOT EXISTS idx_api_keys_api_key_trgm
+				ON api_keys USING gin (LOWER(api_key) gin_trgm_ops)
+			`,
+			lowerSQL: `
+				CREATE INDEX IF NOT EXISTS idx_api_keys_api_key_lower
+				ON api_keys (LOWER(api_key))
+			`,
+		},
+		{
+			name: "idx_provider_api_keys_name",
+			trgmSQL: `
+				CREATE INDEX IF NOT EXISTS idx_provider_api_keys_name_trgm
+				ON provider_api_keys USING gin (LOWER(name) gin_trgm_ops)
+			`,
+			lowerSQL: `
+				CREATE INDEX IF NOT EXISTS idx_provider_api_keys_name_lower
+				ON provider_api_keys (LOWER(name))
+			`,
+		},
+		{
+			name: "idx_provider_api_keys_api_key",
+			trgmSQL: `
+				CREATE INDEX IF NOT EXISTS idx_provider_api_keys_api_key_trgm
+				ON provider_api_keys USING gin (LOWER(api_key) gin_trgm_ops)
+			`,
+			lowerSQL: `
+				CREATE INDEX IF NOT EXISTS idx_provider_api_keys_api_key_lower
+				ON provider_api_keys (LOWER(api_key))
+			`,
+		},
+		{
+			name: "idx_proxies_proxy_url",
+			trgmSQL: `
+				CREATE INDEX IF NOT EXISTS idx_proxies_proxy_url_trgm
+				ON proxies USING gin (LOWER(proxy_url) gin_trgm_ops)
+			`,
+			lowerSQL: `
+				CREATE INDEX IF NOT EXISTS idx_proxies_proxy_url_lower
+				ON proxies (LOWER(proxy_url))
+			`,
+		},
+	}
+	for _, item := range trgmIndexes {
+		if errIdx := conn.Exec(item.trgmSQL).Error; errIdx != nil {
+			if errLower := conn.Exec(item.lowerSQL).Error; errLower != nil {
+				return fmt.Errorf("db: create index %s: %w", item.name, errLower)
+			}
+		}
+	}
+
+	return nil
+}
+
+// seedBaseline inserts the default groups and settings the baseline schema starts with.
+func seedBaseline(conn *gorm.DB) error {
+	for _, seed := range []func(*gorm.DB) error{
+		ensureDefaultGroups,
+		ensureOnlyMappedModelsSetting,
+		ensureQuotaPollSettings,
+		ensureAutoAssignProxySetting,
+		ensureRateLimitSetting,
+		ensureUsagesRetentionSetting,
+		ensureOAuthCallbackHostSetting,
+		ensureAnalyticsAnonymizeSetting,
+		ensureChaosTestingSetting,
+		ensureRequireEmailVerificationSetting,
+		ensureDisplayCurrencySetting,
+	} {
+		if errSeed := seed(conn); errSeed != nil {
+			return errSeed
+		}
+	}
+	return nil
+}
+
+// migrateMySQL applies MySQL schema updates and indexes. MySQL deployments start from the
+// current schema, so the legacy column backfills of the other dialects are not needed.
+func migrateMySQL(conn *gorm.DB) error {
+	if errAutoMigrate := conn.AutoMigrate(migrationModels()...); errAutoMigrate != nil {
+		return fmt.Errorf("db: migrate: %w", errAutoMigrate)
+	}
+	if errSeed := seedBaseline(conn); errSeed != nil {
+		return errSeed
+	}
+
+	// mysqlIndex defines a composite index created when missing, as MySQL has no
+	// CREATE INDEX IF NOT EXISTS.
+	type mysqlIndex struct {
+		table   string // Table name.
+		name    string // Index name.
+		columns string // Column list, quoted where reserved.
+		unique  bool   // Whether the index is unique.
+	}
+	indexes := []mysqlIndex{
+		{table: "auths", name: "idx_auths_updated_at_id", columns: "updated_at DESC, id DESC"},
+		{table: "settings", name: "idx_settings_updated_at_key", columns: "updated_at DESC, `key` DESC"},
+		{table: "plans", name: "idx_plans_sort_order_created_at", columns: "sort_order ASC, created_at DESC"},
+		{table: "plans", name: "idx_plans_is_enabled_sort_order_created_at", columns: "is_enabled, sort_order ASC, created_at DESC"},
+		{table: "bills", name: "idx_bills_user_id_created_at", columns: "user_id, created_at DESC"},
+		{table: "bills", name: "idx_bills_plan_id_created_at", columns: "plan_id, created_at DESC"},
+		{table: "bills", name: "idx_bills_status_created_at", columns: "status, created_at DESC"},
+		{table: "bills", name: "idx_bills_is_enabled_created_at", columns: "is_enabled, created_at DESC"},
+		{table: "billing_rules", name: "idx_billing_rules_match", columns: "auth_group_id, user_group_id, is_enabled, provider, model"},
+		{table: "billing_rules", name: "idx_billing_rules_unique_key", columns: "auth_group_id, user_group_id, provider, model", unique: true},
+		{table: "model_mappings", name: "idx_model_mappings_provider_model_name_is_enabled", columns: "provider, model_name, is_enabled"},
+		{table: "model_mappings", name: "idx_model_mappings_provider_new_model_name_is_enabled", columns: "provider, new_model_name, is_enabled"},
+		{table: "prepaid_cards", name: "idx_prepaid_cards_redeemed_at", columns: "redeemed_at"},
+		{table: "prepaid_cards", name: "idx_prepaid_cards_redeemed_user_group", columns: "redeemed_user_id, user_group_id"},
+		{table: "api_keys", name: "idx_api_keys_user_id_created_at", columns: "user_id, created_at DESC"},
+		{table: "usages", name: "idx_usages_user_id_requested_at", columns: "user_id, requested_at DESC"},
+		{table: "usages", name: "idx_usages_user_id_charged_to_requested_at", columns: "user_id, charged_to, requested_at DESC"},
+		{table: "usages", name: "idx_usages_api_key_id_requested_at", columns: "api_key_id, requested_at DESC"},
+		{table: "usages", name: "idx_usages_user_id_model", columns: "user_id, model"},
+		{table: "usages", name: "idx_usages_user_id_provider_model", columns: "user_id, provider, model"},
+	}
+	migrator := conn.Migrator()
+	for _, item := range indexes {
+		if migrator.HasIndex(item.table, item.name) {
+			continue
+		}
+		kind := "INDEX"
+		if item.unique {
+			kind = "UNIQUE INDEX"
+		}
+		if errIdx := conn.Exec(fmt.Sprintf("CREATE %s %s ON %s (%s)", kind, item.name, item.table, item.columns)).Error; errIdx != nil {
+			return fmt.Errorf("db: create index %s: %w", item.name, errIdx)
+		}
+	}
+
+	return nil
+}
+
 func normalizeAndDeduplicateBillingRulesPostgres(conn *gorm.DB) error {
 	if conn == nil {
 		return fmt.Errorf("db: normalize billing rules: nil connection")
@@ -1965,7 +2035,7 @@ func ensureDefaultGroups(conn *gorm.DB) error {
 // ensureOnlyMappedModelsSetting ensures ONLY_MAPPED_MODELS exists and defaults to true.
 func ensureOnlyMappedModelsSetting(conn *gorm.DB) error {
 	var existing models.Setting
-	if errFind := conn.Where("key = ?", "ONLY_MAPPED_MODELS").First(&existing).Error; errFind == nil {
+	if errFind := conn.Where("? = ?", clause.Column{Name: "key"}, "ONLY_MAPPED_MODELS").First(&existing).Error; errFind == nil {
 		if len(existing.Value) == 0 || string(existing.Value) == "null" {
 			if errUpdate := conn.Model(&existing).Updates(map[string]any{
 				"value":      json.RawMessage("true"),
@@ -2029,6 +2099,26 @@ func ensureUsagesRetentionSetting(conn *gorm.DB) error {
 	return ensureIntSetting(conn, internalsettings.UsagesRetentionDaysKey, internalsettings.DefaultUsagesRetentionDays)
 }
 
+// ensureAnalyticsAnonymizeSetting ensures ANALYTICS_ANONYMIZE exists with defaults.
+func ensureAnalyticsAnonymizeSetting(conn *gorm.DB) error {
+	return ensureBoolSetting(conn, internalsettings.AnalyticsAnonymizeKey, internalsettings.DefaultAnalyticsAnonymize)
+}
+
+// ensureChaosTestingSetting ensures CHAOS_TESTING exists with defaults.
+func ensureChaosTestingSetting(conn *gorm.DB) error {
+	return ensureBoolSetting(conn, internalsettings.ChaosTestingKey, internalsettings.DefaultChaosTesting)
+}
+
+// ensureRequireEmailVerificationSetting ensures REQUIRE_EMAIL_VERIFICATION exists with defaults.
+func ensureRequireEmailVerificationSetting(conn *gorm.DB) error {
+	return ensureBoolSetting(conn, internalsettings.RequireEmailVerificationKey, internalsettings.DefaultRequireEmailVerification)
+}
+
+// ensureDisplayCurrencySetting ensures DISPLAY_CURRENCY exists with defaults.
+func ensureDisplayCurrencySetting(conn *gorm.DB) error {
+	return ensureStringSetting(conn, internalsettings.DisplayCurrencyKey, internalsettings.DefaultDisplayCurrency)
+}
+
 // ensureOAuthCallbackHostSetting ensures OAUTH_CALLBACK_HOST exists with defaults.
 func ensureOAuthCallbackHostSetting(conn *gorm.DB) error {
 	return ensureStringSetting(conn, internalsettings.OAuthCallbackHostKey, internalsettings.DefaultOAuthCallbackHost)
@@ -2043,7 +2133,7 @@ func ensureIntSetting(conn *gorm.DB, key string, value int) error {
 	rawValue := json.RawMessage(payload)
 
 	var existing models.Setting
-	if errFind := conn.Where("key = ?", key).First(&existing).Error; errFind == nil {
+	if errFind := conn.Where("? = ?", clause.Column{Name: "key"}, key).First(&existing).Error; errFind == nil {
 		trimmed := strings.TrimSpace(string(existing.Value))
 		if len(existing.Value) == 0 || trimmed == "" || trimmed == "null" {
 			if errUpdate := conn.Model(&existing).Updates(map[string]any{
@@ -2079,7 +2169,7 @@ func ensureBoolSetting(conn *gorm.DB, key string, value bool) error {
 	rawValue := json.RawMessage(payload)
 
 	var existing models.Setting
-	if errFind := conn.Where("key = ?", key).First(&existing).Error; errFind == nil {
+	if errFind := conn.Where("? = ?", clause.Column{Name: "key"}, key).First(&existing).Error; errFind == nil {
 		trimmed := strings.TrimSpace(string(existing.Value))
 		if len(existing.Value) == 0 || trimmed == "" || trimmed == "null" {
 			if errUpdate := conn.Model(&existing).Updates(map[string]any{
@@ -2115,7 +2205,7 @@ func ensureStringSetting(conn *gorm.DB, key string, value string) error {
 	rawValue := json.RawMessage(payload)
 
 	var existing models.Setting
-	if errFind := conn.Where("key = ?", key).First(&existing).Error; errFind == nil {
+	if errFind := conn.Where("? = ?", clause.Column{Name: "key"}, key).First(&existing).Error; errFind == nil {
 		trimmed := strings.TrimSpace(string(existing.Value))
 		if len(existing.Value) == 0 || trimmed == "" || trimmed == "null" || trimmed == `""` {
 			if errUpdate := conn.Model(&existing).Updates(map[string]any{
@@ -2296,6 +2386,17 @@ func rebuildSQLiteTableIfNeeded(conn *gorm.DB, model any) error {
 	if errRename := migrator.RenameTable(tableName, legacyName); errRename != nil {
 		return fmt.Errorf("db: rename sqlite table %s: %w", tableName, errRename)
 	}
+	// Indexes keep their names when the table is renamed; drop them so the new table can
+	// recreate them.
+	var legacyIndexes []string
+	if errIndexes := conn.Raw("SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL", legacyName).Scan(&legacyIndexes).Error; errIndexes != nil {
+		return fmt.Errorf("db: list sqlite indexes of %s: %w", legacyName, errIndexes)
+	}
+	for _, name := range legacyIndexes {
+		if errDrop := conn.Exec("DROP INDEX " + quoteSQLiteIdentifier(name)).Error; errDrop != nil {
+			return fmt.Errorf("db: drop sqlite index %s: %w", name, errDrop)
+		}
+	}
 
 	if errCreate := conn.Table(tableName).AutoMigrate(model); errCreate != nil {
 		return fmt.Errorf("db: recreate sqlite table %s: %w", tableName, errCreate)
@@ -0,0 +1,170 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+// mysqlIndexedTextType replaces text columns that carry an index, which MySQL cannot index
+// without a prefix length.
+const mysqlIndexedTextType = "VARCHAR(255)"
+
+// mysqlDialector adapts the PostgreSQL-flavoured column types in the model tags to MySQL.
+type mysqlDialector struct {
+	*mysql.Dialector
+}
+
+// newMySQLDialector wraps the gorm MySQL dialector for the given DSN.
+func newMySQLDialector(dsn string) gorm.Dialector {
+	return mysqlDialector{Dialector: mysql.New(mysql.Config{DSN: dsn}).(*mysql.Dialector)}
+}
+
+// Migrator returns a MySQL migrator that resolves column types through this dialector.
+func (d mysqlDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return mysqlMigrator{Migrator: mysql.Migrator{
+		Migrator: migrator.Migrator{
+			Config: migrator.Config{
+				DB:        db,
+				Dialector: d,
+			},
+		},
+		Dialector: *d.Dialector,
+	}}
+}
+
+// DataTypeOf maps jsonb, timestamptz, bytea and indexed text columns to MySQL types.
+func (d mysqlDialector) DataTypeOf(field *schema.Field) string {
+	switch strings.ToLower(string(field.DataType)) {
+	case "jsonb":
+		return "JSON"
+	case "timestamptz":
+		return "DATETIME(3)"
+	case "bytea":
+		return "BLOB"
+	case "text":
+		if isIndexedField(field) {
+			return mysqlIndexedTextType
+		}
+		return "TEXT"
+	}
+	return d.Dialector.DataTypeOf(field)
+}
+
+// isIndexedField reports whether the field is a key or part of an index.
+func isIndexedField(field *schema.Field) bool {
+	if field.PrimaryKey || field.Unique {
+		return true
+	}
+	for _, tag := range []string{"INDEX", "UNIQUEINDEX", "UNIQUE"} {
+		if _, ok := field.TagSettings[tag]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// mysqlMigrator rewrites column defaults MySQL only accepts as expressions.
+type mysqlMigrator struct {
+	mysql.Migrator
+}
+
+// FullDataTypeOf wraps literal defaults of TEXT, BLOB and JSON columns in parentheses, which
+// MySQL 8.0.13 and later accept, and matches CURRENT_TIMESTAMP to the datetime precision.
+func (m mysqlMigrator) FullDataTypeOf(field *schema.Field) clause.Expr {
+	expr := m.Migrator.FullDataTypeOf(field)
+	idx := strings.Index(expr.SQL, " DEFAULT ")
+	if idx < 0 {
+		return expr
+	}
+	head, value, tail := expr.SQL[:idx], expr.SQL[idx+len(" DEFAULT "):], ""
+	if end := strings.Index(value, " COMMENT "); end >= 0 {
+		value, tail = value[:end], value[end:]
+	}
+	dataType := strings.ToLower(m.Migrator.Migrator.DataTypeOf(field))
+	switch {
+	case strings.Contains(dataType, "text"), strings.Contains(dataType, "blob"), strings.Contains(dataType, "json"):
+		value = "(" + value + ")"
+	case strings.HasPrefix(dataType, "datetime(") && strings.EqualFold(value, "CURRENT_TIMESTAMP"):
+		value += strings.TrimPrefix(dataType, "datetime")
+	default:
+		return expr
+	}
+	expr.SQL = head + " DEFAULT " + value + tail
+	return expr
+}
+
+// normalizeMySQLDSN converts mysql:// URLs into driver DSNs and enforces time parsing in loc.
+func normalizeMySQLDSN(dsn string, loc *time.Location) (string, error) {
+	trimmed := strings.TrimSpace(dsn)
+	if strings.HasPrefix(strings.ToLower(trimmed), "mysql://") {
+		trimmed = trimmed[len("mysql://"):]
+		if !strings.Contains(trimmed, "@tcp(") && !strings.Contains(trimmed, "@unix(") {
+			trimmed = mysqlURLToDSN(trimmed)
+		}
+	}
+	cfg, errParse := mysqldriver.ParseDSN(trimmed)
+	if errParse != nil {
+		return "", fmt.Errorf("db: parse mysql dsn: %w", errParse)
+	}
+	cfg.ParseTime = true
+	if loc != nil {
+		cfg.Loc = loc
+	}
+	return cfg.FormatDSN(), nil
+}
+
+// mysqlURLToDSN rewrites "user:pass@host:port/db?params" into "user:pass@tcp(host:port)/db?params".
+func mysqlURLToDSN(url string) string {
+	credentials, rest := "", url
+	if idx := strings.LastIndex(url, "@"); idx >= 0 {
+		credentials, rest = url[:idx+1], url[idx+1:]
+	}
+	host, path := rest, "/"
+	if idx := strings.Index(rest, "/"); idx >= 0 {
+		host, path = rest[:idx], rest[idx:]
+	}
+	return credentials + "tcp(" + host + ")" + path
+}
+
+// openMySQL opens a MySQL connection with timezone handling.
+func openMySQL(dsn string) (*gorm.DB, error) {
+	tz := loadGlobalTimeZone()
+	normalized, errNormalize := normalizeMySQLDSN(dsn, tz.scanLocation)
+	if errNormalize != nil {
+		return nil, errNormalize
+	}
+
+	conn, err := gorm.Open(newMySQLDialector(normalized), &gorm.Config{
+		Logger: newGormLogger(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("db: open mysql: %w", err)
+	}
+
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return nil, fmt.Errorf("db: open mysql sql: %w", err)
+	}
+
+	sqlDB.SetMaxOpenConns(25)
+	sqlDB.SetMaxIdleConns(25)
+	sqlDB.SetConnMaxLifetime(30 * time.Minute)
+
+	pingCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if errPing := sqlDB.PingContext(pingCtx); errPing != nil {
+		_ = sqlDB.Close()
+		return nil, fmt.Errorf("db: ping: %w", errPing)
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,546 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/permcategory"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrSchemaTooNew reports a database migrated by a newer build than this binary.
+var ErrSchemaTooNew = errors.New("db: schema is newer than this build")
+
+// Migration is one ordered schema change. Down reverts Up; a nil Down makes the migration
+// irreversible. Migrations that only add tables list them in Models and leave Up and Down
+// nil to create and drop them.
+type Migration struct {
+	Version     int                  // Unique version, ascending in apply order.
+	Description string               // Human readable summary.
+	Models      []any                // Tables created by the migration.
+	Up          func(*gorm.DB) error // Applies the change.
+	Down        func(*gorm.DB) error // Reverts the change; nil when irreversible.
+}
+
+// up returns the apply function, defaulting to creating Models.
+func (m Migration) up() func(*gorm.DB) error {
+	if m.Up != nil || len(m.Models) == 0 {
+		return m.Up
+	}
+	return func(conn *gorm.DB) error { return conn.AutoMigrate(m.Models...) }
+}
+
+// down returns the revert function, defaulting to dropping Models.
+func (m Migration) down() func(*gorm.DB) error {
+	if m.Down != nil || len(m.Models) == 0 {
+		return m.Down
+	}
+	return func(conn *gorm.DB) error { return dropTables(conn, m.Models) }
+}
+
+// MigrationState describes a known migration and whether it is applied.
+type MigrationState struct {
+	Version     int        `json:"version"`
+	Description string     `json:"description"`
+	Applied     bool       `json:"applied"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"`
+	Reversible  bool       `json:"reversible"`
+}
+
+// migrations lists every schema migration in apply order. Schema changes after the baseline
+// get a new entry here instead of relying on AutoMigrate, so every deployment applies the
+// same DDL.
+func migrations() []Migration {
+	return []Migration{
+		{
+			Version:     1,
+			Description: "baseline schema",
+			Up:          migrateBaseline,
+		},
+		{
+			Version:     2,
+			Description: "user lifecycle events",
+			Models:      []any{&models.UserLifecycleEvent{}},
+		},
+		{
+			Version:     3,
+			Description: "proxy health",
+			Models:      []any{&models.ProxyHealth{}, &models.ProxyHealthCheck{}},
+		},
+		{
+			Version:     4,
+			Description: "proxy region labels",
+			Up: func(conn *gorm.DB) error {
+				// The frozen baseline already has the column; only databases upgraded from before versioning may lack it.
+				if conn.Migrator().HasColumn(&models.Proxy{}, "region") {
+					return nil
+				}
+				return conn.Migrator().AddColumn(&models.Proxy{}, "Region")
+			},
+			Down: func(conn *gorm.DB) error { return conn.Migrator().DropColumn(&models.Proxy{}, "Region") },
+		},
+		{
+			Version:     5,
+			Description: "usage proxy attribution",
+			Up:          addUsageProxyColumns,
+			Down: func(conn *gorm.DB) error {
+				migrator := conn.Migrator()
+				if migrator.HasIndex(&models.Usage{}, "ProxyHash") {
+					if errIndex := migrator.DropIndex(&models.Usage{}, "ProxyHash"); errIndex != nil {
+						return errIndex
+					}
+				}
+				if errHash := migrator.DropColumn(&models.Usage{}, "ProxyHash"); errHash != nil {
+					return errHash
+				}
+				return migrator.DropColumn(&models.Usage{}, "ProxyLabel")
+			},
+		},
+		{
+			Version:     6,
+			Description: "setting change history",
+			Models:      []any{&models.SettingChange{}},
+		},
+		{
+			Version:     7,
+			Description: "organizations",
+			Up:          addOrganizations,
+			Down: func(conn *gorm.DB) error {
+				migrator := conn.Migrator()
+				for _, model := range organizationScopedModels() {
+					if errDrop := migrator.DropColumn(model, "OrganizationID"); errDrop != nil {
+						return errDrop
+					}
+				}
+				return dropTables(conn, []any{&models.Organization{}})
+			},
+		},
+		{
+			Version:     8,
+			Description: "teams",
+			Models:      []any{&models.Team{}, &models.TeamMember{}},
+			Up:          addTeams,
+			Down: func(conn *gorm.DB) error {
+				migrator := conn.Migrator()
+				for _, column := range teamColumns() {
+					if errDrop := migrator.DropColumn(column.model, column.field); errDrop != nil {
+						return errDrop
+					}
+				}
+				return dropTables(conn, []any{&models.Team{}, &models.TeamMember{}})
+			},
+		},
+		{
+			Version:     9,
+			Description: "retry policies",
+			Models:      []any{&models.RetryPolicy{}},
+		},
+		{
+			Version:     10,
+			Description: "provider key canaries",
+			Up:          addProviderKeyCanaries,
+			Down: func(conn *gorm.DB) error {
+				migrator := conn.Migrator()
+				for _, column := range canaryColumns() {
+					if errDrop := migrator.DropColumn(column.model, column.field); errDrop != nil {
+						return errDrop
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Version:     11,
+			Description: "admin permission categories",
+			Up:          grantPermissionCategories,
+			Down:        revokePermissionCategories,
+		},
+		{
+			Version:     12,
+			Description: "auth re-authentication flows",
+			Models:      []any{&models.AuthReauth{}},
+		},
+		{
+			Version:     13,
+			Description: "usage idempotency keys",
+			Up:          addUsageIdempotencyKey,
+			Down: func(conn *gorm.DB) error {
+				migrator := conn.Migrator()
+				if migrator.HasIndex(&models.Usage{}, "IdempotencyKey") {
+					if errIndex := migrator.DropIndex(&models.Usage{}, "IdempotencyKey"); errIndex != nil {
+						return errIndex
+					}
+				}
+				return migrator.DropColumn(&models.Usage{}, "IdempotencyKey")
+			},
+		},
+	}
+}
+
+// addedColumn names a column added by a migration to an existing table.
+type addedColumn struct {
+	model any
+	field string
+}
+
+// teamColumns lists the team attribution columns on existing tables.
+func teamColumns() []addedColumn {
+	return []addedColumn{
+		{model: &models.APIKey{}, field: "TeamID"},
+		{model: &models.Usage{}, field: "TeamID"},
+		{model: &models.Usage{}, field: "TeamMemberID"},
+	}
+}
+
+// addTeams creates the team tables and the team attribution columns. The frozen baseline
+// already has the columns, so they are only added where missing.
+func addTeams(conn *gorm.DB) error {
+	if errTables := conn.AutoMigrate(&models.Team{}, &models.TeamMember{}); errTables != nil {
+		return errTables
+	}
+	migrator := conn.Migrator()
+	for _, column := range teamColumns() {
+		if !migrator.HasColumn(column.model, column.field) {
+			if errAdd := migrator.AddColumn(column.model, column.field); errAdd != nil {
+				return errAdd
+			}
+		}
+		if !migrator.HasIndex(column.model, column.field) {
+			if errIndex := migrator.CreateIndex(column.model, column.field); errIndex != nil {
+				return errIndex
+			}
+		}
+	}
+	return nil
+}
+
+// canaryColumns lists the columns added by the provider key canaries migration.
+func canaryColumns() []addedColumn {
+	return []addedColumn{
+		{model: &models.ProviderAPIKey{}, field: "CanaryPercent"},
+		{model: &models.ProviderAPIKey{}, field: "CanaryStartedAt"},
+		{model: &models.Usage{}, field: "ProviderAPIKeyID"},
+	}
+}
+
+// addProviderKeyCanaries adds the canary columns and indexes usage by provider key where the
+// baseline does not already have them.
+func addProviderKeyCanaries(conn *gorm.DB) error {
+	migrator := conn.Migrator()
+	for _, column := range canaryColumns() {
+		if !migrator.HasColumn(column.model, column.field) {
+			if errAdd := migrator.AddColumn(column.model, column.field); errAdd != nil {
+				return errAdd
+			}
+		}
+	}
+	if !migrator.HasIndex(&models.Usage{}, "ProviderAPIKeyID") {
+		return migrator.CreateIndex(&models.Usage{}, "ProviderAPIKeyID")
+	}
+	return nil
+}
+
+// organizationScopedModels lists the tables carrying an organization_id column.
+func organizationScopedModels() []any {
+	return []any{&models.User{}, &models.UserGroup{}, &models.Admin{}}
+}
+
+// addOrganizations creates the organizations table and the organization_id columns where the
+// baseline does not already have them.
+func addOrganizations(conn *gorm.DB) error {
+	if errTable := conn.AutoMigrate(&models.Organization{}); errTable != nil {
+		return errTable
+	}
+	migrator := conn.Migrator()
+	for _, model := range organizationScopedModels() {
+		if !migrator.HasColumn(model, "OrganizationID") {
+			if errAdd := migrator.AddColumn(model, "OrganizationID"); errAdd != nil {
+				return errAdd
+			}
+		}
+		if !migrator.HasIndex(model, "OrganizationID") {
+			if errIndex := migrator.CreateIndex(model, "OrganizationID"); errIndex != nil {
+				return errIndex
+			}
+		}
+	}
+	return nil
+}
+
+// addUsageProxyColumns adds the proxy attribution columns to usages where the baseline does
+// not already have them.
+func addUsageProxyColumns(conn *gorm.DB) error {
+	migrator := conn.Migrator()
+	for _, field := range []string{"ProxyHash", "ProxyLabel"} {
+		if migrator.HasColumn(&models.Usage{}, field) {
+			continue
+		}
+		if errAdd := migrator.AddColumn(&models.Usage{}, field); errAdd != nil {
+			return errAdd
+		}
+	}
+	if migrator.HasIndex(&models.Usage{}, "ProxyHash") {
+		return nil
+	}
+	return migrator.CreateIndex(&models.Usage{}, "ProxyHash")
+}
+
+// addUsageIdempotencyKey adds the unique usage idempotency key where it is missing.
+func addUsageIdempotencyKey(conn *gorm.DB) error {
+	migrator := conn.Migrator()
+	if !migrator.HasColumn(&models.Usage{}, "IdempotencyKey") {
+		if errAdd := migrator.AddColumn(&models.Usage{}, "IdempotencyKey"); errAdd != nil {
+			return errAdd
+		}
+	}
+	if migrator.HasIndex(&models.Usage{}, "IdempotencyKey") {
+		return nil
+	}
+	return migrator.CreateIndex(&models.Usage{}, "IdempotencyKey")
+}
+
+// bulkDeleteJobsRoute prefixes the bulk delete job routes, which are checked against the
+// category of the entity a job deletes rather than a category of their own.
+const bulkDeleteJobsRoute = " /v0/admin/bulk-delete-jobs"
+
+// grantPermissionCategories adds the category permissions now required next to route keys to
+// every admin and role already holding routes of that category, so existing grants keep working.
+// Holders of bulk delete routes get the categories of every entity they could delete before.
+func grantPermissionCategories(conn *gorm.DB) error {
+	return rewritePermissions(conn, func(perms []string) []string {
+		for _, perm := range perms {
+			if strings.Contains(perm, bulkDeleteJobsRoute) {
+				perms = append(perms, permcategory.Infrastructure, permcategory.Billing, permcategory.Analytics)
+				break
+			}
+		}
+		return append(perms, permcategory.Implied(perms)...)
+	})
+}
+
+// revokePermissionCategories removes the category permissions from admins and roles.
+func revokePermissionCategories(conn *gorm.DB) error {
+	return rewritePermissions(conn, func(perms []string) []string {
+		return slices.DeleteFunc(perms, func(perm string) bool {
+			return slices.Contains(permcategory.All(), perm)
+		})
+	})
+}
+
+// rewritePermissions applies change to the direct permissions of every admin and role,
+// writing only the rows it changes. Columns are updated without hooks or timestamps.
+func rewritePermissions(conn *gorm.DB, change func([]string) []string) error {
+	for _, model := range []any{&models.Admin{}, &models.AdminRole{}} {
+		var rows []struct {
+			ID          uint64
+			Permissions datatypes.JSON
+		}
+		if errFind := conn.Model(model).Select("id", "permissions").Find(&rows).Error; errFind != nil {
+			return errFind
+		}
+		for _, row := range rows {
+			var current []string
+			if errUnmarshal := json.Unmarshal(row.Permissions, &current); errUnmarshal != nil {
+				continue
+			}
+			current = normalizePermissionList(current)
+			next := normalizePermissionList(change(slices.Clone(current)))
+			if slices.Equal(current, next) {
+				continue
+			}
+			raw, errMarshal := json.Marshal(next)
+			if errMarshal != nil {
+				return errMarshal
+			}
+			if errUpdate := conn.Model(model).Where("id = ?", row.ID).UpdateColumn("permissions", datatypes.JSON(raw)).Error; errUpdate != nil {
+				return errUpdate
+			}
+		}
+	}
+	return nil
+}
+
+// normalizePermissionList trims, de-duplicates and sorts permission keys the way the admin
+// permission table stores them.
+func normalizePermissionList(perms []string) []string {
+	out := make([]string, 0, len(perms))
+	for _, perm := range perms {
+		if trimmed := strings.TrimSpace(perm); trimmed != "" {
+			out = append(out, trimmed)
+		}
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+// schemaModels lists the models of the baseline and of every later migration.
+func schemaModels() []any {
+	out := migrationModels()
+	for _, migration := range migrations() {
+		out = append(out, migration.Models...)
+	}
+	return out
+}
+
+// LatestSchemaVersion returns the highest migration version this build knows.
+func LatestSchemaVersion() int {
+	latest := 0
+	for _, migration := range migrations() {
+		if migration.Version > latest {
+			latest = migration.Version
+		}
+	}
+	return latest
+}
+
+// Migrate applies all pending migrations. It refuses to touch a database whose schema was
+// migrated by a newer build.
+func Migrate(conn *gorm.DB) error {
+	return MigrateTo(conn, LatestSchemaVersion())
+}
+
+// MigrateTo applies or reverts migrations until the schema is at target.
+func MigrateTo(conn *gorm.DB, target int) error {
+	if conn == nil {
+		return fmt.Errorf("db: nil connection")
+	}
+	latest := LatestSchemaVersion()
+	if target < 0 || target > latest {
+		return fmt.Errorf("db: migration target %d outside 0..%d", target, latest)
+	}
+	if errTable := ensureSchemaMigrationsTable(conn); errTable != nil {
+		return errTable
+	}
+	current, errVersion := SchemaVersion(conn)
+	if errVersion != nil {
+		return errVersion
+	}
+	if current > latest {
+		return fmt.Errorf("%w: database is at version %d, this build supports up to %d", ErrSchemaTooNew, current, latest)
+	}
+
+	all := migrations()
+	if target >= current {
+		for _, migration := range all {
+			if migration.Version <= current || migration.Version > target {
+				continue
+			}
+			if errUp := migration.up()(conn); errUp != nil {
+				return fmt.Errorf("db: migration %d (%s): %w", migration.Version, migration.Description, errUp)
+			}
+			if errRecord := conn.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.SchemaMigration{
+				Version:     migration.Version,
+				Description: migration.Description,
+				AppliedAt:   time.Now().UTC(),
+			}).Error; errRecord != nil {
+				return fmt.Errorf("db: record migration %d: %w", migration.Version, errRecord)
+			}
+		}
+		return nil
+	}
+
+	for i := len(all) - 1; i >= 0; i-- {
+		migration := all[i]
+		if migration.Version > current || migration.Version <= target {
+			continue
+		}
+		down := migration.down()
+		if down == nil {
+			return fmt.Errorf("db: migration %d (%s) is irreversible", migration.Version, migration.Description)
+		}
+		if errDown := down(conn); errDown != nil {
+			return fmt.Errorf("db: revert migration %d (%s): %w", migration.Version, migration.Description, errDown)
+		}
+		if errDelete := conn.Where("version = ?", migration.Version).Delete(&models.SchemaMigration{}).Error; errDelete != nil {
+			return fmt.Errorf("db: unrecord migration %d: %w", migration.Version, errDelete)
+		}
+	}
+	return nil
+}
+
+// SchemaVersion returns the highest applied migration version, or 0 for an unversioned database.
+func SchemaVersion(conn *gorm.DB) (int, error) {
+	if conn == nil {
+		return 0, fmt.Errorf("db: nil connection")
+	}
+	if !conn.Migrator().HasTable(&models.SchemaMigration{}) {
+		return 0, nil
+	}
+	var version *int
+	if errMax := conn.Model(&models.SchemaMigration{}).Select("MAX(version)").Scan(&version).Error; errMax != nil {
+		return 0, fmt.Errorf("db: read schema version: %w", errMax)
+	}
+	if version == nil {
+		return 0, nil
+	}
+	return *version, nil
+}
+
+// MigrationStatus lists known migrations with their applied state, plus applied versions
+// this build does not know.
+func MigrationStatus(conn *gorm.DB) ([]MigrationState, error) {
+	if conn == nil {
+		return nil, fmt.Errorf("db: nil connection")
+	}
+	applied := map[int]models.SchemaMigration{}
+	if conn.Migrator().HasTable(&models.SchemaMigration{}) {
+		var rows []models.SchemaMigration
+		if errFind := conn.Order("version ASC").Find(&rows).Error; errFind != nil {
+			return nil, fmt.Errorf("db: list schema migrations: %w", errFind)
+		}
+		for _, row := range rows {
+			applied[row.Version] = row
+		}
+	}
+
+	out := make([]MigrationState, 0, len(applied)+1)
+	for _, migration := range migrations() {
+		state := MigrationState{Version: migration.Version, Description: migration.Description, Reversible: migration.down() != nil}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			state.Applied, state.AppliedAt = true, &appliedAt
+			delete(applied, migration.Version)
+		}
+		out = append(out, state)
+	}
+	unknown := make([]int, 0, len(applied))
+	for version := range applied {
+		unknown = append(unknown, version)
+	}
+	sort.Ints(unknown)
+	for _, version := range unknown {
+		row := applied[version]
+		out = append(out, MigrationState{Version: row.Version, Description: row.Description, Applied: true, AppliedAt: &row.AppliedAt})
+	}
+	return out, nil
+}
+
+// ensureSchemaMigrationsTable creates the version bookkeeping table when missing.
+func ensureSchemaMigrationsTable(conn *gorm.DB) error {
+	migrator := conn.Migrator()
+	if migrator.HasTable(&models.SchemaMigration{}) {
+		return nil
+	}
+	if errCreate := migrator.CreateTable(&models.SchemaMigration{}); errCreate != nil {
+		return fmt.Errorf("db: create schema_migrations: %w", errCreate)
+	}
+	return nil
+}
+
+// dropTables drops the tables of the given models in reverse order.
+func dropTables(conn *gorm.DB, tables []any) error {
+	for i := len(tables) - 1; i >= 0; i-- {
+		if errDrop := conn.Migrator().DropTable(tables[i]); errDrop != nil {
+			return errDrop
+		}
+	}
+	return nil
+}
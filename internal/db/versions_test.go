@@ -0,0 +1,167 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func openVersionsTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:versions_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	return conn
+}
+
+func TestMigrateRecordsVersionsAndIsIdempotent(t *testing.T) {
+	conn := openVersionsTestDB(t)
+	for i := 0; i < 2; i++ {
+		if errMigrate := Migrate(conn); errMigrate != nil {
+			t.Fatalf("migrate run %d: %v", i+1, errMigrate)
+		}
+	}
+	version, errVersion := SchemaVersion(conn)
+	if errVersion != nil || version != LatestSchemaVersion() {
+		t.Fatalf("expected version %d, got %d %v", LatestSchemaVersion(), version, errVersion)
+	}
+	states, errStatus := MigrationStatus(conn)
+	if errStatus != nil {
+		t.Fatalf("status: %v", errStatus)
+	}
+	for _, state := range states {
+		if !state.Applied || state.AppliedAt == nil {
+			t.Fatalf("expected migration %d applied, got %+v", state.Version, state)
+		}
+	}
+}
+
+func TestMigrateAdoptsUnversionedDatabase(t *testing.T) {
+	conn := openVersionsTestDB(t)
+	if errBaseline := migrateBaseline(conn); errBaseline != nil {
+		t.Fatalf("baseline: %v", errBaseline)
+	}
+	if version, _ := SchemaVersion(conn); version != 0 {
+		t.Fatalf("expected unversioned database, got %d", version)
+	}
+	if errMigrate := Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate existing schema: %v", errMigrate)
+	}
+	if version, _ := SchemaVersion(conn); version != LatestSchemaVersion() {
+		t.Fatalf("expected version %d, got %d", LatestSchemaVersion(), version)
+	}
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	conn := openVersionsTestDB(t)
+	if errMigrate := Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate: %v", errMigrate)
+	}
+	future := models.SchemaMigration{Version: LatestSchemaVersion() + 1, Description: "from the future", AppliedAt: time.Now().UTC()}
+	if errCreate := conn.Create(&future).Error; errCreate != nil {
+		t.Fatalf("record future migration: %v", errCreate)
+	}
+
+	if errMigrate := Migrate(conn); !errors.Is(errMigrate, ErrSchemaTooNew) {
+		t.Fatalf("expected ErrSchemaTooNew, got %v", errMigrate)
+	}
+	states, errStatus := MigrationStatus(conn)
+	if errStatus != nil {
+		t.Fatalf("status: %v", errStatus)
+	}
+	if last := states[len(states)-1]; last.Version != future.Version || !last.Applied || last.Reversible {
+		t.Fatalf("expected unknown applied version listed last, got %+v", last)
+	}
+}
+
+func TestMigrateToRevertsAndReapplies(t *testing.T) {
+	conn := openVersionsTestDB(t)
+	if errMigrate := Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate: %v", errMigrate)
+	}
+	if errDown := MigrateTo(conn, 1); errDown != nil {
+		t.Fatalf("migrate down: %v", errDown)
+	}
+	if conn.Migrator().HasTable(&models.Organization{}) {
+		t.Fatal("expected organizations table dropped")
+	}
+	if version, _ := SchemaVersion(conn); version != 1 {
+		t.Fatalf("expected version 1, got %d", version)
+	}
+	if errTarget := MigrateTo(conn, LatestSchemaVersion()+1); errTarget == nil {
+		t.Fatal("expected unknown target to fail")
+	}
+	if errUp := MigrateTo(conn, LatestSchemaVersion()); errUp != nil {
+		t.Fatalf("migrate up: %v", errUp)
+	}
+	if !conn.Migrator().HasTable(&models.Organization{}) {
+		t.Fatal("expected organizations table recreated")
+	}
+}
+
+func TestMigrateRefusesToRevertBaseline(t *testing.T) {
+	conn := openVersionsTestDB(t)
+	if errMigrate := Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate: %v", errMigrate)
+	}
+	if errDown := MigrateTo(conn, 0); errDown == nil {
+		t.Fatal("expected reverting the baseline to fail")
+	}
+	if !conn.Migrator().HasTable(&models.User{}) {
+		t.Fatal("expected users table kept")
+	}
+	if version, _ := SchemaVersion(conn); version != 1 {
+		t.Fatalf("expected version 1, got %d", version)
+	}
+}
+
+func TestMigrateGrantsPermissionCategories(t *testing.T) {
+	conn := openVersionsTestDB(t)
+	if errMigrate := MigrateTo(conn, 10); errMigrate != nil {
+		t.Fatalf("migrate to 10: %v", errMigrate)
+	}
+	finance := models.Admin{Username: "finance", Password: "x", Permissions: datatypes.JSON(`["GET /v0/admin/bills","GET /v0/admin/users"]`)}
+	if errCreate := conn.Create(&finance).Error; errCreate != nil {
+		t.Fatalf("create admin: %v", errCreate)
+	}
+	role := models.AdminRole{Name: "ops", Permissions: datatypes.JSON(`["PUT /v0/admin/provider-api-keys/:id"]`)}
+	if errCreate := conn.Create(&role).Error; errCreate != nil {
+		t.Fatalf("create role: %v", errCreate)
+	}
+	cleanup := models.AdminRole{Name: "cleanup", Permissions: datatypes.JSON(`["POST /v0/admin/bulk-delete-jobs"]`)}
+	if errCreate := conn.Create(&cleanup).Error; errCreate != nil {
+		t.Fatalf("create role: %v", errCreate)
+	}
+
+	if errMigrate := Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate: %v", errMigrate)
+	}
+	conn.First(&finance, finance.ID)
+	conn.First(&role, role.ID)
+	if got := string(finance.Permissions); got != `["GET /v0/admin/bills","GET /v0/admin/users","accounts","billing"]` {
+		t.Fatalf("unexpected admin permissions %s", got)
+	}
+	if got := string(role.Permissions); got != `["PUT /v0/admin/provider-api-keys/:id","infrastructure"]` {
+		t.Fatalf("unexpected role permissions %s", got)
+	}
+	conn.First(&cleanup, cleanup.ID)
+	if got := string(cleanup.Permissions); got != `["POST /v0/admin/bulk-delete-jobs","analytics","billing","infrastructure"]` {
+		t.Fatalf("expected bulk delete holders to keep every entity, got %s", got)
+	}
+
+	if errDown := MigrateTo(conn, 10); errDown != nil {
+		t.Fatalf("revert: %v", errDown)
+	}
+	conn.First(&finance, finance.ID)
+	if got := string(finance.Permissions); got != `["GET /v0/admin/bills","GET /v0/admin/users"]` {
+		t.Fatalf("expected categories to be revoked, got %s", got)
+	}
+}
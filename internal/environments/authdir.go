@@ -0,0 +1,84 @@
+package environments
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// AuthDirResult summarizes one auth directory sync.
+type AuthDirResult struct {
+	Environment string `json:"environment"` // Environment tag.
+	AuthDir     string `json:"auth_dir"`    // Target directory.
+	Written     int    `json:"written"`     // Files created or changed.
+	Removed     int    `json:"removed"`     // Files removed because the auth left the environment.
+}
+
+// SyncAuthDir writes the available auth files tagged for env into dir and removes files
+// for known auths that no longer belong to it. Files not named after an auth key are left alone.
+func SyncAuthDir(ctx context.Context, db *gorm.DB, env, dir string) (AuthDirResult, error) {
+	result := AuthDirResult{Environment: env, AuthDir: dir}
+	if db == nil {
+		return result, errors.New("nil db")
+	}
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return result, nil
+	}
+	var rows []models.Auth
+	if errFind := db.WithContext(ctx).
+		Select("id", "key", "content", "is_available", "environments").
+		Order("id ASC").
+		Find(&rows).Error; errFind != nil {
+		return result, fmt.Errorf("load auths: %w", errFind)
+	}
+	if errMkdir := os.MkdirAll(dir, 0o700); errMkdir != nil {
+		return result, fmt.Errorf("create auth dir: %w", errMkdir)
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		name := AuthFileName(row.Key)
+		if name == "" {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if row.IsAvailable && len(row.Content) > 0 && Matches(Decode(row.Environments), env) {
+			existing, errRead := os.ReadFile(path)
+			if errRead == nil && bytes.Equal(existing, row.Content) {
+				continue
+			}
+			if errWrite := os.WriteFile(path, row.Content, 0o600); errWrite != nil {
+				return result, fmt.Errorf("write auth file %s: %w", name, errWrite)
+			}
+			result.Written++
+			continue
+		}
+		errRemove := os.Remove(path)
+		if errRemove == nil {
+			result.Removed++
+		} else if !os.IsNotExist(errRemove) {
+			return result, fmt.Errorf("remove auth file %s: %w", name, errRemove)
+		}
+	}
+	return result, nil
+}
+
+// AuthFileName maps an auth key to a file name inside the target auth dir.
+func AuthFileName(key string) string {
+	name := filepath.Base(strings.TrimSpace(key))
+	if name == "." || name == string(filepath.Separator) || name == "" {
+		return ""
+	}
+	if !strings.EqualFold(filepath.Ext(name), ".json") {
+		name += ".json"
+	}
+	return name
+}
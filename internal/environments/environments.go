@@ -0,0 +1,105 @@
+// Package environments tags provider keys and auth files with named environments
+// (prod, staging, dev, ...) so one panel can manage credentials for several CLIProxyAPI instances.
+package environments
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+)
+
+// namePattern restricts environment tags to short slug-like names.
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// Normalize lowercases, validates, de-duplicates and sorts environment tags.
+func Normalize(values []string) ([]string, error) {
+	seen := make(map[string]struct{}, len(values))
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		name := strings.ToLower(strings.TrimSpace(value))
+		if name == "" {
+			continue
+		}
+		if !namePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid environment %q", value)
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// Decode parses a stored environment tag list; invalid payloads decode as untagged.
+func Decode(value datatypes.JSON) []string {
+	if len(value) == 0 {
+		return []string{}
+	}
+	var tags []string
+	if errUnmarshal := json.Unmarshal(value, &tags); errUnmarshal != nil {
+		return []string{}
+	}
+	normalized, errNormalize := Normalize(tags)
+	if errNormalize != nil {
+		return []string{}
+	}
+	return normalized
+}
+
+// Marshal encodes environment tags for storage.
+func Marshal(tags []string) datatypes.JSON {
+	if len(tags) == 0 {
+		return datatypes.JSON("[]")
+	}
+	data, errMarshal := json.Marshal(tags)
+	if errMarshal != nil {
+		return datatypes.JSON("[]")
+	}
+	return datatypes.JSON(data)
+}
+
+// Matches reports whether a credential with tags belongs to env.
+// Untagged credentials are shared with every environment, and an instance
+// without an environment name serves every credential.
+func Matches(tags []string, env string) bool {
+	env = strings.ToLower(strings.TrimSpace(env))
+	if env == "" || len(tags) == 0 {
+		return true
+	}
+	for _, tag := range tags {
+		if tag == env {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterProviderKeys keeps the provider keys that belong to env.
+func FilterProviderKeys(rows []models.ProviderAPIKey, env string) []models.ProviderAPIKey {
+	out := make([]models.ProviderAPIKey, 0, len(rows))
+	for i := range rows {
+		if Matches(Decode(rows[i].Environments), env) {
+			out = append(out, rows[i])
+		}
+	}
+	return out
+}
+
+// FilterAuths keeps the auth records that belong to env.
+func FilterAuths(rows []models.Auth, env string) []models.Auth {
+	out := make([]models.Auth, 0, len(rows))
+	for i := range rows {
+		if Matches(Decode(rows[i].Environments), env) {
+			out = append(out, rows[i])
+		}
+	}
+	return out
+}
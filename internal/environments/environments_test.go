@@ -0,0 +1,118 @@
+package environments
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func setupEnvironmentsDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:environments_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func TestNormalizeAndMatches(t *testing.T) {
+	tags, errNormalize := Normalize([]string{" Prod ", "staging", "prod", ""})
+	if errNormalize != nil {
+		t.Fatalf("Normalize: %v", errNormalize)
+	}
+	if len(tags) != 2 || tags[0] != "prod" || tags[1] != "staging" {
+		t.Fatalf("unexpected tags: %v", tags)
+	}
+	if _, errNormalize = Normalize([]string{"bad env"}); errNormalize == nil {
+		t.Fatalf("expected invalid environment name to be rejected")
+	}
+
+	if !Matches(nil, "prod") {
+		t.Fatalf("untagged credentials must be shared with every environment")
+	}
+	if !Matches([]string{"staging"}, "") {
+		t.Fatalf("an instance without an environment must serve every credential")
+	}
+	if Matches([]string{"staging"}, "prod") {
+		t.Fatalf("staging credentials must not leak into prod")
+	}
+	if got := Decode(Marshal(tags)); len(got) != 2 {
+		t.Fatalf("expected round trip, got %v", got)
+	}
+}
+
+func TestSyncAuthDirWritesTaggedAuthsAndRemovesStaleFiles(t *testing.T) {
+	conn := setupEnvironmentsDB(t)
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "staging-auths")
+
+	rows := []models.Auth{
+		{Key: "shared.json", Content: datatypes.JSON(`{"type":"codex"}`), IsAvailable: true, Environments: Marshal(nil)},
+		{Key: "staging-only", Content: datatypes.JSON(`{"type":"claude"}`), IsAvailable: true, Environments: Marshal([]string{"staging"})},
+		{Key: "prod-only.json", Content: datatypes.JSON(`{"type":"gemini"}`), IsAvailable: true, Environments: Marshal([]string{"prod"})},
+	}
+	if errCreate := conn.Create(&rows).Error; errCreate != nil {
+		t.Fatalf("create auths: %v", errCreate)
+	}
+	if errMkdir := os.MkdirAll(dir, 0o700); errMkdir != nil {
+		t.Fatalf("mkdir: %v", errMkdir)
+	}
+	if errWrite := os.WriteFile(filepath.Join(dir, "prod-only.json"), []byte("{}"), 0o600); errWrite != nil {
+		t.Fatalf("seed stale file: %v", errWrite)
+	}
+	if errWrite := os.WriteFile(filepath.Join(dir, "unmanaged.json"), []byte("{}"), 0o600); errWrite != nil {
+		t.Fatalf("seed unmanaged file: %v", errWrite)
+	}
+
+	result, errSync := SyncAuthDir(ctx, conn, "staging", dir)
+	if errSync != nil {
+		t.Fatalf("SyncAuthDir: %v", errSync)
+	}
+	if result.Written != 2 || result.Removed != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	for _, name := range []string{"shared.json", "staging-only.json", "unmanaged.json"} {
+		if _, errStat := os.Stat(filepath.Join(dir, name)); errStat != nil {
+			t.Fatalf("expected %s to exist: %v", name, errStat)
+		}
+	}
+	if _, errStat := os.Stat(filepath.Join(dir, "prod-only.json")); !os.IsNotExist(errStat) {
+		t.Fatalf("expected prod-only.json to be removed, got %v", errStat)
+	}
+
+	result, errSync = SyncAuthDir(ctx, conn, "staging", dir)
+	if errSync != nil {
+		t.Fatalf("SyncAuthDir rerun: %v", errSync)
+	}
+	if result.Written != 0 || result.Removed != 0 {
+		t.Fatalf("expected unchanged rerun, got %+v", result)
+	}
+}
+
+func TestNewSyncerRequiresAuthDirTargets(t *testing.T) {
+	conn := setupEnvironmentsDB(t)
+	if NewSyncer(conn, config.EnvironmentsConfig{Targets: []config.EnvironmentTarget{{Name: "dev", ConfigPath: "/tmp/dev.yaml"}}}) != nil {
+		t.Fatalf("expected no syncer without auth dir targets")
+	}
+	s := NewSyncer(conn, config.EnvironmentsConfig{Targets: []config.EnvironmentTarget{{Name: "dev", AuthDir: t.TempDir()}}})
+	if s == nil {
+		t.Fatalf("expected syncer for auth dir target")
+	}
+	if errSync := s.SyncOnce(context.Background()); errSync != nil {
+		t.Fatalf("SyncOnce: %v", errSync)
+	}
+}
@@ -0,0 +1,134 @@
+package environments
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const defaultSyncInterval = 30 * time.Second
+
+// Syncer mirrors auth files into the auth dirs of configured environment targets.
+type Syncer struct {
+	db          *gorm.DB
+	targets     []config.EnvironmentTarget
+	interval    time.Duration
+	fingerprint string
+}
+
+// NewSyncer constructs an auth dir syncer; returns nil when db is nil or no target has an auth dir.
+func NewSyncer(db *gorm.DB, cfg config.EnvironmentsConfig) *Syncer {
+	if db == nil {
+		return nil
+	}
+	targets := make([]config.EnvironmentTarget, 0, len(cfg.Targets))
+	for _, target := range cfg.Targets {
+		if target.AuthDir != "" {
+			targets = append(targets, target)
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	return &Syncer{db: db, targets: targets, interval: defaultSyncInterval}
+}
+
+// Start launches the sync loop in a background goroutine.
+func (s *Syncer) Start(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go s.run(ctx)
+	log.Infof("environment auth syncer started (targets=%d interval=%s)", len(s.targets), s.interval)
+}
+
+func (s *Syncer) run(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		if errSync := s.SyncOnce(ctx); errSync != nil {
+			log.WithError(errSync).Warn("environment auth syncer: sync failed")
+		}
+		timer := time.NewTimer(s.interval)
+		select {
+		case <-ctx.Done():
+			if !timer.Stop() {
+				<-timer.C
+			}
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// SyncOnce syncs every target when auth records changed since the previous run.
+func (s *Syncer) SyncOnce(ctx context.Context) error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	fingerprint, errFingerprint := authFingerprint(ctx, s.db)
+	if errFingerprint != nil {
+		return errFingerprint
+	}
+	if fingerprint == s.fingerprint {
+		return nil
+	}
+	if _, errSync := SyncAuthDirs(ctx, s.db, s.targets); errSync != nil {
+		return errSync
+	}
+	s.fingerprint = fingerprint
+	return nil
+}
+
+// SyncAuthDirs syncs auth files into every target that has an auth dir.
+func SyncAuthDirs(ctx context.Context, db *gorm.DB, targets []config.EnvironmentTarget) ([]AuthDirResult, error) {
+	results := make([]AuthDirResult, 0, len(targets))
+	for _, target := range targets {
+		if target.AuthDir == "" {
+			continue
+		}
+		result, errSync := SyncAuthDir(ctx, db, target.Name, target.AuthDir)
+		if errSync != nil {
+			return results, fmt.Errorf("environment %s: %w", target.Name, errSync)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// authFingerprint changes whenever an auth row is added, updated or deleted.
+func authFingerprint(ctx context.Context, db *gorm.DB) (string, error) {
+	var row struct {
+		Count int64
+		MaxID uint64
+	}
+	if errScan := db.WithContext(ctx).
+		Model(&models.Auth{}).
+		Select("COUNT(*) AS count, COALESCE(MAX(id), 0) AS max_id").
+		Scan(&row).Error; errScan != nil {
+		return "", fmt.Errorf("auth fingerprint: %w", errScan)
+	}
+	var latest struct {
+		UpdatedAt time.Time
+	}
+	if row.Count > 0 {
+		if errTake := db.WithContext(ctx).
+			Model(&models.Auth{}).
+			Select("updated_at").
+			Order("updated_at DESC").
+			Limit(1).
+			Scan(&latest).Error; errTake != nil {
+			return "", fmt.Errorf("auth fingerprint: %w", errTake)
+		}
+	}
+	return fmt.Sprintf("%d:%d:%d", row.Count, row.MaxID, latest.UpdatedAt.UnixNano()), nil
+}
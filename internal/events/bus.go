@@ -0,0 +1,411 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Type identifies an event kind published on the bus.
+type Type string
+
+// Event types published by subsystems.
+const (
+	// TypeUsageRecorded is emitted after a usage row is persisted.
+	TypeUsageRecorded Type = "usage.recorded"
+	// TypeAPIKeyDisabled is emitted when an API key is revoked or disabled.
+	TypeAPIKeyDisabled Type = "api_key.disabled"
+	// TypeQuotaLow is emitted when a credential quota drops below the warning threshold.
+	TypeQuotaLow Type = "quota.low"
+	// TypeLoginFailed is emitted when an admin or user login attempt fails.
+	TypeLoginFailed Type = "auth.login_failed"
+	// TypeAuthTokenInvalid is emitted when an auth file token is rejected upstream.
+	TypeAuthTokenInvalid Type = "auth_file.token_invalid"
+	// TypeTierUpgradeProposed is emitted when a user qualifies for a tier upgrade awaiting confirmation.
+	TypeTierUpgradeProposed Type = "tier_upgrade.proposed"
+	// TypeTierUpgradeApplied is emitted when a user is moved to a higher user group.
+	TypeTierUpgradeApplied Type = "tier_upgrade.applied"
+	// TypeBillRenewed is emitted when an auto-renewing bill creates the next period's bill.
+	TypeBillRenewed Type = "bill.renewed"
+	// TypeUsageAnomaly is emitted when a user or API key spends far above its usual rate.
+	TypeUsageAnomaly Type = "usage.anomaly"
+	// TypeBalanceInsufficient is emitted when a user's prepaid balance cannot cover a charge.
+	TypeBalanceInsufficient Type = "billing.balance_insufficient"
+	// TypeHealthCheckFailed is emitted when a provider credential starts failing health checks.
+	TypeHealthCheckFailed Type = "provider.health_check_failed"
+	// TypeSLOBurnRate is emitted when an SLO spends its error budget faster than allowed.
+	TypeSLOBurnRate Type = "slo.burn_rate"
+	// TypeBillQuotaThreshold is emitted when usage first crosses a share of a bill's quota.
+	TypeBillQuotaThreshold Type = "bill.quota_threshold"
+	// TypeBillExhausted is emitted when usage spends the last of a bill's quota.
+	TypeBillExhausted Type = "bill.exhausted"
+	// TypePrepaidRedeemed is emitted when a user redeems a prepaid card.
+	TypePrepaidRedeemed Type = "prepaid_card.redeemed"
+	// TypeWebhookPing is emitted by admins to test a webhook endpoint.
+	TypeWebhookPing Type = "webhook.ping"
+	// TypeIPRejected is emitted when an API key or admin is used from an address outside its allowlist.
+	TypeIPRejected Type = "access.ip_rejected"
+	// TypeUserSuspended is emitted when the lifecycle scheduler disables the API keys of a user without balance.
+	TypeUserSuspended Type = "user.suspended"
+	// TypeUserResumed is emitted when the lifecycle scheduler re-enables the API keys of a suspended user.
+	TypeUserResumed Type = "user.resumed"
+	// TypeUserInactive is emitted when the lifecycle scheduler warns an inactive user.
+	TypeUserInactive Type = "user.inactive"
+	// TypeUserAnonymized is emitted when the lifecycle scheduler anonymizes an inactive user.
+	TypeUserAnonymized Type = "user.anonymized"
+)
+
+// Severity describes how important an event is.
+type Severity string
+
+// Severity levels ordered from least to most important.
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// Rank returns the ordering weight of a severity level.
+func (s Severity) Rank() int {
+	switch s {
+	case SeverityCritical:
+		return 2
+	case SeverityWarning:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Event is a typed message delivered to bus subscribers.
+type Event struct {
+	ID         string         `json:"id"`
+	Type       Type           `json:"type"`
+	Severity   Severity       `json:"severity"`
+	Subject    string         `json:"subject,omitempty"`
+	Message    string         `json:"message,omitempty"`
+	Data       map[string]any `json:"data,omitempty"`
+	OccurredAt time.Time      `json:"occurred_at"`
+}
+
+// Subscriber receives events from the bus.
+type Subscriber interface {
+	Name() string
+	Handle(ctx context.Context, event Event) error
+}
+
+// SubscriberFunc adapts a function into a Subscriber.
+type SubscriberFunc struct {
+	SubscriberName string
+	Fn             func(ctx context.Context, event Event) error
+}
+
+// Name returns the subscriber name.
+func (f SubscriberFunc) Name() string { return f.SubscriberName }
+
+// Handle invokes the wrapped function.
+func (f SubscriberFunc) Handle(ctx context.Context, event Event) error {
+	if f.Fn == nil {
+		return nil
+	}
+	return f.Fn(ctx, event)
+}
+
+type subscription struct {
+	subscriber  Subscriber
+	types       map[Type]struct{}
+	minSeverity Severity
+}
+
+func (s subscription) matches(event Event) bool {
+	if event.Severity.Rank() < s.minSeverity.Rank() {
+		return false
+	}
+	if len(s.types) == 0 {
+		return true
+	}
+	_, ok := s.types[event.Type]
+	return ok
+}
+
+const (
+	defaultQueueSize      = 1024
+	defaultHandlerTimeout = 15 * time.Second
+)
+
+// Bus fans out published events to subscribers on a background worker.
+type Bus struct {
+	mu            sync.RWMutex
+	subscriptions []subscription
+	queue         chan Event
+	startOnce     sync.Once
+	done          chan struct{}
+}
+
+// NewBus constructs a bus with the given queue capacity.
+func NewBus(queueSize int) *Bus {
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	return &Bus{
+		queue: make(chan Event, queueSize),
+		done:  make(chan struct{}),
+	}
+}
+
+// Subscribe registers a subscriber for the given event types (all types when empty).
+func (b *Bus) Subscribe(sub Subscriber, types ...Type) {
+	b.SubscribeWithSeverity(sub, SeverityInfo, types...)
+}
+
+// SubscribeWithSeverity registers a subscriber that only receives events at or above minSeverity.
+func (b *Bus) SubscribeWithSeverity(sub Subscriber, minSeverity Severity, types ...Type) {
+	if b == nil || sub == nil {
+		return
+	}
+	typeSet := make(map[Type]struct{}, len(types))
+	for _, t := range types {
+		if strings.TrimSpace(string(t)) == "" {
+			continue
+		}
+		typeSet[t] = struct{}{}
+	}
+	b.mu.Lock()
+	b.subscriptions = append(b.subscriptions, subscription{
+		subscriber:  sub,
+		types:       typeSet,
+		minSeverity: minSeverity,
+	})
+	b.mu.Unlock()
+}
+
+// Publish enqueues an event for asynchronous delivery; it never blocks the caller.
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	if b == nil {
+		return
+	}
+	event = normalizeEvent(event)
+	select {
+	case b.queue <- event:
+	default:
+		log.Warnf("event bus: queue full, dropping event %s", event.Type)
+	}
+}
+
+// Start launches the delivery worker in a background goroutine.
+func (b *Bus) Start(ctx context.Context) {
+	if b == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	b.startOnce.Do(func() {
+		go b.run(ctx)
+	})
+}
+
+// Done is closed after the worker stops and the queue has been drained.
+func (b *Bus) Done() <-chan struct{} {
+	return b.done
+}
+
+func (b *Bus) run(ctx context.Context) {
+	defer close(b.done)
+	for {
+		select {
+		case <-ctx.Done():
+			b.drain()
+			return
+		case event := <-b.queue:
+			b.dispatch(event)
+		}
+	}
+}
+
+// drain delivers whatever is left in the queue on shutdown.
+func (b *Bus) drain() {
+	for {
+		select {
+		case event := <-b.queue:
+			b.dispatch(event)
+		default:
+			return
+		}
+	}
+}
+
+func (b *Bus) dispatch(event Event) {
+	b.mu.RLock()
+	subs := make([]subscription, len(b.subscriptions))
+	copy(subs, b.subscriptions)
+	b.mu.RUnlock()
+
+	for _, sub := range subs {
+		if !sub.matches(event) {
+			continue
+		}
+		b.deliver(sub.subscriber, event)
+	}
+}
+
+func (b *Bus) deliver(sub Subscriber, event Event) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			log.Errorf("event bus: subscriber %s panicked on %s: %v", sub.Name(), event.Type, recovered)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), defaultHandlerTimeout)
+	defer cancel()
+	if errHandle := sub.Handle(ctx, event); errHandle != nil {
+		log.WithError(errHandle).Warnf("event bus: subscriber %s failed on %s", sub.Name(), event.Type)
+	}
+}
+
+func normalizeEvent(event Event) Event {
+	if strings.TrimSpace(event.ID) == "" {
+		event.ID = newEventID()
+	}
+	if event.Severity == "" {
+		event.Severity = SeverityInfo
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	} else {
+		event.OccurredAt = event.OccurredAt.UTC()
+	}
+	return event
+}
+
+func newEventID() string {
+	buf := make([]byte, 12)
+	if _, errRead := rand.Read(buf); errRead != nil {
+		return time.Now().UTC().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(buf)
+}
+
+// defaultBus is the process-wide bus used by package-level helpers.
+var defaultBus = NewBus(defaultQueueSize)
+
+// Default returns the process-wide event bus.
+func Default() *Bus { return defaultBus }
+
+// Publish enqueues an event on the process-wide bus.
+func Publish(ctx context.Context, event Event) { defaultBus.Publish(ctx, event) }
+
+// PublishLoginFailed emits a login failure for the given realm ("admin" or "user").
+func PublishLoginFailed(ctx context.Context, realm, username, clientIP, reason string) {
+	Publish(ctx, Event{
+		Type:     TypeLoginFailed,
+		Severity: SeverityWarning,
+		Subject:  realm + ":" + username,
+		Message:  reason,
+		Data: map[string]any{
+			"realm":     realm,
+			"username":  username,
+			"client_ip": clientIP,
+		},
+	})
+}
+
+// PublishAPIKeyDisabled emits an API key disabled event.
+func PublishAPIKeyDisabled(ctx context.Context, apiKeyID uint64, reason string) {
+	Publish(ctx, Event{
+		Type:     TypeAPIKeyDisabled,
+		Severity: SeverityInfo,
+		Subject:  "api_key:" + strconv.FormatUint(apiKeyID, 10),
+		Message:  reason,
+		Data: map[string]any{
+			"api_key_id": apiKeyID,
+		},
+	})
+}
+
+// PublishIPRejected emits an allowlist rejection; subject identifies the key or account, e.g. "api_key:7".
+func PublishIPRejected(ctx context.Context, subject, clientIP, path string) {
+	Publish(ctx, Event{
+		Type:     TypeIPRejected,
+		Severity: SeverityWarning,
+		Subject:  subject,
+		Message:  "request from " + clientIP + " is outside the ip allowlist",
+		Data: map[string]any{
+			"client_ip": clientIP,
+			"path":      path,
+		},
+	})
+}
+
+// PublishBalanceInsufficient emits an insufficient balance event for a user.
+func PublishBalanceInsufficient(ctx context.Context, userID uint64, reason string, data map[string]any) {
+	payload := map[string]any{"user_id": userID}
+	for key, value := range data {
+		payload[key] = value
+	}
+	Publish(ctx, Event{
+		Type:     TypeBalanceInsufficient,
+		Severity: SeverityWarning,
+		Subject:  "user:" + strconv.FormatUint(userID, 10),
+		Message:  reason,
+		Data:     payload,
+	})
+}
+
+// PublishHealthCheckFailed emits a health check failure for a provider credential; source is
+// the credential kind and targetID its row ID.
+func PublishHealthCheckFailed(ctx context.Context, provider, source string, targetID uint64, statusCode int, reason string) {
+	Publish(ctx, Event{
+		Type:     TypeHealthCheckFailed,
+		Severity: SeverityWarning,
+		Subject:  source + ":" + strconv.FormatUint(targetID, 10),
+		Message:  reason,
+		Data: map[string]any{
+			"provider":    provider,
+			"source":      source,
+			"target_id":   targetID,
+			"status_code": statusCode,
+		},
+	})
+}
+
+// PublishPrepaidRedeemed emits a prepaid card redemption by a user.
+func PublishPrepaidRedeemed(ctx context.Context, userID, cardID uint64, cardSN string, amount float64) {
+	Publish(ctx, Event{
+		Type:     TypePrepaidRedeemed,
+		Severity: SeverityInfo,
+		Subject:  "user:" + strconv.FormatUint(userID, 10),
+		Message:  "prepaid card " + cardSN + " redeemed",
+		Data: map[string]any{
+			"user_id": userID,
+			"card_id": cardID,
+			"card_sn": cardSN,
+			"amount":  amount,
+		},
+	})
+}
+
+// PublishUserLifecycle emits a lifecycle action taken on a user; apiKeyIDs lists the keys it
+// disabled or re-enabled.
+func PublishUserLifecycle(ctx context.Context, eventType Type, userID uint64, reason string, apiKeyIDs []uint64, data map[string]any) {
+	severity := SeverityInfo
+	if eventType == TypeUserSuspended || eventType == TypeUserAnonymized {
+		severity = SeverityWarning
+	}
+	payload := map[string]any{"user_id": userID, "api_key_ids": apiKeyIDs}
+	for key, value := range data {
+		payload[key] = value
+	}
+	Publish(ctx, Event{
+		Type:     eventType,
+		Severity: severity,
+		Subject:  "user:" + strconv.FormatUint(userID, 10),
+		Message:  reason,
+		Data:     payload,
+	})
+}
@@ -0,0 +1,99 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBusDeliversMatchingEvents(t *testing.T) {
+	bus := NewBus(8)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan Event, 4)
+	bus.Subscribe(SubscriberFunc{
+		SubscriberName: "test",
+		Fn: func(_ context.Context, event Event) error {
+			received <- event
+			return nil
+		},
+	}, TypeLoginFailed)
+	bus.Start(ctx)
+
+	bus.Publish(ctx, Event{Type: TypeUsageRecorded})
+	bus.Publish(ctx, Event{Type: TypeLoginFailed, Subject: "admin:root"})
+
+	select {
+	case event := <-received:
+		if event.Type != TypeLoginFailed {
+			t.Fatalf("expected %s, got %s", TypeLoginFailed, event.Type)
+		}
+		if event.ID == "" || event.OccurredAt.IsZero() || event.Severity != SeverityInfo {
+			t.Fatalf("expected normalized event, got %+v", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+
+	select {
+	case event := <-received:
+		t.Fatalf("unexpected extra event: %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestBusSeverityFilter(t *testing.T) {
+	bus := NewBus(8)
+	received := make(chan Event, 4)
+	bus.SubscribeWithSeverity(SubscriberFunc{
+		SubscriberName: "warn",
+		Fn: func(_ context.Context, event Event) error {
+			received <- event
+			return nil
+		},
+	}, SeverityWarning)
+
+	bus.Publish(context.Background(), Event{Type: TypeUsageRecorded})
+	bus.Publish(context.Background(), Event{Type: TypeAuthTokenInvalid, Severity: SeverityWarning})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	bus.Start(ctx)
+	cancel()
+	<-bus.Done()
+
+	if len(received) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(received))
+	}
+	if event := <-received; event.Type != TypeAuthTokenInvalid {
+		t.Fatalf("unexpected event type %s", event.Type)
+	}
+}
+
+func TestBusRecoversFromSubscriberPanic(t *testing.T) {
+	bus := NewBus(8)
+	received := make(chan Event, 1)
+	bus.Subscribe(SubscriberFunc{
+		SubscriberName: "panics",
+		Fn: func(context.Context, Event) error {
+			panic("boom")
+		},
+	})
+	bus.Subscribe(SubscriberFunc{
+		SubscriberName: "ok",
+		Fn: func(_ context.Context, event Event) error {
+			received <- event
+			return nil
+		},
+	})
+	bus.Publish(context.Background(), Event{Type: TypeQuotaLow})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	bus.Start(ctx)
+	cancel()
+	<-bus.Done()
+
+	if len(received) != 1 {
+		t.Fatalf("expected healthy subscriber to receive event, got %d", len(received))
+	}
+}
@@ -0,0 +1,133 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultSMTPPort = 587
+
+// EmailConfig mirrors the EVENT_EMAIL setting.
+type EmailConfig struct {
+	Host     string   `json:"host"`     // SMTP server host.
+	Port     int      `json:"port"`     // SMTP server port; defaults to 587.
+	Username string   `json:"username"` // SMTP auth username; empty skips auth.
+	Password string   `json:"password"` // SMTP auth password.
+	From     string   `json:"from"`     // Sender address.
+	To       []string `json:"to"`       // Recipient addresses.
+}
+
+// LoadEmailConfig reads EVENT_EMAIL; invalid values disable email delivery.
+func LoadEmailConfig() EmailConfig {
+	var cfg EmailConfig
+	raw, ok := internalsettings.DBConfigValue(internalsettings.EventEmailKey)
+	if !ok || len(bytes.TrimSpace(raw)) == 0 {
+		return cfg
+	}
+	if errUnmarshal := json.Unmarshal(raw, &cfg); errUnmarshal != nil {
+		log.WithError(errUnmarshal).Warn("events: invalid email setting")
+		return EmailConfig{}
+	}
+	cfg.Host = strings.TrimSpace(cfg.Host)
+	cfg.From = strings.TrimSpace(cfg.From)
+	recipients := make([]string, 0, len(cfg.To))
+	for _, to := range cfg.To {
+		if to = strings.TrimSpace(to); to != "" {
+			recipients = append(recipients, to)
+		}
+	}
+	cfg.To = recipients
+	if cfg.Port <= 0 {
+		cfg.Port = defaultSMTPPort
+	}
+	return cfg
+}
+
+// enabled reports whether the config has enough to send mail.
+func (c EmailConfig) enabled() bool {
+	return c.Host != "" && c.From != "" && len(c.To) > 0
+}
+
+// sendMailFunc matches smtp.SendMail so tests can capture messages.
+type sendMailFunc func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+
+// EmailSubscriber mails events to the recipients configured in EVENT_EMAIL.
+type EmailSubscriber struct {
+	config func() EmailConfig
+	send   sendMailFunc
+	now    func() time.Time
+}
+
+// NewEmailSubscriber constructs an email subscriber reading EVENT_EMAIL on every delivery.
+func NewEmailSubscriber() *EmailSubscriber {
+	return &EmailSubscriber{config: LoadEmailConfig, send: smtp.SendMail, now: time.Now}
+}
+
+// Name returns the subscriber name.
+func (s *EmailSubscriber) Name() string { return "email" }
+
+// Handle sends the event as a plain-text email.
+func (s *EmailSubscriber) Handle(ctx context.Context, event Event) error {
+	if s == nil || s.config == nil || s.send == nil {
+		return nil
+	}
+	cfg := s.config()
+	if !cfg.enabled() {
+		return nil
+	}
+	if errCtx := ctx.Err(); errCtx != nil {
+		return errCtx
+	}
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	if errSend := s.send(addr, auth, cfg.From, cfg.To, buildEmailMessage(cfg, event, s.now())); errSend != nil {
+		return fmt.Errorf("email: send via %s: %w", addr, errSend)
+	}
+	return nil
+}
+
+// buildEmailMessage renders an RFC 5322 message with the event summary and its data.
+func buildEmailMessage(cfg EmailConfig, event Event, now time.Time) []byte {
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(FormatText(event))
+	var b strings.Builder
+	b.WriteString("From: " + cfg.From + "\r\n")
+	b.WriteString("To: " + strings.Join(cfg.To, ", ") + "\r\n")
+	b.WriteString("Subject: " + subject + "\r\n")
+	b.WriteString("Date: " + now.UTC().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(FormatText(event) + "\r\n\r\n")
+	b.WriteString("Event: " + event.ID + "\r\n")
+	b.WriteString("Occurred at: " + event.OccurredAt.UTC().Format(time.RFC3339) + "\r\n")
+	if len(event.Data) > 0 {
+		keys := make([]string, 0, len(event.Data))
+		for key := range event.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		b.WriteString("\r\n")
+		for _, key := range keys {
+			value, errMarshal := json.Marshal(event.Data[key])
+			if errMarshal != nil {
+				continue
+			}
+			b.WriteString(key + ": " + string(value) + "\r\n")
+		}
+	}
+	return []byte(b.String())
+}
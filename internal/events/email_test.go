@@ -0,0 +1,69 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+)
+
+func TestLoadEmailConfigDefaultsPortAndTrimsRecipients(t *testing.T) {
+	internalsettings.StoreDBConfig(time.Now(), map[string]json.RawMessage{
+		internalsettings.EventEmailKey: json.RawMessage(`{"host":" smtp.example.com ","from":"alerts@example.com","to":["ops@example.com"," ",""]}`),
+	})
+	t.Cleanup(func() { internalsettings.StoreDBConfig(time.Now(), nil) })
+
+	cfg := LoadEmailConfig()
+	if cfg.Host != "smtp.example.com" || cfg.Port != defaultSMTPPort || len(cfg.To) != 1 || !cfg.enabled() {
+		t.Fatalf("unexpected email config %+v", cfg)
+	}
+}
+
+func TestEmailSubscriberSendsEvent(t *testing.T) {
+	var (
+		gotAddr string
+		gotAuth smtp.Auth
+		gotTo   []string
+		gotMsg  string
+	)
+	sub := &EmailSubscriber{
+		config: func() EmailConfig {
+			return EmailConfig{Host: "smtp.example.com", Port: 2525, From: "alerts@example.com", To: []string{"ops@example.com"}}
+		},
+		send: func(addr string, auth smtp.Auth, _ string, to []string, msg []byte) error {
+			gotAddr, gotAuth, gotTo, gotMsg = addr, auth, to, string(msg)
+			return nil
+		},
+		now: func() time.Time { return time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC) },
+	}
+	event := Event{
+		ID:         "evt-1",
+		Type:       TypeUsageAnomaly,
+		Severity:   SeverityCritical,
+		Subject:    "api_key:1",
+		Message:    "API key spent $50.00 in the last 1h0m0s",
+		Data:       map[string]any{"subject_id": 1},
+		OccurredAt: time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC),
+	}
+	if errHandle := sub.Handle(context.Background(), event); errHandle != nil {
+		t.Fatalf("Handle: %v", errHandle)
+	}
+	if gotAddr != "smtp.example.com:2525" || gotAuth != nil || len(gotTo) != 1 {
+		t.Fatalf("unexpected send addr=%s auth=%v to=%v", gotAddr, gotAuth, gotTo)
+	}
+	for _, want := range []string{"To: ops@example.com\r\n", "Subject: ", "Event: evt-1\r\n", "subject_id: 1\r\n"} {
+		if !strings.Contains(gotMsg, want) {
+			t.Fatalf("message missing %q:\n%s", want, gotMsg)
+		}
+	}
+
+	sub.config = func() EmailConfig { return EmailConfig{Host: "smtp.example.com"} }
+	gotAddr = ""
+	if errHandle := sub.Handle(context.Background(), event); errHandle != nil || gotAddr != "" {
+		t.Fatalf("expected incomplete config to skip sending, err=%v addr=%s", errHandle, gotAddr)
+	}
+}
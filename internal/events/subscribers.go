@@ -0,0 +1,303 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const defaultSubscriberHTTPTimeout = 10 * time.Second
+
+// AuditSubscriber persists events into the audit_logs table.
+type AuditSubscriber struct {
+	db *gorm.DB
+}
+
+// NewAuditSubscriber constructs an audit subscriber; returns nil when db is nil.
+func NewAuditSubscriber(db *gorm.DB) *AuditSubscriber {
+	if db == nil {
+		return nil
+	}
+	return &AuditSubscriber{db: db}
+}
+
+// Name returns the subscriber name.
+func (s *AuditSubscriber) Name() string { return "audit" }
+
+// Handle stores the event as an audit log row.
+func (s *AuditSubscriber) Handle(ctx context.Context, event Event) error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	data := datatypes.JSON([]byte("{}"))
+	if len(event.Data) > 0 {
+		payload, errMarshal := json.Marshal(event.Data)
+		if errMarshal != nil {
+			return fmt.Errorf("audit: marshal data: %w", errMarshal)
+		}
+		data = datatypes.JSON(payload)
+	}
+	row := models.AuditLog{
+		EventID:    event.ID,
+		EventType:  string(event.Type),
+		Severity:   string(event.Severity),
+		Subject:    event.Subject,
+		Message:    event.Message,
+		Data:       data,
+		OccurredAt: event.OccurredAt,
+	}
+	if errCreate := s.db.WithContext(ctx).Create(&row).Error; errCreate != nil {
+		return fmt.Errorf("audit: create log: %w", errCreate)
+	}
+	return nil
+}
+
+// NotificationTypes lists the events surfaced in the admin notification center.
+var NotificationTypes = []Type{
+	TypeAuthTokenInvalid,
+	TypeBalanceInsufficient,
+	TypeHealthCheckFailed,
+	TypeSLOBurnRate,
+	TypeUsageAnomaly,
+	TypeUserSuspended,
+	TypeUserAnonymized,
+}
+
+// NotificationSubscriber persists events into the admin_notifications table shown in the admin console.
+type NotificationSubscriber struct {
+	db *gorm.DB
+}
+
+// NewNotificationSubscriber constructs a notification subscriber; returns nil when db is nil.
+func NewNotificationSubscriber(db *gorm.DB) *NotificationSubscriber {
+	if db == nil {
+		return nil
+	}
+	return &NotificationSubscriber{db: db}
+}
+
+// Name returns the subscriber name.
+func (s *NotificationSubscriber) Name() string { return "notification" }
+
+// Handle stores the event as an unread admin notification.
+func (s *NotificationSubscriber) Handle(ctx context.Context, event Event) error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	data := datatypes.JSON([]byte("{}"))
+	if len(event.Data) > 0 {
+		payload, errMarshal := json.Marshal(event.Data)
+		if errMarshal != nil {
+			return fmt.Errorf("notification: marshal data: %w", errMarshal)
+		}
+		data = datatypes.JSON(payload)
+	}
+	row := models.AdminNotification{
+		EventID:    event.ID,
+		EventType:  string(event.Type),
+		Severity:   string(event.Severity),
+		Subject:    event.Subject,
+		Message:    event.Message,
+		Data:       data,
+		OccurredAt: event.OccurredAt,
+	}
+	if errCreate := s.db.WithContext(ctx).Create(&row).Error; errCreate != nil {
+		return fmt.Errorf("notification: create row: %w", errCreate)
+	}
+	return nil
+}
+
+// WebhookSubscriber posts the event to every configured webhook URL, either JSON encoded
+// or shaped by the URL's payload template.
+type WebhookSubscriber struct {
+	client    *http.Client
+	urls      func() []string
+	templates func() map[string]WebhookTemplate
+}
+
+// NewWebhookSubscriber constructs a webhook subscriber reading URLs from EVENT_WEBHOOK_URLS
+// and payload templates from EVENT_WEBHOOK_TEMPLATES.
+func NewWebhookSubscriber() *WebhookSubscriber {
+	return &WebhookSubscriber{
+		client: &http.Client{Timeout: defaultSubscriberHTTPTimeout},
+		urls: func() []string {
+			return settingStringList(internalsettings.EventWebhookURLsKey)
+		},
+		templates: loadWebhookTemplates,
+	}
+}
+
+// Name returns the subscriber name.
+func (s *WebhookSubscriber) Name() string { return "webhook" }
+
+// Handle delivers the event to each webhook URL.
+func (s *WebhookSubscriber) Handle(ctx context.Context, event Event) error {
+	if s == nil || s.urls == nil {
+		return nil
+	}
+	urls := s.urls()
+	if len(urls) == 0 {
+		return nil
+	}
+	var templates map[string]WebhookTemplate
+	if s.templates != nil {
+		templates = s.templates()
+	}
+	var failures []string
+	for _, url := range urls {
+		body, contentType, errPayload := webhookPayload(templates, url, event)
+		if errPayload != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", url, errPayload))
+			continue
+		}
+		if errPost := postWithContentType(ctx, s.client, url, contentType, body); errPost != nil {
+			failures = append(failures, errPost.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("webhook: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// ChatSubscriber posts a Slack-compatible text message to a chat webhook.
+type ChatSubscriber struct {
+	client *http.Client
+	url    func() string
+}
+
+// NewChatSubscriber constructs a chat subscriber reading EVENT_CHAT_WEBHOOK_URL.
+func NewChatSubscriber() *ChatSubscriber {
+	return &ChatSubscriber{
+		client: &http.Client{Timeout: defaultSubscriberHTTPTimeout},
+		url: func() string {
+			values := settingStringList(internalsettings.EventChatWebhookURLKey)
+			if len(values) == 0 {
+				return ""
+			}
+			return values[0]
+		},
+	}
+}
+
+// Name returns the subscriber name.
+func (s *ChatSubscriber) Name() string { return "chat" }
+
+// Handle formats the event and posts it to the chat webhook.
+func (s *ChatSubscriber) Handle(ctx context.Context, event Event) error {
+	if s == nil || s.url == nil {
+		return nil
+	}
+	url := s.url()
+	if url == "" {
+		return nil
+	}
+	body, errMarshal := json.Marshal(map[string]string{"text": FormatText(event)})
+	if errMarshal != nil {
+		return fmt.Errorf("chat: marshal message: %w", errMarshal)
+	}
+	return postJSON(ctx, s.client, url, body)
+}
+
+// FormatText renders a short single-line description of an event.
+func FormatText(event Event) string {
+	var b strings.Builder
+	b.WriteString("[")
+	b.WriteString(strings.ToUpper(string(event.Severity)))
+	b.WriteString("] ")
+	b.WriteString(string(event.Type))
+	if event.Subject != "" {
+		b.WriteString(" (")
+		b.WriteString(event.Subject)
+		b.WriteString(")")
+	}
+	if event.Message != "" {
+		b.WriteString(": ")
+		b.WriteString(event.Message)
+	}
+	return b.String()
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, body []byte) error {
+	return postWithContentType(ctx, client, url, "application/json", body)
+}
+
+func postWithContentType(ctx context.Context, client *http.Client, url, contentType string, body []byte) error {
+	req, errReq := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if errReq != nil {
+		return fmt.Errorf("build request %s: %w", url, errReq)
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, errDo := client.Do(req)
+	if errDo != nil {
+		return fmt.Errorf("post %s: %w", url, errDo)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("post %s: unexpected status %d", url, resp.StatusCode)
+	}
+	return nil
+}
+
+// settingStringList reads a DB config value as either a JSON array or a comma-separated string.
+func settingStringList(key string) []string {
+	raw, ok := internalsettings.DBConfigValue(key)
+	if !ok || len(bytes.TrimSpace(raw)) == 0 {
+		return nil
+	}
+	var values []string
+	var list []string
+	if errList := json.Unmarshal(raw, &list); errList == nil {
+		values = list
+	} else {
+		var single string
+		if errString := json.Unmarshal(raw, &single); errString != nil {
+			return nil
+		}
+		values = strings.Split(single, ",")
+	}
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// RegisterDefaultSubscribers wires audit, admin notification, webhook, chat and email
+// subscribers onto the bus. Webhook, chat and email deliveries are throttled; their digests
+// are flushed until ctx is done.
+func RegisterDefaultSubscribers(ctx context.Context, bus *Bus, db *gorm.DB) {
+	if bus == nil {
+		return
+	}
+	if audit := NewAuditSubscriber(db); audit != nil {
+		bus.Subscribe(audit, TypeAPIKeyDisabled, TypeLoginFailed, TypeAuthTokenInvalid, TypeQuotaLow, TypeTierUpgradeApplied, TypeBillRenewed, TypeUsageAnomaly, TypeBalanceInsufficient, TypeHealthCheckFailed, TypeSLOBurnRate, TypeIPRejected, TypeUserSuspended, TypeUserResumed, TypeUserInactive, TypeUserAnonymized)
+	}
+	if notification := NewNotificationSubscriber(db); notification != nil {
+		bus.Subscribe(notification, NotificationTypes...)
+	}
+	webhook := NewThrottledSubscriber(NewWebhookSubscriber())
+	webhook.Start(ctx)
+	bus.Subscribe(webhook)
+	chat := NewThrottledSubscriber(NewChatSubscriber())
+	chat.Start(ctx)
+	bus.SubscribeWithSeverity(chat, SeverityWarning)
+	email := NewThrottledSubscriber(NewEmailSubscriber())
+	email.Start(ctx)
+	bus.SubscribeWithSeverity(email, SeverityWarning)
+}
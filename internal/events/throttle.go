@@ -0,0 +1,271 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+)
+
+// TypeDigest is emitted by throttled channels to summarize suppressed events.
+const TypeDigest Type = "notification.digest"
+
+const (
+	defaultDigestFlushInterval = time.Minute
+	maxDigestSubjects          = 20
+	// throttlePolicyDefaultChannel holds policies that apply to every throttled channel.
+	throttlePolicyDefaultChannel = "*"
+)
+
+// ThrottlePolicy limits how often one event type is delivered on a channel.
+type ThrottlePolicy struct {
+	Window time.Duration // Minimum gap between deliveries for the same type and subject.
+	Digest time.Duration // How long suppressed events are batched before a digest is sent; zero drops them.
+}
+
+// defaultThrottlePolicies keeps alert channels quiet during provider-wide incidents.
+var defaultThrottlePolicies = map[Type]ThrottlePolicy{
+	TypeQuotaLow:         {Window: time.Hour, Digest: 15 * time.Minute},
+	TypeAuthTokenInvalid: {Window: time.Hour, Digest: 15 * time.Minute},
+	TypeLoginFailed:      {Window: 10 * time.Minute, Digest: 15 * time.Minute},
+}
+
+// digestBucket collects suppressed events of one type until the digest is due.
+type digestBucket struct {
+	first    time.Time
+	count    int
+	severity Severity
+	subjects map[string]int
+}
+
+// ThrottledSubscriber wraps a delivery channel with per-type throttling and digest batching.
+type ThrottledSubscriber struct {
+	next     Subscriber
+	policies func() map[Type]ThrottlePolicy
+	now      func() time.Time
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+	pending  map[Type]*digestBucket
+}
+
+// NewThrottledSubscriber wraps next with the throttle policies configured for its channel name.
+func NewThrottledSubscriber(next Subscriber) *ThrottledSubscriber {
+	if next == nil {
+		return nil
+	}
+	channel := next.Name()
+	return &ThrottledSubscriber{
+		next:     next,
+		policies: func() map[Type]ThrottlePolicy { return loadThrottlePolicies(channel) },
+		now:      time.Now,
+		lastSent: make(map[string]time.Time),
+		pending:  make(map[Type]*digestBucket),
+	}
+}
+
+// Name returns the wrapped channel name.
+func (s *ThrottledSubscriber) Name() string { return s.next.Name() }
+
+// Handle delivers the event unless its type was already sent for the same subject within the window.
+func (s *ThrottledSubscriber) Handle(ctx context.Context, event Event) error {
+	if s == nil || s.next == nil {
+		return nil
+	}
+	s.flush(ctx, false)
+
+	policy, ok := s.policies()[event.Type]
+	if !ok || policy.Window <= 0 {
+		return s.next.Handle(ctx, event)
+	}
+
+	now := s.now()
+	key := string(event.Type) + "|" + event.Subject
+	s.mu.Lock()
+	last, seen := s.lastSent[key]
+	if seen && now.Sub(last) < policy.Window {
+		if policy.Digest > 0 {
+			s.addToDigestLocked(event, now)
+		}
+		s.mu.Unlock()
+		return nil
+	}
+	s.lastSent[key] = now
+	s.pruneLocked(now, policy.Window)
+	s.mu.Unlock()
+
+	return s.next.Handle(ctx, event)
+}
+
+// Start periodically flushes due digests until ctx is done, then flushes everything left.
+func (s *ThrottledSubscriber) Start(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go func() {
+		ticker := time.NewTicker(defaultDigestFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				flushCtx, cancel := context.WithTimeout(context.Background(), defaultHandlerTimeout)
+				s.flush(flushCtx, true)
+				cancel()
+				return
+			case <-ticker.C:
+				s.flush(ctx, false)
+			}
+		}
+	}()
+}
+
+func (s *ThrottledSubscriber) addToDigestLocked(event Event, now time.Time) {
+	bucket, ok := s.pending[event.Type]
+	if !ok {
+		bucket = &digestBucket{first: now, subjects: make(map[string]int)}
+		s.pending[event.Type] = bucket
+	}
+	bucket.count++
+	if event.Severity.Rank() > bucket.severity.Rank() || bucket.severity == "" {
+		bucket.severity = event.Severity
+	}
+	bucket.subjects[event.Subject]++
+}
+
+// pruneLocked forgets throttle keys that can no longer suppress anything.
+func (s *ThrottledSubscriber) pruneLocked(now time.Time, window time.Duration) {
+	if len(s.lastSent) < 1024 {
+		return
+	}
+	for key, sent := range s.lastSent {
+		if now.Sub(sent) >= window {
+			delete(s.lastSent, key)
+		}
+	}
+}
+
+// flush sends a digest for every bucket whose batching period elapsed (or all buckets when force is set).
+func (s *ThrottledSubscriber) flush(ctx context.Context, force bool) {
+	if s == nil || s.next == nil {
+		return
+	}
+	policies := s.policies()
+	now := s.now()
+
+	s.mu.Lock()
+	var due []Event
+	for eventType, bucket := range s.pending {
+		policy := policies[eventType]
+		if !force && policy.Digest > 0 && now.Sub(bucket.first) < policy.Digest {
+			continue
+		}
+		due = append(due, buildDigestEvent(s.next.Name(), eventType, bucket, now))
+		delete(s.pending, eventType)
+	}
+	s.mu.Unlock()
+
+	for _, digest := range due {
+		if errHandle := s.next.Handle(ctx, normalizeEvent(digest)); errHandle != nil {
+			log.WithError(errHandle).Warnf("event bus: subscriber %s failed on %s", s.next.Name(), digest.Type)
+		}
+	}
+}
+
+func buildDigestEvent(channel string, eventType Type, bucket *digestBucket, now time.Time) Event {
+	subjects := make([]string, 0, len(bucket.subjects))
+	for subject := range bucket.subjects {
+		subjects = append(subjects, subject)
+	}
+	sort.Slice(subjects, func(i, j int) bool {
+		if bucket.subjects[subjects[i]] != bucket.subjects[subjects[j]] {
+			return bucket.subjects[subjects[i]] > bucket.subjects[subjects[j]]
+		}
+		return subjects[i] < subjects[j]
+	})
+	truncated := false
+	if len(subjects) > maxDigestSubjects {
+		subjects = subjects[:maxDigestSubjects]
+		truncated = true
+	}
+	counts := make(map[string]int, len(subjects))
+	for _, subject := range subjects {
+		counts[subject] = bucket.subjects[subject]
+	}
+	return Event{
+		Type:     TypeDigest,
+		Severity: bucket.severity,
+		Subject:  string(eventType),
+		Message:  fmt.Sprintf("%d %s events suppressed across %d subjects since %s", bucket.count, eventType, len(bucket.subjects), bucket.first.UTC().Format(time.RFC3339)),
+		Data: map[string]any{
+			"channel":         channel,
+			"event_type":      string(eventType),
+			"count":           bucket.count,
+			"subject_count":   len(bucket.subjects),
+			"subjects":        counts,
+			"subjects_capped": truncated,
+			"since":           bucket.first.UTC(),
+			"until":           now.UTC(),
+		},
+	}
+}
+
+// throttlePolicySetting is the JSON form of a policy in EVENT_THROTTLE_POLICIES.
+type throttlePolicySetting struct {
+	Window string `json:"window"` // Go duration, e.g. "1h"; "0" disables throttling.
+	Digest string `json:"digest"` // Go duration, e.g. "15m"; "0" drops suppressed events.
+}
+
+// loadThrottlePolicies merges code defaults with the "*" and channel sections of EVENT_THROTTLE_POLICIES.
+func loadThrottlePolicies(channel string) map[Type]ThrottlePolicy {
+	out := make(map[Type]ThrottlePolicy, len(defaultThrottlePolicies))
+	for eventType, policy := range defaultThrottlePolicies {
+		out[eventType] = policy
+	}
+	raw, ok := internalsettings.DBConfigValue(internalsettings.EventThrottlePoliciesKey)
+	if !ok || len(bytes.TrimSpace(raw)) == 0 {
+		return out
+	}
+	var byChannel map[string]map[string]throttlePolicySetting
+	if errUnmarshal := json.Unmarshal(raw, &byChannel); errUnmarshal != nil {
+		log.WithError(errUnmarshal).Warn("event bus: invalid throttle policies setting")
+		return out
+	}
+	for _, section := range []string{throttlePolicyDefaultChannel, strings.TrimSpace(channel)} {
+		for eventType, setting := range byChannel[section] {
+			policy := out[Type(eventType)]
+			if window, okWindow := parseThrottleDuration(setting.Window); okWindow {
+				policy.Window = window
+			}
+			if digest, okDigest := parseThrottleDuration(setting.Digest); okDigest {
+				policy.Digest = digest
+			}
+			out[Type(eventType)] = policy
+		}
+	}
+	return out
+}
+
+func parseThrottleDuration(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if value == "0" {
+		return 0, true
+	}
+	parsed, errParse := time.ParseDuration(value)
+	if errParse != nil || parsed < 0 {
+		return 0, false
+	}
+	return parsed, true
+}
@@ -0,0 +1,70 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestThrottledSubscriberSuppressesAndDigests(t *testing.T) {
+	var delivered []Event
+	next := SubscriberFunc{
+		SubscriberName: "chat",
+		Fn: func(_ context.Context, event Event) error {
+			delivered = append(delivered, event)
+			return nil
+		},
+	}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sub := NewThrottledSubscriber(next)
+	sub.now = func() time.Time { return now }
+	sub.policies = func() map[Type]ThrottlePolicy {
+		return map[Type]ThrottlePolicy{TypeQuotaLow: {Window: time.Hour, Digest: 15 * time.Minute}}
+	}
+	ctx := context.Background()
+
+	_ = sub.Handle(ctx, Event{Type: TypeQuotaLow, Subject: "auth:a", Severity: SeverityWarning})
+	_ = sub.Handle(ctx, Event{Type: TypeQuotaLow, Subject: "auth:b", Severity: SeverityWarning})
+	now = now.Add(time.Minute)
+	_ = sub.Handle(ctx, Event{Type: TypeQuotaLow, Subject: "auth:a", Severity: SeverityWarning})
+	_ = sub.Handle(ctx, Event{Type: TypeQuotaLow, Subject: "auth:a", Severity: SeverityCritical})
+	_ = sub.Handle(ctx, Event{Type: TypeLoginFailed, Subject: "admin:root"})
+
+	if len(delivered) != 3 {
+		t.Fatalf("expected 3 deliveries before digest, got %d", len(delivered))
+	}
+
+	now = now.Add(20 * time.Minute)
+	sub.flush(ctx, false)
+	if len(delivered) != 4 {
+		t.Fatalf("expected digest delivery, got %d events", len(delivered))
+	}
+	digest := delivered[3]
+	if digest.Type != TypeDigest || digest.Subject != string(TypeQuotaLow) {
+		t.Fatalf("unexpected digest event: %+v", digest)
+	}
+	if digest.Severity != SeverityCritical {
+		t.Fatalf("expected digest to carry highest severity, got %s", digest.Severity)
+	}
+	if count, _ := digest.Data["count"].(int); count != 2 {
+		t.Fatalf("expected 2 suppressed events, got %v", digest.Data["count"])
+	}
+
+	now = now.Add(time.Hour)
+	_ = sub.Handle(ctx, Event{Type: TypeQuotaLow, Subject: "auth:a"})
+	if len(delivered) != 5 {
+		t.Fatalf("expected delivery after window elapsed, got %d events", len(delivered))
+	}
+}
+
+func TestParseThrottleDuration(t *testing.T) {
+	if value, ok := parseThrottleDuration("30m"); !ok || value != 30*time.Minute {
+		t.Fatalf("expected 30m, got %s (ok=%v)", value, ok)
+	}
+	if value, ok := parseThrottleDuration("0"); !ok || value != 0 {
+		t.Fatalf("expected explicit zero, got %s (ok=%v)", value, ok)
+	}
+	if _, ok := parseThrottleDuration("soon"); ok {
+		t.Fatal("expected invalid duration to be ignored")
+	}
+}
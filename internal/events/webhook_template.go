@@ -0,0 +1,175 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+)
+
+// webhookTemplateDefaultURL selects the template used by webhook URLs without their own entry.
+const webhookTemplateDefaultURL = "*"
+
+// defaultWebhookContentType is sent when a template does not set a content type.
+const defaultWebhookContentType = "application/json"
+
+// ErrWebhookNotConfigured is returned when test-firing a URL missing from EVENT_WEBHOOK_URLS.
+var ErrWebhookNotConfigured = errors.New("webhook: url is not configured")
+
+// WebhookTemplate shapes the payload posted to one webhook, e.g. Slack blocks or a
+// PagerDuty event. The template is a Go text/template executed against the Event.
+type WebhookTemplate struct {
+	Template    string `json:"template"`               // Go text/template body.
+	ContentType string `json:"content_type,omitempty"` // Request content type; defaults to application/json.
+}
+
+// contentType returns the effective content type.
+func (t WebhookTemplate) contentType() string {
+	if contentType := strings.TrimSpace(t.ContentType); contentType != "" {
+		return contentType
+	}
+	return defaultWebhookContentType
+}
+
+// webhookTemplateFuncs are available inside payload templates.
+var webhookTemplateFuncs = template.FuncMap{
+	// json encodes a value as JSON, so strings are quoted and escaped safely.
+	"json": func(v any) (string, error) {
+		out, errMarshal := json.Marshal(v)
+		return string(out), errMarshal
+	},
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"text":    FormatText,
+	"rfc3339": func(t time.Time) string { return t.UTC().Format(time.RFC3339) },
+}
+
+// RenderWebhookPayload executes the template for an event. JSON content types must render
+// valid JSON so a broken template fails loudly instead of being rejected by the receiver.
+func RenderWebhookPayload(tpl WebhookTemplate, event Event) ([]byte, error) {
+	parsed, errParse := template.New("webhook").Funcs(webhookTemplateFuncs).Option("missingkey=zero").Parse(tpl.Template)
+	if errParse != nil {
+		return nil, fmt.Errorf("webhook template: %w", errParse)
+	}
+	var buf bytes.Buffer
+	if errExec := parsed.Execute(&buf, event); errExec != nil {
+		return nil, fmt.Errorf("webhook template: %w", errExec)
+	}
+	if strings.Contains(strings.ToLower(tpl.contentType()), "json") && !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("webhook template: rendered payload is not valid JSON")
+	}
+	return buf.Bytes(), nil
+}
+
+// SampleEvent returns a representative event used to validate and test-fire templates.
+func SampleEvent(eventType Type) Event {
+	if strings.TrimSpace(string(eventType)) == "" {
+		eventType = TypeQuotaLow
+	}
+	return Event{
+		ID:         "evt_sample",
+		Type:       eventType,
+		Severity:   SeverityWarning,
+		Subject:    "sample-subject",
+		Message:    "sample event sent from the admin console",
+		Data:       map[string]any{"sample": true},
+		OccurredAt: time.Now().UTC(),
+	}
+}
+
+// ValidateWebhookTemplatesSetting checks an EVENT_WEBHOOK_TEMPLATES value by rendering every
+// template against a sample event.
+func ValidateWebhookTemplatesSetting(raw json.RawMessage) error {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var templates map[string]WebhookTemplate
+	if errUnmarshal := json.Unmarshal(raw, &templates); errUnmarshal != nil {
+		return fmt.Errorf("value must be a JSON object of webhook templates: %w", errUnmarshal)
+	}
+	sample := SampleEvent("")
+	for url, tpl := range templates {
+		if _, errRender := RenderWebhookPayload(tpl, sample); errRender != nil {
+			return fmt.Errorf("%s: %w", url, errRender)
+		}
+	}
+	return nil
+}
+
+// loadWebhookTemplates reads EVENT_WEBHOOK_TEMPLATES keyed by URL.
+func loadWebhookTemplates() map[string]WebhookTemplate {
+	raw, ok := internalsettings.DBConfigValue(internalsettings.EventWebhookTemplatesKey)
+	if !ok || len(bytes.TrimSpace(raw)) == 0 {
+		return nil
+	}
+	var templates map[string]WebhookTemplate
+	if errUnmarshal := json.Unmarshal(raw, &templates); errUnmarshal != nil {
+		log.WithError(errUnmarshal).Warn("event bus: invalid webhook templates setting")
+		return nil
+	}
+	return templates
+}
+
+// webhookTemplateFor returns the template for a URL, falling back to the "*" entry.
+func webhookTemplateFor(templates map[string]WebhookTemplate, url string) (WebhookTemplate, bool) {
+	if tpl, ok := templates[url]; ok && strings.TrimSpace(tpl.Template) != "" {
+		return tpl, true
+	}
+	if tpl, ok := templates[webhookTemplateDefaultURL]; ok && strings.TrimSpace(tpl.Template) != "" {
+		return tpl, true
+	}
+	return WebhookTemplate{}, false
+}
+
+// webhookPayload renders the body and content type posted to a URL; URLs without a
+// template receive the JSON encoded event.
+func webhookPayload(templates map[string]WebhookTemplate, url string, event Event) ([]byte, string, error) {
+	if tpl, ok := webhookTemplateFor(templates, url); ok {
+		body, errRender := RenderWebhookPayload(tpl, event)
+		return body, tpl.contentType(), errRender
+	}
+	body, errMarshal := json.Marshal(event)
+	if errMarshal != nil {
+		return nil, "", fmt.Errorf("webhook: marshal event: %w", errMarshal)
+	}
+	return body, defaultWebhookContentType, nil
+}
+
+// TestFire posts a sample event to a configured webhook URL. A non-nil override replaces the
+// stored template so admins can try a template before saving it. It returns the payload sent.
+func (s *WebhookSubscriber) TestFire(ctx context.Context, url string, eventType Type, override *WebhookTemplate) ([]byte, error) {
+	if s == nil || s.urls == nil {
+		return nil, ErrWebhookNotConfigured
+	}
+	url = strings.TrimSpace(url)
+	configured := false
+	for _, candidate := range s.urls() {
+		if candidate == url {
+			configured = true
+			break
+		}
+	}
+	if !configured {
+		return nil, ErrWebhookNotConfigured
+	}
+	var templates map[string]WebhookTemplate
+	if s.templates != nil {
+		templates = s.templates()
+	}
+	if override != nil {
+		templates = map[string]WebhookTemplate{url: *override}
+	}
+	body, contentType, errPayload := webhookPayload(templates, url, SampleEvent(eventType))
+	if errPayload != nil {
+		return nil, errPayload
+	}
+	return body, postWithContentType(ctx, s.client, url, contentType, body)
+}
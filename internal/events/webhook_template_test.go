@@ -0,0 +1,96 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRenderWebhookPayload(t *testing.T) {
+	event := Event{Type: TypeAuthTokenInvalid, Severity: SeverityCritical, Subject: "auth:7", Message: `token "expired"`}
+
+	slack := WebhookTemplate{Template: `{"blocks":[{"type":"section","text":{"type":"mrkdwn","text":{{ json (text .) }}}}]}`}
+	body, errRender := RenderWebhookPayload(slack, event)
+	if errRender != nil {
+		t.Fatalf("render slack template: %v", errRender)
+	}
+	if !strings.Contains(string(body), `[CRITICAL] auth_file.token_invalid (auth:7): token \"expired\"`) {
+		t.Fatalf("unexpected slack payload: %s", body)
+	}
+
+	plain := WebhookTemplate{Template: `{{ upper (printf "%s" .Severity) }} {{ .Subject }}`, ContentType: "text/plain"}
+	if body, errRender = RenderWebhookPayload(plain, event); errRender != nil || string(body) != "CRITICAL auth:7" {
+		t.Fatalf("unexpected plain payload %q err=%v", body, errRender)
+	}
+
+	if _, errRender = RenderWebhookPayload(WebhookTemplate{Template: `{"text": {{ .Subject }}}`}, event); errRender == nil {
+		t.Fatal("expected invalid JSON output to be rejected")
+	}
+	if _, errRender = RenderWebhookPayload(WebhookTemplate{Template: `{{ .Subject `}, event); errRender == nil {
+		t.Fatal("expected parse error")
+	}
+}
+
+func TestValidateWebhookTemplatesSetting(t *testing.T) {
+	if errValidate := ValidateWebhookTemplatesSetting([]byte(`{"*":{"template":"{\"summary\": {{ json .Message }}}"}}`)); errValidate != nil {
+		t.Fatalf("expected valid setting, got %v", errValidate)
+	}
+	if errValidate := ValidateWebhookTemplatesSetting([]byte(`["not", "an", "object"]`)); errValidate == nil {
+		t.Fatal("expected non-object setting to be rejected")
+	}
+	if errValidate := ValidateWebhookTemplatesSetting([]byte(`{"https://example.com":{"template":"{{ .Missing }}"}}`)); errValidate == nil {
+		t.Fatal("expected unknown field to be rejected")
+	}
+}
+
+func TestWebhookSubscriberUsesTemplates(t *testing.T) {
+	type delivery struct {
+		contentType string
+		body        string
+	}
+	received := make(chan delivery, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- delivery{contentType: r.Header.Get("Content-Type"), body: string(body)}
+	}))
+	defer server.Close()
+
+	templated := server.URL + "/pagerduty"
+	raw := server.URL + "/raw"
+	subscriber := &WebhookSubscriber{
+		client: server.Client(),
+		urls:   func() []string { return []string{templated, raw} },
+		templates: func() map[string]WebhookTemplate {
+			return map[string]WebhookTemplate{
+				templated: {Template: `{"event_action":"trigger","payload":{"summary":{{ json .Message }},"severity":{{ json .Severity }}}}`},
+			}
+		},
+	}
+
+	if errHandle := subscriber.Handle(context.Background(), Event{ID: "evt_1", Type: TypeQuotaLow, Severity: SeverityWarning, Message: "quota low"}); errHandle != nil {
+		t.Fatalf("handle: %v", errHandle)
+	}
+	first, second := <-received, <-received
+	if first.body != `{"event_action":"trigger","payload":{"summary":"quota low","severity":"warning"}}` || first.contentType != "application/json" {
+		t.Fatalf("unexpected templated delivery: %+v", first)
+	}
+	if !strings.Contains(second.body, `"id":"evt_1"`) {
+		t.Fatalf("expected raw event JSON, got %s", second.body)
+	}
+
+	if _, errFire := subscriber.TestFire(context.Background(), "http://127.0.0.1:1/other", "", nil); !errors.Is(errFire, ErrWebhookNotConfigured) {
+		t.Fatalf("expected ErrWebhookNotConfigured, got %v", errFire)
+	}
+	override := &WebhookTemplate{Template: "sample {{ .Type }}", ContentType: "text/plain"}
+	payload, errFire := subscriber.TestFire(context.Background(), raw, TypeLoginFailed, override)
+	if errFire != nil || string(payload) != "sample auth.login_failed" {
+		t.Fatalf("unexpected test fire result %q err=%v", payload, errFire)
+	}
+	if fired := <-received; fired.contentType != "text/plain" || fired.body != "sample auth.login_failed" {
+		t.Fatalf("unexpected test fire delivery: %+v", fired)
+	}
+}
@@ -0,0 +1,109 @@
+// Package headertemplate resolves placeholders in provider key headers for every upstream
+// request, so providers requiring idempotency or correlation headers can be configured
+// without code changes. Supported placeholders:
+//
+//	{{uuid}}        a random UUID, shared by all headers and retries of one client request
+//	{{request_id}}  the client request ID
+//	{{timestamp}}   the current time in RFC 3339, UTC
+//	{{unix}}        the current Unix time in seconds
+//	{{unix_ms}}     the current Unix time in milliseconds
+//	{{env:NAME}}    the environment variable CPAB_HEADER_NAME
+//
+// Environment lookups are confined to the CPAB_HEADER_ prefix so header templates cannot
+// forward the proxy's own secrets upstream.
+package headertemplate
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix prefixes the environment variables readable through {{env:NAME}}.
+const EnvPrefix = "CPAB_HEADER_"
+
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([a-z_]+)(?::([A-Za-z0-9_]+))?\s*\}\}`)
+
+// Vars holds the per-request values placeholders resolve to.
+type Vars struct {
+	Now       time.Time // Request time.
+	RequestID string    // Client request ID; empty when unknown.
+	UUID      string    // Value of {{uuid}}.
+}
+
+// NewVars returns the values for a request received at now.
+func NewVars(now time.Time, requestID string) Vars {
+	return Vars{Now: now, RequestID: requestID, UUID: NewUUID()}
+}
+
+// IsTemplate reports whether value contains placeholders.
+func IsTemplate(value string) bool {
+	return strings.Contains(value, "{{")
+}
+
+// Validate checks that every placeholder in value is supported and well formed.
+func Validate(value string) error {
+	rest := placeholderPattern.ReplaceAllStringFunc(value, func(match string) string {
+		return ""
+	})
+	if strings.Contains(rest, "{{") || strings.Contains(rest, "}}") {
+		return fmt.Errorf("malformed placeholder in %q", value)
+	}
+	for _, groups := range placeholderPattern.FindAllStringSubmatch(value, -1) {
+		name, arg := groups[1], groups[2]
+		switch name {
+		case "uuid", "request_id", "timestamp", "unix", "unix_ms":
+			if arg != "" {
+				return fmt.Errorf("placeholder {{%s}} takes no argument", name)
+			}
+		case "env":
+			if arg == "" {
+				return fmt.Errorf("placeholder {{env:NAME}} needs a variable name")
+			}
+		default:
+			return fmt.Errorf("unknown placeholder {{%s}}", name)
+		}
+	}
+	return nil
+}
+
+// Render resolves the placeholders in value. Unknown placeholders are left untouched.
+func Render(value string, vars Vars) string {
+	if !IsTemplate(value) {
+		return value
+	}
+	return placeholderPattern.ReplaceAllStringFunc(value, func(match string) string {
+		groups := placeholderPattern.FindStringSubmatch(match)
+		switch groups[1] {
+		case "uuid":
+			return vars.UUID
+		case "request_id":
+			return vars.RequestID
+		case "timestamp":
+			return vars.Now.UTC().Format(time.RFC3339)
+		case "unix":
+			return strconv.FormatInt(vars.Now.Unix(), 10)
+		case "unix_ms":
+			return strconv.FormatInt(vars.Now.UnixMilli(), 10)
+		case "env":
+			return os.Getenv(EnvPrefix + groups[2])
+		default:
+			return match
+		}
+	})
+}
+
+// NewUUID returns a random version 4 UUID.
+func NewUUID() string {
+	var b [16]byte
+	if _, errRead := rand.Read(b[:]); errRead != nil {
+		return ""
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,48 @@
+package headertemplate
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	valid := []string{"static", "req-{{uuid}}", "{{ request_id }}", "{{timestamp}}/{{unix}}/{{unix_ms}}", "Bearer {{env:TOKEN}}"}
+	for _, value := range valid {
+		if errValidate := Validate(value); errValidate != nil {
+			t.Fatalf("Validate(%q): %v", value, errValidate)
+		}
+	}
+	invalid := []string{"{{nonce}}", "{{uuid:x}}", "{{env}}", "{{uuid", "a}}b", "{{env:A-B}}"}
+	for _, value := range invalid {
+		if errValidate := Validate(value); errValidate == nil {
+			t.Fatalf("expected Validate(%q) to fail", value)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	t.Setenv(EnvPrefix+"TENANT", "acme")
+	t.Setenv("TENANT", "leaked")
+	now := time.Date(2026, 10, 15, 12, 0, 0, 500_000_000, time.UTC)
+	vars := Vars{Now: now, RequestID: "req-1", UUID: "u-1"}
+
+	cases := map[string]string{
+		"static":                         "static",
+		"{{uuid}}:{{uuid}}":              "u-1:u-1",
+		"{{request_id}}":                 "req-1",
+		"{{timestamp}}":                  "2026-10-15T12:00:00Z",
+		"{{unix}}-{{unix_ms}}":           "1792065600-1792065600500",
+		"{{env:TENANT}}/{{env:MISSING}}": "acme/",
+	}
+	for template, want := range cases {
+		if got := Render(template, vars); got != want {
+			t.Fatalf("Render(%q) = %q, want %q", template, got, want)
+		}
+	}
+
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if first, second := NewUUID(), NewUUID(); !uuidPattern.MatchString(first) || first == second {
+		t.Fatalf("unexpected UUIDs %q and %q", first, second)
+	}
+}
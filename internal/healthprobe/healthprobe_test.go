@@ -0,0 +1,124 @@
+package healthprobe
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func setupHealthDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:healthprobe_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func TestProberRecordsKeyChecksAndSummarizes(t *testing.T) {
+	conn := setupHealthDB(t)
+	ctx := context.Background()
+
+	var gotAPIKey, gotVersion, gotCustom string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/models":
+			gotAPIKey = r.Header.Get("x-api-key")
+			gotVersion = r.Header.Get("anthropic-version")
+			gotCustom = r.Header.Get("X-Team")
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer upstream.Close()
+
+	keys := []models.ProviderAPIKey{
+		{Provider: "claude", APIKey: "sk-ant", BaseURL: upstream.URL, IsEnabled: true, Headers: datatypes.JSON(`{"X-Team":"ops"}`), Environments: datatypes.JSON(`[]`)},
+		{Provider: "openai-compatibility", Name: "router", BaseURL: upstream.URL + "/broken", IsEnabled: true, APIKeyEntries: datatypes.JSON(`[{"api_key":"sk-or"}]`), Environments: datatypes.JSON(`[]`)},
+		{Provider: "codex", APIKey: "", IsEnabled: true, Environments: datatypes.JSON(`[]`)},
+	}
+	if errCreate := conn.Create(&keys).Error; errCreate != nil {
+		t.Fatalf("create keys: %v", errCreate)
+	}
+
+	stale := models.ProviderHealthCheck{Provider: "claude", Source: SourceAPIKey, Success: true, CheckedAt: time.Now().Add(-30 * 24 * time.Hour).UTC()}
+	if errCreate := conn.Create(&stale).Error; errCreate != nil {
+		t.Fatalf("create stale check: %v", errCreate)
+	}
+
+	prober := NewProber(conn)
+	if errRun := prober.RunOnce(ctx); errRun != nil {
+		t.Fatalf("RunOnce: %v", errRun)
+	}
+	if gotAPIKey != "sk-ant" || gotVersion == "" || gotCustom != "ops" {
+		t.Fatalf("unexpected claude probe headers: key=%q version=%q custom=%q", gotAPIKey, gotVersion, gotCustom)
+	}
+
+	var count int64
+	if errCount := conn.Model(&models.ProviderHealthCheck{}).Count(&count).Error; errCount != nil {
+		t.Fatalf("count checks: %v", errCount)
+	}
+	if count != 2 {
+		t.Fatalf("expected two fresh checks without the keyless codex key or the stale row, got %d", count)
+	}
+
+	if errRecord := Record(ctx, conn, models.ProviderHealthCheck{Provider: "claude", Source: SourceAuth, Success: false, Error: "quota failed"}); errRecord != nil {
+		t.Fatalf("Record: %v", errRecord)
+	}
+
+	summaries, errSummarize := Summarize(ctx, conn, time.Now().Add(-time.Hour))
+	if errSummarize != nil {
+		t.Fatalf("Summarize: %v", errSummarize)
+	}
+	if len(summaries) != 2 {
+		t.Fatalf("expected two providers, got %+v", summaries)
+	}
+	claude, router := summaries[0], summaries[1]
+	if claude.Provider != "claude" || claude.Checks != 2 || claude.Failures != 1 || claude.Status != StatusDown || claude.LastError != "quota failed" {
+		t.Fatalf("unexpected claude summary: %+v", claude)
+	}
+	if router.Provider != "router" || router.Status != StatusDown || router.P95LatencyMillis != 0 {
+		t.Fatalf("unexpected router summary: %+v", router)
+	}
+}
+
+func TestPercentileAndClassify(t *testing.T) {
+	if got := percentile([]int64{50, 10, 40, 20, 30}, 0.95); got != 50 {
+		t.Fatalf("expected p95 50, got %d", got)
+	}
+	if got := percentile([]int64{10, 20}, 0.5); got != 10 {
+		t.Fatalf("expected p50 10, got %d", got)
+	}
+	if got := percentile(nil, 0.95); got != 0 {
+		t.Fatalf("expected 0 for no values, got %d", got)
+	}
+
+	cases := []struct {
+		checks, failures int64
+		want             string
+	}{
+		{checks: 20, failures: 0, want: StatusHealthy},
+		{checks: 20, failures: 1, want: StatusHealthy},
+		{checks: 20, failures: 2, want: StatusDegraded},
+		{checks: 20, failures: 10, want: StatusDown},
+	}
+	for _, tc := range cases {
+		if got := classify(&ProviderHealth{Checks: tc.checks, Failures: tc.failures}); got != tc.want {
+			t.Fatalf("classify(%d/%d) = %s, want %s", tc.failures, tc.checks, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package healthprobe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultInterval = 5 * time.Minute
+	defaultTimeout  = 15 * time.Second
+	// defaultRetention bounds how long individual checks are kept.
+	defaultRetention = 7 * 24 * time.Hour
+	maxConcurrency   = 4
+	// maxDrainBytes caps how much of a probe response is read before closing it.
+	maxDrainBytes = 64 << 10
+)
+
+// Default upstream endpoints used when a provider API key has no base URL override.
+const (
+	defaultGeminiBaseURL = "https://generativelanguage.googleapis.com"
+	defaultCodexBaseURL  = "https://api.openai.com/v1"
+	defaultClaudeBaseURL = "https://api.anthropic.com"
+)
+
+// Prober periodically lists models through every enabled provider API key and records
+// the latency and status of each request.
+type Prober struct {
+	db        *gorm.DB
+	interval  time.Duration
+	timeout   time.Duration
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewProber constructs a provider health prober; returns nil when db is nil.
+func NewProber(db *gorm.DB) *Prober {
+	if db == nil {
+		return nil
+	}
+	return &Prober{
+		db:        db,
+		interval:  defaultInterval,
+		timeout:   defaultTimeout,
+		retention: defaultRetention,
+		now:       time.Now,
+	}
+}
+
+// Start launches the probing loop in a background goroutine.
+func (p *Prober) Start(ctx context.Context) {
+	if p == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go p.run(ctx)
+	log.Infof("provider health prober started (interval=%s)", p.interval)
+}
+
+func (p *Prober) run(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		if errRun := p.RunOnce(ctx); errRun != nil {
+			log.WithError(errRun).Warn("provider health prober: run failed")
+		}
+		timer := time.NewTimer(p.interval)
+		select {
+		case <-ctx.Done():
+			if !timer.Stop() {
+				<-timer.C
+			}
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// RunOnce probes every enabled provider API key once and prunes expired checks.
+func (p *Prober) RunOnce(ctx context.Context) error {
+	if p == nil || p.db == nil {
+		return nil
+	}
+	var rows []models.ProviderAPIKey
+	if errFind := p.db.WithContext(ctx).Where("is_enabled = ?", true).Order("id ASC").Find(&rows).Error; errFind != nil {
+		return fmt.Errorf("healthprobe: load provider keys: %w", errFind)
+	}
+
+	sem := make(chan struct{}, maxConcurrency)
+	var wg sync.WaitGroup
+	for i := range rows {
+		target, ok := targetForKey(&rows[i])
+		if !ok {
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			check := p.probe(ctx, target)
+			if errRecord := Record(ctx, p.db, check); errRecord != nil {
+				log.WithError(errRecord).Warnf("provider health prober: record failed (provider=%s key=%d)", target.provider, target.keyID)
+			}
+		}()
+	}
+	wg.Wait()
+
+	cutoff := p.now().Add(-p.retention).UTC()
+	if errPrune := p.db.WithContext(ctx).Where("checked_at < ?", cutoff).Delete(&models.ProviderHealthCheck{}).Error; errPrune != nil {
+		return fmt.Errorf("healthprobe: prune checks: %w", errPrune)
+	}
+	return nil
+}
+
+// probeTarget describes the models-list request used to probe one provider API key.
+type probeTarget struct {
+	provider string
+	keyID    uint64
+	url      string
+	proxyURL string
+	headers  map[string]string
+}
+
+// targetForKey builds the probe request for a provider API key; ok is false when the key
+// cannot be probed.
+func targetForKey(row *models.ProviderAPIKey) (probeTarget, bool) {
+	provider := strings.ToLower(strings.TrimSpace(row.Provider))
+	apiKey := strings.TrimSpace(row.APIKey)
+	baseURL := strings.TrimRight(strings.TrimSpace(row.BaseURL), "/")
+	target := probeTarget{
+		provider: provider,
+		keyID:    row.ID,
+		proxyURL: strings.TrimSpace(row.ProxyURL),
+		headers:  make(map[string]string),
+	}
+	// Compatibility endpoints may accept anonymous requests, the first-party APIs never do.
+	requireKey := true
+
+	switch provider {
+	case "gemini":
+		if baseURL == "" {
+			baseURL = defaultGeminiBaseURL
+		}
+		target.url = baseURL + "/v1beta/models?pageSize=1"
+		target.headers["x-goog-api-key"] = apiKey
+	case "codex":
+		if baseURL == "" {
+			baseURL = defaultCodexBaseURL
+		}
+		target.url = baseURL + "/models"
+		target.headers["Authorization"] = "Bearer " + apiKey
+	case "claude", "claude-code":
+		target.provider = "claude"
+		if baseURL == "" {
+			baseURL = defaultClaudeBaseURL
+		}
+		target.url = baseURL + "/v1/models?limit=1"
+		target.headers["x-api-key"] = apiKey
+		target.headers["anthropic-version"] = "2023-06-01"
+	case "openai", "openai-compatibility":
+		if baseURL == "" {
+			return probeTarget{}, false
+		}
+		// Compatibility providers are told apart by name rather than by the shared provider kind.
+		if name := strings.TrimSpace(row.Name); name != "" {
+			target.provider = name
+		}
+		apiKey, target.proxyURL = firstAPIKeyEntry(row, target.proxyURL)
+		target.url = baseURL + "/models"
+		if apiKey != "" {
+			target.headers["Authorization"] = "Bearer " + apiKey
+		}
+		requireKey = false
+	default:
+		return probeTarget{}, false
+	}
+	if requireKey && apiKey == "" {
+		return probeTarget{}, false
+	}
+
+	var extra map[string]string
+	if len(row.Headers) > 0 && json.Unmarshal(row.Headers, &extra) == nil {
+		for key, value := range extra {
+			target.headers[key] = value
+		}
+	}
+	return target, true
+}
+
+// firstAPIKeyEntry returns the first nested API key of an OpenAI-compatible provider and
+// its proxy, falling back to the provider-level proxy.
+func firstAPIKeyEntry(row *models.ProviderAPIKey, proxyURL string) (string, string) {
+	var entries []struct {
+		APIKey   string `json:"api_key"`
+		ProxyURL string `json:"proxy_url"`
+	}
+	if len(row.APIKeyEntries) == 0 || json.Unmarshal(row.APIKeyEntries, &entries) != nil {
+		return strings.TrimSpace(row.APIKey), proxyURL
+	}
+	for _, entry := range entries {
+		if key := strings.TrimSpace(entry.APIKey); key != "" {
+			if entryProxy := strings.TrimSpace(entry.ProxyURL); entryProxy != "" {
+				proxyURL = entryProxy
+			}
+			return key, proxyURL
+		}
+	}
+	return strings.TrimSpace(row.APIKey), proxyURL
+}
+
+// probe sends the target request and converts the outcome into a health check.
+func (p *Prober) probe(ctx context.Context, target probeTarget) models.ProviderHealthCheck {
+	check := models.ProviderHealthCheck{
+		Provider: target.provider,
+		Source:   SourceAPIKey,
+		TargetID: target.keyID,
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	req, errReq := http.NewRequestWithContext(reqCtx, http.MethodGet, target.url, nil)
+	if errReq != nil {
+		check.Error = errReq.Error()
+		check.CheckedAt = p.now().UTC()
+		return check
+	}
+	for key, value := range target.headers {
+		req.Header.Set(key, value)
+	}
+
+	client := &http.Client{}
+	if target.proxyURL != "" {
+		proxy, errProxy := url.Parse(target.proxyURL)
+		if errProxy != nil {
+			check.Error = "invalid proxy url"
+			check.CheckedAt = p.now().UTC()
+			return check
+		}
+		client.Transport = &http.Transport{Proxy: http.ProxyURL(proxy)}
+	}
+
+	started := p.now()
+	resp, errDo := client.Do(req)
+	check.LatencyMillis = p.now().Sub(started).Milliseconds()
+	check.CheckedAt = p.now().UTC()
+	if errDo != nil {
+		check.Error = errDo.Error()
+		return check
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Errorf("provider health prober: close response body error: %v", errClose)
+		}
+	}()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainBytes))
+
+	check.StatusCode = resp.StatusCode
+	check.Success = resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices
+	if !check.Success {
+		check.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+	return check
+}
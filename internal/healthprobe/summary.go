@@ -0,0 +1,156 @@
+package healthprobe
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// Check sources.
+const (
+	SourceAPIKey = "api_key"
+	SourceAuth   = "auth"
+)
+
+// Health status labels.
+const (
+	StatusHealthy  = "healthy"
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+)
+
+const (
+	// degradedFailureRatio marks a provider degraded once this share of checks fails.
+	degradedFailureRatio = 0.1
+	// downFailureRatio marks a provider down once this share of checks fails.
+	downFailureRatio = 0.5
+)
+
+// ProviderHealth summarizes recent checks for one provider.
+type ProviderHealth struct {
+	Provider         string
+	Status           string
+	Checks           int64
+	Failures         int64
+	P95LatencyMillis int64
+	LastCheckedAt    time.Time
+	LastError        string
+}
+
+// SuccessRate returns the share of successful checks between 0 and 1.
+func (h ProviderHealth) SuccessRate() float64 {
+	if h.Checks == 0 {
+		return 0
+	}
+	return float64(h.Checks-h.Failures) / float64(h.Checks)
+}
+
+// Record stores one health check. A failure following a successful check of the same
+// credential raises a health check failed event; repeated failures stay quiet.
+func Record(ctx context.Context, db *gorm.DB, check models.ProviderHealthCheck) error {
+	if db == nil {
+		return nil
+	}
+	if check.CheckedAt.IsZero() {
+		check.CheckedAt = time.Now().UTC()
+	}
+	var previous []models.ProviderHealthCheck
+	if !check.Success {
+		if errFind := db.WithContext(ctx).
+			Select("id", "success").
+			Where("provider = ? AND source = ? AND target_id = ?", check.Provider, check.Source, check.TargetID).
+			Order("checked_at DESC").
+			Limit(1).
+			Find(&previous).Error; errFind != nil {
+			return fmt.Errorf("healthprobe: load previous check: %w", errFind)
+		}
+	}
+	if errCreate := db.WithContext(ctx).Create(&check).Error; errCreate != nil {
+		return fmt.Errorf("healthprobe: record check: %w", errCreate)
+	}
+	if len(previous) == 1 && previous[0].Success {
+		reason := check.Error
+		if reason == "" {
+			reason = fmt.Sprintf("%s health check failed", check.Provider)
+		}
+		events.PublishHealthCheckFailed(ctx, check.Provider, check.Source, check.TargetID, check.StatusCode, reason)
+	}
+	return nil
+}
+
+// Summarize returns per-provider health for checks since since, ordered by provider name.
+func Summarize(ctx context.Context, db *gorm.DB, since time.Time) ([]ProviderHealth, error) {
+	var rows []models.ProviderHealthCheck
+	if errFind := db.WithContext(ctx).
+		Where("checked_at >= ?", since.UTC()).
+		Order("checked_at ASC").
+		Find(&rows).Error; errFind != nil {
+		return nil, fmt.Errorf("healthprobe: load checks: %w", errFind)
+	}
+
+	byProvider := make(map[string]*ProviderHealth)
+	latencies := make(map[string][]int64)
+	for _, row := range rows {
+		entry, ok := byProvider[row.Provider]
+		if !ok {
+			entry = &ProviderHealth{Provider: row.Provider}
+			byProvider[row.Provider] = entry
+		}
+		entry.Checks++
+		if row.Success {
+			latencies[row.Provider] = append(latencies[row.Provider], row.LatencyMillis)
+		} else {
+			entry.Failures++
+			entry.LastError = row.Error
+		}
+		// Rows are ascending, so the last one seen is the latest.
+		entry.LastCheckedAt = row.CheckedAt
+	}
+
+	out := make([]ProviderHealth, 0, len(byProvider))
+	for provider, entry := range byProvider {
+		entry.P95LatencyMillis = percentile(latencies[provider], 0.95)
+		entry.Status = classify(entry)
+		out = append(out, *entry)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Provider < out[j].Provider })
+	return out, nil
+}
+
+func classify(h *ProviderHealth) string {
+	if h.Checks == 0 {
+		return StatusHealthy
+	}
+	failureRatio := float64(h.Failures) / float64(h.Checks)
+	switch {
+	case failureRatio >= downFailureRatio:
+		return StatusDown
+	case failureRatio >= degradedFailureRatio:
+		return StatusDegraded
+	default:
+		return StatusHealthy
+	}
+}
+
+// percentile returns the nearest-rank percentile of values, or 0 when empty.
+func percentile(values []int64, p float64) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
@@ -3,6 +3,7 @@ package admin
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -13,11 +14,11 @@ import (
 	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
 	handlers "github.com/router-for-me/CLIProxyAPIBusiness/internal/http/api/admin/handlers"
-	"github.com/router-for-me/CLIProxyAPIBusiness/internal/http/api/admin/permissions"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/quota"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
 	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/startupcheck"
 	"gorm.io/gorm"
 )
 
@@ -29,10 +30,18 @@ func RegisterAdminRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, co
 
 	healthHandler := handlers.NewHealthHandler(db)
 	r.GET("/healthz", healthHandler.Healthz)
+	r.GET("/readyz", healthHandler.Readyz)
+
+	metricsHandler := handlers.NewMetricsHandler(db)
+	r.GET("/metrics", metricsHandler.Metrics)
 
 	versionHandler := handlers.NewVersionHandler()
 	r.GET("/v0/version", versionHandler.GetVersion)
 
+	nodeAgentHandler := handlers.NewNodeHandler(db)
+	r.GET("/v0/node/config", nodeAgentHandler.PullConfig)
+	r.POST("/v0/node/status", nodeAgentHandler.ReportStatus)
+
 	adminGroup := r.Group("/v0/admin")
 
 	webAuthn, errWebAuthn := security.NewWebAuthn()
@@ -44,17 +53,22 @@ func RegisterAdminRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, co
 	adminGroup.POST("/login", authHandler.Login)
 	adminGroup.POST("/login/prepare", authHandler.LoginPrepare)
 	adminGroup.POST("/login/totp", authHandler.LoginTOTP)
+	adminGroup.POST("/login/recovery-code", authHandler.LoginRecoveryCode)
 	adminGroup.POST("/login/passkey/options", authHandler.LoginPasskeyOptions)
 	adminGroup.POST("/login/passkey/verify", authHandler.LoginPasskeyVerify)
+	adminGroup.GET("/auth/oidc/login", authHandler.OIDCLogin)
+	adminGroup.GET("/auth/oidc/callback", authHandler.OIDCCallback)
 
 	selfAuthed := adminGroup.Group("")
 	selfAuthed.Use(adminAuthMiddleware(db, jwtCfg))
+	selfAuthed.Use(adminReadOnlyMiddleware())
 
 	mfaHandler := handlers.NewMFAHandler(db, webAuthn)
 	selfAuthed.GET("/mfa/status", mfaHandler.Status)
 	selfAuthed.POST("/mfa/totp/prepare", mfaHandler.PrepareTOTP)
 	selfAuthed.POST("/mfa/totp/confirm", mfaHandler.ConfirmTOTP)
 	selfAuthed.POST("/mfa/totp/disable", mfaHandler.DisableTOTP)
+	selfAuthed.POST("/mfa/totp/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
 	selfAuthed.POST("/mfa/passkey/options", mfaHandler.BeginPasskeyRegistration)
 	selfAuthed.POST("/mfa/passkey/verify", mfaHandler.FinishPasskeyRegistration)
 	selfAuthed.POST("/mfa/passkey/disable", mfaHandler.DisablePasskey)
@@ -62,29 +76,71 @@ func RegisterAdminRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, co
 	authed := adminGroup.Group("")
 	authed.Use(adminAuthMiddleware(db, jwtCfg))
 	authed.Use(adminPermissionMiddleware(db))
+	authed.Use(adminReadOnlyMiddleware())
+	authed.Use(adminRedactionMiddleware())
+	authed.Use(adminAnonymizeMiddleware(jwtCfg.Secret))
+	authed.Use(adminClusterMiddleware())
 
 	apiKeyHandler := handlers.NewAPIKeyHandler(db)
 	authed.POST("/api-keys", apiKeyHandler.Create)
 	authed.GET("/api-keys", apiKeyHandler.List)
 	authed.DELETE("/api-keys/:id", apiKeyHandler.Revoke)
+	authed.PUT("/api-keys/:id/limits", apiKeyHandler.UpdateLimits)
 	authed.POST("/users/:id/api-keys", apiKeyHandler.CreateForUser)
 	authed.GET("/users/:id/api-keys", apiKeyHandler.ListByUser)
 
 	providerKeyHandler := handlers.NewProviderAPIKeyHandler(db, configPath)
 	authed.POST("/provider-api-keys", providerKeyHandler.Create)
 	authed.GET("/provider-api-keys", providerKeyHandler.List)
+	authed.POST("/provider-api-keys/reorder", providerKeyHandler.Reorder)
 	authed.PUT("/provider-api-keys/:id", providerKeyHandler.Update)
 	authed.DELETE("/provider-api-keys/:id", providerKeyHandler.Delete)
+	authed.POST("/provider-api-keys/:id/canary", providerKeyHandler.StartCanary)
+	authed.POST("/provider-api-keys/:id/canary/promote", providerKeyHandler.PromoteCanary)
+	authed.POST("/provider-api-keys/:id/canary/rollback", providerKeyHandler.RollbackCanary)
+	authed.GET("/provider-api-keys/:id/canary/report", providerKeyHandler.CanaryReport)
+	authed.GET("/environments", providerKeyHandler.ListEnvironments)
+	authed.POST("/environments/sync", providerKeyHandler.SyncEnvironments)
+
+	retryPolicyHandler := handlers.NewRetryPolicyHandler(db)
+	authed.GET("/retry-policies", retryPolicyHandler.List)
+	authed.POST("/retry-policies", retryPolicyHandler.Create)
+	authed.PUT("/retry-policies/:id", retryPolicyHandler.Update)
+	authed.DELETE("/retry-policies/:id", retryPolicyHandler.Delete)
 
 	proxyHandler := handlers.NewProxyHandler(db)
 	authed.POST("/proxies", proxyHandler.Create)
 	authed.POST("/proxies/batch", proxyHandler.BatchCreate)
 	authed.GET("/proxies", proxyHandler.List)
+	authed.GET("/proxies/health", proxyHandler.Health)
 	authed.PUT("/proxies/:id", proxyHandler.Update)
 	authed.DELETE("/proxies/:id", proxyHandler.Delete)
 
+	nodeHandler := handlers.NewNodeHandler(db)
+	authed.POST("/nodes", nodeHandler.Create)
+	authed.GET("/nodes", nodeHandler.List)
+	authed.PUT("/nodes/:id", nodeHandler.Update)
+	authed.DELETE("/nodes/:id", nodeHandler.Delete)
+	authed.POST("/nodes/:id/rotate-token", nodeHandler.RotateToken)
+	authed.POST("/nodes/:id/push", nodeHandler.Push)
+
+	clusterHandler := handlers.NewClusterHandler(db)
+	authed.GET("/cluster/instances", clusterHandler.ListInstances)
+
+	backupHandler := handlers.NewBackupHandler(db)
+	authed.GET("/backup", backupHandler.Backup)
+	authed.POST("/restore", backupHandler.Restore)
+
+	userLifecycleHandler := handlers.NewUserLifecycleHandler(db)
+	authed.GET("/user-lifecycle/events", userLifecycleHandler.ListEvents)
+	authed.POST("/user-lifecycle/run", userLifecycleHandler.Run)
+
 	usageHandler := handlers.NewUsageHandler(db)
 	authed.GET("/usage", usageHandler.List)
+	authed.GET("/usage/daily", usageHandler.Daily)
+	authed.GET("/usage/proxies", usageHandler.Proxies)
+	authed.GET("/usages/stream", usageHandler.Stream)
+	authed.GET("/usages/:id/billing-explanation", usageHandler.BillingExplanation)
 
 	billingHandler := handlers.NewBillingHandler(db)
 	authed.GET("/billing/summary", billingHandler.Summary)
@@ -99,6 +155,83 @@ func RegisterAdminRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, co
 	authed.POST("/users/:id/enable", userHandler.Enable)
 	authed.PUT("/users/:id/password", userHandler.ChangePassword)
 
+	userGroupMigrationHandler := handlers.NewUserGroupMigrationHandler(db)
+	authed.POST("/users/:id/group-migrations", userGroupMigrationHandler.Create)
+	authed.GET("/users/:id/group-migrations", userGroupMigrationHandler.ListByUser)
+	authed.POST("/user-group-migrations/:id/cancel", userGroupMigrationHandler.Cancel)
+
+	balanceTransactionHandler := handlers.NewBalanceTransactionHandler(db)
+	authed.POST("/users/:id/balance-adjustments", balanceTransactionHandler.Adjust)
+	authed.GET("/balance-transactions", balanceTransactionHandler.List)
+
+	bulkDeleteJobHandler := handlers.NewBulkDeleteJobHandler(db)
+	authed.POST("/bulk-delete-jobs", bulkDeleteJobHandler.Create)
+	authed.GET("/bulk-delete-jobs", bulkDeleteJobHandler.List)
+	authed.GET("/bulk-delete-jobs/:id", bulkDeleteJobHandler.Get)
+	authed.POST("/bulk-delete-jobs/:id/cancel", bulkDeleteJobHandler.Cancel)
+
+	costReplayHandler := handlers.NewCostReplayHandler(db)
+	authed.POST("/billing-rule-snapshots", costReplayHandler.CreateSnapshot)
+	authed.GET("/billing-rule-snapshots", costReplayHandler.ListSnapshots)
+	authed.GET("/billing-rule-snapshots/:id", costReplayHandler.GetSnapshot)
+	authed.POST("/cost-replay-jobs", costReplayHandler.CreateJob)
+	authed.GET("/cost-replay-jobs", costReplayHandler.ListJobs)
+	authed.GET("/cost-replay-jobs/:id", costReplayHandler.GetJob)
+	authed.POST("/cost-replay-jobs/:id/cancel", costReplayHandler.CancelJob)
+
+	exchangeRateHandler := handlers.NewExchangeRateHandler(db)
+	authed.GET("/exchange-rates", exchangeRateHandler.List)
+	authed.POST("/exchange-rates/fetch", exchangeRateHandler.Fetch)
+	authed.PUT("/exchange-rates/:currency", exchangeRateHandler.Set)
+	authed.DELETE("/exchange-rates/:currency", exchangeRateHandler.Delete)
+
+	notificationHandler := handlers.NewNotificationHandler(db)
+	authed.GET("/notifications", notificationHandler.List)
+	authed.PUT("/notifications", notificationHandler.UpdateAll)
+	authed.PUT("/notifications/:id", notificationHandler.Update)
+
+	sloHandler := handlers.NewSLOHandler(db)
+	authed.GET("/slos", sloHandler.List)
+	authed.POST("/slos", sloHandler.Create)
+	authed.GET("/slos/status", sloHandler.Status)
+	authed.GET("/slos/:id/status", sloHandler.StatusOne)
+	authed.PUT("/slos/:id", sloHandler.Update)
+	authed.DELETE("/slos/:id", sloHandler.Delete)
+
+	webhookHandler := handlers.NewWebhookHandler(db)
+	authed.GET("/webhooks", webhookHandler.List)
+	authed.POST("/webhooks", webhookHandler.Create)
+	authed.PUT("/webhooks/:id", webhookHandler.Update)
+	authed.DELETE("/webhooks/:id", webhookHandler.Delete)
+	authed.GET("/webhooks/:id/deliveries", webhookHandler.Deliveries)
+	authed.POST("/webhooks/:id/test", webhookHandler.Test)
+	authed.POST("/webhook-deliveries/:id/retry", webhookHandler.RetryDelivery)
+
+	mfaComplianceHandler := handlers.NewMFAComplianceHandler(db)
+	authed.GET("/mfa-compliance", mfaComplianceHandler.Report)
+
+	chaosHandler := handlers.NewChaosHandler()
+	authed.GET("/chaos/faults", chaosHandler.List)
+	authed.POST("/chaos/faults", chaosHandler.Inject)
+	authed.DELETE("/chaos/faults", chaosHandler.ClearAll)
+	authed.DELETE("/chaos/faults/:id", chaosHandler.Clear)
+
+	invoiceHandler := handlers.NewInvoiceHandler(db)
+	authed.POST("/invoices/generate", invoiceHandler.Generate)
+	authed.GET("/invoices", invoiceHandler.List)
+	authed.GET("/invoices/:id", invoiceHandler.Get)
+	authed.GET("/invoices/:id/render", invoiceHandler.Render)
+	authed.POST("/invoices/:id/finalize", invoiceHandler.Finalize)
+	authed.POST("/invoices/:id/mark-paid", invoiceHandler.MarkPaid)
+	authed.POST("/invoices/:id/send", invoiceHandler.Send)
+
+	coopHandler := handlers.NewCoopHandler(db)
+	authed.GET("/coop/contributions", coopHandler.List)
+	authed.POST("/coop/contributions/:id/suspend", coopHandler.Suspend)
+	authed.POST("/coop/contributions/:id/resume", coopHandler.Resume)
+	authed.DELETE("/coop/contributions/:id", coopHandler.Revoke)
+	authed.POST("/coop/settle", coopHandler.Settle)
+
 	authGroupHandler := handlers.NewAuthGroupHandler(db)
 	authed.POST("/auth-groups", authGroupHandler.Create)
 	authed.GET("/auth-groups", authGroupHandler.List)
@@ -111,6 +244,9 @@ func RegisterAdminRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, co
 	authed.POST("/auth-files", authFileHandler.Create)
 	authed.POST("/auth-files/import", authFileHandler.Import)
 	authed.POST("/auth-files/import-by-provider", authFileHandler.ImportByProvider)
+	authed.GET("/auth-files/import-conflicts", authFileHandler.ListImportConflicts)
+	authed.POST("/auth-files/import-conflicts/:id/resolve", authFileHandler.ResolveImportConflict)
+	authed.POST("/auth-files/poll-enabled", authFileHandler.SetPollEnabled)
 	authed.GET("/auth-files", authFileHandler.List)
 	authed.GET("/auth-files/:id", authFileHandler.Get)
 	authed.PUT("/auth-files/:id", authFileHandler.Update)
@@ -118,7 +254,11 @@ func RegisterAdminRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, co
 	authed.POST("/auth-files/:id/available", authFileHandler.SetAvailable)
 	authed.POST("/auth-files/:id/unavailable", authFileHandler.SetUnavailable)
 	authed.GET("/auth-files/types", authFileHandler.ListTypes)
+	authed.GET("/auth-files/health", authFileHandler.Health)
 	authed.GET("/auth-files/model-presets", authFileHandler.ListModelPresets)
+	authed.GET("/auth-files/pending", authFileHandler.ListPending)
+	authed.POST("/auth-files/:id/approve", authFileHandler.Approve)
+	authed.POST("/auth-files/:id/reject", authFileHandler.Reject)
 
 	var quotaRefresher interface {
 		RefreshByAuthKey(ctx context.Context, authKey string) error
@@ -129,8 +269,10 @@ func RegisterAdminRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, co
 	quotaTaskStore := handlers.NewQuotaManualRefreshTaskStore(24*time.Hour, 200)
 	quotaHandler := handlers.NewQuotaHandler(db, quotaRefresher, quotaTaskStore)
 	authed.GET("/quotas", quotaHandler.List)
+	authed.GET("/quotas/providers", quotaHandler.ListProviders)
 	authed.POST("/quotas/manual-refresh", quotaHandler.CreateManualRefresh)
 	authed.GET("/quotas/manual-refresh/:task_id", quotaHandler.GetManualRefresh)
+	authed.POST("/auth-files/:id/refresh-quota", quotaHandler.RefreshAuthFile)
 
 	userGroupHandler := handlers.NewUserGroupHandler(db)
 	authed.POST("/user-groups", userGroupHandler.Create)
@@ -157,6 +299,19 @@ func RegisterAdminRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, co
 	authed.PUT("/prepaid-cards/:id", prepaidCardHandler.Update)
 	authed.DELETE("/prepaid-cards/:id", prepaidCardHandler.Delete)
 
+	requestLogHandler := handlers.NewRequestLogHandler(db)
+	authed.GET("/request-logs", requestLogHandler.List)
+	authed.POST("/request-logs/purge", requestLogHandler.Purge)
+	authed.GET("/request-logs/:id", requestLogHandler.Get)
+	authed.POST("/request-logs/:id/redact", requestLogHandler.Redact)
+
+	invitationHandler := handlers.NewInvitationHandler(db)
+	authed.POST("/invitations", invitationHandler.Create)
+	authed.GET("/invitations", invitationHandler.List)
+	authed.GET("/invitations/:id", invitationHandler.Get)
+	authed.PUT("/invitations/:id", invitationHandler.Update)
+	authed.DELETE("/invitations/:id", invitationHandler.Delete)
+
 	adminHandler := handlers.NewAdminHandler(db)
 	authed.POST("/admins", adminHandler.Create)
 	authed.GET("/admins", adminHandler.List)
@@ -167,6 +322,13 @@ func RegisterAdminRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, co
 	authed.POST("/admins/:id/enable", adminHandler.Enable)
 	authed.PUT("/admins/:id/password", adminHandler.ChangePassword)
 
+	adminRoleHandler := handlers.NewAdminRoleHandler(db)
+	authed.GET("/admin-roles", adminRoleHandler.List)
+	authed.GET("/admin-roles/templates", adminRoleHandler.Templates)
+	authed.POST("/admin-roles", adminRoleHandler.Create)
+	authed.PUT("/admin-roles/:id", adminRoleHandler.Update)
+	authed.DELETE("/admin-roles/:id", adminRoleHandler.Delete)
+
 	permissionHandler := handlers.NewPermissionHandler()
 	authed.GET("/permissions", permissionHandler.List)
 
@@ -184,12 +346,24 @@ func RegisterAdminRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, co
 	authed.GET("/model-mappings", modelMappingHandler.List)
 	authed.GET("/model-mappings/providers", modelMappingHandler.AvailableProviders)
 	authed.GET("/model-mappings/available-models", modelMappingHandler.AvailableModels)
+	authed.POST("/model-mappings/bulk", modelMappingHandler.BulkUpsert)
+	authed.GET("/model-mappings/resolve", modelMappingHandler.Resolve)
+	authed.GET("/model-mappings/conflicts", modelMappingHandler.Conflicts)
 	authed.GET("/model-mappings/:id", modelMappingHandler.Get)
 	authed.PUT("/model-mappings/:id", modelMappingHandler.Update)
 	authed.DELETE("/model-mappings/:id", modelMappingHandler.Delete)
 	authed.POST("/model-mappings/:id/enable", modelMappingHandler.Enable)
 	authed.POST("/model-mappings/:id/disable", modelMappingHandler.Disable)
 
+	modelCatalogHandler := handlers.NewModelCatalogHandler(db)
+	authed.GET("/models/catalog", modelCatalogHandler.Catalog)
+
+	modelDisplayHandler := handlers.NewModelDisplayHandler(db)
+	authed.POST("/model-displays", modelDisplayHandler.Create)
+	authed.GET("/model-displays", modelDisplayHandler.List)
+	authed.PUT("/model-displays/:id", modelDisplayHandler.Update)
+	authed.DELETE("/model-displays/:id", modelDisplayHandler.Delete)
+
 	payloadRuleHandler := handlers.NewModelPayloadRuleHandler(db)
 	authed.GET("/model-mappings/:id/payload-rules", payloadRuleHandler.List)
 	authed.POST("/model-mappings/:id/payload-rules", payloadRuleHandler.Create)
@@ -216,18 +390,56 @@ func RegisterAdminRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, co
 	authed.POST("/plans/:id/enable", planHandler.Enable)
 	authed.POST("/plans/:id/disable", planHandler.Disable)
 
+	tierUpgradeHandler := handlers.NewTierUpgradeHandler(db)
+	authed.POST("/tier-upgrade-rules", tierUpgradeHandler.CreateRule)
+	authed.GET("/tier-upgrade-rules", tierUpgradeHandler.ListRules)
+	authed.PUT("/tier-upgrade-rules/:id", tierUpgradeHandler.UpdateRule)
+	authed.DELETE("/tier-upgrade-rules/:id", tierUpgradeHandler.DeleteRule)
+	authed.GET("/tier-upgrades", tierUpgradeHandler.List)
+	authed.POST("/tier-upgrades/:id/approve", tierUpgradeHandler.Approve)
+	authed.POST("/tier-upgrades/:id/reject", tierUpgradeHandler.Reject)
+
 	settingHandler := handlers.NewSettingHandler(db)
 	authed.POST("/settings", settingHandler.Create)
 	authed.GET("/settings", settingHandler.List)
+	authed.PUT("/settings", settingHandler.BulkUpdate)
+	authed.GET("/settings/registry", settingHandler.Registry)
+	authed.GET("/settings/history", settingHandler.History)
 	authed.GET("/settings/:key", settingHandler.Get)
 	authed.PUT("/settings/:key", settingHandler.Update)
 	authed.DELETE("/settings/:key", settingHandler.Delete)
 
+	securityConfigHandler := handlers.NewSecurityConfigHandler(db, jwtCfg)
+	authed.GET("/security/jwt", securityConfigHandler.JWTStatus)
+	authed.POST("/security/jwt/rotate", securityConfigHandler.RotateJWT)
+	authed.GET("/security/webauthn", securityConfigHandler.WebAuthnConfig)
+	authed.PUT("/security/webauthn", securityConfigHandler.UpdateWebAuthn)
+
+	organizationHandler := handlers.NewOrganizationHandler(db, jwtCfg)
+	authed.POST("/organizations", organizationHandler.Create)
+	authed.GET("/organizations", organizationHandler.List)
+	authed.GET("/organizations/current", organizationHandler.Current)
+	authed.POST("/organizations/select", organizationHandler.Select)
+	authed.GET("/organizations/:id", organizationHandler.Get)
+	authed.PUT("/organizations/:id", organizationHandler.Update)
+	authed.DELETE("/organizations/:id", organizationHandler.Delete)
+
+	consistencyHandler := handlers.NewConsistencyHandler(db, configPath)
+	authed.GET("/consistency", consistencyHandler.Report)
+	authed.POST("/consistency/repair", consistencyHandler.Repair)
+
+	webhookTemplateHandler := handlers.NewWebhookTemplateHandler()
+	authed.POST("/webhook-templates/validate", webhookTemplateHandler.Validate)
+	authed.POST("/webhook-templates/test-fire", webhookTemplateHandler.TestFire)
+
 	dashboardHandler := handlers.NewDashboardHandler(db)
 	authed.GET("/dashboard/kpi", dashboardHandler.KPI)
+	authed.GET("/dashboard/kpi/history", dashboardHandler.KPIHistory)
 	authed.GET("/dashboard/traffic", dashboardHandler.Traffic)
 	authed.GET("/dashboard/cost-distribution", dashboardHandler.CostDistribution)
 	authed.GET("/dashboard/model-health", dashboardHandler.ModelHealth)
+	authed.GET("/dashboard/retry-pressure", dashboardHandler.RetryPressure)
+	authed.GET("/dashboard/error-breakdown", dashboardHandler.ErrorBreakdown)
 	authed.GET("/dashboard/transactions", dashboardHandler.RecentTransactions)
 	authed.GET("/dashboard/transactions/:id/request-log", dashboardHandler.GetTransactionRequestLog)
 
@@ -259,6 +471,20 @@ func RegisterAdminRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, co
 		authed.POST("/tokens/iflow-cookie", tokenRequester.RequestIFlowCookieToken)
 		authed.POST("/tokens/get-auth-status", tokenRequester.GetAuthStatus)
 		authed.POST("/tokens/oauth-callback", tokenRequester.PostOAuthCallback)
+
+		reauthHandler := handlers.NewAuthFileReauthHandler(db, map[string]gin.HandlerFunc{
+			"claude":         withOAuthCallbackDefaults(tokenRequester.RequestAnthropicToken),
+			"gemini":         withOAuthCallbackDefaults(tokenRequester.RequestGeminiCLIToken),
+			"codex":          withOAuthCallbackDefaults(tokenRequester.RequestCodexToken),
+			"antigravity":    withOAuthCallbackDefaults(tokenRequester.RequestAntigravityToken),
+			"qwen":           tokenRequester.RequestQwenToken,
+			"kiro":           withOAuthCallbackDefaults(tokenRequester.RequestKiroToken),
+			"kimi":           tokenRequester.RequestKimiToken,
+			"github-copilot": tokenRequester.RequestGitHubToken,
+			"kilo":           tokenRequester.RequestKiloToken,
+			"iflow":          withOAuthCallbackDefaults(tokenRequester.RequestIFlowToken),
+		})
+		authed.POST("/auth-files/:id/reauth", reauthHandler.Reauth)
 	}
 }
 
@@ -318,6 +544,11 @@ func adminAuthMiddleware(db *gorm.DB, jwtCfg config.JWTConfig) gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			return
 		}
+		// Enrollment-only tokens issued under MFA_POLICY may reach nothing but the MFA endpoints.
+		if claims.Scope == security.TokenScopeMFAEnrollment && !strings.HasPrefix(c.FullPath(), "/v0/admin/mfa/") {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "mfa enrollment required", "mfa_enrollment_required": true})
+			return
+		}
 
 		var admin models.Admin
 		if errFind := db.WithContext(c.Request.Context()).First(&admin, claims.AdminID).Error; errFind != nil {
@@ -328,12 +559,50 @@ func adminAuthMiddleware(db *gorm.DB, jwtCfg config.JWTConfig) gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin disabled"})
 			return
 		}
+		if !handlers.AdminIPAllowed(c, admin) {
+			return
+		}
 
-		adminPermissions := permissions.ParsePermissions(admin.Permissions)
+		organizationID, errOrganization := handlers.AdminOrganizationScope(c.Request.Context(), db, admin, claims.OrganizationID)
+		switch {
+		case errors.Is(errOrganization, handlers.ErrOrganizationDisabled):
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "organization disabled"})
+			return
+		case errors.Is(errOrganization, handlers.ErrOrganizationMismatch), errors.Is(errOrganization, handlers.ErrOrganizationNotFound):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			return
+		case errOrganization != nil:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "load organization failed"})
+			return
+		}
+
+		adminPermissions, errPermissions := handlers.EffectiveAdminPermissions(c.Request.Context(), db, admin)
+		if errPermissions != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "load permissions failed"})
+			return
+		}
 		c.Set("adminID", admin.ID)
 		c.Set("adminUsername", admin.Username)
 		c.Set("adminPermissions", adminPermissions)
 		c.Set("adminIsSuperAdmin", admin.IsSuperAdmin)
+		c.Set("adminOrganizationID", organizationID)
+		c.Set("adminOrganizationAdmin", admin.OrganizationID != nil)
+		c.Next()
+	}
+}
+
+// adminReadOnlyMiddleware rejects admin writes while the startup check reports a degraded boot.
+func adminReadOnlyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if startupcheck.Current().ReadOnly() {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "server is in read-only mode, see /readyz"})
+			return
+		}
 		c.Next()
 	}
 }
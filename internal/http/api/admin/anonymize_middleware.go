@@ -0,0 +1,159 @@
+package admin
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+)
+
+// anonymizedRoutePrefixes lists the aggregate analytics endpoints covered by ANALYTICS_ANONYMIZE.
+var anonymizedRoutePrefixes = []string{
+	"/v0/admin/dashboard",
+	"/v0/admin/logs",
+	"/v0/admin/usage",
+	"/v0/admin/usages",
+	"/v0/admin/billing/summary",
+}
+
+// pseudonymFields maps normalized field names (lowercase, no underscores) to the pseudonym
+// prefix used for their values. Both snake_case tags and untagged model fields match.
+var pseudonymFields = map[string]string{
+	"username":  "user",
+	"email":     "user",
+	"useremail": "user",
+	"userid":    "user",
+	"apikeyid":  "key",
+	"apikey":    "key",
+	"authkey":   "key",
+	"authindex": "key",
+	"authid":    "key",
+}
+
+// adminAnonymizeMiddleware replaces user and key identifiers in analytics responses with
+// stable pseudonyms when ANALYTICS_ANONYMIZE is enabled. Super admins see real identities.
+func adminAnonymizeMiddleware(secret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !analyticsAnonymizeEnabled() || !isAnonymizedRoute(c.FullPath()) {
+			c.Next()
+			return
+		}
+		if isSuperAdmin, ok := readAdminIsSuperAdminFromContext(c); ok && isSuperAdmin {
+			c.Next()
+			return
+		}
+
+		writer := &redactingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if !writer.buffering {
+			return
+		}
+		body := writer.buf.Bytes()
+		if anonymized, ok := anonymizeJSON(body, secret); ok {
+			body = anonymized
+		}
+		c.Writer.Header().Del("Content-Length")
+		_, _ = c.Writer.Write(body)
+	}
+}
+
+// analyticsAnonymizeEnabled reports whether the ANALYTICS_ANONYMIZE switch is on.
+func analyticsAnonymizeEnabled() bool {
+	raw, ok := internalsettings.DBConfigValue(internalsettings.AnalyticsAnonymizeKey)
+	if !ok {
+		return internalsettings.DefaultAnalyticsAnonymize
+	}
+	raw = bytes.TrimSpace(raw)
+	var enabled bool
+	if errUnmarshal := json.Unmarshal(raw, &enabled); errUnmarshal == nil {
+		return enabled
+	}
+	var text string
+	if errUnmarshal := json.Unmarshal(raw, &text); errUnmarshal == nil {
+		text = strings.TrimSpace(text)
+		return strings.EqualFold(text, "true") || text == "1"
+	}
+	return false
+}
+
+// isAnonymizedRoute reports whether a route serves aggregate analytics.
+func isAnonymizedRoute(path string) bool {
+	for _, prefix := range anonymizedRoutePrefixes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// anonymizeJSON replaces identifiers in a JSON document; ok is false when the body is not valid JSON.
+func anonymizeJSON(body []byte, secret string) ([]byte, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var doc any
+	if errDecode := decoder.Decode(&doc); errDecode != nil {
+		return nil, false
+	}
+	out, errMarshal := json.Marshal(anonymizeValue(doc, secret))
+	if errMarshal != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+// anonymizeValue walks a decoded JSON value and pseudonymizes identifier fields.
+func anonymizeValue(value any, secret string) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		for key, field := range typed {
+			if prefix, ok := pseudonymFields[strings.ReplaceAll(strings.ToLower(key), "_", "")]; ok {
+				typed[key] = pseudonymize(field, prefix, secret)
+				continue
+			}
+			typed[key] = anonymizeValue(field, secret)
+		}
+		return typed
+	case []any:
+		for i, item := range typed {
+			typed[i] = anonymizeValue(item, secret)
+		}
+		return typed
+	default:
+		return value
+	}
+}
+
+// pseudonymize derives a stable pseudonym from an identifier. The same value always maps to
+// the same pseudonym so charts and tables stay consistent, and the keyed hash keeps it from
+// being reversed by hashing guessed usernames.
+func pseudonymize(value any, prefix, secret string) any {
+	var raw string
+	switch typed := value.(type) {
+	case nil:
+		return nil
+	case string:
+		raw = strings.TrimSpace(typed)
+	case json.Number:
+		raw = typed.String()
+	default:
+		encoded, errMarshal := json.Marshal(typed)
+		if errMarshal != nil {
+			return redactedValue
+		}
+		raw = string(encoded)
+	}
+	if raw == "" {
+		return value
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(prefix + ":" + raw))
+	return prefix + "-" + hex.EncodeToString(mac.Sum(nil))[:12]
+}
@@ -0,0 +1,90 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+)
+
+func newAnonymizeTestEngine(superAdmin bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("adminIsSuperAdmin", superAdmin)
+		c.Next()
+	})
+	r.Use(adminAnonymizeMiddleware("test-secret"))
+	r.GET("/v0/admin/dashboard/transactions", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"transactions": []gin.H{
+			{"username": "alice", "model": "gpt-5", "cost_micros": 10},
+			{"username": "bob", "model": "gpt-5", "cost_micros": 20},
+			{"username": "alice", "model": "claude", "cost_micros": 30},
+		}})
+	})
+	r.GET("/v0/admin/usage", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"usage": []gin.H{{"UserID": 7, "APIKeyID": 3, "AuthIndex": "", "Model": "gpt-5"}}})
+	})
+	r.GET("/v0/admin/users", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"users": []gin.H{{"username": "alice"}}})
+	})
+	return r
+}
+
+func getAnonymizedJSON(t *testing.T, r *gin.Engine, path string) map[string]any {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	var out map[string]any
+	if errDecode := json.Unmarshal(w.Body.Bytes(), &out); errDecode != nil {
+		t.Fatalf("GET %s: decode body: %v (%s)", path, errDecode, w.Body.String())
+	}
+	return out
+}
+
+func TestAdminAnonymizeMiddlewarePseudonymizesAnalytics(t *testing.T) {
+	internalsettings.StoreDBConfig(time.Now(), map[string]json.RawMessage{
+		internalsettings.AnalyticsAnonymizeKey: json.RawMessage(`true`),
+	})
+	t.Cleanup(func() { internalsettings.StoreDBConfig(time.Now(), nil) })
+	r := newAnonymizeTestEngine(false)
+
+	rows := getAnonymizedJSON(t, r, "/v0/admin/dashboard/transactions")["transactions"].([]any)
+	first := rows[0].(map[string]any)["username"].(string)
+	second := rows[1].(map[string]any)["username"].(string)
+	third := rows[2].(map[string]any)["username"].(string)
+	if !strings.HasPrefix(first, "user-") || first == "alice" || first != third || first == second {
+		t.Fatalf("expected stable distinct pseudonyms, got %q %q %q", first, second, third)
+	}
+	if rows[0].(map[string]any)["model"] != "gpt-5" {
+		t.Fatalf("expected non-identity fields untouched, got %v", rows[0])
+	}
+
+	usage := getAnonymizedJSON(t, r, "/v0/admin/usage")["usage"].([]any)[0].(map[string]any)
+	if !strings.HasPrefix(usage["UserID"].(string), "user-") || !strings.HasPrefix(usage["APIKeyID"].(string), "key-") || usage["AuthIndex"] != "" {
+		t.Fatalf("unexpected usage anonymization: %v", usage)
+	}
+
+	users := getAnonymizedJSON(t, r, "/v0/admin/users")["users"].([]any)[0].(map[string]any)
+	if users["username"] != "alice" {
+		t.Fatalf("expected non-analytics routes untouched, got %v", users)
+	}
+
+	super := getAnonymizedJSON(t, newAnonymizeTestEngine(true), "/v0/admin/dashboard/transactions")["transactions"].([]any)
+	if super[0].(map[string]any)["username"] != "alice" {
+		t.Fatalf("expected super admins to see real usernames, got %v", super[0])
+	}
+}
+
+func TestAdminAnonymizeMiddlewareDisabledByDefault(t *testing.T) {
+	internalsettings.StoreDBConfig(time.Now(), nil)
+	rows := getAnonymizedJSON(t, newAnonymizeTestEngine(false), "/v0/admin/dashboard/transactions")["transactions"].([]any)
+	if rows[0].(map[string]any)["username"] != "alice" {
+		t.Fatalf("expected usernames untouched when disabled, got %v", rows[0])
+	}
+}
@@ -0,0 +1,50 @@
+package admin
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/cluster"
+)
+
+// clusterEventRoutes maps the first admin path segment to the change event its writes publish.
+var clusterEventRoutes = map[string]string{
+	"settings":          cluster.KindSettingsChanged,
+	"security":          cluster.KindSettingsChanged,
+	"auth-files":        cluster.KindConfigChanged,
+	"auth-groups":       cluster.KindConfigChanged,
+	"environments":      cluster.KindConfigChanged,
+	"model-mappings":    cluster.KindConfigChanged,
+	"provider-api-keys": cluster.KindConfigChanged,
+	"proxies":           cluster.KindConfigChanged,
+	"retry-policies":    cluster.KindConfigChanged,
+}
+
+// adminClusterMiddleware tells peer instances to resync after a successful write that feeds
+// the SDK config or the DB-backed settings.
+func adminClusterMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+		if status := c.Writer.Status(); status < http.StatusOK || status >= http.StatusMultipleChoices {
+			return
+		}
+		if kind := clusterEventKind(c.FullPath()); kind != "" {
+			cluster.Publish(c.Request.Context(), kind)
+		}
+	}
+}
+
+// clusterEventKind returns the event published for writes to route, or "" for none.
+func clusterEventKind(route string) string {
+	rest := strings.TrimPrefix(route, "/v0/admin/")
+	if rest == route {
+		return ""
+	}
+	segment, _, _ := strings.Cut(rest, "/")
+	return clusterEventRoutes[segment]
+}
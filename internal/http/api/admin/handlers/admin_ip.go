@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/ipallow"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// AdminIPAllowed reports whether the request comes from an address on the admin's allowlist.
+// Rejections are published for the audit log and answered with 403.
+func AdminIPAllowed(c *gin.Context, admin models.Admin) bool {
+	allowed := ipallow.FromJSON(admin.AllowedIPs)
+	if len(allowed) == 0 {
+		return true
+	}
+	clientIP := ipallow.ClientIP(c.Request, ipallow.TrustedProxies())
+	if allowed.Contains(clientIP) {
+		return true
+	}
+	events.PublishIPRejected(c.Request.Context(), "admin:"+strconv.FormatUint(admin.ID, 10), clientIP.String(), c.Request.URL.Path)
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "source ip not allowed"})
+	return false
+}
+
+// loadAdminAllowedIPs reads the admin's allowlist for flows that load a partial admin row.
+func loadAdminAllowedIPs(c *gin.Context, db *gorm.DB, adminID uint64) (datatypes.JSON, error) {
+	var row models.Admin
+	if errFind := db.WithContext(c.Request.Context()).Select("id", "allowed_ips").First(&row, adminID).Error; errFind != nil {
+		return nil, errFind
+	}
+	return row.AllowedIPs, nil
+}
+
+// allowedIPsOrEmpty renders a stored allowlist as a JSON array.
+func allowedIPsOrEmpty(raw datatypes.JSON) []string {
+	return ipallow.FromJSON(raw).Entries()
+}
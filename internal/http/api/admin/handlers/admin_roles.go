@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/http/api/admin/permissions"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// EffectiveAdminPermissions resolves an admin's permissions from assigned roles, direct grants
+// and denied keys.
+func EffectiveAdminPermissions(ctx context.Context, db *gorm.DB, admin models.Admin) ([]string, error) {
+	var roles []models.AdminRole
+	if errFind := db.WithContext(ctx).
+		Select("admin_roles.id", "admin_roles.permissions").
+		Joins("JOIN admin_role_assignments ON admin_role_assignments.role_id = admin_roles.id").
+		Where("admin_role_assignments.admin_id = ?", admin.ID).
+		Find(&roles).Error; errFind != nil {
+		return nil, errFind
+	}
+	rolePermissions := make([][]string, 0, len(roles))
+	for _, role := range roles {
+		rolePermissions = append(rolePermissions, permissions.ParsePermissions(role.Permissions))
+	}
+	return permissions.Effective(
+		permissions.ParsePermissions(admin.Permissions),
+		rolePermissions,
+		permissions.ParsePermissions(admin.DeniedPermissions),
+	), nil
+}
+
+// adminRoleIDs returns the role IDs assigned to each of the given admins.
+func adminRoleIDs(ctx context.Context, db *gorm.DB, adminIDs []uint64) (map[uint64][]uint64, error) {
+	out := make(map[uint64][]uint64, len(adminIDs))
+	if len(adminIDs) == 0 {
+		return out, nil
+	}
+	var rows []models.AdminRoleAssignment
+	if errFind := db.WithContext(ctx).Where("admin_id IN ?", adminIDs).Order("role_id ASC").Find(&rows).Error; errFind != nil {
+		return nil, errFind
+	}
+	for _, row := range rows {
+		out[row.AdminID] = append(out[row.AdminID], row.RoleID)
+	}
+	return out, nil
+}
+
+// errUnknownRole is returned when a role assignment names a missing role.
+var errUnknownRole = errors.New("unknown role")
+
+// replaceAdminRoles assigns exactly roleIDs to the admin inside tx.
+func replaceAdminRoles(tx *gorm.DB, adminID uint64, roleIDs []uint64) error {
+	unique := make([]uint64, 0, len(roleIDs))
+	seen := make(map[uint64]struct{}, len(roleIDs))
+	for _, id := range roleIDs {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		unique = append(unique, id)
+	}
+	if len(unique) > 0 {
+		var count int64
+		if errCount := tx.Model(&models.AdminRole{}).Where("id IN ?", unique).Count(&count).Error; errCount != nil {
+			return errCount
+		}
+		if count != int64(len(unique)) {
+			return errUnknownRole
+		}
+	}
+	if errDelete := tx.Where("admin_id = ?", adminID).Delete(&models.AdminRoleAssignment{}).Error; errDelete != nil {
+		return errDelete
+	}
+	if len(unique) == 0 {
+		return nil
+	}
+	rows := make([]models.AdminRoleAssignment, 0, len(unique))
+	for _, id := range unique {
+		rows = append(rows, models.AdminRoleAssignment{AdminID: adminID, RoleID: id})
+	}
+	return tx.Create(&rows).Error
+}
+
+// AdminRoleHandler manages admin role definitions.
+type AdminRoleHandler struct {
+	db *gorm.DB
+}
+
+// NewAdminRoleHandler constructs an AdminRoleHandler.
+func NewAdminRoleHandler(db *gorm.DB) *AdminRoleHandler {
+	return &AdminRoleHandler{db: db}
+}
+
+// adminRoleRequest defines the request body for creating or updating a role.
+type adminRoleRequest struct {
+	Name        *string   `json:"name"`
+	Description *string   `json:"description"`
+	Permissions *[]string `json:"permissions"`
+	Template    string    `json:"template"` // Built-in template to copy permissions from on create.
+}
+
+// roleJSON renders a role with the number of admins holding it.
+func roleJSON(role models.AdminRole, adminCount int64) gin.H {
+	return gin.H{
+		"id":          role.ID,
+		"name":        role.Name,
+		"description": role.Description,
+		"permissions": permissions.ParsePermissions(role.Permissions),
+		"admin_count": adminCount,
+		"created_at":  role.CreatedAt,
+		"updated_at":  role.UpdatedAt,
+	}
+}
+
+// validateRolePermissions normalizes and validates a role's permission list.
+func validateRolePermissions(perms []string) (datatypes.JSON, bool) {
+	normalized := permissions.NormalizePermissions(perms)
+	if errValidate := permissions.ValidatePermissions(normalized); errValidate != nil {
+		return nil, false
+	}
+	raw, errMarshal := permissions.MarshalPermissions(normalized)
+	if errMarshal != nil {
+		return nil, false
+	}
+	return datatypes.JSON(raw), true
+}
+
+// List returns all roles.
+func (h *AdminRoleHandler) List(c *gin.Context) {
+	ctx := c.Request.Context()
+	var roles []models.AdminRole
+	if errFind := h.db.WithContext(ctx).Order("name ASC").Find(&roles).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list roles failed"})
+		return
+	}
+	type roleCount struct {
+		RoleID uint64
+		Count  int64
+	}
+	var counts []roleCount
+	if errCount := h.db.WithContext(ctx).Model(&models.AdminRoleAssignment{}).
+		Select("role_id, COUNT(*) AS count").
+		Group("role_id").
+		Scan(&counts).Error; errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list roles failed"})
+		return
+	}
+	countByRole := make(map[uint64]int64, len(counts))
+	for _, item := range counts {
+		countByRole[item.RoleID] = item.Count
+	}
+	out := make([]gin.H, 0, len(roles))
+	for _, role := range roles {
+		out = append(out, roleJSON(role, countByRole[role.ID]))
+	}
+	c.JSON(http.StatusOK, gin.H{"roles": out})
+}
+
+// Templates returns the built-in role templates.
+func (h *AdminRoleHandler) Templates(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"templates": permissions.Templates()})
+}
+
+// Create adds a role, optionally seeded from a template.
+func (h *AdminRoleHandler) Create(c *gin.Context) {
+	var body adminRoleRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	var perms []string
+	role := models.AdminRole{}
+	if name := strings.TrimSpace(body.Template); name != "" {
+		tpl, ok := permissions.TemplateByName(name)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown template"})
+			return
+		}
+		role.Name = tpl.Name
+		role.Description = tpl.Description
+		perms = tpl.Permissions
+	}
+	if body.Name != nil {
+		role.Name = strings.TrimSpace(*body.Name)
+	}
+	if body.Description != nil {
+		role.Description = strings.TrimSpace(*body.Description)
+	}
+	if body.Permissions != nil {
+		perms = *body.Permissions
+	}
+	if role.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing name"})
+		return
+	}
+	permissionsJSON, ok := validateRolePermissions(perms)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid permissions"})
+		return
+	}
+	role.Permissions = permissionsJSON
+
+	ctx := c.Request.Context()
+	var existing int64
+	if errCount := h.db.WithContext(ctx).Model(&models.AdminRole{}).Where("name = ?", role.Name).Count(&existing).Error; errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create role failed"})
+		return
+	}
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "role name already exists"})
+		return
+	}
+	if errCreate := h.db.WithContext(ctx).Create(&role).Error; errCreate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create role failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, roleJSON(role, 0))
+}
+
+// Update modifies a role; changes apply to every admin holding it on their next request.
+func (h *AdminRoleHandler) Update(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var body adminRoleRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	updates := map[string]any{"updated_at": time.Now().UTC()}
+	if body.Name != nil {
+		name := strings.TrimSpace(*body.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+			return
+		}
+		var existing int64
+		if errCount := h.db.WithContext(c.Request.Context()).Model(&models.AdminRole{}).
+			Where("name = ? AND id <> ?", name, id).Count(&existing).Error; errCount != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+			return
+		}
+		if existing > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "role name already exists"})
+			return
+		}
+		updates["name"] = name
+	}
+	if body.Description != nil {
+		updates["description"] = strings.TrimSpace(*body.Description)
+	}
+	if body.Permissions != nil {
+		permissionsJSON, ok := validateRolePermissions(*body.Permissions)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid permissions"})
+			return
+		}
+		updates["permissions"] = permissionsJSON
+	}
+
+	res := h.db.WithContext(c.Request.Context()).Model(&models.AdminRole{}).Where("id = ?", id).Updates(updates)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// Delete removes a role and unassigns it from every admin.
+func (h *AdminRoleHandler) Delete(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var deleted int64
+	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if errUnassign := tx.Where("role_id = ?", id).Delete(&models.AdminRoleAssignment{}).Error; errUnassign != nil {
+			return errUnassign
+		}
+		res := tx.Delete(&models.AdminRole{}, id)
+		deleted = res.RowsAffected
+		return res.Error
+	})
+	if errTx != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	if deleted == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/gin-gonic/gin"
 	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/http/api/admin/permissions"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/ipallow"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
 	"gorm.io/datatypes"
@@ -32,6 +33,11 @@ type createAdminRequest struct {
 	Password     string   `json:"password"`
 	Permissions  []string `json:"permissions"`
 	IsSuperAdmin bool     `json:"is_super_admin"`
+
+	RoleIDs           []uint64 `json:"role_ids"`           // Roles granting permissions on top of Permissions.
+	DeniedPermissions []string `json:"denied_permissions"` // Keys removed from the roles' grants.
+	AllowedIPs        []string `json:"allowed_ips"`        // Source IPs or CIDRs the admin may sign in from.
+	OrganizationID    *uint64  `json:"organization_id"`    // Confines the admin to one organization when set.
 }
 
 // Create creates a new admin account.
@@ -68,6 +74,26 @@ func (h *AdminHandler) Create(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "marshal permissions failed"})
 		return
 	}
+	deniedJSON, okDenied := validateRolePermissions(body.DeniedPermissions)
+	if !okDenied {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid denied permissions"})
+		return
+	}
+	allowedIPs, errAllowed := ipallow.Encode(body.AllowedIPs)
+	if errAllowed != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errAllowed.Error()})
+		return
+	}
+	if body.OrganizationID != nil && *body.OrganizationID == 0 {
+		body.OrganizationID = nil
+	}
+	if body.OrganizationID != nil && body.IsSuperAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "organization admins cannot be super admins"})
+		return
+	}
+	if !checkOrganizationExists(c, h.db, body.OrganizationID) {
+		return
+	}
 
 	now := time.Now().UTC()
 	admin := models.Admin{
@@ -78,17 +104,35 @@ func (h *AdminHandler) Create(c *gin.Context) {
 		Permissions:  datatypes.JSON(permissionsJSON),
 		CreatedAt:    now,
 		UpdatedAt:    now,
+
+		DeniedPermissions: deniedJSON,
+		AllowedIPs:        datatypes.JSON(allowedIPs),
+		OrganizationID:    body.OrganizationID,
 	}
-	if errCreate := h.db.WithContext(c.Request.Context()).Create(&admin).Error; errCreate != nil {
+	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if errCreate := tx.Create(&admin).Error; errCreate != nil {
+			return errCreate
+		}
+		return replaceAdminRoles(tx, admin.ID, body.RoleIDs)
+	})
+	if errTx != nil {
+		if errors.Is(errTx, errUnknownRole) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown role"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "create admin failed"})
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{
-		"id":             admin.ID,
-		"username":       admin.Username,
-		"active":         admin.Active,
-		"is_super_admin": admin.IsSuperAdmin,
-		"permissions":    permissions.ParsePermissions(admin.Permissions),
+		"id":                 admin.ID,
+		"username":           admin.Username,
+		"active":             admin.Active,
+		"is_super_admin":     admin.IsSuperAdmin,
+		"permissions":        permissions.ParsePermissions(admin.Permissions),
+		"role_ids":           roleIDsOrEmpty(body.RoleIDs),
+		"denied_permissions": permissions.ParsePermissions(admin.DeniedPermissions),
+		"allowed_ips":        allowedIPsOrEmpty(admin.AllowedIPs),
+		"organization_id":    admin.OrganizationID,
 	})
 }
 
@@ -115,16 +159,29 @@ func (h *AdminHandler) List(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "list admins failed"})
 		return
 	}
+	adminIDs := make([]uint64, 0, len(rows))
+	for _, row := range rows {
+		adminIDs = append(adminIDs, row.ID)
+	}
+	rolesByAdmin, errRoles := adminRoleIDs(c.Request.Context(), h.db, adminIDs)
+	if errRoles != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list admins failed"})
+		return
+	}
 	out := make([]gin.H, 0, len(rows))
 	for _, row := range rows {
 		out = append(out, gin.H{
-			"id":             row.ID,
-			"username":       row.Username,
-			"active":         row.Active,
-			"is_super_admin": row.IsSuperAdmin,
-			"permissions":    permissions.ParsePermissions(row.Permissions),
-			"created_at":     row.CreatedAt,
-			"updated_at":     row.UpdatedAt,
+			"id":                 row.ID,
+			"username":           row.Username,
+			"active":             row.Active,
+			"is_super_admin":     row.IsSuperAdmin,
+			"permissions":        permissions.ParsePermissions(row.Permissions),
+			"role_ids":           roleIDsOrEmpty(rolesByAdmin[row.ID]),
+			"denied_permissions": permissions.ParsePermissions(row.DeniedPermissions),
+			"allowed_ips":        allowedIPsOrEmpty(row.AllowedIPs),
+			"organization_id":    row.OrganizationID,
+			"created_at":         row.CreatedAt,
+			"updated_at":         row.UpdatedAt,
 		})
 	}
 	c.JSON(http.StatusOK, gin.H{"admins": out})
@@ -146,14 +203,29 @@ func (h *AdminHandler) Get(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
 		return
 	}
+	rolesByAdmin, errRoles := adminRoleIDs(c.Request.Context(), h.db, []uint64{admin.ID})
+	if errRoles != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	effective, errEffective := EffectiveAdminPermissions(c.Request.Context(), h.db, admin)
+	if errEffective != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"id":             admin.ID,
-		"username":       admin.Username,
-		"active":         admin.Active,
-		"is_super_admin": admin.IsSuperAdmin,
-		"permissions":    permissions.ParsePermissions(admin.Permissions),
-		"created_at":     admin.CreatedAt,
-		"updated_at":     admin.UpdatedAt,
+		"id":                    admin.ID,
+		"username":              admin.Username,
+		"active":                admin.Active,
+		"is_super_admin":        admin.IsSuperAdmin,
+		"permissions":           permissions.ParsePermissions(admin.Permissions),
+		"role_ids":              roleIDsOrEmpty(rolesByAdmin[admin.ID]),
+		"denied_permissions":    permissions.ParsePermissions(admin.DeniedPermissions),
+		"effective_permissions": effective,
+		"allowed_ips":           allowedIPsOrEmpty(admin.AllowedIPs),
+		"organization_id":       admin.OrganizationID,
+		"created_at":            admin.CreatedAt,
+		"updated_at":            admin.UpdatedAt,
 	})
 }
 
@@ -162,6 +234,11 @@ type updateAdminRequest struct {
 	Username     *string   `json:"username"`
 	Permissions  *[]string `json:"permissions"`
 	IsSuperAdmin *bool     `json:"is_super_admin"`
+
+	RoleIDs           *[]uint64 `json:"role_ids"`           // Replaces the assigned roles when set.
+	DeniedPermissions *[]string `json:"denied_permissions"` // Replaces the denied keys when set.
+	AllowedIPs        *[]string `json:"allowed_ips"`        // Replaces the source allowlist when set; empty allows all.
+	OrganizationID    *uint64   `json:"organization_id"`    // Moves the admin to an organization when set; zero makes it global.
 }
 
 // Update modifies admin account fields.
@@ -202,13 +279,73 @@ func (h *AdminHandler) Update(c *gin.Context) {
 	if body.IsSuperAdmin != nil {
 		updates["is_super_admin"] = *body.IsSuperAdmin
 	}
+	if body.DeniedPermissions != nil {
+		deniedJSON, ok := validateRolePermissions(*body.DeniedPermissions)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid denied permissions"})
+			return
+		}
+		updates["denied_permissions"] = deniedJSON
+	}
+	if body.AllowedIPs != nil {
+		allowedIPs, errAllowed := ipallow.Encode(*body.AllowedIPs)
+		if errAllowed != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errAllowed.Error()})
+			return
+		}
+		updates["allowed_ips"] = datatypes.JSON(allowedIPs)
+	}
+	if body.IsSuperAdmin != nil || body.OrganizationID != nil {
+		var current models.Admin
+		if errFind := h.db.WithContext(c.Request.Context()).Select("id", "is_super_admin", "organization_id").First(&current, id).Error; errFind != nil {
+			if errors.Is(errFind, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+			return
+		}
+		superAdmin, organizationID := current.IsSuperAdmin, current.OrganizationID
+		if body.IsSuperAdmin != nil {
+			superAdmin = *body.IsSuperAdmin
+		}
+		if body.OrganizationID != nil {
+			organizationID = nil
+			if *body.OrganizationID != 0 {
+				organizationID = body.OrganizationID
+			}
+			if !checkOrganizationExists(c, h.db, organizationID) {
+				return
+			}
+			updates["organization_id"] = organizationID
+		}
+		if superAdmin && organizationID != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "organization admins cannot be super admins"})
+			return
+		}
+	}
 
-	res := h.db.WithContext(c.Request.Context()).Model(&models.Admin{}).Where("id = ?", id).Updates(updates)
-	if res.Error != nil {
+	var updated int64
+	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.Admin{}).Where("id = ?", id).Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		updated = res.RowsAffected
+		if updated == 0 || body.RoleIDs == nil {
+			return nil
+		}
+		return replaceAdminRoles(tx, id, *body.RoleIDs)
+	})
+	if errTx != nil {
+		if errors.Is(errTx, errUnknownRole) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown role"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
 		return
 	}
-	if res.RowsAffected == 0 {
+	if updated == 0 {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
@@ -222,12 +359,20 @@ func (h *AdminHandler) Delete(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	res := h.db.WithContext(c.Request.Context()).Delete(&models.Admin{}, id)
-	if res.Error != nil {
+	var deleted int64
+	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if errUnassign := tx.Where("admin_id = ?", id).Delete(&models.AdminRoleAssignment{}).Error; errUnassign != nil {
+			return errUnassign
+		}
+		res := tx.Delete(&models.Admin{}, id)
+		deleted = res.RowsAffected
+		return res.Error
+	})
+	if errTx != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
 		return
 	}
-	if res.RowsAffected == 0 {
+	if deleted == 0 {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
@@ -340,3 +485,11 @@ func (h *AdminHandler) ChangePassword(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
+
+// roleIDsOrEmpty keeps JSON responses as arrays rather than null.
+func roleIDsOrEmpty(ids []uint64) []uint64 {
+	if ids == nil {
+		return []uint64{}
+	}
+	return ids
+}
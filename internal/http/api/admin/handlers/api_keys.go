@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
 	"gorm.io/gorm"
@@ -38,6 +39,10 @@ func (h *APIKeyHandler) Create(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing name"})
 		return
 	}
+	if _, scoped := organizationScope(c); scoped {
+		c.JSON(http.StatusForbidden, gin.H{"error": "organization admins can only issue keys to users"})
+		return
+	}
 	token, errGenerate := security.GenerateAPIKey()
 	if errGenerate != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "generate api key failed"})
@@ -84,6 +89,9 @@ func (h *APIKeyHandler) CreateForUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing name"})
 		return
 	}
+	if !checkOrganizationUser(c, h.db, userID) {
+		return
+	}
 
 	token, errGenerate := security.GenerateAPIKey()
 	if errGenerate != nil {
@@ -119,6 +127,9 @@ func (h *APIKeyHandler) ListByUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
 		return
 	}
+	if !checkOrganizationUser(c, h.db, userID) {
+		return
+	}
 
 	var rows []models.APIKey
 	if errFind := h.db.WithContext(c.Request.Context()).
@@ -144,6 +155,8 @@ func (h *APIKeyHandler) ListByUser(c *gin.Context) {
 			"expires_at":   row.ExpiresAt,
 			"revoked_at":   row.RevokedAt,
 			"last_used_at": row.LastUsedAt,
+			"rpm_limit":    row.RPMLimit,
+			"tpm_limit":    row.TPMLimit,
 			"created_at":   row.CreatedAt,
 		})
 	}
@@ -154,7 +167,8 @@ func (h *APIKeyHandler) ListByUser(c *gin.Context) {
 // List returns all API keys.
 func (h *APIKeyHandler) List(c *gin.Context) {
 	var rows []models.APIKey
-	if errFind := h.db.WithContext(c.Request.Context()).Order("created_at DESC").Find(&rows).Error; errFind != nil {
+	q := scopeToOrganizationUsers(c, h.db, h.db.WithContext(c.Request.Context()), "user_id")
+	if errFind := q.Order("created_at DESC").Find(&rows).Error; errFind != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "list api keys failed"})
 		return
 	}
@@ -167,6 +181,8 @@ func (h *APIKeyHandler) List(c *gin.Context) {
 			"active":       row.Active,
 			"revoked_at":   row.RevokedAt,
 			"last_used_at": row.LastUsedAt,
+			"rpm_limit":    row.RPMLimit,
+			"tpm_limit":    row.TPMLimit,
 			"created_at":   row.CreatedAt,
 			"updated_at":   row.UpdatedAt,
 		})
@@ -182,7 +198,7 @@ func (h *APIKeyHandler) Revoke(c *gin.Context) {
 		return
 	}
 	now := time.Now().UTC()
-	res := h.db.WithContext(c.Request.Context()).Model(&models.APIKey{}).
+	res := scopeToOrganizationUsers(c, h.db, h.db.WithContext(c.Request.Context()).Model(&models.APIKey{}), "user_id").
 		Where("id = ? AND revoked_at IS NULL", id).
 		Updates(map[string]any{
 			"active":     false,
@@ -197,5 +213,47 @@ func (h *APIKeyHandler) Revoke(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
+	events.PublishAPIKeyDisabled(c.Request.Context(), id, "revoked by admin")
 	c.Status(http.StatusNoContent)
 }
+
+// updateAPIKeyLimitsRequest defines the request body for changing an API key's rate limits.
+type updateAPIKeyLimitsRequest struct {
+	RPMLimit *int `json:"rpm_limit"` // Requests per minute; zero removes the limit.
+	TPMLimit *int `json:"tpm_limit"` // Tokens per minute; zero removes the limit.
+}
+
+// UpdateLimits sets the per-minute request and token limits of an API key.
+func (h *APIKeyHandler) UpdateLimits(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var body updateAPIKeyLimitsRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	if (body.RPMLimit != nil && *body.RPMLimit < 0) || (body.TPMLimit != nil && *body.TPMLimit < 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rate limit"})
+		return
+	}
+	updates := map[string]any{"updated_at": time.Now().UTC()}
+	if body.RPMLimit != nil {
+		updates["rpm_limit"] = *body.RPMLimit
+	}
+	if body.TPMLimit != nil {
+		updates["tpm_limit"] = *body.TPMLimit
+	}
+	res := scopeToOrganizationUsers(c, h.db, h.db.WithContext(c.Request.Context()).Model(&models.APIKey{}), "user_id").Where("id = ?", id).Updates(updates)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
 	"gorm.io/gorm"
@@ -47,6 +48,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	var admin models.Admin
 	if errFind := h.db.WithContext(c.Request.Context()).Where("username = ?", username).First(&admin).Error; errFind != nil {
+		events.PublishLoginFailed(c.Request.Context(), "admin", username, c.ClientIP(), "unknown username")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
 	}
@@ -62,9 +64,14 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	if !security.CheckPassword(admin.Password, password) {
+		events.PublishLoginFailed(c.Request.Context(), "admin", username, c.ClientIP(), "invalid password")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
 	}
 
-	h.respondWithAdminToken(c, admin)
+	extra, allowed := h.enforceMFAPolicy(c, admin)
+	if !allowed {
+		return
+	}
+	h.respondWithAdminToken(c, admin, extra)
 }
@@ -13,13 +13,19 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/authcooldown"
 	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/environments"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/proxyassign"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
+// minQuotaPollIntervalSeconds is the shortest per-auth quota poll interval accepted.
+const minQuotaPollIntervalSeconds = 10
+
 // AuthFileHandler manages auth file endpoints.
 type AuthFileHandler struct {
 	db *gorm.DB
@@ -42,6 +48,10 @@ type createAuthFileRequest struct {
 	Priority    int                 `json:"priority"`
 	Whitelist   *bool               `json:"whitelist_enabled"`
 	Allowed     []string            `json:"allowed_models"`
+	// QuotaPollIntervalSeconds overrides the global quota poll interval; 0 or omitted uses the global setting.
+	QuotaPollIntervalSeconds *int `json:"quota_poll_interval_seconds"`
+	// Environments tags the auth for named environments; empty shares it with every environment.
+	Environments []string `json:"environments"`
 }
 
 type importAuthFilesFailure struct {
@@ -50,8 +60,11 @@ type importAuthFilesFailure struct {
 }
 
 type importAuthFilesResponse struct {
-	Imported int                      `json:"imported"`
-	Failed   []importAuthFilesFailure `json:"failed"`
+	Imported  int                      `json:"imported"`
+	Pending   int                      `json:"pending"`
+	Unchanged int                      `json:"unchanged"`
+	Conflicts []importAuthConflict     `json:"conflicts"`
+	Failed    []importAuthFilesFailure `json:"failed"`
 }
 
 // Create creates a new auth file entry.
@@ -82,6 +95,16 @@ func (h *AuthFileHandler) Create(c *gin.Context) {
 	if body.IsAvailable != nil {
 		isAvailable = *body.IsAvailable
 	}
+	pollInterval, errPollInterval := normalizeQuotaPollIntervalSeconds(body.QuotaPollIntervalSeconds)
+	if errPollInterval != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errPollInterval.Error()})
+		return
+	}
+	envTags, errEnv := environments.Normalize(body.Environments)
+	if errEnv != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errEnv.Error()})
+		return
+	}
 	contentMap := map[string]any{}
 	if body.Content != nil {
 		contentMap = body.Content
@@ -115,17 +138,6 @@ func (h *AuthFileHandler) Create(c *gin.Context) {
 			contentMap["proxy_url"] = proxyURL
 		}
 	}
-	if proxyURL == "" && autoAssignProxyEnabled() {
-		assignedProxyURL, errAssignProxy := pickRandomProxyURL(c.Request.Context(), h.db)
-		if errAssignProxy != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "auto assign proxy failed"})
-			return
-		}
-		if assignedProxyURL != "" {
-			proxyURL = assignedProxyURL
-		}
-	}
-
 	contentJSON := datatypes.JSON("{}")
 	if len(contentMap) > 0 {
 		contentBytes, errMarshal := json.Marshal(contentMap)
@@ -165,20 +177,36 @@ func (h *AuthFileHandler) Create(c *gin.Context) {
 			return
 		}
 	}
+	if proxyURL == "" && autoAssignProxyEnabled() {
+		provider, _ := resolveAuthFileProviderFromContent(contentMap)
+		assignedProxyURL, errAssignProxy := pickProxyURL(c.Request.Context(), h.db, proxyassign.Target{
+			Provider:     provider,
+			AuthGroupIDs: authGroupIDs.Values(),
+		})
+		if errAssignProxy != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "auto assign proxy failed"})
+			return
+		}
+		if assignedProxyURL != "" {
+			proxyURL = assignedProxyURL
+		}
+	}
 	auth := models.Auth{
-		Key:              key,
-		Name:             name,
-		AuthGroupID:      authGroupIDs,
-		ProxyURL:         proxyURL,
-		Content:          contentJSON,
-		WhitelistEnabled: whitelistEnabled,
-		AllowedModels:    allowedModelsJSON,
-		ExcludedModels:   excludedModelsJSON,
-		IsAvailable:      isAvailable,
-		RateLimit:        body.RateLimit,
-		Priority:         body.Priority,
-		CreatedAt:        now,
-		UpdatedAt:        now,
+		Key:                      key,
+		Name:                     name,
+		AuthGroupID:              authGroupIDs,
+		ProxyURL:                 proxyURL,
+		Content:                  contentJSON,
+		WhitelistEnabled:         whitelistEnabled,
+		AllowedModels:            allowedModelsJSON,
+		ExcludedModels:           excludedModelsJSON,
+		IsAvailable:              isAvailable,
+		RateLimit:                body.RateLimit,
+		Priority:                 body.Priority,
+		QuotaPollIntervalSeconds: pollInterval,
+		Environments:             environments.Marshal(envTags),
+		CreatedAt:                now,
+		UpdatedAt:                now,
 	}
 
 	if errCreate := h.db.WithContext(c.Request.Context()).Create(&auth).Error; errCreate != nil {
@@ -191,25 +219,34 @@ func (h *AuthFileHandler) Create(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"id":                auth.ID,
-		"key":               auth.Key,
-		"name":              auth.Name,
-		"auth_group_id":     auth.AuthGroupID.Clean(),
-		"proxy_url":         auth.ProxyURL,
-		"content":           auth.Content,
-		"whitelist_enabled": auth.WhitelistEnabled,
-		"allowed_models":    decodeExcludedModels(auth.AllowedModels),
-		"excluded_models":   decodeExcludedModels(auth.ExcludedModels),
-		"is_available":      auth.IsAvailable,
-		"rate_limit":        auth.RateLimit,
-		"priority":          auth.Priority,
-		"created_at":        auth.CreatedAt,
-		"updated_at":        auth.UpdatedAt,
+		"id":                          auth.ID,
+		"key":                         auth.Key,
+		"name":                        auth.Name,
+		"auth_group_id":               auth.AuthGroupID.Clean(),
+		"proxy_url":                   auth.ProxyURL,
+		"content":                     auth.Content,
+		"whitelist_enabled":           auth.WhitelistEnabled,
+		"allowed_models":              decodeExcludedModels(auth.AllowedModels),
+		"excluded_models":             decodeExcludedModels(auth.ExcludedModels),
+		"is_available":                auth.IsAvailable,
+		"rate_limit":                  auth.RateLimit,
+		"priority":                    auth.Priority,
+		"quota_poll_interval_seconds": auth.QuotaPollIntervalSeconds,
+		"poll_enabled":                auth.PollEnabled,
+		"environments":                environments.Decode(auth.Environments),
+		"created_at":                  auth.CreatedAt,
+		"updated_at":                  auth.UpdatedAt,
 	})
 }
 
-// Import uploads multiple auth json files and persists them into the auth table.
+// Import uploads multiple auth json files and persists them into the auth table. Files whose
+// key already exists are held as conflicts for resolution unless on_conflict=overwrite.
 func (h *AuthFileHandler) Import(c *gin.Context) {
+	conflictMode, okMode := parseImportConflictMode(c)
+	if !okMode {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid on_conflict"})
+		return
+	}
 	form, errForm := c.MultipartForm()
 	if errForm != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid multipart form"})
@@ -248,7 +285,11 @@ func (h *AuthFileHandler) Import(c *gin.Context) {
 	}
 
 	now := time.Now().UTC()
+	approvalPolicy := loadAuthImportApprovalPolicy()
 	imported := 0
+	pending := 0
+	unchanged := 0
+	conflicts := make([]importAuthConflict, 0)
 	failures := make([]importAuthFilesFailure, 0)
 
 	for _, file := range files {
@@ -341,8 +382,13 @@ func (h *AuthFileHandler) Import(c *gin.Context) {
 				proxyURL = normalized
 			}
 		}
+		explicitProxy := proxyURL != ""
 		if proxyURL == "" && autoAssignProxyEnabled() {
-			assignedProxyURL, errAssignProxy := pickRandomProxyURL(c.Request.Context(), h.db)
+			provider, _ := resolveAuthFileProviderFromContent(payload)
+			assignedProxyURL, errAssignProxy := pickProxyURL(c.Request.Context(), h.db, proxyassign.Target{
+				Provider:     provider,
+				AuthGroupIDs: authGroupIDs.Values(),
+			})
 			if errAssignProxy != nil {
 				failures = append(failures, importAuthFilesFailure{
 					File:  file.Filename,
@@ -381,9 +427,12 @@ func (h *AuthFileHandler) Import(c *gin.Context) {
 			"content":       auth.Content,
 			"updated_at":    now,
 		}
+		authType, _ := payload["type"].(string)
+		review := reviewAuthImport(c, approvalPolicy, authType)
+		review.apply(&auth, updateFields)
 
 		var existing models.Auth
-		errFindExisting := h.db.WithContext(c.Request.Context()).Where("key = ?", key).First(&existing).Error
+		errFindExisting := h.db.WithContext(c.Request.Context()).Where("? = ?", clause.Column{Name: "key"}, key).First(&existing).Error
 		if errFindExisting != nil && !errors.Is(errFindExisting, gorm.ErrRecordNotFound) {
 			failures = append(failures, importAuthFilesFailure{
 				File:  file.Filename,
@@ -391,6 +440,22 @@ func (h *AuthFileHandler) Import(c *gin.Context) {
 			})
 			continue
 		}
+		if errFindExisting == nil && conflictMode == importConflictReport {
+			conflict, errHold := holdImportConflict(c, h.db, &existing, &auth, explicitProxy, file.Filename)
+			if errHold != nil {
+				failures = append(failures, importAuthFilesFailure{
+					File:  file.Filename,
+					Error: "import auth file failed",
+				})
+				continue
+			}
+			if conflict == nil {
+				unchanged++
+			} else {
+				conflicts = append(conflicts, *conflict)
+			}
+			continue
+		}
 		if errFindExisting == nil {
 			whitelistEnabled, allowedModels, excludedModels, errReconcile := reconcileWhitelistOnImportConflict(existing, payload)
 			if errReconcile != nil {
@@ -432,12 +497,31 @@ func (h *AuthFileHandler) Import(c *gin.Context) {
 			})
 			continue
 		}
+		if review.pending() {
+			// is_available defaults to true, so the zero value is not written on create.
+			errHold := h.db.WithContext(c.Request.Context()).Model(&models.Auth{}).
+				Where("? = ?", clause.Column{Name: "key"}, key).
+				Update("is_available", false).Error
+			if errHold != nil {
+				failures = append(failures, importAuthFilesFailure{
+					File:  file.Filename,
+					Error: "import auth file failed",
+				})
+				continue
+			}
+		}
 		imported++
+		if review.pending() {
+			pending++
+		}
 	}
 
 	c.JSON(http.StatusOK, importAuthFilesResponse{
-		Imported: imported,
-		Failed:   failures,
+		Imported:  imported,
+		Pending:   pending,
+		Unchanged: unchanged,
+		Conflicts: conflicts,
+		Failed:    failures,
 	})
 }
 
@@ -447,13 +531,14 @@ func (h *AuthFileHandler) List(c *gin.Context) {
 		keyQ         = strings.TrimSpace(c.Query("key"))
 		authGroupIDQ = strings.TrimSpace(c.Query("auth_group_id"))
 		typeQ        = strings.TrimSpace(c.Query("type"))
+		environmentQ = strings.ToLower(strings.TrimSpace(c.Query("environment")))
 	)
 
 	q := h.db.WithContext(c.Request.Context()).Model(&models.Auth{})
 	if keyQ != "" {
 		pattern := dbutil.NormalizeLikePattern(h.db, "%"+keyQ+"%")
 		q = q.Where(
-			dbutil.CaseInsensitiveLikeExpr(h.db, "key")+" OR "+dbutil.CaseInsensitiveLikeExpr(h.db, "name"),
+			dbutil.CaseInsensitiveLikeExpr(h.db, "auths.key")+" OR "+dbutil.CaseInsensitiveLikeExpr(h.db, "auths.name"),
 			pattern,
 			pattern,
 		)
@@ -473,6 +558,9 @@ func (h *AuthFileHandler) List(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "list auth files failed"})
 		return
 	}
+	if environmentQ != "" {
+		rows = environments.FilterAuths(rows, environmentQ)
+	}
 
 	groupMap, errGroups := loadAuthGroupMap(c.Request.Context(), h.db, rows)
 	if errGroups != nil {
@@ -484,20 +572,29 @@ func (h *AuthFileHandler) List(c *gin.Context) {
 	for _, row := range rows {
 		authGroupIDs := row.AuthGroupID.Clean()
 		item := gin.H{
-			"id":                row.ID,
-			"key":               row.Key,
-			"name":              row.Name,
-			"auth_group_id":     authGroupIDs,
-			"proxy_url":         row.ProxyURL,
-			"content":           row.Content,
-			"whitelist_enabled": row.WhitelistEnabled,
-			"allowed_models":    decodeExcludedModels(row.AllowedModels),
-			"excluded_models":   decodeExcludedModels(row.ExcludedModels),
-			"is_available":      row.IsAvailable,
-			"rate_limit":        row.RateLimit,
-			"priority":          row.Priority,
-			"created_at":        row.CreatedAt,
-			"updated_at":        row.UpdatedAt,
+			"id":                          row.ID,
+			"key":                         row.Key,
+			"name":                        row.Name,
+			"auth_group_id":               authGroupIDs,
+			"proxy_url":                   row.ProxyURL,
+			"content":                     row.Content,
+			"whitelist_enabled":           row.WhitelistEnabled,
+			"allowed_models":              decodeExcludedModels(row.AllowedModels),
+			"excluded_models":             decodeExcludedModels(row.ExcludedModels),
+			"is_available":                row.IsAvailable,
+			"pending_approval":            row.PendingApproval,
+			"approval_reason":             row.ApprovalReason,
+			"imported_by":                 row.ImportedBy,
+			"cooling_down":                row.CoolingDown,
+			"cooldown_until":              row.CooldownUntil,
+			"cooldown_reason":             row.CooldownReason,
+			"rate_limit":                  row.RateLimit,
+			"priority":                    row.Priority,
+			"quota_poll_interval_seconds": row.QuotaPollIntervalSeconds,
+			"poll_enabled":                row.PollEnabled,
+			"environments":                environments.Decode(row.Environments),
+			"created_at":                  row.CreatedAt,
+			"updated_at":                  row.UpdatedAt,
 		}
 		item["auth_group"] = buildAuthGroupSummaries(authGroupIDs, groupMap)
 		out = append(out, item)
@@ -530,20 +627,29 @@ func (h *AuthFileHandler) Get(c *gin.Context) {
 		return
 	}
 	item := gin.H{
-		"id":                auth.ID,
-		"key":               auth.Key,
-		"name":              auth.Name,
-		"auth_group_id":     authGroupIDs,
-		"proxy_url":         auth.ProxyURL,
-		"content":           auth.Content,
-		"whitelist_enabled": auth.WhitelistEnabled,
-		"allowed_models":    decodeExcludedModels(auth.AllowedModels),
-		"excluded_models":   decodeExcludedModels(auth.ExcludedModels),
-		"is_available":      auth.IsAvailable,
-		"rate_limit":        auth.RateLimit,
-		"priority":          auth.Priority,
-		"created_at":        auth.CreatedAt,
-		"updated_at":        auth.UpdatedAt,
+		"id":                          auth.ID,
+		"key":                         auth.Key,
+		"name":                        auth.Name,
+		"auth_group_id":               authGroupIDs,
+		"proxy_url":                   auth.ProxyURL,
+		"content":                     auth.Content,
+		"whitelist_enabled":           auth.WhitelistEnabled,
+		"allowed_models":              decodeExcludedModels(auth.AllowedModels),
+		"excluded_models":             decodeExcludedModels(auth.ExcludedModels),
+		"is_available":                auth.IsAvailable,
+		"pending_approval":            auth.PendingApproval,
+		"approval_reason":             auth.ApprovalReason,
+		"imported_by":                 auth.ImportedBy,
+		"cooling_down":                auth.CoolingDown,
+		"cooldown_until":              auth.CooldownUntil,
+		"cooldown_reason":             auth.CooldownReason,
+		"rate_limit":                  auth.RateLimit,
+		"priority":                    auth.Priority,
+		"quota_poll_interval_seconds": auth.QuotaPollIntervalSeconds,
+		"poll_enabled":                auth.PollEnabled,
+		"environments":                environments.Decode(auth.Environments),
+		"created_at":                  auth.CreatedAt,
+		"updated_at":                  auth.UpdatedAt,
 	}
 	item["auth_group"] = buildAuthGroupSummaries(authGroupIDs, groupMap)
 	c.JSON(http.StatusOK, item)
@@ -561,6 +667,12 @@ type updateAuthFileRequest struct {
 	Priority    *int                 `json:"priority"`
 	Whitelist   *bool                `json:"whitelist_enabled"`
 	Allowed     *[]string            `json:"allowed_models"`
+	// QuotaPollIntervalSeconds overrides the global quota poll interval; 0 clears the override.
+	QuotaPollIntervalSeconds *int `json:"quota_poll_interval_seconds"`
+	// PollEnabled excludes the auth from scheduled quota polling when false.
+	PollEnabled *bool `json:"poll_enabled"`
+	// Environments replaces the environment tags when present.
+	Environments *[]string `json:"environments"`
 }
 
 // Update modifies an auth file entry.
@@ -680,7 +792,21 @@ func (h *AuthFileHandler) Update(c *gin.Context) {
 		updates["excluded_models"] = excludedModelsJSON
 	}
 	if body.IsAvailable != nil {
+		if *body.IsAvailable {
+			pendingApproval, errPending := isAuthPendingApproval(h.db, c, id)
+			if errPending != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+				return
+			}
+			if pendingApproval {
+				c.JSON(http.StatusConflict, gin.H{"error": "auth file is pending approval"})
+				return
+			}
+		}
 		updates["is_available"] = *body.IsAvailable
+		for column, value := range authcooldown.ManualOverride(now) {
+			updates[column] = value
+		}
 	}
 	if body.RateLimit != nil {
 		updates["rate_limit"] = *body.RateLimit
@@ -688,6 +814,25 @@ func (h *AuthFileHandler) Update(c *gin.Context) {
 	if body.Priority != nil {
 		updates["priority"] = *body.Priority
 	}
+	if body.PollEnabled != nil {
+		updates["poll_enabled"] = *body.PollEnabled
+	}
+	if body.QuotaPollIntervalSeconds != nil {
+		pollInterval, errPollInterval := normalizeQuotaPollIntervalSeconds(body.QuotaPollIntervalSeconds)
+		if errPollInterval != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errPollInterval.Error()})
+			return
+		}
+		updates["quota_poll_interval_seconds"] = pollInterval
+	}
+	if body.Environments != nil {
+		envTags, errEnv := environments.Normalize(*body.Environments)
+		if errEnv != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errEnv.Error()})
+			return
+		}
+		updates["environments"] = environments.Marshal(envTags)
+	}
 
 	res := h.db.WithContext(c.Request.Context()).Model(&models.Auth{}).Where("id = ?", id).Updates(updates)
 	if res.Error != nil {
@@ -701,6 +846,38 @@ func (h *AuthFileHandler) Update(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
+// setAuthFilesPollEnabledRequest captures the payload for toggling quota polling in bulk.
+type setAuthFilesPollEnabledRequest struct {
+	IDs         []uint64 `json:"ids"`          // Auth file IDs to update.
+	PollEnabled *bool    `json:"poll_enabled"` // Whether the quota poller refreshes them.
+}
+
+// SetPollEnabled includes or excludes several auth files from scheduled quota polling.
+func (h *AuthFileHandler) SetPollEnabled(c *gin.Context) {
+	var body setAuthFilesPollEnabledRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	if len(body.IDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids are required"})
+		return
+	}
+	if body.PollEnabled == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "poll_enabled is required"})
+		return
+	}
+
+	now := time.Now().UTC()
+	res := h.db.WithContext(c.Request.Context()).Model(&models.Auth{}).Where("id IN ?", body.IDs).
+		Updates(map[string]any{"poll_enabled": *body.PollEnabled, "updated_at": now})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"updated": res.RowsAffected})
+}
+
 func parseAuthContentMap(content datatypes.JSON) map[string]any {
 	if len(content) == 0 {
 		return map[string]any{}
@@ -850,6 +1027,18 @@ func reconcileWhitelistOnImportConflict(current models.Auth, newContent map[stri
 	return true, intersection, excludedModels, nil
 }
 
+// normalizeQuotaPollIntervalSeconds validates a per-auth quota poll interval; zero maps to nil (global interval).
+func normalizeQuotaPollIntervalSeconds(value *int) (*int, error) {
+	if value == nil || *value == 0 {
+		return nil, nil
+	}
+	if *value < minQuotaPollIntervalSeconds {
+		return nil, fmt.Errorf("quota_poll_interval_seconds must be 0 or at least %d", minQuotaPollIntervalSeconds)
+	}
+	normalized := *value
+	return &normalized, nil
+}
+
 func marshalStringSliceJSON(values []string) (datatypes.JSON, error) {
 	normalized := normalizeModelNames(values)
 	if len(normalized) == 0 {
@@ -890,9 +1079,22 @@ func (h *AuthFileHandler) SetAvailable(c *gin.Context) {
 		return
 	}
 
+	pendingApproval, errPending := isAuthPendingApproval(h.db, c, id)
+	if errPending != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if pendingApproval {
+		c.JSON(http.StatusConflict, gin.H{"error": "auth file is pending approval"})
+		return
+	}
+
 	now := time.Now().UTC()
+	updates := authcooldown.ManualOverride(now)
+	updates["is_available"] = true
+	updates["updated_at"] = now
 	res := h.db.WithContext(c.Request.Context()).Model(&models.Auth{}).Where("id = ?", id).
-		Updates(map[string]any{"is_available": true, "updated_at": now})
+		Updates(updates)
 	if res.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
 		return
@@ -913,8 +1115,11 @@ func (h *AuthFileHandler) SetUnavailable(c *gin.Context) {
 	}
 
 	now := time.Now().UTC()
+	updates := authcooldown.ManualOverride(now)
+	updates["is_available"] = false
+	updates["updated_at"] = now
 	res := h.db.WithContext(c.Request.Context()).Model(&models.Auth{}).Where("id = ?", id).
-		Updates(map[string]any{"is_available": false, "updated_at": now})
+		Updates(updates)
 	if res.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
 		return
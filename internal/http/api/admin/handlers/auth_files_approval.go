@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	approvalReasonUnknownProvider = "unknown provider"
+	approvalReasonUntrustedAdmin  = "untrusted admin"
+)
+
+// authImportApprovalPolicy mirrors the AUTH_IMPORT_APPROVAL setting.
+type authImportApprovalPolicy struct {
+	Enabled       bool     `json:"enabled"`        // Whether risky imports are held for approval.
+	TrustedAdmins []string `json:"trusted_admins"` // Admin usernames whose imports skip approval.
+}
+
+// loadAuthImportApprovalPolicy reads AUTH_IMPORT_APPROVAL; invalid values disable the queue.
+func loadAuthImportApprovalPolicy() authImportApprovalPolicy {
+	var policy authImportApprovalPolicy
+	raw, ok := internalsettings.DBConfigValue(internalsettings.AuthImportApprovalKey)
+	if !ok || len(bytes.TrimSpace(raw)) == 0 {
+		return policy
+	}
+	if errUnmarshal := json.Unmarshal(raw, &policy); errUnmarshal != nil {
+		log.WithError(errUnmarshal).Warn("auth files: invalid import approval setting")
+		return authImportApprovalPolicy{}
+	}
+	return policy
+}
+
+// authImportReview records who imported an auth and why it must wait for approval.
+type authImportReview struct {
+	importedBy string
+	reasons    []string
+}
+
+// reviewAuthImport evaluates one imported auth against the approval policy.
+func reviewAuthImport(c *gin.Context, policy authImportApprovalPolicy, provider string) authImportReview {
+	review := authImportReview{importedBy: c.GetString("adminUsername")}
+	if !policy.Enabled {
+		return review
+	}
+	if _, errProvider := canonicalizeImportProvider(provider); errProvider != nil {
+		review.reasons = append(review.reasons, approvalReasonUnknownProvider)
+	}
+	if !c.GetBool("adminIsSuperAdmin") && !policy.trusts(review.importedBy) {
+		review.reasons = append(review.reasons, approvalReasonUntrustedAdmin)
+	}
+	return review
+}
+
+func (p authImportApprovalPolicy) trusts(username string) bool {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return false
+	}
+	for _, trusted := range p.TrustedAdmins {
+		if strings.EqualFold(strings.TrimSpace(trusted), username) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r authImportReview) pending() bool {
+	return len(r.reasons) > 0
+}
+
+// apply stamps the review onto a new auth row and its on-conflict update fields. Pending
+// imports are kept out of routing, including re-imports over an already approved key.
+func (r authImportReview) apply(auth *models.Auth, updates map[string]any) {
+	auth.ImportedBy = r.importedBy
+	updates["imported_by"] = r.importedBy
+	if !r.pending() {
+		return
+	}
+	reason := strings.Join(r.reasons, ", ")
+	auth.IsAvailable = false
+	auth.PendingApproval = true
+	auth.ApprovalReason = reason
+	updates["is_available"] = false
+	updates["pending_approval"] = true
+	updates["approval_reason"] = reason
+}
+
+// isAuthPendingApproval reports whether the auth with id waits for approval.
+func isAuthPendingApproval(db *gorm.DB, c *gin.Context, id uint64) (bool, error) {
+	var count int64
+	errCount := db.WithContext(c.Request.Context()).Model(&models.Auth{}).
+		Where("id = ? AND pending_approval = ?", id, true).
+		Count(&count).Error
+	return count > 0, errCount
+}
+
+// ListPending returns auth files waiting for super-admin approval.
+func (h *AuthFileHandler) ListPending(c *gin.Context) {
+	var rows []models.Auth
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Where("pending_approval = ?", true).
+		Order("updated_at DESC").
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list pending auth files failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		authType, _ := parseAuthContentMap(row.Content)["type"].(string)
+		out = append(out, gin.H{
+			"id":              row.ID,
+			"key":             row.Key,
+			"name":            row.Name,
+			"type":            authType,
+			"proxy_url":       row.ProxyURL,
+			"approval_reason": row.ApprovalReason,
+			"imported_by":     row.ImportedBy,
+			"created_at":      row.CreatedAt,
+			"updated_at":      row.UpdatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"auth_files": out})
+}
+
+// Approve releases a pending auth file into routing. Super admins only.
+func (h *AuthFileHandler) Approve(c *gin.Context) {
+	row, ok := h.loadPendingAuth(c)
+	if !ok {
+		return
+	}
+	now := time.Now().UTC()
+	if errUpdate := h.db.WithContext(c.Request.Context()).Model(&models.Auth{}).Where("id = ?", row.ID).
+		Updates(map[string]any{
+			"pending_approval": false,
+			"approval_reason":  "",
+			"is_available":     true,
+			"updated_at":       now,
+		}).Error; errUpdate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// Reject deletes a pending auth file. Super admins only.
+func (h *AuthFileHandler) Reject(c *gin.Context) {
+	row, ok := h.loadPendingAuth(c)
+	if !ok {
+		return
+	}
+	if errDelete := h.db.WithContext(c.Request.Context()).Delete(&models.Auth{}, row.ID).Error; errDelete != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// loadPendingAuth checks the caller is a super admin and loads the pending auth named by
+// the id parameter, writing the error response when it cannot.
+func (h *AuthFileHandler) loadPendingAuth(c *gin.Context) (*models.Auth, bool) {
+	if !c.GetBool("adminIsSuperAdmin") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "super admin required"})
+		return nil, false
+	}
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return nil, false
+	}
+	var row models.Auth
+	if errFind := h.db.WithContext(c.Request.Context()).First(&row, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return nil, false
+	}
+	if !row.PendingApproval {
+		c.JSON(http.StatusConflict, gin.H{"error": "auth file is not pending approval"})
+		return nil, false
+	}
+	return &row, true
+}
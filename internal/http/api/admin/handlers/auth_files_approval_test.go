@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+)
+
+func TestAuthFiles_Import_HoldsRiskyImportsForApproval(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	internalsettings.StoreDBConfig(time.Now(), map[string]json.RawMessage{
+		internalsettings.AuthImportApprovalKey: json.RawMessage(`{"enabled":true,"trusted_admins":["ops"]}`),
+	})
+	t.Cleanup(func() { internalsettings.StoreDBConfig(time.Now(), nil) })
+
+	db := setupAuthFilesWhitelistDB(t)
+	h := NewAuthFileHandler(db)
+	admin := "intern"
+	superAdmin := false
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("adminUsername", admin)
+		c.Set("adminIsSuperAdmin", superAdmin)
+		c.Next()
+	})
+	router.POST("/v0/admin/auth-files/import", h.Import)
+	router.POST("/v0/admin/auth-files/:id/available", h.SetAvailable)
+	router.POST("/v0/admin/auth-files/:id/approve", h.Approve)
+
+	importFiles := func(files map[string]string) importAuthFilesResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, buildAuthFilesImportRequest(t, "/v0/admin/auth-files/import", files))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d body=%s", w.Code, w.Body.String())
+		}
+		return decodeImportAuthFilesResponse(t, w.Body.Bytes())
+	}
+
+	resp := importFiles(map[string]string{
+		"personal.json": `{"id":"auth-personal","type":"claude"}`,
+	})
+	if resp.Imported != 1 || resp.Pending != 1 {
+		t.Fatalf("expected one pending import, got %+v", resp)
+	}
+	var held models.Auth
+	if errFind := db.Where("key = ?", "auth-personal").First(&held).Error; errFind != nil {
+		t.Fatalf("query held row failed: %v", errFind)
+	}
+	if held.IsAvailable || !held.PendingApproval || held.ApprovalReason != approvalReasonUntrustedAdmin || held.ImportedBy != "intern" {
+		t.Fatalf("expected held import, got %+v", held)
+	}
+
+	admin = "ops"
+	resp = importFiles(map[string]string{
+		"known.json":   `{"id":"auth-known","type":"codex"}`,
+		"unknown.json": `{"id":"auth-unknown","type":"mystery"}`,
+	})
+	if resp.Imported != 2 || resp.Pending != 1 {
+		t.Fatalf("expected only the unknown provider held, got %+v", resp)
+	}
+	var known models.Auth
+	if errFind := db.Where("key = ?", "auth-known").First(&known).Error; errFind != nil {
+		t.Fatalf("query known row failed: %v", errFind)
+	}
+	if !known.IsAvailable || known.PendingApproval {
+		t.Fatalf("expected trusted import to join routing, got %+v", known)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v0/admin/auth-files/"+strconv.FormatUint(held.ID, 10)+"/available", nil))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 enabling a pending auth, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v0/admin/auth-files/"+strconv.FormatUint(held.ID, 10)+"/approve", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 approving without super admin, got %d", w.Code)
+	}
+
+	superAdmin = true
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v0/admin/auth-files/"+strconv.FormatUint(held.ID, 10)+"/approve", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 approving, got %d body=%s", w.Code, w.Body.String())
+	}
+	if errFind := db.First(&held, held.ID).Error; errFind != nil {
+		t.Fatalf("reload held row failed: %v", errFind)
+	}
+	if !held.IsAvailable || held.PendingApproval || held.ApprovalReason != "" {
+		t.Fatalf("expected approved auth in routing, got %+v", held)
+	}
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+)
+
+// quotaStaleIntervals is how many poll intervals may pass before quota data counts as stale.
+const quotaStaleIntervals = 3
+
+// Auth health states reported per auth file, ordered from worst to best.
+const (
+	authHealthInvalid     = "token_invalid"
+	authHealthError       = "auth_error"
+	authHealthStaleQuota  = "quota_stale"
+	authHealthUnchecked   = "unchecked"
+	authHealthUnavailable = "unavailable"
+	authHealthOK          = "ok"
+)
+
+// authHealthRow holds the auth columns needed for the health view.
+type authHealthRow struct {
+	ID              uint64         // Auth ID.
+	Key             string         // Auth key.
+	Name            string         // Display name.
+	ContentType     string         // Provider type from content.
+	IsAvailable     bool           // Availability flag.
+	TokenInvalid    bool           // Token health flag.
+	LastAuthCheckAt sql.NullString // Latest auth check time, parsed per driver.
+	LastAuthError   string         // Latest auth check error.
+
+	QuotaPollIntervalSeconds sql.NullInt64 // Per-auth quota poll interval override.
+}
+
+// quotaFreshnessRow holds the latest quota update per auth.
+type quotaFreshnessRow struct {
+	AuthID    uint64         // Related auth ID.
+	UpdatedAt sql.NullString // Latest quota update time, parsed per driver.
+}
+
+// Health aggregates token and quota freshness state per auth file, grouped by provider type.
+func (h *AuthFileHandler) Health(c *gin.Context) {
+	ctx := c.Request.Context()
+	typeExpr := dbutil.JSONExtractTextExpr(h.db, "content", "type")
+
+	var auths []authHealthRow
+	if errFind := h.db.WithContext(ctx).
+		Model(&models.Auth{}).
+		Select("auths.id, auths.key, auths.name, " + typeExpr + " AS content_type, is_available, token_invalid, last_auth_check_at, last_auth_error, quota_poll_interval_seconds").
+		Order("id ASC").
+		Scan(&auths).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list auth files failed"})
+		return
+	}
+
+	var quotas []quotaFreshnessRow
+	if errQuota := h.db.WithContext(ctx).
+		Model(&models.Quota{}).
+		Select("auth_id, MAX(updated_at) AS updated_at").
+		Group("auth_id").
+		Scan(&quotas).Error; errQuota != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list quota failed"})
+		return
+	}
+	quotaUpdatedAt := make(map[uint64]time.Time, len(quotas))
+	for _, row := range quotas {
+		if updatedAt := parseQuotaListAuthCheckTime(row.UpdatedAt); updatedAt != nil {
+			quotaUpdatedAt[row.AuthID] = *updatedAt
+		}
+	}
+
+	now := time.Now().UTC()
+	staleAfter := resolveQuotaStaleAfter()
+
+	type providerSummary struct {
+		items  []gin.H
+		counts map[string]int
+	}
+	providers := make(map[string]*providerSummary)
+	totals := map[string]int{}
+	for _, row := range auths {
+		provider := strings.ToLower(strings.TrimSpace(row.ContentType))
+		if provider == "" {
+			provider = "unknown"
+		}
+		var quotaAt *time.Time
+		quotaStale := true
+		if updatedAt, ok := quotaUpdatedAt[row.ID]; ok {
+			updatedAtCopy := updatedAt
+			quotaAt = &updatedAtCopy
+			rowStaleAfter := staleAfter
+			if row.QuotaPollIntervalSeconds.Valid && row.QuotaPollIntervalSeconds.Int64 > 0 {
+				rowStaleAfter = time.Duration(row.QuotaPollIntervalSeconds.Int64*quotaStaleIntervals) * time.Second
+			}
+			quotaStale = now.Sub(updatedAt) > rowStaleAfter
+		}
+		checkedAt := parseQuotaListAuthCheckTime(row.LastAuthCheckAt)
+		status := classifyAuthHealth(row, checkedAt != nil, quotaStale)
+
+		summary, ok := providers[provider]
+		if !ok {
+			summary = &providerSummary{counts: map[string]int{}}
+			providers[provider] = summary
+		}
+		summary.counts[status]++
+		totals[status]++
+		summary.items = append(summary.items, gin.H{
+			"id":                 row.ID,
+			"key":                row.Key,
+			"name":               row.Name,
+			"status":             status,
+			"is_available":       row.IsAvailable,
+			"token_invalid":      row.TokenInvalid,
+			"last_auth_error":    row.LastAuthError,
+			"last_auth_check_at": checkedAt,
+			"quota_updated_at":   quotaAt,
+			"quota_stale":        quotaStale,
+			"needs_attention":    status != authHealthOK && status != authHealthUnavailable,
+		})
+	}
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]gin.H, 0, len(names))
+	for _, name := range names {
+		summary := providers[name]
+		sort.SliceStable(summary.items, func(i, j int) bool {
+			return authHealthRank(summary.items[i]["status"].(string)) < authHealthRank(summary.items[j]["status"].(string))
+		})
+		out = append(out, gin.H{
+			"provider": name,
+			"total":    len(summary.items),
+			"counts":   summary.counts,
+			"auths":    summary.items,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"generated_at":        now,
+		"quota_stale_after_s": int(staleAfter / time.Second),
+		"total":               len(auths),
+		"counts":              totals,
+		"providers":           out,
+	})
+}
+
+// classifyAuthHealth returns the most severe state that applies to an auth.
+func classifyAuthHealth(row authHealthRow, checked, quotaStale bool) string {
+	switch {
+	case row.TokenInvalid:
+		return authHealthInvalid
+	case strings.TrimSpace(row.LastAuthError) != "":
+		return authHealthError
+	case !row.IsAvailable:
+		return authHealthUnavailable
+	case !checked:
+		return authHealthUnchecked
+	case quotaStale:
+		return authHealthStaleQuota
+	default:
+		return authHealthOK
+	}
+}
+
+func authHealthRank(status string) int {
+	switch status {
+	case authHealthInvalid:
+		return 0
+	case authHealthError:
+		return 1
+	case authHealthStaleQuota:
+		return 2
+	case authHealthUnchecked:
+		return 3
+	case authHealthUnavailable:
+		return 4
+	default:
+		return 5
+	}
+}
+
+// resolveQuotaStaleAfter derives the quota staleness threshold from the poll interval setting.
+func resolveQuotaStaleAfter() time.Duration {
+	intervalSeconds := internalsettings.DefaultQuotaPollIntervalSeconds
+	if raw, ok := internalsettings.DBConfigValue(internalsettings.QuotaPollIntervalSecondsKey); ok {
+		if parsed, okParse := parseQuotaManualRefreshInt(raw); okParse && parsed > 0 {
+			intervalSeconds = parsed
+		}
+	}
+	return time.Duration(intervalSeconds*quotaStaleIntervals) * time.Second
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func TestAuthFileHealthGroupsByProvider(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dsn := fmt.Sprintf("file:authhealth_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.AutoMigrate(&models.Auth{}, &models.Quota{}); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+
+	checkedAt := time.Now().UTC().Add(-time.Minute)
+	healthy := models.Auth{
+		Key:             "codex-ok",
+		Content:         datatypes.JSON([]byte(`{"type":"codex"}`)),
+		IsAvailable:     true,
+		LastAuthCheckAt: &checkedAt,
+	}
+	broken := models.Auth{
+		Key:             "codex-broken",
+		Content:         datatypes.JSON([]byte(`{"type":"codex"}`)),
+		IsAvailable:     true,
+		TokenInvalid:    true,
+		LastAuthCheckAt: &checkedAt,
+		LastAuthError:   "token expired",
+	}
+	unchecked := models.Auth{
+		Key:         "claude-new",
+		Content:     datatypes.JSON([]byte(`{"type":"claude"}`)),
+		IsAvailable: true,
+	}
+	for _, auth := range []*models.Auth{&healthy, &broken, &unchecked} {
+		if errCreate := db.Create(auth).Error; errCreate != nil {
+			t.Fatalf("create auth: %v", errCreate)
+		}
+	}
+	quota := models.Quota{AuthID: healthy.ID, Type: "codex", Data: datatypes.JSON([]byte(`{}`))}
+	if errCreate := db.Create(&quota).Error; errCreate != nil {
+		t.Fatalf("create quota: %v", errCreate)
+	}
+
+	router := gin.New()
+	router.GET("/v0/admin/auth-files/health", NewAuthFileHandler(db).Health)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v0/admin/auth-files/health", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Total     int `json:"total"`
+		Providers []struct {
+			Provider string `json:"provider"`
+			Auths    []struct {
+				Key    string `json:"key"`
+				Status string `json:"status"`
+			} `json:"auths"`
+		} `json:"providers"`
+	}
+	if errDecode := json.Unmarshal(w.Body.Bytes(), &resp); errDecode != nil {
+		t.Fatalf("decode response: %v", errDecode)
+	}
+	if resp.Total != 3 || len(resp.Providers) != 2 {
+		t.Fatalf("expected 3 auths in 2 providers, got %d in %d", resp.Total, len(resp.Providers))
+	}
+	statuses := map[string]string{}
+	for _, provider := range resp.Providers {
+		for _, auth := range provider.Auths {
+			statuses[auth.Key] = auth.Status
+		}
+	}
+	if statuses["codex-ok"] != authHealthOK {
+		t.Fatalf("expected codex-ok healthy, got %q", statuses["codex-ok"])
+	}
+	if statuses["codex-broken"] != authHealthInvalid {
+		t.Fatalf("expected codex-broken token_invalid, got %q", statuses["codex-broken"])
+	}
+	if statuses["claude-new"] != authHealthUnchecked {
+		t.Fatalf("expected claude-new unchecked, got %q", statuses["claude-new"])
+	}
+	if resp.Providers[1].Auths[0].Key != "codex-broken" {
+		t.Fatalf("expected worst auth first, got %q", resp.Providers[1].Auths[0].Key)
+	}
+}
@@ -0,0 +1,432 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Import conflict modes selected with the on_conflict query parameter.
+const (
+	importConflictReport    = "report"    // Hold colliding entries for resolution (default).
+	importConflictOverwrite = "overwrite" // Replace the existing auth's content, proxy and groups.
+)
+
+// Conflict resolutions accepted by ResolveImportConflict.
+const (
+	importResolutionKeepMine   = "keep_mine"
+	importResolutionTakeTheirs = "take_theirs"
+	importResolutionMerge      = "merge"
+)
+
+// Per-field choices for a merge resolution.
+const (
+	importFieldMine   = "mine"
+	importFieldTheirs = "theirs"
+)
+
+// Diff field names outside the auth content document.
+const (
+	importFieldProxyURL    = "proxy_url"
+	importFieldAuthGroupID = "auth_group_id"
+	importFieldContent     = "content."
+)
+
+// importAuthConflict describes one held import entry and how it differs from the existing auth.
+// Current and incoming keep the auth's shape (content, proxy_url, auth_group_id) so the admin
+// redaction middleware masks their secrets like any other auth payload.
+type importAuthConflict struct {
+	ID       uint64   `json:"id"`       // Conflict id to resolve.
+	Key      string   `json:"key"`      // Colliding auth key.
+	AuthID   uint64   `json:"auth_id"`  // Existing auth id.
+	Source   string   `json:"source"`   // Uploaded file name or entry index.
+	Fields   []string `json:"fields"`   // Differing fields, e.g. content.access_token or proxy_url.
+	Current  gin.H    `json:"current"`  // Existing values of the differing fields.
+	Incoming gin.H    `json:"incoming"` // Imported values of the differing fields.
+}
+
+// resolveImportConflictRequest defines the body for resolving a held import entry.
+type resolveImportConflictRequest struct {
+	Resolution string            `json:"resolution"` // keep_mine, take_theirs or merge.
+	Fields     map[string]string `json:"fields"`     // Merge choices per field: mine or theirs; unlisted fields keep mine.
+}
+
+// parseImportConflictMode reads the on_conflict query parameter.
+func parseImportConflictMode(c *gin.Context) (string, bool) {
+	switch mode := strings.ToLower(strings.TrimSpace(c.Query("on_conflict"))); mode {
+	case "", importConflictReport:
+		return importConflictReport, true
+	case importConflictOverwrite:
+		return importConflictOverwrite, true
+	default:
+		return "", false
+	}
+}
+
+// holdImportConflict stores an incoming entry that collides with existing and returns its diff.
+// It returns nil when the entry matches the existing auth, leaving nothing to resolve. Entries
+// without an explicit proxy keep the existing one instead of an auto-assigned proxy.
+func holdImportConflict(c *gin.Context, db *gorm.DB, existing *models.Auth, incoming *models.Auth, explicitProxy bool, source string) (*importAuthConflict, error) {
+	proxyURL := incoming.ProxyURL
+	if !explicitProxy {
+		proxyURL = existing.ProxyURL
+	}
+	row := models.AuthImportConflict{
+		AuthKey:     existing.Key,
+		AuthID:      existing.ID,
+		Source:      source,
+		ProxyURL:    proxyURL,
+		AuthGroupID: incoming.AuthGroupID.Clean(),
+		Content:     incoming.Content,
+		ImportedBy:  incoming.ImportedBy,
+	}
+	fields, current, theirs, errDiff := diffImportConflict(existing, &row)
+	if errDiff != nil {
+		return nil, errDiff
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	now := time.Now().UTC()
+	row.CreatedAt = now
+	row.UpdatedAt = now
+	if errUpsert := db.WithContext(c.Request.Context()).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "auth_key"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"auth_id":       row.AuthID,
+			"source":        row.Source,
+			"proxy_url":     row.ProxyURL,
+			"auth_group_id": row.AuthGroupID,
+			"content":       row.Content,
+			"imported_by":   row.ImportedBy,
+			"updated_at":    now,
+		}),
+	}).Create(&row).Error; errUpsert != nil {
+		return nil, errUpsert
+	}
+	var stored models.AuthImportConflict
+	if errFind := db.WithContext(c.Request.Context()).Where("auth_key = ?", existing.Key).First(&stored).Error; errFind != nil {
+		return nil, errFind
+	}
+	return &importAuthConflict{
+		ID:       stored.ID,
+		Key:      stored.AuthKey,
+		AuthID:   stored.AuthID,
+		Source:   stored.Source,
+		Fields:   fields,
+		Current:  current,
+		Incoming: theirs,
+	}, nil
+}
+
+// diffImportConflict lists the fields where the held entry differs from the existing auth.
+// The proxy_url content field is folded into the proxy_url column it mirrors.
+func diffImportConflict(existing *models.Auth, held *models.AuthImportConflict) ([]string, gin.H, gin.H, error) {
+	currentContent, errCurrent := decodeImportContent(existing.Content)
+	if errCurrent != nil {
+		return nil, nil, nil, errCurrent
+	}
+	incomingContent, errIncoming := decodeImportContent(held.Content)
+	if errIncoming != nil {
+		return nil, nil, nil, errIncoming
+	}
+
+	fields := make([]string, 0)
+	current := gin.H{}
+	theirs := gin.H{}
+
+	keys := make(map[string]struct{}, len(currentContent)+len(incomingContent))
+	for key := range currentContent {
+		keys[key] = struct{}{}
+	}
+	for key := range incomingContent {
+		keys[key] = struct{}{}
+	}
+	delete(keys, importFieldProxyURL)
+	currentDiff := make(map[string]any)
+	incomingDiff := make(map[string]any)
+	for key := range keys {
+		mine, okMine := currentContent[key]
+		other, okOther := incomingContent[key]
+		if okMine == okOther && reflect.DeepEqual(mine, other) {
+			continue
+		}
+		fields = append(fields, importFieldContent+key)
+		if okMine {
+			currentDiff[key] = mine
+		}
+		if okOther {
+			incomingDiff[key] = other
+		}
+	}
+	if len(currentDiff) > 0 || len(incomingDiff) > 0 {
+		current["content"] = currentDiff
+		theirs["content"] = incomingDiff
+	}
+
+	if strings.TrimSpace(existing.ProxyURL) != strings.TrimSpace(held.ProxyURL) {
+		fields = append(fields, importFieldProxyURL)
+		current[importFieldProxyURL] = existing.ProxyURL
+		theirs[importFieldProxyURL] = held.ProxyURL
+	}
+
+	mineGroups := sortedAuthGroupIDs(existing.AuthGroupID)
+	theirGroups := sortedAuthGroupIDs(held.AuthGroupID)
+	if !reflect.DeepEqual(mineGroups, theirGroups) {
+		fields = append(fields, importFieldAuthGroupID)
+		current[importFieldAuthGroupID] = mineGroups
+		theirs[importFieldAuthGroupID] = theirGroups
+	}
+
+	sort.Strings(fields)
+	return fields, current, theirs, nil
+}
+
+func decodeImportContent(raw datatypes.JSON) (map[string]any, error) {
+	content := make(map[string]any)
+	if len(raw) == 0 {
+		return content, nil
+	}
+	if errUnmarshal := json.Unmarshal(raw, &content); errUnmarshal != nil {
+		return nil, errUnmarshal
+	}
+	if content == nil {
+		content = make(map[string]any)
+	}
+	return content, nil
+}
+
+func sortedAuthGroupIDs(ids models.AuthGroupIDs) []uint64 {
+	values := ids.Values()
+	if values == nil {
+		values = []uint64{}
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	return values
+}
+
+// mergeImportConflict applies the fields chosen as theirs onto the existing auth and returns
+// the resulting content, proxy URL and auth groups.
+func mergeImportConflict(existing *models.Auth, held *models.AuthImportConflict, theirs map[string]struct{}) (map[string]any, string, models.AuthGroupIDs, error) {
+	content, errCurrent := decodeImportContent(existing.Content)
+	if errCurrent != nil {
+		return nil, "", nil, errCurrent
+	}
+	incomingContent, errIncoming := decodeImportContent(held.Content)
+	if errIncoming != nil {
+		return nil, "", nil, errIncoming
+	}
+	proxyURL := existing.ProxyURL
+	authGroupIDs := existing.AuthGroupID.Clean()
+
+	for field := range theirs {
+		switch {
+		case field == importFieldProxyURL:
+			proxyURL = held.ProxyURL
+			if value, ok := incomingContent[importFieldProxyURL]; ok {
+				content[importFieldProxyURL] = value
+			} else {
+				delete(content, importFieldProxyURL)
+			}
+		case field == importFieldAuthGroupID:
+			authGroupIDs = held.AuthGroupID.Clean()
+		case strings.HasPrefix(field, importFieldContent):
+			key := strings.TrimPrefix(field, importFieldContent)
+			if value, ok := incomingContent[key]; ok {
+				content[key] = value
+			} else {
+				delete(content, key)
+			}
+		}
+	}
+	return content, proxyURL, authGroupIDs, nil
+}
+
+// ListImportConflicts returns held import entries with their current field-level differences.
+func (h *AuthFileHandler) ListImportConflicts(c *gin.Context) {
+	var rows []models.AuthImportConflict
+	if errFind := h.db.WithContext(c.Request.Context()).Order("created_at ASC, id ASC").Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list import conflicts failed"})
+		return
+	}
+	authIDs := make([]uint64, 0, len(rows))
+	for _, row := range rows {
+		authIDs = append(authIDs, row.AuthID)
+	}
+	auths := make(map[uint64]*models.Auth, len(authIDs))
+	if len(authIDs) > 0 {
+		var existing []models.Auth
+		if errFind := h.db.WithContext(c.Request.Context()).Where("id IN ?", authIDs).Find(&existing).Error; errFind != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "list import conflicts failed"})
+			return
+		}
+		for i := range existing {
+			auths[existing[i].ID] = &existing[i]
+		}
+	}
+
+	conflicts := make([]importAuthConflict, 0, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		conflict := importAuthConflict{ID: row.ID, Key: row.AuthKey, AuthID: row.AuthID, Source: row.Source, Fields: []string{}}
+		if existing, ok := auths[row.AuthID]; ok {
+			fields, current, theirs, errDiff := diffImportConflict(existing, row)
+			if errDiff != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "diff import conflict failed"})
+				return
+			}
+			conflict.Fields, conflict.Current, conflict.Incoming = fields, current, theirs
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	c.JSON(http.StatusOK, gin.H{"conflicts": conflicts})
+}
+
+// ResolveImportConflict keeps the existing auth, takes the imported entry, or merges them per field.
+func (h *AuthFileHandler) ResolveImportConflict(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var body resolveImportConflictRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	resolution := strings.ToLower(strings.TrimSpace(body.Resolution))
+
+	ctx := c.Request.Context()
+	var held models.AuthImportConflict
+	if errFind := h.db.WithContext(ctx).First(&held, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query import conflict failed"})
+		return
+	}
+	var existing models.Auth
+	if errFind := h.db.WithContext(ctx).First(&existing, held.AuthID).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			_ = h.db.WithContext(ctx).Delete(&models.AuthImportConflict{}, held.ID).Error
+			c.JSON(http.StatusConflict, gin.H{"error": "auth file no longer exists; import the entry again"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query auth file failed"})
+		return
+	}
+
+	fields, _, _, errDiff := diffImportConflict(&existing, &held)
+	if errDiff != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "diff import conflict failed"})
+		return
+	}
+	theirs := make(map[string]struct{}, len(fields))
+	switch resolution {
+	case importResolutionKeepMine:
+	case importResolutionTakeTheirs:
+		for _, field := range fields {
+			theirs[field] = struct{}{}
+		}
+	case importResolutionMerge:
+		known := make(map[string]struct{}, len(fields))
+		for _, field := range fields {
+			known[field] = struct{}{}
+		}
+		for field, choice := range body.Fields {
+			field = strings.TrimSpace(field)
+			if _, ok := known[field]; !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "field " + field + " has no conflict"})
+				return
+			}
+			switch strings.ToLower(strings.TrimSpace(choice)) {
+			case importFieldMine:
+			case importFieldTheirs:
+				theirs[field] = struct{}{}
+			default:
+				c.JSON(http.StatusBadRequest, gin.H{"error": "field choice must be mine or theirs"})
+				return
+			}
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "resolution must be keep_mine, take_theirs or merge"})
+		return
+	}
+
+	applied := make([]string, 0, len(theirs))
+	for field := range theirs {
+		applied = append(applied, field)
+	}
+	sort.Strings(applied)
+	if len(applied) == 0 {
+		if errDelete := h.db.WithContext(ctx).Delete(&models.AuthImportConflict{}, held.ID).Error; errDelete != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "resolve import conflict failed"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"auth_id": existing.ID, "applied": applied, "pending": existing.PendingApproval})
+		return
+	}
+
+	content, proxyURL, authGroupIDs, errMerge := mergeImportConflict(&existing, &held, theirs)
+	if errMerge != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "merge import conflict failed"})
+		return
+	}
+	whitelistEnabled, allowedModels, excludedModels, errReconcile := reconcileWhitelistOnImportConflict(existing, content)
+	if errReconcile != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reconcile whitelist failed: " + errReconcile.Error()})
+		return
+	}
+	allowedModelsJSON, errAllowed := marshalStringSliceJSON(allowedModels)
+	if errAllowed != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "resolve import conflict failed"})
+		return
+	}
+	excludedModelsJSON, errExcluded := marshalStringSliceJSON(excludedModels)
+	if errExcluded != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "resolve import conflict failed"})
+		return
+	}
+	contentBytes, errMarshal := json.Marshal(content)
+	if errMarshal != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "resolve import conflict failed"})
+		return
+	}
+
+	updates := map[string]any{
+		"content":           datatypes.JSON(contentBytes),
+		"proxy_url":         proxyURL,
+		"auth_group_id":     authGroupIDs,
+		"whitelist_enabled": whitelistEnabled,
+		"allowed_models":    allowedModelsJSON,
+		"excluded_models":   excludedModelsJSON,
+		"updated_at":        time.Now().UTC(),
+	}
+	authType, _ := content["type"].(string)
+	review := reviewAuthImport(c, loadAuthImportApprovalPolicy(), authType)
+	review.apply(&existing, updates)
+
+	errTx := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if errUpdate := tx.Model(&models.Auth{}).Where("id = ?", existing.ID).Updates(updates).Error; errUpdate != nil {
+			return errUpdate
+		}
+		return tx.Delete(&models.AuthImportConflict{}, held.ID).Error
+	})
+	if errTx != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "resolve import conflict failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"auth_id": existing.ID, "applied": applied, "pending": review.pending()})
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func setupAuthImportConflictDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:auth_import_conflicts_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.AutoMigrate(&models.AuthGroup{}, &models.Auth{}, &models.AuthImportConflict{}); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return db
+}
+
+func TestAuthFiles_Import_HoldsConflictsAndResolvesPerField(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupAuthImportConflictDB(t)
+	defaultGroup := models.AuthGroup{Name: "conflict-default", IsDefault: true}
+	if errCreate := db.Create(&defaultGroup).Error; errCreate != nil {
+		t.Fatalf("create default auth group: %v", errCreate)
+	}
+	groupID := defaultGroup.ID
+	now := time.Now().UTC()
+	row := models.Auth{
+		Key:         "conflict-auth",
+		Name:        "conflict-auth",
+		AuthGroupID: models.AuthGroupIDs{&groupID},
+		Content:     datatypes.JSON(`{"id":"conflict-auth","type":"claude","email":"a@example.com","access_token":"old","prefix":"team"}`),
+		IsAvailable: true,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if errCreate := db.Create(&row).Error; errCreate != nil {
+		t.Fatalf("create auth row: %v", errCreate)
+	}
+
+	h := NewAuthFileHandler(db)
+	router := gin.New()
+	router.POST("/v0/admin/auth-files/import", h.Import)
+	router.GET("/v0/admin/auth-files/import-conflicts", h.ListImportConflicts)
+	router.POST("/v0/admin/auth-files/import-conflicts/:id/resolve", h.ResolveImportConflict)
+
+	importFiles := func(files map[string]string) importAuthFilesResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, buildAuthFilesImportRequest(t, "/v0/admin/auth-files/import", files))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d body=%s", w.Code, w.Body.String())
+		}
+		return decodeImportAuthFilesResponse(t, w.Body.Bytes())
+	}
+
+	resp := importFiles(map[string]string{
+		"same.json": `{"id":"conflict-auth","type":"claude","email":"a@example.com","access_token":"old","prefix":"team"}`,
+	})
+	if resp.Imported != 0 || resp.Unchanged != 1 || len(resp.Conflicts) != 0 {
+		t.Fatalf("expected identical re-import to be unchanged, got %+v", resp)
+	}
+
+	resp = importFiles(map[string]string{
+		"conflict.json": `{"id":"conflict-auth","type":"claude","email":"a@example.com","access_token":"new","base_url":"https://example.com"}`,
+	})
+	if resp.Imported != 0 || len(resp.Conflicts) != 1 {
+		t.Fatalf("expected one held conflict, got %+v", resp)
+	}
+	conflict := resp.Conflicts[0]
+	wantFields := []string{"content.access_token", "content.base_url", "content.prefix"}
+	if conflict.Key != "conflict-auth" || conflict.AuthID != row.ID || !reflect.DeepEqual(conflict.Fields, wantFields) {
+		t.Fatalf("unexpected conflict %+v", conflict)
+	}
+	var saved models.Auth
+	if errFind := db.First(&saved, row.ID).Error; errFind != nil {
+		t.Fatalf("reload auth: %v", errFind)
+	}
+	if !bytes.Equal(saved.Content, row.Content) {
+		t.Fatalf("held conflict must not change the auth, got %s", saved.Content)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v0/admin/auth-files/import-conflicts", nil))
+	var listed struct {
+		Conflicts []importAuthConflict `json:"conflicts"`
+	}
+	if errDecode := json.Unmarshal(w.Body.Bytes(), &listed); errDecode != nil || len(listed.Conflicts) != 1 || listed.Conflicts[0].ID != conflict.ID {
+		t.Fatalf("unexpected conflict list %s err=%v", w.Body.String(), errDecode)
+	}
+
+	resolve := func(body string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/v0/admin/auth-files/import-conflicts/"+strconv.FormatUint(conflict.ID, 10)+"/resolve", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := resolve(`{"resolution":"merge","fields":{"content.email":"theirs"}}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a field without conflict, got %d", rec.Code)
+	}
+	if rec := resolve(`{"resolution":"merge","fields":{"content.access_token":"theirs","content.prefix":"theirs","content.base_url":"mine"}}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 resolving, got %d body=%s", rec.Code, rec.Body.String())
+	}
+
+	if errFind := db.First(&saved, row.ID).Error; errFind != nil {
+		t.Fatalf("reload auth: %v", errFind)
+	}
+	var content map[string]any
+	if errDecode := json.Unmarshal(saved.Content, &content); errDecode != nil {
+		t.Fatalf("decode content: %v", errDecode)
+	}
+	if content["access_token"] != "new" || content["email"] != "a@example.com" {
+		t.Fatalf("expected merged content, got %v", content)
+	}
+	if _, ok := content["prefix"]; ok {
+		t.Fatalf("expected prefix removed by taking theirs, got %v", content)
+	}
+	if _, ok := content["base_url"]; ok {
+		t.Fatalf("expected base_url to keep mine, got %v", content)
+	}
+	if got := saved.AuthGroupID.Values(); !reflect.DeepEqual(got, []uint64{groupID}) {
+		t.Fatalf("auth_group_id=%v, want [%d]", got, groupID)
+	}
+	var remaining int64
+	db.Model(&models.AuthImportConflict{}).Count(&remaining)
+	if remaining != 0 {
+		t.Fatalf("expected resolved conflict removed, %d left", remaining)
+	}
+	if rec := resolve(`{"resolution":"keep_mine"}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a resolved conflict, got %d", rec.Code)
+	}
+}
@@ -8,11 +8,13 @@ import (
 	"fmt"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/proxyassign"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -32,8 +34,11 @@ type importAuthFilesByProviderFailure struct {
 }
 
 type importAuthFilesByProviderResponse struct {
-	Imported int                                `json:"imported"`
-	Failed   []importAuthFilesByProviderFailure `json:"failed"`
+	Imported  int                                `json:"imported"`
+	Pending   int                                `json:"pending"`
+	Unchanged int                                `json:"unchanged"`
+	Conflicts []importAuthConflict               `json:"conflicts"`
+	Failed    []importAuthFilesByProviderFailure `json:"failed"`
 }
 
 type providerImportRule struct {
@@ -369,8 +374,14 @@ func resolveProviderImportAuthGroupIDs(c *gin.Context, db *gorm.DB, provided mod
 	return models.AuthGroupIDs{}, nil
 }
 
-// ImportByProvider imports auth entries using explicit provider-driven validation.
+// ImportByProvider imports auth entries using explicit provider-driven validation. Entries whose
+// key already exists are held as conflicts for resolution unless on_conflict=overwrite.
 func (h *AuthFileHandler) ImportByProvider(c *gin.Context) {
+	conflictMode, okMode := parseImportConflictMode(c)
+	if !okMode {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid on_conflict"})
+		return
+	}
 	var body importAuthFilesByProviderRequest
 	if errBind := c.ShouldBindJSON(&body); errBind != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
@@ -404,7 +415,11 @@ func (h *AuthFileHandler) ImportByProvider(c *gin.Context) {
 	}
 
 	now := time.Now().UTC()
+	review := reviewAuthImport(c, loadAuthImportApprovalPolicy(), provider)
 	imported := 0
+	pending := 0
+	unchanged := 0
+	conflicts := make([]importAuthConflict, 0)
 	failures := make([]importAuthFilesByProviderFailure, 0)
 
 	for idx, entry := range body.Entries {
@@ -430,8 +445,12 @@ func (h *AuthFileHandler) ImportByProvider(c *gin.Context) {
 		if rawProxy, okProxy := normalized["proxy_url"].(string); okProxy {
 			proxyURL = strings.TrimSpace(rawProxy)
 		}
+		explicitProxy := proxyURL != ""
 		if proxyURL == "" && autoAssignProxyEnabled() {
-			assignedProxyURL, errAssignProxy := pickRandomProxyURL(c.Request.Context(), h.db)
+			assignedProxyURL, errAssignProxy := pickProxyURL(c.Request.Context(), h.db, proxyassign.Target{
+				Provider:     provider,
+				AuthGroupIDs: authGroupIDs.Values(),
+			})
 			if errAssignProxy != nil {
 				failures = append(failures, importAuthFilesByProviderFailure{
 					Index: idx + 1,
@@ -466,14 +485,47 @@ func (h *AuthFileHandler) ImportByProvider(c *gin.Context) {
 			UpdatedAt:   now,
 		}
 
+		updateFields := map[string]any{
+			"auth_group_id": auth.AuthGroupID,
+			"proxy_url":     auth.ProxyURL,
+			"content":       auth.Content,
+			"updated_at":    now,
+		}
+		review.apply(&auth, updateFields)
+
+		if conflictMode == importConflictReport {
+			var existing models.Auth
+			errFindExisting := h.db.WithContext(c.Request.Context()).Where("? = ?", clause.Column{Name: "key"}, key).First(&existing).Error
+			if errFindExisting != nil && !errors.Is(errFindExisting, gorm.ErrRecordNotFound) {
+				failures = append(failures, importAuthFilesByProviderFailure{
+					Index: idx + 1,
+					Key:   key,
+					Error: "import auth file failed",
+				})
+				continue
+			}
+			if errFindExisting == nil {
+				conflict, errHold := holdImportConflict(c, h.db, &existing, &auth, explicitProxy, "entry "+strconv.Itoa(idx+1))
+				if errHold != nil {
+					failures = append(failures, importAuthFilesByProviderFailure{
+						Index: idx + 1,
+						Key:   key,
+						Error: "import auth file failed",
+					})
+					continue
+				}
+				if conflict == nil {
+					unchanged++
+				} else {
+					conflicts = append(conflicts, *conflict)
+				}
+				continue
+			}
+		}
+
 		errCreate := h.db.WithContext(c.Request.Context()).Clauses(clause.OnConflict{
-			Columns: []clause.Column{{Name: "key"}},
-			DoUpdates: clause.Assignments(map[string]any{
-				"auth_group_id": auth.AuthGroupID,
-				"proxy_url":     auth.ProxyURL,
-				"content":       auth.Content,
-				"updated_at":    now,
-			}),
+			Columns:   []clause.Column{{Name: "key"}},
+			DoUpdates: clause.Assignments(updateFields),
 		}).Create(&auth).Error
 		if errCreate != nil {
 			failures = append(failures, importAuthFilesByProviderFailure{
@@ -485,10 +537,16 @@ func (h *AuthFileHandler) ImportByProvider(c *gin.Context) {
 		}
 
 		imported++
+		if review.pending() {
+			pending++
+		}
 	}
 
 	c.JSON(http.StatusOK, importAuthFilesByProviderResponse{
-		Imported: imported,
-		Failed:   failures,
+		Imported:  imported,
+		Pending:   pending,
+		Unchanged: unchanged,
+		Conflicts: conflicts,
+		Failed:    failures,
 	})
 }
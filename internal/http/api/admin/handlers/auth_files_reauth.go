@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/store"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// AuthFileReauthHandler restarts provider OAuth flows for existing auth files.
+type AuthFileReauthHandler struct {
+	db       *gorm.DB                   // Database handle for auth files.
+	starters map[string]gin.HandlerFunc // OAuth flow starters keyed by canonical provider.
+}
+
+// NewAuthFileReauthHandler constructs a re-authentication handler.
+func NewAuthFileReauthHandler(db *gorm.DB, starters map[string]gin.HandlerFunc) *AuthFileReauthHandler {
+	return &AuthFileReauthHandler{db: db, starters: starters}
+}
+
+// Reauth starts the provider OAuth flow for an auth file; once the flow completes the
+// refreshed credentials are written back onto the same auth row and token_invalid is cleared.
+// The pending flow is keyed by the OAuth state the starter returns, and the new credential
+// must belong to the auth file's account (email and project), so another login completing
+// meanwhile creates its own auth file instead of overwriting this one.
+func (h *AuthFileReauthHandler) Reauth(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var row models.Auth
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Select("id", "key", "content").
+		Where("id = ?", id).
+		First(&row).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	provider, errProvider := resolveAuthFileProviderFromContent(parseAuthContentMap(row.Content))
+	if errProvider != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errProvider.Error()})
+		return
+	}
+	start, ok := h.starters[provider]
+	if !ok || start == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "re-authentication is not supported for this provider"})
+		return
+	}
+
+	email, projectID := authFileIdentity(row.Content)
+	if email == "" && projectID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": store.ErrReauthNoIdentity.Error()})
+		return
+	}
+
+	writer := &oauthStartWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	start(c)
+	c.Writer = writer.ResponseWriter
+	if writer.Status() >= http.StatusBadRequest {
+		return
+	}
+	var started struct {
+		State string `json:"state"`
+	}
+	if errDecode := json.Unmarshal(writer.body.Bytes(), &started); errDecode != nil || strings.TrimSpace(started.State) == "" {
+		log.WithField("auth_key", row.Key).Warn("reauth: oauth starter returned no state; credential will be saved as a new auth file")
+		return
+	}
+	if errExpect := store.ExpectReauth(c.Request.Context(), h.db, started.State, provider, row.Key, row.Content, store.DefaultReauthTTL); errExpect != nil {
+		log.WithError(errExpect).WithField("auth_key", row.Key).Warn("reauth: record pending flow failed")
+	}
+}
+
+// authFileIdentity returns the account email and project stored in auth content.
+func authFileIdentity(content []byte) (email, projectID string) {
+	metadata := parseAuthContentMap(content)
+	if v, ok := metadata["email"].(string); ok {
+		email = strings.TrimSpace(v)
+	}
+	if v, ok := metadata["project_id"].(string); ok {
+		projectID = strings.TrimSpace(v)
+	}
+	return email, projectID
+}
+
+// oauthStartWriter passes an OAuth starter's response through while keeping a copy, so the
+// flow state it returns can be recorded.
+type oauthStartWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write forwards data and keeps a copy.
+func (w *oauthStartWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString forwards s and keeps a copy.
+func (w *oauthStartWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -470,7 +470,7 @@ func TestAuthFiles_ImportConflict_WhitelistRecomputedWhenIntersectionExists(t *t
 	router := gin.New()
 	router.POST("/v0/admin/auth-files/import", h.Import)
 
-	req := buildAuthFilesImportRequest(t, "/v0/admin/auth-files/import", map[string]string{
+	req := buildAuthFilesImportRequest(t, "/v0/admin/auth-files/import?on_conflict=overwrite", map[string]string{
 		"conflict.json": `{"id":"import-whitelist-intersection","type":"claude","access_token":"new"}`,
 	})
 	w := httptest.NewRecorder()
@@ -520,7 +520,7 @@ func TestAuthFiles_ImportConflict_WhitelistKeepsBlockAllWhenNoIntersection(t *te
 	router := gin.New()
 	router.POST("/v0/admin/auth-files/import", h.Import)
 
-	req := buildAuthFilesImportRequest(t, "/v0/admin/auth-files/import", map[string]string{
+	req := buildAuthFilesImportRequest(t, "/v0/admin/auth-files/import?on_conflict=overwrite", map[string]string{
 		"conflict.json": `{"id":"import-whitelist-empty-intersection","type":"claude","access_token":"new"}`,
 	})
 	w := httptest.NewRecorder()
@@ -570,7 +570,7 @@ func TestAuthFiles_ImportConflict_WhitelistKeepsBlockAllWhenOldAllowlistEmpty(t
 	router := gin.New()
 	router.POST("/v0/admin/auth-files/import", h.Import)
 
-	req := buildAuthFilesImportRequest(t, "/v0/admin/auth-files/import", map[string]string{
+	req := buildAuthFilesImportRequest(t, "/v0/admin/auth-files/import?on_conflict=overwrite", map[string]string{
 		"conflict.json": `{"id":"import-whitelist-block-all","type":"claude","access_token":"new"}`,
 	})
 	w := httptest.NewRecorder()
@@ -620,7 +620,7 @@ func TestAuthFiles_ImportConflict_FailsWhenUniverseUnavailableAndKeepsOriginalRe
 	router := gin.New()
 	router.POST("/v0/admin/auth-files/import", h.Import)
 
-	req := buildAuthFilesImportRequest(t, "/v0/admin/auth-files/import", map[string]string{
+	req := buildAuthFilesImportRequest(t, "/v0/admin/auth-files/import?on_conflict=overwrite", map[string]string{
 		"conflict.json": `{"id":"import-whitelist-no-universe","type":"claude","access_token":"new"}`,
 	})
 	w := httptest.NewRecorder()
@@ -688,7 +688,7 @@ func TestAuthFiles_ImportConflict_FailsWhenProviderCannotBeResolvedAndKeepsOrigi
 	router := gin.New()
 	router.POST("/v0/admin/auth-files/import", h.Import)
 
-	req := buildAuthFilesImportRequest(t, "/v0/admin/auth-files/import", map[string]string{
+	req := buildAuthFilesImportRequest(t, "/v0/admin/auth-files/import?on_conflict=overwrite", map[string]string{
 		"conflict.json": `{"id":"import-whitelist-unresolvable-provider","access_token":"new"}`,
 	})
 	w := httptest.NewRecorder()
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 	"strconv"
@@ -8,8 +9,10 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/authschedule"
 	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -29,6 +32,7 @@ type createAuthGroupRequest struct {
 	IsDefault   bool                `json:"is_default"`
 	RateLimit   int                 `json:"rate_limit"`
 	UserGroupID models.UserGroupIDs `json:"user_group_id"`
+	Schedule    json.RawMessage     `json:"schedule"` // Routing windows; null or no windows routes always.
 }
 
 // Create creates a new auth group.
@@ -44,12 +48,20 @@ func (h *AuthGroupHandler) Create(c *gin.Context) {
 		return
 	}
 
+	schedule, off, errSchedule := normalizeAuthGroupSchedule(body.Schedule)
+	if errSchedule != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errSchedule.Error()})
+		return
+	}
+
 	now := time.Now().UTC()
 	group := models.AuthGroup{
 		Name:        name,
 		IsDefault:   body.IsDefault,
 		RateLimit:   body.RateLimit,
 		UserGroupID: body.UserGroupID.Clean(),
+		Schedule:    schedule,
+		OffSchedule: off,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
@@ -73,6 +85,8 @@ func (h *AuthGroupHandler) Create(c *gin.Context) {
 		"is_default":    group.IsDefault,
 		"rate_limit":    group.RateLimit,
 		"user_group_id": group.UserGroupID.Clean(),
+		"schedule":      group.Schedule,
+		"off_schedule":  group.OffSchedule,
 		"created_at":    group.CreatedAt,
 		"updated_at":    group.UpdatedAt,
 	})
@@ -109,6 +123,8 @@ func (h *AuthGroupHandler) List(c *gin.Context) {
 			"is_default":    row.IsDefault,
 			"rate_limit":    row.RateLimit,
 			"user_group_id": row.UserGroupID.Clean(),
+			"schedule":      row.Schedule,
+			"off_schedule":  row.OffSchedule,
 			"created_at":    row.CreatedAt,
 			"updated_at":    row.UpdatedAt,
 		})
@@ -138,6 +154,8 @@ func (h *AuthGroupHandler) Get(c *gin.Context) {
 		"is_default":    group.IsDefault,
 		"rate_limit":    group.RateLimit,
 		"user_group_id": group.UserGroupID.Clean(),
+		"schedule":      group.Schedule,
+		"off_schedule":  group.OffSchedule,
 		"created_at":    group.CreatedAt,
 		"updated_at":    group.UpdatedAt,
 	})
@@ -149,6 +167,7 @@ type updateAuthGroupRequest struct {
 	IsDefault   *bool                `json:"is_default"`
 	RateLimit   *int                 `json:"rate_limit"`
 	UserGroupID *models.UserGroupIDs `json:"user_group_id"`
+	Schedule    json.RawMessage      `json:"schedule"` // Present to replace the schedule; null clears it.
 }
 
 // Update modifies an auth group.
@@ -163,6 +182,18 @@ func (h *AuthGroupHandler) Update(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
 		return
 	}
+	var (
+		schedule    []byte
+		offSchedule bool
+	)
+	if body.Schedule != nil {
+		var errSchedule error
+		schedule, offSchedule, errSchedule = normalizeAuthGroupSchedule(body.Schedule)
+		if errSchedule != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errSchedule.Error()})
+			return
+		}
+	}
 
 	now := time.Now().UTC()
 	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
@@ -186,6 +217,9 @@ func (h *AuthGroupHandler) Update(c *gin.Context) {
 		if body.UserGroupID != nil {
 			updates["user_group_id"] = body.UserGroupID.Clean()
 		}
+		if body.Schedule != nil {
+			updates["schedule"] = datatypes.JSON(schedule)
+		}
 
 		res := tx.Model(&models.AuthGroup{}).Where("id = ?", id).Updates(updates)
 		if res.Error != nil {
@@ -194,6 +228,10 @@ func (h *AuthGroupHandler) Update(c *gin.Context) {
 		if res.RowsAffected == 0 {
 			return gorm.ErrRecordNotFound
 		}
+		if body.Schedule != nil {
+			// Apply the new schedule right away instead of waiting for the evaluator.
+			return authschedule.SetOffSchedule(c.Request.Context(), tx, id, offSchedule, now)
+		}
 		return nil
 	})
 	if errTx != nil {
@@ -214,15 +252,27 @@ func (h *AuthGroupHandler) Delete(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	res := h.db.WithContext(c.Request.Context()).Delete(&models.AuthGroup{}, id)
-	if res.Error != nil {
+	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var group models.AuthGroup
+		if errFind := tx.Select("id", "off_schedule").First(&group, id).Error; errFind != nil {
+			return errFind
+		}
+		if group.OffSchedule {
+			// Members of a group hidden by its schedule become routable again.
+			if errRestore := authschedule.SetOffSchedule(c.Request.Context(), tx, id, false, time.Now().UTC()); errRestore != nil {
+				return errRestore
+			}
+		}
+		return tx.Delete(&models.AuthGroup{}, id).Error
+	})
+	if errTx != nil {
+		if errors.Is(errTx, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
 		return
 	}
-	if res.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
-		return
-	}
 	c.Status(http.StatusNoContent)
 }
 
@@ -261,3 +311,14 @@ func (h *AuthGroupHandler) SetDefault(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
+
+// normalizeAuthGroupSchedule validates a schedule for storage and reports whether the group is
+// outside it right now.
+func normalizeAuthGroupSchedule(raw json.RawMessage) (datatypes.JSON, bool, error) {
+	normalized, errNormalize := authschedule.Normalize(raw)
+	if errNormalize != nil {
+		return nil, false, errNormalize
+	}
+	schedule, _ := authschedule.Parse(normalized)
+	return datatypes.JSON(normalized), !schedule.Active(time.Now().UTC()), nil
+}
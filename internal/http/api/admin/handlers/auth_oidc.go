@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/oidc"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	// oidcStateCookie carries the signed state of an SSO login between login and callback.
+	oidcStateCookie = "cpab_admin_oidc_state"
+	// oidcCookiePath scopes the state cookie to the SSO endpoints.
+	oidcCookiePath = "/v0/admin/auth/oidc"
+)
+
+// SSO provisioning errors.
+var (
+	errOIDCNoAccount     = errors.New("no admin account is linked to this identity")
+	errOIDCUsernameTaken = errors.New("admin username is already taken by a local account")
+)
+
+// OIDCLogin starts an SSO login by redirecting to the identity provider.
+func (h *AuthHandler) OIDCLogin(c *gin.Context) {
+	cfg := oidc.LoadConfig()
+	if !cfg.Ready() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "oidc login is not enabled"})
+		return
+	}
+
+	var secrets [3]string
+	for i := range secrets {
+		value, errRandom := oidc.RandomToken()
+		if errRandom != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "start oidc login failed"})
+			return
+		}
+		secrets[i] = value
+	}
+	state, nonce, verifier := secrets[0], secrets[1], secrets[2]
+
+	authURL, errURL := oidc.ClientFor(cfg).AuthCodeURL(c.Request.Context(), state, nonce, verifier)
+	if errURL != nil {
+		log.WithError(errURL).Warn("oidc: build authorization url failed")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "oidc provider unavailable"})
+		return
+	}
+	stateToken, errToken := security.GenerateOIDCStateToken(h.jwtCfg.Secret, state, nonce, verifier)
+	if errToken != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "start oidc login failed"})
+		return
+	}
+	setOIDCStateCookie(c, stateToken, int(security.OIDCStateExpiry/time.Second))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback completes an SSO login. The redirect URL registered at the identity provider
+// forwards its code and state query parameters here; on success the response matches the
+// password login response.
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	cfg := oidc.LoadConfig()
+	if !cfg.Ready() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "oidc login is not enabled"})
+		return
+	}
+	ctx := c.Request.Context()
+
+	stateCookie, _ := c.Cookie(oidcStateCookie)
+	setOIDCStateCookie(c, "", -1)
+	if providerError := strings.TrimSpace(c.Query("error")); providerError != "" {
+		events.PublishLoginFailed(ctx, "admin", "", c.ClientIP(), "sso error: "+providerError)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "oidc login failed"})
+		return
+	}
+	code := strings.TrimSpace(c.Query("code"))
+	state := strings.TrimSpace(c.Query("state"))
+	if code == "" || state == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing code or state"})
+		return
+	}
+	stateClaims, errState := security.ParseOIDCStateToken(h.jwtCfg.Secret, stateCookie)
+	if errState != nil || subtle.ConstantTimeCompare([]byte(stateClaims.State), []byte(state)) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid oidc state"})
+		return
+	}
+
+	client := oidc.ClientFor(cfg)
+	rawIDToken, errExchange := client.Exchange(ctx, code, stateClaims.Verifier)
+	if errExchange != nil {
+		log.WithError(errExchange).Warn("oidc: code exchange failed")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "oidc token exchange failed"})
+		return
+	}
+	claims, errVerify := client.Verify(ctx, rawIDToken, stateClaims.Nonce)
+	if errVerify != nil {
+		log.WithError(errVerify).Warn("oidc: id token rejected")
+		events.PublishLoginFailed(ctx, "admin", "", c.ClientIP(), "sso invalid id token")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid id token"})
+		return
+	}
+
+	username := oidcUsername(cfg, claims)
+	access := cfg.ResolveAccess(claims.Strings(cfg.GroupsClaim))
+	if !access.Allowed {
+		events.PublishLoginFailed(ctx, "admin", username, c.ClientIP(), "sso groups not permitted")
+		c.JSON(http.StatusForbidden, gin.H{"error": "not permitted to sign in"})
+		return
+	}
+
+	admin, errProvision := syncOIDCAdmin(ctx, h.db, cfg, claims.Subject(), username, access)
+	if errProvision != nil {
+		switch {
+		case errors.Is(errProvision, errOIDCNoAccount):
+			events.PublishLoginFailed(ctx, "admin", username, c.ClientIP(), "sso account not provisioned")
+			c.JSON(http.StatusForbidden, gin.H{"error": errProvision.Error()})
+		case errors.Is(errProvision, errOIDCUsernameTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": errProvision.Error()})
+		default:
+			log.WithError(errProvision).Warn("oidc: provision admin failed")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "provision admin failed"})
+		}
+		return
+	}
+	if !admin.Active {
+		c.JSON(http.StatusForbidden, gin.H{"error": "admin account is disabled"})
+		return
+	}
+	// The identity provider enforces its own MFA, so the local MFA policy does not apply.
+	h.respondWithAdminToken(c, admin, gin.H{"sso": true})
+}
+
+func setOIDCStateCookie(c *gin.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, value, maxAge, oidcCookiePath, "", c.Request.TLS != nil, true)
+}
+
+// oidcUsername picks the admin username from the configured claim, then email, then subject.
+func oidcUsername(cfg oidc.Config, claims oidc.Claims) string {
+	for _, name := range []string{cfg.UsernameClaim, "email"} {
+		if value := claims.String(name); value != "" {
+			return value
+		}
+	}
+	return claims.Subject()
+}
+
+// syncOIDCAdmin finds or provisions the admin linked to an IdP identity and replaces its
+// super admin flag and roles with those mapped from the identity's groups, so the identity
+// provider stays the source of truth. Mapped role names that do not exist are skipped.
+func syncOIDCAdmin(ctx context.Context, db *gorm.DB, cfg oidc.Config, subject, username string, access oidc.Access) (models.Admin, error) {
+	var admin models.Admin
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		errFind := tx.Where("oidc_issuer = ? AND oidc_subject = ?", cfg.Issuer, subject).First(&admin).Error
+		switch {
+		case errors.Is(errFind, gorm.ErrRecordNotFound):
+			if !cfg.ProvisionEnabled() {
+				return errOIDCNoAccount
+			}
+			var taken int64
+			if errCount := tx.Model(&models.Admin{}).Where("username = ?", username).Count(&taken).Error; errCount != nil {
+				return errCount
+			}
+			if taken > 0 {
+				return errOIDCUsernameTaken
+			}
+			// SSO accounts get an unknown random password so they cannot use password login.
+			password, errRandom := oidc.RandomToken()
+			if errRandom != nil {
+				return errRandom
+			}
+			hash, errHash := security.HashPassword(password)
+			if errHash != nil {
+				return errHash
+			}
+			now := time.Now().UTC()
+			admin = models.Admin{
+				Username:     username,
+				Password:     hash,
+				Active:       true,
+				IsSuperAdmin: access.SuperAdmin,
+				Permissions:  datatypes.JSON("[]"),
+				CreatedAt:    now,
+				UpdatedAt:    now,
+
+				DeniedPermissions: datatypes.JSON("[]"),
+				AllowedIPs:        datatypes.JSON("[]"),
+				OIDCIssuer:        cfg.Issuer,
+				OIDCSubject:       subject,
+			}
+			if errCreate := tx.Create(&admin).Error; errCreate != nil {
+				return errCreate
+			}
+		case errFind != nil:
+			return errFind
+		default:
+			if admin.IsSuperAdmin != access.SuperAdmin {
+				if errUpdate := tx.Model(&models.Admin{}).Where("id = ?", admin.ID).
+					Updates(map[string]any{"is_super_admin": access.SuperAdmin, "updated_at": time.Now().UTC()}).Error; errUpdate != nil {
+					return errUpdate
+				}
+				admin.IsSuperAdmin = access.SuperAdmin
+			}
+		}
+
+		var roleIDs []uint64
+		if len(access.Roles) > 0 {
+			if errRoles := tx.Model(&models.AdminRole{}).Where("name IN ?", access.Roles).Order("id ASC").Pluck("id", &roleIDs).Error; errRoles != nil {
+				return errRoles
+			}
+			if len(roleIDs) != len(access.Roles) {
+				log.Warnf("oidc: some mapped admin roles do not exist: %v", access.Roles)
+			}
+		}
+		return replaceAdminRoles(tx, admin.ID, roleIDs)
+	})
+	if errTx != nil {
+		return models.Admin{}, errTx
+	}
+	return admin, nil
+}
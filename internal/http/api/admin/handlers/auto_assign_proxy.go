@@ -4,11 +4,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"strings"
 
-	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
-	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/proxyassign"
 	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
 	"gorm.io/gorm"
 )
@@ -22,29 +20,10 @@ func autoAssignProxyEnabled() bool {
 	return parseDBConfigBool(raw)
 }
 
-// pickRandomProxyURL selects a random proxy URL from the proxy table.
-func pickRandomProxyURL(ctx context.Context, db *gorm.DB) (string, error) {
-	var row models.Proxy
-	if errFind := db.WithContext(ctx).
-		Order(randomOrderExpr(db)).
-		Limit(1).
-		Take(&row).Error; errFind != nil {
-		if errors.Is(errFind, gorm.ErrRecordNotFound) {
-			return "", nil
-		}
-		return "", errFind
-	}
-	return strings.TrimSpace(row.ProxyURL), nil
-}
-
-// randomOrderExpr returns a dialect-aware random ordering expression.
-func randomOrderExpr(db *gorm.DB) string {
-	switch dbutil.DialectName(db) {
-	case dbutil.DialectSQLite, dbutil.DialectPostgres:
-		return "RANDOM()"
-	default:
-		return "RANDOM()"
-	}
+// pickProxyURL selects a proxy URL for target with the configured assignment strategy,
+// skipping proxies the proxy health checker marked inactive.
+func pickProxyURL(ctx context.Context, db *gorm.DB, target proxyassign.Target) (string, error) {
+	return proxyassign.Pick(ctx, db, proxyassign.LoadConfig(), target)
 }
 
 // parseDBConfigBool parses a boolean from JSON config payloads.
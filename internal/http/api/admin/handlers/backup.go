@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/backup"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/cluster"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// backupPassphraseHeader carries the passphrase that seals or opens a backup.
+	backupPassphraseHeader = "X-Backup-Passphrase"
+	// maxRestoreBytes caps the size of an uploaded backup.
+	maxRestoreBytes = 64 << 20
+)
+
+// BackupHandler exports and restores the database-backed configuration.
+type BackupHandler struct {
+	db *gorm.DB // Database handle for backed up tables.
+}
+
+// NewBackupHandler constructs a backup handler with a database dependency.
+func NewBackupHandler(db *gorm.DB) *BackupHandler {
+	return &BackupHandler{db: db}
+}
+
+// Backup streams an encrypted archive of provider keys, auth files, users, groups, billing
+// rules and settings. Archives contain every secret, so only super admins may take them.
+func (h *BackupHandler) Backup(c *gin.Context) {
+	if !c.GetBool("adminIsSuperAdmin") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "super admin required"})
+		return
+	}
+	now := time.Now().UTC()
+	archive, errBuild := backup.Build(c.Request.Context(), h.db, now)
+	if errBuild != nil {
+		log.WithError(errBuild).Error("build backup failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "build backup failed"})
+		return
+	}
+	sealed, errSeal := backup.Seal(archive, c.GetHeader(backupPassphraseHeader))
+	if errSeal != nil {
+		if errors.Is(errSeal, backup.ErrWeakPassphrase) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s header must be at least %d characters", backupPassphraseHeader, backup.MinPassphraseLength)})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "seal backup failed"})
+		return
+	}
+	filename := fmt.Sprintf("cpab-backup-%s.json", now.Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/octet-stream", sealed)
+}
+
+// Restore applies an uploaded archive. The strategy query parameter (skip, overwrite or
+// merge) decides what happens to rows that already exist.
+func (h *BackupHandler) Restore(c *gin.Context) {
+	if !c.GetBool("adminIsSuperAdmin") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "super admin required"})
+		return
+	}
+	strategy, errStrategy := backup.ParseStrategy(c.Query("strategy"))
+	if errStrategy != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid strategy, use skip, overwrite or merge"})
+		return
+	}
+	data, errRead := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxRestoreBytes))
+	if errRead != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "backup too large"})
+		return
+	}
+	archive, errOpen := backup.Open(data, c.GetHeader(backupPassphraseHeader))
+	if errOpen != nil {
+		switch {
+		case errors.Is(errOpen, backup.ErrBadPassphrase):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "wrong passphrase or corrupted backup"})
+		case errors.Is(errOpen, backup.ErrUnsupported):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported backup format"})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid backup"})
+		}
+		return
+	}
+
+	ctx := c.Request.Context()
+	result, errRestore := backup.Restore(ctx, h.db, archive, strategy)
+	if errRestore != nil {
+		log.WithError(errRestore).Error("restore backup failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "restore failed"})
+		return
+	}
+	if errRefresh := internalsettings.RefreshDBConfigSnapshot(ctx, h.db); errRefresh != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "refresh settings snapshot failed"})
+		return
+	}
+	cluster.Publish(ctx, cluster.KindConfigChanged)
+	cluster.Publish(ctx, cluster.KindSettingsChanged)
+	c.JSON(http.StatusOK, gin.H{
+		"created_at":  archive.CreatedAt,
+		"app_version": archive.AppVersion,
+		"strategy":    result.Strategy,
+		"sections":    result.Sections,
+	})
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// balanceTransactionListLimit caps ledger list responses.
+const balanceTransactionListLimit = 500
+
+// BalanceTransactionHandler manages balance adjustments and the balance ledger.
+type BalanceTransactionHandler struct {
+	db *gorm.DB // Database handle for ledger and balance records.
+}
+
+// NewBalanceTransactionHandler constructs a balance transaction handler.
+func NewBalanceTransactionHandler(db *gorm.DB) *BalanceTransactionHandler {
+	return &BalanceTransactionHandler{db: db}
+}
+
+// adjustBalanceRequest defines the request body for a manual balance adjustment.
+type adjustBalanceRequest struct {
+	Target        string  `json:"target"`          // "prepaid" or "bill".
+	Kind          string  `json:"kind"`            // "adjustment" (default) or "refund".
+	Amount        float64 `json:"amount"`          // Signed change; positive credits, negative debits.
+	BillID        uint64  `json:"bill_id"`         // Bill to adjust; required for bill adjustments.
+	PrepaidCardID uint64  `json:"prepaid_card_id"` // Card to adjust; optional for prepaid adjustments.
+	Reason        string  `json:"reason"`          // Required explanation recorded in the ledger.
+}
+
+// Adjust credits or debits a user's prepaid balance or bill quota and records it in the ledger.
+func (h *BalanceTransactionHandler) Adjust(c *gin.Context) {
+	userID, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var body adjustBalanceRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	kind := models.BalanceTransactionKind(strings.TrimSpace(body.Kind))
+	if kind == "" {
+		kind = models.BalanceTransactionKindAdjustment
+	}
+	if kind != models.BalanceTransactionKindAdjustment && kind != models.BalanceTransactionKindRefund {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be adjustment or refund"})
+		return
+	}
+	target := models.BalanceTransactionTarget(strings.TrimSpace(body.Target))
+	if target != models.BalanceTransactionTargetPrepaid && target != models.BalanceTransactionTargetBill {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target must be prepaid or bill"})
+		return
+	}
+	if body.Amount == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amount must be non-zero"})
+		return
+	}
+	if strings.TrimSpace(body.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+		return
+	}
+	if target == models.BalanceTransactionTargetBill && body.BillID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bill_id is required"})
+		return
+	}
+
+	actor := "admin"
+	if username := strings.TrimSpace(c.GetString("adminUsername")); username != "" {
+		actor = "admin:" + username
+	}
+	entries, errAdjust := billing.AdjustBalance(c.Request.Context(), h.db, billing.Adjustment{
+		UserID:        userID,
+		Target:        target,
+		Kind:          kind,
+		Amount:        body.Amount,
+		BillID:        body.BillID,
+		PrepaidCardID: body.PrepaidCardID,
+		Reason:        body.Reason,
+		Actor:         actor,
+	}, time.Now().UTC())
+	switch {
+	case errors.Is(errAdjust, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user, bill or prepaid card not found"})
+		return
+	case errors.Is(errAdjust, billing.ErrInvalidAdjustment):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid adjustment"})
+		return
+	case errors.Is(errAdjust, billing.ErrInsufficientPrepaidBalance), errors.Is(errAdjust, billing.ErrInsufficientBillQuota):
+		c.JSON(http.StatusConflict, gin.H{"error": errAdjust.Error()})
+		return
+	case errAdjust != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "adjust balance failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(entries))
+	for i := range entries {
+		out = append(out, formatBalanceTransaction(&entries[i]))
+	}
+	c.JSON(http.StatusCreated, gin.H{"transactions": out})
+}
+
+// List returns ledger entries filtered by user_id, target, kind, bill_id and time range.
+func (h *BalanceTransactionHandler) List(c *gin.Context) {
+	q := h.db.WithContext(c.Request.Context()).Model(&models.BalanceTransaction{})
+	for _, column := range []string{"user_id", "bill_id", "prepaid_card_id", "usage_id"} {
+		raw := strings.TrimSpace(c.Query(column))
+		if raw == "" {
+			continue
+		}
+		id, errParse := strconv.ParseUint(raw, 10, 64)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + column})
+			return
+		}
+		q = q.Where(column+" = ?", id)
+	}
+	if target := strings.TrimSpace(c.Query("target")); target != "" {
+		q = q.Where("target = ?", target)
+	}
+	if kind := strings.TrimSpace(c.Query("kind")); kind != "" {
+		q = q.Where("kind = ?", kind)
+	}
+	if raw := strings.TrimSpace(c.Query("since")); raw != "" {
+		since, errSince := time.Parse(time.RFC3339, raw)
+		if errSince != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid since"})
+			return
+		}
+		q = q.Where("created_at >= ?", since)
+	}
+	if raw := strings.TrimSpace(c.Query("until")); raw != "" {
+		until, errUntil := time.Parse(time.RFC3339, raw)
+		if errUntil != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid until"})
+			return
+		}
+		q = q.Where("created_at < ?", until)
+	}
+	var rows []models.BalanceTransaction
+	if errFind := q.Order("id DESC").Limit(balanceTransactionListLimit).Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list balance transactions failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatBalanceTransaction(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"transactions": out})
+}
+
+// formatBalanceTransaction converts a ledger entry into a response payload.
+func formatBalanceTransaction(row *models.BalanceTransaction) gin.H {
+	return gin.H{
+		"id":              row.ID,
+		"user_id":         row.UserID,
+		"target":          row.Target,
+		"kind":            row.Kind,
+		"amount":          row.Amount,
+		"balance_after":   row.BalanceAfter,
+		"prepaid_card_id": row.PrepaidCardID,
+		"bill_id":         row.BillID,
+		"usage_id":        row.UsageID,
+		"reason":          row.Reason,
+		"actor":           row.Actor,
+		"created_at":      row.CreatedAt,
+	}
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"strconv"
@@ -9,7 +11,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 	internalbilling "github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/currency"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -35,7 +39,14 @@ type createBillingRuleRequest struct {
 	PriceOutputToken      *float64 `json:"price_output_token"`       // Price per output token.
 	PriceCacheCreateToken *float64 `json:"price_cache_create_token"` // Price per cache create token.
 	PriceCacheReadToken   *float64 `json:"price_cache_read_token"`   // Price per cache read token.
+	Currency              string   `json:"currency"`                 // Price currency; defaults to USD.
 	IsEnabled             *bool    `json:"is_enabled"`               // Required enabled flag.
+
+	TokenTiers    json.RawMessage `json:"token_tiers"`    // Tier table for tiered billing.
+	MinimumCharge *float64        `json:"minimum_charge"` // Optional minimum charge per request.
+
+	EnergyWhPerMillionTokens *float64 `json:"energy_wh_per_million_tokens"` // Optional energy factor.
+	CarbonGramsPerKWh        *float64 `json:"carbon_grams_per_kwh"`         // Optional carbon intensity.
 }
 
 // Create validates input and inserts a billing rule.
@@ -60,17 +71,24 @@ func (h *BillingRuleHandler) Create(c *gin.Context) {
 	}
 
 	billingType := models.BillingType(body.BillingType)
-	if billingType != models.BillingTypePerRequest && billingType != models.BillingTypePerToken {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "billing_type must be 1 (per_request) or 2 (per_token)"})
+	if !isRuleBillingType(billingType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "billing_type must be 1 (per_request), 2 (per_token) or 3 (tiered)"})
 		return
 	}
 
-	if billingType == models.BillingTypePerRequest {
+	tokenTiers := normalizeTokenTiers(body.TokenTiers)
+	switch billingType {
+	case models.BillingTypePerRequest:
 		if body.PricePerRequest == nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "price_per_request is required for per_request billing"})
 			return
 		}
-	} else {
+	case models.BillingTypeTiered:
+		if errTiers := validateTokenTiers(tokenTiers); errTiers != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errTiers})
+			return
+		}
+	default:
 		if body.PriceInputToken == nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "price_input_token is required for per_token billing"})
 			return
@@ -99,6 +117,19 @@ func (h *BillingRuleHandler) Create(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "model is required"})
 		return
 	}
+	if errFactor := validateFootprintFactors(body.EnergyWhPerMillionTokens, body.CarbonGramsPerKWh); errFactor != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errFactor})
+		return
+	}
+	if body.MinimumCharge != nil && *body.MinimumCharge < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "minimum_charge must be non-negative"})
+		return
+	}
+	ruleCurrency, errCurrency := h.resolveRuleCurrency(c, body.Currency)
+	if errCurrency != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errCurrency})
+		return
+	}
 
 	now := time.Now().UTC()
 	rule := models.BillingRule{
@@ -112,9 +143,15 @@ func (h *BillingRuleHandler) Create(c *gin.Context) {
 		PriceOutputToken:      body.PriceOutputToken,
 		PriceCacheCreateToken: body.PriceCacheCreateToken,
 		PriceCacheReadToken:   body.PriceCacheReadToken,
+		Currency:              ruleCurrency,
+		TokenTiers:            tokenTiers,
+		MinimumCharge:         body.MinimumCharge,
 		IsEnabled:             *body.IsEnabled,
 		CreatedAt:             now,
 		UpdatedAt:             now,
+
+		EnergyWhPerMillionTokens: body.EnergyWhPerMillionTokens,
+		CarbonGramsPerKWh:        body.CarbonGramsPerKWh,
 	}
 
 	if errCreate := h.db.WithContext(c.Request.Context()).Create(&rule).Error; errCreate != nil {
@@ -194,7 +231,14 @@ type updateBillingRuleRequest struct {
 	PriceOutputToken      *float64 `json:"price_output_token"`       // Optional output token price.
 	PriceCacheCreateToken *float64 `json:"price_cache_create_token"` // Optional cache create price.
 	PriceCacheReadToken   *float64 `json:"price_cache_read_token"`   // Optional cache read price.
+	Currency              *string  `json:"currency"`                 // Optional price currency.
 	IsEnabled             *bool    `json:"is_enabled"`               // Optional enabled flag.
+
+	TokenTiers    json.RawMessage `json:"token_tiers"`    // Optional tier table.
+	MinimumCharge *float64        `json:"minimum_charge"` // Optional minimum charge; negative clears it.
+
+	EnergyWhPerMillionTokens *float64 `json:"energy_wh_per_million_tokens"` // Optional energy factor.
+	CarbonGramsPerKWh        *float64 `json:"carbon_grams_per_kwh"`         // Optional carbon intensity.
 }
 
 // Update validates and applies billing rule changes.
@@ -261,8 +305,8 @@ func (h *BillingRuleHandler) Update(c *gin.Context) {
 	newBillingType := existing.BillingType
 	if body.BillingType != nil {
 		bt := models.BillingType(*body.BillingType)
-		if bt != models.BillingTypePerRequest && bt != models.BillingTypePerToken {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "billing_type must be 1 (per_request) or 2 (per_token)"})
+		if !isRuleBillingType(bt) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "billing_type must be 1 (per_request), 2 (per_token) or 3 (tiered)"})
 			return
 		}
 		newBillingType = bt
@@ -289,12 +333,23 @@ func (h *BillingRuleHandler) Update(c *gin.Context) {
 		newPriceCacheReadToken = body.PriceCacheReadToken
 	}
 
-	if newBillingType == models.BillingTypePerRequest {
+	newTokenTiers := existing.TokenTiers
+	if body.TokenTiers != nil {
+		newTokenTiers = normalizeTokenTiers(body.TokenTiers)
+	}
+
+	switch newBillingType {
+	case models.BillingTypePerRequest:
 		if newPricePerRequest == nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "price_per_request is required for per_request billing"})
 			return
 		}
-	} else {
+	case models.BillingTypeTiered:
+		if errTiers := validateTokenTiers(newTokenTiers); errTiers != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errTiers})
+			return
+		}
+	default:
 		if newPriceInputToken == nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "price_input_token is required for per_token billing"})
 			return
@@ -326,10 +381,36 @@ func (h *BillingRuleHandler) Update(c *gin.Context) {
 		"price_output_token":       newPriceOutputToken,
 		"price_cache_create_token": newPriceCacheCreateToken,
 		"price_cache_read_token":   newPriceCacheReadToken,
+		"token_tiers":              newTokenTiers,
+	}
+	if body.MinimumCharge != nil {
+		if *body.MinimumCharge < 0 {
+			updates["minimum_charge"] = nil
+		} else {
+			updates["minimum_charge"] = *body.MinimumCharge
+		}
 	}
 	if body.IsEnabled != nil {
 		updates["is_enabled"] = *body.IsEnabled
 	}
+	if body.Currency != nil {
+		ruleCurrency, errCurrency := h.resolveRuleCurrency(c, *body.Currency)
+		if errCurrency != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errCurrency})
+			return
+		}
+		updates["currency"] = ruleCurrency
+	}
+	if errFactor := validateFootprintFactors(body.EnergyWhPerMillionTokens, body.CarbonGramsPerKWh); errFactor != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errFactor})
+		return
+	}
+	if body.EnergyWhPerMillionTokens != nil {
+		updates["energy_wh_per_million_tokens"] = body.EnergyWhPerMillionTokens
+	}
+	if body.CarbonGramsPerKWh != nil {
+		updates["carbon_grams_per_kwh"] = body.CarbonGramsPerKWh
+	}
 
 	res := h.db.WithContext(c.Request.Context()).Model(&models.BillingRule{}).Where("id = ?", id).Updates(updates)
 	if res.Error != nil {
@@ -393,21 +474,88 @@ func (h *BillingRuleHandler) SetEnabled(c *gin.Context) {
 // formatRule converts a billing rule into a response payload.
 func (h *BillingRuleHandler) formatRule(rule *models.BillingRule) gin.H {
 	return gin.H{
-		"id":                       rule.ID,
-		"auth_group_id":            rule.AuthGroupID,
-		"user_group_id":            rule.UserGroupID,
-		"provider":                 rule.Provider,
-		"model":                    rule.Model,
-		"billing_type":             rule.BillingType,
-		"price_per_request":        rule.PricePerRequest,
-		"price_input_token":        rule.PriceInputToken,
-		"price_output_token":       rule.PriceOutputToken,
-		"price_cache_create_token": rule.PriceCacheCreateToken,
-		"price_cache_read_token":   rule.PriceCacheReadToken,
-		"is_enabled":               rule.IsEnabled,
-		"created_at":               rule.CreatedAt,
-		"updated_at":               rule.UpdatedAt,
+		"id":                           rule.ID,
+		"auth_group_id":                rule.AuthGroupID,
+		"user_group_id":                rule.UserGroupID,
+		"provider":                     rule.Provider,
+		"model":                        rule.Model,
+		"billing_type":                 rule.BillingType,
+		"price_per_request":            rule.PricePerRequest,
+		"price_input_token":            rule.PriceInputToken,
+		"price_output_token":           rule.PriceOutputToken,
+		"price_cache_create_token":     rule.PriceCacheCreateToken,
+		"price_cache_read_token":       rule.PriceCacheReadToken,
+		"currency":                     rule.Currency,
+		"token_tiers":                  rule.TokenTiers,
+		"minimum_charge":               rule.MinimumCharge,
+		"energy_wh_per_million_tokens": rule.EnergyWhPerMillionTokens,
+		"carbon_grams_per_kwh":         rule.CarbonGramsPerKWh,
+		"is_enabled":                   rule.IsEnabled,
+		"created_at":                   rule.CreatedAt,
+		"updated_at":                   rule.UpdatedAt,
+	}
+}
+
+// isRuleBillingType reports whether billingType can be set on a billing rule.
+func isRuleBillingType(billingType models.BillingType) bool {
+	switch billingType {
+	case models.BillingTypePerRequest, models.BillingTypePerToken, models.BillingTypeTiered:
+		return true
+	default:
+		return false
+	}
+}
+
+// normalizeTokenTiers converts a request tier table into the stored JSON, treating null as unset.
+func normalizeTokenTiers(raw json.RawMessage) datatypes.JSON {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return nil
+	}
+	return datatypes.JSON(trimmed)
+}
+
+// validateTokenTiers checks the tier table of a tiered rule and returns an error message.
+func validateTokenTiers(raw datatypes.JSON) string {
+	tiers, errParse := internalbilling.ParseTokenTiers(raw)
+	if errParse != nil {
+		return errParse.Error()
+	}
+	if len(tiers) == 0 {
+		return "token_tiers is required for tiered billing"
+	}
+	return ""
+}
+
+// resolveRuleCurrency normalizes a rule currency and checks that it can be converted to USD.
+// It returns the code, or a validation message.
+func (h *BillingRuleHandler) resolveRuleCurrency(c *gin.Context, raw string) (string, string) {
+	code, errCode := currency.Normalize(raw)
+	if errCode != nil {
+		return "", "currency must be a three-letter ISO code"
+	}
+	if code == currency.Base {
+		return code, ""
+	}
+	table, errLoad := currency.Load(c.Request.Context(), h.db)
+	if errLoad != nil {
+		return "", "load exchange rates failed"
+	}
+	if _, errRate := table.Rate(code); errRate != nil {
+		return "", "no exchange rate configured for " + code
+	}
+	return code, ""
+}
+
+// validateFootprintFactors checks optional footprint factors and returns an error message.
+func validateFootprintFactors(energyWhPerMillionTokens, carbonGramsPerKWh *float64) string {
+	if energyWhPerMillionTokens != nil && *energyWhPerMillionTokens < 0 {
+		return "energy_wh_per_million_tokens must be non-negative"
+	}
+	if carbonGramsPerKWh != nil && *carbonGramsPerKWh < 0 {
+		return "carbon_grams_per_kwh must be non-negative"
 	}
+	return ""
 }
 
 // batchImportRequest captures the payload for batch importing billing rules.
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/currency"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"gorm.io/gorm"
 )
@@ -28,6 +29,7 @@ type createBillRequest struct {
 	UserID      uint64  `json:"user_id"`      // User ID.
 	PeriodType  int     `json:"period_type"`  // Billing period type.
 	Amount      float64 `json:"amount"`       // Billing amount.
+	Currency    string  `json:"currency"`     // ISO currency of amount; defaults to USD.
 	PeriodStart string  `json:"period_start"` // RFC3339 period start.
 	PeriodEnd   string  `json:"period_end"`   // RFC3339 period end.
 	TotalQuota  float64 `json:"total_quota"`  // Total quota.
@@ -38,6 +40,8 @@ type createBillRequest struct {
 	RateLimit   *int    `json:"rate_limit"`   // Optional rate limit per second.
 	IsEnabled   *bool   `json:"is_enabled"`   // Optional active flag.
 	Status      int     `json:"status"`       // Bill status.
+	AutoRenew   bool    `json:"auto_renew"`   // Create the next period's bill automatically.
+	RenewalMode int     `json:"renewal_mode"` // Optional renewal payment mode (1 manual, 2 prepaid).
 }
 
 // Create validates input and inserts a bill record.
@@ -56,6 +60,9 @@ func (h *BillHandler) Create(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
 		return
 	}
+	if !checkOrganizationUser(c, h.db, body.UserID) {
+		return
+	}
 
 	periodType := models.BillPeriodType(body.PeriodType)
 	if periodType != models.BillPeriodTypeMonthly && periodType != models.BillPeriodTypeYearly {
@@ -69,6 +76,21 @@ func (h *BillHandler) Create(c *gin.Context) {
 		return
 	}
 
+	renewalMode := models.BillRenewalModeManual
+	if body.RenewalMode != 0 {
+		renewalMode = models.BillRenewalMode(body.RenewalMode)
+		if !validBillRenewalMode(renewalMode) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "renewal_mode must be 1 (manual) or 2 (prepaid)"})
+			return
+		}
+	}
+
+	billCurrency, errCurrency := currency.Normalize(body.Currency)
+	if errCurrency != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "currency must be a three-letter ISO code"})
+		return
+	}
+
 	periodStart, errParseStart := time.Parse(time.RFC3339, body.PeriodStart)
 	if errParseStart != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid period_start format, use RFC3339"})
@@ -106,6 +128,7 @@ func (h *BillHandler) Create(c *gin.Context) {
 		UserGroupID: plan.UserGroupID.Clean(),
 		PeriodType:  periodType,
 		Amount:      body.Amount,
+		Currency:    billCurrency,
 		PeriodStart: periodStart,
 		PeriodEnd:   periodEnd,
 		TotalQuota:  body.TotalQuota,
@@ -116,6 +139,8 @@ func (h *BillHandler) Create(c *gin.Context) {
 		RateLimit:   rateLimit,
 		IsEnabled:   isEnabled,
 		Status:      status,
+		AutoRenew:   body.AutoRenew,
+		RenewalMode: renewalMode,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
@@ -136,7 +161,7 @@ func (h *BillHandler) List(c *gin.Context) {
 		enabledQ = strings.TrimSpace(c.Query("is_enabled"))
 	)
 
-	q := h.db.WithContext(c.Request.Context()).Model(&models.Bill{})
+	q := scopeToOrganizationUsers(c, h.db, h.db.WithContext(c.Request.Context()).Model(&models.Bill{}), "user_id")
 	if planIDQ != "" {
 		if id, errParse := strconv.ParseUint(planIDQ, 10, 64); errParse == nil {
 			q = q.Where("plan_id = ?", id)
@@ -180,7 +205,7 @@ func (h *BillHandler) Get(c *gin.Context) {
 		return
 	}
 	var bill models.Bill
-	if errFind := h.db.WithContext(c.Request.Context()).First(&bill, id).Error; errFind != nil {
+	if errFind := scopeToOrganizationUsers(c, h.db, h.db.WithContext(c.Request.Context()), "user_id").First(&bill, id).Error; errFind != nil {
 		if errors.Is(errFind, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			return
@@ -197,6 +222,7 @@ type updateBillRequest struct {
 	UserID      *uint64  `json:"user_id"`      // Optional user ID.
 	PeriodType  *int     `json:"period_type"`  // Optional period type.
 	Amount      *float64 `json:"amount"`       // Optional amount.
+	Currency    *string  `json:"currency"`     // Optional ISO currency of amount.
 	PeriodStart *string  `json:"period_start"` // Optional RFC3339 period start.
 	PeriodEnd   *string  `json:"period_end"`   // Optional RFC3339 period end.
 	TotalQuota  *float64 `json:"total_quota"`  // Optional total quota.
@@ -207,6 +233,8 @@ type updateBillRequest struct {
 	RateLimit   *int     `json:"rate_limit"`   // Optional rate limit per second.
 	IsEnabled   *bool    `json:"is_enabled"`   // Optional active flag.
 	Status      *int     `json:"status"`       // Optional bill status.
+	AutoRenew   *bool    `json:"auto_renew"`   // Optional auto-renewal flag.
+	RenewalMode *int     `json:"renewal_mode"` // Optional renewal payment mode.
 }
 
 // Update validates and applies bill field updates.
@@ -223,7 +251,7 @@ func (h *BillHandler) Update(c *gin.Context) {
 	}
 
 	var existing models.Bill
-	if errFind := h.db.WithContext(c.Request.Context()).First(&existing, id).Error; errFind != nil {
+	if errFind := scopeToOrganizationUsers(c, h.db, h.db.WithContext(c.Request.Context()), "user_id").First(&existing, id).Error; errFind != nil {
 		if errors.Is(errFind, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			return
@@ -248,6 +276,9 @@ func (h *BillHandler) Update(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "user_id cannot be 0"})
 			return
 		}
+		if !checkOrganizationUser(c, h.db, *body.UserID) {
+			return
+		}
 		updates["user_id"] = *body.UserID
 	}
 	if body.PeriodType != nil {
@@ -261,6 +292,14 @@ func (h *BillHandler) Update(c *gin.Context) {
 	if body.Amount != nil {
 		updates["amount"] = *body.Amount
 	}
+	if body.Currency != nil {
+		billCurrency, errCurrency := currency.Normalize(*body.Currency)
+		if errCurrency != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "currency must be a three-letter ISO code"})
+			return
+		}
+		updates["currency"] = billCurrency
+	}
 	if body.PeriodStart != nil {
 		t, errParseTime := time.Parse(time.RFC3339, *body.PeriodStart)
 		if errParseTime != nil {
@@ -307,6 +346,18 @@ func (h *BillHandler) Update(c *gin.Context) {
 		updates["status"] = s
 	}
 
+	if body.AutoRenew != nil {
+		updates["auto_renew"] = *body.AutoRenew
+	}
+	if body.RenewalMode != nil {
+		mode := models.BillRenewalMode(*body.RenewalMode)
+		if !validBillRenewalMode(mode) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "renewal_mode must be 1 (manual) or 2 (prepaid)"})
+			return
+		}
+		updates["renewal_mode"] = mode
+	}
+
 	res := h.db.WithContext(c.Request.Context()).Model(&models.Bill{}).Where("id = ?", id).Updates(updates)
 	if res.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
@@ -322,7 +373,7 @@ func (h *BillHandler) Delete(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	res := h.db.WithContext(c.Request.Context()).Delete(&models.Bill{}, id)
+	res := scopeToOrganizationUsers(c, h.db, h.db.WithContext(c.Request.Context()), "user_id").Delete(&models.Bill{}, id)
 	if res.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
 		return
@@ -353,7 +404,7 @@ func (h *BillHandler) setEnabled(c *gin.Context, enabled bool) {
 	}
 
 	now := time.Now().UTC()
-	res := h.db.WithContext(c.Request.Context()).Model(&models.Bill{}).Where("id = ?", id).
+	res := scopeToOrganizationUsers(c, h.db, h.db.WithContext(c.Request.Context()).Model(&models.Bill{}), "user_id").Where("id = ?", id).
 		Updates(map[string]any{"is_enabled": enabled, "updated_at": now})
 	if res.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
@@ -369,23 +420,32 @@ func (h *BillHandler) setEnabled(c *gin.Context, enabled bool) {
 // formatBill converts a bill model into a response payload.
 func (h *BillHandler) formatBill(bill *models.Bill) gin.H {
 	return gin.H{
-		"id":            bill.ID,
-		"plan_id":       bill.PlanID,
-		"user_id":       bill.UserID,
-		"user_group_id": bill.UserGroupID.Clean(),
-		"period_type":   bill.PeriodType,
-		"amount":        bill.Amount,
-		"period_start":  bill.PeriodStart,
-		"period_end":    bill.PeriodEnd,
-		"total_quota":   bill.TotalQuota,
-		"daily_quota":   bill.DailyQuota,
-		"used_quota":    bill.UsedQuota,
-		"left_quota":    bill.LeftQuota,
-		"used_count":    bill.UsedCount,
-		"rate_limit":    bill.RateLimit,
-		"is_enabled":    bill.IsEnabled,
-		"status":        bill.Status,
-		"created_at":    bill.CreatedAt,
-		"updated_at":    bill.UpdatedAt,
+		"id":              bill.ID,
+		"plan_id":         bill.PlanID,
+		"user_id":         bill.UserID,
+		"user_group_id":   bill.UserGroupID.Clean(),
+		"period_type":     bill.PeriodType,
+		"amount":          bill.Amount,
+		"currency":        bill.Currency,
+		"period_start":    bill.PeriodStart,
+		"period_end":      bill.PeriodEnd,
+		"total_quota":     bill.TotalQuota,
+		"daily_quota":     bill.DailyQuota,
+		"used_quota":      bill.UsedQuota,
+		"left_quota":      bill.LeftQuota,
+		"used_count":      bill.UsedCount,
+		"rate_limit":      bill.RateLimit,
+		"is_enabled":      bill.IsEnabled,
+		"status":          bill.Status,
+		"auto_renew":      bill.AutoRenew,
+		"renewal_mode":    bill.RenewalMode,
+		"renewed_bill_id": bill.RenewedBillID,
+		"created_at":      bill.CreatedAt,
+		"updated_at":      bill.UpdatedAt,
 	}
 }
+
+// validBillRenewalMode reports whether mode is a known renewal payment mode.
+func validBillRenewalMode(mode models.BillRenewalMode) bool {
+	return mode == models.BillRenewalModeManual || mode == models.BillRenewalModePrepaid
+}
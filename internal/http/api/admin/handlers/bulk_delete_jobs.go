@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/bulkdelete"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/http/api/admin/permissions"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// bulkDeleteJobListLimit caps how many jobs the list endpoint returns.
+const bulkDeleteJobListLimit = 100
+
+// bulkDeleteEntityCategories maps each bulk delete entity to the category permission needed to
+// create, see or cancel its jobs, on top of the bulk delete route keys.
+var bulkDeleteEntityCategories = map[string]string{
+	bulkdelete.EntityAuths:        permissions.PermissionInfrastructure,
+	bulkdelete.EntityPrepaidCards: permissions.PermissionBilling,
+	bulkdelete.EntityUsages:       permissions.PermissionAnalytics,
+}
+
+// BulkDeleteJobHandler queues and tracks asynchronous bulk deletes.
+type BulkDeleteJobHandler struct {
+	db *gorm.DB // Database handle for job records.
+}
+
+// NewBulkDeleteJobHandler constructs a bulk delete job handler.
+func NewBulkDeleteJobHandler(db *gorm.DB) *BulkDeleteJobHandler {
+	return &BulkDeleteJobHandler{db: db}
+}
+
+// createBulkDeleteJobRequest captures the payload for a bulk delete job.
+type createBulkDeleteJobRequest struct {
+	Entity    string              `json:"entity"`     // usages, prepaid_cards or auths.
+	Criteria  bulkdelete.Criteria `json:"criteria"`   // Filter selecting the rows to delete.
+	BatchSize int                 `json:"batch_size"` // Rows per batch; defaults to 500.
+	DryRun    bool                `json:"dry_run"`    // Count matching rows without queueing a job.
+}
+
+// Create counts matching rows for a dry run, or queues a bulk delete job.
+func (h *BulkDeleteJobHandler) Create(c *gin.Context) {
+	var body createBulkDeleteJobRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	entity, errEntity := bulkdelete.NormalizeEntity(body.Entity)
+	if errEntity != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entity"})
+		return
+	}
+	if !canBulkDelete(c, entity) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		return
+	}
+	if errValidate := body.Criteria.Validate(entity); errValidate != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errValidate.Error()})
+		return
+	}
+	batchSize := body.BatchSize
+	if batchSize == 0 {
+		batchSize = bulkdelete.DefaultBatchSize
+	}
+	if batchSize < 0 || batchSize > bulkdelete.MaxBatchSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid batch_size"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	count, errCount := bulkdelete.Count(ctx, h.db, entity, body.Criteria)
+	if errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "count failed"})
+		return
+	}
+	if body.DryRun {
+		c.JSON(http.StatusOK, gin.H{"dry_run": true, "entity": entity, "count": count})
+		return
+	}
+
+	criteria, errMarshal := json.Marshal(body.Criteria)
+	if errMarshal != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid criteria"})
+		return
+	}
+	job := models.BulkDeleteJob{
+		Entity:    entity,
+		Criteria:  criteria,
+		BatchSize: batchSize,
+		Status:    models.BulkDeleteStatusPending,
+		Total:     count,
+	}
+	if adminID, okAdmin := readAdminIDFromContext(c); okAdmin {
+		job.CreatedBy = &adminID
+	}
+	if errCreate := h.db.WithContext(ctx).Create(&job).Error; errCreate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create job failed"})
+		return
+	}
+	c.JSON(http.StatusAccepted, formatBulkDeleteJob(&job))
+}
+
+// List returns recent bulk delete jobs, optionally filtered by status.
+func (h *BulkDeleteJobHandler) List(c *gin.Context) {
+	q := h.db.WithContext(c.Request.Context()).Model(&models.BulkDeleteJob{}).
+		Where("entity IN ?", allowedBulkDeleteEntities(c))
+	if status := strings.TrimSpace(c.Query("status")); status != "" {
+		q = q.Where("status = ?", status)
+	}
+	var rows []models.BulkDeleteJob
+	if errFind := q.Order("id DESC").Limit(bulkDeleteJobListLimit).Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list jobs failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatBulkDeleteJob(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": out})
+}
+
+// Get returns one bulk delete job with its progress.
+func (h *BulkDeleteJobHandler) Get(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var job models.BulkDeleteJob
+	if errFind := h.db.WithContext(c.Request.Context()).First(&job, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query job failed"})
+		return
+	}
+	if !canBulkDelete(c, job.Entity) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, formatBulkDeleteJob(&job))
+}
+
+// Cancel stops a pending or running bulk delete job.
+func (h *BulkDeleteJobHandler) Cancel(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var job models.BulkDeleteJob
+	if errFind := h.db.WithContext(c.Request.Context()).Select("id", "entity").First(&job, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query job failed"})
+		return
+	}
+	if !canBulkDelete(c, job.Entity) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if errCancel := bulkdelete.Cancel(c.Request.Context(), h.db, id); errCancel != nil {
+		switch {
+		case errors.Is(errCancel, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		case errors.Is(errCancel, bulkdelete.ErrNotCancellable):
+			c.JSON(http.StatusConflict, gin.H{"error": "job already finished"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cancel failed"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// canBulkDelete reports whether the current admin holds the category permission of entity.
+func canBulkDelete(c *gin.Context, entity string) bool {
+	if c.GetBool("adminIsSuperAdmin") {
+		return true
+	}
+	category, ok := bulkDeleteEntityCategories[entity]
+	if !ok {
+		return false
+	}
+	value, _ := c.Get("adminPermissions")
+	adminPermissions, _ := value.([]string)
+	return permissions.HasPermission(adminPermissions, category)
+}
+
+// allowedBulkDeleteEntities lists the entities whose jobs the current admin may see.
+func allowedBulkDeleteEntities(c *gin.Context) []string {
+	out := make([]string, 0, len(bulkDeleteEntityCategories))
+	for entity := range bulkDeleteEntityCategories {
+		if canBulkDelete(c, entity) {
+			out = append(out, entity)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// formatBulkDeleteJob converts a job into a response payload.
+func formatBulkDeleteJob(job *models.BulkDeleteJob) gin.H {
+	progress := 0.0
+	switch {
+	case job.Status == models.BulkDeleteStatusCompleted:
+		progress = 1
+	case job.Total > 0:
+		progress = float64(job.Deleted) / float64(job.Total)
+		if progress > 1 {
+			progress = 1
+		}
+	}
+	return gin.H{
+		"id":          job.ID,
+		"entity":      job.Entity,
+		"criteria":    json.RawMessage(job.Criteria),
+		"batch_size":  job.BatchSize,
+		"status":      job.Status,
+		"total":       job.Total,
+		"deleted":     job.Deleted,
+		"progress":    progress,
+		"last_error":  job.LastError,
+		"created_by":  job.CreatedBy,
+		"started_at":  job.StartedAt,
+		"finished_at": job.FinishedAt,
+		"created_at":  job.CreatedAt,
+		"updated_at":  job.UpdatedAt,
+	}
+}
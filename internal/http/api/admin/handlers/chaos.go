@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/chaos"
+	log "github.com/sirupsen/logrus"
+)
+
+// ChaosHandler lets admins inject and clear faults for resilience testing.
+type ChaosHandler struct{}
+
+// NewChaosHandler constructs a chaos handler.
+func NewChaosHandler() *ChaosHandler {
+	return &ChaosHandler{}
+}
+
+// injectFaultRequest defines the request body for arming a fault.
+type injectFaultRequest struct {
+	Kind            string  `json:"kind"`             // provider_error, db_latency or config_sync_failure.
+	Provider        string  `json:"provider"`         // Provider filter for provider_error.
+	Model           string  `json:"model"`            // Model filter for provider_error.
+	StatusCode      int     `json:"status_code"`      // 429 or 5xx; defaults to 500.
+	Rate            float64 `json:"rate"`             // Failure probability; defaults to 1.
+	LatencyMs       int64   `json:"latency_ms"`       // Delay for db_latency.
+	Count           int     `json:"count"`            // Failures before disarming; 0 means until expiry.
+	DurationSeconds int64   `json:"duration_seconds"` // How long the fault stays armed.
+}
+
+// List returns whether chaos testing is enabled and the faults armed on this node.
+func (h *ChaosHandler) List(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"enabled": chaos.Enabled(),
+		"faults":  chaos.List(time.Now().UTC()),
+	})
+}
+
+// Inject arms a fault on this node.
+func (h *ChaosHandler) Inject(c *gin.Context) {
+	var body injectFaultRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	actor := "admin"
+	if username := strings.TrimSpace(c.GetString("adminUsername")); username != "" {
+		actor = "admin:" + username
+	}
+	fault, errInject := chaos.Inject(chaos.Spec{
+		Kind:       chaos.Kind(strings.TrimSpace(body.Kind)),
+		Provider:   body.Provider,
+		Model:      body.Model,
+		StatusCode: body.StatusCode,
+		Rate:       body.Rate,
+		Latency:    time.Duration(body.LatencyMs) * time.Millisecond,
+		Count:      body.Count,
+		Duration:   time.Duration(body.DurationSeconds) * time.Second,
+		CreatedBy:  actor,
+	}, time.Now().UTC())
+	switch {
+	case errors.Is(errInject, chaos.ErrDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": "chaos testing is disabled"})
+		return
+	case errors.Is(errInject, chaos.ErrInvalidFault):
+		c.JSON(http.StatusBadRequest, gin.H{"error": errInject.Error()})
+		return
+	case errInject != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "inject fault failed"})
+		return
+	}
+	log.WithFields(log.Fields{
+		"fault_id":   fault.ID,
+		"kind":       fault.Kind,
+		"created_by": fault.CreatedBy,
+		"expires_at": fault.ExpiresAt,
+	}).Warn("chaos fault injected")
+	c.JSON(http.StatusCreated, fault)
+}
+
+// Clear disarms one fault.
+func (h *ChaosHandler) Clear(c *gin.Context) {
+	id := strings.TrimSpace(c.Param("id"))
+	if errClear := chaos.Clear(id); errClear != nil {
+		if errors.Is(errClear, chaos.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "clear fault failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// ClearAll disarms every fault on this node.
+func (h *ChaosHandler) ClearAll(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"cleared": chaos.ClearAll()})
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/cluster"
+	"gorm.io/gorm"
+)
+
+// ClusterHandler reports the instances that share this database.
+type ClusterHandler struct {
+	db *gorm.DB // Database handle for the instance registry.
+}
+
+// NewClusterHandler constructs a cluster handler with a database dependency.
+func NewClusterHandler(db *gorm.DB) *ClusterHandler {
+	return &ClusterHandler{db: db}
+}
+
+// ListInstances returns registered instances with their liveness and the bus state of this instance.
+func (h *ClusterHandler) ListInstances(c *gin.Context) {
+	cfg := cluster.LoadConfig()
+	instances, errList := cluster.ListInstances(c.Request.Context(), h.db, cfg, time.Now().UTC())
+	if errList != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list instances failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"instance_id":       cluster.InstanceID(),
+		"bus_enabled":       cfg.Enabled,
+		"bus_connected":     cluster.Connected(),
+		"channel":           cfg.Channel,
+		"heartbeat_seconds": cfg.HeartbeatSeconds,
+		"instances":         instances,
+	})
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/consistency"
+	"gorm.io/gorm"
+)
+
+// ConsistencyHandler serves the database and config file consistency report.
+type ConsistencyHandler struct {
+	db         *gorm.DB // Database handle.
+	configPath string   // Config file checked against the database.
+}
+
+// NewConsistencyHandler constructs a consistency handler.
+func NewConsistencyHandler(db *gorm.DB, configPath string) *ConsistencyHandler {
+	return &ConsistencyHandler{db: db, configPath: configPath}
+}
+
+// Report returns the last consistency report; refresh=true runs the check again first.
+func (h *ConsistencyHandler) Report(c *gin.Context) {
+	report := consistency.Current()
+	if refresh, _ := strconv.ParseBool(c.Query("refresh")); refresh {
+		fresh, errCheck := consistency.Check(c.Request.Context(), h.db, h.configPath, time.Now())
+		if errCheck != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "consistency check failed"})
+			return
+		}
+		consistency.SetCurrent(fresh)
+		report = fresh
+	}
+	c.JSON(http.StatusOK, consistencyResponse(report))
+}
+
+// Repair runs the check again and repairs every fixable problem. Config file problems are
+// left for the operator.
+func (h *ConsistencyHandler) Repair(c *gin.Context) {
+	ctx := c.Request.Context()
+	report, errCheck := consistency.Check(ctx, h.db, h.configPath, time.Now())
+	if errCheck != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "consistency check failed"})
+		return
+	}
+	if errRepair := consistency.Repair(ctx, h.db, &report); errRepair != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "consistency repair failed"})
+		return
+	}
+	consistency.SetCurrent(report)
+	c.JSON(http.StatusOK, consistencyResponse(report))
+}
+
+// consistencyResponse renders a report with its repaired count.
+func consistencyResponse(report consistency.Report) gin.H {
+	return gin.H{
+		"checked_at": report.CheckedAt,
+		"issues":     report.Issues,
+		"repaired":   report.Repaired(),
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/coop"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// CoopHandler gives admins oversight of user-contributed co-op credentials.
+type CoopHandler struct {
+	db *gorm.DB // Database handle for contribution records.
+}
+
+// NewCoopHandler constructs a co-op handler.
+func NewCoopHandler(db *gorm.DB) *CoopHandler {
+	return &CoopHandler{db: db}
+}
+
+// List returns contributions filtered by user_id and status.
+func (h *CoopHandler) List(c *gin.Context) {
+	q := h.db.WithContext(c.Request.Context()).Model(&models.CoopContribution{})
+	if userIDQ := strings.TrimSpace(c.Query("user_id")); userIDQ != "" {
+		if id, errParse := strconv.ParseUint(userIDQ, 10, 64); errParse == nil {
+			q = q.Where("user_id = ?", id)
+		}
+	}
+	if status := strings.TrimSpace(c.Query("status")); status != "" {
+		q = q.Where("status = ?", status)
+	}
+	var rows []models.CoopContribution
+	if errFind := q.Order("created_at DESC").Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list contributions failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatCoopContribution(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"contributions": out})
+}
+
+// suspendCoopRequest captures an optional suspension note.
+type suspendCoopRequest struct {
+	Reason string `json:"reason"` // Optional reason shown to admins.
+}
+
+// Suspend takes a contribution out of routing without deleting the credential.
+func (h *CoopHandler) Suspend(c *gin.Context) {
+	var body suspendCoopRequest
+	if c.Request.ContentLength > 0 {
+		if errBind := c.ShouldBindJSON(&body); errBind != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+			return
+		}
+	}
+	h.setSuspended(c, true, body.Reason)
+}
+
+// Resume returns a suspended contribution to routing.
+func (h *CoopHandler) Resume(c *gin.Context) {
+	h.setSuspended(c, false, "")
+}
+
+func (h *CoopHandler) setSuspended(c *gin.Context, suspended bool, reason string) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	contribution, errSet := coop.SetSuspended(c.Request.Context(), h.db, id, suspended, reason, time.Now().UTC())
+	if !writeCoopError(c, errSet) {
+		return
+	}
+	c.JSON(http.StatusOK, formatCoopContribution(contribution))
+}
+
+// Revoke force-withdraws a contribution, settling earned credit and deleting the credential.
+func (h *CoopHandler) Revoke(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	contribution, errRevoke := coop.Revoke(c.Request.Context(), h.db, id, 0, time.Now().UTC())
+	if !writeCoopError(c, errRevoke) {
+		return
+	}
+	c.JSON(http.StatusOK, formatCoopContribution(contribution))
+}
+
+// Settle credits contributors for usage served so far instead of waiting for the settler.
+func (h *CoopHandler) Settle(c *gin.Context) {
+	settlements, errSettle := coop.Settle(c.Request.Context(), h.db, time.Now().UTC())
+	if errSettle != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "settle failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"settlements": settlements})
+}
+
+// writeCoopError maps coop errors to responses; it returns true when err is nil.
+func writeCoopError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, coop.ErrNotActive):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update contribution failed"})
+	}
+	return false
+}
+
+// formatCoopContribution converts a contribution into a response payload.
+func formatCoopContribution(row *models.CoopContribution) gin.H {
+	return gin.H{
+		"id":              row.ID,
+		"user_id":         row.UserID,
+		"auth_id":         row.AuthID,
+		"auth_key":        row.AuthKey,
+		"provider":        row.Provider,
+		"status":          row.Status,
+		"credit_rate":     row.CreditRate,
+		"settled_until":   row.SettledUntil,
+		"credited_amount": row.CreditedAmount,
+		"served_requests": row.ServedRequests,
+		"suspend_reason":  row.SuspendReason,
+		"revoked_at":      row.RevokedAt,
+		"created_at":      row.CreatedAt,
+		"updated_at":      row.UpdatedAt,
+	}
+}
@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/costreplay"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/invoice"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// costReplayListLimit caps how many snapshots or jobs the list endpoints return.
+const costReplayListLimit = 100
+
+// CostReplayHandler manages billing rule snapshots and the jobs that reprice usage against them.
+type CostReplayHandler struct {
+	db *gorm.DB // Database handle for snapshot and job records.
+}
+
+// NewCostReplayHandler constructs a cost replay handler.
+func NewCostReplayHandler(db *gorm.DB) *CostReplayHandler {
+	return &CostReplayHandler{db: db}
+}
+
+// createBillingRuleSnapshotRequest captures the payload for a billing rule snapshot.
+type createBillingRuleSnapshotRequest struct {
+	Name  string            `json:"name"`  // Display name.
+	Rules []costreplay.Rule `json:"rules"` // Proposed rules; omit to capture the live rules.
+}
+
+// CreateSnapshot captures the live billing rules, or stores proposed rules to evaluate.
+func (h *CostReplayHandler) CreateSnapshot(c *gin.Context) {
+	var body createBillingRuleSnapshotRequest
+	if c.Request.ContentLength > 0 {
+		if errBind := c.ShouldBindJSON(&body); errBind != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+			return
+		}
+	}
+	var createdBy *uint64
+	if adminID, okAdmin := readAdminIDFromContext(c); okAdmin {
+		createdBy = &adminID
+	}
+	ctx := c.Request.Context()
+	now := time.Now().UTC()
+	var (
+		snapshot  *models.BillingRuleSnapshot
+		errCreate error
+	)
+	if body.Rules != nil {
+		snapshot, errCreate = costreplay.CreateProposed(ctx, h.db, body.Name, body.Rules, createdBy, now)
+	} else {
+		snapshot, errCreate = costreplay.Capture(ctx, h.db, body.Name, createdBy, now)
+	}
+	if errCreate != nil {
+		if errors.Is(errCreate, costreplay.ErrInvalidRules) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errCreate.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create snapshot failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, formatBillingRuleSnapshot(snapshot, true))
+}
+
+// ListSnapshots returns recent snapshots without their rules.
+func (h *CostReplayHandler) ListSnapshots(c *gin.Context) {
+	var rows []models.BillingRuleSnapshot
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Omit("rules").
+		Order("created_at DESC, id DESC").
+		Limit(costReplayListLimit).
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list snapshots failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatBillingRuleSnapshot(&rows[i], false))
+	}
+	c.JSON(http.StatusOK, gin.H{"snapshots": out})
+}
+
+// GetSnapshot returns one snapshot with its rules.
+func (h *CostReplayHandler) GetSnapshot(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var snapshot models.BillingRuleSnapshot
+	if errFind := h.db.WithContext(c.Request.Context()).First(&snapshot, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query snapshot failed"})
+		return
+	}
+	c.JSON(http.StatusOK, formatBillingRuleSnapshot(&snapshot, true))
+}
+
+// createCostReplayJobRequest captures the payload for a cost replay job.
+type createCostReplayJobRequest struct {
+	SnapshotID  uint64     `json:"snapshot_id"`  // Snapshot to price against.
+	AsOf        *time.Time `json:"as_of"`        // Use the latest captured snapshot at this time instead.
+	Month       string     `json:"month"`        // YYYY-MM month to reprice.
+	PeriodStart *time.Time `json:"period_start"` // Explicit period start, when month is omitted.
+	PeriodEnd   *time.Time `json:"period_end"`   // Explicit period end, when month is omitted.
+	UserID      *uint64    `json:"user_id"`      // Restrict to one user.
+}
+
+// CreateJob queues a replay of a period's usage against a snapshot.
+func (h *CostReplayHandler) CreateJob(c *gin.Context) {
+	var body createCostReplayJobRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	ctx := c.Request.Context()
+
+	snapshotID := body.SnapshotID
+	if snapshotID == 0 {
+		if body.AsOf == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "snapshot_id or as_of is required"})
+			return
+		}
+		snapshot, errAsOf := costreplay.SnapshotAsOf(ctx, h.db, *body.AsOf)
+		if errAsOf != nil {
+			if errors.Is(errAsOf, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "no snapshot as of that time"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "query snapshot failed"})
+			return
+		}
+		snapshotID = snapshot.ID
+	}
+
+	var start, end time.Time
+	switch {
+	case strings.TrimSpace(body.Month) != "":
+		month, errMonth := invoice.ParseMonth(body.Month)
+		if errMonth != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid month"})
+			return
+		}
+		start, end = invoice.Period(month)
+	case body.PeriodStart != nil && body.PeriodEnd != nil:
+		start, end = *body.PeriodStart, *body.PeriodEnd
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "month or period_start and period_end are required"})
+		return
+	}
+
+	var createdBy *uint64
+	if adminID, okAdmin := readAdminIDFromContext(c); okAdmin {
+		createdBy = &adminID
+	}
+	job, errCreate := costreplay.CreateJob(ctx, h.db, snapshotID, start, end, body.UserID, createdBy)
+	if errCreate != nil {
+		switch {
+		case errors.Is(errCreate, costreplay.ErrInvalidPeriod):
+			c.JSON(http.StatusBadRequest, gin.H{"error": errCreate.Error()})
+		case errors.Is(errCreate, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "snapshot not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "create job failed"})
+		}
+		return
+	}
+	c.JSON(http.StatusAccepted, formatCostReplayJob(job))
+}
+
+// ListJobs returns recent cost replay jobs, optionally filtered by status.
+func (h *CostReplayHandler) ListJobs(c *gin.Context) {
+	q := h.db.WithContext(c.Request.Context()).Model(&models.CostReplayJob{}).Omit("report")
+	if status := strings.TrimSpace(c.Query("status")); status != "" {
+		q = q.Where("status = ?", status)
+	}
+	var rows []models.CostReplayJob
+	if errFind := q.Order("id DESC").Limit(costReplayListLimit).Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list jobs failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatCostReplayJob(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": out})
+}
+
+// GetJob returns one cost replay job with its progress and report.
+func (h *CostReplayHandler) GetJob(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var job models.CostReplayJob
+	if errFind := h.db.WithContext(c.Request.Context()).First(&job, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query job failed"})
+		return
+	}
+	c.JSON(http.StatusOK, formatCostReplayJob(&job))
+}
+
+// CancelJob stops a pending or running cost replay job.
+func (h *CostReplayHandler) CancelJob(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if errCancel := costreplay.Cancel(c.Request.Context(), h.db, id); errCancel != nil {
+		switch {
+		case errors.Is(errCancel, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		case errors.Is(errCancel, costreplay.ErrNotCancellable):
+			c.JSON(http.StatusConflict, gin.H{"error": "job already finished"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cancel failed"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// formatBillingRuleSnapshot converts a snapshot into a response payload.
+func formatBillingRuleSnapshot(snapshot *models.BillingRuleSnapshot, withRules bool) gin.H {
+	out := gin.H{
+		"id":          snapshot.ID,
+		"name":        snapshot.Name,
+		"rule_count":  snapshot.RuleCount,
+		"fingerprint": snapshot.Fingerprint,
+		"proposed":    snapshot.Proposed,
+		"created_by":  snapshot.CreatedBy,
+		"created_at":  snapshot.CreatedAt,
+	}
+	if withRules {
+		out["rules"] = json.RawMessage(snapshot.Rules)
+	}
+	return out
+}
+
+// formatCostReplayJob converts a job into a response payload.
+func formatCostReplayJob(job *models.CostReplayJob) gin.H {
+	progress := 0.0
+	switch {
+	case job.Status == models.CostReplayStatusCompleted:
+		progress = 1
+	case job.Total > 0:
+		progress = float64(job.Processed) / float64(job.Total)
+		if progress > 1 {
+			progress = 1
+		}
+	}
+	var report any
+	if len(job.Report) > 0 {
+		report = json.RawMessage(job.Report)
+	}
+	return gin.H{
+		"id":           job.ID,
+		"snapshot_id":  job.SnapshotID,
+		"period_start": job.PeriodStart,
+		"period_end":   job.PeriodEnd,
+		"user_id":      job.UserID,
+		"status":       job.Status,
+		"total":        job.Total,
+		"processed":    job.Processed,
+		"progress":     progress,
+		"report":       report,
+		"last_error":   job.LastError,
+		"created_by":   job.CreatedBy,
+		"started_at":   job.StartedAt,
+		"finished_at":  job.FinishedAt,
+		"created_at":   job.CreatedAt,
+		"updated_at":   job.UpdatedAt,
+	}
+}
@@ -11,7 +11,11 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/currency"
+	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/healthprobe"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/stats"
 	"gorm.io/gorm"
 )
 
@@ -57,6 +61,7 @@ type kpiResponse struct {
 	TodayTokensTrend  float64 `json:"today_tokens_trend"`  // Trend vs yesterday.
 	TodayCachedTokens int64   `json:"today_cached_tokens"` // Cached tokens today.
 	CachedTokensTrend float64 `json:"cached_tokens_trend"` // Trend vs yesterday.
+	Currency          string  `json:"currency"`            // Display currency of the cost fields.
 	TodayCostMicros   int64   `json:"today_cost_micros"`   // Total cost today in micros.
 	TodayCostTrend    float64 `json:"today_cost_trend"`    // Trend vs yesterday.
 	AvgRequestTimeMs  int64   `json:"avg_request_time_ms"` // Average request time in ms.
@@ -65,95 +70,70 @@ type kpiResponse struct {
 	SuccessRateTrend  float64 `json:"success_rate_trend"`  // Trend vs yesterday.
 	MtdCostMicros     int64   `json:"mtd_cost_micros"`     // Month-to-date cost in micros.
 	CostTrend         float64 `json:"cost_trend"`          // Trend vs last month.
+
+	MtdEnergyMilliWh    int64 `json:"mtd_energy_milli_wh"`   // Month-to-date estimated energy in mWh.
+	MtdCarbonMilligrams int64 `json:"mtd_carbon_milligrams"` // Month-to-date estimated emissions in mg CO2e.
 }
 
-// KPI returns global KPI data for all users
+// KPI returns global KPI data for all users. Finished hours come from the usage_hourly
+// rollups; only the hours the stats aggregator has not finalized are read from usages.
 func (h *DashboardHandler) KPI(c *gin.Context) {
+	ctx := c.Request.Context()
 	loc := time.Local
 	now := time.Now().In(loc)
 	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
 	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
 	yesterday := today.AddDate(0, 0, -1)
+	tomorrow := today.AddDate(0, 0, 1)
+	lastMonthStart := monthStart.AddDate(0, -1, 0)
+	lastMonthSameDay := lastMonthStart.AddDate(0, 0, now.Day()-1)
+	scope := organizationStatsScope(c)
 
-	var todayStats struct {
-		Total            int64
-		Failed           int64
-		AvgRequestTimeMs float64
-		ActiveUsers      int64
-		TotalTokens      int64
-		CachedTokens     int64
-		CostMicros       int64
+	todayStats, errToday := stats.QueryTotals(ctx, h.db, today, tomorrow, scope)
+	if errToday != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
 	}
-	h.db.WithContext(c.Request.Context()).Model(&models.Usage{}).
-		Where("requested_at >= ?", today).
-		Select(`
-			COUNT(*) AS total,
-			SUM(CASE WHEN failed THEN 1 ELSE 0 END) AS failed,
-			COUNT(DISTINCT CASE WHEN user_id IS NULL THEN NULL ELSE user_id END) AS active_users,
-			COALESCE(SUM(total_tokens), 0) AS total_tokens,
-			COALESCE(SUM(cached_tokens), 0) AS cached_tokens,
-			COALESCE(SUM(cost_micros), 0) AS cost_micros,
-			COALESCE(AVG(GREATEST(EXTRACT(EPOCH FROM (created_at - requested_at)) * 1000, 0)), 0) AS avg_request_time_ms
-		`).
-		Scan(&todayStats)
-
-	var yesterdayStats struct {
-		Total            int64
-		Failed           int64
-		AvgRequestTimeMs float64
-		ActiveUsers      int64
-		TotalTokens      int64
-		CachedTokens     int64
-		CostMicros       int64
+	yesterdayStats, errYesterday := stats.QueryTotals(ctx, h.db, yesterday, today, scope)
+	if errYesterday != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
 	}
-	h.db.WithContext(c.Request.Context()).Model(&models.Usage{}).
-		Where("requested_at >= ? AND requested_at < ?", yesterday, today).
-		Select(`
-			COUNT(*) AS total,
-			SUM(CASE WHEN failed THEN 1 ELSE 0 END) AS failed,
-			COUNT(DISTINCT CASE WHEN user_id IS NULL THEN NULL ELSE user_id END) AS active_users,
-			COALESCE(SUM(total_tokens), 0) AS total_tokens,
-			COALESCE(SUM(cached_tokens), 0) AS cached_tokens,
-			COALESCE(SUM(cost_micros), 0) AS cost_micros,
-			COALESCE(AVG(GREATEST(EXTRACT(EPOCH FROM (created_at - requested_at)) * 1000, 0)), 0) AS avg_request_time_ms
-		`).
-		Scan(&yesterdayStats)
-
-	var mtdCost int64
-	h.db.WithContext(c.Request.Context()).Model(&models.Usage{}).
-		Where("requested_at >= ?", monthStart).
-		Select("COALESCE(SUM(cost_micros), 0)").
-		Scan(&mtdCost)
-
-	lastMonthStart := monthStart.AddDate(0, -1, 0)
-	lastMonthSameDay := lastMonthStart.AddDate(0, 0, now.Day()-1)
-	var lastMtdCost int64
-	h.db.WithContext(c.Request.Context()).Model(&models.Usage{}).
-		Where("requested_at >= ? AND requested_at < ?", lastMonthStart, lastMonthSameDay).
-		Select("COALESCE(SUM(cost_micros), 0)").
-		Scan(&lastMtdCost)
+	mtdStats, errMtd := stats.QueryTotals(ctx, h.db, monthStart, tomorrow, scope)
+	if errMtd != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	lastMtdStats, errLastMtd := stats.QueryTotals(ctx, h.db, lastMonthStart, lastMonthSameDay, scope)
+	if errLastMtd != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	mtdCost := mtdStats.CostMicros
+	lastMtdCost := lastMtdStats.CostMicros
 
-	requestsTrend := calcTrend(float64(yesterdayStats.Total), float64(todayStats.Total))
+	requestsTrend := calcTrend(float64(yesterdayStats.Requests), float64(todayStats.Requests))
 	activeUsersTrend := calcTrend(float64(yesterdayStats.ActiveUsers), float64(todayStats.ActiveUsers))
 	todayTokensTrend := calcTrend(float64(yesterdayStats.TotalTokens), float64(todayStats.TotalTokens))
 	cachedTokensTrend := calcTrend(float64(yesterdayStats.CachedTokens), float64(todayStats.CachedTokens))
 	todayCostTrend := calcTrend(float64(yesterdayStats.CostMicros), float64(todayStats.CostMicros))
 	successRate := 100.0
-	if todayStats.Total > 0 {
-		successRate = float64(todayStats.Total-todayStats.Failed) / float64(todayStats.Total) * 100
+	if todayStats.Requests > 0 {
+		successRate = float64(todayStats.Requests-todayStats.FailedRequests) / float64(todayStats.Requests) * 100
 	}
 	yesterdaySuccessRate := 100.0
-	if yesterdayStats.Total > 0 {
-		yesterdaySuccessRate = float64(yesterdayStats.Total-yesterdayStats.Failed) / float64(yesterdayStats.Total) * 100
+	if yesterdayStats.Requests > 0 {
+		yesterdaySuccessRate = float64(yesterdayStats.Requests-yesterdayStats.FailedRequests) / float64(yesterdayStats.Requests) * 100
 	}
 	successRateTrend := successRate - yesterdaySuccessRate
-	avgRequestTimeToday := int64(math.Round(todayStats.AvgRequestTimeMs))
-	avgRequestTimeYesterday := int64(math.Round(yesterdayStats.AvgRequestTimeMs))
+	avgRequestTimeToday := int64(math.Round(todayStats.AvgDurationMillis()))
+	avgRequestTimeYesterday := int64(math.Round(yesterdayStats.AvgDurationMillis()))
 	requestTimeTrend := calcTrend(float64(avgRequestTimeYesterday), float64(avgRequestTimeToday))
 	costTrend := calcTrend(float64(lastMtdCost), float64(mtdCost))
+	display := currency.Display(ctx, h.db)
 
 	c.JSON(http.StatusOK, kpiResponse{
-		TotalRequests:     todayStats.Total,
+		TotalRequests:     todayStats.Requests,
 		RequestsTrend:     requestsTrend,
 		TodayActiveUsers:  todayStats.ActiveUsers,
 		ActiveUsersTrend:  activeUsersTrend,
@@ -161,14 +141,18 @@ func (h *DashboardHandler) KPI(c *gin.Context) {
 		TodayTokensTrend:  todayTokensTrend,
 		TodayCachedTokens: todayStats.CachedTokens,
 		CachedTokensTrend: cachedTokensTrend,
-		TodayCostMicros:   todayStats.CostMicros,
+		Currency:          display.Currency,
+		TodayCostMicros:   display.Micros(todayStats.CostMicros),
 		TodayCostTrend:    todayCostTrend,
 		AvgRequestTimeMs:  avgRequestTimeToday,
 		RequestTimeTrend:  requestTimeTrend,
 		SuccessRate:       successRate,
 		SuccessRateTrend:  successRateTrend,
-		MtdCostMicros:     mtdCost,
+		MtdCostMicros:     display.Micros(mtdCost),
 		CostTrend:         costTrend,
+
+		MtdEnergyMilliWh:    mtdStats.EnergyMilliWh,
+		MtdCarbonMilligrams: mtdStats.CarbonMilligrams,
 	})
 }
 
@@ -185,25 +169,18 @@ func (h *DashboardHandler) Traffic(c *gin.Context) {
 	now := time.Now().In(loc)
 	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
 
-	points := make([]trafficPoint, 24)
-	for i := 0; i < 24; i++ {
-		hourStart := today.Add(time.Duration(i) * time.Hour)
-		hourEnd := hourStart.Add(time.Hour)
-
-		var count int64
-		var errCount int64
-		h.db.WithContext(c.Request.Context()).Model(&models.Usage{}).
-			Where("requested_at >= ? AND requested_at < ?", hourStart, hourEnd).
-			Count(&count)
-		h.db.WithContext(c.Request.Context()).Model(&models.Usage{}).
-			Where("requested_at >= ? AND requested_at < ? AND failed = true", hourStart, hourEnd).
-			Count(&errCount)
-
-		points[i] = trafficPoint{
-			Time:     hourStart.Format("15:04"),
-			Requests: count,
-			Errors:   errCount,
-		}
+	hours, errHours := stats.QueryHourly(c.Request.Context(), h.db, today, today.AddDate(0, 0, 1), organizationStatsScope(c))
+	if errHours != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	points := make([]trafficPoint, 0, len(hours))
+	for _, hour := range hours {
+		points = append(points, trafficPoint{
+			Time:     hour.Hour.In(loc).Format("15:04"),
+			Requests: hour.Requests,
+			Errors:   hour.FailedRequests,
+		})
 	}
 
 	c.JSON(http.StatusOK, gin.H{"points": points})
@@ -214,6 +191,9 @@ type costItem struct {
 	Model      string  `json:"model"`       // Model identifier.
 	CostMicros int64   `json:"cost_micros"` // Cost in micros.
 	Percentage float64 `json:"percentage"`  // Share of total cost.
+
+	EnergyMilliWh    int64 `json:"energy_milli_wh"`   // Estimated energy in mWh.
+	CarbonMilligrams int64 `json:"carbon_milligrams"` // Estimated emissions in mg CO2e.
 }
 
 // CostDistribution returns global cost distribution grouped by model
@@ -222,24 +202,18 @@ func (h *DashboardHandler) CostDistribution(c *gin.Context) {
 	now := time.Now().In(loc)
 	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
 
-	// modelCost captures aggregated costs per model.
-	type modelCost struct {
-		Model      string // Model identifier.
-		CostMicros int64  // Aggregated cost in micros.
+	results, errModels := stats.QueryByModel(c.Request.Context(), h.db, monthStart, now.AddDate(0, 0, 1), organizationStatsScope(c))
+	if errModels != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
 	}
-	var results []modelCost
-	h.db.WithContext(c.Request.Context()).Model(&models.Usage{}).
-		Where("requested_at >= ?", monthStart).
-		Select("model, COALESCE(SUM(cost_micros), 0) AS cost_micros").
-		Group("model").
-		Order("cost_micros DESC").
-		Scan(&results)
 
 	var totalCost int64
 	for _, r := range results {
 		totalCost += r.CostMicros
 	}
 
+	display := currency.Display(c.Request.Context(), h.db)
 	items := make([]costItem, 0, len(results))
 	for _, r := range results {
 		pct := 0.0
@@ -248,31 +222,63 @@ func (h *DashboardHandler) CostDistribution(c *gin.Context) {
 		}
 		items = append(items, costItem{
 			Model:      r.Model,
-			CostMicros: r.CostMicros,
+			CostMicros: display.Micros(r.CostMicros),
 			Percentage: pct,
+
+			EnergyMilliWh:    r.EnergyMilliWh,
+			CarbonMilligrams: r.CarbonMilligrams,
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{"items": items})
+	c.JSON(http.StatusOK, gin.H{"items": items, "currency": display.Currency})
 }
 
 // healthItem represents a provider health status entry.
 type healthItem struct {
-	Provider string `json:"provider"` // Provider display name.
-	Status   string `json:"status"`   // Health status label.
-	Latency  string `json:"latency"`  // Observed latency label.
+	Provider         string  `json:"provider"`        // Provider display name.
+	Status           string  `json:"status"`          // Health status label.
+	Latency          string  `json:"latency"`         // Observed p95 latency label.
+	P95LatencyMillis int64   `json:"p95_latency_ms"`  // p95 latency of successful checks.
+	SuccessRate      float64 `json:"success_rate"`    // Share of successful checks.
+	Checks           int64   `json:"checks"`          // Checks in the window.
+	LastCheckedAt    string  `json:"last_checked_at"` // Latest check time (RFC3339).
+	LastError        string  `json:"last_error"`      // Latest failure detail.
 }
 
-// ModelHealth returns model health status
+// modelHealthWindow is how far back provider health checks are summarized.
+const modelHealthWindow = time.Hour
+
+// ModelHealth returns per-provider health from recent probe and quota poll checks.
 func (h *DashboardHandler) ModelHealth(c *gin.Context) {
-	items := []healthItem{
-		{Provider: "OpenAI API", Status: "healthy", Latency: "12ms"},
-		{Provider: "Anthropic API", Status: "healthy", Latency: "45ms"},
-		{Provider: "Local LLM Cluster", Status: "degraded", Latency: "Degraded"},
+	summaries, errSummarize := healthprobe.Summarize(c.Request.Context(), h.db, time.Now().Add(-modelHealthWindow))
+	if errSummarize != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	items := make([]healthItem, 0, len(summaries))
+	for _, summary := range summaries {
+		items = append(items, healthItem{
+			Provider:         summary.Provider,
+			Status:           summary.Status,
+			Latency:          formatHealthLatency(summary),
+			P95LatencyMillis: summary.P95LatencyMillis,
+			SuccessRate:      summary.SuccessRate(),
+			Checks:           summary.Checks,
+			LastCheckedAt:    summary.LastCheckedAt.Format(time.RFC3339),
+			LastError:        summary.LastError,
+		})
 	}
 	c.JSON(http.StatusOK, gin.H{"items": items})
 }
 
+// formatHealthLatency renders the p95 latency label shown on dashboard cards.
+func formatHealthLatency(summary healthprobe.ProviderHealth) string {
+	if summary.Checks == summary.Failures {
+		return "Unavailable"
+	}
+	return strconv.FormatInt(summary.P95LatencyMillis, 10) + "ms"
+}
+
 // transactionItem represents a recent usage record for the dashboard.
 type transactionItem struct {
 	ID            uint64 `json:"id"`              // Usage record ID.
@@ -280,6 +286,7 @@ type transactionItem struct {
 	Username      string `json:"username"`        // Caller username.
 	Status        string `json:"status"`          // HTTP-like status label.
 	StatusType    string `json:"status_type"`     // UI status type.
+	ErrorCode     string `json:"error_code"`      // Canonical failure cause; empty for successes.
 	Timestamp     string `json:"timestamp"`       // Local timestamp string.
 	Provider      string `json:"provider"`        // Provider credential display name.
 	Model         string `json:"model"`           // Model identifier.
@@ -311,22 +318,36 @@ func (h *DashboardHandler) RecentTransactions(c *gin.Context) {
 	}
 	offset := (page - 1) * pageSize
 
+	// A cursor from next_cursor replaces page; deep offsets on usages get slow.
+	keyset := dbutil.RequestedAtKeyset(true)
+	var after *dbutil.Cursor
+	if cursor := strings.TrimSpace(c.Query("cursor")); cursor != "" {
+		pos, errCursor := keyset.Decode(cursor)
+		if errCursor != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		after, offset = &pos, 0
+	}
+
 	var total int64
-	base := h.db.WithContext(c.Request.Context()).Model(&models.Usage{})
+	base := scopeToOrganizationUsers(c, h.db, h.db.WithContext(c.Request.Context()).Model(&models.Usage{}), "user_id")
 	if errCount := base.Session(&gorm.Session{}).Count(&total).Error; errCount != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "query usages failed"})
 		return
 	}
 
 	var usages []models.Usage
-	if errFind := base.Session(&gorm.Session{}).
-		Order("requested_at DESC").
-		Limit(pageSize).
+	if errFind := keyset.Apply(base.Session(&gorm.Session{}), after).
+		Limit(pageSize + 1).
 		Offset(offset).
 		Find(&usages).Error; errFind != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "query usages failed"})
 		return
 	}
+	usages, nextCursor := dbutil.NextPage(keyset, usages, pageSize, func(u models.Usage) dbutil.Cursor {
+		return dbutil.Cursor{Value: dbutil.TimeValue(u.RequestedAt), ID: u.ID}
+	})
 
 	authIDsSet := make(map[uint64]struct{})
 
@@ -495,6 +516,7 @@ func (h *DashboardHandler) RecentTransactions(c *gin.Context) {
 		}
 	}
 
+	display := currency.Display(c.Request.Context(), h.db)
 	transactions := make([]transactionItem, 0, len(usages))
 	for _, u := range usages {
 		username := ""
@@ -534,6 +556,7 @@ func (h *DashboardHandler) RecentTransactions(c *gin.Context) {
 			Username:      username,
 			Status:        status,
 			StatusType:    statusType,
+			ErrorCode:     u.ErrorCode,
 			Timestamp:     u.RequestedAt.In(time.Local).Format("2006-01-02 15:04:05"),
 			Provider:      providerLabel,
 			Model:         u.Model,
@@ -543,15 +566,17 @@ func (h *DashboardHandler) RecentTransactions(c *gin.Context) {
 			InputTokens:   u.InputTokens,
 			CachedTokens:  u.CachedTokens,
 			OutputTokens:  u.OutputTokens,
-			CostMicros:    u.CostMicros,
+			CostMicros:    display.Micros(u.CostMicros),
 		})
 	}
 
 	c.JSON(http.StatusOK, gin.H{
+		"currency":     display.Currency,
 		"transactions": transactions,
 		"total":        total,
 		"page":         page,
 		"page_size":    pageSize,
+		"next_cursor":  nextCursor,
 	})
 }
 
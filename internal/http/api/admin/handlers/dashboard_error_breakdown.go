@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/usageerror"
+)
+
+const (
+	// errorBreakdownDefaultHours is the window used when no hours parameter is given.
+	errorBreakdownDefaultHours = 24
+	// errorBreakdownMaxHours bounds the raw usage scan.
+	errorBreakdownMaxHours = 168
+)
+
+// errorBreakdownProvider lists one provider's failures by error code.
+type errorBreakdownProvider struct {
+	Provider string                 `json:"provider"` // Provider name.
+	Failures int64                  `json:"failures"` // Failed requests in the window.
+	Codes    []usageerror.CodeCount `json:"codes"`    // Failures per error code.
+}
+
+// ErrorBreakdown counts failed requests per canonical error code over the last hours
+// (default 24, at most 168), overall and per provider.
+func (h *DashboardHandler) ErrorBreakdown(c *gin.Context) {
+	hours := errorBreakdownDefaultHours
+	if raw := strings.TrimSpace(c.Query("hours")); raw != "" {
+		parsed, errParse := strconv.Atoi(raw)
+		if errParse != nil || parsed <= 0 || parsed > errorBreakdownMaxHours {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid hours"})
+			return
+		}
+		hours = parsed
+	}
+	now := time.Now()
+	from := now.Add(-time.Duration(hours) * time.Hour).UTC()
+
+	var rows []struct {
+		Provider  string
+		ErrorCode string
+		Failures  int64
+	}
+	if errScan := h.db.WithContext(c.Request.Context()).
+		Model(&models.Usage{}).
+		Where("requested_at >= ? AND requested_at < ? AND failed = ?", from, now.UTC(), true).
+		Select("provider, error_code, COUNT(*) AS failures").
+		Group("provider, error_code").
+		Scan(&rows).Error; errScan != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	totals := make(map[string]int64)
+	byProvider := make(map[string]map[string]int64)
+	var failures int64
+	for _, row := range rows {
+		totals[row.ErrorCode] += row.Failures
+		failures += row.Failures
+		if byProvider[row.Provider] == nil {
+			byProvider[row.Provider] = make(map[string]int64)
+		}
+		byProvider[row.Provider][row.ErrorCode] += row.Failures
+	}
+	providers := make([]errorBreakdownProvider, 0, len(byProvider))
+	for provider, counts := range byProvider {
+		item := errorBreakdownProvider{Provider: provider, Codes: usageerror.Breakdown(counts)}
+		for _, count := range counts {
+			item.Failures += count
+		}
+		providers = append(providers, item)
+	}
+	sort.Slice(providers, func(i, j int) bool {
+		if providers[i].Failures != providers[j].Failures {
+			return providers[i].Failures > providers[j].Failures
+		}
+		return providers[i].Provider < providers[j].Provider
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"hours":     hours,
+		"failures":  failures,
+		"codes":     usageerror.Breakdown(totals),
+		"providers": providers,
+	})
+}
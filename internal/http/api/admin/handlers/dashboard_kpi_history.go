@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/kpisnapshot"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+)
+
+// kpiHistoryDefaultDays is the range returned when no from date is given.
+const kpiHistoryDefaultDays = 365
+
+// KPIHistory returns daily KPI snapshots for long-range trend charts.
+// Optional from/to query parameters accept YYYY-MM-DD local dates (inclusive).
+func (h *DashboardHandler) KPIHistory(c *gin.Context) {
+	today := kpisnapshot.DayStart(time.Now())
+	from := today.AddDate(0, 0, -kpiHistoryDefaultDays+1)
+	to := today
+
+	if raw := strings.TrimSpace(c.Query("from")); raw != "" {
+		parsed, errParse := time.ParseInLocation(time.DateOnly, raw, time.Local)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+			return
+		}
+		from = parsed
+	}
+	if raw := strings.TrimSpace(c.Query("to")); raw != "" {
+		parsed, errParse := time.ParseInLocation(time.DateOnly, raw, time.Local)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+			return
+		}
+		to = parsed
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must not be before from"})
+		return
+	}
+
+	var rows []models.KPISnapshot
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Where("day >= ? AND day < ?", from, to.AddDate(0, 0, 1)).
+		Order("day ASC").
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	out := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, gin.H{
+			"day":             row.Day.In(time.Local).Format(time.DateOnly),
+			"requests":        row.Requests,
+			"failed_requests": row.FailedRequests,
+			"success_rate":    row.SuccessRate,
+			"input_tokens":    row.InputTokens,
+			"output_tokens":   row.OutputTokens,
+			"cached_tokens":   row.CachedTokens,
+			"total_tokens":    row.TotalTokens,
+			"cost_micros":     row.CostMicros,
+			"active_users":    row.ActiveUsers,
+			"active_api_keys": row.ActiveAPIKeys,
+			"updated_at":      row.UpdatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"from":      from.Format(time.DateOnly),
+		"to":        to.Format(time.DateOnly),
+		"snapshots": out,
+	})
+}
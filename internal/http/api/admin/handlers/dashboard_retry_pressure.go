@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+)
+
+const (
+	// retryPressureDefaultHours is the window used when no hours parameter is given.
+	retryPressureDefaultHours = 24
+	// retryPressureMaxHours bounds the raw usage scan.
+	retryPressureMaxHours = 168
+)
+
+// retryPressureItem summarizes rate limiting seen from one provider.
+type retryPressureItem struct {
+	Provider             string  `json:"provider"`                // Provider name.
+	TotalRequests        int64   `json:"total_requests"`          // Requests in the window.
+	ThrottledRequests    int64   `json:"throttled_requests"`      // Requests answered with 429.
+	ThrottledRatio       float64 `json:"throttled_ratio"`         // Throttled share of requests.
+	AvgRetryAfterSeconds float64 `json:"avg_retry_after_seconds"` // Mean Retry-After of throttled requests that carried one.
+	MaxRetryAfterSeconds int64   `json:"max_retry_after_seconds"` // Longest Retry-After in the window.
+}
+
+// RetryPressure aggregates 429 responses and their Retry-After hints per provider over the
+// last hours (default 24, at most 168).
+func (h *DashboardHandler) RetryPressure(c *gin.Context) {
+	hours := retryPressureDefaultHours
+	if raw := strings.TrimSpace(c.Query("hours")); raw != "" {
+		parsed, errParse := strconv.Atoi(raw)
+		if errParse != nil || parsed <= 0 || parsed > retryPressureMaxHours {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid hours"})
+			return
+		}
+		hours = parsed
+	}
+	now := time.Now()
+	from := now.Add(-time.Duration(hours) * time.Hour).UTC()
+
+	var rows []struct {
+		Provider             string
+		TotalRequests        int64
+		ThrottledRequests    int64
+		HintedRequests       int64
+		RetryAfterSeconds    int64
+		MaxRetryAfterSeconds int64
+	}
+	if errScan := h.db.WithContext(c.Request.Context()).
+		Model(&models.Usage{}).
+		Where("requested_at >= ? AND requested_at < ?", from, now.UTC()).
+		Select(`
+			provider,
+			COUNT(*) AS total_requests,
+			COALESCE(SUM(CASE WHEN error_status_code = 429 THEN 1 ELSE 0 END), 0) AS throttled_requests,
+			COALESCE(SUM(CASE WHEN retry_after_seconds > 0 THEN 1 ELSE 0 END), 0) AS hinted_requests,
+			COALESCE(SUM(retry_after_seconds), 0) AS retry_after_seconds,
+			COALESCE(MAX(retry_after_seconds), 0) AS max_retry_after_seconds
+		`).
+		Group("provider").
+		Scan(&rows).Error; errScan != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	items := make([]retryPressureItem, 0, len(rows))
+	for _, row := range rows {
+		if row.ThrottledRequests == 0 {
+			continue
+		}
+		item := retryPressureItem{
+			Provider:             row.Provider,
+			TotalRequests:        row.TotalRequests,
+			ThrottledRequests:    row.ThrottledRequests,
+			ThrottledRatio:       float64(row.ThrottledRequests) / float64(row.TotalRequests),
+			MaxRetryAfterSeconds: row.MaxRetryAfterSeconds,
+		}
+		if row.HintedRequests > 0 {
+			item.AvgRetryAfterSeconds = float64(row.RetryAfterSeconds) / float64(row.HintedRequests)
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].ThrottledRequests != items[j].ThrottledRequests {
+			return items[i].ThrottledRequests > items[j].ThrottledRequests
+		}
+		return items[i].Provider < items[j].Provider
+	})
+	c.JSON(http.StatusOK, gin.H{"hours": hours, "items": items})
+}
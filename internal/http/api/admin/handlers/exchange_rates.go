@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/currency"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// ExchangeRateHandler manages the exchange rates used to price and display non-USD amounts.
+type ExchangeRateHandler struct {
+	db *gorm.DB // Database handle for exchange rate records.
+}
+
+// NewExchangeRateHandler constructs an exchange rate handler.
+func NewExchangeRateHandler(db *gorm.DB) *ExchangeRateHandler {
+	return &ExchangeRateHandler{db: db}
+}
+
+// setExchangeRateRequest defines the request body for setting a rate.
+type setExchangeRateRequest struct {
+	Rate float64 `json:"rate"` // Units of the currency per 1 USD.
+}
+
+// List returns every stored rate along with the display currency.
+func (h *ExchangeRateHandler) List(c *gin.Context) {
+	var rows []models.ExchangeRate
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Order("currency ASC").
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list exchange rates failed"})
+		return
+	}
+	rates := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		rates = append(rates, formatExchangeRate(&row))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"base":             currency.Base,
+		"display_currency": currency.DisplayCurrency(),
+		"rates":            rates,
+	})
+}
+
+// Set creates or replaces the manual rate for a currency. Manual rates are not
+// overwritten by the feed.
+func (h *ExchangeRateHandler) Set(c *gin.Context) {
+	var body setExchangeRateRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	row, errSet := currency.SetRate(c.Request.Context(), h.db, c.Param("currency"), body.Rate, models.ExchangeRateSourceManual)
+	switch {
+	case errors.Is(errSet, currency.ErrInvalidCode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid currency"})
+		return
+	case errors.Is(errSet, currency.ErrInvalidRate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rate must be a positive number and currency must not be " + currency.Base})
+		return
+	case errSet != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "set exchange rate failed"})
+		return
+	}
+	c.JSON(http.StatusOK, formatExchangeRate(row))
+}
+
+// Delete removes a rate that no billing rule is priced in.
+func (h *ExchangeRateHandler) Delete(c *gin.Context) {
+	errDelete := currency.DeleteRate(c.Request.Context(), h.db, c.Param("currency"))
+	switch {
+	case errors.Is(errDelete, currency.ErrInvalidCode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid currency"})
+		return
+	case errors.Is(errDelete, currency.ErrRateInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": "exchange rate is used by billing rules"})
+		return
+	case errors.Is(errDelete, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	case errDelete != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete exchange rate failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// Fetch refreshes rates from the configured feed immediately.
+func (h *ExchangeRateHandler) Fetch(c *gin.Context) {
+	written, errFetch := currency.FetchConfigured(c.Request.Context(), h.db)
+	if errFetch != nil {
+		if errors.Is(errFetch, currency.ErrNoFeed) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "exchange rate feed is not configured"})
+			return
+		}
+		log.WithError(errFetch).Warn("admin exchange rates: fetch failed")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "fetch exchange rates failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"updated": written})
+}
+
+// formatExchangeRate converts an exchange rate into a response payload.
+func formatExchangeRate(row *models.ExchangeRate) gin.H {
+	return gin.H{
+		"currency":   strings.ToUpper(row.Currency),
+		"rate":       row.Rate,
+		"source":     row.Source,
+		"created_at": row.CreatedAt,
+		"updated_at": row.UpdatedAt,
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/shutdown"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/startupcheck"
 	"gorm.io/gorm"
 )
 
@@ -30,3 +32,28 @@ func (h *HealthHandler) Healthz(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
+
+// Readyz reports database connectivity together with the startup check outcome.
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	report := startupcheck.Current()
+	dbOK := true
+	if sqlDB, err := h.db.DB(); err != nil {
+		dbOK = false
+	} else if errPing := sqlDB.PingContext(c.Request.Context()); errPing != nil {
+		dbOK = false
+	}
+	status := http.StatusOK
+	draining := shutdown.Default().Draining()
+	ready := dbOK && report.Ready() && !draining
+	if !ready {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, gin.H{
+		"ready":      ready,
+		"database":   dbOK,
+		"read_only":  report.ReadOnly(),
+		"draining":   draining,
+		"checked_at": report.CheckedAt,
+		"issues":     report.Issues,
+	})
+}
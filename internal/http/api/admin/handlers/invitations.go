@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/invitation"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// InvitationHandler handles admin operations for registration invitation codes.
+type InvitationHandler struct {
+	db *gorm.DB // Database handle for invitation queries.
+}
+
+// NewInvitationHandler wires an invitation handler with its database dependency.
+func NewInvitationHandler(db *gorm.DB) *InvitationHandler {
+	return &InvitationHandler{db: db}
+}
+
+// createInvitationRequest captures the payload for generating invitation codes.
+type createInvitationRequest struct {
+	Code        string     `json:"code"`          // Optional custom code; only valid with count 1.
+	Count       int        `json:"count"`         // Number of codes to generate; defaults to 1.
+	Note        string     `json:"note"`          // Optional admin note.
+	UserGroupID *uint64    `json:"user_group_id"` // Optional user group for registered users.
+	PlanID      *uint64    `json:"plan_id"`       // Optional plan granted on registration.
+	PlanDays    int        `json:"plan_days"`     // Granted bill length in days; zero is one month.
+	MaxUses     *int       `json:"max_uses"`      // Registrations per code; defaults to 1, zero is unlimited.
+	ExpiresAt   *time.Time `json:"expires_at"`    // Optional expiry.
+}
+
+// Create generates one or more invitation codes.
+func (h *InvitationHandler) Create(c *gin.Context) {
+	var body createInvitationRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	count := body.Count
+	if count == 0 {
+		count = 1
+	}
+	if count < 0 || count > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "count must be between 1 and 1000"})
+		return
+	}
+	code := invitation.NormalizeCode(body.Code)
+	if code != "" && count != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a custom code requires count 1"})
+		return
+	}
+	maxUses := 1
+	if body.MaxUses != nil {
+		maxUses = *body.MaxUses
+	}
+	if maxUses < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_uses cannot be negative"})
+		return
+	}
+	if body.PlanDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "plan_days cannot be negative"})
+		return
+	}
+	userGroupID, errGroup := h.optionalRef(c, &models.UserGroup{}, body.UserGroupID)
+	if errGroup != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user group not found"})
+		return
+	}
+	planID, errPlan := h.optionalRef(c, &models.Plan{}, body.PlanID)
+	if errPlan != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "plan not found"})
+		return
+	}
+
+	now := time.Now().UTC()
+	created := make([]gin.H, 0, count)
+	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		for i := 0; i < count; i++ {
+			value := code
+			if value == "" {
+				generated, errCode := invitation.GenerateCode(12)
+				if errCode != nil {
+					return errCode
+				}
+				value = generated
+			}
+			invite := models.Invitation{
+				Code:        value,
+				Note:        strings.TrimSpace(body.Note),
+				UserGroupID: userGroupID,
+				PlanID:      planID,
+				PlanDays:    body.PlanDays,
+				MaxUses:     maxUses,
+				ExpiresAt:   body.ExpiresAt,
+				IsEnabled:   true,
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			}
+			if errCreate := tx.Create(&invite).Error; errCreate != nil {
+				return errCreate
+			}
+			created = append(created, formatInvitation(&invite))
+		}
+		return nil
+	})
+	if errTx != nil {
+		if code != "" {
+			var existing int64
+			h.db.WithContext(c.Request.Context()).Model(&models.Invitation{}).Where("code = ?", code).Count(&existing)
+			if existing > 0 {
+				c.JSON(http.StatusConflict, gin.H{"error": "code already exists"})
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create invitations failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"invitations": created})
+}
+
+// optionalRef returns a copy of id when it is set and the referenced row exists.
+func (h *InvitationHandler) optionalRef(c *gin.Context, model any, id *uint64) (*uint64, error) {
+	if id == nil || *id == 0 {
+		return nil, nil
+	}
+	var found int64
+	if errCount := h.db.WithContext(c.Request.Context()).Model(model).Where("id = ?", *id).Count(&found).Error; errCount != nil {
+		return nil, errCount
+	}
+	if found == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	idCopy := *id
+	return &idCopy, nil
+}
+
+// List returns invitation codes, newest first. The status query filters by active, expired,
+// exhausted or disabled.
+func (h *InvitationHandler) List(c *gin.Context) {
+	now := time.Now().UTC()
+	q := h.db.WithContext(c.Request.Context()).Model(&models.Invitation{})
+	if code := invitation.NormalizeCode(c.Query("code")); code != "" {
+		q = q.Where("code LIKE ?", "%"+code+"%")
+	}
+	switch strings.TrimSpace(c.Query("status")) {
+	case "active":
+		q = q.Where("is_enabled = ? AND (expires_at IS NULL OR expires_at > ?) AND (max_uses = 0 OR used_count < max_uses)", true, now)
+	case "expired":
+		q = q.Where("expires_at IS NOT NULL AND expires_at <= ?", now)
+	case "exhausted":
+		q = q.Where("max_uses > 0 AND used_count >= max_uses")
+	case "disabled":
+		q = q.Where("is_enabled = ?", false)
+	}
+	var rows []models.Invitation
+	if errFind := q.Order("created_at DESC").Order("id DESC").Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list invitations failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatInvitation(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"invitations": out})
+}
+
+// Get returns an invitation with the users who registered with it.
+func (h *InvitationHandler) Get(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var invite models.Invitation
+	if errFind := h.db.WithContext(c.Request.Context()).First(&invite, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	var redemptions []struct {
+		UserID    uint64
+		Username  string
+		BillID    *uint64
+		CreatedAt time.Time
+	}
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Table("invitation_redemptions").
+		Select("invitation_redemptions.user_id, users.username, invitation_redemptions.bill_id, invitation_redemptions.created_at").
+		Joins("LEFT JOIN users ON users.id = invitation_redemptions.user_id").
+		Where("invitation_redemptions.invitation_id = ?", invite.ID).
+		Order("invitation_redemptions.created_at DESC").
+		Scan(&redemptions).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	out := formatInvitation(&invite)
+	items := make([]gin.H, 0, len(redemptions))
+	for _, row := range redemptions {
+		items = append(items, gin.H{
+			"user_id":     row.UserID,
+			"username":    row.Username,
+			"bill_id":     row.BillID,
+			"redeemed_at": row.CreatedAt,
+		})
+	}
+	out["redemptions"] = items
+	c.JSON(http.StatusOK, out)
+}
+
+// updateInvitationRequest captures optional fields for invitation updates.
+type updateInvitationRequest struct {
+	Note        *string    `json:"note"`          // Optional admin note.
+	UserGroupID *uint64    `json:"user_group_id"` // Optional user group; zero clears it.
+	PlanID      *uint64    `json:"plan_id"`       // Optional plan; zero clears it.
+	PlanDays    *int       `json:"plan_days"`     // Optional granted bill length in days.
+	MaxUses     *int       `json:"max_uses"`      // Optional registration cap; zero is unlimited.
+	ExpiresAt   *time.Time `json:"expires_at"`    // Optional expiry.
+	ClearExpiry bool       `json:"clear_expiry"`  // Removes the expiry.
+	IsEnabled   *bool      `json:"is_enabled"`    // Optional active flag.
+}
+
+// Update applies validated field changes to an invitation.
+func (h *InvitationHandler) Update(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var body updateInvitationRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	updates := map[string]any{"updated_at": time.Now().UTC()}
+	if body.Note != nil {
+		updates["note"] = strings.TrimSpace(*body.Note)
+	}
+	if body.UserGroupID != nil {
+		userGroupID, errGroup := h.optionalRef(c, &models.UserGroup{}, body.UserGroupID)
+		if errGroup != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user group not found"})
+			return
+		}
+		updates["user_group_id"] = userGroupID
+	}
+	if body.PlanID != nil {
+		planID, errPlan := h.optionalRef(c, &models.Plan{}, body.PlanID)
+		if errPlan != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "plan not found"})
+			return
+		}
+		updates["plan_id"] = planID
+	}
+	if body.PlanDays != nil {
+		if *body.PlanDays < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "plan_days cannot be negative"})
+			return
+		}
+		updates["plan_days"] = *body.PlanDays
+	}
+	if body.MaxUses != nil {
+		if *body.MaxUses < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "max_uses cannot be negative"})
+			return
+		}
+		updates["max_uses"] = *body.MaxUses
+	}
+	if body.ClearExpiry {
+		updates["expires_at"] = nil
+	} else if body.ExpiresAt != nil {
+		updates["expires_at"] = body.ExpiresAt.UTC()
+	}
+	if body.IsEnabled != nil {
+		updates["is_enabled"] = *body.IsEnabled
+	}
+	if len(updates) == 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no fields to update"})
+		return
+	}
+	res := h.db.WithContext(c.Request.Context()).Model(&models.Invitation{}).Where("id = ?", id).Updates(updates)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// Delete removes an invitation; redemption records are kept.
+func (h *InvitationHandler) Delete(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	res := h.db.WithContext(c.Request.Context()).Delete(&models.Invitation{}, id)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// formatInvitation maps an invitation model into a response payload.
+func formatInvitation(invite *models.Invitation) gin.H {
+	return gin.H{
+		"id":            invite.ID,
+		"code":          invite.Code,
+		"note":          invite.Note,
+		"user_group_id": invite.UserGroupID,
+		"plan_id":       invite.PlanID,
+		"plan_days":     invite.PlanDays,
+		"max_uses":      invite.MaxUses,
+		"used_count":    invite.UsedCount,
+		"expires_at":    invite.ExpiresAt,
+		"is_enabled":    invite.IsEnabled,
+		"created_at":    invite.CreatedAt,
+		"updated_at":    invite.UpdatedAt,
+	}
+}
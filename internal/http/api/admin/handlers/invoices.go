@@ -0,0 +1,395 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/currency"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/invoice"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/mail"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"gorm.io/gorm"
+)
+
+// invoiceListLimit caps how many invoices the list endpoint returns.
+const invoiceListLimit = 200
+
+// InvoiceHandler generates invoices and manages their lifecycle.
+type InvoiceHandler struct {
+	db *gorm.DB // Database handle for invoice records.
+}
+
+// NewInvoiceHandler constructs an invoice handler.
+func NewInvoiceHandler(db *gorm.DB) *InvoiceHandler {
+	return &InvoiceHandler{db: db}
+}
+
+// generateInvoiceRequest captures the payload for invoice generation.
+type generateInvoiceRequest struct {
+	UserID      *uint64 `json:"user_id"`       // User to invoice.
+	UserGroupID *uint64 `json:"user_group_id"` // User group to invoice.
+	Month       string  `json:"month"`         // Billed month as YYYY-MM; defaults to the previous month.
+	TaxRate     float64 `json:"tax_rate"`      // Tax rate applied to the subtotal (0.2 = 20%).
+	Currency    string  `json:"currency"`      // ISO currency code; defaults to the display currency.
+}
+
+// Generate drafts an invoice for a user or user group, or for every billed user when
+// neither is given.
+func (h *InvoiceHandler) Generate(c *gin.Context) {
+	var body generateInvoiceRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	month := time.Now().AddDate(0, -1, 0)
+	if strings.TrimSpace(body.Month) != "" {
+		parsed, errMonth := invoice.ParseMonth(body.Month)
+		if errMonth != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid month"})
+			return
+		}
+		month = parsed
+	}
+	code := strings.TrimSpace(body.Currency)
+	if code == "" {
+		code = currency.DisplayCurrency()
+	}
+	code, errCode := currency.Normalize(code)
+	if errCode != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid currency"})
+		return
+	}
+	ctx := c.Request.Context()
+	if code != currency.Base {
+		table, errRates := currency.Load(ctx, h.db)
+		if errRates != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "load exchange rates failed"})
+			return
+		}
+		if _, errRate := table.Rate(code); errRate != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "no exchange rate configured for " + code})
+			return
+		}
+	}
+	opts := invoice.Options{TaxRate: body.TaxRate, Currency: code}
+	if adminID, okAdmin := readAdminIDFromContext(c); okAdmin {
+		opts.CreatedBy = &adminID
+	}
+
+	if body.UserID == nil && body.UserGroupID == nil {
+		if opts.TaxRate < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tax_rate"})
+			return
+		}
+		rows, errGenerate := invoice.GenerateAll(ctx, h.db, month, opts)
+		if errGenerate != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "generate invoices failed"})
+			return
+		}
+		out := make([]gin.H, 0, len(rows))
+		for i := range rows {
+			out = append(out, formatInvoice(&rows[i]))
+		}
+		c.JSON(http.StatusOK, gin.H{"invoices": out})
+		return
+	}
+
+	row, errGenerate := invoice.Generate(ctx, h.db, invoice.Subject{UserID: body.UserID, UserGroupID: body.UserGroupID}, month, opts)
+	if errGenerate != nil {
+		switch {
+		case errors.Is(errGenerate, invoice.ErrInvalidSubject):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "set either user_id or user_group_id"})
+		case errors.Is(errGenerate, invoice.ErrInvalidTaxRate):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tax_rate"})
+		case errors.Is(errGenerate, invoice.ErrNotDraft):
+			c.JSON(http.StatusConflict, gin.H{"error": "invoice already finalized"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "generate invoice failed"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, formatInvoice(row))
+}
+
+// List returns invoices filtered by user, user group, status or month.
+func (h *InvoiceHandler) List(c *gin.Context) {
+	q := h.db.WithContext(c.Request.Context()).Model(&models.Invoice{})
+	if raw := strings.TrimSpace(c.Query("user_id")); raw != "" {
+		userID, errParse := strconv.ParseUint(raw, 10, 64)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+			return
+		}
+		q = q.Where("user_id = ?", userID)
+	}
+	if raw := strings.TrimSpace(c.Query("user_group_id")); raw != "" {
+		groupID, errParse := strconv.ParseUint(raw, 10, 64)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_group_id"})
+			return
+		}
+		q = q.Where("user_group_id = ?", groupID)
+	}
+	if status := strings.TrimSpace(c.Query("status")); status != "" {
+		q = q.Where("status = ?", status)
+	}
+	if raw := strings.TrimSpace(c.Query("month")); raw != "" {
+		month, errMonth := invoice.ParseMonth(raw)
+		if errMonth != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid month"})
+			return
+		}
+		start, _ := invoice.Period(month)
+		q = q.Where("period_start = ?", start)
+	}
+	var rows []models.Invoice
+	if errFind := q.Order("period_start DESC, id DESC").Limit(invoiceListLimit).Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list invoices failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatInvoice(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"invoices": out})
+}
+
+// Get returns one invoice with its line items.
+func (h *InvoiceHandler) Get(c *gin.Context) {
+	row, ok := h.load(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, formatInvoice(row))
+}
+
+// Render downloads an invoice as a PDF (default) or JSON document.
+func (h *InvoiceHandler) Render(c *gin.Context) {
+	row, ok := h.load(c)
+	if !ok {
+		return
+	}
+	doc, errDoc := invoice.NewDocument(row, invoiceIssuer(), h.billTo(c, row))
+	if errDoc != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "render invoice failed"})
+		return
+	}
+	name := doc.Number
+	switch strings.ToLower(strings.TrimSpace(c.DefaultQuery("format", "pdf"))) {
+	case "pdf":
+		data, errRender := invoice.RenderPDF(doc)
+		if errRender != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "render invoice failed"})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".pdf"))
+		c.Data(http.StatusOK, "application/pdf", data)
+	case "json":
+		data, errRender := invoice.RenderJSON(doc)
+		if errRender != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "render invoice failed"})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".json"))
+		c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format"})
+	}
+}
+
+// sendInvoiceRequest optionally overrides the invoice recipient.
+type sendInvoiceRequest struct {
+	To string `json:"to"` // Recipient address; defaults to the invoiced user's email.
+}
+
+// Send emails a finalized invoice as a PDF attachment.
+func (h *InvoiceHandler) Send(c *gin.Context) {
+	row, ok := h.load(c)
+	if !ok {
+		return
+	}
+	var body sendInvoiceRequest
+	if c.Request.ContentLength > 0 {
+		if errBind := c.ShouldBindJSON(&body); errBind != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+			return
+		}
+	}
+	if row.Status == models.InvoiceStatusDraft {
+		c.JSON(http.StatusConflict, gin.H{"error": "invoice is not finalized"})
+		return
+	}
+	mailer := mail.New()
+	if !mailer.Enabled() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "email is not configured"})
+		return
+	}
+	ctx := c.Request.Context()
+	to := strings.TrimSpace(body.To)
+	username := ""
+	if row.UserID != nil {
+		var user models.User
+		if errFind := h.db.WithContext(ctx).Select("id", "username", "email").First(&user, *row.UserID).Error; errFind == nil {
+			username = user.Username
+			if to == "" {
+				to = strings.TrimSpace(user.Email)
+			}
+		}
+	}
+	if to == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no recipient email"})
+		return
+	}
+	doc, errDoc := invoice.NewDocument(row, invoiceIssuer(), h.billTo(c, row))
+	if errDoc != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "render invoice failed"})
+		return
+	}
+	pdf, errRender := invoice.RenderPDF(doc)
+	if errRender != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "render invoice failed"})
+		return
+	}
+	errSend := mailer.SendTemplate(ctx, to, mail.TemplateInvoice, map[string]any{
+		"SiteName": mail.SiteName(),
+		"Username": username,
+		"Number":   doc.Number,
+		"Period":   doc.PeriodStart.Format("January 2006"),
+		"Total":    strconv.FormatFloat(doc.Total, 'f', 2, 64),
+		"Currency": doc.Currency,
+		"Status":   doc.Status,
+	}, mail.Attachment{Filename: doc.Number + ".pdf", ContentType: "application/pdf", Data: pdf})
+	if errSend != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "send email failed: " + errSend.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sent": true, "to": to})
+}
+
+// Finalize freezes a draft invoice and assigns its number.
+func (h *InvoiceHandler) Finalize(c *gin.Context) {
+	h.transition(c, invoice.Finalize)
+}
+
+// MarkPaid records payment of a finalized invoice.
+func (h *InvoiceHandler) MarkPaid(c *gin.Context) {
+	h.transition(c, invoice.MarkPaid)
+}
+
+// transition runs a status change and maps its errors to responses.
+func (h *InvoiceHandler) transition(c *gin.Context, apply func(context.Context, *gorm.DB, uint64, time.Time) (*models.Invoice, error)) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	row, errApply := apply(c.Request.Context(), h.db, id, time.Now().UTC())
+	if errApply != nil {
+		switch {
+		case errors.Is(errApply, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		case errors.Is(errApply, invoice.ErrNotDraft):
+			c.JSON(http.StatusConflict, gin.H{"error": "invoice is not a draft"})
+		case errors.Is(errApply, invoice.ErrNotFinalized):
+			c.JSON(http.StatusConflict, gin.H{"error": "invoice is not finalized"})
+		case errors.Is(errApply, invoice.ErrStatusChanged):
+			c.JSON(http.StatusConflict, gin.H{"error": "invoice status changed, reload and retry"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "update invoice failed"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, formatInvoice(row))
+}
+
+// load fetches the invoice named by the id path parameter, writing the error response on failure.
+func (h *InvoiceHandler) load(c *gin.Context) (*models.Invoice, bool) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return nil, false
+	}
+	var row models.Invoice
+	if errFind := h.db.WithContext(c.Request.Context()).First(&row, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query invoice failed"})
+		return nil, false
+	}
+	return &row, true
+}
+
+// billTo names the invoiced user or user group for rendered documents.
+func (h *InvoiceHandler) billTo(c *gin.Context, row *models.Invoice) string {
+	ctx := c.Request.Context()
+	if row.UserID != nil {
+		var user models.User
+		if errFind := h.db.WithContext(ctx).Select("id", "username", "email").First(&user, *row.UserID).Error; errFind == nil {
+			if user.Email != "" {
+				return fmt.Sprintf("%s <%s>", user.Username, user.Email)
+			}
+			return user.Username
+		}
+		return fmt.Sprintf("user #%d", *row.UserID)
+	}
+	if row.UserGroupID != nil {
+		var group models.UserGroup
+		if errFind := h.db.WithContext(ctx).Select("id", "name").First(&group, *row.UserGroupID).Error; errFind == nil {
+			return group.Name
+		}
+		return fmt.Sprintf("user group #%d", *row.UserGroupID)
+	}
+	return ""
+}
+
+// invoiceIssuer returns the configured site name used as the invoice issuer.
+func invoiceIssuer() string {
+	raw, ok := internalsettings.DBConfigValue(internalsettings.SiteNameKey)
+	if !ok {
+		return ""
+	}
+	var name string
+	if errUnmarshal := json.Unmarshal(raw, &name); errUnmarshal != nil {
+		return ""
+	}
+	return strings.TrimSpace(name)
+}
+
+// formatInvoice converts an invoice into a response payload.
+func formatInvoice(row *models.Invoice) gin.H {
+	lines := json.RawMessage(row.LineItems)
+	if len(lines) == 0 {
+		lines = json.RawMessage("[]")
+	}
+	return gin.H{
+		"id":            row.ID,
+		"number":        row.Number,
+		"user_id":       row.UserID,
+		"user_group_id": row.UserGroupID,
+		"period_start":  row.PeriodStart,
+		"period_end":    row.PeriodEnd,
+		"currency":      row.Currency,
+		"exchange_rate": row.ExchangeRate,
+		"line_items":    lines,
+		"subtotal":      row.Subtotal,
+		"tax_rate":      row.TaxRate,
+		"tax":           row.Tax,
+		"total":         row.Total,
+		"status":        row.Status,
+		"finalized_at":  row.FinalizedAt,
+		"paid_at":       row.PaidAt,
+		"created_by":    row.CreatedBy,
+		"created_at":    row.CreatedAt,
+		"updated_at":    row.UpdatedAt,
+	}
+}
@@ -8,7 +8,10 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/currency"
+	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/usageerror"
 	"gorm.io/gorm"
 )
 
@@ -56,10 +59,13 @@ type adminLogDetailQuery struct {
 	Model    string `form:"model"`                   // Model filter.
 	Provider string `form:"provider"`                // Provider filter.
 	Project  string `form:"project"`                 // Project/source filter.
+	Limit    int    `form:"limit,default=500"`       // Page size.
+	Cursor   string `form:"cursor"`                  // next_cursor of the previous page.
 }
 
 // adminLogDetailEntry represents a single usage record in detail view.
 type adminLogDetailEntry struct {
+	ID           uint64    `json:"id"`            // Usage ID.
 	RequestedAt  time.Time `json:"requested_at"`  // Request timestamp.
 	InputTokens  int64     `json:"input_tokens"`  // Input token count.
 	OutputTokens int64     `json:"output_tokens"` // Output token count.
@@ -162,6 +168,7 @@ func (h *AdminLogsHandler) List(c *gin.Context) {
 		return
 	}
 
+	display := currency.Display(ctx, h.db)
 	logs := make([]adminLogEntry, 0, len(aggs))
 	for _, a := range aggs {
 		status := "normal"
@@ -193,7 +200,7 @@ func (h *AdminLogsHandler) List(c *gin.Context) {
 			OutputTokens: a.OutputTokens,
 			TotalTokens:  a.TotalTokens,
 			CostMicros:   a.CostMicros,
-			Cost:         fmt.Sprintf("$%.2f", float64(a.CostMicros)/1_000_000),
+			Cost:         display.Format(a.CostMicros, 2),
 			FailedCount:  a.FailedCount,
 			Status:       status,
 			StatusText:   statusText,
@@ -201,14 +208,16 @@ func (h *AdminLogsHandler) List(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"logs":  logs,
-		"total": total,
-		"page":  q.Page,
-		"limit": q.Limit,
+		"logs":     logs,
+		"total":    total,
+		"currency": display.Currency,
+		"page":     q.Page,
+		"limit":    q.Limit,
 	})
 }
 
-// Detail returns per-request usage entries for a date and filters.
+// Detail returns per-request usage entries for a date and filters, newest first. Pass
+// next_cursor back as cursor until it is empty to page through busy days.
 func (h *AdminLogsHandler) Detail(c *gin.Context) {
 	var q adminLogDetailQuery
 	if errBind := c.ShouldBindQuery(&q); errBind != nil {
@@ -235,7 +244,8 @@ func (h *AdminLogsHandler) Detail(c *gin.Context) {
 	query := h.db.WithContext(ctx).
 		Model(&models.Usage{}).
 		Select(`
-			requested_at,
+			usages.id,
+			usages.requested_at,
 			input_tokens,
 			output_tokens,
 			cached_tokens,
@@ -257,14 +267,32 @@ func (h *AdminLogsHandler) Detail(c *gin.Context) {
 		query = query.Where("source = ?", strings.TrimSpace(q.Project))
 	}
 
+	if q.Limit < 1 || q.Limit > 1000 {
+		q.Limit = 500
+	}
+	keyset := dbutil.RequestedAtKeyset(true)
+	var after *dbutil.Cursor
+	if cursor := strings.TrimSpace(q.Cursor); cursor != "" {
+		pos, errCursor := keyset.Decode(cursor)
+		if errCursor != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		after = &pos
+	}
+
 	var rows []adminLogDetailEntry
-	if errFind := query.
-		Order("requested_at DESC").
+	if errFind := keyset.Apply(query, after).
+		Limit(q.Limit + 1).
 		Scan(&rows).Error; errFind != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "query details failed"})
 		return
 	}
+	rows, nextCursor := dbutil.NextPage(keyset, rows, q.Limit, func(row adminLogDetailEntry) dbutil.Cursor {
+		return dbutil.Cursor{Value: dbutil.TimeValue(row.RequestedAt), ID: row.ID}
+	})
 
+	display := currency.Display(ctx, h.db)
 	details := make([]gin.H, 0, len(rows))
 	for _, row := range rows {
 		details = append(details, gin.H{
@@ -274,12 +302,12 @@ func (h *AdminLogsHandler) Detail(c *gin.Context) {
 			"output_tokens": row.OutputTokens,
 			"cached_tokens": row.CachedTokens,
 			"total_tokens":  row.TotalTokens,
-			"cost":          fmt.Sprintf("$%.4f", float64(row.CostMicros)/1_000_000),
+			"cost":          display.Format(row.CostMicros, 4),
 			"success":       !row.Failed,
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{"details": details})
+	c.JSON(http.StatusOK, gin.H{"details": details, "currency": display.Currency, "next_cursor": nextCursor})
 }
 
 // Stats returns aggregated KPIs for today vs yesterday.
@@ -308,7 +336,7 @@ func (h *AdminLogsHandler) Stats(c *gin.Context) {
 			COALESCE(SUM(total_tokens), 0) AS total_tokens,
 			SUM(CASE WHEN failed THEN 1 ELSE 0 END) AS failed_count,
 			COALESCE(SUM(cost_micros), 0) AS cost_micros,
-			COALESCE(AVG(GREATEST(EXTRACT(EPOCH FROM (created_at - requested_at)) * 1000, 0)), 0) AS avg_request_time_ms
+			COALESCE(AVG(` + dbutil.DurationMillisExpr(h.db, "requested_at", "created_at") + `), 0) AS avg_request_time_ms
 		`).Scan(&todayStats)
 
 	h.db.WithContext(ctx).Model(&models.Usage{}).
@@ -318,7 +346,7 @@ func (h *AdminLogsHandler) Stats(c *gin.Context) {
 			COALESCE(SUM(total_tokens), 0) AS total_tokens,
 			SUM(CASE WHEN failed THEN 1 ELSE 0 END) AS failed_count,
 			COALESCE(SUM(cost_micros), 0) AS cost_micros,
-			COALESCE(AVG(GREATEST(EXTRACT(EPOCH FROM (created_at - requested_at)) * 1000, 0)), 0) AS avg_request_time_ms
+			COALESCE(AVG(` + dbutil.DurationMillisExpr(h.db, "requested_at", "created_at") + `), 0) AS avg_request_time_ms
 		`).Scan(&yesterdayStats)
 
 	requestsChange := percentChange(todayStats.Requests, yesterdayStats.Requests)
@@ -331,6 +359,13 @@ func (h *AdminLogsHandler) Stats(c *gin.Context) {
 	yesterdayErrorRate := calcErrorRate(yesterdayStats.FailedCount, yesterdayStats.Requests)
 	errorRateChange := errorRate - yesterdayErrorRate
 
+	errorCodes, errCodes := failedCountsByCode(h.db.WithContext(ctx).Model(&models.Usage{}).
+		Where("requested_at >= ?", todayStart))
+	if errCodes != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query logs failed"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"requests_today":          todayStats.Requests,
 		"requests_today_display":  formatNumber(todayStats.Requests),
@@ -343,9 +378,30 @@ func (h *AdminLogsHandler) Stats(c *gin.Context) {
 		"error_rate":              errorRate,
 		"error_rate_display":      fmt.Sprintf("%.2f%%", errorRate),
 		"error_rate_change":       errorRateChange,
+		"error_breakdown":         usageerror.Breakdown(errorCodes),
 	})
 }
 
+// failedCountsByCode counts the failed usage rows of query per error code.
+func failedCountsByCode(query *gorm.DB) (map[string]int64, error) {
+	var rows []struct {
+		ErrorCode string
+		Failures  int64
+	}
+	if errScan := query.
+		Where("failed = ?", true).
+		Select("error_code, COUNT(*) AS failures").
+		Group("error_code").
+		Scan(&rows).Error; errScan != nil {
+		return nil, errScan
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.ErrorCode] += row.Failures
+	}
+	return counts, nil
+}
+
 // Trend returns a seven-day trend of requests and tokens.
 func (h *AdminLogsHandler) Trend(c *gin.Context) {
 	ctx := c.Request.Context()
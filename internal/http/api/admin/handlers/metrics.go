@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/metrics"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// MetricsHandler serves Prometheus metrics to scrapers holding an admin API key.
+type MetricsHandler struct {
+	db *gorm.DB
+}
+
+// NewMetricsHandler constructs a MetricsHandler.
+func NewMetricsHandler(db *gorm.DB) *MetricsHandler {
+	return &MetricsHandler{db: db}
+}
+
+// Metrics renders the default metrics registry in the Prometheus text format.
+// Scrapers authenticate with "Authorization: Bearer <admin api key>".
+func (h *MetricsHandler) Metrics(c *gin.Context) {
+	token := strings.TrimSpace(c.GetHeader("Authorization"))
+	if !strings.HasPrefix(token, "Bearer ") {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+		return
+	}
+	token = strings.TrimSpace(strings.TrimPrefix(token, "Bearer "))
+
+	var apiKey models.APIKey
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Where("api_key = ? AND active = ? AND revoked_at IS NULL", token, true).
+		First(&apiKey).Error; errFind != nil || !apiKey.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "metrics require an admin api key"})
+		return
+	}
+	metrics.Default.Handler().ServeHTTP(c.Writer, c.Request)
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modeldisplay"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// ModelDisplayHandler manages admin CRUD endpoints for model display metadata.
+type ModelDisplayHandler struct {
+	db *gorm.DB // Database handle for model display records.
+}
+
+// NewModelDisplayHandler constructs a model display handler.
+func NewModelDisplayHandler(db *gorm.DB) *ModelDisplayHandler {
+	return &ModelDisplayHandler{db: db}
+}
+
+// createModelDisplayRequest captures the payload for creating model display metadata.
+type createModelDisplayRequest struct {
+	ModelID       string   `json:"model_id"`       // Exposed model ID.
+	DisplayName   string   `json:"display_name"`   // Friendly name.
+	Category      string   `json:"category"`       // Grouping label.
+	Description   string   `json:"description"`    // Short description.
+	ContextWindow int      `json:"context_window"` // Context window in tokens.
+	Capabilities  []string `json:"capabilities"`   // Capability flags.
+	SortOrder     int      `json:"sort_order"`     // Ordering within the category.
+	IsEnabled     *bool    `json:"is_enabled"`     // Optional active flag.
+}
+
+// updateModelDisplayRequest captures optional fields for model display updates.
+type updateModelDisplayRequest struct {
+	ModelID       *string   `json:"model_id"`       // Optional exposed model ID.
+	DisplayName   *string   `json:"display_name"`   // Optional friendly name.
+	Category      *string   `json:"category"`       // Optional grouping label.
+	Description   *string   `json:"description"`    // Optional description.
+	ContextWindow *int      `json:"context_window"` // Optional context window.
+	Capabilities  *[]string `json:"capabilities"`   // Optional capability flags.
+	SortOrder     *int      `json:"sort_order"`     // Optional ordering.
+	IsEnabled     *bool     `json:"is_enabled"`     // Optional active flag.
+}
+
+// Create validates input and inserts model display metadata.
+func (h *ModelDisplayHandler) Create(c *gin.Context) {
+	var body createModelDisplayRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	modelID := strings.TrimSpace(body.ModelID)
+	if modelID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "model_id is required"})
+		return
+	}
+	if body.ContextWindow < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "context_window must be non-negative"})
+		return
+	}
+	capabilities, errCapabilities := modeldisplay.NormalizeCapabilities(body.Capabilities)
+	if errCapabilities != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errCapabilities.Error()})
+		return
+	}
+	capabilitiesJSON, errMarshal := marshalJSON(capabilities)
+	if errMarshal != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid capabilities"})
+		return
+	}
+
+	now := time.Now().UTC()
+	row := models.ModelDisplay{
+		ModelID:       modelID,
+		DisplayName:   strings.TrimSpace(body.DisplayName),
+		Category:      strings.TrimSpace(body.Category),
+		Description:   strings.TrimSpace(body.Description),
+		ContextWindow: body.ContextWindow,
+		Capabilities:  capabilitiesJSON,
+		SortOrder:     body.SortOrder,
+		IsEnabled:     true,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if body.IsEnabled != nil {
+		row.IsEnabled = *body.IsEnabled
+	}
+	if errCreate := h.db.WithContext(c.Request.Context()).Create(&row).Error; errCreate != nil {
+		if isDuplicateKeyError(errCreate) {
+			c.JSON(http.StatusConflict, gin.H{"error": "model_id already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create model display failed"})
+		return
+	}
+	modeldisplay.Invalidate()
+	c.JSON(http.StatusCreated, formatModelDisplay(&row))
+}
+
+// List returns model display metadata ordered by category and sort order.
+func (h *ModelDisplayHandler) List(c *gin.Context) {
+	q := h.db.WithContext(c.Request.Context()).Model(&models.ModelDisplay{})
+	if category := strings.TrimSpace(c.Query("category")); category != "" {
+		q = q.Where("category = ?", category)
+	}
+	if keyword := strings.TrimSpace(c.Query("keyword")); keyword != "" {
+		pattern := dbutil.NormalizeLikePattern(h.db, "%"+keyword+"%")
+		q = q.Where("("+dbutil.CaseInsensitiveLikeExpr(h.db, "model_id")+" OR "+dbutil.CaseInsensitiveLikeExpr(h.db, "display_name")+")", pattern, pattern)
+	}
+	var rows []models.ModelDisplay
+	if errFind := q.Order("category ASC, sort_order ASC, model_id ASC").Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list model displays failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatModelDisplay(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"model_displays": out,
+		"capabilities":   modeldisplay.CapabilityFlags(),
+	})
+}
+
+// Update modifies model display metadata by ID.
+func (h *ModelDisplayHandler) Update(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var body updateModelDisplayRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+
+	var row models.ModelDisplay
+	if errFind := h.db.WithContext(c.Request.Context()).First(&row, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	if body.ModelID != nil {
+		modelID := strings.TrimSpace(*body.ModelID)
+		if modelID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "model_id cannot be empty"})
+			return
+		}
+		row.ModelID = modelID
+	}
+	if body.DisplayName != nil {
+		row.DisplayName = strings.TrimSpace(*body.DisplayName)
+	}
+	if body.Category != nil {
+		row.Category = strings.TrimSpace(*body.Category)
+	}
+	if body.Description != nil {
+		row.Description = strings.TrimSpace(*body.Description)
+	}
+	if body.ContextWindow != nil {
+		if *body.ContextWindow < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "context_window must be non-negative"})
+			return
+		}
+		row.ContextWindow = *body.ContextWindow
+	}
+	if body.Capabilities != nil {
+		capabilities, errCapabilities := modeldisplay.NormalizeCapabilities(*body.Capabilities)
+		if errCapabilities != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errCapabilities.Error()})
+			return
+		}
+		capabilitiesJSON, errMarshal := marshalJSON(capabilities)
+		if errMarshal != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid capabilities"})
+			return
+		}
+		row.Capabilities = capabilitiesJSON
+	}
+	if body.SortOrder != nil {
+		row.SortOrder = *body.SortOrder
+	}
+	if body.IsEnabled != nil {
+		row.IsEnabled = *body.IsEnabled
+	}
+	row.UpdatedAt = time.Now().UTC()
+
+	if errSave := h.db.WithContext(c.Request.Context()).Save(&row).Error; errSave != nil {
+		if isDuplicateKeyError(errSave) {
+			c.JSON(http.StatusConflict, gin.H{"error": "model_id already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	modeldisplay.Invalidate()
+	c.JSON(http.StatusOK, formatModelDisplay(&row))
+}
+
+// Delete removes model display metadata by ID.
+func (h *ModelDisplayHandler) Delete(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if errDelete := h.db.WithContext(c.Request.Context()).Delete(&models.ModelDisplay{}, id).Error; errDelete != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	modeldisplay.Invalidate()
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// formatModelDisplay formats a model display row for API responses.
+func formatModelDisplay(row *models.ModelDisplay) gin.H {
+	entry := modeldisplay.FromModel(row)
+	capabilities := entry.Capabilities
+	if capabilities == nil {
+		capabilities = []string{}
+	}
+	return gin.H{
+		"id":             row.ID,
+		"model_id":       row.ModelID,
+		"display_name":   row.DisplayName,
+		"label":          entry.Label(),
+		"category":       row.Category,
+		"description":    row.Description,
+		"context_window": row.ContextWindow,
+		"capabilities":   capabilities,
+		"sort_order":     row.SortOrder,
+		"is_enabled":     row.IsEnabled,
+		"created_at":     row.CreatedAt,
+		"updated_at":     row.UpdatedAt,
+	}
+}
+
+// isDuplicateKeyError reports whether err is a unique constraint violation.
+func isDuplicateKeyError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "duplicate") || strings.Contains(msg, "unique")
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelmapping"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+)
+
+// bulkModelMappingRequest carries pasted mappings: either structured entries or raw CSV/JSON
+// content with its format.
+type bulkModelMappingRequest struct {
+	Mappings []modelmapping.Entry `json:"mappings"` // Structured entries; wins over Content.
+	Format   string               `json:"format"`   // "csv" or "json" for Content.
+	Content  string               `json:"content"`  // Pasted CSV or JSON array.
+	DryRun   bool                 `json:"dry_run"`  // Report what would change without writing.
+}
+
+// BulkUpsert creates or updates many model mappings at once and reports the cross-provider
+// alias conflicts of the resulting set.
+func (h *ModelMappingHandler) BulkUpsert(c *gin.Context) {
+	var body bulkModelMappingRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	var (
+		entries  []modelmapping.Entry
+		errParse error
+	)
+	if len(body.Mappings) > 0 {
+		raw, _ := json.Marshal(body.Mappings)
+		entries, errParse = modelmapping.ParseBulk(modelmapping.FormatJSON, raw)
+	} else {
+		entries, errParse = modelmapping.ParseBulk(body.Format, []byte(body.Content))
+	}
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errParse.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if body.DryRun {
+		var existing []models.ModelMapping
+		if errFind := h.db.WithContext(ctx).Find(&existing).Error; errFind != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+			return
+		}
+		merged, result := modelmapping.Merge(existing, entries)
+		c.JSON(http.StatusOK, gin.H{
+			"dry_run":   true,
+			"created":   result.Created,
+			"updated":   result.Updated,
+			"conflicts": conflictsJSON(modelmapping.FindConflicts(merged)),
+		})
+		return
+	}
+
+	result, errUpsert := modelmapping.Upsert(ctx, h.db, entries, time.Now().UTC())
+	if errUpsert != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "bulk upsert failed"})
+		return
+	}
+	var rows []models.ModelMapping
+	if errFind := h.db.WithContext(ctx).Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"dry_run":   false,
+		"created":   result.Created,
+		"updated":   result.Updated,
+		"conflicts": conflictsJSON(modelmapping.FindConflicts(rows)),
+	})
+}
+
+// Conflicts lists aliases that enabled mappings of more than one provider expose.
+func (h *ModelMappingHandler) Conflicts(c *gin.Context) {
+	var rows []models.ModelMapping
+	if errFind := h.db.WithContext(c.Request.Context()).Where("is_enabled = ?", true).Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"conflicts": conflictsJSON(modelmapping.FindConflicts(rows))})
+}
+
+// Resolve is a dry run showing how an incoming model name resolves for each provider against
+// the mappings currently loaded for routing. Without a provider query every provider of the
+// catalog or of a stored mapping is reported.
+func (h *ModelMappingHandler) Resolve(c *gin.Context) {
+	model := strings.TrimSpace(c.Query("model"))
+	if model == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "model is required"})
+		return
+	}
+
+	providers := make([]string, 0)
+	if provider := strings.TrimSpace(c.Query("provider")); provider != "" {
+		providers = append(providers, provider)
+	} else {
+		seen := make(map[string]struct{})
+		for _, item := range listProviderCatalog() {
+			seen[item.ID] = struct{}{}
+		}
+		var stored []string
+		if errFind := h.db.WithContext(c.Request.Context()).Model(&models.ModelMapping{}).
+			Distinct("provider").Pluck("provider", &stored).Error; errFind != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+			return
+		}
+		for _, provider := range stored {
+			if provider = strings.TrimSpace(provider); provider != "" {
+				seen[provider] = struct{}{}
+			}
+		}
+		for provider := range seen {
+			providers = append(providers, provider)
+		}
+		sort.Strings(providers)
+	}
+
+	out := make([]modelmapping.Resolution, 0, len(providers))
+	for _, provider := range providers {
+		resolution := modelmapping.Resolve(provider, model)
+		if c.Query("provider") == "" && !resolution.Aliased && !resolution.Matched {
+			continue
+		}
+		out = append(out, resolution)
+	}
+	c.JSON(http.StatusOK, gin.H{"model": model, "resolutions": out})
+}
+
+func conflictsJSON(conflicts []modelmapping.Conflict) []modelmapping.Conflict {
+	if conflicts == nil {
+		return []modelmapping.Conflict{}
+	}
+	return conflicts
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/access"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/authschedule"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelcatalog"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modeldisplay"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// ModelCatalogHandler serves the merged model catalog for admin model pickers.
+type ModelCatalogHandler struct {
+	db *gorm.DB // Database handle for mappings, auths and billing rules.
+}
+
+// NewModelCatalogHandler constructs a model catalog handler.
+func NewModelCatalogHandler(db *gorm.DB) *ModelCatalogHandler {
+	return &ModelCatalogHandler{db: db}
+}
+
+// Catalog returns every registered or mapped model with its mapping, auth file coverage and
+// billing rule prices. An api_key_id query narrows the list to what that key may request.
+func (h *ModelCatalogHandler) Catalog(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var scope *access.KeyScope
+	if raw := strings.TrimSpace(c.Query("api_key_id")); raw != "" {
+		keyID, errParse := strconv.ParseUint(raw, 10, 64)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid api_key_id"})
+			return
+		}
+		var apiKey models.APIKey
+		if errFind := h.db.WithContext(ctx).First(&apiKey, keyID).Error; errFind != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+			return
+		}
+		keyScope := access.ScopeOf(&apiKey)
+		scope = &keyScope
+	}
+
+	var mappings []models.ModelMapping
+	if errFind := h.db.WithContext(ctx).Find(&mappings).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query mappings failed"})
+		return
+	}
+	var auths []models.Auth
+	if errFind := h.db.WithContext(ctx).
+		Select("id", "auth_group_id", "content", "excluded_models").
+		Where("is_available = ? AND pending_approval = ?", true, false).
+		Find(&auths).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query auths failed"})
+		return
+	}
+	auths = authschedule.FilterAuths(ctx, h.db, auths)
+	var rules []models.BillingRule
+	if errFind := h.db.WithContext(ctx).Where("is_enabled = ?", true).Find(&rules).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query billing rules failed"})
+		return
+	}
+	defaultAuthGroupID, errAuthGroup := billing.ResolveDefaultAuthGroupID(ctx, h.db)
+	if errAuthGroup != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query auth group failed"})
+		return
+	}
+	defaultUserGroupID, errUserGroup := billing.ResolveDefaultUserGroupID(ctx, h.db)
+	if errUserGroup != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query default user group failed"})
+		return
+	}
+
+	in := modelcatalog.Input{
+		Registry: registryModels(mappings),
+		Mappings: mappings,
+		Auths:    auths,
+		Rules:    rules,
+		Displays: modeldisplay.Load(ctx, h.db),
+	}
+	if defaultAuthGroupID != nil {
+		in.DefaultAuthGroupID = *defaultAuthGroupID
+	}
+	if defaultUserGroupID != nil {
+		in.DefaultUserGroupID = *defaultUserGroupID
+	}
+
+	entries := modelcatalog.Build(in)
+	if scope != nil {
+		allowed := entries[:0]
+		for _, entry := range entries {
+			if scope.AllowsModel(entry.Model) && scope.AllowsAnyProvider([]string{entry.Provider}) {
+				allowed = append(allowed, entry)
+			}
+		}
+		entries = allowed
+	}
+	c.JSON(http.StatusOK, gin.H{"models": entries, "total": len(entries)})
+}
+
+// registryModels reads the models currently registered for every catalog provider and every
+// provider named by a stored mapping.
+func registryModels(mappings []models.ModelMapping) []modelcatalog.RegistryModel {
+	seen := make(map[string]struct{})
+	for _, item := range listProviderCatalog() {
+		seen[item.ID] = struct{}{}
+	}
+	for _, mapping := range mappings {
+		if provider := strings.ToLower(strings.TrimSpace(mapping.Provider)); provider != "" {
+			seen[provider] = struct{}{}
+		}
+	}
+	providers := make([]string, 0, len(seen))
+	for provider := range seen {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+
+	registry := cliproxy.GlobalModelRegistry()
+	out := make([]modelcatalog.RegistryModel, 0)
+	for _, provider := range providers {
+		for _, info := range registry.GetAvailableModelsByProvider(provider) {
+			if info == nil || info.ID == "" {
+				continue
+			}
+			out = append(out, modelcatalog.RegistryModel{Provider: provider, ID: info.ID, DisplayName: info.DisplayName})
+		}
+	}
+	return out
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/environments"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// nodePushClient delivers pushed config to node agents.
+var nodePushClient = &http.Client{Timeout: 15 * time.Second}
+
+// nodeConfigBundle is the synced config a remote node applies.
+type nodeConfigBundle struct {
+	Version     string         `json:"version"`      // Content hash of config and auth files.
+	Environment string         `json:"environment"`  // Environment the bundle was built for.
+	GeneratedAt time.Time      `json:"generated_at"` // Build timestamp.
+	Config      nodeSDKConfig  `json:"config"`       // Provider key and model alias sections.
+	AuthFiles   []nodeAuthFile `json:"auth_files"`   // Auth files for the node's auth dir.
+}
+
+// nodeSDKConfig holds the config sections the panel owns, keyed like the SDK config file.
+type nodeSDKConfig struct {
+	GeminiKey           []sdkconfig.GeminiKey                  `json:"gemini-api-key"`
+	CodexKey            []sdkconfig.CodexKey                   `json:"codex-api-key"`
+	ClaudeKey           []sdkconfig.ClaudeKey                  `json:"claude-api-key"`
+	VertexCompatAPIKey  []sdkconfig.VertexCompatKey            `json:"vertex-api-key"`
+	OpenAICompatibility []sdkconfig.OpenAICompatibility        `json:"openai-compatibility"`
+	OAuthModelAlias     map[string][]sdkconfig.OAuthModelAlias `json:"oauth-model-alias"`
+}
+
+// nodeAuthFile is one auth file delivered to a node.
+type nodeAuthFile struct {
+	Name    string          `json:"name"`    // File name inside the auth dir.
+	Content json.RawMessage `json:"content"` // Auth JSON payload.
+}
+
+// loadProviderSyncRows loads the provider keys and enabled model mappings used to build SDK config.
+func loadProviderSyncRows(ctx context.Context, db *gorm.DB) ([]models.ProviderAPIKey, []models.ModelMapping, error) {
+	var rows []models.ProviderAPIKey
+	if errFind := db.WithContext(ctx).Order("id ASC").Find(&rows).Error; errFind != nil {
+		return nil, nil, errFind
+	}
+	var mappingRows []models.ModelMapping
+	if errFindMappings := db.WithContext(ctx).
+		Model(&models.ModelMapping{}).
+		Where("is_enabled = ?", true).
+		Order("provider ASC, new_model_name ASC, model_name ASC").
+		Find(&mappingRows).Error; errFindMappings != nil {
+		return nil, nil, errFindMappings
+	}
+	return rows, mappingRows, nil
+}
+
+// buildNodeConfigBundle assembles the provider config and auth files that belong to env.
+func buildNodeConfigBundle(ctx context.Context, db *gorm.DB, env string) (*nodeConfigBundle, error) {
+	if db == nil {
+		return nil, errors.New("missing db")
+	}
+	rows, mappingRows, errLoad := loadProviderSyncRows(ctx, db)
+	if errLoad != nil {
+		return nil, errLoad
+	}
+	cfg := &sdkconfig.Config{}
+	applyProviderRows(cfg, environments.FilterProviderKeys(rows, env), mappingRows)
+
+	var authRows []models.Auth
+	if errFind := db.WithContext(ctx).
+		Select("id", "key", "content", "is_available", "environments").
+		Where("is_available = ?", true).
+		Order("id ASC").
+		Find(&authRows).Error; errFind != nil {
+		return nil, errFind
+	}
+	authFiles := make([]nodeAuthFile, 0, len(authRows))
+	for _, row := range environments.FilterAuths(authRows, env) {
+		name := environments.AuthFileName(row.Key)
+		if name == "" || !json.Valid(row.Content) {
+			continue
+		}
+		authFiles = append(authFiles, nodeAuthFile{Name: name, Content: json.RawMessage(row.Content)})
+	}
+
+	bundle := &nodeConfigBundle{
+		Environment: env,
+		GeneratedAt: time.Now().UTC(),
+		Config: nodeSDKConfig{
+			GeminiKey:           cfg.GeminiKey,
+			CodexKey:            cfg.CodexKey,
+			ClaudeKey:           cfg.ClaudeKey,
+			VertexCompatAPIKey:  cfg.VertexCompatAPIKey,
+			OpenAICompatibility: cfg.OpenAICompatibility,
+			OAuthModelAlias:     cfg.OAuthModelAlias,
+		},
+		AuthFiles: authFiles,
+	}
+	version, errVersion := nodeConfigVersion(bundle)
+	if errVersion != nil {
+		return nil, errVersion
+	}
+	bundle.Version = version
+	return bundle, nil
+}
+
+// nodeConfigVersion hashes the bundle content so unchanged config keeps its version.
+func nodeConfigVersion(bundle *nodeConfigBundle) (string, error) {
+	payload, errMarshal := json.Marshal(struct {
+		Environment string         `json:"environment"`
+		Config      nodeSDKConfig  `json:"config"`
+		AuthFiles   []nodeAuthFile `json:"auth_files"`
+	}{bundle.Environment, bundle.Config, bundle.AuthFiles})
+	if errMarshal != nil {
+		return "", fmt.Errorf("encode node config: %w", errMarshal)
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:16]), nil
+}
+
+// pushNodeConfig posts the node's current bundle to its push URL and records the outcome.
+func pushNodeConfig(ctx context.Context, db *gorm.DB, node *models.Node) error {
+	if node == nil || strings.TrimSpace(node.PushURL) == "" {
+		return errors.New("node has no push url")
+	}
+	bundle, errBuild := buildNodeConfigBundle(ctx, db, node.Environment)
+	if errBuild != nil {
+		return errBuild
+	}
+	errPush := deliverNodeConfig(ctx, node, bundle)
+
+	now := time.Now().UTC()
+	updates := map[string]any{"last_sync_at": now, "updated_at": now}
+	if errPush != nil {
+		updates["sync_status"] = models.NodeSyncError
+		updates["sync_error"] = errPush.Error()
+	} else {
+		updates["config_version"] = bundle.Version
+		updates["sync_error"] = ""
+		if node.AppliedVersion == bundle.Version {
+			updates["sync_status"] = models.NodeSyncApplied
+		} else {
+			updates["sync_status"] = models.NodeSyncPushed
+		}
+	}
+	if errUpdate := db.WithContext(ctx).Model(&models.Node{}).Where("id = ?", node.ID).Updates(updates).Error; errUpdate != nil {
+		return errors.Join(errPush, errUpdate)
+	}
+	return errPush
+}
+
+// deliverNodeConfig sends bundle to the node agent.
+func deliverNodeConfig(ctx context.Context, node *models.Node, bundle *nodeConfigBundle) error {
+	body, errMarshal := json.Marshal(bundle)
+	if errMarshal != nil {
+		return fmt.Errorf("encode node config: %w", errMarshal)
+	}
+	req, errReq := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSpace(node.PushURL), bytes.NewReader(body))
+	if errReq != nil {
+		return errReq
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+node.Token)
+	resp, errDo := nodePushClient.Do(req)
+	if errDo != nil {
+		return errDo
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("node responded %s", resp.Status)
+	}
+	return nil
+}
+
+// pushNodeConfigs pushes the current config to every enabled node with a push URL.
+// Nodes without a push URL pick up changes on their next pull.
+func pushNodeConfigs(ctx context.Context, db *gorm.DB) {
+	if db == nil {
+		return
+	}
+	var nodes []models.Node
+	if errFind := db.WithContext(ctx).
+		Where("is_enabled = ? AND push_url <> ?", true, "").
+		Order("id ASC").
+		Find(&nodes).Error; errFind != nil {
+		log.WithError(errFind).Warn("nodes: load push targets failed")
+		return
+	}
+	for i := range nodes {
+		if errPush := pushNodeConfig(ctx, db, &nodes[i]); errPush != nil {
+			log.WithError(errPush).Warnf("nodes: push config to %s failed", nodes[i].Name)
+		}
+	}
+}
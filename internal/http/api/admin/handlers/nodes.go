@@ -0,0 +1,414 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/environments"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
+	"gorm.io/gorm"
+)
+
+// NodeHandler manages remote proxy nodes and serves their config sync endpoints.
+type NodeHandler struct {
+	db *gorm.DB // Database handle for node records.
+}
+
+// NewNodeHandler constructs a node handler with a database dependency.
+func NewNodeHandler(db *gorm.DB) *NodeHandler {
+	return &NodeHandler{db: db}
+}
+
+// createNodeRequest captures the payload for registering a node.
+type createNodeRequest struct {
+	Name        string `json:"name"`        // Display name.
+	Environment string `json:"environment"` // Optional environment tag.
+	PushURL     string `json:"push_url"`    // Optional agent push endpoint.
+	IsEnabled   *bool  `json:"is_enabled"`  // Optional enabled state.
+}
+
+// updateNodeRequest captures optional fields for node updates.
+type updateNodeRequest struct {
+	Name        *string `json:"name"`        // Optional display name.
+	Environment *string `json:"environment"` // Optional environment tag.
+	PushURL     *string `json:"push_url"`    // Optional agent push endpoint.
+	IsEnabled   *bool   `json:"is_enabled"`  // Optional enabled state.
+}
+
+// nodeStatusRequest captures a sync status report from a node.
+type nodeStatusRequest struct {
+	AppliedVersion string `json:"applied_version"` // Config version now running on the node.
+	AgentVersion   string `json:"agent_version"`   // Node software version.
+	Error          string `json:"error"`           // Apply error, empty on success.
+}
+
+// Create registers a node and returns its token once.
+func (h *NodeHandler) Create(c *gin.Context) {
+	var body createNodeRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	name := strings.TrimSpace(body.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing name"})
+		return
+	}
+	env, errEnv := normalizeNodeEnvironment(body.Environment)
+	if errEnv != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid environment"})
+		return
+	}
+	pushURL, errURL := normalizeNodePushURL(body.PushURL)
+	if errURL != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid push_url"})
+		return
+	}
+	token, errToken := security.GenerateNodeToken()
+	if errToken != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "generate token failed"})
+		return
+	}
+
+	now := time.Now().UTC()
+	row := models.Node{
+		Name:        name,
+		Token:       token,
+		Environment: env,
+		PushURL:     pushURL,
+		IsEnabled:   true,
+		SyncStatus:  models.NodeSyncPending,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if body.IsEnabled != nil {
+		row.IsEnabled = *body.IsEnabled
+	}
+	if errCreate := h.db.WithContext(c.Request.Context()).Create(&row).Error; errCreate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create node failed"})
+		return
+	}
+
+	out := nodeRow(&row)
+	out["token"] = token
+	c.JSON(http.StatusCreated, out)
+}
+
+// List returns registered nodes with their sync state.
+func (h *NodeHandler) List(c *gin.Context) {
+	var rows []models.Node
+	if errFind := h.db.WithContext(c.Request.Context()).Order("id ASC").Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list nodes failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, nodeRow(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"nodes": out})
+}
+
+// Update modifies node settings by ID.
+func (h *NodeHandler) Update(c *gin.Context) {
+	row, ok := h.findNode(c)
+	if !ok {
+		return
+	}
+	var body updateNodeRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	if body.Name != nil {
+		name := strings.TrimSpace(*body.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing name"})
+			return
+		}
+		row.Name = name
+	}
+	if body.Environment != nil {
+		env, errEnv := normalizeNodeEnvironment(*body.Environment)
+		if errEnv != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid environment"})
+			return
+		}
+		row.Environment = env
+	}
+	if body.PushURL != nil {
+		pushURL, errURL := normalizeNodePushURL(*body.PushURL)
+		if errURL != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid push_url"})
+			return
+		}
+		row.PushURL = pushURL
+	}
+	if body.IsEnabled != nil {
+		row.IsEnabled = *body.IsEnabled
+	}
+	row.UpdatedAt = time.Now().UTC()
+	if errSave := h.db.WithContext(c.Request.Context()).Save(row).Error; errSave != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update node failed"})
+		return
+	}
+	c.JSON(http.StatusOK, nodeRow(row))
+}
+
+// Delete removes a node by ID.
+func (h *NodeHandler) Delete(c *gin.Context) {
+	id, errID := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if errDelete := h.db.WithContext(c.Request.Context()).Delete(&models.Node{}, "id = ?", id).Error; errDelete != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete node failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": true})
+}
+
+// RotateToken replaces a node's token and returns the new one once.
+func (h *NodeHandler) RotateToken(c *gin.Context) {
+	row, ok := h.findNode(c)
+	if !ok {
+		return
+	}
+	token, errToken := security.GenerateNodeToken()
+	if errToken != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "generate token failed"})
+		return
+	}
+	row.Token = token
+	row.UpdatedAt = time.Now().UTC()
+	if errSave := h.db.WithContext(c.Request.Context()).Save(row).Error; errSave != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "rotate node token failed"})
+		return
+	}
+	out := nodeRow(row)
+	out["token"] = token
+	c.JSON(http.StatusOK, out)
+}
+
+// Push sends the current config to a node's push URL immediately.
+func (h *NodeHandler) Push(c *gin.Context) {
+	row, ok := h.findNode(c)
+	if !ok {
+		return
+	}
+	if !row.IsEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "node is disabled"})
+		return
+	}
+	if row.PushURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "node has no push_url"})
+		return
+	}
+	errPush := pushNodeConfig(c.Request.Context(), h.db, row)
+	if errFind := h.db.WithContext(c.Request.Context()).First(row, "id = ?", row.ID).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "fetch node failed"})
+		return
+	}
+	if errPush != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "push config failed", "node": nodeRow(row)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"pushed": true, "node": nodeRow(row)})
+}
+
+// PullConfig serves the synced config to an authenticated node. Nodes send the version they
+// hold in If-None-Match and receive 304 when it is still current.
+func (h *NodeHandler) PullConfig(c *gin.Context) {
+	node, ok := h.authenticateNode(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	bundle, errBuild := buildNodeConfigBundle(ctx, h.db, node.Environment)
+	if errBuild != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "build config failed"})
+		return
+	}
+
+	now := time.Now().UTC()
+	updates := map[string]any{"last_seen_at": now}
+	notModified := strings.Trim(strings.TrimSpace(c.GetHeader("If-None-Match")), `"`) == bundle.Version
+	if !notModified && node.ConfigVersion != bundle.Version {
+		updates["config_version"] = bundle.Version
+		updates["last_sync_at"] = now
+		updates["sync_error"] = ""
+		if node.AppliedVersion == bundle.Version {
+			updates["sync_status"] = models.NodeSyncApplied
+		} else {
+			updates["sync_status"] = models.NodeSyncPulled
+		}
+	}
+	if errUpdate := h.db.WithContext(ctx).Model(&models.Node{}).Where("id = ?", node.ID).UpdateColumns(updates).Error; errUpdate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update node failed"})
+		return
+	}
+
+	c.Header("ETag", `"`+bundle.Version+`"`)
+	if notModified {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.JSON(http.StatusOK, bundle)
+}
+
+// ReportStatus records the config version and software version a node is running.
+func (h *NodeHandler) ReportStatus(c *gin.Context) {
+	node, ok := h.authenticateNode(c)
+	if !ok {
+		return
+	}
+	var body nodeStatusRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+
+	now := time.Now().UTC()
+	updates := map[string]any{
+		"last_seen_at": now,
+		"last_sync_at": now,
+	}
+	if agentVersion := strings.TrimSpace(body.AgentVersion); agentVersion != "" {
+		updates["agent_version"] = truncateNodeField(agentVersion, 64)
+	}
+	if applyErr := strings.TrimSpace(body.Error); applyErr != "" {
+		updates["sync_status"] = models.NodeSyncError
+		updates["sync_error"] = truncateNodeField(applyErr, 2048)
+	} else {
+		applied := strings.TrimSpace(body.AppliedVersion)
+		if applied == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing applied_version"})
+			return
+		}
+		updates["applied_version"] = truncateNodeField(applied, 64)
+		updates["sync_status"] = models.NodeSyncApplied
+		updates["sync_error"] = ""
+	}
+	if errUpdate := h.db.WithContext(c.Request.Context()).Model(&models.Node{}).Where("id = ?", node.ID).UpdateColumns(updates).Error; errUpdate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update node failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// findNode loads the node named by the id path parameter, writing an error response on failure.
+func (h *NodeHandler) findNode(c *gin.Context) (*models.Node, bool) {
+	id, errID := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return nil, false
+	}
+	var row models.Node
+	if errFind := h.db.WithContext(c.Request.Context()).First(&row, "id = ?", id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "node not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "fetch node failed"})
+		return nil, false
+	}
+	return &row, true
+}
+
+// authenticateNode resolves the enabled node owning the request's bearer token.
+func (h *NodeHandler) authenticateNode(c *gin.Context) (*models.Node, bool) {
+	header := strings.TrimSpace(c.GetHeader("Authorization"))
+	if !strings.HasPrefix(header, "Bearer ") {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing node token"})
+		return nil, false
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	if token == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing node token"})
+		return nil, false
+	}
+	var row models.Node
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Where("token = ? AND is_enabled = ?", token, true).
+		First(&row).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid node token"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "fetch node failed"})
+		return nil, false
+	}
+	return &row, true
+}
+
+// normalizeNodeEnvironment validates a single environment tag; empty is allowed.
+func normalizeNodeEnvironment(value string) (string, error) {
+	tags, errNormalize := environments.Normalize([]string{value})
+	if errNormalize != nil {
+		return "", errNormalize
+	}
+	if len(tags) == 0 {
+		return "", nil
+	}
+	return tags[0], nil
+}
+
+// normalizeNodePushURL validates an optional http(s) push endpoint.
+func normalizeNodePushURL(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	parsed, errParse := url.Parse(value)
+	if errParse != nil {
+		return "", errParse
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", errors.New("push url must be http or https")
+	}
+	return parsed.String(), nil
+}
+
+// truncateNodeField bounds node-reported strings before storage.
+func truncateNodeField(value string, limit int) string {
+	if len(value) <= limit {
+		return value
+	}
+	return value[:limit]
+}
+
+// nodeRow formats a node for API responses without its token.
+func nodeRow(row *models.Node) gin.H {
+	return gin.H{
+		"id":              row.ID,
+		"name":            row.Name,
+		"environment":     row.Environment,
+		"push_url":        row.PushURL,
+		"mode":            nodeMode(row),
+		"is_enabled":      row.IsEnabled,
+		"config_version":  row.ConfigVersion,
+		"applied_version": row.AppliedVersion,
+		"agent_version":   row.AgentVersion,
+		"in_sync":         row.ConfigVersion != "" && row.ConfigVersion == row.AppliedVersion,
+		"sync_status":     row.SyncStatus,
+		"sync_error":      row.SyncError,
+		"last_seen_at":    row.LastSeenAt,
+		"last_sync_at":    row.LastSyncAt,
+		"created_at":      row.CreatedAt,
+		"updated_at":      row.UpdatedAt,
+	}
+}
+
+// nodeMode reports whether the node receives pushed config or pulls it.
+func nodeMode(row *models.Node) string {
+	if row.PushURL != "" {
+		return "push"
+	}
+	return "pull"
+}
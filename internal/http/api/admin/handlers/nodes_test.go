@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func setupNodeTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:nodes_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.AutoMigrate(&models.ProviderAPIKey{}, &models.ModelMapping{}, &models.Auth{}, &models.Node{}); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return db
+}
+
+func seedNodeCredentials(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	keys := []models.ProviderAPIKey{
+		{Provider: providerCodex, Name: "shared", APIKey: "sk-shared", IsEnabled: true, Environments: datatypes.JSON(`[]`)},
+		{Provider: providerCodex, Name: "prod", APIKey: "sk-prod", IsEnabled: true, Environments: datatypes.JSON(`["prod"]`)},
+		{Provider: providerCodex, Name: "staging", APIKey: "sk-staging", IsEnabled: true, Environments: datatypes.JSON(`["staging"]`)},
+	}
+	if errCreate := db.Create(&keys).Error; errCreate != nil {
+		t.Fatalf("create provider keys: %v", errCreate)
+	}
+	auths := []models.Auth{
+		{Key: "prod-auth", Content: datatypes.JSON(`{"type":"codex"}`), IsAvailable: true, Environments: datatypes.JSON(`["prod"]`)},
+		{Key: "staging-auth", Content: datatypes.JSON(`{"type":"codex"}`), IsAvailable: true, Environments: datatypes.JSON(`["staging"]`)},
+	}
+	if errCreate := db.Create(&auths).Error; errCreate != nil {
+		t.Fatalf("create auths: %v", errCreate)
+	}
+}
+
+func TestNodePullConfigFiltersByEnvironmentAndHonorsVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupNodeTestDB(t)
+	seedNodeCredentials(t, db)
+	node := models.Node{Name: "edge-1", Token: "cpn_test", Environment: "prod", IsEnabled: true, SyncStatus: models.NodeSyncPending}
+	if errCreate := db.Create(&node).Error; errCreate != nil {
+		t.Fatalf("create node: %v", errCreate)
+	}
+
+	h := NewNodeHandler(db)
+	engine := gin.New()
+	engine.GET("/v0/node/config", h.PullConfig)
+	engine.POST("/v0/node/status", h.ReportStatus)
+
+	unauthorized := httptest.NewRecorder()
+	engine.ServeHTTP(unauthorized, httptest.NewRequest(http.MethodGet, "/v0/node/config", nil))
+	if unauthorized.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", unauthorized.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v0/node/config", nil)
+	req.Header.Set("Authorization", "Bearer cpn_test")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var bundle nodeConfigBundle
+	if errDecode := json.Unmarshal(rec.Body.Bytes(), &bundle); errDecode != nil {
+		t.Fatalf("decode bundle: %v", errDecode)
+	}
+	if bundle.Version == "" || rec.Header().Get("ETag") != `"`+bundle.Version+`"` {
+		t.Fatalf("expected versioned bundle, got version %q etag %q", bundle.Version, rec.Header().Get("ETag"))
+	}
+	gotKeys := make([]string, 0, len(bundle.Config.CodexKey))
+	for _, key := range bundle.Config.CodexKey {
+		gotKeys = append(gotKeys, key.APIKey)
+	}
+	if len(gotKeys) != 2 || gotKeys[0] != "sk-shared" || gotKeys[1] != "sk-prod" {
+		t.Fatalf("expected shared and prod codex keys, got %v", gotKeys)
+	}
+	if len(bundle.AuthFiles) != 1 || bundle.AuthFiles[0].Name != "prod-auth.json" {
+		t.Fatalf("expected only the prod auth file, got %+v", bundle.AuthFiles)
+	}
+
+	cached := httptest.NewRequest(http.MethodGet, "/v0/node/config", nil)
+	cached.Header.Set("Authorization", "Bearer cpn_test")
+	cached.Header.Set("If-None-Match", `"`+bundle.Version+`"`)
+	cachedRec := httptest.NewRecorder()
+	engine.ServeHTTP(cachedRec, cached)
+	if cachedRec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for current version, got %d", cachedRec.Code)
+	}
+
+	status := httptest.NewRequest(http.MethodPost, "/v0/node/status", bytes.NewBufferString(`{"applied_version":"`+bundle.Version+`","agent_version":"6.1.0"}`))
+	status.Header.Set("Authorization", "Bearer cpn_test")
+	status.Header.Set("Content-Type", "application/json")
+	statusRec := httptest.NewRecorder()
+	engine.ServeHTTP(statusRec, status)
+	if statusRec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for status report, got %d: %s", statusRec.Code, statusRec.Body.String())
+	}
+
+	var stored models.Node
+	if errFind := db.First(&stored, node.ID).Error; errFind != nil {
+		t.Fatalf("reload node: %v", errFind)
+	}
+	if stored.ConfigVersion != bundle.Version || stored.AppliedVersion != bundle.Version {
+		t.Fatalf("expected node in sync at %s, got config=%s applied=%s", bundle.Version, stored.ConfigVersion, stored.AppliedVersion)
+	}
+	if stored.SyncStatus != models.NodeSyncApplied || stored.AgentVersion != "6.1.0" || stored.LastSeenAt == nil {
+		t.Fatalf("unexpected node status: %+v", stored)
+	}
+}
+
+func TestPushNodeConfigRecordsDelivery(t *testing.T) {
+	db := setupNodeTestDB(t)
+	seedNodeCredentials(t, db)
+
+	var gotAuth string
+	var gotBundle nodeConfigBundle
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &gotBundle)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer agent.Close()
+
+	node := models.Node{Name: "edge-2", Token: "cpn_push", Environment: "staging", PushURL: agent.URL, IsEnabled: true, SyncStatus: models.NodeSyncPending}
+	if errCreate := db.Create(&node).Error; errCreate != nil {
+		t.Fatalf("create node: %v", errCreate)
+	}
+
+	if errPush := pushNodeConfig(context.Background(), db, &node); errPush != nil {
+		t.Fatalf("pushNodeConfig: %v", errPush)
+	}
+	if gotAuth != "Bearer cpn_push" {
+		t.Fatalf("expected node token on push, got %q", gotAuth)
+	}
+	if len(gotBundle.AuthFiles) != 1 || gotBundle.AuthFiles[0].Name != "staging-auth.json" {
+		t.Fatalf("expected only the staging auth file, got %+v", gotBundle.AuthFiles)
+	}
+
+	var stored models.Node
+	if errFind := db.First(&stored, node.ID).Error; errFind != nil {
+		t.Fatalf("reload node: %v", errFind)
+	}
+	if stored.SyncStatus != models.NodeSyncPushed || stored.ConfigVersion != gotBundle.Version || stored.LastSyncAt == nil {
+		t.Fatalf("unexpected node status after push: %+v", stored)
+	}
+
+	agent.Close()
+	if errPush := pushNodeConfig(context.Background(), db, &stored); errPush == nil {
+		t.Fatalf("expected push to a closed agent to fail")
+	}
+	if errFind := db.First(&stored, node.ID).Error; errFind != nil {
+		t.Fatalf("reload node: %v", errFind)
+	}
+	if stored.SyncStatus != models.NodeSyncError || stored.SyncError == "" {
+		t.Fatalf("expected error status after failed push, got %+v", stored)
+	}
+}
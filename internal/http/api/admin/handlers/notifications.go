@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// NotificationHandler lists admin notifications and tracks which ones were read.
+type NotificationHandler struct {
+	db *gorm.DB // Database handle for notification records.
+}
+
+// NewNotificationHandler constructs a notification handler.
+func NewNotificationHandler(db *gorm.DB) *NotificationHandler {
+	return &NotificationHandler{db: db}
+}
+
+// List returns notifications, newest first, optionally filtered by type, severity and unread state.
+func (h *NotificationHandler) List(c *gin.Context) {
+	ctx := c.Request.Context()
+	q := h.db.WithContext(ctx).Model(&models.AdminNotification{})
+	if eventType := strings.TrimSpace(c.Query("type")); eventType != "" {
+		q = q.Where("event_type = ?", eventType)
+	}
+	if severity := strings.TrimSpace(c.Query("severity")); severity != "" {
+		q = q.Where("severity = ?", severity)
+	}
+	if unread, _ := strconv.ParseBool(strings.TrimSpace(c.Query("unread"))); unread {
+		q = q.Where("read_at IS NULL")
+	}
+
+	var rows []models.AdminNotification
+	if errFind := q.Order("occurred_at DESC").Order("id DESC").Limit(500).Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list notifications failed"})
+		return
+	}
+	var unreadCount int64
+	if errCount := h.db.WithContext(ctx).Model(&models.AdminNotification{}).
+		Where("read_at IS NULL").
+		Count(&unreadCount).Error; errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "count notifications failed"})
+		return
+	}
+
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatAdminNotification(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"notifications": out, "unread": unreadCount})
+}
+
+// updateNotificationRequest defines the request body for changing read state.
+type updateNotificationRequest struct {
+	Read *bool    `json:"read"` // True marks read, false marks unread.
+	IDs  []uint64 `json:"ids"`  // Notifications to update in bulk; empty means all unread.
+}
+
+// readStateUpdates returns the column updates that apply the requested read state.
+func readStateUpdates(c *gin.Context, read bool) map[string]any {
+	if !read {
+		return map[string]any{"read_at": nil, "read_by": ""}
+	}
+	return map[string]any{
+		"read_at": time.Now().UTC(),
+		"read_by": "admin:" + c.GetString("adminUsername"),
+	}
+}
+
+// Update marks a single notification read or unread.
+func (h *NotificationHandler) Update(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var body updateNotificationRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	if body.Read == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "read is required"})
+		return
+	}
+	ctx := c.Request.Context()
+	var row models.AdminNotification
+	if errFind := h.db.WithContext(ctx).First(&row, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query notification failed"})
+		return
+	}
+	// Re-reading an already read notification keeps who read it first.
+	if *body.Read != (row.ReadAt != nil) {
+		if errUpdate := h.db.WithContext(ctx).Model(&row).Updates(readStateUpdates(c, *body.Read)).Error; errUpdate != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "update notification failed"})
+			return
+		}
+		if errReload := h.db.WithContext(ctx).First(&row, id).Error; errReload != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "query notification failed"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, formatAdminNotification(&row))
+}
+
+// UpdateAll marks the listed notifications, or every notification, read or unread.
+func (h *NotificationHandler) UpdateAll(c *gin.Context) {
+	var body updateNotificationRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	if body.Read == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "read is required"})
+		return
+	}
+	q := h.db.WithContext(c.Request.Context()).Model(&models.AdminNotification{})
+	if *body.Read {
+		q = q.Where("read_at IS NULL")
+	} else {
+		q = q.Where("read_at IS NOT NULL")
+	}
+	if len(body.IDs) > 0 {
+		q = q.Where("id IN ?", body.IDs)
+	}
+	res := q.Updates(readStateUpdates(c, *body.Read))
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update notifications failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"updated": res.RowsAffected})
+}
+
+// formatAdminNotification converts a notification row into a response payload.
+func formatAdminNotification(row *models.AdminNotification) gin.H {
+	var data map[string]any
+	if len(row.Data) > 0 {
+		_ = json.Unmarshal(row.Data, &data)
+	}
+	return gin.H{
+		"id":          row.ID,
+		"event_id":    row.EventID,
+		"event_type":  row.EventType,
+		"severity":    row.Severity,
+		"subject":     row.Subject,
+		"message":     row.Message,
+		"data":        data,
+		"read_at":     row.ReadAt,
+		"read_by":     row.ReadBy,
+		"occurred_at": row.OccurredAt,
+		"created_at":  row.CreatedAt,
+	}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestNotifications_ListAndUpdateReadState(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dsn := fmt.Sprintf("file:admin_notifications_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.AutoMigrate(&models.AdminNotification{}); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	now := time.Now().UTC()
+	rows := []models.AdminNotification{
+		{EventID: "evt-1", EventType: "usage.anomaly", Severity: "critical", Subject: "api_key:1", OccurredAt: now.Add(-2 * time.Minute)},
+		{EventID: "evt-2", EventType: "billing.balance_insufficient", Severity: "warning", Subject: "user:1", OccurredAt: now.Add(-time.Minute)},
+		{EventID: "evt-3", EventType: "provider.health_check_failed", Severity: "warning", Subject: "api_key:2", OccurredAt: now},
+	}
+	if errCreate := db.Create(&rows).Error; errCreate != nil {
+		t.Fatalf("create notifications: %v", errCreate)
+	}
+
+	h := NewNotificationHandler(db)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("adminUsername", "root"); c.Next() })
+	router.GET("/v0/admin/notifications", h.List)
+	router.PUT("/v0/admin/notifications", h.UpdateAll)
+	router.PUT("/v0/admin/notifications/:id", h.Update)
+
+	type listResponse struct {
+		Notifications []struct {
+			ID       uint64  `json:"id"`
+			Severity string  `json:"severity"`
+			ReadBy   string  `json:"read_by"`
+			ReadAt   *string `json:"read_at"`
+		} `json:"notifications"`
+		Unread int64 `json:"unread"`
+	}
+	list := func(query string) listResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v0/admin/notifications"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d body=%s", w.Code, w.Body.String())
+		}
+		var resp listResponse
+		if errDecode := json.Unmarshal(w.Body.Bytes(), &resp); errDecode != nil {
+			t.Fatalf("decode list: %v", errDecode)
+		}
+		return resp
+	}
+	put := func(path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	resp := list("")
+	if resp.Unread != 3 || len(resp.Notifications) != 3 || resp.Notifications[0].ID != rows[2].ID {
+		t.Fatalf("unexpected list %+v", resp)
+	}
+	if resp = list("?severity=critical"); len(resp.Notifications) != 1 || resp.Notifications[0].ID != rows[0].ID {
+		t.Fatalf("unexpected severity filter %+v", resp)
+	}
+
+	path := "/v0/admin/notifications/" + strconv.FormatUint(rows[0].ID, 10)
+	if w := put(path, `{}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without read, got %d", w.Code)
+	}
+	if w := put(path, `{"read":true}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 marking read, got %d body=%s", w.Code, w.Body.String())
+	}
+	if resp = list("?unread=true"); resp.Unread != 2 || len(resp.Notifications) != 2 {
+		t.Fatalf("expected two unread notifications, got %+v", resp)
+	}
+	if w := put(path, `{"read":false}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 marking unread, got %d body=%s", w.Code, w.Body.String())
+	}
+	if w := put("/v0/admin/notifications/999", `{"read":true}`); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for missing notification, got %d", w.Code)
+	}
+
+	if w := put("/v0/admin/notifications", `{"read":true}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 marking all read, got %d body=%s", w.Code, w.Body.String())
+	}
+	resp = list("")
+	if resp.Unread != 0 {
+		t.Fatalf("expected no unread notifications, got %d", resp.Unread)
+	}
+	for _, n := range resp.Notifications {
+		if n.ReadAt == nil || n.ReadBy != "admin:root" {
+			t.Fatalf("expected notification read by admin:root, got %+v", n)
+		}
+	}
+}
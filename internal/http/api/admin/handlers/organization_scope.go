@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/stats"
+	"gorm.io/gorm"
+)
+
+// organizationScope returns the organization an admin request acts in, as set by the admin
+// auth middleware. False means the global view of a global admin.
+func organizationScope(c *gin.Context) (uint64, bool) {
+	value, ok := c.Get("adminOrganizationID")
+	if !ok {
+		return 0, false
+	}
+	id, okID := value.(uint64)
+	return id, okID && id != 0
+}
+
+// organizationScopeValue returns the request organization as a nullable column value.
+func organizationScopeValue(c *gin.Context) *uint64 {
+	if id, ok := organizationScope(c); ok {
+		return &id
+	}
+	return nil
+}
+
+// scopeToOrganization restricts q to rows owned by the request organization.
+func scopeToOrganization(c *gin.Context, q *gorm.DB) *gorm.DB {
+	if id, ok := organizationScope(c); ok {
+		return q.Where("organization_id = ?", id)
+	}
+	return q
+}
+
+// scopeToOrganizationOrShared restricts q to rows owned by the request organization or by none.
+func scopeToOrganizationOrShared(c *gin.Context, q *gorm.DB) *gorm.DB {
+	if id, ok := organizationScope(c); ok {
+		return q.Where("organization_id = ? OR organization_id IS NULL", id)
+	}
+	return q
+}
+
+// scopeToOrganizationUsers restricts q to rows whose column references a user of the request
+// organization.
+func scopeToOrganizationUsers(c *gin.Context, db *gorm.DB, q *gorm.DB, column string) *gorm.DB {
+	if id, ok := organizationScope(c); ok {
+		return q.Where(column+" IN (?)", db.Model(&models.User{}).Select("id").Where("organization_id = ?", id))
+	}
+	return q
+}
+
+// organizationStatsScope returns the stats scope of the request organization.
+func organizationStatsScope(c *gin.Context) stats.Scope {
+	id, _ := organizationScope(c)
+	return stats.Scope{OrganizationID: id}
+}
+
+// checkOrganizationUser answers 404 and returns false when userID lies outside the request
+// organization.
+func checkOrganizationUser(c *gin.Context, db *gorm.DB, userID uint64) bool {
+	if _, ok := organizationScope(c); !ok {
+		return true
+	}
+	var count int64
+	q := db.WithContext(c.Request.Context()).Model(&models.User{}).Where("id = ?", userID)
+	if errCount := scopeToOrganization(c, q).Count(&count).Error; errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return false
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return false
+	}
+	return true
+}
+
+// checkOrganizationUserGroups answers 400 and returns false when a group is neither shared nor
+// owned by the request organization.
+func checkOrganizationUserGroups(c *gin.Context, db *gorm.DB, ids []uint64) bool {
+	if _, ok := organizationScope(c); !ok || len(ids) == 0 {
+		return true
+	}
+	var count int64
+	q := db.WithContext(c.Request.Context()).Model(&models.UserGroup{}).Where("id IN ?", ids)
+	if errCount := scopeToOrganizationOrShared(c, q).Count(&count).Error; errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return false
+	}
+	if count != int64(len(ids)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown user group"})
+		return false
+	}
+	return true
+}
+
+// checkOrganizationExists answers 400 and returns false when id names no organization. A nil id
+// passes.
+func checkOrganizationExists(c *gin.Context, db *gorm.DB, id *uint64) bool {
+	if id == nil {
+		return true
+	}
+	var count int64
+	if errCount := db.WithContext(c.Request.Context()).Model(&models.Organization{}).Where("id = ?", *id).Count(&count).Error; errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return false
+	}
+	if count == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown organization"})
+		return false
+	}
+	return true
+}
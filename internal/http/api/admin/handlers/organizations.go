@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
+	"gorm.io/gorm"
+)
+
+// Organization scope errors returned by AdminOrganizationScope.
+var (
+	// ErrOrganizationMismatch reports a token naming another organization than the admin's own.
+	ErrOrganizationMismatch = errors.New("organization mismatch")
+	// ErrOrganizationNotFound reports a token naming a deleted organization.
+	ErrOrganizationNotFound = errors.New("organization not found")
+	// ErrOrganizationDisabled reports an organization admin of a disabled organization.
+	ErrOrganizationDisabled = errors.New("organization disabled")
+)
+
+// AdminOrganizationScope resolves the organization an admin session acts in. Organization
+// admins are confined to their own organization; global admins act in the one selected in the
+// token, or globally when the token names none. Zero means the global view.
+func AdminOrganizationScope(ctx context.Context, db *gorm.DB, admin models.Admin, claimed uint64) (uint64, error) {
+	id := claimed
+	if admin.OrganizationID != nil {
+		if claimed != 0 && claimed != *admin.OrganizationID {
+			return 0, ErrOrganizationMismatch
+		}
+		id = *admin.OrganizationID
+	}
+	if id == 0 {
+		return 0, nil
+	}
+	var organization models.Organization
+	if errFind := db.WithContext(ctx).Select("id", "active").First(&organization, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return 0, ErrOrganizationNotFound
+		}
+		return 0, errFind
+	}
+	if admin.OrganizationID != nil && !organization.Active {
+		return 0, ErrOrganizationDisabled
+	}
+	return id, nil
+}
+
+// OrganizationHandler manages organizations and the organization selected by global admins.
+type OrganizationHandler struct {
+	db     *gorm.DB         // Database handle.
+	jwtCfg config.JWTConfig // Signs tokens for organization selection.
+}
+
+// NewOrganizationHandler constructs an organization handler.
+func NewOrganizationHandler(db *gorm.DB, jwtCfg config.JWTConfig) *OrganizationHandler {
+	return &OrganizationHandler{db: db, jwtCfg: jwtCfg}
+}
+
+// organizationRequest captures the payload for creating or updating an organization.
+type organizationRequest struct {
+	Name        *string `json:"name"`        // Unique display name.
+	Description *string `json:"description"` // Free-form description.
+	Active      *bool   `json:"active"`      // Whether organization admins can sign in.
+}
+
+// Create adds an organization.
+func (h *OrganizationHandler) Create(c *gin.Context) {
+	var body organizationRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	if body.Name == nil || strings.TrimSpace(*body.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing name"})
+		return
+	}
+	organization := models.Organization{Name: strings.TrimSpace(*body.Name), Active: true}
+	if body.Description != nil {
+		organization.Description = strings.TrimSpace(*body.Description)
+	}
+	if errCreate := h.db.WithContext(c.Request.Context()).Create(&organization).Error; errCreate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create organization failed"})
+		return
+	}
+	if body.Active != nil && !*body.Active {
+		// Active defaults to true in the schema, so a false value is written separately.
+		if errUpdate := h.db.WithContext(c.Request.Context()).Model(&organization).Update("active", false).Error; errUpdate != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "create organization failed"})
+			return
+		}
+	}
+	c.JSON(http.StatusCreated, h.formatOrganization(c, organization))
+}
+
+// List returns every organization with its member counts.
+func (h *OrganizationHandler) List(c *gin.Context) {
+	var rows []models.Organization
+	if errFind := h.db.WithContext(c.Request.Context()).Order("name ASC").Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list organizations failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, h.formatOrganization(c, row))
+	}
+	c.JSON(http.StatusOK, gin.H{"organizations": out})
+}
+
+// Get returns one organization.
+func (h *OrganizationHandler) Get(c *gin.Context) {
+	organization, ok := h.find(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, h.formatOrganization(c, organization))
+}
+
+// Current returns the organization the session acts in, or null for the global view.
+func (h *OrganizationHandler) Current(c *gin.Context) {
+	id, ok := organizationScope(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{"organization": nil})
+		return
+	}
+	var organization models.Organization
+	if errFind := h.db.WithContext(c.Request.Context()).First(&organization, id).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"organization": h.formatOrganization(c, organization)})
+}
+
+// Update changes an organization.
+func (h *OrganizationHandler) Update(c *gin.Context) {
+	organization, ok := h.find(c)
+	if !ok {
+		return
+	}
+	var body organizationRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	updates := map[string]any{"updated_at": time.Now().UTC()}
+	if body.Name != nil {
+		name := strings.TrimSpace(*body.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+			return
+		}
+		updates["name"] = name
+	}
+	if body.Description != nil {
+		updates["description"] = strings.TrimSpace(*body.Description)
+	}
+	if body.Active != nil {
+		updates["active"] = *body.Active
+	}
+	if errUpdate := h.db.WithContext(c.Request.Context()).Model(&organization).Updates(updates).Error; errUpdate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// Delete removes an empty organization. Users, user groups and admins must be moved or
+// deleted first.
+func (h *OrganizationHandler) Delete(c *gin.Context) {
+	organization, ok := h.find(c)
+	if !ok {
+		return
+	}
+	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		for _, model := range []any{&models.User{}, &models.UserGroup{}, &models.Admin{}} {
+			var count int64
+			if errCount := tx.Model(model).Where("organization_id = ?", organization.ID).Count(&count).Error; errCount != nil {
+				return errCount
+			}
+			if count > 0 {
+				return errOrganizationNotEmpty
+			}
+		}
+		return tx.Delete(&models.Organization{}, organization.ID).Error
+	})
+	if errTx != nil {
+		if errors.Is(errTx, errOrganizationNotEmpty) {
+			c.JSON(http.StatusConflict, gin.H{"error": "organization still has users, user groups or admins"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// errOrganizationNotEmpty aborts deleting an organization that still has members.
+var errOrganizationNotEmpty = errors.New("organization not empty")
+
+// selectOrganizationRequest captures the organization a global admin switches to.
+type selectOrganizationRequest struct {
+	OrganizationID uint64 `json:"organization_id"` // Zero returns to the global view.
+}
+
+// Select issues a token that scopes the global admin's session to one organization, or back
+// to the global view for organization_id 0. Organization admins cannot switch.
+func (h *OrganizationHandler) Select(c *gin.Context) {
+	var body selectOrganizationRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	adminID, okAdmin := readAdminIDFromContext(c)
+	if !okAdmin {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "admin not found"})
+		return
+	}
+	var admin models.Admin
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Select("id", "username", "organization_id").
+		First(&admin, adminID).Error; errFind != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "admin not found"})
+		return
+	}
+	if admin.OrganizationID != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "organization admins cannot switch organizations"})
+		return
+	}
+	if body.OrganizationID != 0 && !checkOrganizationExists(c, h.db, &body.OrganizationID) {
+		return
+	}
+	token, errToken := security.GenerateAdminToken(h.jwtCfg.Secret, admin.ID, admin.Username, body.OrganizationID, h.jwtCfg.Expiry)
+	if errToken != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"token": token, "organization_id": body.OrganizationID})
+}
+
+// find loads the organization named by the id path parameter, answering 400 or 404 on failure.
+func (h *OrganizationHandler) find(c *gin.Context) (models.Organization, bool) {
+	var organization models.Organization
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return organization, false
+	}
+	if errFind := h.db.WithContext(c.Request.Context()).First(&organization, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return organization, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return organization, false
+	}
+	return organization, true
+}
+
+// formatOrganization renders an organization with its member counts.
+func (h *OrganizationHandler) formatOrganization(c *gin.Context, organization models.Organization) gin.H {
+	counts := make(map[string]int64, 3)
+	for name, model := range map[string]any{"users": &models.User{}, "user_groups": &models.UserGroup{}, "admins": &models.Admin{}} {
+		var count int64
+		h.db.WithContext(c.Request.Context()).Model(model).Where("organization_id = ?", organization.ID).Count(&count)
+		counts[name] = count
+	}
+	return gin.H{
+		"id":          organization.ID,
+		"name":        organization.Name,
+		"description": organization.Description,
+		"active":      organization.Active,
+		"users":       counts["users"],
+		"user_groups": counts["user_groups"],
+		"admins":      counts["admins"],
+		"created_at":  organization.CreatedAt,
+		"updated_at":  organization.UpdatedAt,
+	}
+}
@@ -21,11 +21,12 @@ func (h *PermissionHandler) List(c *gin.Context) {
 	out := make([]gin.H, 0, len(defs))
 	for _, def := range defs {
 		out = append(out, gin.H{
-			"key":    def.Key,
-			"method": def.Method,
-			"path":   def.Path,
-			"label":  def.Label,
-			"module": def.Module,
+			"key":      def.Key,
+			"method":   def.Method,
+			"path":     def.Path,
+			"label":    def.Label,
+			"module":   def.Module,
+			"category": def.Category,
 		})
 	}
 	c.JSON(http.StatusOK, gin.H{"permissions": out})
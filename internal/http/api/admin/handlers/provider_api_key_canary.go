@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/canary"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// canaryReportDefaultHours is the report window for keys outside a canary.
+	canaryReportDefaultHours = 24
+	// canaryReportMaxHours bounds the usage scanned by a canary report.
+	canaryReportMaxHours = 720
+)
+
+// startCanaryRequest captures the share of traffic routed to a canary key.
+type startCanaryRequest struct {
+	Percent int `json:"percent"` // Share of the provider's traffic, 1-99.
+}
+
+// promoteCanaryRequest captures how a canary key is promoted.
+type promoteCanaryRequest struct {
+	RetireOthers bool `json:"retire_others"` // Disable the provider's other keys, swapping them out.
+}
+
+// StartCanary routes a share of the provider's traffic to the key so it can be compared with
+// the provider's other keys before taking over.
+func (h *ProviderAPIKeyHandler) StartCanary(c *gin.Context) {
+	id, ok := parseProviderKeyID(c)
+	if !ok {
+		return
+	}
+	var body startCanaryRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	row, errStart := canary.Start(c.Request.Context(), h.db, id, body.Percent, time.Now().UTC())
+	if errStart != nil {
+		respondCanaryError(c, errStart)
+		return
+	}
+	if errSync := h.syncSDKConfig(c.Request.Context()); errSync != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "sync config failed"})
+		return
+	}
+	c.JSON(http.StatusOK, formatProviderRow(row))
+}
+
+// PromoteCanary ends the key's canary so it serves like any other key, optionally retiring
+// the provider's other keys.
+func (h *ProviderAPIKeyHandler) PromoteCanary(c *gin.Context) {
+	id, ok := parseProviderKeyID(c)
+	if !ok {
+		return
+	}
+	var body promoteCanaryRequest
+	if c.Request.ContentLength != 0 {
+		if errBind := c.ShouldBindJSON(&body); errBind != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+			return
+		}
+	}
+	retired, errPromote := canary.Promote(c.Request.Context(), h.db, id, body.RetireOthers, time.Now().UTC())
+	if errPromote != nil {
+		respondCanaryError(c, errPromote)
+		return
+	}
+	if errSync := h.syncSDKConfig(c.Request.Context()); errSync != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "sync config failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"promoted": true, "retired_keys": retired})
+}
+
+// RollbackCanary ends the key's canary and disables the key.
+func (h *ProviderAPIKeyHandler) RollbackCanary(c *gin.Context) {
+	id, ok := parseProviderKeyID(c)
+	if !ok {
+		return
+	}
+	if errRollback := canary.Rollback(c.Request.Context(), h.db, id, time.Now().UTC()); errRollback != nil {
+		respondCanaryError(c, errRollback)
+		return
+	}
+	if errSync := h.syncSDKConfig(c.Request.Context()); errSync != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "sync config failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rolled_back": true})
+}
+
+// CanaryReport compares the key's error rate and latency with the provider's other keys since
+// its canary started, or over the last hours (default 24) when given or outside a canary.
+func (h *ProviderAPIKeyHandler) CanaryReport(c *gin.Context) {
+	id, ok := parseProviderKeyID(c)
+	if !ok {
+		return
+	}
+	var row models.ProviderAPIKey
+	if errFind := h.db.WithContext(c.Request.Context()).First(&row, id).Error; errFind != nil {
+		respondCanaryError(c, errFind)
+		return
+	}
+	now := time.Now().UTC()
+	from := now.Add(-canaryReportDefaultHours * time.Hour)
+	if row.CanaryStartedAt != nil {
+		from = row.CanaryStartedAt.UTC()
+	}
+	if raw := strings.TrimSpace(c.Query("hours")); raw != "" {
+		hours, errParse := strconv.Atoi(raw)
+		if errParse != nil || hours <= 0 || hours > canaryReportMaxHours {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid hours"})
+			return
+		}
+		from = now.Add(-time.Duration(hours) * time.Hour)
+	}
+	report, errReport := canary.Report(c.Request.Context(), h.db, &row, from, now)
+	if errReport != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "canary report failed"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// parseProviderKeyID reads the id path parameter, answering 400 when it is invalid.
+func parseProviderKeyID(c *gin.Context) (uint64, bool) {
+	id, errID := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	return id, true
+}
+
+// respondCanaryError maps canary errors to HTTP responses.
+func respondCanaryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+	case errors.Is(err, canary.ErrInvalidPercent):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, canary.ErrNotCanary), errors.Is(err, canary.ErrNoBaseline):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "canary update failed"})
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/environments"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+)
+
+// environmentSummary describes one environment and the credentials it receives.
+type environmentSummary struct {
+	Name             string `json:"name"`               // Environment tag.
+	Current          bool   `json:"current"`            // Whether this instance serves the environment.
+	ConfigPath       string `json:"config_path"`        // Config file receiving provider keys.
+	AuthDir          string `json:"auth_dir"`           // Directory receiving auth files.
+	ProviderKeyCount int    `json:"provider_key_count"` // Enabled provider keys in the environment.
+	AuthCount        int    `json:"auth_count"`         // Available auth files in the environment.
+}
+
+// ListEnvironments returns the configured environments and the tags in use.
+func (h *ProviderAPIKeyHandler) ListEnvironments(c *gin.Context) {
+	var keyRows []models.ProviderAPIKey
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Select("id", "environments").
+		Where("is_enabled = ?", true).
+		Find(&keyRows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list provider api keys failed"})
+		return
+	}
+	var authRows []models.Auth
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Select("id", "environments").
+		Where("is_available = ?", true).
+		Find(&authRows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list auth files failed"})
+		return
+	}
+
+	tagSet := make(map[string]struct{})
+	for i := range keyRows {
+		for _, tag := range environments.Decode(keyRows[i].Environments) {
+			tagSet[tag] = struct{}{}
+		}
+	}
+	for i := range authRows {
+		for _, tag := range environments.Decode(authRows[i].Environments) {
+			tagSet[tag] = struct{}{}
+		}
+	}
+
+	summaries := make([]environmentSummary, 0, len(h.environments.Targets)+1)
+	if current := h.environments.Current; current != "" {
+		tagSet[current] = struct{}{}
+		summaries = append(summaries, environmentSummary{
+			Name:             current,
+			Current:          true,
+			ConfigPath:       h.configPath,
+			ProviderKeyCount: len(environments.FilterProviderKeys(keyRows, current)),
+			AuthCount:        len(environments.FilterAuths(authRows, current)),
+		})
+	}
+	for _, target := range h.environments.Targets {
+		tagSet[target.Name] = struct{}{}
+		summaries = append(summaries, environmentSummary{
+			Name:             target.Name,
+			ConfigPath:       target.ConfigPath,
+			AuthDir:          target.AuthDir,
+			ProviderKeyCount: len(environments.FilterProviderKeys(keyRows, target.Name)),
+			AuthCount:        len(environments.FilterAuths(authRows, target.Name)),
+		})
+	}
+
+	tags := make([]string, 0, len(tagSet))
+	for tag := range tagSet {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	c.JSON(http.StatusOK, gin.H{
+		"current":      h.environments.Current,
+		"environments": summaries,
+		"tags":         tags,
+	})
+}
+
+// SyncEnvironments rewrites every environment's config file and auth dir from the database.
+func (h *ProviderAPIKeyHandler) SyncEnvironments(c *gin.Context) {
+	if errSync := h.syncSDKConfig(c.Request.Context()); errSync != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "sync config failed"})
+		return
+	}
+	results, errAuthSync := environments.SyncAuthDirs(c.Request.Context(), h.db, h.environments.Targets)
+	if errAuthSync != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "sync auth files failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"synced": true, "auth_dirs": results})
+}
@@ -15,8 +15,12 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy"
 	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
 	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/environments"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/headertemplate"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/proxyassign"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
@@ -52,15 +56,22 @@ var providerUniverseLoader = loadProviderUniverse
 
 // ProviderAPIKeyHandler manages admin CRUD for provider API keys.
 type ProviderAPIKeyHandler struct {
-	db         *gorm.DB // Database handle for provider keys.
-	configPath string   // Config path for SDK sync.
+	db           *gorm.DB                  // Database handle for provider keys.
+	configPath   string                    // Config path for SDK sync.
+	environments config.EnvironmentsConfig // Current environment and extra sync targets.
 }
 
 // NewProviderAPIKeyHandler constructs a handler and trims config path input.
 func NewProviderAPIKeyHandler(db *gorm.DB, configPath string) *ProviderAPIKeyHandler {
+	configPath = strings.TrimSpace(configPath)
+	var envCfg config.EnvironmentsConfig
+	if configPath != "" {
+		envCfg, _ = config.LoadEnvironmentsConfig(configPath)
+	}
 	return &ProviderAPIKeyHandler{
-		db:         db,
-		configPath: strings.TrimSpace(configPath),
+		db:           db,
+		configPath:   configPath,
+		environments: envCfg,
 	}
 }
 
@@ -91,6 +102,7 @@ type createProviderAPIKeyRequest struct {
 	Models         []modelAlias      `json:"models"`            // Model aliases.
 	ExcludedModels []string          `json:"excluded_models"`   // Excluded models.
 	APIKeyEntries  []apiKeyEntry     `json:"api_key_entries"`   // API key entries.
+	Environments   []string          `json:"environments"`      // Environment tags; empty shares the key with all.
 }
 
 // updateProviderAPIKeyRequest captures optional fields for updates.
@@ -108,6 +120,7 @@ type updateProviderAPIKeyRequest struct {
 	Models         *[]modelAlias      `json:"models"`            // Optional model aliases.
 	ExcludedModels *[]string          `json:"excluded_models"`   // Optional excluded models.
 	APIKeyEntries  *[]apiKeyEntry     `json:"api_key_entries"`   // Optional API key entries.
+	Environments   *[]string          `json:"environments"`      // Optional environment tags.
 }
 
 // Create validates and inserts a provider API key record, then syncs config.
@@ -126,7 +139,7 @@ func (h *ProviderAPIKeyHandler) Create(c *gin.Context) {
 
 	proxyURL := strings.TrimSpace(derefString(body.ProxyURL))
 	if proxyURL == "" && autoAssignProxyEnabled() {
-		assignedProxyURL, errAssignProxy := pickRandomProxyURL(c.Request.Context(), h.db)
+		assignedProxyURL, errAssignProxy := pickProxyURL(c.Request.Context(), h.db, proxyassign.Target{Provider: provider})
 		if errAssignProxy != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "auto assign proxy failed"})
 			return
@@ -161,6 +174,10 @@ func (h *ProviderAPIKeyHandler) Create(c *gin.Context) {
 		return
 	}
 
+	if errTemplate := validateHeaderTemplates(body.Headers); errTemplate != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errTemplate.Error()})
+		return
+	}
 	headersJSON, errHeaders := marshalJSON(body.Headers)
 	if errHeaders != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid headers"})
@@ -193,10 +210,17 @@ func (h *ProviderAPIKeyHandler) Create(c *gin.Context) {
 		return
 	}
 
+	envTags, errEnv := environments.Normalize(body.Environments)
+	if errEnv != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errEnv.Error()})
+		return
+	}
+
 	row.Headers = headersJSON
 	row.Models = modelsJSON
 	row.ExcludedModels = excludedJSON
 	row.APIKeyEntries = apiKeyEntriesJSON
+	row.Environments = environments.Marshal(envTags)
 
 	normalizeProviderFields(&row)
 	ensureProviderName(&row)
@@ -300,6 +324,7 @@ func (h *ProviderAPIKeyHandler) List(c *gin.Context) {
 	providerQ := normalizeProvider(rawProvider)
 	keywordQ := strings.TrimSpace(c.Query("keyword"))
 	statusQ := strings.ToLower(strings.TrimSpace(c.Query("status")))
+	environmentQ := strings.ToLower(strings.TrimSpace(c.Query("environment")))
 
 	if rawProvider != "" && providerQ == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid provider"})
@@ -334,6 +359,9 @@ func (h *ProviderAPIKeyHandler) List(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "list api keys failed"})
 		return
 	}
+	if environmentQ != "" {
+		rows = environments.FilterProviderKeys(rows, environmentQ)
+	}
 
 	out := make([]gin.H, 0, len(rows))
 	for i := range rows {
@@ -408,6 +436,10 @@ func (h *ProviderAPIKeyHandler) Update(c *gin.Context) {
 		row.ProxyURL = strings.TrimSpace(*body.ProxyURL)
 	}
 	if body.Headers != nil {
+		if errTemplate := validateHeaderTemplates(*body.Headers); errTemplate != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errTemplate.Error()})
+			return
+		}
 		headersJSON, errHeaders := marshalJSON(*body.Headers)
 		if errHeaders != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid headers"})
@@ -468,6 +500,14 @@ func (h *ProviderAPIKeyHandler) Update(c *gin.Context) {
 		}
 		row.APIKeyEntries = apiKeyEntriesJSON
 	}
+	if body.Environments != nil {
+		envTags, errEnv := environments.Normalize(*body.Environments)
+		if errEnv != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errEnv.Error()})
+			return
+		}
+		row.Environments = environments.Marshal(envTags)
+	}
 
 	normalizeProviderFields(&row)
 	ensureProviderName(&row)
@@ -515,12 +555,41 @@ func (h *ProviderAPIKeyHandler) Delete(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"deleted": true})
 }
 
-// syncSDKConfig rebuilds SDK config based on DB records and saves it.
+// syncSDKConfig rebuilds SDK config based on DB records and saves it to this
+// instance's config file and to every environment target's config file, then
+// pushes the new config to remote nodes in the background.
 func (h *ProviderAPIKeyHandler) syncSDKConfig(ctx context.Context) error {
 	if h == nil || h.db == nil {
 		return errors.New("missing db")
 	}
-	configPath := strings.TrimSpace(h.configPath)
+	defer func() { go pushNodeConfigs(context.Background(), h.db) }()
+	if strings.TrimSpace(h.configPath) == "" {
+		return nil
+	}
+
+	rows, mappingRows, errLoad := loadProviderSyncRows(ctx, h.db)
+	if errLoad != nil {
+		return errLoad
+	}
+
+	if errWrite := writeSDKConfig(h.configPath, environments.FilterProviderKeys(rows, h.environments.Current), mappingRows); errWrite != nil {
+		return errWrite
+	}
+	for _, target := range h.environments.Targets {
+		if target.ConfigPath == "" {
+			continue
+		}
+		if errWrite := writeSDKConfig(target.ConfigPath, environments.FilterProviderKeys(rows, target.Name), mappingRows); errWrite != nil {
+			return fmt.Errorf("environment %s: %w", target.Name, errWrite)
+		}
+	}
+	return nil
+}
+
+// writeSDKConfig replaces the provider key sections of the config file at configPath.
+// Missing config files are skipped.
+func writeSDKConfig(configPath string, rows []models.ProviderAPIKey, mappingRows []models.ModelMapping) error {
+	configPath = strings.TrimSpace(configPath)
 	if configPath == "" {
 		return nil
 	}
@@ -531,25 +600,19 @@ func (h *ProviderAPIKeyHandler) syncSDKConfig(ctx context.Context) error {
 		return errStat
 	}
 
-	var rows []models.ProviderAPIKey
-	if errFind := h.db.WithContext(ctx).Order("id ASC").Find(&rows).Error; errFind != nil {
-		return errFind
-	}
-
-	var mappingRows []models.ModelMapping
-	if errFindMappings := h.db.WithContext(ctx).
-		Model(&models.ModelMapping{}).
-		Where("is_enabled = ?", true).
-		Order("provider ASC, new_model_name ASC, model_name ASC").
-		Find(&mappingRows).Error; errFindMappings != nil {
-		return errFindMappings
-	}
-
 	cfg, errLoad := sdkconfig.LoadConfig(configPath)
 	if errLoad != nil {
 		return errLoad
 	}
 
+	applyProviderRows(cfg, rows, mappingRows)
+
+	return sdkconfig.SaveConfigPreserveComments(configPath, cfg)
+}
+
+// applyProviderRows replaces the provider key and model alias sections of cfg with the
+// enabled rows and mappings.
+func applyProviderRows(cfg *sdkconfig.Config, rows []models.ProviderAPIKey, mappingRows []models.ModelMapping) {
 	geminiKeys := make([]sdkconfig.GeminiKey, 0)
 	codexKeys := make([]sdkconfig.CodexKey, 0)
 	claudeKeys := make([]sdkconfig.ClaudeKey, 0)
@@ -646,8 +709,6 @@ func (h *ProviderAPIKeyHandler) syncSDKConfig(ctx context.Context) error {
 	cfg.SanitizeClaudeKeys()
 	cfg.SanitizeVertexCompatKeys()
 	cfg.SanitizeOpenAICompatibility()
-
-	return sdkconfig.SaveConfigPreserveComments(configPath, cfg)
 }
 
 // buildOAuthModelMappings converts model mappings into SDK config entries.
@@ -968,6 +1029,16 @@ func marshalJSON(value interface{}) (datatypes.JSON, error) {
 	return datatypes.JSON(data), nil
 }
 
+// validateHeaderTemplates rejects header values with unknown or malformed placeholders.
+func validateHeaderTemplates(headers map[string]string) error {
+	for name, value := range headers {
+		if errValidate := headertemplate.Validate(value); errValidate != nil {
+			return fmt.Errorf("header %s: %w", name, errValidate)
+		}
+	}
+	return nil
+}
+
 // decodeHeaders decodes headers JSON into a map.
 func decodeHeaders(value datatypes.JSON) map[string]string {
 	if len(value) == 0 {
@@ -1059,6 +1130,9 @@ func formatProviderRow(row *models.ProviderAPIKey) gin.H {
 		"whitelist_enabled": row.WhitelistEnabled,
 		"excluded_models":   decodeExcludedModels(row.ExcludedModels),
 		"api_key_entries":   decodeAPIKeyEntries(row.APIKeyEntries),
+		"environments":      environments.Decode(row.Environments),
+		"canary_percent":    row.CanaryPercent,
+		"canary_started_at": row.CanaryStartedAt,
 		"created_at":        row.CreatedAt,
 		"updated_at":        row.UpdatedAt,
 	}
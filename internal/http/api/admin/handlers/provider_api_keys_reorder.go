@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// reorderProviderAPIKeysRequest carries provider keys in their new selection order.
+type reorderProviderAPIKeysRequest struct {
+	Provider string   `json:"provider"` // Optional provider every listed key must belong to.
+	IDs      []uint64 `json:"ids"`      // Key IDs, most preferred first.
+}
+
+// errReorderInvalid wraps reorder validation failures answered with 400.
+var errReorderInvalid = errors.New("invalid reorder")
+
+// Reorder recomputes provider key priorities from an ordered ID list in one transaction and
+// syncs the SDK config once. Each provider in the list must be listed completely; its keys get
+// priorities from len-1 down to 0 in the given order, so the first key is preferred.
+func (h *ProviderAPIKeyHandler) Reorder(c *gin.Context) {
+	var body reorderProviderAPIKeysRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	if len(body.IDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids are required"})
+		return
+	}
+	provider := ""
+	if body.Provider != "" {
+		provider = normalizeProvider(body.Provider)
+		if provider == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown provider"})
+			return
+		}
+	}
+
+	updated := 0
+	now := time.Now().UTC()
+	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var rows []models.ProviderAPIKey
+		if errFind := tx.Select("id", "provider", "priority").Where("id IN ?", body.IDs).Find(&rows).Error; errFind != nil {
+			return errFind
+		}
+		byID := make(map[uint64]models.ProviderAPIKey, len(rows))
+		for _, row := range rows {
+			byID[row.ID] = row
+		}
+		listed := make(map[string]int)
+		seen := make(map[uint64]struct{}, len(body.IDs))
+		for _, id := range body.IDs {
+			row, ok := byID[id]
+			if !ok {
+				return fmt.Errorf("%w: api key %d not found", errReorderInvalid, id)
+			}
+			if _, dup := seen[id]; dup {
+				return fmt.Errorf("%w: api key %d listed twice", errReorderInvalid, id)
+			}
+			seen[id] = struct{}{}
+			if provider != "" && normalizeProvider(row.Provider) != provider {
+				return fmt.Errorf("%w: api key %d does not belong to provider %s", errReorderInvalid, id, provider)
+			}
+			listed[row.Provider]++
+		}
+		for name, count := range listed {
+			var total int64
+			if errCount := tx.Model(&models.ProviderAPIKey{}).Where("provider = ?", name).Count(&total).Error; errCount != nil {
+				return errCount
+			}
+			if int64(count) != total {
+				return fmt.Errorf("%w: ids must list every key of provider %s", errReorderInvalid, name)
+			}
+		}
+
+		remaining := listed
+		for _, id := range body.IDs {
+			row := byID[id]
+			remaining[row.Provider]--
+			priority := remaining[row.Provider]
+			if row.Priority == priority {
+				continue
+			}
+			if errUpdate := tx.Model(&models.ProviderAPIKey{}).Where("id = ?", id).
+				Updates(map[string]any{"priority": priority, "updated_at": now}).Error; errUpdate != nil {
+				return errUpdate
+			}
+			updated++
+		}
+		return nil
+	})
+	if errTx != nil {
+		if errors.Is(errTx, errReorderInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errTx.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "reorder api keys failed"})
+		return
+	}
+
+	if updated > 0 {
+		if errSync := h.syncSDKConfig(c.Request.Context()); errSync != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "sync config failed"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
+}
@@ -13,9 +13,13 @@ import (
 	"github.com/gin-gonic/gin"
 	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/proxyhealth"
 	"gorm.io/gorm"
 )
 
+// maxProxyRegionLength bounds proxy region labels to their column size.
+const maxProxyRegionLength = 64
+
 // ProxyHandler manages admin proxy CRUD endpoints.
 type ProxyHandler struct {
 	db *gorm.DB // Database handle for proxy records.
@@ -29,16 +33,19 @@ func NewProxyHandler(db *gorm.DB) *ProxyHandler {
 // createProxyRequest captures the payload for creating a proxy.
 type createProxyRequest struct {
 	ProxyURL string `json:"proxy_url"` // Proxy URL.
+	Region   string `json:"region"`    // Optional region label.
 }
 
 // updateProxyRequest captures the payload for updating a proxy.
 type updateProxyRequest struct {
 	ProxyURL *string `json:"proxy_url"` // Optional updated proxy URL.
+	Region   *string `json:"region"`    // Optional updated region label.
 }
 
 // batchCreateProxyRequest captures the payload for batch proxy creation.
 type batchCreateProxyRequest struct {
 	ProxyURLs []string `json:"proxy_urls"` // List of proxy URLs.
+	Region    string   `json:"region"`     // Optional region label applied to every proxy.
 }
 
 // Create validates and inserts a new proxy record.
@@ -55,9 +62,15 @@ func (h *ProxyHandler) Create(c *gin.Context) {
 		return
 	}
 
+	if len(normalizeProxyRegion(body.Region)) > maxProxyRegionLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "region too long"})
+		return
+	}
+
 	now := time.Now().UTC()
 	row := models.Proxy{
 		ProxyURL:  normalized,
+		Region:    normalizeProxyRegion(body.Region),
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
@@ -125,6 +138,14 @@ func (h *ProxyHandler) Update(c *gin.Context) {
 		}
 		row.ProxyURL = normalized
 	}
+	if body.Region != nil {
+		region := normalizeProxyRegion(*body.Region)
+		if len(region) > maxProxyRegionLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "region too long"})
+			return
+		}
+		row.Region = region
+	}
 
 	row.UpdatedAt = time.Now().UTC()
 	if errSave := h.db.WithContext(c.Request.Context()).Save(&row).Error; errSave != nil {
@@ -143,7 +164,16 @@ func (h *ProxyHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	if errDelete := h.db.WithContext(c.Request.Context()).Delete(&models.Proxy{}, "id = ?", id).Error; errDelete != nil {
+	errDelete := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if errHealth := tx.Delete(&models.ProxyHealth{}, "proxy_id = ?", id).Error; errHealth != nil {
+			return errHealth
+		}
+		if errChecks := tx.Delete(&models.ProxyHealthCheck{}, "proxy_id = ?", id).Error; errChecks != nil {
+			return errChecks
+		}
+		return tx.Delete(&models.Proxy{}, "id = ?", id).Error
+	})
+	if errDelete != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete proxy failed"})
 		return
 	}
@@ -165,6 +195,11 @@ func (h *ProxyHandler) BatchCreate(c *gin.Context) {
 	}
 
 	now := time.Now().UTC()
+	region := normalizeProxyRegion(body.Region)
+	if len(region) > maxProxyRegionLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "region too long"})
+		return
+	}
 	rows := make([]models.Proxy, 0, len(body.ProxyURLs))
 	for idx, raw := range body.ProxyURLs {
 		trimmed := strings.TrimSpace(raw)
@@ -178,6 +213,7 @@ func (h *ProxyHandler) BatchCreate(c *gin.Context) {
 		}
 		rows = append(rows, models.Proxy{
 			ProxyURL:  normalized,
+			Region:    region,
 			CreatedAt: now,
 			UpdatedAt: now,
 		})
@@ -232,6 +268,12 @@ func normalizeProxyURL(raw string) (string, error) {
 	return parsed.String(), nil
 }
 
+// normalizeProxyRegion trims a region label and lowercases it so provider mappings match
+// regardless of case.
+func normalizeProxyRegion(raw string) string {
+	return strings.ToLower(strings.TrimSpace(raw))
+}
+
 // proxyRow converts a proxy model into a response payload.
 func proxyRow(row *models.Proxy) gin.H {
 	if row == nil {
@@ -240,7 +282,25 @@ func proxyRow(row *models.Proxy) gin.H {
 	return gin.H{
 		"id":         row.ID,
 		"proxy_url":  row.ProxyURL,
+		"region":     row.Region,
 		"created_at": row.CreatedAt,
 		"updated_at": row.UpdatedAt,
 	}
 }
+
+// Health reports the latest check of every proxy with failure rate and mean latency over
+// the configured window. Inactive proxies are skipped by automatic proxy assignment.
+func (h *ProxyHandler) Health(c *gin.Context) {
+	cfg := proxyhealth.LoadConfig()
+	statuses, errReport := proxyhealth.Report(c.Request.Context(), h.db, time.Now().UTC(), cfg.Window())
+	if errReport != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query proxy health failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":           cfg.Enabled,
+		"window_hours":      cfg.WindowHours,
+		"failure_threshold": cfg.FailureThreshold,
+		"proxies":           statuses,
+	})
+}
@@ -52,6 +52,12 @@ type quotaListRow struct {
 	TokenInvalid    bool           `gorm:"column:token_invalid"`
 	LastAuthCheckAt sql.NullString `gorm:"column:last_auth_check_at"`
 	LastAuthError   string         `gorm:"column:last_auth_error"`
+
+	Plan              string          `gorm:"column:plan"`
+	RemainingRequests sql.NullInt64   `gorm:"column:remaining_requests"`
+	RemainingTokens   sql.NullInt64   `gorm:"column:remaining_tokens"`
+	RemainingFraction sql.NullFloat64 `gorm:"column:remaining_fraction"`
+	ResetAt           sql.NullString  `gorm:"column:reset_at"`
 }
 
 // List returns quota records with paging and filters.
@@ -126,7 +132,7 @@ func (h *QuotaHandler) List(c *gin.Context) {
 	offset := (q.Page - 1) * q.Limit
 	var rows []quotaListRow
 	if errFind := base.
-		Select("quota.id, quota.auth_id, auths.name AS auth_name, quota.type, quota.data, quota.updated_at, auths.key AS auth_key, auths.is_available AS is_available, auths.token_invalid AS token_invalid, CAST(auths.last_auth_check_at AS TEXT) AS last_auth_check_at, auths.last_auth_error AS last_auth_error").
+		Select("quota.id, quota.auth_id, auths.name AS auth_name, quota.type, quota.data, quota.updated_at, auths.key AS auth_key, auths.is_available AS is_available, auths.token_invalid AS token_invalid, CAST(auths.last_auth_check_at AS TEXT) AS last_auth_check_at, auths.last_auth_error AS last_auth_error, quota.plan, quota.remaining_requests, quota.remaining_tokens, quota.remaining_fraction, CAST(quota.reset_at AS TEXT) AS reset_at").
 		Order("auths.id ASC, quota.updated_at DESC").
 		Offset(offset).
 		Limit(q.Limit).
@@ -154,6 +160,11 @@ func (h *QuotaHandler) List(c *gin.Context) {
 			"token_invalid":      row.TokenInvalid,
 			"last_auth_check_at": lastAuthCheckAt,
 			"last_auth_error":    row.LastAuthError,
+			"plan":               row.Plan,
+			"remaining_requests": nullInt64Value(row.RemainingRequests),
+			"remaining_tokens":   nullInt64Value(row.RemainingTokens),
+			"remaining_fraction": nullFloat64Value(row.RemainingFraction),
+			"reset_at":           parseQuotaListAuthCheckTime(row.ResetAt),
 		})
 	}
 
@@ -166,6 +177,20 @@ func (h *QuotaHandler) List(c *gin.Context) {
 	})
 }
 
+func nullInt64Value(value sql.NullInt64) *int64 {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Int64
+}
+
+func nullFloat64Value(value sql.NullFloat64) *float64 {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Float64
+}
+
 func parseQuotaListAuthCheckTime(value sql.NullString) *time.Time {
 	if !value.Valid {
 		return nil
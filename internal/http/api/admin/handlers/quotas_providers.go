@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/quota"
+)
+
+// quotaProviderCountRow holds the number of stored quota rows per auth type.
+type quotaProviderCountRow struct {
+	Type  string `gorm:"column:type"`
+	Total int64  `gorm:"column:total"`
+}
+
+// ListProviders returns the auth providers the quota poller can refresh.
+func (h *QuotaHandler) ListProviders(c *gin.Context) {
+	var rows []quotaProviderCountRow
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Model(&models.Quota{}).
+		Select("type, COUNT(*) AS total").
+		Group("type").
+		Scan(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list quota providers failed"})
+		return
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[strings.ToLower(strings.TrimSpace(row.Type))] += row.Total
+	}
+
+	names := quota.RegisteredProviders()
+	providers := make([]gin.H, 0, len(names))
+	for _, name := range names {
+		providers = append(providers, gin.H{
+			"name":        name,
+			"quota_count": counts[name],
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"providers": providers})
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/quota"
+	"gorm.io/gorm"
+)
+
+// quotaRefreshAuthFileTimeout bounds a synchronous single-auth quota refresh.
+const quotaRefreshAuthFileTimeout = 60 * time.Second
+
+// RefreshAuthFile refreshes the quota of one auth file synchronously and returns the stored payload.
+func (h *QuotaHandler) RefreshAuthFile(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if h.manualRefresher == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "quota refresher unavailable"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var auth models.Auth
+	if errFind := h.db.WithContext(ctx).
+		Select("id", "key", "name", "is_available").
+		First(&auth, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	refreshCtx, cancel := context.WithTimeout(ctx, quotaRefreshAuthFileTimeout)
+	defer cancel()
+	startedAt := time.Now().UTC()
+	if errRefresh := h.manualRefresher.RefreshByAuthKey(refreshCtx, auth.Key); errRefresh != nil {
+		status := http.StatusBadGateway
+		if errors.Is(errRefresh, quota.ErrUnsupportedProvider) {
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, gin.H{
+			"error":    "quota refresh failed",
+			"detail":   errRefresh.Error(),
+			"auth_id":  auth.ID,
+			"auth_key": auth.Key,
+		})
+		return
+	}
+
+	var rows []models.Quota
+	if errFind := h.db.WithContext(ctx).
+		Where("auth_id = ?", auth.ID).
+		Order("updated_at DESC").
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "load quota failed"})
+		return
+	}
+
+	out := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		payload := row.Data
+		if isAntigravityType(row.Type) {
+			payload = normalizeAntigravityQuota(row.Data)
+		}
+		out = append(out, gin.H{
+			"id":                 row.ID,
+			"type":               row.Type,
+			"data":               payload,
+			"updated_at":         row.UpdatedAt,
+			"plan":               row.Plan,
+			"remaining_requests": row.RemainingRequests,
+			"remaining_tokens":   row.RemainingTokens,
+			"remaining_fraction": row.RemainingFraction,
+			"reset_at":           row.ResetAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"auth_id":      auth.ID,
+		"auth_key":     auth.Key,
+		"auth_name":    auth.Name,
+		"refreshed_at": startedAt,
+		"duration_ms":  time.Since(startedAt).Milliseconds(),
+		"quotas":       out,
+	})
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/quota"
+	"gorm.io/datatypes"
+)
+
+func TestRefreshAuthFileReturnsStoredQuota(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupQuotaManualRefreshDB(t)
+
+	auth := models.Auth{Key: "refresh-auth", Content: datatypes.JSON([]byte(`{"type":"codex"}`))}
+	if errCreate := db.Create(&auth).Error; errCreate != nil {
+		t.Fatalf("create auth: %v", errCreate)
+	}
+	if errCreate := db.Create(&models.Quota{AuthID: auth.ID, Type: "codex", Data: datatypes.JSON([]byte(`{"plan":"plus"}`))}).Error; errCreate != nil {
+		t.Fatalf("create quota: %v", errCreate)
+	}
+
+	refresher := &manualRefreshTestRefresher{}
+	handler := NewQuotaHandler(db, refresher, nil)
+	router := gin.New()
+	router.POST("/v0/admin/auth-files/:id/refresh-quota", handler.RefreshAuthFile)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/v0/admin/auth-files/%d/refresh-quota", auth.ID), nil)
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if calls := refresher.callsSnapshot(); len(calls) != 1 || calls[0] != "refresh-auth" {
+		t.Fatalf("expected refresh for auth key, got %v", calls)
+	}
+
+	var resp struct {
+		AuthID uint64 `json:"auth_id"`
+		Quotas []struct {
+			Type string         `json:"type"`
+			Data map[string]any `json:"data"`
+		} `json:"quotas"`
+	}
+	if errJSON := json.Unmarshal(rec.Body.Bytes(), &resp); errJSON != nil {
+		t.Fatalf("decode response: %v", errJSON)
+	}
+	if resp.AuthID != auth.ID || len(resp.Quotas) != 1 || resp.Quotas[0].Data["plan"] != "plus" {
+		t.Fatalf("unexpected response: %s", rec.Body.String())
+	}
+}
+
+func TestRefreshAuthFileMapsUnsupportedProvider(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupQuotaManualRefreshDB(t)
+
+	auth := models.Auth{Key: "unsupported-auth", Content: datatypes.JSON([]byte(`{"type":"qwen"}`))}
+	if errCreate := db.Create(&auth).Error; errCreate != nil {
+		t.Fatalf("create auth: %v", errCreate)
+	}
+
+	refresher := &manualRefreshTestRefresher{errByKey: map[string]error{"unsupported-auth": quota.ErrUnsupportedProvider}}
+	handler := NewQuotaHandler(db, refresher, nil)
+	router := gin.New()
+	router.POST("/v0/admin/auth-files/:id/refresh-quota", handler.RefreshAuthFile)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/v0/admin/auth-files/%d/refresh-quota", auth.ID), nil)
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/requestlog"
+	"gorm.io/gorm"
+)
+
+// RequestLogHandler serves admin endpoints for captured request bodies.
+type RequestLogHandler struct {
+	db *gorm.DB // Database handle for request log queries.
+}
+
+// NewRequestLogHandler constructs a request log handler.
+func NewRequestLogHandler(db *gorm.DB) *RequestLogHandler {
+	return &RequestLogHandler{db: db}
+}
+
+// requestLogListQuery defines filters for the request log list.
+type requestLogListQuery struct {
+	Page      int    `form:"page,default=1"`   // Page number.
+	Limit     int    `form:"limit,default=20"` // Page size.
+	RequestID string `form:"request_id"`       // Exact request ID.
+	UserID    uint64 `form:"user_id"`          // Requesting user.
+	APIKeyID  uint64 `form:"api_key_id"`       // API key used.
+	Model     string `form:"model"`            // Requested model.
+	Status    int    `form:"status"`           // Response status code.
+	Since     string `form:"since"`            // RFC 3339 lower bound on capture time.
+	Until     string `form:"until"`            // RFC 3339 upper bound on capture time.
+}
+
+// List returns captured requests newest first, without their bodies.
+func (h *RequestLogHandler) List(c *gin.Context) {
+	var q requestLogListQuery
+	if errBind := c.ShouldBindQuery(&q); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query"})
+		return
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.Limit < 1 || q.Limit > 100 {
+		q.Limit = 20
+	}
+
+	query := h.db.WithContext(c.Request.Context()).Model(&models.RequestLog{})
+	if requestID := strings.TrimSpace(q.RequestID); requestID != "" {
+		query = query.Where("request_id = ?", requestID)
+	}
+	if q.UserID != 0 {
+		query = query.Where("user_id = ?", q.UserID)
+	}
+	if q.APIKeyID != 0 {
+		query = query.Where("api_key_id = ?", q.APIKeyID)
+	}
+	if model := strings.TrimSpace(q.Model); model != "" {
+		query = query.Where("model = ?", model)
+	}
+	if q.Status != 0 {
+		query = query.Where("status_code = ?", q.Status)
+	}
+	for _, bound := range []struct {
+		raw string
+		op  string
+	}{{q.Since, ">="}, {q.Until, "<"}} {
+		if strings.TrimSpace(bound.raw) == "" {
+			continue
+		}
+		at, errParse := time.Parse(time.RFC3339, strings.TrimSpace(bound.raw))
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid time, expected RFC 3339"})
+			return
+		}
+		query = query.Where("created_at "+bound.op+" ?", at)
+	}
+
+	var total int64
+	if errCount := query.Session(&gorm.Session{}).Count(&total).Error; errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "count request logs failed"})
+		return
+	}
+	var rows []models.RequestLog
+	if errFind := query.
+		Omit("request_body", "response_body").
+		Order("created_at DESC").Order("id DESC").
+		Offset((q.Page - 1) * q.Limit).Limit(q.Limit).
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list request logs failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatRequestLog(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"request_logs": out,
+		"total":        total,
+		"page":         q.Page,
+		"limit":        q.Limit,
+	})
+}
+
+// Get returns one captured request with its bodies and the usage rows it produced.
+func (h *RequestLogHandler) Get(c *gin.Context) {
+	row, ok := h.load(c)
+	if !ok {
+		return
+	}
+	requestBody, responseBody, errBodies := requestlog.Bodies(c.Request.Context(), row)
+	if errBodies != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "load request log bodies failed"})
+		return
+	}
+	out := formatRequestLog(row)
+	out["request_body"] = requestBody
+	out["response_body"] = responseBody
+
+	usages := make([]gin.H, 0)
+	if requestID := strings.TrimSpace(row.RequestID); requestID != "" {
+		var usageRows []models.Usage
+		if errFind := h.db.WithContext(c.Request.Context()).
+			Select("id", "provider", "model", "failed", "error_status_code", "total_tokens", "cost_micros", "requested_at").
+			Where("request_id = ?", requestID).
+			Order("id ASC").
+			Find(&usageRows).Error; errFind != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "query usage failed"})
+			return
+		}
+		for _, usage := range usageRows {
+			usages = append(usages, gin.H{
+				"id":                usage.ID,
+				"provider":          usage.Provider,
+				"model":             usage.Model,
+				"failed":            usage.Failed,
+				"error_status_code": usage.ErrorStatusCode,
+				"total_tokens":      usage.TotalTokens,
+				"cost_micros":       usage.CostMicros,
+				"requested_at":      usage.RequestedAt,
+			})
+		}
+	}
+	out["usages"] = usages
+	c.JSON(http.StatusOK, out)
+}
+
+// Redact clears the bodies of one captured request while keeping its metadata.
+func (h *RequestLogHandler) Redact(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if errRedact := requestlog.Redact(c.Request.Context(), h.db, id, time.Now().UTC()); errRedact != nil {
+		if errors.Is(errRedact, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "redact failed"})
+		return
+	}
+	row, ok := h.load(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, formatRequestLog(row))
+}
+
+// purgeRequestLogsRequest selects the captured requests to delete.
+type purgeRequestLogsRequest struct {
+	Before *time.Time `json:"before"`  // Delete rows captured before this time.
+	UserID uint64     `json:"user_id"` // Delete rows of this user.
+	All    bool       `json:"all"`     // Required to delete every row without a filter.
+}
+
+// Purge deletes captured requests by age or user.
+func (h *RequestLogHandler) Purge(c *gin.Context) {
+	var body purgeRequestLogsRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	filter := requestlog.PurgeFilter{UserID: body.UserID}
+	if body.Before != nil {
+		filter.Before = body.Before.UTC()
+	}
+	if filter.Before.IsZero() && filter.UserID == 0 && !body.All {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "before, user_id or all is required"})
+		return
+	}
+	deleted, errPurge := requestlog.Purge(c.Request.Context(), h.db, filter)
+	if errPurge != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "purge failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": deleted})
+}
+
+// load reads the row named by the id path parameter, writing the error response on failure.
+func (h *RequestLogHandler) load(c *gin.Context) (*models.RequestLog, bool) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return nil, false
+	}
+	var row models.RequestLog
+	if errFind := h.db.WithContext(c.Request.Context()).First(&row, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return nil, false
+	}
+	return &row, true
+}
+
+// formatRequestLog converts a row to its list payload, without bodies.
+func formatRequestLog(row *models.RequestLog) gin.H {
+	return gin.H{
+		"id":                 row.ID,
+		"request_id":         row.RequestID,
+		"user_id":            row.UserID,
+		"api_key_id":         row.APIKeyID,
+		"method":             row.Method,
+		"path":               row.Path,
+		"model":              row.Model,
+		"status_code":        row.StatusCode,
+		"duration_ms":        row.DurationMs,
+		"client_ip":          row.ClientIP,
+		"request_truncated":  row.RequestTruncated,
+		"response_truncated": row.ResponseTruncated,
+		"payload_backend":    row.PayloadBackend,
+		"payload_bytes":      row.PayloadBytes,
+		"expires_at":         row.ExpiresAt,
+		"redacted":           row.RedactedAt != nil,
+		"redacted_at":        row.RedactedAt,
+		"created_at":         row.CreatedAt,
+	}
+}
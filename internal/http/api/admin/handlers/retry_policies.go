@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/retrypolicy"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	// retryPolicyMaxRetries bounds max_retries so a policy cannot stall requests indefinitely.
+	retryPolicyMaxRetries = 10
+	// retryPolicyMaxBackoffMs bounds backoff delays to five minutes.
+	retryPolicyMaxBackoffMs = 300_000
+)
+
+// RetryPolicyHandler manages per-provider retry and failover policies.
+type RetryPolicyHandler struct {
+	db *gorm.DB // Database handle.
+}
+
+// NewRetryPolicyHandler constructs a retry policy handler.
+func NewRetryPolicyHandler(db *gorm.DB) *RetryPolicyHandler {
+	return &RetryPolicyHandler{db: db}
+}
+
+// retryPolicyRequest captures the payload for creating or updating a retry policy.
+type retryPolicyRequest struct {
+	Provider             *string `json:"provider"`               // Provider name, or "*" for the default policy.
+	MaxRetries           *int    `json:"max_retries"`            // Retries allowed after the first attempt.
+	RetryableStatusCodes *[]int  `json:"retryable_status_codes"` // Upstream statuses worth retrying; empty retries any failure.
+	BackoffInitialMs     *int    `json:"backoff_initial_ms"`     // Delay before the first retry.
+	BackoffMaxMs         *int    `json:"backoff_max_ms"`         // Upper bound of the retry delay.
+	FailoverToNextKey    *bool   `json:"failover_to_next_key"`   // Whether retries may move to another credential.
+	IsEnabled            *bool   `json:"is_enabled"`             // Whether the policy is active.
+	Description          *string `json:"description"`            // Human-readable description.
+}
+
+// List returns every retry policy with its failover statistics since the instance started.
+func (h *RetryPolicyHandler) List(c *gin.Context) {
+	var rows []models.RetryPolicy
+	if errFind := h.db.WithContext(c.Request.Context()).Order("provider ASC").Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list retry policies failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, formatRetryPolicy(row))
+	}
+	c.JSON(http.StatusOK, gin.H{"policies": out})
+}
+
+// Create adds a retry policy for a provider without one.
+func (h *RetryPolicyHandler) Create(c *gin.Context) {
+	var body retryPolicyRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	if body.Provider == nil || strings.TrimSpace(*body.Provider) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing provider"})
+		return
+	}
+	policy := models.RetryPolicy{FailoverToNextKey: true, IsEnabled: true}
+	if errApply := applyRetryPolicyRequest(&policy, body); errApply != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errApply.Error()})
+		return
+	}
+	if h.providerTaken(c, policy.Provider, 0) {
+		return
+	}
+	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if errCreate := tx.Create(&policy).Error; errCreate != nil {
+			return errCreate
+		}
+		// Booleans defaulting to true in the schema are written separately when false.
+		return tx.Model(&policy).Updates(map[string]any{
+			"failover_to_next_key": policy.FailoverToNextKey,
+			"is_enabled":           policy.IsEnabled,
+		}).Error
+	})
+	if errTx != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create retry policy failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, formatRetryPolicy(policy))
+}
+
+// Update changes a retry policy.
+func (h *RetryPolicyHandler) Update(c *gin.Context) {
+	policy, ok := h.find(c)
+	if !ok {
+		return
+	}
+	var body retryPolicyRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	if errApply := applyRetryPolicyRequest(&policy, body); errApply != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errApply.Error()})
+		return
+	}
+	if h.providerTaken(c, policy.Provider, policy.ID) {
+		return
+	}
+	policy.UpdatedAt = time.Now().UTC()
+	if errSave := h.db.WithContext(c.Request.Context()).Save(&policy).Error; errSave != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	c.JSON(http.StatusOK, formatRetryPolicy(policy))
+}
+
+// Delete removes a retry policy; its provider falls back to the default policy.
+func (h *RetryPolicyHandler) Delete(c *gin.Context) {
+	policy, ok := h.find(c)
+	if !ok {
+		return
+	}
+	if errDelete := h.db.WithContext(c.Request.Context()).Delete(&models.RetryPolicy{}, policy.ID).Error; errDelete != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// applyRetryPolicyRequest validates the request and copies its fields onto policy.
+func applyRetryPolicyRequest(policy *models.RetryPolicy, body retryPolicyRequest) error {
+	if body.Provider != nil {
+		provider := strings.ToLower(strings.TrimSpace(*body.Provider))
+		if provider == "" {
+			return errors.New("provider cannot be empty")
+		}
+		policy.Provider = provider
+	}
+	if body.MaxRetries != nil {
+		if *body.MaxRetries < 0 || *body.MaxRetries > retryPolicyMaxRetries {
+			return errors.New("max_retries must be between 0 and " + strconv.Itoa(retryPolicyMaxRetries))
+		}
+		policy.MaxRetries = *body.MaxRetries
+	}
+	if body.RetryableStatusCodes != nil {
+		codes := make([]int, 0, len(*body.RetryableStatusCodes))
+		seen := make(map[int]struct{}, len(*body.RetryableStatusCodes))
+		for _, code := range *body.RetryableStatusCodes {
+			if code < http.StatusBadRequest || code > 599 {
+				return errors.New("retryable_status_codes must be HTTP error statuses")
+			}
+			if _, dup := seen[code]; dup {
+				continue
+			}
+			seen[code] = struct{}{}
+			codes = append(codes, code)
+		}
+		raw, errMarshal := json.Marshal(codes)
+		if errMarshal != nil {
+			return errMarshal
+		}
+		policy.RetryableStatusCodes = datatypes.JSON(raw)
+	}
+	if body.BackoffInitialMs != nil {
+		policy.BackoffInitialMs = *body.BackoffInitialMs
+	}
+	if body.BackoffMaxMs != nil {
+		policy.BackoffMaxMs = *body.BackoffMaxMs
+	}
+	if policy.BackoffInitialMs < 0 || policy.BackoffInitialMs > retryPolicyMaxBackoffMs ||
+		policy.BackoffMaxMs < 0 || policy.BackoffMaxMs > retryPolicyMaxBackoffMs {
+		return errors.New("backoff must be between 0 and " + strconv.Itoa(retryPolicyMaxBackoffMs) + " ms")
+	}
+	if policy.BackoffMaxMs > 0 && policy.BackoffMaxMs < policy.BackoffInitialMs {
+		return errors.New("backoff_max_ms cannot be less than backoff_initial_ms")
+	}
+	if body.FailoverToNextKey != nil {
+		policy.FailoverToNextKey = *body.FailoverToNextKey
+	}
+	if body.IsEnabled != nil {
+		policy.IsEnabled = *body.IsEnabled
+	}
+	if body.Description != nil {
+		policy.Description = strings.TrimSpace(*body.Description)
+	}
+	return nil
+}
+
+// providerTaken answers 409 when another policy already covers provider.
+func (h *RetryPolicyHandler) providerTaken(c *gin.Context, provider string, exceptID uint64) bool {
+	var count int64
+	if errCount := h.db.WithContext(c.Request.Context()).Model(&models.RetryPolicy{}).
+		Where("provider = ? AND id <> ?", provider, exceptID).
+		Count(&count).Error; errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return true
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "provider already has a retry policy"})
+		return true
+	}
+	return false
+}
+
+// find loads the retry policy named by the id path parameter, answering 400 or 404 on failure.
+func (h *RetryPolicyHandler) find(c *gin.Context) (models.RetryPolicy, bool) {
+	var policy models.RetryPolicy
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return policy, false
+	}
+	if errFind := h.db.WithContext(c.Request.Context()).First(&policy, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return policy, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return policy, false
+	}
+	return policy, true
+}
+
+// formatRetryPolicy renders a retry policy with its statistics.
+func formatRetryPolicy(policy models.RetryPolicy) gin.H {
+	codes := retrypolicy.StatusCodes(policy.RetryableStatusCodes)
+	if codes == nil {
+		codes = []int{}
+	}
+	stats := retrypolicy.StatsFor(policy.Provider)
+	return gin.H{
+		"id":                     policy.ID,
+		"provider":               policy.Provider,
+		"max_retries":            policy.MaxRetries,
+		"retryable_status_codes": codes,
+		"backoff_initial_ms":     policy.BackoffInitialMs,
+		"backoff_max_ms":         policy.BackoffMaxMs,
+		"failover_to_next_key":   policy.FailoverToNextKey,
+		"is_enabled":             policy.IsEnabled,
+		"description":            policy.Description,
+		"stats":                  stats,
+		"failover_rate":          stats.FailoverRate(),
+		"created_at":             policy.CreatedAt,
+		"updated_at":             policy.UpdatedAt,
+	}
+}
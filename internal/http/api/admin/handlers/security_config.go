@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"gorm.io/gorm"
+)
+
+// defaultJWTRotationOverlap keeps tokens of the replaced secret valid when the request omits
+// overlap_seconds.
+const defaultJWTRotationOverlap = 24 * time.Hour
+
+// SecurityConfigHandler rotates the JWT secret and updates the WebAuthn relying party at
+// runtime. Both are stored as settings, so every instance picks them up on its next
+// snapshot refresh without a restart.
+type SecurityConfigHandler struct {
+	db     *gorm.DB         // Database handle for settings.
+	jwtCfg config.JWTConfig // Configured JWT secret and expiry.
+}
+
+// NewSecurityConfigHandler constructs a security config handler.
+func NewSecurityConfigHandler(db *gorm.DB, jwtCfg config.JWTConfig) *SecurityConfigHandler {
+	return &SecurityConfigHandler{db: db, jwtCfg: jwtCfg}
+}
+
+// JWTStatus reports where the signing secret comes from and the current overlap window.
+func (h *SecurityConfigHandler) JWTStatus(c *gin.Context) {
+	out := gin.H{
+		"source":         "config",
+		"expiry_seconds": int64(h.jwtCfg.Expiry / time.Second),
+	}
+	if rotation, ok := security.LoadJWTRotation(); ok {
+		out["source"] = "rotation"
+		out["rotated_at"] = rotation.RotatedAt
+		if rotation.PreviousValidUntil != nil && time.Now().Before(*rotation.PreviousValidUntil) {
+			out["previous_valid_until"] = rotation.PreviousValidUntil
+		}
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// rotateJWTRequest captures the payload for rotating the JWT secret.
+type rotateJWTRequest struct {
+	Secret         string `json:"secret"`          // New secret; generated when empty.
+	OverlapSeconds *int64 `json:"overlap_seconds"` // How long the replaced secret stays valid; 0 revokes it at once.
+}
+
+// RotateJWT replaces the JWT signing secret. Tokens signed with the replaced secret are
+// accepted until the overlap ends, at most the token expiry; with overlap 0 every session,
+// including the caller's, must log in again.
+func (h *SecurityConfigHandler) RotateJWT(c *gin.Context) {
+	var body rotateJWTRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	overlap := defaultJWTRotationOverlap
+	if body.OverlapSeconds != nil {
+		if *body.OverlapSeconds < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "overlap_seconds must not be negative"})
+			return
+		}
+		overlap = time.Duration(*body.OverlapSeconds) * time.Second
+	}
+	if h.jwtCfg.Expiry > 0 && overlap > h.jwtCfg.Expiry {
+		overlap = h.jwtCfg.Expiry
+	}
+	secret := strings.TrimSpace(body.Secret)
+	if secret == "" {
+		generated, errGenerate := security.GenerateRandomString(2 * security.MinJWTSecretLength)
+		if errGenerate != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "generate secret failed"})
+			return
+		}
+		secret = generated
+	}
+
+	rotation, errRotate := security.Rotate(h.jwtCfg.Secret, secret, overlap, time.Now())
+	if errRotate != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errRotate.Error()})
+		return
+	}
+	value, errMarshal := json.Marshal(rotation)
+	if errMarshal != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "encode rotation failed"})
+		return
+	}
+	if errSave := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		_, errSaveSetting := saveSetting(tx, c, internalsettings.JWTRotationKey, value)
+		return errSaveSetting
+	}); errSave != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "save rotation failed"})
+		return
+	}
+	if errRefresh := internalsettings.RefreshDBConfigSnapshot(c.Request.Context(), h.db); errRefresh != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "refresh settings snapshot failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"rotated_at":           rotation.RotatedAt,
+		"previous_valid_until": rotation.PreviousValidUntil,
+	})
+}
+
+// WebAuthnConfig returns the effective WebAuthn relying party.
+func (h *SecurityConfigHandler) WebAuthnConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, security.LoadWebAuthnConfig())
+}
+
+// updateWebAuthnRequest captures the payload for changing the WebAuthn relying party.
+type updateWebAuthnRequest struct {
+	RPID    string   `json:"rp_id"`   // Relying party ID.
+	RPName  string   `json:"rp_name"` // Relying party display name; unchanged when empty.
+	Origins []string `json:"origins"` // Allowed origins.
+}
+
+// UpdateWebAuthn validates and stores a new relying party. Ceremonies already in flight finish
+// against the old origins; the next one uses the new configuration. Passkeys registered
+// under another RP ID stop working, so changing it forces users to register again.
+func (h *SecurityConfigHandler) UpdateWebAuthn(c *gin.Context) {
+	var body updateWebAuthnRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	next := security.LoadWebAuthnConfig()
+	next.RPID = strings.TrimSpace(body.RPID)
+	if name := strings.TrimSpace(body.RPName); name != "" {
+		next.RPName = name
+	}
+	next.Origins = make([]string, 0, len(body.Origins))
+	for _, origin := range body.Origins {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			next.Origins = append(next.Origins, origin)
+		}
+	}
+	if errValidate := next.Validate(); errValidate != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errValidate.Error()})
+		return
+	}
+	if _, errBuild := next.Build(); errBuild != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errBuild.Error()})
+		return
+	}
+
+	values := make(map[string]json.RawMessage, 3)
+	for key, value := range map[string]any{
+		internalsettings.WebAuthnRPIDKey:    next.RPID,
+		internalsettings.WebAuthnRPNameKey:  next.RPName,
+		internalsettings.WebAuthnOriginsKey: next.Origins,
+	} {
+		raw, errMarshal := json.Marshal(value)
+		if errMarshal != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "encode webauthn settings failed"})
+			return
+		}
+		values[key] = raw
+	}
+	if errSave := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		for _, key := range []string{internalsettings.WebAuthnRPIDKey, internalsettings.WebAuthnRPNameKey, internalsettings.WebAuthnOriginsKey} {
+			if _, errSaveSetting := saveSetting(tx, c, key, values[key]); errSaveSetting != nil {
+				return errSaveSetting
+			}
+		}
+		return nil
+	}); errSave != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "save webauthn settings failed"})
+		return
+	}
+	if errRefresh := internalsettings.RefreshDBConfigSnapshot(c.Request.Context(), h.db); errRefresh != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "refresh settings snapshot failed"})
+		return
+	}
+	c.JSON(http.StatusOK, security.LoadWebAuthnConfig())
+}
@@ -1,18 +1,17 @@
 package handlers
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
-	"math"
 	"net/http"
-	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // SettingHandler manages admin CRUD for settings values.
@@ -31,20 +30,6 @@ type createSettingRequest struct {
 	Value json.RawMessage `json:"value"` // JSON value payload.
 }
 
-var positiveIntSettingKeys = map[string]struct{}{
-	internalsettings.QuotaPollIntervalSecondsKey: {},
-	internalsettings.QuotaPollMaxConcurrencyKey:  {},
-}
-
-var nonNegativeIntSettingKeys = map[string]struct{}{
-	internalsettings.RateLimitKey:           {},
-	internalsettings.RateLimitRedisDBKey:    {},
-	internalsettings.UsagesRetentionDaysKey: {},
-}
-
-var errPositiveIntegerValue = errors.New("value must be a positive integer")
-var errNonNegativeIntegerValue = errors.New("value must be a non-negative integer")
-
 // Create validates and inserts a setting, then refreshes the snapshot.
 func (h *SettingHandler) Create(c *gin.Context) {
 	var body createSettingRequest
@@ -65,7 +50,7 @@ func (h *SettingHandler) Create(c *gin.Context) {
 	}
 
 	var existing models.Setting
-	if errFind := h.db.WithContext(c.Request.Context()).Where("key = ?", key).First(&existing).Error; errFind == nil {
+	if errFind := h.db.WithContext(c.Request.Context()).Where("? = ?", clause.Column{Name: "key"}, key).First(&existing).Error; errFind == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "key already exists"})
 		return
 	}
@@ -75,7 +60,13 @@ func (h *SettingHandler) Create(c *gin.Context) {
 		Value: body.Value,
 	}
 
-	if errCreate := h.db.WithContext(c.Request.Context()).Create(&setting).Error; errCreate != nil {
+	errCreate := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if errCreate := tx.Create(&setting).Error; errCreate != nil {
+			return errCreate
+		}
+		return recordSettingChange(tx, c, key, models.SettingChangeCreate, nil, setting.Value)
+	})
+	if errCreate != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "create setting failed"})
 		return
 	}
@@ -83,19 +74,19 @@ func (h *SettingHandler) Create(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "refresh settings snapshot failed"})
 		return
 	}
-	c.JSON(http.StatusCreated, h.formatSetting(&setting))
+	c.JSON(http.StatusCreated, h.formatSetting(c, &setting))
 }
 
 // List returns all settings sorted by key.
 func (h *SettingHandler) List(c *gin.Context) {
 	var rows []models.Setting
-	if errFind := h.db.WithContext(c.Request.Context()).Order("key ASC").Find(&rows).Error; errFind != nil {
+	if errFind := h.db.WithContext(c.Request.Context()).Order(clause.OrderByColumn{Column: clause.Column{Name: "key"}}).Find(&rows).Error; errFind != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "list settings failed"})
 		return
 	}
 	out := make([]gin.H, 0, len(rows))
 	for _, row := range rows {
-		out = append(out, h.formatSetting(&row))
+		out = append(out, h.formatSetting(c, &row))
 	}
 	c.JSON(http.StatusOK, gin.H{"settings": out})
 }
@@ -108,7 +99,7 @@ func (h *SettingHandler) Get(c *gin.Context) {
 		return
 	}
 	var setting models.Setting
-	if errFind := h.db.WithContext(c.Request.Context()).Where("key = ?", key).First(&setting).Error; errFind != nil {
+	if errFind := h.db.WithContext(c.Request.Context()).Where("? = ?", clause.Column{Name: "key"}, key).First(&setting).Error; errFind != nil {
 		if errors.Is(errFind, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			return
@@ -116,7 +107,7 @@ func (h *SettingHandler) Get(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
 		return
 	}
-	c.JSON(http.StatusOK, h.formatSetting(&setting))
+	c.JSON(http.StatusOK, h.formatSetting(c, &setting))
 }
 
 // updateSettingRequest captures the payload for updating a setting.
@@ -143,7 +134,7 @@ func (h *SettingHandler) Update(c *gin.Context) {
 	}
 
 	var existing models.Setting
-	if errFind := h.db.WithContext(c.Request.Context()).Where("key = ?", key).First(&existing).Error; errFind != nil {
+	if errFind := h.db.WithContext(c.Request.Context()).Where("? = ?", clause.Column{Name: "key"}, key).First(&existing).Error; errFind != nil {
 		if errors.Is(errFind, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			return
@@ -152,9 +143,14 @@ func (h *SettingHandler) Update(c *gin.Context) {
 		return
 	}
 
-	res := h.db.WithContext(c.Request.Context()).Model(&models.Setting{}).Where("key = ?", key).
-		Update("value", body.Value)
-	if res.Error != nil {
+	errUpdate := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if errUpdate := tx.Model(&models.Setting{}).Where("? = ?", clause.Column{Name: "key"}, key).
+			Update("value", body.Value).Error; errUpdate != nil {
+			return errUpdate
+		}
+		return recordSettingChange(tx, c, key, models.SettingChangeUpdate, existing.Value, body.Value)
+	})
+	if errUpdate != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
 		return
 	}
@@ -172,13 +168,26 @@ func (h *SettingHandler) Delete(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid key"})
 		return
 	}
-	res := h.db.WithContext(c.Request.Context()).Where("key = ?", key).Delete(&models.Setting{})
-	if res.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+	if def, ok := internalsettings.LookupDefinition(key); ok && def.Managed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internalsettings.ErrManagedSetting.Error()})
 		return
 	}
-	if res.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	var existing models.Setting
+	errDelete := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if errFind := tx.Where("? = ?", clause.Column{Name: "key"}, key).First(&existing).Error; errFind != nil {
+			return errFind
+		}
+		if errDelete := tx.Where("? = ?", clause.Column{Name: "key"}, key).Delete(&models.Setting{}).Error; errDelete != nil {
+			return errDelete
+		}
+		return recordSettingChange(tx, c, key, models.SettingChangeDelete, existing.Value, nil)
+	})
+	if errDelete != nil {
+		if errors.Is(errDelete, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
 		return
 	}
 	if errRefresh := internalsettings.RefreshDBConfigSnapshot(c.Request.Context(), h.db); errRefresh != nil {
@@ -188,86 +197,23 @@ func (h *SettingHandler) Delete(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// validateSettingValue checks value against the settings registry. Keys missing from the
+// registry are accepted as free-form JSON on the per-key routes.
 func validateSettingValue(key string, value json.RawMessage) error {
-	if _, ok := positiveIntSettingKeys[key]; !ok {
-		if _, okNonNegative := nonNegativeIntSettingKeys[key]; !okNonNegative {
-			return nil
-		}
-		if _, ok := parseNonNegativeInt(value); !ok {
-			return errNonNegativeIntegerValue
-		}
-		return nil
-	}
-	if _, ok := parsePositiveInt(value); !ok {
-		return errPositiveIntegerValue
+	if key == internalsettings.EventWebhookTemplatesKey {
+		return events.ValidateWebhookTemplatesSetting(value)
 	}
-	return nil
-}
-
-func parsePositiveInt(raw json.RawMessage) (int, bool) {
-	raw = bytes.TrimSpace(raw)
-	if len(raw) == 0 {
-		return 0, false
-	}
-	var parsedInt int
-	if errUnmarshalInt := json.Unmarshal(raw, &parsedInt); errUnmarshalInt == nil {
-		return parsedInt, parsedInt > 0
-	}
-	var parsedString string
-	if errUnmarshalString := json.Unmarshal(raw, &parsedString); errUnmarshalString == nil {
-		parsed, errParse := strconv.Atoi(strings.TrimSpace(parsedString))
-		if errParse != nil {
-			return 0, false
-		}
-		return parsed, parsed > 0
-	}
-	var parsedFloat float64
-	if errUnmarshalFloat := json.Unmarshal(raw, &parsedFloat); errUnmarshalFloat == nil {
-		if math.IsNaN(parsedFloat) || math.IsInf(parsedFloat, 0) {
-			return 0, false
-		}
-		if parsedFloat <= 0 || parsedFloat != math.Trunc(parsedFloat) {
-			return 0, false
-		}
-		return int(parsedFloat), true
-	}
-	return 0, false
-}
-
-func parseNonNegativeInt(raw json.RawMessage) (int, bool) {
-	raw = bytes.TrimSpace(raw)
-	if len(raw) == 0 {
-		return 0, false
-	}
-	var parsedInt int
-	if errUnmarshalInt := json.Unmarshal(raw, &parsedInt); errUnmarshalInt == nil {
-		return parsedInt, parsedInt >= 0
-	}
-	var parsedString string
-	if errUnmarshalString := json.Unmarshal(raw, &parsedString); errUnmarshalString == nil {
-		parsed, errParse := strconv.Atoi(strings.TrimSpace(parsedString))
-		if errParse != nil {
-			return 0, false
-		}
-		return parsed, parsed >= 0
-	}
-	var parsedFloat float64
-	if errUnmarshalFloat := json.Unmarshal(raw, &parsedFloat); errUnmarshalFloat == nil {
-		if math.IsNaN(parsedFloat) || math.IsInf(parsedFloat, 0) {
-			return 0, false
-		}
-		if parsedFloat < 0 || parsedFloat != math.Trunc(parsedFloat) {
-			return 0, false
-		}
-		return int(parsedFloat), true
+	def, ok := internalsettings.LookupDefinition(key)
+	if !ok {
+		return nil
 	}
-	return 0, false
+	return def.Validate(value)
 }
 
-// formatSetting formats a setting row into response JSON.
-func (h *SettingHandler) formatSetting(s *models.Setting) gin.H {
+// formatSetting formats a setting row into response JSON, masking secret values.
+func (h *SettingHandler) formatSetting(c *gin.Context, s *models.Setting) gin.H {
 	return gin.H{
 		"key":   s.Key,
-		"value": s.Value,
+		"value": settingResponseValue(c, s.Key, s.Value),
 	}
 }
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	permissions "github.com/router-for-me/CLIProxyAPIBusiness/internal/http/api/admin/permissions"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// redactedSettingValue replaces secret values in the change history.
+var redactedSettingValue = json.RawMessage(`"[redacted]"`)
+
+// settingResponseValue masks stored values before they are returned. Managed settings are
+// always masked; other secret settings are masked for admins without the secrets permission.
+func settingResponseValue(c *gin.Context, key string, value json.RawMessage) json.RawMessage {
+	def, ok := internalsettings.LookupDefinition(key)
+	if !ok || len(bytes.TrimSpace(value)) == 0 {
+		return value
+	}
+	if def.Managed || (def.Secret && !canViewSettingSecrets(c)) {
+		return redactedSettingValue
+	}
+	return value
+}
+
+// canViewSettingSecrets reports whether the current admin may read secret settings.
+func canViewSettingSecrets(c *gin.Context) bool {
+	if c.GetBool("adminIsSuperAdmin") {
+		return true
+	}
+	value, _ := c.Get("adminPermissions")
+	adminPermissions, _ := value.([]string)
+	return permissions.HasPermission(adminPermissions, permissions.PermissionSecrets)
+}
+
+// recordSettingChange appends a history row for key inside tx. Values of secret settings
+// are replaced by a marker; nil values stay NULL.
+func recordSettingChange(tx *gorm.DB, c *gin.Context, key, action string, oldValue, newValue json.RawMessage) error {
+	if def, ok := internalsettings.LookupDefinition(key); ok && def.Secret {
+		if len(bytes.TrimSpace(oldValue)) > 0 {
+			oldValue = redactedSettingValue
+		}
+		if len(bytes.TrimSpace(newValue)) > 0 {
+			newValue = redactedSettingValue
+		}
+	}
+	change := models.SettingChange{
+		Key:           key,
+		Action:        action,
+		OldValue:      oldValue,
+		NewValue:      newValue,
+		AdminUsername: strings.TrimSpace(c.GetString("adminUsername")),
+	}
+	if adminID, ok := readAdminIDFromContext(c); ok {
+		change.AdminID = &adminID
+	}
+	return tx.Create(&change).Error
+}
+
+// settingHistoryQuery defines filters for the setting change history.
+type settingHistoryQuery struct {
+	Page  int    `form:"page,default=1"`   // Page number.
+	Limit int    `form:"limit,default=20"` // Page size.
+	Key   string `form:"key"`              // Setting key.
+}
+
+// History returns setting changes newest first.
+func (h *SettingHandler) History(c *gin.Context) {
+	var q settingHistoryQuery
+	if errBind := c.ShouldBindQuery(&q); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query"})
+		return
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.Limit < 1 || q.Limit > 100 {
+		q.Limit = 20
+	}
+
+	query := h.db.WithContext(c.Request.Context()).Model(&models.SettingChange{})
+	if key := strings.TrimSpace(q.Key); key != "" {
+		query = query.Where("? = ?", clause.Column{Name: "key"}, key)
+	}
+
+	var total int64
+	if errCount := query.Session(&gorm.Session{}).Count(&total).Error; errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "count setting changes failed"})
+		return
+	}
+	var rows []models.SettingChange
+	if errFind := query.
+		Order("created_at DESC").Order("id DESC").
+		Offset((q.Page - 1) * q.Limit).Limit(q.Limit).
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list setting changes failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		out = append(out, gin.H{
+			"id":             row.ID,
+			"key":            row.Key,
+			"action":         row.Action,
+			"old_value":      row.OldValue,
+			"new_value":      row.NewValue,
+			"admin_id":       row.AdminID,
+			"admin_username": row.AdminUsername,
+			"created_at":     row.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"changes": out,
+		"total":   total,
+		"page":    q.Page,
+		"limit":   q.Limit,
+	})
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// bulkUpdateSettingsRequest captures the payload for updating several settings at once.
+type bulkUpdateSettingsRequest struct {
+	Settings map[string]json.RawMessage `json:"settings"` // Registered key to new JSON value.
+}
+
+// BulkUpdate validates every value against the settings registry and, when all pass, writes
+// them in one transaction. Unknown keys and invalid values are reported per key and nothing
+// is written.
+func (h *SettingHandler) BulkUpdate(c *gin.Context) {
+	var body bulkUpdateSettingsRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	if len(body.Settings) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "settings is required"})
+		return
+	}
+
+	values := make(map[string]json.RawMessage, len(body.Settings))
+	invalid := make(map[string]string)
+	for rawKey, value := range body.Settings {
+		key := strings.TrimSpace(rawKey)
+		errValidate := internalsettings.ValidateValue(key, value)
+		if errValidate == nil && key == internalsettings.EventWebhookTemplatesKey {
+			errValidate = events.ValidateWebhookTemplatesSetting(value)
+		}
+		if errValidate != nil {
+			invalid[rawKey] = errValidate.Error()
+			continue
+		}
+		values[key] = json.RawMessage(bytes.TrimSpace(value))
+	}
+	if len(invalid) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid settings", "errors": invalid})
+		return
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	changed := make([]string, 0, len(keys))
+	errSave := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		for _, key := range keys {
+			saved, errSaveSetting := saveSetting(tx, c, key, values[key])
+			if errSaveSetting != nil {
+				return errSaveSetting
+			}
+			if saved {
+				changed = append(changed, key)
+			}
+		}
+		return nil
+	})
+	if errSave != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update settings failed"})
+		return
+	}
+	if len(changed) > 0 {
+		if errRefresh := internalsettings.RefreshDBConfigSnapshot(c.Request.Context(), h.db); errRefresh != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "refresh settings snapshot failed"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"changed": changed})
+}
+
+// Registry returns every registered setting with its schema and stored value.
+func (h *SettingHandler) Registry(c *gin.Context) {
+	var rows []models.Setting
+	if errFind := h.db.WithContext(c.Request.Context()).Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list settings failed"})
+		return
+	}
+	stored := make(map[string]json.RawMessage, len(rows))
+	for _, row := range rows {
+		stored[row.Key] = row.Value
+	}
+	defs := internalsettings.Definitions()
+	out := make([]gin.H, 0, len(defs))
+	for _, def := range defs {
+		value, ok := stored[def.Key]
+		out = append(out, gin.H{
+			"definition": def,
+			"value":      settingResponseValue(c, def.Key, value),
+			"configured": ok,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"settings": out})
+}
+
+// saveSetting creates or updates key inside tx and records the change. It reports false when
+// the stored value already equals value.
+func saveSetting(tx *gorm.DB, c *gin.Context, key string, value json.RawMessage) (bool, error) {
+	var existing models.Setting
+	errFind := tx.Where("? = ?", clause.Column{Name: "key"}, key).First(&existing).Error
+	switch {
+	case errors.Is(errFind, gorm.ErrRecordNotFound):
+		if errCreate := tx.Create(&models.Setting{Key: key, Value: value}).Error; errCreate != nil {
+			return false, errCreate
+		}
+		return true, recordSettingChange(tx, c, key, models.SettingChangeCreate, nil, value)
+	case errFind != nil:
+		return false, errFind
+	}
+	if settingValuesEqual(existing.Value, value) {
+		return false, nil
+	}
+	if errUpdate := tx.Model(&models.Setting{}).Where("? = ?", clause.Column{Name: "key"}, key).
+		Update("value", value).Error; errUpdate != nil {
+		return false, errUpdate
+	}
+	return true, recordSettingChange(tx, c, key, models.SettingChangeUpdate, existing.Value, value)
+}
+
+// settingValuesEqual reports whether two JSON values are semantically equal.
+func settingValuesEqual(a, b json.RawMessage) bool {
+	var left, right any
+	if json.Unmarshal(a, &left) != nil || json.Unmarshal(b, &right) != nil {
+		return bytes.Equal(bytes.TrimSpace(a), bytes.TrimSpace(b))
+	}
+	leftJSON, _ := json.Marshal(left)
+	rightJSON, _ := json.Marshal(right)
+	return bytes.Equal(leftJSON, rightJSON)
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/slo"
+	"gorm.io/gorm"
+)
+
+// SLOHandler manages service level objectives and reports their status.
+type SLOHandler struct {
+	db *gorm.DB // Database handle for SLO records.
+}
+
+// NewSLOHandler constructs an SLO handler.
+func NewSLOHandler(db *gorm.DB) *SLOHandler {
+	return &SLOHandler{db: db}
+}
+
+// sloRequest captures the payload for creating or updating an SLO.
+type sloRequest struct {
+	Name            *string  `json:"name"`
+	Description     *string  `json:"description"`
+	Kind            *string  `json:"kind"`             // latency or error_rate.
+	ThresholdMillis *int64   `json:"threshold_millis"` // Latency threshold; latency SLOs only.
+	Objective       *float64 `json:"objective"`        // Target share of good requests, in percent.
+	WindowDays      *int     `json:"window_days"`      // Compliance window; defaults to 30.
+	Provider        *string  `json:"provider"`
+	Model           *string  `json:"model"`
+	IsEnabled       *bool    `json:"is_enabled"`
+}
+
+// apply copies the set fields onto row.
+func (r *sloRequest) apply(row *models.SLO) {
+	if r.Name != nil {
+		row.Name = *r.Name
+	}
+	if r.Description != nil {
+		row.Description = *r.Description
+	}
+	if r.Kind != nil {
+		row.Kind = models.SLOKind(*r.Kind)
+	}
+	if r.ThresholdMillis != nil {
+		row.ThresholdMillis = *r.ThresholdMillis
+	}
+	if r.Objective != nil {
+		row.Objective = *r.Objective
+	}
+	if r.WindowDays != nil {
+		row.WindowDays = *r.WindowDays
+	}
+	if r.Provider != nil {
+		row.Provider = *r.Provider
+	}
+	if r.Model != nil {
+		row.Model = *r.Model
+	}
+	if r.IsEnabled != nil {
+		row.IsEnabled = *r.IsEnabled
+	}
+}
+
+// Create validates and inserts a new SLO.
+func (h *SLOHandler) Create(c *gin.Context) {
+	var body sloRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	row := models.SLO{IsEnabled: true}
+	body.apply(&row)
+	if errNormalize := slo.Normalize(&row); errNormalize != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errNormalize.Error()})
+		return
+	}
+	if h.nameTaken(c, row.Name, 0) {
+		c.JSON(http.StatusConflict, gin.H{"error": "name already exists"})
+		return
+	}
+	if errCreate := h.db.WithContext(c.Request.Context()).Create(&row).Error; errCreate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create slo failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, formatSLO(&row))
+}
+
+// List returns every SLO with its last evaluated state.
+func (h *SLOHandler) List(c *gin.Context) {
+	var rows []models.SLO
+	if errFind := h.db.WithContext(c.Request.Context()).Order("name ASC").Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list slos failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatSLO(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"slos": out})
+}
+
+// Update validates and updates an SLO by ID.
+func (h *SLOHandler) Update(c *gin.Context) {
+	row, ok := h.load(c)
+	if !ok {
+		return
+	}
+	var body sloRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	body.apply(row)
+	if errNormalize := slo.Normalize(row); errNormalize != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errNormalize.Error()})
+		return
+	}
+	if h.nameTaken(c, row.Name, row.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "name already exists"})
+		return
+	}
+	row.UpdatedAt = time.Now().UTC()
+	if errSave := h.db.WithContext(c.Request.Context()).Save(row).Error; errSave != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update slo failed"})
+		return
+	}
+	c.JSON(http.StatusOK, formatSLO(row))
+}
+
+// Delete removes an SLO by ID.
+func (h *SLOHandler) Delete(c *gin.Context) {
+	id, errID := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	res := h.db.WithContext(c.Request.Context()).Delete(&models.SLO{}, "id = ?", id)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete slo failed"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "slo not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": true})
+}
+
+// Status evaluates every enabled SLO now for the dashboard.
+func (h *SLOHandler) Status(c *gin.Context) {
+	ctx := c.Request.Context()
+	var rows []models.SLO
+	if errFind := h.db.WithContext(ctx).Where("is_enabled = ?", true).Order("name ASC").Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list slos failed"})
+		return
+	}
+	now := time.Now().UTC()
+	out := make([]slo.Status, 0, len(rows))
+	for i := range rows {
+		status, errEval := slo.Evaluate(ctx, h.db, &rows[i], now)
+		if errEval != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "evaluate slo failed"})
+			return
+		}
+		out = append(out, status)
+	}
+	c.JSON(http.StatusOK, gin.H{"slos": out})
+}
+
+// StatusOne evaluates a single SLO now, enabled or not.
+func (h *SLOHandler) StatusOne(c *gin.Context) {
+	row, ok := h.load(c)
+	if !ok {
+		return
+	}
+	status, errEval := slo.Evaluate(c.Request.Context(), h.db, row, time.Now().UTC())
+	if errEval != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "evaluate slo failed"})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+func (h *SLOHandler) load(c *gin.Context) (*models.SLO, bool) {
+	id, errID := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return nil, false
+	}
+	var row models.SLO
+	if errFind := h.db.WithContext(c.Request.Context()).First(&row, "id = ?", id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "slo not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "fetch slo failed"})
+		return nil, false
+	}
+	return &row, true
+}
+
+func (h *SLOHandler) nameTaken(c *gin.Context, name string, exceptID uint64) bool {
+	var count int64
+	h.db.WithContext(c.Request.Context()).Model(&models.SLO{}).
+		Where("name = ? AND id <> ?", name, exceptID).
+		Count(&count)
+	return count > 0
+}
+
+// formatSLO converts an SLO row into a response payload.
+func formatSLO(row *models.SLO) gin.H {
+	return gin.H{
+		"id":                    row.ID,
+		"name":                  row.Name,
+		"description":           row.Description,
+		"kind":                  row.Kind,
+		"threshold_millis":      row.ThresholdMillis,
+		"objective":             row.Objective,
+		"window_days":           row.WindowDays,
+		"provider":              row.Provider,
+		"model":                 row.Model,
+		"is_enabled":            row.IsEnabled,
+		"last_evaluated_at":     row.LastEvaluatedAt,
+		"last_state":            row.LastState,
+		"last_compliance":       row.LastCompliance,
+		"last_budget_remaining": row.LastBudgetRemaining,
+		"last_alerted_at":       row.LastAlertedAt,
+		"created_at":            row.CreatedAt,
+		"updated_at":            row.UpdatedAt,
+	}
+}
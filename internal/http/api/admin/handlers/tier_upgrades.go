@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/tierupgrade"
+	"gorm.io/gorm"
+)
+
+// TierUpgradeHandler manages tier upgrade rules and upgrade decisions.
+type TierUpgradeHandler struct {
+	db *gorm.DB // Database handle for tier upgrade records.
+}
+
+// NewTierUpgradeHandler constructs a tier upgrade handler.
+func NewTierUpgradeHandler(db *gorm.DB) *TierUpgradeHandler {
+	return &TierUpgradeHandler{db: db}
+}
+
+// createTierUpgradeRuleRequest captures the payload for creating a rule.
+type createTierUpgradeRuleRequest struct {
+	Name                string  `json:"name"`                 // Rule name.
+	FromUserGroupID     uint64  `json:"from_user_group_id"`   // Watched user group ID.
+	ToUserGroupID       uint64  `json:"to_user_group_id"`     // Target user group ID.
+	WindowDays          int     `json:"window_days"`          // Rolling window in days.
+	ThresholdAmount     float64 `json:"threshold_amount"`     // Usage cost threshold.
+	RequireConfirmation *bool   `json:"require_confirmation"` // Optional confirmation flag.
+	IsEnabled           *bool   `json:"is_enabled"`           // Optional active flag.
+}
+
+// CreateRule validates input and inserts a tier upgrade rule.
+func (h *TierUpgradeHandler) CreateRule(c *gin.Context) {
+	var body createTierUpgradeRuleRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+
+	name := strings.TrimSpace(body.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+	if body.WindowDays <= 0 {
+		body.WindowDays = 7
+	}
+	if body.ThresholdAmount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "threshold_amount must be positive"})
+		return
+	}
+	if errGroups := h.validateGroups(c, body.FromUserGroupID, body.ToUserGroupID); errGroups != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errGroups})
+		return
+	}
+
+	requireConfirmation := true
+	if body.RequireConfirmation != nil {
+		requireConfirmation = *body.RequireConfirmation
+	}
+	isEnabled := true
+	if body.IsEnabled != nil {
+		isEnabled = *body.IsEnabled
+	}
+
+	now := time.Now().UTC()
+	rule := models.TierUpgradeRule{
+		Name:                name,
+		FromUserGroupID:     body.FromUserGroupID,
+		ToUserGroupID:       body.ToUserGroupID,
+		WindowDays:          body.WindowDays,
+		ThresholdAmount:     body.ThresholdAmount,
+		RequireConfirmation: requireConfirmation,
+		IsEnabled:           isEnabled,
+		CreatedAt:           now,
+		UpdatedAt:           now,
+	}
+	if errCreate := h.db.WithContext(c.Request.Context()).Create(&rule).Error; errCreate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create rule failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, formatTierUpgradeRule(&rule))
+}
+
+// ListRules returns all tier upgrade rules.
+func (h *TierUpgradeHandler) ListRules(c *gin.Context) {
+	var rows []models.TierUpgradeRule
+	if errFind := h.db.WithContext(c.Request.Context()).Order("id ASC").Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list rules failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatTierUpgradeRule(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"rules": out})
+}
+
+// updateTierUpgradeRuleRequest captures optional fields for rule updates.
+type updateTierUpgradeRuleRequest struct {
+	Name                *string  `json:"name"`                 // Optional rule name.
+	FromUserGroupID     *uint64  `json:"from_user_group_id"`   // Optional watched user group ID.
+	ToUserGroupID       *uint64  `json:"to_user_group_id"`     // Optional target user group ID.
+	WindowDays          *int     `json:"window_days"`          // Optional rolling window in days.
+	ThresholdAmount     *float64 `json:"threshold_amount"`     // Optional usage cost threshold.
+	RequireConfirmation *bool    `json:"require_confirmation"` // Optional confirmation flag.
+	IsEnabled           *bool    `json:"is_enabled"`           // Optional active flag.
+}
+
+// UpdateRule applies field updates to a tier upgrade rule.
+func (h *TierUpgradeHandler) UpdateRule(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var body updateTierUpgradeRuleRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+
+	var existing models.TierUpgradeRule
+	if errFind := h.db.WithContext(c.Request.Context()).First(&existing, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	updates := map[string]any{
+		"updated_at": time.Now().UTC(),
+	}
+	if body.Name != nil {
+		name := strings.TrimSpace(*body.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+			return
+		}
+		updates["name"] = name
+	}
+	fromID, toID := existing.FromUserGroupID, existing.ToUserGroupID
+	if body.FromUserGroupID != nil {
+		fromID = *body.FromUserGroupID
+	}
+	if body.ToUserGroupID != nil {
+		toID = *body.ToUserGroupID
+	}
+	if body.FromUserGroupID != nil || body.ToUserGroupID != nil {
+		if errGroups := h.validateGroups(c, fromID, toID); errGroups != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errGroups})
+			return
+		}
+		updates["from_user_group_id"] = fromID
+		updates["to_user_group_id"] = toID
+	}
+	if body.WindowDays != nil {
+		if *body.WindowDays <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "window_days must be positive"})
+			return
+		}
+		updates["window_days"] = *body.WindowDays
+	}
+	if body.ThresholdAmount != nil {
+		if *body.ThresholdAmount <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "threshold_amount must be positive"})
+			return
+		}
+		updates["threshold_amount"] = *body.ThresholdAmount
+	}
+	if body.RequireConfirmation != nil {
+		updates["require_confirmation"] = *body.RequireConfirmation
+	}
+	if body.IsEnabled != nil {
+		updates["is_enabled"] = *body.IsEnabled
+	}
+
+	if errUpdate := h.db.WithContext(c.Request.Context()).Model(&models.TierUpgradeRule{}).
+		Where("id = ?", id).Updates(updates).Error; errUpdate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// DeleteRule removes a tier upgrade rule by ID.
+func (h *TierUpgradeHandler) DeleteRule(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	res := h.db.WithContext(c.Request.Context()).Delete(&models.TierUpgradeRule{}, id)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// List returns tier upgrades, optionally filtered by status and user.
+func (h *TierUpgradeHandler) List(c *gin.Context) {
+	q := h.db.WithContext(c.Request.Context()).Model(&models.TierUpgrade{})
+	if status := strings.TrimSpace(c.Query("status")); status != "" {
+		q = q.Where("status = ?", status)
+	}
+	if rawUserID := strings.TrimSpace(c.Query("user_id")); rawUserID != "" {
+		userID, errParse := strconv.ParseUint(rawUserID, 10, 64)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+			return
+		}
+		q = q.Where("user_id = ?", userID)
+	}
+
+	var rows []models.TierUpgrade
+	if errFind := q.Order("created_at DESC").Limit(500).Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list upgrades failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatTierUpgrade(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"upgrades": out})
+}
+
+// Approve applies a pending tier upgrade on behalf of the user.
+func (h *TierUpgradeHandler) Approve(c *gin.Context) {
+	h.decide(c, true)
+}
+
+// Reject declines a pending tier upgrade.
+func (h *TierUpgradeHandler) Reject(c *gin.Context) {
+	h.decide(c, false)
+}
+
+func (h *TierUpgradeHandler) decide(c *gin.Context, approve bool) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var errDecide error
+	if approve {
+		errDecide = tierupgrade.Apply(c.Request.Context(), h.db, id, 0)
+	} else {
+		errDecide = tierupgrade.Decline(c.Request.Context(), h.db, id, 0)
+	}
+	switch {
+	case errDecide == nil:
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	case errors.Is(errDecide, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(errDecide, tierupgrade.ErrUpgradeNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": "upgrade is not pending"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+	}
+}
+
+// validateGroups checks both user groups exist and differ; returns an error message or "".
+func (h *TierUpgradeHandler) validateGroups(c *gin.Context, fromID, toID uint64) string {
+	if fromID == 0 || toID == 0 {
+		return "from_user_group_id and to_user_group_id are required"
+	}
+	if fromID == toID {
+		return "from_user_group_id and to_user_group_id must differ"
+	}
+	var count int64
+	if errCount := h.db.WithContext(c.Request.Context()).Model(&models.UserGroup{}).
+		Where("id IN ?", []uint64{fromID, toID}).Count(&count).Error; errCount != nil || count != 2 {
+		return "user group not found"
+	}
+	return ""
+}
+
+// formatTierUpgradeRule converts a rule into a response payload.
+func formatTierUpgradeRule(rule *models.TierUpgradeRule) gin.H {
+	return gin.H{
+		"id":                   rule.ID,
+		"name":                 rule.Name,
+		"from_user_group_id":   rule.FromUserGroupID,
+		"to_user_group_id":     rule.ToUserGroupID,
+		"window_days":          rule.WindowDays,
+		"threshold_amount":     rule.ThresholdAmount,
+		"require_confirmation": rule.RequireConfirmation,
+		"is_enabled":           rule.IsEnabled,
+		"created_at":           rule.CreatedAt,
+		"updated_at":           rule.UpdatedAt,
+	}
+}
+
+// formatTierUpgrade converts a tier upgrade into a response payload.
+func formatTierUpgrade(upgrade *models.TierUpgrade) gin.H {
+	return gin.H{
+		"id":                 upgrade.ID,
+		"rule_id":            upgrade.RuleID,
+		"user_id":            upgrade.UserID,
+		"from_user_group_id": upgrade.FromUserGroupID,
+		"to_user_group_id":   upgrade.ToUserGroupID,
+		"usage_amount":       upgrade.UsageAmount,
+		"status":             upgrade.Status,
+		"decided_at":         upgrade.DecidedAt,
+		"created_at":         upgrade.CreatedAt,
+	}
+}
@@ -1,16 +1,25 @@
 package handlers
 
 import (
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"gorm.io/gorm"
 )
 
+// usageSortColumns maps the sort parameter to the usages column it orders by.
+var usageSortColumns = map[string]string{
+	"requested_at": "requested_at",
+	"cost":         "cost_micros",
+	"total_tokens": "total_tokens",
+}
+
 // UsageHandler handles admin usage listing endpoints.
 type UsageHandler struct {
 	db *gorm.DB
@@ -21,46 +30,173 @@ func NewUsageHandler(db *gorm.DB) *UsageHandler {
 	return &UsageHandler{db: db}
 }
 
-// List returns usage records with optional filters.
+// usageSearchQuery defines the filters of the usage search.
+type usageSearchQuery struct {
+	UserID    string `form:"user_id"`    // Requesting user.
+	APIKeyID  string `form:"api_key_id"` // API key used.
+	AuthID    string `form:"auth_id"`    // Auth file that served the request.
+	ProxyHash string `form:"proxy_hash"` // Fingerprint of the proxy that served the request.
+	Provider  string `form:"provider"`   // Provider name.
+	Model     string `form:"model"`      // Model name.
+	Status    string `form:"status"`     // Upstream error status code.
+	ErrorCode string `form:"error_code"` // Canonical failure cause.
+	Failed    string `form:"failed"`     // "true" for failures only, "false" for successes only.
+	MinCost   string `form:"min_cost"`   // Lower bound on cost, inclusive.
+	MaxCost   string `form:"max_cost"`   // Upper bound on cost, inclusive.
+	From      string `form:"from"`       // RFC 3339 lower bound on request time, inclusive.
+	To        string `form:"to"`         // RFC 3339 upper bound on request time, inclusive.
+	Sort      string `form:"sort"`       // requested_at, cost or total_tokens.
+	Order     string `form:"order"`      // desc (default) or asc.
+	Limit     string `form:"limit"`      // Page size, at most 1000.
+	Cursor    string `form:"cursor"`     // next_cursor of the previous page.
+}
+
+// List searches usage records. Every filter is optional and filters combine with AND. Rows
+// are ordered by the sort column and then by ID; pass next_cursor back as cursor, with the
+// same filters and sort, until has_more is false.
 func (h *UsageHandler) List(c *gin.Context) {
-	var (
-		apiKeyIDStr = strings.TrimSpace(c.Query("api_key_id"))
-		fromStr     = strings.TrimSpace(c.Query("from"))
-		toStr       = strings.TrimSpace(c.Query("to"))
-		limitStr    = strings.TrimSpace(c.Query("limit"))
-	)
+	var q usageSearchQuery
+	if errBind := c.ShouldBindQuery(&q); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query"})
+		return
+	}
 
 	limit := 100
-	if limitStr != "" {
-		if v, err := strconv.Atoi(limitStr); err == nil && v > 0 {
+	if raw := strings.TrimSpace(q.Limit); raw != "" {
+		if v, err := strconv.Atoi(raw); err == nil && v > 0 {
 			if v > 1000 {
 				v = 1000
 			}
 			limit = v
 		}
 	}
+	sortKey := strings.ToLower(strings.TrimSpace(q.Sort))
+	if sortKey == "" {
+		sortKey = "requested_at"
+	}
+	column, ok := usageSortColumns[sortKey]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sort, expected requested_at, cost or total_tokens"})
+		return
+	}
+	order := strings.ToLower(strings.TrimSpace(q.Order))
+	switch order {
+	case "":
+		order = "desc"
+	case "asc", "desc":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order, expected asc or desc"})
+		return
+	}
 
-	q := h.db.WithContext(c.Request.Context()).Model(&models.Usage{})
-	if apiKeyIDStr != "" {
-		if id, errParseUint := strconv.ParseUint(apiKeyIDStr, 10, 64); errParseUint == nil {
-			q = q.Where("api_key_id = ?", id)
+	query := scopeToOrganizationUsers(c, h.db, h.db.WithContext(c.Request.Context()).Model(&models.Usage{}), "user_id")
+	for _, filter := range []struct {
+		name, column, raw string
+	}{
+		{"user_id", "user_id", q.UserID},
+		{"api_key_id", "api_key_id", q.APIKeyID},
+		{"auth_id", "auth_id", q.AuthID},
+	} {
+		raw := strings.TrimSpace(filter.raw)
+		if raw == "" {
+			continue
+		}
+		id, errParse := strconv.ParseUint(raw, 10, 64)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + filter.name})
+			return
+		}
+		query = query.Where(filter.column+" = ?", id)
+	}
+	if provider := strings.TrimSpace(q.Provider); provider != "" {
+		query = query.Where("provider = ?", provider)
+	}
+	if model := strings.TrimSpace(q.Model); model != "" {
+		query = query.Where("model = ?", model)
+	}
+	if proxyHash := strings.TrimSpace(q.ProxyHash); proxyHash != "" {
+		query = query.Where("proxy_hash = ?", proxyHash)
+	}
+	if errorCode := strings.TrimSpace(q.ErrorCode); errorCode != "" {
+		query = query.Where("error_code = ?", errorCode)
+	}
+	if raw := strings.TrimSpace(q.Status); raw != "" {
+		status, errParse := strconv.Atoi(raw)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+			return
 		}
+		query = query.Where("error_status_code = ?", status)
 	}
-	if fromStr != "" {
-		if t, err := time.Parse(time.RFC3339, fromStr); err == nil {
-			q = q.Where("requested_at >= ?", t.UTC())
+	if raw := strings.TrimSpace(q.Failed); raw != "" {
+		failed, errParse := strconv.ParseBool(raw)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid failed"})
+			return
 		}
+		query = query.Where("failed = ?", failed)
 	}
-	if toStr != "" {
-		if t, err := time.Parse(time.RFC3339, toStr); err == nil {
-			q = q.Where("requested_at <= ?", t.UTC())
+	for _, bound := range []struct {
+		name, raw, op string
+	}{{"min_cost", q.MinCost, ">="}, {"max_cost", q.MaxCost, "<="}} {
+		raw := strings.TrimSpace(bound.raw)
+		if raw == "" {
+			continue
+		}
+		cost, errParse := strconv.ParseFloat(raw, 64)
+		if errParse != nil || math.IsNaN(cost) || math.IsInf(cost, 0) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + bound.name})
+			return
 		}
+		query = query.Where("cost_micros "+bound.op+" ?", int64(math.Round(cost*1_000_000)))
+	}
+	for _, bound := range []struct {
+		name, raw, op string
+	}{{"from", q.From, ">="}, {"to", q.To, "<="}} {
+		raw := strings.TrimSpace(bound.raw)
+		if raw == "" {
+			continue
+		}
+		at, errParse := time.Parse(time.RFC3339, raw)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + bound.name + ", expected RFC 3339"})
+			return
+		}
+		query = query.Where("requested_at "+bound.op+" ?", at.UTC())
+	}
+
+	keyset := dbutil.Keyset{Column: "usages." + column, IDColumn: "usages.id", Time: sortKey == "requested_at", Desc: order == "desc"}
+	var after *dbutil.Cursor
+	if cursor := strings.TrimSpace(q.Cursor); cursor != "" {
+		pos, errCursor := keyset.Decode(cursor)
+		if errCursor != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		after = &pos
 	}
 
 	var rows []models.Usage
-	if errFind := q.Order("requested_at DESC").Limit(limit).Find(&rows).Error; errFind != nil {
+	if errFind := keyset.Apply(query, after).
+		Limit(limit + 1).
+		Find(&rows).Error; errFind != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"usage": rows})
+	hasMore := len(rows) > limit
+	rows, nextCursor := dbutil.NextPage(keyset, rows, limit, func(row models.Usage) dbutil.Cursor {
+		switch sortKey {
+		case "cost":
+			return dbutil.Cursor{Value: row.CostMicros, ID: row.ID}
+		case "total_tokens":
+			return dbutil.Cursor{Value: row.TotalTokens, ID: row.ID}
+		default:
+			return dbutil.Cursor{Value: dbutil.TimeValue(row.RequestedAt), ID: row.ID}
+		}
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"usage":       rows,
+		"has_more":    hasMore,
+		"next_cursor": nextCursor,
+	})
 }
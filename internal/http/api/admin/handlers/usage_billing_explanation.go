@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// BillingExplanation re-runs the billing calculation for a usage record and returns each step.
+// Rules are evaluated as they are now; the response flags when the result differs from what was charged.
+func (h *UsageHandler) BillingExplanation(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var row models.Usage
+	if errFind := h.db.WithContext(ctx).First(&row, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	explanation, errExplain := billing.ExplainCost(ctx, h.db, billing.CostInput{
+		Provider:        row.Provider,
+		Model:           row.Model,
+		APIKeyID:        row.APIKeyID,
+		UserID:          row.UserID,
+		AuthID:          row.AuthID,
+		UserGroupID:     row.UserGroupID,
+		Failed:          row.Failed,
+		InputTokens:     row.InputTokens,
+		OutputTokens:    row.OutputTokens,
+		ReasoningTokens: row.ReasoningTokens,
+		CachedTokens:    row.CachedTokens,
+
+		CacheCreationTokens: row.CacheCreationTokens,
+	})
+	if errExplain != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "explain billing failed"})
+		return
+	}
+
+	var currentRuleID *uint64
+	if explanation.Rule != nil {
+		ruleID := explanation.Rule.ID
+		currentRuleID = &ruleID
+	}
+	ruleChanged := row.BillingRuleID != nil && (currentRuleID == nil || *currentRuleID != *row.BillingRuleID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"usage": gin.H{
+			"id":                    row.ID,
+			"provider":              row.Provider,
+			"model":                 row.Model,
+			"requested_at":          row.RequestedAt,
+			"request_id":            row.RequestID,
+			"user_id":               row.UserID,
+			"user_group_id":         row.UserGroupID,
+			"api_key_id":            row.APIKeyID,
+			"auth_id":               row.AuthID,
+			"failed":                row.Failed,
+			"input_tokens":          row.InputTokens,
+			"output_tokens":         row.OutputTokens,
+			"reasoning_tokens":      row.ReasoningTokens,
+			"cached_tokens":         row.CachedTokens,
+			"cache_creation_tokens": row.CacheCreationTokens,
+			"total_tokens":          row.TotalTokens,
+		},
+		"recorded": gin.H{
+			"cost_micros":     row.CostMicros,
+			"cost":            float64(row.CostMicros) / 1_000_000,
+			"billing_rule_id": row.BillingRuleID,
+			"charged_to":      row.ChargedTo,
+			"charged_balance": describeChargedBalance(&row),
+		},
+		"explanation":            explanation,
+		"recomputed_cost_micros": explanation.TotalMicros,
+		"recomputed_cost":        float64(explanation.TotalMicros) / 1_000_000,
+		"matches_recorded":       explanation.TotalMicros == row.CostMicros,
+		"rule_changed":           ruleChanged,
+	})
+}
+
+// describeChargedBalance explains which balance a usage record was deducted from.
+func describeChargedBalance(row *models.Usage) string {
+	switch row.ChargedTo {
+	case "bill":
+		return "deducted from the quota of the user's active paid bills"
+	case "prepaid":
+		return "deducted from the user's prepaid card balance"
+	}
+	switch {
+	case row.CostMicros == 0:
+		return "not charged: cost was zero"
+	case row.UserID == nil:
+		return "not charged: request has no user"
+	default:
+		return "not charged"
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/usagerollup"
+)
+
+// usageDailyDefaultDays is the range returned when no from date is given.
+const usageDailyDefaultDays = 365
+
+// Daily returns usage rolled up into usage_daily once raw rows left the retention window.
+// Optional from/to query parameters accept YYYY-MM-DD local dates (inclusive); provider,
+// model, user_id and api_key_id narrow the buckets.
+func (h *UsageHandler) Daily(c *gin.Context) {
+	today := usagerollup.DayStart(time.Now())
+	from := today.AddDate(0, 0, -usageDailyDefaultDays+1)
+	to := today
+
+	if raw := strings.TrimSpace(c.Query("from")); raw != "" {
+		parsed, errParse := time.ParseInLocation(time.DateOnly, raw, time.Local)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+			return
+		}
+		from = parsed
+	}
+	if raw := strings.TrimSpace(c.Query("to")); raw != "" {
+		parsed, errParse := time.ParseInLocation(time.DateOnly, raw, time.Local)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+			return
+		}
+		to = parsed
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must not be before from"})
+		return
+	}
+
+	q := h.db.WithContext(c.Request.Context()).
+		Model(&models.UsageDaily{}).
+		Where("day >= ? AND day < ?", from, to.AddDate(0, 0, 1))
+	if provider := strings.TrimSpace(c.Query("provider")); provider != "" {
+		q = q.Where("provider = ?", provider)
+	}
+	if model := strings.TrimSpace(c.Query("model")); model != "" {
+		q = q.Where("model = ?", model)
+	}
+	for _, filter := range []string{"user_id", "api_key_id"} {
+		raw := strings.TrimSpace(c.Query(filter))
+		if raw == "" {
+			continue
+		}
+		id, errParse := strconv.ParseUint(raw, 10, 64)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + filter})
+			return
+		}
+		q = q.Where(filter+" = ?", id)
+	}
+
+	var rows []models.UsageDaily
+	if errFind := q.Order("day ASC, provider ASC, model ASC, user_id ASC, api_key_id ASC").Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	out := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, gin.H{
+			"day":               row.Day.In(time.Local).Format(time.DateOnly),
+			"provider":          row.Provider,
+			"model":             row.Model,
+			"user_id":           row.UserID,
+			"api_key_id":        row.APIKeyID,
+			"requests":          row.Requests,
+			"failed_requests":   row.FailedRequests,
+			"input_tokens":      row.InputTokens,
+			"output_tokens":     row.OutputTokens,
+			"reasoning_tokens":  row.ReasoningTokens,
+			"cached_tokens":     row.CachedTokens,
+			"total_tokens":      row.TotalTokens,
+			"cost_micros":       row.CostMicros,
+			"energy_milli_wh":   row.EnergyMilliWh,
+			"carbon_milligrams": row.CarbonMilligrams,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"from":  from.Format(time.DateOnly),
+		"to":    to.Format(time.DateOnly),
+		"usage": out,
+	})
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/proxyref"
+)
+
+// usageProxiesDefaultWindow is the range aggregated when no from time is given.
+const usageProxiesDefaultWindow = 24 * time.Hour
+
+// usageProxyBucket is the usage of one proxy.
+type usageProxyBucket struct {
+	ProxyHash      string
+	ProxyLabel     string
+	Requests       int64
+	FailedRequests int64
+	TotalTokens    int64
+	CostMicros     int64
+}
+
+// Proxies aggregates usage per proxy so error spikes can be tied to a proxy. Optional
+// from/to query parameters accept RFC 3339 times and default to the last 24 hours; only
+// requests served through a proxy are counted. Proxies still in the pool are matched by
+// fingerprint and report their ID and region.
+func (h *UsageHandler) Proxies(c *gin.Context) {
+	to := time.Now().UTC()
+	from := to.Add(-usageProxiesDefaultWindow)
+	for _, bound := range []struct {
+		name   string
+		target *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		raw := strings.TrimSpace(c.Query(bound.name))
+		if raw == "" {
+			continue
+		}
+		parsed, errParse := time.Parse(time.RFC3339, raw)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + bound.name + ", expected RFC 3339"})
+			return
+		}
+		*bound.target = parsed.UTC()
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must not be before from"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var buckets []usageProxyBucket
+	if errScan := h.db.WithContext(ctx).Model(&models.Usage{}).
+		Select("proxy_hash, MAX(proxy_label) AS proxy_label, COUNT(*) AS requests, "+
+			"COALESCE(SUM(CASE WHEN failed THEN 1 ELSE 0 END), 0) AS failed_requests, "+
+			"COALESCE(SUM(total_tokens), 0) AS total_tokens, COALESCE(SUM(cost_micros), 0) AS cost_micros").
+		Where("proxy_hash <> '' AND requested_at >= ? AND requested_at <= ?", from, to).
+		Group("proxy_hash").
+		Scan(&buckets).Error; errScan != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	var proxies []models.Proxy
+	if errFind := h.db.WithContext(ctx).Find(&proxies).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list proxies failed"})
+		return
+	}
+	byHash := make(map[string]*models.Proxy, len(proxies))
+	for i := range proxies {
+		byHash[proxyref.Hash(proxies[i].ProxyURL)] = &proxies[i]
+	}
+
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].Requests != buckets[j].Requests {
+			return buckets[i].Requests > buckets[j].Requests
+		}
+		return buckets[i].ProxyHash < buckets[j].ProxyHash
+	})
+	out := make([]gin.H, 0, len(buckets))
+	for _, bucket := range buckets {
+		item := gin.H{
+			"proxy_hash":      bucket.ProxyHash,
+			"proxy_label":     bucket.ProxyLabel,
+			"proxy_id":        nil,
+			"region":          "",
+			"requests":        bucket.Requests,
+			"failed_requests": bucket.FailedRequests,
+			"error_rate":      float64(bucket.FailedRequests) / float64(bucket.Requests),
+			"total_tokens":    bucket.TotalTokens,
+			"cost":            float64(bucket.CostMicros) / 1_000_000,
+		}
+		if proxy, ok := byHash[bucket.ProxyHash]; ok {
+			item["proxy_id"] = proxy.ID
+			item["region"] = proxy.Region
+		}
+		out = append(out, item)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"from":    from,
+		"to":      to,
+		"proxies": out,
+	})
+}
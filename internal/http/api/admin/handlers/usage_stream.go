@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalusage "github.com/router-for-me/CLIProxyAPIBusiness/internal/usage"
+)
+
+const (
+	usageStreamDefaultLimit = 500
+	usageStreamMaxLimit     = 5000
+	// usageStreamClockSkew covers clock drift between the node that stamped created_at and the
+	// node serving the stream.
+	usageStreamClockSkew    = 30 * time.Second
+	usageStreamCursorPrefix = "u1:"
+	// usageStreamChangeInsert marks a newly recorded usage row; usage rows are append-only.
+	usageStreamChangeInsert = "insert"
+)
+
+// usageStreamSettleDelay hides rows younger than this. The usage writer stamps created_at inside
+// the insert transaction and cancels that transaction after MaxUsageCommitDelay, retries and
+// outbox replays included, so once a row is older than the delay every row with a lower ID has
+// either committed or rolled back. Rows are therefore never skipped by a cursor, provided node
+// clocks drift by less than usageStreamClockSkew.
+var usageStreamSettleDelay = internalusage.MaxUsageCommitDelay(0) + usageStreamClockSkew
+
+// Stream returns usage rows in ascending ID order for incremental export to external billing systems.
+// Consumers pass the returned next_cursor back until has_more is false and keep polling with it for new rows;
+// rows appear once they are older than settled_before, see usageStreamSettleDelay.
+func (h *UsageHandler) Stream(c *gin.Context) {
+	var (
+		cursorStr    = strings.TrimSpace(c.Query("cursor"))
+		fromStr      = strings.TrimSpace(c.Query("from"))
+		toStr        = strings.TrimSpace(c.Query("to"))
+		userIDStr    = strings.TrimSpace(c.Query("user_id"))
+		chargedToStr = strings.ToLower(strings.TrimSpace(c.Query("charged_to")))
+		limitStr     = strings.TrimSpace(c.Query("limit"))
+	)
+
+	afterID, errCursor := decodeUsageStreamCursor(cursorStr)
+	if errCursor != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+		return
+	}
+
+	limit := usageStreamDefaultLimit
+	if limitStr != "" {
+		v, errAtoi := strconv.Atoi(limitStr)
+		if errAtoi != nil || v <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		if v > usageStreamMaxLimit {
+			v = usageStreamMaxLimit
+		}
+		limit = v
+	}
+
+	settledBefore := time.Now().UTC().Add(-usageStreamSettleDelay)
+	q := h.db.WithContext(c.Request.Context()).
+		Model(&models.Usage{}).
+		Where("id > ?", afterID).
+		Where("created_at <= ?", settledBefore)
+	if fromStr != "" {
+		from, errParse := time.Parse(time.RFC3339, fromStr)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+			return
+		}
+		q = q.Where("requested_at >= ?", from.UTC())
+	}
+	if toStr != "" {
+		to, errParse := time.Parse(time.RFC3339, toStr)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+			return
+		}
+		q = q.Where("requested_at < ?", to.UTC())
+	}
+	if userIDStr != "" {
+		userID, errParse := strconv.ParseUint(userIDStr, 10, 64)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+			return
+		}
+		q = q.Where("user_id = ?", userID)
+	}
+	if chargedToStr != "" {
+		switch chargedToStr {
+		case "bill", "prepaid", "none":
+			q = q.Where("charged_to = ?", chargedToStr)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid charged_to"})
+			return
+		}
+	}
+
+	var rows []models.Usage
+	if errFind := q.Order("id ASC").Limit(limit + 1).Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+
+	items := make([]gin.H, 0, len(rows))
+	lastID := afterID
+	for _, row := range rows {
+		lastID = row.ID
+		items = append(items, gin.H{
+			"change":           usageStreamChangeInsert,
+			"sequence":         row.ID,
+			"id":               row.ID,
+			"request_id":       row.RequestID,
+			"provider":         row.Provider,
+			"model":            row.Model,
+			"user_id":          row.UserID,
+			"user_group_id":    row.UserGroupID,
+			"api_key_id":       row.APIKeyID,
+			"auth_id":          row.AuthID,
+			"source":           row.Source,
+			"requested_at":     row.RequestedAt,
+			"failed":           row.Failed,
+			"input_tokens":     row.InputTokens,
+			"output_tokens":    row.OutputTokens,
+			"reasoning_tokens": row.ReasoningTokens,
+			"cached_tokens":    row.CachedTokens,
+			"total_tokens":     row.TotalTokens,
+			"cost_micros":      row.CostMicros,
+			"billing_rule_id":  row.BillingRuleID,
+			"charged_to":       row.ChargedTo,
+			"created_at":       row.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":          items,
+		"next_cursor":    encodeUsageStreamCursor(lastID),
+		"has_more":       hasMore,
+		"settled_before": settledBefore,
+	})
+}
+
+// encodeUsageStreamCursor builds the opaque cursor for rows after the given usage ID.
+func encodeUsageStreamCursor(afterID uint64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(usageStreamCursorPrefix + strconv.FormatUint(afterID, 10)))
+}
+
+// decodeUsageStreamCursor returns the usage ID encoded in the cursor; an empty cursor starts from the beginning.
+func decodeUsageStreamCursor(cursor string) (uint64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, errDecode := base64.RawURLEncoding.DecodeString(cursor)
+	if errDecode != nil {
+		return 0, errDecode
+	}
+	value, ok := strings.CutPrefix(string(raw), usageStreamCursorPrefix)
+	if !ok {
+		return 0, errors.New("usage stream: unknown cursor version")
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// UserGroupMigrationHandler schedules and applies user group changes together with bill handling.
+type UserGroupMigrationHandler struct {
+	db *gorm.DB // Database handle for migration records.
+}
+
+// NewUserGroupMigrationHandler constructs a user group migration handler.
+func NewUserGroupMigrationHandler(db *gorm.DB) *UserGroupMigrationHandler {
+	return &UserGroupMigrationHandler{db: db}
+}
+
+// createUserGroupMigrationRequest captures the payload for a group migration.
+type createUserGroupMigrationRequest struct {
+	UserGroupID  models.UserGroupIDs `json:"user_group_id"` // Target user group IDs.
+	BillStrategy string              `json:"bill_strategy"` // prorate (default), close or retag.
+	Boundary     string              `json:"boundary"`      // now (default), next_day or period_end.
+	EffectiveAt  *time.Time          `json:"effective_at"`  // Optional explicit boundary time.
+	DryRun       bool                `json:"dry_run"`       // Preview bill changes without saving.
+}
+
+// Create schedules a group migration for a user, applying it immediately when the boundary is now.
+func (h *UserGroupMigrationHandler) Create(c *gin.Context) {
+	userID, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var body createUserGroupMigrationRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var user models.User
+	if errFind := h.db.WithContext(ctx).Select("id", "user_group_id").First(&user, userID).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query user failed"})
+		return
+	}
+
+	target := body.UserGroupID.Clean()
+	if len(target) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_group_id is required"})
+		return
+	}
+	var groupCount int64
+	if errCount := h.db.WithContext(ctx).Model(&models.UserGroup{}).
+		Where("id IN ?", target.Values()).Count(&groupCount).Error; errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query user groups failed"})
+		return
+	}
+	if int(groupCount) != len(target) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user group not found"})
+		return
+	}
+
+	strategy, ok := billing.ParseGroupMigrationBillStrategy(body.BillStrategy)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid bill_strategy"})
+		return
+	}
+	effectiveAt, errBoundary := billing.ResolveGroupMigrationBoundary(ctx, h.db, user.ID, body.Boundary, body.EffectiveAt, time.Now().UTC())
+	if errBoundary != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid boundary"})
+		return
+	}
+
+	migration := models.UserGroupMigration{
+		UserID:          user.ID,
+		FromUserGroupID: user.UserGroupID.Clean(),
+		ToUserGroupID:   target,
+		BillStrategy:    strategy,
+		EffectiveAt:     effectiveAt,
+	}
+	if adminID, okAdmin := readAdminIDFromContext(c); okAdmin {
+		migration.CreatedBy = &adminID
+	}
+
+	changes, errPlan := billing.PlanGroupMigration(ctx, h.db, &migration)
+	if errPlan != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "plan migration failed"})
+		return
+	}
+	if body.DryRun {
+		migration.Status = models.GroupMigrationStatusScheduled
+		out := formatUserGroupMigration(&migration)
+		out["bill_changes"] = changes
+		c.JSON(http.StatusOK, out)
+		return
+	}
+
+	if errSchedule := billing.ScheduleGroupMigration(ctx, h.db, &migration); errSchedule != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "apply migration failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, formatUserGroupMigration(&migration))
+}
+
+// ListByUser returns group migrations recorded for a user.
+func (h *UserGroupMigrationHandler) ListByUser(c *gin.Context) {
+	userID, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var rows []models.UserGroupMigration
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list migrations failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatUserGroupMigration(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"migrations": out})
+}
+
+// Cancel cancels a scheduled group migration.
+func (h *UserGroupMigrationHandler) Cancel(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if errCancel := billing.CancelGroupMigration(c.Request.Context(), h.db, id); errCancel != nil {
+		if errors.Is(errCancel, billing.ErrGroupMigrationNotScheduled) {
+			c.JSON(http.StatusConflict, gin.H{"error": "migration is not scheduled"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cancel failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// formatUserGroupMigration converts a migration into a response payload.
+func formatUserGroupMigration(row *models.UserGroupMigration) gin.H {
+	out := gin.H{
+		"id":                 row.ID,
+		"user_id":            row.UserID,
+		"from_user_group_id": row.FromUserGroupID.Clean(),
+		"to_user_group_id":   row.ToUserGroupID.Clean(),
+		"bill_strategy":      row.BillStrategy,
+		"effective_at":       row.EffectiveAt,
+		"status":             row.Status,
+		"last_error":         row.LastError,
+		"applied_at":         row.AppliedAt,
+		"created_by":         row.CreatedBy,
+		"created_at":         row.CreatedAt,
+		"updated_at":         row.UpdatedAt,
+	}
+	if len(row.Result) > 0 {
+		out["bill_changes"] = json.RawMessage(row.Result)
+	}
+	return out
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/gin-gonic/gin"
 	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/requestlog"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/usergroup"
 	"gorm.io/gorm"
 )
 
@@ -28,6 +30,20 @@ type createUserGroupRequest struct {
 	Name      string `json:"name"`
 	IsDefault bool   `json:"is_default"`
 	RateLimit int    `json:"rate_limit"`
+	RPMLimit  int    `json:"rpm_limit"` // Requests per minute per member.
+	TPMLimit  int    `json:"tpm_limit"` // Tokens per minute per member.
+
+	MaxConcurrentRequests int `json:"max_concurrent_requests"` // In-flight request cap per member.
+
+	DailySpendLimit   float64 `json:"daily_spend_limit"`
+	MonthlySpendLimit float64 `json:"monthly_spend_limit"`
+
+	ParentID           *uint64 `json:"parent_id"`             // Group whose policies fill in unset limits.
+	BillingRuleGroupID *uint64 `json:"billing_rule_group_id"` // Group whose billing rules apply when none of this group's match.
+
+	RequestLogEnabled bool `json:"request_log_enabled"` // Capture request and response bodies of members.
+
+	OrganizationID *uint64 `json:"organization_id"` // Owning organization; ignored for organization admins, who always create in their own.
 }
 
 // Create creates a new user group.
@@ -42,12 +58,49 @@ func (h *UserGroupHandler) Create(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing name"})
 		return
 	}
+	if body.DailySpendLimit < 0 || body.MonthlySpendLimit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid spend limit"})
+		return
+	}
+	if body.RPMLimit < 0 || body.TPMLimit < 0 || body.MaxConcurrentRequests < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rate limit"})
+		return
+	}
+	organizationID := organizationScopeValue(c)
+	if organizationID != nil && body.IsDefault {
+		c.JSON(http.StatusForbidden, gin.H{"error": "organization admins cannot set the default group"})
+		return
+	}
+	if organizationID == nil {
+		organizationID = nonZeroID(body.OrganizationID)
+		if !checkOrganizationExists(c, h.db, organizationID) {
+			return
+		}
+	}
+	parentID, billingRuleGroupID := nonZeroID(body.ParentID), nonZeroID(body.BillingRuleGroupID)
+	if !h.validateGroupLinks(c, 0, parentID, billingRuleGroupID) {
+		return
+	}
 
 	now := time.Now().UTC()
 	group := models.UserGroup{
-		Name:      name,
-		IsDefault: body.IsDefault,
-		RateLimit: body.RateLimit,
+		Name:                  name,
+		IsDefault:             body.IsDefault,
+		RateLimit:             body.RateLimit,
+		RPMLimit:              body.RPMLimit,
+		TPMLimit:              body.TPMLimit,
+		MaxConcurrentRequests: body.MaxConcurrentRequests,
+
+		DailySpendLimit:   body.DailySpendLimit,
+		MonthlySpendLimit: body.MonthlySpendLimit,
+
+		ParentID:           parentID,
+		BillingRuleGroupID: billingRuleGroupID,
+
+		RequestLogEnabled: body.RequestLogEnabled,
+
+		OrganizationID: organizationID,
+
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
@@ -65,12 +118,19 @@ func (h *UserGroupHandler) Create(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "create user group failed"})
 		return
 	}
+	if group.RequestLogEnabled {
+		requestlog.InvalidateGroups()
+	}
 	c.JSON(http.StatusCreated, gin.H{
-		"id":         group.ID,
-		"name":       group.Name,
-		"is_default": group.IsDefault,
-		"created_at": group.CreatedAt,
-		"updated_at": group.UpdatedAt,
+		"id":                    group.ID,
+		"name":                  group.Name,
+		"is_default":            group.IsDefault,
+		"parent_id":             group.ParentID,
+		"billing_rule_group_id": group.BillingRuleGroupID,
+		"request_log_enabled":   group.RequestLogEnabled,
+		"organization_id":       group.OrganizationID,
+		"created_at":            group.CreatedAt,
+		"updated_at":            group.UpdatedAt,
 	})
 }
 
@@ -81,7 +141,7 @@ func (h *UserGroupHandler) List(c *gin.Context) {
 		idQ   = strings.TrimSpace(c.Query("id"))
 	)
 
-	q := h.db.WithContext(c.Request.Context()).Model(&models.UserGroup{})
+	q := scopeToOrganizationOrShared(c, h.db.WithContext(c.Request.Context()).Model(&models.UserGroup{}))
 	if nameQ != "" {
 		pattern := dbutil.NormalizeLikePattern(h.db, "%"+nameQ+"%")
 		q = q.Where(dbutil.CaseInsensitiveLikeExpr(h.db, "name"), pattern)
@@ -100,12 +160,21 @@ func (h *UserGroupHandler) List(c *gin.Context) {
 	out := make([]gin.H, 0, len(rows))
 	for _, row := range rows {
 		out = append(out, gin.H{
-			"id":         row.ID,
-			"name":       row.Name,
-			"is_default": row.IsDefault,
-			"rate_limit": row.RateLimit,
-			"created_at": row.CreatedAt,
-			"updated_at": row.UpdatedAt,
+			"id":                      row.ID,
+			"name":                    row.Name,
+			"is_default":              row.IsDefault,
+			"rate_limit":              row.RateLimit,
+			"rpm_limit":               row.RPMLimit,
+			"tpm_limit":               row.TPMLimit,
+			"max_concurrent_requests": row.MaxConcurrentRequests,
+			"daily_spend_limit":       row.DailySpendLimit,
+			"monthly_spend_limit":     row.MonthlySpendLimit,
+			"parent_id":               row.ParentID,
+			"billing_rule_group_id":   row.BillingRuleGroupID,
+			"request_log_enabled":     row.RequestLogEnabled,
+			"organization_id":         row.OrganizationID,
+			"created_at":              row.CreatedAt,
+			"updated_at":              row.UpdatedAt,
 		})
 	}
 	c.JSON(http.StatusOK, gin.H{"user_groups": out})
@@ -119,7 +188,7 @@ func (h *UserGroupHandler) Get(c *gin.Context) {
 		return
 	}
 	var group models.UserGroup
-	if errFind := h.db.WithContext(c.Request.Context()).First(&group, id).Error; errFind != nil {
+	if errFind := scopeToOrganizationOrShared(c, h.db.WithContext(c.Request.Context())).First(&group, id).Error; errFind != nil {
 		if errors.Is(errFind, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			return
@@ -127,13 +196,28 @@ func (h *UserGroupHandler) Get(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
 		return
 	}
+	policy, errPolicy := usergroup.Resolve(c.Request.Context(), h.db, group.ID)
+	if errPolicy != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"id":         group.ID,
-		"name":       group.Name,
-		"is_default": group.IsDefault,
-		"rate_limit": group.RateLimit,
-		"created_at": group.CreatedAt,
-		"updated_at": group.UpdatedAt,
+		"id":                      group.ID,
+		"name":                    group.Name,
+		"is_default":              group.IsDefault,
+		"rate_limit":              group.RateLimit,
+		"rpm_limit":               group.RPMLimit,
+		"tpm_limit":               group.TPMLimit,
+		"max_concurrent_requests": group.MaxConcurrentRequests,
+		"daily_spend_limit":       group.DailySpendLimit,
+		"monthly_spend_limit":     group.MonthlySpendLimit,
+		"parent_id":               group.ParentID,
+		"billing_rule_group_id":   group.BillingRuleGroupID,
+		"request_log_enabled":     group.RequestLogEnabled,
+		"organization_id":         group.OrganizationID,
+		"effective":               effectivePolicyJSON(policy),
+		"created_at":              group.CreatedAt,
+		"updated_at":              group.UpdatedAt,
 	})
 }
 
@@ -142,6 +226,18 @@ type updateUserGroupRequest struct {
 	Name      *string `json:"name"`
 	IsDefault *bool   `json:"is_default"`
 	RateLimit *int    `json:"rate_limit"`
+	RPMLimit  *int    `json:"rpm_limit"`
+	TPMLimit  *int    `json:"tpm_limit"`
+
+	MaxConcurrentRequests *int `json:"max_concurrent_requests"`
+
+	DailySpendLimit   *float64 `json:"daily_spend_limit"`
+	MonthlySpendLimit *float64 `json:"monthly_spend_limit"`
+
+	ParentID           *uint64 `json:"parent_id"`             // Zero clears the parent.
+	BillingRuleGroupID *uint64 `json:"billing_rule_group_id"` // Zero clears the billing rule group.
+
+	RequestLogEnabled *bool `json:"request_log_enabled"`
 }
 
 // Update modifies a user group.
@@ -156,6 +252,22 @@ func (h *UserGroupHandler) Update(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
 		return
 	}
+	if (body.DailySpendLimit != nil && *body.DailySpendLimit < 0) || (body.MonthlySpendLimit != nil && *body.MonthlySpendLimit < 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid spend limit"})
+		return
+	}
+	if (body.RPMLimit != nil && *body.RPMLimit < 0) || (body.TPMLimit != nil && *body.TPMLimit < 0) ||
+		(body.MaxConcurrentRequests != nil && *body.MaxConcurrentRequests < 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rate limit"})
+		return
+	}
+	if _, scoped := organizationScope(c); scoped && body.IsDefault != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "organization admins cannot set the default group"})
+		return
+	}
+	if !h.validateGroupLinks(c, id, nonZeroID(body.ParentID), nonZeroID(body.BillingRuleGroupID)) {
+		return
+	}
 
 	now := time.Now().UTC()
 	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
@@ -176,8 +288,32 @@ func (h *UserGroupHandler) Update(c *gin.Context) {
 		if body.RateLimit != nil {
 			updates["rate_limit"] = *body.RateLimit
 		}
+		if body.RPMLimit != nil {
+			updates["rpm_limit"] = *body.RPMLimit
+		}
+		if body.TPMLimit != nil {
+			updates["tpm_limit"] = *body.TPMLimit
+		}
+		if body.MaxConcurrentRequests != nil {
+			updates["max_concurrent_requests"] = *body.MaxConcurrentRequests
+		}
+		if body.DailySpendLimit != nil {
+			updates["daily_spend_limit"] = *body.DailySpendLimit
+		}
+		if body.MonthlySpendLimit != nil {
+			updates["monthly_spend_limit"] = *body.MonthlySpendLimit
+		}
+		if body.ParentID != nil {
+			updates["parent_id"] = nonZeroID(body.ParentID)
+		}
+		if body.BillingRuleGroupID != nil {
+			updates["billing_rule_group_id"] = nonZeroID(body.BillingRuleGroupID)
+		}
+		if body.RequestLogEnabled != nil {
+			updates["request_log_enabled"] = *body.RequestLogEnabled
+		}
 
-		res := tx.Model(&models.UserGroup{}).Where("id = ?", id).Updates(updates)
+		res := scopeToOrganization(c, tx.Model(&models.UserGroup{})).Where("id = ?", id).Updates(updates)
 		if res.Error != nil {
 			return res.Error
 		}
@@ -194,6 +330,9 @@ func (h *UserGroupHandler) Update(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
 		return
 	}
+	if body.RequestLogEnabled != nil {
+		requestlog.InvalidateGroups()
+	}
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
@@ -204,18 +343,103 @@ func (h *UserGroupHandler) Delete(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	res := h.db.WithContext(c.Request.Context()).Delete(&models.UserGroup{}, id)
-	if res.Error != nil {
+	now := time.Now().UTC()
+	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		res := scopeToOrganization(c, tx).Delete(&models.UserGroup{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		// Detach groups that inherited from the deleted one.
+		if errParent := tx.Model(&models.UserGroup{}).Where("parent_id = ?", id).
+			Updates(map[string]any{"parent_id": nil, "updated_at": now}).Error; errParent != nil {
+			return errParent
+		}
+		return tx.Model(&models.UserGroup{}).Where("billing_rule_group_id = ?", id).
+			Updates(map[string]any{"billing_rule_group_id": nil, "updated_at": now}).Error
+	})
+	if errTx != nil {
+		if errors.Is(errTx, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
 		return
 	}
-	if res.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
-		return
-	}
+	requestlog.InvalidateGroups()
 	c.Status(http.StatusNoContent)
 }
 
+// validateGroupLinks checks the parent and billing rule group of group id (zero when creating)
+// and writes an error response when either is invalid.
+func (h *UserGroupHandler) validateGroupLinks(c *gin.Context, id uint64, parentID, billingRuleGroupID *uint64) bool {
+	ctx := c.Request.Context()
+	var linked []uint64
+	for _, linkedID := range []*uint64{parentID, billingRuleGroupID} {
+		if linkedID != nil && (len(linked) == 0 || linked[0] != *linkedID) {
+			linked = append(linked, *linkedID)
+		}
+	}
+	if !checkOrganizationUserGroups(c, h.db, linked) {
+		return false
+	}
+	if parentID != nil {
+		if errParent := usergroup.ValidateParent(ctx, h.db, id, *parentID); errParent != nil {
+			switch {
+			case errors.Is(errParent, usergroup.ErrParentNotFound),
+				errors.Is(errParent, usergroup.ErrParentCycle),
+				errors.Is(errParent, usergroup.ErrTooDeep):
+				c.JSON(http.StatusBadRequest, gin.H{"error": errParent.Error()})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+			}
+			return false
+		}
+	}
+	if billingRuleGroupID != nil {
+		if *billingRuleGroupID == id {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid billing rule group"})
+			return false
+		}
+		var count int64
+		if errCount := h.db.WithContext(ctx).Model(&models.UserGroup{}).
+			Where("id = ?", *billingRuleGroupID).Count(&count).Error; errCount != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+			return false
+		}
+		if count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "billing rule group not found"})
+			return false
+		}
+	}
+	return true
+}
+
+// nonZeroID returns nil for a missing or zero ID.
+func nonZeroID(id *uint64) *uint64 {
+	if id == nil || *id == 0 {
+		return nil
+	}
+	value := *id
+	return &value
+}
+
+// effectivePolicyJSON renders the limits a group applies after inheriting from its parents.
+func effectivePolicyJSON(policy usergroup.Policy) gin.H {
+	return gin.H{
+		"rate_limit":              policy.RateLimit,
+		"rpm_limit":               policy.RPMLimit,
+		"tpm_limit":               policy.TPMLimit,
+		"max_concurrent_requests": policy.MaxConcurrentRequests,
+		"daily_spend_limit":       policy.DailySpendLimit,
+		"monthly_spend_limit":     policy.MonthlySpendLimit,
+		"billing_rule_group_ids":  policy.BillingRuleGroupIDs,
+		"request_log":             policy.RequestLog,
+	}
+}
+
 // SetDefault marks a user group as default.
 func (h *UserGroupHandler) SetDefault(c *gin.Context) {
 	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/lifecycle"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// UserLifecycleHandler serves admin endpoints for automated user lifecycle actions.
+type UserLifecycleHandler struct {
+	db *gorm.DB // Database handle for lifecycle events.
+}
+
+// NewUserLifecycleHandler constructs a user lifecycle handler.
+func NewUserLifecycleHandler(db *gorm.DB) *UserLifecycleHandler {
+	return &UserLifecycleHandler{db: db}
+}
+
+// userLifecycleListQuery defines filters for the lifecycle event list.
+type userLifecycleListQuery struct {
+	Page   int    `form:"page,default=1"`   // Page number.
+	Limit  int    `form:"limit,default=20"` // Page size.
+	UserID uint64 `form:"user_id"`          // Affected user.
+	Action string `form:"action"`           // Lifecycle action.
+	Open   bool   `form:"open"`             // Only unresolved suspensions.
+}
+
+// ListEvents returns lifecycle actions newest first.
+func (h *UserLifecycleHandler) ListEvents(c *gin.Context) {
+	var q userLifecycleListQuery
+	if errBind := c.ShouldBindQuery(&q); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query"})
+		return
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.Limit < 1 || q.Limit > 100 {
+		q.Limit = 20
+	}
+
+	query := h.db.WithContext(c.Request.Context()).Model(&models.UserLifecycleEvent{})
+	if q.UserID != 0 {
+		query = query.Where("user_id = ?", q.UserID)
+	}
+	if action := strings.TrimSpace(q.Action); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if q.Open {
+		query = query.Where("action = ? AND resolved_at IS NULL", models.LifecycleActionSuspend)
+	}
+
+	var total int64
+	if errCount := query.Session(&gorm.Session{}).Count(&total).Error; errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "count lifecycle events failed"})
+		return
+	}
+	var rows []models.UserLifecycleEvent
+	if errFind := query.
+		Order("created_at DESC").Order("id DESC").
+		Offset((q.Page - 1) * q.Limit).Limit(q.Limit).
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list lifecycle events failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		keyIDs := make([]uint64, 0)
+		_ = json.Unmarshal(row.APIKeyIDs, &keyIDs)
+		out = append(out, gin.H{
+			"id":          row.ID,
+			"user_id":     row.UserID,
+			"action":      row.Action,
+			"detail":      row.Detail,
+			"api_key_ids": keyIDs,
+			"resolved_at": row.ResolvedAt,
+			"created_at":  row.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"events": out,
+		"total":  total,
+		"page":   q.Page,
+		"limit":  q.Limit,
+	})
+}
+
+// Run evaluates the configured policies once, even when the scheduler is disabled.
+func (h *UserLifecycleHandler) Run(c *gin.Context) {
+	cfg := lifecycle.LoadConfig()
+	result, errRun := lifecycle.NewScheduler(h.db).RunOnce(c.Request.Context(), cfg, time.Now().UTC())
+	if errRun != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "run lifecycle policies failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"config": cfg,
+		"result": result,
+	})
+}
@@ -33,6 +33,13 @@ type createUserRequest struct {
 	DailyMaxUsage *float64            `json:"daily_max_usage"`
 	RateLimit     int                 `json:"rate_limit"`
 	Disabled      *bool               `json:"disabled"`
+
+	MaxConcurrentRequests int `json:"max_concurrent_requests"` // In-flight request cap; zero defers to the user group.
+
+	DailySpendLimit   float64 `json:"daily_spend_limit"`
+	MonthlySpendLimit float64 `json:"monthly_spend_limit"`
+
+	OrganizationID *uint64 `json:"organization_id"` // Owning organization; ignored for organization admins, who always create in their own.
 }
 
 // Create creates a new user account.
@@ -52,6 +59,24 @@ func (h *UserHandler) Create(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing password"})
 		return
 	}
+	if body.DailySpendLimit < 0 || body.MonthlySpendLimit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid spend limit"})
+		return
+	}
+	if body.MaxConcurrentRequests < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid max_concurrent_requests"})
+		return
+	}
+	organizationID := organizationScopeValue(c)
+	if organizationID == nil {
+		organizationID = nonZeroID(body.OrganizationID)
+		if !checkOrganizationExists(c, h.db, organizationID) {
+			return
+		}
+	}
+	if !checkOrganizationUserGroups(c, h.db, body.UserGroupID.Values()) {
+		return
+	}
 
 	hash, errHash := security.HashPassword(password)
 	if errHash != nil {
@@ -61,18 +86,22 @@ func (h *UserHandler) Create(c *gin.Context) {
 
 	now := time.Now().UTC()
 	user := models.User{
-		Username:    username,
-		Email:       strings.TrimSpace(body.Email),
-		Password:    hash,
-		UserGroupID: body.UserGroupID.Clean(),
+		Username:       username,
+		Email:          strings.TrimSpace(body.Email),
+		Password:       hash,
+		UserGroupID:    body.UserGroupID.Clean(),
+		OrganizationID: organizationID,
 		DailyMaxUsage: func() float64 {
 			if body.DailyMaxUsage == nil {
 				return 0
 			}
 			return *body.DailyMaxUsage
 		}(),
-		RateLimit: body.RateLimit,
-		Active:    true,
+		RateLimit:             body.RateLimit,
+		MaxConcurrentRequests: body.MaxConcurrentRequests,
+		DailySpendLimit:       body.DailySpendLimit,
+		MonthlySpendLimit:     body.MonthlySpendLimit,
+		Active:                true,
 		Disabled: func() bool {
 			if body.Disabled == nil {
 				return false
@@ -87,10 +116,12 @@ func (h *UserHandler) Create(c *gin.Context) {
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{
-		"id":         user.ID,
-		"username":   user.Username,
-		"email":      user.Email,
-		"rate_limit": user.RateLimit,
+		"id":                      user.ID,
+		"username":                user.Username,
+		"email":                   user.Email,
+		"rate_limit":              user.RateLimit,
+		"max_concurrent_requests": user.MaxConcurrentRequests,
+		"organization_id":         user.OrganizationID,
 	})
 }
 
@@ -103,7 +134,12 @@ func (h *UserHandler) List(c *gin.Context) {
 		searchQ   = strings.TrimSpace(c.Query("search"))
 	)
 
-	q := h.db.WithContext(c.Request.Context()).Model(&models.User{})
+	q := scopeToOrganization(c, h.db.WithContext(c.Request.Context()).Model(&models.User{}))
+	if orgQ := strings.TrimSpace(c.Query("organization_id")); orgQ != "" {
+		if orgID, errParse := strconv.ParseUint(orgQ, 10, 64); errParse == nil {
+			q = q.Where("organization_id = ?", orgID)
+		}
+	}
 	if usernameQ != "" {
 		pattern := dbutil.NormalizeLikePattern(h.db, "%"+usernameQ+"%")
 		q = q.Where(dbutil.CaseInsensitiveLikeExpr(h.db, "username"), pattern)
@@ -122,7 +158,7 @@ func (h *UserHandler) List(c *gin.Context) {
 		ciPattern := dbutil.NormalizeLikePattern(h.db, searchPattern)
 		q = q.Where(
 			dbutil.CaseInsensitiveLikeExpr(h.db, "username")+" OR "+
-				dbutil.CaseInsensitiveLikeExpr(h.db, "email")+" OR CAST(id AS TEXT) LIKE ?",
+				dbutil.CaseInsensitiveLikeExpr(h.db, "email")+" OR "+dbutil.CastTextExpr(h.db, "id")+" LIKE ?",
 			ciPattern,
 			ciPattern,
 			searchPattern,
@@ -165,18 +201,24 @@ func (h *UserHandler) List(c *gin.Context) {
 	out := make([]gin.H, 0, len(rows))
 	for _, row := range rows {
 		out = append(out, gin.H{
-			"id":                 row.ID,
-			"username":           row.Username,
-			"email":              row.Email,
-			"user_group_id":      row.UserGroupID.Clean(),
-			"bill_user_group_id": row.BillUserGroupID.Clean(),
-			"daily_max_usage":    row.DailyMaxUsage,
-			"today_cost_micros":  todayCostByUserID[row.ID],
-			"rate_limit":         row.RateLimit,
-			"active":             row.Active,
-			"disabled":           row.Disabled,
-			"created_at":         row.CreatedAt,
-			"updated_at":         row.UpdatedAt,
+			"id":                      row.ID,
+			"username":                row.Username,
+			"email":                   row.Email,
+			"email_verified_at":       row.EmailVerifiedAt,
+			"ldap_dn":                 row.LDAPDN,
+			"user_group_id":           row.UserGroupID.Clean(),
+			"bill_user_group_id":      row.BillUserGroupID.Clean(),
+			"organization_id":         row.OrganizationID,
+			"daily_max_usage":         row.DailyMaxUsage,
+			"today_cost_micros":       todayCostByUserID[row.ID],
+			"rate_limit":              row.RateLimit,
+			"max_concurrent_requests": row.MaxConcurrentRequests,
+			"daily_spend_limit":       row.DailySpendLimit,
+			"monthly_spend_limit":     row.MonthlySpendLimit,
+			"active":                  row.Active,
+			"disabled":                row.Disabled,
+			"created_at":              row.CreatedAt,
+			"updated_at":              row.UpdatedAt,
 		})
 	}
 	c.JSON(http.StatusOK, gin.H{"users": out})
@@ -190,7 +232,7 @@ func (h *UserHandler) Get(c *gin.Context) {
 		return
 	}
 	var user models.User
-	if errFind := h.db.WithContext(c.Request.Context()).First(&user, id).Error; errFind != nil {
+	if errFind := scopeToOrganization(c, h.db.WithContext(c.Request.Context())).First(&user, id).Error; errFind != nil {
 		if errors.Is(errFind, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			return
@@ -199,17 +241,23 @@ func (h *UserHandler) Get(c *gin.Context) {
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
-		"id":                 user.ID,
-		"username":           user.Username,
-		"email":              user.Email,
-		"user_group_id":      user.UserGroupID.Clean(),
-		"bill_user_group_id": user.BillUserGroupID.Clean(),
-		"daily_max_usage":    user.DailyMaxUsage,
-		"rate_limit":         user.RateLimit,
-		"active":             user.Active,
-		"disabled":           user.Disabled,
-		"created_at":         user.CreatedAt,
-		"updated_at":         user.UpdatedAt,
+		"id":                      user.ID,
+		"username":                user.Username,
+		"email":                   user.Email,
+		"email_verified_at":       user.EmailVerifiedAt,
+		"ldap_dn":                 user.LDAPDN,
+		"user_group_id":           user.UserGroupID.Clean(),
+		"bill_user_group_id":      user.BillUserGroupID.Clean(),
+		"organization_id":         user.OrganizationID,
+		"daily_max_usage":         user.DailyMaxUsage,
+		"rate_limit":              user.RateLimit,
+		"max_concurrent_requests": user.MaxConcurrentRequests,
+		"daily_spend_limit":       user.DailySpendLimit,
+		"monthly_spend_limit":     user.MonthlySpendLimit,
+		"active":                  user.Active,
+		"disabled":                user.Disabled,
+		"created_at":              user.CreatedAt,
+		"updated_at":              user.UpdatedAt,
 	})
 }
 
@@ -221,6 +269,15 @@ type updateUserRequest struct {
 	DailyMaxUsage *float64             `json:"daily_max_usage"`
 	RateLimit     *int                 `json:"rate_limit"`
 	Disabled      *bool                `json:"disabled"`
+
+	MaxConcurrentRequests *int `json:"max_concurrent_requests"`
+
+	DailySpendLimit   *float64 `json:"daily_spend_limit"`
+	MonthlySpendLimit *float64 `json:"monthly_spend_limit"`
+
+	EmailVerified *bool `json:"email_verified"` // Marks the email verified or unverified by hand.
+
+	OrganizationID *uint64 `json:"organization_id"` // Moves the user; zero removes it from its organization. Global admins only.
 }
 
 // Update modifies a user account.
@@ -245,21 +302,65 @@ func (h *UserHandler) Update(c *gin.Context) {
 	}
 	if body.Email != nil {
 		updates["email"] = strings.TrimSpace(*body.Email)
+		// A new address has not been verified yet.
+		updates["email_verified_at"] = gorm.Expr("CASE WHEN email = ? THEN email_verified_at ELSE NULL END", strings.TrimSpace(*body.Email))
+	}
+	if body.EmailVerified != nil {
+		if *body.EmailVerified {
+			updates["email_verified_at"] = gorm.Expr("COALESCE(email_verified_at, ?)", time.Now().UTC())
+		} else {
+			updates["email_verified_at"] = nil
+		}
 	}
 	if body.UserGroupID != nil {
+		if !checkOrganizationUserGroups(c, h.db, body.UserGroupID.Values()) {
+			return
+		}
 		updates["user_group_id"] = body.UserGroupID.Clean()
 	}
+	if body.OrganizationID != nil {
+		if _, scoped := organizationScope(c); scoped {
+			c.JSON(http.StatusForbidden, gin.H{"error": "organization admins cannot move users"})
+			return
+		}
+		organizationID := nonZeroID(body.OrganizationID)
+		if !checkOrganizationExists(c, h.db, organizationID) {
+			return
+		}
+		updates["organization_id"] = organizationID
+	}
 	if body.DailyMaxUsage != nil {
 		updates["daily_max_usage"] = *body.DailyMaxUsage
 	}
 	if body.RateLimit != nil {
 		updates["rate_limit"] = *body.RateLimit
 	}
+	if body.MaxConcurrentRequests != nil {
+		if *body.MaxConcurrentRequests < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid max_concurrent_requests"})
+			return
+		}
+		updates["max_concurrent_requests"] = *body.MaxConcurrentRequests
+	}
+	if body.DailySpendLimit != nil {
+		if *body.DailySpendLimit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid spend limit"})
+			return
+		}
+		updates["daily_spend_limit"] = *body.DailySpendLimit
+	}
+	if body.MonthlySpendLimit != nil {
+		if *body.MonthlySpendLimit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid spend limit"})
+			return
+		}
+		updates["monthly_spend_limit"] = *body.MonthlySpendLimit
+	}
 	if body.Disabled != nil {
 		updates["disabled"] = *body.Disabled
 	}
 
-	res := h.db.WithContext(c.Request.Context()).Model(&models.User{}).Where("id = ?", id).Updates(updates)
+	res := scopeToOrganization(c, h.db.WithContext(c.Request.Context()).Model(&models.User{})).Where("id = ?", id).Updates(updates)
 	if res.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
 		return
@@ -282,7 +383,7 @@ func (h *UserHandler) Delete(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	var user models.User
-	if errFind := h.db.WithContext(ctx).First(&user, id).Error; errFind != nil {
+	if errFind := scopeToOrganization(c, h.db.WithContext(ctx)).First(&user, id).Error; errFind != nil {
 		if errors.Is(errFind, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			return
@@ -315,7 +416,7 @@ func (h *UserHandler) Disable(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	res := h.db.WithContext(c.Request.Context()).Model(&models.User{}).
+	res := scopeToOrganization(c, h.db.WithContext(c.Request.Context()).Model(&models.User{})).
 		Where("id = ?", id).
 		Updates(map[string]any{"disabled": true, "updated_at": time.Now().UTC()})
 	if res.Error != nil {
@@ -336,7 +437,7 @@ func (h *UserHandler) Enable(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	res := h.db.WithContext(c.Request.Context()).Model(&models.User{}).
+	res := scopeToOrganization(c, h.db.WithContext(c.Request.Context()).Model(&models.User{})).
 		Where("id = ?", id).
 		Updates(map[string]any{"disabled": false, "updated_at": time.Now().UTC()})
 	if res.Error != nil {
@@ -377,7 +478,7 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "hash password failed"})
 		return
 	}
-	res := h.db.WithContext(c.Request.Context()).Model(&models.User{}).
+	res := scopeToOrganization(c, h.db.WithContext(c.Request.Context()).Model(&models.User{})).
 		Where("id = ?", id).
 		Updates(map[string]any{"password": hash, "updated_at": time.Now().UTC()})
 	if res.Error != nil {
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
+)
+
+// WebhookTemplateHandler validates and test-fires webhook payload templates.
+type WebhookTemplateHandler struct {
+	subscriber *events.WebhookSubscriber // Webhook subscriber used for test deliveries.
+}
+
+// NewWebhookTemplateHandler constructs a webhook template handler.
+func NewWebhookTemplateHandler() *WebhookTemplateHandler {
+	return &WebhookTemplateHandler{subscriber: events.NewWebhookSubscriber()}
+}
+
+// webhookTemplateRequest captures a template and the event type it is rendered for.
+type webhookTemplateRequest struct {
+	Template    string `json:"template"`     // Go text/template body.
+	ContentType string `json:"content_type"` // Optional content type; defaults to application/json.
+	EventType   string `json:"event_type"`   // Optional sample event type.
+}
+
+// Validate renders a template against a sample event and returns the payload or the error.
+func (h *WebhookTemplateHandler) Validate(c *gin.Context) {
+	var body webhookTemplateRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	if strings.TrimSpace(body.Template) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "template is required"})
+		return
+	}
+	tpl := events.WebhookTemplate{Template: body.Template, ContentType: body.ContentType}
+	rendered, errRender := events.RenderWebhookPayload(tpl, events.SampleEvent(events.Type(strings.TrimSpace(body.EventType))))
+	if errRender != nil {
+		c.JSON(http.StatusOK, gin.H{"valid": false, "error": errRender.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"valid": true, "rendered": string(rendered)})
+}
+
+// testFireWebhookRequest selects the webhook to test and an optional unsaved template.
+type testFireWebhookRequest struct {
+	URL         string  `json:"url"`          // Webhook URL; must be listed in EVENT_WEBHOOK_URLS.
+	Template    *string `json:"template"`     // Optional template overriding the stored one.
+	ContentType string  `json:"content_type"` // Content type for the override template.
+	EventType   string  `json:"event_type"`   // Optional sample event type.
+}
+
+// TestFire posts a sample event to a configured webhook URL.
+func (h *WebhookTemplateHandler) TestFire(c *gin.Context) {
+	var body testFireWebhookRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	url := strings.TrimSpace(body.URL)
+	if url == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url is required"})
+		return
+	}
+	var override *events.WebhookTemplate
+	if body.Template != nil {
+		override = &events.WebhookTemplate{Template: *body.Template, ContentType: body.ContentType}
+	}
+	payload, errFire := h.subscriber.TestFire(c.Request.Context(), url, events.Type(strings.TrimSpace(body.EventType)), override)
+	if errors.Is(errFire, events.ErrWebhookNotConfigured) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url is not a configured webhook"})
+		return
+	}
+	if errFire != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"delivered": false, "error": errFire.Error(), "payload": string(payload)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"delivered": true, "payload": string(payload)})
+}
@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/webhook"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// WebhookHandler manages outbound webhooks and their delivery logs.
+type WebhookHandler struct {
+	db *gorm.DB // Database handle for webhook records.
+}
+
+// NewWebhookHandler constructs a webhook handler.
+func NewWebhookHandler(db *gorm.DB) *WebhookHandler {
+	return &WebhookHandler{db: db}
+}
+
+// webhookRequest captures the payload for creating or updating a webhook.
+type webhookRequest struct {
+	Name         *string   `json:"name"`
+	URL          *string   `json:"url"`
+	Secret       *string   `json:"secret"`        // Signing secret; generated when empty on create.
+	EventTypes   *[]string `json:"event_types"`   // Event types to deliver, or "*" for all.
+	IsEnabled    *bool     `json:"is_enabled"`    // Whether new events are delivered.
+	RotateSecret bool      `json:"rotate_secret"` // Replace the secret with a generated one.
+}
+
+// apply validates the set fields and copies them onto row.
+func (r *webhookRequest) apply(row *models.Webhook) error {
+	if r.Name != nil {
+		row.Name = strings.TrimSpace(*r.Name)
+	}
+	if row.Name == "" {
+		return errors.New("name is required")
+	}
+	if r.URL != nil {
+		normalized, errURL := webhook.NormalizeURL(*r.URL)
+		if errURL != nil {
+			return errURL
+		}
+		row.URL = normalized
+	}
+	if row.URL == "" {
+		return errors.New("url is required")
+	}
+	if r.EventTypes != nil {
+		types, errTypes := webhook.NormalizeEventTypes(*r.EventTypes)
+		if errTypes != nil {
+			return errTypes
+		}
+		encoded, _ := json.Marshal(types)
+		row.EventTypes = datatypes.JSON(encoded)
+	}
+	if len(webhook.EventTypes(row)) == 0 {
+		return errors.New("event_types is required")
+	}
+	if r.Secret != nil {
+		row.Secret = strings.TrimSpace(*r.Secret)
+	}
+	if row.Secret == "" || r.RotateSecret {
+		secret, errSecret := webhook.GenerateSecret()
+		if errSecret != nil {
+			return errSecret
+		}
+		row.Secret = secret
+	}
+	if r.IsEnabled != nil {
+		row.IsEnabled = *r.IsEnabled
+	}
+	return nil
+}
+
+// Create validates and inserts a new webhook.
+func (h *WebhookHandler) Create(c *gin.Context) {
+	var body webhookRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	row := models.Webhook{
+		IsEnabled: true,
+		CreatedBy: "admin:" + c.GetString("adminUsername"),
+	}
+	if errApply := body.apply(&row); errApply != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errApply.Error()})
+		return
+	}
+	if errCreate := h.db.WithContext(c.Request.Context()).Create(&row).Error; errCreate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create webhook failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, formatWebhook(&row))
+}
+
+// List returns every webhook.
+func (h *WebhookHandler) List(c *gin.Context) {
+	var rows []models.Webhook
+	if errFind := h.db.WithContext(c.Request.Context()).Order("id ASC").Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list webhooks failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatWebhook(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": out})
+}
+
+// Update validates and updates a webhook by ID.
+func (h *WebhookHandler) Update(c *gin.Context) {
+	row, ok := h.load(c)
+	if !ok {
+		return
+	}
+	var body webhookRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	if errApply := body.apply(row); errApply != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errApply.Error()})
+		return
+	}
+	row.UpdatedAt = time.Now().UTC()
+	if errSave := h.db.WithContext(c.Request.Context()).Save(row).Error; errSave != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update webhook failed"})
+		return
+	}
+	c.JSON(http.StatusOK, formatWebhook(row))
+}
+
+// Delete removes a webhook and its delivery log.
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	id, errID := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var deleted int64
+	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&models.Webhook{}, "id = ?", id)
+		if res.Error != nil {
+			return res.Error
+		}
+		deleted = res.RowsAffected
+		return tx.Delete(&models.WebhookDelivery{}, "webhook_id = ?", id).Error
+	})
+	if errTx != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete webhook failed"})
+		return
+	}
+	if deleted == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": true})
+}
+
+// Deliveries lists the most recent deliveries of a webhook, optionally filtered by status.
+func (h *WebhookHandler) Deliveries(c *gin.Context) {
+	row, ok := h.load(c)
+	if !ok {
+		return
+	}
+	q := h.db.WithContext(c.Request.Context()).Model(&models.WebhookDelivery{}).Where("webhook_id = ?", row.ID)
+	if status := strings.TrimSpace(c.Query("status")); status != "" {
+		q = q.Where("status = ?", status)
+	}
+	var rows []models.WebhookDelivery
+	if errFind := q.Order("id DESC").Limit(500).Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list deliveries failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatWebhookDelivery(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": out})
+}
+
+// Test queues a ping delivery to a webhook, enabled or not.
+func (h *WebhookHandler) Test(c *gin.Context) {
+	row, ok := h.load(c)
+	if !ok {
+		return
+	}
+	delivery, errQueue := webhook.QueuePing(c.Request.Context(), h.db, row, "admin:"+c.GetString("adminUsername"), time.Now())
+	if errQueue != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "queue test delivery failed"})
+		return
+	}
+	webhook.Default().Wake()
+	c.JSON(http.StatusAccepted, formatWebhookDelivery(delivery))
+}
+
+// RetryDelivery requeues a delivery for an immediate attempt.
+func (h *WebhookHandler) RetryDelivery(c *gin.Context) {
+	id, errID := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	delivery, errRetry := webhook.Retry(c.Request.Context(), h.db, id, time.Now())
+	if errRetry != nil {
+		if errors.Is(errRetry, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "delivery not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "retry delivery failed"})
+		return
+	}
+	webhook.Default().Wake()
+	c.JSON(http.StatusAccepted, formatWebhookDelivery(delivery))
+}
+
+func (h *WebhookHandler) load(c *gin.Context) (*models.Webhook, bool) {
+	id, errID := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return nil, false
+	}
+	var row models.Webhook
+	if errFind := h.db.WithContext(c.Request.Context()).First(&row, "id = ?", id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "fetch webhook failed"})
+		return nil, false
+	}
+	return &row, true
+}
+
+// formatWebhook converts a webhook row into a response payload; the secret is masked by the
+// redaction middleware for admins without secrets access.
+func formatWebhook(row *models.Webhook) gin.H {
+	return gin.H{
+		"id":          row.ID,
+		"name":        row.Name,
+		"url":         row.URL,
+		"secret":      row.Secret,
+		"event_types": webhook.EventTypes(row),
+		"is_enabled":  row.IsEnabled,
+		"created_by":  row.CreatedBy,
+		"created_at":  row.CreatedAt,
+		"updated_at":  row.UpdatedAt,
+	}
+}
+
+// formatWebhookDelivery converts a delivery row into a response payload.
+func formatWebhookDelivery(row *models.WebhookDelivery) gin.H {
+	return gin.H{
+		"id":               row.ID,
+		"webhook_id":       row.WebhookID,
+		"event_id":         row.EventID,
+		"event_type":       row.EventType,
+		"payload":          json.RawMessage(row.Payload),
+		"status":           row.Status,
+		"attempts":         row.Attempts,
+		"next_attempt_at":  row.NextAttemptAt,
+		"last_attempt_at":  row.LastAttemptAt,
+		"last_status_code": row.LastStatusCode,
+		"last_error":       row.LastError,
+		"delivered_at":     row.DeliveredAt,
+		"created_at":       row.CreatedAt,
+	}
+}
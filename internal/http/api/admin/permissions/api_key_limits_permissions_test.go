@@ -0,0 +1,11 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesAPIKeyLimitsPermission(t *testing.T) {
+	t.Parallel()
+
+	if _, ok := DefinitionMap()["PUT /v0/admin/api-keys/:id/limits"]; !ok {
+		t.Fatal("DefinitionMap() missing permission key \"PUT /v0/admin/api-keys/:id/limits\"")
+	}
+}
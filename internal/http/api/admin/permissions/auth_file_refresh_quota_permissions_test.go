@@ -0,0 +1,12 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesAuthFileRefreshQuotaPermission(t *testing.T) {
+	t.Parallel()
+
+	key := "POST /v0/admin/auth-files/:id/refresh-quota"
+	if _, ok := DefinitionMap()[key]; !ok {
+		t.Fatalf("DefinitionMap() missing permission key %q", key)
+	}
+}
@@ -0,0 +1,19 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesAuthFilesApprovalPermissions(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{
+		"GET /v0/admin/auth-files/pending",
+		"POST /v0/admin/auth-files/:id/approve",
+		"POST /v0/admin/auth-files/:id/reject",
+	}
+	definitions := DefinitionMap()
+	for _, key := range keys {
+		if _, ok := definitions[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesAuthFileHealthPermission(t *testing.T) {
+	t.Parallel()
+
+	key := "GET /v0/admin/auth-files/health"
+	if _, ok := DefinitionMap()[key]; !ok {
+		t.Fatalf("DefinitionMap() missing permission key %q", key)
+	}
+}
@@ -0,0 +1,12 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesAuthFilesPollEnabledPermission(t *testing.T) {
+	t.Parallel()
+
+	key := "POST /v0/admin/auth-files/poll-enabled"
+	if _, ok := DefinitionMap()[key]; !ok {
+		t.Fatalf("DefinitionMap() missing permission key %q", key)
+	}
+}
@@ -0,0 +1,16 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesBackupPermissions(t *testing.T) {
+	t.Parallel()
+
+	for _, key := range []string{
+		"GET /v0/admin/backup",
+		"POST /v0/admin/restore",
+	} {
+		if _, ok := DefinitionMap()[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
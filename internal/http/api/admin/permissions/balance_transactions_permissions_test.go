@@ -0,0 +1,38 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesBalanceTransactionPermissions(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{
+		"POST /v0/admin/users/:id/balance-adjustments",
+		"GET /v0/admin/balance-transactions",
+	}
+	defs := DefinitionMap()
+	for _, key := range keys {
+		if _, ok := defs[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
+
+func TestBalanceTransactionRoutesRequireBilling(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{
+		"POST /v0/admin/users/:id/balance-adjustments",
+		"GET /v0/admin/balance-transactions",
+	}
+	for _, key := range keys {
+		if CategoryOf(key) != PermissionBilling {
+			t.Fatalf("CategoryOf(%q) = %q, want %q", key, CategoryOf(key), PermissionBilling)
+		}
+		if Allowed([]string{key, PermissionAccounts}, key) {
+			t.Fatalf("expected %q to require the billing permission", key)
+		}
+		if !Allowed([]string{key, PermissionBilling}, key) {
+			t.Fatalf("expected %q to be allowed with the billing permission", key)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesBulkDeleteJobPermissions(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{
+		"POST /v0/admin/bulk-delete-jobs",
+		"GET /v0/admin/bulk-delete-jobs",
+		"GET /v0/admin/bulk-delete-jobs/:id",
+		"POST /v0/admin/bulk-delete-jobs/:id/cancel",
+	}
+	defs := DefinitionMap()
+	for _, key := range keys {
+		if _, ok := defs[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package permissions
+
+import "github.com/router-for-me/CLIProxyAPIBusiness/internal/permcategory"
+
+// Category permissions gate whole areas of the admin API; see package permcategory for how
+// routes are assigned to them.
+const (
+	PermissionBilling        = permcategory.Billing
+	PermissionInfrastructure = permcategory.Infrastructure
+	PermissionAccounts       = permcategory.Accounts
+	PermissionAnalytics      = permcategory.Analytics
+)
+
+// CategoryOf returns the category permission guarding key, or "" when none does.
+func CategoryOf(key string) string {
+	return definitionMap[key].Category
+}
+
+// Allowed reports whether perms grant key, including the category permission it requires.
+func Allowed(perms []string, key string) bool {
+	if !HasPermission(perms, key) {
+		return false
+	}
+	category := CategoryOf(key)
+	return category == "" || HasPermission(perms, category)
+}
+
+// ImpliedCategories returns the category permissions needed by the route keys in perms.
+func ImpliedCategories(perms []string) []string {
+	return NormalizePermissions(permcategory.Implied(perms))
+}
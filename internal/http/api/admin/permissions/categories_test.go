@@ -0,0 +1,52 @@
+package permissions
+
+import "testing"
+
+func TestAllowedRequiresCategoryPermission(t *testing.T) {
+	t.Parallel()
+
+	const providerKey = "PUT /v0/admin/provider-api-keys/:id"
+	const bills = "GET /v0/admin/bills"
+	if CategoryOf(providerKey) != PermissionInfrastructure || CategoryOf(bills) != PermissionBilling {
+		t.Fatalf("unexpected categories %q and %q", CategoryOf(providerKey), CategoryOf(bills))
+	}
+	if CategoryOf("GET /v0/admin/admins") != "" {
+		t.Fatal("expected administrators to have no category")
+	}
+
+	finance := []string{bills, providerKey, PermissionBilling}
+	if !Allowed(finance, bills) {
+		t.Fatal("expected billing routes to be allowed with the billing permission")
+	}
+	if Allowed(finance, providerKey) {
+		t.Fatal("expected provider key routes to require the infrastructure permission")
+	}
+	if Allowed([]string{PermissionInfrastructure}, providerKey) {
+		t.Fatal("expected the category permission alone not to grant a route")
+	}
+	if !Allowed([]string{"GET /v0/admin/admins"}, "GET /v0/admin/admins") {
+		t.Fatal("expected routes without a category to need only their key")
+	}
+	if errValidate := ValidatePermissions([]string{PermissionBilling, PermissionInfrastructure, PermissionAccounts, PermissionAnalytics}); errValidate != nil {
+		t.Fatalf("ValidatePermissions(categories) = %v", errValidate)
+	}
+}
+
+func TestTemplatesGrantImpliedCategories(t *testing.T) {
+	t.Parallel()
+
+	billing, ok := TemplateByName("billing-admin")
+	if !ok {
+		t.Fatal("missing billing-admin template")
+	}
+	if !Allowed(billing.Permissions, "POST /v0/admin/bills") || !Allowed(billing.Permissions, "GET /v0/admin/users") {
+		t.Fatalf("billing-admin should manage bills and read users: %v", billing.Permissions)
+	}
+	if HasPermission(billing.Permissions, PermissionInfrastructure) {
+		t.Fatalf("billing-admin must not reach infrastructure routes: %v", billing.Permissions)
+	}
+	readOnly, _ := TemplateByName("read-only")
+	if !Allowed(readOnly.Permissions, "GET /v0/admin/provider-api-keys") {
+		t.Fatalf("read-only should read provider keys: %v", readOnly.Permissions)
+	}
+}
@@ -0,0 +1,20 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesChaosPermissions(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{
+		"GET /v0/admin/chaos/faults",
+		"POST /v0/admin/chaos/faults",
+		"DELETE /v0/admin/chaos/faults",
+		"DELETE /v0/admin/chaos/faults/:id",
+	}
+	defs := DefinitionMap()
+	for _, key := range keys {
+		if _, ok := defs[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
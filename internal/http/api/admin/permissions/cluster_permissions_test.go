@@ -0,0 +1,11 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesClusterPermissions(t *testing.T) {
+	t.Parallel()
+
+	if _, ok := DefinitionMap()["GET /v0/admin/cluster/instances"]; !ok {
+		t.Fatal("DefinitionMap() missing permission key \"GET /v0/admin/cluster/instances\"")
+	}
+}
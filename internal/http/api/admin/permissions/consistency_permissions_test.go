@@ -0,0 +1,16 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesConsistencyPermissions(t *testing.T) {
+	t.Parallel()
+
+	for _, key := range []string{
+		"GET /v0/admin/consistency",
+		"POST /v0/admin/consistency/repair",
+	} {
+		if _, ok := DefinitionMap()[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesCoopPermissions(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{
+		"GET /v0/admin/coop/contributions",
+		"POST /v0/admin/coop/contributions/:id/suspend",
+		"POST /v0/admin/coop/contributions/:id/resume",
+		"DELETE /v0/admin/coop/contributions/:id",
+		"POST /v0/admin/coop/settle",
+	}
+	defs := DefinitionMap()
+	for _, key := range keys {
+		if _, ok := defs[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesCostReplayPermissions(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{
+		"POST /v0/admin/billing-rule-snapshots",
+		"GET /v0/admin/billing-rule-snapshots",
+		"GET /v0/admin/billing-rule-snapshots/:id",
+		"POST /v0/admin/cost-replay-jobs",
+		"GET /v0/admin/cost-replay-jobs",
+		"GET /v0/admin/cost-replay-jobs/:id",
+		"POST /v0/admin/cost-replay-jobs/:id/cancel",
+	}
+	defs := DefinitionMap()
+	for _, key := range keys {
+		if _, ok := defs[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesCurrencyPermissions(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{
+		"GET /v0/admin/exchange-rates",
+		"POST /v0/admin/exchange-rates/fetch",
+		"PUT /v0/admin/exchange-rates/:currency",
+		"DELETE /v0/admin/exchange-rates/:currency",
+	}
+	defs := DefinitionMap()
+	for _, key := range keys {
+		if _, ok := defs[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesDashboardErrorBreakdownPermission(t *testing.T) {
+	t.Parallel()
+
+	key := "GET /v0/admin/dashboard/error-breakdown"
+	if _, ok := DefinitionMap()[key]; !ok {
+		t.Fatalf("DefinitionMap() missing permission key %q", key)
+	}
+}
@@ -0,0 +1,12 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesDashboardKPIHistoryPermission(t *testing.T) {
+	t.Parallel()
+
+	key := "GET /v0/admin/dashboard/kpi/history"
+	if _, ok := DefinitionMap()[key]; !ok {
+		t.Fatalf("DefinitionMap() missing permission key %q", key)
+	}
+}
@@ -0,0 +1,16 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesEnvironmentPermissions(t *testing.T) {
+	t.Parallel()
+
+	for _, key := range []string{
+		"GET /v0/admin/environments",
+		"POST /v0/admin/environments/sync",
+	} {
+		if _, ok := DefinitionMap()[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesInvitationPermissions(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{
+		"POST /v0/admin/invitations",
+		"GET /v0/admin/invitations",
+		"GET /v0/admin/invitations/:id",
+		"PUT /v0/admin/invitations/:id",
+		"DELETE /v0/admin/invitations/:id",
+	}
+	defs := DefinitionMap()
+	for _, key := range keys {
+		if _, ok := defs[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
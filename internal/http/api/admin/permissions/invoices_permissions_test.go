@@ -0,0 +1,23 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesInvoicePermissions(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{
+		"POST /v0/admin/invoices/generate",
+		"GET /v0/admin/invoices",
+		"GET /v0/admin/invoices/:id",
+		"GET /v0/admin/invoices/:id/render",
+		"POST /v0/admin/invoices/:id/finalize",
+		"POST /v0/admin/invoices/:id/mark-paid",
+		"POST /v0/admin/invoices/:id/send",
+	}
+	defs := DefinitionMap()
+	for _, key := range keys {
+		if _, ok := defs[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesModelDisplayPermissions(t *testing.T) {
+	t.Parallel()
+
+	for _, key := range []string{
+		"POST /v0/admin/model-displays",
+		"GET /v0/admin/model-displays",
+		"PUT /v0/admin/model-displays/:id",
+		"DELETE /v0/admin/model-displays/:id",
+	} {
+		if _, ok := DefinitionMap()[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesModelMappingBulkPermissions(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{
+		"POST /v0/admin/model-mappings/bulk",
+		"GET /v0/admin/model-mappings/resolve",
+		"GET /v0/admin/model-mappings/conflicts",
+	}
+	defs := DefinitionMap()
+	for _, key := range keys {
+		if _, ok := defs[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
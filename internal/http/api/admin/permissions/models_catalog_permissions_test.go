@@ -0,0 +1,11 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesModelCatalogPermission(t *testing.T) {
+	t.Parallel()
+
+	if _, ok := DefinitionMap()["GET /v0/admin/models/catalog"]; !ok {
+		t.Fatalf("DefinitionMap() missing permission key %q", "GET /v0/admin/models/catalog")
+	}
+}
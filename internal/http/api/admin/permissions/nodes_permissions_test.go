@@ -0,0 +1,20 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesNodePermissions(t *testing.T) {
+	t.Parallel()
+
+	for _, key := range []string{
+		"POST /v0/admin/nodes",
+		"GET /v0/admin/nodes",
+		"PUT /v0/admin/nodes/:id",
+		"DELETE /v0/admin/nodes/:id",
+		"POST /v0/admin/nodes/:id/rotate-token",
+		"POST /v0/admin/nodes/:id/push",
+	} {
+		if _, ok := DefinitionMap()[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
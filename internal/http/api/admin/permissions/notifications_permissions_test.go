@@ -0,0 +1,19 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesNotificationPermissions(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{
+		"GET /v0/admin/notifications",
+		"PUT /v0/admin/notifications",
+		"PUT /v0/admin/notifications/:id",
+	}
+	defs := DefinitionMap()
+	for _, key := range keys {
+		if _, ok := defs[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
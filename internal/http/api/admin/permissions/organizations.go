@@ -0,0 +1,43 @@
+package permissions
+
+// organizationKeys lists the permissions whose handlers scope rows to the request
+// organization. Organization admins are denied every other route, whatever their role grants.
+var organizationKeys = map[string]struct{}{
+	"GET /v0/admin/dashboard/kpi":               {},
+	"GET /v0/admin/dashboard/traffic":           {},
+	"GET /v0/admin/dashboard/cost-distribution": {},
+	"GET /v0/admin/dashboard/transactions":      {},
+	"POST /v0/admin/users":                      {},
+	"GET /v0/admin/users":                       {},
+	"GET /v0/admin/users/:id":                   {},
+	"PUT /v0/admin/users/:id":                   {},
+	"DELETE /v0/admin/users/:id":                {},
+	"POST /v0/admin/users/:id/disable":          {},
+	"POST /v0/admin/users/:id/enable":           {},
+	"PUT /v0/admin/users/:id/password":          {},
+	"POST /v0/admin/user-groups":                {},
+	"GET /v0/admin/user-groups":                 {},
+	"GET /v0/admin/user-groups/:id":             {},
+	"PUT /v0/admin/user-groups/:id":             {},
+	"DELETE /v0/admin/user-groups/:id":          {},
+	"GET /v0/admin/api-keys":                    {},
+	"DELETE /v0/admin/api-keys/:id":             {},
+	"PUT /v0/admin/api-keys/:id/limits":         {},
+	"POST /v0/admin/users/:id/api-keys":         {},
+	"GET /v0/admin/users/:id/api-keys":          {},
+	"POST /v0/admin/bills":                      {},
+	"GET /v0/admin/bills":                       {},
+	"GET /v0/admin/bills/:id":                   {},
+	"PUT /v0/admin/bills/:id":                   {},
+	"DELETE /v0/admin/bills/:id":                {},
+	"POST /v0/admin/bills/:id/enable":           {},
+	"POST /v0/admin/bills/:id/disable":          {},
+	"GET /v0/admin/usage":                       {},
+	"GET /v0/admin/organizations/current":       {},
+}
+
+// OrganizationAllowed reports whether organization admins may call the permission key.
+func OrganizationAllowed(key string) bool {
+	_, ok := organizationKeys[key]
+	return ok
+}
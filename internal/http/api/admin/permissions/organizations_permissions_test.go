@@ -0,0 +1,34 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesOrganizationPermissions(t *testing.T) {
+	t.Parallel()
+
+	for _, key := range []string{
+		"POST /v0/admin/organizations",
+		"GET /v0/admin/organizations",
+		"GET /v0/admin/organizations/current",
+		"POST /v0/admin/organizations/select",
+		"GET /v0/admin/organizations/:id",
+		"PUT /v0/admin/organizations/:id",
+		"DELETE /v0/admin/organizations/:id",
+	} {
+		if _, ok := DefinitionMap()[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
+
+func TestOrganizationAllowedKeysAreDefined(t *testing.T) {
+	t.Parallel()
+
+	for key := range organizationKeys {
+		if _, ok := DefinitionMap()[key]; !ok {
+			t.Fatalf("organization allowlist names unknown permission key %q", key)
+		}
+	}
+	if OrganizationAllowed("POST /v0/admin/organizations/select") {
+		t.Fatalf("organization admins must not switch organizations")
+	}
+}
@@ -5,17 +5,24 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/permcategory"
 )
 
 // Definition describes an admin permission.
 type Definition struct {
-	Key    string `json:"key"`
-	Method string `json:"method"`
-	Path   string `json:"path"`
-	Label  string `json:"label"`
-	Module string `json:"module"`
+	Key      string `json:"key"`
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	Label    string `json:"label"`
+	Module   string `json:"module"`
+	Category string `json:"category,omitempty"` // Category permission also required; empty when none.
 }
 
+// PermissionSecrets lets admins read secrets such as API keys, proxy credentials, and auth
+// content unmasked. It is not bound to a route.
+const PermissionSecrets = "secrets"
+
 // Key builds a permission key from method and path.
 func Key(method, path string) string {
 	return strings.ToUpper(method) + " " + path
@@ -111,21 +118,26 @@ func DefinitionMap() map[string]Definition {
 // newDefinition builds a Definition with a normalized key.
 func newDefinition(method, path, label, module string) Definition {
 	upperMethod := strings.ToUpper(method)
+	key := Key(upperMethod, path)
 	return Definition{
-		Key:    Key(upperMethod, path),
-		Method: upperMethod,
-		Path:   path,
-		Label:  label,
-		Module: module,
+		Key:      key,
+		Method:   upperMethod,
+		Path:     path,
+		Label:    label,
+		Module:   module,
+		Category: permcategory.OfRoute(key),
 	}
 }
 
 // definitions is the ordered list of permission definitions.
 var definitions = []Definition{
 	newDefinition("GET", "/v0/admin/dashboard/kpi", "View KPI", "Dashboard"),
+	newDefinition("GET", "/v0/admin/dashboard/kpi/history", "View KPI History", "Dashboard"),
 	newDefinition("GET", "/v0/admin/dashboard/traffic", "View Traffic", "Dashboard"),
 	newDefinition("GET", "/v0/admin/dashboard/cost-distribution", "View Cost Distribution", "Dashboard"),
 	newDefinition("GET", "/v0/admin/dashboard/model-health", "View Model Health", "Dashboard"),
+	newDefinition("GET", "/v0/admin/dashboard/retry-pressure", "View Retry Pressure", "Dashboard"),
+	newDefinition("GET", "/v0/admin/dashboard/error-breakdown", "View Error Breakdown", "Dashboard"),
 	newDefinition("GET", "/v0/admin/dashboard/transactions", "View Recent Transactions", "Dashboard"),
 	newDefinition("GET", "/v0/admin/dashboard/transactions/:id/request-log", "View Transaction Request Log", "Dashboard"),
 
@@ -137,6 +149,65 @@ var definitions = []Definition{
 	newDefinition("POST", "/v0/admin/users/:id/disable", "Disable User", "Users"),
 	newDefinition("POST", "/v0/admin/users/:id/enable", "Enable User", "Users"),
 	newDefinition("PUT", "/v0/admin/users/:id/password", "Change User Password", "Users"),
+	newDefinition("POST", "/v0/admin/users/:id/group-migrations", "Migrate User Group", "Users"),
+	newDefinition("GET", "/v0/admin/users/:id/group-migrations", "List User Group Migrations", "Users"),
+	newDefinition("POST", "/v0/admin/user-group-migrations/:id/cancel", "Cancel User Group Migration", "Users"),
+	newDefinition("POST", "/v0/admin/users/:id/balance-adjustments", "Adjust User Balance", "Users"),
+	newDefinition("GET", "/v0/admin/balance-transactions", "List Balance Transactions", "Users"),
+	newDefinition("GET", "/v0/admin/user-lifecycle/events", "List User Lifecycle Events", "Users"),
+	newDefinition("POST", "/v0/admin/user-lifecycle/run", "Run User Lifecycle Policies", "Users"),
+
+	newDefinition("POST", "/v0/admin/bulk-delete-jobs", "Create Bulk Delete Job", "Bulk Delete"),
+	newDefinition("GET", "/v0/admin/bulk-delete-jobs", "List Bulk Delete Jobs", "Bulk Delete"),
+	newDefinition("GET", "/v0/admin/bulk-delete-jobs/:id", "Get Bulk Delete Job", "Bulk Delete"),
+	newDefinition("POST", "/v0/admin/bulk-delete-jobs/:id/cancel", "Cancel Bulk Delete Job", "Bulk Delete"),
+
+	newDefinition("POST", "/v0/admin/billing-rule-snapshots", "Create Billing Rule Snapshot", "Cost Replay"),
+	newDefinition("GET", "/v0/admin/billing-rule-snapshots", "List Billing Rule Snapshots", "Cost Replay"),
+	newDefinition("GET", "/v0/admin/billing-rule-snapshots/:id", "Get Billing Rule Snapshot", "Cost Replay"),
+	newDefinition("POST", "/v0/admin/cost-replay-jobs", "Create Cost Replay Job", "Cost Replay"),
+	newDefinition("GET", "/v0/admin/cost-replay-jobs", "List Cost Replay Jobs", "Cost Replay"),
+	newDefinition("GET", "/v0/admin/cost-replay-jobs/:id", "Get Cost Replay Job", "Cost Replay"),
+	newDefinition("POST", "/v0/admin/cost-replay-jobs/:id/cancel", "Cancel Cost Replay Job", "Cost Replay"),
+	newDefinition("GET", "/v0/admin/exchange-rates", "List Exchange Rates", "Currency"),
+	newDefinition("POST", "/v0/admin/exchange-rates/fetch", "Fetch Exchange Rates", "Currency"),
+	newDefinition("PUT", "/v0/admin/exchange-rates/:currency", "Set Exchange Rate", "Currency"),
+	newDefinition("DELETE", "/v0/admin/exchange-rates/:currency", "Delete Exchange Rate", "Currency"),
+	newDefinition("GET", "/v0/admin/notifications", "List Notifications", "Notifications"),
+	newDefinition("PUT", "/v0/admin/notifications", "Update Notifications", "Notifications"),
+	newDefinition("PUT", "/v0/admin/notifications/:id", "Update Notification", "Notifications"),
+	newDefinition("GET", "/v0/admin/slos", "List SLOs", "SLOs"),
+	newDefinition("POST", "/v0/admin/slos", "Create SLO", "SLOs"),
+	newDefinition("GET", "/v0/admin/slos/status", "Get SLO Status", "SLOs"),
+	newDefinition("GET", "/v0/admin/slos/:id/status", "Get SLO Status Detail", "SLOs"),
+	newDefinition("PUT", "/v0/admin/slos/:id", "Update SLO", "SLOs"),
+	newDefinition("DELETE", "/v0/admin/slos/:id", "Delete SLO", "SLOs"),
+	newDefinition("GET", "/v0/admin/webhooks", "List Webhooks", "Webhooks"),
+	newDefinition("POST", "/v0/admin/webhooks", "Create Webhook", "Webhooks"),
+	newDefinition("PUT", "/v0/admin/webhooks/:id", "Update Webhook", "Webhooks"),
+	newDefinition("DELETE", "/v0/admin/webhooks/:id", "Delete Webhook", "Webhooks"),
+	newDefinition("GET", "/v0/admin/webhooks/:id/deliveries", "List Webhook Deliveries", "Webhooks"),
+	newDefinition("POST", "/v0/admin/webhooks/:id/test", "Test Webhook", "Webhooks"),
+	newDefinition("POST", "/v0/admin/webhook-deliveries/:id/retry", "Retry Webhook Delivery", "Webhooks"),
+	newDefinition("GET", "/v0/admin/mfa-compliance", "View MFA Compliance", "Administrators"),
+	newDefinition("GET", "/v0/admin/chaos/faults", "List Chaos Faults", "Chaos Testing"),
+	newDefinition("POST", "/v0/admin/chaos/faults", "Inject Chaos Fault", "Chaos Testing"),
+	newDefinition("DELETE", "/v0/admin/chaos/faults", "Clear Chaos Faults", "Chaos Testing"),
+	newDefinition("DELETE", "/v0/admin/chaos/faults/:id", "Clear Chaos Fault", "Chaos Testing"),
+
+	newDefinition("POST", "/v0/admin/invoices/generate", "Generate Invoices", "Invoices"),
+	newDefinition("GET", "/v0/admin/invoices", "List Invoices", "Invoices"),
+	newDefinition("GET", "/v0/admin/invoices/:id", "Get Invoice", "Invoices"),
+	newDefinition("GET", "/v0/admin/invoices/:id/render", "Download Invoice", "Invoices"),
+	newDefinition("POST", "/v0/admin/invoices/:id/finalize", "Finalize Invoice", "Invoices"),
+	newDefinition("POST", "/v0/admin/invoices/:id/mark-paid", "Mark Invoice Paid", "Invoices"),
+	newDefinition("POST", "/v0/admin/invoices/:id/send", "Email Invoice", "Invoices"),
+
+	newDefinition("GET", "/v0/admin/coop/contributions", "List Co-op Contributions", "Co-op Pool"),
+	newDefinition("POST", "/v0/admin/coop/contributions/:id/suspend", "Suspend Co-op Contribution", "Co-op Pool"),
+	newDefinition("POST", "/v0/admin/coop/contributions/:id/resume", "Resume Co-op Contribution", "Co-op Pool"),
+	newDefinition("DELETE", "/v0/admin/coop/contributions/:id", "Revoke Co-op Contribution", "Co-op Pool"),
+	newDefinition("POST", "/v0/admin/coop/settle", "Settle Co-op Credit", "Co-op Pool"),
 
 	newDefinition("POST", "/v0/admin/user-groups", "Create User Group", "User Groups"),
 	newDefinition("GET", "/v0/admin/user-groups", "List User Groups", "User Groups"),
@@ -155,29 +226,47 @@ var definitions = []Definition{
 	newDefinition("POST", "/v0/admin/auth-files", "Create Auth File", "Auth Files"),
 	newDefinition("POST", "/v0/admin/auth-files/import", "Import Auth Files", "Auth Files"),
 	newDefinition("POST", "/v0/admin/auth-files/import-by-provider", "Import Auth Files By Provider", "Auth Files"),
+	newDefinition("GET", "/v0/admin/auth-files/import-conflicts", "List Auth File Import Conflicts", "Auth Files"),
+	newDefinition("POST", "/v0/admin/auth-files/import-conflicts/:id/resolve", "Resolve Auth File Import Conflict", "Auth Files"),
+	newDefinition("POST", "/v0/admin/auth-files/poll-enabled", "Set Auth Files Quota Polling", "Auth Files"),
 	newDefinition("GET", "/v0/admin/auth-files", "List Auth Files", "Auth Files"),
 	newDefinition("GET", "/v0/admin/auth-files/:id", "Get Auth File", "Auth Files"),
 	newDefinition("PUT", "/v0/admin/auth-files/:id", "Update Auth File", "Auth Files"),
 	newDefinition("DELETE", "/v0/admin/auth-files/:id", "Delete Auth File", "Auth Files"),
 	newDefinition("POST", "/v0/admin/auth-files/:id/available", "Set Auth File Available", "Auth Files"),
 	newDefinition("POST", "/v0/admin/auth-files/:id/unavailable", "Set Auth File Unavailable", "Auth Files"),
+	newDefinition("POST", "/v0/admin/auth-files/:id/reauth", "Re-authenticate Auth File", "Auth Files"),
 	newDefinition("GET", "/v0/admin/auth-files/types", "List Auth File Types", "Auth Files"),
+	newDefinition("GET", "/v0/admin/auth-files/health", "Auth File Health", "Auth Files"),
 	newDefinition("GET", "/v0/admin/auth-files/model-presets", "List Auth File Model Presets", "Auth Files"),
+	newDefinition("GET", "/v0/admin/auth-files/pending", "List Pending Auth Files", "Auth Files"),
+	newDefinition("POST", "/v0/admin/auth-files/:id/approve", "Approve Auth File", "Auth Files"),
+	newDefinition("POST", "/v0/admin/auth-files/:id/reject", "Reject Auth File", "Auth Files"),
 
 	newDefinition("GET", "/v0/admin/quotas", "List Quotas", "Quota"),
+	newDefinition("GET", "/v0/admin/quotas/providers", "List Quota Providers", "Quota"),
 	newDefinition("POST", "/v0/admin/quotas/manual-refresh", "Trigger Quota Manual Refresh", "Quota"),
 	newDefinition("GET", "/v0/admin/quotas/manual-refresh/:task_id", "Get Quota Manual Refresh Task", "Quota"),
+	newDefinition("POST", "/v0/admin/auth-files/:id/refresh-quota", "Refresh Auth File Quota", "Quota"),
 
 	newDefinition("POST", "/v0/admin/model-mappings", "Create Model Mapping", "Models"),
 	newDefinition("GET", "/v0/admin/model-mappings", "List Model Mappings", "Models"),
 	newDefinition("GET", "/v0/admin/model-mappings/providers", "List Model Mapping Providers", "Models"),
 	newDefinition("GET", "/v0/admin/model-mappings/available-models", "List Available Models", "Models"),
+	newDefinition("POST", "/v0/admin/model-mappings/bulk", "Bulk Upsert Model Mappings", "Models"),
+	newDefinition("GET", "/v0/admin/model-mappings/resolve", "Resolve Model Mapping", "Models"),
+	newDefinition("GET", "/v0/admin/model-mappings/conflicts", "List Model Mapping Conflicts", "Models"),
 	newDefinition("GET", "/v0/admin/model-references/price", "Get Model Reference Price", "Models"),
+	newDefinition("GET", "/v0/admin/models/catalog", "Get Model Catalog", "Models"),
 	newDefinition("GET", "/v0/admin/model-mappings/:id", "Get Model Mapping", "Models"),
 	newDefinition("PUT", "/v0/admin/model-mappings/:id", "Update Model Mapping", "Models"),
 	newDefinition("DELETE", "/v0/admin/model-mappings/:id", "Delete Model Mapping", "Models"),
 	newDefinition("POST", "/v0/admin/model-mappings/:id/enable", "Enable Model Mapping", "Models"),
 	newDefinition("POST", "/v0/admin/model-mappings/:id/disable", "Disable Model Mapping", "Models"),
+	newDefinition("POST", "/v0/admin/model-displays", "Create Model Display", "Models"),
+	newDefinition("GET", "/v0/admin/model-displays", "List Model Displays", "Models"),
+	newDefinition("PUT", "/v0/admin/model-displays/:id", "Update Model Display", "Models"),
+	newDefinition("DELETE", "/v0/admin/model-displays/:id", "Delete Model Display", "Models"),
 	newDefinition("GET", "/v0/admin/model-mappings/:id/payload-rules", "List Model Payload Rules", "Models"),
 	newDefinition("POST", "/v0/admin/model-mappings/:id/payload-rules", "Create Model Payload Rule", "Models"),
 	newDefinition("PUT", "/v0/admin/model-mappings/:id/payload-rules/:rule_id", "Update Model Payload Rule", "Models"),
@@ -186,20 +275,43 @@ var definitions = []Definition{
 	newDefinition("POST", "/v0/admin/api-keys", "Create API Key", "API Keys"),
 	newDefinition("GET", "/v0/admin/api-keys", "List API Keys", "API Keys"),
 	newDefinition("DELETE", "/v0/admin/api-keys/:id", "Revoke API Key", "API Keys"),
+	newDefinition("PUT", "/v0/admin/api-keys/:id/limits", "Update API Key Rate Limits", "API Keys"),
 	newDefinition("POST", "/v0/admin/users/:id/api-keys", "Create User API Key", "API Keys"),
 	newDefinition("GET", "/v0/admin/users/:id/api-keys", "List User API Keys", "API Keys"),
 
 	newDefinition("POST", "/v0/admin/provider-api-keys", "Create Provider API Key", "Provider API Keys"),
 	newDefinition("GET", "/v0/admin/provider-api-keys", "List Provider API Keys", "Provider API Keys"),
+	newDefinition("POST", "/v0/admin/provider-api-keys/reorder", "Reorder Provider API Keys", "Provider API Keys"),
 	newDefinition("PUT", "/v0/admin/provider-api-keys/:id", "Update Provider API Key", "Provider API Keys"),
 	newDefinition("DELETE", "/v0/admin/provider-api-keys/:id", "Delete Provider API Key", "Provider API Keys"),
+	newDefinition("POST", "/v0/admin/provider-api-keys/:id/canary", "Start Provider API Key Canary", "Provider API Keys"),
+	newDefinition("POST", "/v0/admin/provider-api-keys/:id/canary/promote", "Promote Provider API Key Canary", "Provider API Keys"),
+	newDefinition("POST", "/v0/admin/provider-api-keys/:id/canary/rollback", "Roll Back Provider API Key Canary", "Provider API Keys"),
+	newDefinition("GET", "/v0/admin/provider-api-keys/:id/canary/report", "View Provider API Key Canary Report", "Provider API Keys"),
+	newDefinition("GET", "/v0/admin/environments", "List Environments", "Provider API Keys"),
+	newDefinition("POST", "/v0/admin/environments/sync", "Sync Environments", "Provider API Keys"),
+	newDefinition("GET", "/v0/admin/retry-policies", "List Retry Policies", "Provider API Keys"),
+	newDefinition("POST", "/v0/admin/retry-policies", "Create Retry Policy", "Provider API Keys"),
+	newDefinition("PUT", "/v0/admin/retry-policies/:id", "Update Retry Policy", "Provider API Keys"),
+	newDefinition("DELETE", "/v0/admin/retry-policies/:id", "Delete Retry Policy", "Provider API Keys"),
 
 	newDefinition("POST", "/v0/admin/proxies", "Create Proxy", "Proxies"),
 	newDefinition("POST", "/v0/admin/proxies/batch", "Batch Create Proxies", "Proxies"),
 	newDefinition("GET", "/v0/admin/proxies", "List Proxies", "Proxies"),
+	newDefinition("GET", "/v0/admin/proxies/health", "View Proxy Health", "Proxies"),
 	newDefinition("PUT", "/v0/admin/proxies/:id", "Update Proxy", "Proxies"),
 	newDefinition("DELETE", "/v0/admin/proxies/:id", "Delete Proxy", "Proxies"),
 
+	newDefinition("POST", "/v0/admin/nodes", "Create Node", "Nodes"),
+	newDefinition("GET", "/v0/admin/nodes", "List Nodes", "Nodes"),
+	newDefinition("PUT", "/v0/admin/nodes/:id", "Update Node", "Nodes"),
+	newDefinition("DELETE", "/v0/admin/nodes/:id", "Delete Node", "Nodes"),
+	newDefinition("POST", "/v0/admin/nodes/:id/rotate-token", "Rotate Node Token", "Nodes"),
+	newDefinition("POST", "/v0/admin/nodes/:id/push", "Push Node Config", "Nodes"),
+	newDefinition("GET", "/v0/admin/cluster/instances", "List Cluster Instances", "Nodes"),
+	newDefinition("GET", "/v0/admin/backup", "Download Backup", "Backup"),
+	newDefinition("POST", "/v0/admin/restore", "Restore Backup", "Backup"),
+
 	newDefinition("POST", "/v0/admin/prepaid-cards", "Create Prepaid Card", "Prepaid Cards"),
 	newDefinition("POST", "/v0/admin/prepaid-cards/batch", "Batch Create Prepaid Cards", "Prepaid Cards"),
 	newDefinition("GET", "/v0/admin/prepaid-cards", "List Prepaid Cards", "Prepaid Cards"),
@@ -207,6 +319,16 @@ var definitions = []Definition{
 	newDefinition("PUT", "/v0/admin/prepaid-cards/:id", "Update Prepaid Card", "Prepaid Cards"),
 	newDefinition("DELETE", "/v0/admin/prepaid-cards/:id", "Delete Prepaid Card", "Prepaid Cards"),
 
+	newDefinition("GET", "/v0/admin/request-logs", "List Request Logs", "Request Logs"),
+	newDefinition("POST", "/v0/admin/request-logs/purge", "Purge Request Logs", "Request Logs"),
+	newDefinition("GET", "/v0/admin/request-logs/:id", "Get Request Log", "Request Logs"),
+	newDefinition("POST", "/v0/admin/request-logs/:id/redact", "Redact Request Log", "Request Logs"),
+	newDefinition("POST", "/v0/admin/invitations", "Create Invitations", "Invitations"),
+	newDefinition("GET", "/v0/admin/invitations", "List Invitations", "Invitations"),
+	newDefinition("GET", "/v0/admin/invitations/:id", "Get Invitation", "Invitations"),
+	newDefinition("PUT", "/v0/admin/invitations/:id", "Update Invitation", "Invitations"),
+	newDefinition("DELETE", "/v0/admin/invitations/:id", "Delete Invitation", "Invitations"),
+
 	newDefinition("POST", "/v0/admin/bills", "Create Bill", "Bills"),
 	newDefinition("GET", "/v0/admin/bills", "List Bills", "Bills"),
 	newDefinition("GET", "/v0/admin/bills/:id", "Get Bill", "Bills"),
@@ -232,11 +354,33 @@ var definitions = []Definition{
 
 	newDefinition("POST", "/v0/admin/settings", "Create Setting", "Settings"),
 	newDefinition("GET", "/v0/admin/settings", "List Settings", "Settings"),
+	newDefinition("PUT", "/v0/admin/settings", "Update Settings", "Settings"),
+	newDefinition("GET", "/v0/admin/settings/registry", "View Settings Registry", "Settings"),
+	newDefinition("GET", "/v0/admin/settings/history", "View Settings History", "Settings"),
+	newDefinition("GET", "/v0/admin/security/jwt", "View JWT Status", "Settings"),
+	newDefinition("POST", "/v0/admin/security/jwt/rotate", "Rotate JWT Secret", "Settings"),
+	newDefinition("GET", "/v0/admin/security/webauthn", "View WebAuthn Config", "Settings"),
+	newDefinition("PUT", "/v0/admin/security/webauthn", "Update WebAuthn Config", "Settings"),
+	newDefinition("POST", "/v0/admin/organizations", "Create Organization", "Organizations"),
+	newDefinition("GET", "/v0/admin/organizations", "List Organizations", "Organizations"),
+	newDefinition("GET", "/v0/admin/organizations/current", "View Current Organization", "Organizations"),
+	newDefinition("POST", "/v0/admin/organizations/select", "Select Organization", "Organizations"),
+	newDefinition("GET", "/v0/admin/organizations/:id", "Get Organization", "Organizations"),
+	newDefinition("PUT", "/v0/admin/organizations/:id", "Update Organization", "Organizations"),
+	newDefinition("DELETE", "/v0/admin/organizations/:id", "Delete Organization", "Organizations"),
+	newDefinition("GET", "/v0/admin/consistency", "View Consistency Report", "Settings"),
+	newDefinition("POST", "/v0/admin/consistency/repair", "Repair Consistency Issues", "Settings"),
 	newDefinition("GET", "/v0/admin/settings/:key", "Get Setting", "Settings"),
 	newDefinition("PUT", "/v0/admin/settings/:key", "Update Setting", "Settings"),
 	newDefinition("DELETE", "/v0/admin/settings/:key", "Delete Setting", "Settings"),
+	newDefinition("POST", "/v0/admin/webhook-templates/validate", "Validate Webhook Template", "Settings"),
+	newDefinition("POST", "/v0/admin/webhook-templates/test-fire", "Test Fire Webhook", "Settings"),
 
 	newDefinition("GET", "/v0/admin/usage", "View Usage", "Usage"),
+	newDefinition("GET", "/v0/admin/usage/daily", "View Daily Usage Rollups", "Usage"),
+	newDefinition("GET", "/v0/admin/usage/proxies", "View Usage by Proxy", "Usage"),
+	newDefinition("GET", "/v0/admin/usages/stream", "Stream Usages", "Usage"),
+	newDefinition("GET", "/v0/admin/usages/:id/billing-explanation", "Explain Usage Billing", "Usage"),
 	newDefinition("GET", "/v0/admin/billing/summary", "View Billing Summary", "Billing"),
 
 	newDefinition("POST", "/v0/admin/admins", "Create Administrator", "Administrators"),
@@ -248,6 +392,11 @@ var definitions = []Definition{
 	newDefinition("POST", "/v0/admin/admins/:id/enable", "Enable Administrator", "Administrators"),
 	newDefinition("PUT", "/v0/admin/admins/:id/password", "Change Administrator Password", "Administrators"),
 	newDefinition("GET", "/v0/admin/permissions", "List Permission Definitions", "Administrators"),
+	newDefinition("GET", "/v0/admin/admin-roles", "List Admin Roles", "Administrators"),
+	newDefinition("GET", "/v0/admin/admin-roles/templates", "List Admin Role Templates", "Administrators"),
+	newDefinition("POST", "/v0/admin/admin-roles", "Create Admin Role", "Administrators"),
+	newDefinition("PUT", "/v0/admin/admin-roles/:id", "Update Admin Role", "Administrators"),
+	newDefinition("DELETE", "/v0/admin/admin-roles/:id", "Delete Admin Role", "Administrators"),
 
 	newDefinition("POST", "/v0/admin/plans", "Create Plan", "Plans"),
 	newDefinition("GET", "/v0/admin/plans", "List Plans", "Plans"),
@@ -256,6 +405,13 @@ var definitions = []Definition{
 	newDefinition("DELETE", "/v0/admin/plans/:id", "Delete Plan", "Plans"),
 	newDefinition("POST", "/v0/admin/plans/:id/enable", "Enable Plan", "Plans"),
 	newDefinition("POST", "/v0/admin/plans/:id/disable", "Disable Plan", "Plans"),
+	newDefinition("POST", "/v0/admin/tier-upgrade-rules", "Create Tier Upgrade Rule", "Tier Upgrades"),
+	newDefinition("GET", "/v0/admin/tier-upgrade-rules", "List Tier Upgrade Rules", "Tier Upgrades"),
+	newDefinition("PUT", "/v0/admin/tier-upgrade-rules/:id", "Update Tier Upgrade Rule", "Tier Upgrades"),
+	newDefinition("DELETE", "/v0/admin/tier-upgrade-rules/:id", "Delete Tier Upgrade Rule", "Tier Upgrades"),
+	newDefinition("GET", "/v0/admin/tier-upgrades", "List Tier Upgrades", "Tier Upgrades"),
+	newDefinition("POST", "/v0/admin/tier-upgrades/:id/approve", "Approve Tier Upgrade", "Tier Upgrades"),
+	newDefinition("POST", "/v0/admin/tier-upgrades/:id/reject", "Reject Tier Upgrade", "Tier Upgrades"),
 
 	newDefinition("POST", "/v0/admin/tokens/anthropic", "Request Anthropic Token", "Auth Tokens"),
 	newDefinition("POST", "/v0/admin/tokens/gemini", "Request Gemini Token", "Auth Tokens"),
@@ -270,6 +426,12 @@ var definitions = []Definition{
 	newDefinition("POST", "/v0/admin/tokens/iflow-cookie", "Request IFlow Cookie Token", "Auth Tokens"),
 	newDefinition("POST", "/v0/admin/tokens/get-auth-status", "Check Auth Status", "Auth Tokens"),
 	newDefinition("POST", "/v0/admin/tokens/oauth-callback", "Submit OAuth Callback", "Auth Tokens"),
+
+	{Key: PermissionSecrets, Label: "View Secrets", Module: "Security"},
+	{Key: PermissionBilling, Label: "Billing Area", Module: "Security"},
+	{Key: PermissionInfrastructure, Label: "Infrastructure Area", Module: "Security"},
+	{Key: PermissionAccounts, Label: "Accounts Area", Module: "Security"},
+	{Key: PermissionAnalytics, Label: "Analytics Area", Module: "Security"},
 }
 
 // definitionMap provides fast lookup for permission definitions.
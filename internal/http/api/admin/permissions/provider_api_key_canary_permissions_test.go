@@ -0,0 +1,20 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesProviderAPIKeyCanaryPermissions(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{
+		"POST /v0/admin/provider-api-keys/:id/canary",
+		"POST /v0/admin/provider-api-keys/:id/canary/promote",
+		"POST /v0/admin/provider-api-keys/:id/canary/rollback",
+		"GET /v0/admin/provider-api-keys/:id/canary/report",
+	}
+	defs := DefinitionMap()
+	for _, key := range keys {
+		if _, ok := defs[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
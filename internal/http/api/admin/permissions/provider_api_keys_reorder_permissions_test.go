@@ -0,0 +1,12 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesProviderAPIKeysReorderPermission(t *testing.T) {
+	t.Parallel()
+
+	key := "POST /v0/admin/provider-api-keys/reorder"
+	if _, ok := DefinitionMap()[key]; !ok {
+		t.Fatalf("DefinitionMap() missing permission key %q", key)
+	}
+}
@@ -0,0 +1,12 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesQuotaProvidersPermission(t *testing.T) {
+	t.Parallel()
+
+	key := "GET /v0/admin/quotas/providers"
+	if _, ok := DefinitionMap()[key]; !ok {
+		t.Fatalf("DefinitionMap() missing permission key %q", key)
+	}
+}
@@ -0,0 +1,116 @@
+package permissions
+
+import "net/http"
+
+// Template is a suggested role definition admins can start a role from.
+type Template struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// billingModules groups the modules a billing administrator manages.
+var billingModules = map[string]struct{}{
+	"Billing":       {},
+	"Billing Rules": {},
+	"Bills":         {},
+	"Currency":      {},
+	"Invoices":      {},
+	"Plans":         {},
+	"Prepaid Cards": {},
+	"Tier Upgrades": {},
+}
+
+// supportModules groups the modules support staff can read.
+var supportModules = map[string]struct{}{
+	"API Keys":      {},
+	"Bills":         {},
+	"Dashboard":     {},
+	"Logs":          {},
+	"Notifications": {},
+	"Prepaid Cards": {},
+	"Usage":         {},
+	"Users":         {},
+}
+
+// Templates returns the built-in role templates derived from the current definitions.
+func Templates() []Template {
+	return []Template{
+		{
+			Name:        "read-only",
+			Description: "View every admin page without changing anything.",
+			Permissions: collect(func(def Definition) bool { return def.Method == http.MethodGet }),
+		},
+		{
+			Name:        "billing-admin",
+			Description: "Manage plans, bills, prepaid cards, invoices and billing rules.",
+			Permissions: collect(func(def Definition) bool {
+				if _, ok := billingModules[def.Module]; ok {
+					return true
+				}
+				return def.Method == http.MethodGet && (def.Module == "Users" || def.Module == "Dashboard")
+			}),
+		},
+		{
+			Name:        "support",
+			Description: "Look up users, keys, usage and logs and edit user accounts.",
+			Permissions: collect(func(def Definition) bool {
+				if _, ok := supportModules[def.Module]; !ok {
+					return false
+				}
+				if def.Method == http.MethodGet {
+					return true
+				}
+				return def.Module == "Users" && def.Method != http.MethodDelete
+			}),
+		},
+	}
+}
+
+// TemplateByName returns the named template.
+func TemplateByName(name string) (Template, bool) {
+	for _, tpl := range Templates() {
+		if tpl.Name == name {
+			return tpl, true
+		}
+	}
+	return Template{}, false
+}
+
+// collect returns the normalized keys of route definitions matching keep, plus the category
+// permissions those routes require.
+func collect(keep func(Definition) bool) []string {
+	out := make([]string, 0, len(definitions))
+	for _, def := range definitions {
+		if def.Method == "" {
+			continue
+		}
+		if keep(def) {
+			out = append(out, def.Key)
+		}
+	}
+	return NormalizePermissions(append(out, ImpliedCategories(out)...))
+}
+
+// Effective merges role permissions with an admin's own grants and removes denied keys.
+func Effective(grants []string, roles [][]string, denies []string) []string {
+	merged := append([]string{}, grants...)
+	for _, role := range roles {
+		merged = append(merged, role...)
+	}
+	merged = NormalizePermissions(merged)
+	if len(denies) == 0 {
+		return merged
+	}
+	denied := make(map[string]struct{}, len(denies))
+	for _, deny := range denies {
+		denied[deny] = struct{}{}
+	}
+	out := merged[:0]
+	for _, perm := range merged {
+		if _, ok := denied[perm]; !ok {
+			out = append(out, perm)
+		}
+	}
+	return out
+}
@@ -0,0 +1,60 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesAdminRolePermissions(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{
+		"GET /v0/admin/admin-roles",
+		"GET /v0/admin/admin-roles/templates",
+		"POST /v0/admin/admin-roles",
+		"PUT /v0/admin/admin-roles/:id",
+		"DELETE /v0/admin/admin-roles/:id",
+	}
+	defs := DefinitionMap()
+	for _, key := range keys {
+		if _, ok := defs[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
+
+func TestTemplatesOnlyUseKnownPermissions(t *testing.T) {
+	t.Parallel()
+
+	for _, tpl := range Templates() {
+		if len(tpl.Permissions) == 0 {
+			t.Fatalf("template %q has no permissions", tpl.Name)
+		}
+		if errValidate := ValidatePermissions(tpl.Permissions); errValidate != nil {
+			t.Fatalf("template %q: %v", tpl.Name, errValidate)
+		}
+	}
+	readOnly, ok := TemplateByName("read-only")
+	if !ok {
+		t.Fatal("missing read-only template")
+	}
+	if HasPermission(readOnly.Permissions, "POST /v0/admin/admins") || !HasPermission(readOnly.Permissions, "GET /v0/admin/admins") {
+		t.Fatalf("read-only template should only grant reads: %v", readOnly.Permissions)
+	}
+}
+
+func TestEffectiveMergesRolesAndAppliesDenies(t *testing.T) {
+	t.Parallel()
+
+	got := Effective(
+		[]string{"GET /v0/admin/logs"},
+		[][]string{{"GET /v0/admin/bills", "POST /v0/admin/bills"}, {"GET /v0/admin/bills"}},
+		[]string{"POST /v0/admin/bills"},
+	)
+	want := []string{"GET /v0/admin/bills", "GET /v0/admin/logs"}
+	if len(got) != len(want) {
+		t.Fatalf("Effective() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Effective() = %v, want %v", got, want)
+		}
+	}
+}
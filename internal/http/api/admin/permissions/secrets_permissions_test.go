@@ -0,0 +1,18 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesSecretsPermission(t *testing.T) {
+	t.Parallel()
+
+	def, ok := DefinitionMap()[PermissionSecrets]
+	if !ok {
+		t.Fatalf("DefinitionMap() missing permission key %q", PermissionSecrets)
+	}
+	if def.Method != "" || def.Path != "" {
+		t.Fatalf("expected secrets permission to be route-free, got %+v", def)
+	}
+	if errValidate := ValidatePermissions([]string{PermissionSecrets}); errValidate != nil {
+		t.Fatalf("ValidatePermissions(secrets) = %v", errValidate)
+	}
+}
@@ -0,0 +1,18 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesSecurityConfigPermissions(t *testing.T) {
+	t.Parallel()
+
+	for _, key := range []string{
+		"GET /v0/admin/security/jwt",
+		"POST /v0/admin/security/jwt/rotate",
+		"GET /v0/admin/security/webauthn",
+		"PUT /v0/admin/security/webauthn",
+	} {
+		if _, ok := DefinitionMap()[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
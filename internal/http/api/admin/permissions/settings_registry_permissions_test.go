@@ -0,0 +1,17 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesSettingsRegistryPermissions(t *testing.T) {
+	t.Parallel()
+
+	for _, key := range []string{
+		"PUT /v0/admin/settings",
+		"GET /v0/admin/settings/registry",
+		"GET /v0/admin/settings/history",
+	} {
+		if _, ok := DefinitionMap()[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesSLOPermissions(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{
+		"GET /v0/admin/slos",
+		"POST /v0/admin/slos",
+		"GET /v0/admin/slos/status",
+		"GET /v0/admin/slos/:id/status",
+		"PUT /v0/admin/slos/:id",
+		"DELETE /v0/admin/slos/:id",
+	}
+	defs := DefinitionMap()
+	for _, key := range keys {
+		if _, ok := defs[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
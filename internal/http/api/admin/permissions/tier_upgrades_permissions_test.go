@@ -0,0 +1,28 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesTierUpgradePermissions(t *testing.T) {
+	t.Parallel()
+
+	definitionMap := DefinitionMap()
+	requiredKeys := []string{
+		"POST /v0/admin/tier-upgrade-rules",
+		"GET /v0/admin/tier-upgrade-rules",
+		"PUT /v0/admin/tier-upgrade-rules/:id",
+		"DELETE /v0/admin/tier-upgrade-rules/:id",
+		"GET /v0/admin/tier-upgrades",
+		"POST /v0/admin/tier-upgrades/:id/approve",
+		"POST /v0/admin/tier-upgrades/:id/reject",
+	}
+
+	for _, key := range requiredKeys {
+		key := key
+		t.Run(key, func(t *testing.T) {
+			t.Parallel()
+			if _, ok := definitionMap[key]; !ok {
+				t.Fatalf("DefinitionMap() missing permission key %q", key)
+			}
+		})
+	}
+}
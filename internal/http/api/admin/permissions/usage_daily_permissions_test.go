@@ -0,0 +1,11 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesUsageDailyPermission(t *testing.T) {
+	t.Parallel()
+
+	if _, ok := DefinitionMap()["GET /v0/admin/usage/daily"]; !ok {
+		t.Fatalf("DefinitionMap() missing permission key %q", "GET /v0/admin/usage/daily")
+	}
+}
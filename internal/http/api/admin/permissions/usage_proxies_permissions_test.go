@@ -0,0 +1,11 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesUsageProxiesPermission(t *testing.T) {
+	t.Parallel()
+
+	if _, ok := DefinitionMap()["GET /v0/admin/usage/proxies"]; !ok {
+		t.Fatalf("DefinitionMap() missing permission key %q", "GET /v0/admin/usage/proxies")
+	}
+}
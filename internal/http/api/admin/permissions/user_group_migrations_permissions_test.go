@@ -0,0 +1,24 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesUserGroupMigrationPermissions(t *testing.T) {
+	t.Parallel()
+
+	definitionMap := DefinitionMap()
+	requiredKeys := []string{
+		"POST /v0/admin/users/:id/group-migrations",
+		"GET /v0/admin/users/:id/group-migrations",
+		"POST /v0/admin/user-group-migrations/:id/cancel",
+	}
+
+	for _, key := range requiredKeys {
+		key := key
+		t.Run(key, func(t *testing.T) {
+			t.Parallel()
+			if _, ok := definitionMap[key]; !ok {
+				t.Fatalf("DefinitionMap() missing permission key %q", key)
+			}
+		})
+	}
+}
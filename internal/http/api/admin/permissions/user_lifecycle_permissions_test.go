@@ -0,0 +1,16 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesUserLifecyclePermissions(t *testing.T) {
+	t.Parallel()
+
+	for _, key := range []string{
+		"GET /v0/admin/user-lifecycle/events",
+		"POST /v0/admin/user-lifecycle/run",
+	} {
+		if _, ok := DefinitionMap()[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesWebhookTemplatePermissions(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{
+		"POST /v0/admin/webhook-templates/validate",
+		"POST /v0/admin/webhook-templates/test-fire",
+	}
+	defs := DefinitionMap()
+	for _, key := range keys {
+		if _, ok := defs[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package permissions
+
+import "testing"
+
+func TestDefinitionMapIncludesWebhookPermissions(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{
+		"GET /v0/admin/webhooks",
+		"POST /v0/admin/webhooks",
+		"PUT /v0/admin/webhooks/:id",
+		"DELETE /v0/admin/webhooks/:id",
+		"GET /v0/admin/webhooks/:id/deliveries",
+		"POST /v0/admin/webhooks/:id/test",
+		"POST /v0/admin/webhook-deliveries/:id/retry",
+	}
+	defs := DefinitionMap()
+	for _, key := range keys {
+		if _, ok := defs[key]; !ok {
+			t.Fatalf("DefinitionMap() missing permission key %q", key)
+		}
+	}
+}
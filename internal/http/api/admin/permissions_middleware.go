@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/http/api/admin/handlers"
 	permissions "github.com/router-for-me/CLIProxyAPIBusiness/internal/http/api/admin/permissions"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"gorm.io/gorm"
@@ -46,22 +47,33 @@ func adminPermissionMiddleware(db *gorm.DB) gin.HandlerFunc {
 			}
 
 			var admin models.Admin
-			if errFind := db.WithContext(c.Request.Context()).Select("id", "permissions", "is_super_admin").First(&admin, adminID).Error; errFind != nil {
+			if errFind := db.WithContext(c.Request.Context()).Select("id", "permissions", "denied_permissions", "is_super_admin").First(&admin, adminID).Error; errFind != nil {
 				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "admin not found"})
 				return
 			}
-			adminPermissions = permissions.ParsePermissions(admin.Permissions)
+			effective, errEffective := handlers.EffectiveAdminPermissions(c.Request.Context(), db, admin)
+			if errEffective != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "load permissions failed"})
+				return
+			}
+			adminPermissions = effective
 			adminIsSuperAdmin = admin.IsSuperAdmin
 			c.Set("adminPermissions", adminPermissions)
 			c.Set("adminIsSuperAdmin", adminIsSuperAdmin)
 		}
 
+		// Organization admins only reach routes whose handlers scope rows to their organization.
+		if c.GetBool("adminOrganizationAdmin") && !permissions.OrganizationAllowed(key) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+			return
+		}
+
 		if adminIsSuperAdmin {
 			c.Next()
 			return
 		}
 
-		if !permissions.HasPermission(adminPermissions, key) {
+		if !permissions.Allowed(adminPermissions, key) {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "permission denied"})
 			return
 		}
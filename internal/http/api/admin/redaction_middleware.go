@@ -0,0 +1,245 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	permissions "github.com/router-for-me/CLIProxyAPIBusiness/internal/http/api/admin/permissions"
+)
+
+// redactedValue replaces secrets that cannot be partially shown.
+const redactedValue = "********"
+
+// secretFields are masked wherever they appear in a response.
+var secretFields = map[string]struct{}{
+	"api_key":       {},
+	"access_token":  {},
+	"refresh_token": {},
+	"id_token":      {},
+	"password":      {},
+	"secret":        {},
+	"client_secret": {},
+	"bind_password": {},
+	"cookie":        {},
+}
+
+// proxyFields hold proxy URLs whose credentials are masked.
+var proxyFields = map[string]struct{}{
+	"proxy_url": {},
+	"proxy":     {},
+}
+
+// opaqueFields hold credential documents whose string leaves are masked.
+var opaqueFields = map[string]struct{}{
+	"content": {},
+	"headers": {},
+}
+
+// opaqueKeepFields stay readable inside opaque documents.
+var opaqueKeepFields = map[string]struct{}{
+	"type":  {},
+	"email": {},
+}
+
+// routeSecretFields adds fields that only hold secrets under a route prefix.
+var routeSecretFields = map[string][]string{
+	"/v0/admin/api-keys": {"key"},
+}
+
+// adminRedactionMiddleware masks secrets in JSON responses for admins without the secrets permission.
+func adminRedactionMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if canViewSecrets(c) {
+			c.Next()
+			return
+		}
+
+		writer := &redactingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if !writer.buffering {
+			return
+		}
+		body := writer.buf.Bytes()
+		if redacted, ok := redactJSON(body, extraSecretFields(c.FullPath())); ok {
+			body = redacted
+		}
+		c.Writer.Header().Del("Content-Length")
+		_, _ = c.Writer.Write(body)
+	}
+}
+
+// canViewSecrets reports whether the current admin may read unmasked secrets.
+func canViewSecrets(c *gin.Context) bool {
+	if isSuperAdmin, ok := readAdminIsSuperAdminFromContext(c); ok && isSuperAdmin {
+		return true
+	}
+	adminPermissions, ok := readAdminPermissionsFromContext(c)
+	return ok && permissions.HasPermission(adminPermissions, permissions.PermissionSecrets)
+}
+
+// extraSecretFields returns the route-scoped secret fields for a path.
+func extraSecretFields(path string) map[string]struct{} {
+	out := make(map[string]struct{})
+	for prefix, fields := range routeSecretFields {
+		if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+			continue
+		}
+		for _, field := range fields {
+			out[field] = struct{}{}
+		}
+	}
+	return out
+}
+
+// redactingWriter buffers JSON bodies so they can be masked; other bodies such as
+// event streams pass through untouched.
+type redactingWriter struct {
+	gin.ResponseWriter
+	buf       bytes.Buffer
+	decided   bool
+	buffering bool
+}
+
+// Write buffers JSON output and forwards everything else.
+func (w *redactingWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.decided = true
+		w.buffering = strings.Contains(strings.ToLower(w.Header().Get("Content-Type")), "json")
+	}
+	if !w.buffering {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.buf.Write(data)
+}
+
+// WriteString buffers JSON output and forwards everything else.
+func (w *redactingWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// redactJSON masks secrets in a JSON document; ok is false when the body is not valid JSON.
+func redactJSON(body []byte, extra map[string]struct{}) ([]byte, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var doc any
+	if errDecode := decoder.Decode(&doc); errDecode != nil {
+		return nil, false
+	}
+	out, errMarshal := json.Marshal(redactValue(doc, extra))
+	if errMarshal != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+// redactValue walks a decoded JSON value and masks secret fields.
+func redactValue(value any, extra map[string]struct{}) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		for key, field := range typed {
+			name := strings.ToLower(key)
+			if _, ok := secretFields[name]; ok {
+				typed[key] = maskSecret(field)
+				continue
+			}
+			if _, ok := extra[name]; ok {
+				typed[key] = maskSecret(field)
+				continue
+			}
+			if _, ok := proxyFields[name]; ok {
+				typed[key] = maskProxy(field)
+				continue
+			}
+			if _, ok := opaqueFields[name]; ok {
+				typed[key] = maskOpaque(field)
+				continue
+			}
+			typed[key] = redactValue(field, extra)
+		}
+		return typed
+	case []any:
+		for i, item := range typed {
+			typed[i] = redactValue(item, extra)
+		}
+		return typed
+	default:
+		return value
+	}
+}
+
+// maskSecret keeps the first and last four characters of long strings.
+func maskSecret(value any) any {
+	switch typed := value.(type) {
+	case nil:
+		return nil
+	case string:
+		if typed == "" {
+			return typed
+		}
+		runes := []rune(typed)
+		if len(runes) <= 12 {
+			return redactedValue
+		}
+		return string(runes[:4]) + "..." + string(runes[len(runes)-4:])
+	case []any:
+		for i, item := range typed {
+			typed[i] = maskSecret(item)
+		}
+		return typed
+	default:
+		return redactedValue
+	}
+}
+
+// maskProxy hides the password of a proxy URL while keeping scheme, user, and host.
+func maskProxy(value any) any {
+	raw, ok := value.(string)
+	if !ok || raw == "" {
+		return value
+	}
+	parsed, errParse := url.Parse(raw)
+	if errParse != nil || parsed.Host == "" {
+		return redactedValue
+	}
+	if parsed.User == nil {
+		return raw
+	}
+	if _, hasPassword := parsed.User.Password(); !hasPassword {
+		return raw
+	}
+	username := url.User(parsed.User.Username()).String()
+	parsed.User = nil
+	return strings.Replace(parsed.String(), "://", "://"+username+":"+redactedValue+"@", 1)
+}
+
+// maskOpaque masks every string leaf of a credential document except descriptive fields.
+func maskOpaque(value any) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		for key, field := range typed {
+			if _, keep := opaqueKeepFields[strings.ToLower(key)]; keep {
+				continue
+			}
+			typed[key] = maskOpaque(field)
+		}
+		return typed
+	case []any:
+		for i, item := range typed {
+			typed[i] = maskOpaque(item)
+		}
+		return typed
+	case string:
+		if typed == "" {
+			return typed
+		}
+		return redactedValue
+	default:
+		return value
+	}
+}
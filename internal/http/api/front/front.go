@@ -19,16 +19,29 @@ func RegisterFrontRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, mo
 		return
 	}
 
-	front := r.Group("/v0/front")
+	r.GET("/v0/branding", handlers.GetBranding)
+
+	badgeHandler := handlers.NewBadgeHandler(db)
+	r.GET("/v0/badges/:token", badgeHandler.Get)
 
 	authHandler := handlers.NewAuthHandler(db, jwtCfg)
+	r.GET("/v0/auth/oauth/:provider", authHandler.SocialLogin)
+	r.GET("/v0/auth/oauth/:provider/callback", authHandler.SocialCallback)
+	r.POST("/v0/auth/register", authHandler.Register)
+
+	front := r.Group("/v0/front")
+
 	front.POST("/register", authHandler.Register)
 	front.POST("/login", authHandler.Login)
 	front.POST("/login/prepare", authHandler.LoginPrepare)
 	front.POST("/login/totp", authHandler.LoginTOTP)
+	front.POST("/login/recovery-code", authHandler.LoginRecoveryCode)
 	front.POST("/login/passkey/options", authHandler.LoginPasskeyOptions)
 	front.POST("/login/passkey/verify", authHandler.LoginPasskeyVerify)
 	front.POST("/reset-password", authHandler.ResetPassword)
+	front.POST("/reset-password/request", authHandler.RequestPasswordReset)
+	front.POST("/reset-password/confirm", authHandler.ConfirmPasswordReset)
+	front.POST("/verify-email", authHandler.VerifyEmail)
 	front.GET("/config", handlers.GetPublicConfig)
 
 	authed := front.Group("")
@@ -36,7 +49,10 @@ func RegisterFrontRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, mo
 
 	profileHandler := handlers.NewProfileHandler(db)
 	authed.GET("/profile", profileHandler.Get)
-	authed.PUT("/profile/password", profileHandler.ChangePassword)
+	authed.POST("/profile/verify-email", authHandler.ResendVerification)
+	authed.GET("/profile/identities", authHandler.ListIdentities)
+	authed.POST("/profile/identities/:provider", authHandler.LinkIdentity)
+	authed.DELETE("/profile/identities/:provider", authHandler.UnlinkIdentity)
 
 	webAuthn, errWebAuthn := security.NewWebAuthn()
 	if errWebAuthn != nil {
@@ -47,6 +63,7 @@ func RegisterFrontRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, mo
 	authed.POST("/mfa/totp/prepare", mfaHandler.PrepareTOTP)
 	authed.POST("/mfa/totp/confirm", mfaHandler.ConfirmTOTP)
 	authed.POST("/mfa/totp/disable", mfaHandler.DisableTOTP)
+	authed.POST("/mfa/totp/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
 	authed.POST("/mfa/passkey/options", mfaHandler.BeginPasskeyRegistration)
 	authed.POST("/mfa/passkey/verify", mfaHandler.FinishPasskeyRegistration)
 	authed.POST("/mfa/passkey/disable", mfaHandler.DisablePasskey)
@@ -56,13 +73,36 @@ func RegisterFrontRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, mo
 	authed.POST("/prepaid-card/redeem", prepaidHandler.Redeem)
 	authed.GET("/prepaid-cards", prepaidHandler.List)
 
+	balanceHandler := handlers.NewBalanceFrontHandler(db)
+	authed.GET("/balance", balanceHandler.Get)
+
+	// /v0/user serves account summaries and credential changes with the same user token as /v0/front.
+	userAPI := r.Group("/v0/user")
+	userAPI.Use(userAuthMiddleware(db, jwtCfg))
+	userAPI.GET("/balance", balanceHandler.Overview)
+
+	passwordHandler := handlers.NewPasswordHandler(db, jwtCfg)
+	userAPI.POST("/password", passwordHandler.Change)
+	userAPI.POST("/password/passkey/options", passwordHandler.PasskeyOptions)
+
 	planHandler := handlers.NewPlanFrontHandler(db)
 	authed.GET("/plans", planHandler.List)
 
+	tierUpgradeHandler := handlers.NewTierUpgradeFrontHandler(db)
+	authed.GET("/tier-upgrades", tierUpgradeHandler.List)
+	authed.POST("/tier-upgrades/:id/accept", tierUpgradeHandler.Accept)
+	authed.POST("/tier-upgrades/:id/decline", tierUpgradeHandler.Decline)
+
 	billHandler := handlers.NewBillFrontHandler(db)
 	authed.POST("/bills", billHandler.Create)
 	authed.GET("/bills", billHandler.List)
 
+	coopHandler := handlers.NewCoopFrontHandler(db)
+	authed.GET("/coop", coopHandler.Status)
+	authed.GET("/coop/contributions", coopHandler.List)
+	authed.POST("/coop/contributions", coopHandler.Contribute)
+	authed.DELETE("/coop/contributions/:id", coopHandler.Revoke)
+
 	apiKeyHandler := handlers.NewAPIKeyHandler(db)
 	authed.GET("/api-keys", apiKeyHandler.List)
 	authed.GET("/api-keys/stats", apiKeyHandler.Stats)
@@ -72,6 +112,25 @@ func RegisterFrontRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, mo
 	authed.DELETE("/api-keys/:id", apiKeyHandler.Delete)
 	authed.POST("/api-keys/:id/renew", apiKeyHandler.Renew)
 	authed.POST("/api-keys/:id/regenerate", apiKeyHandler.Regenerate)
+	userAPI.GET("/api-keys/:id/usage", apiKeyHandler.Usage)
+
+	teamHandler := handlers.NewTeamFrontHandler(db)
+	authed.POST("/teams", teamHandler.Create)
+	authed.GET("/teams", teamHandler.List)
+	authed.GET("/teams/:id", teamHandler.Get)
+	authed.DELETE("/teams/:id", teamHandler.Delete)
+	authed.POST("/teams/:id/members", teamHandler.Invite)
+	authed.POST("/teams/:id/accept", teamHandler.Accept)
+	authed.DELETE("/teams/:id/members/:user_id", teamHandler.RemoveMember)
+	authed.GET("/teams/:id/api-keys", teamHandler.ListAPIKeys)
+	authed.POST("/teams/:id/api-keys", teamHandler.CreateAPIKey)
+	authed.DELETE("/teams/:id/api-keys/:key_id", teamHandler.RevokeAPIKey)
+	authed.GET("/teams/:id/balance", teamHandler.Balance)
+	authed.GET("/teams/:id/usage", teamHandler.Usage)
+
+	authed.GET("/badges", badgeHandler.List)
+	authed.POST("/badges", badgeHandler.Create)
+	authed.DELETE("/badges/:id", badgeHandler.Delete)
 
 	usageHandler := handlers.NewUsageHandler(db)
 	authed.GET("/usage/stats", usageHandler.Stats)
@@ -85,6 +144,7 @@ func RegisterFrontRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, mo
 
 	modelPricingHandler := handlers.NewModelPricingHandler(db, modelStore)
 	authed.GET("/models/pricing", modelPricingHandler.List)
+	authed.GET("/models/catalog", modelPricingHandler.Catalog)
 
 	logsHandler := handlers.NewLogsHandler(db)
 	authed.GET("/logs", logsHandler.List)
@@ -120,6 +180,11 @@ func userAuthMiddleware(db *gorm.DB, jwtCfg config.JWTConfig) gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			return
 		}
+		// Enrollment-only tokens issued under MFA_POLICY may reach nothing but the MFA endpoints.
+		if claims.Scope == security.TokenScopeMFAEnrollment && !strings.HasPrefix(c.FullPath(), "/v0/front/mfa/") {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "mfa enrollment required", "mfa_enrollment_required": true})
+			return
+		}
 
 		var user models.User
 		if errFind := db.WithContext(c.Request.Context()).First(&user, claims.UserID).Error; errFind != nil {
@@ -130,6 +195,10 @@ func userAuthMiddleware(db *gorm.DB, jwtCfg config.JWTConfig) gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "user disabled"})
 			return
 		}
+		if handlers.SessionRevoked(user, claims) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session revoked"})
+			return
+		}
 
 		c.Set("userID", user.ID)
 		c.Next()
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/usagerollup"
+	"gorm.io/gorm"
+)
+
+const (
+	// apiKeyUsageDefaultDays is the range returned when no from date is given.
+	apiKeyUsageDefaultDays = 30
+	// apiKeyUsageMaxDays bounds the requested range.
+	apiKeyUsageMaxDays = 92
+)
+
+// Usage returns the per-day and per-model usage of one of the user's API keys. Optional
+// from/to query parameters accept YYYY-MM-DD local dates (inclusive); format=csv downloads
+// the day and model buckets instead of JSON.
+func (h *APIKeyHandler) Usage(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var key models.APIKey
+	if errFind := h.db.WithContext(ctx).Select("id", "name").
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&key).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query api key failed"})
+		return
+	}
+
+	to := usagerollup.DayStart(time.Now())
+	from := to.AddDate(0, 0, -apiKeyUsageDefaultDays+1)
+	if raw := strings.TrimSpace(c.Query("from")); raw != "" {
+		parsed, errDate := time.ParseInLocation(time.DateOnly, raw, time.Local)
+		if errDate != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+			return
+		}
+		from = parsed
+	}
+	if raw := strings.TrimSpace(c.Query("to")); raw != "" {
+		parsed, errDate := time.ParseInLocation(time.DateOnly, raw, time.Local)
+		if errDate != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+			return
+		}
+		to = parsed
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must not be before from"})
+		return
+	}
+	if to.After(from.AddDate(0, 0, apiKeyUsageMaxDays-1)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("range must not exceed %d days", apiKeyUsageMaxDays)})
+		return
+	}
+
+	breakdown, errBreakdown := usagerollup.APIKeyBreakdown(ctx, h.db, key.ID, from, to)
+	if errBreakdown != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query usage failed"})
+		return
+	}
+
+	if strings.EqualFold(strings.TrimSpace(c.Query("format")), "csv") {
+		filename := fmt.Sprintf("api-key-%d-usage-%s-%s.csv", key.ID, breakdown.From, breakdown.To)
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.Status(http.StatusOK)
+		c.Writer.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w := csv.NewWriter(c.Writer)
+		_ = w.Write([]string{"day", "provider", "model", "requests", "failed_requests", "input_tokens", "output_tokens", "cached_tokens", "total_tokens", "cost"})
+		for _, row := range breakdown.Rows {
+			_ = w.Write([]string{
+				row.Day,
+				row.Provider,
+				row.Model,
+				strconv.FormatInt(row.Requests, 10),
+				strconv.FormatInt(row.FailedRequests, 10),
+				strconv.FormatInt(row.InputTokens, 10),
+				strconv.FormatInt(row.OutputTokens, 10),
+				strconv.FormatInt(row.CachedTokens, 10),
+				strconv.FormatInt(row.TotalTokens, 10),
+				strconv.FormatFloat(float64(row.CostMicros)/1_000_000, 'f', 6, 64),
+			})
+		}
+		w.Flush()
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"api_key_id":   key.ID,
+		"api_key_name": key.Name,
+		"from":         breakdown.From,
+		"to":           breakdown.To,
+		"total":        breakdown.Total,
+		"days":         breakdown.Days,
+		"models":       breakdown.Models,
+	})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -8,8 +9,12 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/access"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/ipallow"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -101,18 +106,27 @@ func (h *APIKeyHandler) serializeAPIKey(row *models.APIKey) gin.H {
 	if len(row.APIKey) >= 8 {
 		prefix = row.APIKey[:8] + "········" + row.APIKey[len(row.APIKey)-4:]
 	}
+	scope := access.ScopeOf(row)
+	var maxTokens *int
+	if scope.MaxTokens > 0 {
+		maxTokens = &scope.MaxTokens
+	}
 	return gin.H{
-		"id":           row.ID,
-		"name":         row.Name,
-		"key":          row.APIKey,
-		"key_prefix":   prefix,
-		"active":       row.Active,
-		"status":       row.Status(),
-		"expires_at":   row.ExpiresAt,
-		"revoked_at":   row.RevokedAt,
-		"last_used_at": row.LastUsedAt,
-		"created_at":   row.CreatedAt,
-		"updated_at":   row.UpdatedAt,
+		"id":                     row.ID,
+		"name":                   row.Name,
+		"key":                    row.APIKey,
+		"key_prefix":             prefix,
+		"active":                 row.Active,
+		"status":                 row.Status(),
+		"allowed_models":         nonNilScopeList(scope.Models),
+		"allowed_providers":      nonNilScopeList(scope.Providers),
+		"max_tokens_per_request": maxTokens,
+		"allowed_ips":            ipallow.FromJSON(row.AllowedIPs).Entries(),
+		"expires_at":             row.ExpiresAt,
+		"revoked_at":             row.RevokedAt,
+		"last_used_at":           row.LastUsedAt,
+		"created_at":             row.CreatedAt,
+		"updated_at":             row.UpdatedAt,
 	}
 }
 
@@ -192,10 +206,49 @@ func formatTokens(tokens int64) string {
 	return fmt.Sprintf("%d", tokens)
 }
 
+// nonNilScopeList renders an unrestricted scope list as an empty JSON array.
+func nonNilScopeList(items []string) []string {
+	if items == nil {
+		return []string{}
+	}
+	return items
+}
+
+// marshalScopeList normalizes a scope list for storage.
+func marshalScopeList(items []string) (datatypes.JSON, error) {
+	raw, errMarshal := json.Marshal(access.NormalizeScopeList(items))
+	if errMarshal != nil {
+		return nil, errMarshal
+	}
+	return datatypes.JSON(raw), nil
+}
+
+// resolveExpiry picks the key expiry from an explicit date or a day count; explicit dates
+// must lie in the future.
+func resolveExpiry(now time.Time, expiresAt *time.Time, expiresInDays *int) (*time.Time, bool) {
+	if expiresAt != nil {
+		if !expiresAt.After(now) {
+			return nil, false
+		}
+		exp := expiresAt.UTC()
+		return &exp, true
+	}
+	if expiresInDays != nil && *expiresInDays > 0 {
+		exp := now.AddDate(0, 0, *expiresInDays)
+		return &exp, true
+	}
+	return nil, true
+}
+
 // createAPIKeyRequest defines the request body for creating keys.
 type createAPIKeyRequest struct {
-	Name      string `json:"name"`
-	ExpiresIn *int   `json:"expires_in_days"`
+	Name                string     `json:"name"`
+	ExpiresIn           *int       `json:"expires_in_days"`
+	ExpiresAt           *time.Time `json:"expires_at"`
+	AllowedModels       []string   `json:"allowed_models"`
+	AllowedProviders    []string   `json:"allowed_providers"`
+	MaxTokensPerRequest *int       `json:"max_tokens_per_request"`
+	AllowedIPs          []string   `json:"allowed_ips"`
 }
 
 // Create creates a new API key for the user.
@@ -216,6 +269,17 @@ func (h *APIKeyHandler) Create(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing name"})
 		return
 	}
+	if emailVerificationRequired() {
+		var user models.User
+		if errFind := h.db.WithContext(c.Request.Context()).Select("id", "email_verified_at").First(&user, userID).Error; errFind != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "query user failed"})
+			return
+		}
+		if user.EmailVerifiedAt == nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "email verification required"})
+			return
+		}
+	}
 
 	token, errGenerate := security.GenerateAPIKey()
 	if errGenerate != nil {
@@ -224,37 +288,66 @@ func (h *APIKeyHandler) Create(c *gin.Context) {
 	}
 
 	now := time.Now().UTC()
-	var expiresAt *time.Time
-	if body.ExpiresIn != nil && *body.ExpiresIn > 0 {
-		exp := now.AddDate(0, 0, *body.ExpiresIn)
-		expiresAt = &exp
+	expiresAt, okExpiry := resolveExpiry(now, body.ExpiresAt, body.ExpiresIn)
+	if !okExpiry {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+	if body.MaxTokensPerRequest != nil && *body.MaxTokensPerRequest < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid max_tokens_per_request"})
+		return
+	}
+	var maxTokens *int
+	if body.MaxTokensPerRequest != nil && *body.MaxTokensPerRequest > 0 {
+		maxTokens = body.MaxTokensPerRequest
+	}
+	allowedModels, errModels := marshalScopeList(body.AllowedModels)
+	allowedProviders, errProviders := marshalScopeList(body.AllowedProviders)
+	if errModels != nil || errProviders != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid scope"})
+		return
+	}
+	allowedIPs, errAllowedIPs := ipallow.Encode(body.AllowedIPs)
+	if errAllowedIPs != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errAllowedIPs.Error()})
+		return
 	}
 
 	row := models.APIKey{
-		UserID:    &userID,
-		Name:      name,
-		APIKey:    token,
-		IsAdmin:   false,
-		Active:    true,
-		ExpiresAt: expiresAt,
-		CreatedAt: now,
-		UpdatedAt: now,
+		UserID:              &userID,
+		Name:                name,
+		APIKey:              token,
+		IsAdmin:             false,
+		Active:              true,
+		ExpiresAt:           expiresAt,
+		AllowedModels:       allowedModels,
+		AllowedProviders:    allowedProviders,
+		MaxTokensPerRequest: maxTokens,
+		AllowedIPs:          datatypes.JSON(allowedIPs),
+		CreatedAt:           now,
+		UpdatedAt:           now,
 	}
 	if errCreate := h.db.WithContext(c.Request.Context()).Create(&row).Error; errCreate != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "create api key failed"})
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{
-		"id":    row.ID,
-		"name":  row.Name,
-		"token": token,
+		"id":         row.ID,
+		"name":       row.Name,
+		"token":      token,
+		"expires_at": row.ExpiresAt,
 	})
 }
 
 // updateAPIKeyRequest defines the request body for updating keys.
 type updateAPIKeyRequest struct {
-	Name      *string `json:"name"`
-	ExpiresIn *int    `json:"expires_in_days"`
+	Name                *string    `json:"name"`
+	ExpiresIn           *int       `json:"expires_in_days"`
+	ExpiresAt           *time.Time `json:"expires_at"`
+	AllowedModels       *[]string  `json:"allowed_models"`
+	AllowedProviders    *[]string  `json:"allowed_providers"`
+	MaxTokensPerRequest *int       `json:"max_tokens_per_request"` // 0 removes the cap.
+	AllowedIPs          *[]string  `json:"allowed_ips"`            // Empty list removes the restriction.
 }
 
 // Update updates an API key's metadata or expiry.
@@ -281,7 +374,14 @@ func (h *APIKeyHandler) Update(c *gin.Context) {
 	if body.Name != nil {
 		updates["name"] = strings.TrimSpace(*body.Name)
 	}
-	if body.ExpiresIn != nil {
+	if body.ExpiresAt != nil {
+		if !body.ExpiresAt.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+			return
+		}
+		exp := body.ExpiresAt.UTC()
+		updates["expires_at"] = &exp
+	} else if body.ExpiresIn != nil {
 		if *body.ExpiresIn <= 0 {
 			updates["expires_at"] = nil
 		} else {
@@ -289,6 +389,41 @@ func (h *APIKeyHandler) Update(c *gin.Context) {
 			updates["expires_at"] = &exp
 		}
 	}
+	if body.AllowedModels != nil {
+		allowedModels, errModels := marshalScopeList(*body.AllowedModels)
+		if errModels != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid allowed_models"})
+			return
+		}
+		updates["allowed_models"] = allowedModels
+	}
+	if body.AllowedProviders != nil {
+		allowedProviders, errProviders := marshalScopeList(*body.AllowedProviders)
+		if errProviders != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid allowed_providers"})
+			return
+		}
+		updates["allowed_providers"] = allowedProviders
+	}
+	if body.AllowedIPs != nil {
+		allowedIPs, errAllowedIPs := ipallow.Encode(*body.AllowedIPs)
+		if errAllowedIPs != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errAllowedIPs.Error()})
+			return
+		}
+		updates["allowed_ips"] = datatypes.JSON(allowedIPs)
+	}
+	if body.MaxTokensPerRequest != nil {
+		switch {
+		case *body.MaxTokensPerRequest < 0:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid max_tokens_per_request"})
+			return
+		case *body.MaxTokensPerRequest == 0:
+			updates["max_tokens_per_request"] = nil
+		default:
+			updates["max_tokens_per_request"] = *body.MaxTokensPerRequest
+		}
+	}
 
 	res := h.db.WithContext(c.Request.Context()).Model(&models.APIKey{}).
 		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
@@ -334,6 +469,7 @@ func (h *APIKeyHandler) Revoke(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
+	events.PublishAPIKeyDisabled(c.Request.Context(), id, "revoked by user")
 	c.Status(http.StatusNoContent)
 }
 
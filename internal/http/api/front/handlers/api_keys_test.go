@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	dbpkg "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+)
+
+func TestAPIKeyCreateRequiresVerifiedEmailWhenEnabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	conn, errOpen := dbpkg.Open(":memory:")
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := dbpkg.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	t.Cleanup(func() { internalsettings.StoreDBConfig(time.Now(), nil) })
+	requireVerification := func(enabled bool) {
+		internalsettings.StoreDBConfig(time.Now(), map[string]json.RawMessage{
+			internalsettings.RequireEmailVerificationKey: json.RawMessage(strconv.FormatBool(enabled)),
+		})
+	}
+
+	verifiedAt := time.Now().UTC()
+	unverified := models.User{Username: "unverified", Email: "unverified@example.com", Password: "x"}
+	verified := models.User{Username: "verified", Email: "verified@example.com", Password: "x", EmailVerifiedAt: &verifiedAt}
+	for _, user := range []*models.User{&unverified, &verified} {
+		if errCreate := conn.Create(user).Error; errCreate != nil {
+			t.Fatalf("create user: %v", errCreate)
+		}
+	}
+
+	h := NewAPIKeyHandler(conn)
+	create := func(userID uint64) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(map[string]any{"name": "key"})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("userID", userID)
+		c.Request = httptest.NewRequest(http.MethodPost, "/v0/front/api-keys", bytes.NewReader(payload))
+		c.Request.Header.Set("Content-Type", "application/json")
+		h.Create(c)
+		return w
+	}
+	countKeys := func(userID uint64) int64 {
+		var count int64
+		if errCount := conn.Model(&models.APIKey{}).Where("user_id = ?", userID).Count(&count).Error; errCount != nil {
+			t.Fatalf("count api keys: %v", errCount)
+		}
+		return count
+	}
+
+	requireVerification(true)
+	if w := create(unverified.ID); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for an unverified email, got %d body=%s", w.Code, w.Body.String())
+	}
+	if count := countKeys(unverified.ID); count != 0 {
+		t.Fatalf("expected no key for the unverified user, got %d", count)
+	}
+	if w := create(verified.ID); w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 for a verified email, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	requireVerification(false)
+	if w := create(unverified.ID); w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 with verification off, got %d body=%s", w.Code, w.Body.String())
+	}
+	if count := countKeys(unverified.ID); count != 1 {
+		t.Fatalf("expected one key for the unverified user, got %d", count)
+	}
+}
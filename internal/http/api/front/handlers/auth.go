@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"strings"
@@ -8,8 +9,13 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/invitation"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/ldapauth"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/mail"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
+	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
@@ -17,21 +23,23 @@ import (
 type AuthHandler struct {
 	db     *gorm.DB
 	jwtCfg config.JWTConfig
+	mailer *mail.Mailer // Sends verification and password reset links.
 }
 
 // NewAuthHandler constructs an AuthHandler.
 func NewAuthHandler(db *gorm.DB, jwtCfg config.JWTConfig) *AuthHandler {
-	return &AuthHandler{db: db, jwtCfg: jwtCfg}
+	return &AuthHandler{db: db, jwtCfg: jwtCfg, mailer: mail.New()}
 }
 
 // registerRequest defines the request body for user registration.
 type registerRequest struct {
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Username   string `json:"username"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	InviteCode string `json:"invite_code"` // Invitation code; required when registration is invite-only.
 }
 
-// Register creates a new user account.
+// Register creates a new user account, redeeming an invitation code when one is given.
 func (h *AuthHandler) Register(c *gin.Context) {
 	var body registerRequest
 	if errBind := c.ShouldBindJSON(&body); errBind != nil {
@@ -48,12 +56,28 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing password"})
 		return
 	}
+	inviteCode := invitation.NormalizeCode(body.InviteCode)
+	if inviteCode == "" && invitation.InviteOnly() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing invite code"})
+		return
+	}
 
 	var exists models.User
 	if errCheck := h.db.WithContext(c.Request.Context()).Where("username = ?", username).First(&exists).Error; errCheck == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "username already exists"})
 		return
 	}
+	email := strings.TrimSpace(body.Email)
+	if email == "" && emailVerificationRequired() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing email"})
+		return
+	}
+	if email != "" {
+		if errCheck := h.db.WithContext(c.Request.Context()).Where("email = ?", email).First(&exists).Error; errCheck == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "email already exists"})
+			return
+		}
+	}
 
 	hash, errHash := security.HashPassword(password)
 	if errHash != nil {
@@ -64,7 +88,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	now := time.Now().UTC()
 	user := models.User{
 		Username:  username,
-		Email:     strings.TrimSpace(body.Email),
+		Email:     email,
 		Password:  hash,
 		Active:    true,
 		Disabled:  false,
@@ -80,14 +104,43 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "query default user group failed"})
 		return
 	}
-	if errCreate := h.db.WithContext(c.Request.Context()).Create(&user).Error; errCreate != nil {
+	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if inviteCode == "" {
+			return tx.Create(&user).Error
+		}
+		invite, errClaim := invitation.Claim(tx, inviteCode, now)
+		if errClaim != nil {
+			return errClaim
+		}
+		if groups := invitation.UserGroups(invite); groups != nil {
+			user.UserGroupID = groups
+		}
+		if errCreate := tx.Create(&user).Error; errCreate != nil {
+			return errCreate
+		}
+		return invitation.Grant(c.Request.Context(), tx, invite, user.ID, now)
+	})
+	if errTx != nil {
+		if errors.Is(errTx, invitation.ErrInvalidCode) || errors.Is(errTx, invitation.ErrExpired) || errors.Is(errTx, invitation.ErrExhausted) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errTx.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "create user failed"})
 		return
 	}
+	verificationSent := false
+	if user.Email != "" && h.mailer.Enabled() {
+		if errSend := h.mailer.SendVerification(c.Request.Context(), h.db, &user); errSend != nil {
+			log.WithError(errSend).WithField("user_id", user.ID).Warn("send verification email failed")
+		} else {
+			verificationSent = true
+		}
+	}
 	c.JSON(http.StatusCreated, gin.H{
-		"id":       user.ID,
-		"username": user.Username,
-		"email":    user.Email,
+		"id":                user.ID,
+		"username":          user.Username,
+		"email":             user.Email,
+		"verification_sent": verificationSent,
 	})
 }
 
@@ -111,9 +164,18 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	directoryUser, errDirectory := h.directoryLogin(c.Request.Context(), username, body.Password)
+	if errDirectory != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "sync directory user failed"})
+		return
+	}
+
 	var user models.User
-	if errFind := h.db.WithContext(c.Request.Context()).Where("username = ?", username).First(&user).Error; errFind != nil {
+	if directoryUser != nil {
+		user = *directoryUser
+	} else if errFind := h.db.WithContext(c.Request.Context()).Where("username = ?", username).First(&user).Error; errFind != nil {
 		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			events.PublishLoginFailed(c.Request.Context(), "user", username, c.ClientIP(), "unknown username")
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 			return
 		}
@@ -126,7 +188,9 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	if !security.CheckPassword(user.Password, password) {
+	// Directory accounts only sign in through the directory, even if it is unreachable.
+	if directoryUser == nil && (user.LDAPDN != "" || !security.CheckPassword(user.Password, password)) {
+		events.PublishLoginFailed(c.Request.Context(), "user", username, c.ClientIP(), "invalid password")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
 	}
@@ -136,7 +200,36 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	h.respondWithUserToken(c, user)
+	extra, allowed := h.enforceMFAPolicy(c, user)
+	if !allowed {
+		return
+	}
+	h.respondWithUserToken(c, user, extra)
+}
+
+// directoryLogin authenticates the user against LDAP when it is configured and returns the
+// linked local account. It returns nil when the directory does not vouch for the user, in
+// which case the local password applies.
+func (h *AuthHandler) directoryLogin(ctx context.Context, username, password string) (*models.User, error) {
+	cfg := ldapauth.LoadConfig()
+	if !cfg.Ready() {
+		return nil, nil
+	}
+	identity, errAuth := ldapauth.Authenticate(ctx, cfg, username, password)
+	if errAuth != nil {
+		if !errors.Is(errAuth, ldapauth.ErrInvalidCredentials) && !errors.Is(errAuth, ldapauth.ErrUserNotFound) {
+			log.WithError(errAuth).Warn("ldap login failed; falling back to local password")
+		}
+		return nil, nil
+	}
+	user, errSync := ldapauth.SyncUser(ctx, h.db, cfg, username, identity, time.Now().UTC())
+	if errSync != nil {
+		if errors.Is(errSync, ldapauth.ErrLocalAccount) || errors.Is(errSync, ldapauth.ErrProvisionDisabled) {
+			return nil, nil
+		}
+		return nil, errSync
+	}
+	return &user, nil
 }
 
 // resetPasswordRequest defines the request body for password resets.
@@ -146,8 +239,13 @@ type resetPasswordRequest struct {
 	NewPassword string `json:"new_password"`
 }
 
-// ResetPassword updates a user's password after verification.
+// ResetPassword updates a user's password after matching username and email. Once SMTP is
+// configured, resets must go through the emailed link instead.
 func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	if h.mailer.Enabled() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "use the emailed password reset link"})
+		return
+	}
 	var body resetPasswordRequest
 	if errBind := c.ShouldBindJSON(&body); errBind != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
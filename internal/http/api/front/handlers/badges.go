@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/badge"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// BadgeHandler manages the current user's usage badges and serves them publicly.
+type BadgeHandler struct {
+	db      *gorm.DB
+	server  *badge.Server
+	limiter *badge.Limiter
+}
+
+// NewBadgeHandler constructs a BadgeHandler.
+func NewBadgeHandler(db *gorm.DB) *BadgeHandler {
+	return &BadgeHandler{db: db, server: badge.NewServer(db), limiter: badge.NewLimiter(badge.DefaultClientLimit)}
+}
+
+// createBadgeRequest defines the request body for creating a badge.
+type createBadgeRequest struct {
+	Project string `json:"project"` // Usage source to limit the badge to; empty covers all usage.
+	Label   string `json:"label"`   // Left-hand badge text.
+}
+
+// List returns the current user's badges.
+func (h *BadgeHandler) List(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var rows []models.UsageBadge
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list badges failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatBadge(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"badges": out})
+}
+
+// Create issues a new badge token for the current user.
+func (h *BadgeHandler) Create(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var body createBadgeRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	row, errCreate := badge.Create(c.Request.Context(), h.db, userID, body.Project, body.Label)
+	if errCreate != nil {
+		if errors.Is(errCreate, badge.ErrLimitReached) {
+			c.JSON(http.StatusConflict, gin.H{"error": errCreate.Error()})
+			return
+		}
+		log.WithError(errCreate).Warn("create usage badge failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create badge failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, formatBadge(row))
+}
+
+// Delete removes one of the current user's badges; its public URL stops working.
+func (h *BadgeHandler) Delete(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var row models.UsageBadge
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&row).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query badge failed"})
+		return
+	}
+	if errDelete := h.db.WithContext(c.Request.Context()).Delete(&row).Error; errDelete != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	h.server.Forget(row.Token)
+	c.Status(http.StatusNoContent)
+}
+
+// Get serves a badge by token without authentication. The token may end in ".svg" or
+// ".json", or the format can be chosen with ?format=; ?metric= picks requests or uptime
+// for the SVG.
+func (h *BadgeHandler) Get(c *gin.Context) {
+	if allowed, reset := h.limiter.Allow(c.ClientIP()); !allowed {
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(reset).Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+		return
+	}
+	token := strings.TrimSpace(c.Param("token"))
+	format := strings.ToLower(strings.TrimSpace(c.Query("format")))
+	switch {
+	case strings.HasSuffix(token, ".svg"):
+		token, format = strings.TrimSuffix(token, ".svg"), "svg"
+	case strings.HasSuffix(token, ".json"):
+		token, format = strings.TrimSuffix(token, ".json"), "json"
+	}
+
+	stats, errStats := h.server.Stats(c.Request.Context(), token)
+	if errStats != nil {
+		if errors.Is(errStats, badge.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		log.WithError(errStats).Warn("usage badge lookup failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "badge unavailable"})
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=300")
+	if format != "svg" {
+		c.JSON(http.StatusOK, stats)
+		return
+	}
+	metric := badge.MetricRequests
+	if strings.EqualFold(strings.TrimSpace(c.Query("metric")), badge.MetricUptime) {
+		metric = badge.MetricUptime
+	}
+	label, message, color := stats.Text(metric)
+	c.Data(http.StatusOK, "image/svg+xml; charset=utf-8", badge.RenderSVG(label, message, color))
+}
+
+// formatBadge converts a badge row into a response payload.
+func formatBadge(row *models.UsageBadge) gin.H {
+	return gin.H{
+		"id":         row.ID,
+		"token":      row.Token,
+		"project":    row.Project,
+		"label":      row.Label,
+		"path":       "/v0/badges/" + row.Token + ".svg",
+		"created_at": row.CreatedAt,
+	}
+}
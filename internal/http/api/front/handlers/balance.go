@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// BalanceFrontHandler reports the current user's spendable balance.
+type BalanceFrontHandler struct {
+	db *gorm.DB
+}
+
+// NewBalanceFrontHandler constructs a BalanceFrontHandler.
+func NewBalanceFrontHandler(db *gorm.DB) *BalanceFrontHandler {
+	return &BalanceFrontHandler{db: db}
+}
+
+// Get returns bill quota with its daily limit state and the redeemed prepaid balance.
+func (h *BalanceFrontHandler) Get(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	now := time.Now()
+	billState, errState := billing.LoadDailyBillState(ctx, h.db, userID, now)
+	if errState != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "load bill quota failed"})
+		return
+	}
+
+	var prepaidBalance float64
+	if errSum := h.db.WithContext(ctx).
+		Model(&models.PrepaidCard{}).
+		Where("redeemed_user_id = ? AND is_enabled = ? AND balance > 0 AND redeemed_at IS NOT NULL", userID, true).
+		Where("(expires_at IS NULL OR expires_at >= ?)", now.UTC()).
+		Select("COALESCE(SUM(balance), 0)").
+		Scan(&prepaidBalance).Error; errSum != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "load prepaid balance failed"})
+		return
+	}
+
+	status := "ok"
+	switch {
+	case billState.LimitReached:
+		status = "daily_limit_reached"
+	case !billState.HasActiveBill && prepaidBalance <= 0:
+		status = "insufficient_balance"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":          status,
+		"bill":            billState,
+		"prepaid_balance": prepaidBalance,
+	})
+}
+
+// Overview returns active bills, prepaid card balances, today's spend and how many days the
+// remaining balance lasts at the recent burn rate.
+func (h *BalanceFrontHandler) Overview(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	overview, errOverview := billing.LoadBalanceOverview(c.Request.Context(), h.db, userID, time.Now())
+	if errOverview != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "load balance overview failed"})
+		return
+	}
+	c.JSON(http.StatusOK, overview)
+}
@@ -9,9 +9,9 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 // BillFrontHandler handles billing endpoints for users.
@@ -26,13 +26,12 @@ func NewBillFrontHandler(db *gorm.DB) *BillFrontHandler {
 
 // createBillFrontRequest defines the request body for creating bills.
 type createBillFrontRequest struct {
-	PlanID uint64 `json:"plan_id"`
+	PlanID    uint64 `json:"plan_id"`
+	AutoRenew bool   `json:"auto_renew"` // Renew from prepaid balance before the period ends.
 }
 
 // Create purchases a plan using prepaid balance and creates a bill.
 func (h *BillFrontHandler) Create(c *gin.Context) {
-	const balanceEpsilon = 0.01
-
 	userID := getUserID(c)
 	if userID == 0 {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -62,50 +61,9 @@ func (h *BillFrontHandler) Create(c *gin.Context) {
 	}
 
 	requiredAmount := plan.MonthPrice
-	insufficientErr := errors.New("insufficient prepaid card balance")
 	var created models.Bill
 	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
 		now := time.Now().UTC()
-		var cards []models.PrepaidCard
-		if errCards := tx.WithContext(c.Request.Context()).
-			Clauses(clause.Locking{Strength: "UPDATE"}).
-			Where("redeemed_user_id = ? AND is_enabled = ? AND balance > 0 AND redeemed_at IS NOT NULL", userID, true).
-			Where("(expires_at IS NULL OR expires_at >= ?)", now).
-			Order("expires_at ASC NULLS LAST, redeemed_at ASC NULLS LAST, id ASC").
-			Find(&cards).Error; errCards != nil {
-			return errCards
-		}
-
-		totalBalance := 0.0
-		for _, card := range cards {
-			totalBalance += card.Balance
-		}
-		if totalBalance+balanceEpsilon < requiredAmount {
-			return insufficientErr
-		}
-
-		remaining := requiredAmount
-		for _, card := range cards {
-			if remaining <= 0 {
-				break
-			}
-			if card.Balance <= 0 {
-				continue
-			}
-			deduct := card.Balance
-			if deduct > remaining {
-				deduct = remaining
-			}
-			res := tx.WithContext(c.Request.Context()).
-				Model(&models.PrepaidCard{}).
-				Where("id = ?", card.ID).
-				Update("balance", gorm.Expr("balance - ?", deduct))
-			if res.Error != nil {
-				return res.Error
-			}
-			remaining -= deduct
-		}
-
 		periodEnd := now.AddDate(0, 1, 0)
 		bill := models.Bill{
 			PlanID:      plan.ID,
@@ -123,12 +81,21 @@ func (h *BillFrontHandler) Create(c *gin.Context) {
 			RateLimit:   plan.RateLimit,
 			IsEnabled:   true,
 			Status:      models.BillStatusPaid,
+			AutoRenew:   body.AutoRenew,
+			RenewalMode: models.BillRenewalModePrepaid,
 			CreatedAt:   now,
 			UpdatedAt:   now,
 		}
 		if errCreateBill := tx.WithContext(c.Request.Context()).Create(&bill).Error; errCreateBill != nil {
 			return errCreateBill
 		}
+		if errDeduct := billing.DeductPrepaidBalance(c.Request.Context(), tx, userID, requiredAmount, now, billing.LedgerRef{
+			Kind:   models.BalanceTransactionKindBillPayment,
+			BillID: &bill.ID,
+			Reason: "plan purchase",
+		}); errDeduct != nil {
+			return errDeduct
+		}
 		if errRefresh := refreshBillUserGroupIDs(c.Request.Context(), tx, userID); errRefresh != nil {
 			return errRefresh
 		}
@@ -137,7 +104,7 @@ func (h *BillFrontHandler) Create(c *gin.Context) {
 	})
 
 	if errTx != nil {
-		if errors.Is(errTx, insufficientErr) {
+		if errors.Is(errTx, billing.ErrInsufficientPrepaidBalance) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "insufficient prepaid card balance to complete subscription"})
 			return
 		}
@@ -255,6 +222,7 @@ func (h *BillFrontHandler) formatBill(bill *models.Bill) gin.H {
 		"user_id":      bill.UserID,
 		"period_type":  bill.PeriodType,
 		"amount":       bill.Amount,
+		"currency":     bill.Currency,
 		"period_start": bill.PeriodStart,
 		"period_end":   bill.PeriodEnd,
 		"total_quota":  bill.TotalQuota,
@@ -265,6 +233,7 @@ func (h *BillFrontHandler) formatBill(bill *models.Bill) gin.H {
 		"rate_limit":   bill.RateLimit,
 		"is_enabled":   bill.IsEnabled,
 		"status":       bill.Status,
+		"auto_renew":   bill.AutoRenew,
 		"created_at":   bill.CreatedAt,
 		"updated_at":   bill.UpdatedAt,
 	}
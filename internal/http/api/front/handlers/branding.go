@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+)
+
+// brandingResponse is the response payload for white-label branding.
+type brandingResponse struct {
+	ProductName  string `json:"product_name"`  // Display name of the product.
+	LogoURL      string `json:"logo_url"`      // Logo image URL.
+	FaviconURL   string `json:"favicon_url"`   // Favicon URL.
+	SupportEmail string `json:"support_email"` // Support contact address.
+	HomepageURL  string `json:"homepage_url"`  // Reseller homepage link.
+	DocsURL      string `json:"docs_url"`      // Documentation link.
+	TermsURL     string `json:"terms_url"`     // Terms of service link.
+	PrivacyURL   string `json:"privacy_url"`   // Privacy policy link.
+}
+
+// GetBranding returns the white-label branding configured in settings.
+func GetBranding(c *gin.Context) {
+	c.JSON(http.StatusOK, loadBranding())
+}
+
+// loadBranding resolves branding values from the DB config snapshot.
+func loadBranding() brandingResponse {
+	productName := dbConfigString(internalsettings.BrandingProductNameKey)
+	if productName == "" {
+		productName = dbConfigString(internalsettings.SiteNameKey)
+	}
+	if productName == "" {
+		productName = internalsettings.DefaultSiteName
+	}
+	return brandingResponse{
+		ProductName:  productName,
+		LogoURL:      dbConfigString(internalsettings.BrandingLogoURLKey),
+		FaviconURL:   dbConfigString(internalsettings.BrandingFaviconURLKey),
+		SupportEmail: dbConfigString(internalsettings.BrandingSupportEmailKey),
+		HomepageURL:  dbConfigString(internalsettings.BrandingHomepageURLKey),
+		DocsURL:      dbConfigString(internalsettings.BrandingDocsURLKey),
+		TermsURL:     dbConfigString(internalsettings.BrandingTermsURLKey),
+		PrivacyURL:   dbConfigString(internalsettings.BrandingPrivacyURLKey),
+	}
+}
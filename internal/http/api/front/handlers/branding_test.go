@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+)
+
+func TestGetBrandingFallsBackToSiteName(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	internalsettings.StoreDBConfig(time.Now(), map[string]json.RawMessage{
+		internalsettings.SiteNameKey:             json.RawMessage(`"Reseller Portal"`),
+		internalsettings.BrandingLogoURLKey:      json.RawMessage(`"https://cdn.example.com/logo.svg"`),
+		internalsettings.BrandingSupportEmailKey: json.RawMessage(`"help@example.com"`),
+	})
+	t.Cleanup(func() { internalsettings.StoreDBConfig(time.Now(), nil) })
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v0/branding", nil)
+
+	GetBranding(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d body=%s", w.Code, w.Body.String())
+	}
+	var resp brandingResponse
+	if errDecode := json.Unmarshal(w.Body.Bytes(), &resp); errDecode != nil {
+		t.Fatalf("decode response: %v", errDecode)
+	}
+	if resp.ProductName != "Reseller Portal" {
+		t.Fatalf("expected product name from SITE_NAME, got %q", resp.ProductName)
+	}
+	if resp.LogoURL != "https://cdn.example.com/logo.svg" || resp.SupportEmail != "help@example.com" {
+		t.Fatalf("unexpected branding payload: %+v", resp)
+	}
+	if resp.DocsURL != "" {
+		t.Fatalf("expected empty docs url, got %q", resp.DocsURL)
+	}
+}
+
+func TestGetBrandingPrefersProductName(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	internalsettings.StoreDBConfig(time.Now(), map[string]json.RawMessage{
+		internalsettings.SiteNameKey:            json.RawMessage(`"Site"`),
+		internalsettings.BrandingProductNameKey: json.RawMessage(`"White Label"`),
+	})
+	t.Cleanup(func() { internalsettings.StoreDBConfig(time.Now(), nil) })
+
+	if got := loadBranding().ProductName; got != "White Label" {
+		t.Fatalf("expected product name override, got %q", got)
+	}
+}
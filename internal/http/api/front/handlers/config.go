@@ -8,11 +8,13 @@ import (
 
 	"github.com/gin-gonic/gin"
 	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/sociallogin"
 )
 
 // publicConfigResponse is the response payload for public config.
 type publicConfigResponse struct {
-	SiteName string `json:"site_name"`
+	SiteName             string   `json:"site_name"`
+	SocialLoginProviders []string `json:"social_login_providers"` // Providers offered on the login page.
 }
 
 // GetPublicConfig returns public configuration for the front UI.
@@ -21,7 +23,10 @@ func GetPublicConfig(c *gin.Context) {
 	if siteName == "" {
 		siteName = internalsettings.DefaultSiteName
 	}
-	c.JSON(http.StatusOK, publicConfigResponse{SiteName: siteName})
+	c.JSON(http.StatusOK, publicConfigResponse{
+		SiteName:             siteName,
+		SocialLoginProviders: sociallogin.LoadConfig().Enabled(),
+	})
 }
 
 // dbConfigString reads a string value from the DB config snapshot.
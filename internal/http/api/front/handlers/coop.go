@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/coop"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// CoopFrontHandler lets trusted users contribute credentials to the co-op pool.
+type CoopFrontHandler struct {
+	db *gorm.DB
+}
+
+// NewCoopFrontHandler constructs a CoopFrontHandler.
+func NewCoopFrontHandler(db *gorm.DB) *CoopFrontHandler {
+	return &CoopFrontHandler{db: db}
+}
+
+// contributeCoopRequest defines the request body for contributing a credential.
+type contributeCoopRequest struct {
+	Name    string         `json:"name"`
+	Content map[string]any `json:"content"` // Credential JSON; must include its provider type.
+}
+
+// Status reports whether the current user may contribute and the pool's credit rate.
+func (h *CoopFrontHandler) Status(c *gin.Context) {
+	user, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+	policy := coop.LoadPolicy()
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":          policy.Enabled,
+		"can_contribute":   policy.Enabled && policy.Trusts(user.Username),
+		"credit_rate":      policy.CreditRate,
+		"require_approval": policy.RequireApproval,
+	})
+}
+
+// Contribute stores a credential in the shared pool on behalf of the current user.
+func (h *CoopFrontHandler) Contribute(c *gin.Context) {
+	user, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+	var body contributeCoopRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	contribution, _, errContribute := coop.Contribute(c.Request.Context(), h.db, coop.LoadPolicy(), user, body.Name, body.Content, time.Now().UTC())
+	switch {
+	case errors.Is(errContribute, coop.ErrPoolDisabled), errors.Is(errContribute, coop.ErrUserNotTrusted):
+		c.JSON(http.StatusForbidden, gin.H{"error": "contributions are not available for this account"})
+		return
+	case errors.Is(errContribute, coop.ErrInvalidCredential):
+		c.JSON(http.StatusBadRequest, gin.H{"error": errContribute.Error()})
+		return
+	case errContribute != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "contribute failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, h.formatContribution(contribution))
+}
+
+// List returns the current user's contributions and the credit they earned.
+func (h *CoopFrontHandler) List(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var rows []models.CoopContribution
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list contributions failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, h.formatContribution(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"contributions": out})
+}
+
+// Revoke withdraws one of the current user's credentials from the pool.
+func (h *CoopFrontHandler) Revoke(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	contribution, errRevoke := coop.Revoke(c.Request.Context(), h.db, id, userID, time.Now().UTC())
+	switch {
+	case errors.Is(errRevoke, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	case errors.Is(errRevoke, coop.ErrNotActive):
+		c.JSON(http.StatusConflict, gin.H{"error": "contribution already revoked"})
+		return
+	case errRevoke != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "revoke failed"})
+		return
+	}
+	c.JSON(http.StatusOK, h.formatContribution(contribution))
+}
+
+// loadUser loads the authenticated user, writing the error response when it cannot.
+func (h *CoopFrontHandler) loadUser(c *gin.Context) (*models.User, bool) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return nil, false
+	}
+	var user models.User
+	if errFind := h.db.WithContext(c.Request.Context()).Select("id", "username").First(&user, userID).Error; errFind != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return nil, false
+	}
+	return &user, true
+}
+
+// formatContribution converts a contribution into a response payload without the credential.
+func (h *CoopFrontHandler) formatContribution(row *models.CoopContribution) gin.H {
+	return gin.H{
+		"id":              row.ID,
+		"auth_key":        row.AuthKey,
+		"provider":        row.Provider,
+		"status":          row.Status,
+		"credit_rate":     row.CreditRate,
+		"credited_amount": row.CreditedAmount,
+		"served_requests": row.ServedRequests,
+		"settled_until":   row.SettledUntil,
+		"revoked_at":      row.RevokedAt,
+		"created_at":      row.CreatedAt,
+	}
+}
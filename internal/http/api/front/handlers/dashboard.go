@@ -10,6 +10,9 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/currency"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/healthprobe"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"gorm.io/gorm"
 )
@@ -60,10 +63,16 @@ type kpiResponse struct {
 	AvgTokensTrend   float64 `json:"avg_tokens_trend"`
 	SuccessRate      float64 `json:"success_rate"`
 	SuccessRateTrend float64 `json:"success_rate_trend"`
+	Currency         string  `json:"currency"` // Display currency of the cost fields; the budget stays in USD.
 	TodayCostMicros  int64   `json:"today_cost_micros"`
 	TodayCostTrend   float64 `json:"today_cost_trend"`
 	MtdCostMicros    int64   `json:"mtd_cost_micros"`
 	CostTrend        float64 `json:"cost_trend"`
+
+	MtdEnergyMilliWh    int64 `json:"mtd_energy_milli_wh"`
+	MtdCarbonMilligrams int64 `json:"mtd_carbon_milligrams"`
+
+	Budget billing.SpendLimitState `json:"budget"` // Remaining daily and monthly spend budget.
 }
 
 // KPI returns key performance indicators for the dashboard.
@@ -74,6 +83,12 @@ func (h *DashboardHandler) KPI(c *gin.Context) {
 		return
 	}
 
+	budget, errBudget := billing.LoadSpendLimitState(c.Request.Context(), h.db, userID, time.Now())
+	if errBudget != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "load spend limits failed"})
+		return
+	}
+
 	var apiKeyIDs []uint64
 	if errFind := h.db.WithContext(c.Request.Context()).Model(&models.APIKey{}).
 		Where("user_id = ?", userID).
@@ -83,7 +98,7 @@ func (h *DashboardHandler) KPI(c *gin.Context) {
 	}
 
 	if len(apiKeyIDs) == 0 {
-		c.JSON(http.StatusOK, kpiResponse{SuccessRate: 100.0})
+		c.JSON(http.StatusOK, kpiResponse{SuccessRate: 100.0, Currency: currency.Display(c.Request.Context(), h.db).Currency, Budget: budget})
 		return
 	}
 
@@ -115,11 +130,16 @@ func (h *DashboardHandler) KPI(c *gin.Context) {
 		Select("COUNT(*) AS total, SUM(CASE WHEN failed THEN 1 ELSE 0 END) AS failed, COALESCE(SUM(total_tokens), 0) AS total_tokens, COALESCE(SUM(cost_micros), 0) AS cost_micros").
 		Scan(&yesterdayStats)
 
-	var mtdCost int64
+	var mtdStats struct {
+		CostMicros       int64
+		EnergyMilliWh    int64
+		CarbonMilligrams int64
+	}
 	h.db.WithContext(c.Request.Context()).Model(&models.Usage{}).
 		Where("api_key_id IN ? AND requested_at >= ?", apiKeyIDs, monthStart).
-		Select("COALESCE(SUM(cost_micros), 0)").
-		Scan(&mtdCost)
+		Select("COALESCE(SUM(cost_micros), 0) AS cost_micros, COALESCE(SUM(energy_milli_wh), 0) AS energy_milli_wh, COALESCE(SUM(carbon_milligrams), 0) AS carbon_milligrams").
+		Scan(&mtdStats)
+	mtdCost := mtdStats.CostMicros
 
 	lastMonthStart := monthStart.AddDate(0, -1, 0)
 	lastMonthSameDay := lastMonthStart.AddDate(0, 0, now.Day()-1)
@@ -152,6 +172,7 @@ func (h *DashboardHandler) KPI(c *gin.Context) {
 		yesterdayAvgTokens = float64(yesterdayStats.TotalTokens) / float64(yesterdayStats.Total)
 	}
 	avgTokensTrend := calcTrend(yesterdayAvgTokens, avgTokens)
+	display := currency.Display(c.Request.Context(), h.db)
 
 	c.JSON(http.StatusOK, kpiResponse{
 		TotalRequests:    todayStats.Total,
@@ -162,10 +183,16 @@ func (h *DashboardHandler) KPI(c *gin.Context) {
 		AvgTokensTrend:   avgTokensTrend,
 		SuccessRate:      successRate,
 		SuccessRateTrend: successRateTrend,
-		TodayCostMicros:  todayStats.CostMicros,
+		Currency:         display.Currency,
+		TodayCostMicros:  display.Micros(todayStats.CostMicros),
 		TodayCostTrend:   todayCostTrend,
-		MtdCostMicros:    mtdCost,
+		MtdCostMicros:    display.Micros(mtdCost),
 		CostTrend:        costTrend,
+
+		MtdEnergyMilliWh:    mtdStats.EnergyMilliWh,
+		MtdCarbonMilligrams: mtdStats.CarbonMilligrams,
+
+		Budget: budget,
 	})
 }
 
@@ -226,6 +253,9 @@ type costItem struct {
 	Model      string  `json:"model"`
 	CostMicros int64   `json:"cost_micros"`
 	Percentage float64 `json:"percentage"`
+
+	EnergyMilliWh    int64 `json:"energy_milli_wh"`
+	CarbonMilligrams int64 `json:"carbon_milligrams"`
 }
 
 // CostDistribution returns cost breakdown by model.
@@ -255,13 +285,15 @@ func (h *DashboardHandler) CostDistribution(c *gin.Context) {
 
 	// modelCost holds aggregate cost per model.
 	type modelCost struct {
-		Model      string
-		CostMicros int64
+		Model            string
+		CostMicros       int64
+		EnergyMilliWh    int64
+		CarbonMilligrams int64
 	}
 	var results []modelCost
 	h.db.WithContext(c.Request.Context()).Model(&models.Usage{}).
 		Where("api_key_id IN ? AND requested_at >= ?", apiKeyIDs, monthStart).
-		Select("model, COALESCE(SUM(cost_micros), 0) AS cost_micros").
+		Select("model, COALESCE(SUM(cost_micros), 0) AS cost_micros, COALESCE(SUM(energy_milli_wh), 0) AS energy_milli_wh, COALESCE(SUM(carbon_milligrams), 0) AS carbon_milligrams").
 		Group("model").
 		Order("cost_micros DESC").
 		Scan(&results)
@@ -271,6 +303,7 @@ func (h *DashboardHandler) CostDistribution(c *gin.Context) {
 		totalCost += r.CostMicros
 	}
 
+	display := currency.Display(c.Request.Context(), h.db)
 	items := make([]costItem, 0, len(results))
 	for _, r := range results {
 		pct := 0.0
@@ -279,27 +312,46 @@ func (h *DashboardHandler) CostDistribution(c *gin.Context) {
 		}
 		items = append(items, costItem{
 			Model:      r.Model,
-			CostMicros: r.CostMicros,
+			CostMicros: display.Micros(r.CostMicros),
 			Percentage: pct,
+
+			EnergyMilliWh:    r.EnergyMilliWh,
+			CarbonMilligrams: r.CarbonMilligrams,
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{"items": items})
+	c.JSON(http.StatusOK, gin.H{"items": items, "currency": display.Currency})
 }
 
 // healthItem defines a model health status item.
 type healthItem struct {
-	Provider string `json:"provider"`
-	Status   string `json:"status"`
-	Latency  string `json:"latency"`
+	Provider         string  `json:"provider"`
+	Status           string  `json:"status"`
+	Latency          string  `json:"latency"`
+	P95LatencyMillis int64   `json:"p95_latency_ms"`
+	SuccessRate      float64 `json:"success_rate"`
 }
 
-// ModelHealth returns placeholder model health status.
+// ModelHealth returns per-provider health from the last hour of upstream checks.
 func (h *DashboardHandler) ModelHealth(c *gin.Context) {
-	items := []healthItem{
-		{Provider: "OpenAI API", Status: "healthy", Latency: "12ms"},
-		{Provider: "Anthropic API", Status: "healthy", Latency: "45ms"},
-		{Provider: "Local LLM Cluster", Status: "degraded", Latency: "Degraded"},
+	summaries, errSummarize := healthprobe.Summarize(c.Request.Context(), h.db, time.Now().Add(-time.Hour))
+	if errSummarize != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	items := make([]healthItem, 0, len(summaries))
+	for _, summary := range summaries {
+		latency := strconv.FormatInt(summary.P95LatencyMillis, 10) + "ms"
+		if summary.Checks == summary.Failures {
+			latency = "Unavailable"
+		}
+		items = append(items, healthItem{
+			Provider:         summary.Provider,
+			Status:           summary.Status,
+			Latency:          latency,
+			P95LatencyMillis: summary.P95LatencyMillis,
+			SuccessRate:      summary.SuccessRate(),
+		})
 	}
 	c.JSON(http.StatusOK, gin.H{"items": items})
 }
@@ -493,6 +545,7 @@ func (h *DashboardHandler) RecentTransactions(c *gin.Context) {
 		}
 	}
 
+	display := currency.Display(c.Request.Context(), h.db)
 	transactions := make([]transactionItem, 0, len(usages))
 	for _, u := range usages {
 		authLabel := ""
@@ -528,11 +581,12 @@ func (h *DashboardHandler) RecentTransactions(c *gin.Context) {
 			InputTokens:   u.InputTokens,
 			CachedTokens:  u.CachedTokens,
 			OutputTokens:  u.OutputTokens,
-			CostMicros:    u.CostMicros,
+			CostMicros:    display.Micros(u.CostMicros),
 		})
 	}
 
 	c.JSON(http.StatusOK, gin.H{
+		"currency":     display.Currency,
 		"transactions": transactions,
 		"total":        total,
 		"page":         page,
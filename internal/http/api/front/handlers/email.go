@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/mail"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// emailVerificationRequired reports whether the REQUIRE_EMAIL_VERIFICATION switch is on.
+func emailVerificationRequired() bool {
+	raw, ok := internalsettings.DBConfigValue(internalsettings.RequireEmailVerificationKey)
+	if !ok {
+		return internalsettings.DefaultRequireEmailVerification
+	}
+	raw = bytes.TrimSpace(raw)
+	var enabled bool
+	if errUnmarshal := json.Unmarshal(raw, &enabled); errUnmarshal == nil {
+		return enabled
+	}
+	var text string
+	if errUnmarshal := json.Unmarshal(raw, &text); errUnmarshal == nil {
+		text = strings.TrimSpace(text)
+		return strings.EqualFold(text, "true") || text == "1"
+	}
+	return false
+}
+
+// emailTokenRequest carries a token from an emailed link.
+type emailTokenRequest struct {
+	Token string `json:"token"`
+}
+
+// VerifyEmail confirms the user's email address with an emailed token.
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var body emailTokenRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	ctx := c.Request.Context()
+	now := time.Now().UTC()
+	token, errConsume := mail.ConsumeToken(ctx, h.db, models.EmailTokenPurposeVerifyEmail, body.Token, now)
+	if errConsume != nil {
+		if errors.Is(errConsume, mail.ErrInvalidToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "verify email failed"})
+		return
+	}
+	// The address may have changed since the link was sent; only the mailed address is verified.
+	res := h.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND email = ?", token.UserID, token.Email).
+		Updates(map[string]any{"email_verified_at": now, "updated_at": now})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "verify email failed"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired token"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// ResendVerification mails a fresh verification link to the current user.
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if !h.mailer.Enabled() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "email is not configured"})
+		return
+	}
+	var user models.User
+	if errFind := h.db.WithContext(c.Request.Context()).First(&user, userID).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if strings.TrimSpace(user.Email) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no email address on file"})
+		return
+	}
+	if user.EmailVerifiedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "email already verified"})
+		return
+	}
+	if errSend := h.mailer.SendVerification(c.Request.Context(), h.db, &user); errSend != nil {
+		log.WithError(errSend).WithField("user_id", user.ID).Warn("send verification email failed")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "send email failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// passwordResetRequest names the account that wants a reset link.
+type passwordResetRequest struct {
+	Email string `json:"email"`
+}
+
+// RequestPasswordReset mails a reset link to the account with the given email. It answers the
+// same whether or not the account exists so addresses cannot be probed.
+func (h *AuthHandler) RequestPasswordReset(c *gin.Context) {
+	if !h.mailer.Enabled() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "email is not configured"})
+		return
+	}
+	var body passwordResetRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	email := strings.TrimSpace(body.Email)
+	if email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing email"})
+		return
+	}
+	var user models.User
+	errFind := h.db.WithContext(c.Request.Context()).Where("email = ?", email).First(&user).Error
+	switch {
+	case errFind == nil && !user.Disabled:
+		if errSend := h.mailer.SendPasswordReset(c.Request.Context(), h.db, &user); errSend != nil {
+			log.WithError(errSend).WithField("user_id", user.ID).Warn("send password reset email failed")
+		}
+	case errFind != nil && !errors.Is(errFind, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// confirmPasswordResetRequest carries the emailed token and the new password.
+type confirmPasswordResetRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// ConfirmPasswordReset sets a new password with an emailed token and signs out other sessions.
+func (h *AuthHandler) ConfirmPasswordReset(c *gin.Context) {
+	var body confirmPasswordResetRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	newPassword := strings.TrimSpace(body.NewPassword)
+	if newPassword == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing new password"})
+		return
+	}
+	hash, errHash := security.HashPassword(newPassword)
+	if errHash != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "hash password failed"})
+		return
+	}
+	ctx := c.Request.Context()
+	now := time.Now().UTC()
+	token, errConsume := mail.ConsumeToken(ctx, h.db, models.EmailTokenPurposePasswordReset, body.Token, now)
+	if errConsume != nil {
+		if errors.Is(errConsume, mail.ErrInvalidToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "reset password failed"})
+		return
+	}
+	// Receiving the link proves ownership of the address, so it also counts as verification.
+	if errUpdate := h.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", token.UserID).Updates(map[string]any{
+		"password":            hash,
+		"sessions_revoked_at": now,
+		"email_verified_at":   gorm.Expr("COALESCE(email_verified_at, ?)", now),
+		"updated_at":          now,
+	}).Error; errUpdate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "reset password failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
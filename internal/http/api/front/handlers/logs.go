@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/currency"
+	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"gorm.io/gorm"
 )
@@ -146,6 +148,7 @@ func (h *LogsHandler) List(c *gin.Context) {
 		return
 	}
 
+	display := currency.Display(ctx, h.db)
 	logs := make([]logEntry, 0, len(aggs))
 	for _, a := range aggs {
 		status := "normal"
@@ -177,7 +180,7 @@ func (h *LogsHandler) List(c *gin.Context) {
 			OutputTokens: a.OutputTokens,
 			TotalTokens:  a.TotalTokens,
 			CostMicros:   a.CostMicros,
-			Cost:         fmt.Sprintf("$%.2f", float64(a.CostMicros)/1_000_000),
+			Cost:         display.Format(a.CostMicros, 2),
 			FailedCount:  a.FailedCount,
 			Status:       status,
 			StatusText:   statusText,
@@ -185,10 +188,11 @@ func (h *LogsHandler) List(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"logs":  logs,
-		"total": total,
-		"page":  q.Page,
-		"limit": q.Limit,
+		"logs":     logs,
+		"total":    total,
+		"currency": display.Currency,
+		"page":     q.Page,
+		"limit":    q.Limit,
 	})
 }
 
@@ -224,7 +228,7 @@ func (h *LogsHandler) Stats(c *gin.Context) {
 			COALESCE(SUM(total_tokens), 0) AS total_tokens,
 			SUM(CASE WHEN failed THEN 1 ELSE 0 END) AS failed_count,
 			COALESCE(SUM(cost_micros), 0) AS cost_micros,
-			COALESCE(AVG(GREATEST(EXTRACT(EPOCH FROM (created_at - requested_at)) * 1000, 0)), 0) AS avg_request_time_ms
+			COALESCE(AVG(` + dbutil.DurationMillisExpr(h.db, "requested_at", "created_at") + `), 0) AS avg_request_time_ms
 		`).Scan(&todayStats)
 
 	h.db.WithContext(ctx).Model(&models.Usage{}).
@@ -234,7 +238,7 @@ func (h *LogsHandler) Stats(c *gin.Context) {
 			COALESCE(SUM(total_tokens), 0) AS total_tokens,
 			SUM(CASE WHEN failed THEN 1 ELSE 0 END) AS failed_count,
 			COALESCE(SUM(cost_micros), 0) AS cost_micros,
-			COALESCE(AVG(GREATEST(EXTRACT(EPOCH FROM (created_at - requested_at)) * 1000, 0)), 0) AS avg_request_time_ms
+			COALESCE(AVG(` + dbutil.DurationMillisExpr(h.db, "requested_at", "created_at") + `), 0) AS avg_request_time_ms
 		`).Scan(&yesterdayStats)
 
 	requestsChange := calcChange(todayStats.Requests, yesterdayStats.Requests)
@@ -475,6 +479,7 @@ func (h *LogsHandler) Detail(c *gin.Context) {
 		return
 	}
 
+	display := currency.Display(ctx, h.db)
 	details := make([]gin.H, 0, len(rows))
 	for _, row := range rows {
 		details = append(details, gin.H{
@@ -483,12 +488,12 @@ func (h *LogsHandler) Detail(c *gin.Context) {
 			"output_tokens": row.OutputTokens,
 			"cached_tokens": row.CachedTokens,
 			"total_tokens":  row.TotalTokens,
-			"cost":          fmt.Sprintf("$%.4f", float64(row.CostMicros)/1_000_000),
+			"cost":          display.Format(row.CostMicros, 4),
 			"success":       !row.Failed,
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{"details": details})
+	c.JSON(http.StatusOK, gin.H{"details": details, "currency": display.Currency})
 }
 
 // formatNumber formats large counts with suffixes.
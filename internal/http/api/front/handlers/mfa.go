@@ -2,20 +2,22 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"image/png"
 	"io"
 	"net/http"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/pquerna/otp/totp"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/mfasession"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
 	log "github.com/sirupsen/logrus"
@@ -33,21 +35,14 @@ func NewMFAHandler(db *gorm.DB, webAuthn *webauthn.WebAuthn) *MFAHandler {
 	return &MFAHandler{db: db, webAuthn: webAuthn}
 }
 
-// sessionEntry stores WebAuthn session data with expiry.
-type sessionEntry struct {
-	data    webauthn.SessionData
-	expires time.Time
-}
-
-// sessionStore keeps temporary WebAuthn sessions in memory.
+// sessionStore keeps WebAuthn sessions in the shared MFA session store.
 type sessionStore struct {
-	mu    sync.Mutex
-	items map[string]sessionEntry
+	scope string
 }
 
-// newSessionStore creates an empty session store.
-func newSessionStore() *sessionStore {
-	return &sessionStore{items: make(map[string]sessionEntry)}
+// newSessionStore creates a session store under the given scope.
+func newSessionStore(scope string) *sessionStore {
+	return &sessionStore{scope: scope}
 }
 
 // loadWebAuthn loads WebAuthn configuration.
@@ -57,94 +52,89 @@ func loadWebAuthn() (*webauthn.WebAuthn, error) {
 
 // Set stores session data with expiry.
 func (s *sessionStore) Set(key string, data webauthn.SessionData) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	expires := data.Expires
 	if expires.IsZero() {
 		expires = time.Now().Add(5 * time.Minute)
 	}
-	s.items[key] = sessionEntry{data: data, expires: expires}
+	raw, errMarshal := json.Marshal(data)
+	if errMarshal != nil {
+		log.WithError(errMarshal).Warn("encode passkey session failed")
+		return
+	}
+	if errPut := mfasession.Default().Put(context.Background(), s.scope, key, raw, expires); errPut != nil {
+		log.WithError(errPut).WithField("scope", s.scope).Warn("store passkey session failed")
+	}
 }
 
 // Get returns session data if present and not expired.
 func (s *sessionStore) Get(key string) (webauthn.SessionData, bool) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	entry, ok := s.items[key]
+	raw, ok, errGet := mfasession.Default().Get(context.Background(), s.scope, key)
+	if errGet != nil {
+		log.WithError(errGet).WithField("scope", s.scope).Warn("load passkey session failed")
+		return webauthn.SessionData{}, false
+	}
 	if !ok {
 		return webauthn.SessionData{}, false
 	}
-	if time.Now().After(entry.expires) {
-		delete(s.items, key)
+	var data webauthn.SessionData
+	if errUnmarshal := json.Unmarshal(raw, &data); errUnmarshal != nil {
 		return webauthn.SessionData{}, false
 	}
-	return entry.data, true
+	return data, true
 }
 
 // Delete removes a session entry.
 func (s *sessionStore) Delete(key string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	delete(s.items, key)
-}
-
-// secretEntry stores a TOTP secret with expiry.
-type secretEntry struct {
-	secret  string
-	expires time.Time
+	if errDelete := mfasession.Default().Delete(context.Background(), s.scope, key); errDelete != nil {
+		log.WithError(errDelete).WithField("scope", s.scope).Warn("delete passkey session failed")
+	}
 }
 
-// secretStore keeps temporary TOTP secrets in memory.
+// secretStore keeps pending TOTP secrets in the shared MFA session store.
 type secretStore struct {
-	mu    sync.Mutex
-	items map[string]secretEntry
+	scope string
 }
 
-// newSecretStore creates an empty secret store.
-func newSecretStore() *secretStore {
-	return &secretStore{items: make(map[string]secretEntry)}
+// newSecretStore creates a secret store under the given scope.
+func newSecretStore(scope string) *secretStore {
+	return &secretStore{scope: scope}
 }
 
 // Set stores a secret with expiry.
 func (s *secretStore) Set(key, secret string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.items[key] = secretEntry{secret: secret, expires: time.Now().Add(10 * time.Minute)}
+	if errPut := mfasession.Default().Put(context.Background(), s.scope, key, []byte(secret), time.Now().Add(10*time.Minute)); errPut != nil {
+		log.WithError(errPut).WithField("scope", s.scope).Warn("store totp secret failed")
+	}
 }
 
 // Get returns a secret if present and not expired.
 func (s *secretStore) Get(key string) (string, bool) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	entry, ok := s.items[key]
-	if !ok {
+	raw, ok, errGet := mfasession.Default().Get(context.Background(), s.scope, key)
+	if errGet != nil {
+		log.WithError(errGet).WithField("scope", s.scope).Warn("load totp secret failed")
 		return "", false
 	}
-	if time.Now().After(entry.expires) {
-		delete(s.items, key)
+	if !ok {
 		return "", false
 	}
-	return entry.secret, true
+	return string(raw), true
 }
 
 // Delete removes a secret entry.
 func (s *secretStore) Delete(key string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	delete(s.items, key)
+	if errDelete := mfasession.Default().Delete(context.Background(), s.scope, key); errDelete != nil {
+		log.WithError(errDelete).WithField("scope", s.scope).Warn("delete totp secret failed")
+	}
 }
 
-// In-memory MFA session stores for passkey and TOTP flows.
+// MFA session stores for passkey and TOTP flows; scopes keep admin and user keys apart.
 var (
 	// passkeyRegistrationSessions stores in-flight registration sessions.
-	passkeyRegistrationSessions = newSessionStore()
+	passkeyRegistrationSessions = newSessionStore("user:passkey_registration")
 	// passkeyLoginSessions stores in-flight login sessions.
-	passkeyLoginSessions = newSessionStore()
+	passkeyLoginSessions = newSessionStore("user:passkey_login")
 	// totpPendingSecrets stores pending TOTP secrets for confirmation.
-	totpPendingSecrets = newSecretStore()
+	totpPendingSecrets = newSecretStore("user:totp_pending")
 )
 
 // userWebAuthnUser adapts a user model to WebAuthn interfaces.
@@ -238,9 +228,20 @@ func (h *MFAHandler) Status(c *gin.Context) {
 	totpEnabled := strings.TrimSpace(user.TOTPSecret) != ""
 	passkeyEnabled := len(user.PasskeyID) > 0 && len(user.PasskeyPublicKey) > 0
 
+	recoveryRemaining := int64(0)
+	if totpEnabled {
+		remaining, errCount := security.RemainingRecoveryCodes(c.Request.Context(), h.db, models.MFAOwnerUser, user.ID)
+		if errCount != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+			return
+		}
+		recoveryRemaining = remaining
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"totp_enabled":    totpEnabled,
-		"passkey_enabled": passkeyEnabled,
+		"totp_enabled":             totpEnabled,
+		"passkey_enabled":          passkeyEnabled,
+		"recovery_codes_remaining": recoveryRemaining,
 	})
 }
 
@@ -320,15 +321,73 @@ func (h *MFAHandler) ConfirmTOTP(c *gin.Context) {
 		return
 	}
 
-	if errUpdate := h.db.WithContext(c.Request.Context()).Model(&models.User{}).
-		Where("id = ?", userID).
-		Updates(map[string]any{"totp_secret": secret, "updated_at": time.Now().UTC()}).Error; errUpdate != nil {
+	ctx := c.Request.Context()
+	var recoveryCodes []string
+	errTx := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if errUpdate := tx.Model(&models.User{}).
+			Where("id = ?", userID).
+			Updates(map[string]any{"totp_secret": secret, "updated_at": time.Now().UTC()}).Error; errUpdate != nil {
+			return errUpdate
+		}
+		codes, errCodes := security.ReplaceRecoveryCodes(ctx, tx, models.MFAOwnerUser, userID)
+		if errCodes != nil {
+			return errCodes
+		}
+		recoveryCodes = codes
+		return nil
+	})
+	if errTx != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
 		return
 	}
 
 	totpPendingSecrets.Delete(fmt.Sprintf("%d", userID))
-	c.JSON(http.StatusOK, gin.H{"ok": true})
+	// Recovery codes are only ever shown here and on regeneration.
+	c.JSON(http.StatusOK, gin.H{"ok": true, "recovery_codes": recoveryCodes})
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after checking a current TOTP code.
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, ok := readUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	var body totpConfirmRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	code := strings.TrimSpace(body.Code)
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing code"})
+		return
+	}
+
+	var user models.User
+	if errFind := h.db.WithContext(c.Request.Context()).Select("id", "totp_secret").First(&user, userID).Error; errFind != nil {
+		if errFind == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if strings.TrimSpace(user.TOTPSecret) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "totp not enabled"})
+		return
+	}
+	if !totp.Validate(code, user.TOTPSecret) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid code"})
+		return
+	}
+
+	recoveryCodes, errCodes := security.ReplaceRecoveryCodes(c.Request.Context(), h.db, models.MFAOwnerUser, user.ID)
+	if errCodes != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": recoveryCodes})
 }
 
 // DisableTOTP removes the user's TOTP secret.
@@ -354,6 +413,10 @@ func (h *MFAHandler) DisableTOTP(c *gin.Context) {
 		return
 	}
 
+	if errDelete := security.DeleteRecoveryCodes(c.Request.Context(), h.db, models.MFAOwnerUser, userID); errDelete != nil {
+		log.WithError(errDelete).WithField("user_id", userID).Warn("delete recovery codes failed")
+	}
+
 	totpPendingSecrets.Delete(fmt.Sprintf("%d", userID))
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
@@ -575,6 +638,55 @@ func (h *AuthHandler) LoginTOTP(c *gin.Context) {
 	h.respondWithUserToken(c, user)
 }
 
+// loginRecoveryCodeRequest defines the request body for recovery code login.
+type loginRecoveryCodeRequest struct {
+	Username     string `json:"username"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// LoginRecoveryCode authenticates a user with a single-use TOTP recovery code when the
+// authenticator is unavailable.
+func (h *AuthHandler) LoginRecoveryCode(c *gin.Context) {
+	var body loginRecoveryCodeRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	username := strings.TrimSpace(body.Username)
+	code := strings.TrimSpace(body.RecoveryCode)
+	if username == "" || code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "username and recovery code are required"})
+		return
+	}
+
+	var user models.User
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Where("username = ?", username).
+		First(&user).Error; errFind != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		return
+	}
+	if user.Disabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "user disabled"})
+		return
+	}
+	if strings.TrimSpace(user.TOTPSecret) == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "totp not enabled"})
+		return
+	}
+	valid, errConsume := security.ConsumeRecoveryCode(c.Request.Context(), h.db, models.MFAOwnerUser, user.ID, code, time.Now())
+	if errConsume != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "verify recovery code failed"})
+		return
+	}
+	if !valid {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid recovery code"})
+		return
+	}
+
+	h.respondWithUserToken(c, user)
+}
+
 // loginPasskeyRequest defines the request body for passkey login options.
 type loginPasskeyRequest struct {
 	Username string `json:"username"`
@@ -709,19 +821,51 @@ func (h *AuthHandler) LoginPasskeyVerify(c *gin.Context) {
 	h.respondWithUserToken(c, user)
 }
 
-// respondWithUserToken generates a JWT and responds with user info.
-func (h *AuthHandler) respondWithUserToken(c *gin.Context, user models.User) {
+// respondWithUserToken generates a JWT and responds with user info plus any extra fields.
+func (h *AuthHandler) respondWithUserToken(c *gin.Context, user models.User, extra ...gin.H) {
 	token, errToken := security.GenerateToken(h.jwtCfg.Secret, user.ID, user.Username, user.Name, user.Email, h.jwtCfg.Expiry)
 	if errToken != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	out := gin.H{
 		"user_id":  user.ID,
 		"username": user.Username,
 		"name":     user.Name,
 		"email":    user.Email,
 		"token":    token,
-	})
+	}
+	for _, fields := range extra {
+		for k, v := range fields {
+			out[k] = v
+		}
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// enforceMFAPolicy applies MFA_POLICY to a password-only login. When the user must enroll first
+// it answers 403 with a short-lived enrollment token and returns false; during the grace
+// period it returns the deadline to include in the login response.
+func (h *AuthHandler) enforceMFAPolicy(c *gin.Context, user models.User) (gin.H, bool) {
+	enforcement, deadline := security.LoadMFAPolicy().Evaluate(models.MFAOwnerUser, false, user.CreatedAt, time.Now())
+	switch enforcement {
+	case security.MFAGracePeriod:
+		return gin.H{"mfa_enrollment_deadline": deadline}, true
+	case security.MFAEnrollmentRequired:
+		token, errToken := security.GenerateEnrollmentToken(h.jwtCfg.Secret, user.ID, user.Username, user.Name, user.Email)
+		if errToken != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+			return nil, false
+		}
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":                   "mfa enrollment required",
+			"mfa_enrollment_required": true,
+			"mfa_enrollment_deadline": deadline,
+			"enrollment_token":        token,
+		})
+		return nil, false
+	default:
+		return nil, true
+	}
 }
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/access"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelcatalog"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modeldisplay"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+)
+
+// modelCatalogItem is one entry of the user-facing model catalog.
+type modelCatalogItem struct {
+	Provider      string              `json:"provider"`
+	Model         string              `json:"model"`
+	DisplayName   string              `json:"display_name"`
+	Category      string              `json:"category,omitempty"`
+	Description   string              `json:"description,omitempty"`
+	ContextWindow int                 `json:"context_window,omitempty"`
+	Capabilities  []string            `json:"capabilities,omitempty"`
+	Price         *modelcatalog.Price `json:"price,omitempty"`
+}
+
+// Catalog returns the models the user may request together with the price they pay, in one
+// list for model pickers. An api_key_id query narrows it to what one of the user's keys allows.
+func (h *ModelPricingHandler) Catalog(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if h.db == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database not initialized"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var scope *access.KeyScope
+	if raw := strings.TrimSpace(c.Query("api_key_id")); raw != "" {
+		keyID, errParse := strconv.ParseUint(raw, 10, 64)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid api_key_id"})
+			return
+		}
+		var apiKey models.APIKey
+		if errFind := h.db.WithContext(ctx).
+			Where("id = ? AND user_id = ?", keyID, userID).
+			First(&apiKey).Error; errFind != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+			return
+		}
+		keyScope := access.ScopeOf(&apiKey)
+		scope = &keyScope
+	}
+
+	pricing, message, errLoad := h.loadPricingContext(ctx, userID)
+	if errLoad != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+		return
+	}
+	onlyMapped := loadOnlyMapped()
+	available, errModels := h.loadAvailableModels(ctx, onlyMapped)
+	if errModels != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "load available models failed"})
+		return
+	}
+	displays := modeldisplay.Load(ctx, h.db)
+
+	out := make([]modelCatalogItem, 0, len(available))
+	for _, item := range available {
+		provider := strings.TrimSpace(item.Provider)
+		modelID := strings.TrimSpace(item.ModelID)
+		if provider == "" || modelID == "" {
+			continue
+		}
+		if scope != nil && (!scope.AllowsModel(modelID) || !scope.AllowsAnyProvider([]string{provider})) {
+			continue
+		}
+		billingUserGroupID := pricing.billingUserGroup(provider, modelID)
+		if billingUserGroupID == nil {
+			continue
+		}
+
+		result := modelCatalogItem{
+			Provider:    provider,
+			Model:       modelID,
+			DisplayName: item.DisplayName,
+			Price:       modelcatalog.PriceOf(pricing.selectRule(*billingUserGroupID, provider, modelID)),
+		}
+		if display, ok := modeldisplay.Lookup(displays, modelID); ok {
+			result.DisplayName = display.Label()
+			result.Category = display.Category
+			result.Description = display.Description
+			result.ContextWindow = display.ContextWindow
+			result.Capabilities = display.Capabilities
+		}
+		out = append(out, result)
+	}
+	sortModelCatalog(out)
+
+	c.JSON(http.StatusOK, gin.H{"models": out, "only_mapped": onlyMapped})
+}
+
+// sortModelCatalog sorts catalog items by provider and model.
+func sortModelCatalog(list []modelCatalogItem) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Provider == list[j].Provider {
+			return strings.ToLower(list[i].Model) < strings.ToLower(list[j].Model)
+		}
+		return list[i].Provider < list[j].Provider
+	})
+}
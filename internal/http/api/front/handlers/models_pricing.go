@@ -11,6 +11,7 @@ import (
 	"github.com/gin-gonic/gin"
 	sdkcliproxy "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modeldisplay"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelmapping"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelregistry"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
@@ -35,6 +36,10 @@ type modelPricingItem struct {
 	Model                 string             `json:"model"`
 	DisplayName           string             `json:"display_name"`
 	OriginalModel         string             `json:"original_model,omitempty"`
+	Category              string             `json:"category,omitempty"`
+	Description           string             `json:"description,omitempty"`
+	ContextWindow         int                `json:"context_window,omitempty"`
+	Capabilities          []string           `json:"capabilities,omitempty"`
 	BillingType           models.BillingType `json:"billing_type"`
 	RuleID                uint64             `json:"rule_id,omitempty"`
 	PricePerRequest       *float64           `json:"price_per_request,omitempty"`
@@ -42,6 +47,9 @@ type modelPricingItem struct {
 	PriceOutputToken      *float64           `json:"price_output_token,omitempty"`
 	PriceCacheCreateToken *float64           `json:"price_cache_create_token,omitempty"`
 	PriceCacheReadToken   *float64           `json:"price_cache_read_token,omitempty"`
+
+	TokenTiers    []models.BillingTokenTier `json:"token_tiers,omitempty"`
+	MinimumCharge *float64                  `json:"minimum_charge,omitempty"`
 }
 
 // modelAvailability captures availability metadata for a model.
@@ -65,56 +73,161 @@ func (h *ModelPricingHandler) List(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
+	pricing, message, errLoad := h.loadPricingContext(ctx, userID)
+	if errLoad != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+		return
+	}
+
+	onlyMapped := loadOnlyMapped()
+	available, errModels := h.loadAvailableModels(ctx, onlyMapped)
+	if errModels != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "load available models failed"})
+		return
+	}
+
+	displays := modeldisplay.Load(ctx, h.db)
+
+	perRequest := make([]modelPricingItem, 0)
+	perToken := make([]modelPricingItem, 0)
+	tiered := make([]modelPricingItem, 0)
+	unpriced := make([]modelPricingItem, 0)
+
+	for _, item := range available {
+		provider := strings.TrimSpace(item.Provider)
+		modelID := strings.TrimSpace(item.ModelID)
+		if provider == "" || modelID == "" {
+			continue
+		}
+
+		billingUserGroupID := pricing.billingUserGroup(provider, modelID)
+		if billingUserGroupID == nil {
+			continue
+		}
+
+		rule := pricing.selectRule(*billingUserGroupID, provider, modelID)
+		result := modelPricingItem{
+			Provider:      provider,
+			Model:         modelID,
+			DisplayName:   item.DisplayName,
+			OriginalModel: item.OriginalModel,
+		}
+		if display, ok := modeldisplay.Lookup(displays, modelID); ok {
+			result.DisplayName = display.Label()
+			result.Category = display.Category
+			result.Description = display.Description
+			result.ContextWindow = display.ContextWindow
+			result.Capabilities = display.Capabilities
+		}
+		if rule != nil {
+			result.BillingType = rule.BillingType
+			result.RuleID = rule.ID
+			result.PricePerRequest = rule.PricePerRequest
+			result.PriceInputToken = rule.PriceInputToken
+			result.PriceOutputToken = rule.PriceOutputToken
+			result.PriceCacheCreateToken = rule.PriceCacheCreateToken
+			result.PriceCacheReadToken = rule.PriceCacheReadToken
+			result.MinimumCharge = rule.MinimumCharge
+			if tiers, errTiers := billing.ParseTokenTiers(rule.TokenTiers); errTiers == nil {
+				result.TokenTiers = tiers
+			}
+		}
 
+		switch result.BillingType {
+		case models.BillingTypePerRequest:
+			perRequest = append(perRequest, result)
+		case models.BillingTypePerToken:
+			perToken = append(perToken, result)
+		case models.BillingTypeTiered:
+			tiered = append(tiered, result)
+		default:
+			unpriced = append(unpriced, result)
+		}
+	}
+
+	sortModelPricing(perRequest)
+	sortModelPricing(perToken)
+	sortModelPricing(tiered)
+	sortModelPricing(unpriced)
+
+	c.JSON(http.StatusOK, gin.H{
+		"per_request": perRequest,
+		"per_token":   perToken,
+		"tiered":      tiered,
+		"unpriced":    unpriced,
+		"only_mapped": onlyMapped,
+	})
+}
+
+// pricingContext holds the groups and billing rules that decide which models a user sees and
+// what they pay for them.
+type pricingContext struct {
+	authGroupID             uint64
+	defaultAuthGroupID      uint64
+	defaultUserGroupIDValue uint64
+	defaultUserGroupID      *uint64
+	primaryUserGroupID      *uint64 // Billing group for models without a group restriction.
+	assignedUserGroupIDs    []uint64
+	billedUserGroupIDs      []uint64
+	userAccessGroups        map[uint64]struct{}
+	rules                   []models.BillingRule
+}
+
+// loadPricingContext resolves the user's groups and loads the billing rules that can apply to
+// them. On failure it also returns the message to report to the client.
+func (h *ModelPricingHandler) loadPricingContext(ctx context.Context, userID uint64) (*pricingContext, string, error) {
 	var user models.User
 	if errFind := h.db.WithContext(ctx).Select("id", "user_group_id", "bill_user_group_id").First(&user, userID).Error; errFind != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "query user failed"})
-		return
+		return nil, "query user failed", errFind
 	}
 	defaultUserGroupID, errDefaultUserGroup := billing.ResolveDefaultUserGroupID(ctx, h.db)
 	if errDefaultUserGroup != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "query default user group failed"})
-		return
+		return nil, "query default user group failed", errDefaultUserGroup
 	}
 
 	authGroupID, errAuthGroup := billing.ResolveDefaultAuthGroupID(ctx, h.db)
 	if errAuthGroup != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "query auth group failed"})
-		return
+		return nil, "query auth group failed", errAuthGroup
 	}
 
-	var authGroupIDValue uint64
+	p := &pricingContext{
+		defaultUserGroupID:   defaultUserGroupID,
+		assignedUserGroupIDs: user.UserGroupID.Values(),
+		billedUserGroupIDs:   user.BillUserGroupID.Values(),
+	}
 	if authGroupID != nil {
-		authGroupIDValue = *authGroupID
+		p.authGroupID = *authGroupID
 	}
-	defaultAuthGroupIDValue := authGroupIDValue
-
-	var defaultUserGroupIDValue uint64
+	p.defaultAuthGroupID = p.authGroupID
 	if defaultUserGroupID != nil {
-		defaultUserGroupIDValue = *defaultUserGroupID
+		p.defaultUserGroupIDValue = *defaultUserGroupID
+	}
+	p.primaryUserGroupID = user.UserGroupID.Primary()
+	if p.primaryUserGroupID == nil {
+		p.primaryUserGroupID = user.BillUserGroupID.Primary()
+	}
+	if p.primaryUserGroupID == nil {
+		p.primaryUserGroupID = defaultUserGroupID
 	}
 
-	assignedUserGroupIDs := user.UserGroupID.Values()
-	billedUserGroupIDs := user.BillUserGroupID.Values()
-
-	userAccessGroups := make(map[uint64]struct{}, len(assignedUserGroupIDs)+len(billedUserGroupIDs)+1)
-	for _, id := range assignedUserGroupIDs {
+	p.userAccessGroups = make(map[uint64]struct{}, len(p.assignedUserGroupIDs)+len(p.billedUserGroupIDs)+1)
+	for _, id := range p.assignedUserGroupIDs {
 		if id == 0 {
 			continue
 		}
-		userAccessGroups[id] = struct{}{}
+		p.userAccessGroups[id] = struct{}{}
 	}
-	for _, id := range billedUserGroupIDs {
+	for _, id := range p.billedUserGroupIDs {
 		if id == 0 {
 			continue
 		}
-		userAccessGroups[id] = struct{}{}
+		p.userAccessGroups[id] = struct{}{}
 	}
-	if len(userAccessGroups) == 0 && defaultUserGroupID != nil && *defaultUserGroupID != 0 {
-		userAccessGroups[*defaultUserGroupID] = struct{}{}
+	if len(p.userAccessGroups) == 0 && defaultUserGroupID != nil && *defaultUserGroupID != 0 {
+		p.userAccessGroups[*defaultUserGroupID] = struct{}{}
 	}
 
-	billingUserGroupIDs := make([]uint64, 0, len(assignedUserGroupIDs)+len(billedUserGroupIDs)+1)
+	billingUserGroupIDs := make([]uint64, 0, len(p.assignedUserGroupIDs)+len(p.billedUserGroupIDs)+1)
 	seenBilling := make(map[uint64]struct{}, cap(billingUserGroupIDs))
 	addBilling := func(id uint64) {
 		if id == 0 {
@@ -126,132 +239,81 @@ func (h *ModelPricingHandler) List(c *gin.Context) {
 		seenBilling[id] = struct{}{}
 		billingUserGroupIDs = append(billingUserGroupIDs, id)
 	}
-	for _, id := range assignedUserGroupIDs {
+	for _, id := range p.assignedUserGroupIDs {
 		addBilling(id)
 	}
-	for _, id := range billedUserGroupIDs {
+	for _, id := range p.billedUserGroupIDs {
 		addBilling(id)
 	}
 	if defaultUserGroupID != nil {
 		addBilling(*defaultUserGroupID)
 	}
 
-	rules, errRules := h.loadBillingRules(ctx, authGroupIDValue, billingUserGroupIDs, defaultAuthGroupIDValue, defaultUserGroupIDValue)
+	rules, errRules := h.loadBillingRules(ctx, p.authGroupID, billingUserGroupIDs, p.defaultAuthGroupID, p.defaultUserGroupIDValue)
 	if errRules != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "query billing rules failed"})
-		return
-	}
-
-	onlyMapped := loadOnlyMapped()
-	available, errModels := h.loadAvailableModels(ctx, onlyMapped)
-	if errModels != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "load available models failed"})
-		return
+		return nil, "query billing rules failed", errRules
 	}
+	p.rules = rules
+	return p, "", nil
+}
 
-	perRequest := make([]modelPricingItem, 0)
-	perToken := make([]modelPricingItem, 0)
-	unpriced := make([]modelPricingItem, 0)
-
-	for _, item := range available {
-		provider := strings.TrimSpace(item.Provider)
-		modelID := strings.TrimSpace(item.ModelID)
-		if provider == "" || modelID == "" {
-			continue
+// billingUserGroup returns the group that bills the user for a model, or nil when the model's
+// mapping restricts it to groups the user is not in.
+func (p *pricingContext) billingUserGroup(provider, modelID string) *uint64 {
+	var billingUserGroupID *uint64
+	if allowed, okAllowed := modelmapping.LookupUserGroupIDs(provider, modelID); okAllowed && len(allowed.Clean()) > 0 {
+		hasGroup := false
+		for _, allowedID := range allowed.Values() {
+			if _, ok := p.userAccessGroups[allowedID]; ok {
+				hasGroup = true
+				break
+			}
+		}
+		if !hasGroup {
+			return nil
 		}
 
-		var billingUserGroupID *uint64
-		if allowed, okAllowed := modelmapping.LookupUserGroupIDs(provider, modelID); okAllowed && len(allowed.Clean()) > 0 {
-			hasGroup := false
-			for _, allowedID := range allowed.Values() {
-				if _, ok := userAccessGroups[allowedID]; ok {
-					hasGroup = true
-					break
-				}
-			}
-			if !hasGroup {
+		allowedSet := make(map[uint64]struct{}, len(allowed.Values()))
+		for _, allowedID := range allowed.Values() {
+			if allowedID == 0 {
 				continue
 			}
-
-			allowedSet := make(map[uint64]struct{}, len(allowed.Values()))
-			for _, allowedID := range allowed.Values() {
-				if allowedID == 0 {
-					continue
-				}
-				allowedSet[allowedID] = struct{}{}
+			allowedSet[allowedID] = struct{}{}
+		}
+		for _, candidate := range p.assignedUserGroupIDs {
+			if _, ok := allowedSet[candidate]; ok {
+				idCopy := candidate
+				billingUserGroupID = &idCopy
+				break
 			}
-			for _, candidate := range assignedUserGroupIDs {
+		}
+		if billingUserGroupID == nil {
+			for _, candidate := range p.billedUserGroupIDs {
 				if _, ok := allowedSet[candidate]; ok {
 					idCopy := candidate
 					billingUserGroupID = &idCopy
 					break
 				}
 			}
-			if billingUserGroupID == nil {
-				for _, candidate := range billedUserGroupIDs {
-					if _, ok := allowedSet[candidate]; ok {
-						idCopy := candidate
-						billingUserGroupID = &idCopy
-						break
-					}
-				}
-			}
-			if billingUserGroupID == nil && defaultUserGroupID != nil {
-				if _, ok := allowedSet[*defaultUserGroupID]; ok {
-					billingUserGroupID = defaultUserGroupID
-				}
-			}
-		} else {
-			billingUserGroupID = user.UserGroupID.Primary()
-			if billingUserGroupID == nil {
-				billingUserGroupID = user.BillUserGroupID.Primary()
-			}
-			if billingUserGroupID == nil {
-				billingUserGroupID = defaultUserGroupID
-			}
-		}
-
-		if billingUserGroupID == nil || *billingUserGroupID == 0 {
-			continue
-		}
-
-		rule := billing.SelectBillingRule(rules, authGroupIDValue, *billingUserGroupID, defaultAuthGroupIDValue, defaultUserGroupIDValue, provider, modelID)
-		result := modelPricingItem{
-			Provider:      provider,
-			Model:         modelID,
-			DisplayName:   item.DisplayName,
-			OriginalModel: item.OriginalModel,
 		}
-		if rule != nil {
-			result.BillingType = rule.BillingType
-			result.RuleID = rule.ID
-			result.PricePerRequest = rule.PricePerRequest
-			result.PriceInputToken = rule.PriceInputToken
-			result.PriceOutputToken = rule.PriceOutputToken
-			result.PriceCacheCreateToken = rule.PriceCacheCreateToken
-			result.PriceCacheReadToken = rule.PriceCacheReadToken
-		}
-
-		switch result.BillingType {
-		case models.BillingTypePerRequest:
-			perRequest = append(perRequest, result)
-		case models.BillingTypePerToken:
-			perToken = append(perToken, result)
-		default:
-			unpriced = append(unpriced, result)
+		if billingUserGroupID == nil && p.defaultUserGroupID != nil {
+			if _, ok := allowedSet[*p.defaultUserGroupID]; ok {
+				billingUserGroupID = p.defaultUserGroupID
+			}
 		}
+	} else {
+		billingUserGroupID = p.primaryUserGroupID
 	}
 
-	sortModelPricing(perRequest)
-	sortModelPricing(perToken)
-	sortModelPricing(unpriced)
+	if billingUserGroupID == nil || *billingUserGroupID == 0 {
+		return nil
+	}
+	return billingUserGroupID
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"per_request": perRequest,
-		"per_token":   perToken,
-		"unpriced":    unpriced,
-		"only_mapped": onlyMapped,
-	})
+// selectRule picks the billing rule pricing a model for the given user group.
+func (p *pricingContext) selectRule(userGroupID uint64, provider, modelID string) *models.BillingRule {
+	return billing.SelectBillingRule(p.rules, p.authGroupID, userGroupID, p.defaultAuthGroupID, p.defaultUserGroupIDValue, provider, modelID)
 }
 
 // loadBillingRules loads enabled billing rules for the given groups.
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/pquerna/otp/totp"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// passwordChangePasskeySessions stores in-flight passkey assertions for password changes.
+var passwordChangePasskeySessions = newSessionStore("user:password_change_passkey")
+
+// PasswordHandler handles password changes that require re-authentication.
+type PasswordHandler struct {
+	db     *gorm.DB
+	jwtCfg config.JWTConfig
+}
+
+// NewPasswordHandler constructs a PasswordHandler.
+func NewPasswordHandler(db *gorm.DB, jwtCfg config.JWTConfig) *PasswordHandler {
+	return &PasswordHandler{db: db, jwtCfg: jwtCfg}
+}
+
+// SessionRevoked reports whether a user token was issued before the user's sessions were revoked.
+func SessionRevoked(user models.User, claims *security.UserClaims) bool {
+	if user.SessionsRevokedAt == nil || claims == nil {
+		return false
+	}
+	if claims.IssuedAt == nil {
+		return true
+	}
+	// JWT timestamps have second precision.
+	return claims.IssuedAt.Time.Before(user.SessionsRevokedAt.Truncate(time.Second))
+}
+
+// passwordChangeRequest defines the request body for a verified password change.
+type passwordChangeRequest struct {
+	CurrentPassword  string          `json:"current_password"`  // Current password.
+	NewPassword      string          `json:"new_password"`      // New password.
+	TOTPCode         string          `json:"totp_code"`         // TOTP code when TOTP is enrolled.
+	PasskeyAssertion json.RawMessage `json:"passkey_assertion"` // Passkey assertion when a passkey is enrolled.
+}
+
+// PasskeyOptions starts a passkey assertion used to confirm a password change.
+func (h *PasswordHandler) PasskeyOptions(c *gin.Context) {
+	userID, ok := readUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	webAuthn, errWebAuthn := loadWebAuthn()
+	if errWebAuthn != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "passkey not configured"})
+		return
+	}
+
+	var user models.User
+	if errFind := h.db.WithContext(c.Request.Context()).First(&user, userID).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if len(user.PasskeyID) == 0 || len(user.PasskeyPublicKey) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "passkey not enabled"})
+		return
+	}
+
+	assertion, session, errBegin := webAuthn.BeginLogin(newUserWebAuthnUser(user), webauthn.WithUserVerification(protocol.VerificationPreferred))
+	if errBegin != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "begin passkey verification failed"})
+		return
+	}
+	passwordChangePasskeySessions.Set(fmt.Sprintf("%d", user.ID), *session)
+	c.JSON(http.StatusOK, assertion)
+}
+
+// Change verifies the current password and MFA factor, rotates the password and revokes other sessions.
+// A fresh token for the calling session is returned.
+func (h *PasswordHandler) Change(c *gin.Context) {
+	userID, ok := readUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var body passwordChangeRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	currentPassword := strings.TrimSpace(body.CurrentPassword)
+	newPassword := strings.TrimSpace(body.NewPassword)
+	if currentPassword == "" || newPassword == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing password"})
+		return
+	}
+	if len(newPassword) < 6 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "password must be at least 6 characters"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var user models.User
+	if errFind := h.db.WithContext(ctx).First(&user, userID).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	if !security.CheckPassword(user.Password, currentPassword) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "current password incorrect"})
+		return
+	}
+	if security.CheckPassword(user.Password, newPassword) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "new password must differ from current password"})
+		return
+	}
+
+	totpEnabled := strings.TrimSpace(user.TOTPSecret) != ""
+	passkeyEnabled := len(user.PasskeyID) > 0 && len(user.PasskeyPublicKey) > 0
+	if totpEnabled || passkeyEnabled {
+		verified := false
+		if code := strings.TrimSpace(body.TOTPCode); totpEnabled && code != "" {
+			if !totp.Validate(code, user.TOTPSecret) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid code"})
+				return
+			}
+			verified = true
+		}
+		if !verified && passkeyEnabled && len(body.PasskeyAssertion) > 0 {
+			if errPasskey := h.verifyPasskeyAssertion(user, body.PasskeyAssertion); errPasskey != nil {
+				log.WithError(errPasskey).WithField("user_id", user.ID).Warn("password change passkey verification failed")
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "passkey verification failed"})
+				return
+			}
+			verified = true
+		}
+		if !verified {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":           "mfa required",
+				"totp_enabled":    totpEnabled,
+				"passkey_enabled": passkeyEnabled,
+			})
+			return
+		}
+	}
+
+	hash, errHash := security.HashPassword(newPassword)
+	if errHash != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "hash password failed"})
+		return
+	}
+
+	now := time.Now().UTC()
+	if errUpdate := h.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", user.ID).
+		Updates(map[string]any{
+			"password":            hash,
+			"sessions_revoked_at": now,
+			"updated_at":          now,
+		}).Error; errUpdate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "change password failed"})
+		return
+	}
+
+	token, errToken := security.GenerateToken(h.jwtCfg.Secret, user.ID, user.Username, user.Name, user.Email, h.jwtCfg.Expiry)
+	if errToken != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "token": token})
+}
+
+// verifyPasskeyAssertion validates a passkey assertion against the pending password change session.
+func (h *PasswordHandler) verifyPasskeyAssertion(user models.User, rawAssertion json.RawMessage) error {
+	webAuthn, errWebAuthn := loadWebAuthn()
+	if errWebAuthn != nil {
+		return errWebAuthn
+	}
+	sessionKey := fmt.Sprintf("%d", user.ID)
+	session, ok := passwordChangePasskeySessions.Get(sessionKey)
+	if !ok {
+		return errors.New("passkey verification expired")
+	}
+	parsed, errParse := protocol.ParseCredentialRequestResponseBytes(rawAssertion)
+	if errParse != nil {
+		return errParse
+	}
+	credential, errValidate := webAuthn.ValidateLogin(newUserWebAuthnUser(user), session, parsed)
+	if errValidate != nil {
+		return errValidate
+	}
+	passwordChangePasskeySessions.Delete(sessionKey)
+
+	signCount := uint32(credential.Authenticator.SignCount)
+	return h.db.Model(&models.User{}).
+		Where("id = ?", user.ID).
+		Update("passkey_sign_count", signCount).Error
+}
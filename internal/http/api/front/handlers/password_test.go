@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pquerna/otp/totp"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
+	dbpkg "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
+)
+
+func TestPasswordChangeRequiresTOTPAndRevokesSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	conn, errOpen := dbpkg.Open(":memory:")
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := dbpkg.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+
+	hash, errHash := security.HashPassword("old-secret")
+	if errHash != nil {
+		t.Fatalf("hash password: %v", errHash)
+	}
+	key, errKey := totp.Generate(totp.GenerateOpts{Issuer: "test", AccountName: "pw-user"})
+	if errKey != nil {
+		t.Fatalf("generate totp: %v", errKey)
+	}
+	user := models.User{Username: "pw-user", Email: "pw-user@example.com", Password: hash, TOTPSecret: key.Secret()}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+
+	h := NewPasswordHandler(conn, config.JWTConfig{Secret: "test-secret", Expiry: time.Hour})
+	call := func(body map[string]any) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("userID", user.ID)
+		c.Request = httptest.NewRequest(http.MethodPost, "/v0/user/password", bytes.NewReader(payload))
+		c.Request.Header.Set("Content-Type", "application/json")
+		h.Change(c)
+		return w
+	}
+
+	if w := call(map[string]any{"current_password": "old-secret", "new_password": "new-secret"}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected mfa required, got %d body=%s", w.Code, w.Body.String())
+	}
+	if w := call(map[string]any{"current_password": "wrong", "new_password": "new-secret"}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected wrong password rejection, got %d", w.Code)
+	}
+
+	code, errCode := totp.GenerateCode(key.Secret(), time.Now())
+	if errCode != nil {
+		t.Fatalf("generate code: %v", errCode)
+	}
+	w := call(map[string]any{"current_password": "old-secret", "new_password": "new-secret", "totp_code": code})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	var updated models.User
+	if errFind := conn.First(&updated, user.ID).Error; errFind != nil {
+		t.Fatalf("load user: %v", errFind)
+	}
+	if !security.CheckPassword(updated.Password, "new-secret") {
+		t.Fatal("expected password to be rotated")
+	}
+	if updated.SessionsRevokedAt == nil {
+		t.Fatal("expected sessions to be revoked")
+	}
+
+	stale := &security.UserClaims{UserID: user.ID}
+	stale.IssuedAt = jwt.NewNumericDate(updated.SessionsRevokedAt.Add(-time.Minute))
+	if !SessionRevoked(updated, stale) {
+		t.Fatal("expected token issued before revocation to be rejected")
+	}
+	fresh := &security.UserClaims{UserID: user.ID}
+	fresh.IssuedAt = jwt.NewNumericDate(updated.SessionsRevokedAt.Add(time.Second))
+	if SessionRevoked(updated, fresh) {
+		t.Fatal("expected token issued after revocation to be accepted")
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -63,6 +65,7 @@ func (h *PrepaidCardFrontHandler) Redeem(c *gin.Context) {
 	}
 
 	var result gin.H
+	var redeemed models.PrepaidCard
 	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
 		var card models.PrepaidCard
 		if errFind := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
@@ -107,6 +110,7 @@ func (h *PrepaidCardFrontHandler) Redeem(c *gin.Context) {
 		card.RedeemedUserID = &userID
 		card.RedeemedAt = &now
 		card.ExpiresAt = expiresAt
+		redeemed = card
 		result = gin.H{
 			"id":          card.ID,
 			"name":        card.Name,
@@ -124,6 +128,7 @@ func (h *PrepaidCardFrontHandler) Redeem(c *gin.Context) {
 		return
 	}
 
+	events.PublishPrepaidRedeemed(c.Request.Context(), userID, redeemed.ID, redeemed.CardSN, redeemed.Amount)
 	c.JSON(http.StatusOK, gin.H{"card": result})
 }
 
@@ -138,7 +143,8 @@ func (h *PrepaidCardFrontHandler) GetCurrent(c *gin.Context) {
 	var card models.PrepaidCard
 	if errFind := h.db.WithContext(c.Request.Context()).
 		Where("redeemed_user_id = ?", userID).
-		Order("redeemed_at DESC NULLS LAST, created_at DESC").
+		Order(dbutil.NullsLastOrder(h.db, "redeemed_at", "DESC")).
+		Order("created_at DESC").
 		First(&card).Error; errFind != nil {
 		if errors.Is(errFind, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusOK, gin.H{"card": nil})
@@ -173,7 +179,8 @@ func (h *PrepaidCardFrontHandler) List(c *gin.Context) {
 	var cards []models.PrepaidCard
 	if errFind := h.db.WithContext(c.Request.Context()).
 		Where("redeemed_user_id = ?", userID).
-		Order("redeemed_at DESC NULLS LAST, created_at DESC").
+		Order(dbutil.NullsLastOrder(h.db, "redeemed_at", "DESC")).
+		Order("created_at DESC").
 		Find(&cards).Error; errFind != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "query cards failed"})
 		return
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/invitation"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/sociallogin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// socialStateCookie carries the signed state of a social login between redirect and callback.
+	socialStateCookie = "cpab_social_state"
+	// socialCookiePath scopes the state cookie to the social login endpoints.
+	socialCookiePath = "/v0/auth/oauth"
+)
+
+// SocialLogin starts a social login by redirecting to the provider.
+func (h *AuthHandler) SocialLogin(c *gin.Context) {
+	authURL, ok := h.startSocialLogin(c, 0)
+	if !ok {
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// LinkIdentity starts linking a provider account to the signed-in user. The response carries
+// the authorization URL for the browser to open; the callback then links instead of signing in.
+func (h *AuthHandler) LinkIdentity(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	authURL, ok := h.startSocialLogin(c, userID)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"url": authURL})
+}
+
+// startSocialLogin sets the state cookie and returns the provider authorization URL; it
+// answers the request itself when the provider is unavailable.
+func (h *AuthHandler) startSocialLogin(c *gin.Context, linkUserID uint64) (string, bool) {
+	name := strings.ToLower(strings.TrimSpace(c.Param("provider")))
+	providerCfg, ok := sociallogin.LoadConfig().Provider(name)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "login provider is not enabled"})
+		return "", false
+	}
+	client, errClient := sociallogin.NewClient(name, providerCfg, nil)
+	if errClient != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "login provider is not enabled"})
+		return "", false
+	}
+	state, errState := sociallogin.RandomToken()
+	verifier, errVerifier := sociallogin.RandomToken()
+	if errState != nil || errVerifier != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "start social login failed"})
+		return "", false
+	}
+	stateToken, errToken := security.GenerateSocialStateToken(h.jwtCfg.Secret, name, state, verifier, linkUserID)
+	if errToken != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "start social login failed"})
+		return "", false
+	}
+	setSocialStateCookie(c, stateToken, int(security.SocialStateExpiry/time.Second))
+	return client.AuthCodeURL(state, verifier), true
+}
+
+// SocialCallback completes a social login or provider link. The redirect URL registered at
+// the provider forwards its code and state query parameters here; a sign-in answers like the
+// password login.
+func (h *AuthHandler) SocialCallback(c *gin.Context) {
+	ctx := c.Request.Context()
+	name := strings.ToLower(strings.TrimSpace(c.Param("provider")))
+	providerCfg, ok := sociallogin.LoadConfig().Provider(name)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "login provider is not enabled"})
+		return
+	}
+
+	stateCookie, _ := c.Cookie(socialStateCookie)
+	setSocialStateCookie(c, "", -1)
+	if providerError := strings.TrimSpace(c.Query("error")); providerError != "" {
+		events.PublishLoginFailed(ctx, "user", "", c.ClientIP(), name+" error: "+providerError)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "social login failed"})
+		return
+	}
+	code := strings.TrimSpace(c.Query("code"))
+	state := strings.TrimSpace(c.Query("state"))
+	if code == "" || state == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing code or state"})
+		return
+	}
+	stateClaims, errState := security.ParseSocialStateToken(h.jwtCfg.Secret, stateCookie)
+	if errState != nil || stateClaims.Provider != name || subtle.ConstantTimeCompare([]byte(stateClaims.State), []byte(state)) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid social login state"})
+		return
+	}
+
+	client, errClient := sociallogin.NewClient(name, providerCfg, nil)
+	if errClient != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "login provider is not enabled"})
+		return
+	}
+	accessToken, errExchange := client.Exchange(ctx, code, stateClaims.Verifier)
+	if errExchange != nil {
+		log.WithError(errExchange).Warn("social login: code exchange failed")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "social login token exchange failed"})
+		return
+	}
+	profile, errProfile := client.Profile(ctx, accessToken)
+	if errProfile != nil {
+		log.WithError(errProfile).Warn("social login: profile lookup failed")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "social login profile lookup failed"})
+		return
+	}
+	now := time.Now().UTC()
+
+	if stateClaims.LinkUserID != 0 {
+		if errLink := sociallogin.Link(ctx, h.db, stateClaims.LinkUserID, profile, now); errLink != nil {
+			respondSocialError(c, errLink)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"linked": true, "provider": name})
+		return
+	}
+
+	signInCfg := sociallogin.LoadConfig()
+	// Invite-only deployments register new users through invitation codes only.
+	if invitation.InviteOnly() {
+		signInCfg.Registration = sociallogin.RegistrationClosed
+	}
+	user, created, errSignIn := sociallogin.SignIn(ctx, h.db, signInCfg, profile, now)
+	if errSignIn != nil {
+		events.PublishLoginFailed(ctx, "user", profile.Email, c.ClientIP(), name+": "+errSignIn.Error())
+		respondSocialError(c, errSignIn)
+		return
+	}
+	if user.Disabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "user disabled"})
+		return
+	}
+	// Users with MFA finish signing in through the TOTP or passkey login, as after a password.
+	if strings.TrimSpace(user.TOTPSecret) != "" || len(user.PasskeyID) > 0 || len(user.PasskeyPublicKey) > 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "mfa required", "mfa_required": true, "username": user.Username})
+		return
+	}
+	extra, allowed := h.enforceMFAPolicy(c, user)
+	if !allowed {
+		return
+	}
+	h.respondWithUserToken(c, user, extra, gin.H{"provider": name, "registered": created})
+}
+
+func respondSocialError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sociallogin.ErrEmailUnverified), errors.Is(err, sociallogin.ErrRegistrationClosed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, sociallogin.ErrAccountUnverified), errors.Is(err, sociallogin.ErrIdentityTaken),
+		errors.Is(err, sociallogin.ErrAlreadyLinked):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.WithError(err).Warn("social login: account lookup failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "social login failed"})
+	}
+}
+
+// ListIdentities returns the signed-in user's linked provider accounts and the providers
+// available for linking.
+func (h *AuthHandler) ListIdentities(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var rows []models.UserIdentity
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Where("user_id = ?", userID).
+		Order("provider ASC").
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, gin.H{
+			"provider":   row.Provider,
+			"login":      row.Login,
+			"email":      row.Email,
+			"created_at": row.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"identities": out, "providers": sociallogin.LoadConfig().Enabled()})
+}
+
+// UnlinkIdentity removes the signed-in user's account link at a provider.
+func (h *AuthHandler) UnlinkIdentity(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	provider := strings.ToLower(strings.TrimSpace(c.Param("provider")))
+	if errUnlink := sociallogin.Unlink(c.Request.Context(), h.db, userID, provider); errUnlink != nil {
+		if errors.Is(errUnlink, sociallogin.ErrNotLinked) {
+			c.JSON(http.StatusNotFound, gin.H{"error": errUnlink.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "unlink failed"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func setSocialStateCookie(c *gin.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(socialStateCookie, value, maxAge, socialCookiePath, "", c.Request.TLS != nil, true)
+}
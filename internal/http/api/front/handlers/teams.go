@@ -0,0 +1,440 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/team"
+	"gorm.io/gorm"
+)
+
+// TeamFrontHandler lets users share API keys and a budget with a team.
+type TeamFrontHandler struct {
+	db *gorm.DB
+}
+
+// NewTeamFrontHandler constructs a TeamFrontHandler.
+func NewTeamFrontHandler(db *gorm.DB) *TeamFrontHandler {
+	return &TeamFrontHandler{db: db}
+}
+
+// createTeamRequest defines the request body for creating a team.
+type createTeamRequest struct {
+	Name string `json:"name"`
+}
+
+// inviteTeamMemberRequest defines the request body for inviting a user.
+type inviteTeamMemberRequest struct {
+	Login string `json:"login"` // Username or email of the invited user.
+}
+
+// createTeamAPIKeyRequest defines the request body for issuing a team key.
+type createTeamAPIKeyRequest struct {
+	Name          string `json:"name"`
+	ExpiresInDays *int   `json:"expires_in_days"`
+}
+
+// Create makes a team owned by the current user.
+func (h *TeamFrontHandler) Create(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var body createTeamRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	if strings.TrimSpace(body.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing name"})
+		return
+	}
+	created, errCreate := team.Create(c.Request.Context(), h.db, userID, body.Name, time.Now().UTC())
+	if errCreate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create team failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, h.formatTeam(created, userID))
+}
+
+// List returns the teams the current user belongs to and their pending invitations.
+func (h *TeamFrontHandler) List(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var memberships []models.TeamMember
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&memberships).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list teams failed"})
+		return
+	}
+	teamIDs := make([]uint64, 0, len(memberships))
+	for _, membership := range memberships {
+		teamIDs = append(teamIDs, membership.TeamID)
+	}
+	teamsByID := make(map[uint64]models.Team, len(teamIDs))
+	if len(teamIDs) > 0 {
+		var rows []models.Team
+		if errFind := h.db.WithContext(c.Request.Context()).Where("id IN ?", teamIDs).Find(&rows).Error; errFind != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "list teams failed"})
+			return
+		}
+		for _, row := range rows {
+			teamsByID[row.ID] = row
+		}
+	}
+	teams := make([]gin.H, 0, len(memberships))
+	invitations := make([]gin.H, 0)
+	for _, membership := range memberships {
+		row, ok := teamsByID[membership.TeamID]
+		if !ok {
+			continue
+		}
+		if membership.JoinedAt == nil {
+			invitations = append(invitations, gin.H{"team_id": row.ID, "name": row.Name, "invited_at": membership.CreatedAt})
+			continue
+		}
+		teams = append(teams, h.formatTeam(&row, userID))
+	}
+	c.JSON(http.StatusOK, gin.H{"teams": teams, "invitations": invitations})
+}
+
+// Get returns a team with its members and invitations.
+func (h *TeamFrontHandler) Get(c *gin.Context) {
+	userID, teamID, ok := h.params(c)
+	if !ok {
+		return
+	}
+	row, _, errLoad := team.Load(c.Request.Context(), h.db, teamID, userID)
+	if !h.handleError(c, errLoad, "load team failed") {
+		return
+	}
+	var members []models.TeamMember
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Preload("User").
+		Where("team_id = ?", teamID).
+		Order("created_at ASC").
+		Find(&members).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "load team failed"})
+		return
+	}
+	out := h.formatTeam(row, userID)
+	memberRows := make([]gin.H, 0, len(members))
+	for i := range members {
+		memberRows = append(memberRows, formatTeamMember(&members[i]))
+	}
+	out["members"] = memberRows
+	c.JSON(http.StatusOK, out)
+}
+
+// Delete revokes the team's keys and removes the team. Owner only.
+func (h *TeamFrontHandler) Delete(c *gin.Context) {
+	userID, teamID, ok := h.params(c)
+	if !ok {
+		return
+	}
+	errDelete := team.Delete(c.Request.Context(), h.db, teamID, userID, time.Now().UTC())
+	if !h.handleError(c, errDelete, "delete team failed") {
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Invite invites a user by username or email. Owner only.
+func (h *TeamFrontHandler) Invite(c *gin.Context) {
+	userID, teamID, ok := h.params(c)
+	if !ok {
+		return
+	}
+	var body inviteTeamMemberRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	member, errInvite := team.Invite(c.Request.Context(), h.db, teamID, userID, body.Login, time.Now().UTC())
+	if !h.handleError(c, errInvite, "invite failed") {
+		return
+	}
+	c.JSON(http.StatusCreated, formatTeamMember(member))
+}
+
+// Accept joins a team the current user was invited to.
+func (h *TeamFrontHandler) Accept(c *gin.Context) {
+	userID, teamID, ok := h.params(c)
+	if !ok {
+		return
+	}
+	errAccept := team.Accept(c.Request.Context(), h.db, teamID, userID, time.Now().UTC())
+	if !h.handleError(c, errAccept, "accept failed") {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// RemoveMember removes a member, or lets the current user leave or decline an invitation.
+// The removed member's team keys are revoked.
+func (h *TeamFrontHandler) RemoveMember(c *gin.Context) {
+	userID, teamID, ok := h.params(c)
+	if !ok {
+		return
+	}
+	memberID, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("user_id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	errRemove := team.RemoveMember(c.Request.Context(), h.db, teamID, userID, memberID, time.Now().UTC())
+	if !h.handleError(c, errRemove, "remove member failed") {
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListAPIKeys returns the team's keys with the member who issued each.
+func (h *TeamFrontHandler) ListAPIKeys(c *gin.Context) {
+	userID, teamID, ok := h.params(c)
+	if !ok {
+		return
+	}
+	_, _, errLoad := team.Load(c.Request.Context(), h.db, teamID, userID)
+	if !h.handleError(c, errLoad, "list api keys failed") {
+		return
+	}
+	var rows []models.APIKey
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Preload("User").
+		Where("team_id = ?", teamID).
+		Order("created_at DESC").
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list api keys failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatTeamAPIKey(&rows[i], userID))
+	}
+	c.JSON(http.StatusOK, gin.H{"api_keys": out})
+}
+
+// CreateAPIKey issues a team key owned by the current member and charged to the team pool.
+func (h *TeamFrontHandler) CreateAPIKey(c *gin.Context) {
+	userID, teamID, ok := h.params(c)
+	if !ok {
+		return
+	}
+	var body createTeamAPIKeyRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	name := strings.TrimSpace(body.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing name"})
+		return
+	}
+	_, _, errLoad := team.Load(c.Request.Context(), h.db, teamID, userID)
+	if !h.handleError(c, errLoad, "create api key failed") {
+		return
+	}
+	token, errGenerate := security.GenerateAPIKey()
+	if errGenerate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "generate api key failed"})
+		return
+	}
+	now := time.Now().UTC()
+	expiresAt, _ := resolveExpiry(now, nil, body.ExpiresInDays)
+	row := models.APIKey{
+		UserID:    &userID,
+		TeamID:    &teamID,
+		Name:      name,
+		APIKey:    token,
+		Active:    true,
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if errCreate := h.db.WithContext(c.Request.Context()).Create(&row).Error; errCreate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create api key failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"id":         row.ID,
+		"name":       row.Name,
+		"token":      token,
+		"team_id":    teamID,
+		"expires_at": row.ExpiresAt,
+	})
+}
+
+// RevokeAPIKey revokes a team key. Members may revoke their own keys; the owner any.
+func (h *TeamFrontHandler) RevokeAPIKey(c *gin.Context) {
+	userID, teamID, ok := h.params(c)
+	if !ok {
+		return
+	}
+	keyID, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("key_id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid key id"})
+		return
+	}
+	row, _, errLoad := team.Load(c.Request.Context(), h.db, teamID, userID)
+	if !h.handleError(c, errLoad, "revoke failed") {
+		return
+	}
+	q := h.db.WithContext(c.Request.Context()).Model(&models.APIKey{}).Where("id = ? AND team_id = ?", keyID, teamID)
+	if row.OwnerUserID != userID {
+		q = q.Where("user_id = ?", userID)
+	}
+	now := time.Now().UTC()
+	res := q.Updates(map[string]any{"active": false, "revoked_at": now, "updated_at": now})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "revoke failed"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// Balance returns the team pool: the owner's bills and prepaid cards.
+func (h *TeamFrontHandler) Balance(c *gin.Context) {
+	userID, teamID, ok := h.params(c)
+	if !ok {
+		return
+	}
+	row, _, errLoad := team.Load(c.Request.Context(), h.db, teamID, userID)
+	if !h.handleError(c, errLoad, "load balance overview failed") {
+		return
+	}
+	overview, errOverview := billing.LoadBalanceOverview(c.Request.Context(), h.db, row.OwnerUserID, time.Now())
+	if errOverview != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "load balance overview failed"})
+		return
+	}
+	c.JSON(http.StatusOK, overview)
+}
+
+// Usage returns team key usage per member over the last days (default 30, max 365).
+func (h *TeamFrontHandler) Usage(c *gin.Context) {
+	userID, teamID, ok := h.params(c)
+	if !ok {
+		return
+	}
+	days := 30
+	if raw := strings.TrimSpace(c.Query("days")); raw != "" {
+		parsed, errParse := strconv.Atoi(raw)
+		if errParse != nil || parsed < 1 || parsed > 365 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid days"})
+			return
+		}
+		days = parsed
+	}
+	_, _, errLoad := team.Load(c.Request.Context(), h.db, teamID, userID)
+	if !h.handleError(c, errLoad, "load usage failed") {
+		return
+	}
+	since := time.Now().UTC().AddDate(0, 0, -days)
+	members, errUsage := team.UsageByMember(c.Request.Context(), h.db, teamID, since)
+	if errUsage != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "load usage failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"days": days, "since": since, "members": members})
+}
+
+// params reads the current user and the team id path parameter, answering on failure.
+func (h *TeamFrontHandler) params(c *gin.Context) (uint64, uint64, bool) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return 0, 0, false
+	}
+	teamID, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, 0, false
+	}
+	return userID, teamID, true
+}
+
+// handleError answers team errors and returns true when err is nil.
+func (h *TeamFrontHandler) handleError(c *gin.Context, err error, fallback string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, team.ErrNotMember), errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "team not found"})
+	case errors.Is(err, team.ErrNotOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, team.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	case errors.Is(err, team.ErrAlreadyInvited), errors.Is(err, team.ErrNotInvited), errors.Is(err, team.ErrOwnerCannotLeave):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+	return false
+}
+
+// formatTeam renders a team from the point of view of userID.
+func (h *TeamFrontHandler) formatTeam(row *models.Team, userID uint64) gin.H {
+	return gin.H{
+		"id":            row.ID,
+		"name":          row.Name,
+		"owner_user_id": row.OwnerUserID,
+		"is_owner":      row.OwnerUserID == userID,
+		"created_at":    row.CreatedAt,
+	}
+}
+
+// formatTeamMember renders a membership or pending invitation.
+func formatTeamMember(member *models.TeamMember) gin.H {
+	return gin.H{
+		"user_id":    member.UserID,
+		"username":   member.User.Username,
+		"role":       member.Role,
+		"pending":    member.JoinedAt == nil,
+		"joined_at":  member.JoinedAt,
+		"invited_at": member.CreatedAt,
+	}
+}
+
+// formatTeamAPIKey renders a team key; the token is only shown to the member who issued it.
+func formatTeamAPIKey(row *models.APIKey, userID uint64) gin.H {
+	prefix := ""
+	if len(row.APIKey) >= 8 {
+		prefix = row.APIKey[:8] + "········" + row.APIKey[len(row.APIKey)-4:]
+	}
+	out := gin.H{
+		"id":           row.ID,
+		"name":         row.Name,
+		"key_prefix":   prefix,
+		"status":       row.Status(),
+		"expires_at":   row.ExpiresAt,
+		"revoked_at":   row.RevokedAt,
+		"last_used_at": row.LastUsedAt,
+		"created_at":   row.CreatedAt,
+	}
+	if row.User != nil {
+		out["user_id"] = row.User.ID
+		out["username"] = row.User.Username
+	}
+	if row.UserID != nil && *row.UserID == userID {
+		out["key"] = row.APIKey
+	}
+	return out
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/tierupgrade"
+	"gorm.io/gorm"
+)
+
+// TierUpgradeFrontHandler lets users review and confirm proposed tier upgrades.
+type TierUpgradeFrontHandler struct {
+	db *gorm.DB
+}
+
+// NewTierUpgradeFrontHandler constructs a TierUpgradeFrontHandler.
+func NewTierUpgradeFrontHandler(db *gorm.DB) *TierUpgradeFrontHandler {
+	return &TierUpgradeFrontHandler{db: db}
+}
+
+// List returns the current user's pending tier upgrades with target group names.
+func (h *TierUpgradeFrontHandler) List(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var rows []models.TierUpgrade
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Where("user_id = ? AND status = ?", userID, models.TierUpgradeStatusPending).
+		Order("created_at DESC").
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list upgrades failed"})
+		return
+	}
+
+	groupNames := map[uint64]string{}
+	if len(rows) > 0 {
+		ids := make([]uint64, 0, len(rows)*2)
+		for _, row := range rows {
+			ids = append(ids, row.FromUserGroupID, row.ToUserGroupID)
+		}
+		var groups []models.UserGroup
+		if errGroups := h.db.WithContext(c.Request.Context()).
+			Select("id", "name").
+			Where("id IN ?", ids).
+			Find(&groups).Error; errGroups == nil {
+			for _, group := range groups {
+				groupNames[group.ID] = group.Name
+			}
+		}
+	}
+
+	out := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, gin.H{
+			"id":                   row.ID,
+			"from_user_group_id":   row.FromUserGroupID,
+			"from_user_group_name": groupNames[row.FromUserGroupID],
+			"to_user_group_id":     row.ToUserGroupID,
+			"to_user_group_name":   groupNames[row.ToUserGroupID],
+			"usage_amount":         row.UsageAmount,
+			"created_at":           row.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"upgrades": out})
+}
+
+// Accept moves the current user into the proposed user group.
+func (h *TierUpgradeFrontHandler) Accept(c *gin.Context) {
+	h.decide(c, true)
+}
+
+// Decline rejects the proposed tier upgrade.
+func (h *TierUpgradeFrontHandler) Decline(c *gin.Context) {
+	h.decide(c, false)
+}
+
+func (h *TierUpgradeFrontHandler) decide(c *gin.Context, accept bool) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var errDecide error
+	if accept {
+		errDecide = tierupgrade.Apply(c.Request.Context(), h.db, id, userID)
+	} else {
+		errDecide = tierupgrade.Decline(c.Request.Context(), h.db, id, userID)
+	}
+	switch {
+	case errDecide == nil:
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	case errors.Is(errDecide, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(errDecide, tierupgrade.ErrUpgradeNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": "upgrade is not pending"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+	}
+}
@@ -0,0 +1,91 @@
+package http
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/access"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/ratelimit"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// APIKeyRateLimitMiddleware enforces the per-minute request and token budgets configured on
+// API keys and user groups, and reports the tightest budgets through X-RateLimit-* headers.
+// Token budgets are charged once usage is recorded, so they admit requests while positive.
+func APIKeyRateLimitMiddleware(db *gorm.DB, manager *ratelimit.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c == nil || c.Request == nil || c.Request.URL == nil {
+			if c != nil {
+				c.Next()
+			}
+			return
+		}
+		if db == nil || manager == nil || !access.RequiresAPIKey(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+		token := access.ExtractAPIKey(c.Request)
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		var apiKey models.APIKey
+		if errFind := db.WithContext(c.Request.Context()).
+			Select("id", "user_id", "rpm_limit", "tpm_limit").
+			Where("api_key = ? AND active = ? AND revoked_at IS NULL", token, true).
+			First(&apiKey).Error; errFind != nil {
+			// Unknown keys are rejected by the access provider.
+			c.Next()
+			return
+		}
+		limits, errLimits := ratelimit.ResolveKeyLimits(c.Request.Context(), db, &apiKey)
+		if errLimits != nil {
+			log.WithError(errLimits).Warn("rate limit: resolve api key limits failed")
+			c.Next()
+			return
+		}
+		if !limits.Enabled() {
+			c.Next()
+			return
+		}
+		check, errCheck := manager.CheckKey(c.Request.Context(), limits)
+		if errCheck != nil {
+			log.WithError(errCheck).Warn("rate limit: api key check failed")
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		writeRateLimitHeaders(header, "Requests", check.Requests)
+		writeRateLimitHeaders(header, "Tokens", check.Tokens)
+		if !check.Allowed {
+			header.Set("Retry-After", strconv.Itoa(ceilSeconds(check.RetryAfter())))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// writeRateLimitHeaders sets X-RateLimit-{Limit,Remaining,Reset}-<kind> for one budget.
+func writeRateLimitHeaders(header http.Header, kind string, result *ratelimit.BucketResult) {
+	if result == nil {
+		return
+	}
+	header.Set("X-RateLimit-Limit-"+kind, strconv.Itoa(result.Limit))
+	header.Set("X-RateLimit-Remaining-"+kind, strconv.Itoa(result.Remaining))
+	header.Set("X-RateLimit-Reset-"+kind, strconv.Itoa(ceilSeconds(result.Reset))+"s")
+}
+
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,62 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/access"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/ratelimit"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// ConcurrencyLimitMiddleware caps how many proxy requests a user may have in flight at once.
+// The user's max_concurrent_requests applies first, then that of the user's primary group.
+func ConcurrencyLimitMiddleware(db *gorm.DB, inFlight *ratelimit.InFlight) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c == nil || c.Request == nil || c.Request.URL == nil {
+			if c != nil {
+				c.Next()
+			}
+			return
+		}
+		if db == nil || inFlight == nil || !access.RequiresAPIKey(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+		token := access.ExtractAPIKey(c.Request)
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		var apiKey models.APIKey
+		if errFind := db.WithContext(c.Request.Context()).
+			Select("id", "user_id").
+			Where("api_key = ? AND active = ? AND revoked_at IS NULL", token, true).
+			First(&apiKey).Error; errFind != nil || apiKey.UserID == nil || *apiKey.UserID == 0 {
+			// Unknown keys are rejected by the access provider; admin keys have no owner.
+			c.Next()
+			return
+		}
+		limit, errLimit := ratelimit.ResolveMaxConcurrent(c.Request.Context(), db, *apiKey.UserID)
+		if errLimit != nil {
+			log.WithError(errLimit).Warn("concurrency limit: resolve user limit failed")
+			c.Next()
+			return
+		}
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+		release, ok := inFlight.Acquire(fmt.Sprintf("u:%d", *apiKey.UserID), limit)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many concurrent requests"})
+			return
+		}
+		defer release()
+		c.Next()
+	}
+}
@@ -0,0 +1,152 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/access"
+	internalauth "github.com/router-for-me/CLIProxyAPIBusiness/internal/auth"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// DebugUpstreamModelHeader forces the upstream model name, bypassing model mapping aliases.
+	DebugUpstreamModelHeader = "X-CPAB-Debug-Upstream-Model"
+	// DebugAuthHeader forces the request onto the auth with the given key.
+	DebugAuthHeader = "X-CPAB-Debug-Auth"
+)
+
+// DebugRouteMiddleware lets admin API keys pin a proxy request to an explicit upstream model and credential.
+// Requests carrying the debug headers with a non-admin key are rejected; every override is logged.
+func DebugRouteMiddleware(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c == nil || c.Request == nil || c.Request.URL == nil {
+			if c != nil {
+				c.Next()
+			}
+			return
+		}
+
+		upstreamModel := strings.TrimSpace(c.GetHeader(DebugUpstreamModelHeader))
+		authKey := strings.TrimSpace(c.GetHeader(DebugAuthHeader))
+		if upstreamModel == "" && authKey == "" {
+			c.Next()
+			return
+		}
+		// Never forward the debug headers upstream.
+		c.Request.Header.Del(DebugUpstreamModelHeader)
+		c.Request.Header.Del(DebugAuthHeader)
+
+		apiKey, okAdmin := loadAdminAPIKey(c, db)
+		if !okAdmin {
+			log.Warnf("debug route: rejected override from non-admin key (path=%s)", c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "debug routing requires an admin api key"})
+			return
+		}
+
+		if upstreamModel != "" {
+			if errRewrite := rewriteRequestModel(c, upstreamModel); errRewrite != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": errRewrite.Error()})
+				return
+			}
+		}
+
+		log.WithFields(log.Fields{
+			"api_key_id":     apiKey.ID,
+			"path":           c.Request.URL.Path,
+			"upstream_model": upstreamModel,
+			"auth_key":       authKey,
+		}).Warn("debug route: model mapping bypassed")
+		internalauth.SetDebugRoute(c, internalauth.DebugRoute{Model: upstreamModel, AuthKey: authKey})
+		c.Next()
+	}
+}
+
+// loadAdminAPIKey resolves the request API key and reports whether it is an active admin key.
+func loadAdminAPIKey(c *gin.Context, db *gorm.DB) (*models.APIKey, bool) {
+	if db == nil {
+		return nil, false
+	}
+	token := access.ExtractAPIKey(c.Request)
+	if token == "" {
+		return nil, false
+	}
+	var apiKey models.APIKey
+	if errFind := db.WithContext(c.Request.Context()).
+		Where("api_key = ? AND active = ? AND revoked_at IS NULL", token, true).
+		First(&apiKey).Error; errFind != nil {
+		return nil, false
+	}
+	if !apiKey.IsAdmin {
+		return nil, false
+	}
+	return &apiKey, true
+}
+
+// rewriteRequestModel replaces the model of a proxy request: the model segment of a Gemini
+// style path (/v1beta/models/<model>:<action>) or the "model" field of a JSON body.
+func rewriteRequestModel(c *gin.Context, model string) error {
+	if rewritePathModel(c, model) {
+		return nil
+	}
+	r := c.Request
+	if r.Body == nil {
+		return errors.New("debug route: request body required to override model")
+	}
+	raw, errRead := io.ReadAll(r.Body)
+	_ = r.Body.Close()
+	if errRead != nil {
+		return errors.New("debug route: read request body failed")
+	}
+	var body map[string]json.RawMessage
+	if errUnmarshal := json.Unmarshal(raw, &body); errUnmarshal != nil || body == nil {
+		return errors.New("debug route: model override requires a JSON request body")
+	}
+	encodedModel, errMarshal := json.Marshal(model)
+	if errMarshal != nil {
+		return errors.New("debug route: encode model failed")
+	}
+	body["model"] = encodedModel
+	rewritten, errMarshal := json.Marshal(body)
+	if errMarshal != nil {
+		return errors.New("debug route: encode request body failed")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(rewritten))
+	r.ContentLength = int64(len(rewritten))
+	return nil
+}
+
+// rewritePathModel replaces the model segment of a Gemini style path, and of the route
+// parameter the handler reads it from. It reports false for paths without a model segment.
+func rewritePathModel(c *gin.Context, model string) bool {
+	const marker = "/models/"
+	path := c.Request.URL.Path
+	idx := strings.Index(path, marker)
+	if idx < 0 || !strings.HasPrefix(path, "/v1beta") {
+		return false
+	}
+	segment := path[idx+len(marker):]
+	current, action, hasAction := strings.Cut(segment, ":")
+	if strings.TrimSpace(current) == "" || strings.Contains(current, "/") {
+		return false
+	}
+	rewritten := model
+	if hasAction {
+		rewritten += ":" + action
+	}
+	c.Request.URL.Path = path[:idx+len(marker)] + rewritten
+	c.Request.URL.RawPath = ""
+	for i := range c.Params {
+		if strings.TrimPrefix(c.Params[i].Value, "/") == segment {
+			c.Params[i].Value = strings.TrimSuffix(c.Params[i].Value, segment) + rewritten
+		}
+	}
+	return true
+}
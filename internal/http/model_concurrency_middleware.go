@@ -0,0 +1,50 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/access"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/ratelimit"
+)
+
+// ModelConcurrencyMiddleware caps in-flight proxy requests per model as configured in
+// MODEL_CONCURRENCY. Requests beyond a cap wait in the model's FIFO queue; a full queue or
+// an exceeded wait is answered with 429.
+func ModelConcurrencyMiddleware(gate *ratelimit.ModelGate) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c == nil || c.Request == nil || c.Request.URL == nil {
+			if c != nil {
+				c.Next()
+			}
+			return
+		}
+		if gate == nil || !access.RequiresAPIKey(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+		limits := ratelimit.LoadModelLimits()
+		if len(limits) == 0 {
+			c.Next()
+			return
+		}
+		key, limit, ok := limits.Lookup(access.RequestModel(c.Request))
+		if !ok {
+			c.Next()
+			return
+		}
+		release, errAcquire := gate.Acquire(c.Request.Context(), key, limit)
+		switch {
+		case errors.Is(errAcquire, ratelimit.ErrModelQueueFull), errors.Is(errAcquire, ratelimit.ErrModelQueueTimeout):
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": errAcquire.Error()})
+			return
+		case errAcquire != nil:
+			// The client went away while queued.
+			c.Abort()
+			return
+		}
+		defer release()
+		c.Next()
+	}
+}
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modeldisplay"
+)
+
+// withModelDisplay returns copies of registry model maps with display metadata merged in.
+func withModelDisplay(data []map[string]any, displays map[string]modeldisplay.Entry, handlerType string) []map[string]any {
+	if len(displays) == 0 || len(data) == 0 {
+		return data
+	}
+	out := make([]map[string]any, 0, len(data))
+	for _, model := range data {
+		item := make(map[string]any, len(model)+4)
+		for k, v := range model {
+			item[k] = v
+		}
+		applyModelDisplay(item, displays, handlerType)
+		out = append(out, item)
+	}
+	return out
+}
+
+// applyModelDisplay merges admin display metadata into a model list item using the
+// field names of the handler type's wire format.
+func applyModelDisplay(item map[string]any, displays map[string]modeldisplay.Entry, handlerType string) {
+	if item == nil || len(displays) == 0 {
+		return
+	}
+	var id string
+	if handlerType == "gemini" {
+		name, _ := item["name"].(string)
+		id = strings.TrimPrefix(strings.TrimSpace(name), "models/")
+	} else {
+		id, _ = item["id"].(string)
+	}
+	entry, ok := modeldisplay.Lookup(displays, id)
+	if !ok {
+		return
+	}
+
+	switch handlerType {
+	case "gemini":
+		item["displayName"] = entry.Label()
+		if entry.Description != "" {
+			item["description"] = entry.Description
+		}
+		if entry.ContextWindow > 0 {
+			item["inputTokenLimit"] = entry.ContextWindow
+		}
+	case "claude":
+		item["display_name"] = entry.Label()
+	default:
+		item["display_name"] = entry.Label()
+		if entry.Category != "" {
+			item["category"] = entry.Category
+		}
+		if entry.Description != "" {
+			item["description"] = entry.Description
+		}
+		if entry.ContextWindow > 0 {
+			item["context_length"] = entry.ContextWindow
+		}
+		if len(entry.Capabilities) > 0 {
+			item["capabilities"] = entry.Capabilities
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	sdkcliproxy "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modeldisplay"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelmapping"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelregistry"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
@@ -38,6 +39,7 @@ func CLIProxyModelsMiddleware(db *gorm.DB, store *modelregistry.Store) gin.Handl
 		switch path {
 		case "/v1/models":
 			onlyMapped := dbConfigBool("ONLY_MAPPED_MODELS")
+			displays := modeldisplay.Load(c.Request.Context(), db)
 			userGroups, billUserGroups, okUser := loadUserGroupMembership(c, db)
 			userAgent := c.GetHeader("User-Agent")
 			if strings.HasPrefix(userAgent, "claude-cli") {
@@ -46,6 +48,7 @@ func CLIProxyModelsMiddleware(db *gorm.DB, store *modelregistry.Store) gin.Handl
 					if okUser {
 						data = filterOpenAIRegistryModelsByUserGroups(data, "claude", userGroups, billUserGroups)
 					}
+					data = withModelDisplay(data, displays, "claude")
 					c.AbortWithStatusJSON(http.StatusOK, gin.H{"data": data})
 					return
 				}
@@ -64,6 +67,7 @@ func CLIProxyModelsMiddleware(db *gorm.DB, store *modelregistry.Store) gin.Handl
 				for _, info := range modelInfos {
 					m := convertModelToMap(info, "claude")
 					if m != nil {
+						applyModelDisplay(m, displays, "claude")
 						data = append(data, m)
 					}
 				}
@@ -94,6 +98,7 @@ func CLIProxyModelsMiddleware(db *gorm.DB, store *modelregistry.Store) gin.Handl
 					if ownedBy, exists := model["owned_by"]; exists {
 						filteredModel["owned_by"] = ownedBy
 					}
+					applyModelDisplay(filteredModel, displays, "openai")
 					filtered = append(filtered, filteredModel)
 				}
 				c.AbortWithStatusJSON(http.StatusOK, gin.H{"object": "list", "data": filtered})
@@ -123,6 +128,7 @@ func CLIProxyModelsMiddleware(db *gorm.DB, store *modelregistry.Store) gin.Handl
 				if info.Created > 0 {
 					item["created"] = info.Created
 				}
+				applyModelDisplay(item, displays, "openai")
 				data = append(data, item)
 			}
 
@@ -131,6 +137,7 @@ func CLIProxyModelsMiddleware(db *gorm.DB, store *modelregistry.Store) gin.Handl
 
 		case "/v1beta/models":
 			onlyMapped := dbConfigBool("ONLY_MAPPED_MODELS")
+			displays := modeldisplay.Load(c.Request.Context(), db)
 			userGroups, billUserGroups, okUser := loadUserGroupMembership(c, db)
 			rawModels := make([]map[string]any, 0)
 			if !onlyMapped {
@@ -170,6 +177,7 @@ func CLIProxyModelsMiddleware(db *gorm.DB, store *modelregistry.Store) gin.Handl
 				if _, ok := normalizedModel["supportedGenerationMethods"]; !ok {
 					normalizedModel["supportedGenerationMethods"] = defaultMethods
 				}
+				applyModelDisplay(normalizedModel, displays, "gemini")
 				normalizedModels = append(normalizedModels, normalizedModel)
 			}
 
@@ -0,0 +1,138 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/access"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/logging"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/requestlog"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// requestLogSaveTimeout bounds the insert that runs after the response is sent.
+const requestLogSaveTimeout = 5 * time.Second
+
+// RequestLogMiddleware captures truncated request and response bodies of proxy requests for
+// users that request logging covers. Rows share the request ID of their usage rows; bodies go
+// to the payload store when one is configured.
+func RequestLogMiddleware(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c == nil || c.Request == nil || c.Request.URL == nil {
+			if c != nil {
+				c.Next()
+			}
+			return
+		}
+		if db == nil || !access.RequiresAPIKey(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+		ctx := c.Request.Context()
+		cfg := requestlog.LoadConfig()
+		if !requestlog.MayCapture(ctx, db, cfg) {
+			c.Next()
+			return
+		}
+		token := access.ExtractAPIKey(c.Request)
+		if token == "" {
+			c.Next()
+			return
+		}
+		var apiKey models.APIKey
+		if errFind := db.WithContext(ctx).
+			Select("id", "user_id").
+			Where("api_key = ? AND active = ? AND revoked_at IS NULL", token, true).
+			First(&apiKey).Error; errFind != nil {
+			c.Next()
+			return
+		}
+		var userID uint64
+		if apiKey.UserID != nil {
+			userID = *apiKey.UserID
+		}
+		enabled, errEnabled := requestlog.EnabledForUser(ctx, db, cfg, userID)
+		if errEnabled != nil {
+			log.WithError(errEnabled).Warn("request log: resolve user failed")
+		}
+		if !enabled {
+			c.Next()
+			return
+		}
+
+		var requestBody []byte
+		if c.Request.Body != nil {
+			raw, errRead := io.ReadAll(c.Request.Body)
+			_ = c.Request.Body.Close()
+			if errRead != nil {
+				log.WithError(errRead).Debug("request log: read request body failed")
+			}
+			requestBody = raw
+			c.Request.Body = io.NopCloser(bytes.NewReader(raw))
+		}
+
+		writer := &requestLogWriter{ResponseWriter: c.Writer, limit: cfg.MaxBodyBytes}
+		c.Writer = writer
+		start := time.Now()
+		c.Next()
+
+		row := models.RequestLog{
+			RequestID:  logging.GetGinRequestID(c),
+			APIKeyID:   &apiKey.ID,
+			UserID:     apiKey.UserID,
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+			Model:      requestlog.ModelOf(requestBody),
+			StatusCode: writer.Status(),
+			DurationMs: time.Since(start).Milliseconds(),
+			ClientIP:   c.ClientIP(),
+		}
+		row.RequestBody, row.RequestTruncated = requestlog.Truncate(requestBody, cfg.MaxBodyBytes)
+		row.ResponseBody, _ = requestlog.Truncate(writer.body.Bytes(), cfg.MaxBodyBytes)
+		row.ResponseTruncated = writer.truncated
+
+		saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), requestLogSaveTimeout)
+		defer cancel()
+		if errSave := requestlog.Save(saveCtx, db, &row, cfg.RetentionDays, time.Now()); errSave != nil {
+			log.WithError(errSave).Warn("request log: store row failed")
+		}
+	}
+}
+
+// requestLogWriter copies up to limit bytes of the response while passing it through.
+type requestLogWriter struct {
+	gin.ResponseWriter
+	limit     int
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *requestLogWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *requestLogWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *requestLogWriter) capture(data []byte) {
+	room := w.limit - w.body.Len()
+	if room <= 0 {
+		if len(data) > 0 {
+			w.truncated = true
+		}
+		return
+	}
+	if len(data) > room {
+		data = data[:room]
+		w.truncated = true
+	}
+	w.body.Write(data)
+}
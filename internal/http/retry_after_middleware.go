@@ -0,0 +1,61 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/retryafter"
+)
+
+// RetryAfterMiddleware makes rate-limited proxy responses carry a sanitized Retry-After.
+// Upstream values are normalized to whole seconds within retryafter.MaxSeconds, and when
+// the upstream only reports the back-off in its error body the header is derived from it.
+func RetryAfterMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c == nil {
+			return
+		}
+		c.Writer = &retryAfterWriter{ResponseWriter: c.Writer}
+		c.Next()
+	}
+}
+
+// retryAfterWriter rewrites Retry-After right before a 429 response's headers go out.
+type retryAfterWriter struct {
+	gin.ResponseWriter
+}
+
+func (w *retryAfterWriter) Write(data []byte) (int, error) {
+	w.applyRetryAfter(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *retryAfterWriter) WriteString(s string) (int, error) {
+	w.applyRetryAfter([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *retryAfterWriter) WriteHeaderNow() {
+	w.applyRetryAfter(nil)
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *retryAfterWriter) Flush() {
+	w.applyRetryAfter(nil)
+	w.ResponseWriter.Flush()
+}
+
+func (w *retryAfterWriter) applyRetryAfter(body []byte) {
+	if w.Written() || w.Status() != http.StatusTooManyRequests {
+		return
+	}
+	header := w.Header()
+	seconds, ok := retryafter.Resolve(header.Get(retryafter.Header), body, time.Now())
+	if !ok {
+		header.Del(retryafter.Header)
+		return
+	}
+	header.Set(retryafter.Header, strconv.Itoa(seconds))
+}
@@ -0,0 +1,186 @@
+// Package invitation redeems admin-issued invitation codes at self-service registration: it
+// claims a use of the code, and grants the new user the code's user group and plan.
+package invitation
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"gorm.io/gorm"
+)
+
+// Redemption errors.
+var (
+	// ErrInvalidCode is returned for unknown or disabled codes.
+	ErrInvalidCode = errors.New("invalid invitation code")
+	// ErrExpired is returned for codes past their expiry.
+	ErrExpired = errors.New("invitation code has expired")
+	// ErrExhausted is returned when every use of a code is taken.
+	ErrExhausted = errors.New("invitation code has been used up")
+)
+
+// InviteOnly reports whether registration requires an invitation code.
+func InviteOnly() bool {
+	raw, ok := internalsettings.DBConfigValue(internalsettings.RegistrationInviteOnlyKey)
+	if !ok {
+		return false
+	}
+	raw = bytes.TrimSpace(raw)
+	var enabled bool
+	if errUnmarshal := json.Unmarshal(raw, &enabled); errUnmarshal == nil {
+		return enabled
+	}
+	var text string
+	if errUnmarshal := json.Unmarshal(raw, &text); errUnmarshal == nil {
+		text = strings.TrimSpace(text)
+		return strings.EqualFold(text, "true") || text == "1"
+	}
+	return false
+}
+
+// NormalizeCode trims a code and upper-cases it; generated codes are upper case.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// GenerateCode returns a random code of the requested length without ambiguous characters.
+func GenerateCode(length int) (string, error) {
+	const alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	buf := make([]byte, length)
+	if _, errRead := rand.Read(buf); errRead != nil {
+		return "", errRead
+	}
+	out := make([]byte, length)
+	for i, b := range buf {
+		out[i] = alphabet[int(b)%len(alphabet)]
+	}
+	return string(out), nil
+}
+
+// Claim takes one use of a code inside the registration transaction. The use is released
+// again if the transaction rolls back.
+func Claim(tx *gorm.DB, code string, now time.Time) (*models.Invitation, error) {
+	var invite models.Invitation
+	if errFind := tx.Where("code = ?", NormalizeCode(code)).First(&invite).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidCode
+		}
+		return nil, errFind
+	}
+	if !invite.IsEnabled {
+		return nil, ErrInvalidCode
+	}
+	if invite.ExpiresAt != nil && !now.Before(*invite.ExpiresAt) {
+		return nil, ErrExpired
+	}
+	res := tx.Model(&models.Invitation{}).
+		Where("id = ? AND (max_uses = 0 OR used_count < max_uses)", invite.ID).
+		Updates(map[string]any{"used_count": gorm.Expr("used_count + 1"), "updated_at": now})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrExhausted
+	}
+	invite.UsedCount++
+	return &invite, nil
+}
+
+// UserGroups returns the user groups a user registering with invite starts in, or nil to
+// keep the default group.
+func UserGroups(invite *models.Invitation) models.UserGroupIDs {
+	if invite == nil || invite.UserGroupID == nil || *invite.UserGroupID == 0 {
+		return nil
+	}
+	id := *invite.UserGroupID
+	return models.UserGroupIDs{&id}
+}
+
+// Grant records the redemption for a newly created user and grants the invite's plan as a
+// complimentary paid bill.
+func Grant(ctx context.Context, tx *gorm.DB, invite *models.Invitation, userID uint64, now time.Time) error {
+	redemption := models.InvitationRedemption{InvitationID: invite.ID, UserID: userID, CreatedAt: now}
+	if invite.PlanID != nil && *invite.PlanID != 0 {
+		bill, errBill := grantPlan(ctx, tx, *invite.PlanID, invite.PlanDays, userID, now)
+		if errBill != nil {
+			return errBill
+		}
+		redemption.BillID = &bill.ID
+	}
+	return tx.WithContext(ctx).Create(&redemption).Error
+}
+
+func grantPlan(ctx context.Context, tx *gorm.DB, planID uint64, days int, userID uint64, now time.Time) (*models.Bill, error) {
+	var plan models.Plan
+	if errFind := tx.WithContext(ctx).First(&plan, planID).Error; errFind != nil {
+		return nil, errFind
+	}
+	periodEnd := now.AddDate(0, 1, 0)
+	if days > 0 {
+		periodEnd = now.AddDate(0, 0, days)
+	}
+	bill := models.Bill{
+		PlanID:      plan.ID,
+		UserID:      userID,
+		UserGroupID: plan.UserGroupID.Clean(),
+		PeriodType:  models.BillPeriodTypeMonthly,
+		Amount:      0,
+		PeriodStart: now,
+		PeriodEnd:   periodEnd,
+		TotalQuota:  plan.TotalQuota,
+		DailyQuota:  plan.DailyQuota,
+		LeftQuota:   plan.TotalQuota,
+		RateLimit:   plan.RateLimit,
+		IsEnabled:   true,
+		Status:      models.BillStatusPaid,
+		RenewalMode: models.BillRenewalModeManual,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if errCreate := tx.WithContext(ctx).Create(&bill).Error; errCreate != nil {
+		return nil, errCreate
+	}
+	if errRefresh := refreshBillUserGroupIDs(ctx, tx, userID, now); errRefresh != nil {
+		return nil, errRefresh
+	}
+	return &bill, nil
+}
+
+// refreshBillUserGroupIDs recomputes the user groups granted by the user's active bills.
+func refreshBillUserGroupIDs(ctx context.Context, tx *gorm.DB, userID uint64, now time.Time) error {
+	var bills []models.Bill
+	if errFind := tx.WithContext(ctx).
+		Model(&models.Bill{}).
+		Select("user_group_id").
+		Where("user_id = ? AND is_enabled = ? AND status = ? AND left_quota > 0", userID, true, models.BillStatusPaid).
+		Where("period_start <= ? AND period_end >= ?", now, now).
+		Find(&bills).Error; errFind != nil {
+		return errFind
+	}
+	seen := make(map[uint64]struct{})
+	merged := make(models.UserGroupIDs, 0)
+	for _, bill := range bills {
+		for _, gid := range bill.UserGroupID.Clean() {
+			if gid == nil || *gid == 0 {
+				continue
+			}
+			if _, ok := seen[*gid]; ok {
+				continue
+			}
+			seen[*gid] = struct{}{}
+			idCopy := *gid
+			merged = append(merged, &idCopy)
+		}
+	}
+	return tx.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ?", userID).
+		Update("bill_user_group_id", merged.Clean()).Error
+}
@@ -0,0 +1,136 @@
+package invitation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func setupInvitationDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:invitation_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func TestClaim(t *testing.T) {
+	conn := setupInvitationDB(t)
+	now := time.Date(2026, 6, 1, 10, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	invites := []models.Invitation{
+		{Code: "ONCE", MaxUses: 1, IsEnabled: true},
+		{Code: "UNLIMITED", MaxUses: 0, IsEnabled: true},
+		{Code: "OLD", MaxUses: 5, IsEnabled: true, ExpiresAt: &past},
+		{Code: "OFF", MaxUses: 5, IsEnabled: false},
+	}
+	for i := range invites {
+		if errCreate := conn.Create(&invites[i]).Error; errCreate != nil {
+			t.Fatalf("create invitation: %v", errCreate)
+		}
+	}
+	// Disabled codes are created enabled by the column default; switch them off explicitly.
+	if errUpdate := conn.Model(&models.Invitation{}).Where("code = ?", "OFF").Update("is_enabled", false).Error; errUpdate != nil {
+		t.Fatalf("disable invitation: %v", errUpdate)
+	}
+
+	if _, errClaim := Claim(conn, " once ", now); errClaim != nil {
+		t.Fatalf("claim: %v", errClaim)
+	}
+	if _, errClaim := Claim(conn, "ONCE", now); !errors.Is(errClaim, ErrExhausted) {
+		t.Fatalf("expected exhausted, got %v", errClaim)
+	}
+	for i := 0; i < 3; i++ {
+		if _, errClaim := Claim(conn, "UNLIMITED", now); errClaim != nil {
+			t.Fatalf("claim unlimited: %v", errClaim)
+		}
+	}
+	if _, errClaim := Claim(conn, "OLD", now); !errors.Is(errClaim, ErrExpired) {
+		t.Fatalf("expected expired, got %v", errClaim)
+	}
+	if _, errClaim := Claim(conn, "OFF", now); !errors.Is(errClaim, ErrInvalidCode) {
+		t.Fatalf("expected invalid for disabled code, got %v", errClaim)
+	}
+	if _, errClaim := Claim(conn, "MISSING", now); !errors.Is(errClaim, ErrInvalidCode) {
+		t.Fatalf("expected invalid for unknown code, got %v", errClaim)
+	}
+
+	// A rolled back registration releases its claim.
+	errTx := conn.Transaction(func(tx *gorm.DB) error {
+		if _, errClaim := Claim(tx, "UNLIMITED", now); errClaim != nil {
+			return errClaim
+		}
+		return errors.New("registration failed")
+	})
+	if errTx == nil {
+		t.Fatal("expected rollback")
+	}
+	var unlimited models.Invitation
+	if errFind := conn.Where("code = ?", "UNLIMITED").First(&unlimited).Error; errFind != nil {
+		t.Fatalf("load invitation: %v", errFind)
+	}
+	if unlimited.UsedCount != 3 {
+		t.Fatalf("expected 3 uses, got %d", unlimited.UsedCount)
+	}
+}
+
+func TestGrant(t *testing.T) {
+	conn := setupInvitationDB(t)
+	now := time.Date(2026, 6, 1, 10, 0, 0, 0, time.UTC)
+	group := models.UserGroup{Name: "Trial", CreatedAt: now, UpdatedAt: now}
+	if errCreate := conn.Create(&group).Error; errCreate != nil {
+		t.Fatalf("create group: %v", errCreate)
+	}
+	plan := models.Plan{Name: "Starter", TotalQuota: 10, UserGroupID: models.UserGroupIDs{&group.ID}, IsEnabled: true}
+	if errCreate := conn.Create(&plan).Error; errCreate != nil {
+		t.Fatalf("create plan: %v", errCreate)
+	}
+	invite := models.Invitation{Code: "WELCOME", MaxUses: 1, IsEnabled: true, UserGroupID: &group.ID, PlanID: &plan.ID, PlanDays: 14}
+	if errCreate := conn.Create(&invite).Error; errCreate != nil {
+		t.Fatalf("create invitation: %v", errCreate)
+	}
+	user := models.User{Username: "newbie", Password: "x", Active: true, UserGroupID: UserGroups(&invite), CreatedAt: now, UpdatedAt: now}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+	if errGrant := Grant(context.Background(), conn, &invite, user.ID, now); errGrant != nil {
+		t.Fatalf("grant: %v", errGrant)
+	}
+
+	var redemption models.InvitationRedemption
+	if errFind := conn.Where("user_id = ?", user.ID).First(&redemption).Error; errFind != nil {
+		t.Fatalf("load redemption: %v", errFind)
+	}
+	if redemption.InvitationID != invite.ID || redemption.BillID == nil {
+		t.Fatalf("unexpected redemption: %+v", redemption)
+	}
+	var bill models.Bill
+	if errFind := conn.First(&bill, *redemption.BillID).Error; errFind != nil {
+		t.Fatalf("load bill: %v", errFind)
+	}
+	if bill.Amount != 0 || bill.Status != models.BillStatusPaid || !bill.PeriodEnd.Equal(now.AddDate(0, 0, 14)) || bill.LeftQuota != 10 {
+		t.Fatalf("unexpected bill: %+v", bill)
+	}
+	var stored models.User
+	if errFind := conn.First(&stored, user.ID).Error; errFind != nil {
+		t.Fatalf("load user: %v", errFind)
+	}
+	if ids := stored.UserGroupID.Values(); len(ids) != 1 || ids[0] != group.ID {
+		t.Fatalf("expected invite group, got %v", ids)
+	}
+	if ids := stored.BillUserGroupID.Values(); len(ids) != 1 || ids[0] != group.ID {
+		t.Fatalf("expected bill group refresh, got %v", ids)
+	}
+}
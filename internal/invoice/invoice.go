@@ -0,0 +1,361 @@
+// Package invoice generates monthly invoices for users and user groups from usage
+// aggregates and renders them as JSON or PDF documents.
+package invoice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/currency"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefaultCurrency is used when no currency is given.
+const DefaultCurrency = currency.Base
+
+var (
+	// ErrInvalidSubject is returned when neither or both of user and user group are set.
+	ErrInvalidSubject = errors.New("invoice: exactly one of user or user group is required")
+	// ErrInvalidTaxRate is returned for negative tax rates.
+	ErrInvalidTaxRate = errors.New("invoice: invalid tax rate")
+	// ErrNotDraft is returned when regenerating or finalizing an invoice that is no longer a draft.
+	ErrNotDraft = errors.New("invoice: not a draft")
+	// ErrNotFinalized is returned when marking an invoice paid before it is finalized.
+	ErrNotFinalized = errors.New("invoice: not finalized")
+	// ErrStatusChanged is returned when another request changed the invoice status first.
+	ErrStatusChanged = errors.New("invoice: status changed concurrently")
+)
+
+// Subject identifies who is invoiced.
+type Subject struct {
+	UserID      *uint64 // Invoiced user.
+	UserGroupID *uint64 // Invoiced user group.
+}
+
+// validate checks that exactly one subject field is set.
+func (s Subject) validate() error {
+	if (s.UserID == nil) == (s.UserGroupID == nil) {
+		return ErrInvalidSubject
+	}
+	return nil
+}
+
+// LineItem aggregates a month of usage for one provider and model.
+type LineItem struct {
+	Provider     string  `json:"provider"`
+	Model        string  `json:"model"`
+	Requests     int64   `json:"requests"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	CachedTokens int64   `json:"cached_tokens"`
+	TotalTokens  int64   `json:"total_tokens"`
+	Amount       float64 `json:"amount"`
+}
+
+// Options tunes invoice generation.
+type Options struct {
+	TaxRate   float64 // Tax rate applied to the subtotal (0.2 = 20%).
+	Currency  string  // ISO currency code; defaults to DefaultCurrency. Amounts are converted from USD.
+	CreatedBy *uint64 // Admin ID generating the invoice.
+}
+
+// Period returns the local calendar month containing month as [start, end).
+func Period(month time.Time) (time.Time, time.Time) {
+	local := month.In(time.Local)
+	start := time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, time.Local)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// ParseMonth parses a YYYY-MM month in local time.
+func ParseMonth(raw string) (time.Time, error) {
+	month, errParse := time.ParseInLocation("2006-01", strings.TrimSpace(raw), time.Local)
+	if errParse != nil {
+		return time.Time{}, fmt.Errorf("invoice: invalid month %q", raw)
+	}
+	return month, nil
+}
+
+// aggregateRow is one grouped sum read from usages or usage_daily.
+type aggregateRow struct {
+	Provider     string
+	Model        string
+	Requests     int64
+	InputTokens  int64
+	OutputTokens int64
+	CachedTokens int64
+	TotalTokens  int64
+	CostMicros   int64
+}
+
+// Aggregate sums the subject's usage in [start, end) per provider and model. Raw usages
+// rows are matched on the user, or on the billing user group recorded with the request.
+// Rows already rolled up into usage_daily carry no group, so group invoices attribute them
+// through current group membership.
+func Aggregate(ctx context.Context, db *gorm.DB, subject Subject, start, end time.Time) ([]LineItem, error) {
+	if errSubject := subject.validate(); errSubject != nil {
+		return nil, errSubject
+	}
+	const sums = "provider, model, COUNT(*) AS requests, " +
+		"COALESCE(SUM(input_tokens), 0) AS input_tokens, COALESCE(SUM(output_tokens), 0) AS output_tokens, " +
+		"COALESCE(SUM(cached_tokens), 0) AS cached_tokens, COALESCE(SUM(total_tokens), 0) AS total_tokens, " +
+		"COALESCE(SUM(cost_micros), 0) AS cost_micros"
+
+	live := db.WithContext(ctx).Model(&models.Usage{}).
+		Select(sums).
+		Where("requested_at >= ? AND requested_at < ?", start.UTC(), end.UTC()).
+		Group("provider, model")
+	if subject.UserID != nil {
+		live = live.Where("user_id = ?", *subject.UserID)
+	} else {
+		live = live.Where("user_group_id = ?", *subject.UserGroupID)
+	}
+	var rows []aggregateRow
+	if errLive := live.Scan(&rows).Error; errLive != nil {
+		return nil, fmt.Errorf("invoice: aggregate usages: %w", errLive)
+	}
+
+	userIDs, errMembers := subjectUserIDs(ctx, db, subject)
+	if errMembers != nil {
+		return nil, errMembers
+	}
+	if len(userIDs) > 0 {
+		var rolled []aggregateRow
+		if errDaily := db.WithContext(ctx).Model(&models.UsageDaily{}).
+			Select(strings.Replace(sums, "COUNT(*)", "COALESCE(SUM(requests), 0)", 1)).
+			Where("day >= ? AND day < ? AND user_id IN ?", start, end, userIDs).
+			Group("provider, model").
+			Scan(&rolled).Error; errDaily != nil {
+			return nil, fmt.Errorf("invoice: aggregate usage_daily: %w", errDaily)
+		}
+		rows = append(rows, rolled...)
+	}
+
+	merged := make(map[string]*LineItem, len(rows))
+	for _, row := range rows {
+		key := row.Provider + "\x00" + row.Model
+		item, ok := merged[key]
+		if !ok {
+			item = &LineItem{Provider: row.Provider, Model: row.Model}
+			merged[key] = item
+		}
+		item.Requests += row.Requests
+		item.InputTokens += row.InputTokens
+		item.OutputTokens += row.OutputTokens
+		item.CachedTokens += row.CachedTokens
+		item.TotalTokens += row.TotalTokens
+		item.Amount += float64(row.CostMicros) / 1_000_000
+	}
+	items := make([]LineItem, 0, len(merged))
+	for _, item := range merged {
+		items = append(items, *item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Provider != items[j].Provider {
+			return items[i].Provider < items[j].Provider
+		}
+		return items[i].Model < items[j].Model
+	})
+	return items, nil
+}
+
+// subjectUserIDs returns the user, or the current members of the user group.
+func subjectUserIDs(ctx context.Context, db *gorm.DB, subject Subject) ([]uint64, error) {
+	if subject.UserID != nil {
+		return []uint64{*subject.UserID}, nil
+	}
+	var users []models.User
+	if errFind := db.WithContext(ctx).Select("id", "user_group_id").Find(&users).Error; errFind != nil {
+		return nil, fmt.Errorf("invoice: load group members: %w", errFind)
+	}
+	var ids []uint64
+	for _, user := range users {
+		for _, groupID := range user.UserGroupID.Values() {
+			if groupID == *subject.UserGroupID {
+				ids = append(ids, user.ID)
+				break
+			}
+		}
+	}
+	return ids, nil
+}
+
+// Generate builds the subject's invoice for the month containing month. An existing draft
+// for the same period is refreshed in place; a finalized or paid one returns ErrNotDraft.
+func Generate(ctx context.Context, db *gorm.DB, subject Subject, month time.Time, opts Options) (*models.Invoice, error) {
+	if errSubject := subject.validate(); errSubject != nil {
+		return nil, errSubject
+	}
+	if opts.TaxRate < 0 || math.IsNaN(opts.TaxRate) || math.IsInf(opts.TaxRate, 0) {
+		return nil, ErrInvalidTaxRate
+	}
+	code, errCode := currency.Normalize(opts.Currency)
+	if errCode != nil {
+		return nil, errCode
+	}
+	rate := 1.0
+	if code != currency.Base {
+		table, errRates := currency.Load(ctx, db)
+		if errRates != nil {
+			return nil, errRates
+		}
+		if rate, errCode = table.Rate(code); errCode != nil {
+			return nil, errCode
+		}
+	}
+	start, end := Period(month)
+	items, errAggregate := Aggregate(ctx, db, subject, start, end)
+	if errAggregate != nil {
+		return nil, errAggregate
+	}
+	for i := range items {
+		items[i].Amount *= rate
+	}
+	lines, errMarshal := json.Marshal(items)
+	if errMarshal != nil {
+		return nil, fmt.Errorf("invoice: encode line items: %w", errMarshal)
+	}
+	var subtotal float64
+	for _, item := range items {
+		subtotal += item.Amount
+	}
+	tax := subtotal * opts.TaxRate
+
+	var out models.Invoice
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		q := tx.Where("period_start = ?", start)
+		if subject.UserID != nil {
+			q = q.Where("user_id = ? AND user_group_id IS NULL", *subject.UserID)
+		} else {
+			q = q.Where("user_group_id = ? AND user_id IS NULL", *subject.UserGroupID)
+		}
+		errFind := q.Take(&out).Error
+		switch {
+		case errFind == nil && out.Status != models.InvoiceStatusDraft:
+			return ErrNotDraft
+		case errFind != nil && !errors.Is(errFind, gorm.ErrRecordNotFound):
+			return errFind
+		case errFind != nil:
+			out = models.Invoice{
+				UserID:      subject.UserID,
+				UserGroupID: subject.UserGroupID,
+				PeriodStart: start,
+				PeriodEnd:   end,
+				Status:      models.InvoiceStatusDraft,
+			}
+		}
+		out.Currency = code
+		out.ExchangeRate = rate
+		out.LineItems = lines
+		out.Subtotal = subtotal
+		out.TaxRate = opts.TaxRate
+		out.Tax = tax
+		out.Total = subtotal + tax
+		out.CreatedBy = opts.CreatedBy
+		return tx.Save(&out).Error
+	})
+	if errTx != nil {
+		return nil, errTx
+	}
+	return &out, nil
+}
+
+// GenerateAll drafts invoices for every user with usage in the month containing month.
+// Users whose invoice is already finalized are skipped.
+func GenerateAll(ctx context.Context, db *gorm.DB, month time.Time, opts Options) ([]models.Invoice, error) {
+	start, end := Period(month)
+	var userIDs []uint64
+	if errLive := db.WithContext(ctx).Model(&models.Usage{}).
+		Where("requested_at >= ? AND requested_at < ? AND user_id IS NOT NULL", start.UTC(), end.UTC()).
+		Distinct().Pluck("user_id", &userIDs).Error; errLive != nil {
+		return nil, fmt.Errorf("invoice: list billed users: %w", errLive)
+	}
+	var rolledIDs []uint64
+	if errDaily := db.WithContext(ctx).Model(&models.UsageDaily{}).
+		Where("day >= ? AND day < ? AND user_id <> 0", start, end).
+		Distinct().Pluck("user_id", &rolledIDs).Error; errDaily != nil {
+		return nil, fmt.Errorf("invoice: list billed users: %w", errDaily)
+	}
+	seen := make(map[uint64]struct{}, len(userIDs)+len(rolledIDs))
+	var out []models.Invoice
+	for _, userID := range append(userIDs, rolledIDs...) {
+		if _, ok := seen[userID]; ok {
+			continue
+		}
+		seen[userID] = struct{}{}
+		id := userID
+		inv, errGenerate := Generate(ctx, db, Subject{UserID: &id}, month, opts)
+		if errors.Is(errGenerate, ErrNotDraft) {
+			continue
+		}
+		if errGenerate != nil {
+			return out, errGenerate
+		}
+		out = append(out, *inv)
+	}
+	return out, nil
+}
+
+// Finalize freezes a draft invoice and assigns its number.
+func Finalize(ctx context.Context, db *gorm.DB, id uint64, now time.Time) (*models.Invoice, error) {
+	return transition(ctx, db, id, func(inv *models.Invoice) (map[string]any, error) {
+		if inv.Status != models.InvoiceStatusDraft {
+			return nil, ErrNotDraft
+		}
+		number := fmt.Sprintf("INV-%s-%06d", inv.PeriodStart.In(time.Local).Format("200601"), inv.ID)
+		return map[string]any{"status": models.InvoiceStatusFinalized, "number": number, "finalized_at": now, "updated_at": now}, nil
+	})
+}
+
+// MarkPaid records payment of a finalized invoice.
+func MarkPaid(ctx context.Context, db *gorm.DB, id uint64, now time.Time) (*models.Invoice, error) {
+	return transition(ctx, db, id, func(inv *models.Invoice) (map[string]any, error) {
+		if inv.Status != models.InvoiceStatusFinalized {
+			return nil, ErrNotFinalized
+		}
+		return map[string]any{"status": models.InvoiceStatusPaid, "paid_at": now, "updated_at": now}, nil
+	})
+}
+
+// transition applies a status change guarded by the status the invoice was loaded with.
+func transition(ctx context.Context, db *gorm.DB, id uint64, next func(*models.Invoice) (map[string]any, error)) (*models.Invoice, error) {
+	var inv models.Invoice
+	if errFind := db.WithContext(ctx).First(&inv, id).Error; errFind != nil {
+		return nil, errFind
+	}
+	updates, errNext := next(&inv)
+	if errNext != nil {
+		return nil, errNext
+	}
+	res := db.WithContext(ctx).Model(&models.Invoice{}).
+		Where("id = ? AND status = ?", inv.ID, inv.Status).
+		Updates(updates)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrStatusChanged
+	}
+	if errReload := db.WithContext(ctx).First(&inv, id).Error; errReload != nil {
+		return nil, errReload
+	}
+	return &inv, nil
+}
+
+// DecodeLineItems returns the invoice's line items.
+func DecodeLineItems(inv *models.Invoice) ([]LineItem, error) {
+	if inv == nil || len(inv.LineItems) == 0 {
+		return []LineItem{}, nil
+	}
+	var items []LineItem
+	if errUnmarshal := json.Unmarshal(inv.LineItems, &items); errUnmarshal != nil {
+		return nil, fmt.Errorf("invoice: decode line items: %w", errUnmarshal)
+	}
+	return items, nil
+}
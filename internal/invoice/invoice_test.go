@@ -0,0 +1,133 @@
+package invoice
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func setupInvoiceDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:invoice_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func TestGenerateFinalizeAndMarkPaid(t *testing.T) {
+	conn := setupInvoiceDB(t)
+	ctx := context.Background()
+
+	group := models.UserGroup{Name: "invoice-group"}
+	if errCreate := conn.Create(&group).Error; errCreate != nil {
+		t.Fatalf("create group: %v", errCreate)
+	}
+	groupID := group.ID
+	user := models.User{Username: "invoice-user", Email: "invoice-user@example.com", Password: "x", UserGroupID: models.UserGroupIDs{&groupID}}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+	userID := user.ID
+
+	month, _ := ParseMonth("2026-09")
+	start, _ := Period(month)
+	rows := []models.Usage{
+		{Provider: "openai", Model: "gpt-5", UserID: &userID, UserGroupID: &groupID, RequestedAt: start.Add(48 * time.Hour), InputTokens: 100, OutputTokens: 50, TotalTokens: 150, CostMicros: 1_500_000},
+		{Provider: "openai", Model: "gpt-5", UserID: &userID, UserGroupID: &groupID, RequestedAt: start.Add(72 * time.Hour), InputTokens: 10, OutputTokens: 5, TotalTokens: 15, CostMicros: 500_000},
+		{Provider: "openai", Model: "gpt-5", UserID: &userID, UserGroupID: &groupID, RequestedAt: start.AddDate(0, 1, 1), CostMicros: 9_000_000},
+	}
+	if errCreate := conn.Create(&rows).Error; errCreate != nil {
+		t.Fatalf("create usages: %v", errCreate)
+	}
+	daily := models.UsageDaily{Day: start, Provider: "claude", Model: "claude-sonnet", UserID: userID, Requests: 4, InputTokens: 40, CostMicros: 1_000_000}
+	if errCreate := conn.Create(&daily).Error; errCreate != nil {
+		t.Fatalf("create usage daily: %v", errCreate)
+	}
+
+	inv, errGenerate := Generate(ctx, conn, Subject{UserID: &userID}, month, Options{TaxRate: 0.1})
+	if errGenerate != nil {
+		t.Fatalf("generate invoice: %v", errGenerate)
+	}
+	items, _ := DecodeLineItems(inv)
+	if len(items) != 2 || items[0].Provider != "claude" || items[1].Requests != 2 || items[1].InputTokens != 110 {
+		t.Fatalf("unexpected line items: %+v", items)
+	}
+	if math.Abs(inv.Subtotal-3) > 1e-9 || math.Abs(inv.Tax-0.3) > 1e-9 || math.Abs(inv.Total-3.3) > 1e-9 || inv.Currency != DefaultCurrency {
+		t.Fatalf("unexpected totals: subtotal=%v tax=%v total=%v currency=%s", inv.Subtotal, inv.Tax, inv.Total, inv.Currency)
+	}
+
+	again, errAgain := Generate(ctx, conn, Subject{UserID: &userID}, month, Options{})
+	if errAgain != nil || again.ID != inv.ID || again.Tax != 0 {
+		t.Fatalf("expected regenerate to refresh the draft, got %+v err=%v", again, errAgain)
+	}
+
+	groupInvoice, errGroup := Generate(ctx, conn, Subject{UserGroupID: &groupID}, month, Options{})
+	if errGroup != nil || groupInvoice.ID == inv.ID || math.Abs(groupInvoice.Subtotal-3) > 1e-9 {
+		t.Fatalf("expected a separate group invoice with subtotal 3, got %+v err=%v", groupInvoice, errGroup)
+	}
+
+	if _, errPaid := MarkPaid(ctx, conn, inv.ID, time.Now().UTC()); !errors.Is(errPaid, ErrNotFinalized) {
+		t.Fatalf("expected ErrNotFinalized, got %v", errPaid)
+	}
+	finalized, errFinalize := Finalize(ctx, conn, inv.ID, time.Now().UTC())
+	if errFinalize != nil || finalized.Status != models.InvoiceStatusFinalized || finalized.Number != fmt.Sprintf("INV-202609-%06d", inv.ID) {
+		t.Fatalf("unexpected finalize result: %+v err=%v", finalized, errFinalize)
+	}
+	if _, errRegenerate := Generate(ctx, conn, Subject{UserID: &userID}, month, Options{}); !errors.Is(errRegenerate, ErrNotDraft) {
+		t.Fatalf("expected ErrNotDraft after finalize, got %v", errRegenerate)
+	}
+	paid, errPaid := MarkPaid(ctx, conn, inv.ID, time.Now().UTC())
+	if errPaid != nil || paid.Status != models.InvoiceStatusPaid || paid.PaidAt == nil {
+		t.Fatalf("unexpected mark paid result: %+v err=%v", paid, errPaid)
+	}
+
+	doc, errDoc := NewDocument(paid, "Example Reseller", user.Username)
+	if errDoc != nil {
+		t.Fatalf("new document: %v", errDoc)
+	}
+	pdf, errPDF := RenderPDF(doc)
+	if errPDF != nil {
+		t.Fatalf("render pdf: %v", errPDF)
+	}
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) || !bytes.Contains(pdf, []byte("claude/claude-sonnet")) {
+		t.Fatalf("unexpected pdf output:\n%s", pdf)
+	}
+	rendered, errJSON := RenderJSON(doc)
+	if errJSON != nil || !bytes.Contains(rendered, []byte(`"number": "INV-202609-`)) {
+		t.Fatalf("unexpected json output: %s err=%v", rendered, errJSON)
+	}
+}
+
+func TestGenerateRejectsInvalidInput(t *testing.T) {
+	conn := setupInvoiceDB(t)
+	ctx := context.Background()
+	id := uint64(1)
+	month := time.Now()
+
+	if _, errGenerate := Generate(ctx, conn, Subject{}, month, Options{}); !errors.Is(errGenerate, ErrInvalidSubject) {
+		t.Fatalf("expected ErrInvalidSubject for empty subject, got %v", errGenerate)
+	}
+	if _, errGenerate := Generate(ctx, conn, Subject{UserID: &id, UserGroupID: &id}, month, Options{}); !errors.Is(errGenerate, ErrInvalidSubject) {
+		t.Fatalf("expected ErrInvalidSubject for both subjects, got %v", errGenerate)
+	}
+	if _, errGenerate := Generate(ctx, conn, Subject{UserID: &id}, month, Options{TaxRate: -0.1}); !errors.Is(errGenerate, ErrInvalidTaxRate) {
+		t.Fatalf("expected ErrInvalidTaxRate, got %v", errGenerate)
+	}
+	if _, errParse := ParseMonth("2026-13"); errParse == nil {
+		t.Fatalf("expected invalid month error")
+	}
+}
@@ -0,0 +1,223 @@
+package invoice
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+)
+
+// PDF page layout in points (A4).
+const (
+	pdfPageWidth    = 595
+	pdfPageHeight   = 842
+	pdfMargin       = 50
+	pdfLineHeight   = 14
+	pdfFontSize     = 8
+	pdfTitleSize    = 16
+	pdfLinesPerPage = (pdfPageHeight - 2*pdfMargin) / pdfLineHeight
+)
+
+// Document is the rendered form of an invoice shared by the JSON and PDF outputs.
+type Document struct {
+	Issuer      string     `json:"issuer"`
+	Number      string     `json:"number"`
+	Status      string     `json:"status"`
+	BillTo      string     `json:"bill_to"`
+	PeriodStart time.Time  `json:"period_start"`
+	PeriodEnd   time.Time  `json:"period_end"`
+	Currency    string     `json:"currency"`
+	LineItems   []LineItem `json:"line_items"`
+	Subtotal    float64    `json:"subtotal"`
+	TaxRate     float64    `json:"tax_rate"`
+	Tax         float64    `json:"tax"`
+	Total       float64    `json:"total"`
+	FinalizedAt *time.Time `json:"finalized_at,omitempty"`
+	PaidAt      *time.Time `json:"paid_at,omitempty"`
+}
+
+// NewDocument prepares an invoice for rendering; billTo names the invoiced user or group.
+func NewDocument(inv *models.Invoice, issuer, billTo string) (Document, error) {
+	items, errItems := DecodeLineItems(inv)
+	if errItems != nil {
+		return Document{}, errItems
+	}
+	number := inv.Number
+	if number == "" {
+		number = fmt.Sprintf("DRAFT-%d", inv.ID)
+	}
+	return Document{
+		Issuer:      issuer,
+		Number:      number,
+		Status:      string(inv.Status),
+		BillTo:      billTo,
+		PeriodStart: inv.PeriodStart,
+		PeriodEnd:   inv.PeriodEnd,
+		Currency:    inv.Currency,
+		LineItems:   items,
+		Subtotal:    inv.Subtotal,
+		TaxRate:     inv.TaxRate,
+		Tax:         inv.Tax,
+		Total:       inv.Total,
+		FinalizedAt: inv.FinalizedAt,
+		PaidAt:      inv.PaidAt,
+	}, nil
+}
+
+// RenderJSON encodes the document as indented JSON.
+func RenderJSON(doc Document) ([]byte, error) {
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// RenderPDF lays the document out as a plain, text-only PDF using the built-in Courier
+// fonts, so columns line up and no font files or third-party libraries are needed.
+func RenderPDF(doc Document) ([]byte, error) {
+	lines := pdfLines(doc)
+	var pages [][]pdfLine
+	for len(lines) > 0 {
+		n := pdfLinesPerPage
+		if n > len(lines) {
+			n = len(lines)
+		}
+		pages = append(pages, lines[:n])
+		lines = lines[n:]
+	}
+
+	w := &pdfWriter{}
+	w.buf.WriteString("%PDF-1.4\n")
+	// Objects 1-4 are fixed: catalog, page tree, regular font, bold font.
+	pageIDs := make([]int, len(pages))
+	for i := range pages {
+		pageIDs[i] = 5 + 2*i
+	}
+	kids := make([]string, len(pageIDs))
+	for i, id := range pageIDs {
+		kids[i] = fmt.Sprintf("%d 0 R", id)
+	}
+	w.object(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	w.object(2, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	w.object(3, "<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	w.object(4, "<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>")
+	for i, page := range pages {
+		content := pdfContent(page, i+1, len(pages))
+		w.object(pageIDs[i], fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, pageIDs[i]+1))
+		w.object(pageIDs[i]+1, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+	return w.finish(), nil
+}
+
+// pdfLine is one text line; bold lines use the heading font.
+type pdfLine struct {
+	text string
+	bold bool
+	size int
+}
+
+// pdfLines flattens the document into text lines.
+func pdfLines(doc Document) []pdfLine {
+	money := func(v float64) string { return fmt.Sprintf("%.2f %s", v, doc.Currency) }
+	lines := []pdfLine{
+		{text: "INVOICE " + doc.Number, bold: true, size: pdfTitleSize},
+		{},
+		{text: "Issuer: " + doc.Issuer},
+		{text: "Bill to: " + doc.BillTo},
+		{text: fmt.Sprintf("Period: %s - %s", doc.PeriodStart.Format("2006-01-02"), doc.PeriodEnd.AddDate(0, 0, -1).Format("2006-01-02"))},
+		{text: "Status: " + doc.Status},
+	}
+	if doc.FinalizedAt != nil {
+		lines = append(lines, pdfLine{text: "Issued: " + doc.FinalizedAt.Format("2006-01-02")})
+	}
+	if doc.PaidAt != nil {
+		lines = append(lines, pdfLine{text: "Paid: " + doc.PaidAt.Format("2006-01-02")})
+	}
+	lines = append(lines,
+		pdfLine{},
+		pdfLine{text: fmt.Sprintf("%-40s %10s %14s %14s %16s", "Model", "Requests", "Input", "Output", "Amount"), bold: true},
+	)
+	for _, item := range doc.LineItems {
+		name := item.Provider + "/" + item.Model
+		if len(name) > 40 {
+			name = name[:37] + "..."
+		}
+		lines = append(lines, pdfLine{text: fmt.Sprintf("%-40s %10d %14d %14d %16s", name, item.Requests, item.InputTokens, item.OutputTokens, money(item.Amount))})
+	}
+	if len(doc.LineItems) == 0 {
+		lines = append(lines, pdfLine{text: "No usage in this period."})
+	}
+	lines = append(lines,
+		pdfLine{},
+		pdfLine{text: fmt.Sprintf("%81s %16s", "Subtotal", money(doc.Subtotal))},
+		pdfLine{text: fmt.Sprintf("%81s %16s", fmt.Sprintf("Tax (%.2f%%)", doc.TaxRate*100), money(doc.Tax))},
+		pdfLine{text: fmt.Sprintf("%81s %16s", "Total", money(doc.Total)), bold: true},
+	)
+	return lines
+}
+
+// pdfContent builds a page content stream with a page footer.
+func pdfContent(lines []pdfLine, page, pages int) string {
+	var b strings.Builder
+	y := pdfPageHeight - pdfMargin
+	for _, line := range lines {
+		if line.text != "" {
+			font, size := "/F1", pdfFontSize
+			if line.bold {
+				font = "/F2"
+			}
+			if line.size > 0 {
+				size = line.size
+			}
+			fmt.Fprintf(&b, "BT %s %d Tf %d %d Td (%s) Tj ET\n", font, size, pdfMargin, y, pdfEscape(line.text))
+		}
+		y -= pdfLineHeight
+	}
+	fmt.Fprintf(&b, "BT /F1 8 Tf %d %d Td (Page %d of %d) Tj ET", pdfPageWidth-pdfMargin-60, pdfMargin/2, page, pages)
+	return b.String()
+}
+
+// pdfEscape escapes a string literal and replaces characters outside WinAnsi with '?'.
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// pdfWriter accumulates objects and their byte offsets for the cross-reference table.
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets map[int]int
+}
+
+// object writes an indirect object.
+func (w *pdfWriter) object(id int, body string) {
+	if w.offsets == nil {
+		w.offsets = make(map[int]int)
+	}
+	w.offsets[id] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+// finish appends the cross-reference table and trailer.
+func (w *pdfWriter) finish() []byte {
+	count := len(w.offsets) + 1
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", count)
+	for id := 1; id < count; id++ {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", w.offsets[id])
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", count, xref)
+	return w.buf.Bytes()
+}
@@ -0,0 +1,194 @@
+// Package ipallow matches request source addresses against IP and CIDR allowlists and
+// resolves the client address behind trusted reverse proxies.
+package ipallow
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+)
+
+// List is a parsed set of allowed networks; an empty list allows every address.
+type List []netip.Prefix
+
+// Parse converts IP and CIDR entries into a list, rejecting malformed entries.
+func Parse(entries []string) (List, error) {
+	out := make(List, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			prefix, errPrefix := netip.ParsePrefix(entry)
+			if errPrefix != nil {
+				return nil, fmt.Errorf("invalid cidr %q", entry)
+			}
+			out = append(out, prefix.Masked())
+			continue
+		}
+		addr, errAddr := netip.ParseAddr(entry)
+		if errAddr != nil {
+			return nil, fmt.Errorf("invalid ip %q", entry)
+		}
+		addr = addr.Unmap()
+		out = append(out, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return out, nil
+}
+
+// Normalize validates entries and returns them in canonical form for storage.
+func Normalize(entries []string) ([]string, error) {
+	list, errParse := Parse(entries)
+	if errParse != nil {
+		return nil, errParse
+	}
+	out := make([]string, 0, len(list))
+	seen := make(map[netip.Prefix]struct{}, len(list))
+	for _, prefix := range list {
+		if _, ok := seen[prefix]; ok {
+			continue
+		}
+		seen[prefix] = struct{}{}
+		if prefix.IsSingleIP() {
+			out = append(out, prefix.Addr().String())
+			continue
+		}
+		out = append(out, prefix.String())
+	}
+	return out, nil
+}
+
+// Encode validates entries and returns them as a canonical JSON array for storage.
+func Encode(entries []string) ([]byte, error) {
+	normalized, errNormalize := Normalize(entries)
+	if errNormalize != nil {
+		return nil, errNormalize
+	}
+	return json.Marshal(normalized)
+}
+
+// FromJSON parses a stored JSON array of entries, skipping malformed ones.
+func FromJSON(raw []byte) List {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil
+	}
+	var entries []string
+	if errUnmarshal := json.Unmarshal(raw, &entries); errUnmarshal != nil {
+		return nil
+	}
+	out := make(List, 0, len(entries))
+	for _, entry := range entries {
+		parsed, errParse := Parse([]string{entry})
+		if errParse != nil {
+			continue
+		}
+		out = append(out, parsed...)
+	}
+	return out
+}
+
+// Entries renders the list as strings.
+func (l List) Entries() []string {
+	out := make([]string, 0, len(l))
+	for _, prefix := range l {
+		if prefix.IsSingleIP() {
+			out = append(out, prefix.Addr().String())
+			continue
+		}
+		out = append(out, prefix.String())
+	}
+	return out
+}
+
+// Contains reports whether addr falls inside any network of the list.
+func (l List) Contains(addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range l {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether addr may pass; an empty list allows everything.
+func (l List) Allows(addr netip.Addr) bool {
+	return len(l) == 0 || l.Contains(addr)
+}
+
+// TrustedProxies loads the proxies whose forwarding headers are honored from TRUSTED_PROXIES.
+func TrustedProxies() List {
+	raw, ok := internalsettings.DBConfigValue(internalsettings.TrustedProxiesKey)
+	if !ok || len(bytes.TrimSpace(raw)) == 0 {
+		return nil
+	}
+	var entries []string
+	if errList := json.Unmarshal(raw, &entries); errList != nil {
+		var single string
+		if errString := json.Unmarshal(raw, &single); errString != nil {
+			log.WithError(errList).Warn("ipallow: invalid trusted proxies setting")
+			return nil
+		}
+		entries = strings.Split(single, ",")
+	}
+	list, errParse := Parse(entries)
+	if errParse != nil {
+		log.WithError(errParse).Warn("ipallow: invalid trusted proxies setting")
+		return nil
+	}
+	return list
+}
+
+// ClientIP returns the request source address. Forwarding headers are only honored when the
+// direct peer is a trusted proxy; X-Forwarded-For is walked from the right, skipping trusted hops.
+func ClientIP(r *http.Request, trusted List) netip.Addr {
+	if r == nil {
+		return netip.Addr{}
+	}
+	peer := remoteAddr(r.RemoteAddr)
+	if !trusted.Contains(peer) {
+		return peer
+	}
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, errAddr := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if errAddr != nil {
+				break
+			}
+			addr = addr.Unmap()
+			if !trusted.Contains(addr) {
+				return addr
+			}
+		}
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		if addr, errAddr := netip.ParseAddr(realIP); errAddr == nil {
+			return addr.Unmap()
+		}
+	}
+	return peer
+}
+
+func remoteAddr(value string) netip.Addr {
+	host := strings.TrimSpace(value)
+	if h, _, errSplit := net.SplitHostPort(host); errSplit == nil {
+		host = h
+	}
+	addr, errAddr := netip.ParseAddr(host)
+	if errAddr != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
@@ -0,0 +1,94 @@
+package ipallow
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestParseAndAllows(t *testing.T) {
+	list, errParse := Parse([]string{"10.0.0.0/8", " 192.168.1.5 ", "2001:db8::/32", ""})
+	if errParse != nil {
+		t.Fatalf("parse: %v", errParse)
+	}
+	cases := map[string]bool{
+		"10.1.2.3":        true,
+		"192.168.1.5":     true,
+		"192.168.1.6":     false,
+		"2001:db8::1":     true,
+		"::ffff:10.9.9.9": true,
+		"2001:db9::1":     false,
+	}
+	for ip, want := range cases {
+		if got := list.Allows(netip.MustParseAddr(ip)); got != want {
+			t.Fatalf("Allows(%s)=%v want %v", ip, got, want)
+		}
+	}
+	if !List(nil).Allows(netip.MustParseAddr("1.2.3.4")) {
+		t.Fatalf("expected empty list to allow everything")
+	}
+	if _, errParse := Parse([]string{"10.0.0.0/33"}); errParse == nil {
+		t.Fatalf("expected invalid cidr error")
+	}
+	if _, errParse := Parse([]string{"not-an-ip"}); errParse == nil {
+		t.Fatalf("expected invalid ip error")
+	}
+}
+
+func TestNormalizeCanonicalizesEntries(t *testing.T) {
+	entries, errNormalize := Normalize([]string{"10.1.2.3/8", "10.0.0.0/8", "1.2.3.4"})
+	if errNormalize != nil {
+		t.Fatalf("normalize: %v", errNormalize)
+	}
+	if len(entries) != 2 || entries[0] != "10.0.0.0/8" || entries[1] != "1.2.3.4" {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+}
+
+func TestClientIPHonorsOnlyTrustedProxies(t *testing.T) {
+	trusted, _ := Parse([]string{"127.0.0.1", "172.16.0.0/12"})
+
+	direct := httptest.NewRequest("GET", "/v1/models", nil)
+	direct.RemoteAddr = "203.0.113.9:4000"
+	direct.Header.Set("X-Forwarded-For", "198.51.100.1")
+	if got := ClientIP(direct, trusted); got.String() != "203.0.113.9" {
+		t.Fatalf("expected untrusted peer address, got %s", got)
+	}
+
+	proxied := httptest.NewRequest("GET", "/v1/models", nil)
+	proxied.RemoteAddr = "127.0.0.1:4000"
+	proxied.Header.Set("X-Forwarded-For", "6.6.6.6, 198.51.100.1, 172.16.0.2")
+	if got := ClientIP(proxied, trusted); got.String() != "198.51.100.1" {
+		t.Fatalf("expected rightmost untrusted hop, got %s", got)
+	}
+
+	realIP := httptest.NewRequest("GET", "/v1/models", nil)
+	realIP.RemoteAddr = "127.0.0.1:4000"
+	realIP.Header.Set("X-Real-IP", "198.51.100.7")
+	if got := ClientIP(realIP, trusted); got.String() != "198.51.100.7" {
+		t.Fatalf("expected X-Real-IP, got %s", got)
+	}
+}
+
+func TestFromJSONSkipsMalformedEntries(t *testing.T) {
+	list := FromJSON([]byte(`["10.0.0.1","bogus","192.168.0.0/16"]`))
+	if got := list.Entries(); len(got) != 2 || got[0] != "10.0.0.1" || got[1] != "192.168.0.0/16" {
+		t.Fatalf("unexpected entries %v", got)
+	}
+}
+
+func TestEncodeRoundTrips(t *testing.T) {
+	raw, errEncode := Encode([]string{" 10.0.0.5 ", "10.0.0.5"})
+	if errEncode != nil {
+		t.Fatalf("encode: %v", errEncode)
+	}
+	if string(raw) != `["10.0.0.5"]` {
+		t.Fatalf("unexpected encoding %s", raw)
+	}
+	if _, errEncode := Encode([]string{"10.0.0"}); errEncode == nil {
+		t.Fatalf("expected invalid entry error")
+	}
+	if raw, _ := Encode(nil); string(raw) != `[]` {
+		t.Fatalf("expected empty array, got %s", raw)
+	}
+}
@@ -0,0 +1,176 @@
+package kpisnapshot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultSnapshotInterval = time.Hour
+	// defaultBackfillDays bounds how far back missing snapshots are rebuilt from raw usage.
+	defaultBackfillDays = 31
+)
+
+// Snapshotter periodically aggregates daily dashboard KPIs into kpi_snapshots.
+type Snapshotter struct {
+	db           *gorm.DB
+	interval     time.Duration
+	backfillDays int
+}
+
+// NewSnapshotter constructs a KPI snapshotter; returns nil when db is nil.
+func NewSnapshotter(db *gorm.DB) *Snapshotter {
+	if db == nil {
+		return nil
+	}
+	return &Snapshotter{db: db, interval: defaultSnapshotInterval, backfillDays: defaultBackfillDays}
+}
+
+// Start launches the snapshot loop in a background goroutine.
+func (s *Snapshotter) Start(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go s.run(ctx)
+	log.Infof("kpi snapshotter started (interval=%s)", s.interval)
+}
+
+func (s *Snapshotter) run(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		if errSnapshot := s.SnapshotOnce(ctx, time.Now()); errSnapshot != nil {
+			log.WithError(errSnapshot).Warn("kpi snapshotter: snapshot failed")
+		}
+		timer := time.NewTimer(s.interval)
+		select {
+		case <-ctx.Done():
+			if !timer.Stop() {
+				<-timer.C
+			}
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// SnapshotOnce refreshes today's partial snapshot, finalizes yesterday and backfills missing recent days.
+func (s *Snapshotter) SnapshotOnce(ctx context.Context, now time.Time) error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	today := DayStart(now)
+	existing := make(map[int64]struct{})
+	var days []time.Time
+	if errFind := s.db.WithContext(ctx).
+		Model(&models.KPISnapshot{}).
+		Where("day >= ?", today.AddDate(0, 0, -s.backfillDays)).
+		Pluck("day", &days).Error; errFind != nil {
+		return fmt.Errorf("kpi snapshot: load days: %w", errFind)
+	}
+	for _, day := range days {
+		existing[DayStart(day).Unix()] = struct{}{}
+	}
+
+	for offset := s.backfillDays; offset >= 0; offset-- {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		day := today.AddDate(0, 0, -offset)
+		_, done := existing[day.Unix()]
+		// Yesterday is always refreshed once more to pick up late-arriving usage rows.
+		if done && offset > 1 {
+			continue
+		}
+		if _, errSnapshot := SnapshotDay(ctx, s.db, day); errSnapshot != nil {
+			return errSnapshot
+		}
+	}
+	return nil
+}
+
+// DayStart returns the local midnight that starts the day containing t.
+func DayStart(t time.Time) time.Time {
+	local := t.In(time.Local)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// SnapshotDay aggregates usage for the local day starting at day and upserts its snapshot.
+func SnapshotDay(ctx context.Context, db *gorm.DB, day time.Time) (*models.KPISnapshot, error) {
+	if db == nil {
+		return nil, fmt.Errorf("kpi snapshot: nil db")
+	}
+	start := DayStart(day)
+	end := start.AddDate(0, 0, 1)
+
+	var stats struct {
+		Requests       int64
+		FailedRequests int64
+		InputTokens    int64
+		OutputTokens   int64
+		CachedTokens   int64
+		TotalTokens    int64
+		CostMicros     int64
+		ActiveUsers    int64
+		ActiveAPIKeys  int64
+	}
+	if errScan := db.WithContext(ctx).
+		Model(&models.Usage{}).
+		Where("requested_at >= ? AND requested_at < ?", start.UTC(), end.UTC()).
+		Select(`
+			COUNT(*) AS requests,
+			COALESCE(SUM(CASE WHEN failed THEN 1 ELSE 0 END), 0) AS failed_requests,
+			COALESCE(SUM(input_tokens), 0) AS input_tokens,
+			COALESCE(SUM(output_tokens), 0) AS output_tokens,
+			COALESCE(SUM(cached_tokens), 0) AS cached_tokens,
+			COALESCE(SUM(total_tokens), 0) AS total_tokens,
+			COALESCE(SUM(cost_micros), 0) AS cost_micros,
+			COUNT(DISTINCT user_id) AS active_users,
+			COUNT(DISTINCT api_key_id) AS active_api_keys
+		`).
+		Scan(&stats).Error; errScan != nil {
+		return nil, fmt.Errorf("kpi snapshot: aggregate %s: %w", start.Format(time.DateOnly), errScan)
+	}
+
+	successRate := 0.0
+	if stats.Requests > 0 {
+		successRate = float64(stats.Requests-stats.FailedRequests) / float64(stats.Requests) * 100
+	}
+	now := time.Now().UTC()
+	snapshot := models.KPISnapshot{
+		Day:            start,
+		Requests:       stats.Requests,
+		FailedRequests: stats.FailedRequests,
+		SuccessRate:    successRate,
+		InputTokens:    stats.InputTokens,
+		OutputTokens:   stats.OutputTokens,
+		CachedTokens:   stats.CachedTokens,
+		TotalTokens:    stats.TotalTokens,
+		CostMicros:     stats.CostMicros,
+		ActiveUsers:    stats.ActiveUsers,
+		ActiveAPIKeys:  stats.ActiveAPIKeys,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if errUpsert := db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "day"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"requests", "failed_requests", "success_rate",
+			"input_tokens", "output_tokens", "cached_tokens", "total_tokens",
+			"cost_micros", "active_users", "active_api_keys", "updated_at",
+		}),
+	}).Create(&snapshot).Error; errUpsert != nil {
+		return nil, fmt.Errorf("kpi snapshot: upsert %s: %w", start.Format(time.DateOnly), errUpsert)
+	}
+	return &snapshot, nil
+}
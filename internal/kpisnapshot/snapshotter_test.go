@@ -0,0 +1,98 @@
+package kpisnapshot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func setupSnapshotDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:kpi_snapshot_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func TestSnapshotDayAggregatesAndUpserts(t *testing.T) {
+	conn := setupSnapshotDB(t)
+	ctx := context.Background()
+	day := DayStart(time.Now()).AddDate(0, 0, -1)
+
+	userA, userB := uint64(1), uint64(2)
+	rows := []models.Usage{
+		{Provider: "codex", Model: "gpt-5", UserID: &userA, RequestedAt: day.Add(time.Hour), InputTokens: 10, OutputTokens: 5, TotalTokens: 15, CostMicros: 100},
+		{Provider: "codex", Model: "gpt-5", UserID: &userB, RequestedAt: day.Add(2 * time.Hour), Failed: true, TotalTokens: 0},
+		{Provider: "codex", Model: "gpt-5", UserID: &userA, RequestedAt: day.AddDate(0, 0, 1).Add(time.Hour), TotalTokens: 99, CostMicros: 999},
+	}
+	if errCreate := conn.Create(&rows).Error; errCreate != nil {
+		t.Fatalf("create usage: %v", errCreate)
+	}
+
+	snapshot, errSnapshot := SnapshotDay(ctx, conn, day)
+	if errSnapshot != nil {
+		t.Fatalf("SnapshotDay: %v", errSnapshot)
+	}
+	if snapshot.Requests != 2 || snapshot.FailedRequests != 1 || snapshot.SuccessRate != 50 {
+		t.Fatalf("unexpected request stats: %+v", snapshot)
+	}
+	if snapshot.TotalTokens != 15 || snapshot.CostMicros != 100 || snapshot.ActiveUsers != 2 {
+		t.Fatalf("unexpected usage stats: %+v", snapshot)
+	}
+
+	late := models.Usage{Provider: "codex", Model: "gpt-5", UserID: &userA, RequestedAt: day.Add(3 * time.Hour), TotalTokens: 5, CostMicros: 50}
+	if errCreate := conn.Create(&late).Error; errCreate != nil {
+		t.Fatalf("create late usage: %v", errCreate)
+	}
+	if _, errSnapshot = SnapshotDay(ctx, conn, day); errSnapshot != nil {
+		t.Fatalf("SnapshotDay refresh: %v", errSnapshot)
+	}
+	var stored []models.KPISnapshot
+	if errFind := conn.Find(&stored).Error; errFind != nil {
+		t.Fatalf("load snapshots: %v", errFind)
+	}
+	if len(stored) != 1 {
+		t.Fatalf("expected a single upserted snapshot, got %d", len(stored))
+	}
+	if stored[0].Requests != 3 || stored[0].CostMicros != 150 {
+		t.Fatalf("expected refreshed snapshot, got %+v", stored[0])
+	}
+}
+
+func TestSnapshotOnceBackfillsMissingDays(t *testing.T) {
+	conn := setupSnapshotDB(t)
+	s := NewSnapshotter(conn)
+	s.backfillDays = 3
+
+	if errSnapshot := s.SnapshotOnce(context.Background(), time.Now()); errSnapshot != nil {
+		t.Fatalf("SnapshotOnce: %v", errSnapshot)
+	}
+	var count int64
+	if errCount := conn.Model(&models.KPISnapshot{}).Count(&count).Error; errCount != nil {
+		t.Fatalf("count snapshots: %v", errCount)
+	}
+	if count != 4 {
+		t.Fatalf("expected 4 daily snapshots, got %d", count)
+	}
+
+	if errSnapshot := s.SnapshotOnce(context.Background(), time.Now()); errSnapshot != nil {
+		t.Fatalf("SnapshotOnce rerun: %v", errSnapshot)
+	}
+	if errCount := conn.Model(&models.KPISnapshot{}).Count(&count).Error; errCount != nil {
+		t.Fatalf("count snapshots: %v", errCount)
+	}
+	if count != 4 {
+		t.Fatalf("expected rerun to keep 4 snapshots, got %d", count)
+	}
+}
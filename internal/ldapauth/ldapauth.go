@@ -0,0 +1,232 @@
+// Package ldapauth authenticates front users against an LDAP or Active Directory server:
+// a service bind finds the user entry, a bind as that entry checks the password, and the
+// entry's name, email and group attributes are mapped onto the local user account.
+package ldapauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultUserFilter     = "(&(objectClass=person)(uid={username}))"
+	defaultNameAttribute  = "cn"
+	defaultEmailAttribute = "mail"
+	defaultGroupAttribute = "memberOf"
+	defaultTimeoutSeconds = 10
+)
+
+// Authentication errors.
+var (
+	// ErrInvalidCredentials is returned when the directory rejects the user's password.
+	ErrInvalidCredentials = errors.New("ldap: invalid credentials")
+	// ErrUserNotFound is returned when the user filter matches no single entry.
+	ErrUserNotFound = errors.New("ldap: user not found")
+)
+
+// Config mirrors the LDAP setting.
+type Config struct {
+	Enabled            bool                `json:"enabled"`              // Whether front logins try the directory first.
+	URL                string              `json:"url"`                  // Server URL, e.g. ldaps://dc.example.com:636.
+	StartTLS           bool                `json:"start_tls"`            // Upgrade ldap:// connections with StartTLS.
+	InsecureSkipVerify bool                `json:"insecure_skip_verify"` // Skip TLS certificate checks; for testing only.
+	BindDN             string              `json:"bind_dn"`              // Service account used to search for users; empty binds anonymously.
+	BindPassword       string              `json:"bind_password"`        // Service account password.
+	BaseDN             string              `json:"base_dn"`              // Subtree searched for users.
+	UserFilter         string              `json:"user_filter"`          // Search filter; {username} is replaced with the escaped login name.
+	NameAttribute      string              `json:"name_attribute"`       // Attribute mapped to the display name; defaults to cn.
+	EmailAttribute     string              `json:"email_attribute"`      // Attribute mapped to the email; defaults to mail.
+	GroupAttribute     string              `json:"group_attribute"`      // Attribute listing group DNs; defaults to memberOf.
+	GroupMapping       map[string][]string `json:"group_mapping"`        // User group names granted per directory group DN or CN.
+	AutoProvision      *bool               `json:"auto_provision"`       // Create users on first login; defaults to true.
+	TimeoutSeconds     int                 `json:"timeout_seconds"`      // Dial and request timeout.
+}
+
+// LoadConfig reads the LDAP setting; invalid values disable directory logins.
+func LoadConfig() Config {
+	var cfg Config
+	raw, ok := internalsettings.DBConfigValue(internalsettings.LDAPKey)
+	if ok && len(bytes.TrimSpace(raw)) > 0 {
+		if errUnmarshal := json.Unmarshal(raw, &cfg); errUnmarshal != nil {
+			log.WithError(errUnmarshal).Warn("ldap: invalid setting")
+			cfg = Config{}
+		}
+	}
+	return cfg.withDefaults()
+}
+
+func (c Config) withDefaults() Config {
+	c.URL = strings.TrimSpace(c.URL)
+	c.BaseDN = strings.TrimSpace(c.BaseDN)
+	if strings.TrimSpace(c.UserFilter) == "" {
+		c.UserFilter = defaultUserFilter
+	}
+	if strings.TrimSpace(c.NameAttribute) == "" {
+		c.NameAttribute = defaultNameAttribute
+	}
+	if strings.TrimSpace(c.EmailAttribute) == "" {
+		c.EmailAttribute = defaultEmailAttribute
+	}
+	if strings.TrimSpace(c.GroupAttribute) == "" {
+		c.GroupAttribute = defaultGroupAttribute
+	}
+	if c.TimeoutSeconds <= 0 {
+		c.TimeoutSeconds = defaultTimeoutSeconds
+	}
+	return c
+}
+
+// Ready reports whether directory logins are enabled and configured.
+func (c Config) Ready() bool {
+	return c.Enabled && c.URL != "" && c.BaseDN != ""
+}
+
+// ProvisionEnabled reports whether unknown directory users get a local account.
+func (c Config) ProvisionEnabled() bool {
+	return c.AutoProvision == nil || *c.AutoProvision
+}
+
+func (c Config) timeout() time.Duration {
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+// Identity is a user entry authenticated by the directory.
+type Identity struct {
+	DN     string   // Distinguished name of the entry.
+	Name   string   // Display name.
+	Email  string   // Email address.
+	Groups []string // Group DNs the entry belongs to.
+}
+
+// conn is the part of an LDAP connection used for authentication.
+type conn interface {
+	Bind(username, password string) error
+	Search(request *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close() error
+}
+
+// dial opens a directory connection; replaced in tests.
+var dial = func(ctx context.Context, cfg Config) (conn, error) {
+	dialer := &net.Dialer{Timeout: cfg.timeout()}
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify} //nolint:gosec // Opt-in for lab directories.
+	l, errDial := ldap.DialURL(cfg.URL, ldap.DialWithDialer(dialer), ldap.DialWithTLSConfig(tlsConfig))
+	if errDial != nil {
+		return nil, fmt.Errorf("ldap: dial: %w", errDial)
+	}
+	l.SetTimeout(cfg.timeout())
+	if cfg.StartTLS && strings.HasPrefix(strings.ToLower(cfg.URL), "ldap://") {
+		if errTLS := l.StartTLS(tlsConfig); errTLS != nil {
+			_ = l.Close()
+			return nil, fmt.Errorf("ldap: start tls: %w", errTLS)
+		}
+	}
+	return l, nil
+}
+
+// Authenticate checks username and password against the directory and returns the entry.
+func Authenticate(ctx context.Context, cfg Config, username, password string) (*Identity, error) {
+	cfg = cfg.withDefaults()
+	username = strings.TrimSpace(username)
+	// An empty password would be an unauthenticated bind that many servers accept.
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+	l, errDial := dial(ctx, cfg)
+	if errDial != nil {
+		return nil, errDial
+	}
+	defer func() { _ = l.Close() }()
+
+	if cfg.BindDN != "" {
+		if errBind := l.Bind(cfg.BindDN, cfg.BindPassword); errBind != nil {
+			return nil, fmt.Errorf("ldap: service bind: %w", errBind)
+		}
+	}
+	filter := strings.ReplaceAll(cfg.UserFilter, "{username}", ldap.EscapeFilter(username))
+	result, errSearch := l.Search(ldap.NewSearchRequest(
+		cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, cfg.TimeoutSeconds, false,
+		filter,
+		[]string{"dn", cfg.NameAttribute, cfg.EmailAttribute, cfg.GroupAttribute},
+		nil,
+	))
+	if errSearch != nil {
+		return nil, fmt.Errorf("ldap: search: %w", errSearch)
+	}
+	if len(result.Entries) != 1 {
+		return nil, ErrUserNotFound
+	}
+	entry := result.Entries[0]
+	if errBind := l.Bind(entry.DN, password); errBind != nil {
+		if ldap.IsErrorWithCode(errBind, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("ldap: user bind: %w", errBind)
+	}
+	return &Identity{
+		DN:     entry.DN,
+		Name:   strings.TrimSpace(entry.GetAttributeValue(cfg.NameAttribute)),
+		Email:  strings.TrimSpace(entry.GetAttributeValue(cfg.EmailAttribute)),
+		Groups: entry.GetAttributeValues(cfg.GroupAttribute),
+	}, nil
+}
+
+// MapGroups returns the user group names granted to the directory groups, matching mapping
+// keys case-insensitively against either the full group DN or its leading CN.
+func (c Config) MapGroups(groups []string) []string {
+	member := make(map[string]struct{}, len(groups)*2)
+	for _, group := range groups {
+		group = strings.ToLower(strings.TrimSpace(group))
+		if group == "" {
+			continue
+		}
+		member[group] = struct{}{}
+		if cn := leadingCN(group); cn != "" {
+			member[cn] = struct{}{}
+		}
+	}
+	var out []string
+	seen := make(map[string]struct{})
+	for key, names := range c.GroupMapping {
+		if _, ok := member[strings.ToLower(strings.TrimSpace(key))]; !ok {
+			continue
+		}
+		for _, name := range names {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if _, dup := seen[name]; dup {
+				continue
+			}
+			seen[name] = struct{}{}
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// leadingCN returns the value of the first RDN when it is a cn, e.g. "devs" for
+// "cn=devs,ou=groups,dc=example,dc=com".
+func leadingCN(dn string) string {
+	parsed, errParse := ldap.ParseDN(dn)
+	if errParse != nil || len(parsed.RDNs) == 0 {
+		return ""
+	}
+	for _, attr := range parsed.RDNs[0].Attributes {
+		if strings.EqualFold(attr.Type, "cn") {
+			return strings.ToLower(attr.Value)
+		}
+	}
+	return ""
+}
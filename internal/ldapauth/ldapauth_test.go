@@ -0,0 +1,190 @@
+package ldapauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+type fakeConn struct {
+	passwords map[string]string
+	entries   []*ldap.Entry
+	filters   []string
+}
+
+func (f *fakeConn) Bind(username, password string) error {
+	if want, ok := f.passwords[username]; ok && want == password {
+		return nil
+	}
+	return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+}
+
+func (f *fakeConn) Search(request *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	f.filters = append(f.filters, request.Filter)
+	return &ldap.SearchResult{Entries: f.entries}, nil
+}
+
+func (f *fakeConn) Close() error { return nil }
+
+func useFakeDirectory(t *testing.T, fake *fakeConn) {
+	t.Helper()
+	original := dial
+	dial = func(context.Context, Config) (conn, error) { return fake, nil }
+	t.Cleanup(func() { dial = original })
+}
+
+const aliceDN = "uid=alice,ou=people,dc=example,dc=com"
+
+func newDirectory() *fakeConn {
+	return &fakeConn{
+		passwords: map[string]string{"cn=svc,dc=example,dc=com": "svc-secret", aliceDN: "alice-secret"},
+		entries: []*ldap.Entry{ldap.NewEntry(aliceDN, map[string][]string{
+			"cn":       {"Alice Example"},
+			"mail":     {"alice@example.com"},
+			"memberOf": {"CN=Developers,OU=Groups,DC=example,DC=com", "cn=staff,ou=groups,dc=example,dc=com"},
+		})},
+	}
+}
+
+func testConfig() Config {
+	return Config{
+		Enabled:      true,
+		URL:          "ldap://directory.test",
+		BindDN:       "cn=svc,dc=example,dc=com",
+		BindPassword: "svc-secret",
+		BaseDN:       "dc=example,dc=com",
+	}.withDefaults()
+}
+
+func TestAuthenticate(t *testing.T) {
+	fake := newDirectory()
+	useFakeDirectory(t, fake)
+	cfg := testConfig()
+
+	identity, errAuth := Authenticate(context.Background(), cfg, "alice", "alice-secret")
+	if errAuth != nil {
+		t.Fatalf("authenticate: %v", errAuth)
+	}
+	if identity.DN != aliceDN || identity.Name != "Alice Example" || identity.Email != "alice@example.com" || len(identity.Groups) != 2 {
+		t.Fatalf("unexpected identity: %+v", identity)
+	}
+
+	if _, errAuth = Authenticate(context.Background(), cfg, "alice", "wrong"); !errors.Is(errAuth, ErrInvalidCredentials) {
+		t.Fatalf("expected invalid credentials, got %v", errAuth)
+	}
+	if _, errAuth = Authenticate(context.Background(), cfg, "alice", ""); !errors.Is(errAuth, ErrInvalidCredentials) {
+		t.Fatalf("expected empty password to be rejected, got %v", errAuth)
+	}
+
+	if _, errAuth = Authenticate(context.Background(), cfg, "bob*)(uid=*", "x"); errAuth == nil {
+		t.Fatal("expected failure for unknown user")
+	}
+	if got := fake.filters[len(fake.filters)-1]; got != `(&(objectClass=person)(uid=bob\2a\29\28uid=\2a))` {
+		t.Fatalf("username not escaped in filter: %s", got)
+	}
+
+	fake.entries = nil
+	if _, errAuth = Authenticate(context.Background(), cfg, "carol", "x"); !errors.Is(errAuth, ErrUserNotFound) {
+		t.Fatalf("expected user not found, got %v", errAuth)
+	}
+}
+
+func TestMapGroups(t *testing.T) {
+	cfg := Config{GroupMapping: map[string][]string{
+		"developers":                                 {"Pro"},
+		"cn=staff,ou=groups,dc=example,dc=com":       {"Staff", "Pro"},
+		"cn=contractors,ou=groups,dc=example,dc=com": {"Limited"},
+	}}
+	got := cfg.MapGroups([]string{"CN=Developers,OU=Groups,DC=example,DC=com", "CN=Staff,OU=Groups,DC=Example,DC=com"})
+	if len(got) != 2 {
+		t.Fatalf("expected Pro and Staff, got %v", got)
+	}
+	seen := map[string]bool{}
+	for _, name := range got {
+		seen[name] = true
+	}
+	if !seen["Pro"] || !seen["Staff"] {
+		t.Fatalf("expected Pro and Staff, got %v", got)
+	}
+}
+
+func setupLDAPDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:ldapauth_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func TestSyncUser(t *testing.T) {
+	conn := setupLDAPDB(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var defaultGroup models.UserGroup
+	if errFind := conn.Where("is_default = ?", true).First(&defaultGroup).Error; errFind != nil {
+		t.Fatalf("load default group: %v", errFind)
+	}
+	proGroup := models.UserGroup{Name: "Pro", CreatedAt: now, UpdatedAt: now}
+	if errCreate := conn.Create(&proGroup).Error; errCreate != nil {
+		t.Fatalf("create group: %v", errCreate)
+	}
+	cfg := testConfig()
+	cfg.GroupMapping = map[string][]string{"developers": {"Pro"}}
+	identity := &Identity{DN: aliceDN, Name: "Alice", Email: "alice@example.com", Groups: []string{"cn=developers,dc=example,dc=com"}}
+
+	user, errSync := SyncUser(context.Background(), conn, cfg, "alice", identity, now)
+	if errSync != nil {
+		t.Fatalf("provision: %v", errSync)
+	}
+	if user.ID == 0 || user.LDAPDN != aliceDN || user.Email != "alice@example.com" || user.EmailVerifiedAt == nil {
+		t.Fatalf("unexpected provisioned user: %+v", user)
+	}
+	if ids := user.UserGroupID.Values(); len(ids) != 1 || ids[0] != proGroup.ID {
+		t.Fatalf("expected mapped group %d, got %v", proGroup.ID, ids)
+	}
+
+	identity.Name = "Alice Renamed"
+	identity.Groups = nil
+	user, errSync = SyncUser(context.Background(), conn, cfg, "alice", identity, now.Add(time.Hour))
+	if errSync != nil {
+		t.Fatalf("sync: %v", errSync)
+	}
+	var stored models.User
+	if errFind := conn.First(&stored, user.ID).Error; errFind != nil {
+		t.Fatalf("load user: %v", errFind)
+	}
+	if stored.Name != "Alice Renamed" {
+		t.Fatalf("expected name refresh, got %q", stored.Name)
+	}
+	if ids := stored.UserGroupID.Values(); len(ids) != 1 || ids[0] != defaultGroup.ID {
+		t.Fatalf("expected fallback to default group, got %v", ids)
+	}
+
+	local := models.User{Username: "bob", Password: "x", Active: true, CreatedAt: now, UpdatedAt: now}
+	if errCreate := conn.Create(&local).Error; errCreate != nil {
+		t.Fatalf("create local user: %v", errCreate)
+	}
+	bob := &Identity{DN: "uid=bob,ou=people,dc=example,dc=com"}
+	if _, errSync = SyncUser(context.Background(), conn, cfg, "bob", bob, now); !errors.Is(errSync, ErrLocalAccount) {
+		t.Fatalf("expected local account conflict, got %v", errSync)
+	}
+
+	disabled := false
+	cfg.AutoProvision = &disabled
+	carol := &Identity{DN: "uid=carol,ou=people,dc=example,dc=com"}
+	if _, errSync = SyncUser(context.Background(), conn, cfg, "carol", carol, now); !errors.Is(errSync, ErrProvisionDisabled) {
+		t.Fatalf("expected provisioning disabled, got %v", errSync)
+	}
+}
@@ -0,0 +1,169 @@
+package ldapauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Account linking errors.
+var (
+	// ErrLocalAccount is returned when the login name belongs to a local account, which a
+	// directory identity never takes over.
+	ErrLocalAccount = errors.New("ldap: username belongs to a local account")
+	// ErrProvisionDisabled is returned for unknown directory users when auto provisioning is off.
+	ErrProvisionDisabled = errors.New("ldap: auto provisioning disabled")
+)
+
+// SyncUser finds or provisions the local account linked to the directory entry and refreshes
+// its name, email and user groups from the directory.
+func SyncUser(ctx context.Context, db *gorm.DB, cfg Config, username string, identity *Identity, now time.Time) (models.User, error) {
+	var user models.User
+	if db == nil || identity == nil {
+		return user, errors.New("ldap: missing database or identity")
+	}
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		groups, errGroups := resolveUserGroups(tx, cfg, identity.Groups)
+		if errGroups != nil {
+			return errGroups
+		}
+		errFind := tx.Where("ldap_dn = ?", identity.DN).First(&user).Error
+		switch {
+		case errors.Is(errFind, gorm.ErrRecordNotFound):
+			if !cfg.ProvisionEnabled() {
+				return ErrProvisionDisabled
+			}
+			var taken int64
+			if errCount := tx.Model(&models.User{}).Where("username = ?", username).Count(&taken).Error; errCount != nil {
+				return errCount
+			}
+			if taken > 0 {
+				return ErrLocalAccount
+			}
+			// Directory accounts get an unknown random password so local login cannot succeed.
+			hash, errHash := randomPasswordHash()
+			if errHash != nil {
+				return errHash
+			}
+			user = models.User{
+				Username:  username,
+				Name:      identity.Name,
+				Password:  hash,
+				LDAPDN:    identity.DN,
+				Active:    true,
+				CreatedAt: now,
+				UpdatedAt: now,
+			}
+			if email, ok := freeEmail(tx, identity.Email, 0); ok {
+				// The directory is authoritative for its addresses.
+				user.Email = email
+				user.EmailVerifiedAt = &now
+			}
+			if groups == nil {
+				defaultGroup, errDefault := defaultUserGroup(tx)
+				if errDefault != nil {
+					return errDefault
+				}
+				groups = defaultGroup
+			}
+			user.UserGroupID = groups
+			return tx.Create(&user).Error
+		case errFind != nil:
+			return errFind
+		}
+
+		updates := map[string]any{"updated_at": now}
+		if identity.Name != "" && identity.Name != user.Name {
+			updates["name"] = identity.Name
+			user.Name = identity.Name
+		}
+		if identity.Email != "" && !strings.EqualFold(identity.Email, user.Email) {
+			if email, ok := freeEmail(tx, identity.Email, user.ID); ok {
+				updates["email"] = email
+				updates["email_verified_at"] = now
+				user.Email = email
+				user.EmailVerifiedAt = &now
+			}
+		}
+		if groups != nil {
+			updates["user_group_id"] = groups
+			user.UserGroupID = groups
+		}
+		if len(updates) == 1 {
+			return nil
+		}
+		return tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(updates).Error
+	})
+	if errTx != nil {
+		return models.User{}, errTx
+	}
+	return user, nil
+}
+
+// resolveUserGroups maps directory groups to user group IDs. It returns nil when no group
+// mapping is configured, leaving the account's groups alone; when a mapping exists but none
+// of its groups match, the user falls back to the default user group.
+func resolveUserGroups(tx *gorm.DB, cfg Config, groups []string) (models.UserGroupIDs, error) {
+	if len(cfg.GroupMapping) == 0 {
+		return nil, nil
+	}
+	names := cfg.MapGroups(groups)
+	if len(names) == 0 {
+		return defaultUserGroup(tx)
+	}
+	var ids []uint64
+	if errFind := tx.Model(&models.UserGroup{}).Where("name IN ?", names).Order("id ASC").Pluck("id", &ids).Error; errFind != nil {
+		return nil, errFind
+	}
+	if len(ids) != len(names) {
+		log.Warnf("ldap: some mapped user groups do not exist: %v", names)
+	}
+	if len(ids) == 0 {
+		return defaultUserGroup(tx)
+	}
+	out := make(models.UserGroupIDs, 0, len(ids))
+	for i := range ids {
+		out = append(out, &ids[i])
+	}
+	return out, nil
+}
+
+func defaultUserGroup(tx *gorm.DB) (models.UserGroupIDs, error) {
+	var group models.UserGroup
+	if errFind := tx.Where("is_default = ?", true).First(&group).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return models.UserGroupIDs{}, nil
+		}
+		return nil, errFind
+	}
+	return models.UserGroupIDs{&group.ID}, nil
+}
+
+// freeEmail reports whether email may be stored on the user without clashing with another account.
+func freeEmail(tx *gorm.DB, email string, userID uint64) (string, bool) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return "", false
+	}
+	var taken int64
+	if errCount := tx.Model(&models.User{}).Where("email = ? AND id <> ?", email, userID).Count(&taken).Error; errCount != nil || taken > 0 {
+		return "", false
+	}
+	return email, true
+}
+
+func randomPasswordHash() (string, error) {
+	buf := make([]byte, 32)
+	if _, errRead := rand.Read(buf); errRead != nil {
+		return "", errRead
+	}
+	return security.HashPassword(hex.EncodeToString(buf))
+}
@@ -0,0 +1,535 @@
+// Package lifecycle applies automated user lifecycle policies: API keys of users whose bill
+// quota and prepaid balance are used up are suspended until they top up, and accounts that
+// stay inactive are warned and later anonymized. Every action is recorded in
+// user_lifecycle_events and published on the event bus, which feeds the audit log, admin
+// notifications and user mail.
+package lifecycle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultIntervalMinutes = 60
+	// disabledRecheck is how often a disabled scheduler rereads its setting.
+	disabledRecheck = 5 * time.Minute
+	// anonymizedDomain is the reserved domain of scrubbed email addresses.
+	anonymizedDomain = "anonymized.invalid"
+)
+
+// Config mirrors the USER_LIFECYCLE setting. Day thresholds of zero turn the action off.
+type Config struct {
+	Enabled               bool `json:"enabled"`                 // Whether the scheduler runs.
+	IntervalMinutes       int  `json:"interval_minutes"`        // How often policies are evaluated.
+	SuspendOnZeroBalance  bool `json:"suspend_on_zero_balance"` // Disable API keys when bill quota and prepaid balance are used up.
+	WarnInactiveDays      int  `json:"warn_inactive_days"`      // Warn users without requests for this many days, e.g. 60.
+	AnonymizeInactiveDays int  `json:"anonymize_inactive_days"` // Anonymize users without requests for this many days, e.g. 180.
+}
+
+// LoadConfig reads USER_LIFECYCLE and fills defaults; invalid values disable the scheduler.
+func LoadConfig() Config {
+	var cfg Config
+	raw, ok := internalsettings.DBConfigValue(internalsettings.UserLifecycleKey)
+	if ok && len(bytes.TrimSpace(raw)) > 0 {
+		if errUnmarshal := json.Unmarshal(raw, &cfg); errUnmarshal != nil {
+			log.WithError(errUnmarshal).Warn("user lifecycle: invalid setting")
+			cfg = Config{}
+		}
+	}
+	if cfg.AnonymizeInactiveDays > 0 && cfg.WarnInactiveDays > 0 && cfg.AnonymizeInactiveDays <= cfg.WarnInactiveDays {
+		log.Warnf("user lifecycle: anonymize_inactive_days (%d) must exceed warn_inactive_days (%d), anonymization disabled", cfg.AnonymizeInactiveDays, cfg.WarnInactiveDays)
+	}
+	return cfg.withDefaults()
+}
+
+func (c Config) withDefaults() Config {
+	if c.IntervalMinutes <= 0 {
+		c.IntervalMinutes = defaultIntervalMinutes
+	}
+	if c.WarnInactiveDays < 0 {
+		c.WarnInactiveDays = 0
+	}
+	if c.AnonymizeInactiveDays < 0 {
+		c.AnonymizeInactiveDays = 0
+	}
+	if c.AnonymizeInactiveDays > 0 && c.WarnInactiveDays > 0 && c.AnonymizeInactiveDays <= c.WarnInactiveDays {
+		c.AnonymizeInactiveDays = 0
+	}
+	return c
+}
+
+// Scheduler periodically evaluates the lifecycle policies.
+type Scheduler struct {
+	db *gorm.DB
+}
+
+// NewScheduler constructs a lifecycle scheduler; returns nil when db is nil.
+func NewScheduler(db *gorm.DB) *Scheduler {
+	if db == nil {
+		return nil
+	}
+	return &Scheduler{db: db}
+}
+
+// Start launches the scheduling loop in a background goroutine.
+func (s *Scheduler) Start(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go s.run(ctx)
+	log.Info("user lifecycle scheduler started")
+}
+
+func (s *Scheduler) run(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		cfg := LoadConfig()
+		wait := disabledRecheck
+		if cfg.Enabled {
+			wait = time.Duration(cfg.IntervalMinutes) * time.Minute
+			if result, errRun := s.RunOnce(ctx, cfg, time.Now().UTC()); errRun != nil {
+				log.WithError(errRun).Warn("user lifecycle scheduler: run failed")
+			} else if result.Changed() {
+				log.Infof("user lifecycle scheduler: resumed %d, suspended %d, warned %d, anonymized %d users",
+					result.Resumed, result.Suspended, result.Warned, result.Anonymized)
+			}
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			if !timer.Stop() {
+				<-timer.C
+			}
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// Result counts the users changed by one run.
+type Result struct {
+	Resumed    int `json:"resumed"`    // Users whose suspended keys were re-enabled.
+	Suspended  int `json:"suspended"`  // Users whose keys were disabled for lack of balance.
+	Warned     int `json:"warned"`     // Users warned about inactivity.
+	Anonymized int `json:"anonymized"` // Users anonymized after inactivity.
+}
+
+// Changed reports whether the run acted on any user.
+func (r Result) Changed() bool {
+	return r.Resumed > 0 || r.Suspended > 0 || r.Warned > 0 || r.Anonymized > 0
+}
+
+// RunOnce evaluates every policy once. Suspensions are lifted before new ones are taken so a
+// user who topped up is not suspended again in the same run; suspensions are lifted even when
+// the suspend policy was turned off since.
+func (s *Scheduler) RunOnce(ctx context.Context, cfg Config, now time.Time) (Result, error) {
+	var result Result
+	if s == nil || s.db == nil {
+		return result, nil
+	}
+	cfg = cfg.withDefaults()
+	now = now.UTC()
+
+	resumed, errResume := s.resume(ctx, now)
+	result.Resumed = resumed
+	if errResume != nil {
+		return result, errResume
+	}
+	if cfg.SuspendOnZeroBalance {
+		suspended, errSuspend := s.suspend(ctx, now)
+		result.Suspended = suspended
+		if errSuspend != nil {
+			return result, errSuspend
+		}
+	}
+	if cfg.WarnInactiveDays > 0 {
+		warned, errWarn := s.warnInactive(ctx, cfg, now)
+		result.Warned = warned
+		if errWarn != nil {
+			return result, errWarn
+		}
+	}
+	if cfg.AnonymizeInactiveDays > 0 {
+		anonymized, errAnonymize := s.anonymizeInactive(ctx, cfg, now)
+		result.Anonymized = anonymized
+		if errAnonymize != nil {
+			return result, errAnonymize
+		}
+	}
+	return result, nil
+}
+
+// HasBalance reports whether the user has a paid bill with quota left in its current period
+// or an unexpired prepaid card with balance.
+func HasBalance(ctx context.Context, db *gorm.DB, userID uint64, now time.Time) (bool, error) {
+	if db == nil {
+		return false, errors.New("user lifecycle: nil db")
+	}
+	now = now.UTC()
+	var bills int64
+	if errCount := db.WithContext(ctx).Model(&models.Bill{}).
+		Where("user_id = ? AND is_enabled = ? AND status = ? AND left_quota > 0", userID, true, models.BillStatusPaid).
+		Where("period_start <= ? AND period_end >= ?", now, now).
+		Count(&bills).Error; errCount != nil {
+		return false, fmt.Errorf("user lifecycle: count bills of user %d: %w", userID, errCount)
+	}
+	if bills > 0 {
+		return true, nil
+	}
+	var cards int64
+	if errCount := db.WithContext(ctx).Model(&models.PrepaidCard{}).
+		Where("redeemed_user_id = ? AND is_enabled = ? AND balance > 0 AND redeemed_at IS NOT NULL", userID, true).
+		Where("(expires_at IS NULL OR expires_at >= ?)", now).
+		Count(&cards).Error; errCount != nil {
+		return false, fmt.Errorf("user lifecycle: count prepaid cards of user %d: %w", userID, errCount)
+	}
+	return cards > 0, nil
+}
+
+// suspend disables the active API keys of users without balance.
+func (s *Scheduler) suspend(ctx context.Context, now time.Time) (int, error) {
+	var userIDs []uint64
+	if errFind := s.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("user_id IS NOT NULL AND active = ? AND revoked_at IS NULL AND is_admin = ?", true, false).
+		Distinct("user_id").
+		Order("user_id ASC").
+		Pluck("user_id", &userIDs).Error; errFind != nil {
+		return 0, fmt.Errorf("user lifecycle: list users with active keys: %w", errFind)
+	}
+
+	suspended := 0
+	for _, userID := range userIDs {
+		if ctx.Err() != nil {
+			return suspended, ctx.Err()
+		}
+		funded, errBalance := HasBalance(ctx, s.db, userID, now)
+		if errBalance != nil {
+			return suspended, errBalance
+		}
+		if funded {
+			continue
+		}
+		const reason = "bill quota and prepaid balance are used up"
+		var keyIDs []uint64
+		errTx := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if errPluck := tx.Model(&models.APIKey{}).
+				Where("user_id = ? AND active = ? AND revoked_at IS NULL AND is_admin = ?", userID, true, false).
+				Order("id ASC").
+				Pluck("id", &keyIDs).Error; errPluck != nil {
+				return errPluck
+			}
+			if len(keyIDs) == 0 {
+				return nil
+			}
+			if errUpdate := tx.Model(&models.APIKey{}).Where("id IN ?", keyIDs).
+				Updates(map[string]any{"active": false, "updated_at": now}).Error; errUpdate != nil {
+				return errUpdate
+			}
+			return recordEvent(tx, userID, models.LifecycleActionSuspend, reason, keyIDs, now)
+		})
+		if errTx != nil {
+			return suspended, fmt.Errorf("user lifecycle: suspend user %d: %w", userID, errTx)
+		}
+		if len(keyIDs) == 0 {
+			continue
+		}
+		suspended++
+		events.PublishUserLifecycle(ctx, events.TypeUserSuspended, userID, "api keys disabled: "+reason, keyIDs, nil)
+	}
+	return suspended, nil
+}
+
+// resume re-enables the keys of suspended users who have balance again. Keys revoked or
+// deleted in the meantime stay off.
+func (s *Scheduler) resume(ctx context.Context, now time.Time) (int, error) {
+	var open []models.UserLifecycleEvent
+	if errFind := s.db.WithContext(ctx).
+		Where("action = ? AND resolved_at IS NULL", models.LifecycleActionSuspend).
+		Order("user_id ASC, id ASC").
+		Find(&open).Error; errFind != nil {
+		return 0, fmt.Errorf("user lifecycle: list suspensions: %w", errFind)
+	}
+	byUser := make(map[uint64][]models.UserLifecycleEvent)
+	order := make([]uint64, 0)
+	for _, row := range open {
+		if _, seen := byUser[row.UserID]; !seen {
+			order = append(order, row.UserID)
+		}
+		byUser[row.UserID] = append(byUser[row.UserID], row)
+	}
+
+	resumed := 0
+	for _, userID := range order {
+		if ctx.Err() != nil {
+			return resumed, ctx.Err()
+		}
+		funded, errBalance := HasBalance(ctx, s.db, userID, now)
+		if errBalance != nil {
+			return resumed, errBalance
+		}
+		if !funded {
+			continue
+		}
+		rows := byUser[userID]
+		rowIDs := make([]uint64, 0, len(rows))
+		suspendedKeys := make([]uint64, 0)
+		for _, row := range rows {
+			rowIDs = append(rowIDs, row.ID)
+			suspendedKeys = append(suspendedKeys, decodeKeyIDs(row.APIKeyIDs)...)
+		}
+		var keyIDs []uint64
+		errTx := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if len(suspendedKeys) > 0 {
+				if errPluck := tx.Model(&models.APIKey{}).
+					Where("id IN ? AND user_id = ? AND active = ? AND revoked_at IS NULL", suspendedKeys, userID, false).
+					Order("id ASC").
+					Pluck("id", &keyIDs).Error; errPluck != nil {
+					return errPluck
+				}
+			}
+			if len(keyIDs) > 0 {
+				if errUpdate := tx.Model(&models.APIKey{}).Where("id IN ?", keyIDs).
+					Updates(map[string]any{"active": true, "updated_at": now}).Error; errUpdate != nil {
+					return errUpdate
+				}
+			}
+			if errResolve := tx.Model(&models.UserLifecycleEvent{}).Where("id IN ?", rowIDs).
+				Update("resolved_at", now).Error; errResolve != nil {
+				return errResolve
+			}
+			return recordEvent(tx, userID, models.LifecycleActionResume, "balance available again", keyIDs, now)
+		})
+		if errTx != nil {
+			return resumed, fmt.Errorf("user lifecycle: resume user %d: %w", userID, errTx)
+		}
+		resumed++
+		events.PublishUserLifecycle(ctx, events.TypeUserResumed, userID, "api keys re-enabled after top-up", keyIDs, nil)
+	}
+	return resumed, nil
+}
+
+// inactiveUser is a user without requests since a cutoff.
+type inactiveUser struct {
+	ID        uint64
+	CreatedAt time.Time
+}
+
+// inactiveUsers lists users created before cutoff who sent no request since and were not
+// anonymized yet.
+func (s *Scheduler) inactiveUsers(ctx context.Context, cutoff time.Time) ([]inactiveUser, error) {
+	var users []inactiveUser
+	if errFind := s.db.WithContext(ctx).Model(&models.User{}).
+		Select("users.id, users.created_at").
+		Where("users.created_at < ?", cutoff).
+		Where("NOT EXISTS (SELECT 1 FROM usages WHERE usages.user_id = users.id AND usages.requested_at >= ?)", cutoff).
+		Where("NOT EXISTS (SELECT 1 FROM user_lifecycle_events WHERE user_lifecycle_events.user_id = users.id AND user_lifecycle_events.action = ?)", models.LifecycleActionAnonymize).
+		Order("users.id ASC").
+		Scan(&users).Error; errFind != nil {
+		return nil, fmt.Errorf("user lifecycle: list inactive users: %w", errFind)
+	}
+	return users, nil
+}
+
+// lastWarning returns when the user was last warned about inactivity, or nil when the user
+// was never warned or sent a request after the latest warning.
+func (s *Scheduler) lastWarning(ctx context.Context, userID uint64) (*time.Time, error) {
+	var rows []models.UserLifecycleEvent
+	if errFind := s.db.WithContext(ctx).
+		Where("user_id = ? AND action = ?", userID, models.LifecycleActionWarnInactive).
+		Order("created_at DESC, id DESC").
+		Limit(1).
+		Find(&rows).Error; errFind != nil {
+		return nil, fmt.Errorf("user lifecycle: load warning of user %d: %w", userID, errFind)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	warnedAt := rows[0].CreatedAt
+	var later int64
+	if errCount := s.db.WithContext(ctx).Model(&models.Usage{}).
+		Where("user_id = ? AND requested_at > ?", userID, warnedAt).
+		Count(&later).Error; errCount != nil {
+		return nil, fmt.Errorf("user lifecycle: count requests of user %d: %w", userID, errCount)
+	}
+	if later > 0 {
+		return nil, nil
+	}
+	return &warnedAt, nil
+}
+
+// warnInactive warns users inactive for WarnInactiveDays, once per period of inactivity.
+func (s *Scheduler) warnInactive(ctx context.Context, cfg Config, now time.Time) (int, error) {
+	users, errUsers := s.inactiveUsers(ctx, now.AddDate(0, 0, -cfg.WarnInactiveDays))
+	if errUsers != nil {
+		return 0, errUsers
+	}
+	warned := 0
+	for _, user := range users {
+		if ctx.Err() != nil {
+			return warned, ctx.Err()
+		}
+		warnedAt, errWarned := s.lastWarning(ctx, user.ID)
+		if errWarned != nil {
+			return warned, errWarned
+		}
+		if warnedAt != nil {
+			continue
+		}
+		reason := fmt.Sprintf("no requests for %d days", cfg.WarnInactiveDays)
+		if errRecord := recordEvent(s.db.WithContext(ctx), user.ID, models.LifecycleActionWarnInactive, reason, nil, now); errRecord != nil {
+			return warned, fmt.Errorf("user lifecycle: warn user %d: %w", user.ID, errRecord)
+		}
+		warned++
+		data := map[string]any{"inactive_days": cfg.WarnInactiveDays}
+		if cfg.AnonymizeInactiveDays > 0 {
+			data["anonymize_after_days"] = cfg.AnonymizeInactiveDays - cfg.WarnInactiveDays
+		}
+		events.PublishUserLifecycle(ctx, events.TypeUserInactive, user.ID, reason, nil, data)
+	}
+	return warned, nil
+}
+
+// anonymizeInactive anonymizes users inactive for AnonymizeInactiveDays. When warnings are
+// on, a user is only anonymized once the warning is as old as the gap between both thresholds.
+func (s *Scheduler) anonymizeInactive(ctx context.Context, cfg Config, now time.Time) (int, error) {
+	users, errUsers := s.inactiveUsers(ctx, now.AddDate(0, 0, -cfg.AnonymizeInactiveDays))
+	if errUsers != nil {
+		return 0, errUsers
+	}
+	anonymized := 0
+	for _, user := range users {
+		if ctx.Err() != nil {
+			return anonymized, ctx.Err()
+		}
+		if cfg.WarnInactiveDays > 0 {
+			warnedAt, errWarned := s.lastWarning(ctx, user.ID)
+			if errWarned != nil {
+				return anonymized, errWarned
+			}
+			if warnedAt == nil || warnedAt.After(now.AddDate(0, 0, -(cfg.AnonymizeInactiveDays-cfg.WarnInactiveDays))) {
+				continue
+			}
+		}
+		reason := fmt.Sprintf("no requests for %d days", cfg.AnonymizeInactiveDays)
+		keyIDs, errAnonymize := Anonymize(ctx, s.db, user.ID, reason, now)
+		if errAnonymize != nil {
+			return anonymized, errAnonymize
+		}
+		anonymized++
+		events.PublishUserLifecycle(ctx, events.TypeUserAnonymized, user.ID, "user anonymized: "+reason, keyIDs, nil)
+	}
+	return anonymized, nil
+}
+
+// Anonymize scrubs the personal data of a user, disables the account, revokes its API keys
+// and drops linked logins and recovery codes. Usage and billing rows are kept for accounting.
+// Returns the revoked key IDs.
+func Anonymize(ctx context.Context, db *gorm.DB, userID uint64, reason string, now time.Time) ([]uint64, error) {
+	if db == nil {
+		return nil, errors.New("user lifecycle: nil db")
+	}
+	now = now.UTC()
+	id := strconv.FormatUint(userID, 10)
+	var keyIDs []uint64
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if errPluck := tx.Model(&models.APIKey{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Order("id ASC").
+			Pluck("id", &keyIDs).Error; errPluck != nil {
+			return errPluck
+		}
+		if len(keyIDs) > 0 {
+			if errRevoke := tx.Model(&models.APIKey{}).Where("id IN ?", keyIDs).
+				Updates(map[string]any{"active": false, "revoked_at": now, "updated_at": now}).Error; errRevoke != nil {
+				return errRevoke
+			}
+		}
+		res := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]any{
+			"username":                "anonymized-" + id,
+			"name":                    "",
+			"email":                   "anonymized-" + id + "@" + anonymizedDomain,
+			"password":                "",
+			"ldap_dn":                 "",
+			"email_verified_at":       nil,
+			"totp_secret":             "",
+			"passkey_id":              nil,
+			"passkey_public_key":      nil,
+			"passkey_sign_count":      nil,
+			"passkey_backup_eligible": nil,
+			"passkey_backup_state":    nil,
+			"active":                  false,
+			"disabled":                true,
+			"sessions_revoked_at":     now,
+			"updated_at":              now,
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if errDelete := tx.Where("user_id = ?", userID).Delete(&models.UserIdentity{}).Error; errDelete != nil {
+			return errDelete
+		}
+		if errDelete := tx.Where("user_id = ?", userID).Delete(&models.EmailToken{}).Error; errDelete != nil {
+			return errDelete
+		}
+		if errDelete := tx.Where("owner_type = ? AND owner_id = ?", models.MFAOwnerUser, userID).Delete(&models.MFARecoveryCode{}).Error; errDelete != nil {
+			return errDelete
+		}
+		if errResolve := tx.Model(&models.UserLifecycleEvent{}).
+			Where("user_id = ? AND action = ? AND resolved_at IS NULL", userID, models.LifecycleActionSuspend).
+			Update("resolved_at", now).Error; errResolve != nil {
+			return errResolve
+		}
+		return recordEvent(tx, userID, models.LifecycleActionAnonymize, reason, keyIDs, now)
+	})
+	if errTx != nil {
+		return nil, fmt.Errorf("user lifecycle: anonymize user %d: %w", userID, errTx)
+	}
+	return keyIDs, nil
+}
+
+// recordEvent stores one lifecycle action.
+func recordEvent(tx *gorm.DB, userID uint64, action, detail string, keyIDs []uint64, now time.Time) error {
+	if keyIDs == nil {
+		keyIDs = []uint64{}
+	}
+	raw, errMarshal := json.Marshal(keyIDs)
+	if errMarshal != nil {
+		return errMarshal
+	}
+	return tx.Create(&models.UserLifecycleEvent{
+		UserID:    userID,
+		Action:    action,
+		Detail:    detail,
+		APIKeyIDs: raw,
+		CreatedAt: now,
+	}).Error
+}
+
+// decodeKeyIDs reads the key IDs stored on a lifecycle event.
+func decodeKeyIDs(raw []byte) []uint64 {
+	var ids []uint64
+	if len(bytes.TrimSpace(raw)) > 0 {
+		_ = json.Unmarshal(raw, &ids)
+	}
+	return ids
+}
@@ -0,0 +1,212 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func setupLifecycleDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:lifecycle_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func seedUser(t *testing.T, conn *gorm.DB, name string, createdAt time.Time) *models.User {
+	t.Helper()
+	user := &models.User{Username: name, Email: name + "@example.com", Password: "hash", Active: true, CreatedAt: createdAt}
+	if errCreate := conn.Create(user).Error; errCreate != nil {
+		t.Fatalf("seed user: %v", errCreate)
+	}
+	return user
+}
+
+func seedKey(t *testing.T, conn *gorm.DB, userID uint64, key string) *models.APIKey {
+	t.Helper()
+	row := &models.APIKey{UserID: &userID, Name: key, APIKey: key, Active: true}
+	if errCreate := conn.Create(row).Error; errCreate != nil {
+		t.Fatalf("seed api key: %v", errCreate)
+	}
+	return row
+}
+
+func keyActive(t *testing.T, conn *gorm.DB, id uint64) bool {
+	t.Helper()
+	var row models.APIKey
+	if errFind := conn.First(&row, id).Error; errFind != nil {
+		t.Fatalf("load api key: %v", errFind)
+	}
+	return row.Active
+}
+
+func TestConfigRejectsAnonymizeBeforeWarning(t *testing.T) {
+	cfg := Config{WarnInactiveDays: 60, AnonymizeInactiveDays: 30}.withDefaults()
+	if cfg.AnonymizeInactiveDays != 0 {
+		t.Fatalf("anonymize days = %d, want 0", cfg.AnonymizeInactiveDays)
+	}
+	if cfg.IntervalMinutes != defaultIntervalMinutes {
+		t.Fatalf("interval = %d, want %d", cfg.IntervalMinutes, defaultIntervalMinutes)
+	}
+}
+
+func TestSuspendAndResumeOnBalance(t *testing.T) {
+	conn := setupLifecycleDB(t)
+	ctx := context.Background()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	broke := seedUser(t, conn, "broke", now.AddDate(0, -1, 0))
+	funded := seedUser(t, conn, "funded", now.AddDate(0, -1, 0))
+	brokeKey := seedKey(t, conn, broke.ID, "sk-broke")
+	fundedKey := seedKey(t, conn, funded.ID, "sk-funded")
+	redeemedAt := now.Add(-time.Hour)
+	if errCreate := conn.Create(&models.PrepaidCard{
+		Name: "card", CardSN: "SN-1", Password: "pw", Amount: 10, Balance: 5, IsEnabled: true,
+		RedeemedUserID: &funded.ID, RedeemedAt: &redeemedAt,
+	}).Error; errCreate != nil {
+		t.Fatalf("seed prepaid card: %v", errCreate)
+	}
+
+	scheduler := NewScheduler(conn)
+	cfg := Config{Enabled: true, SuspendOnZeroBalance: true}
+	result, errRun := scheduler.RunOnce(ctx, cfg, now)
+	if errRun != nil {
+		t.Fatalf("RunOnce: %v", errRun)
+	}
+	if result.Suspended != 1 || result.Resumed != 0 {
+		t.Fatalf("result = %+v, want one suspension", result)
+	}
+	if keyActive(t, conn, brokeKey.ID) {
+		t.Fatal("key of user without balance still active")
+	}
+	if !keyActive(t, conn, fundedKey.ID) {
+		t.Fatal("key of funded user was disabled")
+	}
+
+	result, errRun = scheduler.RunOnce(ctx, cfg, now.Add(time.Hour))
+	if errRun != nil {
+		t.Fatalf("second RunOnce: %v", errRun)
+	}
+	if result.Changed() {
+		t.Fatalf("second run result = %+v, want no changes", result)
+	}
+
+	if errCreate := conn.Create(&models.PrepaidCard{
+		Name: "card", CardSN: "SN-2", Password: "pw", Amount: 10, Balance: 10, IsEnabled: true,
+		RedeemedUserID: &broke.ID, RedeemedAt: &redeemedAt,
+	}).Error; errCreate != nil {
+		t.Fatalf("seed top-up: %v", errCreate)
+	}
+	result, errRun = scheduler.RunOnce(ctx, cfg, now.Add(2*time.Hour))
+	if errRun != nil {
+		t.Fatalf("third RunOnce: %v", errRun)
+	}
+	if result.Resumed != 1 || result.Suspended != 0 {
+		t.Fatalf("third run result = %+v, want one resume", result)
+	}
+	if !keyActive(t, conn, brokeKey.ID) {
+		t.Fatal("suspended key not re-enabled after top-up")
+	}
+	var open int64
+	if errCount := conn.Model(&models.UserLifecycleEvent{}).
+		Where("action = ? AND resolved_at IS NULL", models.LifecycleActionSuspend).
+		Count(&open).Error; errCount != nil {
+		t.Fatalf("count open suspensions: %v", errCount)
+	}
+	if open != 0 {
+		t.Fatalf("open suspensions = %d, want 0", open)
+	}
+}
+
+func TestWarnThenAnonymizeInactiveUser(t *testing.T) {
+	conn := setupLifecycleDB(t)
+	ctx := context.Background()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	idle := seedUser(t, conn, "idle", start)
+	busy := seedUser(t, conn, "busy", start)
+	idleKey := seedKey(t, conn, idle.ID, "sk-idle")
+	if errCreate := conn.Create(&models.UserIdentity{UserID: idle.ID, Provider: "github", Subject: "42"}).Error; errCreate != nil {
+		t.Fatalf("seed identity: %v", errCreate)
+	}
+
+	scheduler := NewScheduler(conn)
+	cfg := Config{Enabled: true, WarnInactiveDays: 60, AnonymizeInactiveDays: 180}
+
+	// Day 179: only idle is past the warning threshold, nobody can be anonymized yet.
+	now := start.AddDate(0, 0, 179)
+	busyAt := now.Add(-time.Hour)
+	if errCreate := conn.Create(&models.Usage{Provider: "p", Model: "m", UserID: &busy.ID, RequestedAt: busyAt}).Error; errCreate != nil {
+		t.Fatalf("seed usage: %v", errCreate)
+	}
+	result, errRun := scheduler.RunOnce(ctx, cfg, now)
+	if errRun != nil {
+		t.Fatalf("RunOnce: %v", errRun)
+	}
+	if result.Warned != 1 || result.Anonymized != 0 {
+		t.Fatalf("result = %+v, want one warning", result)
+	}
+	result, errRun = scheduler.RunOnce(ctx, cfg, now.Add(time.Hour))
+	if errRun != nil {
+		t.Fatalf("second RunOnce: %v", errRun)
+	}
+	if result.Warned != 0 {
+		t.Fatalf("second run warned %d users, want 0", result.Warned)
+	}
+
+	// Past 180 days but the warning is younger than the 120 day notice period.
+	result, errRun = scheduler.RunOnce(ctx, cfg, start.AddDate(0, 0, 200))
+	if errRun != nil {
+		t.Fatalf("third RunOnce: %v", errRun)
+	}
+	if result.Anonymized != 0 {
+		t.Fatalf("anonymized %d users before the notice period ended", result.Anonymized)
+	}
+
+	result, errRun = scheduler.RunOnce(ctx, cfg, now.AddDate(0, 0, 121))
+	if errRun != nil {
+		t.Fatalf("fourth RunOnce: %v", errRun)
+	}
+	if result.Anonymized != 1 || result.Warned != 1 {
+		t.Fatalf("result = %+v, want busy warned and idle anonymized", result)
+	}
+
+	var user models.User
+	if errFind := conn.First(&user, idle.ID).Error; errFind != nil {
+		t.Fatalf("load user: %v", errFind)
+	}
+	if user.Username != fmt.Sprintf("anonymized-%d", idle.ID) || user.Email != fmt.Sprintf("anonymized-%d@anonymized.invalid", idle.ID) {
+		t.Fatalf("user not scrubbed: %q %q", user.Username, user.Email)
+	}
+	if user.Active || !user.Disabled || user.Password != "" {
+		t.Fatalf("user still usable: active=%v disabled=%v", user.Active, user.Disabled)
+	}
+	if keyActive(t, conn, idleKey.ID) {
+		t.Fatal("api key of anonymized user still active")
+	}
+	var identities int64
+	if errCount := conn.Model(&models.UserIdentity{}).Where("user_id = ?", idle.ID).Count(&identities).Error; errCount != nil {
+		t.Fatalf("count identities: %v", errCount)
+	}
+	if identities != 0 {
+		t.Fatalf("identities = %d, want 0", identities)
+	}
+
+	result, errRun = scheduler.RunOnce(ctx, cfg, now.AddDate(0, 0, 200))
+	if errRun != nil {
+		t.Fatalf("final RunOnce: %v", errRun)
+	}
+	if result.Changed() {
+		t.Fatalf("anonymized users acted on again: %+v", result)
+	}
+}
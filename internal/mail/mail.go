@@ -0,0 +1,310 @@
+// Package mail sends transactional email to users over SMTP. Settings come from the SMTP DB
+// config key and are re-read on every send, so admins can change them without a restart.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultSMTPPort = 587
+	dialTimeout     = 10 * time.Second
+)
+
+// ErrDisabled is returned when SMTP is not configured.
+var ErrDisabled = errors.New("mail: smtp is not configured")
+
+// Config mirrors the SMTP setting.
+type Config struct {
+	Host        string `json:"host"`         // SMTP server host.
+	Port        int    `json:"port"`         // SMTP server port; defaults to 587.
+	Username    string `json:"username"`     // SMTP auth username; empty skips auth.
+	Password    string `json:"password"`     // SMTP auth password.
+	From        string `json:"from"`         // Sender address.
+	FromName    string `json:"from_name"`    // Sender display name; defaults to the site name.
+	ImplicitTLS bool   `json:"implicit_tls"` // Connect over TLS (port 465) instead of STARTTLS.
+	BaseURL     string `json:"base_url"`     // Public portal URL used in emailed links.
+}
+
+// LoadConfig reads the SMTP setting; invalid values disable mail.
+func LoadConfig() Config {
+	var cfg Config
+	raw, ok := internalsettings.DBConfigValue(internalsettings.SMTPKey)
+	if !ok || len(bytes.TrimSpace(raw)) == 0 {
+		return cfg
+	}
+	if errUnmarshal := json.Unmarshal(raw, &cfg); errUnmarshal != nil {
+		log.WithError(errUnmarshal).Warn("mail: invalid smtp setting")
+		return Config{}
+	}
+	cfg.Host = strings.TrimSpace(cfg.Host)
+	cfg.From = strings.TrimSpace(cfg.From)
+	cfg.FromName = strings.TrimSpace(cfg.FromName)
+	cfg.BaseURL = strings.TrimSpace(cfg.BaseURL)
+	if cfg.Port <= 0 {
+		cfg.Port = defaultSMTPPort
+	}
+	return cfg
+}
+
+// Enabled reports whether the config has enough to send mail.
+func (c Config) Enabled() bool {
+	return c.Host != "" && c.From != ""
+}
+
+// Link joins path onto the configured base URL; it returns path unchanged without one.
+func (c Config) Link(path string) string {
+	base := strings.TrimRight(c.BaseURL, "/")
+	if base == "" {
+		return path
+	}
+	return base + "/" + strings.TrimLeft(path, "/")
+}
+
+// Attachment is a file sent along with a message.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Message is one outbound email.
+type Message struct {
+	To          string // Recipient address.
+	Subject     string
+	Body        string // Plain-text body.
+	Attachments []Attachment
+}
+
+// sendFunc delivers a rendered message; tests replace it to capture mail.
+type sendFunc func(ctx context.Context, cfg Config, from string, to []string, msg []byte) error
+
+// Mailer sends messages with the current SMTP settings.
+type Mailer struct {
+	config func() Config
+	send   sendFunc
+	now    func() time.Time
+}
+
+// New constructs a mailer reading the SMTP setting on every send.
+func New() *Mailer {
+	return &Mailer{config: LoadConfig, send: sendSMTP, now: time.Now}
+}
+
+// Config returns the current mail settings.
+func (m *Mailer) Config() Config {
+	if m == nil || m.config == nil {
+		return Config{}
+	}
+	return m.config()
+}
+
+// Enabled reports whether SMTP is configured.
+func (m *Mailer) Enabled() bool {
+	return m.Config().Enabled()
+}
+
+// Send delivers msg; it returns ErrDisabled when SMTP is not configured.
+func (m *Mailer) Send(ctx context.Context, msg Message) error {
+	cfg := m.Config()
+	if !cfg.Enabled() {
+		return ErrDisabled
+	}
+	to, errAddr := mail.ParseAddress(strings.TrimSpace(msg.To))
+	if errAddr != nil {
+		return fmt.Errorf("mail: invalid recipient %q: %w", msg.To, errAddr)
+	}
+	if errCtx := ctx.Err(); errCtx != nil {
+		return errCtx
+	}
+	body, errBuild := buildMessage(cfg, to.Address, msg, m.now())
+	if errBuild != nil {
+		return errBuild
+	}
+	if errSend := m.send(ctx, cfg, cfg.From, []string{to.Address}, body); errSend != nil {
+		return fmt.Errorf("mail: send via %s: %w", cfg.Host, errSend)
+	}
+	return nil
+}
+
+// SendTemplate renders the named template with data and sends it to to.
+func (m *Mailer) SendTemplate(ctx context.Context, to, name string, data any, attachments ...Attachment) error {
+	subject, body, errRender := Render(name, data)
+	if errRender != nil {
+		return errRender
+	}
+	return m.Send(ctx, Message{To: to, Subject: subject, Body: body, Attachments: attachments})
+}
+
+// buildMessage renders an RFC 5322 message, switching to multipart/mixed for attachments.
+func buildMessage(cfg Config, to string, msg Message, now time.Time) ([]byte, error) {
+	from := cfg.From
+	if name := fromName(cfg); name != "" {
+		from = (&mail.Address{Name: name, Address: cfg.From}).String()
+	}
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(msg.Subject)
+	var b bytes.Buffer
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + to + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	b.WriteString("Date: " + now.UTC().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("Message-ID: " + messageID(cfg.From) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	if len(msg.Attachments) == 0 {
+		b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+		b.WriteString("\r\n")
+		b.WriteString(normalizeNewlines(msg.Body))
+		return b.Bytes(), nil
+	}
+
+	boundary, errBoundary := randomHex(16)
+	if errBoundary != nil {
+		return nil, errBoundary
+	}
+	b.WriteString("Content-Type: multipart/mixed; boundary=\"" + boundary + "\"\r\n")
+	b.WriteString("\r\n")
+	b.WriteString("--" + boundary + "\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(normalizeNewlines(msg.Body) + "\r\n")
+	for _, att := range msg.Attachments {
+		contentType := att.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		b.WriteString("--" + boundary + "\r\n")
+		b.WriteString("Content-Type: " + contentType + "\r\n")
+		b.WriteString("Content-Transfer-Encoding: base64\r\n")
+		b.WriteString("Content-Disposition: " + mime.FormatMediaType("attachment", map[string]string{"filename": att.Filename}) + "\r\n")
+		b.WriteString("\r\n")
+		encoded := base64.StdEncoding.EncodeToString(att.Data)
+		for len(encoded) > 76 {
+			b.WriteString(encoded[:76] + "\r\n")
+			encoded = encoded[76:]
+		}
+		b.WriteString(encoded + "\r\n")
+	}
+	b.WriteString("--" + boundary + "--\r\n")
+	return b.Bytes(), nil
+}
+
+// fromName returns the configured sender name, falling back to the site name.
+func fromName(cfg Config) string {
+	if cfg.FromName != "" {
+		return cfg.FromName
+	}
+	return SiteName()
+}
+
+// SiteName returns the configured site name used in mail copy.
+func SiteName() string {
+	raw, ok := internalsettings.DBConfigValue(internalsettings.SiteNameKey)
+	if !ok {
+		return ""
+	}
+	var name string
+	if errUnmarshal := json.Unmarshal(raw, &name); errUnmarshal != nil {
+		return ""
+	}
+	return strings.TrimSpace(name)
+}
+
+func normalizeNewlines(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\n", "\r\n")
+}
+
+func messageID(from string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 && at < len(from)-1 {
+		domain = from[at+1:]
+	}
+	id, errID := randomHex(12)
+	if errID != nil {
+		id = strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return "<" + id + "@" + domain + ">"
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, errRead := rand.Read(buf); errRead != nil {
+		return "", fmt.Errorf("mail: random: %w", errRead)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// sendSMTP delivers over STARTTLS (when offered) or implicit TLS.
+func sendSMTP(ctx context.Context, cfg Config, from string, to []string, msg []byte) error {
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	var conn net.Conn
+	var errDial error
+	if cfg.ImplicitTLS {
+		conn, errDial = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: cfg.Host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, errDial = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if errDial != nil {
+		return errDial
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	client, errClient := smtp.NewClient(conn, cfg.Host)
+	if errClient != nil {
+		_ = conn.Close()
+		return errClient
+	}
+	defer func() { _ = client.Close() }()
+	if !cfg.ImplicitTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if errTLS := client.StartTLS(&tls.Config{ServerName: cfg.Host}); errTLS != nil {
+				return errTLS
+			}
+		}
+	}
+	if cfg.Username != "" {
+		if errAuth := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); errAuth != nil {
+			return errAuth
+		}
+	}
+	if errMail := client.Mail(from); errMail != nil {
+		return errMail
+	}
+	for _, rcpt := range to {
+		if errRcpt := client.Rcpt(rcpt); errRcpt != nil {
+			return errRcpt
+		}
+	}
+	w, errData := client.Data()
+	if errData != nil {
+		return errData
+	}
+	if _, errWrite := w.Write(msg); errWrite != nil {
+		_ = w.Close()
+		return errWrite
+	}
+	if errClose := w.Close(); errClose != nil {
+		return errClose
+	}
+	return client.Quit()
+}
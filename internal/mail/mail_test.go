@@ -0,0 +1,172 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func setupMailDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:mail_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+// captureMailer returns a mailer that records sent messages instead of dialing SMTP.
+func captureMailer(sent *[]string) *Mailer {
+	return &Mailer{
+		config: func() Config {
+			return Config{Host: "smtp.example.com", Port: 2525, From: "noreply@example.com", FromName: "Example", BaseURL: "https://portal.example.com/"}
+		},
+		send: func(_ context.Context, _ Config, _ string, _ []string, msg []byte) error {
+			*sent = append(*sent, string(msg))
+			return nil
+		},
+		now: time.Now,
+	}
+}
+
+func TestRenderTemplates(t *testing.T) {
+	t.Parallel()
+
+	subject, body, errRender := Render(TemplatePasswordReset, map[string]any{
+		"SiteName": "Acme", "Username": "alice", "Link": "https://x/reset?token=t", "ExpiresIn": "1 hour",
+	})
+	if errRender != nil {
+		t.Fatalf("Render: %v", errRender)
+	}
+	if subject != "Reset your password for Acme" || !strings.Contains(body, "https://x/reset?token=t") {
+		t.Fatalf("unexpected render %q / %q", subject, body)
+	}
+	if _, _, errMissing := Render("missing", nil); errMissing == nil {
+		t.Fatal("expected unknown template to fail")
+	}
+}
+
+func TestSendBuildsMultipartWithAttachment(t *testing.T) {
+	t.Parallel()
+
+	var sent []string
+	m := captureMailer(&sent)
+	errSend := m.Send(context.Background(), Message{
+		To:          "bob@example.com",
+		Subject:     "Invoice INV-1",
+		Body:        "see attached\n",
+		Attachments: []Attachment{{Filename: "INV-1.pdf", ContentType: "application/pdf", Data: []byte("%PDF-1.4")}},
+	})
+	if errSend != nil || len(sent) != 1 {
+		t.Fatalf("Send = %v, sent %d", errSend, len(sent))
+	}
+	msg := sent[0]
+	for _, want := range []string{
+		"From: \"Example\" <noreply@example.com>\r\n",
+		"To: bob@example.com\r\n",
+		"Content-Type: multipart/mixed;",
+		"filename=INV-1.pdf",
+		"JVBERi0xLjQ=",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("message missing %q:\n%s", want, msg)
+		}
+	}
+
+	disabled := &Mailer{config: func() Config { return Config{} }, send: m.send, now: time.Now}
+	if errDisabled := disabled.Send(context.Background(), Message{To: "bob@example.com"}); !errors.Is(errDisabled, ErrDisabled) {
+		t.Fatalf("expected ErrDisabled, got %v", errDisabled)
+	}
+}
+
+func TestPasswordResetTokenIsSingleUse(t *testing.T) {
+	t.Parallel()
+
+	conn := setupMailDB(t)
+	user := models.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+	var sent []string
+	m := captureMailer(&sent)
+	ctx := context.Background()
+	if errSend := m.SendPasswordReset(ctx, conn, &user); errSend != nil {
+		t.Fatalf("SendPasswordReset: %v", errSend)
+	}
+	idx := strings.Index(sent[0], "https://portal.example.com/reset-password?token=")
+	if idx < 0 {
+		t.Fatalf("reset link missing:\n%s", sent[0])
+	}
+	token := strings.Fields(sent[0][idx+len("https://portal.example.com/reset-password?token="):])[0]
+
+	// A newer link revokes the older one.
+	if errSend := m.SendPasswordReset(ctx, conn, &user); errSend != nil {
+		t.Fatalf("SendPasswordReset again: %v", errSend)
+	}
+	if _, errOld := ConsumeToken(ctx, conn, models.EmailTokenPurposePasswordReset, token, time.Now()); !errors.Is(errOld, ErrInvalidToken) {
+		t.Fatalf("expected revoked token to be rejected, got %v", errOld)
+	}
+
+	fresh, errIssue := IssueToken(ctx, conn, &user, models.EmailTokenPurposePasswordReset, time.Hour, time.Now())
+	if errIssue != nil {
+		t.Fatalf("IssueToken: %v", errIssue)
+	}
+	if _, errPurpose := ConsumeToken(ctx, conn, models.EmailTokenPurposeVerifyEmail, fresh, time.Now()); !errors.Is(errPurpose, ErrInvalidToken) {
+		t.Fatalf("expected purpose mismatch to be rejected, got %v", errPurpose)
+	}
+	if _, errExpired := ConsumeToken(ctx, conn, models.EmailTokenPurposePasswordReset, fresh, time.Now().Add(2*time.Hour)); !errors.Is(errExpired, ErrInvalidToken) {
+		t.Fatalf("expected expired token to be rejected, got %v", errExpired)
+	}
+	row, errConsume := ConsumeToken(ctx, conn, models.EmailTokenPurposePasswordReset, fresh, time.Now())
+	if errConsume != nil || row.UserID != user.ID {
+		t.Fatalf("ConsumeToken = %+v, %v", row, errConsume)
+	}
+	if _, errReuse := ConsumeToken(ctx, conn, models.EmailTokenPurposePasswordReset, fresh, time.Now()); !errors.Is(errReuse, ErrInvalidToken) {
+		t.Fatalf("expected reused token to be rejected, got %v", errReuse)
+	}
+}
+
+func TestLowBalanceNotifierThrottlesPerUser(t *testing.T) {
+	t.Parallel()
+
+	conn := setupMailDB(t)
+	user := models.User{Username: "carol", Email: "carol@example.com", Password: "x"}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+	var sent []string
+	n := NewLowBalanceNotifier(conn, captureMailer(&sent))
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	n.now = func() time.Time { return now }
+
+	event := events.Event{Type: events.TypeBillExhausted, Data: map[string]any{"user_id": user.ID, "bill_id": uint64(7)}}
+	for i := 0; i < 2; i++ {
+		if errHandle := n.Handle(context.Background(), event); errHandle != nil {
+			t.Fatalf("Handle: %v", errHandle)
+		}
+	}
+	if len(sent) != 1 || !strings.Contains(sent[0], "To: carol@example.com") || !strings.Contains(sent[0], "run out") {
+		t.Fatalf("expected one exhaustion email, got %d:\n%s", len(sent), strings.Join(sent, "\n---\n"))
+	}
+
+	now = now.Add(lowBalanceCooldown)
+	if errHandle := n.Handle(context.Background(), event); errHandle != nil {
+		t.Fatalf("Handle after cooldown: %v", errHandle)
+	}
+	if len(sent) != 2 {
+		t.Fatalf("expected a second email after the cooldown, got %d", len(sent))
+	}
+}
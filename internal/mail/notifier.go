@@ -0,0 +1,189 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// lowBalanceCooldown bounds how often one user is warned about the same kind of event.
+const lowBalanceCooldown = 6 * time.Hour
+
+// LowBalanceTypes lists the events that warn users by email.
+var LowBalanceTypes = []events.Type{
+	events.TypeBalanceInsufficient,
+	events.TypeBillQuotaThreshold,
+	events.TypeBillExhausted,
+}
+
+// LowBalanceNotifier mails users when their balance or bill quota runs low.
+type LowBalanceNotifier struct {
+	db     *gorm.DB
+	mailer *Mailer
+	now    func() time.Time
+
+	mu   sync.Mutex
+	sent map[string]time.Time // Last warning per user and event type.
+}
+
+// NewLowBalanceNotifier constructs a low-balance notifier; returns nil when db is nil.
+func NewLowBalanceNotifier(db *gorm.DB, mailer *Mailer) *LowBalanceNotifier {
+	if db == nil || mailer == nil {
+		return nil
+	}
+	return &LowBalanceNotifier{db: db, mailer: mailer, now: time.Now, sent: make(map[string]time.Time)}
+}
+
+// Name returns the subscriber name.
+func (n *LowBalanceNotifier) Name() string { return "mail_low_balance" }
+
+// Handle mails the affected user, at most once per cooldown for each event type.
+func (n *LowBalanceNotifier) Handle(ctx context.Context, event events.Event) error {
+	if n == nil || !n.mailer.Enabled() {
+		return nil
+	}
+	userID, ok := dataUint(event.Data, "user_id")
+	if !ok {
+		return nil
+	}
+	key := strconv.FormatUint(userID, 10) + ":" + string(event.Type)
+	now := n.now()
+	n.mu.Lock()
+	if last, seen := n.sent[key]; seen && now.Sub(last) < lowBalanceCooldown {
+		n.mu.Unlock()
+		return nil
+	}
+	n.sent[key] = now
+	n.mu.Unlock()
+
+	var user models.User
+	if errFind := n.db.WithContext(ctx).Select("id", "username", "email").First(&user, userID).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("mail: load user %d: %w", userID, errFind)
+	}
+	if strings.TrimSpace(user.Email) == "" {
+		return nil
+	}
+	errSend := n.mailer.SendTemplate(ctx, user.Email, TemplateLowBalance, map[string]any{
+		"SiteName":  SiteName(),
+		"Username":  user.Username,
+		"Exhausted": event.Type != events.TypeBillQuotaThreshold,
+		"Detail":    lowBalanceDetail(event),
+		"Link":      n.mailer.Config().Link("bills"),
+	})
+	if errSend != nil {
+		n.mu.Lock()
+		delete(n.sent, key)
+		n.mu.Unlock()
+	}
+	return errSend
+}
+
+// lowBalanceDetail explains the event in user-facing terms.
+func lowBalanceDetail(event events.Event) string {
+	switch event.Type {
+	case events.TypeBillQuotaThreshold:
+		if left, ok := event.Data["left_quota"].(float64); ok {
+			return fmt.Sprintf("One of your bills has used most of its quota; %.2f remains.", left)
+		}
+		return "One of your bills has used most of its quota."
+	case events.TypeBillExhausted:
+		return "One of your bills has used all of its quota."
+	default:
+		if msg := strings.TrimSpace(event.Message); msg != "" {
+			return "Your balance is insufficient: " + msg + "."
+		}
+		return "Your balance is insufficient."
+	}
+}
+
+// LifecycleTypes lists the lifecycle events that are mailed to the affected user.
+var LifecycleTypes = []events.Type{
+	events.TypeUserSuspended,
+	events.TypeUserInactive,
+}
+
+// LifecycleNotifier mails users when the lifecycle scheduler suspends their keys or warns
+// them about inactivity. Each event is emitted once per action, so no cooldown applies.
+type LifecycleNotifier struct {
+	db     *gorm.DB
+	mailer *Mailer
+}
+
+// NewLifecycleNotifier constructs a lifecycle notifier; returns nil when db is nil.
+func NewLifecycleNotifier(db *gorm.DB, mailer *Mailer) *LifecycleNotifier {
+	if db == nil || mailer == nil {
+		return nil
+	}
+	return &LifecycleNotifier{db: db, mailer: mailer}
+}
+
+// Name returns the subscriber name.
+func (n *LifecycleNotifier) Name() string { return "mail_lifecycle" }
+
+// Handle mails the affected user.
+func (n *LifecycleNotifier) Handle(ctx context.Context, event events.Event) error {
+	if n == nil || !n.mailer.Enabled() {
+		return nil
+	}
+	userID, ok := dataUint(event.Data, "user_id")
+	if !ok {
+		return nil
+	}
+	var user models.User
+	if errFind := n.db.WithContext(ctx).Select("id", "username", "email").First(&user, userID).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("mail: load user %d: %w", userID, errFind)
+	}
+	if strings.TrimSpace(user.Email) == "" {
+		return nil
+	}
+	inactiveDays, _ := dataUint(event.Data, "inactive_days")
+	anonymizeAfterDays, _ := dataUint(event.Data, "anonymize_after_days")
+	link := "api-keys"
+	if event.Type == events.TypeUserSuspended {
+		link = "bills"
+	}
+	return n.mailer.SendTemplate(ctx, user.Email, TemplateLifecycle, map[string]any{
+		"SiteName":           SiteName(),
+		"Username":           user.Username,
+		"Suspended":          event.Type == events.TypeUserSuspended,
+		"InactiveDays":       inactiveDays,
+		"AnonymizeAfterDays": anonymizeAfterDays,
+		"Link":               n.mailer.Config().Link(link),
+	})
+}
+
+// dataUint reads an unsigned ID from event data, tolerating JSON-decoded numbers.
+func dataUint(data map[string]any, key string) (uint64, bool) {
+	switch v := data[key].(type) {
+	case uint64:
+		return v, v > 0
+	case uint:
+		return uint64(v), v > 0
+	case int:
+		return uint64(v), v > 0
+	case int64:
+		return uint64(v), v > 0
+	case float64:
+		return uint64(v), v > 0
+	case json.Number:
+		n, errParse := strconv.ParseUint(v.String(), 10, 64)
+		return n, errParse == nil && n > 0
+	default:
+		return 0, false
+	}
+}
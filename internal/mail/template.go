@@ -0,0 +1,37 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Template names.
+const (
+	TemplateVerifyEmail   = "verify_email"
+	TemplatePasswordReset = "password_reset"
+	TemplateLowBalance    = "low_balance"
+	TemplateInvoice       = "invoice"
+	TemplateLifecycle     = "lifecycle"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// Render executes the named template's "subject" and "body" blocks with data.
+func Render(name string, data any) (subject, body string, err error) {
+	tmpl, errParse := template.ParseFS(templateFS, "templates/"+name+".tmpl")
+	if errParse != nil {
+		return "", "", fmt.Errorf("mail: template %q: %w", name, errParse)
+	}
+	var subjectBuf, bodyBuf bytes.Buffer
+	if errExec := tmpl.ExecuteTemplate(&subjectBuf, "subject", data); errExec != nil {
+		return "", "", fmt.Errorf("mail: template %q subject: %w", name, errExec)
+	}
+	if errExec := tmpl.ExecuteTemplate(&bodyBuf, "body", data); errExec != nil {
+		return "", "", fmt.Errorf("mail: template %q body: %w", name, errExec)
+	}
+	return strings.TrimSpace(subjectBuf.String()), strings.TrimSpace(bodyBuf.String()) + "\n", nil
+}
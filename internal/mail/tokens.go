@@ -0,0 +1,134 @@
+package mail
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// VerifyEmailTTL bounds how long an email verification link is accepted.
+	VerifyEmailTTL = 48 * time.Hour
+	// PasswordResetTTL bounds how long a password reset link is accepted.
+	PasswordResetTTL = time.Hour
+)
+
+// ErrInvalidToken is returned for unknown, expired or already used tokens.
+var ErrInvalidToken = errors.New("mail: invalid or expired token")
+
+// IssueToken stores a new single-use token for user and returns its plaintext. Earlier unused
+// tokens with the same purpose are revoked so only the latest link works.
+func IssueToken(ctx context.Context, db *gorm.DB, user *models.User, purpose models.EmailTokenPurpose, ttl time.Duration, now time.Time) (string, error) {
+	token, errToken := randomHex(32)
+	if errToken != nil {
+		return "", errToken
+	}
+	now = now.UTC()
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if errRevoke := tx.Model(&models.EmailToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, purpose).
+			Update("used_at", now).Error; errRevoke != nil {
+			return errRevoke
+		}
+		return tx.Create(&models.EmailToken{
+			UserID:    user.ID,
+			Purpose:   purpose,
+			TokenHash: hashToken(token),
+			Email:     user.Email,
+			ExpiresAt: now.Add(ttl),
+		}).Error
+	})
+	if errTx != nil {
+		return "", fmt.Errorf("mail: issue token: %w", errTx)
+	}
+	return token, nil
+}
+
+// ConsumeToken marks a valid token used and returns it; it returns ErrInvalidToken otherwise.
+func ConsumeToken(ctx context.Context, db *gorm.DB, purpose models.EmailTokenPurpose, token string, now time.Time) (*models.EmailToken, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, ErrInvalidToken
+	}
+	now = now.UTC()
+	var row models.EmailToken
+	if errFind := db.WithContext(ctx).
+		Where("token_hash = ? AND purpose = ?", hashToken(token), purpose).
+		First(&row).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("mail: load token: %w", errFind)
+	}
+	if row.UsedAt != nil || !now.Before(row.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+	res := db.WithContext(ctx).Model(&models.EmailToken{}).
+		Where("id = ? AND used_at IS NULL", row.ID).
+		Update("used_at", now)
+	if res.Error != nil {
+		return nil, fmt.Errorf("mail: consume token: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrInvalidToken
+	}
+	row.UsedAt = &now
+	return &row, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// SendVerification issues a verification token for user and mails the confirmation link.
+func (m *Mailer) SendVerification(ctx context.Context, db *gorm.DB, user *models.User) error {
+	return m.sendTokenLink(ctx, db, user, models.EmailTokenPurposeVerifyEmail, VerifyEmailTTL, TemplateVerifyEmail, "verify-email")
+}
+
+// SendPasswordReset issues a password reset token for user and mails the reset link.
+func (m *Mailer) SendPasswordReset(ctx context.Context, db *gorm.DB, user *models.User) error {
+	return m.sendTokenLink(ctx, db, user, models.EmailTokenPurposePasswordReset, PasswordResetTTL, TemplatePasswordReset, "reset-password")
+}
+
+func (m *Mailer) sendTokenLink(ctx context.Context, db *gorm.DB, user *models.User, purpose models.EmailTokenPurpose, ttl time.Duration, name, page string) error {
+	cfg := m.Config()
+	if !cfg.Enabled() {
+		return ErrDisabled
+	}
+	if strings.TrimSpace(user.Email) == "" {
+		return fmt.Errorf("mail: user %d has no email address", user.ID)
+	}
+	token, errToken := IssueToken(ctx, db, user, purpose, ttl, m.now())
+	if errToken != nil {
+		return errToken
+	}
+	return m.SendTemplate(ctx, user.Email, name, map[string]any{
+		"SiteName":  SiteName(),
+		"Username":  user.Username,
+		"Link":      cfg.Link(page + "?token=" + token),
+		"ExpiresIn": formatTTL(ttl),
+	})
+}
+
+func formatTTL(ttl time.Duration) string {
+	if ttl >= 24*time.Hour && ttl%(24*time.Hour) == 0 {
+		days := int(ttl / (24 * time.Hour))
+		if days == 1 {
+			return "1 day"
+		}
+		return fmt.Sprintf("%d days", days)
+	}
+	hours := int(ttl / time.Hour)
+	if hours == 1 {
+		return "1 hour"
+	}
+	return fmt.Sprintf("%d hours", hours)
+}
@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"strings"
+	"time"
+)
+
+// Default is the process-wide registry served on /metrics.
+var Default = NewRegistry()
+
+var (
+	requestsTotal = Default.NewCounterVec("cpab_requests_total",
+		"Proxied requests recorded by the usage plugin.", "provider", "model", "status")
+	tokensTotal = Default.NewCounterVec("cpab_tokens_total",
+		"Tokens consumed by proxied requests.", "provider", "model", "type")
+	costMicrosTotal = Default.NewCounterVec("cpab_cost_micros_total",
+		"Billed cost of proxied requests in micros.", "provider", "model")
+	requestDuration = Default.NewHistogramVec("cpab_request_duration_seconds",
+		"Time from request start until usage was recorded.",
+		[]float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}, "provider")
+	quotaPollsTotal = Default.NewCounterVec("cpab_quota_polls_total",
+		"Quota refresh attempts by provider and result.", "provider", "result")
+	billingDeductionsTotal = Default.NewCounterVec("cpab_billing_deductions_total",
+		"Usage charges by the balance they were deducted from.", "charged_to")
+	billingDeductedMicrosTotal = Default.NewCounterVec("cpab_billing_deducted_micros_total",
+		"Usage cost in micros by the balance it was deducted from.", "charged_to")
+	modelInFlight = Default.NewGaugeVec("cpab_model_in_flight",
+		"Requests holding a per-model concurrency slot.", "model")
+	modelQueueDepth = Default.NewGaugeVec("cpab_model_queue_depth",
+		"Requests waiting for a per-model concurrency slot.", "model")
+	modelQueueRejectionsTotal = Default.NewCounterVec("cpab_model_queue_rejections_total",
+		"Requests rejected by per-model concurrency limits.", "model", "reason")
+	retryPolicyEventsTotal = Default.NewCounterVec("cpab_retry_policy_events_total",
+		"Retry policy decisions by policy provider and event.", "provider", "event")
+)
+
+// UsageSample describes one recorded request for metrics.
+type UsageSample struct {
+	Provider        string        // Provider name.
+	Model           string        // Model name.
+	Failed          bool          // Whether the request failed.
+	InputTokens     int64         // Input token count.
+	OutputTokens    int64         // Output token count.
+	ReasoningTokens int64         // Reasoning token count.
+	CachedTokens    int64         // Cached token count.
+	CostMicros      int64         // Cost in micros.
+	Duration        time.Duration // Request duration; zero skips the histogram.
+}
+
+// ObserveUsage records request, token, cost and latency metrics for a recorded request.
+func ObserveUsage(sample UsageSample) {
+	provider := labelOrUnknown(sample.Provider)
+	model := labelOrUnknown(sample.Model)
+	status := "success"
+	if sample.Failed {
+		status = "failed"
+	}
+	requestsTotal.Inc(provider, model, status)
+	tokensTotal.Add(float64(sample.InputTokens), provider, model, "input")
+	tokensTotal.Add(float64(sample.OutputTokens), provider, model, "output")
+	tokensTotal.Add(float64(sample.ReasoningTokens), provider, model, "reasoning")
+	tokensTotal.Add(float64(sample.CachedTokens), provider, model, "cached")
+	costMicrosTotal.Add(float64(sample.CostMicros), provider, model)
+	if sample.Duration > 0 {
+		requestDuration.Observe(sample.Duration.Seconds(), provider)
+	}
+}
+
+// ObserveQuotaPoll records the outcome of a quota refresh.
+func ObserveQuotaPoll(provider string, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	quotaPollsTotal.Inc(labelOrUnknown(provider), result)
+}
+
+// ObserveBillingDeduction records a usage charge against a balance ("bill", "prepaid" or "none").
+func ObserveBillingDeduction(chargedTo string, costMicros int64) {
+	chargedTo = labelOrUnknown(chargedTo)
+	billingDeductionsTotal.Inc(chargedTo)
+	billingDeductedMicrosTotal.Add(float64(costMicros), chargedTo)
+}
+
+// ObserveModelQueue records the in-flight and queued requests of a concurrency-limited model.
+func ObserveModelQueue(model string, inFlight, queued int) {
+	model = labelOrUnknown(model)
+	modelInFlight.Set(float64(inFlight), model)
+	modelQueueDepth.Set(float64(queued), model)
+}
+
+// ObserveModelQueueRejection records a request turned away by a per-model limit
+// ("queue_full" or "timeout").
+func ObserveModelQueueRejection(model, reason string) {
+	modelQueueRejectionsTotal.Inc(labelOrUnknown(model), labelOrUnknown(reason))
+}
+
+// ObserveRetryPolicy records a retry policy decision ("failover", "max_retries",
+// "not_retryable" or "failover_disabled").
+func ObserveRetryPolicy(provider, event string) {
+	retryPolicyEventsTotal.Inc(labelOrUnknown(provider), labelOrUnknown(event))
+}
+
+func labelOrUnknown(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
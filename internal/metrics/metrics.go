@@ -0,0 +1,294 @@
+// Package metrics keeps in-process Prometheus counters and histograms and renders them
+// in the Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// contentType is the Prometheus text exposition format content type.
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// collector is a metric family that can render itself.
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+// Registry holds metric families in registration order.
+type Registry struct {
+	mu         sync.RWMutex
+	collectors []collector
+	names      map[string]struct{}
+}
+
+// NewRegistry constructs an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]struct{})}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.names[c.name()]; exists {
+		panic("metrics: duplicate metric " + c.name())
+	}
+	r.names[c.name()] = struct{}{}
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteText renders every registered family in the Prometheus text format.
+func (r *Registry) WriteText(w io.Writer) {
+	r.mu.RLock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.RUnlock()
+	for _, c := range collectors {
+		c.write(w)
+	}
+}
+
+// Handler serves the registry in the Prometheus text format.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		r.WriteText(w)
+	})
+}
+
+// CounterVec is a monotonically increasing counter partitioned by labels.
+type CounterVec struct {
+	metricName string
+	help       string
+	labelNames []string
+
+	mu     sync.Mutex
+	values map[string]*counterSeries
+}
+
+type counterSeries struct {
+	labels []string
+	value  float64
+}
+
+// NewCounterVec registers a counter family on r.
+func (r *Registry) NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	c := &CounterVec{metricName: name, help: help, labelNames: labelNames, values: make(map[string]*counterSeries)}
+	r.register(c)
+	return c
+}
+
+// Inc adds one to the series identified by labelValues.
+func (c *CounterVec) Inc(labelValues ...string) { c.Add(1, labelValues...) }
+
+// Add adds v to the series identified by labelValues; negative values are ignored.
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if c == nil || v < 0 || math.IsNaN(v) {
+		return
+	}
+	labelValues = normalizeLabelValues(labelValues, len(c.labelNames))
+	key := strings.Join(labelValues, "\xff")
+	c.mu.Lock()
+	series, ok := c.values[key]
+	if !ok {
+		series = &counterSeries{labels: labelValues}
+		c.values[key] = series
+	}
+	series.value += v
+	c.mu.Unlock()
+}
+
+func (c *CounterVec) name() string { return c.metricName }
+
+func (c *CounterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	writeHeader(w, c.metricName, c.help, "counter")
+	for _, key := range sortedKeys(c.values) {
+		series := c.values[key]
+		fmt.Fprintf(w, "%s%s %s\n", c.metricName, formatLabels(c.labelNames, series.labels, "", ""), formatValue(series.value))
+	}
+}
+
+// GaugeVec is a value that can go up and down, partitioned by labels.
+type GaugeVec struct {
+	metricName string
+	help       string
+	labelNames []string
+
+	mu     sync.Mutex
+	values map[string]*counterSeries
+}
+
+// NewGaugeVec registers a gauge family on r.
+func (r *Registry) NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	g := &GaugeVec{metricName: name, help: help, labelNames: labelNames, values: make(map[string]*counterSeries)}
+	r.register(g)
+	return g
+}
+
+// Set sets the series identified by labelValues to v.
+func (g *GaugeVec) Set(v float64, labelValues ...string) {
+	if g == nil || math.IsNaN(v) {
+		return
+	}
+	labelValues = normalizeLabelValues(labelValues, len(g.labelNames))
+	key := strings.Join(labelValues, "\xff")
+	g.mu.Lock()
+	series, ok := g.values[key]
+	if !ok {
+		series = &counterSeries{labels: labelValues}
+		g.values[key] = series
+	}
+	series.value = v
+	g.mu.Unlock()
+}
+
+func (g *GaugeVec) name() string { return g.metricName }
+
+func (g *GaugeVec) write(w io.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	writeHeader(w, g.metricName, g.help, "gauge")
+	for _, key := range sortedKeys(g.values) {
+		series := g.values[key]
+		fmt.Fprintf(w, "%s%s %s\n", g.metricName, formatLabels(g.labelNames, series.labels, "", ""), formatValue(series.value))
+	}
+}
+
+// HistogramVec tracks value distributions in cumulative buckets partitioned by labels.
+type HistogramVec struct {
+	metricName string
+	help       string
+	labelNames []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	values map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labels []string
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec registers a histogram family with the given upper bucket bounds on r.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	h := &HistogramVec{metricName: name, help: help, labelNames: labelNames, buckets: sorted, values: make(map[string]*histogramSeries)}
+	r.register(h)
+	return h
+}
+
+// Observe records v in the series identified by labelValues.
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	if h == nil || math.IsNaN(v) {
+		return
+	}
+	labelValues = normalizeLabelValues(labelValues, len(h.labelNames))
+	key := strings.Join(labelValues, "\xff")
+	h.mu.Lock()
+	series, ok := h.values[key]
+	if !ok {
+		series = &histogramSeries{labels: labelValues, counts: make([]uint64, len(h.buckets))}
+		h.values[key] = series
+	}
+	for i, bound := range h.buckets {
+		if v <= bound {
+			series.counts[i]++
+		}
+	}
+	series.count++
+	series.sum += v
+	h.mu.Unlock()
+}
+
+func (h *HistogramVec) name() string { return h.metricName }
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	writeHeader(w, h.metricName, h.help, "histogram")
+	for _, key := range sortedKeys(h.values) {
+		series := h.values[key]
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, formatLabels(h.labelNames, series.labels, "le", formatValue(bound)), series.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, formatLabels(h.labelNames, series.labels, "le", "+Inf"), series.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.metricName, formatLabels(h.labelNames, series.labels, "", ""), formatValue(series.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.metricName, formatLabels(h.labelNames, series.labels, "", ""), series.count)
+	}
+}
+
+func writeHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, strings.NewReplacer("\\", `\\`, "\n", `\n`).Replace(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+// normalizeLabelValues pads or truncates values to the declared label count.
+func normalizeLabelValues(values []string, n int) []string {
+	out := make([]string, n)
+	copy(out, values)
+	return out
+}
+
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(values[i]))
+		b.WriteByte('"')
+	}
+	if extraName != "" {
+		if len(names) > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(extraName)
+		b.WriteString(`="`)
+		b.WriteString(extraValue)
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer("\\", `\\`, "\"", `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCounterVecWritesTextFormat(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("test_requests_total", "Test requests.", "provider", "status")
+	c.Inc("codex", "success")
+	c.Add(2, "codex", "success")
+	c.Inc(`we"ird`, "failed")
+	c.Add(-1, "codex", "success")
+
+	var buf bytes.Buffer
+	r.WriteText(&buf)
+	out := buf.String()
+	for _, want := range []string{
+		"# HELP test_requests_total Test requests.\n",
+		"# TYPE test_requests_total counter\n",
+		`test_requests_total{provider="codex",status="success"} 3` + "\n",
+		`test_requests_total{provider="we\"ird",status="failed"} 1` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestGaugeVecOverwritesValues(t *testing.T) {
+	r := NewRegistry()
+	g := r.NewGaugeVec("test_queue_depth", "Test queue depth.", "model")
+	g.Set(3, "o1")
+	g.Set(1, "o1")
+	g.Set(0, "opus")
+
+	var buf bytes.Buffer
+	r.WriteText(&buf)
+	out := buf.String()
+	for _, want := range []string{
+		"# TYPE test_queue_depth gauge\n",
+		`test_queue_depth{model="o1"} 1` + "\n",
+		`test_queue_depth{model="opus"} 0` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestHistogramVecWritesCumulativeBuckets(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogramVec("test_duration_seconds", "Test durations.", []float64{1, 5}, "provider")
+	h.Observe(0.5, "claude")
+	h.Observe(3, "claude")
+	h.Observe(10, "claude")
+
+	var buf bytes.Buffer
+	r.WriteText(&buf)
+	out := buf.String()
+	for _, want := range []string{
+		"# TYPE test_duration_seconds histogram\n",
+		`test_duration_seconds_bucket{provider="claude",le="1"} 1` + "\n",
+		`test_duration_seconds_bucket{provider="claude",le="5"} 2` + "\n",
+		`test_duration_seconds_bucket{provider="claude",le="+Inf"} 3` + "\n",
+		`test_duration_seconds_sum{provider="claude"} 13.5` + "\n",
+		`test_duration_seconds_count{provider="claude"} 3` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRegistryRejectsDuplicateNames(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("dup_total", "First.")
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected duplicate registration to panic")
+		}
+	}()
+	r.NewCounterVec("dup_total", "Second.")
+}
+
+func TestObserveUsageUsesUnknownForEmptyLabels(t *testing.T) {
+	ObserveUsage(UsageSample{Failed: true, InputTokens: 3})
+
+	var buf bytes.Buffer
+	Default.WriteText(&buf)
+	if !strings.Contains(buf.String(), `cpab_requests_total{provider="unknown",model="unknown",status="failed"}`) {
+		t.Fatalf("expected unknown labels in output:\n%s", buf.String())
+	}
+}
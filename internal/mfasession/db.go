@@ -0,0 +1,110 @@
+package mfasession
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultPurgeInterval is how often the janitor removes expired database entries.
+const defaultPurgeInterval = 10 * time.Minute
+
+// DBStore keeps entries in the mfa_sessions table shared by every replica.
+type DBStore struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewDBStore constructs a database-backed store; returns nil when db is nil.
+func NewDBStore(db *gorm.DB) *DBStore {
+	if db == nil {
+		return nil
+	}
+	return &DBStore{db: db, now: time.Now}
+}
+
+// Put upserts value until expiresAt.
+func (s *DBStore) Put(ctx context.Context, scope, key string, value []byte, expiresAt time.Time) error {
+	row := models.MFASession{Scope: scope, Key: key, Value: value, ExpiresAt: expiresAt.UTC(), CreatedAt: s.now().UTC()}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "scope"}, {Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "expires_at", "created_at"}),
+	}).Create(&row).Error
+}
+
+// Get returns the value when present and not expired.
+func (s *DBStore) Get(ctx context.Context, scope, key string) ([]byte, bool, error) {
+	var row models.MFASession
+	errFind := s.db.WithContext(ctx).
+		Where("scope = ? AND ? = ? AND expires_at > ?", scope, clause.Column{Name: "key"}, key, s.now().UTC()).
+		First(&row).Error
+	if errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, errFind
+	}
+	return row.Value, true, nil
+}
+
+// Delete removes the value.
+func (s *DBStore) Delete(ctx context.Context, scope, key string) error {
+	return s.db.WithContext(ctx).Where("scope = ? AND ? = ?", scope, clause.Column{Name: "key"}, key).Delete(&models.MFASession{}).Error
+}
+
+// Purge removes entries that expired before now.
+func (s *DBStore) Purge(ctx context.Context, now time.Time) (int64, error) {
+	res := s.db.WithContext(ctx).Where("expires_at <= ?", now.UTC()).Delete(&models.MFASession{})
+	return res.RowsAffected, res.Error
+}
+
+// Janitor periodically purges expired entries from a store.
+type Janitor struct {
+	store    Store
+	interval time.Duration
+	now      func() time.Time
+}
+
+// NewJanitor constructs a janitor; returns nil when store is nil.
+func NewJanitor(store Store) *Janitor {
+	if store == nil {
+		return nil
+	}
+	return &Janitor{store: store, interval: defaultPurgeInterval, now: time.Now}
+}
+
+// Start launches the purge loop in a background goroutine.
+func (j *Janitor) Start(ctx context.Context) {
+	if j == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go j.run(ctx)
+}
+
+func (j *Janitor) run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			removed, errPurge := j.store.Purge(ctx, j.now())
+			if errPurge != nil {
+				log.WithError(errPurge).Warn("mfa session: purge expired entries failed")
+				continue
+			}
+			if removed > 0 {
+				log.Debugf("mfa session: purged %d expired entries", removed)
+			}
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Package mfasession stores short-lived MFA ceremony state: WebAuthn registration and login
+// sessions and TOTP secrets awaiting confirmation. The process-wide store defaults to memory;
+// the app installs the database store so ceremonies survive restarts and span replicas.
+package mfasession
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Store keeps expiring values grouped by scope.
+type Store interface {
+	// Put stores value under scope/key until expiresAt, replacing any previous value.
+	Put(ctx context.Context, scope, key string, value []byte, expiresAt time.Time) error
+	// Get returns the value when present and not expired.
+	Get(ctx context.Context, scope, key string) ([]byte, bool, error)
+	// Delete removes the value; deleting a missing key is not an error.
+	Delete(ctx context.Context, scope, key string) error
+	// Purge removes entries that expired before now and returns how many were removed.
+	Purge(ctx context.Context, now time.Time) (int64, error)
+}
+
+var defaultStore atomic.Pointer[Store]
+
+var fallbackStore Store = NewMemoryStore()
+
+// SetDefault installs the process-wide store; nil restores the in-memory fallback.
+func SetDefault(s Store) {
+	if s == nil {
+		defaultStore.Store(nil)
+		return
+	}
+	defaultStore.Store(&s)
+}
+
+// Default returns the process-wide store.
+func Default() Store {
+	if s := defaultStore.Load(); s != nil {
+		return *s
+	}
+	return fallbackStore
+}
+
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// MemoryStore keeps entries in process memory; state is lost on restart.
+type MemoryStore struct {
+	mu    sync.Mutex
+	items map[string]memoryEntry
+	now   func() time.Time
+}
+
+// NewMemoryStore constructs an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{items: make(map[string]memoryEntry), now: time.Now}
+}
+
+func memoryKey(scope, key string) string {
+	return scope + "\x00" + key
+}
+
+// Put stores value until expiresAt.
+func (s *MemoryStore) Put(_ context.Context, scope, key string, value []byte, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[memoryKey(scope, key)] = memoryEntry{value: append([]byte(nil), value...), expiresAt: expiresAt}
+	return nil
+}
+
+// Get returns the value when present and not expired.
+func (s *MemoryStore) Get(_ context.Context, scope, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := memoryKey(scope, key)
+	entry, ok := s.items[k]
+	if !ok {
+		return nil, false, nil
+	}
+	if s.now().After(entry.expiresAt) {
+		delete(s.items, k)
+		return nil, false, nil
+	}
+	return append([]byte(nil), entry.value...), true, nil
+}
+
+// Delete removes the value.
+func (s *MemoryStore) Delete(_ context.Context, scope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, memoryKey(scope, key))
+	return nil
+}
+
+// Purge removes expired entries.
+func (s *MemoryStore) Purge(_ context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var removed int64
+	for k, entry := range s.items {
+		if now.After(entry.expiresAt) {
+			delete(s.items, k)
+			removed++
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,86 @@
+package mfasession
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"gorm.io/gorm"
+)
+
+func setupSessionDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:mfasession_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func TestStoresExpireAndScopeEntries(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	memory := NewMemoryStore()
+	memory.now = clock
+	dbStore := NewDBStore(setupSessionDB(t))
+	dbStore.now = clock
+
+	for name, store := range map[string]Store{"memory": memory, "db": dbStore} {
+		ctx := context.Background()
+		if errPut := store.Put(ctx, "admin:totp_pending", "1", []byte("first"), now.Add(time.Minute)); errPut != nil {
+			t.Fatalf("%s: Put: %v", name, errPut)
+		}
+		// A second Put on the same key replaces the value rather than conflicting.
+		if errPut := store.Put(ctx, "admin:totp_pending", "1", []byte("secret"), now.Add(time.Minute)); errPut != nil {
+			t.Fatalf("%s: Put again: %v", name, errPut)
+		}
+		if value, ok, errGet := store.Get(ctx, "admin:totp_pending", "1"); errGet != nil || !ok || string(value) != "secret" {
+			t.Fatalf("%s: Get = %q, %v, %v", name, value, ok, errGet)
+		}
+		if _, ok, _ := store.Get(ctx, "user:totp_pending", "1"); ok {
+			t.Fatalf("%s: expected scopes to be isolated", name)
+		}
+
+		now = now.Add(2 * time.Minute)
+		if _, ok, _ := store.Get(ctx, "admin:totp_pending", "1"); ok {
+			t.Fatalf("%s: expected expired entry to be hidden", name)
+		}
+		if errPut := store.Put(ctx, "admin:passkey_login", "alice", []byte("{}"), now.Add(time.Minute)); errPut != nil {
+			t.Fatalf("%s: Put: %v", name, errPut)
+		}
+		if errDelete := store.Delete(ctx, "admin:passkey_login", "alice"); errDelete != nil {
+			t.Fatalf("%s: Delete: %v", name, errDelete)
+		}
+		if _, ok, _ := store.Get(ctx, "admin:passkey_login", "alice"); ok {
+			t.Fatalf("%s: expected deleted entry to be gone", name)
+		}
+	}
+}
+
+func TestDBStorePurgeRemovesExpiredRows(t *testing.T) {
+	t.Parallel()
+
+	conn := setupSessionDB(t)
+	store := NewDBStore(conn)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	_ = store.Put(ctx, "user:passkey_login", "old", []byte("x"), now.Add(-time.Minute))
+	_ = store.Put(ctx, "user:passkey_login", "new", []byte("y"), now.Add(time.Minute))
+
+	removed, errPurge := store.Purge(ctx, now)
+	if errPurge != nil || removed != 1 {
+		t.Fatalf("Purge = %d, %v", removed, errPurge)
+	}
+	if _, ok, _ := store.Get(ctx, "user:passkey_login", "new"); !ok {
+		t.Fatal("expected unexpired entry to survive the purge")
+	}
+}
@@ -0,0 +1,245 @@
+// Package modelcatalog merges the models registered by upstream providers, the model mappings,
+// the auth file whitelists and the billing rules into one list for model pickers.
+package modelcatalog
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modeldisplay"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+)
+
+// RegistryModel is one model an upstream provider registered.
+type RegistryModel struct {
+	Provider    string
+	ID          string
+	DisplayName string
+}
+
+// Price is the pricing of one billing rule.
+type Price struct {
+	RuleID                uint64                    `json:"rule_id"`
+	AuthGroupID           uint64                    `json:"auth_group_id"`
+	UserGroupID           uint64                    `json:"user_group_id"`
+	BillingType           models.BillingType        `json:"billing_type"`
+	Currency              string                    `json:"currency,omitempty"`
+	PricePerRequest       *float64                  `json:"price_per_request,omitempty"`
+	PriceInputToken       *float64                  `json:"price_input_token,omitempty"`
+	PriceOutputToken      *float64                  `json:"price_output_token,omitempty"`
+	PriceCacheCreateToken *float64                  `json:"price_cache_create_token,omitempty"`
+	PriceCacheReadToken   *float64                  `json:"price_cache_read_token,omitempty"`
+	TokenTiers            []models.BillingTokenTier `json:"token_tiers,omitempty"`
+	MinimumCharge         *float64                  `json:"minimum_charge,omitempty"`
+}
+
+// PriceOf converts a billing rule into a Price.
+func PriceOf(rule *models.BillingRule) *Price {
+	if rule == nil {
+		return nil
+	}
+	price := &Price{
+		RuleID:                rule.ID,
+		AuthGroupID:           rule.AuthGroupID,
+		UserGroupID:           rule.UserGroupID,
+		BillingType:           rule.BillingType,
+		Currency:              rule.Currency,
+		PricePerRequest:       rule.PricePerRequest,
+		PriceInputToken:       rule.PriceInputToken,
+		PriceOutputToken:      rule.PriceOutputToken,
+		PriceCacheCreateToken: rule.PriceCacheCreateToken,
+		PriceCacheReadToken:   rule.PriceCacheReadToken,
+		MinimumCharge:         rule.MinimumCharge,
+	}
+	if tiers, errTiers := billing.ParseTokenTiers(rule.TokenTiers); errTiers == nil {
+		price.TokenTiers = tiers
+	}
+	return price
+}
+
+// AuthCoverage counts the routable auth files of an entry's provider and how many of them
+// exclude the entry's upstream model.
+type AuthCoverage struct {
+	Total    int `json:"total"`
+	Excluded int `json:"excluded"`
+}
+
+// Entry is one client-visible model of one provider.
+type Entry struct {
+	Provider       string              `json:"provider"`
+	Model          string              `json:"model"`          // Name clients request.
+	UpstreamModel  string              `json:"upstream_model"` // Name sent to the provider.
+	DisplayName    string              `json:"display_name"`
+	Category       string              `json:"category,omitempty"`
+	Description    string              `json:"description,omitempty"`
+	ContextWindow  int                 `json:"context_window,omitempty"`
+	Capabilities   []string            `json:"capabilities,omitempty"`
+	InRegistry     bool                `json:"in_registry"` // Whether a connected provider currently serves the upstream model.
+	MappingID      uint64              `json:"mapping_id,omitempty"`
+	MappingEnabled bool                `json:"mapping_enabled"`
+	UserGroupIDs   models.UserGroupIDs `json:"user_group_id"` // Groups allowed by the mapping; empty allows all.
+	Auths          AuthCoverage        `json:"auths"`
+	DefaultPrice   *Price              `json:"default_price,omitempty"` // Rule pricing members of the default groups.
+	Prices         []Price             `json:"prices,omitempty"`        // Every enabled rule naming this provider and model.
+}
+
+// Input holds everything a catalog is built from.
+type Input struct {
+	Registry []RegistryModel
+	Mappings []models.ModelMapping
+	Auths    []models.Auth // Routable auth files.
+	Rules    []models.BillingRule
+	Displays map[string]modeldisplay.Entry
+
+	DefaultAuthGroupID uint64
+	DefaultUserGroupID uint64
+}
+
+// Build merges the input into entries ordered by provider and model. Every mapping yields an
+// entry; registry models without a mapping are listed under their own name.
+func Build(in Input) []Entry {
+	registry := make(map[string]RegistryModel, len(in.Registry))
+	for _, model := range in.Registry {
+		provider, id := strings.TrimSpace(model.Provider), strings.TrimSpace(model.ID)
+		if provider == "" || id == "" {
+			continue
+		}
+		registry[key(provider, id)] = model
+	}
+
+	entries := make([]Entry, 0, len(in.Mappings)+len(registry))
+	mapped := make(map[string]struct{}, len(in.Mappings))
+	for _, mapping := range in.Mappings {
+		provider := strings.ToLower(strings.TrimSpace(mapping.Provider))
+		upstream := strings.TrimSpace(mapping.ModelName)
+		alias := strings.TrimSpace(mapping.NewModelName)
+		if provider == "" || upstream == "" || alias == "" {
+			continue
+		}
+		mapped[key(provider, upstream)] = struct{}{}
+		entry := Entry{
+			Provider:       provider,
+			Model:          alias,
+			UpstreamModel:  upstream,
+			DisplayName:    alias,
+			MappingID:      mapping.ID,
+			MappingEnabled: mapping.IsEnabled,
+			UserGroupIDs:   mapping.UserGroupID.Clean(),
+		}
+		if model, ok := registry[key(provider, upstream)]; ok {
+			entry.InRegistry = true
+			if name := strings.TrimSpace(model.DisplayName); name != "" {
+				entry.DisplayName = name
+			}
+		}
+		entries = append(entries, entry)
+	}
+	for k, model := range registry {
+		if _, ok := mapped[k]; ok {
+			continue
+		}
+		id := strings.TrimSpace(model.ID)
+		name := strings.TrimSpace(model.DisplayName)
+		if name == "" {
+			name = id
+		}
+		entries = append(entries, Entry{
+			Provider:      strings.ToLower(strings.TrimSpace(model.Provider)),
+			Model:         id,
+			UpstreamModel: id,
+			DisplayName:   name,
+			InRegistry:    true,
+			UserGroupIDs:  models.UserGroupIDs{},
+		})
+	}
+
+	coverage := newCoverage(in.Auths)
+	for i := range entries {
+		entry := &entries[i]
+		if display, ok := modeldisplay.Lookup(in.Displays, entry.Model); ok {
+			entry.DisplayName = display.Label()
+			entry.Category = display.Category
+			entry.Description = display.Description
+			entry.ContextWindow = display.ContextWindow
+			entry.Capabilities = display.Capabilities
+		}
+		entry.Auths = coverage.of(entry.Provider, entry.UpstreamModel)
+		for j := range in.Rules {
+			rule := &in.Rules[j]
+			if rule.IsEnabled && strings.EqualFold(strings.TrimSpace(rule.Provider), entry.Provider) && strings.TrimSpace(rule.Model) == entry.Model {
+				entry.Prices = append(entry.Prices, *PriceOf(rule))
+			}
+		}
+		if in.DefaultAuthGroupID != 0 && in.DefaultUserGroupID != 0 {
+			entry.DefaultPrice = PriceOf(billing.SelectBillingRule(in.Rules, in.DefaultAuthGroupID, in.DefaultUserGroupID,
+				in.DefaultAuthGroupID, in.DefaultUserGroupID, entry.Provider, entry.Model))
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Provider != entries[j].Provider {
+			return entries[i].Provider < entries[j].Provider
+		}
+		if a, b := strings.ToLower(entries[i].Model), strings.ToLower(entries[j].Model); a != b {
+			return a < b
+		}
+		return entries[i].MappingID < entries[j].MappingID
+	})
+	return entries
+}
+
+func key(provider, model string) string {
+	return strings.ToLower(strings.TrimSpace(provider)) + "\x00" + strings.ToLower(strings.TrimSpace(model))
+}
+
+// coverage indexes auth files by provider with their excluded models.
+type coverage map[string][]map[string]struct{}
+
+func newCoverage(auths []models.Auth) coverage {
+	out := make(coverage)
+	for i := range auths {
+		provider := authProvider(auths[i].Content)
+		if provider == "" {
+			continue
+		}
+		var excludedList []string
+		_ = json.Unmarshal(auths[i].ExcludedModels, &excludedList)
+		excluded := make(map[string]struct{}, len(excludedList))
+		for _, model := range excludedList {
+			if model = strings.ToLower(strings.TrimSpace(model)); model != "" {
+				excluded[model] = struct{}{}
+			}
+		}
+		out[provider] = append(out[provider], excluded)
+	}
+	return out
+}
+
+func (c coverage) of(provider, upstream string) AuthCoverage {
+	auths := c[strings.ToLower(provider)]
+	result := AuthCoverage{Total: len(auths)}
+	upstream = strings.ToLower(strings.TrimSpace(upstream))
+	for _, excluded := range auths {
+		if _, ok := excluded[upstream]; ok {
+			result.Excluded++
+		}
+	}
+	return result
+}
+
+// authProvider reads the provider from an auth file's type, falling back to its provider field.
+func authProvider(content []byte) string {
+	var meta struct {
+		Type     string `json:"type"`
+		Provider string `json:"provider"`
+	}
+	if errUnmarshal := json.Unmarshal(content, &meta); errUnmarshal != nil {
+		return ""
+	}
+	if provider := strings.TrimSpace(meta.Type); provider != "" {
+		return strings.ToLower(provider)
+	}
+	return strings.ToLower(strings.TrimSpace(meta.Provider))
+}
@@ -0,0 +1,73 @@
+package modelcatalog
+
+import (
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modeldisplay"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+)
+
+func TestBuildMergesRegistryMappingsAndPrices(t *testing.T) {
+	price := 0.5
+	catalog := Build(Input{
+		Registry: []RegistryModel{
+			{Provider: "claude", ID: "claude-sonnet-4", DisplayName: "Claude Sonnet 4"},
+			{Provider: "claude", ID: "claude-haiku"},
+			{Provider: "codex", ID: "gpt-5"},
+		},
+		Mappings: []models.ModelMapping{
+			{ID: 7, Provider: "Claude", ModelName: "claude-sonnet-4", NewModelName: "sonnet", IsEnabled: true, UserGroupID: models.UserGroupIDs{ptr(3)}},
+			{ID: 8, Provider: "gemini", ModelName: "gemini-pro", NewModelName: "pro", IsEnabled: false},
+		},
+		Auths: []models.Auth{
+			{Content: datatypes.JSON(`{"type":"claude"}`), ExcludedModels: datatypes.JSON(`["claude-haiku"]`)},
+			{Content: datatypes.JSON(`{"type":"claude"}`), ExcludedModels: datatypes.JSON(`[]`)},
+			{Content: datatypes.JSON(`{"provider":"codex"}`)},
+		},
+		Rules: []models.BillingRule{
+			{ID: 1, AuthGroupID: 1, UserGroupID: 1, Provider: "claude", Model: "sonnet", BillingType: models.BillingTypePerRequest, PricePerRequest: &price, IsEnabled: true},
+			{ID: 2, AuthGroupID: 1, UserGroupID: 3, Provider: "claude", Model: "sonnet", BillingType: models.BillingTypePerRequest, IsEnabled: true},
+			{ID: 3, AuthGroupID: 1, UserGroupID: 1, Provider: "claude", Model: "claude-sonnet-4", IsEnabled: false},
+		},
+		Displays: map[string]modeldisplay.Entry{
+			"gpt-5": {ModelID: "gpt-5", DisplayName: "GPT-5", Category: "chat"},
+		},
+		DefaultAuthGroupID: 1,
+		DefaultUserGroupID: 1,
+	})
+
+	if len(catalog) != 4 {
+		t.Fatalf("expected 4 entries, got %+v", catalog)
+	}
+	haiku, sonnet, gpt, pro := catalog[0], catalog[1], catalog[2], catalog[3]
+
+	if haiku.Model != "claude-haiku" || haiku.MappingID != 0 || !haiku.InRegistry || haiku.DisplayName != "claude-haiku" {
+		t.Fatalf("unexpected unmapped entry %+v", haiku)
+	}
+	if haiku.Auths != (AuthCoverage{Total: 2, Excluded: 1}) {
+		t.Fatalf("unexpected haiku coverage %+v", haiku.Auths)
+	}
+
+	if sonnet.Model != "sonnet" || sonnet.UpstreamModel != "claude-sonnet-4" || sonnet.MappingID != 7 || !sonnet.InRegistry {
+		t.Fatalf("unexpected mapped entry %+v", sonnet)
+	}
+	if sonnet.DisplayName != "Claude Sonnet 4" || len(sonnet.UserGroupIDs) != 1 {
+		t.Fatalf("unexpected mapped entry metadata %+v", sonnet)
+	}
+	if len(sonnet.Prices) != 2 || sonnet.DefaultPrice == nil || sonnet.DefaultPrice.RuleID != 1 || *sonnet.DefaultPrice.PricePerRequest != price {
+		t.Fatalf("unexpected sonnet prices %+v default %+v", sonnet.Prices, sonnet.DefaultPrice)
+	}
+
+	if gpt.Provider != "codex" || gpt.DisplayName != "GPT-5" || gpt.Category != "chat" || gpt.Auths.Total != 1 || gpt.DefaultPrice != nil {
+		t.Fatalf("unexpected registry entry %+v", gpt)
+	}
+
+	if pro.Provider != "gemini" || pro.InRegistry || pro.MappingEnabled || pro.Auths.Total != 0 {
+		t.Fatalf("unexpected disabled mapping entry %+v", pro)
+	}
+}
+
+func ptr(v uint64) *uint64 {
+	return &v
+}
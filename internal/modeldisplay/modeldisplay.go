@@ -0,0 +1,169 @@
+// Package modeldisplay caches admin-managed display metadata for user-visible models
+// and renders friendly catalog labels.
+package modeldisplay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// cacheTTL bounds how long other instances serve stale metadata after an admin edit.
+const cacheTTL = 30 * time.Second
+
+// capabilityFlags lists the capability flags admins may assign.
+var capabilityFlags = []string{"audio", "embeddings", "image_generation", "json_mode", "reasoning", "tools", "vision", "web_search"}
+
+// Entry is the display metadata of one model ID.
+type Entry struct {
+	ModelID       string   `json:"model_id"`
+	DisplayName   string   `json:"display_name"`
+	Category      string   `json:"category,omitempty"`
+	Description   string   `json:"description,omitempty"`
+	ContextWindow int      `json:"context_window,omitempty"`
+	Capabilities  []string `json:"capabilities,omitempty"`
+	SortOrder     int      `json:"sort_order"`
+}
+
+// Label returns the user-facing name, e.g. "Claude Sonnet 4.5 (200k ctx)".
+func (e Entry) Label() string {
+	name := strings.TrimSpace(e.DisplayName)
+	if name == "" {
+		name = e.ModelID
+	}
+	if e.ContextWindow > 0 {
+		return fmt.Sprintf("%s (%s ctx)", name, FormatContextWindow(e.ContextWindow))
+	}
+	return name
+}
+
+// FormatContextWindow renders a token count compactly, e.g. 200000 -> "200k", 131072 -> "128k".
+func FormatContextWindow(tokens int) string {
+	switch {
+	case tokens <= 0:
+		return "0"
+	case tokens%(1<<20) == 0:
+		return strconv.Itoa(tokens>>20) + "M"
+	case tokens%1_000_000 == 0:
+		return strconv.Itoa(tokens/1_000_000) + "M"
+	case tokens%1024 == 0 && tokens < 1_000_000:
+		return strconv.Itoa(tokens>>10) + "k"
+	case tokens >= 1_000_000:
+		return strings.TrimSuffix(strconv.FormatFloat(float64(tokens)/1_000_000, 'f', 1, 64), ".0") + "M"
+	case tokens >= 1000:
+		return strconv.Itoa((tokens+500)/1000) + "k"
+	default:
+		return strconv.Itoa(tokens)
+	}
+}
+
+// CapabilityFlags returns the capability flags admins may assign.
+func CapabilityFlags() []string {
+	return append([]string(nil), capabilityFlags...)
+}
+
+// NormalizeCapabilities lowercases, validates, de-duplicates and sorts capability flags.
+func NormalizeCapabilities(values []string) ([]string, error) {
+	out := make([]string, 0, len(values))
+	seen := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		flag := strings.ToLower(strings.TrimSpace(value))
+		if flag == "" {
+			continue
+		}
+		idx := sort.SearchStrings(capabilityFlags, flag)
+		if idx >= len(capabilityFlags) || capabilityFlags[idx] != flag {
+			return nil, fmt.Errorf("unknown capability %q", value)
+		}
+		if _, ok := seen[flag]; ok {
+			continue
+		}
+		seen[flag] = struct{}{}
+		out = append(out, flag)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// FromModel converts a stored row into an Entry.
+func FromModel(row *models.ModelDisplay) Entry {
+	entry := Entry{
+		ModelID:       strings.TrimSpace(row.ModelID),
+		DisplayName:   strings.TrimSpace(row.DisplayName),
+		Category:      strings.TrimSpace(row.Category),
+		Description:   strings.TrimSpace(row.Description),
+		ContextWindow: row.ContextWindow,
+		SortOrder:     row.SortOrder,
+	}
+	if len(row.Capabilities) > 0 {
+		var flags []string
+		if errUnmarshal := json.Unmarshal(row.Capabilities, &flags); errUnmarshal == nil {
+			entry.Capabilities, _ = NormalizeCapabilities(flags)
+		}
+	}
+	return entry
+}
+
+// snapshot is the cached metadata keyed by lowercase model ID.
+type snapshot struct {
+	loadedAt time.Time
+	byModel  map[string]Entry
+}
+
+var current atomic.Pointer[snapshot]
+
+// Invalidate drops the cached metadata so the next lookup reloads it.
+func Invalidate() {
+	current.Store(nil)
+}
+
+// Load returns the enabled display metadata keyed by lowercase model ID, reloading it
+// when the cache is older than cacheTTL. A failed reload keeps serving the previous snapshot.
+func Load(ctx context.Context, db *gorm.DB) map[string]Entry {
+	snap := current.Load()
+	if snap != nil && time.Since(snap.loadedAt) < cacheTTL {
+		return snap.byModel
+	}
+	if db == nil {
+		if snap != nil {
+			return snap.byModel
+		}
+		return map[string]Entry{}
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var rows []models.ModelDisplay
+	if errFind := db.WithContext(ctx).Where("is_enabled = ?", true).Order("id ASC").Find(&rows).Error; errFind != nil {
+		log.WithError(errFind).Warn("model display: load metadata failed")
+		if snap != nil {
+			return snap.byModel
+		}
+		return map[string]Entry{}
+	}
+	byModel := make(map[string]Entry, len(rows))
+	for i := range rows {
+		entry := FromModel(&rows[i])
+		if entry.ModelID == "" {
+			continue
+		}
+		byModel[strings.ToLower(entry.ModelID)] = entry
+	}
+	current.Store(&snapshot{loadedAt: time.Now(), byModel: byModel})
+	return byModel
+}
+
+// Lookup returns the display metadata for modelID.
+func Lookup(entries map[string]Entry, modelID string) (Entry, bool) {
+	entry, ok := entries[strings.ToLower(strings.TrimSpace(modelID))]
+	return entry, ok
+}
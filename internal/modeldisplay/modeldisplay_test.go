@@ -0,0 +1,94 @@
+package modeldisplay
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func TestLabelAndFormatContextWindow(t *testing.T) {
+	cases := map[int]string{
+		200000:  "200k",
+		131072:  "128k",
+		32768:   "32k",
+		1048576: "1M",
+		2000000: "2M",
+		1500000: "1.5M",
+		900:     "900",
+	}
+	for tokens, want := range cases {
+		if got := FormatContextWindow(tokens); got != want {
+			t.Fatalf("FormatContextWindow(%d) = %q, want %q", tokens, got, want)
+		}
+	}
+
+	entry := Entry{ModelID: "claude-sonnet-4-5", DisplayName: "Claude Sonnet 4.5", ContextWindow: 200000}
+	if got := entry.Label(); got != "Claude Sonnet 4.5 (200k ctx)" {
+		t.Fatalf("Label() = %q", got)
+	}
+	if got := (Entry{ModelID: "gpt-5"}).Label(); got != "gpt-5" {
+		t.Fatalf("expected model ID fallback, got %q", got)
+	}
+}
+
+func TestNormalizeCapabilities(t *testing.T) {
+	got, errNormalize := NormalizeCapabilities([]string{" Vision", "tools", "vision", ""})
+	if errNormalize != nil {
+		t.Fatalf("NormalizeCapabilities: %v", errNormalize)
+	}
+	if len(got) != 2 || got[0] != "tools" || got[1] != "vision" {
+		t.Fatalf("unexpected capabilities: %v", got)
+	}
+	if _, errNormalize = NormalizeCapabilities([]string{"telepathy"}); errNormalize == nil {
+		t.Fatalf("expected unknown capability to be rejected")
+	}
+}
+
+func TestLoadCachesEnabledEntries(t *testing.T) {
+	dsn := fmt.Sprintf("file:modeldisplay_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := conn.AutoMigrate(&models.ModelDisplay{}); errMigrate != nil {
+		t.Fatalf("migrate: %v", errMigrate)
+	}
+	rows := []models.ModelDisplay{
+		{ModelID: "Claude-Sonnet-4-5", DisplayName: "Claude Sonnet 4.5", Category: "Claude", ContextWindow: 200000, Capabilities: datatypes.JSON(`["vision","tools"]`), IsEnabled: true},
+		{ModelID: "hidden", DisplayName: "Hidden", Capabilities: datatypes.JSON(`[]`), IsEnabled: true},
+	}
+	if errCreate := conn.Create(&rows).Error; errCreate != nil {
+		t.Fatalf("create rows: %v", errCreate)
+	}
+	if errUpdate := conn.Model(&models.ModelDisplay{}).Where("model_id = ?", "hidden").Update("is_enabled", false).Error; errUpdate != nil {
+		t.Fatalf("disable row: %v", errUpdate)
+	}
+
+	Invalidate()
+	defer Invalidate()
+	entries := Load(context.Background(), conn)
+	entry, ok := Lookup(entries, "claude-sonnet-4-5")
+	if !ok || entry.Category != "Claude" || len(entry.Capabilities) != 2 || entry.Capabilities[0] != "tools" {
+		t.Fatalf("unexpected entry: %+v (found=%v)", entry, ok)
+	}
+	if _, ok = Lookup(entries, "hidden"); ok {
+		t.Fatalf("expected disabled metadata to be skipped")
+	}
+
+	if errDelete := conn.Where("1 = 1").Delete(&models.ModelDisplay{}).Error; errDelete != nil {
+		t.Fatalf("delete rows: %v", errDelete)
+	}
+	if _, ok = Lookup(Load(context.Background(), conn), "claude-sonnet-4-5"); !ok {
+		t.Fatalf("expected cached metadata before invalidation")
+	}
+	Invalidate()
+	if _, ok = Lookup(Load(context.Background(), conn), "claude-sonnet-4-5"); ok {
+		t.Fatalf("expected reload after invalidation")
+	}
+}
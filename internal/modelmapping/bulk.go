@@ -0,0 +1,369 @@
+package modelmapping
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// Bulk input formats.
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// maxBulkEntries bounds a single bulk upsert.
+const maxBulkEntries = 5000
+
+// Entry is one mapping of a bulk upsert. Optional fields left nil keep the stored value on
+// update and take the column default on create.
+type Entry struct {
+	Provider     string              `json:"provider"`
+	ModelName    string              `json:"model_name"`
+	NewModelName string              `json:"new_model_name"`
+	Fork         *bool               `json:"fork,omitempty"`
+	Selector     *int                `json:"selector,omitempty"`
+	RateLimit    *int                `json:"rate_limit,omitempty"`
+	UserGroupID  models.UserGroupIDs `json:"user_group_id,omitempty"`
+	IsEnabled    *bool               `json:"is_enabled,omitempty"`
+}
+
+// ParseBulk decodes pasted mappings. JSON input is an array of entries; CSV input needs a header
+// row naming at least provider, model_name and new_model_name, and separates user group IDs
+// with ";" or "|".
+func ParseBulk(format string, data []byte) ([]Entry, error) {
+	var (
+		entries []Entry
+		errRead error
+	)
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case FormatJSON, "":
+		if errRead = json.Unmarshal(data, &entries); errRead != nil {
+			return nil, fmt.Errorf("invalid json: %w", errRead)
+		}
+	case FormatCSV:
+		if entries, errRead = parseCSV(data); errRead != nil {
+			return nil, errRead
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	return normalizeEntries(entries)
+}
+
+func parseCSV(data []byte) ([]Entry, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	header, errHeader := reader.Read()
+	if errHeader != nil {
+		return nil, errors.New("csv: missing header row")
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range []string{"provider", "model_name", "new_model_name"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("csv: missing %s column", required)
+		}
+	}
+
+	var entries []Entry
+	for line := 2; ; line++ {
+		record, errRecord := reader.Read()
+		if errors.Is(errRecord, io.EOF) {
+			break
+		}
+		if errRecord != nil {
+			return nil, fmt.Errorf("csv line %d: %w", line, errRecord)
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		entry := Entry{Provider: field("provider"), ModelName: field("model_name"), NewModelName: field("new_model_name")}
+		if entry.Provider == "" && entry.ModelName == "" && entry.NewModelName == "" {
+			continue
+		}
+		var errField error
+		if entry.Fork, errField = optionalBool(field("fork")); errField != nil {
+			return nil, fmt.Errorf("csv line %d: fork: %w", line, errField)
+		}
+		if entry.IsEnabled, errField = optionalBool(field("is_enabled")); errField != nil {
+			return nil, fmt.Errorf("csv line %d: is_enabled: %w", line, errField)
+		}
+		if entry.Selector, errField = optionalInt(field("selector")); errField != nil {
+			return nil, fmt.Errorf("csv line %d: selector: %w", line, errField)
+		}
+		if entry.RateLimit, errField = optionalInt(field("rate_limit")); errField != nil {
+			return nil, fmt.Errorf("csv line %d: rate_limit: %w", line, errField)
+		}
+		if groups := field("user_group_id"); groups != "" {
+			for _, part := range strings.FieldsFunc(groups, func(r rune) bool { return r == ';' || r == '|' }) {
+				id, errID := strconv.ParseUint(strings.TrimSpace(part), 10, 64)
+				if errID != nil {
+					return nil, fmt.Errorf("csv line %d: invalid user_group_id %q", line, part)
+				}
+				entry.UserGroupID = append(entry.UserGroupID, &id)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func optionalBool(value string) (*bool, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parsed, errParse := strconv.ParseBool(value)
+	if errParse != nil {
+		return nil, fmt.Errorf("invalid boolean %q", value)
+	}
+	return &parsed, nil
+}
+
+func optionalInt(value string) (*int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parsed, errParse := strconv.Atoi(value)
+	if errParse != nil {
+		return nil, fmt.Errorf("invalid number %q", value)
+	}
+	return &parsed, nil
+}
+
+// normalizeEntries trims and validates entries and rejects the same provider and model twice.
+func normalizeEntries(entries []Entry) ([]Entry, error) {
+	if len(entries) == 0 {
+		return nil, errors.New("no mappings")
+	}
+	if len(entries) > maxBulkEntries {
+		return nil, fmt.Errorf("too many mappings (max %d)", maxBulkEntries)
+	}
+	seen := make(map[string]int, len(entries))
+	for i := range entries {
+		entry := &entries[i]
+		entry.Provider = strings.TrimSpace(entry.Provider)
+		entry.ModelName = strings.TrimSpace(entry.ModelName)
+		entry.NewModelName = strings.TrimSpace(entry.NewModelName)
+		switch {
+		case entry.Provider == "":
+			return nil, fmt.Errorf("mapping %d: provider is required", i+1)
+		case entry.ModelName == "":
+			return nil, fmt.Errorf("mapping %d: model_name is required", i+1)
+		case entry.NewModelName == "":
+			return nil, fmt.Errorf("mapping %d: new_model_name is required", i+1)
+		}
+		if entry.Selector != nil && (*entry.Selector < 0 || *entry.Selector > 2) {
+			return nil, fmt.Errorf("mapping %d: selector must be 0, 1, or 2", i+1)
+		}
+		if entry.UserGroupID != nil {
+			entry.UserGroupID = entry.UserGroupID.Clean()
+		}
+		key := makeLowerKey(entry.Provider, entry.ModelName)
+		if first, dup := seen[key]; dup {
+			return nil, fmt.Errorf("mapping %d duplicates mapping %d (%s/%s)", i+1, first, entry.Provider, entry.ModelName)
+		}
+		seen[key] = i + 1
+	}
+	return entries, nil
+}
+
+// UpsertResult counts the rows written by Upsert.
+type UpsertResult struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+}
+
+// Upsert creates or updates one mapping per entry, matching stored rows on provider and model
+// name case-insensitively; when several rows match, the newest is updated.
+func Upsert(ctx context.Context, db *gorm.DB, entries []Entry, now time.Time) (UpsertResult, error) {
+	var result UpsertResult
+	if db == nil {
+		return result, errors.New("nil db")
+	}
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []models.ModelMapping
+		if errFind := tx.Select("id", "provider", "model_name").Order("id ASC").Find(&existing).Error; errFind != nil {
+			return errFind
+		}
+		idByKey := make(map[string]uint64, len(existing))
+		for _, row := range existing {
+			idByKey[makeLowerKey(row.Provider, row.ModelName)] = row.ID
+		}
+		for _, entry := range entries {
+			if id, ok := idByKey[makeLowerKey(entry.Provider, entry.ModelName)]; ok {
+				updates := map[string]any{"new_model_name": entry.NewModelName, "updated_at": now}
+				if entry.Fork != nil {
+					updates["fork"] = *entry.Fork
+				}
+				if entry.Selector != nil {
+					updates["selector"] = *entry.Selector
+				}
+				if entry.RateLimit != nil {
+					updates["rate_limit"] = *entry.RateLimit
+				}
+				if entry.UserGroupID != nil {
+					updates["user_group_id"] = entry.UserGroupID.Clean()
+				}
+				if entry.IsEnabled != nil {
+					updates["is_enabled"] = *entry.IsEnabled
+				}
+				if errUpdate := tx.Model(&models.ModelMapping{}).Where("id = ?", id).Updates(updates).Error; errUpdate != nil {
+					return errUpdate
+				}
+				result.Updated++
+				continue
+			}
+			mapping := models.ModelMapping{
+				Provider:     entry.Provider,
+				ModelName:    entry.ModelName,
+				NewModelName: entry.NewModelName,
+				UserGroupID:  entry.UserGroupID.Clean(),
+				IsEnabled:    true,
+				CreatedAt:    now,
+				UpdatedAt:    now,
+			}
+			if entry.Fork != nil {
+				mapping.Fork = *entry.Fork
+			}
+			if entry.Selector != nil {
+				mapping.Selector = *entry.Selector
+			}
+			if entry.RateLimit != nil {
+				mapping.RateLimit = *entry.RateLimit
+			}
+			if entry.IsEnabled != nil {
+				mapping.IsEnabled = *entry.IsEnabled
+			}
+			if errCreate := tx.Create(&mapping).Error; errCreate != nil {
+				return errCreate
+			}
+			if entry.IsEnabled != nil && !*entry.IsEnabled {
+				// GORM replaces a false value with the column default on create.
+				if errDisable := tx.Model(&mapping).Update("is_enabled", false).Error; errDisable != nil {
+					return errDisable
+				}
+			}
+			result.Created++
+		}
+		return nil
+	})
+	if errTx != nil {
+		return UpsertResult{}, errTx
+	}
+	return result, nil
+}
+
+// Merge applies entries to existing rows in memory the way Upsert would, for previews. New
+// rows get no ID.
+func Merge(existing []models.ModelMapping, entries []Entry) ([]models.ModelMapping, UpsertResult) {
+	var result UpsertResult
+	out := append([]models.ModelMapping(nil), existing...)
+	indexByKey := make(map[string]int, len(out))
+	for i := range out {
+		key := makeLowerKey(out[i].Provider, out[i].ModelName)
+		if prev, ok := indexByKey[key]; !ok || out[i].ID > out[prev].ID {
+			indexByKey[key] = i
+		}
+	}
+	for _, entry := range entries {
+		i, ok := indexByKey[makeLowerKey(entry.Provider, entry.ModelName)]
+		if !ok {
+			out = append(out, models.ModelMapping{Provider: entry.Provider, ModelName: entry.ModelName, IsEnabled: true})
+			i = len(out) - 1
+			result.Created++
+		} else {
+			result.Updated++
+		}
+		row := &out[i]
+		row.NewModelName = entry.NewModelName
+		if entry.Fork != nil {
+			row.Fork = *entry.Fork
+		}
+		if entry.Selector != nil {
+			row.Selector = *entry.Selector
+		}
+		if entry.RateLimit != nil {
+			row.RateLimit = *entry.RateLimit
+		}
+		if entry.UserGroupID != nil {
+			row.UserGroupID = entry.UserGroupID.Clean()
+		}
+		if entry.IsEnabled != nil {
+			row.IsEnabled = *entry.IsEnabled
+		}
+	}
+	return out, result
+}
+
+// Conflict is an alias exposed by enabled mappings of more than one provider, so which
+// upstream model serves it depends on the provider the request is routed to.
+type Conflict struct {
+	Alias      string         `json:"alias"`
+	Providers  []string       `json:"providers"`
+	Mappings   []ConflictItem `json:"mappings"`
+	SameTarget bool           `json:"same_target"` // Whether every provider maps the alias to the same model name.
+}
+
+// ConflictItem is one mapping taking part in a conflict.
+type ConflictItem struct {
+	ID        uint64 `json:"id"`
+	Provider  string `json:"provider"`
+	ModelName string `json:"model_name"`
+}
+
+// FindConflicts returns the aliases shared by enabled mappings of different providers,
+// compared case-insensitively and ordered by alias.
+func FindConflicts(rows []models.ModelMapping) []Conflict {
+	byAlias := make(map[string][]models.ModelMapping)
+	for _, row := range rows {
+		alias := strings.TrimSpace(row.NewModelName)
+		if !row.IsEnabled || alias == "" || strings.TrimSpace(row.Provider) == "" {
+			continue
+		}
+		key := strings.ToLower(alias)
+		byAlias[key] = append(byAlias[key], row)
+	}
+	var out []Conflict
+	for _, group := range byAlias {
+		providers := make(map[string]struct{})
+		for _, row := range group {
+			providers[strings.ToLower(strings.TrimSpace(row.Provider))] = struct{}{}
+		}
+		if len(providers) < 2 {
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool { return group[i].ID < group[j].ID })
+		conflict := Conflict{Alias: strings.TrimSpace(group[0].NewModelName), SameTarget: true}
+		for provider := range providers {
+			conflict.Providers = append(conflict.Providers, provider)
+		}
+		sort.Strings(conflict.Providers)
+		for _, row := range group {
+			conflict.Mappings = append(conflict.Mappings, ConflictItem{ID: row.ID, Provider: strings.TrimSpace(row.Provider), ModelName: strings.TrimSpace(row.ModelName)})
+			if !strings.EqualFold(strings.TrimSpace(row.ModelName), strings.TrimSpace(group[0].ModelName)) {
+				conflict.SameTarget = false
+			}
+		}
+		out = append(out, conflict)
+	}
+	sort.Slice(out, func(i, j int) bool { return strings.ToLower(out[i].Alias) < strings.ToLower(out[j].Alias) })
+	return out
+}
@@ -0,0 +1,117 @@
+package modelmapping
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestParseBulkCSV(t *testing.T) {
+	content := "provider,model_name,new_model_name,selector,user_group_id,is_enabled\n" +
+		"claude, claude-sonnet-4 ,sonnet,1,3;4,\n" +
+		"\n" +
+		"codex,gpt-5,gpt,,,false\n"
+	entries, errParse := ParseBulk(FormatCSV, []byte(content))
+	if errParse != nil {
+		t.Fatalf("parse: %v", errParse)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	first := entries[0]
+	if first.ModelName != "claude-sonnet-4" || first.Selector == nil || *first.Selector != 1 || len(first.UserGroupID) != 2 || first.IsEnabled != nil {
+		t.Fatalf("unexpected first entry %+v", first)
+	}
+	if second := entries[1]; second.IsEnabled == nil || *second.IsEnabled || second.UserGroupID != nil {
+		t.Fatalf("unexpected second entry %+v", second)
+	}
+
+	for name, input := range map[string]string{
+		"missing column": "provider,model_name\nclaude,a\n",
+		"duplicate":      "provider,model_name,new_model_name\nclaude,a,x\nClaude,A,y\n",
+		"bad selector":   "provider,model_name,new_model_name,selector\nclaude,a,x,5\n",
+	} {
+		if _, errInvalid := ParseBulk(FormatCSV, []byte(input)); errInvalid == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestUpsertAndConflicts(t *testing.T) {
+	dsn := fmt.Sprintf("file:modelmapping_bulk_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	existing := models.ModelMapping{Provider: "claude", ModelName: "claude-sonnet-4", NewModelName: "old", Selector: 2, IsEnabled: true}
+	if errCreate := conn.Create(&existing).Error; errCreate != nil {
+		t.Fatalf("create mapping: %v", errCreate)
+	}
+
+	entries, errParse := ParseBulk(FormatJSON, []byte(`[
+		{"provider":"Claude","model_name":"claude-sonnet-4","new_model_name":"sonnet"},
+		{"provider":"kiro","model_name":"claude-sonnet-4","new_model_name":"Sonnet"},
+		{"provider":"codex","model_name":"gpt-5","new_model_name":"gpt","is_enabled":false}
+	]`))
+	if errParse != nil {
+		t.Fatalf("parse: %v", errParse)
+	}
+
+	var before []models.ModelMapping
+	if errFind := conn.Find(&before).Error; errFind != nil {
+		t.Fatalf("load mappings: %v", errFind)
+	}
+	merged, preview := Merge(before, entries)
+	if preview.Created != 2 || preview.Updated != 1 || len(FindConflicts(merged)) != 1 {
+		t.Fatalf("unexpected preview %+v", preview)
+	}
+
+	result, errUpsert := Upsert(context.Background(), conn, entries, time.Now().UTC())
+	if errUpsert != nil {
+		t.Fatalf("upsert: %v", errUpsert)
+	}
+	if result.Created != 2 || result.Updated != 1 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	var rows []models.ModelMapping
+	if errFind := conn.Order("id ASC").Find(&rows).Error; errFind != nil {
+		t.Fatalf("load mappings: %v", errFind)
+	}
+	if len(rows) != 3 || rows[0].NewModelName != "sonnet" || rows[0].Selector != 2 || rows[2].IsEnabled {
+		t.Fatalf("unexpected rows %+v", rows)
+	}
+
+	conflicts := FindConflicts(rows)
+	if len(conflicts) != 1 {
+		t.Fatalf("expected one conflict, got %+v", conflicts)
+	}
+	if conflict := conflicts[0]; !strings.EqualFold(conflict.Alias, "sonnet") || len(conflict.Providers) != 2 || !conflict.SameTarget {
+		t.Fatalf("unexpected conflict %+v", conflict)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	StoreModelMappings(time.Now().UTC(), []models.ModelMapping{
+		{ID: 1, Provider: "claude", ModelName: "claude-sonnet-4", NewModelName: "sonnet", Selector: 1, RateLimit: 5, IsEnabled: true},
+	})
+	resolution := Resolve("claude", "claude-sonnet-4")
+	if !resolution.Aliased || resolution.Alias != "sonnet" || !resolution.Matched || resolution.MappingID != 1 || resolution.RateLimit != 5 {
+		t.Fatalf("unexpected resolution %+v", resolution)
+	}
+	if byAlias := Resolve("claude", "sonnet"); byAlias.Aliased || !byAlias.Matched || byAlias.Selector != 1 {
+		t.Fatalf("unexpected alias resolution %+v", byAlias)
+	}
+	if missing := Resolve("codex", "claude-sonnet-4"); missing.Aliased || missing.Matched {
+		t.Fatalf("unexpected resolution for unmapped provider %+v", missing)
+	}
+}
@@ -201,3 +201,34 @@ func makeKey(provider, model string) string {
 func makeLowerKey(provider, model string) string {
 	return strings.ToLower(strings.TrimSpace(provider)) + "\x00" + strings.ToLower(strings.TrimSpace(model))
 }
+
+// Resolution describes how the live snapshot treats a model name for one provider.
+type Resolution struct {
+	Provider     string              `json:"provider"`
+	Model        string              `json:"model"`
+	Alias        string              `json:"alias,omitempty"` // Client-visible name of Model when it is mapped.
+	Aliased      bool                `json:"aliased"`         // Whether LookupMappedModelName renames Model.
+	Matched      bool                `json:"matched"`         // Whether a mapping supplies routing settings.
+	MappingID    uint64              `json:"mapping_id,omitempty"`
+	Selector     int                 `json:"selector"`
+	RateLimit    int                 `json:"rate_limit"`
+	UserGroupIDs models.UserGroupIDs `json:"user_group_id"`
+}
+
+// Resolve explains how provider + model resolve against the current snapshot: the alias the
+// model is exposed as, and the mapping whose selector, rate limit and user groups apply.
+func Resolve(provider, model string) Resolution {
+	out := Resolution{Provider: strings.TrimSpace(provider), Model: strings.TrimSpace(model)}
+	if alias, ok := LookupMappedModelName(out.Provider, out.Model); ok {
+		out.Alias, out.Aliased = alias, true
+	}
+	if id, selector, ok := LookupSelector(out.Provider, out.Model); ok {
+		out.Matched, out.MappingID, out.Selector = true, id, selector
+		_, out.RateLimit, _ = LookupRateLimit(out.Provider, out.Model)
+		out.UserGroupIDs, _ = LookupUserGroupIDs(out.Provider, out.Model)
+	}
+	if out.UserGroupIDs == nil {
+		out.UserGroupIDs = models.UserGroupIDs{}
+	}
+	return out
+}
@@ -17,7 +17,15 @@ type Admin struct {
 
 	IsSuperAdmin bool `gorm:"not null;default:false"` // Grants all permissions when true.
 
-	Permissions datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'"` // Permission keys in JSON.
+	OrganizationID *uint64 `gorm:"index"` // Organization the admin is confined to; nil for global admins.
+
+	Permissions       datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'"` // Permission keys granted directly, on top of roles.
+	DeniedPermissions datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'"` // Permission keys removed from the roles' grants.
+
+	AllowedIPs datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'"` // Source IPs or CIDRs the admin may sign in from; empty allows all.
+
+	OIDCIssuer  string `gorm:"column:oidc_issuer;type:text;index:idx_admins_oidc_identity"`  // Issuer of the linked SSO identity.
+	OIDCSubject string `gorm:"column:oidc_subject;type:text;index:idx_admins_oidc_identity"` // Subject of the linked SSO identity; empty for local accounts.
 
 	TOTPSecret            string  `gorm:"type:text"`    // TOTP secret for MFA.
 	PasskeyID             []byte  `gorm:"type:bytea"`   // WebAuthn credential ID.
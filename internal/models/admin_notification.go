@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// AdminNotification is an alert shown in the admin console until an admin marks it read.
+type AdminNotification struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	EventID   string `gorm:"type:varchar(64);not null;index"`          // Bus event identifier.
+	EventType string `gorm:"type:varchar(64);not null;index"`          // Event type, e.g. usage.anomaly.
+	Severity  string `gorm:"type:varchar(16);not null;default:'info'"` // Event severity.
+	Subject   string `gorm:"type:text;not null;default:'';index"`      // Subject the alert refers to.
+	Message   string `gorm:"type:text;not null;default:''"`            // Human readable summary.
+
+	Data datatypes.JSON `gorm:"type:jsonb;not null;default:'{}'"` // Event payload.
+
+	ReadAt *time.Time `gorm:"index"`             // When an admin marked the alert read.
+	ReadBy string     `gorm:"type:varchar(255)"` // Admin that marked the alert read.
+
+	OccurredAt time.Time `gorm:"not null;index"`          // Event timestamp.
+	CreatedAt  time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// AdminRole is a named set of admin permissions that can be assigned to many admins.
+type AdminRole struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Name        string         `gorm:"type:varchar(100);not null;uniqueIndex"` // Unique role name, e.g. "billing-admin".
+	Description string         `gorm:"type:text"`                              // Free-form description.
+	Permissions datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'"`       // Permission keys in JSON.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
+
+// AdminRoleAssignment links an admin to a role.
+type AdminRoleAssignment struct {
+	AdminID uint64 `gorm:"primaryKey"`       // Assigned admin ID.
+	RoleID  uint64 `gorm:"primaryKey;index"` // Assigned role ID.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Assignment timestamp.
+}
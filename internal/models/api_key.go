@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
 
 // APIKey represents an API key issued to a user or admin.
 type APIKey struct {
@@ -9,6 +13,8 @@ type APIKey struct {
 	UserID *uint64 `gorm:"index"`             // Owning user ID when bound to a user.
 	User   *User   `gorm:"foreignKey:UserID"` // Associated user record.
 
+	TeamID *uint64 `gorm:"index"` // Team whose pool pays for the key; UserID is the member who issued it.
+
 	Name   string `gorm:"type:text;not null"`             // Display name for the key.
 	APIKey string `gorm:"type:text;not null;uniqueIndex"` // Full API key string.
 
@@ -19,6 +25,14 @@ type APIKey struct {
 	RevokedAt  *time.Time // Revocation timestamp when disabled.
 	LastUsedAt *time.Time // Last successful usage time.
 
+	AllowedModels       datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'"` // Model names the key may request; empty allows all.
+	AllowedProviders    datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'"` // Providers the key may route to; empty allows all.
+	MaxTokensPerRequest *int           // Optional cap on output tokens per request.
+	AllowedIPs          datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'"` // Source IPs or CIDRs allowed to use the key; empty allows all.
+
+	RPMLimit int `gorm:"not null;default:0"` // Requests per minute for this key; zero means unlimited.
+	TPMLimit int `gorm:"not null;default:0"` // Tokens per minute for this key; zero means unlimited.
+
 	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
 	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
 }
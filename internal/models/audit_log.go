@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// AuditLog stores a persisted copy of security relevant bus events.
+type AuditLog struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	EventID   string `gorm:"type:varchar(64);not null;index"`          // Bus event identifier.
+	EventType string `gorm:"type:varchar(64);not null;index"`          // Event type, e.g. auth.login_failed.
+	Severity  string `gorm:"type:varchar(16);not null;default:'info'"` // Event severity.
+	Subject   string `gorm:"type:text;not null;default:'';index"`      // Subject the event refers to.
+	Message   string `gorm:"type:text;not null;default:''"`            // Human readable summary.
+
+	Data datatypes.JSON `gorm:"type:jsonb;not null;default:'{}'"` // Event payload.
+
+	OccurredAt time.Time `gorm:"not null;index"`          // Event timestamp.
+	CreatedAt  time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+}
@@ -179,6 +179,21 @@ type Auth struct {
 	LastAuthCheckAt *time.Time `gorm:"type:timestamptz"`                    // Latest auth health check time.
 	LastAuthError   string     `gorm:"type:text"`                           // Latest auth health check error detail.
 
+	CoolingDown    bool       `gorm:"type:boolean;not null;default:false;index"` // Whether the cooldown scheduler took the auth out of routing.
+	CooldownUntil  *time.Time `gorm:"index"`                                     // End of the latest automatic cooldown window.
+	CooldownReason string     `gorm:"type:text"`                                 // Upstream error that started the latest cooldown.
+
+	PendingApproval bool   `gorm:"type:boolean;not null;default:false;index"` // Whether the import waits for super-admin approval before routing.
+	ApprovalReason  string `gorm:"type:text"`                                 // Why the import was held for approval.
+	ImportedBy      string `gorm:"type:varchar(255)"`                         // Admin username that last imported the auth.
+
+	ContributedByUserID *uint64 `gorm:"index"` // Front user who contributed the credential to the co-op pool.
+
+	QuotaPollIntervalSeconds *int `gorm:"column:quota_poll_interval_seconds"` // Per-auth quota poll interval override; nil uses the global setting.
+	PollEnabled              bool `gorm:"type:boolean;not null;default:true"` // Whether the quota poller refreshes this auth.
+
+	Environments datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'"` // Environment tags; empty shares the auth with every environment.
+
 	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
 	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
 }
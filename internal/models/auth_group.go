@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
 
 // AuthGroup groups auth entries for access control.
 type AuthGroup struct {
@@ -12,6 +16,9 @@ type AuthGroup struct {
 
 	UserGroupID UserGroupIDs `gorm:"type:jsonb;not null;default:'[]'"` // Allowed user group IDs.
 
+	Schedule    datatypes.JSON `gorm:"type:jsonb"`             // Time windows the group routes in; empty routes always.
+	OffSchedule bool           `gorm:"not null;default:false"` // Set while the group is outside its schedule, hiding its auths.
+
 	Auths []Auth `gorm:"-"` // Related auth records (not persisted).
 
 	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// AuthImportConflict holds an imported auth entry whose key collides with an existing
+// auth until an admin resolves it.
+type AuthImportConflict struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	AuthKey string `gorm:"type:text;not null;uniqueIndex"` // Key of the existing auth; a newer import replaces the held entry.
+	AuthID  uint64 `gorm:"not null;index"`                 // Existing auth the entry collides with.
+	Source  string `gorm:"type:text"`                      // Uploaded file name or entry index.
+
+	ProxyURL    string         `gorm:"type:text"`                        // Incoming proxy override.
+	AuthGroupID AuthGroupIDs   `gorm:"type:jsonb;not null;default:'[]'"` // Incoming auth group IDs.
+	Content     datatypes.JSON `gorm:"type:jsonb;not null"`              // Incoming auth payload content.
+
+	ImportedBy string `gorm:"type:varchar(255)"` // Admin username that uploaded the entry.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// AuthReauth is a pending re-authentication of an auth file. It is keyed by the OAuth flow
+// state so concurrent flows stay apart, and stored in the database so the credential can be
+// saved by whichever instance completes the flow.
+type AuthReauth struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	State     string    `gorm:"type:varchar(255);not null;uniqueIndex"` // OAuth state returned when the flow started.
+	Provider  string    `gorm:"type:varchar(64);not null;index"`        // Canonical provider of the auth file.
+	AuthKey   string    `gorm:"type:text;not null"`                     // Auth file key receiving the new credential.
+	Email     string    `gorm:"type:text"`                              // Account email the new credential must carry.
+	ProjectID string    `gorm:"type:text"`                              // Project the new credential must carry, when the auth file has one.
+	ExpiresAt time.Time `gorm:"not null;index"`                         // When the pending flow is abandoned.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+}
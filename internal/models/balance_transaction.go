@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// BalanceTransactionTarget identifies which balance a ledger entry changed.
+type BalanceTransactionTarget string
+
+// BalanceTransactionTarget constants define ledger targets.
+const (
+	// BalanceTransactionTargetPrepaid marks a change to a prepaid card balance.
+	BalanceTransactionTargetPrepaid BalanceTransactionTarget = "prepaid"
+	// BalanceTransactionTargetBill marks a change to a bill's remaining quota.
+	BalanceTransactionTargetBill BalanceTransactionTarget = "bill"
+)
+
+// BalanceTransactionKind describes why a balance changed.
+type BalanceTransactionKind string
+
+// BalanceTransactionKind constants define ledger entry kinds.
+const (
+	// BalanceTransactionKindUsage records a deduction for proxied usage.
+	BalanceTransactionKindUsage BalanceTransactionKind = "usage"
+	// BalanceTransactionKindBillPayment records prepaid balance spent on a bill.
+	BalanceTransactionKindBillPayment BalanceTransactionKind = "bill_payment"
+	// BalanceTransactionKindAdjustment records a manual admin credit or debit.
+	BalanceTransactionKindAdjustment BalanceTransactionKind = "adjustment"
+	// BalanceTransactionKindRefund records an admin refund.
+	BalanceTransactionKindRefund BalanceTransactionKind = "refund"
+)
+
+// BalanceTransaction is one ledger entry for a change to a user's prepaid balance or bill quota.
+// Amount is signed: credits are positive and deductions negative.
+type BalanceTransaction struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	UserID uint64                   `gorm:"not null;index"`                  // Owner of the changed balance.
+	Target BalanceTransactionTarget `gorm:"type:varchar(16);not null;index"` // Balance that changed.
+	Kind   BalanceTransactionKind   `gorm:"type:varchar(32);not null;index"` // Reason category.
+
+	Amount       float64 `gorm:"type:decimal(20,10);not null"` // Signed change applied.
+	BalanceAfter float64 `gorm:"type:decimal(20,10);not null"` // Card balance or bill left quota after the change.
+
+	PrepaidCardID *uint64 `gorm:"index"` // Changed prepaid card, for prepaid entries.
+	BillID        *uint64 `gorm:"index"` // Changed or purchased bill, if any.
+	UsageID       *uint64 `gorm:"index"` // Usage row that caused the deduction, if any.
+
+	Reason string `gorm:"type:text"`         // Free-form explanation.
+	Actor  string `gorm:"type:varchar(128)"` // "system" or "admin:<username>".
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime;index"` // Creation timestamp.
+}
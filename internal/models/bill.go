@@ -28,6 +28,17 @@ const (
 	BillStatusRefunded BillStatus = 4
 )
 
+// BillRenewalMode controls how an auto-renewed bill is paid.
+type BillRenewalMode int
+
+// BillRenewalMode constants define renewal payment modes.
+const (
+	// BillRenewalModeManual creates the next bill as pending until it is paid.
+	BillRenewalModeManual BillRenewalMode = 1
+	// BillRenewalModePrepaid pays the next bill from prepaid card balance, falling back to pending.
+	BillRenewalModePrepaid BillRenewalMode = 2
+)
+
 // Bill records a user billing period and quota usage.
 type Bill struct {
 	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
@@ -42,7 +53,8 @@ type Bill struct {
 
 	PeriodType BillPeriodType `gorm:"not null"` // Billing period type.
 
-	Amount float64 `gorm:"type:decimal(10,2);not null;default:0"` // Bill amount.
+	Amount   float64 `gorm:"type:decimal(10,2);not null;default:0"`  // Bill amount.
+	Currency string  `gorm:"type:varchar(8);not null;default:'USD'"` // ISO currency of Amount; quotas stay in USD.
 
 	PeriodStart time.Time `gorm:"not null"` // Period start time.
 	PeriodEnd   time.Time `gorm:"not null"` // Period end time.
@@ -58,6 +70,10 @@ type Bill struct {
 	IsEnabled bool       `gorm:"not null;default:true"` // Whether the bill is active.
 	Status    BillStatus `gorm:"not null;default:1"`    // Current bill status.
 
+	AutoRenew     bool            `gorm:"not null;default:false"` // Whether the next period is created automatically.
+	RenewalMode   BillRenewalMode `gorm:"not null;default:1"`     // How the renewed bill is paid.
+	RenewedBillID *uint64         `gorm:"index"`                  // Bill created by auto-renewal, if any.
+
 	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
 	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
 }
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
 
 // BillingType defines how costs are calculated.
 type BillingType int
@@ -11,8 +15,20 @@ const (
 	BillingTypePerRequest BillingType = 1
 	// BillingTypePerToken charges per token.
 	BillingTypePerToken BillingType = 2
+	// BillingTypeTiered charges per token with prices that step down as monthly volume grows.
+	BillingTypeTiered BillingType = 3
 )
 
+// BillingTokenTier prices the tokens that fall within one volume band of a tiered rule.
+// Prices are per 1,000,000 tokens, like the flat per-token prices.
+type BillingTokenTier struct {
+	UpToTokens            int64    `json:"up_to_tokens"`             // Upper bound of cumulative monthly volume; zero means unbounded.
+	PriceInputToken       *float64 `json:"price_input_token"`        // Input token price within the band.
+	PriceOutputToken      *float64 `json:"price_output_token"`       // Output token price within the band.
+	PriceCacheCreateToken *float64 `json:"price_cache_create_token"` // Cache create token price within the band.
+	PriceCacheReadToken   *float64 `json:"price_cache_read_token"`   // Cache read token price within the band.
+}
+
 // BillingRule defines pricing and applicability for a provider/model pair.
 type BillingRule struct {
 	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
@@ -30,6 +46,15 @@ type BillingRule struct {
 	PriceCacheCreateToken *float64 `gorm:"type:decimal(20,10)"` // Cache create token price.
 	PriceCacheReadToken   *float64 `gorm:"type:decimal(20,10)"` // Cache read token price.
 
+	Currency string `gorm:"type:varchar(8);not null;default:'USD'"` // ISO currency of the prices; costs are converted to USD.
+
+	TokenTiers    datatypes.JSON `gorm:"type:jsonb"`          // Ordered []BillingTokenTier for tiered billing.
+	MinimumCharge *float64       `gorm:"type:decimal(20,10)"` // Optional minimum charge per successful request.
+
+	// Optional footprint factors used to estimate energy and CO2 per request.
+	EnergyWhPerMillionTokens *float64 `gorm:"type:decimal(20,10)"`                             // Energy in Wh per 1M tokens.
+	CarbonGramsPerKWh        *float64 `gorm:"column:carbon_grams_per_kwh;type:decimal(20,10)"` // Grid carbon intensity in gCO2e per kWh.
+
 	IsEnabled bool `gorm:"not null;default:true"` // Whether the rule is active.
 
 	AuthGroup AuthGroup `gorm:"foreignKey:AuthGroupID"` // Auth group relation.
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// BulkDeleteStatus represents the lifecycle state of a bulk delete job.
+type BulkDeleteStatus string
+
+// BulkDeleteStatus constants define bulk delete job states.
+const (
+	// BulkDeleteStatusPending marks a job waiting for the runner.
+	BulkDeleteStatusPending BulkDeleteStatus = "pending"
+	// BulkDeleteStatusRunning marks a job whose batches are being deleted.
+	BulkDeleteStatusRunning BulkDeleteStatus = "running"
+	// BulkDeleteStatusCompleted marks a job that deleted every matching row.
+	BulkDeleteStatusCompleted BulkDeleteStatus = "completed"
+	// BulkDeleteStatusCancelled marks a job stopped by an admin.
+	BulkDeleteStatusCancelled BulkDeleteStatus = "cancelled"
+	// BulkDeleteStatusFailed marks a job stopped by an error.
+	BulkDeleteStatusFailed BulkDeleteStatus = "failed"
+)
+
+// BulkDeleteJob records an asynchronous, batched delete of rows matching admin criteria.
+type BulkDeleteJob struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Entity    string         `gorm:"type:varchar(32);not null"` // Target entity (usages, prepaid_cards, auths).
+	Criteria  datatypes.JSON `gorm:"type:jsonb;not null"`       // Filter selecting the rows to delete.
+	BatchSize int            `gorm:"not null"`                  // Rows deleted per batch.
+
+	Status    BulkDeleteStatus `gorm:"type:varchar(16);not null;index"` // Current job status.
+	Total     int64            `gorm:"not null;default:0"`              // Matching rows counted when the job started.
+	Deleted   int64            `gorm:"not null;default:0"`              // Rows deleted so far.
+	LastError string           `gorm:"type:text"`                       // Error captured when the job fails.
+
+	CreatedBy *uint64 // Admin ID that created the job.
+
+	StartedAt  *time.Time // When the runner picked the job up.
+	FinishedAt *time.Time // When the job completed, failed or was cancelled.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ClusterInstance registers one running server that shares this database. Each instance
+// refreshes its own row on a heartbeat.
+type ClusterInstance struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	InstanceID   string `gorm:"type:varchar(64);not null;uniqueIndex"` // Identifier generated at process start.
+	Hostname     string `gorm:"type:varchar(255);not null;default:''"` // Host the process runs on.
+	Version      string `gorm:"type:varchar(64);not null;default:''"`  // Build version.
+	Environment  string `gorm:"type:varchar(32);not null;default:''"`  // Environment tag the instance serves.
+	BusConnected bool   `gorm:"not null;default:false"`                // Whether the instance is subscribed to the change bus.
+
+	StartedAt  time.Time `gorm:"not null"`       // Process start time.
+	LastSeenAt time.Time `gorm:"not null;index"` // Latest heartbeat.
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// CoopContributionStatus represents the lifecycle state of a co-op pool contribution.
+type CoopContributionStatus string
+
+// CoopContributionStatus constants define contribution states.
+const (
+	// CoopContributionStatusActive marks a credential serving pool traffic (or awaiting approval).
+	CoopContributionStatusActive CoopContributionStatus = "active"
+	// CoopContributionStatusSuspended marks a credential taken out of routing by an admin.
+	CoopContributionStatusSuspended CoopContributionStatus = "suspended"
+	// CoopContributionStatusRevoked marks a withdrawn credential whose auth row was deleted.
+	CoopContributionStatusRevoked CoopContributionStatus = "revoked"
+)
+
+// CoopContribution records a provider credential a user contributed to the shared pool and
+// the credit earned from usage it served.
+type CoopContribution struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	UserID   uint64 `gorm:"not null;index"`            // Contributing user ID.
+	AuthID   uint64 `gorm:"not null;index"`            // Auth row holding the credential; kept after revocation.
+	AuthKey  string `gorm:"type:text;not null"`        // Auth key at contribution time.
+	Provider string `gorm:"type:varchar(64);not null"` // Credential provider type.
+
+	Status CoopContributionStatus `gorm:"type:varchar(16);not null;default:'active';index"` // Current status.
+
+	CreditRate     float64    `gorm:"type:decimal(10,4);not null;default:0"`  // Share of served cost credited back.
+	SettledUntil   time.Time  `gorm:"not null"`                               // Usage up to this time has been credited.
+	CreditedAmount float64    `gorm:"type:decimal(20,10);not null;default:0"` // Total credit issued.
+	ServedRequests int64      `gorm:"not null;default:0"`                     // Requests from other users served so far.
+	SuspendReason  string     `gorm:"type:text"`                              // Admin note when suspended.
+	RevokedAt      *time.Time // Time the contribution was withdrawn.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
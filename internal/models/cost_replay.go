@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// BillingRuleSnapshot freezes a set of billing rules so usage can be repriced against it later.
+type BillingRuleSnapshot struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Name        string         `gorm:"type:varchar(128);not null"` // Display name.
+	Rules       datatypes.JSON `gorm:"type:jsonb;not null"`        // []BillingRule as captured or proposed.
+	RuleCount   int            `gorm:"not null;default:0"`         // Number of rules in the snapshot.
+	Fingerprint string         `gorm:"type:varchar(64);index"`     // SHA-256 of the pricing fields, used to skip unchanged captures.
+	Proposed    bool           `gorm:"not null;default:false"`     // Whether the rules were supplied rather than captured.
+
+	CreatedBy *uint64 // Admin ID that created the snapshot; nil for automatic captures.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime;index"` // Creation timestamp.
+}
+
+// CostReplayStatus represents the lifecycle state of a cost replay job.
+type CostReplayStatus string
+
+// CostReplayStatus constants define cost replay job states.
+const (
+	// CostReplayStatusPending marks a job waiting for the runner.
+	CostReplayStatusPending CostReplayStatus = "pending"
+	// CostReplayStatusRunning marks a job whose usage is being repriced.
+	CostReplayStatusRunning CostReplayStatus = "running"
+	// CostReplayStatusCompleted marks a job whose report is ready.
+	CostReplayStatusCompleted CostReplayStatus = "completed"
+	// CostReplayStatusCancelled marks a job stopped by an admin.
+	CostReplayStatusCancelled CostReplayStatus = "cancelled"
+	// CostReplayStatusFailed marks a job stopped by an error.
+	CostReplayStatusFailed CostReplayStatus = "failed"
+)
+
+// CostReplayJob reprices the usage of a period against a billing rule snapshot.
+type CostReplayJob struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	SnapshotID  uint64    `gorm:"not null;index"` // Billing rule snapshot to price against.
+	PeriodStart time.Time `gorm:"not null"`       // Inclusive start of the repriced usage.
+	PeriodEnd   time.Time `gorm:"not null"`       // Exclusive end of the repriced usage.
+	UserID      *uint64   // Restrict the replay to one user, if set.
+
+	Status    CostReplayStatus `gorm:"type:varchar(16);not null;index"` // Current job status.
+	Total     int64            `gorm:"not null;default:0"`              // Usage rows counted when the job started.
+	Processed int64            `gorm:"not null;default:0"`              // Usage rows repriced so far.
+	Report    datatypes.JSON   `gorm:"type:jsonb"`                      // Comparison report once completed.
+	LastError string           `gorm:"type:text"`                       // Error captured when the job fails.
+
+	CreatedBy *uint64 // Admin ID that created the job.
+
+	StartedAt  *time.Time // When the runner picked the job up.
+	FinishedAt *time.Time // When the job completed, failed or was cancelled.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
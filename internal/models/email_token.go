@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// EmailTokenPurpose names what an emailed token authorizes.
+type EmailTokenPurpose string
+
+// EmailTokenPurpose constants define emailed token uses.
+const (
+	// EmailTokenPurposeVerifyEmail confirms that a user owns their email address.
+	EmailTokenPurposeVerifyEmail EmailTokenPurpose = "verify_email"
+	// EmailTokenPurposePasswordReset allows a user to choose a new password.
+	EmailTokenPurposePasswordReset EmailTokenPurpose = "password_reset"
+)
+
+// EmailToken is a single-use token mailed to a user; only its hash is stored.
+type EmailToken struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	UserID    uint64            `gorm:"not null;index"`                        // Owning user ID.
+	Purpose   EmailTokenPurpose `gorm:"type:varchar(32);not null;index"`       // What the token authorizes.
+	TokenHash string            `gorm:"type:varchar(64);not null;uniqueIndex"` // SHA-256 hex of the token.
+	Email     string            `gorm:"type:text;not null"`                    // Address the token was sent to.
+
+	ExpiresAt time.Time  `gorm:"not null;index"` // When the token stops being accepted.
+	UsedAt    *time.Time // When the token was consumed.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+}
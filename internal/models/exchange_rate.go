@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// ExchangeRateSource records where an exchange rate came from.
+type ExchangeRateSource string
+
+// ExchangeRateSource constants define rate origins.
+const (
+	// ExchangeRateSourceManual marks a rate entered by an admin; fetches never overwrite it.
+	ExchangeRateSourceManual ExchangeRateSource = "manual"
+	// ExchangeRateSourceFetched marks a rate pulled from the configured rate feed.
+	ExchangeRateSourceFetched ExchangeRateSource = "fetched"
+)
+
+// ExchangeRate converts the USD base billing currency into another currency.
+type ExchangeRate struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Currency string             `gorm:"type:varchar(8);not null;uniqueIndex"` // ISO currency code.
+	Rate     float64            `gorm:"type:decimal(20,10);not null"`         // Units of Currency per 1 USD.
+	Source   ExchangeRateSource `gorm:"type:varchar(16);not null"`            // Manual or fetched.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
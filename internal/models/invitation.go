@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Invitation is an admin-issued code that lets someone register a user account.
+type Invitation struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Code string `gorm:"type:varchar(64);not null;uniqueIndex"` // Code entered at registration.
+	Note string `gorm:"type:text"`                             // Admin note, e.g. who the code was sent to.
+
+	UserGroupID *uint64 `gorm:"index"`              // User group assigned instead of the default group, if any.
+	PlanID      *uint64 `gorm:"index"`              // Plan granted as a complimentary bill, if any.
+	PlanDays    int     `gorm:"not null;default:0"` // Length of the granted bill in days; zero grants one month.
+
+	MaxUses   int        `gorm:"not null;default:0"` // Registrations allowed; zero is unlimited.
+	UsedCount int        `gorm:"not null;default:0"` // Registrations so far.
+	ExpiresAt *time.Time // Code expiry, if any.
+
+	IsEnabled bool `gorm:"not null;default:true"` // Whether the code can be redeemed.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
+
+// InvitationRedemption records a user registered with an invitation.
+type InvitationRedemption struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	InvitationID uint64  `gorm:"not null;index"`       // Redeemed invitation ID.
+	UserID       uint64  `gorm:"not null;uniqueIndex"` // Registered user ID.
+	BillID       *uint64 // Complimentary bill granted on registration, if any.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Redemption timestamp.
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// InvoiceStatus represents the lifecycle state of an invoice.
+type InvoiceStatus string
+
+// InvoiceStatus constants define invoice states.
+const (
+	// InvoiceStatusDraft marks an invoice that can still be regenerated.
+	InvoiceStatusDraft InvoiceStatus = "draft"
+	// InvoiceStatusFinalized marks an issued invoice with a number; its lines are frozen.
+	InvoiceStatusFinalized InvoiceStatus = "finalized"
+	// InvoiceStatusPaid marks a finalized invoice that has been settled.
+	InvoiceStatusPaid InvoiceStatus = "paid"
+)
+
+// Invoice records the usage charges of a user or user group over one calendar month.
+type Invoice struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Number string `gorm:"type:varchar(64);index"` // Invoice number, assigned on finalize.
+
+	UserID      *uint64 `gorm:"index"` // Billed user, when invoicing a user.
+	UserGroupID *uint64 `gorm:"index"` // Billed user group, when invoicing a group.
+
+	PeriodStart time.Time `gorm:"not null;index"` // Local midnight starting the billed month.
+	PeriodEnd   time.Time `gorm:"not null"`       // Local midnight starting the following month.
+
+	Currency     string         `gorm:"type:varchar(8);not null"`               // ISO currency code.
+	ExchangeRate float64        `gorm:"type:decimal(20,10);not null;default:1"` // Currency units per USD used for the amounts.
+	LineItems    datatypes.JSON `gorm:"type:jsonb;not null"`                    // Per provider and model line items.
+
+	Subtotal float64 `gorm:"type:decimal(20,10);not null;default:0"` // Sum of line item amounts.
+	TaxRate  float64 `gorm:"type:decimal(20,10);not null;default:0"` // Tax rate applied to the subtotal (0.2 = 20%).
+	Tax      float64 `gorm:"type:decimal(20,10);not null;default:0"` // Tax amount.
+	Total    float64 `gorm:"type:decimal(20,10);not null;default:0"` // Subtotal plus tax.
+
+	Status      InvoiceStatus `gorm:"type:varchar(16);not null;index"` // Current invoice status.
+	FinalizedAt *time.Time    // When the invoice was finalized.
+	PaidAt      *time.Time    // When the invoice was marked paid.
+
+	CreatedBy *uint64 // Admin ID that generated the invoice.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
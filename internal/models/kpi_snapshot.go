@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// KPISnapshot stores one day of aggregated dashboard KPIs so trends outlive usage retention.
+type KPISnapshot struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Day time.Time `gorm:"not null;uniqueIndex"` // Local midnight that starts the snapshot day.
+
+	Requests       int64   `gorm:"not null;default:0"` // Total requests.
+	FailedRequests int64   `gorm:"not null;default:0"` // Failed requests.
+	SuccessRate    float64 `gorm:"not null;default:0"` // Success rate percentage.
+	InputTokens    int64   `gorm:"not null;default:0"` // Input token count.
+	OutputTokens   int64   `gorm:"not null;default:0"` // Output token count.
+	CachedTokens   int64   `gorm:"not null;default:0"` // Cached token count.
+	TotalTokens    int64   `gorm:"not null;default:0"` // Total token count.
+	CostMicros     int64   `gorm:"not null;default:0"` // Total cost in micros.
+	ActiveUsers    int64   `gorm:"not null;default:0"` // Distinct users with requests.
+	ActiveAPIKeys  int64   `gorm:"not null;default:0"` // Distinct API keys with requests.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last refresh timestamp.
+}
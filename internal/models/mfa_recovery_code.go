@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// MFAOwnerType names the kind of account a recovery code belongs to.
+type MFAOwnerType string
+
+// MFAOwnerType constants define recovery code owners.
+const (
+	// MFAOwnerAdmin marks codes belonging to an admin account.
+	MFAOwnerAdmin MFAOwnerType = "admin"
+	// MFAOwnerUser marks codes belonging to a front-end user account.
+	MFAOwnerUser MFAOwnerType = "user"
+)
+
+// MFARecoveryCode is a single-use TOTP recovery code; only its hash is stored.
+type MFARecoveryCode struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	OwnerType MFAOwnerType `gorm:"type:varchar(16);not null;index:idx_mfa_recovery_codes_owner"` // Admin or user.
+	OwnerID   uint64       `gorm:"not null;index:idx_mfa_recovery_codes_owner"`                  // Owning account ID.
+	CodeHash  string       `gorm:"type:varchar(64);not null;uniqueIndex"`                        // SHA-256 hex of the normalized code.
+
+	UsedAt *time.Time // When the code was consumed.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// MFASession holds short-lived MFA ceremony state (WebAuthn sessions, pending TOTP secrets)
+// so in-flight enrollments and logins survive restarts and work across replicas.
+type MFASession struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Scope string `gorm:"type:varchar(64);not null;uniqueIndex:idx_mfa_sessions_scope_key"`  // Store name, e.g. "admin:passkey_login".
+	Key   string `gorm:"type:varchar(255);not null;uniqueIndex:idx_mfa_sessions_scope_key"` // Entry key within the scope.
+	Value []byte `gorm:"type:bytea;not null"`                                               // Encoded entry.
+
+	ExpiresAt time.Time `gorm:"not null;index"` // When the entry stops being returned.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+}
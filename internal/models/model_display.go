@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// ModelDisplay stores admin-managed catalog metadata for a user-visible model ID.
+type ModelDisplay struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	ModelID       string         `gorm:"type:varchar(255);not null;uniqueIndex"`     // Exposed model ID the metadata applies to.
+	DisplayName   string         `gorm:"type:varchar(255);not null;default:''"`      // Friendly name shown to users.
+	Category      string         `gorm:"type:varchar(64);not null;default:'';index"` // Grouping label, e.g. "Claude".
+	Description   string         `gorm:"type:text;not null;default:''"`              // Short description.
+	ContextWindow int            `gorm:"not null;default:0"`                         // Context window in tokens; 0 hides it.
+	Capabilities  datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'"`           // Capability flags, e.g. ["vision","tools"].
+	SortOrder     int            `gorm:"not null;default:0"`                         // Ordering within the category (ascending).
+	IsEnabled     bool           `gorm:"not null;default:true"`                      // Whether the metadata is applied.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Node sync states reported in Node.SyncStatus.
+const (
+	NodeSyncPending = "pending" // No config has been delivered yet.
+	NodeSyncPulled  = "pulled"  // The node downloaded the latest config.
+	NodeSyncPushed  = "pushed"  // The latest config was pushed to the node.
+	NodeSyncApplied = "applied" // The node reported the config as applied.
+	NodeSyncError   = "error"   // Delivery or apply failed; see SyncError.
+)
+
+// Node represents a remote CLIProxyAPI instance that receives synced config from this server.
+type Node struct {
+	ID   uint64 `gorm:"primaryKey;autoIncrement"`              // Primary key.
+	Name string `gorm:"type:varchar(64);not null;uniqueIndex"` // Display name.
+
+	Token       string `gorm:"type:text;not null;uniqueIndex"`       // Bearer token the node authenticates with.
+	Environment string `gorm:"type:varchar(32);not null;default:''"` // Environment tag; empty receives every credential.
+	PushURL     string `gorm:"type:text;not null;default:''"`        // Optional agent endpoint that receives pushed config.
+	IsEnabled   bool   `gorm:"not null;default:true"`                // Whether the node may pull and receive config.
+
+	ConfigVersion  string     `gorm:"type:varchar(64);not null;default:''"`        // Latest config version delivered to the node.
+	AppliedVersion string     `gorm:"type:varchar(64);not null;default:''"`        // Config version the node reported as applied.
+	AgentVersion   string     `gorm:"type:varchar(64);not null;default:''"`        // Software version reported by the node.
+	SyncStatus     string     `gorm:"type:varchar(16);not null;default:'pending'"` // Last sync state.
+	SyncError      string     `gorm:"type:text;not null;default:''"`               // Last delivery or apply error.
+	LastSeenAt     *time.Time // Last authenticated request from the node.
+	LastSyncAt     *time.Time // Last config delivery or status report.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
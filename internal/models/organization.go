@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Organization is a tenant of a shared deployment. Users, user groups and organization admins
+// may belong to one; rows without an organization are global and managed by global admins.
+// API keys, usage and bills belong to the organization of their user.
+type Organization struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Name        string `gorm:"type:text;not null;uniqueIndex"` // Display name.
+	Description string `gorm:"type:text"`                      // Free-form description.
+	Active      bool   `gorm:"not null;default:true"`          // Whether organization admins can sign in.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
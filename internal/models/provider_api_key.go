@@ -26,6 +26,13 @@ type ProviderAPIKey struct {
 	ExcludedModels datatypes.JSON `gorm:"type:jsonb"` // Excluded models list.
 	APIKeyEntries  datatypes.JSON `gorm:"type:jsonb"` // Nested API key entries.
 
+	Environments datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'"` // Environment tags; empty shares the key with every environment.
+
+	// CanaryPercent routes that share of the provider's traffic to this key while it is trialled
+	// against the provider's other keys; zero serves the key like any other.
+	CanaryPercent   int        `gorm:"not null;default:0"`
+	CanaryStartedAt *time.Time // When the current canary started; nil outside a canary.
+
 	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
 	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
 }
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// ProviderHealthCheck records the outcome of one lightweight upstream request, either a
+// probe through a provider API key or a quota poll through an auth file.
+type ProviderHealthCheck struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Provider string `gorm:"type:varchar(255);not null;index"` // Provider name the check ran against.
+	Source   string `gorm:"type:varchar(32);not null"`        // Credential kind: api_key or auth.
+	TargetID uint64 `gorm:"not null;default:0"`               // Provider API key or auth ID.
+
+	Success       bool   `gorm:"not null;default:false"` // Whether the upstream answered successfully.
+	StatusCode    int    `gorm:"not null;default:0"`     // HTTP status, 0 when unknown or unreachable.
+	LatencyMillis int64  `gorm:"not null;default:0"`     // Round-trip latency in milliseconds.
+	Error         string `gorm:"type:text"`              // Failure detail.
+
+	CheckedAt time.Time `gorm:"not null;index"` // When the check finished.
+}
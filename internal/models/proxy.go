@@ -4,8 +4,9 @@ import "time"
 
 // Proxy represents an upstream proxy endpoint.
 type Proxy struct {
-	ID       uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
-	ProxyURL string `gorm:"type:text;not null"`       // Proxy URL.
+	ID       uint64 `gorm:"primaryKey;autoIncrement"`             // Primary key.
+	ProxyURL string `gorm:"type:text;not null"`                   // Proxy URL.
+	Region   string `gorm:"type:varchar(64);not null;default:''"` // Region label matched by region-affinity assignment.
 
 	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
 	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
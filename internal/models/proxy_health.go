@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// ProxyHealth is the latest health state of one proxy as maintained by the proxy health checker.
+type ProxyHealth struct {
+	ProxyID uint64 `gorm:"primaryKey;autoIncrement:false"` // Proxy the state belongs to.
+
+	Active              bool   `gorm:"not null;index"`                       // False once the proxy failed too many checks in a row.
+	ConsecutiveFailures int    `gorm:"not null;default:0"`                   // Failed checks since the last success.
+	LatencyMillis       int64  `gorm:"not null;default:0"`                   // Latency of the last check in milliseconds.
+	ExitIP              string `gorm:"type:varchar(64);not null;default:''"` // Egress IP seen by the last successful check.
+	LastError           string `gorm:"type:text"`                            // Failure detail of the last failed check.
+
+	CheckedAt     *time.Time // When the proxy was last checked.
+	LastSuccessAt *time.Time // When the proxy last passed a check.
+	DeactivatedAt *time.Time // When the proxy was marked inactive; nil while active.
+
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
+
+// TableName overrides the default table name.
+func (ProxyHealth) TableName() string {
+	return "proxy_health"
+}
+
+// ProxyHealthCheck records the outcome of one proxy check.
+type ProxyHealthCheck struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	ProxyID       uint64 `gorm:"not null;index"`                       // Checked proxy.
+	Success       bool   `gorm:"not null;default:false"`               // Whether the check URL answered through the proxy.
+	LatencyMillis int64  `gorm:"not null;default:0"`                   // Round-trip latency in milliseconds.
+	ExitIP        string `gorm:"type:varchar(64);not null;default:''"` // Egress IP reported by the check URL.
+	Error         string `gorm:"type:text"`                            // Failure detail.
+
+	CheckedAt time.Time `gorm:"not null;index"` // When the check finished.
+}
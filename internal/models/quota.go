@@ -15,6 +15,13 @@ type Quota struct {
 
 	Data datatypes.JSON `gorm:"type:jsonb;not null;default:'{}'"` // Quota payload.
 
+	// Normalized fields extracted from Data; nil when the provider payload does not report them.
+	Plan              string     `gorm:"type:text"`                 // Subscription plan reported by the provider.
+	RemainingRequests *int64     `gorm:"column:remaining_requests"` // Remaining request allowance.
+	RemainingTokens   *int64     `gorm:"column:remaining_tokens"`   // Remaining token allowance.
+	RemainingFraction *float64   `gorm:"column:remaining_fraction"` // Remaining share of the tightest limit, 0..1.
+	ResetAt           *time.Time `gorm:"index"`                     // When the tightest limit resets.
+
 	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
 	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
 }
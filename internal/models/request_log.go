@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// RequestLog stores the captured request and response bodies of one proxy request, inline or
+// as a reference to an object store. Rows link to their usage rows through RequestID.
+type RequestLog struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	RequestID string  `gorm:"type:text;index"` // Request ID shared with usages.request_id.
+	UserID    *uint64 `gorm:"index"`           // Requesting user ID.
+	APIKeyID  *uint64 `gorm:"index"`           // API key the request used.
+
+	Method     string `gorm:"type:text;not null"`            // HTTP method.
+	Path       string `gorm:"type:text;not null"`            // Request path without the query.
+	Model      string `gorm:"type:text;index"`               // Model named in the request body, if any.
+	StatusCode int    `gorm:"not null;default:0"`            // Response status code.
+	DurationMs int64  `gorm:"not null;default:0"`            // Time until the response finished.
+	ClientIP   string `gorm:"type:text;not null;default:''"` // Client address.
+
+	RequestBody       string `gorm:"type:text"`              // Request body, truncated to the configured size.
+	ResponseBody      string `gorm:"type:text"`              // Response body, truncated to the configured size.
+	RequestTruncated  bool   `gorm:"not null;default:false"` // Whether RequestBody was cut.
+	ResponseTruncated bool   `gorm:"not null;default:false"` // Whether ResponseBody was cut.
+
+	PayloadBackend string     `gorm:"type:varchar(16);not null;default:''"` // Object store holding the bodies; empty when they are stored inline.
+	PayloadKey     string     `gorm:"type:text"`                            // Object key of the gzip-compressed bodies.
+	PayloadBytes   int64      `gorm:"not null;default:0"`                   // Stored object size.
+	ExpiresAt      *time.Time `gorm:"index"`                                // When the row and its object are deleted.
+
+	RedactedAt *time.Time // When an admin cleared the bodies, if ever.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime;index"` // Capture timestamp.
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// RetryPolicyDefaultProvider names the policy applied to providers without their own.
+const RetryPolicyDefaultProvider = "*"
+
+// RetryPolicy controls how a failed upstream request is retried on a provider's next
+// credential. The default policy ("*") also drives the SDK request-retry settings.
+type RetryPolicy struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Provider             string         `gorm:"type:varchar(64);not null;uniqueIndex"` // Provider name, or "*" for the default policy.
+	MaxRetries           int            `gorm:"not null;default:0"`                    // Retries allowed after the first attempt.
+	RetryableStatusCodes datatypes.JSON `gorm:"type:jsonb"`                            // Upstream statuses worth retrying; empty retries any failure.
+	BackoffInitialMs     int            `gorm:"not null;default:0"`                    // Delay before the first retry; doubles on every further one.
+	BackoffMaxMs         int            `gorm:"not null;default:0"`                    // Upper bound of the retry delay.
+	FailoverToNextKey    bool           `gorm:"not null;default:true"`                 // Whether retries may move to another credential.
+	IsEnabled            bool           `gorm:"not null;default:true;index"`           // Whether the policy is active.
+	Description          string         `gorm:"type:text"`                             // Human-readable description.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// SchemaMigration records one applied versioned schema migration.
+type SchemaMigration struct {
+	Version     int       `gorm:"primaryKey;autoIncrement:false"`        // Migration version, ascending.
+	Description string    `gorm:"type:varchar(255);not null;default:''"` // Human readable summary.
+	AppliedAt   time.Time `gorm:"not null"`                              // When the migration was applied.
+}
@@ -0,0 +1,159 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/crypto"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// BeforeSave encrypts the auth content when encryption at rest is enabled.
+func (a *Auth) BeforeSave(tx *gorm.DB) error {
+	return encryptJSONColumn(tx, "content", a.Content)
+}
+
+// AfterSave restores the plaintext content on the in-memory record.
+func (a *Auth) AfterSave(tx *gorm.DB) error {
+	return decryptJSONField(&a.Content)
+}
+
+// AfterFind decrypts the auth content after loading.
+func (a *Auth) AfterFind(tx *gorm.DB) error {
+	return decryptJSONField(&a.Content)
+}
+
+// BeforeSave encrypts the provider API key and nested key entries when encryption is enabled.
+func (k *ProviderAPIKey) BeforeSave(tx *gorm.DB) error {
+	if errKey := encryptStringColumn(tx, "api_key", k.APIKey); errKey != nil {
+		return errKey
+	}
+	return encryptJSONColumn(tx, "api_key_entries", k.APIKeyEntries)
+}
+
+// AfterSave restores the plaintext secrets on the in-memory record.
+func (k *ProviderAPIKey) AfterSave(tx *gorm.DB) error {
+	return k.decryptSecrets()
+}
+
+// AfterFind decrypts the provider secrets after loading.
+func (k *ProviderAPIKey) AfterFind(tx *gorm.DB) error {
+	return k.decryptSecrets()
+}
+
+func (k *ProviderAPIKey) decryptSecrets() error {
+	plain, errDecrypt := crypto.Default().DecryptString(k.APIKey)
+	if errDecrypt != nil {
+		return fmt.Errorf("provider api key %d: %w", k.ID, errDecrypt)
+	}
+	k.APIKey = plain
+	return decryptJSONField(&k.APIKeyEntries)
+}
+
+// BeforeSave encrypts the webhook signing secret when encryption is enabled.
+func (w *Webhook) BeforeSave(tx *gorm.DB) error {
+	return encryptStringColumn(tx, "secret", w.Secret)
+}
+
+// AfterSave restores the plaintext secret on the in-memory record.
+func (w *Webhook) AfterSave(tx *gorm.DB) error {
+	return w.decryptSecret()
+}
+
+// AfterFind decrypts the webhook signing secret after loading.
+func (w *Webhook) AfterFind(tx *gorm.DB) error {
+	return w.decryptSecret()
+}
+
+func (w *Webhook) decryptSecret() error {
+	plain, errDecrypt := crypto.Default().DecryptString(w.Secret)
+	if errDecrypt != nil {
+		return fmt.Errorf("webhook %d: %w", w.ID, errDecrypt)
+	}
+	w.Secret = plain
+	return nil
+}
+
+// pendingColumnValue returns the value a statement is about to write for column.
+// Map based updates carry the value in the destination map; struct writes use the record field.
+func pendingColumnValue(tx *gorm.DB, column string, field any) (any, bool) {
+	switch dest := tx.Statement.Dest.(type) {
+	case map[string]any:
+		value, ok := dest[column]
+		return value, ok
+	case *map[string]any:
+		if dest == nil {
+			return nil, false
+		}
+		value, ok := (*dest)[column]
+		return value, ok
+	}
+	return field, true
+}
+
+func encryptStringColumn(tx *gorm.DB, column, field string) error {
+	c := crypto.Default()
+	if !c.Enabled() {
+		return nil
+	}
+	value, ok := pendingColumnValue(tx, column, field)
+	if !ok {
+		return nil
+	}
+	plain, isString := value.(string)
+	if !isString || plain == "" || crypto.IsEncrypted(plain) {
+		return nil
+	}
+	sealed, errEncrypt := c.EncryptString(plain)
+	if errEncrypt != nil {
+		return errEncrypt
+	}
+	tx.Statement.SetColumn(column, sealed, true)
+	return nil
+}
+
+func encryptJSONColumn(tx *gorm.DB, column string, field datatypes.JSON) error {
+	c := crypto.Default()
+	if !c.Enabled() {
+		return nil
+	}
+	value, ok := pendingColumnValue(tx, column, field)
+	if !ok {
+		return nil
+	}
+	var raw []byte
+	switch typed := value.(type) {
+	case datatypes.JSON:
+		raw = typed
+	case json.RawMessage:
+		raw = typed
+	case []byte:
+		raw = typed
+	case string:
+		raw = []byte(typed)
+	default:
+		return nil
+	}
+	if len(raw) == 0 || crypto.IsEncryptedJSON(raw) {
+		return nil
+	}
+	sealed, errEncrypt := c.EncryptJSON(raw)
+	if errEncrypt != nil {
+		return errEncrypt
+	}
+	tx.Statement.SetColumn(column, datatypes.JSON(sealed), true)
+	return nil
+}
+
+func decryptJSONField(field *datatypes.JSON) error {
+	if field == nil || !crypto.IsEncryptedJSON(*field) {
+		return nil
+	}
+	plain, errDecrypt := crypto.Default().DecryptJSON(*field)
+	if errDecrypt != nil {
+		return errDecrypt
+	}
+	*field = datatypes.JSON(plain)
+	return nil
+}
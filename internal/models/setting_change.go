@@ -0,0 +1,32 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Setting change actions recorded in the setting history.
+const (
+	// SettingChangeCreate added a setting.
+	SettingChangeCreate = "create"
+	// SettingChangeUpdate replaced the value of a setting.
+	SettingChangeUpdate = "update"
+	// SettingChangeDelete removed a setting.
+	SettingChangeDelete = "delete"
+)
+
+// SettingChange records one change of a DB-backed setting.
+type SettingChange struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Key    string `gorm:"type:varchar(255);not null;index"` // Changed setting key.
+	Action string `gorm:"type:varchar(16);not null"`        // create, update or delete.
+
+	OldValue json.RawMessage `gorm:"type:jsonb"` // Value before the change; redacted for secret settings.
+	NewValue json.RawMessage `gorm:"type:jsonb"` // Value after the change; redacted for secret settings.
+
+	AdminID       *uint64 `gorm:"index"`                                 // Admin who made the change.
+	AdminUsername string  `gorm:"type:varchar(255);not null;default:''"` // Admin username at the time of the change.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime;index"` // When the change was made.
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// SLOKind identifies what an SLO measures.
+type SLOKind string
+
+// SLOKind constants define supported objectives.
+const (
+	// SLOKindLatency counts requests slower than ThresholdMillis as bad.
+	SLOKindLatency SLOKind = "latency"
+	// SLOKindErrorRate counts failed requests as bad.
+	SLOKindErrorRate SLOKind = "error_rate"
+)
+
+// SLO is an admin-defined service level objective evaluated continuously against usages,
+// e.g. "99% of requests finish within 30s" or "error rate below 1%".
+type SLO struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Name        string  `gorm:"type:varchar(255);not null;uniqueIndex"` // Display name.
+	Description string  `gorm:"type:text"`                              // Optional description.
+	Kind        SLOKind `gorm:"type:varchar(16);not null"`              // latency or error_rate.
+
+	ThresholdMillis int64   `gorm:"not null;default:0"`                    // Latency threshold; latency SLOs only.
+	Objective       float64 `gorm:"type:decimal(7,4);not null"`            // Target share of good requests, in percent.
+	WindowDays      int     `gorm:"not null;default:30"`                   // Compliance window in days.
+	Provider        string  `gorm:"type:varchar(255);not null;default:''"` // Provider filter; empty matches all.
+	Model           string  `gorm:"type:varchar(255);not null;default:''"` // Model filter; empty matches all.
+	IsEnabled       bool    `gorm:"not null;default:true"`                 // Whether the SLO is evaluated.
+
+	LastEvaluatedAt     *time.Time // Last evaluation time.
+	LastState           string     `gorm:"type:varchar(16);not null;default:''"` // ok, burning or breached.
+	LastCompliance      float64    `gorm:"not null;default:0"`                   // Good requests in the window, in percent.
+	LastBudgetRemaining float64    `gorm:"not null;default:0"`                   // Error budget left, in percent.
+	LastAlertedAt       *time.Time // Last burn-rate alert; bounds repeat alerts.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// StatsCursor records how far a stats aggregation has finalized its buckets.
+type StatsCursor struct {
+	Name      string    `gorm:"type:varchar(64);primaryKey"` // Aggregation name.
+	Through   time.Time `gorm:"not null"`                    // Buckets before this instant are final.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"`     // Last advance timestamp.
+}
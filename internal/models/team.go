@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// TeamRole names a member's role within a team.
+type TeamRole string
+
+// TeamRole constants define team member roles.
+const (
+	// TeamRoleOwner manages the team and funds its pool.
+	TeamRoleOwner TeamRole = "owner"
+	// TeamRoleMember issues team keys and spends from the pool.
+	TeamRoleMember TeamRole = "member"
+)
+
+// Team groups front users who share API keys and a budget. Team keys are charged to the
+// owner's bills and prepaid cards, which form the team pool.
+type Team struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Name        string `gorm:"type:text;not null"`     // Display name.
+	OwnerUserID uint64 `gorm:"not null;index"`         // User who manages the team and pays for its keys.
+	Owner       User   `gorm:"foreignKey:OwnerUserID"` // Owning user record.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
+
+// TeamMember links a user to a team. Invited users become members once they accept.
+type TeamMember struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	TeamID uint64 `gorm:"not null;uniqueIndex:idx_team_members_team_user,priority:1"`       // Team ID.
+	UserID uint64 `gorm:"not null;uniqueIndex:idx_team_members_team_user,priority:2;index"` // Member user ID.
+	User   User   `gorm:"foreignKey:UserID"`                                                // Member user record.
+
+	Role            TeamRole   `gorm:"type:varchar(16);not null;default:'member'"` // Role within the team.
+	InvitedByUserID *uint64    // User who sent the invitation; nil for the owner.
+	JoinedAt        *time.Time // When the invitation was accepted; nil while pending.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
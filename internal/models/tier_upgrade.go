@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// TierUpgradeStatus represents the lifecycle state of a tier upgrade.
+type TierUpgradeStatus string
+
+// TierUpgradeStatus constants define tier upgrade states.
+const (
+	// TierUpgradeStatusPending marks an upgrade awaiting confirmation.
+	TierUpgradeStatusPending TierUpgradeStatus = "pending"
+	// TierUpgradeStatusApplied marks an upgrade that moved the user.
+	TierUpgradeStatusApplied TierUpgradeStatus = "applied"
+	// TierUpgradeStatusDeclined marks an upgrade rejected by the user or an admin.
+	TierUpgradeStatusDeclined TierUpgradeStatus = "declined"
+)
+
+// TierUpgradeRule moves users to a higher user group once sustained usage exceeds a threshold.
+type TierUpgradeRule struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Name string `gorm:"type:text;not null"` // Display name.
+
+	FromUserGroupID uint64 `gorm:"not null;index"` // User group the rule watches.
+	ToUserGroupID   uint64 `gorm:"not null"`       // User group users are moved to.
+
+	WindowDays      int     `gorm:"not null;default:7"`                     // Rolling window evaluated, in days.
+	ThresholdAmount float64 `gorm:"type:decimal(20,10);not null;default:0"` // Usage cost over the window that triggers the upgrade.
+
+	RequireConfirmation bool `gorm:"not null"` // Whether the user must accept before moving.
+	IsEnabled           bool `gorm:"not null"` // Whether the rule is evaluated.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
+
+// TierUpgrade records a proposed or applied tier upgrade for a user.
+type TierUpgrade struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	RuleID uint64 `gorm:"not null;index"` // Related tier upgrade rule ID.
+	UserID uint64 `gorm:"not null;index"` // Related user ID.
+
+	FromUserGroupID uint64 `gorm:"not null"` // User group before the upgrade.
+	ToUserGroupID   uint64 `gorm:"not null"` // User group after the upgrade.
+
+	UsageAmount float64 `gorm:"type:decimal(20,10);not null;default:0"` // Usage cost observed in the window.
+
+	Status    TierUpgradeStatus `gorm:"type:varchar(16);not null;index"` // Current upgrade status.
+	DecidedAt *time.Time        // When the upgrade was applied or declined.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
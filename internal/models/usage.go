@@ -17,11 +17,24 @@ type Usage struct {
 	UserGroupID *uint64 `gorm:"index"` // Billing user group ID, when available.
 	APIKeyID    *uint64 `gorm:"index"` // Related API key ID.
 	AuthID      *uint64 `gorm:"index"` // Related auth ID.
+	// TeamID is the team of a team key. UserID is then the team owner who paid and
+	// TeamMemberID the member who made the request.
+	TeamID       *uint64 `gorm:"index"`
+	TeamMemberID *uint64 `gorm:"index"`
+	// ProviderAPIKeyID is the provider key that served the request, nil for auth files.
+	ProviderAPIKeyID *uint64 `gorm:"index"`
 
 	AuthKey   string `gorm:"type:text;index"` // Auth key value.
 	AuthIndex string `gorm:"type:text"`       // Auth index identifier.
 	RequestID string `gorm:"type:text;index"` // Request ID for tracing.
 	Source    string `gorm:"type:text"`       // Usage source marker.
+	// IdempotencyKey identifies the usage record across outbox replays, so a record whose commit
+	// was never acknowledged is inserted and charged once. Nil on rows written before it existed.
+	IdempotencyKey *string `gorm:"type:varchar(32);uniqueIndex"`
+	// ProxyHash fingerprints the proxy that served the request, empty for direct requests.
+	ProxyHash string `gorm:"type:varchar(16);not null;default:'';index"`
+	// ProxyLabel is the scheme, host and port of that proxy with credentials stripped.
+	ProxyLabel string `gorm:"type:text;not null;default:''"`
 	// VariantOrigin records the requested thinking strength from client input.
 	VariantOrigin string `gorm:"type:text"`
 	// Variant records the actual thinking strength sent upstream after adaptation.
@@ -32,14 +45,25 @@ type Usage struct {
 
 	ErrorStatusCode *int           `gorm:"index"`      // HTTP status code for failed requests.
 	ErrorDetail     datatypes.JSON `gorm:"type:jsonb"` // Structured error detail JSON.
+	// ErrorCode is the canonical failure cause from the usageerror package, empty for successes.
+	ErrorCode string `gorm:"type:text;not null;default:'';index"`
+	// RetryAfterSeconds is the sanitized back-off returned with a 429, 0 when none.
+	RetryAfterSeconds int `gorm:"not null;default:0"`
 
 	InputTokens     int64 `gorm:"not null;default:0"` // Input token count.
 	OutputTokens    int64 `gorm:"not null;default:0"` // Output token count.
 	ReasoningTokens int64 `gorm:"not null;default:0"` // Reasoning token count.
 	CachedTokens    int64 `gorm:"not null;default:0"` // Cached token count.
-	TotalTokens     int64 `gorm:"not null;default:0"` // Total token count.
+	// CacheCreationTokens counts cache-write tokens. The NOT NULL default lets existing rows
+	// backfill to zero when the column is added.
+	CacheCreationTokens int64 `gorm:"not null;default:0"`
+	TotalTokens         int64 `gorm:"not null;default:0"` // Total token count.
 
-	CostMicros int64 `gorm:"not null;default:0"` // Cost in micros.
+	CostMicros    int64   `gorm:"not null;default:0"` // Cost in micros.
+	BillingRuleID *uint64 `gorm:"index"`              // Billing rule that priced the request.
+
+	EnergyMilliWh    int64 `gorm:"not null;default:0"` // Estimated energy in milliwatt-hours.
+	CarbonMilligrams int64 `gorm:"not null;default:0"` // Estimated emissions in milligrams CO2e.
 
 	// ChargedTo indicates where the cost was deducted.
 	// Values: "bill", "prepaid", "none".
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// UsageBadge is a public, token-addressed badge showing a user's or project's monthly
+// request count and uptime, meant to be embedded in READMEs.
+type UsageBadge struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	UserID  uint64 `gorm:"not null;index"`                        // Owning user ID.
+	Token   string `gorm:"type:varchar(64);not null;uniqueIndex"` // Public token in the badge URL.
+	Project string `gorm:"type:text;not null;default:''"`         // Usage source the badge is limited to; empty covers all usage.
+	Label   string `gorm:"type:varchar(64);not null;default:''"`  // Left-hand badge text; empty uses the metric name.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// UsageDaily aggregates usages rows that aged out of the retention window, one row per
+// local day, provider, model, user and API key. Zero IDs stand for usage without an owner.
+type UsageDaily struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Day      time.Time `gorm:"not null;uniqueIndex:idx_usage_daily_bucket,priority:1;index"`             // Local midnight that starts the day.
+	Provider string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_usage_daily_bucket,priority:2"` // Provider name.
+	Model    string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_usage_daily_bucket,priority:3"` // Model name.
+	UserID   uint64    `gorm:"not null;default:0;uniqueIndex:idx_usage_daily_bucket,priority:4;index"`   // User ID, 0 when unknown.
+	APIKeyID uint64    `gorm:"not null;default:0;uniqueIndex:idx_usage_daily_bucket,priority:5"`         // API key ID, 0 when unknown.
+
+	Requests        int64 `gorm:"not null;default:0"` // Total requests.
+	FailedRequests  int64 `gorm:"not null;default:0"` // Failed requests.
+	InputTokens     int64 `gorm:"not null;default:0"` // Input token count.
+	OutputTokens    int64 `gorm:"not null;default:0"` // Output token count.
+	ReasoningTokens int64 `gorm:"not null;default:0"` // Reasoning token count.
+	CachedTokens    int64 `gorm:"not null;default:0"` // Cached token count.
+	TotalTokens     int64 `gorm:"not null;default:0"` // Total token count.
+	CostMicros      int64 `gorm:"not null;default:0"` // Total cost in micros.
+
+	EnergyMilliWh    int64 `gorm:"not null;default:0"` // Estimated energy in milliwatt-hours.
+	CarbonMilligrams int64 `gorm:"not null;default:0"` // Estimated emissions in milligrams CO2e.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last rollup timestamp.
+}
+
+// TableName overrides the default table name.
+func (UsageDaily) TableName() string {
+	return "usage_daily"
+}
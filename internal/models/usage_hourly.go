@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// UsageHourly aggregates usages per local hour, provider, model and user so dashboards do
+// not scan raw rows. A zero user ID stands for usage without an owner.
+type UsageHourly struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Hour     time.Time `gorm:"not null;uniqueIndex:idx_usage_hourly_bucket,priority:1;index"`             // Start of the local hour.
+	Provider string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_usage_hourly_bucket,priority:2"` // Provider name.
+	Model    string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_usage_hourly_bucket,priority:3"` // Model name.
+	UserID   uint64    `gorm:"not null;default:0;uniqueIndex:idx_usage_hourly_bucket,priority:4"`         // User ID, 0 when unknown.
+
+	Requests         int64 `gorm:"not null;default:0"` // Total requests.
+	FailedRequests   int64 `gorm:"not null;default:0"` // Failed requests.
+	TotalTokens      int64 `gorm:"not null;default:0"` // Total token count.
+	CachedTokens     int64 `gorm:"not null;default:0"` // Cached token count.
+	CostMicros       int64 `gorm:"not null;default:0"` // Total cost in micros.
+	EnergyMilliWh    int64 `gorm:"not null;default:0"` // Estimated energy in milliwatt-hours.
+	CarbonMilligrams int64 `gorm:"not null;default:0"` // Estimated emissions in milligrams CO2e.
+	DurationMillis   int64 `gorm:"not null;default:0"` // Summed request durations in milliseconds.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last aggregation timestamp.
+}
+
+// TableName overrides the default table name.
+func (UsageHourly) TableName() string {
+	return "usage_hourly"
+}
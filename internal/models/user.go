@@ -11,6 +11,12 @@ type User struct {
 	Email    string `gorm:"type:text;uniqueIndex"`          // Email address.
 	Password string `gorm:"type:text;not null"`             // Hashed password.
 
+	LDAPDN string `gorm:"column:ldap_dn;type:text;index"` // Distinguished name of the linked directory entry; empty for local accounts.
+
+	EmailVerifiedAt *time.Time // When the user confirmed their email address.
+
+	OrganizationID *uint64 `gorm:"index"` // Owning organization; nil for users outside any organization.
+
 	UserGroupID UserGroupIDs `gorm:"type:jsonb;not null;default:'[]'"` // Assigned user group IDs.
 	UserGroup   []*UserGroup `gorm:"-"`                                // Assigned user groups.
 
@@ -22,6 +28,11 @@ type User struct {
 	DailyMaxUsage float64 `gorm:"type:decimal(20,10);not null;default:0"` // Daily usage cap.
 	RateLimit     int     `gorm:"not null;default:0"`                     // Rate limit per second.
 
+	MaxConcurrentRequests int `gorm:"not null;default:0"` // In-flight proxy request cap; zero defers to the user group.
+
+	DailySpendLimit   float64 `gorm:"type:decimal(20,10);not null;default:0"` // Hard daily spend cap across all charges; zero defers to groups.
+	MonthlySpendLimit float64 `gorm:"type:decimal(20,10);not null;default:0"` // Hard monthly spend cap across all charges; zero defers to groups.
+
 	Active   bool `gorm:"not null;default:true"`  // Whether the user can sign in.
 	Disabled bool `gorm:"not null;default:false"` // Explicit disable flag.
 
@@ -32,6 +43,8 @@ type User struct {
 	PasskeyBackupEligible *bool   `gorm:"type:boolean"` // WebAuthn backup eligibility flag.
 	PasskeyBackupState    *bool   `gorm:"type:boolean"` // WebAuthn backup state flag.
 
+	SessionsRevokedAt *time.Time // Tokens issued before this instant are rejected.
+
 	APIKeys []APIKey `gorm:"foreignKey:UserID"` // Related API keys.
 
 	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
@@ -9,6 +9,20 @@ type UserGroup struct {
 	Name      string `gorm:"type:text;not null;uniqueIndex"` // Display name.
 	IsDefault bool   `gorm:"not null;default:false"`         // Marks the default group.
 	RateLimit int    `gorm:"not null;default:0"`             // Rate limit per second.
+	RPMLimit  int    `gorm:"not null;default:0"`             // Requests per minute per member across their keys; zero means unlimited.
+	TPMLimit  int    `gorm:"not null;default:0"`             // Tokens per minute per member across their keys; zero means unlimited.
+
+	MaxConcurrentRequests int `gorm:"not null;default:0"` // In-flight proxy request cap per member; zero means unlimited.
+
+	DailySpendLimit   float64 `gorm:"type:decimal(20,10);not null;default:0"` // Daily spend cap for members; zero means unlimited.
+	MonthlySpendLimit float64 `gorm:"type:decimal(20,10);not null;default:0"` // Monthly spend cap for members; zero means unlimited.
+
+	ParentID           *uint64 `gorm:"index"` // Parent group whose policies fill in settings left at zero.
+	BillingRuleGroupID *uint64 // Group whose billing rules price members when this group has no matching rule of its own.
+
+	RequestLogEnabled bool `gorm:"not null;default:false"` // Captures request and response bodies of members and subgroups.
+
+	OrganizationID *uint64 `gorm:"index"` // Owning organization; nil for groups shared by every organization.
 
 	Users []User `gorm:"-"` // Related users (not persisted).
 
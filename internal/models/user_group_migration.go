@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// GroupMigrationBillStrategy controls how active bills are handled when a user changes group.
+type GroupMigrationBillStrategy string
+
+// GroupMigrationBillStrategy constants define bill handling strategies.
+const (
+	// GroupMigrationBillStrategyProrate closes old bills at the boundary and carries the unused share into new bills.
+	GroupMigrationBillStrategyProrate GroupMigrationBillStrategy = "prorate"
+	// GroupMigrationBillStrategyClose ends old bills at the boundary without carry-over.
+	GroupMigrationBillStrategyClose GroupMigrationBillStrategy = "close"
+	// GroupMigrationBillStrategyRetag keeps old bills running but moves them to the new user groups.
+	GroupMigrationBillStrategyRetag GroupMigrationBillStrategy = "retag"
+)
+
+// GroupMigrationStatus represents the lifecycle state of a user group migration.
+type GroupMigrationStatus string
+
+// GroupMigrationStatus constants define migration states.
+const (
+	// GroupMigrationStatusScheduled marks a migration waiting for its effective time.
+	GroupMigrationStatusScheduled GroupMigrationStatus = "scheduled"
+	// GroupMigrationStatusApplied marks a completed migration.
+	GroupMigrationStatusApplied GroupMigrationStatus = "applied"
+	// GroupMigrationStatusCancelled marks a migration cancelled before it took effect.
+	GroupMigrationStatusCancelled GroupMigrationStatus = "cancelled"
+	// GroupMigrationStatusFailed marks a migration whose apply step failed.
+	GroupMigrationStatusFailed GroupMigrationStatus = "failed"
+)
+
+// UserGroupMigration records a scheduled change of a user's groups and its bill handling.
+type UserGroupMigration struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	UserID uint64 `gorm:"not null;index"` // Related user ID.
+
+	FromUserGroupID UserGroupIDs `gorm:"type:jsonb;not null;default:'[]'"` // User group IDs before the migration.
+	ToUserGroupID   UserGroupIDs `gorm:"type:jsonb;not null;default:'[]'"` // User group IDs after the migration.
+
+	BillStrategy GroupMigrationBillStrategy `gorm:"type:varchar(16);not null"` // How active bills are handled.
+	EffectiveAt  time.Time                  `gorm:"not null;index"`            // Boundary at which the migration applies.
+
+	Status    GroupMigrationStatus `gorm:"type:varchar(16);not null;index"` // Current migration status.
+	Result    datatypes.JSON       `gorm:"type:jsonb"`                      // Bill changes performed on apply.
+	LastError string               `gorm:"type:text"`                       // Error captured when apply fails.
+	AppliedAt *time.Time           // When the migration was applied.
+
+	CreatedBy *uint64 // Admin ID that scheduled the migration.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
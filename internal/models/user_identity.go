@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// UserIdentity links a front user to an account at a social login provider.
+type UserIdentity struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	UserID   uint64 `gorm:"not null;index"`                                                     // Linked user ID.
+	Provider string `gorm:"type:varchar(32);not null;uniqueIndex:idx_user_identities_subject"`  // Provider name, e.g. github or google.
+	Subject  string `gorm:"type:varchar(255);not null;uniqueIndex:idx_user_identities_subject"` // Stable account ID at the provider.
+	Login    string `gorm:"type:text"`                                                          // Provider handle at link time.
+	Email    string `gorm:"type:text"`                                                          // Provider email at link time.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// User lifecycle actions recorded by the lifecycle scheduler.
+const (
+	// LifecycleActionSuspend disabled the API keys of a user without balance.
+	LifecycleActionSuspend = "suspend"
+	// LifecycleActionResume re-enabled the API keys of a suspended user after a top-up.
+	LifecycleActionResume = "resume"
+	// LifecycleActionWarnInactive warned an inactive user before anonymization.
+	LifecycleActionWarnInactive = "warn_inactive"
+	// LifecycleActionAnonymize scrubbed the personal data of an inactive user.
+	LifecycleActionAnonymize = "anonymize"
+)
+
+// UserLifecycleEvent records one automated lifecycle action taken on a user.
+type UserLifecycleEvent struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	UserID uint64 `gorm:"not null;index"`                  // Affected user.
+	Action string `gorm:"type:varchar(32);not null;index"` // Lifecycle action.
+	Detail string `gorm:"type:text;not null;default:''"`   // Human readable reason.
+
+	APIKeyIDs datatypes.JSON `gorm:"column:api_key_ids;type:jsonb;not null;default:'[]'"` // API keys the action disabled or re-enabled.
+
+	ResolvedAt *time.Time // When a suspension was lifted.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime;index"` // When the action ran.
+}
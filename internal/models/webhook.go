@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Webhook is an admin-configured endpoint that receives signed event payloads.
+type Webhook struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Name       string         `gorm:"type:varchar(255);not null"`            // Display name.
+	URL        string         `gorm:"type:text;not null"`                    // Delivery URL.
+	Secret     string         `gorm:"type:text;not null"`                    // HMAC signing secret; encrypted at rest when enabled.
+	EventTypes datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'"`      // Event types delivered; empty delivers every event.
+	IsEnabled  bool           `gorm:"not null;default:true;index"`           // Whether new events are delivered.
+	CreatedBy  string         `gorm:"type:varchar(255);not null;default:''"` // Admin that created the webhook.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
+
+// WebhookDeliveryStatus represents the lifecycle state of a webhook delivery.
+type WebhookDeliveryStatus string
+
+// WebhookDeliveryStatus constants define delivery states.
+const (
+	// WebhookDeliveryStatusPending marks a delivery waiting for its next attempt.
+	WebhookDeliveryStatusPending WebhookDeliveryStatus = "pending"
+	// WebhookDeliveryStatusSucceeded marks a delivery acknowledged with a 2xx response.
+	WebhookDeliveryStatusSucceeded WebhookDeliveryStatus = "succeeded"
+	// WebhookDeliveryStatusFailed marks a delivery that ran out of attempts.
+	WebhookDeliveryStatusFailed WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery logs one event sent to one webhook, including every retry.
+type WebhookDelivery struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	WebhookID uint64 `gorm:"not null;index"`                  // Target webhook ID.
+	EventID   string `gorm:"type:varchar(64);not null;index"` // Bus event identifier.
+	EventType string `gorm:"type:varchar(64);not null;index"` // Event type.
+	Payload   string `gorm:"type:text;not null"`              // JSON body sent on every attempt.
+
+	Status         WebhookDeliveryStatus `gorm:"type:varchar(16);not null;default:'pending';index"` // Current status.
+	Attempts       int                   `gorm:"not null;default:0"`                                // Attempts made so far.
+	NextAttemptAt  *time.Time            `gorm:"index"`                                             // When a pending delivery is retried.
+	LastAttemptAt  *time.Time            // Most recent attempt time.
+	LastStatusCode int                   `gorm:"not null;default:0"` // HTTP status of the last attempt, 0 when unreachable.
+	LastError      string                `gorm:"type:text"`          // Failure detail of the last attempt.
+	DeliveredAt    *time.Time            // When the endpoint acknowledged the delivery.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
@@ -0,0 +1,400 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// discoveryTTL bounds how long discovery documents and signing keys are cached.
+	discoveryTTL = time.Hour
+	// maxResponseBytes caps IdP responses read by the client.
+	maxResponseBytes = 1 << 20
+	defaultTimeout   = 10 * time.Second
+)
+
+// ErrInvalidIDToken is returned when an ID token fails verification.
+var ErrInvalidIDToken = errors.New("oidc: invalid id token")
+
+// discovery holds the provider metadata the client needs.
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Client runs the authorization code flow against one provider configuration.
+type Client struct {
+	cfg        Config
+	httpClient *http.Client
+
+	mu          sync.Mutex
+	meta        *discovery
+	metaFetched time.Time
+	keys        map[string]any
+	keysFetched time.Time
+}
+
+// NewClient constructs a client; a nil httpClient uses a default with a short timeout.
+func NewClient(cfg Config, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultTimeout}
+	}
+	return &Client{cfg: cfg.withDefaults(), httpClient: httpClient}
+}
+
+var (
+	clientsMu sync.Mutex
+	clients   = make(map[string]*Client)
+)
+
+// ClientFor returns a cached client for cfg so discovery and keys survive between logins;
+// a changed setting yields a fresh client.
+func ClientFor(cfg Config) *Client {
+	cfg = cfg.withDefaults()
+	key := strings.Join([]string{cfg.Issuer, cfg.ClientID, cfg.ClientSecret, cfg.RedirectURL}, "\x00")
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	if client, ok := clients[key]; ok {
+		client.cfg = cfg
+		return client
+	}
+	client := NewClient(cfg, nil)
+	clients[key] = client
+	return client
+}
+
+// Config returns the configuration the client runs with.
+func (c *Client) Config() Config { return c.cfg }
+
+// RandomToken returns a URL-safe random string for state, nonce and PKCE verifiers.
+func RandomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, errRead := rand.Read(buf); errRead != nil {
+		return "", fmt.Errorf("oidc: random token: %w", errRead)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// CodeChallenge derives the S256 PKCE challenge of verifier.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the provider authorization URL for a login attempt.
+func (c *Client) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	meta, errMeta := c.discover(ctx)
+	if errMeta != nil {
+		return "", errMeta
+	}
+	target, errParse := url.Parse(meta.AuthorizationEndpoint)
+	if errParse != nil {
+		return "", fmt.Errorf("oidc: invalid authorization endpoint: %w", errParse)
+	}
+	query := target.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", c.cfg.ClientID)
+	query.Set("redirect_uri", c.cfg.RedirectURL)
+	query.Set("scope", strings.Join(c.cfg.scopes(), " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", CodeChallenge(verifier))
+	query.Set("code_challenge_method", "S256")
+	target.RawQuery = query.Encode()
+	return target.String(), nil
+}
+
+// Exchange redeems an authorization code and returns the raw ID token.
+func (c *Client) Exchange(ctx context.Context, code, verifier string) (string, error) {
+	meta, errMeta := c.discover(ctx)
+	if errMeta != nil {
+		return "", errMeta
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.cfg.RedirectURL)
+	form.Set("client_id", c.cfg.ClientID)
+	form.Set("code_verifier", verifier)
+	req, errReq := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if errReq != nil {
+		return "", fmt.Errorf("oidc: build token request: %w", errReq)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+	}
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, errDo := c.doJSON(req, &token)
+	if errDo != nil {
+		return "", fmt.Errorf("oidc: token request: %w", errDo)
+	}
+	if status != http.StatusOK || token.Error != "" {
+		return "", fmt.Errorf("oidc: token request failed (status %d): %s %s", status, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return "", errors.New("oidc: token response has no id_token")
+	}
+	return token.IDToken, nil
+}
+
+// Claims are the verified claims of an ID token.
+type Claims map[string]any
+
+// Subject returns the sub claim.
+func (c Claims) Subject() string {
+	subject, _ := c["sub"].(string)
+	return subject
+}
+
+// String returns a string claim.
+func (c Claims) String(name string) string {
+	value, _ := c[name].(string)
+	return strings.TrimSpace(value)
+}
+
+// Strings returns a claim holding a list of strings, or a single string.
+func (c Claims) Strings(name string) []string {
+	switch typed := c[name].(type) {
+	case string:
+		if strings.TrimSpace(typed) == "" {
+			return nil
+		}
+		return []string{typed}
+	case []any:
+		out := make([]string, 0, len(typed))
+		for _, item := range typed {
+			if value, ok := item.(string); ok && strings.TrimSpace(value) != "" {
+				out = append(out, value)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// Verify checks the ID token signature against the provider keys, and its issuer, audience,
+// expiry and nonce.
+func (c *Client) Verify(ctx context.Context, rawIDToken, nonce string) (Claims, error) {
+	meta, errMeta := c.discover(ctx)
+	if errMeta != nil {
+		return nil, errMeta
+	}
+	claims := jwt.MapClaims{}
+	_, errParse := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return c.signingKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(meta.Issuer),
+		jwt.WithAudience(c.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if errParse != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, errParse)
+	}
+	if got, _ := claims["nonce"].(string); nonce == "" || got != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	if subject, _ := claims["sub"].(string); strings.TrimSpace(subject) == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+	return Claims(claims), nil
+}
+
+// discover loads and caches the provider metadata.
+func (c *Client) discover(ctx context.Context) (*discovery, error) {
+	c.mu.Lock()
+	if c.meta != nil && time.Since(c.metaFetched) < discoveryTTL {
+		meta := c.meta
+		c.mu.Unlock()
+		return meta, nil
+	}
+	c.mu.Unlock()
+
+	if c.cfg.Issuer == "" {
+		return nil, errors.New("oidc: issuer is not configured")
+	}
+	req, errReq := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.Issuer+"/.well-known/openid-configuration", nil)
+	if errReq != nil {
+		return nil, fmt.Errorf("oidc: build discovery request: %w", errReq)
+	}
+	var meta discovery
+	status, errDo := c.doJSON(req, &meta)
+	if errDo != nil {
+		return nil, fmt.Errorf("oidc: discovery: %w", errDo)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("oidc: discovery failed with status %d", status)
+	}
+	if strings.TrimRight(meta.Issuer, "/") != c.cfg.Issuer {
+		return nil, fmt.Errorf("oidc: discovery issuer %q does not match %q", meta.Issuer, c.cfg.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, errors.New("oidc: discovery document is incomplete")
+	}
+
+	c.mu.Lock()
+	c.meta = &meta
+	c.metaFetched = time.Now()
+	c.mu.Unlock()
+	return &meta, nil
+}
+
+// signingKey returns the provider key with id kid, refetching the JWKS once when the key
+// is unknown so rotated keys are picked up.
+func (c *Client) signingKey(ctx context.Context, kid string) (any, error) {
+	c.mu.Lock()
+	keys, fresh := c.keys, time.Since(c.keysFetched) < discoveryTTL
+	c.mu.Unlock()
+	if key := pickKey(keys, kid); key != nil && fresh {
+		return key, nil
+	}
+	keys, errFetch := c.fetchKeys(ctx)
+	if errFetch != nil {
+		return nil, errFetch
+	}
+	if key := pickKey(keys, kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("oidc: unknown signing key %q", kid)
+}
+
+func pickKey(keys map[string]any, kid string) any {
+	if key, ok := keys[kid]; ok {
+		return key
+	}
+	// Providers with a single key may omit kid from tokens.
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key
+		}
+	}
+	return nil
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (c *Client) fetchKeys(ctx context.Context) (map[string]any, error) {
+	meta, errMeta := c.discover(ctx)
+	if errMeta != nil {
+		return nil, errMeta
+	}
+	req, errReq := http.NewRequestWithContext(ctx, http.MethodGet, meta.JWKSURI, nil)
+	if errReq != nil {
+		return nil, fmt.Errorf("oidc: build jwks request: %w", errReq)
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	status, errDo := c.doJSON(req, &set)
+	if errDo != nil {
+		return nil, fmt.Errorf("oidc: jwks: %w", errDo)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("oidc: jwks failed with status %d", status)
+	}
+	keys := make(map[string]any, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, errKey := jwk.publicKey()
+		if errKey != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.keysFetched = time.Now()
+	c.mu.Unlock()
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 {
+			return nil, errors.New("oidc: invalid rsa key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("oidc: unsupported curve %q", k.Crv)
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(k.X)
+		y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+		if errX != nil || errY != nil {
+			return nil, errors.New("oidc: invalid ec key")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("oidc: unsupported key type %q", k.Kty)
+	}
+}
+
+// doJSON performs req and decodes a JSON response body into out.
+func (c *Client) doJSON(req *http.Request, out any) (int, error) {
+	resp, errDo := c.httpClient.Do(req)
+	if errDo != nil {
+		return 0, errDo
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, errRead := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if errRead != nil {
+		return resp.StatusCode, errRead
+	}
+	if len(body) > 0 {
+		if errUnmarshal := json.Unmarshal(body, out); errUnmarshal != nil && resp.StatusCode == http.StatusOK {
+			return resp.StatusCode, fmt.Errorf("decode response: %w", errUnmarshal)
+		}
+	}
+	return resp.StatusCode, nil
+}
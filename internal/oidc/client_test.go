@@ -0,0 +1,167 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type fakeProvider struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	claims   jwt.MapClaims
+	verifier string
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	t.Helper()
+	key, errKey := rsa.GenerateKey(rand.Reader, 2048)
+	if errKey != nil {
+		t.Fatalf("generate key: %v", errKey)
+	}
+	p := &fakeProvider{key: key}
+	mux := http.NewServeMux()
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kid": "k1",
+			"kty": "RSA",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if errParse := r.ParseForm(); errParse != nil || r.PostForm.Get("code") != "good-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		if user, _, ok := r.BasicAuth(); !ok || user != "cpab" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		p.verifier = r.PostForm.Get("code_verifier")
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": p.sign(t, p.claims)})
+	})
+	return p
+}
+
+func (p *fakeProvider) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "k1"
+	signed, errSign := token.SignedString(p.key)
+	if errSign != nil {
+		t.Fatalf("sign id token: %v", errSign)
+	}
+	return signed
+}
+
+func TestClientAuthorizationCodeFlow(t *testing.T) {
+	provider := newFakeProvider(t)
+	client := NewClient(Config{
+		Issuer:       provider.server.URL,
+		ClientID:     "cpab",
+		ClientSecret: "s3cret",
+		RedirectURL:  "https://admin.example.com/sso/callback",
+		Scopes:       []string{"groups", "openid"},
+	}, nil)
+	ctx := context.Background()
+
+	authURL, errURL := client.AuthCodeURL(ctx, "state-1", "nonce-1", "verifier-1")
+	if errURL != nil {
+		t.Fatalf("auth code url: %v", errURL)
+	}
+	parsed, _ := url.Parse(authURL)
+	query := parsed.Query()
+	if query.Get("scope") != "openid profile email groups" || query.Get("code_challenge") != CodeChallenge("verifier-1") || query.Get("state") != "state-1" {
+		t.Fatalf("unexpected authorization url %s", authURL)
+	}
+
+	provider.claims = jwt.MapClaims{
+		"iss":    provider.server.URL,
+		"aud":    "cpab",
+		"sub":    "user-1",
+		"nonce":  "nonce-1",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"email":  "ops@example.com",
+		"groups": []string{"platform-admins"},
+	}
+	rawIDToken, errExchange := client.Exchange(ctx, "good-code", "verifier-1")
+	if errExchange != nil {
+		t.Fatalf("exchange: %v", errExchange)
+	}
+	if provider.verifier != "verifier-1" {
+		t.Fatalf("expected pkce verifier to reach the token endpoint")
+	}
+	claims, errVerify := client.Verify(ctx, rawIDToken, "nonce-1")
+	if errVerify != nil {
+		t.Fatalf("verify: %v", errVerify)
+	}
+	if claims.Subject() != "user-1" || !reflect.DeepEqual(claims.Strings("groups"), []string{"platform-admins"}) {
+		t.Fatalf("unexpected claims %v", claims)
+	}
+
+	if _, errVerify = client.Verify(ctx, rawIDToken, "other-nonce"); !errors.Is(errVerify, ErrInvalidIDToken) {
+		t.Fatalf("expected nonce mismatch to be rejected, got %v", errVerify)
+	}
+	provider.claims["aud"] = "someone-else"
+	if _, errVerify = client.Verify(ctx, provider.sign(t, provider.claims), "nonce-1"); !errors.Is(errVerify, ErrInvalidIDToken) {
+		t.Fatalf("expected foreign audience to be rejected, got %v", errVerify)
+	}
+	if _, errExchange = client.Exchange(ctx, "bad-code", "verifier-1"); errExchange == nil {
+		t.Fatalf("expected invalid code to fail")
+	}
+}
+
+func TestResolveAccess(t *testing.T) {
+	cfg := Config{
+		RoleMapping: map[string][]string{
+			"billing":   {"billing-admin"},
+			"support":   {"support", "billing-admin"},
+			"nobody-in": {"auditor"},
+		},
+		SuperAdminGroups: []string{"root"},
+	}
+	access := cfg.ResolveAccess([]string{"support", "billing"})
+	if !access.Allowed || access.SuperAdmin || !reflect.DeepEqual(access.Roles, []string{"billing-admin", "support"}) {
+		t.Fatalf("unexpected access %+v", access)
+	}
+	if access = cfg.ResolveAccess([]string{"root"}); !access.Allowed || !access.SuperAdmin {
+		t.Fatalf("expected super admin group to grant access, got %+v", access)
+	}
+	if access = cfg.ResolveAccess([]string{"interns"}); access.Allowed {
+		t.Fatalf("expected unmapped groups to be refused")
+	}
+
+	cfg.AllowedGroups = []string{"staff"}
+	if access = cfg.ResolveAccess([]string{"support"}); access.Allowed {
+		t.Fatalf("expected allowed_groups to be required")
+	}
+	if access = cfg.ResolveAccess([]string{"staff"}); !access.Allowed || len(access.Roles) != 0 {
+		t.Fatalf("expected staff to sign in without roles, got %+v", access)
+	}
+}
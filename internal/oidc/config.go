@@ -0,0 +1,141 @@
+// Package oidc implements the OpenID Connect authorization code flow with PKCE used for
+// admin panel single sign-on: provider discovery, the code exchange, ID token verification
+// against the provider's JWKS, and mapping of IdP groups to admin roles.
+package oidc
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultGroupsClaim   = "groups"
+	defaultUsernameClaim = "preferred_username"
+)
+
+// Config mirrors the OIDC setting.
+type Config struct {
+	Enabled          bool                `json:"enabled"`            // Whether SSO login is offered.
+	Issuer           string              `json:"issuer"`             // Issuer URL; discovery is read from /.well-known/openid-configuration.
+	ClientID         string              `json:"client_id"`          // OAuth client ID registered at the IdP.
+	ClientSecret     string              `json:"client_secret"`      // OAuth client secret; empty for public clients.
+	RedirectURL      string              `json:"redirect_url"`       // Registered redirect URI that forwards code and state to the callback endpoint.
+	Scopes           []string            `json:"scopes"`             // Extra scopes requested besides openid, profile and email.
+	UsernameClaim    string              `json:"username_claim"`     // Claim used as the admin username; defaults to preferred_username, then email.
+	GroupsClaim      string              `json:"groups_claim"`       // Claim listing the user's IdP groups; defaults to groups.
+	RoleMapping      map[string][]string `json:"role_mapping"`       // Admin role names granted per IdP group.
+	SuperAdminGroups []string            `json:"super_admin_groups"` // IdP groups whose members become super admins.
+	AllowedGroups    []string            `json:"allowed_groups"`     // When set, only members of these groups may sign in.
+	AutoProvision    *bool               `json:"auto_provision"`     // Create admin accounts on first login; defaults to true.
+}
+
+// LoadConfig reads the OIDC setting; invalid values disable SSO.
+func LoadConfig() Config {
+	var cfg Config
+	raw, ok := internalsettings.DBConfigValue(internalsettings.OIDCKey)
+	if ok && len(bytes.TrimSpace(raw)) > 0 {
+		if errUnmarshal := json.Unmarshal(raw, &cfg); errUnmarshal != nil {
+			log.WithError(errUnmarshal).Warn("oidc: invalid setting")
+			cfg = Config{}
+		}
+	}
+	return cfg.withDefaults()
+}
+
+func (c Config) withDefaults() Config {
+	c.Issuer = strings.TrimRight(strings.TrimSpace(c.Issuer), "/")
+	c.ClientID = strings.TrimSpace(c.ClientID)
+	c.RedirectURL = strings.TrimSpace(c.RedirectURL)
+	if strings.TrimSpace(c.GroupsClaim) == "" {
+		c.GroupsClaim = defaultGroupsClaim
+	}
+	if strings.TrimSpace(c.UsernameClaim) == "" {
+		c.UsernameClaim = defaultUsernameClaim
+	}
+	return c
+}
+
+// Ready reports whether SSO is enabled and configured well enough to start a login.
+func (c Config) Ready() bool {
+	return c.Enabled && c.Issuer != "" && c.ClientID != "" && c.RedirectURL != ""
+}
+
+// ProvisionEnabled reports whether unknown IdP users get an admin account.
+func (c Config) ProvisionEnabled() bool {
+	return c.AutoProvision == nil || *c.AutoProvision
+}
+
+// scopes returns the requested scopes, always including openid, profile and email.
+func (c Config) scopes() []string {
+	out := []string{"openid", "profile", "email"}
+	seen := map[string]struct{}{"openid": {}, "profile": {}, "email": {}}
+	for _, scope := range c.Scopes {
+		scope = strings.TrimSpace(scope)
+		if scope == "" {
+			continue
+		}
+		if _, ok := seen[scope]; ok {
+			continue
+		}
+		seen[scope] = struct{}{}
+		out = append(out, scope)
+	}
+	return out
+}
+
+// Access is what an IdP identity is entitled to in the admin panel.
+type Access struct {
+	Allowed    bool     // Whether the identity may sign in at all.
+	SuperAdmin bool     // Whether the identity is in a super admin group.
+	Roles      []string // Admin role names granted through role_mapping, sorted and unique.
+}
+
+// ResolveAccess maps IdP groups to admin access. Identities need an allowed group when
+// allowed_groups is set, and at least one mapped role or super admin group otherwise.
+func (c Config) ResolveAccess(groups []string) Access {
+	member := make(map[string]struct{}, len(groups))
+	for _, group := range groups {
+		member[strings.TrimSpace(group)] = struct{}{}
+	}
+	inAny := func(list []string) bool {
+		for _, group := range list {
+			if _, ok := member[strings.TrimSpace(group)]; ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	var access Access
+	access.SuperAdmin = inAny(c.SuperAdminGroups)
+	seen := make(map[string]struct{})
+	for group, roles := range c.RoleMapping {
+		if _, ok := member[strings.TrimSpace(group)]; !ok {
+			continue
+		}
+		for _, role := range roles {
+			role = strings.TrimSpace(role)
+			if role == "" {
+				continue
+			}
+			if _, dup := seen[role]; dup {
+				continue
+			}
+			seen[role] = struct{}{}
+			access.Roles = append(access.Roles, role)
+		}
+	}
+	sort.Strings(access.Roles)
+
+	if len(c.AllowedGroups) > 0 {
+		access.Allowed = inAny(c.AllowedGroups)
+	} else {
+		access.Allowed = access.SuperAdmin || len(access.Roles) > 0
+	}
+	return access
+}
@@ -0,0 +1,87 @@
+package payloadstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore keeps objects as files below a directory.
+type LocalStore struct {
+	dir string
+}
+
+// NewLocalStore constructs a store rooted at dir, creating it when missing.
+func NewLocalStore(dir string) (*LocalStore, error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return nil, errors.New("payloadstore: local backend needs a directory")
+	}
+	if errDir := os.MkdirAll(dir, 0o700); errDir != nil {
+		return nil, fmt.Errorf("payloadstore: create dir: %w", errDir)
+	}
+	return &LocalStore{dir: dir}, nil
+}
+
+// Backend returns BackendLocal.
+func (s *LocalStore) Backend() string { return BackendLocal }
+
+// path maps key to a file below the root, rejecting keys that would escape it.
+func (s *LocalStore) path(key string) (string, error) {
+	rel := filepath.FromSlash(key)
+	if key == "" || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("payloadstore: invalid key %q", key)
+	}
+	return filepath.Join(s.dir, rel), nil
+}
+
+// Put writes body to a temporary file and renames it into place.
+func (s *LocalStore) Put(_ context.Context, key string, body []byte) error {
+	path, errPath := s.path(key)
+	if errPath != nil {
+		return errPath
+	}
+	if errDir := os.MkdirAll(filepath.Dir(path), 0o700); errDir != nil {
+		return fmt.Errorf("payloadstore: create dir: %w", errDir)
+	}
+	tmpPath := path + ".tmp"
+	if errWrite := os.WriteFile(tmpPath, body, 0o600); errWrite != nil {
+		return fmt.Errorf("payloadstore: write %s: %w", key, errWrite)
+	}
+	if errRename := os.Rename(tmpPath, path); errRename != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("payloadstore: write %s: %w", key, errRename)
+	}
+	return nil
+}
+
+// Get reads the file stored under key.
+func (s *LocalStore) Get(_ context.Context, key string) ([]byte, error) {
+	path, errPath := s.path(key)
+	if errPath != nil {
+		return nil, errPath
+	}
+	body, errRead := os.ReadFile(path)
+	if errors.Is(errRead, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if errRead != nil {
+		return nil, fmt.Errorf("payloadstore: read %s: %w", key, errRead)
+	}
+	return body, nil
+}
+
+// Delete removes the file stored under key.
+func (s *LocalStore) Delete(_ context.Context, key string) error {
+	path, errPath := s.path(key)
+	if errPath != nil {
+		return errPath
+	}
+	if errRemove := os.Remove(path); errRemove != nil && !errors.Is(errRemove, os.ErrNotExist) {
+		return fmt.Errorf("payloadstore: delete %s: %w", key, errRemove)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+// Package payloadstore keeps large captured bodies in an object store (local disk, S3 or GCS)
+// so the database only holds a reference to them. The process-wide store is nil until the app
+// installs one; callers then keep bodies inline in the database.
+package payloadstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Backend names accepted by New.
+const (
+	BackendDatabase = "database" // Bodies stay inline in the database; New returns nil.
+	BackendLocal    = "local"    // Files below a local directory.
+	BackendS3       = "s3"       // Amazon S3 or an S3-compatible service.
+	BackendGCS      = "gcs"      // Google Cloud Storage through its S3-compatible XML API.
+)
+
+// gcsEndpoint is the XML API endpoint used for GCS when none is configured.
+const gcsEndpoint = "https://storage.googleapis.com"
+
+// ErrNotFound reports a key with no stored object.
+var ErrNotFound = errors.New("payloadstore: object not found")
+
+// Store reads and writes opaque objects by key.
+type Store interface {
+	// Backend returns the backend name recorded next to object keys.
+	Backend() string
+	// Put stores body under key, replacing any previous object.
+	Put(ctx context.Context, key string, body []byte) error
+	// Get returns the object stored under key, or ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes the object; deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Options selects and configures a backend.
+type Options struct {
+	Backend         string // One of the Backend constants; empty means BackendDatabase.
+	Dir             string // Root directory of the local backend.
+	Bucket          string // Bucket of the S3 and GCS backends.
+	Prefix          string // Key prefix inside the bucket or directory.
+	Endpoint        string // Service URL; defaults to AWS for S3 and to the XML API for GCS.
+	Region          string // Signing region; defaults to us-east-1 for S3 and auto for GCS.
+	AccessKeyID     string // Access key, or a GCS HMAC key ID.
+	SecretAccessKey string // Secret key, or a GCS HMAC secret.
+	PathStyle       bool   // Address the bucket in the path instead of the host name.
+}
+
+// New constructs the store selected by opts, or nil for BackendDatabase.
+func New(opts Options) (Store, error) {
+	var (
+		store    Store
+		errStore error
+	)
+	switch strings.ToLower(strings.TrimSpace(opts.Backend)) {
+	case "", BackendDatabase:
+		return nil, nil
+	case BackendLocal:
+		store, errStore = NewLocalStore(opts.Dir)
+	case BackendS3:
+		store, errStore = NewS3Store(BackendS3, opts)
+	case BackendGCS:
+		if strings.TrimSpace(opts.Endpoint) == "" {
+			opts.Endpoint = gcsEndpoint
+		}
+		if strings.TrimSpace(opts.Region) == "" {
+			opts.Region = "auto"
+		}
+		store, errStore = NewS3Store(BackendGCS, opts)
+	default:
+		return nil, fmt.Errorf("payloadstore: unknown backend %q", opts.Backend)
+	}
+	if errStore != nil {
+		return nil, errStore
+	}
+	if prefix := strings.Trim(strings.TrimSpace(opts.Prefix), "/"); prefix != "" {
+		store = prefixedStore{Store: store, prefix: prefix + "/"}
+	}
+	return store, nil
+}
+
+// prefixedStore places every key below a fixed prefix.
+type prefixedStore struct {
+	Store
+	prefix string
+}
+
+func (s prefixedStore) Put(ctx context.Context, key string, body []byte) error {
+	return s.Store.Put(ctx, s.prefix+key, body)
+}
+
+func (s prefixedStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return s.Store.Get(ctx, s.prefix+key)
+}
+
+func (s prefixedStore) Delete(ctx context.Context, key string) error {
+	return s.Store.Delete(ctx, s.prefix+key)
+}
+
+var defaultStore atomic.Pointer[Store]
+
+// SetDefault installs the process-wide store; nil keeps bodies in the database.
+func SetDefault(s Store) {
+	if s == nil {
+		defaultStore.Store(nil)
+		return
+	}
+	defaultStore.Store(&s)
+}
+
+// Default returns the process-wide store, or nil when bodies stay in the database.
+func Default() Store {
+	if s := defaultStore.Load(); s != nil {
+		return *s
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package payloadstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// s3RequestTimeout bounds one object request.
+	s3RequestTimeout = 30 * time.Second
+	// s3ErrorBodyLimit caps how much of an error response is quoted.
+	s3ErrorBodyLimit = 512
+)
+
+// S3Store keeps objects in an S3-compatible bucket. Requests are signed with AWS Signature
+// Version 4, which GCS also accepts with HMAC keys, so one client serves both services.
+type S3Store struct {
+	backend         string
+	endpoint        *url.URL
+	bucket          string
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	pathStyle       bool
+	client          *http.Client
+	now             func() time.Time
+}
+
+// NewS3Store constructs a bucket store recorded under backend.
+func NewS3Store(backend string, opts Options) (*S3Store, error) {
+	bucket := strings.TrimSpace(opts.Bucket)
+	if bucket == "" {
+		return nil, fmt.Errorf("payloadstore: %s backend needs a bucket", backend)
+	}
+	accessKeyID, secretAccessKey := strings.TrimSpace(opts.AccessKeyID), strings.TrimSpace(opts.SecretAccessKey)
+	if accessKeyID == "" || secretAccessKey == "" {
+		return nil, fmt.Errorf("payloadstore: %s backend needs an access key and secret", backend)
+	}
+	region := strings.TrimSpace(opts.Region)
+	if region == "" {
+		region = "us-east-1"
+	}
+	rawEndpoint := strings.TrimSpace(opts.Endpoint)
+	if rawEndpoint == "" {
+		rawEndpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	endpoint, errParse := url.Parse(strings.TrimRight(rawEndpoint, "/"))
+	if errParse != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("payloadstore: invalid endpoint %q", rawEndpoint)
+	}
+	return &S3Store{
+		backend:         backend,
+		endpoint:        endpoint,
+		bucket:          bucket,
+		region:          region,
+		accessKeyID:     accessKeyID,
+		secretAccessKey: secretAccessKey,
+		pathStyle:       opts.PathStyle,
+		client:          &http.Client{Timeout: s3RequestTimeout},
+		now:             time.Now,
+	}, nil
+}
+
+// Backend returns the backend name the store was constructed with.
+func (s *S3Store) Backend() string { return s.backend }
+
+// Put uploads body under key.
+func (s *S3Store) Put(ctx context.Context, key string, body []byte) error {
+	resp, errDo := s.do(ctx, http.MethodPut, key, body)
+	if errDo != nil {
+		return errDo
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 != 2 {
+		return s.statusError("put", key, resp)
+	}
+	return nil
+}
+
+// Get downloads the object stored under key.
+func (s *S3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, errDo := s.do(ctx, http.MethodGet, key, nil)
+	if errDo != nil {
+		return nil, errDo
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, s.statusError("get", key, resp)
+	}
+	body, errRead := io.ReadAll(resp.Body)
+	if errRead != nil {
+		return nil, fmt.Errorf("payloadstore: get %s: %w", key, errRead)
+	}
+	return body, nil
+}
+
+// Delete removes the object stored under key.
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	resp, errDo := s.do(ctx, http.MethodDelete, key, nil)
+	if errDo != nil {
+		return errDo
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusNotFound {
+		return s.statusError("delete", key, resp)
+	}
+	return nil
+}
+
+// do sends one signed object request.
+func (s *S3Store) do(ctx context.Context, method, key string, body []byte) (*http.Response, error) {
+	if strings.TrimSpace(key) == "" {
+		return nil, errors.New("payloadstore: empty key")
+	}
+	target := *s.endpoint
+	escapedKey := awsURIEncode(key, false)
+	if s.pathStyle {
+		target.Path = s.endpoint.Path + "/" + s.bucket + "/" + key
+		target.RawPath = s.endpoint.Path + "/" + awsURIEncode(s.bucket, true) + "/" + escapedKey
+	} else {
+		target.Host = s.bucket + "." + s.endpoint.Host
+		target.Path = s.endpoint.Path + "/" + key
+		target.RawPath = s.endpoint.Path + "/" + escapedKey
+	}
+	req, errReq := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+	if errReq != nil {
+		return nil, fmt.Errorf("payloadstore: build request: %w", errReq)
+	}
+	if body == nil {
+		req.Body, req.ContentLength = http.NoBody, 0
+	}
+	sum := sha256.Sum256(body)
+	s.sign(req, hex.EncodeToString(sum[:]))
+	resp, errDo := s.client.Do(req)
+	if errDo != nil {
+		return nil, fmt.Errorf("payloadstore: %s %s: %w", strings.ToLower(method), key, errDo)
+	}
+	return resp, nil
+}
+
+// sign adds AWS Signature Version 4 headers covering every header already set on req.
+func (s *S3Store) sign(req *http.Request, payloadHash string) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(strings.Fields(strings.Join(values, ",")), " ")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + s.region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.secretAccessKey), day)
+	for _, part := range []string{s.region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKeyID, scope, signedHeaders, signature))
+}
+
+// statusError describes a failed response, quoting the start of its body.
+func (s *S3Store) statusError(op, key string, resp *http.Response) error {
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, s3ErrorBodyLimit))
+	return fmt.Errorf("payloadstore: %s %s: status %d: %s", op, key, resp.StatusCode, strings.TrimSpace(string(snippet)))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsURIEncode percent-encodes everything but unreserved characters, keeping slashes unless
+// encodeSlash is set, as Signature Version 4 requires.
+func awsURIEncode(value string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
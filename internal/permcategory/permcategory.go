@@ -0,0 +1,107 @@
+// Package permcategory names the areas of the admin API guarded by category permissions and
+// assigns admin routes to them. It sits below both the admin permission table and the schema
+// migrations that grant categories, so neither depends on the other.
+package permcategory
+
+import "strings"
+
+// Category permissions gate whole areas of the admin API. A route in a category needs both its
+// own key and the category key, so finance staff holding only Billing cannot reach provider
+// credentials even when a role grants the route keys by mistake.
+const (
+	Billing        = "billing"        // Plans, bills, prepaid cards, billing rules and invoices.
+	Infrastructure = "infrastructure" // Provider keys, auth files, proxies, models and nodes.
+	Accounts       = "accounts"       // Users, user groups, API keys and organizations.
+	Analytics      = "analytics"      // Dashboards, usage and logs.
+)
+
+// adminRoutePrefix is the path prefix of every categorized route.
+const adminRoutePrefix = "/v0/admin/"
+
+// All returns every category permission.
+func All() []string {
+	return []string{Accounts, Analytics, Billing, Infrastructure}
+}
+
+// resourceCategories assigns admin resources, the first path segment after /v0/admin/, to a
+// category. Resources not listed, such as administrators, settings and bulk delete jobs, are
+// guarded by their route keys alone; bulk delete jobs check the category of the deleted entity
+// in their handler.
+var resourceCategories = map[string]string{
+	"billing":                Billing,
+	"billing-rule-snapshots": Billing,
+	"billing-rules":          Billing,
+	"balance-transactions":   Billing,
+	"bills":                  Billing,
+	"coop":                   Billing,
+	"cost-replay-jobs":       Billing,
+	"exchange-rates":         Billing,
+	"invoices":               Billing,
+	"plans":                  Billing,
+	"prepaid-cards":          Billing,
+	"tier-upgrade-rules":     Billing,
+	"tier-upgrades":          Billing,
+
+	"auth-files":        Infrastructure,
+	"auth-groups":       Infrastructure,
+	"chaos":             Infrastructure,
+	"cluster":           Infrastructure,
+	"environments":      Infrastructure,
+	"model-displays":    Infrastructure,
+	"model-mappings":    Infrastructure,
+	"model-references":  Infrastructure,
+	"models":            Infrastructure,
+	"nodes":             Infrastructure,
+	"provider-api-keys": Infrastructure,
+	"proxies":           Infrastructure,
+	"quotas":            Infrastructure,
+	"retry-policies":    Infrastructure,
+	"tokens":            Infrastructure,
+
+	"api-keys":              Accounts,
+	"invitations":           Accounts,
+	"organizations":         Accounts,
+	"user-group-migrations": Accounts,
+	"user-groups":           Accounts,
+	"user-lifecycle":        Accounts,
+	"users":                 Accounts,
+
+	"dashboard":    Analytics,
+	"logs":         Analytics,
+	"request-logs": Analytics,
+	"slos":         Analytics,
+	"usage":        Analytics,
+	"usages":       Analytics,
+}
+
+// routeCategories assigns single routes whose category differs from their resource's. They
+// are matched by route permission key before the resource lookup.
+var routeCategories = map[string]string{
+	"POST /v0/admin/users/:id/balance-adjustments": Billing,
+}
+
+// OfRoute returns the category guarding the route permission key ("METHOD /path"), or ""
+// when none does.
+func OfRoute(key string) string {
+	if category, ok := routeCategories[key]; ok {
+		return category
+	}
+	_, path, found := strings.Cut(key, " ")
+	if !found || !strings.HasPrefix(path, adminRoutePrefix) {
+		return ""
+	}
+	resource, _, _ := strings.Cut(strings.TrimPrefix(path, adminRoutePrefix), "/")
+	return resourceCategories[resource]
+}
+
+// Implied returns the category permissions needed by the route keys in perms, possibly with
+// duplicates.
+func Implied(perms []string) []string {
+	out := make([]string, 0, 4)
+	for _, perm := range perms {
+		if category := OfRoute(perm); category != "" {
+			out = append(out, category)
+		}
+	}
+	return out
+}
@@ -0,0 +1,33 @@
+package permcategory
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestOfRouteUsesAdminResource(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"PUT /v0/admin/provider-api-keys/:id":          Infrastructure,
+		"GET /v0/admin/bills":                          Billing,
+		"GET /v0/admin/users/:id/api-keys":             Accounts,
+		"GET /v0/admin/request-logs/:id":               Analytics,
+		"GET /v0/admin/admins":                         "",
+		"POST /v0/admin/bulk-delete-jobs":              "",
+		"GET /v0/front/bills":                          "",
+		Billing:                                        "",
+		"GET /v0/admin/billing-rules/snapshots":        Billing,
+		"GET /v0/admin/balance-transactions":           Billing,
+		"POST /v0/admin/users/:id/balance-adjustments": Billing,
+	}
+	for key, want := range cases {
+		if got := OfRoute(key); got != want {
+			t.Errorf("OfRoute(%q) = %q, want %q", key, got, want)
+		}
+	}
+	implied := Implied([]string{"GET /v0/admin/bills", "GET /v0/admin/admins", "GET /v0/admin/usage"})
+	if !slices.Equal(implied, []string{Billing, Analytics}) {
+		t.Fatalf("unexpected implied categories %v", implied)
+	}
+}
@@ -0,0 +1,233 @@
+// Package proxyassign picks a proxy from the pool for newly created auth files and provider
+// API keys. The strategy is selected by AUTO_ASSIGN_PROXY_STRATEGY; proxies the proxy health
+// checker marked inactive are never picked.
+package proxyassign
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/proxyref"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Strategy selects how a proxy is picked.
+type Strategy string
+
+const (
+	// StrategyRandom picks any active proxy.
+	StrategyRandom Strategy = "random"
+	// StrategyLeastUsed picks the active proxy referenced by the fewest auth files and provider API keys.
+	StrategyLeastUsed Strategy = "least_used"
+	// StrategyRegion prefers proxies whose region matches the provider in PROXY_PROVIDER_REGIONS,
+	// least used first, and falls back to least used over the whole pool.
+	StrategyRegion Strategy = "region"
+	// StrategySticky reuses the proxy most auth files of the same auth group already use,
+	// and falls back to least used.
+	StrategySticky Strategy = "sticky_group"
+)
+
+// ParseStrategy validates a strategy name; empty selects random.
+func ParseStrategy(raw string) (Strategy, error) {
+	switch strategy := Strategy(strings.ToLower(strings.TrimSpace(raw))); strategy {
+	case "":
+		return StrategyRandom, nil
+	case StrategyRandom, StrategyLeastUsed, StrategyRegion, StrategySticky:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown proxy assignment strategy %q", raw)
+	}
+}
+
+// Config is the assignment configuration read from settings.
+type Config struct {
+	Strategy        Strategy          // Selection strategy.
+	ProviderRegions map[string]string // Provider to preferred proxy region.
+}
+
+// LoadConfig reads AUTO_ASSIGN_PROXY_STRATEGY and PROXY_PROVIDER_REGIONS; invalid values
+// fall back to random assignment and no region preferences.
+func LoadConfig() Config {
+	cfg := Config{Strategy: StrategyRandom, ProviderRegions: map[string]string{}}
+	if raw, ok := internalsettings.DBConfigValue(internalsettings.AutoAssignProxyStrategyKey); ok && len(bytes.TrimSpace(raw)) > 0 {
+		var name string
+		if errUnmarshal := json.Unmarshal(raw, &name); errUnmarshal != nil {
+			name = string(bytes.TrimSpace(raw))
+		}
+		strategy, errParse := ParseStrategy(name)
+		if errParse != nil {
+			log.WithError(errParse).Warn("proxy assignment: invalid strategy, using random")
+		} else {
+			cfg.Strategy = strategy
+		}
+	}
+	if raw, ok := internalsettings.DBConfigValue(internalsettings.ProxyProviderRegionsKey); ok && len(bytes.TrimSpace(raw)) > 0 {
+		var regions map[string]string
+		if errUnmarshal := json.Unmarshal(raw, &regions); errUnmarshal != nil {
+			log.WithError(errUnmarshal).Warn("proxy assignment: invalid provider regions")
+		}
+		for provider, region := range regions {
+			provider = strings.ToLower(strings.TrimSpace(provider))
+			if region = strings.TrimSpace(region); provider != "" && region != "" {
+				cfg.ProviderRegions[provider] = region
+			}
+		}
+	}
+	return cfg
+}
+
+// Target describes what the proxy is assigned to.
+type Target struct {
+	Provider     string   // Provider of the auth file or API key, used by region assignment.
+	AuthGroupIDs []uint64 // Auth groups of the auth file, used by sticky assignment.
+}
+
+// candidate is an active proxy with its reference count.
+type candidate struct {
+	url    string
+	region string
+	uses   int
+}
+
+// Pick returns the proxy URL for target, or "" when the pool has no active proxy.
+func Pick(ctx context.Context, db *gorm.DB, cfg Config, target Target) (string, error) {
+	if db == nil {
+		return "", fmt.Errorf("proxy assignment: nil db")
+	}
+	var proxies []models.Proxy
+	inactive := db.Model(&models.ProxyHealth{}).Select("proxy_id").Where("active = ?", false)
+	if errFind := db.WithContext(ctx).
+		Where("id NOT IN (?)", inactive).
+		Order("id ASC").
+		Find(&proxies).Error; errFind != nil {
+		return "", fmt.Errorf("proxy assignment: list proxies: %w", errFind)
+	}
+	if len(proxies) == 0 {
+		return "", nil
+	}
+	candidates := make([]candidate, 0, len(proxies))
+	for _, proxy := range proxies {
+		if proxyURL := strings.TrimSpace(proxy.ProxyURL); proxyURL != "" {
+			candidates = append(candidates, candidate{url: proxyURL, region: strings.TrimSpace(proxy.Region)})
+		}
+	}
+	if len(candidates) == 0 {
+		return "", nil
+	}
+
+	if cfg.Strategy == "" || cfg.Strategy == StrategyRandom {
+		return candidates[rand.IntN(len(candidates))].url, nil
+	}
+
+	refs, errRefs := loadReferences(ctx, db)
+	if errRefs != nil {
+		return "", errRefs
+	}
+	for i := range candidates {
+		candidates[i].uses = refs.uses[proxyref.Normalize(candidates[i].url)]
+	}
+
+	switch cfg.Strategy {
+	case StrategyRegion:
+		region := cfg.ProviderRegions[strings.ToLower(strings.TrimSpace(target.Provider))]
+		if region != "" {
+			matching := make([]candidate, 0, len(candidates))
+			for _, c := range candidates {
+				if strings.EqualFold(c.region, region) {
+					matching = append(matching, c)
+				}
+			}
+			if len(matching) > 0 {
+				return leastUsed(matching).url, nil
+			}
+		}
+	case StrategySticky:
+		if url, ok := refs.stickyFor(target.AuthGroupIDs, candidates); ok {
+			return url, nil
+		}
+	}
+	return leastUsed(candidates).url, nil
+}
+
+// leastUsed returns the candidate with the fewest references, the oldest proxy on ties.
+func leastUsed(candidates []candidate) candidate {
+	best := candidates[0]
+	for _, c := range candidates[1:] {
+		if c.uses < best.uses {
+			best = c
+		}
+	}
+	return best
+}
+
+// references counts how often each proxy URL is used.
+type references struct {
+	uses      map[string]int            // Auth files and provider API keys per proxy URL.
+	groupUses map[uint64]map[string]int // Auth files per auth group and proxy URL.
+}
+
+// stickyFor returns the active proxy most used by auth files sharing one of groupIDs.
+func (r references) stickyFor(groupIDs []uint64, candidates []candidate) (string, bool) {
+	if len(groupIDs) == 0 {
+		return "", false
+	}
+	counts := make(map[string]int)
+	for _, groupID := range groupIDs {
+		for key, n := range r.groupUses[groupID] {
+			counts[key] += n
+		}
+	}
+	ordered := make([]candidate, 0, len(candidates))
+	for _, c := range candidates {
+		if counts[proxyref.Normalize(c.url)] > 0 {
+			ordered = append(ordered, c)
+		}
+	}
+	if len(ordered) == 0 {
+		return "", false
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return counts[proxyref.Normalize(ordered[i].url)] > counts[proxyref.Normalize(ordered[j].url)]
+	})
+	return ordered[0].url, true
+}
+
+// loadReferences counts the proxy URLs of auth files and provider API keys.
+func loadReferences(ctx context.Context, db *gorm.DB) (references, error) {
+	refs := references{uses: make(map[string]int), groupUses: make(map[uint64]map[string]int)}
+	var auths []models.Auth
+	if errFind := db.WithContext(ctx).
+		Select("id", "proxy_url", "auth_group_id").
+		Where("proxy_url IS NOT NULL AND proxy_url <> ''").
+		Find(&auths).Error; errFind != nil {
+		return refs, fmt.Errorf("proxy assignment: list auth proxies: %w", errFind)
+	}
+	for _, auth := range auths {
+		key := proxyref.Normalize(auth.ProxyURL)
+		refs.uses[key]++
+		for _, groupID := range auth.AuthGroupID.Values() {
+			if refs.groupUses[groupID] == nil {
+				refs.groupUses[groupID] = make(map[string]int)
+			}
+			refs.groupUses[groupID][key]++
+		}
+	}
+	var keyProxies []string
+	if errPluck := db.WithContext(ctx).Model(&models.ProviderAPIKey{}).
+		Where("proxy_url IS NOT NULL AND proxy_url <> ''").
+		Pluck("proxy_url", &keyProxies).Error; errPluck != nil {
+		return refs, fmt.Errorf("proxy assignment: list provider key proxies: %w", errPluck)
+	}
+	for _, proxyURL := range keyProxies {
+		refs.uses[proxyref.Normalize(proxyURL)]++
+	}
+	return refs, nil
+}
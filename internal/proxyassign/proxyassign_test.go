@@ -0,0 +1,114 @@
+package proxyassign
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func setupAssignDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:proxyassign_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func uint64Ptr(v uint64) *uint64 { return &v }
+
+func TestPickStrategies(t *testing.T) {
+	conn := setupAssignDB(t)
+	ctx := context.Background()
+
+	proxies := []models.Proxy{
+		{ProxyURL: "http://10.0.0.1:8080/", Region: "us"},
+		{ProxyURL: "http://10.0.0.2:8080/", Region: "eu"},
+		{ProxyURL: "http://10.0.0.3:8080/", Region: "eu"},
+		{ProxyURL: "http://10.0.0.4:8080/", Region: "us"},
+	}
+	if errCreate := conn.Create(&proxies).Error; errCreate != nil {
+		t.Fatalf("create proxies: %v", errCreate)
+	}
+	// Proxy 4 is dead and must never be picked although nothing references it.
+	if errCreate := conn.Create(&models.ProxyHealth{ProxyID: proxies[3].ID, Active: false}).Error; errCreate != nil {
+		t.Fatalf("create health: %v", errCreate)
+	}
+	// Proxy 1 serves two auth files of group 7, proxy 2 one auth file of group 9 and a key.
+	auths := []models.Auth{
+		{Key: "a1", Content: datatypes.JSON(`{}`), ProxyURL: "http://10.0.0.1:8080", AuthGroupID: models.AuthGroupIDs{uint64Ptr(7)}},
+		{Key: "a2", Content: datatypes.JSON(`{}`), ProxyURL: "http://10.0.0.1:8080/", AuthGroupID: models.AuthGroupIDs{uint64Ptr(7)}},
+		{Key: "a3", Content: datatypes.JSON(`{}`), ProxyURL: "http://10.0.0.2:8080/", AuthGroupID: models.AuthGroupIDs{uint64Ptr(9)}},
+	}
+	if errCreate := conn.Create(&auths).Error; errCreate != nil {
+		t.Fatalf("create auths: %v", errCreate)
+	}
+	if errCreate := conn.Create(&models.ProviderAPIKey{Provider: "codex", ProxyURL: "http://10.0.0.2:8080/"}).Error; errCreate != nil {
+		t.Fatalf("create provider key: %v", errCreate)
+	}
+
+	regions := map[string]string{"claude": "us", "gemini": "eu", "codex": "apac"}
+	for _, tc := range []struct {
+		name     string
+		strategy Strategy
+		target   Target
+		want     string
+	}{
+		{"least used", StrategyLeastUsed, Target{}, "http://10.0.0.3:8080/"},
+		{"region match", StrategyRegion, Target{Provider: "claude"}, "http://10.0.0.1:8080/"},
+		{"region least used", StrategyRegion, Target{Provider: "Gemini"}, "http://10.0.0.3:8080/"},
+		{"region without proxies", StrategyRegion, Target{Provider: "codex"}, "http://10.0.0.3:8080/"},
+		{"sticky group", StrategySticky, Target{AuthGroupIDs: []uint64{9}}, "http://10.0.0.2:8080/"},
+		{"sticky majority", StrategySticky, Target{AuthGroupIDs: []uint64{7, 9}}, "http://10.0.0.1:8080/"},
+		{"sticky new group", StrategySticky, Target{AuthGroupIDs: []uint64{11}}, "http://10.0.0.3:8080/"},
+	} {
+		got, errPick := Pick(ctx, conn, Config{Strategy: tc.strategy, ProviderRegions: regions}, tc.target)
+		if errPick != nil {
+			t.Fatalf("%s: %v", tc.name, errPick)
+		}
+		if got != tc.want {
+			t.Fatalf("%s: picked %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	for i := 0; i < 20; i++ {
+		got, errPick := Pick(ctx, conn, Config{Strategy: StrategyRandom}, Target{})
+		if errPick != nil {
+			t.Fatalf("random: %v", errPick)
+		}
+		if got == proxies[3].ProxyURL {
+			t.Fatal("random picked an inactive proxy")
+		}
+	}
+}
+
+func TestPickEmptyPool(t *testing.T) {
+	conn := setupAssignDB(t)
+	got, errPick := Pick(context.Background(), conn, Config{Strategy: StrategyLeastUsed}, Target{})
+	if errPick != nil || got != "" {
+		t.Fatalf("Pick on empty pool = %q, %v", got, errPick)
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	if strategy, errParse := ParseStrategy(" Least_Used "); errParse != nil || strategy != StrategyLeastUsed {
+		t.Fatalf("ParseStrategy = %q, %v", strategy, errParse)
+	}
+	if strategy, errParse := ParseStrategy(""); errParse != nil || strategy != StrategyRandom {
+		t.Fatalf("ParseStrategy(empty) = %q, %v", strategy, errParse)
+	}
+	if _, errParse := ParseStrategy("round_robin"); errParse == nil {
+		t.Fatal("expected error for unknown strategy")
+	}
+}
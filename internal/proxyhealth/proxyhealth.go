@@ -0,0 +1,326 @@
+// Package proxyhealth periodically checks every proxy of the pool by fetching an IP echo
+// URL through it. Each check records latency and exit IP; a proxy that fails several checks
+// in a row is marked inactive, which keeps it out of automatic proxy assignment until a
+// later check succeeds again.
+package proxyhealth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultIntervalSeconds  = 300
+	defaultTimeoutSeconds   = 10
+	defaultFailureThreshold = 3
+	defaultWindowHours      = 24
+	// DefaultCheckURL answers with the caller's public IP as plain text.
+	DefaultCheckURL = "https://api.ipify.org"
+	// disabledRecheck is how often a disabled checker rereads its setting.
+	disabledRecheck = 5 * time.Minute
+	// retention bounds how long individual checks are kept.
+	retention      = 7 * 24 * time.Hour
+	maxConcurrency = 4
+	// maxBodyBytes caps how much of the check response is read.
+	maxBodyBytes = 4 << 10
+)
+
+// Config mirrors the PROXY_HEALTH setting.
+type Config struct {
+	Enabled          bool   `json:"enabled"`           // Whether the checker runs.
+	IntervalSeconds  int    `json:"interval_seconds"`  // How often every proxy is checked.
+	TimeoutSeconds   int    `json:"timeout_seconds"`   // Deadline of one check.
+	FailureThreshold int    `json:"failure_threshold"` // Consecutive failures that mark a proxy inactive.
+	WindowHours      int    `json:"window_hours"`      // Window of the reported failure rate and latency.
+	CheckURL         string `json:"check_url"`         // IP echo URL fetched through each proxy.
+}
+
+// LoadConfig reads PROXY_HEALTH and fills defaults; invalid values disable the checker.
+func LoadConfig() Config {
+	var cfg Config
+	raw, ok := internalsettings.DBConfigValue(internalsettings.ProxyHealthKey)
+	if ok && len(bytes.TrimSpace(raw)) > 0 {
+		if errUnmarshal := json.Unmarshal(raw, &cfg); errUnmarshal != nil {
+			log.WithError(errUnmarshal).Warn("proxy health: invalid setting")
+			cfg = Config{}
+		}
+	}
+	return cfg.withDefaults()
+}
+
+func (c Config) withDefaults() Config {
+	if c.IntervalSeconds <= 0 {
+		c.IntervalSeconds = defaultIntervalSeconds
+	}
+	if c.TimeoutSeconds <= 0 {
+		c.TimeoutSeconds = defaultTimeoutSeconds
+	}
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = defaultFailureThreshold
+	}
+	if c.WindowHours <= 0 {
+		c.WindowHours = defaultWindowHours
+	}
+	c.CheckURL = strings.TrimSpace(c.CheckURL)
+	if c.CheckURL == "" {
+		c.CheckURL = DefaultCheckURL
+	}
+	return c
+}
+
+// Window returns the reporting window.
+func (c Config) Window() time.Duration {
+	return time.Duration(c.withDefaults().WindowHours) * time.Hour
+}
+
+// Checker periodically checks the proxy pool.
+type Checker struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewChecker constructs a proxy health checker; returns nil when db is nil.
+func NewChecker(db *gorm.DB) *Checker {
+	if db == nil {
+		return nil
+	}
+	return &Checker{db: db, now: time.Now}
+}
+
+// Start launches the checking loop in a background goroutine.
+func (c *Checker) Start(ctx context.Context) {
+	if c == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go c.run(ctx)
+	log.Info("proxy health checker started")
+}
+
+func (c *Checker) run(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		cfg := LoadConfig()
+		wait := disabledRecheck
+		if cfg.Enabled {
+			wait = time.Duration(cfg.IntervalSeconds) * time.Second
+			if result, errRun := c.RunOnce(ctx, cfg); errRun != nil {
+				log.WithError(errRun).Warn("proxy health checker: run failed")
+			} else if result.Deactivated > 0 || result.Reactivated > 0 {
+				log.Infof("proxy health checker: checked %d, healthy %d, deactivated %d, reactivated %d proxies",
+					result.Checked, result.Healthy, result.Deactivated, result.Reactivated)
+			}
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			if !timer.Stop() {
+				<-timer.C
+			}
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// Result counts the proxies of one run.
+type Result struct {
+	Checked     int `json:"checked"`     // Proxies checked.
+	Healthy     int `json:"healthy"`     // Proxies that passed.
+	Deactivated int `json:"deactivated"` // Proxies marked inactive by this run.
+	Reactivated int `json:"reactivated"` // Inactive proxies that passed again.
+}
+
+// RunOnce checks every proxy once, updates its health state and prunes expired checks.
+func (c *Checker) RunOnce(ctx context.Context, cfg Config) (Result, error) {
+	var result Result
+	if c == nil || c.db == nil {
+		return result, nil
+	}
+	cfg = cfg.withDefaults()
+
+	var proxies []models.Proxy
+	if errFind := c.db.WithContext(ctx).Order("id ASC").Find(&proxies).Error; errFind != nil {
+		return result, fmt.Errorf("proxy health: list proxies: %w", errFind)
+	}
+
+	checks := make([]models.ProxyHealthCheck, len(proxies))
+	sem := make(chan struct{}, maxConcurrency)
+	var wg sync.WaitGroup
+	for i := range proxies {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return result, ctx.Err()
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			checks[i] = c.check(ctx, cfg, &proxies[i])
+		}(i)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return result, ctx.Err()
+	}
+
+	for i := range checks {
+		deactivated, reactivated, errRecord := c.record(ctx, cfg, checks[i])
+		if errRecord != nil {
+			return result, errRecord
+		}
+		result.Checked++
+		if checks[i].Success {
+			result.Healthy++
+		}
+		if deactivated {
+			result.Deactivated++
+			log.Warnf("proxy health checker: proxy %d marked inactive: %s", checks[i].ProxyID, checks[i].Error)
+		}
+		if reactivated {
+			result.Reactivated++
+		}
+	}
+
+	cutoff := c.now().Add(-retention).UTC()
+	if errPrune := c.db.WithContext(ctx).Where("checked_at < ?", cutoff).Delete(&models.ProxyHealthCheck{}).Error; errPrune != nil {
+		return result, fmt.Errorf("proxy health: prune checks: %w", errPrune)
+	}
+	return result, nil
+}
+
+// record stores a check and folds it into the proxy's health state.
+func (c *Checker) record(ctx context.Context, cfg Config, check models.ProxyHealthCheck) (deactivated, reactivated bool, err error) {
+	err = c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if errCreate := tx.Create(&check).Error; errCreate != nil {
+			return errCreate
+		}
+		var state models.ProxyHealth
+		found := tx.Where("proxy_id = ?", check.ProxyID).Limit(1).Find(&state)
+		if found.Error != nil {
+			return found.Error
+		}
+		if found.RowsAffected == 0 {
+			state = models.ProxyHealth{ProxyID: check.ProxyID, Active: true}
+		}
+		at := check.CheckedAt
+		state.CheckedAt = &at
+		state.LatencyMillis = check.LatencyMillis
+		if check.Success {
+			reactivated = !state.Active
+			state.Active = true
+			state.ConsecutiveFailures = 0
+			state.ExitIP = check.ExitIP
+			state.LastSuccessAt = &at
+			state.DeactivatedAt = nil
+		} else {
+			state.ConsecutiveFailures++
+			state.LastError = check.Error
+			if state.Active && state.ConsecutiveFailures >= cfg.FailureThreshold {
+				deactivated = true
+				state.Active = false
+				state.DeactivatedAt = &at
+			}
+		}
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&state).Error
+	})
+	if err != nil {
+		return false, false, fmt.Errorf("proxy health: record proxy %d: %w", check.ProxyID, err)
+	}
+	return deactivated, reactivated, nil
+}
+
+// check fetches the check URL through the proxy.
+func (c *Checker) check(ctx context.Context, cfg Config, proxy *models.Proxy) models.ProxyHealthCheck {
+	out := models.ProxyHealthCheck{ProxyID: proxy.ID}
+	started := c.now()
+	exitIP, errCheck := Check(ctx, proxy.ProxyURL, cfg.CheckURL, time.Duration(cfg.TimeoutSeconds)*time.Second)
+	out.LatencyMillis = c.now().Sub(started).Milliseconds()
+	out.CheckedAt = c.now().UTC()
+	if errCheck != nil {
+		out.Error = errCheck.Error()
+		return out
+	}
+	out.Success = true
+	out.ExitIP = exitIP
+	return out
+}
+
+// Check fetches checkURL through proxyURL and returns the exit IP it reports. The check URL
+// may answer with a bare IP or a JSON object holding it in "ip" or "origin".
+func Check(ctx context.Context, proxyURL, checkURL string, timeout time.Duration) (string, error) {
+	proxy, errProxy := url.Parse(strings.TrimSpace(proxyURL))
+	if errProxy != nil || proxy.Host == "" {
+		return "", fmt.Errorf("invalid proxy url")
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, errReq := http.NewRequestWithContext(reqCtx, http.MethodGet, checkURL, nil)
+	if errReq != nil {
+		return "", errReq
+	}
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxy), DisableKeepAlives: true}}
+	resp, errDo := client.Do(req)
+	if errDo != nil {
+		return "", errDo
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Errorf("proxy health checker: close response body error: %v", errClose)
+		}
+	}()
+	body, errRead := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+	if errRead != nil {
+		return "", errRead
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	exitIP := parseExitIP(body)
+	if exitIP == "" {
+		return "", fmt.Errorf("check url did not report an ip")
+	}
+	return exitIP, nil
+}
+
+// parseExitIP extracts an IP from a plain text or JSON echo response.
+func parseExitIP(body []byte) string {
+	body = bytes.TrimSpace(body)
+	var payload struct {
+		IP     string `json:"ip"`
+		Origin string `json:"origin"`
+	}
+	if json.Unmarshal(body, &payload) == nil {
+		body = []byte(payload.IP)
+		if payload.IP == "" {
+			// httpbin reports "client, proxy" chains; the first entry is the client.
+			body = []byte(strings.Split(payload.Origin, ",")[0])
+		}
+	}
+	candidate := strings.TrimSpace(string(body))
+	if net.ParseIP(candidate) == nil {
+		return ""
+	}
+	return candidate
+}
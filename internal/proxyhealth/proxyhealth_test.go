@@ -0,0 +1,115 @@
+package proxyhealth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func setupProxyHealthDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:proxyhealth_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func TestRunOnceDeactivatesAndReactivatesProxies(t *testing.T) {
+	conn := setupProxyHealthDB(t)
+	ctx := context.Background()
+
+	// The test servers act as forward proxies: they receive the absolute-form request for the
+	// check URL and answer it themselves.
+	var flakyDown atomic.Bool
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("203.0.113.7\n"))
+	}))
+	defer healthy.Close()
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if flakyDown.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"origin":"198.51.100.2, 10.0.0.1"}`))
+	}))
+	defer flaky.Close()
+
+	proxies := []models.Proxy{{ProxyURL: healthy.URL + "/"}, {ProxyURL: flaky.URL + "/"}}
+	if errCreate := conn.Create(&proxies).Error; errCreate != nil {
+		t.Fatalf("create proxies: %v", errCreate)
+	}
+
+	checker := NewChecker(conn)
+	cfg := Config{Enabled: true, FailureThreshold: 2, CheckURL: "http://ip.test/"}
+	flakyDown.Store(true)
+	for run, want := range []Result{
+		{Checked: 2, Healthy: 1},
+		{Checked: 2, Healthy: 1, Deactivated: 1},
+	} {
+		result, errRun := checker.RunOnce(ctx, cfg)
+		if errRun != nil {
+			t.Fatalf("run %d: %v", run, errRun)
+		}
+		if result != want {
+			t.Fatalf("run %d = %+v, want %+v", run, result, want)
+		}
+	}
+
+	statuses, errReport := Report(ctx, conn, time.Now(), time.Hour)
+	if errReport != nil {
+		t.Fatalf("Report: %v", errReport)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("expected two statuses, got %d", len(statuses))
+	}
+	if !statuses[0].Active || statuses[0].ExitIP != "203.0.113.7" || statuses[0].Checks != 2 || statuses[0].FailureRate != 0 {
+		t.Fatalf("unexpected healthy status: %+v", statuses[0])
+	}
+	if statuses[1].Active || statuses[1].ConsecutiveFailures != 2 || statuses[1].FailureRate != 1 || statuses[1].DeactivatedAt == nil {
+		t.Fatalf("unexpected dead status: %+v", statuses[1])
+	}
+
+	flakyDown.Store(false)
+	result, errRun := checker.RunOnce(ctx, cfg)
+	if errRun != nil {
+		t.Fatalf("recovery run: %v", errRun)
+	}
+	if result.Reactivated != 1 || result.Healthy != 2 {
+		t.Fatalf("recovery run = %+v", result)
+	}
+	var state models.ProxyHealth
+	if errFind := conn.First(&state, "proxy_id = ?", proxies[1].ID).Error; errFind != nil {
+		t.Fatalf("load state: %v", errFind)
+	}
+	if !state.Active || state.ExitIP != "198.51.100.2" || state.DeactivatedAt != nil {
+		t.Fatalf("unexpected recovered state: %+v", state)
+	}
+}
+
+func TestParseExitIP(t *testing.T) {
+	for body, want := range map[string]string{
+		"203.0.113.7\n":              "203.0.113.7",
+		`{"ip":"2001:db8::1"}`:       "2001:db8::1",
+		`{"origin":"198.51.100.2"}`:  "198.51.100.2",
+		"<html>blocked</html>":       "",
+		`{"message":"rate limited"}`: "",
+	} {
+		if got := parseExitIP([]byte(body)); got != want {
+			t.Fatalf("parseExitIP(%q) = %q, want %q", body, got, want)
+		}
+	}
+}
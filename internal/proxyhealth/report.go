@@ -0,0 +1,99 @@
+package proxyhealth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// Status is the health of one proxy for the admin report. Proxies never checked are
+// reported active with zero checks.
+type Status struct {
+	ProxyID             uint64     `json:"proxy_id"`
+	ProxyURL            string     `json:"proxy_url"`
+	Active              bool       `json:"active"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LatencyMillis       int64      `json:"latency_ms"`     // Latency of the last check.
+	AvgLatencyMillis    int64      `json:"avg_latency_ms"` // Mean latency of successful checks in the window.
+	ExitIP              string     `json:"exit_ip"`
+	LastError           string     `json:"last_error,omitempty"`
+	Checks              int64      `json:"checks"`       // Checks in the window.
+	Failures            int64      `json:"failures"`     // Failed checks in the window.
+	FailureRate         float64    `json:"failure_rate"` // Failures over checks in the window, 0 to 1.
+	CheckedAt           *time.Time `json:"checked_at"`
+	LastSuccessAt       *time.Time `json:"last_success_at"`
+	DeactivatedAt       *time.Time `json:"deactivated_at"`
+}
+
+// windowStats aggregates the checks of one proxy.
+type windowStats struct {
+	ProxyID    uint64
+	Checks     int64
+	Failures   int64
+	LatencySum int64
+	Successes  int64
+}
+
+// Report returns the health of every proxy, ordered by ID, with failure rate and latency
+// computed over the checks since now minus window.
+func Report(ctx context.Context, db *gorm.DB, now time.Time, window time.Duration) ([]Status, error) {
+	if db == nil {
+		return nil, fmt.Errorf("proxy health: nil db")
+	}
+	var proxies []models.Proxy
+	if errFind := db.WithContext(ctx).Order("id ASC").Find(&proxies).Error; errFind != nil {
+		return nil, fmt.Errorf("proxy health: list proxies: %w", errFind)
+	}
+	var states []models.ProxyHealth
+	if errFind := db.WithContext(ctx).Find(&states).Error; errFind != nil {
+		return nil, fmt.Errorf("proxy health: list states: %w", errFind)
+	}
+	var stats []windowStats
+	if errScan := db.WithContext(ctx).Model(&models.ProxyHealthCheck{}).
+		Select("proxy_id, COUNT(*) AS checks, "+
+			"COALESCE(SUM(CASE WHEN success THEN 0 ELSE 1 END), 0) AS failures, "+
+			"COALESCE(SUM(CASE WHEN success THEN 1 ELSE 0 END), 0) AS successes, "+
+			"COALESCE(SUM(CASE WHEN success THEN latency_millis ELSE 0 END), 0) AS latency_sum").
+		Where("checked_at >= ?", now.Add(-window).UTC()).
+		Group("proxy_id").
+		Scan(&stats).Error; errScan != nil {
+		return nil, fmt.Errorf("proxy health: aggregate checks: %w", errScan)
+	}
+
+	stateByID := make(map[uint64]models.ProxyHealth, len(states))
+	for _, state := range states {
+		stateByID[state.ProxyID] = state
+	}
+	statsByID := make(map[uint64]windowStats, len(stats))
+	for _, row := range stats {
+		statsByID[row.ProxyID] = row
+	}
+
+	out := make([]Status, 0, len(proxies))
+	for _, proxy := range proxies {
+		status := Status{ProxyID: proxy.ID, ProxyURL: proxy.ProxyURL, Active: true}
+		if state, ok := stateByID[proxy.ID]; ok {
+			status.Active = state.Active
+			status.ConsecutiveFailures = state.ConsecutiveFailures
+			status.LatencyMillis = state.LatencyMillis
+			status.ExitIP = state.ExitIP
+			status.LastError = state.LastError
+			status.CheckedAt = state.CheckedAt
+			status.LastSuccessAt = state.LastSuccessAt
+			status.DeactivatedAt = state.DeactivatedAt
+		}
+		if row, ok := statsByID[proxy.ID]; ok && row.Checks > 0 {
+			status.Checks = row.Checks
+			status.Failures = row.Failures
+			status.FailureRate = float64(row.Failures) / float64(row.Checks)
+			if row.Successes > 0 {
+				status.AvgLatencyMillis = row.LatencySum / row.Successes
+			}
+		}
+		out = append(out, status)
+	}
+	return out, nil
+}
@@ -0,0 +1,43 @@
+// Package proxyref derives a stable, credential-free reference to a proxy URL so usage rows
+// can be attributed to the proxy that served them without storing proxy passwords.
+package proxyref
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+)
+
+// hashLength is the number of hex characters kept from the SHA-256 of a proxy URL.
+const hashLength = 16
+
+// Normalize trims a proxy URL and drops a trailing slash so equivalent spellings match.
+func Normalize(raw string) string {
+	return strings.TrimRight(strings.TrimSpace(raw), "/")
+}
+
+// Hash returns a short fingerprint of the proxy URL including its credentials, so two
+// accounts on the same proxy host are told apart; empty for an empty URL.
+func Hash(raw string) string {
+	normalized := Normalize(raw)
+	if normalized == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])[:hashLength]
+}
+
+// Label returns the scheme, host and port of the proxy URL with credentials stripped, or
+// "invalid" when the URL cannot be parsed.
+func Label(raw string) string {
+	normalized := Normalize(raw)
+	if normalized == "" {
+		return ""
+	}
+	parsed, errParse := url.Parse(normalized)
+	if errParse != nil || parsed.Host == "" {
+		return "invalid"
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
@@ -0,0 +1,213 @@
+package quota
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NormalizedQuota holds the provider-independent fields extracted from a raw quota payload.
+// Nil fields mean the provider did not report the value.
+type NormalizedQuota struct {
+	Plan              string     // Subscription plan, e.g. "plus".
+	RemainingRequests *int64     // Remaining request allowance.
+	RemainingTokens   *int64     // Remaining token allowance.
+	RemainingFraction *float64   // Remaining share (0..1) of the tightest limit.
+	ResetAt           *time.Time // When the tightest limit resets.
+}
+
+// QuotaNormalizer is optionally implemented by a QuotaProvider to extract common fields from its payload.
+type QuotaNormalizer interface {
+	NormalizeQuota(payload []byte) NormalizedQuota
+}
+
+// NormalizeQuota extracts common fields from a payload using the registered provider's normalizer.
+// Unknown providers and malformed payloads yield an empty NormalizedQuota.
+func NormalizeQuota(provider string, payload []byte) NormalizedQuota {
+	p, ok := lookupProvider(provider)
+	if !ok {
+		return NormalizedQuota{}
+	}
+	normalizer, ok := p.(QuotaNormalizer)
+	if !ok {
+		return NormalizedQuota{}
+	}
+	return normalizer.NormalizeQuota(payload)
+}
+
+// columns returns the quota table updates for the normalized fields.
+func (n NormalizedQuota) columns() map[string]any {
+	return map[string]any{
+		"plan":               n.Plan,
+		"remaining_requests": n.RemainingRequests,
+		"remaining_tokens":   n.RemainingTokens,
+		"remaining_fraction": n.RemainingFraction,
+		"reset_at":           n.ResetAt,
+	}
+}
+
+// considerWindow keeps the limit with the lowest remaining fraction.
+func (n *NormalizedQuota) considerWindow(fraction float64, resetAt *time.Time) {
+	fraction = math.Max(0, math.Min(1, fraction))
+	if n.RemainingFraction != nil && *n.RemainingFraction <= fraction {
+		return
+	}
+	n.RemainingFraction = &fraction
+	n.ResetAt = resetAt
+}
+
+// NormalizeQuota extracts the tightest model bucket from fetchAvailableModels.
+func (antigravityProvider) NormalizeQuota(payload []byte) NormalizedQuota {
+	var out NormalizedQuota
+	root := decodeQuotaPayload(payload)
+	for _, value := range mapFromAny(root["models"]) {
+		info := mapFromAny(mapFromAny(value)["quotaInfo"])
+		if fraction, ok := numberFromAny(info["remainingFraction"]); ok {
+			out.considerWindow(fraction, timeFromAny(info["resetTime"]))
+		}
+	}
+	return out
+}
+
+// NormalizeQuota extracts the plan and the most used rate limit window from wham/usage.
+func (codexProvider) NormalizeQuota(payload []byte) NormalizedQuota {
+	root := decodeQuotaPayload(payload)
+	out := NormalizedQuota{Plan: normalizeString(root["plan_type"])}
+	rateLimit := mapFromAny(root["rate_limit"])
+	for _, key := range []string{"primary_window", "secondary_window"} {
+		window := mapFromAny(rateLimit[key])
+		if usedPercent, ok := numberFromAny(window["used_percent"]); ok {
+			out.considerWindow(1-usedPercent/100, timeFromAny(window["reset_at"]))
+		}
+	}
+	return out
+}
+
+// NormalizeQuota extracts the tightest bucket from retrieveUserQuota.
+func (geminiCLIProvider) NormalizeQuota(payload []byte) NormalizedQuota {
+	var out NormalizedQuota
+	root := decodeQuotaPayload(payload)
+	buckets, _ := root["buckets"].([]any)
+	for _, value := range buckets {
+		bucket := mapFromAny(value)
+		fraction, ok := numberFromAny(bucket["remainingFraction"])
+		if !ok {
+			continue
+		}
+		previous := out.RemainingFraction
+		out.considerWindow(fraction, timeFromAny(bucket["resetTime"]))
+		if out.RemainingFraction == previous {
+			continue
+		}
+		out.RemainingRequests, out.RemainingTokens = nil, nil
+		if amount, okAmount := int64FromAny(bucket["remainingAmount"]); okAmount {
+			if strings.EqualFold(normalizeString(bucket["tokenType"]), "REQUESTS") {
+				out.RemainingRequests = &amount
+			} else {
+				out.RemainingTokens = &amount
+			}
+		}
+	}
+	return out
+}
+
+// NormalizeQuota extracts the plan and premium interaction allowance from copilot_internal/user.
+func (copilotProvider) NormalizeQuota(payload []byte) NormalizedQuota {
+	root := decodeQuotaPayload(payload)
+	out := NormalizedQuota{Plan: normalizeString(root["copilot_plan"])}
+	resetAt := timeFromAny(root["quota_reset_date_utc"])
+	if resetAt == nil {
+		resetAt = timeFromAny(root["quota_reset_date"])
+	}
+	snapshots := mapFromAny(root["quota_snapshots"])
+	for _, key := range []string{"premium_interactions", "chat", "completions"} {
+		snapshot := mapFromAny(snapshots[key])
+		if normalizeBool(snapshot["unlimited"]) {
+			continue
+		}
+		percent, ok := numberFromAny(snapshot["percent_remaining"])
+		if !ok {
+			continue
+		}
+		previous := out.RemainingFraction
+		out.considerWindow(percent/100, resetAt)
+		if out.RemainingFraction == previous {
+			continue
+		}
+		out.RemainingRequests = nil
+		if remaining, okRemaining := int64FromAny(snapshot["remaining"]); okRemaining {
+			out.RemainingRequests = &remaining
+		}
+	}
+	return out
+}
+
+// NormalizeQuota extracts the most used usage window from api/oauth/usage.
+func (claudeProvider) NormalizeQuota(payload []byte) NormalizedQuota {
+	var out NormalizedQuota
+	root := decodeQuotaPayload(payload)
+	for _, key := range []string{"five_hour", "seven_day", "seven_day_opus", "seven_day_sonnet"} {
+		window := mapFromAny(root[key])
+		if utilization, ok := numberFromAny(window["utilization"]); ok {
+			out.considerWindow(1-utilization/100, timeFromAny(window["resets_at"]))
+		}
+	}
+	return out
+}
+
+func decodeQuotaPayload(payload []byte) map[string]any {
+	var root map[string]any
+	if errUnmarshal := json.Unmarshal(payload, &root); errUnmarshal != nil {
+		return nil
+	}
+	return root
+}
+
+func numberFromAny(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		parsed, errParse := v.Float64()
+		return parsed, errParse == nil
+	case string:
+		parsed, errParse := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return parsed, errParse == nil
+	default:
+		return 0, false
+	}
+}
+
+func int64FromAny(value any) (int64, bool) {
+	parsed, ok := numberFromAny(value)
+	if !ok || math.IsNaN(parsed) || math.IsInf(parsed, 0) {
+		return 0, false
+	}
+	return int64(parsed), true
+}
+
+// timeFromAny parses RFC 3339 strings, plain dates and unix seconds.
+func timeFromAny(value any) *time.Time {
+	var parsed time.Time
+	switch v := value.(type) {
+	case float64:
+		if v <= 0 {
+			return nil
+		}
+		parsed = time.Unix(int64(v), 0)
+	case string:
+		v = strings.TrimSpace(v)
+		var errParse error
+		if parsed, errParse = time.Parse(time.RFC3339Nano, v); errParse != nil {
+			if parsed, errParse = time.Parse(time.DateOnly, v); errParse != nil {
+				return nil
+			}
+		}
+	default:
+		return nil
+	}
+	parsed = parsed.UTC()
+	return &parsed
+}
@@ -0,0 +1,60 @@
+package quota
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeQuotaCodexPicksMostUsedWindow(t *testing.T) {
+	payload := []byte(`{"plan_type":"plus","rate_limit":{"primary_window":{"used_percent":25,"reset_at":1767240000},"secondary_window":{"used_percent":80,"reset_at":1767830400}}}`)
+
+	got := NormalizeQuota("codex", payload)
+	if got.Plan != "plus" {
+		t.Fatalf("expected plan plus, got %q", got.Plan)
+	}
+	if got.RemainingFraction == nil || *got.RemainingFraction < 0.199 || *got.RemainingFraction > 0.201 {
+		t.Fatalf("expected remaining fraction 0.2, got %v", got.RemainingFraction)
+	}
+	if got.ResetAt == nil || !got.ResetAt.Equal(time.Unix(1767830400, 0)) {
+		t.Fatalf("expected secondary window reset, got %v", got.ResetAt)
+	}
+}
+
+func TestNormalizeQuotaGeminiCLIKeepsTightestBucketAmount(t *testing.T) {
+	payload := []byte(`{"buckets":[{"remainingFraction":0.9,"remainingAmount":"900","tokenType":"REQUESTS","resetTime":"2026-01-02T00:00:00Z"},{"remainingFraction":0.1,"remainingAmount":"5000","tokenType":"TOKENS","resetTime":"2026-01-01T12:00:00Z"}]}`)
+
+	got := NormalizeQuota("gemini-cli", payload)
+	if got.RemainingTokens == nil || *got.RemainingTokens != 5000 {
+		t.Fatalf("expected remaining tokens 5000, got %v", got.RemainingTokens)
+	}
+	if got.RemainingRequests != nil {
+		t.Fatalf("expected remaining requests nil, got %v", *got.RemainingRequests)
+	}
+	want := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	if got.ResetAt == nil || !got.ResetAt.Equal(want) {
+		t.Fatalf("expected reset %s, got %v", want, got.ResetAt)
+	}
+}
+
+func TestNormalizeQuotaCopilotSkipsUnlimitedSnapshots(t *testing.T) {
+	payload := []byte(`{"copilot_plan":"individual","quota_reset_date":"2026-02-01","quota_snapshots":{"chat":{"unlimited":true,"percent_remaining":100},"premium_interactions":{"remaining":231,"entitlement":300,"percent_remaining":77}}}`)
+
+	got := NormalizeQuota("github-copilot", payload)
+	if got.Plan != "individual" {
+		t.Fatalf("expected plan individual, got %q", got.Plan)
+	}
+	if got.RemainingRequests == nil || *got.RemainingRequests != 231 {
+		t.Fatalf("expected remaining requests 231, got %v", got.RemainingRequests)
+	}
+	want := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	if got.ResetAt == nil || !got.ResetAt.Equal(want) {
+		t.Fatalf("expected reset %s, got %v", want, got.ResetAt)
+	}
+}
+
+func TestNormalizeQuotaUnknownProviderIsEmpty(t *testing.T) {
+	got := NormalizeQuota("unknown", []byte(`{"plan":"x"}`))
+	if got.Plan != "" || got.RemainingFraction != nil || got.ResetAt != nil {
+		t.Fatalf("expected empty normalization, got %+v", got)
+	}
+}
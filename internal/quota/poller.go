@@ -12,8 +12,12 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/healthprobe"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/metrics"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
 
@@ -21,6 +25,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 const (
@@ -28,6 +33,7 @@ const (
 	defaultRequestTimeout = 20 * time.Second
 	maxConcurrentRequests = 5
 	noAuthRetryInterval   = 10 * time.Second
+	minPollWakeInterval   = 10 * time.Second
 	maxErrorBodyBytes     = 512
 )
 
@@ -35,6 +41,8 @@ const (
 	antigravityUserAgent = "antigravity/1.11.5 windows/amd64"
 	codexUserAgent       = "codex_cli_rs/0.76.0 (Debian 13.0.0; x86_64) WindowsTerminal"
 	copilotUserAgent     = "GitHubCopilotChat/0.38.2"
+	claudeUserAgent      = "claude-cli/2.0.14 (external, cli)"
+	claudeOAuthBeta      = "oauth-2025-04-20"
 )
 
 var (
@@ -46,6 +54,7 @@ var (
 	geminiCLIQuotaURL = "https://cloudcode-pa.googleapis.com/v1internal:retrieveUserQuota"
 	codexUsageURL     = "https://chatgpt.com/backend-api/wham/usage"
 	copilotUserURL    = "https://api.github.com/copilot_internal/user"
+	claudeUsageURL    = "https://api.anthropic.com/api/oauth/usage"
 
 	ErrUnsupportedProvider = errors.New("quota poller: unsupported provider")
 )
@@ -91,9 +100,11 @@ func (e *providerRequestError) Provider() string {
 }
 
 type authRowInfo struct {
-	ID          uint64
-	Type        string
-	RuntimeOnly bool
+	ID           uint64
+	Type         string
+	RuntimeOnly  bool
+	PollEnabled  bool          // False when the auth opted out of scheduled polling.
+	PollInterval time.Duration // Per-auth override; zero uses the global interval.
 }
 
 // Poller periodically fetches quota data for stored auth entries.
@@ -103,6 +114,9 @@ type Poller struct {
 	interval       time.Duration
 	requestTimeout time.Duration
 	hadAuths       bool
+	lastPolled     map[string]time.Time // Last scheduled poll per auth key; only touched by the poll loop.
+	started        atomic.Bool          // Set once Start launched the loop.
+	done           chan struct{}        // Closed when the loop returns.
 }
 
 // NewPoller constructs a quota poller.
@@ -115,6 +129,8 @@ func NewPoller(db *gorm.DB, manager *coreauth.Manager) *Poller {
 		manager:        manager,
 		interval:       defaultPollInterval,
 		requestTimeout: defaultRequestTimeout,
+		lastPolled:     make(map[string]time.Time),
+		done:           make(chan struct{}),
 	}
 }
 
@@ -126,11 +142,29 @@ func (p *Poller) Start(ctx context.Context) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	if !p.started.CompareAndSwap(false, true) {
+		return
+	}
 	go p.run(ctx)
 	log.Infof("quota poller started (interval=%s)", p.interval)
 }
 
+// Wait blocks until the loop started by Start has returned after its context was cancelled,
+// so no quota write is cut off mid-poll. It returns ctx.Err() when ctx expires first.
+func (p *Poller) Wait(ctx context.Context) error {
+	if p == nil || !p.started.Load() {
+		return nil
+	}
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (p *Poller) run(ctx context.Context) {
+	defer close(p.done)
 	for {
 		if ctx != nil && ctx.Err() != nil {
 			return
@@ -182,6 +216,13 @@ func (p *Poller) poll(ctx context.Context) time.Duration {
 		return interval
 	}
 
+	if p.lastPolled == nil {
+		p.lastPolled = make(map[string]time.Time)
+	}
+	now := time.Now()
+	nextWake := interval
+	seen := make(map[string]struct{}, len(auths))
+
 	sem := make(chan struct{}, maxConcurrency)
 	var wg sync.WaitGroup
 	shouldStop := false
@@ -194,7 +235,7 @@ func (p *Poller) poll(ctx context.Context) time.Duration {
 			continue
 		}
 		row, ok := rowMap[auth.ID]
-		if !ok || row.RuntimeOnly {
+		if !ok || row.RuntimeOnly || !row.PollEnabled {
 			continue
 		}
 
@@ -202,7 +243,20 @@ func (p *Poller) poll(ctx context.Context) time.Duration {
 		if provider == "" {
 			provider = strings.ToLower(strings.TrimSpace(row.Type))
 		}
-		if provider != "antigravity" && provider != "codex" && provider != "gemini-cli" && provider != "github-copilot" {
+		if _, ok := lookupProvider(provider); !ok {
+			continue
+		}
+
+		seen[auth.ID] = struct{}{}
+		every := interval
+		if row.PollInterval > 0 {
+			every = row.PollInterval
+		}
+		due, wait := pollDue(p.lastPolled[auth.ID], every, now)
+		if wait < nextWake {
+			nextWake = wait
+		}
+		if !due {
 			continue
 		}
 
@@ -216,6 +270,7 @@ func (p *Poller) poll(ctx context.Context) time.Duration {
 			break
 		}
 
+		p.lastPolled[auth.ID] = now
 		wg.Add(1)
 		authCopy := auth
 		rowCopy := row
@@ -229,7 +284,35 @@ func (p *Poller) poll(ctx context.Context) time.Duration {
 	}
 
 	wg.Wait()
-	return interval
+	for key := range p.lastPolled {
+		if _, ok := seen[key]; !ok {
+			delete(p.lastPolled, key)
+		}
+	}
+	if nextWake < minPollWakeInterval {
+		nextWake = minPollWakeInterval
+	}
+	return nextWake
+}
+
+// pollDue reports whether an auth last polled at last is due at now, and how long until its next poll.
+func pollDue(last time.Time, every time.Duration, now time.Time) (bool, time.Duration) {
+	if last.IsZero() {
+		return true, every
+	}
+	elapsed := now.Sub(last)
+	if elapsed >= every {
+		return true, every
+	}
+	return false, every - elapsed
+}
+
+// pollIntervalOverride converts the optional per-auth interval column into a duration.
+func pollIntervalOverride(seconds *int) time.Duration {
+	if seconds == nil || *seconds <= 0 {
+		return 0
+	}
+	return time.Duration(*seconds) * time.Second
 }
 
 func (p *Poller) refreshAuth(ctx context.Context, auth *coreauth.Auth, row authRowInfo) error {
@@ -248,20 +331,33 @@ func (p *Poller) refreshAuth(ctx context.Context, auth *coreauth.Auth, row authR
 		provider = strings.ToLower(strings.TrimSpace(row.Type))
 	}
 
-	var errRefresh error
-	switch provider {
-	case "antigravity":
-		errRefresh = p.pollAntigravity(ctx, auth, row)
-	case "codex":
-		errRefresh = p.pollCodex(ctx, auth, row)
-	case "gemini-cli":
-		errRefresh = p.pollGeminiCLI(ctx, auth, row)
-	case "github-copilot":
-		errRefresh = p.pollCopilot(ctx, auth, row)
-	default:
+	quotaProvider, ok := lookupProvider(provider)
+	if !ok {
 		return ErrUnsupportedProvider
 	}
 
+	started := time.Now()
+	payload, errRefresh := quotaProvider.FetchQuota(ctx, p.doRequest, auth)
+	metrics.ObserveQuotaPoll(provider, errRefresh)
+	p.recordHealthCheck(ctx, provider, row.ID, time.Since(started), errRefresh)
+	if errRefresh == nil {
+		authType := strings.TrimSpace(row.Type)
+		if authType == "" {
+			authType = provider
+		}
+		var normalized NormalizedQuota
+		if normalizer, okNormalizer := quotaProvider.(QuotaNormalizer); okNormalizer {
+			normalized = normalizer.NormalizeQuota(payload)
+		}
+		previous, errSave := p.saveQuota(ctx, row.ID, authType, payload, normalized)
+		if errSave != nil {
+			log.WithError(errSave).Warnf("quota poller: %s save failed (auth=%s)", provider, auth.ID)
+			errRefresh = errSave
+		} else if threshold := resolveQuotaLowThreshold(); crossedQuotaLow(previous, normalized.RemainingFraction, threshold) {
+			publishQuotaLow(ctx, provider, row.ID, auth.ID, *normalized.RemainingFraction, threshold, normalized.ResetAt)
+		}
+	}
+
 	if row.ID != 0 {
 		if errHealth := p.updateAuthHealth(ctx, row.ID, errRefresh); errHealth != nil {
 			log.WithError(errHealth).Warnf("quota poller: update auth health failed (auth=%s)", auth.ID)
@@ -329,8 +425,8 @@ func (p *Poller) loadAuthRowByKey(ctx context.Context, authKey string) (authRowI
 
 	var row models.Auth
 	errFind := p.db.WithContext(ctx).
-		Select("id", "key", "content").
-		Where("key = ?", authKey).
+		Select("id", "key", "content", "quota_poll_interval_seconds", "poll_enabled").
+		Where("? = ?", clause.Column{Name: "key"}, authKey).
 		First(&row).Error
 	if errors.Is(errFind, gorm.ErrRecordNotFound) {
 		return authRowInfo{}, false, nil
@@ -341,9 +437,11 @@ func (p *Poller) loadAuthRowByKey(ctx context.Context, authKey string) (authRowI
 
 	metadata := parseMetadata(row.Content)
 	return authRowInfo{
-		ID:          row.ID,
-		Type:        normalizeString(metadata["type"]),
-		RuntimeOnly: isRuntimeOnly(metadata),
+		ID:           row.ID,
+		Type:         normalizeString(metadata["type"]),
+		RuntimeOnly:  isRuntimeOnly(metadata),
+		PollEnabled:  row.PollEnabled,
+		PollInterval: pollIntervalOverride(row.QuotaPollIntervalSeconds),
 	}, true, nil
 }
 
@@ -357,7 +455,7 @@ func (p *Poller) loadAuthRows(ctx context.Context) (map[string]authRowInfo, erro
 
 	var rows []models.Auth
 	if errFind := p.db.WithContext(ctx).
-		Select("id", "key", "content").
+		Select("id", "key", "content", "quota_poll_interval_seconds", "poll_enabled").
 		Order("id ASC").
 		Find(&rows).Error; errFind != nil {
 		return nil, errFind
@@ -367,9 +465,11 @@ func (p *Poller) loadAuthRows(ctx context.Context) (map[string]authRowInfo, erro
 	for _, row := range rows {
 		metadata := parseMetadata(row.Content)
 		rowMap[row.Key] = authRowInfo{
-			ID:          row.ID,
-			Type:        normalizeString(metadata["type"]),
-			RuntimeOnly: isRuntimeOnly(metadata),
+			ID:           row.ID,
+			Type:         normalizeString(metadata["type"]),
+			RuntimeOnly:  isRuntimeOnly(metadata),
+			PollEnabled:  row.PollEnabled,
+			PollInterval: pollIntervalOverride(row.QuotaPollIntervalSeconds),
 		}
 	}
 	return rowMap, nil
@@ -395,148 +495,6 @@ func (p *Poller) resolvePollConfig() (time.Duration, int) {
 	return time.Duration(intervalSeconds) * time.Second, maxConcurrency
 }
 
-func (p *Poller) pollAntigravity(ctx context.Context, auth *coreauth.Auth, row authRowInfo) error {
-	headers := http.Header{}
-	headers.Set("Content-Type", "application/json")
-	headers.Set("User-Agent", antigravityUserAgent)
-	body := []byte("{}")
-	var lastErr error
-
-	for _, url := range antigravityQuotaURLs {
-		status, payload, errReq := p.doRequest(ctx, auth, http.MethodPost, url, body, headers)
-		if errReq != nil {
-			log.WithError(errReq).Warnf("quota poller: antigravity request failed (auth=%s)", auth.ID)
-			lastErr = errReq
-			continue
-		}
-		if status < http.StatusOK || status >= http.StatusMultipleChoices {
-			log.Warnf("quota poller: antigravity status=%d (auth=%s body=%s)", status, auth.ID, summarizePayload(payload))
-			lastErr = &providerRequestError{
-				provider:   "antigravity",
-				statusCode: status,
-				err:        fmt.Errorf("quota poller: antigravity non-2xx status=%d", status),
-			}
-			continue
-		}
-		if errSave := p.saveQuota(ctx, row.ID, row.Type, payload); errSave != nil {
-			log.WithError(errSave).Warnf("quota poller: antigravity save failed (auth=%s)", auth.ID)
-			return errSave
-		}
-		return nil
-	}
-	if lastErr == nil {
-		lastErr = errors.New("quota poller: antigravity refresh failed")
-	}
-	return lastErr
-}
-
-func (p *Poller) pollCodex(ctx context.Context, auth *coreauth.Auth, row authRowInfo) error {
-	metadata := auth.Metadata
-	accountID := resolveCodexAccountID(metadata)
-	if accountID == "" {
-		log.Warnf("quota poller: codex missing account id (auth=%s)", auth.ID)
-		return errors.New("quota poller: codex missing account id")
-	}
-
-	headers := http.Header{}
-	headers.Set("Content-Type", "application/json")
-	headers.Set("User-Agent", codexUserAgent)
-	headers.Set("Chatgpt-Account-Id", accountID)
-
-	status, payload, errReq := p.doRequest(ctx, auth, http.MethodGet, codexUsageURL, nil, headers)
-	if errReq != nil {
-		log.WithError(errReq).Warnf("quota poller: codex request failed (auth=%s)", auth.ID)
-		return errReq
-	}
-	if status < http.StatusOK || status >= http.StatusMultipleChoices {
-		log.Warnf("quota poller: codex status=%d (auth=%s body=%s)", status, auth.ID, summarizePayload(payload))
-		return &providerRequestError{
-			provider:   "codex",
-			statusCode: status,
-			err:        fmt.Errorf("quota poller: codex non-2xx status=%d", status),
-		}
-	}
-	if errSave := p.saveQuota(ctx, row.ID, row.Type, payload); errSave != nil {
-		log.WithError(errSave).Warnf("quota poller: codex save failed (auth=%s)", auth.ID)
-		return errSave
-	}
-	return nil
-}
-
-func (p *Poller) pollGeminiCLI(ctx context.Context, auth *coreauth.Auth, row authRowInfo) error {
-	metadata := auth.Metadata
-	projectID := resolveGeminiProjectID(metadata)
-	if projectID == "" {
-		log.Warnf("quota poller: gemini-cli missing project id (auth=%s)", auth.ID)
-		return errors.New("quota poller: gemini-cli missing project id")
-	}
-
-	headers := http.Header{}
-	headers.Set("Content-Type", "application/json")
-	body, errMarshal := json.Marshal(map[string]string{"project": projectID})
-	if errMarshal != nil {
-		log.WithError(errMarshal).Warnf("quota poller: gemini-cli request body failed (auth=%s)", auth.ID)
-		return errMarshal
-	}
-
-	status, payload, errReq := p.doRequest(ctx, auth, http.MethodPost, geminiCLIQuotaURL, body, headers)
-	if errReq != nil {
-		log.WithError(errReq).Warnf("quota poller: gemini-cli request failed (auth=%s)", auth.ID)
-		return errReq
-	}
-	if status < http.StatusOK || status >= http.StatusMultipleChoices {
-		log.Warnf("quota poller: gemini-cli status=%d (auth=%s body=%s)", status, auth.ID, summarizePayload(payload))
-		return &providerRequestError{
-			provider:   "gemini-cli",
-			statusCode: status,
-			err:        fmt.Errorf("quota poller: gemini-cli non-2xx status=%d", status),
-		}
-	}
-	if errSave := p.saveQuota(ctx, row.ID, row.Type, payload); errSave != nil {
-		log.WithError(errSave).Warnf("quota poller: gemini-cli save failed (auth=%s)", auth.ID)
-		return errSave
-	}
-	return nil
-}
-
-func (p *Poller) pollCopilot(ctx context.Context, auth *coreauth.Auth, row authRowInfo) error {
-	metadata := auth.Metadata
-	accessToken := resolveCopilotAccessToken(metadata)
-	if accessToken == "" {
-		log.Warnf("quota poller: github-copilot missing access token (auth=%s)", auth.ID)
-		return errors.New("quota poller: github-copilot missing access token")
-	}
-
-	headers := http.Header{}
-	headers.Set("Accept", "application/json")
-	headers.Set("Authorization", "Bearer "+accessToken)
-	headers.Set("User-Agent", copilotUserAgent)
-
-	status, payload, errReq := p.doRequest(ctx, auth, http.MethodGet, copilotUserURL, nil, headers)
-	if errReq != nil {
-		log.WithError(errReq).Warnf("quota poller: github-copilot request failed (auth=%s)", auth.ID)
-		return errReq
-	}
-	if status < http.StatusOK || status >= http.StatusMultipleChoices {
-		log.Warnf("quota poller: github-copilot status=%d (auth=%s body=%s)", status, auth.ID, summarizePayload(payload))
-		return &providerRequestError{
-			provider:   "github-copilot",
-			statusCode: status,
-			err:        fmt.Errorf("quota poller: github-copilot non-2xx status=%d", status),
-		}
-	}
-
-	authType := strings.TrimSpace(row.Type)
-	if authType == "" {
-		authType = "github-copilot"
-	}
-	if errSave := p.saveQuota(ctx, row.ID, authType, payload); errSave != nil {
-		log.WithError(errSave).Warnf("quota poller: github-copilot save failed (auth=%s)", auth.ID)
-		return errSave
-	}
-	return nil
-}
-
 func (p *Poller) doRequest(ctx context.Context, auth *coreauth.Auth, method, targetURL string, body []byte, headers http.Header) (int, []byte, error) {
 	if p == nil || p.manager == nil {
 		return 0, nil, errors.New("quota poller: manager not initialized")
@@ -570,9 +528,32 @@ func (p *Poller) doRequest(ctx context.Context, auth *coreauth.Auth, method, tar
 	return resp.StatusCode, payload, nil
 }
 
-func (p *Poller) saveQuota(ctx context.Context, authID uint64, authType string, payload []byte) error {
+// recordHealthCheck stores the quota request outcome as a provider health sample.
+func (p *Poller) recordHealthCheck(ctx context.Context, provider string, authID uint64, latency time.Duration, errRefresh error) {
 	if p == nil || p.db == nil {
-		return errors.New("quota poller: db not initialized")
+		return
+	}
+	check := models.ProviderHealthCheck{
+		Provider:      provider,
+		Source:        healthprobe.SourceAuth,
+		TargetID:      authID,
+		Success:       errRefresh == nil,
+		LatencyMillis: latency.Milliseconds(),
+		CheckedAt:     time.Now().UTC(),
+	}
+	if errRefresh != nil {
+		check.Error = errRefresh.Error()
+	}
+	if errRecord := healthprobe.Record(ctx, p.db, check); errRecord != nil {
+		log.WithError(errRecord).Warnf("quota poller: record health check failed (provider=%s)", provider)
+	}
+}
+
+// saveQuota upserts the quota row of an auth and returns the remaining fraction it held before,
+// or nil when the row is new or had none.
+func (p *Poller) saveQuota(ctx context.Context, authID uint64, authType string, payload []byte, normalized NormalizedQuota) (*float64, error) {
+	if p == nil || p.db == nil {
+		return nil, errors.New("quota poller: db not initialized")
 	}
 	if ctx == nil {
 		ctx = context.Background()
@@ -585,7 +566,7 @@ func (p *Poller) saveQuota(ctx context.Context, authID uint64, authType string,
 
 	payload = normalizePayload(payload)
 	if len(payload) == 0 {
-		return errors.New("quota poller: empty payload")
+		return nil, errors.New("quota poller: empty payload")
 	}
 
 	now := time.Now().UTC()
@@ -594,25 +575,77 @@ func (p *Poller) saveQuota(ctx context.Context, authID uint64, authType string,
 		Where("auth_id = ? AND type = ?", authID, authType).
 		First(&existing).Error
 	if errFind == nil {
-		return p.db.WithContext(ctx).
+		updates := normalized.columns()
+		updates["data"] = datatypes.JSON(payload)
+		updates["updated_at"] = now
+		return existing.RemainingFraction, p.db.WithContext(ctx).
 			Model(&models.Quota{}).
 			Where("id = ?", existing.ID).
-			Updates(map[string]any{
-				"data":       datatypes.JSON(payload),
-				"updated_at": now,
-			}).Error
+			Updates(updates).Error
 	}
 	if errors.Is(errFind, gorm.ErrRecordNotFound) {
 		row := models.Quota{
-			AuthID:    authID,
-			Type:      authType,
-			Data:      datatypes.JSON(payload),
-			CreatedAt: now,
-			UpdatedAt: now,
+			AuthID:            authID,
+			Type:              authType,
+			Data:              datatypes.JSON(payload),
+			Plan:              normalized.Plan,
+			RemainingRequests: normalized.RemainingRequests,
+			RemainingTokens:   normalized.RemainingTokens,
+			RemainingFraction: normalized.RemainingFraction,
+			ResetAt:           normalized.ResetAt,
+			CreatedAt:         now,
+			UpdatedAt:         now,
+		}
+		return nil, p.db.WithContext(ctx).Create(&row).Error
+	}
+	return nil, errFind
+}
+
+// resolveQuotaLowThreshold returns the remaining fraction (0..1) at or below which a quota is
+// low, or 0 when low quota events are disabled.
+func resolveQuotaLowThreshold() float64 {
+	percent := internalsettings.DefaultQuotaLowThresholdPercent
+	if raw, ok := internalsettings.DBConfigValue(internalsettings.QuotaLowThresholdPercentKey); ok {
+		if parsed, okParse := parseDBConfigInt(raw); okParse && parsed >= 0 && parsed <= 100 {
+			percent = parsed
 		}
-		return p.db.WithContext(ctx).Create(&row).Error
 	}
-	return errFind
+	return float64(percent) / 100
+}
+
+// crossedQuotaLow reports whether the remaining fraction just dropped to or below threshold.
+// A quota first seen already low counts as crossing; one that stays low does not fire again
+// until it recovers above the threshold.
+func crossedQuotaLow(previous, current *float64, threshold float64) bool {
+	if threshold <= 0 || current == nil || *current > threshold {
+		return false
+	}
+	return previous == nil || *previous > threshold
+}
+
+// publishQuotaLow announces that an auth's tightest quota limit dropped below the threshold.
+func publishQuotaLow(ctx context.Context, provider string, authID uint64, authKey string, remaining, threshold float64, resetAt *time.Time) {
+	severity := events.SeverityWarning
+	if remaining <= 0 {
+		severity = events.SeverityCritical
+	}
+	data := map[string]any{
+		"provider":           provider,
+		"auth_id":            authID,
+		"auth_key":           authKey,
+		"remaining_fraction": remaining,
+		"threshold":          threshold,
+	}
+	if resetAt != nil {
+		data["reset_at"] = resetAt.UTC()
+	}
+	events.Publish(ctx, events.Event{
+		Type:     events.TypeQuotaLow,
+		Severity: severity,
+		Subject:  "auth:" + authKey,
+		Message:  fmt.Sprintf("%s quota has %.0f%% left", provider, remaining*100),
+		Data:     data,
+	})
 }
 
 func normalizePayload(payload []byte) []byte {
@@ -700,7 +733,7 @@ func resolveGeminiProjectID(metadata map[string]any) string {
 	return ""
 }
 
-func resolveCopilotAccessToken(metadata map[string]any) string {
+func resolveOAuthAccessToken(metadata map[string]any) string {
 	if metadata == nil {
 		return ""
 	}
@@ -916,10 +949,38 @@ func (p *Poller) updateAuthHealth(ctx context.Context, authID uint64, errRefresh
 		}
 	}
 
-	return p.db.WithContext(ctx).
+	markInvalid, _ := updates["token_invalid"].(bool)
+	var previous models.Auth
+	if markInvalid {
+		if errFind := p.db.WithContext(ctx).
+			Select("id", "key", "token_invalid").
+			Where("id = ?", authID).
+			Take(&previous).Error; errFind != nil {
+			previous = models.Auth{}
+		}
+	}
+
+	if errUpdate := p.db.WithContext(ctx).
 		Model(&models.Auth{}).
 		Where("id = ?", authID).
-		Updates(updates).Error
+		Updates(updates).Error; errUpdate != nil {
+		return errUpdate
+	}
+
+	// Only announce the transition so a persistently broken credential does not flood subscribers.
+	if markInvalid && previous.ID != 0 && !previous.TokenInvalid {
+		events.Publish(ctx, events.Event{
+			Type:     events.TypeAuthTokenInvalid,
+			Severity: events.SeverityWarning,
+			Subject:  "auth:" + previous.Key,
+			Message:  errRefresh.Error(),
+			Data: map[string]any{
+				"auth_id":  authID,
+				"auth_key": previous.Key,
+			},
+		})
+	}
+	return nil
 }
 
 func refreshStatusCode(err error) (int, bool) {
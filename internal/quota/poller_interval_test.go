@@ -0,0 +1,87 @@
+package quota
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+)
+
+func TestPollDue(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	due, wait := pollDue(time.Time{}, time.Minute, now)
+	if !due || wait != time.Minute {
+		t.Fatalf("never polled: expected due with wait 1m, got due=%v wait=%s", due, wait)
+	}
+
+	due, wait = pollDue(now.Add(-20*time.Second), time.Minute, now)
+	if due || wait != 40*time.Second {
+		t.Fatalf("recently polled: expected not due with wait 40s, got due=%v wait=%s", due, wait)
+	}
+
+	due, wait = pollDue(now.Add(-time.Hour), 30*time.Second, now)
+	if !due || wait != 30*time.Second {
+		t.Fatalf("overdue: expected due with wait 30s, got due=%v wait=%s", due, wait)
+	}
+}
+
+func TestLoadAuthRowsReadsPollIntervalOverride(t *testing.T) {
+	db := setupPollerManualRefreshDB(t)
+	fast := 30
+	rows := []models.Auth{
+		{Key: "fast-key", Content: datatypes.JSON([]byte(`{"type":"codex"}`)), QuotaPollIntervalSeconds: &fast},
+		{Key: "default-key", Content: datatypes.JSON([]byte(`{"type":"codex"}`))},
+	}
+	if errCreate := db.Create(&rows).Error; errCreate != nil {
+		t.Fatalf("create auth rows: %v", errCreate)
+	}
+
+	poller := &Poller{db: db}
+	rowMap, errLoad := poller.loadAuthRows(context.Background())
+	if errLoad != nil {
+		t.Fatalf("loadAuthRows returned error: %v", errLoad)
+	}
+	if got := rowMap["fast-key"].PollInterval; got != 30*time.Second {
+		t.Fatalf("expected fast-key interval 30s, got %s", got)
+	}
+	if got := rowMap["default-key"].PollInterval; got != 0 {
+		t.Fatalf("expected default-key to use global interval, got %s", got)
+	}
+
+	row, ok, errRow := poller.loadAuthRowByKey(context.Background(), "fast-key")
+	if errRow != nil || !ok {
+		t.Fatalf("loadAuthRowByKey: ok=%v err=%v", ok, errRow)
+	}
+	if row.PollInterval != 30*time.Second {
+		t.Fatalf("expected loadAuthRowByKey interval 30s, got %s", row.PollInterval)
+	}
+}
+
+func TestLoadAuthRowsReadsPollEnabled(t *testing.T) {
+	db := setupPollerManualRefreshDB(t)
+	rows := []models.Auth{
+		{Key: "polled-key", Content: datatypes.JSON([]byte(`{"type":"codex"}`))},
+		{Key: "quiet-key", Content: datatypes.JSON([]byte(`{"type":"codex"}`))},
+	}
+	if errCreate := db.Create(&rows).Error; errCreate != nil {
+		t.Fatalf("create auth rows: %v", errCreate)
+	}
+	if errUpdate := db.Model(&models.Auth{}).Where("key = ?", "quiet-key").Update("poll_enabled", false).Error; errUpdate != nil {
+		t.Fatalf("disable polling: %v", errUpdate)
+	}
+
+	poller := &Poller{db: db}
+	rowMap, errLoad := poller.loadAuthRows(context.Background())
+	if errLoad != nil {
+		t.Fatalf("loadAuthRows returned error: %v", errLoad)
+	}
+	if !rowMap["polled-key"].PollEnabled {
+		t.Fatalf("expected polled-key to default to polling enabled")
+	}
+	if rowMap["quiet-key"].PollEnabled {
+		t.Fatalf("expected quiet-key to be excluded from polling")
+	}
+}
@@ -432,3 +432,61 @@ func TestRefreshAuthCopilotUsesBearerAccessTokenHeader(t *testing.T) {
 		t.Fatalf("expected bearer access token header, got %q", executor.receivedAuthHeader)
 	}
 }
+
+func TestRefreshAuthClaudeSavesUsageWindows(t *testing.T) {
+	db := setupPollerManualRefreshDB(t)
+	manager := coreauth.NewManager(nil, nil, nil)
+	executor := &sequenceProviderExecutor{
+		provider:                "claude",
+		statuses:                []int{http.StatusOK},
+		bodies:                  []string{`{"five_hour":{"utilization":42,"resets_at":"2026-01-01T05:00:00Z"},"seven_day":{"utilization":10,"resets_at":"2026-01-07T00:00:00Z"}}`},
+		requirePresetAuthHeader: true,
+		expectedPresetAuth:      "Bearer sk-ant-oat-test",
+	}
+	manager.RegisterExecutor(executor)
+
+	authRecord := &coreauth.Auth{
+		ID:       "auth-claude",
+		Provider: "claude",
+		Metadata: map[string]any{"access_token": "sk-ant-oat-test"},
+	}
+	auth, errRegister := manager.Register(context.Background(), authRecord)
+	if errRegister != nil {
+		t.Fatalf("register auth: %v", errRegister)
+	}
+
+	row := models.Auth{Key: auth.ID, Content: datatypes.JSON([]byte(`{"type":"claude"}`))}
+	if errCreate := db.Create(&row).Error; errCreate != nil {
+		t.Fatalf("create auth row: %v", errCreate)
+	}
+
+	poller := &Poller{db: db, manager: manager}
+	errRefresh := poller.refreshAuth(context.Background(), auth, authRowInfo{ID: row.ID, Type: "claude"})
+	if errRefresh != nil {
+		t.Fatalf("expected refresh success, got %v", errRefresh)
+	}
+
+	var quotaRow models.Quota
+	if errLoad := db.WithContext(context.Background()).
+		Where("auth_id = ? AND type = ?", row.ID, "claude").
+		First(&quotaRow).Error; errLoad != nil {
+		t.Fatalf("load quota row: %v", errLoad)
+	}
+	var payload map[string]any
+	if errJSON := json.Unmarshal(quotaRow.Data, &payload); errJSON != nil {
+		t.Fatalf("unmarshal quota payload: %v", errJSON)
+	}
+	if _, okWindow := payload["five_hour"].(map[string]any); !okWindow {
+		t.Fatalf("expected five_hour window in payload, got %#v", payload)
+	}
+}
+
+func TestRefreshAuthReturnsErrorWhenClaudeAccessTokenMissing(t *testing.T) {
+	poller := &Poller{manager: coreauth.NewManager(nil, nil, nil)}
+	auth := &coreauth.Auth{ID: "auth-claude-empty", Provider: "claude", Metadata: map[string]any{}}
+
+	err := poller.refreshAuth(context.Background(), auth, authRowInfo{Type: "claude"})
+	if err == nil || !strings.Contains(err.Error(), "claude missing access token") {
+		t.Fatalf("expected missing access token error, got %v", err)
+	}
+}
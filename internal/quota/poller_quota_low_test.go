@@ -0,0 +1,75 @@
+package quota
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
+)
+
+func TestCrossedQuotaLow(t *testing.T) {
+	fraction := func(v float64) *float64 { return &v }
+	cases := []struct {
+		name      string
+		previous  *float64
+		current   *float64
+		threshold float64
+		want      bool
+	}{
+		{name: "drops below", previous: fraction(0.5), current: fraction(0.05), threshold: 0.1, want: true},
+		{name: "lands on threshold", previous: fraction(0.2), current: fraction(0.1), threshold: 0.1, want: true},
+		{name: "first poll already low", previous: nil, current: fraction(0), threshold: 0.1, want: true},
+		{name: "stays low", previous: fraction(0.08), current: fraction(0.02), threshold: 0.1, want: false},
+		{name: "above threshold", previous: fraction(0.5), current: fraction(0.3), threshold: 0.1, want: false},
+		{name: "no fraction reported", previous: fraction(0.5), current: nil, threshold: 0.1, want: false},
+		{name: "disabled", previous: fraction(0.5), current: fraction(0), threshold: 0, want: false},
+	}
+	for _, tc := range cases {
+		if got := crossedQuotaLow(tc.previous, tc.current, tc.threshold); got != tc.want {
+			t.Errorf("%s: crossedQuotaLow = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestPublishQuotaLowEmitsEvent(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []events.Event
+	)
+	bus := events.Default()
+	bus.Subscribe(events.SubscriberFunc{
+		SubscriberName: "quota-low-test",
+		Fn: func(_ context.Context, event events.Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, event)
+			return nil
+		},
+	}, events.TypeQuotaLow)
+	bus.Start(context.Background())
+
+	resetAt := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	publishQuotaLow(context.Background(), "codex", 7, "codex-a.json", 0.05, 0.1, &resetAt)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		count := len(received)
+		mu.Unlock()
+		if count > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 1 {
+		t.Fatalf("expected one quota.low event, got %d", len(received))
+	}
+	event := received[0]
+	if event.Subject != "auth:codex-a.json" || event.Severity != events.SeverityWarning || event.Data["remaining_fraction"] != 0.05 {
+		t.Fatalf("unexpected event %+v", event)
+	}
+}
@@ -0,0 +1,229 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+// RequestFunc performs an HTTP request on behalf of an auth and returns the status code and body.
+type RequestFunc func(ctx context.Context, auth *coreauth.Auth, method, targetURL string, body []byte, headers http.Header) (int, []byte, error)
+
+// QuotaProvider fetches quota data for auths of a single provider.
+type QuotaProvider interface {
+	// Name returns the lower-case auth provider name, e.g. "codex".
+	Name() string
+	// FetchQuota returns the raw quota payload to store for the auth.
+	FetchQuota(ctx context.Context, do RequestFunc, auth *coreauth.Auth) ([]byte, error)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]QuotaProvider)
+)
+
+func init() {
+	RegisterProvider(antigravityProvider{})
+	RegisterProvider(codexProvider{})
+	RegisterProvider(geminiCLIProvider{})
+	RegisterProvider(copilotProvider{})
+	RegisterProvider(claudeProvider{})
+}
+
+// RegisterProvider adds a quota provider, replacing any provider registered under the same name.
+func RegisterProvider(provider QuotaProvider) {
+	if provider == nil {
+		return
+	}
+	name := strings.ToLower(strings.TrimSpace(provider.Name()))
+	if name == "" {
+		return
+	}
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[name] = provider
+}
+
+// RegisteredProviders returns the sorted names of all registered quota providers.
+func RegisteredProviders() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupProvider(name string) (QuotaProvider, bool) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	provider, ok := providers[strings.ToLower(strings.TrimSpace(name))]
+	return provider, ok
+}
+
+// checkProviderStatus converts a non-2xx response into a providerRequestError.
+func checkProviderStatus(provider string, auth *coreauth.Auth, status int, payload []byte) error {
+	if status >= http.StatusOK && status < http.StatusMultipleChoices {
+		return nil
+	}
+	log.Warnf("quota poller: %s status=%d (auth=%s body=%s)", provider, status, auth.ID, summarizePayload(payload))
+	return &providerRequestError{
+		provider:   provider,
+		statusCode: status,
+		err:        fmt.Errorf("quota poller: %s non-2xx status=%d", provider, status),
+	}
+}
+
+type antigravityProvider struct{}
+
+func (antigravityProvider) Name() string { return "antigravity" }
+
+func (antigravityProvider) FetchQuota(ctx context.Context, do RequestFunc, auth *coreauth.Auth) ([]byte, error) {
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	headers.Set("User-Agent", antigravityUserAgent)
+	body := []byte("{}")
+	var lastErr error
+
+	for _, url := range antigravityQuotaURLs {
+		status, payload, errReq := do(ctx, auth, http.MethodPost, url, body, headers)
+		if errReq != nil {
+			log.WithError(errReq).Warnf("quota poller: antigravity request failed (auth=%s)", auth.ID)
+			lastErr = errReq
+			continue
+		}
+		if errStatus := checkProviderStatus("antigravity", auth, status, payload); errStatus != nil {
+			lastErr = errStatus
+			continue
+		}
+		return payload, nil
+	}
+	if lastErr == nil {
+		lastErr = errors.New("quota poller: antigravity refresh failed")
+	}
+	return nil, lastErr
+}
+
+type codexProvider struct{}
+
+func (codexProvider) Name() string { return "codex" }
+
+func (codexProvider) FetchQuota(ctx context.Context, do RequestFunc, auth *coreauth.Auth) ([]byte, error) {
+	accountID := resolveCodexAccountID(auth.Metadata)
+	if accountID == "" {
+		log.Warnf("quota poller: codex missing account id (auth=%s)", auth.ID)
+		return nil, errors.New("quota poller: codex missing account id")
+	}
+
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	headers.Set("User-Agent", codexUserAgent)
+	headers.Set("Chatgpt-Account-Id", accountID)
+
+	status, payload, errReq := do(ctx, auth, http.MethodGet, codexUsageURL, nil, headers)
+	if errReq != nil {
+		log.WithError(errReq).Warnf("quota poller: codex request failed (auth=%s)", auth.ID)
+		return nil, errReq
+	}
+	if errStatus := checkProviderStatus("codex", auth, status, payload); errStatus != nil {
+		return nil, errStatus
+	}
+	return payload, nil
+}
+
+type geminiCLIProvider struct{}
+
+func (geminiCLIProvider) Name() string { return "gemini-cli" }
+
+func (geminiCLIProvider) FetchQuota(ctx context.Context, do RequestFunc, auth *coreauth.Auth) ([]byte, error) {
+	projectID := resolveGeminiProjectID(auth.Metadata)
+	if projectID == "" {
+		log.Warnf("quota poller: gemini-cli missing project id (auth=%s)", auth.ID)
+		return nil, errors.New("quota poller: gemini-cli missing project id")
+	}
+
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	body, errMarshal := json.Marshal(map[string]string{"project": projectID})
+	if errMarshal != nil {
+		log.WithError(errMarshal).Warnf("quota poller: gemini-cli request body failed (auth=%s)", auth.ID)
+		return nil, errMarshal
+	}
+
+	status, payload, errReq := do(ctx, auth, http.MethodPost, geminiCLIQuotaURL, body, headers)
+	if errReq != nil {
+		log.WithError(errReq).Warnf("quota poller: gemini-cli request failed (auth=%s)", auth.ID)
+		return nil, errReq
+	}
+	if errStatus := checkProviderStatus("gemini-cli", auth, status, payload); errStatus != nil {
+		return nil, errStatus
+	}
+	return payload, nil
+}
+
+type copilotProvider struct{}
+
+func (copilotProvider) Name() string { return "github-copilot" }
+
+func (copilotProvider) FetchQuota(ctx context.Context, do RequestFunc, auth *coreauth.Auth) ([]byte, error) {
+	accessToken := resolveOAuthAccessToken(auth.Metadata)
+	if accessToken == "" {
+		log.Warnf("quota poller: github-copilot missing access token (auth=%s)", auth.ID)
+		return nil, errors.New("quota poller: github-copilot missing access token")
+	}
+
+	headers := http.Header{}
+	headers.Set("Accept", "application/json")
+	headers.Set("Authorization", "Bearer "+accessToken)
+	headers.Set("User-Agent", copilotUserAgent)
+
+	status, payload, errReq := do(ctx, auth, http.MethodGet, copilotUserURL, nil, headers)
+	if errReq != nil {
+		log.WithError(errReq).Warnf("quota poller: github-copilot request failed (auth=%s)", auth.ID)
+		return nil, errReq
+	}
+	if errStatus := checkProviderStatus("github-copilot", auth, status, payload); errStatus != nil {
+		return nil, errStatus
+	}
+	return payload, nil
+}
+
+// claudeProvider fetches the rate-limit windows (five hour, seven day, per model) of a Claude OAuth account.
+type claudeProvider struct{}
+
+func (claudeProvider) Name() string { return "claude" }
+
+func (claudeProvider) FetchQuota(ctx context.Context, do RequestFunc, auth *coreauth.Auth) ([]byte, error) {
+	accessToken := resolveOAuthAccessToken(auth.Metadata)
+	if accessToken == "" {
+		log.Warnf("quota poller: claude missing access token (auth=%s)", auth.ID)
+		return nil, errors.New("quota poller: claude missing access token")
+	}
+
+	headers := http.Header{}
+	headers.Set("Accept", "application/json")
+	headers.Set("Content-Type", "application/json")
+	headers.Set("Authorization", "Bearer "+accessToken)
+	headers.Set("Anthropic-Beta", claudeOAuthBeta)
+	headers.Set("User-Agent", claudeUserAgent)
+
+	status, payload, errReq := do(ctx, auth, http.MethodGet, claudeUsageURL, nil, headers)
+	if errReq != nil {
+		log.WithError(errReq).Warnf("quota poller: claude request failed (auth=%s)", auth.ID)
+		return nil, errReq
+	}
+	if errStatus := checkProviderStatus("claude", auth, status, payload); errStatus != nil {
+		return nil, errStatus
+	}
+	return payload, nil
+}
@@ -0,0 +1,79 @@
+package quota
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+)
+
+type staticQuotaProvider struct {
+	name    string
+	payload string
+	calls   int
+}
+
+func (s *staticQuotaProvider) Name() string { return s.name }
+
+func (s *staticQuotaProvider) FetchQuota(context.Context, RequestFunc, *coreauth.Auth) ([]byte, error) {
+	s.calls++
+	return []byte(s.payload), nil
+}
+
+func TestRegisteredProvidersIncludesBuiltins(t *testing.T) {
+	names := RegisteredProviders()
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		seen[name] = true
+	}
+	for _, want := range []string{"antigravity", "claude", "codex", "gemini-cli", "github-copilot"} {
+		if !seen[want] {
+			t.Fatalf("expected builtin provider %q in %v", want, names)
+		}
+	}
+}
+
+func TestRefreshAuthUsesRegisteredProvider(t *testing.T) {
+	provider := &staticQuotaProvider{name: "Test-Registry", payload: `{"remaining":5}`}
+	RegisterProvider(provider)
+	t.Cleanup(func() {
+		providersMu.Lock()
+		delete(providers, "test-registry")
+		providersMu.Unlock()
+	})
+
+	db := setupPollerManualRefreshDB(t)
+	row := models.Auth{Key: "auth-registry", Content: datatypes.JSON([]byte(`{"type":"test-registry"}`))}
+	if errCreate := db.Create(&row).Error; errCreate != nil {
+		t.Fatalf("create auth row: %v", errCreate)
+	}
+
+	poller := &Poller{db: db, manager: coreauth.NewManager(nil, nil, nil)}
+	auth := &coreauth.Auth{ID: "auth-registry", Provider: "test-registry"}
+	if errRefresh := poller.refreshAuth(context.Background(), auth, authRowInfo{ID: row.ID}); errRefresh != nil {
+		t.Fatalf("expected refresh success, got %v", errRefresh)
+	}
+	if provider.calls != 1 {
+		t.Fatalf("expected provider to be called once, got %d", provider.calls)
+	}
+
+	var quotaRow models.Quota
+	if errLoad := db.Where("auth_id = ? AND type = ?", row.ID, "test-registry").First(&quotaRow).Error; errLoad != nil {
+		t.Fatalf("load quota row: %v", errLoad)
+	}
+}
+
+func TestCheckProviderStatusWrapsNon2xx(t *testing.T) {
+	auth := &coreauth.Auth{ID: "auth-status"}
+	if errStatus := checkProviderStatus("codex", auth, http.StatusOK, nil); errStatus != nil {
+		t.Fatalf("expected nil for 200, got %v", errStatus)
+	}
+	errStatus := checkProviderStatus("codex", auth, http.StatusUnauthorized, []byte("denied"))
+	statusCode, ok := refreshStatusCode(errStatus)
+	if !ok || statusCode != http.StatusUnauthorized {
+		t.Fatalf("expected status 401, got %d (ok=%v)", statusCode, ok)
+	}
+}
@@ -0,0 +1,153 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Rate describes a token bucket that holds Capacity tokens and refills evenly over Period.
+type Rate struct {
+	Capacity int
+	Period   time.Duration
+}
+
+// PerMinute returns a rate of n tokens per minute.
+func PerMinute(n int) Rate {
+	return Rate{Capacity: n, Period: time.Minute}
+}
+
+// Enabled reports whether the rate limits anything.
+func (r Rate) Enabled() bool {
+	return r.Capacity > 0 && r.Period > 0
+}
+
+// perSecond returns the refill speed in tokens per second.
+func (r Rate) perSecond() float64 {
+	return float64(r.Capacity) / r.Period.Seconds()
+}
+
+// BucketResult describes the outcome of a token bucket check.
+type BucketResult struct {
+	Allowed    bool
+	Limit      int           // Bucket capacity.
+	Remaining  int           // Whole tokens left after the check; zero when in debt.
+	Reset      time.Duration // Time until the bucket is full again.
+	RetryAfter time.Duration // Time until the denied request would fit; zero when allowed.
+}
+
+// bucketOutcome derives a result from the token level left after a check.
+func bucketOutcome(rate Rate, allowed bool, tokens float64, need float64) BucketResult {
+	speed := rate.perSecond()
+	result := BucketResult{Allowed: allowed, Limit: rate.Capacity}
+	if tokens > 0 {
+		result.Remaining = int(math.Floor(tokens))
+	}
+	result.Reset = secondsDuration((float64(rate.Capacity) - tokens) / speed)
+	if !allowed {
+		result.RetryAfter = secondsDuration((need - tokens) / speed)
+	}
+	return result
+}
+
+func secondsDuration(seconds float64) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
+
+// takeNeed is the token level a check requires. A zero cost only checks that the bucket is
+// not empty, which is how token-per-minute budgets admit requests before their size is known.
+func takeNeed(cost int) float64 {
+	if cost <= 0 {
+		return 1
+	}
+	return float64(cost)
+}
+
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+	rate    Rate
+}
+
+// refill tops the bucket up for the time elapsed since the last update.
+func (b *memoryBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(b.rate.Capacity), b.tokens+elapsed*b.rate.perSecond())
+		b.updated = now
+	}
+}
+
+// bucketSweepEvery is how many checks pass between sweeps of refilled buckets.
+const bucketSweepEvery = 4096
+
+// MemoryBuckets implements token buckets in process memory.
+type MemoryBuckets struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+	checks  int
+}
+
+// NewMemoryBuckets constructs an empty bucket set.
+func NewMemoryBuckets() *MemoryBuckets {
+	return &MemoryBuckets{buckets: make(map[string]*memoryBucket)}
+}
+
+// Take removes cost tokens from the bucket when enough are available.
+func (m *MemoryBuckets) Take(key string, rate Rate, cost int, now time.Time) BucketResult {
+	if key == "" || !rate.Enabled() {
+		return BucketResult{Allowed: true}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checks++
+	if m.checks%bucketSweepEvery == 0 {
+		m.sweepLocked(now)
+	}
+	bucket := m.buckets[key]
+	if bucket == nil || bucket.rate != rate {
+		tokens := float64(rate.Capacity)
+		if bucket != nil {
+			bucket.refill(now)
+			tokens = math.Min(bucket.tokens, tokens)
+		}
+		bucket = &memoryBucket{tokens: tokens, updated: now, rate: rate}
+		m.buckets[key] = bucket
+	}
+	bucket.refill(now)
+	need := takeNeed(cost)
+	if bucket.tokens < need {
+		return bucketOutcome(rate, false, bucket.tokens, need)
+	}
+	if cost > 0 {
+		bucket.tokens -= float64(cost)
+	}
+	return bucketOutcome(rate, true, bucket.tokens, need)
+}
+
+// Debit removes cost tokens from an existing bucket, allowing it to go into debt.
+func (m *MemoryBuckets) Debit(key string, cost int, now time.Time) {
+	if key == "" || cost <= 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	bucket := m.buckets[key]
+	if bucket == nil {
+		return
+	}
+	bucket.refill(now)
+	bucket.tokens -= float64(cost)
+}
+
+// sweepLocked drops buckets that have refilled completely; they behave like new ones.
+func (m *MemoryBuckets) sweepLocked(now time.Time) {
+	for key, bucket := range m.buckets {
+		bucket.refill(now)
+		if bucket.tokens >= float64(bucket.rate.Capacity) {
+			delete(m.buckets, key)
+		}
+	}
+}
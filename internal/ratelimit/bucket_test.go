@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryBucketsTakeAndRefill(t *testing.T) {
+	buckets := NewMemoryBuckets()
+	now := time.Unix(1_700_000_000, 0)
+	rate := PerMinute(2)
+
+	if result := buckets.Take("k", rate, 1, now); !result.Allowed || result.Remaining != 1 {
+		t.Fatalf("first take: %+v", result)
+	}
+	if result := buckets.Take("k", rate, 1, now); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("second take: %+v", result)
+	}
+	denied := buckets.Take("k", rate, 1, now)
+	if denied.Allowed || denied.RetryAfter != 30*time.Second || denied.Reset != time.Minute {
+		t.Fatalf("expected denial with 30s retry, got %+v", denied)
+	}
+	if result := buckets.Take("k", rate, 1, now.Add(30*time.Second)); !result.Allowed {
+		t.Fatalf("expected refill after 30s, got %+v", result)
+	}
+}
+
+func TestMemoryBucketsDebitPutsTokenBudgetInDebt(t *testing.T) {
+	buckets := NewMemoryBuckets()
+	now := time.Unix(1_700_000_000, 0)
+	rate := PerMinute(1000)
+
+	if result := buckets.Take("tpm", rate, 0, now); !result.Allowed || result.Remaining != 1000 {
+		t.Fatalf("admission check should not consume tokens: %+v", result)
+	}
+	buckets.Debit("tpm", 1500, now)
+	denied := buckets.Take("tpm", rate, 0, now)
+	if denied.Allowed || denied.Remaining != 0 {
+		t.Fatalf("expected denial while in debt, got %+v", denied)
+	}
+	// 501 tokens of debt at 1000/min clear after about 30.06s.
+	if result := buckets.Take("tpm", rate, 0, now.Add(31*time.Second)); !result.Allowed {
+		t.Fatalf("expected admission after the debt refilled, got %+v", result)
+	}
+	buckets.Debit("missing", 10, now)
+	if _, ok := buckets.buckets["missing"]; ok {
+		t.Fatalf("debit must not create buckets")
+	}
+}
+
+func TestManagerCheckKeyReportsTightestBudget(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	manager := NewManager(func() SettingsConfig { return SettingsConfig{} }, func() time.Time { return now }, nil)
+	limits := KeyLimits{APIKeyID: 7, UserID: 3, KeyRPM: 10, GroupRPM: 1, KeyTPM: 500}
+	ctx := context.Background()
+
+	first, errCheck := manager.CheckKey(ctx, limits)
+	if errCheck != nil || !first.Allowed {
+		t.Fatalf("first check: %+v %v", first, errCheck)
+	}
+	if first.Requests == nil || first.Requests.Limit != 1 || first.Requests.Remaining != 0 {
+		t.Fatalf("expected group budget to be the tightest, got %+v", first.Requests)
+	}
+	if first.Tokens == nil || first.Tokens.Remaining != 500 {
+		t.Fatalf("unexpected token budget %+v", first.Tokens)
+	}
+
+	second, _ := manager.CheckKey(ctx, limits)
+	if second.Allowed || second.RetryAfter() != time.Minute {
+		t.Fatalf("expected group budget to deny for a minute, got %+v", second)
+	}
+
+	manager.DebitTokens(ctx, 7, 3, 600)
+	now = now.Add(time.Minute)
+	third, _ := manager.CheckKey(ctx, limits)
+	if !third.Allowed || third.Tokens.Remaining != 400 {
+		t.Fatalf("expected tokens refilled to 400 after debt, got %+v", third.Tokens)
+	}
+}
@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/usergroup"
+	"gorm.io/gorm"
+)
+
+// InFlight counts requests currently being served per key, e.g. per user.
+type InFlight struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// NewInFlight constructs an empty in-flight counter.
+func NewInFlight() *InFlight {
+	return &InFlight{counts: make(map[string]int)}
+}
+
+var defaultInFlight = NewInFlight()
+
+// DefaultInFlight returns the process-wide in-flight counter used by the proxy middleware.
+func DefaultInFlight() *InFlight { return defaultInFlight }
+
+// Acquire reserves a slot for key when fewer than limit requests are in flight. The returned
+// release function must be called exactly once when the request finishes.
+func (f *InFlight) Acquire(key string, limit int) (func(), bool) {
+	if f == nil || key == "" || limit <= 0 {
+		return func() {}, true
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.counts[key] >= limit {
+		return nil, false
+	}
+	f.counts[key]++
+	var once sync.Once
+	return func() {
+		once.Do(func() { f.release(key) })
+	}, true
+}
+
+// Current returns the number of in-flight requests for key.
+func (f *InFlight) Current(key string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.counts[key]
+}
+
+func (f *InFlight) release(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.counts[key] <= 1 {
+		delete(f.counts, key)
+		return
+	}
+	f.counts[key]--
+}
+
+// ResolveMaxConcurrent returns the user's in-flight request cap, falling back to the cap of
+// the user's primary group and its parents; zero means unlimited.
+func ResolveMaxConcurrent(ctx context.Context, db *gorm.DB, userID uint64) (int, error) {
+	if db == nil || userID == 0 {
+		return 0, nil
+	}
+	var user struct {
+		MaxConcurrentRequests int
+		UserGroupID           models.UserGroupIDs `gorm:"column:user_group_id"`
+	}
+	if errFind := db.WithContext(ctx).
+		Model(&models.User{}).
+		Select("max_concurrent_requests", "user_group_id").
+		Where("id = ?", userID).
+		Take(&user).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, errFind
+	}
+	if user.MaxConcurrentRequests > 0 {
+		return user.MaxConcurrentRequests, nil
+	}
+	groupID := user.UserGroupID.Primary()
+	if groupID == nil || *groupID == 0 {
+		return 0, nil
+	}
+	policy, errPolicy := usergroup.Resolve(ctx, db, *groupID)
+	if errPolicy != nil {
+		return 0, errPolicy
+	}
+	return policy.MaxConcurrentRequests, nil
+}
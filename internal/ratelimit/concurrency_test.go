@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestInFlightAcquireAndRelease(t *testing.T) {
+	inFlight := NewInFlight()
+
+	releaseA, okA := inFlight.Acquire("u:1", 2)
+	releaseB, okB := inFlight.Acquire("u:1", 2)
+	if !okA || !okB {
+		t.Fatalf("expected two slots")
+	}
+	if _, ok := inFlight.Acquire("u:1", 2); ok {
+		t.Fatalf("expected third request to be rejected")
+	}
+	if _, ok := inFlight.Acquire("u:2", 2); !ok {
+		t.Fatalf("expected other users to be unaffected")
+	}
+
+	releaseA()
+	releaseA()
+	if got := inFlight.Current("u:1"); got != 1 {
+		t.Fatalf("expected release to be idempotent, got %d in flight", got)
+	}
+	if _, ok := inFlight.Acquire("u:1", 2); !ok {
+		t.Fatalf("expected freed slot to be reusable")
+	}
+	releaseB()
+
+	if release, ok := inFlight.Acquire("u:1", 0); !ok || release == nil {
+		t.Fatalf("expected zero limit to be unlimited")
+	}
+}
+
+func TestInFlightConcurrentAcquireNeverExceedsLimit(t *testing.T) {
+	inFlight := NewInFlight()
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		acquired int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok := inFlight.Acquire("u:1", 5); ok {
+				mu.Lock()
+				acquired++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if acquired != 5 {
+		t.Fatalf("expected exactly 5 slots, got %d", acquired)
+	}
+}
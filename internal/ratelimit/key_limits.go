@@ -0,0 +1,143 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/usergroup"
+	"gorm.io/gorm"
+)
+
+var defaultManager = NewManager(LoadSettingsConfig, time.Now, nil)
+
+// Default returns the process-wide manager enforcing per API key and per user group budgets.
+func Default() *Manager { return defaultManager }
+
+// KeyLimits holds the per-minute request and token budgets applying to one API key. Group
+// budgets come from the owner's primary user group, inheriting from its parents, and are shared by all of the owner's keys.
+type KeyLimits struct {
+	APIKeyID uint64
+	UserID   uint64
+	KeyRPM   int
+	KeyTPM   int
+	GroupRPM int
+	GroupTPM int
+}
+
+// Enabled reports whether any budget applies.
+func (l KeyLimits) Enabled() bool {
+	return l.KeyRPM > 0 || l.KeyTPM > 0 || l.GroupRPM > 0 || l.GroupTPM > 0
+}
+
+// ResolveKeyLimits loads the key's own budgets and those of its owner's primary user group.
+func ResolveKeyLimits(ctx context.Context, db *gorm.DB, apiKey *models.APIKey) (KeyLimits, error) {
+	if apiKey == nil {
+		return KeyLimits{}, nil
+	}
+	limits := KeyLimits{APIKeyID: apiKey.ID, KeyRPM: apiKey.RPMLimit, KeyTPM: apiKey.TPMLimit}
+	if db == nil || apiKey.UserID == nil || *apiKey.UserID == 0 {
+		return limits, nil
+	}
+	limits.UserID = *apiKey.UserID
+	_, groupID, errUser := loadUserRateLimit(ctx, db, limits.UserID)
+	if errUser != nil {
+		return KeyLimits{}, errUser
+	}
+	if groupID == nil || *groupID == 0 {
+		return limits, nil
+	}
+	policy, errPolicy := usergroup.Resolve(ctx, db, *groupID)
+	if errPolicy != nil {
+		return KeyLimits{}, errPolicy
+	}
+	limits.GroupRPM = policy.RPMLimit
+	limits.GroupTPM = policy.TPMLimit
+	return limits, nil
+}
+
+// KeyCheck is the combined outcome of checking every budget of a key.
+type KeyCheck struct {
+	Allowed  bool
+	Requests *BucketResult // Tightest request budget; nil when none applies.
+	Tokens   *BucketResult // Tightest token budget; nil when none applies.
+}
+
+// RetryAfter returns how long the caller should wait before retrying a denied request.
+func (k KeyCheck) RetryAfter() time.Duration {
+	var wait time.Duration
+	for _, result := range []*BucketResult{k.Requests, k.Tokens} {
+		if result != nil && result.RetryAfter > wait {
+			wait = result.RetryAfter
+		}
+	}
+	return wait
+}
+
+func requestBucketKeys(apiKeyID, userID uint64) (string, string) {
+	return fmt.Sprintf("rpm:k:%d", apiKeyID), fmt.Sprintf("rpm:u:%d", userID)
+}
+
+func tokenBucketKeys(apiKeyID, userID uint64) (string, string) {
+	return fmt.Sprintf("tpm:k:%d", apiKeyID), fmt.Sprintf("tpm:u:%d", userID)
+}
+
+// CheckKey consumes one request from each request budget and requires every token budget
+// to be positive. Token budgets are charged afterwards through DebitTokens.
+func (m *Manager) CheckKey(ctx context.Context, limits KeyLimits) (KeyCheck, error) {
+	check := KeyCheck{Allowed: true}
+	keyRPM, groupRPM := requestBucketKeys(limits.APIKeyID, limits.UserID)
+	keyTPM, groupTPM := tokenBucketKeys(limits.APIKeyID, limits.UserID)
+	steps := []struct {
+		key    string
+		rate   int
+		cost   int
+		target **BucketResult
+	}{
+		{keyRPM, limits.KeyRPM, 1, &check.Requests},
+		{groupRPM, limits.GroupRPM, 1, &check.Requests},
+		{keyTPM, limits.KeyTPM, 0, &check.Tokens},
+		{groupTPM, limits.GroupTPM, 0, &check.Tokens},
+	}
+	for _, step := range steps {
+		isGroup := step.key == groupRPM || step.key == groupTPM
+		if step.rate <= 0 || (isGroup && limits.UserID == 0) {
+			continue
+		}
+		result, errTake := m.Take(ctx, step.key, PerMinute(step.rate), step.cost)
+		if errTake != nil {
+			return KeyCheck{}, errTake
+		}
+		if !result.Allowed {
+			check.Allowed = false
+		}
+		if current := *step.target; current == nil || tighter(result, *current) {
+			res := result
+			*step.target = &res
+		}
+	}
+	return check, nil
+}
+
+// tighter reports whether a leaves less headroom than b.
+func tighter(a, b BucketResult) bool {
+	if a.Allowed != b.Allowed {
+		return !a.Allowed
+	}
+	return a.Remaining < b.Remaining
+}
+
+// DebitTokens charges a finished request's tokens against the key and group token budgets.
+func (m *Manager) DebitTokens(ctx context.Context, apiKeyID, userID uint64, tokens int64) {
+	if m == nil || tokens <= 0 {
+		return
+	}
+	keyTPM, groupTPM := tokenBucketKeys(apiKeyID, userID)
+	if apiKeyID != 0 {
+		m.Debit(ctx, keyTPM, int(tokens))
+	}
+	if userID != 0 {
+		m.Debit(ctx, groupTPM, int(tokens))
+	}
+}
@@ -31,6 +31,7 @@ type Manager struct {
 	provider       SettingsProvider
 	nowFn          func() time.Time
 	memoryLimiter  Limiter
+	memoryBuckets  *MemoryBuckets
 	newRedisClient RedisClientFactory
 	mu             sync.Mutex
 	redisLimiter   *RedisLimiter
@@ -53,6 +54,7 @@ func NewManager(provider SettingsProvider, nowFn func() time.Time, newRedisClien
 		provider:       provider,
 		nowFn:          nowFn,
 		memoryLimiter:  NewMemoryLimiter(),
+		memoryBuckets:  NewMemoryBuckets(),
 		newRedisClient: newRedisClient,
 	}
 }
@@ -76,6 +78,57 @@ func (m *Manager) Allow(ctx context.Context, key string, limit int) (Result, err
 	return m.memoryLimiter.Allow(ctx, key, limit, now)
 }
 
+// Take checks a token bucket and consumes cost tokens using the best available backend.
+// A zero cost only requires the bucket to hold at least one token.
+func (m *Manager) Take(ctx context.Context, key string, rate Rate, cost int) (BucketResult, error) {
+	if m == nil || key == "" || !rate.Enabled() {
+		return BucketResult{Allowed: true}, nil
+	}
+	now := m.nowFn()
+	if limiter, ok := m.activeRedis(ctx, now); ok {
+		result, errTake := limiter.Take(ctx, key, rate, cost, now)
+		if errTake == nil {
+			return result, nil
+		}
+		m.tripBreaker(errTake, now)
+	}
+	return m.memoryBuckets.Take(key, rate, cost, now), nil
+}
+
+// Debit removes cost tokens from a bucket previously checked with Take, letting it go into
+// debt; token budgets are charged this way once a request's actual usage is known.
+func (m *Manager) Debit(ctx context.Context, key string, cost int) {
+	if m == nil || key == "" || cost <= 0 {
+		return
+	}
+	now := m.nowFn()
+	if limiter, ok := m.activeRedis(ctx, now); ok {
+		errDebit := limiter.Debit(ctx, key, cost, now)
+		if errDebit == nil {
+			return
+		}
+		m.tripBreaker(errDebit, now)
+	}
+	m.memoryBuckets.Debit(key, cost, now)
+}
+
+// activeRedis returns the Redis limiter when Redis is enabled and reachable.
+func (m *Manager) activeRedis(ctx context.Context, now time.Time) (*RedisLimiter, bool) {
+	cfg := m.provider()
+	if !cfg.RedisEnabled || m.isBreakerActive(now) {
+		return nil, false
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	limiter, errEnsure := m.ensureRedis(ctx, cfg, now)
+	if errEnsure != nil {
+		m.tripBreaker(errEnsure, now)
+		return nil, false
+	}
+	return limiter, limiter != nil
+}
+
 func (m *Manager) allowRedis(ctx context.Context, key string, limit int, now time.Time, cfg SettingsConfig) (Result, bool) {
 	if m == nil {
 		return Result{}, false
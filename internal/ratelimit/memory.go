@@ -51,3 +51,46 @@ func (l *MemoryLimiter) Allow(_ context.Context, key string, limit int, now time
 	l.mu.Unlock()
 	return Result{Allowed: true, Remaining: remaining, Reset: reset}, nil
 }
+
+// CounterState is one exported fixed-window counter.
+type CounterState struct {
+	Key    string `json:"key"`
+	Window int64  `json:"window"` // Unix second the count belongs to.
+	Count  int    `json:"count"`
+}
+
+// Export returns the counters whose window has not ended before now.
+func (l *MemoryLimiter) Export(now time.Time) []CounterState {
+	sec := now.Unix()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]CounterState, 0, len(l.counters))
+	for key, entry := range l.counters {
+		if entry.window < sec || entry.count == 0 {
+			continue
+		}
+		out = append(out, CounterState{Key: key, Window: entry.window, Count: entry.count})
+	}
+	return out
+}
+
+// Import merges exported counters that are still current, keeping the higher count when a
+// key is already being tracked for the same window.
+func (l *MemoryLimiter) Import(states []CounterState, now time.Time) {
+	sec := now.Unix()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, state := range states {
+		if state.Key == "" || state.Window < sec || state.Count <= 0 {
+			continue
+		}
+		entry := l.counters[state.Key]
+		if entry == nil || entry.window < state.Window {
+			l.counters[state.Key] = &memoryEntry{window: state.Window, count: state.Count}
+			continue
+		}
+		if entry.window == state.Window && entry.count < state.Count {
+			entry.count = state.Count
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/metrics"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultModelQueueWait bounds how long a queued request waits when max_wait_seconds is unset.
+const defaultModelQueueWait = 30 * time.Second
+
+var (
+	// ErrModelQueueFull is returned when a model has no free slot and its queue is full.
+	ErrModelQueueFull = errors.New("model queue full")
+	// ErrModelQueueTimeout is returned when a queued request waited max_wait_seconds.
+	ErrModelQueueTimeout = errors.New("model queue wait exceeded")
+)
+
+// ModelLimit caps the requests served at once for a model.
+type ModelLimit struct {
+	MaxConcurrent  int `json:"max_concurrent"`   // Requests served at once; zero disables the cap.
+	MaxQueue       int `json:"max_queue"`        // Requests allowed to wait for a slot; zero rejects instead.
+	MaxWaitSeconds int `json:"max_wait_seconds"` // How long a request may wait; zero uses 30 seconds.
+}
+
+// wait returns how long a queued request may wait.
+func (l ModelLimit) wait() time.Duration {
+	if l.MaxWaitSeconds <= 0 {
+		return defaultModelQueueWait
+	}
+	return time.Duration(l.MaxWaitSeconds) * time.Second
+}
+
+// ModelLimits maps requested model names, or "prefix*" patterns, to their limits. Models
+// matching one pattern share its slots.
+type ModelLimits map[string]ModelLimit
+
+// Lookup returns the entry governing model: an exact, case-insensitive name first, then the
+// longest matching pattern. The returned key identifies the shared slots.
+func (l ModelLimits) Lookup(model string) (string, ModelLimit, bool) {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if model == "" || len(l) == 0 {
+		return "", ModelLimit{}, false
+	}
+	bestKey, bestPrefix := "", -1
+	for key, limit := range l {
+		if limit.MaxConcurrent <= 0 {
+			continue
+		}
+		pattern := strings.ToLower(strings.TrimSpace(key))
+		if pattern == model {
+			return key, limit, true
+		}
+		prefix, isPattern := strings.CutSuffix(pattern, "*")
+		if isPattern && strings.HasPrefix(model, prefix) && len(prefix) > bestPrefix {
+			bestKey, bestPrefix = key, len(prefix)
+		}
+	}
+	if bestPrefix < 0 {
+		return "", ModelLimit{}, false
+	}
+	return bestKey, l[bestKey], true
+}
+
+// modelLimitsSnapshot caches the parsed MODEL_CONCURRENCY value.
+type modelLimitsSnapshot struct {
+	raw    string
+	limits ModelLimits
+}
+
+var modelLimitsCache atomic.Pointer[modelLimitsSnapshot]
+
+// LoadModelLimits reads MODEL_CONCURRENCY; invalid values disable per-model limits.
+func LoadModelLimits() ModelLimits {
+	raw, ok := internalsettings.DBConfigValue(internalsettings.ModelConcurrencyKey)
+	raw = bytes.TrimSpace(raw)
+	if !ok || len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	if cached := modelLimitsCache.Load(); cached != nil && cached.raw == string(raw) {
+		return cached.limits
+	}
+	var limits ModelLimits
+	if errUnmarshal := json.Unmarshal(raw, &limits); errUnmarshal != nil {
+		log.WithError(errUnmarshal).Warn("model concurrency: invalid setting")
+		limits = nil
+	}
+	modelLimitsCache.Store(&modelLimitsSnapshot{raw: string(raw), limits: limits})
+	return limits
+}
+
+// ModelGate enforces per-model concurrency caps. Requests beyond the cap wait in a FIFO
+// queue and receive freed slots in arrival order.
+type ModelGate struct {
+	mu    sync.Mutex
+	slots map[string]*modelSlots
+}
+
+// modelSlots tracks one model's holders and waiters.
+type modelSlots struct {
+	inFlight int
+	waiters  []chan struct{}
+}
+
+// NewModelGate constructs an empty gate.
+func NewModelGate() *ModelGate {
+	return &ModelGate{slots: make(map[string]*modelSlots)}
+}
+
+var defaultModelGate = NewModelGate()
+
+// DefaultModelGate returns the process-wide gate used by the proxy middleware.
+func DefaultModelGate() *ModelGate { return defaultModelGate }
+
+// Acquire takes a slot for key, queueing up to limit.MaxQueue requests for up to the limit's
+// wait. The returned release function must be called exactly once when the request finishes.
+// Cancelling ctx while queued returns ctx.Err().
+func (g *ModelGate) Acquire(ctx context.Context, key string, limit ModelLimit) (func(), error) {
+	if g == nil || key == "" || limit.MaxConcurrent <= 0 {
+		return func() {}, nil
+	}
+	g.mu.Lock()
+	s, ok := g.slots[key]
+	if !ok {
+		s = &modelSlots{}
+		g.slots[key] = s
+	}
+	if s.inFlight < limit.MaxConcurrent && len(s.waiters) == 0 {
+		s.inFlight++
+		g.observe(key, s)
+		g.mu.Unlock()
+		return g.releaser(key, limit), nil
+	}
+	if len(s.waiters) >= limit.MaxQueue {
+		g.mu.Unlock()
+		metrics.ObserveModelQueueRejection(key, "queue_full")
+		return nil, ErrModelQueueFull
+	}
+	ready := make(chan struct{})
+	s.waiters = append(s.waiters, ready)
+	g.observe(key, s)
+	g.mu.Unlock()
+
+	timer := time.NewTimer(limit.wait())
+	defer timer.Stop()
+	var errWait error
+	select {
+	case <-ready:
+		return g.releaser(key, limit), nil
+	case <-timer.C:
+		errWait = ErrModelQueueTimeout
+	case <-ctx.Done():
+		errWait = ctx.Err()
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i, waiter := range s.waiters {
+		if waiter == ready {
+			s.waiters = append(s.waiters[:i], s.waiters[i+1:]...)
+			g.observe(key, s)
+			if errors.Is(errWait, ErrModelQueueTimeout) {
+				metrics.ObserveModelQueueRejection(key, "timeout")
+			}
+			return nil, errWait
+		}
+	}
+	// A slot was handed over while giving up; keep it rather than leak it.
+	return g.releaser(key, limit), nil
+}
+
+// Stats returns the requests holding and waiting for key's slots.
+func (g *ModelGate) Stats(key string) (inFlight, queued int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if s, ok := g.slots[key]; ok {
+		return s.inFlight, len(s.waiters)
+	}
+	return 0, 0
+}
+
+// releaser returns a once-only function freeing a slot of key.
+func (g *ModelGate) releaser(key string, limit ModelLimit) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() { g.release(key, limit) })
+	}
+}
+
+// release hands the slot to the oldest waiter, or frees it when nobody waits or the cap was
+// lowered below the current holders.
+func (g *ModelGate) release(key string, limit ModelLimit) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	s, ok := g.slots[key]
+	if !ok {
+		return
+	}
+	if len(s.waiters) > 0 && s.inFlight <= limit.MaxConcurrent {
+		next := s.waiters[0]
+		s.waiters = s.waiters[1:]
+		close(next)
+	} else {
+		s.inFlight--
+	}
+	g.observe(key, s)
+	if s.inFlight <= 0 && len(s.waiters) == 0 {
+		delete(g.slots, key)
+	}
+}
+
+// observe publishes key's gauges; callers hold g.mu.
+func (g *ModelGate) observe(key string, s *modelSlots) {
+	metrics.ObserveModelQueue(key, s.inFlight, len(s.waiters))
+}
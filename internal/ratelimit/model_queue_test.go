@@ -0,0 +1,127 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestModelLimitsLookup(t *testing.T) {
+	limits := ModelLimits{
+		"o1":              {MaxConcurrent: 2},
+		"claude-*":        {MaxConcurrent: 8},
+		"claude-opus-*":   {MaxConcurrent: 1},
+		"gpt-4o-disabled": {MaxConcurrent: 0},
+	}
+	cases := map[string]string{
+		"O1":                "o1",
+		"claude-opus-4":     "claude-opus-*",
+		"claude-sonnet-4":   "claude-*",
+		"gpt-4o-disabled":   "",
+		"o1-mini":           "",
+		"":                  "",
+		"claude-opus-4-1-x": "claude-opus-*",
+	}
+	for model, want := range cases {
+		key, _, ok := limits.Lookup(model)
+		if key != want || ok != (want != "") {
+			t.Fatalf("Lookup(%q) = %q, %v; want %q", model, key, ok, want)
+		}
+	}
+}
+
+func TestModelGateQueuesInArrivalOrder(t *testing.T) {
+	gate := NewModelGate()
+	limit := ModelLimit{MaxConcurrent: 1, MaxQueue: 2, MaxWaitSeconds: 5}
+	ctx := context.Background()
+
+	release, errAcquire := gate.Acquire(ctx, "o1", limit)
+	if errAcquire != nil {
+		t.Fatalf("Acquire: %v", errAcquire)
+	}
+
+	order := make(chan int, 2)
+	releases := make(chan func(), 2)
+	for i := 1; i <= 2; i++ {
+		go func(n int) {
+			next, errWait := gate.Acquire(ctx, "o1", limit)
+			if errWait != nil {
+				t.Errorf("queued Acquire %d: %v", n, errWait)
+				return
+			}
+			order <- n
+			releases <- next
+		}(i)
+		waitForQueue(t, gate, "o1", i)
+	}
+
+	if _, errFull := gate.Acquire(ctx, "o1", limit); !errors.Is(errFull, ErrModelQueueFull) {
+		t.Fatalf("expected ErrModelQueueFull, got %v", errFull)
+	}
+
+	release()
+	release()
+	if first := <-order; first != 1 {
+		t.Fatalf("expected the oldest waiter first, got %d", first)
+	}
+	if inFlight, queued := gate.Stats("o1"); inFlight != 1 || queued != 1 {
+		t.Fatalf("expected the slot to be handed over, got %d in flight and %d queued", inFlight, queued)
+	}
+	(<-releases)()
+	if second := <-order; second != 2 {
+		t.Fatalf("expected the second waiter next, got %d", second)
+	}
+	(<-releases)()
+	if inFlight, queued := gate.Stats("o1"); inFlight != 0 || queued != 0 {
+		t.Fatalf("expected an idle gate, got %d in flight and %d queued", inFlight, queued)
+	}
+}
+
+func TestModelGateTimeoutAndCancel(t *testing.T) {
+	gate := NewModelGate()
+	limit := ModelLimit{MaxConcurrent: 1, MaxQueue: 1, MaxWaitSeconds: 1}
+
+	release, errAcquire := gate.Acquire(context.Background(), "opus", limit)
+	if errAcquire != nil {
+		t.Fatalf("Acquire: %v", errAcquire)
+	}
+	defer release()
+
+	start := time.Now()
+	if _, errWait := gate.Acquire(context.Background(), "opus", limit); !errors.Is(errWait, ErrModelQueueTimeout) {
+		t.Fatalf("expected ErrModelQueueTimeout, got %v", errWait)
+	}
+	if waited := time.Since(start); waited < time.Second {
+		t.Fatalf("expected to wait max_wait_seconds, waited %s", waited)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		waitForQueue(t, gate, "opus", 1)
+		cancel()
+	}()
+	if _, errWait := gate.Acquire(ctx, "opus", limit); !errors.Is(errWait, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", errWait)
+	}
+	if _, queued := gate.Stats("opus"); queued != 0 {
+		t.Fatalf("expected abandoned waiters to leave the queue, %d queued", queued)
+	}
+
+	noQueue := ModelLimit{MaxConcurrent: 1}
+	if _, errFull := gate.Acquire(context.Background(), "opus", noQueue); !errors.Is(errFull, ErrModelQueueFull) {
+		t.Fatalf("expected a zero max_queue to reject, got %v", errFull)
+	}
+}
+
+func waitForQueue(t *testing.T, gate *ModelGate, key string, depth int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, queued := gate.Stats(key); queued >= depth {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Errorf("queue for %s never reached %d", key, depth)
+}
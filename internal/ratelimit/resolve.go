@@ -8,7 +8,9 @@ import (
 
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelmapping"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/usergroup"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ResolveLimit resolves the effective rate limit based on priority order.
@@ -122,18 +124,11 @@ func loadUserGroupRateLimit(ctx context.Context, db *gorm.DB, groupID uint64) (i
 	if db == nil || groupID == 0 {
 		return 0, nil
 	}
-	var group models.UserGroup
-	if errFind := db.WithContext(ctx).
-		Model(&models.UserGroup{}).
-		Select("rate_limit").
-		Where("id = ?", groupID).
-		Take(&group).Error; errFind != nil {
-		if errors.Is(errFind, gorm.ErrRecordNotFound) {
-			return 0, nil
-		}
-		return 0, errFind
+	policy, errPolicy := usergroup.Resolve(ctx, db, groupID)
+	if errPolicy != nil {
+		return 0, errPolicy
 	}
-	return group.RateLimit, nil
+	return policy.RateLimit, nil
 }
 
 func loadAuthRateLimit(ctx context.Context, db *gorm.DB, authKey string) (int, *uint64, error) {
@@ -145,7 +140,7 @@ func loadAuthRateLimit(ctx context.Context, db *gorm.DB, authKey string) (int, *
 	if errFind := db.WithContext(ctx).
 		Model(&models.Auth{}).
 		Select("rate_limit", "auth_group_id").
-		Where("key = ?", authKey).
+		Where("? = ?", clause.Column{Name: "key"}, authKey).
 		Take(&auth).Error; errFind != nil {
 		if errors.Is(errFind, gorm.ErrRecordNotFound) {
 			return 0, nil, nil
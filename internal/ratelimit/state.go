@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// managerState is the runtime state a standby instance needs to keep enforcing limits.
+type managerState struct {
+	BreakerUntil *time.Time     `json:"breaker_until,omitempty"` // Redis circuit breaker open until.
+	Counters     []CounterState `json:"counters,omitempty"`      // In-memory fixed-window counters.
+}
+
+// ExportState snapshots the Redis circuit breaker and the in-memory counters.
+func (m *Manager) ExportState(now time.Time) (json.RawMessage, error) {
+	var state managerState
+	m.mu.Lock()
+	if !m.breakerUntil.IsZero() && now.Before(m.breakerUntil) {
+		until := m.breakerUntil.UTC()
+		state.BreakerUntil = &until
+	}
+	m.mu.Unlock()
+	if memory, ok := m.memoryLimiter.(*MemoryLimiter); ok {
+		state.Counters = memory.Export(now)
+	}
+	return json.Marshal(state)
+}
+
+// ImportState restores a snapshot taken by ExportState on another instance.
+func (m *Manager) ImportState(raw json.RawMessage, now time.Time) error {
+	var state managerState
+	if errUnmarshal := json.Unmarshal(raw, &state); errUnmarshal != nil {
+		return errUnmarshal
+	}
+	if state.BreakerUntil != nil && now.Before(*state.BreakerUntil) {
+		m.mu.Lock()
+		if m.breakerUntil.Before(*state.BreakerUntil) {
+			m.breakerUntil = *state.BreakerUntil
+		}
+		m.mu.Unlock()
+	}
+	if memory, ok := m.memoryLimiter.(*MemoryLimiter); ok {
+		memory.Import(state.Counters, now)
+	}
+	return nil
+}
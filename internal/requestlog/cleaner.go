@@ -0,0 +1,71 @@
+package requestlog
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// cleanEvery is how often expired rows are deleted.
+const cleanEvery = time.Hour
+
+// Cleaner deletes expired rows and their stored objects.
+type Cleaner struct {
+	db *gorm.DB
+}
+
+// NewCleaner constructs a retention cleaner; returns nil when db is nil.
+func NewCleaner(db *gorm.DB) *Cleaner {
+	if db == nil {
+		return nil
+	}
+	return &Cleaner{db: db}
+}
+
+// Start launches the cleanup loop in a background goroutine.
+func (c *Cleaner) Start(ctx context.Context) {
+	if c == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go c.run(ctx)
+	log.Info("request log retention cleaner started")
+}
+
+func (c *Cleaner) run(ctx context.Context) {
+	ticker := time.NewTicker(cleanEvery)
+	defer ticker.Stop()
+	for {
+		if deleted, errRun := c.RunOnce(ctx, LoadConfig(), time.Now().UTC()); errRun != nil {
+			log.WithError(errRun).Warn("request log retention cleaner: run failed")
+		} else if deleted > 0 {
+			log.Infof("request log retention cleaner: deleted %d rows", deleted)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce deletes the rows, and their stored objects, that reached their expiry or were
+// captured more than cfg.RetentionDays before now, so lowering the retention also shortens
+// rows captured earlier. Retention is enforced even while capture is disabled so old bodies
+// never linger.
+func (c *Cleaner) RunOnce(ctx context.Context, cfg Config, now time.Time) (int64, error) {
+	if c == nil || c.db == nil {
+		return 0, nil
+	}
+	cfg = cfg.withDefaults()
+	expired, errExpired := Purge(ctx, c.db, PurgeFilter{ExpiredBy: now})
+	if errExpired != nil {
+		return expired, errExpired
+	}
+	aged, errAged := Purge(ctx, c.db, PurgeFilter{Before: now.AddDate(0, 0, -cfg.RetentionDays)})
+	return expired + aged, errAged
+}
@@ -0,0 +1,187 @@
+package requestlog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/payloadstore"
+	"gorm.io/gorm"
+)
+
+// purgeBatchSize bounds how many rows with stored objects are deleted per query.
+const purgeBatchSize = 500
+
+// payload is the object written for one captured request.
+type payload struct {
+	RequestBody  string `json:"request_body"`
+	ResponseBody string `json:"response_body"`
+}
+
+// payloadKey places the bodies of a row by capture day, so prefix listings and bucket
+// lifecycle rules can work per day.
+func payloadKey(row *models.RequestLog) string {
+	return fmt.Sprintf("request-logs/%s/%d.json.gz", row.CreatedAt.UTC().Format("2006/01/02"), row.ID)
+}
+
+// Save stores a captured request that expires retentionDays after now. With an object store
+// installed the bodies are written to it and the row keeps only the key; when the upload
+// fails the row is kept without bodies rather than falling back to the database.
+func Save(ctx context.Context, db *gorm.DB, row *models.RequestLog, retentionDays int, now time.Time) error {
+	if db == nil || row == nil {
+		return errors.New("request log: nil db or row")
+	}
+	now = now.UTC()
+	row.CreatedAt = now
+	expiresAt := now.AddDate(0, 0, Config{RetentionDays: retentionDays}.withDefaults().RetentionDays)
+	row.ExpiresAt = &expiresAt
+
+	store := payloadstore.Default()
+	if store == nil {
+		return db.WithContext(ctx).Create(row).Error
+	}
+	body := payload{RequestBody: row.RequestBody, ResponseBody: row.ResponseBody}
+	row.RequestBody, row.ResponseBody = "", ""
+	if errCreate := db.WithContext(ctx).Create(row).Error; errCreate != nil {
+		return errCreate
+	}
+	encoded, errEncode := encodePayload(body)
+	if errEncode == nil {
+		key := payloadKey(row)
+		if errEncode = store.Put(ctx, key, encoded); errEncode == nil {
+			row.PayloadBackend, row.PayloadKey, row.PayloadBytes = store.Backend(), key, int64(len(encoded))
+			return db.WithContext(ctx).Model(row).UpdateColumns(map[string]any{
+				"payload_backend": row.PayloadBackend,
+				"payload_key":     row.PayloadKey,
+				"payload_bytes":   row.PayloadBytes,
+			}).Error
+		}
+	}
+	return fmt.Errorf("request log: store bodies of row %d: %w", row.ID, errEncode)
+}
+
+// Bodies returns the request and response bodies of a row, reading them from the object
+// store when the row references one.
+func Bodies(ctx context.Context, row *models.RequestLog) (string, string, error) {
+	if row == nil {
+		return "", "", nil
+	}
+	if row.PayloadKey == "" {
+		return row.RequestBody, row.ResponseBody, nil
+	}
+	store, errStore := storeFor(row.PayloadBackend)
+	if errStore != nil {
+		return "", "", errStore
+	}
+	raw, errGet := store.Get(ctx, row.PayloadKey)
+	if errGet != nil {
+		return "", "", fmt.Errorf("request log: load bodies of row %d: %w", row.ID, errGet)
+	}
+	body, errDecode := decodePayload(raw)
+	if errDecode != nil {
+		return "", "", fmt.Errorf("request log: decode bodies of row %d: %w", row.ID, errDecode)
+	}
+	return body.RequestBody, body.ResponseBody, nil
+}
+
+// deletePayload removes the stored object of a row, if it has one.
+func deletePayload(ctx context.Context, backend, key string) error {
+	if key == "" {
+		return nil
+	}
+	store, errStore := storeFor(backend)
+	if errStore != nil {
+		return errStore
+	}
+	return store.Delete(ctx, key)
+}
+
+// storeFor returns the installed store when it matches the backend a row was written to.
+func storeFor(backend string) (payloadstore.Store, error) {
+	store := payloadstore.Default()
+	if store == nil || store.Backend() != backend {
+		return nil, fmt.Errorf("request log: payload store %q is not configured", backend)
+	}
+	return store, nil
+}
+
+// purgeStored deletes the objects and rows of stored payloads selected by q. Rows whose
+// object cannot be deleted are kept so a later run retries them.
+func purgeStored(ctx context.Context, q *gorm.DB) (int64, error) {
+	var (
+		deleted int64
+		afterID uint64
+		errs    []error
+	)
+	for {
+		var rows []models.RequestLog
+		if errFind := q.Session(&gorm.Session{}).
+			Select("id", "payload_backend", "payload_key").
+			Where("payload_key <> ''").
+			Where("id > ?", afterID).
+			Order("id ASC").
+			Limit(purgeBatchSize).
+			Find(&rows).Error; errFind != nil {
+			return deleted, errFind
+		}
+		if len(rows) == 0 {
+			return deleted, nil
+		}
+		ids := make([]uint64, 0, len(rows))
+		for _, row := range rows {
+			afterID = row.ID
+			if errDelete := deletePayload(ctx, row.PayloadBackend, row.PayloadKey); errDelete != nil {
+				errs = append(errs, errDelete)
+				continue
+			}
+			ids = append(ids, row.ID)
+		}
+		if len(ids) > 0 {
+			result := q.Session(&gorm.Session{NewDB: true}).Where("id IN ?", ids).Delete(&models.RequestLog{})
+			if result.Error != nil {
+				return deleted, result.Error
+			}
+			deleted += result.RowsAffected
+		}
+		if len(errs) > 0 {
+			// The store is failing; leave the rest for the next run.
+			return deleted, errors.Join(errs...)
+		}
+	}
+}
+
+func encodePayload(body payload) ([]byte, error) {
+	raw, errMarshal := json.Marshal(body)
+	if errMarshal != nil {
+		return nil, errMarshal
+	}
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, errWrite := writer.Write(raw); errWrite != nil {
+		return nil, errWrite
+	}
+	if errClose := writer.Close(); errClose != nil {
+		return nil, errClose
+	}
+	return buf.Bytes(), nil
+}
+
+func decodePayload(raw []byte) (payload, error) {
+	var body payload
+	reader, errReader := gzip.NewReader(bytes.NewReader(raw))
+	if errReader != nil {
+		return body, errReader
+	}
+	defer func() { _ = reader.Close() }()
+	decoded, errRead := io.ReadAll(reader)
+	if errRead != nil {
+		return body, errRead
+	}
+	return body, json.Unmarshal(decoded, &body)
+}
@@ -0,0 +1,222 @@
+// Package requestlog captures the request and response bodies of proxy requests for debugging
+// customer reports. Capture is off unless the REQUEST_LOG setting enables it globally or the
+// requesting user belongs to a user group with request logging enabled. Bodies are truncated
+// before storage, kept in the payload store when one is configured, and rows never outlive
+// the retention window.
+package requestlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/usergroup"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultMaxBodyBytes is how much of each body is kept when the setting leaves it unset.
+	DefaultMaxBodyBytes = 16 * 1024
+	// MaxBodyBytesLimit caps the configured body size.
+	MaxBodyBytesLimit = 1024 * 1024
+	// DefaultRetentionDays is how long rows are kept when the setting leaves it unset.
+	DefaultRetentionDays = 7
+	// MaxRetentionDays caps the configured retention; captured bodies may hold customer data.
+	MaxRetentionDays = 30
+
+	// groupCacheTTL bounds how long the set of logging user groups is cached.
+	groupCacheTTL = 30 * time.Second
+)
+
+// Config mirrors the REQUEST_LOG setting.
+type Config struct {
+	Enabled       bool `json:"enabled"`        // Capture every user's requests.
+	MaxBodyBytes  int  `json:"max_body_bytes"` // Bytes kept of each request and response body.
+	RetentionDays int  `json:"retention_days"` // Days rows are kept, at most MaxRetentionDays.
+}
+
+// LoadConfig reads REQUEST_LOG and fills defaults; an invalid value disables global capture.
+func LoadConfig() Config {
+	var cfg Config
+	raw, ok := internalsettings.DBConfigValue(internalsettings.RequestLogKey)
+	if ok && len(bytes.TrimSpace(raw)) > 0 {
+		if errUnmarshal := json.Unmarshal(raw, &cfg); errUnmarshal != nil {
+			log.WithError(errUnmarshal).Warn("request log: invalid setting")
+			cfg = Config{}
+		}
+	}
+	return cfg.withDefaults()
+}
+
+func (c Config) withDefaults() Config {
+	if c.MaxBodyBytes <= 0 {
+		c.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if c.MaxBodyBytes > MaxBodyBytesLimit {
+		c.MaxBodyBytes = MaxBodyBytesLimit
+	}
+	if c.RetentionDays <= 0 {
+		c.RetentionDays = DefaultRetentionDays
+	}
+	if c.RetentionDays > MaxRetentionDays {
+		c.RetentionDays = MaxRetentionDays
+	}
+	return c
+}
+
+// groupCache remembers which user groups enable logging so requests of other users cost no
+// extra queries.
+var groupCache struct {
+	mu       sync.Mutex
+	loadedAt time.Time
+	any      bool
+}
+
+// anyGroupEnabled reports whether at least one user group enables request logging.
+func anyGroupEnabled(ctx context.Context, db *gorm.DB) bool {
+	groupCache.mu.Lock()
+	defer groupCache.mu.Unlock()
+	if !groupCache.loadedAt.IsZero() && time.Since(groupCache.loadedAt) < groupCacheTTL {
+		return groupCache.any
+	}
+	var count int64
+	if errCount := db.WithContext(ctx).Model(&models.UserGroup{}).
+		Where("request_log_enabled = ?", true).
+		Count(&count).Error; errCount != nil {
+		log.WithError(errCount).Debug("request log: count logging groups failed")
+		return false
+	}
+	groupCache.loadedAt = time.Now()
+	groupCache.any = count > 0
+	return groupCache.any
+}
+
+// InvalidateGroups drops the cached group state after a user group changes.
+func InvalidateGroups() {
+	groupCache.mu.Lock()
+	groupCache.loadedAt = time.Time{}
+	groupCache.mu.Unlock()
+}
+
+// MayCapture reports whether any request could currently be captured, letting callers skip
+// per-request lookups while logging is off everywhere.
+func MayCapture(ctx context.Context, db *gorm.DB, cfg Config) bool {
+	if db == nil {
+		return false
+	}
+	return cfg.Enabled || anyGroupEnabled(ctx, db)
+}
+
+// EnabledForUser reports whether the user's requests are captured: globally, or because one
+// of their user groups or its ancestors enables logging.
+func EnabledForUser(ctx context.Context, db *gorm.DB, cfg Config, userID uint64) (bool, error) {
+	if cfg.Enabled {
+		return true, nil
+	}
+	if db == nil || userID == 0 {
+		return false, nil
+	}
+	var user models.User
+	if errFind := db.WithContext(ctx).Select("id", "user_group_id").
+		Where("id = ?", userID).
+		Take(&user).Error; errFind != nil {
+		return false, fmt.Errorf("request log: load user %d: %w", userID, errFind)
+	}
+	for _, groupID := range user.UserGroupID.Values() {
+		policy, errPolicy := usergroup.Resolve(ctx, db, groupID)
+		if errPolicy != nil {
+			return false, fmt.Errorf("request log: resolve user group %d: %w", groupID, errPolicy)
+		}
+		if policy.RequestLog {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Truncate keeps at most limit bytes of body without splitting a UTF-8 sequence and reports
+// whether anything was cut. Invalid UTF-8 is replaced so the result stores as text.
+func Truncate(body []byte, limit int) (string, bool) {
+	truncated := false
+	if limit > 0 && len(body) > limit {
+		cut := limit
+		for cut > 0 && cut > limit-utf8.UTFMax && !utf8.RuneStart(body[cut]) {
+			cut--
+		}
+		body = body[:cut]
+		truncated = true
+	}
+	return strings.ToValidUTF8(string(body), "�"), truncated
+}
+
+// ModelOf returns the "model" field of a JSON request body, or "" when there is none.
+func ModelOf(body []byte) string {
+	var payload struct {
+		Model string `json:"model"`
+	}
+	if errUnmarshal := json.Unmarshal(body, &payload); errUnmarshal != nil {
+		return ""
+	}
+	return strings.TrimSpace(payload.Model)
+}
+
+// Redact clears the bodies of one row and deletes their stored object; the row itself stays
+// so its metadata remains linked to usage. It returns gorm.ErrRecordNotFound for an unknown id.
+func Redact(ctx context.Context, db *gorm.DB, id uint64, now time.Time) error {
+	var row models.RequestLog
+	if errFind := db.WithContext(ctx).Select("id", "payload_backend", "payload_key").
+		Where("id = ?", id).
+		Take(&row).Error; errFind != nil {
+		return errFind
+	}
+	if errDelete := deletePayload(ctx, row.PayloadBackend, row.PayloadKey); errDelete != nil {
+		return errDelete
+	}
+	return db.WithContext(ctx).Model(&models.RequestLog{}).Where("id = ?", id).
+		Updates(map[string]any{
+			"request_body":       "",
+			"response_body":      "",
+			"request_truncated":  false,
+			"response_truncated": false,
+			"payload_backend":    "",
+			"payload_key":        "",
+			"payload_bytes":      0,
+			"redacted_at":        now,
+		}).Error
+}
+
+// PurgeFilter selects the rows Purge deletes. A zero filter deletes every row.
+type PurgeFilter struct {
+	Before    time.Time // Rows captured before this time; zero ignores the time.
+	ExpiredBy time.Time // Rows whose expiry is at or before this time; zero ignores expiry.
+	UserID    uint64    // Rows of this user; zero ignores the user.
+}
+
+// Purge deletes the rows matching the filter, and their stored objects, and returns how many
+// rows were removed.
+func Purge(ctx context.Context, db *gorm.DB, filter PurgeFilter) (int64, error) {
+	q := db.WithContext(ctx).Model(&models.RequestLog{}).Where("1 = 1")
+	if !filter.Before.IsZero() {
+		q = q.Where("created_at < ?", filter.Before)
+	}
+	if !filter.ExpiredBy.IsZero() {
+		q = q.Where("expires_at <= ?", filter.ExpiredBy)
+	}
+	if filter.UserID != 0 {
+		q = q.Where("user_id = ?", filter.UserID)
+	}
+	deleted, errStored := purgeStored(ctx, q)
+	if errStored != nil {
+		return deleted, errStored
+	}
+	result := q.Session(&gorm.Session{}).Where("(payload_key IS NULL OR payload_key = '')").Delete(&models.RequestLog{})
+	return deleted + result.RowsAffected, result.Error
+}
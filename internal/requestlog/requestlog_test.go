@@ -0,0 +1,202 @@
+package requestlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/payloadstore"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:requestlog_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func TestConfigDefaultsAndCaps(t *testing.T) {
+	cfg := Config{}.withDefaults()
+	if cfg.MaxBodyBytes != DefaultMaxBodyBytes || cfg.RetentionDays != DefaultRetentionDays {
+		t.Fatalf("unexpected defaults %+v", cfg)
+	}
+	cfg = Config{MaxBodyBytes: 10 * MaxBodyBytesLimit, RetentionDays: 365}.withDefaults()
+	if cfg.MaxBodyBytes != MaxBodyBytesLimit || cfg.RetentionDays != MaxRetentionDays {
+		t.Fatalf("expected caps, got %+v", cfg)
+	}
+}
+
+func TestTruncateKeepsRuneBoundaries(t *testing.T) {
+	body, truncated := Truncate([]byte("héllo"), 2)
+	if body != "h" || !truncated {
+		t.Fatalf("expected cut before the multi-byte rune, got %q truncated=%v", body, truncated)
+	}
+	body, truncated = Truncate([]byte("hello"), 10)
+	if body != "hello" || truncated {
+		t.Fatalf("unexpected result %q truncated=%v", body, truncated)
+	}
+	if body, _ = Truncate([]byte{'a', 0xff}, 10); body != "a�" {
+		t.Fatalf("expected invalid bytes replaced, got %q", body)
+	}
+	if model := ModelOf([]byte(`{"model":" gpt-5 ","messages":[]}`)); model != "gpt-5" {
+		t.Fatalf("unexpected model %q", model)
+	}
+}
+
+func TestEnabledForUserFollowsGroupAncestors(t *testing.T) {
+	conn := openTestDB(t)
+	ctx := context.Background()
+	InvalidateGroups()
+	t.Cleanup(InvalidateGroups)
+
+	parent := models.UserGroup{Name: "parent", RequestLogEnabled: true}
+	if errCreate := conn.Create(&parent).Error; errCreate != nil {
+		t.Fatalf("create parent: %v", errCreate)
+	}
+	child := models.UserGroup{Name: "child", ParentID: &parent.ID}
+	other := models.UserGroup{Name: "other"}
+	for _, group := range []*models.UserGroup{&child, &other} {
+		if errCreate := conn.Create(group).Error; errCreate != nil {
+			t.Fatalf("create group: %v", errCreate)
+		}
+	}
+	logged := models.User{Username: "logged", Email: "logged@example.com", Password: "x", UserGroupID: models.UserGroupIDs{&child.ID}}
+	plain := models.User{Username: "plain", Email: "plain@example.com", Password: "x", UserGroupID: models.UserGroupIDs{&other.ID}}
+	for _, user := range []*models.User{&logged, &plain} {
+		if errCreate := conn.Create(user).Error; errCreate != nil {
+			t.Fatalf("create user: %v", errCreate)
+		}
+	}
+
+	cfg := Config{}.withDefaults()
+	if !MayCapture(ctx, conn, cfg) {
+		t.Fatal("expected capture to be possible while a group enables it")
+	}
+	if enabled, errEnabled := EnabledForUser(ctx, conn, cfg, logged.ID); errEnabled != nil || !enabled {
+		t.Fatalf("expected inherited logging, got %v %v", enabled, errEnabled)
+	}
+	if enabled, errEnabled := EnabledForUser(ctx, conn, cfg, plain.ID); errEnabled != nil || enabled {
+		t.Fatalf("expected no logging, got %v %v", enabled, errEnabled)
+	}
+	cfg.Enabled = true
+	if enabled, _ := EnabledForUser(ctx, conn, cfg, plain.ID); !enabled {
+		t.Fatal("expected global logging to cover every user")
+	}
+}
+
+func TestRedactPurgeAndRetention(t *testing.T) {
+	conn := openTestDB(t)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	userID := uint64(5)
+
+	rows := []models.RequestLog{
+		{RequestID: "old", Method: "POST", Path: "/v1/chat/completions", RequestBody: "a", CreatedAt: now.AddDate(0, 0, -40)},
+		{RequestID: "recent", UserID: &userID, Method: "POST", Path: "/v1/chat/completions", RequestBody: "secret", ResponseBody: "reply", CreatedAt: now.Add(-time.Hour)},
+		{RequestID: "other", Method: "POST", Path: "/v1/messages", CreatedAt: now.Add(-time.Minute)},
+	}
+	for i := range rows {
+		if errCreate := conn.Create(&rows[i]).Error; errCreate != nil {
+			t.Fatalf("create row: %v", errCreate)
+		}
+	}
+
+	if errRedact := Redact(ctx, conn, rows[1].ID, now); errRedact != nil {
+		t.Fatalf("redact: %v", errRedact)
+	}
+	var redacted models.RequestLog
+	if errFind := conn.First(&redacted, rows[1].ID).Error; errFind != nil {
+		t.Fatalf("load redacted: %v", errFind)
+	}
+	if redacted.RequestBody != "" || redacted.ResponseBody != "" || redacted.RedactedAt == nil || redacted.RequestID != "recent" {
+		t.Fatalf("unexpected redacted row %+v", redacted)
+	}
+	if errMissing := Redact(ctx, conn, 9999, now); errMissing != gorm.ErrRecordNotFound {
+		t.Fatalf("expected not found, got %v", errMissing)
+	}
+
+	deleted, errRun := NewCleaner(conn).RunOnce(ctx, Config{RetentionDays: 90}, now)
+	if errRun != nil || deleted != 1 {
+		t.Fatalf("expected retention capped to delete the old row, got %d %v", deleted, errRun)
+	}
+	deleted, errPurge := Purge(ctx, conn, PurgeFilter{UserID: userID})
+	if errPurge != nil || deleted != 1 {
+		t.Fatalf("expected user purge to delete one row, got %d %v", deleted, errPurge)
+	}
+	var remaining []models.RequestLog
+	if errFind := conn.Find(&remaining).Error; errFind != nil {
+		t.Fatalf("load remaining: %v", errFind)
+	}
+	if len(remaining) != 1 || !strings.EqualFold(remaining[0].RequestID, "other") {
+		t.Fatalf("unexpected remaining rows %+v", remaining)
+	}
+}
+
+func TestSaveKeepsBodiesInObjectStoreUntilPurged(t *testing.T) {
+	conn := openTestDB(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, errStore := payloadstore.New(payloadstore.Options{Backend: payloadstore.BackendLocal, Dir: dir})
+	if errStore != nil {
+		t.Fatalf("new store: %v", errStore)
+	}
+	payloadstore.SetDefault(store)
+	defer payloadstore.SetDefault(nil)
+
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	kept := models.RequestLog{RequestBody: `{"model":"gpt-5"}`, ResponseBody: "ok"}
+	if errSave := Save(ctx, conn, &kept, 7, now); errSave != nil {
+		t.Fatalf("save: %v", errSave)
+	}
+	var stored models.RequestLog
+	if errFind := conn.First(&stored, kept.ID).Error; errFind != nil {
+		t.Fatalf("load row: %v", errFind)
+	}
+	if stored.RequestBody != "" || stored.PayloadBackend != payloadstore.BackendLocal || stored.PayloadKey != "request-logs/2026/03/10/1.json.gz" {
+		t.Fatalf("expected only a reference in the row, got %+v", stored)
+	}
+	if stored.ExpiresAt == nil || !stored.ExpiresAt.Equal(now.AddDate(0, 0, 7)) {
+		t.Fatalf("unexpected expiry %v", stored.ExpiresAt)
+	}
+	requestBody, responseBody, errBodies := Bodies(ctx, &stored)
+	if errBodies != nil || requestBody != `{"model":"gpt-5"}` || responseBody != "ok" {
+		t.Fatalf("unexpected bodies %q %q %v", requestBody, responseBody, errBodies)
+	}
+
+	if errRedact := Redact(ctx, conn, kept.ID, now); errRedact != nil {
+		t.Fatalf("redact: %v", errRedact)
+	}
+	if _, errGet := store.Get(ctx, stored.PayloadKey); !errors.Is(errGet, payloadstore.ErrNotFound) {
+		t.Fatalf("expected redact to delete the object, got %v", errGet)
+	}
+
+	expired := models.RequestLog{RequestBody: "a", ResponseBody: "b"}
+	if errSave := Save(ctx, conn, &expired, 1, now); errSave != nil {
+		t.Fatalf("save: %v", errSave)
+	}
+	deleted, errPurge := Purge(ctx, conn, PurgeFilter{ExpiredBy: now.AddDate(0, 0, 2)})
+	if errPurge != nil || deleted != 1 {
+		t.Fatalf("expected the expired row purged, got %d %v", deleted, errPurge)
+	}
+	if _, errGet := store.Get(ctx, expired.PayloadKey); !errors.Is(errGet, payloadstore.ErrNotFound) {
+		t.Fatalf("expected purge to delete the object, got %v", errGet)
+	}
+	var remaining int64
+	conn.Model(&models.RequestLog{}).Count(&remaining)
+	if remaining != 1 {
+		t.Fatalf("expected the redacted row to remain, got %d rows", remaining)
+	}
+}
@@ -0,0 +1,116 @@
+package retryafter
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimitHeaders returns the upstream rate-limit and quota headers of a response keyed by
+// lower-case name, so they can be stored with the failed request and read back later.
+func RateLimitHeaders(header http.Header) map[string]string {
+	out := make(map[string]string)
+	for name, values := range header {
+		lower := strings.ToLower(name)
+		if len(values) == 0 || !isRateLimitHeader(lower) {
+			continue
+		}
+		out[lower] = strings.TrimSpace(values[0])
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func isRateLimitHeader(name string) bool {
+	if name == "retry-after" {
+		return true
+	}
+	for _, marker := range []string{"ratelimit", "rate-limit", "quota", "reset"} {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// FromResetHeaders returns the longest reset among the limits that the stored headers report
+// as exhausted: an OpenAI/Anthropic style "remaining" counterpart of 0, a Codex used-percent
+// of 100, or a reset without any counterpart. Resets are read as durations ("6m0s"), seconds,
+// unix timestamps, RFC 3339 or HTTP dates.
+func FromResetHeaders(headers map[string]string, now time.Time) (time.Duration, bool) {
+	var (
+		longest time.Duration
+		found   bool
+	)
+	for name, value := range headers {
+		if !strings.Contains(name, "reset") || !limitExhausted(headers, name) {
+			continue
+		}
+		wait, ok := parseReset(name, value, now)
+		if !ok {
+			continue
+		}
+		if !found || wait > longest {
+			longest, found = wait, true
+		}
+	}
+	return longest, found
+}
+
+// limitExhausted reports whether the limit behind reset header name has run out.
+func limitExhausted(headers map[string]string, name string) bool {
+	if prefix, ok := strings.CutSuffix(name, "-reset-after-seconds"); ok {
+		if used, okUsed := headers[prefix+"-used-percent"]; okUsed {
+			percent, errParse := strconv.ParseFloat(strings.TrimSpace(used), 64)
+			return errParse == nil && percent >= 100
+		}
+		return true
+	}
+	remaining, ok := headers[strings.Replace(name, "reset", "remaining", 1)]
+	if !ok {
+		return true
+	}
+	count, errParse := strconv.ParseFloat(strings.TrimSpace(remaining), 64)
+	return errParse == nil && count <= 0
+}
+
+func parseReset(name, value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if number, errParse := strconv.ParseFloat(value, 64); errParse == nil {
+		if number < 0 || math.IsNaN(number) || math.IsInf(number, 0) {
+			return 0, false
+		}
+		switch {
+		case strings.HasSuffix(name, "seconds") || number < 1e9:
+			return time.Duration(number * float64(time.Second)), true
+		case number >= 1e12:
+			return clampPast(time.UnixMilli(int64(number)).Sub(now)), true
+		default:
+			return clampPast(time.Unix(int64(number), 0).Sub(now)), true
+		}
+	}
+	if wait, errParse := time.ParseDuration(value); errParse == nil && wait >= 0 {
+		return wait, true
+	}
+	if at, errParse := time.Parse(time.RFC3339, value); errParse == nil {
+		return clampPast(at.Sub(now)), true
+	}
+	if at, errParse := http.ParseTime(value); errParse == nil {
+		return clampPast(at.Sub(now)), true
+	}
+	return 0, false
+}
+
+func clampPast(wait time.Duration) time.Duration {
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
@@ -0,0 +1,85 @@
+package retryafter
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimitHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-Ratelimit-Reset-Requests", "6m0s")
+	header.Set("Anthropic-Ratelimit-Tokens-Remaining", "0")
+	header.Set("X-Codex-Primary-Reset-After-Seconds", "120")
+	header.Set("Content-Type", "application/json")
+
+	got := RateLimitHeaders(header)
+	if len(got) != 3 {
+		t.Fatalf("expected 3 rate-limit headers, got %v", got)
+	}
+	if got["x-ratelimit-reset-requests"] != "6m0s" {
+		t.Fatalf("expected lower-cased header names, got %v", got)
+	}
+	if RateLimitHeaders(http.Header{"Content-Type": {"text/plain"}}) != nil {
+		t.Fatalf("expected nil when no rate-limit headers are present")
+	}
+}
+
+func TestFromResetHeaders(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name    string
+		headers map[string]string
+		want    time.Duration
+		wantOK  bool
+	}{
+		{
+			name: "openai exhausted dimension wins",
+			headers: map[string]string{
+				"x-ratelimit-remaining-requests": "12",
+				"x-ratelimit-reset-requests":     "2s",
+				"x-ratelimit-remaining-tokens":   "0",
+				"x-ratelimit-reset-tokens":       "6m0s",
+			},
+			want: 6 * time.Minute, wantOK: true,
+		},
+		{
+			name: "anthropic rfc3339",
+			headers: map[string]string{
+				"anthropic-ratelimit-requests-remaining": "0",
+				"anthropic-ratelimit-requests-reset":     now.Add(90 * time.Second).Format(time.RFC3339),
+			},
+			want: 90 * time.Second, wantOK: true,
+		},
+		{
+			name: "codex used percent",
+			headers: map[string]string{
+				"x-codex-primary-used-percent":          "40",
+				"x-codex-primary-reset-after-seconds":   "300",
+				"x-codex-secondary-used-percent":        "100",
+				"x-codex-secondary-reset-after-seconds": "86400",
+			},
+			want: 24 * time.Hour, wantOK: true,
+		},
+		{
+			name:    "unix timestamp without counterpart",
+			headers: map[string]string{"x-ratelimit-reset": strconv.FormatInt(now.Add(time.Hour).Unix(), 10)},
+			want:    time.Hour, wantOK: true,
+		},
+		{
+			name: "nothing exhausted",
+			headers: map[string]string{
+				"x-ratelimit-remaining-requests": "3",
+				"x-ratelimit-reset-requests":     "1s",
+			},
+			wantOK: false,
+		},
+	}
+	for _, tc := range cases {
+		got, ok := FromResetHeaders(tc.headers, now)
+		if ok != tc.wantOK || got != tc.want {
+			t.Fatalf("%s: FromResetHeaders = (%s, %v), want (%s, %v)", tc.name, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Package retryafter extracts upstream back-off hints from rate-limited responses and
+// turns them into a sanitized Retry-After value for downstream clients.
+package retryafter
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header is the HTTP header carrying the back-off hint.
+const Header = "Retry-After"
+
+// MaxSeconds caps the Retry-After value forwarded to clients so a misbehaving upstream
+// cannot park them indefinitely.
+const MaxSeconds = 3600
+
+// Parse reads a Retry-After header value given as delta-seconds or an HTTP date.
+func Parse(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, errParse := strconv.ParseFloat(value, 64); errParse == nil {
+		if seconds < 0 || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+			return 0, false
+		}
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+	if at, errParse := http.ParseTime(value); errParse == nil {
+		wait := at.Sub(now)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// bodyKeys lists the JSON fields upstream providers use for the back-off, in seconds.
+var bodyKeys = []string{"retry_after", "retry_after_seconds", "reset_seconds", "resets_in_seconds"}
+
+// FromBody looks for a back-off hint inside an upstream error body. It understands plain
+// second counters at the top level or under "error", and Google RetryInfo details.
+func FromBody(body []byte) (time.Duration, bool) {
+	if len(body) == 0 || !json.Valid(body) {
+		return 0, false
+	}
+	var payload map[string]any
+	if errUnmarshal := json.Unmarshal(body, &payload); errUnmarshal != nil {
+		return 0, false
+	}
+	scopes := []map[string]any{payload}
+	if nested, ok := payload["error"].(map[string]any); ok {
+		scopes = append(scopes, nested)
+	}
+	for _, scope := range scopes {
+		for _, key := range bodyKeys {
+			if wait, ok := secondsValue(scope[key]); ok {
+				return wait, true
+			}
+		}
+		details, _ := scope["details"].([]any)
+		for _, detail := range details {
+			entry, ok := detail.(map[string]any)
+			if !ok {
+				continue
+			}
+			if delay, ok := entry["retryDelay"].(string); ok {
+				if wait, errParse := time.ParseDuration(strings.TrimSpace(delay)); errParse == nil && wait >= 0 {
+					return wait, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+func secondsValue(value any) (time.Duration, bool) {
+	switch typed := value.(type) {
+	case float64:
+		if typed < 0 {
+			return 0, false
+		}
+		return time.Duration(typed * float64(time.Second)), true
+	case string:
+		seconds, errParse := strconv.ParseFloat(strings.TrimSpace(typed), 64)
+		if errParse != nil || seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds * float64(time.Second)), true
+	default:
+		return 0, false
+	}
+}
+
+// Seconds rounds wait up to whole seconds and clamps it to [1, MaxSeconds].
+func Seconds(wait time.Duration) int {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+	if seconds > MaxSeconds {
+		return MaxSeconds
+	}
+	return seconds
+}
+
+// Resolve returns the sanitized Retry-After seconds for a 429 response from its header
+// value, falling back to hints in the body. ok is false when neither carries one.
+func Resolve(header string, body []byte, now time.Time) (int, bool) {
+	if wait, ok := Parse(header, now); ok {
+		return Seconds(wait), true
+	}
+	if wait, ok := FromBody(body); ok {
+		return Seconds(wait), true
+	}
+	return 0, false
+}
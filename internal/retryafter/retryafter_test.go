@@ -0,0 +1,35 @@
+package retryafter
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestResolve(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name   string
+		header string
+		body   string
+		want   int
+		wantOK bool
+	}{
+		{name: "delta seconds", header: "30", want: 30, wantOK: true},
+		{name: "fractional rounds up", header: "1.2", want: 2, wantOK: true},
+		{name: "http date", header: now.Add(90 * time.Second).Format(http.TimeFormat), want: 90, wantOK: true},
+		{name: "past date floors at one", header: now.Add(-time.Minute).Format(http.TimeFormat), want: 1, wantOK: true},
+		{name: "capped", header: "86400", want: MaxSeconds, wantOK: true},
+		{name: "garbage header falls back to body", header: "soon", body: `{"error":{"reset_seconds":12}}`, want: 12, wantOK: true},
+		{name: "codex resets_in_seconds", body: `{"error":{"type":"usage_limit_reached","resets_in_seconds":"45"}}`, want: 45, wantOK: true},
+		{name: "gemini retry info", body: `{"error":{"code":429,"details":[{"@type":"type.googleapis.com/google.rpc.RetryInfo","retryDelay":"7.5s"}]}}`, want: 8, wantOK: true},
+		{name: "no hint", body: `{"error":{"message":"slow down"}}`, wantOK: false},
+		{name: "negative ignored", header: "-5", wantOK: false},
+	}
+	for _, tc := range cases {
+		got, ok := Resolve(tc.header, []byte(tc.body), now)
+		if ok != tc.wantOK || got != tc.want {
+			t.Fatalf("%s: Resolve = (%d, %v), want (%d, %v)", tc.name, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+// Package retrypolicy applies the admin-managed retry policies. When an upstream request
+// fails, the SDK picks the provider's next credential and tries again; the auth selector asks
+// Before whether that retry may happen, and the auth hook reports every attempt's outcome.
+package retrypolicy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/metrics"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+)
+
+// Reasons a policy stops retrying, also used as metric events.
+const (
+	ReasonMaxRetries       = "max_retries"
+	ReasonNotRetryable     = "not_retryable"
+	ReasonFailoverDisabled = "failover_disabled"
+)
+
+// attemptsKey stores the request's attempt tracker on the gin context.
+const attemptsKey = "retryPolicyAttempts"
+
+// Policy is the runtime form of a models.RetryPolicy.
+type Policy struct {
+	Provider             string        // Provider name, or models.RetryPolicyDefaultProvider.
+	MaxRetries           int           // Retries allowed after the first attempt.
+	RetryableStatusCodes []int         // Upstream statuses worth retrying; empty retries any failure.
+	BackoffInitial       time.Duration // Delay before the first retry.
+	BackoffMax           time.Duration // Upper bound of the retry delay; zero leaves it unbounded.
+	FailoverToNextKey    bool          // Whether retries may move to another credential.
+}
+
+// Retryable reports whether a failure with the upstream status may be retried. Failures
+// without a status, such as network errors, are always retryable.
+func (p Policy) Retryable(status int) bool {
+	if len(p.RetryableStatusCodes) == 0 || status <= 0 {
+		return true
+	}
+	return slices.Contains(p.RetryableStatusCodes, status)
+}
+
+// Backoff returns the delay before the given retry, counted from 1: the initial delay
+// doubled on every further retry and capped at BackoffMax.
+func (p Policy) Backoff(retry int) time.Duration {
+	if p.BackoffInitial <= 0 || retry <= 0 {
+		return 0
+	}
+	delay := p.BackoffInitial
+	for i := 1; i < retry; i++ {
+		delay *= 2
+		if p.BackoffMax > 0 && delay >= p.BackoffMax {
+			return p.BackoffMax
+		}
+	}
+	if p.BackoffMax > 0 && delay > p.BackoffMax {
+		return p.BackoffMax
+	}
+	return delay
+}
+
+// StatusCodes decodes a policy's retryable status codes, ignoring invalid values.
+func StatusCodes(raw []byte) []int {
+	if len(raw) == 0 {
+		return nil
+	}
+	var codes []int
+	if errUnmarshal := json.Unmarshal(raw, &codes); errUnmarshal != nil {
+		return nil
+	}
+	return codes
+}
+
+// FromModel converts an enabled policy row; disabled rows return false.
+func FromModel(row models.RetryPolicy) (Policy, bool) {
+	provider := normalizeProvider(row.Provider)
+	if !row.IsEnabled || provider == "" {
+		return Policy{}, false
+	}
+	return Policy{
+		Provider:             provider,
+		MaxRetries:           max(row.MaxRetries, 0),
+		RetryableStatusCodes: StatusCodes(row.RetryableStatusCodes),
+		BackoffInitial:       time.Duration(max(row.BackoffInitialMs, 0)) * time.Millisecond,
+		BackoffMax:           time.Duration(max(row.BackoffMaxMs, 0)) * time.Millisecond,
+		FailoverToNextKey:    row.FailoverToNextKey,
+	}, true
+}
+
+var policies atomic.Pointer[map[string]Policy]
+
+// Store replaces the active policies.
+func Store(list []Policy) {
+	next := make(map[string]Policy, len(list))
+	for _, policy := range list {
+		next[normalizeProvider(policy.Provider)] = policy
+	}
+	policies.Store(&next)
+}
+
+// Lookup returns the policy governing provider: its own, or else the default policy.
+func Lookup(provider string) (Policy, bool) {
+	current := policies.Load()
+	if current == nil {
+		return Policy{}, false
+	}
+	if policy, ok := (*current)[normalizeProvider(provider)]; ok {
+		return policy, true
+	}
+	policy, ok := (*current)[models.RetryPolicyDefaultProvider]
+	return policy, ok
+}
+
+// Default returns the default policy.
+func Default() (Policy, bool) {
+	current := policies.Load()
+	if current == nil {
+		return Policy{}, false
+	}
+	policy, ok := (*current)[models.RetryPolicyDefaultProvider]
+	return policy, ok
+}
+
+func normalizeProvider(provider string) string {
+	return strings.ToLower(strings.TrimSpace(provider))
+}
+
+// StopError ends a request whose retry the policy refused. It carries the status of the last
+// upstream failure so clients see why the request failed.
+type StopError struct {
+	Provider string // Provider whose policy stopped the retry.
+	Reason   string // One of the Reason constants.
+	Status   int    // Status of the last upstream failure; zero when unknown.
+}
+
+// Error implements error.
+func (e *StopError) Error() string {
+	return fmt.Sprintf("upstream request to %s failed and retry policy does not allow another attempt (%s)", e.Provider, e.Reason)
+}
+
+// StatusCode returns the last upstream status, or 502 when it is unknown.
+func (e *StopError) StatusCode() int {
+	if e.Status >= http.StatusBadRequest {
+		return e.Status
+	}
+	return http.StatusBadGateway
+}
+
+// attempts tracks one request's attempts per provider.
+type attempts struct {
+	mu         sync.Mutex
+	byProvider map[string]*attempt
+}
+
+// attempt is one provider's attempt state within a request.
+type attempt struct {
+	picks      int // Credentials handed out so far.
+	lastStatus int // Status of the latest failed attempt.
+}
+
+// Before is called whenever a credential is about to be picked for provider. The first pick of
+// a request passes; later picks are retries, which the policy may refuse with a *StopError or
+// delay by its backoff. Requests outside a gin context, or without a policy, pass unchanged.
+func Before(ctx context.Context, provider string) error {
+	policy, ok := Lookup(provider)
+	if !ok {
+		return nil
+	}
+	state := trackerFor(ctx, true)
+	if state == nil {
+		return nil
+	}
+	state.mu.Lock()
+	current := state.get(provider)
+	current.picks++
+	retry, lastStatus := current.picks-1, current.lastStatus
+	state.mu.Unlock()
+
+	if retry == 0 {
+		record(policy.Provider, eventRequest)
+		return nil
+	}
+	reason := ""
+	switch {
+	case retry > policy.MaxRetries:
+		reason = ReasonMaxRetries
+	case !policy.Retryable(lastStatus):
+		reason = ReasonNotRetryable
+	case !policy.FailoverToNextKey:
+		reason = ReasonFailoverDisabled
+	}
+	if reason != "" {
+		record(policy.Provider, reason)
+		return &StopError{Provider: provider, Reason: reason, Status: lastStatus}
+	}
+	record(policy.Provider, eventFailover)
+	if delay := policy.Backoff(retry); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// RecordResult stores the outcome of an attempt against provider for the next Before call.
+func RecordResult(ctx context.Context, provider string, success bool, status int) {
+	state := trackerFor(ctx, false)
+	if state == nil {
+		return
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	current := state.get(provider)
+	if success {
+		current.lastStatus = 0
+		return
+	}
+	current.lastStatus = status
+}
+
+func (a *attempts) get(provider string) *attempt {
+	provider = normalizeProvider(provider)
+	current, ok := a.byProvider[provider]
+	if !ok {
+		current = &attempt{}
+		a.byProvider[provider] = current
+	}
+	return current
+}
+
+// trackerFor returns the request's attempt tracker, creating it when create is set.
+func trackerFor(ctx context.Context, create bool) *attempts {
+	if ctx == nil {
+		return nil
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil {
+		return nil
+	}
+	if v, exists := ginCtx.Get(attemptsKey); exists {
+		if state, okState := v.(*attempts); okState {
+			return state
+		}
+	}
+	if !create {
+		return nil
+	}
+	state := &attempts{byProvider: make(map[string]*attempt)}
+	ginCtx.Set(attemptsKey, state)
+	return state
+}
+
+// Events counted per policy besides the stop reasons.
+const (
+	eventRequest  = "request"
+	eventFailover = "failover"
+)
+
+// Stats counts a policy's decisions since the process started.
+type Stats struct {
+	Requests                int64 `json:"requests"`                  // Requests the policy governed.
+	Failovers               int64 `json:"failovers"`                 // Retries moved to another credential.
+	StoppedMaxRetries       int64 `json:"stopped_max_retries"`       // Retries refused after max_retries.
+	StoppedNotRetryable     int64 `json:"stopped_not_retryable"`     // Retries refused for a non-retryable status.
+	StoppedFailoverDisabled int64 `json:"stopped_failover_disabled"` // Retries refused because failover is off.
+}
+
+// FailoverRate returns the share of governed requests that failed over at least once, as an
+// upper bound: a request may fail over several times.
+func (s Stats) FailoverRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return min(float64(s.Failovers)/float64(s.Requests), 1)
+}
+
+var (
+	statsMu sync.Mutex
+	stats   = make(map[string]*Stats)
+)
+
+func record(provider, event string) {
+	statsMu.Lock()
+	current, ok := stats[provider]
+	if !ok {
+		current = &Stats{}
+		stats[provider] = current
+	}
+	switch event {
+	case eventRequest:
+		current.Requests++
+	case eventFailover:
+		current.Failovers++
+	case ReasonMaxRetries:
+		current.StoppedMaxRetries++
+	case ReasonNotRetryable:
+		current.StoppedNotRetryable++
+	case ReasonFailoverDisabled:
+		current.StoppedFailoverDisabled++
+	}
+	statsMu.Unlock()
+	if event != eventRequest {
+		metrics.ObserveRetryPolicy(provider, event)
+	}
+}
+
+// StatsFor returns the counters of the policy for provider.
+func StatsFor(provider string) Stats {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	if current, ok := stats[normalizeProvider(provider)]; ok {
+		return *current
+	}
+	return Stats{}
+}
@@ -0,0 +1,105 @@
+package retrypolicy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+)
+
+func requestContext() context.Context {
+	gin.SetMode(gin.TestMode)
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	return context.WithValue(context.Background(), "gin", ginCtx)
+}
+
+func TestFromModelAndLookup(t *testing.T) {
+	defaultPolicy, ok := FromModel(models.RetryPolicy{Provider: "*", MaxRetries: 2, IsEnabled: true, FailoverToNextKey: true})
+	if !ok {
+		t.Fatalf("expected the default policy to convert")
+	}
+	claude, ok := FromModel(models.RetryPolicy{
+		Provider:             " Claude ",
+		MaxRetries:           1,
+		RetryableStatusCodes: datatypes.JSON(`[429, 529]`),
+		BackoffInitialMs:     100,
+		BackoffMaxMs:         250,
+		IsEnabled:            true,
+	})
+	if !ok || claude.Provider != "claude" || len(claude.RetryableStatusCodes) != 2 {
+		t.Fatalf("unexpected claude policy: %+v", claude)
+	}
+	if _, ok := FromModel(models.RetryPolicy{Provider: "gemini"}); ok {
+		t.Fatalf("expected disabled policies to be skipped")
+	}
+	Store([]Policy{defaultPolicy, claude})
+	t.Cleanup(func() { Store(nil) })
+
+	if got, _ := Lookup("CLAUDE"); got.Provider != "claude" {
+		t.Fatalf("expected claude's own policy, got %+v", got)
+	}
+	if got, _ := Lookup("codex"); got.Provider != models.RetryPolicyDefaultProvider {
+		t.Fatalf("expected the default policy, got %+v", got)
+	}
+	if !claude.Retryable(429) || claude.Retryable(400) || !claude.Retryable(0) {
+		t.Fatalf("unexpected retryable statuses")
+	}
+	for retry, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 250 * time.Millisecond} {
+		if got := claude.Backoff(retry); got != want {
+			t.Fatalf("Backoff(%d) = %s, want %s", retry, got, want)
+		}
+	}
+}
+
+func TestBeforeEnforcesPolicy(t *testing.T) {
+	Store([]Policy{
+		{Provider: "openai", MaxRetries: 1, RetryableStatusCodes: []int{429, 503}, FailoverToNextKey: true},
+		{Provider: "claude", MaxRetries: 3},
+	})
+	t.Cleanup(func() { Store(nil) })
+	before := StatsFor("openai")
+
+	ctx := requestContext()
+	if errFirst := Before(ctx, "openai"); errFirst != nil {
+		t.Fatalf("first attempt: %v", errFirst)
+	}
+	RecordResult(ctx, "openai", false, http.StatusTooManyRequests)
+	if errRetry := Before(ctx, "openai"); errRetry != nil {
+		t.Fatalf("expected a retryable failover, got %v", errRetry)
+	}
+	RecordResult(ctx, "openai", false, http.StatusServiceUnavailable)
+	var stop *StopError
+	if errRetry := Before(ctx, "openai"); !errors.As(errRetry, &stop) || stop.Reason != ReasonMaxRetries || stop.StatusCode() != http.StatusServiceUnavailable {
+		t.Fatalf("expected max_retries stop with the upstream status, got %v", errRetry)
+	}
+
+	ctx = requestContext()
+	_ = Before(ctx, "openai")
+	RecordResult(ctx, "openai", false, http.StatusBadRequest)
+	if errRetry := Before(ctx, "openai"); !errors.As(errRetry, &stop) || stop.Reason != ReasonNotRetryable {
+		t.Fatalf("expected not_retryable stop, got %v", errRetry)
+	}
+
+	ctx = requestContext()
+	_ = Before(ctx, "claude")
+	RecordResult(ctx, "claude", false, 0)
+	if errRetry := Before(ctx, "claude"); !errors.As(errRetry, &stop) || stop.Reason != ReasonFailoverDisabled || stop.StatusCode() != http.StatusBadGateway {
+		t.Fatalf("expected failover_disabled stop, got %v", errRetry)
+	}
+
+	after := StatsFor("openai")
+	if after.Requests-before.Requests != 2 || after.Failovers-before.Failovers != 1 ||
+		after.StoppedMaxRetries-before.StoppedMaxRetries != 1 || after.StoppedNotRetryable-before.StoppedNotRetryable != 1 {
+		t.Fatalf("unexpected stats: before %+v after %+v", before, after)
+	}
+
+	if errNoCtx := Before(context.Background(), "openai"); errNoCtx != nil {
+		t.Fatalf("expected requests without a gin context to pass, got %v", errNoCtx)
+	}
+}
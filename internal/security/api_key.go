@@ -29,3 +29,15 @@ func GenerateRandomString(length int) (string, error) {
 	}
 	return hex.EncodeToString(bytes)[:length], nil
 }
+
+// nodeTokenPrefix is the prefix used for generated proxy node tokens.
+const nodeTokenPrefix = "cpn_"
+
+// GenerateNodeToken creates a new random token for a remote proxy node.
+func GenerateNodeToken() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, secret); err != nil {
+		return "", fmt.Errorf("generate node token: %w", err)
+	}
+	return nodeTokenPrefix + hex.EncodeToString(secret), nil
+}
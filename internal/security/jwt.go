@@ -15,19 +15,29 @@ var (
 	ErrExpiredToken = errors.New("token expired")
 )
 
+// TokenScopeMFAEnrollment marks a token that may only reach the MFA enrollment endpoints. It
+// is issued when the MFA policy blocks password-only login so the account can still enroll.
+const TokenScopeMFAEnrollment = "mfa_enrollment"
+
+// MFAEnrollmentTokenExpiry bounds enrollment-only tokens.
+const MFAEnrollmentTokenExpiry = 15 * time.Minute
+
 // UserClaims defines JWT claims for end users.
 type UserClaims struct {
 	UserID   uint64 `json:"user_id"`
 	Username string `json:"username"`
 	Name     string `json:"name"`
 	Email    string `json:"email"`
+	Scope    string `json:"scope,omitempty"` // Restricts the token; empty grants full access.
 	jwt.RegisteredClaims
 }
 
 // AdminClaims defines JWT claims for administrators.
 type AdminClaims struct {
-	AdminID  uint64 `json:"admin_id"`
-	Username string `json:"username"`
+	AdminID        uint64 `json:"admin_id"`
+	Username       string `json:"username"`
+	Scope          string `json:"scope,omitempty"`  // Restricts the token; empty grants full access.
+	OrganizationID uint64 `json:"org_id,omitempty"` // Organization the session acts in; zero for the global view.
 	jwt.RegisteredClaims
 }
 
@@ -45,17 +55,31 @@ func GenerateToken(secret string, userID uint64, username, name, email string, e
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return token.SignedString([]byte(signingSecret(secret)))
 }
 
-// ParseToken validates a user JWT and returns its claims.
+// GenerateEnrollmentToken signs a short-lived user JWT limited to MFA enrollment.
+func GenerateEnrollmentToken(secret string, userID uint64, username, name, email string) (string, error) {
+	now := time.Now().UTC()
+	claims := UserClaims{
+		UserID:   userID,
+		Username: username,
+		Name:     name,
+		Email:    email,
+		Scope:    TokenScopeMFAEnrollment,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(MFAEnrollmentTokenExpiry)),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(signingSecret(secret)))
+}
+
+// ParseToken validates a user JWT and returns its claims. During a rotation overlap tokens
+// signed with the previous secret are accepted too.
 func ParseToken(secret string, tokenString string) (*UserClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &UserClaims{}, func(t *jwt.Token) (any, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrInvalidToken
-		}
-		return []byte(secret), nil
-	})
+	token, err := parseWithRotation(secret, tokenString, func() jwt.Claims { return &UserClaims{} }, plainSecret)
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
 			return nil, ErrExpiredToken
@@ -69,29 +93,44 @@ func ParseToken(secret string, tokenString string) (*UserClaims, error) {
 	return claims, nil
 }
 
-// GenerateAdminToken signs an admin JWT with the configured expiry.
-func GenerateAdminToken(secret string, adminID uint64, username string, expiry time.Duration) (string, error) {
+// GenerateAdminToken signs an admin JWT with the configured expiry, scoped to organizationID.
+// Zero issues a global session; organization admins always get their own organization and
+// global admins get the one they selected.
+func GenerateAdminToken(secret string, adminID uint64, username string, organizationID uint64, expiry time.Duration) (string, error) {
+	now := time.Now().UTC()
+	claims := AdminClaims{
+		AdminID:        adminID,
+		Username:       username,
+		OrganizationID: organizationID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(signingSecret(secret)))
+}
+
+// GenerateAdminEnrollmentToken signs a short-lived admin JWT limited to MFA enrollment.
+func GenerateAdminEnrollmentToken(secret string, adminID uint64, username string) (string, error) {
 	now := time.Now().UTC()
 	claims := AdminClaims{
 		AdminID:  adminID,
 		Username: username,
+		Scope:    TokenScopeMFAEnrollment,
 		RegisteredClaims: jwt.RegisteredClaims{
 			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(MFAEnrollmentTokenExpiry)),
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return token.SignedString([]byte(signingSecret(secret)))
 }
 
-// ParseAdminToken validates an admin JWT and returns its claims.
+// ParseAdminToken validates an admin JWT and returns its claims. During a rotation overlap
+// tokens signed with the previous secret are accepted too.
 func ParseAdminToken(secret string, tokenString string) (*AdminClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &AdminClaims{}, func(t *jwt.Token) (any, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrInvalidToken
-		}
-		return []byte(secret), nil
-	})
+	token, err := parseWithRotation(secret, tokenString, func() jwt.Claims { return &AdminClaims{} }, plainSecret)
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
 			return nil, ErrExpiredToken
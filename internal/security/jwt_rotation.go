@@ -0,0 +1,108 @@
+package security
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+)
+
+// MinJWTSecretLength is the shortest secret accepted by rotation.
+const MinJWTSecretLength = 32
+
+// JWTRotation mirrors the JWT_ROTATION setting. Once present, Secret replaces the configured
+// secret for signing, and PreviousSecret keeps verifying tokens until PreviousValidUntil so
+// sessions issued before the rotation survive the overlap.
+type JWTRotation struct {
+	Secret             string     `json:"secret"`                         // Signing secret.
+	PreviousSecret     string     `json:"previous_secret,omitempty"`      // Secret replaced by the last rotation.
+	PreviousValidUntil *time.Time `json:"previous_valid_until,omitempty"` // End of the overlap window.
+	RotatedAt          time.Time  `json:"rotated_at"`                     // When the last rotation happened.
+}
+
+// LoadJWTRotation reads JWT_ROTATION from the settings snapshot.
+func LoadJWTRotation() (JWTRotation, bool) {
+	var rotation JWTRotation
+	raw, ok := internalsettings.DBConfigValue(internalsettings.JWTRotationKey)
+	if !ok || len(bytes.TrimSpace(raw)) == 0 {
+		return rotation, false
+	}
+	if errUnmarshal := json.Unmarshal(raw, &rotation); errUnmarshal != nil {
+		return JWTRotation{}, false
+	}
+	rotation.Secret = strings.TrimSpace(rotation.Secret)
+	rotation.PreviousSecret = strings.TrimSpace(rotation.PreviousSecret)
+	return rotation, rotation.Secret != ""
+}
+
+// Rotate replaces the effective signing secret with next. The secret in effect so far keeps
+// verifying tokens for overlap; a previous secret still inside its own window is dropped.
+func Rotate(configured, next string, overlap time.Duration, now time.Time) (JWTRotation, error) {
+	next = strings.TrimSpace(next)
+	if len(next) < MinJWTSecretLength {
+		return JWTRotation{}, errors.New("secret is too short")
+	}
+	current := signingSecret(configured)
+	if next == current {
+		return JWTRotation{}, errors.New("secret is already in use")
+	}
+	rotation := JWTRotation{Secret: next, RotatedAt: now.UTC()}
+	if overlap > 0 && current != "" {
+		until := now.Add(overlap).UTC()
+		rotation.PreviousSecret = current
+		rotation.PreviousValidUntil = &until
+	}
+	return rotation, nil
+}
+
+// signingSecret returns the rotated secret, or configured before the first rotation.
+func signingSecret(configured string) string {
+	if rotation, ok := LoadJWTRotation(); ok {
+		return rotation.Secret
+	}
+	return configured
+}
+
+// verificationSecrets returns the secrets tokens may be signed with at now, signing secret first.
+func verificationSecrets(configured string, now time.Time) []string {
+	rotation, ok := LoadJWTRotation()
+	if !ok {
+		return []string{configured}
+	}
+	secrets := []string{rotation.Secret}
+	if rotation.PreviousSecret != "" && rotation.PreviousValidUntil != nil && now.Before(*rotation.PreviousValidUntil) {
+		secrets = append(secrets, rotation.PreviousSecret)
+	}
+	return secrets
+}
+
+// parseWithRotation validates tokenString against every accepted secret. key derives the
+// HMAC key from a secret; a signature mismatch moves on to the next secret, any other error
+// is final.
+func parseWithRotation(configured, tokenString string, claims func() jwt.Claims, key func(string) []byte, opts ...jwt.ParserOption) (*jwt.Token, error) {
+	var (
+		token *jwt.Token
+		err   error = ErrInvalidToken
+	)
+	for _, secret := range verificationSecrets(configured, time.Now()) {
+		token, err = jwt.ParseWithClaims(tokenString, claims(), func(t *jwt.Token) (any, error) {
+			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, ErrInvalidToken
+			}
+			return key(secret), nil
+		}, opts...)
+		if err == nil || !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+			return token, err
+		}
+	}
+	return token, err
+}
+
+// plainSecret uses the secret itself as the HMAC key.
+func plainSecret(secret string) []byte {
+	return []byte(secret)
+}
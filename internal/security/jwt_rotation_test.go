@@ -0,0 +1,96 @@
+package security
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+)
+
+// storeRotation publishes rotation in the settings snapshot until the test ends.
+func storeRotation(t *testing.T, rotation JWTRotation) {
+	t.Helper()
+	raw, errMarshal := json.Marshal(rotation)
+	if errMarshal != nil {
+		t.Fatalf("marshal rotation: %v", errMarshal)
+	}
+	internalsettings.StoreDBConfig(time.Now(), map[string]json.RawMessage{internalsettings.JWTRotationKey: raw})
+	t.Cleanup(func() { internalsettings.StoreDBConfig(time.Now(), nil) })
+}
+
+func TestJWTRotationAcceptsPreviousSecretDuringOverlap(t *testing.T) {
+	configured := strings.Repeat("a", MinJWTSecretLength)
+	internalsettings.StoreDBConfig(time.Now(), nil)
+	oldToken, errToken := GenerateAdminToken(configured, 7, "root", 0, time.Hour)
+	if errToken != nil {
+		t.Fatalf("generate token: %v", errToken)
+	}
+
+	rotation, errRotate := Rotate(configured, strings.Repeat("b", MinJWTSecretLength), time.Hour, time.Now())
+	if errRotate != nil {
+		t.Fatalf("rotate: %v", errRotate)
+	}
+	storeRotation(t, rotation)
+
+	if claims, errParse := ParseAdminToken(configured, oldToken); errParse != nil || claims.AdminID != 7 {
+		t.Fatalf("old token during overlap = %+v, %v", claims, errParse)
+	}
+	newToken, errToken := GenerateAdminToken(configured, 8, "root", 3, time.Hour)
+	if errToken != nil {
+		t.Fatalf("generate token: %v", errToken)
+	}
+
+	expired := time.Now().Add(-time.Minute)
+	rotation.PreviousValidUntil = &expired
+	storeRotation(t, rotation)
+	if _, errParse := ParseAdminToken(configured, oldToken); !errors.Is(errParse, ErrInvalidToken) {
+		t.Fatalf("old token after overlap: expected ErrInvalidToken, got %v", errParse)
+	}
+	if claims, errParse := ParseAdminToken(configured, newToken); errParse != nil || claims.OrganizationID != 3 {
+		t.Fatalf("new token after overlap = %+v, %v", claims, errParse)
+	}
+
+	// Without the rotation only the rotated secret itself verifies the new token.
+	internalsettings.StoreDBConfig(time.Now(), nil)
+	if _, errParse := ParseAdminToken(rotation.Secret, newToken); errParse != nil {
+		t.Fatalf("new token must be signed with the rotated secret: %v", errParse)
+	}
+}
+
+func TestRotateRejectsWeakOrReusedSecret(t *testing.T) {
+	internalsettings.StoreDBConfig(time.Now(), nil)
+	configured := strings.Repeat("a", MinJWTSecretLength)
+	if _, errRotate := Rotate(configured, "short", time.Hour, time.Now()); errRotate == nil {
+		t.Fatal("expected short secret to be rejected")
+	}
+	if _, errRotate := Rotate(configured, configured, time.Hour, time.Now()); errRotate == nil {
+		t.Fatal("expected reused secret to be rejected")
+	}
+	rotation, errRotate := Rotate(configured, strings.Repeat("b", MinJWTSecretLength), 0, time.Now())
+	if errRotate != nil || rotation.PreviousSecret != "" || rotation.PreviousValidUntil != nil {
+		t.Fatalf("zero overlap must drop the previous secret, got %+v %v", rotation, errRotate)
+	}
+}
+
+func TestWebAuthnConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	valid := WebAuthnConfig{RPID: "example.com", RPName: "Admin", Origins: []string{"https://example.com", "https://admin.example.com"}}
+	if errValidate := valid.Validate(); errValidate != nil {
+		t.Fatalf("valid config: %v", errValidate)
+	}
+	for _, cfg := range []WebAuthnConfig{
+		{RPID: "example.com", Origins: []string{"https://evil.com"}},
+		{RPID: "example.com", Origins: []string{"https://notexample.com"}},
+		{RPID: "example.com", Origins: []string{"example.com"}},
+		{RPID: "", Origins: []string{"https://example.com"}},
+		{RPID: "example.com"},
+	} {
+		if errValidate := cfg.Validate(); errValidate == nil {
+			t.Fatalf("expected %+v to be rejected", cfg)
+		}
+	}
+}
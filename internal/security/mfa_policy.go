@@ -0,0 +1,83 @@
+package security
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+)
+
+// MFAPolicy mirrors the MFA_POLICY setting.
+type MFAPolicy struct {
+	RequireAdmins bool       `json:"require_admins"` // Whether every admin must enroll TOTP or a passkey.
+	RequireUsers  bool       `json:"require_users"`  // Whether every front user must enroll TOTP or a passkey.
+	GraceDays     int        `json:"grace_days"`     // Days an unenrolled account may still sign in with a password.
+	EnforceFrom   *time.Time `json:"enforce_from"`   // Start of the grace period; accounts created later start from creation.
+}
+
+// MFAEnforcement is the outcome of applying the policy to one account.
+type MFAEnforcement int
+
+// MFAEnforcement values.
+const (
+	// MFANotRequired means password-only login is allowed.
+	MFANotRequired MFAEnforcement = iota
+	// MFAGracePeriod means login is allowed but the account must enroll before the deadline.
+	MFAGracePeriod
+	// MFAEnrollmentRequired means password-only login is blocked until the account enrolls.
+	MFAEnrollmentRequired
+)
+
+// LoadMFAPolicy reads MFA_POLICY; invalid values disable enforcement.
+func LoadMFAPolicy() MFAPolicy {
+	var policy MFAPolicy
+	raw, ok := internalsettings.DBConfigValue(internalsettings.MFAPolicyKey)
+	if !ok || len(bytes.TrimSpace(raw)) == 0 {
+		return policy
+	}
+	if errUnmarshal := json.Unmarshal(raw, &policy); errUnmarshal != nil {
+		log.WithError(errUnmarshal).Warn("security: invalid mfa policy setting")
+		return MFAPolicy{}
+	}
+	if policy.GraceDays < 0 {
+		policy.GraceDays = 0
+	}
+	return policy
+}
+
+// Requires reports whether the policy applies to the owner type.
+func (p MFAPolicy) Requires(owner models.MFAOwnerType) bool {
+	switch owner {
+	case models.MFAOwnerAdmin:
+		return p.RequireAdmins
+	case models.MFAOwnerUser:
+		return p.RequireUsers
+	default:
+		return false
+	}
+}
+
+// Deadline returns when an unenrolled account created at createdAt loses password-only login.
+func (p MFAPolicy) Deadline(createdAt time.Time) time.Time {
+	start := createdAt
+	if p.EnforceFrom != nil && p.EnforceFrom.After(start) {
+		start = *p.EnforceFrom
+	}
+	return start.Add(time.Duration(p.GraceDays) * 24 * time.Hour)
+}
+
+// Evaluate applies the policy to an account and returns the outcome and, when enforced,
+// the enrollment deadline.
+func (p MFAPolicy) Evaluate(owner models.MFAOwnerType, enrolled bool, createdAt, now time.Time) (MFAEnforcement, time.Time) {
+	if enrolled || !p.Requires(owner) {
+		return MFANotRequired, time.Time{}
+	}
+	deadline := p.Deadline(createdAt)
+	if now.Before(deadline) {
+		return MFAGracePeriod, deadline
+	}
+	return MFAEnrollmentRequired, deadline
+}
@@ -0,0 +1,53 @@
+package security
+
+import (
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+)
+
+func TestMFAPolicyEvaluateGracePeriod(t *testing.T) {
+	t.Parallel()
+
+	enforceFrom := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	policy := MFAPolicy{RequireAdmins: true, GraceDays: 7, EnforceFrom: &enforceFrom}
+	oldAccount := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if got, _ := policy.Evaluate(models.MFAOwnerUser, false, oldAccount, enforceFrom); got != MFANotRequired {
+		t.Fatalf("expected users to be exempt, got %v", got)
+	}
+	if got, _ := policy.Evaluate(models.MFAOwnerAdmin, true, oldAccount, enforceFrom.AddDate(0, 1, 0)); got != MFANotRequired {
+		t.Fatalf("expected enrolled admins to pass, got %v", got)
+	}
+	got, deadline := policy.Evaluate(models.MFAOwnerAdmin, false, oldAccount, enforceFrom.Add(24*time.Hour))
+	if got != MFAGracePeriod || !deadline.Equal(enforceFrom.AddDate(0, 0, 7)) {
+		t.Fatalf("expected grace until %s, got %v %s", enforceFrom.AddDate(0, 0, 7), got, deadline)
+	}
+	if got, _ := policy.Evaluate(models.MFAOwnerAdmin, false, oldAccount, enforceFrom.AddDate(0, 0, 7)); got != MFAEnrollmentRequired {
+		t.Fatalf("expected enrollment to be required after the grace period, got %v", got)
+	}
+
+	// Accounts created after enforcement starts get their own grace period.
+	newAccount := enforceFrom.AddDate(0, 0, 30)
+	if got, deadline := policy.Evaluate(models.MFAOwnerAdmin, false, newAccount, newAccount.Add(time.Hour)); got != MFAGracePeriod || !deadline.Equal(newAccount.AddDate(0, 0, 7)) {
+		t.Fatalf("expected a fresh grace period for new accounts, got %v %s", got, deadline)
+	}
+}
+
+func TestEnrollmentTokenCarriesScope(t *testing.T) {
+	t.Parallel()
+
+	token, errToken := GenerateAdminEnrollmentToken("secret", 3, "root")
+	if errToken != nil {
+		t.Fatalf("GenerateAdminEnrollmentToken: %v", errToken)
+	}
+	claims, errParse := ParseAdminToken("secret", token)
+	if errParse != nil || claims.Scope != TokenScopeMFAEnrollment || claims.AdminID != 3 {
+		t.Fatalf("ParseAdminToken = %+v, %v", claims, errParse)
+	}
+	full, _ := GenerateAdminToken("secret", 3, "root", 0, time.Hour)
+	if claims, _ := ParseAdminToken("secret", full); claims.Scope != "" {
+		t.Fatalf("expected regular tokens to be unscoped, got %q", claims.Scope)
+	}
+}
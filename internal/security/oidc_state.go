@@ -0,0 +1,61 @@
+package security
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OIDCStateExpiry bounds how long an SSO login may take at the identity provider.
+const OIDCStateExpiry = 10 * time.Minute
+
+// oidcStateAudience keeps state tokens from being accepted anywhere else.
+const oidcStateAudience = "admin-oidc-state"
+
+// OIDCStateClaims carries the per-login secrets of an SSO login between the login redirect
+// and the callback.
+type OIDCStateClaims struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	jwt.RegisteredClaims
+}
+
+// oidcStateSecret derives the state signing key so state tokens never verify as admin tokens.
+func oidcStateSecret(secret string) []byte {
+	return []byte(secret + ":" + oidcStateAudience)
+}
+
+// GenerateOIDCStateToken signs the state, nonce and PKCE verifier of an SSO login.
+func GenerateOIDCStateToken(secret, state, nonce, verifier string) (string, error) {
+	now := time.Now().UTC()
+	claims := OIDCStateClaims{
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{oidcStateAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(OIDCStateExpiry)),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(oidcStateSecret(signingSecret(secret)))
+}
+
+// ParseOIDCStateToken validates a state token and returns its claims.
+func ParseOIDCStateToken(secret, tokenString string) (*OIDCStateClaims, error) {
+	token, err := parseWithRotation(secret, tokenString, func() jwt.Claims { return &OIDCStateClaims{} }, oidcStateSecret, jwt.WithAudience(oidcStateAudience))
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+	claims, ok := token.Claims.(*OIDCStateClaims)
+	if !ok || !token.Valid || claims.State == "" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
@@ -0,0 +1,107 @@
+package security
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// RecoveryCodeCount is how many recovery codes are issued per TOTP enrollment.
+const RecoveryCodeCount = 10
+
+// recoveryCodeAlphabet avoids characters that are easy to confuse when copied by hand.
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// GenerateRecoveryCodes returns RecoveryCodeCount codes formatted as "xxxxx-xxxxx".
+func GenerateRecoveryCodes() ([]string, error) {
+	codes := make([]string, 0, RecoveryCodeCount)
+	buf := make([]byte, 10)
+	for len(codes) < RecoveryCodeCount {
+		if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+			return nil, fmt.Errorf("generate recovery codes: %w", err)
+		}
+		var b strings.Builder
+		for i, v := range buf {
+			if i == 5 {
+				b.WriteByte('-')
+			}
+			b.WriteByte(recoveryCodeAlphabet[int(v)%len(recoveryCodeAlphabet)])
+		}
+		codes = append(codes, b.String())
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode returns the stored hash of a code, ignoring case, spaces and dashes.
+func HashRecoveryCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// ReplaceRecoveryCodes discards the owner's existing codes and stores a fresh set, returning
+// the plaintext codes. Pass a transaction to tie the swap to another update.
+func ReplaceRecoveryCodes(ctx context.Context, db *gorm.DB, ownerType models.MFAOwnerType, ownerID uint64) ([]string, error) {
+	codes, errGenerate := GenerateRecoveryCodes()
+	if errGenerate != nil {
+		return nil, errGenerate
+	}
+	rows := make([]models.MFARecoveryCode, 0, len(codes))
+	for _, code := range codes {
+		rows = append(rows, models.MFARecoveryCode{OwnerType: ownerType, OwnerID: ownerID, CodeHash: HashRecoveryCode(code)})
+	}
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if errDelete := DeleteRecoveryCodes(ctx, tx, ownerType, ownerID); errDelete != nil {
+			return errDelete
+		}
+		return tx.Create(&rows).Error
+	})
+	if errTx != nil {
+		return nil, fmt.Errorf("store recovery codes: %w", errTx)
+	}
+	return codes, nil
+}
+
+// DeleteRecoveryCodes removes every recovery code belonging to the owner.
+func DeleteRecoveryCodes(ctx context.Context, db *gorm.DB, ownerType models.MFAOwnerType, ownerID uint64) error {
+	return db.WithContext(ctx).
+		Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).
+		Delete(&models.MFARecoveryCode{}).Error
+}
+
+// ConsumeRecoveryCode marks an unused code used and reports whether it was valid.
+func ConsumeRecoveryCode(ctx context.Context, db *gorm.DB, ownerType models.MFAOwnerType, ownerID uint64, code string, now time.Time) (bool, error) {
+	if strings.TrimSpace(code) == "" {
+		return false, nil
+	}
+	// The conditional update makes concurrent attempts with the same code succeed at most once.
+	res := db.WithContext(ctx).Model(&models.MFARecoveryCode{}).
+		Where("owner_type = ? AND owner_id = ? AND code_hash = ? AND used_at IS NULL", ownerType, ownerID, HashRecoveryCode(code)).
+		Update("used_at", now.UTC())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// RemainingRecoveryCodes counts the owner's unused recovery codes.
+func RemainingRecoveryCodes(ctx context.Context, db *gorm.DB, ownerType models.MFAOwnerType, ownerID uint64) (int64, error) {
+	var count int64
+	errCount := db.WithContext(ctx).Model(&models.MFARecoveryCode{}).
+		Where("owner_type = ? AND owner_id = ? AND used_at IS NULL", ownerType, ownerID).
+		Count(&count).Error
+	return count, errCount
+}
@@ -0,0 +1,67 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func setupRecoveryDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:recovery_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func TestRecoveryCodesAreSingleUseAndScopedToOwner(t *testing.T) {
+	t.Parallel()
+
+	conn := setupRecoveryDB(t)
+	ctx := context.Background()
+	codes, errReplace := ReplaceRecoveryCodes(ctx, conn, models.MFAOwnerUser, 7)
+	if errReplace != nil {
+		t.Fatalf("ReplaceRecoveryCodes: %v", errReplace)
+	}
+	if len(codes) != RecoveryCodeCount {
+		t.Fatalf("expected %d codes, got %d", RecoveryCodeCount, len(codes))
+	}
+
+	if ok, _ := ConsumeRecoveryCode(ctx, conn, models.MFAOwnerAdmin, 7, codes[0], time.Now()); ok {
+		t.Fatal("expected a user code to be rejected for an admin with the same id")
+	}
+	// Codes are accepted regardless of case, spacing and dashes.
+	typed := strings.ToUpper(strings.ReplaceAll(codes[0], "-", " "))
+	if ok, errConsume := ConsumeRecoveryCode(ctx, conn, models.MFAOwnerUser, 7, typed, time.Now()); !ok || errConsume != nil {
+		t.Fatalf("ConsumeRecoveryCode = %v, %v", ok, errConsume)
+	}
+	if ok, _ := ConsumeRecoveryCode(ctx, conn, models.MFAOwnerUser, 7, codes[0], time.Now()); ok {
+		t.Fatal("expected a used code to be rejected")
+	}
+	if remaining, _ := RemainingRecoveryCodes(ctx, conn, models.MFAOwnerUser, 7); remaining != RecoveryCodeCount-1 {
+		t.Fatalf("expected %d remaining, got %d", RecoveryCodeCount-1, remaining)
+	}
+
+	fresh, errRegenerate := ReplaceRecoveryCodes(ctx, conn, models.MFAOwnerUser, 7)
+	if errRegenerate != nil {
+		t.Fatalf("ReplaceRecoveryCodes again: %v", errRegenerate)
+	}
+	if ok, _ := ConsumeRecoveryCode(ctx, conn, models.MFAOwnerUser, 7, codes[1], time.Now()); ok {
+		t.Fatal("expected regeneration to invalidate the previous set")
+	}
+	if ok, _ := ConsumeRecoveryCode(ctx, conn, models.MFAOwnerUser, 7, fresh[1], time.Now()); !ok {
+		t.Fatal("expected a regenerated code to be accepted")
+	}
+}
@@ -0,0 +1,63 @@
+package security
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SocialStateExpiry bounds how long a social login may take at the provider.
+const SocialStateExpiry = 10 * time.Minute
+
+// socialStateAudience keeps state tokens from being accepted anywhere else.
+const socialStateAudience = "user-social-state"
+
+// SocialStateClaims carries the per-login secrets of a social login between the redirect to
+// the provider and the callback. LinkUserID is set when a signed-in user links a provider.
+type SocialStateClaims struct {
+	Provider   string `json:"provider"`
+	State      string `json:"state"`
+	Verifier   string `json:"verifier"`
+	LinkUserID uint64 `json:"link_user_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// socialStateSecret derives the state signing key so state tokens never verify as user tokens.
+func socialStateSecret(secret string) []byte {
+	return []byte(secret + ":" + socialStateAudience)
+}
+
+// GenerateSocialStateToken signs the provider, state and PKCE verifier of a social login.
+func GenerateSocialStateToken(secret, provider, state, verifier string, linkUserID uint64) (string, error) {
+	now := time.Now().UTC()
+	claims := SocialStateClaims{
+		Provider:   provider,
+		State:      state,
+		Verifier:   verifier,
+		LinkUserID: linkUserID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{socialStateAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(SocialStateExpiry)),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(socialStateSecret(signingSecret(secret)))
+}
+
+// ParseSocialStateToken validates a state token and returns its claims.
+func ParseSocialStateToken(secret, tokenString string) (*SocialStateClaims, error) {
+	token, err := parseWithRotation(secret, tokenString, func() jwt.Claims { return &SocialStateClaims{} }, socialStateSecret, jwt.WithAudience(socialStateAudience))
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+	claims, ok := token.Claims.(*SocialStateClaims)
+	if !ok || !token.Valid || claims.State == "" || claims.Provider == "" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
@@ -3,6 +3,8 @@ package security
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/url"
 	"strings"
 
@@ -20,16 +22,24 @@ const (
 	webAuthnOrigin = "https://router-for.me"
 )
 
-// NewWebAuthn builds a WebAuthn configuration using DB-backed overrides.
-func NewWebAuthn() (*webauthn.WebAuthn, error) {
+// WebAuthnConfig is the effective relying party configuration.
+type WebAuthnConfig struct {
+	RPID    string   `json:"rp_id"`   // Relying party ID.
+	RPName  string   `json:"rp_name"` // Relying party display name.
+	Origins []string `json:"origins"` // Allowed origins.
+}
+
+// LoadWebAuthnConfig resolves the relying party from DB-backed overrides and defaults. It
+// reads the settings snapshot on every call, so RP changes apply to the next ceremony.
+func LoadWebAuthnConfig() WebAuthnConfig {
 	rpName := webAuthnRPName
-	if override := dbConfigString("WEB_AUTHN_RP_NAME"); override != "" {
+	if override := dbConfigString(internalsettings.WebAuthnRPNameKey); override != "" {
 		rpName = override
 	}
 
-	origins := dbConfigStrings("WEB_AUTHN_ORIGINS")
+	origins := dbConfigStrings(internalsettings.WebAuthnOriginsKey)
 	if len(origins) == 0 {
-		if override := dbConfigString("WEB_AUTHN_ORIGIN"); override != "" {
+		if override := dbConfigString(internalsettings.WebAuthnOriginKey); override != "" {
 			origins = []string{override}
 		}
 	}
@@ -38,19 +48,50 @@ func NewWebAuthn() (*webauthn.WebAuthn, error) {
 	}
 
 	rpID := webAuthnRPID
-	if override := dbConfigString("WEB_AUTHN_RPID"); override != "" {
+	if override := dbConfigString(internalsettings.WebAuthnRPIDKey); override != "" {
 		rpID = override
 	} else if derived := deriveRPIDFromOrigins(origins); derived != "" {
 		rpID = derived
 	}
+	return WebAuthnConfig{RPID: rpID, RPName: rpName, Origins: origins}
+}
 
+// Validate checks that every origin is an absolute URL whose host is the RP ID or one of its
+// subdomains, as browsers require.
+func (c WebAuthnConfig) Validate() error {
+	rpID := strings.ToLower(strings.TrimSpace(c.RPID))
+	if rpID == "" {
+		return errors.New("rp_id is required")
+	}
+	if len(c.Origins) == 0 {
+		return errors.New("at least one origin is required")
+	}
+	for _, origin := range c.Origins {
+		host := strings.ToLower(originHost(origin))
+		if host == "" {
+			return fmt.Errorf("origin %q is not an absolute url", origin)
+		}
+		if host != rpID && !strings.HasSuffix(host, "."+rpID) {
+			return fmt.Errorf("origin %q does not belong to rp_id %q", origin, c.RPID)
+		}
+	}
+	return nil
+}
+
+// Build constructs the WebAuthn instance for the configuration.
+func (c WebAuthnConfig) Build() (*webauthn.WebAuthn, error) {
 	return webauthn.New(&webauthn.Config{
-		RPID:          rpID,
-		RPDisplayName: rpName,
-		RPOrigins:     origins,
+		RPID:          c.RPID,
+		RPDisplayName: c.RPName,
+		RPOrigins:     c.Origins,
 	})
 }
 
+// NewWebAuthn builds a WebAuthn configuration using DB-backed overrides.
+func NewWebAuthn() (*webauthn.WebAuthn, error) {
+	return LoadWebAuthnConfig().Build()
+}
+
 // deriveRPIDFromOrigins extracts an RP ID from the configured origins.
 func deriveRPIDFromOrigins(origins []string) string {
 	for _, origin := range origins {
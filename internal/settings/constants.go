@@ -6,12 +6,36 @@ const (
 	SiteNameKey = "SITE_NAME"
 	// DefaultSiteName is the fallback UI site name.
 	DefaultSiteName = "CLIProxyAPI"
+	// BrandingProductNameKey overrides the product name returned to the user portal.
+	BrandingProductNameKey = "BRANDING_PRODUCT_NAME"
+	// BrandingLogoURLKey defines the logo URL shown in the user portal.
+	BrandingLogoURLKey = "BRANDING_LOGO_URL"
+	// BrandingFaviconURLKey defines the favicon URL shown in the user portal.
+	BrandingFaviconURLKey = "BRANDING_FAVICON_URL"
+	// BrandingSupportEmailKey defines the support contact address shown to users.
+	BrandingSupportEmailKey = "BRANDING_SUPPORT_EMAIL"
+	// BrandingHomepageURLKey defines the reseller homepage link.
+	BrandingHomepageURLKey = "BRANDING_HOMEPAGE_URL"
+	// BrandingDocsURLKey defines the documentation link.
+	BrandingDocsURLKey = "BRANDING_DOCS_URL"
+	// BrandingTermsURLKey defines the terms of service link.
+	BrandingTermsURLKey = "BRANDING_TERMS_URL"
+	// BrandingPrivacyURLKey defines the privacy policy link.
+	BrandingPrivacyURLKey = "BRANDING_PRIVACY_URL"
+	// OnlyMappedModelsKey hides models without a model mapping.
+	OnlyMappedModelsKey = "ONLY_MAPPED_MODELS"
 	// QuotaPollIntervalSecondsKey controls the quota poll interval in seconds.
 	QuotaPollIntervalSecondsKey = "QUOTA_POLL_INTERVAL_SECONDS"
 	// QuotaPollMaxConcurrencyKey controls the max concurrent quota requests.
 	QuotaPollMaxConcurrencyKey = "QUOTA_POLL_MAX_CONCURRENCY"
+	// QuotaLowThresholdPercentKey sets the remaining quota share (percent) below which a quota.low event fires; 0 disables it.
+	QuotaLowThresholdPercentKey = "QUOTA_LOW_THRESHOLD_PERCENT"
 	// AutoAssignProxyKey toggles auto assignment of proxies on create.
 	AutoAssignProxyKey = "AUTO_ASSIGN_PROXY"
+	// AutoAssignProxyStrategyKey selects how auto assignment picks a proxy (random, least_used, region or sticky_group).
+	AutoAssignProxyStrategyKey = "AUTO_ASSIGN_PROXY_STRATEGY"
+	// ProxyProviderRegionsKey maps providers to the proxy region preferred by region assignment (JSON object).
+	ProxyProviderRegionsKey = "PROXY_PROVIDER_REGIONS"
 	// RateLimitKey controls the default rate limit per second.
 	RateLimitKey = "RATE_LIMIT"
 	// RateLimitRedisEnabledKey toggles Redis-backed rate limiting.
@@ -28,12 +52,84 @@ const (
 	UsagesRetentionDaysKey = "USAGES_RETENTION_DAYS"
 	// OAuthCallbackHostKey controls the host used in local OAuth callback redirect URIs.
 	OAuthCallbackHostKey = "OAUTH_CALLBACK_HOST"
+	// EventWebhookURLsKey lists webhook URLs (array or comma-separated string) that receive bus events.
+	EventWebhookURLsKey = "EVENT_WEBHOOK_URLS"
+	// EventWebhookTemplatesKey maps webhook URLs (or "*") to Go-template payload shapes (JSON object).
+	EventWebhookTemplatesKey = "EVENT_WEBHOOK_TEMPLATES"
+	// EventChatWebhookURLKey defines a Slack-compatible incoming webhook for warning events.
+	EventChatWebhookURLKey = "EVENT_CHAT_WEBHOOK_URL"
+	// EventEmailKey configures SMTP delivery of warning events (JSON object with host, port, username, password, from and to).
+	EventEmailKey = "EVENT_EMAIL"
+	// SMTPKey configures outbound user email (JSON object with host, port, username, password, from, from_name, implicit_tls and base_url).
+	SMTPKey = "SMTP"
+	// EventThrottlePoliciesKey overrides per-channel notification throttle and digest policies (JSON object).
+	EventThrottlePoliciesKey = "EVENT_THROTTLE_POLICIES"
+	// AuthImportApprovalKey holds risky auth import approval rules (JSON object with enabled and trusted_admins).
+	AuthImportApprovalKey = "AUTH_IMPORT_APPROVAL"
+	// CoopPoolKey configures user-contributed credential pools (JSON object with enabled, trusted_users, auth_group_id, credit_rate and require_approval).
+	CoopPoolKey = "COOP_POOL"
+	// AnalyticsAnonymizeKey replaces user and key identifiers in analytics responses with pseudonyms.
+	AnalyticsAnonymizeKey = "ANALYTICS_ANONYMIZE"
+	// ChaosTestingKey allows admins to inject faults for resilience testing; keep it off in production.
+	ChaosTestingKey = "CHAOS_TESTING"
+	// DisplayCurrencyKey sets the ISO currency dashboards, logs and invoices show costs in.
+	DisplayCurrencyKey = "DISPLAY_CURRENCY"
+	// ExchangeRateFeedKey configures fetched exchange rates (JSON object with url and interval_minutes).
+	ExchangeRateFeedKey = "EXCHANGE_RATE_FEED"
+	// UsageAnomalyDetectionKey configures spend spike alerts (JSON object with enabled, window_minutes, baseline_days, multiplier and floors).
+	UsageAnomalyDetectionKey = "USAGE_ANOMALY_DETECTION"
+	// RequireEmailVerificationKey blocks API key creation until the user has verified their email.
+	RequireEmailVerificationKey = "REQUIRE_EMAIL_VERIFICATION"
+	// MFAPolicyKey enforces MFA enrollment per role (JSON object with require_admins, require_users, grace_days and enforce_from).
+	MFAPolicyKey = "MFA_POLICY"
+	// TrustedProxiesKey lists proxy IPs or CIDRs (array or comma-separated string) whose forwarding headers are honored.
+	TrustedProxiesKey = "TRUSTED_PROXIES"
+	// AuthCooldownKey configures automatic auth cooldowns after upstream 429s (JSON object with enabled, interval_seconds, lookback_minutes, min_seconds, max_seconds and backoff_seconds per provider).
+	AuthCooldownKey = "AUTH_COOLDOWN"
+	// OIDCKey configures admin panel single sign-on (JSON object with enabled, issuer, client_id, client_secret, redirect_url, role_mapping and group options).
+	OIDCKey = "OIDC"
+	// LDAPKey configures directory logins for front users (JSON object with enabled, url, bind_dn, bind_password, base_dn, user_filter, attribute names and group_mapping).
+	LDAPKey = "LDAP"
+	// SocialLoginKey configures GitHub and Google sign-in for front users (JSON object with registration and per-provider client settings).
+	SocialLoginKey = "SOCIAL_LOGIN"
+	// RegistrationInviteOnlyKey requires an invitation code for self-service registration.
+	RegistrationInviteOnlyKey = "REGISTRATION_INVITE_ONLY"
+	// RequestLogKey configures request body capture (JSON object with enabled, max_body_bytes and retention_days).
+	RequestLogKey = "REQUEST_LOG"
+	// ClusterBusKey configures cross-instance change notifications (JSON object with enabled, redis_addr, redis_password, redis_db, channel and heartbeat_seconds).
+	ClusterBusKey = "CLUSTER_BUS"
+	// UserLifecycleKey configures automated user lifecycle actions (JSON object with enabled, interval_minutes, suspend_on_zero_balance, resume_on_top_up, warn_inactive_days and anonymize_inactive_days).
+	UserLifecycleKey = "USER_LIFECYCLE"
+	// ProxyHealthKey configures proxy pool health checks (JSON object with enabled, interval_seconds, timeout_seconds, failure_threshold, window_hours and check_url).
+	ProxyHealthKey = "PROXY_HEALTH"
+	// JWTRotationKey holds the rotated JWT signing secret (JSON object with secret, previous_secret, previous_valid_until and rotated_at); it overrides the configured secret.
+	JWTRotationKey = "JWT_ROTATION"
+	// ModelConcurrencyKey caps in-flight requests per model (JSON object mapping model names or "prefix*" patterns to max_concurrent, max_queue and max_wait_seconds).
+	ModelConcurrencyKey = "MODEL_CONCURRENCY"
+	// WebAuthnRPIDKey overrides the WebAuthn relying party ID; derived from the origins when empty.
+	WebAuthnRPIDKey = "WEB_AUTHN_RPID"
+	// WebAuthnRPNameKey overrides the WebAuthn relying party display name.
+	WebAuthnRPNameKey = "WEB_AUTHN_RP_NAME"
+	// WebAuthnOriginKey sets a single allowed WebAuthn origin; WEB_AUTHN_ORIGINS takes precedence.
+	WebAuthnOriginKey = "WEB_AUTHN_ORIGIN"
+	// WebAuthnOriginsKey lists the allowed WebAuthn origins.
+	WebAuthnOriginsKey = "WEB_AUTHN_ORIGINS"
 	// DefaultQuotaPollIntervalSeconds is the fallback poll interval (seconds).
 	DefaultQuotaPollIntervalSeconds = 180
 	// DefaultQuotaPollMaxConcurrency is the fallback max concurrency.
 	DefaultQuotaPollMaxConcurrency = 5
+	// DefaultQuotaLowThresholdPercent is the fallback low quota warning threshold.
+	DefaultQuotaLowThresholdPercent = 10
 	// DefaultAutoAssignProxy sets auto-assign proxy default.
 	DefaultAutoAssignProxy = false
+	// DefaultAnalyticsAnonymize sets the anonymized analytics default.
+	DefaultAnalyticsAnonymize = false
+	// DefaultChaosTesting sets the chaos testing default.
+	DefaultChaosTesting = false
+	// DefaultRequireEmailVerification sets the email verification requirement default.
+	DefaultRequireEmailVerification = false
+	// DefaultDisplayCurrency is the fallback display currency.
+	DefaultDisplayCurrency = "USD"
 	// DefaultRateLimit is the fallback rate limit (0 means unlimited).
 	DefaultRateLimit = 0
 	// DefaultRateLimitRedisPrefix is the fallback Redis key prefix.
@@ -9,6 +9,7 @@ import (
 
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // RefreshDBConfigSnapshot reloads all settings from the database and updates the in-memory snapshot.
@@ -26,7 +27,7 @@ func RefreshDBConfigSnapshot(ctx context.Context, db *gorm.DB) error {
 	var rows []models.Setting
 	if errFind := db.WithContext(ctx).
 		Select("key", "value", "updated_at").
-		Order("key ASC").
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "key"}}).
 		Find(&rows).Error; errFind != nil {
 		return errFind
 	}
@@ -0,0 +1,36 @@
+package settings
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestRefreshDBConfigSnapshotQuotesKeyOnMySQL(t *testing.T) {
+	conn, errOpen := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "user:pass@tcp(127.0.0.1:3306)/cpab?parseTime=true",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if errOpen != nil {
+		t.Fatalf("open mysql dialector: %v", errOpen)
+	}
+	var query string
+	if errRegister := conn.Callback().Query().After("gorm:query").Register("test:capture", func(tx *gorm.DB) {
+		query = tx.Statement.SQL.String()
+	}); errRegister != nil {
+		t.Fatalf("register callback: %v", errRegister)
+	}
+
+	previous := loadDBConfig()
+	t.Cleanup(func() { StoreDBConfig(previous.updatedAt, previous.values) })
+	if errRefresh := RefreshDBConfigSnapshot(context.Background(), conn); errRefresh != nil {
+		t.Fatalf("refresh: %v", errRefresh)
+	}
+	// key is reserved in MySQL, so every reference must be quoted.
+	if !strings.Contains(query, "ORDER BY `key`") || strings.Contains(query, " key ") {
+		t.Fatalf("expected quoted key column, got %s", query)
+	}
+}
@@ -0,0 +1,284 @@
+package settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ValueType is the JSON shape a registered setting accepts.
+type ValueType string
+
+const (
+	// TypeBool accepts true or false; "true", "false", "1" and "0" strings are tolerated.
+	TypeBool ValueType = "bool"
+	// TypeInt accepts an integer, or a string holding one, within Min and Max.
+	TypeInt ValueType = "int"
+	// TypeString accepts a string, optionally matching Pattern.
+	TypeString ValueType = "string"
+	// TypeURL accepts an empty string or an absolute http(s) URL.
+	TypeURL ValueType = "url"
+	// TypeEmail accepts an empty string or a bare email address.
+	TypeEmail ValueType = "email"
+	// TypeEnum accepts one of Enum.
+	TypeEnum ValueType = "enum"
+	// TypeList accepts an array of strings or a comma-separated string.
+	TypeList ValueType = "list"
+	// TypeObject accepts a JSON object; its fields are validated by the feature reading it.
+	TypeObject ValueType = "object"
+)
+
+// Definition describes a DB-backed setting.
+type Definition struct {
+	Key         string    `json:"key"`
+	Type        ValueType `json:"type"`
+	Default     any       `json:"default,omitempty"`
+	Min         *int64    `json:"min,omitempty"`
+	Max         *int64    `json:"max,omitempty"`
+	Enum        []string  `json:"enum,omitempty"`
+	Pattern     string    `json:"pattern,omitempty"`
+	Secret      bool      `json:"secret"`  // Value holds credentials; masked in responses and change history.
+	Managed     bool      `json:"managed"` // Value is written by a dedicated endpoint only and never returned.
+	Description string    `json:"description"`
+}
+
+// ErrUnknownSetting reports a key missing from the registry.
+var ErrUnknownSetting = errors.New("unknown setting")
+
+// ErrManagedSetting reports a write to a setting owned by a dedicated endpoint.
+var ErrManagedSetting = errors.New("setting is managed by a dedicated endpoint")
+
+func int64Ptr(v int64) *int64 { return &v }
+
+// registry lists every setting read from the settings table.
+var registry = []Definition{
+	{Key: SiteNameKey, Type: TypeString, Default: DefaultSiteName, Description: "UI site name."},
+	{Key: BrandingProductNameKey, Type: TypeString, Description: "Product name shown in the user portal."},
+	{Key: BrandingLogoURLKey, Type: TypeURL, Description: "Logo URL shown in the user portal."},
+	{Key: BrandingFaviconURLKey, Type: TypeURL, Description: "Favicon URL shown in the user portal."},
+	{Key: BrandingSupportEmailKey, Type: TypeEmail, Description: "Support contact address shown to users."},
+	{Key: BrandingHomepageURLKey, Type: TypeURL, Description: "Reseller homepage link."},
+	{Key: BrandingDocsURLKey, Type: TypeURL, Description: "Documentation link."},
+	{Key: BrandingTermsURLKey, Type: TypeURL, Description: "Terms of service link."},
+	{Key: BrandingPrivacyURLKey, Type: TypeURL, Description: "Privacy policy link."},
+	{Key: OnlyMappedModelsKey, Type: TypeBool, Default: true, Description: "Expose only models with a model mapping."},
+	{Key: QuotaPollIntervalSecondsKey, Type: TypeInt, Default: DefaultQuotaPollIntervalSeconds, Min: int64Ptr(1), Max: int64Ptr(86400), Description: "Quota poll interval in seconds."},
+	{Key: QuotaPollMaxConcurrencyKey, Type: TypeInt, Default: DefaultQuotaPollMaxConcurrency, Min: int64Ptr(1), Max: int64Ptr(100), Description: "Maximum concurrent quota requests."},
+	{Key: QuotaLowThresholdPercentKey, Type: TypeInt, Default: DefaultQuotaLowThresholdPercent, Min: int64Ptr(0), Max: int64Ptr(100), Description: "Remaining quota percent below which a quota.low event fires; 0 disables it."},
+	{Key: AutoAssignProxyKey, Type: TypeBool, Default: DefaultAutoAssignProxy, Description: "Assign a pool proxy to new auth files and provider API keys."},
+	{Key: AutoAssignProxyStrategyKey, Type: TypeEnum, Default: "random", Enum: []string{"random", "least_used", "region", "sticky_group"}, Description: "How auto assignment picks a proxy."},
+	{Key: ProxyProviderRegionsKey, Type: TypeObject, Description: "Provider to preferred proxy region for region assignment."},
+	{Key: ProxyHealthKey, Type: TypeObject, Description: "Proxy pool health checks."},
+	{Key: RateLimitKey, Type: TypeInt, Default: DefaultRateLimit, Min: int64Ptr(0), Description: "Default requests per second per API key; 0 is unlimited."},
+	{Key: ModelConcurrencyKey, Type: TypeObject, Description: "Per-model concurrency caps with optional FIFO queueing."},
+	{Key: RateLimitRedisEnabledKey, Type: TypeBool, Default: false, Description: "Share rate limits across instances through Redis."},
+	{Key: RateLimitRedisAddrKey, Type: TypeString, Description: "Redis address for rate limiting."},
+	{Key: RateLimitRedisPasswordKey, Type: TypeString, Secret: true, Description: "Redis password for rate limiting."},
+	{Key: RateLimitRedisDBKey, Type: TypeInt, Default: 0, Min: int64Ptr(0), Max: int64Ptr(15), Description: "Redis DB index for rate limiting."},
+	{Key: RateLimitRedisPrefixKey, Type: TypeString, Default: DefaultRateLimitRedisPrefix, Description: "Redis key prefix for rate limiting."},
+	{Key: UsagesRetentionDaysKey, Type: TypeInt, Default: DefaultUsagesRetentionDays, Min: int64Ptr(0), Description: "Days raw usage rows are kept; 0 keeps them forever."},
+	{Key: OAuthCallbackHostKey, Type: TypeString, Default: DefaultOAuthCallbackHost, Description: "Host used in local OAuth callback redirect URIs."},
+	{Key: EventWebhookURLsKey, Type: TypeList, Secret: true, Description: "Webhook URLs that receive bus events."},
+	{Key: EventWebhookTemplatesKey, Type: TypeObject, Description: "Webhook URL (or \"*\") to Go-template payload shapes."},
+	{Key: EventChatWebhookURLKey, Type: TypeURL, Secret: true, Description: "Slack-compatible incoming webhook for warning events."},
+	{Key: EventEmailKey, Type: TypeObject, Secret: true, Description: "SMTP delivery of warning events."},
+	{Key: SMTPKey, Type: TypeObject, Secret: true, Description: "Outbound user email."},
+	{Key: EventThrottlePoliciesKey, Type: TypeObject, Description: "Per-channel notification throttle and digest policies."},
+	{Key: AuthImportApprovalKey, Type: TypeObject, Description: "Risky auth import approval rules."},
+	{Key: CoopPoolKey, Type: TypeObject, Description: "User-contributed credential pools."},
+	{Key: AnalyticsAnonymizeKey, Type: TypeBool, Default: DefaultAnalyticsAnonymize, Description: "Pseudonymize user and key identifiers in analytics."},
+	{Key: ChaosTestingKey, Type: TypeBool, Default: DefaultChaosTesting, Description: "Allow fault injection; keep off in production."},
+	{Key: DisplayCurrencyKey, Type: TypeString, Default: DefaultDisplayCurrency, Pattern: "^[A-Za-z]{3}$", Description: "ISO currency costs are shown in."},
+	{Key: ExchangeRateFeedKey, Type: TypeObject, Description: "Fetched exchange rates."},
+	{Key: UsageAnomalyDetectionKey, Type: TypeObject, Description: "Spend spike alerts."},
+	{Key: RequireEmailVerificationKey, Type: TypeBool, Default: DefaultRequireEmailVerification, Description: "Block API key creation until the user verified their email."},
+	{Key: MFAPolicyKey, Type: TypeObject, Description: "MFA enrollment requirements per role."},
+	{Key: TrustedProxiesKey, Type: TypeList, Description: "Proxy IPs or CIDRs whose forwarding headers are honored."},
+	{Key: AuthCooldownKey, Type: TypeObject, Description: "Automatic auth cooldowns after upstream 429s."},
+	{Key: OIDCKey, Type: TypeObject, Secret: true, Description: "Admin panel single sign-on."},
+	{Key: LDAPKey, Type: TypeObject, Secret: true, Description: "Directory logins for front users."},
+	{Key: SocialLoginKey, Type: TypeObject, Secret: true, Description: "GitHub and Google sign-in for front users."},
+	{Key: RegistrationInviteOnlyKey, Type: TypeBool, Default: false, Description: "Require an invitation code for self-service registration."},
+	{Key: RequestLogKey, Type: TypeObject, Description: "Request body capture."},
+	{Key: ClusterBusKey, Type: TypeObject, Secret: true, Description: "Cross-instance change notifications."},
+	{Key: UserLifecycleKey, Type: TypeObject, Description: "Automated user lifecycle actions."},
+	{Key: JWTRotationKey, Type: TypeObject, Secret: true, Managed: true, Description: "Rotated JWT signing secret; written by the rotation endpoint."},
+	{Key: WebAuthnRPIDKey, Type: TypeString, Description: "WebAuthn relying party ID; derived from the origins when empty."},
+	{Key: WebAuthnRPNameKey, Type: TypeString, Description: "WebAuthn relying party display name."},
+	{Key: WebAuthnOriginKey, Type: TypeURL, Description: "Single allowed WebAuthn origin."},
+	{Key: WebAuthnOriginsKey, Type: TypeList, Description: "Allowed WebAuthn origins."},
+}
+
+// Definitions returns the registered settings ordered by key.
+func Definitions() []Definition {
+	out := make([]Definition, len(registry))
+	copy(out, registry)
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// LookupDefinition returns the definition of key.
+func LookupDefinition(key string) (Definition, bool) {
+	key = strings.TrimSpace(key)
+	for _, def := range registry {
+		if def.Key == key {
+			return def, true
+		}
+	}
+	return Definition{}, false
+}
+
+// ValidateValue checks raw against the definition of key. Unregistered keys are rejected
+// with ErrUnknownSetting.
+func ValidateValue(key string, raw json.RawMessage) error {
+	def, ok := LookupDefinition(key)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownSetting, key)
+	}
+	return def.Validate(raw)
+}
+
+// Validate checks raw against the definition.
+func (d Definition) Validate(raw json.RawMessage) error {
+	if d.Managed {
+		return fmt.Errorf("%w: %s", ErrManagedSetting, d.Key)
+	}
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || !json.Valid(raw) {
+		return errors.New("value must be valid json")
+	}
+	if string(raw) == "null" {
+		// null clears the value so the feature falls back to its default.
+		return nil
+	}
+	switch d.Type {
+	case TypeBool:
+		if _, ok := parseBool(raw); !ok {
+			return errors.New("value must be a boolean")
+		}
+	case TypeInt:
+		n, ok := parseInt(raw)
+		if !ok {
+			return errors.New("value must be an integer")
+		}
+		if d.Min != nil && n < *d.Min {
+			return fmt.Errorf("value must be at least %d", *d.Min)
+		}
+		if d.Max != nil && n > *d.Max {
+			return fmt.Errorf("value must be at most %d", *d.Max)
+		}
+	case TypeString:
+		var s string
+		if json.Unmarshal(raw, &s) != nil {
+			return errors.New("value must be a string")
+		}
+		if d.Pattern != "" && !regexp.MustCompile(d.Pattern).MatchString(strings.TrimSpace(s)) {
+			return fmt.Errorf("value must match %s", d.Pattern)
+		}
+	case TypeURL:
+		if !isOptionalHTTPURL(raw) {
+			return errors.New("value must be an absolute http(s) url or empty")
+		}
+	case TypeEmail:
+		if !isOptionalEmail(raw) {
+			return errors.New("value must be a valid email address or empty")
+		}
+	case TypeEnum:
+		var s string
+		if json.Unmarshal(raw, &s) != nil {
+			return errors.New("value must be a string")
+		}
+		for _, allowed := range d.Enum {
+			if strings.EqualFold(strings.TrimSpace(s), allowed) {
+				return nil
+			}
+		}
+		return fmt.Errorf("value must be one of %s", strings.Join(d.Enum, ", "))
+	case TypeList:
+		var list []string
+		var s string
+		if json.Unmarshal(raw, &list) != nil && json.Unmarshal(raw, &s) != nil {
+			return errors.New("value must be an array of strings or a comma-separated string")
+		}
+	case TypeObject:
+		if raw[0] != '{' {
+			return errors.New("value must be a json object")
+		}
+	}
+	return nil
+}
+
+// parseBool parses booleans and their common string spellings.
+func parseBool(raw json.RawMessage) (bool, bool) {
+	var b bool
+	if json.Unmarshal(raw, &b) == nil {
+		return b, true
+	}
+	var s string
+	if json.Unmarshal(raw, &s) != nil {
+		return false, false
+	}
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "true", "1":
+		return true, true
+	case "false", "0":
+		return false, true
+	}
+	return false, false
+}
+
+// parseInt parses integral numbers and strings holding one.
+func parseInt(raw json.RawMessage) (int64, bool) {
+	var f float64
+	if json.Unmarshal(raw, &f) == nil {
+		if math.IsNaN(f) || math.IsInf(f, 0) || f != math.Trunc(f) || math.Abs(f) > 1<<53 {
+			return 0, false
+		}
+		return int64(f), true
+	}
+	var s string
+	if json.Unmarshal(raw, &s) != nil {
+		return 0, false
+	}
+	n, errParse := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	return n, errParse == nil
+}
+
+// isOptionalHTTPURL reports whether raw is an empty string or an absolute http(s) URL.
+func isOptionalHTTPURL(raw json.RawMessage) bool {
+	var value string
+	if json.Unmarshal(raw, &value) != nil {
+		return false
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return true
+	}
+	parsed, errParse := url.Parse(value)
+	if errParse != nil || parsed.Host == "" {
+		return false
+	}
+	return parsed.Scheme == "http" || parsed.Scheme == "https"
+}
+
+// isOptionalEmail reports whether raw is an empty string or a bare email address.
+func isOptionalEmail(raw json.RawMessage) bool {
+	var value string
+	if json.Unmarshal(raw, &value) != nil {
+		return false
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return true
+	}
+	addr, errParse := mail.ParseAddress(value)
+	return errParse == nil && addr.Address == value
+}
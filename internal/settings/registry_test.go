@@ -0,0 +1,76 @@
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRegistryCoversSettingKeys(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, def := range Definitions() {
+		if seen[def.Key] {
+			t.Fatalf("duplicate definition %s", def.Key)
+		}
+		seen[def.Key] = true
+		if strings.TrimSpace(def.Description) == "" {
+			t.Fatalf("definition %s has no description", def.Key)
+		}
+	}
+	for _, key := range []string{SiteNameKey, RateLimitKey, QuotaPollIntervalSecondsKey, SMTPKey, UserLifecycleKey} {
+		if !seen[key] {
+			t.Fatalf("registry missing %s", key)
+		}
+	}
+}
+
+func TestValidateValue(t *testing.T) {
+	cases := []struct {
+		key   string
+		value string
+		ok    bool
+	}{
+		{QuotaPollIntervalSecondsKey, `30`, true},
+		{QuotaPollIntervalSecondsKey, `"30"`, true},
+		{QuotaPollIntervalSecondsKey, `0`, false},
+		{QuotaPollIntervalSecondsKey, `1.5`, false},
+		{RateLimitKey, `0`, true},
+		{RateLimitKey, `-1`, false},
+		{RateLimitRedisDBKey, `16`, false},
+		{AutoAssignProxyKey, `true`, true},
+		{AutoAssignProxyKey, `"yes"`, false},
+		{AutoAssignProxyStrategyKey, `"least_used"`, true},
+		{AutoAssignProxyStrategyKey, `"fastest"`, false},
+		{BrandingLogoURLKey, `""`, true},
+		{BrandingLogoURLKey, `"ftp://example.com/logo.png"`, false},
+		{BrandingSupportEmailKey, `"help@example.com"`, true},
+		{BrandingSupportEmailKey, `"Help <help@example.com>"`, false},
+		{DisplayCurrencyKey, `"eur"`, true},
+		{DisplayCurrencyKey, `"EURO"`, false},
+		{TrustedProxiesKey, `["10.0.0.0/8"]`, true},
+		{TrustedProxiesKey, `"10.0.0.1, 10.0.0.2"`, true},
+		{SMTPKey, `{"host":"smtp.example.com"}`, true},
+		{SMTPKey, `[]`, false},
+		{UsagesRetentionDaysKey, `null`, true},
+		{UsagesRetentionDaysKey, `{`, false},
+	}
+	for _, tc := range cases {
+		errValidate := ValidateValue(tc.key, json.RawMessage(tc.value))
+		if (errValidate == nil) != tc.ok {
+			t.Errorf("ValidateValue(%s, %s) = %v, want ok=%v", tc.key, tc.value, errValidate, tc.ok)
+		}
+	}
+}
+
+func TestValidateValueRejectsUnknownKey(t *testing.T) {
+	if errValidate := ValidateValue("NOT_A_SETTING", json.RawMessage(`1`)); !errors.Is(errValidate, ErrUnknownSetting) {
+		t.Fatalf("expected ErrUnknownSetting, got %v", errValidate)
+	}
+}
+
+func TestValidateValueRejectsManagedKey(t *testing.T) {
+	if errValidate := ValidateValue(JWTRotationKey, json.RawMessage(`{"secret":"attacker-chosen-secret-value-0123456789"}`)); !errors.Is(errValidate, ErrManagedSetting) {
+		t.Fatalf("expected ErrManagedSetting, got %v", errValidate)
+	}
+}
@@ -0,0 +1,107 @@
+// Package shutdown coordinates a graceful stop of the main server. The drainer counts
+// in-flight HTTP requests; once draining starts it turns new requests away so load balancers
+// move traffic elsewhere, and Drain waits for the requests already running to finish.
+package shutdown
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// drainPollInterval is how often Drain rechecks the in-flight count.
+const drainPollInterval = 50 * time.Millisecond
+
+// Drainer tracks in-flight requests and rejects new ones while draining.
+type Drainer struct {
+	inFlight atomic.Int64
+	draining atomic.Bool
+}
+
+// NewDrainer constructs a drainer.
+func NewDrainer() *Drainer {
+	return &Drainer{}
+}
+
+// defaultDrainer is the process-wide drainer used by the main server.
+var defaultDrainer = NewDrainer()
+
+// Default returns the process-wide drainer.
+func Default() *Drainer {
+	return defaultDrainer
+}
+
+// Draining reports whether draining has started.
+func (d *Drainer) Draining() bool {
+	return d != nil && d.draining.Load()
+}
+
+// InFlight returns the number of requests currently being served.
+func (d *Drainer) InFlight() int64 {
+	if d == nil {
+		return 0
+	}
+	return d.inFlight.Load()
+}
+
+// Middleware counts requests and answers 503 once draining has started. Health probes pass
+// through so /readyz can report the drain.
+func (d *Drainer) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if d == nil {
+			c.Next()
+			return
+		}
+		if d.draining.Load() && !isProbe(c.Request.URL.Path) {
+			c.Header("Connection", "close")
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "server is shutting down"})
+			return
+		}
+		d.inFlight.Add(1)
+		defer d.inFlight.Add(-1)
+		c.Next()
+	}
+}
+
+// Drain stops admitting requests and waits until none are in flight or ctx expires. It
+// returns the number of requests still running when it gave up.
+func (d *Drainer) Drain(ctx context.Context) int64 {
+	if d == nil {
+		return 0
+	}
+	d.draining.Store(true)
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		remaining := d.inFlight.Load()
+		if remaining <= 0 {
+			return 0
+		}
+		select {
+		case <-ctx.Done():
+			return remaining
+		case <-ticker.C:
+		}
+	}
+}
+
+// isProbe reports whether path is a health probe.
+func isProbe(path string) bool {
+	return path == "/readyz" || path == "/healthz" || strings.HasPrefix(path, "/healthz/")
+}
+
+// Summary describes one graceful shutdown.
+type Summary struct {
+	Signal            string        // Signal or reason that started the shutdown.
+	DrainedRequests   int64         // Requests in flight when draining started.
+	AbandonedRequests int64         // Requests still running when the drain timed out.
+	QueuedUsage       int           // Usage records queued when the writer was closed.
+	UsageFlushed      bool          // Whether the usage queue was written completely.
+	QuotaPollerOK     bool          // Whether the quota poller stopped before the deadline.
+	Duration          time.Duration // Time from signal to the end of the shutdown.
+}
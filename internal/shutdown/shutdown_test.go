@@ -0,0 +1,87 @@
+package shutdown
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newDrainRouter(d *Drainer, release <-chan struct{}, entered chan<- struct{}) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(d.Middleware())
+	r.GET("/slow", func(c *gin.Context) {
+		entered <- struct{}{}
+		<-release
+		c.Status(http.StatusOK)
+	})
+	r.GET("/readyz", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func TestDrainWaitsForInFlightAndRejectsNewRequests(t *testing.T) {
+	d := NewDrainer()
+	release := make(chan struct{})
+	entered := make(chan struct{}, 1)
+	r := newDrainRouter(d, release, entered)
+
+	slowDone := make(chan int, 1)
+	go func() {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		slowDone <- rec.Code
+	}()
+	<-entered
+	if got := d.InFlight(); got != 1 {
+		t.Fatalf("in flight = %d, want 1", got)
+	}
+
+	drained := make(chan int64, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		drained <- d.Drain(ctx)
+	}()
+	for !d.Draining() {
+		time.Sleep(time.Millisecond)
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Connection") != "close" {
+		t.Fatalf("new request while draining = %d %q", rec.Code, rec.Header().Get("Connection"))
+	}
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("probe while draining = %d, want 200", rec.Code)
+	}
+
+	close(release)
+	if code := <-slowDone; code != http.StatusOK {
+		t.Fatalf("in-flight request = %d, want 200", code)
+	}
+	if remaining := <-drained; remaining != 0 {
+		t.Fatalf("remaining = %d, want 0", remaining)
+	}
+}
+
+func TestDrainGivesUpAtDeadline(t *testing.T) {
+	d := NewDrainer()
+	release := make(chan struct{})
+	defer close(release)
+	entered := make(chan struct{}, 1)
+	r := newDrainRouter(d, release, entered)
+	go r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	<-entered
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if remaining := d.Drain(ctx); remaining != 1 {
+		t.Fatalf("remaining = %d, want 1", remaining)
+	}
+}
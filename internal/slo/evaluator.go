@@ -0,0 +1,141 @@
+package slo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultEvaluateInterval = 5 * time.Minute
+	// alertCooldown bounds how often one SLO raises a burn-rate alert.
+	alertCooldown = time.Hour
+)
+
+// Evaluator periodically evaluates every enabled SLO, stores the result on the SLO row and
+// publishes burn-rate alerts.
+type Evaluator struct {
+	db       *gorm.DB
+	interval time.Duration
+}
+
+// NewEvaluator constructs an SLO evaluator; returns nil when db is nil.
+func NewEvaluator(db *gorm.DB) *Evaluator {
+	if db == nil {
+		return nil
+	}
+	return &Evaluator{db: db, interval: defaultEvaluateInterval}
+}
+
+// Start launches the evaluation loop in a background goroutine.
+func (e *Evaluator) Start(ctx context.Context) {
+	if e == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go e.run(ctx)
+	log.Infof("slo evaluator started (interval=%s)", e.interval)
+}
+
+func (e *Evaluator) run(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, errRun := e.RunOnce(ctx, time.Now().UTC()); errRun != nil {
+			log.WithError(errRun).Warn("slo evaluator: run failed")
+		}
+		timer := time.NewTimer(e.interval)
+		select {
+		case <-ctx.Done():
+			if !timer.Stop() {
+				<-timer.C
+			}
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// RunOnce evaluates every enabled SLO at now and returns the number of alerts raised.
+func (e *Evaluator) RunOnce(ctx context.Context, now time.Time) (int, error) {
+	if e == nil || e.db == nil {
+		return 0, nil
+	}
+	var rows []models.SLO
+	if errFind := e.db.WithContext(ctx).Where("is_enabled = ?", true).Order("id ASC").Find(&rows).Error; errFind != nil {
+		return 0, fmt.Errorf("slo: load objectives: %w", errFind)
+	}
+	raised := 0
+	for i := range rows {
+		if ctx.Err() != nil {
+			return raised, ctx.Err()
+		}
+		row := &rows[i]
+		status, errEval := Evaluate(ctx, e.db, row, now)
+		if errEval != nil {
+			log.WithError(errEval).Warnf("slo evaluator: evaluate %d failed", row.ID)
+			continue
+		}
+		evaluatedAt := now.UTC()
+		updates := map[string]any{
+			"last_evaluated_at":     evaluatedAt,
+			"last_state":            status.State,
+			"last_compliance":       status.Compliance,
+			"last_budget_remaining": status.BudgetRemaining,
+		}
+		alert := status.FastBurn.Alerting || status.SlowBurn.Alerting
+		if alert && row.LastAlertedAt != nil && now.Sub(*row.LastAlertedAt) < alertCooldown {
+			alert = false
+		}
+		if alert {
+			updates["last_alerted_at"] = evaluatedAt
+		}
+		if errUpdate := e.db.WithContext(ctx).Model(&models.SLO{}).Where("id = ?", row.ID).Updates(updates).Error; errUpdate != nil {
+			log.WithError(errUpdate).Warnf("slo evaluator: store status %d failed", row.ID)
+			continue
+		}
+		if alert {
+			publishBurn(ctx, row, status)
+			raised++
+		}
+	}
+	return raised, nil
+}
+
+// publishBurn raises a burn-rate alert; fast burns are critical, slow burns warnings.
+func publishBurn(ctx context.Context, row *models.SLO, status Status) {
+	severity := events.SeverityWarning
+	burn := status.SlowBurn
+	if status.FastBurn.Alerting {
+		severity = events.SeverityCritical
+		burn = status.FastBurn
+	}
+	events.Publish(ctx, events.Event{
+		Type:     events.TypeSLOBurnRate,
+		Severity: severity,
+		Subject:  "slo:" + strconv.FormatUint(row.ID, 10),
+		Message: fmt.Sprintf("SLO %q is burning its error budget at %.1fx over the last %s (%.2f%% budget left)",
+			row.Name, burn.Rate, burn.Window, status.BudgetRemaining),
+		Data: map[string]any{
+			"slo_id":           row.ID,
+			"name":             row.Name,
+			"kind":             string(row.Kind),
+			"objective":        row.Objective,
+			"window":           burn.Window,
+			"burn_rate":        burn.Rate,
+			"requests":         burn.Requests,
+			"bad":              burn.Bad,
+			"compliance":       status.Compliance,
+			"budget_remaining": status.BudgetRemaining,
+		},
+	})
+}
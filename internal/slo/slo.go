@@ -0,0 +1,186 @@
+// Package slo evaluates admin-defined service level objectives against usage records and
+// raises burn-rate alerts on the event bus when an error budget is being spent too fast.
+//
+// Burn rate is the observed bad-request ratio divided by the ratio the objective allows:
+// a burn rate of 1 spends exactly the whole budget over the compliance window. Alerts
+// follow the usual multi-window scheme: a fast burn over the last hour pages, a slower
+// burn over six hours warns.
+package slo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// FastBurnWindow and FastBurnRate raise critical alerts: 2% of a 30-day budget in an hour.
+	FastBurnWindow = time.Hour
+	FastBurnRate   = 14.4
+	// SlowBurnWindow and SlowBurnRate raise warnings: 5% of a 30-day budget in six hours.
+	SlowBurnWindow = 6 * time.Hour
+	SlowBurnRate   = 6
+	// minBurnRequests is how many requests a burn window needs before its rate is trusted.
+	minBurnRequests = 20
+	maxWindowDays   = 90
+)
+
+// States reported for an SLO.
+const (
+	StateOK       = "ok"
+	StateBurning  = "burning"
+	StateBreached = "breached"
+)
+
+// ErrInvalid is returned when an SLO definition is malformed.
+var ErrInvalid = errors.New("slo: invalid definition")
+
+// Normalize trims and validates an SLO definition in place, filling defaults.
+func Normalize(row *models.SLO) error {
+	row.Name = strings.TrimSpace(row.Name)
+	row.Description = strings.TrimSpace(row.Description)
+	row.Provider = strings.TrimSpace(row.Provider)
+	row.Model = strings.TrimSpace(row.Model)
+	row.Kind = models.SLOKind(strings.ToLower(strings.TrimSpace(string(row.Kind))))
+	if row.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	switch row.Kind {
+	case models.SLOKindLatency:
+		if row.ThresholdMillis <= 0 {
+			return fmt.Errorf("%w: threshold_millis must be positive for latency objectives", ErrInvalid)
+		}
+	case models.SLOKindErrorRate:
+		row.ThresholdMillis = 0
+	default:
+		return fmt.Errorf("%w: kind must be latency or error_rate", ErrInvalid)
+	}
+	if row.Objective <= 0 || row.Objective >= 100 {
+		return fmt.Errorf("%w: objective must be between 0 and 100 percent", ErrInvalid)
+	}
+	if row.WindowDays == 0 {
+		row.WindowDays = 30
+	}
+	if row.WindowDays < 1 || row.WindowDays > maxWindowDays {
+		return fmt.Errorf("%w: window_days must be between 1 and %d", ErrInvalid, maxWindowDays)
+	}
+	return nil
+}
+
+// Burn is the burn rate over one alerting window.
+type Burn struct {
+	Window   string  `json:"window"`
+	Requests int64   `json:"requests"`
+	Bad      int64   `json:"bad"`
+	Rate     float64 `json:"rate"`      // Bad ratio over the allowed ratio.
+	Alerting bool    `json:"alerting"`  // Whether the rate crossed its threshold.
+	Limit    float64 `json:"threshold"` // Burn rate that alerts.
+}
+
+// Status is the evaluation of one SLO.
+type Status struct {
+	SLOID           uint64    `json:"slo_id"`
+	Name            string    `json:"name"`
+	Kind            string    `json:"kind"`
+	Objective       float64   `json:"objective"`
+	WindowDays      int       `json:"window_days"`
+	Requests        int64     `json:"requests"`
+	Bad             int64     `json:"bad"`
+	Compliance      float64   `json:"compliance"`       // Good requests in the window, in percent; 100 without traffic.
+	BudgetRemaining float64   `json:"budget_remaining"` // Error budget left, in percent; negative once overspent.
+	FastBurn        Burn      `json:"fast_burn"`
+	SlowBurn        Burn      `json:"slow_burn"`
+	State           string    `json:"state"`
+	EvaluatedAt     time.Time `json:"evaluated_at"`
+}
+
+// Evaluate measures the SLO over its compliance window and both burn windows ending at now.
+func Evaluate(ctx context.Context, db *gorm.DB, row *models.SLO, now time.Time) (Status, error) {
+	out := Status{
+		SLOID:       row.ID,
+		Name:        row.Name,
+		Kind:        string(row.Kind),
+		Objective:   row.Objective,
+		WindowDays:  row.WindowDays,
+		EvaluatedAt: now.UTC(),
+	}
+	allowed := 1 - row.Objective/100
+
+	requests, bad, errCount := countRequests(ctx, db, row, now.AddDate(0, 0, -row.WindowDays), now)
+	if errCount != nil {
+		return Status{}, errCount
+	}
+	out.Requests, out.Bad = requests, bad
+	out.Compliance, out.BudgetRemaining = 100, 100
+	if requests > 0 {
+		badRatio := float64(bad) / float64(requests)
+		out.Compliance = round((1 - badRatio) * 100)
+		out.BudgetRemaining = round((1 - badRatio/allowed) * 100)
+	}
+
+	for _, burn := range []struct {
+		target *Burn
+		window time.Duration
+		limit  float64
+	}{
+		{&out.FastBurn, FastBurnWindow, FastBurnRate},
+		{&out.SlowBurn, SlowBurnWindow, SlowBurnRate},
+	} {
+		requests, bad, errCount := countRequests(ctx, db, row, now.Add(-burn.window), now)
+		if errCount != nil {
+			return Status{}, errCount
+		}
+		*burn.target = Burn{Window: burn.window.String(), Requests: requests, Bad: bad, Limit: burn.limit}
+		if requests > 0 {
+			burn.target.Rate = round(float64(bad) / float64(requests) / allowed)
+		}
+		burn.target.Alerting = requests >= minBurnRequests && burn.target.Rate >= burn.limit
+	}
+
+	switch {
+	case out.BudgetRemaining < 0:
+		out.State = StateBreached
+	case out.FastBurn.Alerting || out.SlowBurn.Alerting:
+		out.State = StateBurning
+	default:
+		out.State = StateOK
+	}
+	return out, nil
+}
+
+// countRequests returns the total and bad requests in [from, to) matching the SLO filters.
+func countRequests(ctx context.Context, db *gorm.DB, row *models.SLO, from, to time.Time) (int64, int64, error) {
+	badExpr := "failed"
+	if row.Kind == models.SLOKindLatency {
+		badExpr = dbutil.DurationMillisExpr(db, "requested_at", "created_at") + " > " + strconv.FormatInt(row.ThresholdMillis, 10)
+	}
+	q := db.WithContext(ctx).Model(&models.Usage{}).
+		Where("requested_at >= ? AND requested_at < ?", from.UTC(), to.UTC())
+	if row.Provider != "" {
+		q = q.Where("provider = ?", row.Provider)
+	}
+	if row.Model != "" {
+		q = q.Where("model = ?", row.Model)
+	}
+	var counts struct {
+		Requests int64
+		Bad      int64
+	}
+	if errScan := q.Select("COUNT(*) AS requests, COALESCE(SUM(CASE WHEN " + badExpr + " THEN 1 ELSE 0 END), 0) AS bad").
+		Scan(&counts).Error; errScan != nil {
+		return 0, 0, fmt.Errorf("slo: count requests: %w", errScan)
+	}
+	return counts.Requests, counts.Bad, nil
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
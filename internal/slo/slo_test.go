@@ -0,0 +1,125 @@
+package slo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func setupSLODB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:slo_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	row := models.SLO{Name: " api latency ", Kind: " Latency ", ThresholdMillis: 30_000, Objective: 99}
+	if errNormalize := Normalize(&row); errNormalize != nil {
+		t.Fatalf("Normalize: %v", errNormalize)
+	}
+	if row.Name != "api latency" || row.Kind != models.SLOKindLatency || row.WindowDays != 30 {
+		t.Fatalf("unexpected normalized row %+v", row)
+	}
+	for _, bad := range []models.SLO{
+		{Name: "x", Kind: models.SLOKindLatency, Objective: 99},
+		{Name: "x", Kind: models.SLOKindErrorRate, Objective: 100},
+		{Name: "x", Kind: "throughput", Objective: 99},
+		{Name: "x", Kind: models.SLOKindErrorRate, Objective: 99, WindowDays: 365},
+		{Kind: models.SLOKindErrorRate, Objective: 99},
+	} {
+		if errNormalize := Normalize(&bad); !errors.Is(errNormalize, ErrInvalid) {
+			t.Fatalf("Normalize(%+v) error = %v, want ErrInvalid", bad, errNormalize)
+		}
+	}
+}
+
+func seedRequests(t *testing.T, conn *gorm.DB, at time.Time, count int, failed bool, duration time.Duration) {
+	t.Helper()
+	rows := make([]models.Usage, 0, count)
+	for i := 0; i < count; i++ {
+		requested := at.Add(time.Duration(i) * time.Second)
+		rows = append(rows, models.Usage{
+			Provider:    "codex",
+			Model:       "gpt-5",
+			RequestedAt: requested,
+			CreatedAt:   requested.Add(duration),
+			Failed:      failed,
+		})
+	}
+	if errCreate := conn.Create(&rows).Error; errCreate != nil {
+		t.Fatalf("seed usage: %v", errCreate)
+	}
+}
+
+func TestEvaluateAndAlertOnFastBurn(t *testing.T) {
+	conn := setupSLODB(t)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	// A quiet week of healthy traffic, then an hour where a fifth of requests fail and are slow.
+	seedRequests(t, conn, now.AddDate(0, 0, -7), 900, false, time.Second)
+	seedRequests(t, conn, now.Add(-30*time.Minute), 80, false, time.Second)
+	seedRequests(t, conn, now.Add(-20*time.Minute), 20, true, 40*time.Second)
+
+	errorRate := models.SLO{Name: "errors", Kind: models.SLOKindErrorRate, Objective: 99, WindowDays: 30, IsEnabled: true}
+	latency := models.SLO{Name: "latency", Kind: models.SLOKindLatency, ThresholdMillis: 30_000, Objective: 99, WindowDays: 30, IsEnabled: true}
+	otherModel := models.SLO{Name: "other", Kind: models.SLOKindErrorRate, Objective: 99, WindowDays: 30, Model: "sonnet", IsEnabled: true}
+	for _, row := range []*models.SLO{&errorRate, &latency, &otherModel} {
+		if errCreate := conn.Create(row).Error; errCreate != nil {
+			t.Fatalf("create slo: %v", errCreate)
+		}
+	}
+
+	status, errEval := Evaluate(ctx, conn, &errorRate, now)
+	if errEval != nil {
+		t.Fatalf("Evaluate: %v", errEval)
+	}
+	if status.Requests != 1000 || status.Bad != 20 || status.Compliance != 98 || status.BudgetRemaining != -100 {
+		t.Fatalf("unexpected window status %+v", status)
+	}
+	if status.FastBurn.Requests != 100 || status.FastBurn.Rate != 20 || !status.FastBurn.Alerting || status.State != StateBreached {
+		t.Fatalf("unexpected fast burn %+v state=%s", status.FastBurn, status.State)
+	}
+	latencyStatus, errEval := Evaluate(ctx, conn, &latency, now)
+	if errEval != nil {
+		t.Fatalf("Evaluate latency: %v", errEval)
+	}
+	if latencyStatus.Bad != 20 {
+		t.Fatalf("expected slow requests counted as bad, got %+v", latencyStatus)
+	}
+
+	evaluator := NewEvaluator(conn)
+	raised, errRun := evaluator.RunOnce(ctx, now)
+	if errRun != nil {
+		t.Fatalf("RunOnce: %v", errRun)
+	}
+	if raised != 2 {
+		t.Fatalf("expected alerts for errors and latency, got %d", raised)
+	}
+	var stored models.SLO
+	if errFind := conn.First(&stored, otherModel.ID).Error; errFind != nil {
+		t.Fatalf("reload slo: %v", errFind)
+	}
+	if stored.LastState != StateOK || stored.LastAlertedAt != nil || stored.LastEvaluatedAt == nil {
+		t.Fatalf("expected unaffected model SLO ok, got %+v", stored)
+	}
+	if raised, _ = evaluator.RunOnce(ctx, now.Add(10*time.Minute)); raised != 0 {
+		t.Fatalf("expected cooldown to suppress repeat alerts, got %d", raised)
+	}
+}
@@ -0,0 +1,95 @@
+// Package sociallogin implements GitHub and Google OAuth sign-in for front users: the
+// authorization code flow, profile lookup, and linking provider identities to local accounts.
+package sociallogin
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+)
+
+// Registration policies for social logins without a matching account.
+const (
+	// RegistrationOpen creates a new user for unknown identities.
+	RegistrationOpen = "open"
+	// RegistrationClosed only signs in identities that are linked or match a verified email.
+	RegistrationClosed = "closed"
+)
+
+// ProviderConfig holds the OAuth client registered at one provider.
+type ProviderConfig struct {
+	Enabled      bool   `json:"enabled"`       // Whether the provider is offered.
+	ClientID     string `json:"client_id"`     // OAuth client ID.
+	ClientSecret string `json:"client_secret"` // OAuth client secret.
+	RedirectURL  string `json:"redirect_url"`  // Registered redirect URI that forwards code and state to the callback endpoint.
+}
+
+// Ready reports whether the provider is enabled and configured.
+func (p ProviderConfig) Ready() bool {
+	return p.Enabled && p.ClientID != "" && p.ClientSecret != "" && p.RedirectURL != ""
+}
+
+// Config mirrors the SOCIAL_LOGIN setting.
+type Config struct {
+	Registration string                    `json:"registration"` // open (default) or closed.
+	Providers    map[string]ProviderConfig `json:"providers"`    // Provider configs keyed by github or google.
+}
+
+// LoadConfig reads the SOCIAL_LOGIN setting; invalid values disable social login.
+func LoadConfig() Config {
+	var cfg Config
+	raw, ok := internalsettings.DBConfigValue(internalsettings.SocialLoginKey)
+	if ok && len(bytes.TrimSpace(raw)) > 0 {
+		if errUnmarshal := json.Unmarshal(raw, &cfg); errUnmarshal != nil {
+			log.WithError(errUnmarshal).Warn("social login: invalid setting")
+			cfg = Config{}
+		}
+	}
+	return cfg.withDefaults()
+}
+
+func (c Config) withDefaults() Config {
+	c.Registration = strings.ToLower(strings.TrimSpace(c.Registration))
+	if c.Registration != RegistrationClosed {
+		c.Registration = RegistrationOpen
+	}
+	providers := make(map[string]ProviderConfig, len(c.Providers))
+	for name, provider := range c.Providers {
+		provider.ClientID = strings.TrimSpace(provider.ClientID)
+		provider.RedirectURL = strings.TrimSpace(provider.RedirectURL)
+		providers[strings.ToLower(strings.TrimSpace(name))] = provider
+	}
+	c.Providers = providers
+	return c
+}
+
+// Provider returns the ready config of a supported provider.
+func (c Config) Provider(name string) (ProviderConfig, bool) {
+	if _, ok := providers[name]; !ok {
+		return ProviderConfig{}, false
+	}
+	provider, ok := c.Providers[name]
+	if !ok || !provider.Ready() {
+		return ProviderConfig{}, false
+	}
+	return provider, true
+}
+
+// Enabled lists the providers ready for sign-in, in a stable order.
+func (c Config) Enabled() []string {
+	out := make([]string, 0, len(providerOrder))
+	for _, name := range providerOrder {
+		if _, ok := c.Provider(name); ok {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// AllowsRegistration reports whether unknown identities may create accounts.
+func (c Config) AllowsRegistration() bool {
+	return c.Registration == RegistrationOpen
+}
@@ -0,0 +1,197 @@
+package sociallogin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
+	"gorm.io/gorm"
+)
+
+// Sign-in and linking errors.
+var (
+	// ErrEmailUnverified is returned when an unlinked identity has no verified provider email.
+	ErrEmailUnverified = errors.New("provider email is not verified")
+	// ErrAccountUnverified is returned when the matching account has not verified its email;
+	// its owner must sign in and link the provider from the profile instead.
+	ErrAccountUnverified = errors.New("an account with this email exists but its email is not verified; sign in and link the provider from your profile")
+	// ErrRegistrationClosed is returned for unknown identities when registration is closed.
+	ErrRegistrationClosed = errors.New("registration through social login is closed")
+	// ErrIdentityTaken is returned when the identity is linked to another user.
+	ErrIdentityTaken = errors.New("this provider account is linked to another user")
+	// ErrAlreadyLinked is returned when the user already linked an account at the provider.
+	ErrAlreadyLinked = errors.New("an account at this provider is already linked")
+	// ErrNotLinked is returned when unlinking a provider the user has not linked.
+	ErrNotLinked = errors.New("provider is not linked")
+)
+
+// SignIn returns the user for a provider identity: the linked user, else the user whose
+// verified email matches the provider's verified email (linking it), else a new user when
+// registration is open. created reports whether a user was created.
+func SignIn(ctx context.Context, db *gorm.DB, cfg Config, profile Profile, now time.Time) (user models.User, created bool, err error) {
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var identity models.UserIdentity
+		errFind := tx.Where("provider = ? AND subject = ?", profile.Provider, profile.Subject).First(&identity).Error
+		if errFind == nil {
+			return tx.First(&user, identity.UserID).Error
+		}
+		if !errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return errFind
+		}
+		if profile.Email == "" || !profile.EmailVerified {
+			return ErrEmailUnverified
+		}
+
+		errFind = tx.Where("email = ?", profile.Email).First(&user).Error
+		switch {
+		case errFind == nil:
+			if user.EmailVerifiedAt == nil {
+				return ErrAccountUnverified
+			}
+		case !errors.Is(errFind, gorm.ErrRecordNotFound):
+			return errFind
+		case !cfg.AllowsRegistration():
+			return ErrRegistrationClosed
+		default:
+			var errCreate error
+			if user, errCreate = createUser(tx, profile, now); errCreate != nil {
+				return errCreate
+			}
+			created = true
+		}
+		return linkIdentity(tx, user.ID, profile, now)
+	})
+	if err != nil {
+		return models.User{}, false, err
+	}
+	return user, created, nil
+}
+
+// Link attaches a provider identity to a signed-in user.
+func Link(ctx context.Context, db *gorm.DB, userID uint64, profile Profile, now time.Time) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing models.UserIdentity
+		errFind := tx.Where("provider = ? AND subject = ?", profile.Provider, profile.Subject).First(&existing).Error
+		if errFind == nil {
+			if existing.UserID == userID {
+				return nil
+			}
+			return ErrIdentityTaken
+		}
+		if !errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return errFind
+		}
+		return linkIdentity(tx, userID, profile, now)
+	})
+}
+
+// Unlink removes the user's identity at a provider.
+func Unlink(ctx context.Context, db *gorm.DB, userID uint64, provider string) error {
+	res := db.WithContext(ctx).Where("user_id = ? AND provider = ?", userID, provider).Delete(&models.UserIdentity{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotLinked
+	}
+	return nil
+}
+
+func linkIdentity(tx *gorm.DB, userID uint64, profile Profile, now time.Time) error {
+	var linked int64
+	if errCount := tx.Model(&models.UserIdentity{}).
+		Where("user_id = ? AND provider = ?", userID, profile.Provider).
+		Count(&linked).Error; errCount != nil {
+		return errCount
+	}
+	if linked > 0 {
+		return ErrAlreadyLinked
+	}
+	return tx.Create(&models.UserIdentity{
+		UserID:    userID,
+		Provider:  profile.Provider,
+		Subject:   profile.Subject,
+		Login:     profile.Login,
+		Email:     profile.Email,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}).Error
+}
+
+// createUser registers a user for a social identity with a verified email, the default user
+// group, and an unknown random password; a password can be set later through a reset.
+func createUser(tx *gorm.DB, profile Profile, now time.Time) (models.User, error) {
+	username, errName := freeUsername(tx, profile)
+	if errName != nil {
+		return models.User{}, errName
+	}
+	password, errRandom := RandomToken()
+	if errRandom != nil {
+		return models.User{}, errRandom
+	}
+	hash, errHash := security.HashPassword(password)
+	if errHash != nil {
+		return models.User{}, errHash
+	}
+	user := models.User{
+		Username:        username,
+		Name:            strings.TrimSpace(profile.Name),
+		Email:           profile.Email,
+		EmailVerifiedAt: &now,
+		Password:        hash,
+		Active:          true,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	var defaultGroup models.UserGroup
+	if errFind := tx.Where("is_default = ?", true).First(&defaultGroup).Error; errFind == nil {
+		user.UserGroupID = models.UserGroupIDs{&defaultGroup.ID}
+	} else if !errors.Is(errFind, gorm.ErrRecordNotFound) {
+		return models.User{}, errFind
+	}
+	if errCreate := tx.Create(&user).Error; errCreate != nil {
+		return models.User{}, errCreate
+	}
+	return user, nil
+}
+
+// freeUsername derives an unused username from the provider handle, adding a numeric suffix
+// on collisions and falling back to provider-subject.
+func freeUsername(tx *gorm.DB, profile Profile) (string, error) {
+	fallback := profile.Provider + "-" + profile.Subject
+	base := sanitizeUsername(profile.Login)
+	if base == "" {
+		base = fallback
+	}
+	candidates := []string{base}
+	for i := 2; i <= 20; i++ {
+		candidates = append(candidates, fmt.Sprintf("%s-%d", base, i))
+	}
+	candidates = append(candidates, fallback)
+	for _, candidate := range candidates {
+		var taken int64
+		if errCount := tx.Model(&models.User{}).Where("username = ?", candidate).Count(&taken).Error; errCount != nil {
+			return "", errCount
+		}
+		if taken == 0 {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("social login: no free username for %s", fallback)
+}
+
+// sanitizeUsername keeps letters, digits, dots, dashes and underscores.
+func sanitizeUsername(login string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(login) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
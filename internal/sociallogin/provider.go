@@ -0,0 +1,241 @@
+package sociallogin
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxResponseBytes caps provider responses read by the client.
+	maxResponseBytes = 1 << 20
+	defaultTimeout   = 10 * time.Second
+)
+
+// Provider describes the OAuth endpoints of a supported provider.
+type Provider struct {
+	Name     string
+	AuthURL  string
+	TokenURL string
+	APIURL   string // Base URL of the profile API.
+	Scopes   []string
+	profile  func(ctx context.Context, c *Client, accessToken string) (Profile, error)
+}
+
+// providerOrder lists the supported providers in display order.
+var providerOrder = []string{"github", "google"}
+
+var providers = map[string]Provider{
+	"github": {
+		Name:     "github",
+		AuthURL:  "https://github.com/login/oauth/authorize",
+		TokenURL: "https://github.com/login/oauth/access_token",
+		APIURL:   "https://api.github.com",
+		Scopes:   []string{"read:user", "user:email"},
+		profile:  githubProfile,
+	},
+	"google": {
+		Name:     "google",
+		AuthURL:  "https://accounts.google.com/o/oauth2/v2/auth",
+		TokenURL: "https://oauth2.googleapis.com/token",
+		APIURL:   "https://openidconnect.googleapis.com",
+		Scopes:   []string{"openid", "email", "profile"},
+		profile:  googleProfile,
+	},
+}
+
+// Profile is the identity a provider reports for the signed-in user.
+type Profile struct {
+	Provider      string
+	Subject       string // Stable provider account ID.
+	Login         string // Provider handle, used to derive a username.
+	Name          string
+	Email         string
+	EmailVerified bool
+}
+
+// Client runs the authorization code flow against one provider.
+type Client struct {
+	provider   Provider
+	cfg        ProviderConfig
+	httpClient *http.Client
+}
+
+// NewClient returns a client for a supported provider; a nil httpClient uses a default with
+// a short timeout.
+func NewClient(name string, cfg ProviderConfig, httpClient *http.Client) (*Client, error) {
+	provider, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("social login: unsupported provider %q", name)
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultTimeout}
+	}
+	return &Client{provider: provider, cfg: cfg, httpClient: httpClient}, nil
+}
+
+// RandomToken returns a URL-safe random string for state and PKCE verifiers.
+func RandomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, errRead := rand.Read(buf); errRead != nil {
+		return "", fmt.Errorf("social login: random token: %w", errRead)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// AuthCodeURL returns the provider authorization URL for a login attempt.
+func (c *Client) AuthCodeURL(state, verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", c.cfg.ClientID)
+	query.Set("redirect_uri", c.cfg.RedirectURL)
+	query.Set("scope", strings.Join(c.provider.Scopes, " "))
+	query.Set("state", state)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(sum[:]))
+	query.Set("code_challenge_method", "S256")
+	return c.provider.AuthURL + "?" + query.Encode()
+}
+
+// Exchange redeems an authorization code and returns the access token.
+func (c *Client) Exchange(ctx context.Context, code, verifier string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.cfg.RedirectURL)
+	form.Set("client_id", c.cfg.ClientID)
+	form.Set("client_secret", c.cfg.ClientSecret)
+	form.Set("code_verifier", verifier)
+	req, errReq := http.NewRequestWithContext(ctx, http.MethodPost, c.provider.TokenURL, strings.NewReader(form.Encode()))
+	if errReq != nil {
+		return "", fmt.Errorf("social login: build token request: %w", errReq)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var token struct {
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, errDo := c.doJSON(req, &token)
+	if errDo != nil {
+		return "", fmt.Errorf("social login: token request: %w", errDo)
+	}
+	// GitHub reports errors with status 200 and an error field.
+	if status != http.StatusOK || token.Error != "" {
+		return "", fmt.Errorf("social login: token request failed (status %d): %s %s", status, token.Error, token.ErrorDescription)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("social login: token response has no access_token")
+	}
+	return token.AccessToken, nil
+}
+
+// Profile loads the signed-in user's identity with an access token.
+func (c *Client) Profile(ctx context.Context, accessToken string) (Profile, error) {
+	profile, errProfile := c.provider.profile(ctx, c, accessToken)
+	if errProfile != nil {
+		return Profile{}, errProfile
+	}
+	profile.Provider = c.provider.Name
+	profile.Email = strings.TrimSpace(profile.Email)
+	if strings.TrimSpace(profile.Subject) == "" {
+		return Profile{}, errors.New("social login: profile has no account id")
+	}
+	return profile, nil
+}
+
+func githubProfile(ctx context.Context, c *Client, accessToken string) (Profile, error) {
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if errGet := c.getJSON(ctx, "/user", accessToken, &user); errGet != nil {
+		return Profile{}, errGet
+	}
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if errGet := c.getJSON(ctx, "/user/emails", accessToken, &emails); errGet != nil {
+		return Profile{}, errGet
+	}
+	profile := Profile{Login: user.Login, Name: user.Name}
+	if user.ID != 0 {
+		profile.Subject = strconv.FormatInt(user.ID, 10)
+	}
+	for _, email := range emails {
+		if email.Primary {
+			profile.Email, profile.EmailVerified = email.Email, email.Verified
+			break
+		}
+	}
+	return profile, nil
+}
+
+func googleProfile(ctx context.Context, c *Client, accessToken string) (Profile, error) {
+	var info struct {
+		Subject       string `json:"sub"`
+		Name          string `json:"name"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+	if errGet := c.getJSON(ctx, "/v1/userinfo", accessToken, &info); errGet != nil {
+		return Profile{}, errGet
+	}
+	login, _, _ := strings.Cut(info.Email, "@")
+	return Profile{
+		Subject:       info.Subject,
+		Login:         login,
+		Name:          info.Name,
+		Email:         info.Email,
+		EmailVerified: info.EmailVerified,
+	}, nil
+}
+
+func (c *Client) getJSON(ctx context.Context, path, accessToken string, out any) error {
+	req, errReq := http.NewRequestWithContext(ctx, http.MethodGet, c.provider.APIURL+path, nil)
+	if errReq != nil {
+		return fmt.Errorf("social login: build profile request: %w", errReq)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	status, errDo := c.doJSON(req, out)
+	if errDo != nil {
+		return fmt.Errorf("social login: profile request: %w", errDo)
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("social login: profile request failed with status %d", status)
+	}
+	return nil
+}
+
+// doJSON sends req and decodes a JSON response body into out, returning the status code.
+func (c *Client) doJSON(req *http.Request, out any) (int, error) {
+	req.Header.Set("Accept", "application/json")
+	resp, errDo := c.httpClient.Do(req)
+	if errDo != nil {
+		return 0, errDo
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, errRead := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if errRead != nil {
+		return resp.StatusCode, errRead
+	}
+	if len(body) > 0 {
+		if errUnmarshal := json.Unmarshal(body, out); errUnmarshal != nil && resp.StatusCode == http.StatusOK {
+			return resp.StatusCode, fmt.Errorf("decode response: %w", errUnmarshal)
+		}
+	}
+	return resp.StatusCode, nil
+}
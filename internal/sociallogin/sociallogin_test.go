@@ -0,0 +1,177 @@
+package sociallogin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestGitHubFlow(t *testing.T) {
+	var tokenForm url.Values
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		tokenForm = r.PostForm
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostForm.Get("code") != "good" {
+			_, _ = w.Write([]byte(`{"error":"bad_verification_code"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"gho_token","token_type":"bearer"}`))
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gho_token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":4242,"login":"octo","name":"Octo Cat"}`))
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"email":"other@example.com","primary":false,"verified":true},{"email":"octo@example.com","primary":true,"verified":true}]`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	provider := providers["github"]
+	provider.AuthURL = srv.URL + "/login/oauth/authorize"
+	provider.TokenURL = srv.URL + "/login/oauth/access_token"
+	provider.APIURL = srv.URL
+	client := &Client{
+		provider:   provider,
+		cfg:        ProviderConfig{Enabled: true, ClientID: "cid", ClientSecret: "csecret", RedirectURL: "https://app.example.com/cb"},
+		httpClient: srv.Client(),
+	}
+
+	authURL, errParse := url.Parse(client.AuthCodeURL("state-1", "verifier-1"))
+	if errParse != nil {
+		t.Fatalf("parse auth url: %v", errParse)
+	}
+	query := authURL.Query()
+	if query.Get("state") != "state-1" || query.Get("client_id") != "cid" || query.Get("code_challenge_method") != "S256" || query.Get("scope") != "read:user user:email" {
+		t.Fatalf("unexpected auth url: %s", authURL)
+	}
+
+	if _, errExchange := client.Exchange(context.Background(), "bad", "verifier-1"); errExchange == nil {
+		t.Fatal("expected exchange error for a rejected code")
+	}
+	accessToken, errExchange := client.Exchange(context.Background(), "good", "verifier-1")
+	if errExchange != nil {
+		t.Fatalf("exchange: %v", errExchange)
+	}
+	if tokenForm.Get("client_secret") != "csecret" || tokenForm.Get("code_verifier") != "verifier-1" {
+		t.Fatalf("unexpected token form: %v", tokenForm)
+	}
+	profile, errProfile := client.Profile(context.Background(), accessToken)
+	if errProfile != nil {
+		t.Fatalf("profile: %v", errProfile)
+	}
+	want := Profile{Provider: "github", Subject: "4242", Login: "octo", Name: "Octo Cat", Email: "octo@example.com", EmailVerified: true}
+	if profile != want {
+		t.Fatalf("unexpected profile: %+v", profile)
+	}
+}
+
+func TestConfigProviders(t *testing.T) {
+	cfg := Config{Providers: map[string]ProviderConfig{
+		"Google": {Enabled: true, ClientID: "id", ClientSecret: "secret", RedirectURL: "https://x/cb"},
+		"github": {Enabled: false, ClientID: "id", ClientSecret: "secret", RedirectURL: "https://x/cb"},
+		"gitlab": {Enabled: true, ClientID: "id", ClientSecret: "secret", RedirectURL: "https://x/cb"},
+	}}.withDefaults()
+	if got := cfg.Enabled(); len(got) != 1 || got[0] != "google" {
+		t.Fatalf("expected only google, got %v", got)
+	}
+	if !cfg.AllowsRegistration() {
+		t.Fatal("expected registration to default to open")
+	}
+}
+
+func setupSocialDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:sociallogin_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func TestSignInAndLink(t *testing.T) {
+	conn := setupSocialDB(t)
+	ctx := context.Background()
+	now := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	open := Config{}.withDefaults()
+	closed := Config{Registration: RegistrationClosed}.withDefaults()
+
+	verified := models.User{Username: "octo", Email: "octo@example.com", EmailVerifiedAt: &now, Password: "x", Active: true, CreatedAt: now, UpdatedAt: now}
+	unverified := models.User{Username: "mona", Email: "mona@example.com", Password: "x", Active: true, CreatedAt: now, UpdatedAt: now}
+	for _, user := range []*models.User{&verified, &unverified} {
+		if errCreate := conn.Create(user).Error; errCreate != nil {
+			t.Fatalf("create user: %v", errCreate)
+		}
+	}
+
+	// A verified email match links the existing account.
+	octo := Profile{Provider: "github", Subject: "1", Login: "octo", Email: "octo@example.com", EmailVerified: true}
+	user, created, errSignIn := SignIn(ctx, conn, closed, octo, now)
+	if errSignIn != nil || created || user.ID != verified.ID {
+		t.Fatalf("expected link to existing user, got %+v created=%v err=%v", user.ID, created, errSignIn)
+	}
+	// The linked identity signs in even without an email.
+	octo.Email, octo.EmailVerified = "", false
+	if user, _, errSignIn = SignIn(ctx, conn, closed, octo, now); errSignIn != nil || user.ID != verified.ID {
+		t.Fatalf("expected linked sign-in, got %d err=%v", user.ID, errSignIn)
+	}
+
+	mona := Profile{Provider: "google", Subject: "m", Email: "mona@example.com", EmailVerified: true}
+	if _, _, errSignIn = SignIn(ctx, conn, open, mona, now); !errors.Is(errSignIn, ErrAccountUnverified) {
+		t.Fatalf("expected unverified account conflict, got %v", errSignIn)
+	}
+	stranger := Profile{Provider: "google", Subject: "s", Login: "octo", Email: "new@example.com", EmailVerified: true}
+	if _, _, errSignIn = SignIn(ctx, conn, closed, stranger, now); !errors.Is(errSignIn, ErrRegistrationClosed) {
+		t.Fatalf("expected registration closed, got %v", errSignIn)
+	}
+	stranger.EmailVerified = false
+	if _, _, errSignIn = SignIn(ctx, conn, open, stranger, now); !errors.Is(errSignIn, ErrEmailUnverified) {
+		t.Fatalf("expected unverified provider email, got %v", errSignIn)
+	}
+
+	stranger.EmailVerified = true
+	user, created, errSignIn = SignIn(ctx, conn, open, stranger, now)
+	if errSignIn != nil || !created {
+		t.Fatalf("expected registration, got created=%v err=%v", created, errSignIn)
+	}
+	if user.Username != "octo-2" || user.Email != "new@example.com" || user.EmailVerifiedAt == nil {
+		t.Fatalf("unexpected registered user: %+v", user)
+	}
+
+	// Linking from the profile.
+	if errLink := Link(ctx, conn, unverified.ID, octo, now); !errors.Is(errLink, ErrIdentityTaken) {
+		t.Fatalf("expected identity taken, got %v", errLink)
+	}
+	if errLink := Link(ctx, conn, unverified.ID, mona, now); errLink != nil {
+		t.Fatalf("link: %v", errLink)
+	}
+	other := Profile{Provider: "google", Subject: "m2"}
+	if errLink := Link(ctx, conn, unverified.ID, other, now); !errors.Is(errLink, ErrAlreadyLinked) {
+		t.Fatalf("expected already linked, got %v", errLink)
+	}
+	if errUnlink := Unlink(ctx, conn, unverified.ID, "google"); errUnlink != nil {
+		t.Fatalf("unlink: %v", errUnlink)
+	}
+	if errUnlink := Unlink(ctx, conn, unverified.ID, "google"); !errors.Is(errUnlink, ErrNotLinked) {
+		t.Fatalf("expected not linked, got %v", errUnlink)
+	}
+}
@@ -0,0 +1,241 @@
+// Package standby shares hot runtime state with a warm standby instance for active-passive
+// deployments. The active instance periodically writes a snapshot of registered components
+// (rate-limit counters, circuit breakers, selector rotation) to a shared directory; an
+// instance that starts while the snapshot is still fresh restores it before serving, so a
+// failover neither resets limits nor reshuffles credential rotation.
+//
+// Sticky user-to-credential bindings are already stored in the database and are shared
+// without a snapshot.
+package standby
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SnapshotFile is the name of the snapshot inside the shared directory.
+const SnapshotFile = "runtime-state.json"
+
+// snapshotVersion is bumped when the snapshot layout changes incompatibly.
+const snapshotVersion = 1
+
+// ErrStale is returned by Restore when the snapshot is older than the allowed age.
+var ErrStale = errors.New("standby: snapshot is stale")
+
+// Component is runtime state that can be exported and restored on another instance.
+type Component interface {
+	ExportState(now time.Time) (json.RawMessage, error)
+	ImportState(raw json.RawMessage, now time.Time) error
+}
+
+// Snapshot is the on-disk runtime state document.
+type Snapshot struct {
+	Version    int                        `json:"version"`
+	Instance   string                     `json:"instance"`    // Host that wrote the snapshot.
+	ExportedAt time.Time                  `json:"exported_at"` // When the snapshot was taken.
+	Components map[string]json.RawMessage `json:"components"`  // State per registered component name.
+}
+
+// Registry holds the components included in snapshots.
+type Registry struct {
+	mu         sync.Mutex
+	components map[string]Component
+}
+
+// NewRegistry constructs an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{components: make(map[string]Component)}
+}
+
+// Register adds or replaces a named component; nil components are ignored.
+func (r *Registry) Register(name string, component Component) {
+	if r == nil || name == "" || component == nil {
+		return
+	}
+	r.mu.Lock()
+	r.components[name] = component
+	r.mu.Unlock()
+}
+
+// names returns the registered component names in a stable order.
+func (r *Registry) names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]string, 0, len(r.components))
+	for name := range r.components {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (r *Registry) component(name string) Component {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.components[name]
+}
+
+// Snapshot exports every registered component; components that fail are logged and skipped.
+func (r *Registry) Snapshot(now time.Time) Snapshot {
+	instance, _ := os.Hostname()
+	snap := Snapshot{
+		Version:    snapshotVersion,
+		Instance:   instance,
+		ExportedAt: now.UTC(),
+		Components: make(map[string]json.RawMessage),
+	}
+	for _, name := range r.names() {
+		raw, errExport := r.component(name).ExportState(now)
+		if errExport != nil {
+			log.WithError(errExport).Warnf("standby: export %s failed", name)
+			continue
+		}
+		snap.Components[name] = raw
+	}
+	return snap
+}
+
+// Apply imports each component present in snap and returns the names restored.
+func (r *Registry) Apply(snap Snapshot, now time.Time) []string {
+	restored := make([]string, 0, len(snap.Components))
+	for _, name := range r.names() {
+		raw, ok := snap.Components[name]
+		if !ok {
+			continue
+		}
+		if errImport := r.component(name).ImportState(raw, now); errImport != nil {
+			log.WithError(errImport).Warnf("standby: restore %s failed", name)
+			continue
+		}
+		restored = append(restored, name)
+	}
+	return restored
+}
+
+// WriteSnapshot atomically replaces the snapshot in dir.
+func WriteSnapshot(dir string, snap Snapshot) error {
+	data, errMarshal := json.Marshal(snap)
+	if errMarshal != nil {
+		return fmt.Errorf("standby: marshal snapshot: %w", errMarshal)
+	}
+	if errMkdir := os.MkdirAll(dir, 0o700); errMkdir != nil {
+		return fmt.Errorf("standby: create dir: %w", errMkdir)
+	}
+	tmp, errTemp := os.CreateTemp(dir, SnapshotFile+".*.tmp")
+	if errTemp != nil {
+		return fmt.Errorf("standby: create temp file: %w", errTemp)
+	}
+	tmpName := tmp.Name()
+	defer func() { _ = os.Remove(tmpName) }()
+	if _, errWrite := tmp.Write(data); errWrite != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("standby: write snapshot: %w", errWrite)
+	}
+	if errSync := tmp.Sync(); errSync != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("standby: sync snapshot: %w", errSync)
+	}
+	if errClose := tmp.Close(); errClose != nil {
+		return fmt.Errorf("standby: close snapshot: %w", errClose)
+	}
+	if errRename := os.Rename(tmpName, filepath.Join(dir, SnapshotFile)); errRename != nil {
+		return fmt.Errorf("standby: replace snapshot: %w", errRename)
+	}
+	return nil
+}
+
+// ReadSnapshot loads the snapshot in dir.
+func ReadSnapshot(dir string) (Snapshot, error) {
+	var snap Snapshot
+	data, errRead := os.ReadFile(filepath.Join(dir, SnapshotFile))
+	if errRead != nil {
+		return snap, errRead
+	}
+	if errUnmarshal := json.Unmarshal(data, &snap); errUnmarshal != nil {
+		return snap, fmt.Errorf("standby: parse snapshot: %w", errUnmarshal)
+	}
+	if snap.Version != snapshotVersion {
+		return snap, fmt.Errorf("standby: unsupported snapshot version %d", snap.Version)
+	}
+	return snap, nil
+}
+
+// Restore applies the snapshot in dir when it is younger than maxAge. A missing snapshot is
+// not an error and restores nothing.
+func Restore(registry *Registry, dir string, maxAge time.Duration, now time.Time) (Snapshot, []string, error) {
+	snap, errRead := ReadSnapshot(dir)
+	if errRead != nil {
+		if errors.Is(errRead, os.ErrNotExist) {
+			return Snapshot{}, nil, nil
+		}
+		return Snapshot{}, nil, errRead
+	}
+	if maxAge > 0 && now.Sub(snap.ExportedAt) > maxAge {
+		return snap, nil, ErrStale
+	}
+	return snap, registry.Apply(snap, now), nil
+}
+
+// Exporter periodically writes the registry snapshot to the shared directory.
+type Exporter struct {
+	registry *Registry
+	dir      string
+	interval time.Duration
+	now      func() time.Time
+}
+
+// NewExporter constructs an exporter; returns nil when dir is empty.
+func NewExporter(registry *Registry, dir string, interval time.Duration) *Exporter {
+	if registry == nil || dir == "" {
+		return nil
+	}
+	return &Exporter{registry: registry, dir: dir, interval: interval, now: time.Now}
+}
+
+// Start launches the export loop; a final snapshot is written when ctx is done so a clean
+// shutdown hands over the latest state.
+func (e *Exporter) Start(ctx context.Context) {
+	if e == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go e.run(ctx)
+	log.Infof("standby state exporter started (dir=%s, interval=%s)", e.dir, e.interval)
+}
+
+func (e *Exporter) run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if errExport := e.ExportOnce(); errExport != nil {
+				log.WithError(errExport).Warn("standby: final export failed")
+			}
+			return
+		case <-ticker.C:
+			if errExport := e.ExportOnce(); errExport != nil {
+				log.WithError(errExport).Warn("standby: export failed")
+			}
+		}
+	}
+}
+
+// ExportOnce writes one snapshot.
+func (e *Exporter) ExportOnce() error {
+	if e == nil {
+		return nil
+	}
+	return WriteSnapshot(e.dir, e.registry.Snapshot(e.now()))
+}
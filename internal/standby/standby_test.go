@@ -0,0 +1,93 @@
+package standby
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/ratelimit"
+)
+
+// memorySettings keeps the rate limit manager on the in-memory backend.
+func memorySettings() ratelimit.SettingsConfig { return ratelimit.SettingsConfig{} }
+
+func TestRateLimitStateSurvivesFailover(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	active := ratelimit.NewManager(memorySettings, clock, nil)
+	for i := 0; i < 2; i++ {
+		if result, _ := active.Allow(context.Background(), "user:1", 3); !result.Allowed {
+			t.Fatalf("request %d unexpectedly limited", i)
+		}
+	}
+
+	activeRegistry := NewRegistry()
+	activeRegistry.Register("rate_limit", active)
+	dir := t.TempDir()
+	exporter := NewExporter(activeRegistry, dir, time.Second)
+	exporter.now = clock
+	if errExport := exporter.ExportOnce(); errExport != nil {
+		t.Fatalf("ExportOnce: %v", errExport)
+	}
+
+	standbyManager := ratelimit.NewManager(memorySettings, clock, nil)
+	standbyRegistry := NewRegistry()
+	standbyRegistry.Register("rate_limit", standbyManager)
+	snap, restored, errRestore := Restore(standbyRegistry, dir, time.Minute, now)
+	if errRestore != nil || len(restored) != 1 || snap.Version != snapshotVersion {
+		t.Fatalf("Restore = %+v, %v, %v", snap, restored, errRestore)
+	}
+
+	if result, _ := standbyManager.Allow(context.Background(), "user:1", 3); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("expected the third request to use the last slot, got %+v", result)
+	}
+	if result, _ := standbyManager.Allow(context.Background(), "user:1", 3); result.Allowed {
+		t.Fatal("expected the restored counter to keep enforcing the limit")
+	}
+}
+
+func TestRestoreSkipsStaleAndMissingSnapshots(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	registry := NewRegistry()
+	if _, restored, errMissing := Restore(registry, dir, time.Minute, time.Now()); errMissing != nil || restored != nil {
+		t.Fatalf("expected missing snapshot to restore nothing, got %v, %v", restored, errMissing)
+	}
+
+	exportedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	snap := Snapshot{Version: snapshotVersion, ExportedAt: exportedAt, Components: map[string]json.RawMessage{}}
+	if errWrite := WriteSnapshot(dir, snap); errWrite != nil {
+		t.Fatalf("WriteSnapshot: %v", errWrite)
+	}
+	if _, _, errStale := Restore(registry, dir, time.Minute, exportedAt.Add(2*time.Minute)); !errors.Is(errStale, ErrStale) {
+		t.Fatalf("expected ErrStale, got %v", errStale)
+	}
+}
+
+type failingComponent struct{}
+
+func (failingComponent) ExportState(time.Time) (json.RawMessage, error) {
+	return nil, errors.New("boom")
+}
+
+func (failingComponent) ImportState(json.RawMessage, time.Time) error { return nil }
+
+func TestSnapshotSkipsFailingComponents(t *testing.T) {
+	t.Parallel()
+
+	registry := NewRegistry()
+	registry.Register("broken", failingComponent{})
+	registry.Register("rate_limit", ratelimit.NewManager(memorySettings, time.Now, nil))
+	snap := registry.Snapshot(time.Now())
+	if _, ok := snap.Components["broken"]; ok {
+		t.Fatal("expected failing component to be skipped")
+	}
+	if _, ok := snap.Components["rate_limit"]; !ok {
+		t.Fatal("expected rate_limit component to be exported")
+	}
+}
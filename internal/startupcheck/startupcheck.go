@@ -0,0 +1,176 @@
+// Package startupcheck validates critical settings and secrets at boot so misconfiguration
+// is reported up front instead of surfacing as obscure runtime errors.
+package startupcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/crypto"
+	"gorm.io/gorm"
+)
+
+// Issue severities.
+const (
+	// SeverityFatal issues stop the server from starting.
+	SeverityFatal = "fatal"
+	// SeverityDegraded issues let the server start with admin writes disabled.
+	SeverityDegraded = "degraded"
+)
+
+// Issue codes.
+const (
+	IssueJWTSecret     = "jwt_secret"
+	IssueEncryptionKey = "encryption_key"
+	IssueConfigPath    = "config_path"
+)
+
+// insecureJWTSecrets lists placeholder secrets shipped in examples and fallbacks.
+var insecureJWTSecrets = map[string]struct{}{
+	"insecure-jwt-secret-change-me":       {},
+	"change-me-to-a-secure-random-string": {},
+	"secret":                              {},
+	"changeme":                            {},
+}
+
+// Issue describes one failed check.
+type Issue struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// Report holds the outcome of a startup check.
+type Report struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Issues    []Issue   `json:"issues"`
+}
+
+// Ready reports whether every check passed.
+func (r Report) Ready() bool { return len(r.Issues) == 0 }
+
+// ReadOnly reports whether the server must refuse admin writes.
+func (r Report) ReadOnly() bool {
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityDegraded {
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns the fatal issues as one error, or nil when the server may start.
+func (r Report) Err() error {
+	var messages []string
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityFatal {
+			messages = append(messages, issue.Message)
+		}
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	return errors.New("startup check failed: " + strings.Join(messages, "; "))
+}
+
+// Input carries the resolved settings to validate.
+type Input struct {
+	ConfigPath    string // Resolved config file path.
+	JWTSecret     string // Effective JWT signing secret.
+	EncryptionKey bool   // Whether a master encryption key is configured.
+}
+
+// Run validates the settings and the database state they depend on.
+func Run(ctx context.Context, db *gorm.DB, in Input) Report {
+	report := Report{CheckedAt: time.Now().UTC(), Issues: []Issue{}}
+
+	secret := strings.TrimSpace(in.JWTSecret)
+	if secret == "" {
+		report.Issues = append(report.Issues, Issue{Code: IssueJWTSecret, Severity: SeverityFatal, Message: "jwt secret is empty (set `jwt.secret` or JWT_SECRET)"})
+	} else if _, insecure := insecureJWTSecrets[strings.ToLower(secret)]; insecure {
+		report.Issues = append(report.Issues, Issue{Code: IssueJWTSecret, Severity: SeverityFatal, Message: "jwt secret is a well-known default, replace it with a random value"})
+	}
+
+	if !in.EncryptionKey && db != nil {
+		encrypted, errCount := hasEncryptedRows(ctx, db)
+		switch {
+		case errCount != nil:
+			report.Issues = append(report.Issues, Issue{Code: IssueEncryptionKey, Severity: SeverityDegraded, Message: fmt.Sprintf("check encrypted rows: %v", errCount)})
+		case encrypted:
+			report.Issues = append(report.Issues, Issue{Code: IssueEncryptionKey, Severity: SeverityDegraded, Message: "encrypted secrets exist but no encryption key is configured (set `encryption.key` or ENCRYPTION_KEY)"})
+		}
+	}
+
+	if errWritable := checkWritable(in.ConfigPath); errWritable != nil {
+		report.Issues = append(report.Issues, Issue{Code: IssueConfigPath, Severity: SeverityDegraded, Message: errWritable.Error()})
+	}
+	return report
+}
+
+// hasEncryptedRows reports whether any auth content or provider key is stored encrypted.
+func hasEncryptedRows(ctx context.Context, db *gorm.DB) (bool, error) {
+	var count int64
+	if errCount := db.WithContext(ctx).Table("auths").
+		Where("CAST(content AS TEXT) LIKE ?", "%\""+crypto.EnvelopeField+"\"%").
+		Count(&count).Error; errCount != nil {
+		return false, errCount
+	}
+	if count > 0 {
+		return true, nil
+	}
+	if errCount := db.WithContext(ctx).Table("provider_api_keys").
+		Where("api_key LIKE ? OR CAST(api_key_entries AS TEXT) LIKE ?", crypto.EncryptedPrefix+"%", "%"+crypto.EncryptedPrefix+"%").
+		Count(&count).Error; errCount != nil {
+		return false, errCount
+	}
+	return count > 0, nil
+}
+
+// checkWritable verifies the config file, or its directory when the file is missing, accepts writes.
+func checkWritable(path string) error {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return errors.New("config path is empty")
+	}
+	info, errStat := os.Stat(path)
+	switch {
+	case errStat == nil && info.IsDir():
+		return fmt.Errorf("config path %s is a directory", path)
+	case errStat == nil:
+		file, errOpen := os.OpenFile(path, os.O_WRONLY, 0)
+		if errOpen != nil {
+			return fmt.Errorf("config path %s is not writable: %v", path, errOpen)
+		}
+		return file.Close()
+	case !os.IsNotExist(errStat):
+		return fmt.Errorf("stat config path %s: %v", path, errStat)
+	}
+	probe, errCreate := os.CreateTemp(filepath.Dir(path), ".cpab-write-check-*")
+	if errCreate != nil {
+		return fmt.Errorf("config directory %s is not writable: %v", filepath.Dir(path), errCreate)
+	}
+	name := probe.Name()
+	_ = probe.Close()
+	return os.Remove(name)
+}
+
+var current atomic.Pointer[Report]
+
+// SetCurrent publishes the report served by readiness checks.
+func SetCurrent(report Report) {
+	current.Store(&report)
+}
+
+// Current returns the published report; an empty report when no check has run.
+func Current() Report {
+	if report := current.Load(); report != nil {
+		return *report
+	}
+	return Report{Issues: []Issue{}}
+}
@@ -0,0 +1,69 @@
+package startupcheck
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func setupStartupCheckDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:startupcheck_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func TestRunReportsFatalAndDegradedIssues(t *testing.T) {
+	conn := setupStartupCheckDB(t)
+	ctx := context.Background()
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if errWrite := os.WriteFile(configPath, []byte("port: 8318\n"), 0o600); errWrite != nil {
+		t.Fatalf("write config: %v", errWrite)
+	}
+
+	report := Run(ctx, conn, Input{ConfigPath: configPath, JWTSecret: "s3cr3t-value-from-the-generator"})
+	if !report.Ready() || report.Err() != nil || report.ReadOnly() {
+		t.Fatalf("expected a clean report, got %+v", report)
+	}
+
+	report = Run(ctx, conn, Input{ConfigPath: configPath, JWTSecret: "insecure-jwt-secret-change-me"})
+	if report.Err() == nil || report.ReadOnly() || report.Issues[0].Code != IssueJWTSecret {
+		t.Fatalf("expected a fatal jwt secret issue, got %+v", report)
+	}
+
+	auth := models.Auth{Key: "sealed", Content: datatypes.JSON(`{"type":"codex"}`)}
+	if errCreate := conn.Create(&auth).Error; errCreate != nil {
+		t.Fatalf("create auth: %v", errCreate)
+	}
+	// Bypass the model hooks, which refuse to persist sealed content without a key.
+	if errExec := conn.Exec("UPDATE auths SET content = ? WHERE id = ?", `{"$enc":"enc:v1:primary:abc"}`, auth.ID).Error; errExec != nil {
+		t.Fatalf("seal auth content: %v", errExec)
+	}
+	report = Run(ctx, conn, Input{ConfigPath: filepath.Join(configPath, "nested.yaml"), JWTSecret: "s3cr3t-value-from-the-generator"})
+	if report.Err() != nil || !report.ReadOnly() || len(report.Issues) != 2 {
+		t.Fatalf("expected degraded encryption and config issues, got %+v", report)
+	}
+	if report.Issues[0].Code != IssueEncryptionKey || report.Issues[1].Code != IssueConfigPath {
+		t.Fatalf("unexpected issue codes: %+v", report.Issues)
+	}
+
+	report = Run(ctx, conn, Input{ConfigPath: configPath, JWTSecret: "s3cr3t-value-from-the-generator", EncryptionKey: true})
+	if !report.Ready() {
+		t.Fatalf("expected a configured key to clear the encryption issue, got %+v", report)
+	}
+}
@@ -0,0 +1,206 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HourlyCursor names the cursor tracking finalized usage_hourly buckets.
+const HourlyCursor = "usage_hourly"
+
+const (
+	defaultInterval = time.Minute
+	// defaultSettle keeps an hour open long enough for the async usage writer to flush it.
+	defaultSettle = 5 * time.Minute
+	// defaultBackfillDays covers this month and last month for dashboard trends.
+	defaultBackfillDays = 62
+)
+
+// Aggregator periodically folds finished hours of usages into usage_hourly.
+type Aggregator struct {
+	db           *gorm.DB
+	interval     time.Duration
+	settle       time.Duration
+	backfillDays int
+}
+
+// NewAggregator constructs a stats aggregator; returns nil when db is nil.
+func NewAggregator(db *gorm.DB) *Aggregator {
+	if db == nil {
+		return nil
+	}
+	return &Aggregator{db: db, interval: defaultInterval, settle: defaultSettle, backfillDays: defaultBackfillDays}
+}
+
+// Start launches the aggregation loop in a background goroutine.
+func (a *Aggregator) Start(ctx context.Context) {
+	if a == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go a.run(ctx)
+	log.Infof("stats aggregator started (interval=%s)", a.interval)
+}
+
+func (a *Aggregator) run(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		if errRun := a.RunOnce(ctx, time.Now()); errRun != nil {
+			log.WithError(errRun).Warn("stats aggregator: run failed")
+		}
+		timer := time.NewTimer(a.interval)
+		select {
+		case <-ctx.Done():
+			if !timer.Stop() {
+				<-timer.C
+			}
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// RunOnce aggregates every hour between the cursor and the last settled hour, advancing
+// the cursor after each one. Without a cursor it backfills from backfillDays ago.
+func (a *Aggregator) RunOnce(ctx context.Context, now time.Time) error {
+	if a == nil || a.db == nil {
+		return nil
+	}
+	through, errCursor := Cursor(ctx, a.db)
+	if errCursor != nil {
+		return errCursor
+	}
+	hour := HourStart(now.AddDate(0, 0, -a.backfillDays))
+	if !through.IsZero() {
+		hour = HourStart(through)
+	}
+	limit := HourStart(now.Add(-a.settle))
+	for hour.Before(limit) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errHour := AggregateHour(ctx, a.db, hour); errHour != nil {
+			return errHour
+		}
+		hour = hour.Add(time.Hour)
+	}
+	return nil
+}
+
+// HourStart returns the start of the local hour containing t.
+func HourStart(t time.Time) time.Time {
+	local := t.In(time.Local)
+	return time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, time.Local)
+}
+
+// Cursor returns the instant before which usage_hourly buckets are final, or the zero
+// time when nothing has been aggregated yet.
+func Cursor(ctx context.Context, db *gorm.DB) (time.Time, error) {
+	var cursor models.StatsCursor
+	errFind := db.WithContext(ctx).Where("name = ?", HourlyCursor).Take(&cursor).Error
+	if errors.Is(errFind, gorm.ErrRecordNotFound) {
+		return time.Time{}, nil
+	}
+	if errFind != nil {
+		return time.Time{}, fmt.Errorf("stats: load cursor: %w", errFind)
+	}
+	return cursor.Through, nil
+}
+
+// AggregateHour rebuilds the usage_hourly buckets of the hour starting at hour and moves
+// the cursor past it in one transaction.
+func AggregateHour(ctx context.Context, db *gorm.DB, hour time.Time) error {
+	start := HourStart(hour)
+	end := start.Add(time.Hour)
+	label := start.Format(time.DateTime)
+
+	var buckets []struct {
+		Provider         string
+		Model            string
+		UserID           uint64
+		Requests         int64
+		FailedRequests   int64
+		TotalTokens      int64
+		CachedTokens     int64
+		CostMicros       int64
+		EnergyMilliWh    int64
+		CarbonMilligrams int64
+		DurationMillis   float64
+	}
+	if errScan := db.WithContext(ctx).
+		Model(&models.Usage{}).
+		Where("requested_at >= ? AND requested_at < ?", start.UTC(), end.UTC()).
+		Select(`
+			provider,
+			model,
+			COALESCE(user_id, 0) AS user_id,
+			COUNT(*) AS requests,
+			COALESCE(SUM(CASE WHEN failed THEN 1 ELSE 0 END), 0) AS failed_requests,
+			COALESCE(SUM(total_tokens), 0) AS total_tokens,
+			COALESCE(SUM(cached_tokens), 0) AS cached_tokens,
+			COALESCE(SUM(cost_micros), 0) AS cost_micros,
+			COALESCE(SUM(energy_milli_wh), 0) AS energy_milli_wh,
+			COALESCE(SUM(carbon_milligrams), 0) AS carbon_milligrams,
+			COALESCE(SUM(` + dbutil.DurationMillisExpr(db, "requested_at", "created_at") + `), 0) AS duration_millis
+		`).
+		Group("provider, model, COALESCE(user_id, 0)").
+		Scan(&buckets).Error; errScan != nil {
+		return fmt.Errorf("stats: aggregate %s: %w", label, errScan)
+	}
+
+	now := time.Now().UTC()
+	rows := make([]models.UsageHourly, 0, len(buckets))
+	for _, b := range buckets {
+		rows = append(rows, models.UsageHourly{
+			Hour:             start.UTC(),
+			Provider:         b.Provider,
+			Model:            b.Model,
+			UserID:           b.UserID,
+			Requests:         b.Requests,
+			FailedRequests:   b.FailedRequests,
+			TotalTokens:      b.TotalTokens,
+			CachedTokens:     b.CachedTokens,
+			CostMicros:       b.CostMicros,
+			EnergyMilliWh:    b.EnergyMilliWh,
+			CarbonMilligrams: b.CarbonMilligrams,
+			DurationMillis:   int64(b.DurationMillis),
+			CreatedAt:        now,
+			UpdatedAt:        now,
+		})
+	}
+
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if errDelete := tx.Where("hour = ?", start.UTC()).Delete(&models.UsageHourly{}).Error; errDelete != nil {
+			return fmt.Errorf("clear buckets: %w", errDelete)
+		}
+		if len(rows) > 0 {
+			if errCreate := tx.CreateInBatches(&rows, 200).Error; errCreate != nil {
+				return fmt.Errorf("insert buckets: %w", errCreate)
+			}
+		}
+		cursor := models.StatsCursor{Name: HourlyCursor, Through: end.UTC(), UpdatedAt: now}
+		if errCursor := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"through", "updated_at"}),
+		}).Create(&cursor).Error; errCursor != nil {
+			return fmt.Errorf("advance cursor: %w", errCursor)
+		}
+		return nil
+	})
+	if errTx != nil {
+		return fmt.Errorf("stats: aggregate %s: %w", label, errTx)
+	}
+	return nil
+}
@@ -0,0 +1,365 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// Totals aggregates usage over a time range.
+type Totals struct {
+	Requests         int64
+	FailedRequests   int64
+	ActiveUsers      int64
+	TotalTokens      int64
+	CachedTokens     int64
+	CostMicros       int64
+	EnergyMilliWh    int64
+	CarbonMilligrams int64
+	DurationMillis   int64
+}
+
+// AvgDurationMillis returns the mean request duration in milliseconds.
+func (t Totals) AvgDurationMillis() float64 {
+	if t.Requests == 0 {
+		return 0
+	}
+	return float64(t.DurationMillis) / float64(t.Requests)
+}
+
+// HourPoint holds request counts for one local hour.
+type HourPoint struct {
+	Hour           time.Time
+	Requests       int64
+	FailedRequests int64
+}
+
+// ModelTotals holds usage aggregated for one model.
+type ModelTotals struct {
+	Model            string
+	Requests         int64
+	CostMicros       int64
+	EnergyMilliWh    int64
+	CarbonMilligrams int64
+}
+
+// organizationUsersCondition keeps rows of one organization's users.
+const organizationUsersCondition = "user_id IN (SELECT id FROM users WHERE organization_id = ?)"
+
+// Scope restricts queries to part of the usage. The zero value covers every user.
+type Scope struct {
+	OrganizationID uint64 // Only users of this organization when non-zero.
+}
+
+// condition returns the raw SQL form of apply, to append to a WHERE clause.
+func (s Scope) condition() string {
+	if s.OrganizationID == 0 {
+		return ""
+	}
+	return " AND " + organizationUsersCondition
+}
+
+// args appends the arguments of condition to args.
+func (s Scope) args(args ...any) []any {
+	if s.OrganizationID == 0 {
+		return args
+	}
+	return append(args, s.OrganizationID)
+}
+
+// apply restricts q, whose table has a user_id column, to the scope.
+func (s Scope) apply(q *gorm.DB) *gorm.DB {
+	if s.OrganizationID == 0 {
+		return q
+	}
+	return q.Where(organizationUsersCondition, s.OrganizationID)
+}
+
+// span is a half-open time range [from, to).
+type span struct {
+	from time.Time
+	to   time.Time
+}
+
+func (s span) empty() bool {
+	return !s.from.Before(s.to)
+}
+
+// split divides [from, to) into the part covered by usage_hourly and the part that must
+// still be read from raw usages.
+func split(ctx context.Context, db *gorm.DB, from, to time.Time) (span, span, error) {
+	through, errCursor := Cursor(ctx, db)
+	if errCursor != nil {
+		return span{}, span{}, errCursor
+	}
+	rolled := span{from: from, to: to}
+	if through.Before(rolled.to) {
+		rolled.to = through
+	}
+	raw := span{from: from, to: to}
+	if through.After(raw.from) {
+		raw.from = through
+	}
+	return rolled, raw, nil
+}
+
+// totalsSelect sums the counters shared by usage_hourly and usages.
+const totalsSelect = `
+	COALESCE(SUM(cost_micros), 0) AS cost_micros,
+	COALESCE(SUM(total_tokens), 0) AS total_tokens,
+	COALESCE(SUM(cached_tokens), 0) AS cached_tokens,
+	COALESCE(SUM(energy_milli_wh), 0) AS energy_milli_wh,
+	COALESCE(SUM(carbon_milligrams), 0) AS carbon_milligrams`
+
+// QueryTotals returns usage totals of scope for [from, to), reading finalized hours from
+// usage_hourly and anything newer from raw usages.
+func QueryTotals(ctx context.Context, db *gorm.DB, from, to time.Time, scope Scope) (Totals, error) {
+	var out Totals
+	rolled, raw, errSplit := split(ctx, db, from, to)
+	if errSplit != nil {
+		return out, errSplit
+	}
+
+	type sums struct {
+		Requests         int64
+		FailedRequests   int64
+		TotalTokens      int64
+		CachedTokens     int64
+		CostMicros       int64
+		EnergyMilliWh    int64
+		CarbonMilligrams int64
+		DurationMillis   float64
+	}
+	add := func(s sums) {
+		out.Requests += s.Requests
+		out.FailedRequests += s.FailedRequests
+		out.TotalTokens += s.TotalTokens
+		out.CachedTokens += s.CachedTokens
+		out.CostMicros += s.CostMicros
+		out.EnergyMilliWh += s.EnergyMilliWh
+		out.CarbonMilligrams += s.CarbonMilligrams
+		out.DurationMillis += int64(s.DurationMillis)
+	}
+
+	var userParts []string
+	var userArgs []any
+	if !rolled.empty() {
+		var s sums
+		if errScan := scope.apply(db.WithContext(ctx).
+			Model(&models.UsageHourly{}).
+			Where("hour >= ? AND hour < ?", rolled.from.UTC(), rolled.to.UTC())).
+			Select(`
+				COALESCE(SUM(requests), 0) AS requests,
+				COALESCE(SUM(failed_requests), 0) AS failed_requests,
+				COALESCE(SUM(duration_millis), 0) AS duration_millis,` + totalsSelect).
+			Scan(&s).Error; errScan != nil {
+			return out, fmt.Errorf("stats: query hourly totals: %w", errScan)
+		}
+		add(s)
+		userParts = append(userParts, "SELECT DISTINCT user_id FROM usage_hourly WHERE hour >= ? AND hour < ? AND user_id <> 0"+scope.condition())
+		userArgs = append(userArgs, scope.args(rolled.from.UTC(), rolled.to.UTC())...)
+	}
+	if !raw.empty() {
+		var s sums
+		if errScan := scope.apply(db.WithContext(ctx).
+			Model(&models.Usage{}).
+			Where("requested_at >= ? AND requested_at < ?", raw.from.UTC(), raw.to.UTC())).
+			Select(`
+				COUNT(*) AS requests,
+				COALESCE(SUM(CASE WHEN failed THEN 1 ELSE 0 END), 0) AS failed_requests,
+				COALESCE(SUM(` + dbutil.DurationMillisExpr(db, "requested_at", "created_at") + `), 0) AS duration_millis,` + totalsSelect).
+			Scan(&s).Error; errScan != nil {
+			return out, fmt.Errorf("stats: query raw totals: %w", errScan)
+		}
+		add(s)
+		userParts = append(userParts, "SELECT DISTINCT user_id FROM usages WHERE requested_at >= ? AND requested_at < ? AND user_id IS NOT NULL"+scope.condition())
+		userArgs = append(userArgs, scope.args(raw.from.UTC(), raw.to.UTC())...)
+	}
+
+	if len(userParts) > 0 {
+		// UNION removes users active in both the rolled-up and the raw range.
+		query := "SELECT COUNT(*) FROM (" + strings.Join(userParts, " UNION ") + ") AS active_users"
+		if errCount := db.WithContext(ctx).Raw(query, userArgs...).Scan(&out.ActiveUsers).Error; errCount != nil {
+			return out, fmt.Errorf("stats: count active users: %w", errCount)
+		}
+	}
+	return out, nil
+}
+
+// QueryUserRequests returns one user's request and failure counts for [from, to), reading
+// finalized hours from usage_hourly and anything newer from raw usages.
+func QueryUserRequests(ctx context.Context, db *gorm.DB, userID uint64, from, to time.Time) (int64, int64, error) {
+	rolled, raw, errSplit := split(ctx, db, from, to)
+	if errSplit != nil {
+		return 0, 0, errSplit
+	}
+	var requests, failed int64
+	type counts struct {
+		Requests       int64
+		FailedRequests int64
+	}
+	if !rolled.empty() {
+		var row counts
+		if errScan := db.WithContext(ctx).
+			Model(&models.UsageHourly{}).
+			Where("user_id = ? AND hour >= ? AND hour < ?", userID, rolled.from.UTC(), rolled.to.UTC()).
+			Select("COALESCE(SUM(requests), 0) AS requests, COALESCE(SUM(failed_requests), 0) AS failed_requests").
+			Scan(&row).Error; errScan != nil {
+			return 0, 0, fmt.Errorf("stats: query hourly user requests: %w", errScan)
+		}
+		requests += row.Requests
+		failed += row.FailedRequests
+	}
+	if !raw.empty() {
+		var row counts
+		if errScan := db.WithContext(ctx).
+			Model(&models.Usage{}).
+			Where("user_id = ? AND requested_at >= ? AND requested_at < ?", userID, raw.from.UTC(), raw.to.UTC()).
+			Select("COUNT(*) AS requests, COALESCE(SUM(CASE WHEN failed THEN 1 ELSE 0 END), 0) AS failed_requests").
+			Scan(&row).Error; errScan != nil {
+			return 0, 0, fmt.Errorf("stats: query raw user requests: %w", errScan)
+		}
+		requests += row.Requests
+		failed += row.FailedRequests
+	}
+	return requests, failed, nil
+}
+
+// QueryHourly returns one point of scope per local hour in [from, to).
+func QueryHourly(ctx context.Context, db *gorm.DB, from, to time.Time, scope Scope) ([]HourPoint, error) {
+	rolled, raw, errSplit := split(ctx, db, from, to)
+	if errSplit != nil {
+		return nil, errSplit
+	}
+
+	byHour := make(map[int64]*HourPoint)
+	var points []HourPoint
+	for hour := HourStart(from); hour.Before(to); hour = hour.Add(time.Hour) {
+		points = append(points, HourPoint{Hour: hour})
+	}
+	for i := range points {
+		byHour[points[i].Hour.Unix()] = &points[i]
+	}
+
+	if !rolled.empty() {
+		var rows []struct {
+			Hour           time.Time
+			Requests       int64
+			FailedRequests int64
+		}
+		if errScan := scope.apply(db.WithContext(ctx).
+			Model(&models.UsageHourly{}).
+			Where("hour >= ? AND hour < ?", rolled.from.UTC(), rolled.to.UTC())).
+			Select("hour, COALESCE(SUM(requests), 0) AS requests, COALESCE(SUM(failed_requests), 0) AS failed_requests").
+			Group("hour").
+			Scan(&rows).Error; errScan != nil {
+			return nil, fmt.Errorf("stats: query hourly points: %w", errScan)
+		}
+		for _, row := range rows {
+			if point, ok := byHour[HourStart(row.Hour).Unix()]; ok {
+				point.Requests += row.Requests
+				point.FailedRequests += row.FailedRequests
+			}
+		}
+	}
+	// The raw range is normally just the current hour; later hours cannot have usage yet.
+	rawEnd := raw.to
+	if next := HourStart(time.Now()).Add(time.Hour); next.Before(rawEnd) {
+		rawEnd = next
+	}
+	for hour := HourStart(raw.from); hour.Before(rawEnd); hour = hour.Add(time.Hour) {
+		start, end := hour, hour.Add(time.Hour)
+		if start.Before(raw.from) {
+			start = raw.from
+		}
+		if end.After(raw.to) {
+			end = raw.to
+		}
+		var row struct {
+			Requests       int64
+			FailedRequests int64
+		}
+		if errScan := scope.apply(db.WithContext(ctx).
+			Model(&models.Usage{}).
+			Where("requested_at >= ? AND requested_at < ?", start.UTC(), end.UTC())).
+			Select("COUNT(*) AS requests, COALESCE(SUM(CASE WHEN failed THEN 1 ELSE 0 END), 0) AS failed_requests").
+			Scan(&row).Error; errScan != nil {
+			return nil, fmt.Errorf("stats: query raw points: %w", errScan)
+		}
+		if point, ok := byHour[hour.Unix()]; ok {
+			point.Requests += row.Requests
+			point.FailedRequests += row.FailedRequests
+		}
+	}
+	return points, nil
+}
+
+// QueryByModel returns usage of scope for [from, to) grouped by model, most expensive first.
+func QueryByModel(ctx context.Context, db *gorm.DB, from, to time.Time, scope Scope) ([]ModelTotals, error) {
+	rolled, raw, errSplit := split(ctx, db, from, to)
+	if errSplit != nil {
+		return nil, errSplit
+	}
+
+	const selectByModel = `model,
+		COALESCE(SUM(cost_micros), 0) AS cost_micros,
+		COALESCE(SUM(energy_milli_wh), 0) AS energy_milli_wh,
+		COALESCE(SUM(carbon_milligrams), 0) AS carbon_milligrams`
+	byModel := make(map[string]*ModelTotals)
+	merge := func(rows []ModelTotals) {
+		for _, row := range rows {
+			entry, ok := byModel[row.Model]
+			if !ok {
+				entry = &ModelTotals{Model: row.Model}
+				byModel[row.Model] = entry
+			}
+			entry.Requests += row.Requests
+			entry.CostMicros += row.CostMicros
+			entry.EnergyMilliWh += row.EnergyMilliWh
+			entry.CarbonMilligrams += row.CarbonMilligrams
+		}
+	}
+
+	if !rolled.empty() {
+		var rows []ModelTotals
+		if errScan := scope.apply(db.WithContext(ctx).
+			Model(&models.UsageHourly{}).
+			Where("hour >= ? AND hour < ?", rolled.from.UTC(), rolled.to.UTC())).
+			Select(selectByModel + ", COALESCE(SUM(requests), 0) AS requests").
+			Group("model").
+			Scan(&rows).Error; errScan != nil {
+			return nil, fmt.Errorf("stats: query hourly models: %w", errScan)
+		}
+		merge(rows)
+	}
+	if !raw.empty() {
+		var rows []ModelTotals
+		if errScan := scope.apply(db.WithContext(ctx).
+			Model(&models.Usage{}).
+			Where("requested_at >= ? AND requested_at < ?", raw.from.UTC(), raw.to.UTC())).
+			Select(selectByModel + ", COUNT(*) AS requests").
+			Group("model").
+			Scan(&rows).Error; errScan != nil {
+			return nil, fmt.Errorf("stats: query raw models: %w", errScan)
+		}
+		merge(rows)
+	}
+
+	out := make([]ModelTotals, 0, len(byModel))
+	for _, entry := range byModel {
+		out = append(out, *entry)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CostMicros != out[j].CostMicros {
+			return out[i].CostMicros > out[j].CostMicros
+		}
+		return out[i].Model < out[j].Model
+	})
+	return out, nil
+}
@@ -0,0 +1,117 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func setupStatsDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:stats_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func TestAggregatorRollsUpSettledHoursAndQueriesMergeRawRows(t *testing.T) {
+	conn := setupStatsDB(t)
+	ctx := context.Background()
+	now := HourStart(time.Now()).Add(30 * time.Minute)
+	current := HourStart(now)
+	previous := current.Add(-2 * time.Hour)
+
+	userA, userB := uint64(1), uint64(2)
+	rows := []models.Usage{
+		{Provider: "codex", Model: "gpt-5", UserID: &userA, RequestedAt: previous.Add(time.Minute), CreatedAt: previous.Add(time.Minute + 200*time.Millisecond), TotalTokens: 10, CostMicros: 100, CarbonMilligrams: 5},
+		{Provider: "codex", Model: "gpt-5", UserID: &userB, RequestedAt: previous.Add(2 * time.Minute), CreatedAt: previous.Add(2*time.Minute + 400*time.Millisecond), Failed: true, TotalTokens: 1},
+		{Provider: "claude", Model: "sonnet", RequestedAt: previous.Add(3 * time.Minute), CreatedAt: previous.Add(3 * time.Minute), TotalTokens: 7, CostMicros: 300},
+		{Provider: "codex", Model: "gpt-5", UserID: &userA, RequestedAt: current.Add(time.Minute), CreatedAt: current.Add(time.Minute), TotalTokens: 20, CostMicros: 50},
+	}
+	if errCreate := conn.Create(&rows).Error; errCreate != nil {
+		t.Fatalf("create usage: %v", errCreate)
+	}
+
+	agg := NewAggregator(conn)
+	agg.backfillDays = 1
+	if errRun := agg.RunOnce(ctx, now); errRun != nil {
+		t.Fatalf("RunOnce: %v", errRun)
+	}
+	through, errCursor := Cursor(ctx, conn)
+	if errCursor != nil {
+		t.Fatalf("Cursor: %v", errCursor)
+	}
+	if !through.Equal(current) {
+		t.Fatalf("expected cursor at %s, got %s", current, through)
+	}
+
+	var bucket models.UsageHourly
+	if errFind := conn.Where("hour = ? AND model = ? AND user_id = ?", previous.UTC(), "gpt-5", userA).First(&bucket).Error; errFind != nil {
+		t.Fatalf("find bucket: %v", errFind)
+	}
+	if bucket.Requests != 1 || bucket.CostMicros != 100 || bucket.CarbonMilligrams != 5 || bucket.DurationMillis < 199 || bucket.DurationMillis > 201 {
+		t.Fatalf("unexpected bucket: %+v", bucket)
+	}
+
+	// Raw rows of a finalized hour are no longer read, so deleting them must not change totals.
+	if errDelete := conn.Where("requested_at < ?", current.UTC()).Delete(&models.Usage{}).Error; errDelete != nil {
+		t.Fatalf("delete raw rows: %v", errDelete)
+	}
+
+	totals, errTotals := QueryTotals(ctx, conn, previous, current.Add(time.Hour), Scope{})
+	if errTotals != nil {
+		t.Fatalf("QueryTotals: %v", errTotals)
+	}
+	if totals.Requests != 4 || totals.FailedRequests != 1 || totals.TotalTokens != 38 || totals.CostMicros != 450 {
+		t.Fatalf("unexpected totals: %+v", totals)
+	}
+	if totals.ActiveUsers != 2 {
+		t.Fatalf("expected users counted once across rollups and raw rows, got %d", totals.ActiveUsers)
+	}
+
+	points, errHourly := QueryHourly(ctx, conn, previous, current.Add(time.Hour), Scope{})
+	if errHourly != nil {
+		t.Fatalf("QueryHourly: %v", errHourly)
+	}
+	if len(points) != 3 || points[0].Requests != 3 || points[0].FailedRequests != 1 || points[1].Requests != 0 || points[2].Requests != 1 {
+		t.Fatalf("unexpected hourly points: %+v", points)
+	}
+
+	byModel, errByModel := QueryByModel(ctx, conn, previous, current.Add(time.Hour), Scope{})
+	if errByModel != nil {
+		t.Fatalf("QueryByModel: %v", errByModel)
+	}
+	if len(byModel) != 2 || byModel[0].Model != "sonnet" || byModel[1].CostMicros != 150 || byModel[1].Requests != 3 {
+		t.Fatalf("unexpected model totals: %+v", byModel)
+	}
+
+	org := models.Organization{Name: "tenant"}
+	if errCreate := conn.Create(&org).Error; errCreate != nil {
+		t.Fatalf("create organization: %v", errCreate)
+	}
+	members := []models.User{
+		{ID: userA, Username: "a", Email: "a@example.com", Password: "x", OrganizationID: &org.ID},
+		{ID: userB, Username: "b", Email: "b@example.com", Password: "x"},
+	}
+	if errCreate := conn.Create(&members).Error; errCreate != nil {
+		t.Fatalf("create users: %v", errCreate)
+	}
+	scoped, errScoped := QueryTotals(ctx, conn, previous, current.Add(time.Hour), Scope{OrganizationID: org.ID})
+	if errScoped != nil {
+		t.Fatalf("QueryTotals scoped: %v", errScoped)
+	}
+	if scoped.Requests != 2 || scoped.CostMicros != 150 || scoped.ActiveUsers != 1 {
+		t.Fatalf("unexpected organization totals: %+v", scoped)
+	}
+}
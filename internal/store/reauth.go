@@ -0,0 +1,134 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// DefaultReauthTTL bounds how long a pending re-authentication waits for its OAuth result.
+const DefaultReauthTTL = 15 * time.Minute
+
+// ErrReauthNoIdentity reports an auth file without an email or project to match the new
+// credential against.
+var ErrReauthNoIdentity = errors.New("auth file has no account email or project to verify")
+
+// ExpectReauth records that the OAuth flow identified by state re-authenticates the auth file
+// authKey, whose current content is content. The credential the flow saves is written back
+// onto authKey only when it carries the same account email and project.
+func ExpectReauth(ctx context.Context, db *gorm.DB, state, provider, authKey string, content []byte, ttl time.Duration) error {
+	state = strings.TrimSpace(state)
+	provider = canonicalReauthProvider(provider)
+	authKey = strings.TrimSpace(authKey)
+	if db == nil || state == "" || provider == "" || authKey == "" {
+		return fmt.Errorf("store: incomplete re-authentication")
+	}
+	email, projectID := reauthIdentity(content)
+	if email == "" && projectID == "" {
+		return ErrReauthNoIdentity
+	}
+	if ttl <= 0 {
+		ttl = DefaultReauthTTL
+	}
+	return db.WithContext(ctx).Create(&models.AuthReauth{
+		State:     state,
+		Provider:  provider,
+		AuthKey:   authKey,
+		Email:     email,
+		ProjectID: projectID,
+		ExpiresAt: time.Now().UTC().Add(ttl),
+	}).Error
+}
+
+// takeReauthTarget claims the pending re-authentication matching a saved credential. Only
+// flows for the provider whose account email and project equal the credential's qualify, and
+// a save for a different, already stored key (e.g. a routine token refresh of another
+// account) is left alone. Claiming deletes the row, so one flow rewrites one auth file even
+// when several instances save concurrently.
+func takeReauthTarget(ctx context.Context, db *gorm.DB, provider, savedKey string, savedKeyExists bool, payload []byte) (string, bool) {
+	provider = canonicalReauthProvider(provider)
+	if db == nil || provider == "" {
+		return "", false
+	}
+	now := time.Now().UTC()
+	conn := db.WithContext(ctx)
+	if errPrune := conn.Where("expires_at <= ?", now).Delete(&models.AuthReauth{}).Error; errPrune != nil {
+		log.WithError(errPrune).Warn("store: prune expired re-authentications failed")
+	}
+	var pending []models.AuthReauth
+	if errFind := conn.Where("provider = ? AND expires_at > ?", provider, now).Order("id ASC").Find(&pending).Error; errFind != nil {
+		log.WithError(errFind).Warn("store: load pending re-authentications failed")
+		return "", false
+	}
+	if len(pending) == 0 {
+		return "", false
+	}
+	email, projectID := reauthIdentity(payload)
+	savedKey = strings.TrimSpace(savedKey)
+	for _, row := range pending {
+		if savedKeyExists && row.AuthKey != savedKey {
+			continue
+		}
+		if !reauthIdentityMatches(row, email, projectID) {
+			continue
+		}
+		result := conn.Where("id = ?", row.ID).Delete(&models.AuthReauth{})
+		if result.Error != nil {
+			log.WithError(result.Error).Warn("store: claim re-authentication failed")
+			return "", false
+		}
+		if result.RowsAffected == 1 {
+			return row.AuthKey, true
+		}
+	}
+	log.WithField("provider", provider).Warn("store: saved credential matches no pending re-authentication account")
+	return "", false
+}
+
+// reauthIdentity extracts the account email and project from auth JSON.
+func reauthIdentity(content []byte) (email, projectID string) {
+	var metadata map[string]any
+	if len(content) == 0 || json.Unmarshal(content, &metadata) != nil {
+		return "", ""
+	}
+	if v, ok := metadata["email"].(string); ok {
+		email = strings.ToLower(strings.TrimSpace(v))
+	}
+	if v, ok := metadata["project_id"].(string); ok {
+		projectID = strings.TrimSpace(v)
+	}
+	return email, projectID
+}
+
+// reauthIdentityMatches reports whether a credential belongs to the pending flow's account.
+func reauthIdentityMatches(row models.AuthReauth, email, projectID string) bool {
+	if row.Email == "" && row.ProjectID == "" {
+		return false
+	}
+	if row.Email != "" && row.Email != email {
+		return false
+	}
+	return row.ProjectID == "" || row.ProjectID == projectID
+}
+
+// canonicalReauthProvider folds provider aliases onto one registry key.
+func canonicalReauthProvider(provider string) string {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	switch provider {
+	case "anthropic":
+		return "claude"
+	case "gemini-cli":
+		return "gemini"
+	case "iflow-cookie":
+		return "iflow"
+	default:
+		return provider
+	}
+}
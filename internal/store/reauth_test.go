@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func openReauthTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:reauth_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.AutoMigrate(&models.AuthReauth{}); errMigrate != nil {
+		t.Fatalf("migrate reauth model: %v", errMigrate)
+	}
+	return db
+}
+
+func TestTakeReauthTargetMatchesAccountIdentity(t *testing.T) {
+	db := openReauthTestDB(t)
+	ctx := context.Background()
+	content := []byte(`{"type":"gemini","email":"Old@example.com","project_id":"proj-1"}`)
+	if errExpect := ExpectReauth(ctx, db, "state-1", "gemini-cli", "gemini-old.json", content, time.Minute); errExpect != nil {
+		t.Fatalf("ExpectReauth: %v", errExpect)
+	}
+
+	if _, ok := takeReauthTarget(ctx, db, "gemini", "gemini-other.json", false, []byte(`{"email":"other@example.com","project_id":"proj-1"}`)); ok {
+		t.Fatalf("expected a credential of another account to be ignored")
+	}
+	if _, ok := takeReauthTarget(ctx, db, "gemini", "gemini-new.json", false, []byte(`{"email":"old@example.com","project_id":"proj-2"}`)); ok {
+		t.Fatalf("expected a credential of another project to be ignored")
+	}
+	if _, ok := takeReauthTarget(ctx, db, "gemini", "gemini-stored.json", true, []byte(`{"email":"old@example.com","project_id":"proj-1"}`)); ok {
+		t.Fatalf("expected a refresh of another stored key to be ignored")
+	}
+	target, ok := takeReauthTarget(ctx, db, "gemini", "gemini-new.json", false, []byte(`{"email":"old@example.com","project_id":"proj-1"}`))
+	if !ok || target != "gemini-old.json" {
+		t.Fatalf("expected pending target, got %q ok=%v", target, ok)
+	}
+	if _, ok := takeReauthTarget(ctx, db, "gemini", "gemini-new.json", false, []byte(`{"email":"old@example.com","project_id":"proj-1"}`)); ok {
+		t.Fatalf("expected pending target to be consumed")
+	}
+}
+
+func TestExpectReauthRequiresIdentityAndExpires(t *testing.T) {
+	db := openReauthTestDB(t)
+	ctx := context.Background()
+	if errExpect := ExpectReauth(ctx, db, "state-1", "claude", "claude-a.json", []byte(`{"type":"claude"}`), time.Minute); !errors.Is(errExpect, ErrReauthNoIdentity) {
+		t.Fatalf("expected ErrReauthNoIdentity, got %v", errExpect)
+	}
+	if errExpect := ExpectReauth(ctx, db, "state-2", "anthropic", "claude-a.json", []byte(`{"email":"a@example.com"}`), time.Nanosecond); errExpect != nil {
+		t.Fatalf("ExpectReauth: %v", errExpect)
+	}
+	time.Sleep(time.Millisecond)
+
+	if _, ok := takeReauthTarget(ctx, db, "claude", "claude-b.json", false, []byte(`{"email":"a@example.com"}`)); ok {
+		t.Fatalf("expected expired target to be dropped")
+	}
+	var remaining int64
+	db.Model(&models.AuthReauth{}).Count(&remaining)
+	if remaining != 0 {
+		t.Fatalf("expected expired rows to be pruned, got %d", remaining)
+	}
+}
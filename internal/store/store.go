@@ -9,6 +9,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/authschedule"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/environments"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
@@ -19,7 +21,8 @@ import (
 
 // GormAuthStore persists CLIProxyAPI auth JSON blobs to PostgreSQL via GORM.
 type GormAuthStore struct {
-	db *gorm.DB
+	db          *gorm.DB
+	environment string // Environment tag served by this instance; empty lists every auth.
 
 	mu      sync.Mutex
 	dirLock sync.RWMutex
@@ -30,6 +33,14 @@ func NewGormAuthStore(db *gorm.DB) *GormAuthStore {
 	return &GormAuthStore{db: db}
 }
 
+// SetEnvironment limits List to auths tagged for env or shared with every environment.
+func (s *GormAuthStore) SetEnvironment(env string) {
+	if s == nil {
+		return
+	}
+	s.environment = strings.ToLower(strings.TrimSpace(env))
+}
+
 // Save upserts an auth record into the database.
 func (s *GormAuthStore) Save(ctx context.Context, auth *cliproxyauth.Auth) (string, error) {
 	if s == nil || s.db == nil {
@@ -81,8 +92,18 @@ func (s *GormAuthStore) Save(ctx context.Context, auth *cliproxyauth.Auth) (stri
 	defer s.mu.Unlock()
 
 	var existing models.Auth
-	errFind := s.db.WithContext(ctx).Where("key = ?", id).First(&existing).Error
-	if errFind == nil {
+	errFind := s.db.WithContext(ctx).Where("? = ?", clause.Column{Name: "key"}, id).First(&existing).Error
+	// A credential for the account of an admin-triggered re-authentication is the
+	// fresh OAuth result; write it back onto the auth file being repaired.
+	reauthed := false
+	if target, ok := takeReauthTarget(ctx, s.db, provider, id, errFind == nil, payload); ok {
+		reauthed = true
+		if target != id {
+			id = target
+			errFind = s.db.WithContext(ctx).Where("? = ?", clause.Column{Name: "key"}, id).First(&existing).Error
+		}
+	}
+	if errFind == nil && !reauthed {
 		if jsonEqual(existing.Content, payload) {
 			return id, nil
 		}
@@ -103,6 +124,17 @@ func (s *GormAuthStore) Save(ctx context.Context, auth *cliproxyauth.Auth) (stri
 		return "", fmt.Errorf("gorm auth store: upsert: %w", err)
 	}
 
+	if reauthed {
+		if errHealth := s.db.WithContext(ctx).Model(&models.Auth{}).
+			Where("? = ?", clause.Column{Name: "key"}, id).
+			Updates(map[string]any{
+				"token_invalid":   false,
+				"last_auth_error": "",
+			}).Error; errHealth != nil {
+			return "", fmt.Errorf("gorm auth store: reset token health: %w", errHealth)
+		}
+	}
+
 	return id, nil
 }
 
@@ -119,6 +151,8 @@ func (s *GormAuthStore) List(ctx context.Context) ([]*cliproxyauth.Auth, error)
 		Find(&rows).Error; errFind != nil {
 		return nil, fmt.Errorf("gorm auth store: list: %w", errFind)
 	}
+	rows = environments.FilterAuths(rows, s.environment)
+	rows = authschedule.FilterAuths(ctx, s.db, rows)
 
 	auths := make([]*cliproxyauth.Auth, 0, len(rows))
 	for _, row := range rows {
@@ -171,7 +205,7 @@ func (s *GormAuthStore) Delete(ctx context.Context, id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if errDelete := s.db.WithContext(ctx).Where("key = ?", id).Delete(&models.Auth{}).Error; errDelete != nil {
+	if errDelete := s.db.WithContext(ctx).Where("? = ?", clause.Column{Name: "key"}, id).Delete(&models.Auth{}).Error; errDelete != nil {
 		return fmt.Errorf("gorm auth store: delete db row: %w", errDelete)
 	}
 	return nil
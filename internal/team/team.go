@@ -0,0 +1,259 @@
+// Package team lets front users share API keys and a budget. The owner's bills and prepaid
+// cards form the team pool: team keys are charged to the owner while usage rows keep the
+// member who made each request.
+package team
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotMember is returned when the user has not joined the team.
+	ErrNotMember = errors.New("team: user is not a member")
+	// ErrNotOwner is returned when a non-owner attempts an owner action.
+	ErrNotOwner = errors.New("team: only the owner can do this")
+	// ErrUserNotFound is returned when an invitation names no active user.
+	ErrUserNotFound = errors.New("team: user not found")
+	// ErrAlreadyInvited is returned when the user is already a member or invited.
+	ErrAlreadyInvited = errors.New("team: user is already a member or invited")
+	// ErrNotInvited is returned when accepting without a pending invitation.
+	ErrNotInvited = errors.New("team: no pending invitation")
+	// ErrOwnerCannotLeave is returned when the owner tries to leave their own team.
+	ErrOwnerCannotLeave = errors.New("team: the owner cannot leave; delete the team instead")
+	// ErrUnavailable is returned for team keys whose team or owner can no longer pay.
+	ErrUnavailable = errors.New("team: team is unavailable")
+)
+
+// Create makes a team owned by ownerID, who joins it as its first member.
+func Create(ctx context.Context, db *gorm.DB, ownerID uint64, name string, now time.Time) (*models.Team, error) {
+	team := models.Team{Name: strings.TrimSpace(name), OwnerUserID: ownerID, CreatedAt: now, UpdatedAt: now}
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if errCreate := tx.Create(&team).Error; errCreate != nil {
+			return errCreate
+		}
+		return tx.Create(&models.TeamMember{
+			TeamID:    team.ID,
+			UserID:    ownerID,
+			Role:      models.TeamRoleOwner,
+			JoinedAt:  &now,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}).Error
+	})
+	if errTx != nil {
+		return nil, errTx
+	}
+	return &team, nil
+}
+
+// Load returns a team the user has joined.
+func Load(ctx context.Context, db *gorm.DB, teamID, userID uint64) (*models.Team, *models.TeamMember, error) {
+	member, errMember := membership(ctx, db, teamID, userID)
+	if errMember != nil {
+		return nil, nil, errMember
+	}
+	var team models.Team
+	if errFind := db.WithContext(ctx).First(&team, teamID).Error; errFind != nil {
+		return nil, nil, errFind
+	}
+	return &team, member, nil
+}
+
+// Invite adds a pending membership for the user named by login, a username or email. Only
+// the owner may invite.
+func Invite(ctx context.Context, db *gorm.DB, teamID, ownerID uint64, login string, now time.Time) (*models.TeamMember, error) {
+	team, _, errLoad := Load(ctx, db, teamID, ownerID)
+	if errLoad != nil {
+		return nil, errLoad
+	}
+	if team.OwnerUserID != ownerID {
+		return nil, ErrNotOwner
+	}
+	login = strings.TrimSpace(login)
+	if login == "" {
+		return nil, ErrUserNotFound
+	}
+	var user models.User
+	if errFind := db.WithContext(ctx).
+		Where("(username = ? OR email = ?) AND disabled = ?", login, login, false).
+		First(&user).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, errFind
+	}
+	var existing int64
+	if errCount := db.WithContext(ctx).Model(&models.TeamMember{}).
+		Where("team_id = ? AND user_id = ?", teamID, user.ID).
+		Count(&existing).Error; errCount != nil {
+		return nil, errCount
+	}
+	if existing > 0 {
+		return nil, ErrAlreadyInvited
+	}
+	member := models.TeamMember{
+		TeamID:          teamID,
+		UserID:          user.ID,
+		Role:            models.TeamRoleMember,
+		InvitedByUserID: &ownerID,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if errCreate := db.WithContext(ctx).Create(&member).Error; errCreate != nil {
+		return nil, errCreate
+	}
+	member.User = user
+	return &member, nil
+}
+
+// Accept turns the user's pending invitation into a membership.
+func Accept(ctx context.Context, db *gorm.DB, teamID, userID uint64, now time.Time) error {
+	res := db.WithContext(ctx).Model(&models.TeamMember{}).
+		Where("team_id = ? AND user_id = ? AND joined_at IS NULL", teamID, userID).
+		Updates(map[string]any{"joined_at": now, "updated_at": now})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotInvited
+	}
+	return nil
+}
+
+// RemoveMember removes memberID from the team and revokes the team keys they issued. The
+// owner may remove anyone but themselves; other users may only remove themselves, which
+// also declines a pending invitation.
+func RemoveMember(ctx context.Context, db *gorm.DB, teamID, actorID, memberID uint64, now time.Time) error {
+	var team models.Team
+	if errFind := db.WithContext(ctx).First(&team, teamID).Error; errFind != nil {
+		return errFind
+	}
+	if memberID == team.OwnerUserID {
+		return ErrOwnerCannotLeave
+	}
+	if actorID != team.OwnerUserID && actorID != memberID {
+		return ErrNotOwner
+	}
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("team_id = ? AND user_id = ?", teamID, memberID).Delete(&models.TeamMember{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotMember
+		}
+		return revokeKeys(tx.Where("team_id = ? AND user_id = ?", teamID, memberID), now)
+	})
+}
+
+// Delete revokes every team key and removes the team. Only the owner may delete it.
+func Delete(ctx context.Context, db *gorm.DB, teamID, ownerID uint64, now time.Time) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var team models.Team
+		if errFind := tx.First(&team, teamID).Error; errFind != nil {
+			return errFind
+		}
+		if team.OwnerUserID != ownerID {
+			return ErrNotOwner
+		}
+		if errRevoke := revokeKeys(tx.Where("team_id = ?", teamID), now); errRevoke != nil {
+			return errRevoke
+		}
+		if errMembers := tx.Where("team_id = ?", teamID).Delete(&models.TeamMember{}).Error; errMembers != nil {
+			return errMembers
+		}
+		return tx.Delete(&models.Team{}, teamID).Error
+	})
+}
+
+// revokeKeys revokes the unrevoked API keys matched by q.
+func revokeKeys(q *gorm.DB, now time.Time) error {
+	return q.Model(&models.APIKey{}).
+		Where("revoked_at IS NULL").
+		Updates(map[string]any{"active": false, "revoked_at": now, "updated_at": now}).Error
+}
+
+// Payer returns the user charged for a team key: the team owner, as long as the member who
+// issued the key still belongs to the team and the owner account is enabled.
+func Payer(ctx context.Context, db *gorm.DB, key *models.APIKey) (uint64, error) {
+	if key == nil || key.TeamID == nil || key.UserID == nil {
+		return 0, ErrUnavailable
+	}
+	if _, errMember := membership(ctx, db, *key.TeamID, *key.UserID); errMember != nil {
+		if errors.Is(errMember, ErrNotMember) {
+			return 0, ErrUnavailable
+		}
+		return 0, errMember
+	}
+	var owner struct {
+		ID       uint64
+		Disabled bool
+	}
+	if errFind := db.WithContext(ctx).Model(&models.Team{}).
+		Select("users.id AS id, users.disabled AS disabled").
+		Joins("JOIN users ON users.id = teams.owner_user_id").
+		Where("teams.id = ?", *key.TeamID).
+		Take(&owner).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return 0, ErrUnavailable
+		}
+		return 0, errFind
+	}
+	if owner.Disabled {
+		return 0, ErrUnavailable
+	}
+	return owner.ID, nil
+}
+
+// MemberUsage summarizes a member's requests made with team keys.
+type MemberUsage struct {
+	UserID     uint64  `json:"user_id"`
+	Username   string  `json:"username"`
+	Requests   int64   `json:"requests"`
+	Tokens     int64   `json:"total_tokens"`
+	CostMicros int64   `json:"cost_micros"`
+	Cost       float64 `json:"cost"`
+}
+
+// UsageByMember returns the team's usage since the given time per member, most expensive
+// first. Members who left keep their rows.
+func UsageByMember(ctx context.Context, db *gorm.DB, teamID uint64, since time.Time) ([]MemberUsage, error) {
+	var rows []MemberUsage
+	if errScan := db.WithContext(ctx).Model(&models.Usage{}).
+		Select(`usages.team_member_id AS user_id,
+			COALESCE(MAX(users.username), '') AS username,
+			COUNT(*) AS requests,
+			COALESCE(SUM(usages.total_tokens), 0) AS tokens,
+			COALESCE(SUM(usages.cost_micros), 0) AS cost_micros`).
+		Joins("LEFT JOIN users ON users.id = usages.team_member_id").
+		Where("usages.team_id = ? AND usages.team_member_id IS NOT NULL AND usages.requested_at >= ?", teamID, since).
+		Group("usages.team_member_id").
+		Order("cost_micros DESC").
+		Scan(&rows).Error; errScan != nil {
+		return nil, errScan
+	}
+	for i := range rows {
+		rows[i].Cost = float64(rows[i].CostMicros) / 1_000_000
+	}
+	return rows, nil
+}
+
+// membership returns the user's joined membership of the team.
+func membership(ctx context.Context, db *gorm.DB, teamID, userID uint64) (*models.TeamMember, error) {
+	var member models.TeamMember
+	if errFind := db.WithContext(ctx).
+		Where("team_id = ? AND user_id = ? AND joined_at IS NOT NULL", teamID, userID).
+		First(&member).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return nil, ErrNotMember
+		}
+		return nil, errFind
+	}
+	return &member, nil
+}
@@ -0,0 +1,178 @@
+package team
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func setupTeamDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:team_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func createTeamUser(t *testing.T, conn *gorm.DB, username string) *models.User {
+	t.Helper()
+	user := models.User{Username: username, Email: username + "@example.com", Password: "x"}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+	return &user
+}
+
+func TestInviteAcceptAndPayer(t *testing.T) {
+	conn := setupTeamDB(t)
+	ctx := context.Background()
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	alice := createTeamUser(t, conn, "alice")
+	bob := createTeamUser(t, conn, "bob")
+
+	created, errCreate := Create(ctx, conn, alice.ID, " research ", now)
+	if errCreate != nil {
+		t.Fatalf("Create: %v", errCreate)
+	}
+	if created.Name != "research" {
+		t.Fatalf("expected trimmed name, got %q", created.Name)
+	}
+
+	if _, errInvite := Invite(ctx, conn, created.ID, bob.ID, "alice", now); !errors.Is(errInvite, ErrNotMember) {
+		t.Fatalf("expected non-members to be unable to invite, got %v", errInvite)
+	}
+	if _, errInvite := Invite(ctx, conn, created.ID, alice.ID, "nobody", now); !errors.Is(errInvite, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", errInvite)
+	}
+	if _, errInvite := Invite(ctx, conn, created.ID, alice.ID, "bob@example.com", now); errInvite != nil {
+		t.Fatalf("Invite: %v", errInvite)
+	}
+	if _, errInvite := Invite(ctx, conn, created.ID, alice.ID, "bob", now); !errors.Is(errInvite, ErrAlreadyInvited) {
+		t.Fatalf("expected ErrAlreadyInvited, got %v", errInvite)
+	}
+
+	teamID := created.ID
+	key := models.APIKey{UserID: &bob.ID, TeamID: &teamID, Name: "shared", APIKey: "sk-team-000000000000001"}
+	if errKey := conn.Create(&key).Error; errKey != nil {
+		t.Fatalf("create key: %v", errKey)
+	}
+	if _, errPayer := Payer(ctx, conn, &key); !errors.Is(errPayer, ErrUnavailable) {
+		t.Fatalf("expected pending members' keys to be unavailable, got %v", errPayer)
+	}
+
+	if errAccept := Accept(ctx, conn, teamID, bob.ID, now); errAccept != nil {
+		t.Fatalf("Accept: %v", errAccept)
+	}
+	if errAccept := Accept(ctx, conn, teamID, bob.ID, now); !errors.Is(errAccept, ErrNotInvited) {
+		t.Fatalf("expected ErrNotInvited on second accept, got %v", errAccept)
+	}
+	payer, errPayer := Payer(ctx, conn, &key)
+	if errPayer != nil || payer != alice.ID {
+		t.Fatalf("expected team owner %d to pay, got %d (%v)", alice.ID, payer, errPayer)
+	}
+
+	conn.Model(&models.User{}).Where("id = ?", alice.ID).Update("disabled", true)
+	if _, errPayer := Payer(ctx, conn, &key); !errors.Is(errPayer, ErrUnavailable) {
+		t.Fatalf("expected disabled owners to block team keys, got %v", errPayer)
+	}
+}
+
+func TestRemoveMemberRevokesKeysAndKeepsUsage(t *testing.T) {
+	conn := setupTeamDB(t)
+	ctx := context.Background()
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	alice := createTeamUser(t, conn, "alice")
+	bob := createTeamUser(t, conn, "bob")
+	carol := createTeamUser(t, conn, "carol")
+
+	created, errCreate := Create(ctx, conn, alice.ID, "research", now)
+	if errCreate != nil {
+		t.Fatalf("Create: %v", errCreate)
+	}
+	teamID := created.ID
+	for _, user := range []*models.User{bob, carol} {
+		if _, errInvite := Invite(ctx, conn, teamID, alice.ID, user.Username, now); errInvite != nil {
+			t.Fatalf("Invite: %v", errInvite)
+		}
+		if errAccept := Accept(ctx, conn, teamID, user.ID, now); errAccept != nil {
+			t.Fatalf("Accept: %v", errAccept)
+		}
+	}
+	bobKey := models.APIKey{UserID: &bob.ID, TeamID: &teamID, Name: "bob", APIKey: "sk-team-bob-000000000001", Active: true}
+	carolKey := models.APIKey{UserID: &carol.ID, TeamID: &teamID, Name: "carol", APIKey: "sk-team-carol-00000000001", Active: true}
+	for _, key := range []*models.APIKey{&bobKey, &carolKey} {
+		if errKey := conn.Create(key).Error; errKey != nil {
+			t.Fatalf("create key: %v", errKey)
+		}
+	}
+	for _, usage := range []models.Usage{
+		{Provider: "openai", Model: "gpt", UserID: &alice.ID, TeamID: &teamID, TeamMemberID: &bob.ID, TotalTokens: 10, CostMicros: 3_000_000, RequestedAt: now},
+		{Provider: "openai", Model: "gpt", UserID: &alice.ID, TeamID: &teamID, TeamMemberID: &bob.ID, TotalTokens: 5, CostMicros: 1_000_000, RequestedAt: now},
+		{Provider: "openai", Model: "gpt", UserID: &alice.ID, TeamID: &teamID, TeamMemberID: &carol.ID, TotalTokens: 7, CostMicros: 500_000, RequestedAt: now},
+		{Provider: "openai", Model: "gpt", UserID: &alice.ID, TotalTokens: 100, CostMicros: 9_000_000, RequestedAt: now},
+	} {
+		if errUsage := conn.Create(&usage).Error; errUsage != nil {
+			t.Fatalf("create usage: %v", errUsage)
+		}
+	}
+
+	if errRemove := RemoveMember(ctx, conn, teamID, carol.ID, bob.ID, now); !errors.Is(errRemove, ErrNotOwner) {
+		t.Fatalf("expected members to be unable to remove others, got %v", errRemove)
+	}
+	if errRemove := RemoveMember(ctx, conn, teamID, alice.ID, alice.ID, now); !errors.Is(errRemove, ErrOwnerCannotLeave) {
+		t.Fatalf("expected ErrOwnerCannotLeave, got %v", errRemove)
+	}
+	if errRemove := RemoveMember(ctx, conn, teamID, bob.ID, bob.ID, now); errRemove != nil {
+		t.Fatalf("RemoveMember: %v", errRemove)
+	}
+
+	var stored models.APIKey
+	if errFind := conn.First(&stored, bobKey.ID).Error; errFind != nil {
+		t.Fatalf("load key: %v", errFind)
+	}
+	if stored.Active || stored.RevokedAt == nil {
+		t.Fatalf("expected the leaving member's key to be revoked, got %+v", stored)
+	}
+	var kept models.APIKey
+	if errFind := conn.First(&kept, carolKey.ID).Error; errFind != nil {
+		t.Fatalf("load key: %v", errFind)
+	}
+	if kept.RevokedAt != nil {
+		t.Fatalf("expected other members' keys to stay active")
+	}
+
+	members, errUsage := UsageByMember(ctx, conn, teamID, now.Add(-time.Hour))
+	if errUsage != nil {
+		t.Fatalf("UsageByMember: %v", errUsage)
+	}
+	if len(members) != 2 || members[0].UserID != bob.ID || members[0].Requests != 2 || members[0].Tokens != 15 || members[0].Cost != 4 {
+		t.Fatalf("unexpected member usage: %+v", members)
+	}
+	if members[0].Username != "bob" || members[1].UserID != carol.ID {
+		t.Fatalf("unexpected member usage: %+v", members)
+	}
+
+	if errDelete := Delete(ctx, conn, teamID, carol.ID, now); !errors.Is(errDelete, ErrNotOwner) {
+		t.Fatalf("expected ErrNotOwner, got %v", errDelete)
+	}
+	if errDelete := Delete(ctx, conn, teamID, alice.ID, now); errDelete != nil {
+		t.Fatalf("Delete: %v", errDelete)
+	}
+	var activeKeys int64
+	conn.Model(&models.APIKey{}).Where("team_id = ? AND revoked_at IS NULL", teamID).Count(&activeKeys)
+	if activeKeys != 0 {
+		t.Fatalf("expected deleting the team to revoke its keys, %d left", activeKeys)
+	}
+}
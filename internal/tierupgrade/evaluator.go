@@ -0,0 +1,300 @@
+package tierupgrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const defaultEvaluateInterval = time.Hour
+
+// ErrUpgradeNotPending is returned when deciding an upgrade that is no longer pending.
+var ErrUpgradeNotPending = errors.New("tier upgrade: not pending")
+
+// Evaluator periodically compares user usage against tier upgrade rules.
+type Evaluator struct {
+	db       *gorm.DB
+	interval time.Duration
+}
+
+// NewEvaluator constructs a tier upgrade evaluator.
+func NewEvaluator(db *gorm.DB) *Evaluator {
+	if db == nil {
+		return nil
+	}
+	return &Evaluator{db: db, interval: defaultEvaluateInterval}
+}
+
+// Start launches the evaluation loop in a background goroutine.
+func (e *Evaluator) Start(ctx context.Context) {
+	if e == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go e.run(ctx)
+	log.Infof("tier upgrade evaluator started (interval=%s)", e.interval)
+}
+
+func (e *Evaluator) run(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		if errEval := e.EvaluateOnce(ctx, time.Now().UTC()); errEval != nil {
+			log.WithError(errEval).Warn("tier upgrade evaluator: evaluate failed")
+		}
+		timer := time.NewTimer(e.interval)
+		select {
+		case <-ctx.Done():
+			if !timer.Stop() {
+				<-timer.C
+			}
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// EvaluateOnce runs every enabled rule once against usage ending at now.
+func (e *Evaluator) EvaluateOnce(ctx context.Context, now time.Time) error {
+	if e == nil || e.db == nil {
+		return nil
+	}
+	var rules []models.TierUpgradeRule
+	if errFind := e.db.WithContext(ctx).
+		Where("is_enabled = ?", true).
+		Order("id ASC").
+		Find(&rules).Error; errFind != nil {
+		return fmt.Errorf("tier upgrade: load rules: %w", errFind)
+	}
+	for i := range rules {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errRule := e.evaluateRule(ctx, &rules[i], now); errRule != nil {
+			log.WithError(errRule).Warnf("tier upgrade evaluator: rule %d failed", rules[i].ID)
+		}
+	}
+	return nil
+}
+
+// userUsage holds aggregated usage cost per user.
+type userUsage struct {
+	UserID     uint64
+	CostMicros int64
+}
+
+func (e *Evaluator) evaluateRule(ctx context.Context, rule *models.TierUpgradeRule, now time.Time) error {
+	if rule.FromUserGroupID == 0 || rule.ToUserGroupID == 0 || rule.FromUserGroupID == rule.ToUserGroupID {
+		return nil
+	}
+	windowDays := rule.WindowDays
+	if windowDays <= 0 {
+		windowDays = 1
+	}
+	windowStart := now.AddDate(0, 0, -windowDays)
+	thresholdMicros := int64(math.Round(rule.ThresholdAmount * 1_000_000))
+
+	var rows []userUsage
+	if errScan := e.db.WithContext(ctx).
+		Model(&models.Usage{}).
+		Select("user_id, SUM(cost_micros) AS cost_micros").
+		Where("user_id IS NOT NULL AND requested_at >= ? AND requested_at < ?", windowStart, now).
+		Group("user_id").
+		Having("SUM(cost_micros) >= ?", thresholdMicros).
+		Scan(&rows).Error; errScan != nil {
+		return fmt.Errorf("aggregate usage: %w", errScan)
+	}
+
+	for _, row := range rows {
+		if row.UserID == 0 || row.CostMicros <= 0 {
+			continue
+		}
+		if errUser := e.evaluateUser(ctx, rule, row, windowStart); errUser != nil {
+			log.WithError(errUser).Warnf("tier upgrade evaluator: user %d failed", row.UserID)
+		}
+	}
+	return nil
+}
+
+func (e *Evaluator) evaluateUser(ctx context.Context, rule *models.TierUpgradeRule, row userUsage, windowStart time.Time) error {
+	var user models.User
+	if errFind := e.db.WithContext(ctx).
+		Select("id", "user_group_id", "disabled").
+		Where("id = ?", row.UserID).
+		First(&user).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("load user: %w", errFind)
+	}
+	if user.Disabled || !containsGroup(user.UserGroupID, rule.FromUserGroupID) || containsGroup(user.UserGroupID, rule.ToUserGroupID) {
+		return nil
+	}
+
+	// Skip users with an open proposal or a decline inside the current window so they are not nagged.
+	var existing int64
+	if errCount := e.db.WithContext(ctx).
+		Model(&models.TierUpgrade{}).
+		Where("rule_id = ? AND user_id = ?", rule.ID, user.ID).
+		Where("status = ? OR (status = ? AND decided_at >= ?)",
+			models.TierUpgradeStatusPending, models.TierUpgradeStatusDeclined, windowStart).
+		Count(&existing).Error; errCount != nil {
+		return fmt.Errorf("count upgrades: %w", errCount)
+	}
+	if existing > 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	upgrade := models.TierUpgrade{
+		RuleID:          rule.ID,
+		UserID:          user.ID,
+		FromUserGroupID: rule.FromUserGroupID,
+		ToUserGroupID:   rule.ToUserGroupID,
+		UsageAmount:     float64(row.CostMicros) / 1_000_000,
+		Status:          models.TierUpgradeStatusPending,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if errCreate := e.db.WithContext(ctx).Create(&upgrade).Error; errCreate != nil {
+		return fmt.Errorf("create upgrade: %w", errCreate)
+	}
+
+	if rule.RequireConfirmation {
+		publish(ctx, events.TypeTierUpgradeProposed, &upgrade)
+		return nil
+	}
+	return Apply(ctx, e.db, upgrade.ID, 0)
+}
+
+// Apply moves the user of a pending upgrade into the target user group.
+// When userID is non-zero the upgrade must belong to that user.
+func Apply(ctx context.Context, db *gorm.DB, upgradeID, userID uint64) error {
+	var applied models.TierUpgrade
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		upgrade, errLoad := loadPending(tx, upgradeID, userID)
+		if errLoad != nil {
+			return errLoad
+		}
+		var user models.User
+		if errFind := tx.Select("id", "user_group_id").Where("id = ?", upgrade.UserID).First(&user).Error; errFind != nil {
+			return fmt.Errorf("tier upgrade: load user: %w", errFind)
+		}
+		now := time.Now().UTC()
+		if errUpdate := tx.Model(&models.User{}).
+			Where("id = ?", user.ID).
+			Updates(map[string]any{
+				"user_group_id": swapGroup(user.UserGroupID, upgrade.FromUserGroupID, upgrade.ToUserGroupID),
+				"updated_at":    now,
+			}).Error; errUpdate != nil {
+			return fmt.Errorf("tier upgrade: update user: %w", errUpdate)
+		}
+		upgrade.Status = models.TierUpgradeStatusApplied
+		upgrade.DecidedAt = &now
+		if errSave := tx.Model(&models.TierUpgrade{}).
+			Where("id = ?", upgrade.ID).
+			Updates(map[string]any{
+				"status":     upgrade.Status,
+				"decided_at": now,
+				"updated_at": now,
+			}).Error; errSave != nil {
+			return fmt.Errorf("tier upgrade: update status: %w", errSave)
+		}
+		applied = *upgrade
+		return nil
+	})
+	if errTx != nil {
+		return errTx
+	}
+	publish(ctx, events.TypeTierUpgradeApplied, &applied)
+	return nil
+}
+
+// Decline marks a pending upgrade as declined without moving the user.
+// When userID is non-zero the upgrade must belong to that user.
+func Decline(ctx context.Context, db *gorm.DB, upgradeID, userID uint64) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		upgrade, errLoad := loadPending(tx, upgradeID, userID)
+		if errLoad != nil {
+			return errLoad
+		}
+		now := time.Now().UTC()
+		return tx.Model(&models.TierUpgrade{}).
+			Where("id = ?", upgrade.ID).
+			Updates(map[string]any{
+				"status":     models.TierUpgradeStatusDeclined,
+				"decided_at": now,
+				"updated_at": now,
+			}).Error
+	})
+}
+
+func loadPending(tx *gorm.DB, upgradeID, userID uint64) (*models.TierUpgrade, error) {
+	q := tx.Where("id = ?", upgradeID)
+	if userID != 0 {
+		q = q.Where("user_id = ?", userID)
+	}
+	var upgrade models.TierUpgrade
+	if errFind := q.First(&upgrade).Error; errFind != nil {
+		return nil, errFind
+	}
+	if upgrade.Status != models.TierUpgradeStatusPending {
+		return nil, ErrUpgradeNotPending
+	}
+	return &upgrade, nil
+}
+
+func containsGroup(ids models.UserGroupIDs, groupID uint64) bool {
+	for _, id := range ids.Values() {
+		if id == groupID {
+			return true
+		}
+	}
+	return false
+}
+
+// swapGroup replaces from with to while keeping the other group memberships in order.
+func swapGroup(ids models.UserGroupIDs, from, to uint64) models.UserGroupIDs {
+	out := make(models.UserGroupIDs, 0, len(ids)+1)
+	replaced := false
+	for _, id := range ids.Values() {
+		value := id
+		if value == from {
+			value = to
+			replaced = true
+		}
+		out = append(out, &value)
+	}
+	if !replaced {
+		value := to
+		out = append(out, &value)
+	}
+	return out.Clean()
+}
+
+func publish(ctx context.Context, eventType events.Type, upgrade *models.TierUpgrade) {
+	events.Publish(ctx, events.Event{
+		Type:    eventType,
+		Subject: "user:" + strconv.FormatUint(upgrade.UserID, 10),
+		Message: fmt.Sprintf("user group %d -> %d (usage %.4f)", upgrade.FromUserGroupID, upgrade.ToUserGroupID, upgrade.UsageAmount),
+		Data: map[string]any{
+			"tier_upgrade_id":    upgrade.ID,
+			"rule_id":            upgrade.RuleID,
+			"user_id":            upgrade.UserID,
+			"from_user_group_id": upgrade.FromUserGroupID,
+			"to_user_group_id":   upgrade.ToUserGroupID,
+			"usage_amount":       upgrade.UsageAmount,
+		},
+	})
+}
@@ -0,0 +1,121 @@
+package tierupgrade
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	dbpkg "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func setupTierUpgradeDB(t *testing.T) (*gorm.DB, models.UserGroup, models.UserGroup) {
+	t.Helper()
+	conn, errOpen := dbpkg.Open(":memory:")
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := dbpkg.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	free := models.UserGroup{Name: "free-tier"}
+	pro := models.UserGroup{Name: "pro-tier"}
+	if errCreate := conn.Create(&free).Error; errCreate != nil {
+		t.Fatalf("create free group: %v", errCreate)
+	}
+	if errCreate := conn.Create(&pro).Error; errCreate != nil {
+		t.Fatalf("create pro group: %v", errCreate)
+	}
+	return conn, free, pro
+}
+
+func createUserWithUsage(t *testing.T, conn *gorm.DB, name string, groupID uint64, costMicros int64, at time.Time) models.User {
+	t.Helper()
+	gid := groupID
+	user := models.User{Username: name, Email: name + "@example.com", Password: "pwd", UserGroupID: models.UserGroupIDs{&gid}}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+	usage := models.Usage{Provider: "openai", Model: "gpt", UserID: &user.ID, CostMicros: costMicros, RequestedAt: at, CreatedAt: at}
+	if errCreate := conn.Create(&usage).Error; errCreate != nil {
+		t.Fatalf("create usage: %v", errCreate)
+	}
+	return user
+}
+
+func TestEvaluateOnceAppliesUpgradeWithoutConfirmation(t *testing.T) {
+	conn, free, pro := setupTierUpgradeDB(t)
+	now := time.Now().UTC()
+	heavy := createUserWithUsage(t, conn, "heavy", free.ID, 12_000_000, now.Add(-time.Hour))
+	light := createUserWithUsage(t, conn, "light", free.ID, 1_000_000, now.Add(-time.Hour))
+
+	rule := models.TierUpgradeRule{Name: "free->pro", FromUserGroupID: free.ID, ToUserGroupID: pro.ID, WindowDays: 7, ThresholdAmount: 10, RequireConfirmation: false, IsEnabled: true}
+	if errCreate := conn.Create(&rule).Error; errCreate != nil {
+		t.Fatalf("create rule: %v", errCreate)
+	}
+
+	if errEval := NewEvaluator(conn).EvaluateOnce(context.Background(), now); errEval != nil {
+		t.Fatalf("evaluate: %v", errEval)
+	}
+
+	var reloaded models.User
+	if errFind := conn.First(&reloaded, heavy.ID).Error; errFind != nil {
+		t.Fatalf("reload user: %v", errFind)
+	}
+	if got := reloaded.UserGroupID.Values(); len(got) != 1 || got[0] != pro.ID {
+		t.Fatalf("expected heavy user in pro group, got %v", got)
+	}
+	var reloadedLight models.User
+	if errFind := conn.First(&reloadedLight, light.ID).Error; errFind != nil {
+		t.Fatalf("reload user: %v", errFind)
+	}
+	if got := reloadedLight.UserGroupID.Values(); len(got) != 1 || got[0] != free.ID {
+		t.Fatalf("expected light user to stay in free group, got %v", got)
+	}
+}
+
+func TestEvaluateOnceProposesUpgradeWhenConfirmationRequired(t *testing.T) {
+	conn, free, pro := setupTierUpgradeDB(t)
+	now := time.Now().UTC()
+	user := createUserWithUsage(t, conn, "heavy", free.ID, 12_000_000, now.Add(-time.Hour))
+
+	rule := models.TierUpgradeRule{Name: "free->pro", FromUserGroupID: free.ID, ToUserGroupID: pro.ID, WindowDays: 7, ThresholdAmount: 10, RequireConfirmation: true, IsEnabled: true}
+	if errCreate := conn.Create(&rule).Error; errCreate != nil {
+		t.Fatalf("create rule: %v", errCreate)
+	}
+
+	evaluator := NewEvaluator(conn)
+	for i := 0; i < 2; i++ {
+		if errEval := evaluator.EvaluateOnce(context.Background(), now); errEval != nil {
+			t.Fatalf("evaluate: %v", errEval)
+		}
+	}
+
+	var upgrades []models.TierUpgrade
+	if errFind := conn.Where("user_id = ?", user.ID).Find(&upgrades).Error; errFind != nil {
+		t.Fatalf("list upgrades: %v", errFind)
+	}
+	if len(upgrades) != 1 || upgrades[0].Status != models.TierUpgradeStatusPending {
+		t.Fatalf("expected one pending upgrade, got %+v", upgrades)
+	}
+
+	if errApply := Apply(context.Background(), conn, upgrades[0].ID, user.ID+100); !errors.Is(errApply, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected other user to be rejected, got %v", errApply)
+	}
+	if errApply := Apply(context.Background(), conn, upgrades[0].ID, user.ID); errApply != nil {
+		t.Fatalf("apply: %v", errApply)
+	}
+	if errDecline := Decline(context.Background(), conn, upgrades[0].ID, user.ID); !errors.Is(errDecline, ErrUpgradeNotPending) {
+		t.Fatalf("expected not pending, got %v", errDecline)
+	}
+
+	var reloaded models.User
+	if errFind := conn.First(&reloaded, user.ID).Error; errFind != nil {
+		t.Fatalf("reload user: %v", errFind)
+	}
+	if got := reloaded.UserGroupID.Values(); len(got) != 1 || got[0] != pro.ID {
+		t.Fatalf("expected user in pro group, got %v", got)
+	}
+}
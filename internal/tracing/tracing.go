@@ -0,0 +1,154 @@
+// Package tracing exports OpenTelemetry spans to an OTLP/HTTP collector and propagates W3C
+// trace context through proxied requests and the usage pipeline.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// scopeName identifies this instrumentation in exported spans.
+const scopeName = "github.com/router-for-me/CLIProxyAPIBusiness"
+
+// TraceparentHeader is the W3C trace context propagation header.
+const TraceparentHeader = "traceparent"
+
+// Options configures span recording and the OTLP/HTTP exporter.
+type Options struct {
+	Endpoint    string            // Collector base URL (".../v1/traces" is appended when missing).
+	ServiceName string            // service.name resource attribute.
+	Headers     map[string]string // Extra request headers, e.g. collector auth.
+	SampleRatio float64           // Fraction of new traces to record (0..1).
+}
+
+var (
+	// active is the provider spans are recorded with; nil while tracing is disabled.
+	active atomic.Pointer[sdktrace.TracerProvider]
+	// disabled hands out non-recording spans that still carry their parent's span context.
+	disabled = noop.NewTracerProvider().Tracer(scopeName)
+	// propagator reads and writes traceparent headers whether or not tracing is enabled.
+	propagator = propagation.TraceContext{}
+)
+
+// Configure enables tracing with an OTLP/HTTP exporter and returns a shutdown function that
+// flushes pending spans. Calling it again replaces the previous setup.
+func Configure(opts Options) (func(context.Context) error, error) {
+	endpoint := strings.TrimRight(strings.TrimSpace(opts.Endpoint), "/")
+	if endpoint == "" {
+		return nil, fmt.Errorf("tracing: missing otlp endpoint")
+	}
+	if !strings.HasSuffix(endpoint, "/v1/traces") {
+		endpoint += "/v1/traces"
+	}
+	if parsed, errParse := url.Parse(endpoint); errParse != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("tracing: invalid otlp endpoint %q", opts.Endpoint)
+	}
+	serviceName := strings.TrimSpace(opts.ServiceName)
+	if serviceName == "" {
+		serviceName = "cpab"
+	}
+	ratio := opts.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+
+	exporterOpts := []otlptracehttp.Option{otlptracehttp.WithEndpointURL(endpoint)}
+	if len(opts.Headers) > 0 {
+		exporterOpts = append(exporterOpts, otlptracehttp.WithHeaders(opts.Headers))
+	}
+	exporter, errExporter := otlptracehttp.New(context.Background(), exporterOpts...)
+	if errExporter != nil {
+		return nil, fmt.Errorf("tracing: create otlp exporter: %w", errExporter)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+		// Child spans follow the sampling decision of their parent so every service in a trace agrees.
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+
+	if previous := active.Swap(provider); previous != nil {
+		_ = previous.Shutdown(context.Background())
+	}
+	return func(ctx context.Context) error {
+		active.CompareAndSwap(provider, nil)
+		return provider.Shutdown(ctx)
+	}, nil
+}
+
+// Disable stops recording spans. Pending spans of the previous provider are flushed in the background.
+func Disable() {
+	if previous := active.Swap(nil); previous != nil {
+		go func() { _ = previous.Shutdown(context.Background()) }()
+	}
+}
+
+// Enabled reports whether spans are being recorded.
+func Enabled() bool { return active.Load() != nil }
+
+// Start begins an internal span as a child of the active span in ctx. When tracing is
+// disabled the span records nothing; it is always safe to End.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+func start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	tracer := disabled
+	if provider := active.Load(); provider != nil {
+		tracer = provider.Tracer(scopeName)
+	}
+	return tracer.Start(ctx, name, opts...)
+}
+
+// RecordError marks the span as failed with err's message; nil errors are ignored.
+func RecordError(span trace.Span, err error) {
+	if span == nil || err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// TraceIDFromContext returns the hex trace ID of the active span, or "" when there is none.
+func TraceIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// Extract returns a copy of ctx carrying the span context found in the traceparent header.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	if header == nil {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// Inject writes the active span context in ctx to the traceparent header.
+func Inject(ctx context.Context, header http.Header) {
+	if header == nil {
+		return
+	}
+	propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
@@ -0,0 +1,180 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestStartIsNoopWhenDisabled(t *testing.T) {
+	Disable()
+	ctx, span := Start(context.Background(), "noop")
+	if span.IsRecording() {
+		t.Fatalf("expected a non-recording span when tracing is disabled")
+	}
+	span.SetAttributes(attribute.String("k", "v"))
+	RecordError(span, nil)
+	span.End()
+	if TraceIDFromContext(ctx) != "" {
+		t.Fatalf("expected no trace id when tracing is disabled")
+	}
+}
+
+func TestExtractWorksWhileDisabled(t *testing.T) {
+	Disable()
+	header := http.Header{}
+	header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := Extract(context.Background(), header)
+	if got := TraceIDFromContext(ctx); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("expected incoming trace id, got %q", got)
+	}
+	ctx, span := Start(ctx, "child")
+	defer span.End()
+	if got := TraceIDFromContext(ctx); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("expected disabled spans to keep the parent trace id, got %q", got)
+	}
+
+	header.Set(TraceparentHeader, "00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+	if got := TraceIDFromContext(Extract(context.Background(), header)); got != "" {
+		t.Fatalf("expected invalid traceparent to be ignored, got %q", got)
+	}
+}
+
+// collector captures OTLP/HTTP protobuf export requests.
+type collector struct {
+	mu       sync.Mutex
+	requests []*coltracepb.ExportTraceServiceRequest
+	headers  []http.Header
+	paths    []string
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	req := &coltracepb.ExportTraceServiceRequest{}
+	_ = proto.Unmarshal(body, req)
+	c.mu.Lock()
+	c.requests = append(c.requests, req)
+	c.headers = append(c.headers, r.Header.Clone())
+	c.paths = append(c.paths, r.URL.Path)
+	c.mu.Unlock()
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *collector) spans() []*tracepb.Span {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []*tracepb.Span
+	for _, req := range c.requests {
+		for _, rs := range req.GetResourceSpans() {
+			for _, ss := range rs.GetScopeSpans() {
+				out = append(out, ss.GetSpans()...)
+			}
+		}
+	}
+	return out
+}
+
+func TestExporterSendsParentedSpans(t *testing.T) {
+	sink := &collector{}
+	server := httptest.NewServer(sink)
+	defer server.Close()
+
+	shutdown, errConfigure := Configure(Options{Endpoint: server.URL, ServiceName: "cpab-test", Headers: map[string]string{"X-Tenant": "t1"}})
+	if errConfigure != nil {
+		t.Fatalf("Configure: %v", errConfigure)
+	}
+
+	ctx, parent := Start(context.Background(), "parent", attribute.String("cpab.provider", "codex"))
+	_, child := Start(ctx, "child", attribute.Int64("cpab.cost_micros", 42))
+	RecordError(child, io.ErrUnexpectedEOF)
+	child.End()
+	parent.End()
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if errShutdown := shutdown(flushCtx); errShutdown != nil {
+		t.Fatalf("shutdown: %v", errShutdown)
+	}
+	if Enabled() {
+		t.Fatalf("expected shutdown to disable tracing")
+	}
+
+	spans := sink.spans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 exported spans, got %d", len(spans))
+	}
+	byName := map[string]*tracepb.Span{}
+	for _, span := range spans {
+		byName[span.GetName()] = span
+	}
+	if hex.EncodeToString(byName["child"].GetTraceId()) != hex.EncodeToString(byName["parent"].GetTraceId()) ||
+		hex.EncodeToString(byName["child"].GetParentSpanId()) != hex.EncodeToString(byName["parent"].GetSpanId()) {
+		t.Fatalf("expected child to be parented to parent: %+v", spans)
+	}
+	if byName["child"].GetStatus().GetCode() != tracepb.Status_STATUS_CODE_ERROR {
+		t.Fatalf("expected child error status, got %+v", byName["child"].GetStatus())
+	}
+	if got := sink.headers[0].Get("X-Tenant"); got != "t1" {
+		t.Fatalf("expected exporter header, got %q", got)
+	}
+	if sink.paths[0] != "/v1/traces" {
+		t.Fatalf("expected export to /v1/traces, got %q", sink.paths[0])
+	}
+}
+
+func TestGinMiddlewareContinuesIncomingTrace(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sink := &collector{}
+	server := httptest.NewServer(sink)
+	defer server.Close()
+
+	shutdown, errConfigure := Configure(Options{Endpoint: server.URL})
+	if errConfigure != nil {
+		t.Fatalf("Configure: %v", errConfigure)
+	}
+	defer func() { _ = shutdown(context.Background()) }()
+
+	var seenTraceID string
+	engine := gin.New()
+	engine.Use(GinMiddleware())
+	engine.GET("/v0/front/balance", func(c *gin.Context) {
+		seenTraceID = TraceIDFromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v0/front/balance", nil)
+	req.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+
+	if seenTraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("expected handler to see incoming trace id, got %q", seenTraceID)
+	}
+	echoed := Extract(context.Background(), rec.Header())
+	if got := TraceIDFromContext(echoed); got != seenTraceID {
+		t.Fatalf("expected response traceparent for the incoming trace, got %q", rec.Header().Get(TraceparentHeader))
+	}
+	if rec.Header().Get(TraceparentHeader) == req.Header.Get(TraceparentHeader) {
+		t.Fatalf("expected response traceparent to name the server span")
+	}
+}
+
+func TestConfigureRejectsInvalidEndpoint(t *testing.T) {
+	for _, endpoint := range []string{"", "collector:4318"} {
+		if _, errConfigure := Configure(Options{Endpoint: endpoint}); errConfigure == nil {
+			t.Fatalf("expected endpoint %q to be rejected", endpoint)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"gorm.io/gorm"
@@ -70,7 +71,7 @@ func TestDeductBillBalanceRespectsUserGroupID(t *testing.T) {
 	amount := 5.0
 	costMicros := int64(amount * 1_000_000)
 	if errTx := conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		deducted, errDeduct := deductBillBalance(ctx, tx, user.ID, &group1.ID, amount, costMicros)
+		deducted, errDeduct := deductBillBalance(ctx, tx, user.ID, &group1.ID, amount, costMicros, billing.LedgerRef{Kind: models.BalanceTransactionKindUsage})
 		if errDeduct != nil {
 			return errDeduct
 		}
@@ -149,7 +150,7 @@ func TestDeductPrepaidBalanceRespectsUserGroupID(t *testing.T) {
 	}
 
 	if errTx := conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		return deductPrepaidBalance(ctx, tx, user.ID, &group1.ID, 5)
+		return deductPrepaidBalance(ctx, tx, user.ID, &group1.ID, 5, billing.LedgerRef{Kind: models.BalanceTransactionKindUsage})
 	}); errTx != nil {
 		t.Fatalf("transaction: %v", errTx)
 	}
@@ -168,4 +169,12 @@ func TestDeductPrepaidBalanceRespectsUserGroupID(t *testing.T) {
 	if updated2.Balance != 10 {
 		t.Fatalf("expected card2 balance=10, got %v", updated2.Balance)
 	}
+
+	var ledger []models.BalanceTransaction
+	if errFind := conn.Where("user_id = ?", user.ID).Find(&ledger).Error; errFind != nil {
+		t.Fatalf("load ledger: %v", errFind)
+	}
+	if len(ledger) != 1 || ledger[0].PrepaidCardID == nil || *ledger[0].PrepaidCardID != card1.ID || ledger[0].Amount != -5 || ledger[0].BalanceAfter != 5 {
+		t.Fatalf("expected one ledger debit of card1, got %+v", ledger)
+	}
 }
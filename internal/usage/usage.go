@@ -3,23 +3,37 @@ package usage
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
-	"math"
+	"fmt"
 	"net/http"
+	"reflect"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/canary"
 	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/logging"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/metrics"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelmapping"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/proxyref"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/ratelimit"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/retryafter"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/tracing"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/usageerror"
 
 	"github.com/gin-gonic/gin"
 	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -27,115 +41,307 @@ import (
 
 // GormUsagePlugin persists usage records and applies billing deductions.
 type GormUsagePlugin struct {
-	db *gorm.DB
+	db     *gorm.DB
+	writer atomic.Pointer[usageWriter]
 }
 
 // NewGormUsagePlugin constructs a GormUsagePlugin backed by GORM.
 func NewGormUsagePlugin(db *gorm.DB) *GormUsagePlugin { return &GormUsagePlugin{db: db} }
 
 // HandleUsage records usage data and deducts bill or prepaid balances.
+// When the async writer is running the record is queued and persisted in batches;
+// otherwise, or when the queue is full, it is persisted before returning.
 func (p *GormUsagePlugin) HandleUsage(ctx context.Context, record coreusage.Record) {
 	if p == nil || p.db == nil {
 		return
 	}
 
+	ctx, span := tracing.Start(usageTraceContext(ctx), "usage.HandleUsage",
+		attribute.String("cpab.provider", strings.TrimSpace(record.Provider)),
+		attribute.String("cpab.model", strings.TrimSpace(record.Model)),
+		attribute.Bool("cpab.failed", record.Failed),
+	)
+	defer span.End()
+
+	entry := prepareUsageEntry(ctx, record)
+	debitTokenBudgets(ctx, entry)
+	w := p.writer.Load()
+	if w != nil && w.enqueue(entry) {
+		span.SetAttributes(attribute.Bool("cpab.async", true))
+		return
+	}
+	errPersist := p.persistUsageEntries([]*usageEntry{entry})
+	if errPersist != nil {
+		tracing.RecordError(span, errPersist)
+	}
+	if w != nil {
+		w.settle(entry, errPersist)
+		return
+	}
+	if errPersist != nil {
+		log.WithError(errPersist).Warn("usage plugin: failed to persist usage or deduct balance")
+	}
+}
+
+// usageEntry is a usage record with every request-scoped value captured, so it can be
+// persisted after the request context is gone.
+type usageEntry struct {
+	ctx                context.Context // Detached context carrying the request's trace span.
+	record             coreusage.Record
+	provider           string
+	model              string
+	apiKeyID           *uint64
+	userID             *uint64
+	billingUserGroupID *uint64
+	teamID             *uint64
+	teamMemberID       *uint64
+	providerAPIKeyID   *uint64
+	authKey            string
+	requestID          string
+	errorStatusCode    *int
+	errorDetail        datatypes.JSON
+	errorCode          string
+	retryAfterSeconds  int
+	idempotencyKey     string    // Stable across outbox replays; empty when none could be generated.
+	completedAt        time.Time // When the request finished; the row's created_at is its insert time.
+	seq                uint64    // Outbox journal sequence; 0 when the entry is not journaled.
+}
+
+// prepareUsageEntry captures the request-scoped parts of a usage record without touching the database.
+func prepareUsageEntry(ctx context.Context, record coreusage.Record) *usageEntry {
 	meta := accessMetadataFromContext(ctx)
 
-	dbCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	entry := &usageEntry{
+		ctx:            trace.ContextWithRemoteSpanContext(context.Background(), trace.SpanContextFromContext(ctx)),
+		record:         record,
+		authKey:        strings.TrimSpace(record.AuthID),
+		idempotencyKey: newUsageIdempotencyKey(),
+		completedAt:    time.Now().UTC(),
+	}
 
-	var apiKeyID *uint64
 	if rawID := strings.TrimSpace(meta["api_key_id"]); rawID != "" {
 		parsed, errParseUint := strconv.ParseUint(rawID, 10, 64)
 		if errParseUint == nil {
 			parsedID := parsed
-			apiKeyID = &parsedID
+			entry.apiKeyID = &parsedID
 		}
 	}
-
-	var userID *uint64
 	if rawID := strings.TrimSpace(meta["user_id"]); rawID != "" {
 		parsed, errParseUint := strconv.ParseUint(rawID, 10, 64)
 		if errParseUint == nil {
 			parsedID := parsed
-			userID = &parsedID
+			entry.userID = &parsedID
 		}
 	}
-
-	var billingUserGroupID *uint64
+	entry.teamID = parseMetaID(meta["team_id"])
+	entry.teamMemberID = parseMetaID(meta["team_member_id"])
+	entry.providerAPIKeyID = canary.KeyIDForAuth(entry.authKey)
 	if rawID := strings.TrimSpace(meta["billing_user_group_id"]); rawID != "" {
 		parsed, errParseUint := strconv.ParseUint(rawID, 10, 64)
 		if errParseUint == nil && parsed != 0 {
 			parsedID := parsed
-			billingUserGroupID = &parsedID
+			entry.billingUserGroupID = &parsedID
 		}
 	}
 
-	authKey := strings.TrimSpace(record.AuthID)
-	authID := resolveAuthRecordID(dbCtx, p.db, authKey)
+	entry.provider = strings.TrimSpace(record.Provider)
+	entry.model = strings.TrimSpace(record.Model)
+	if mappedModel, ok := modelmapping.LookupMappedModelName(entry.provider, entry.model); ok {
+		entry.model = mappedModel
+	}
+	entry.record.Provider = entry.provider
+	entry.record.Model = entry.model
 
-	totalTokens := record.Detail.TotalTokens
-	if totalTokens == 0 {
-		totalTokens = record.Detail.InputTokens + record.Detail.OutputTokens + record.Detail.ReasoningTokens
+	entry.errorStatusCode, entry.errorDetail, entry.errorCode, entry.retryAfterSeconds = buildUsageErrorDetail(ctx, record)
+
+	// Fall back to the trace ID so usage rows can be joined with traces when no request ID was assigned.
+	entry.requestID = requestIDFromContext(ctx)
+	if entry.requestID == "" {
+		entry.requestID = tracing.TraceIDFromContext(ctx)
+	}
+	return entry
+}
+
+// newUsageIdempotencyKey returns a random key identifying one usage record, or "" when the
+// random source fails; such a record is then persisted without replay protection.
+func newUsageIdempotencyKey() string {
+	buf := make([]byte, 16)
+	if _, errRead := rand.Read(buf); errRead != nil {
+		log.WithError(errRead).Warn("usage plugin: generate idempotency key failed")
+		return ""
 	}
+	return hex.EncodeToString(buf)
+}
+
+// parseMetaID parses a non-zero ID from access metadata.
+func parseMetaID(raw string) *uint64 {
+	parsed, errParseUint := strconv.ParseUint(strings.TrimSpace(raw), 10, 64)
+	if errParseUint != nil || parsed == 0 {
+		return nil
+	}
+	return &parsed
+}
 
-	provider := strings.TrimSpace(record.Provider)
-	model := strings.TrimSpace(record.Model)
-	if mappedModel, ok := modelmapping.LookupMappedModelName(provider, model); ok {
-		model = mappedModel
+// debitTokenBudgets charges the request's tokens against its API key and user group
+// per-minute token budgets.
+func debitTokenBudgets(ctx context.Context, entry *usageEntry) {
+	if entry == nil || entry.apiKeyID == nil {
+		return
 	}
+	detail := entry.record.Detail
+	tokens := detail.TotalTokens
+	if tokens == 0 {
+		tokens = detail.InputTokens + detail.OutputTokens + detail.ReasoningTokens
+	}
+	var userID uint64
+	if entry.userID != nil {
+		userID = *entry.userID
+	}
+	ratelimit.Default().DebitTokens(ctx, *entry.apiKeyID, userID, tokens)
+}
 
-	recordForBilling := record
-	recordForBilling.Provider = provider
-	recordForBilling.Model = model
+// buildUsageRow resolves the auth record and prices the entry.
+func (p *GormUsagePlugin) buildUsageRow(ctx context.Context, entry *usageEntry) models.Usage {
+	record := entry.record
+	authID, proxyURL := resolveAuthRecord(ctx, p.db, entry.authKey)
+
+	totalTokens := record.Detail.TotalTokens
+	if totalTokens == 0 {
+		totalTokens = record.Detail.InputTokens + record.Detail.OutputTokens + record.Detail.ReasoningTokens
+	}
 
-	costMicros := calculateCost(dbCtx, p.db, apiKeyID, userID, authID, billingUserGroupID, recordForBilling)
-	amountToDeduct := float64(costMicros) / 1_000_000
+	costMicros, billingRuleID, footprint := calculateCost(ctx, p.db, entry.apiKeyID, entry.userID, authID, entry.billingUserGroupID, record)
 
-	errorStatusCode, errorDetail := buildUsageErrorDetail(ctx, record)
+	var idempotencyKey *string
+	if entry.idempotencyKey != "" {
+		key := entry.idempotencyKey
+		idempotencyKey = &key
+	}
 
-	row := models.Usage{
-		Provider:        provider,
-		Model:           model,
+	return models.Usage{
+		Provider:        entry.provider,
+		Model:           entry.model,
 		VariantOrigin:   strings.TrimSpace(record.VariantOrigin),
 		Variant:         strings.TrimSpace(record.Variant),
-		UserID:          userID,
-		UserGroupID:     billingUserGroupID,
-		APIKeyID:        apiKeyID,
+		UserID:          entry.userID,
+		UserGroupID:     entry.billingUserGroupID,
+		APIKeyID:        entry.apiKeyID,
+		TeamID:          entry.teamID,
+		TeamMemberID:    entry.teamMemberID,
 		AuthID:          authID,
-		AuthKey:         authKey,
+		AuthKey:         entry.authKey,
 		AuthIndex:       strings.TrimSpace(record.AuthIndex),
-		RequestID:       requestIDFromContext(ctx),
+		RequestID:       entry.requestID,
+		IdempotencyKey:  idempotencyKey,
 		Source:          strings.TrimSpace(record.Source),
+		ProxyHash:       proxyref.Hash(proxyURL),
+		ProxyLabel:      proxyref.Label(proxyURL),
 		RequestedAt:     normalizeTime(record.RequestedAt),
 		Failed:          record.Failed,
-		ErrorStatusCode: errorStatusCode,
-		ErrorDetail:     errorDetail,
+		ErrorStatusCode: entry.errorStatusCode,
+		ErrorDetail:     entry.errorDetail,
+		ErrorCode:       entry.errorCode,
+
+		RetryAfterSeconds: entry.retryAfterSeconds,
+		ProviderAPIKeyID:  entry.providerAPIKeyID,
+
 		InputTokens:     record.Detail.InputTokens,
 		OutputTokens:    record.Detail.OutputTokens,
 		ReasoningTokens: record.Detail.ReasoningTokens,
 		CachedTokens:    record.Detail.CachedTokens,
 		TotalTokens:     totalTokens,
 		CostMicros:      costMicros,
-		ChargedTo:       "none",
-		CreatedAt:       time.Now().UTC(),
+		BillingRuleID:   billingRuleID,
+
+		CacheCreationTokens: cacheCreationTokens(record.Detail),
+
+		EnergyMilliWh:    footprint.EnergyMilliWh,
+		CarbonMilligrams: footprint.CarbonMilligrams,
+
+		ChargedTo: "none",
+	}
+}
+
+// persistTimeout bounds the transaction writing n usage entries.
+func persistTimeout(n int) time.Duration {
+	return 5*time.Second + time.Duration(n)*100*time.Millisecond
+}
+
+// persistUsageEntries inserts the entries' usage rows and applies their deductions in one transaction.
+func (p *GormUsagePlugin) persistUsageEntries(entries []*usageEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	ctx, span := tracing.Start(entries[0].ctx, "usage.persistUsageEntries", attribute.Int64("cpab.batch_size", int64(len(entries))))
+	defer span.End()
+
+	dbCtx, cancel := context.WithTimeout(ctx, persistTimeout(len(entries)))
+	defer cancel()
+
+	rows := make([]models.Usage, len(entries))
+	for i, entry := range entries {
+		entryCtx := dbCtx
+		if sc := trace.SpanContextFromContext(entry.ctx); i > 0 && sc.IsValid() {
+			entryCtx = trace.ContextWithRemoteSpanContext(dbCtx, sc)
+		}
+		rows[i] = p.buildUsageRow(entryCtx, entry)
 	}
+	pending := pendingTodayMicros(rows, time.Now())
 
+	var crossings []billQuotaCrossing
+	replayed := make([]bool, len(rows))
 	if errTx := p.db.WithContext(dbCtx).Transaction(func(tx *gorm.DB) error {
-		if errCreate := tx.Create(&row).Error; errCreate != nil {
-			return errCreate
+		crossings = crossings[:0]
+		// An outbox replay of a record whose commit was never acknowledged finds its row
+		// already stored; it is neither inserted nor charged again.
+		stored, errStored := storedIdempotencyKeys(tx, rows)
+		if errStored != nil {
+			return errStored
+		}
+		// created_at is the insert time, so rows committed by retries or outbox replays still
+		// fall inside the usage stream's settle window relative to their ID.
+		insertedAt := time.Now().UTC()
+		inserts := make([]*models.Usage, 0, len(rows))
+		for i := range rows {
+			rows[i].CreatedAt = insertedAt
+			replayed[i] = rows[i].IdempotencyKey != nil && stored[*rows[i].IdempotencyKey]
+			if !replayed[i] {
+				inserts = append(inserts, &rows[i])
+			}
+		}
+		if len(inserts) > 0 {
+			result := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "idempotency_key"}},
+				DoNothing: true,
+			}).CreateInBatches(inserts, len(inserts))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected != int64(len(inserts)) {
+				return errUsageConcurrentReplay
+			}
 		}
 
-		if amountToDeduct > 0 && row.UserID != nil {
+		for i := range rows {
+			if replayed[i] {
+				continue
+			}
+			row := &rows[i]
+			amountToDeduct := float64(row.CostMicros) / 1_000_000
+			if amountToDeduct <= 0 || row.UserID == nil {
+				continue
+			}
 			chargedTo := "none"
-			deducted, errDeductBill := deductBillBalance(dbCtx, tx, *row.UserID, billingUserGroupID, amountToDeduct, costMicros)
+			usageID := row.ID
+			ref := billing.LedgerRef{Kind: models.BalanceTransactionKindUsage, UsageID: &usageID}
+			deducted, errDeductBill := deductBillBalanceTracked(dbCtx, tx, *row.UserID, row.UserGroupID, amountToDeduct, pending[i], ref, &crossings)
 			if errDeductBill != nil {
 				return errDeductBill
 			}
 			if deducted {
 				chargedTo = "bill"
 			} else {
-				if errDeductPrepaid := deductPrepaidBalance(dbCtx, tx, *row.UserID, billingUserGroupID, amountToDeduct); errDeductPrepaid != nil {
+				if errDeductPrepaid := deductPrepaidBalance(dbCtx, tx, *row.UserID, row.UserGroupID, amountToDeduct, ref); errDeductPrepaid != nil {
 					return errDeductPrepaid
 				}
 				chargedTo = "prepaid"
@@ -147,11 +353,174 @@ func (p *GormUsagePlugin) HandleUsage(ctx context.Context, record coreusage.Reco
 				Update("charged_to", chargedTo).Error; errUpdate != nil {
 				return errUpdate
 			}
+			row.ChargedTo = chargedTo
 		}
 		return nil
 	}); errTx != nil {
-		log.WithError(errTx).Warn("usage plugin: failed to persist usage or deduct balance")
+		tracing.RecordError(span, errTx)
+		return errTx
+	}
+
+	for i := range rows {
+		if replayed[i] {
+			continue
+		}
+		row := &rows[i]
+		metrics.ObserveUsage(metrics.UsageSample{
+			Provider:        row.Provider,
+			Model:           row.Model,
+			Failed:          row.Failed,
+			InputTokens:     row.InputTokens,
+			OutputTokens:    row.OutputTokens,
+			ReasoningTokens: row.ReasoningTokens,
+			CachedTokens:    row.CachedTokens,
+			CostMicros:      row.CostMicros,
+			Duration:        entries[i].completedAt.Sub(row.RequestedAt),
+		})
+		if row.CostMicros > 0 {
+			metrics.ObserveBillingDeduction(row.ChargedTo, row.CostMicros)
+		}
+		publishUsageRecorded(entries[i].ctx, row)
 	}
+	for _, crossing := range crossings {
+		publishBillQuotaCrossing(ctx, crossing)
+	}
+	if len(rows) == 1 {
+		span.SetAttributes(
+			attribute.Int64("cpab.usage_id", int64(rows[0].ID)),
+			attribute.String("cpab.request_id", rows[0].RequestID),
+			attribute.Int64("cpab.cost_micros", rows[0].CostMicros),
+			attribute.String("cpab.charged_to", rows[0].ChargedTo),
+		)
+	}
+	return nil
+}
+
+// errUsageConcurrentReplay reports that a record of the batch was stored by another writer
+// between the idempotency lookup and the insert. The batch rolls back and its retry skips it.
+var errUsageConcurrentReplay = errors.New("usage plugin: usage record stored concurrently")
+
+// storedIdempotencyKeys returns the idempotency keys of rows that are already stored.
+func storedIdempotencyKeys(tx *gorm.DB, rows []models.Usage) (map[string]bool, error) {
+	keys := make([]string, 0, len(rows))
+	for i := range rows {
+		if rows[i].IdempotencyKey != nil {
+			keys = append(keys, *rows[i].IdempotencyKey)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	var found []string
+	if errFind := tx.Model(&models.Usage{}).
+		Where("idempotency_key IN ?", keys).
+		Pluck("idempotency_key", &found).Error; errFind != nil {
+		return nil, errFind
+	}
+	stored := make(map[string]bool, len(found))
+	for _, key := range found {
+		stored[key] = true
+	}
+	return stored, nil
+}
+
+// pendingTodayMicros returns, for each row, the cost of that row plus every later row of the
+// batch counted in the same daily total. Bill deduction subtracts it from today's usage sum,
+// which already includes the whole batch, to recover the usage charged before the row.
+func pendingTodayMicros(rows []models.Usage, now time.Time) []int64 {
+	localNow := now.In(time.Local)
+	todayStart := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, time.Local)
+	pending := make([]int64, len(rows))
+	for i := range rows {
+		pending[i] = rows[i].CostMicros
+		if rows[i].UserID == nil {
+			continue
+		}
+		for j := i + 1; j < len(rows); j++ {
+			later := &rows[j]
+			if later.UserID == nil || *later.UserID != *rows[i].UserID || later.RequestedAt.Before(todayStart) {
+				continue
+			}
+			if rows[i].UserGroupID != nil && *rows[i].UserGroupID != 0 &&
+				(later.UserGroupID == nil || *later.UserGroupID != *rows[i].UserGroupID) {
+				continue
+			}
+			pending[i] += later.CostMicros
+		}
+	}
+	return pending
+}
+
+// publishUsageRecorded emits a usage.recorded event for a persisted usage row.
+func publishUsageRecorded(ctx context.Context, row *models.Usage) {
+	if row == nil {
+		return
+	}
+	data := map[string]any{
+		"usage_id":     row.ID,
+		"provider":     row.Provider,
+		"model":        row.Model,
+		"failed":       row.Failed,
+		"total_tokens": row.TotalTokens,
+		"cost_micros":  row.CostMicros,
+		"charged_to":   row.ChargedTo,
+	}
+	subject := ""
+	if row.UserID != nil {
+		data["user_id"] = *row.UserID
+		subject = "user:" + strconv.FormatUint(*row.UserID, 10)
+	}
+	if row.APIKeyID != nil {
+		data["api_key_id"] = *row.APIKeyID
+	}
+	events.Publish(ctx, events.Event{
+		Type:       events.TypeUsageRecorded,
+		Subject:    subject,
+		Data:       data,
+		OccurredAt: row.RequestedAt,
+	})
+}
+
+// publishBillQuotaCrossing emits a bill threshold or exhaustion event.
+func publishBillQuotaCrossing(ctx context.Context, crossing billQuotaCrossing) {
+	event := events.Event{
+		Type:     events.TypeBillQuotaThreshold,
+		Severity: events.SeverityInfo,
+		Subject:  "user:" + strconv.FormatUint(crossing.userID, 10),
+		Message:  fmt.Sprintf("bill %d has used %.0f%% of its quota", crossing.billID, billQuotaThreshold*100),
+		Data: map[string]any{
+			"user_id":     crossing.userID,
+			"bill_id":     crossing.billID,
+			"total_quota": crossing.total,
+			"left_quota":  crossing.left,
+		},
+	}
+	if crossing.exhausted {
+		event.Type = events.TypeBillExhausted
+		event.Severity = events.SeverityWarning
+		event.Message = fmt.Sprintf("bill %d has used all of its quota", crossing.billID)
+	} else {
+		event.Data["threshold"] = billQuotaThreshold
+	}
+	events.Publish(ctx, event)
+}
+
+// usageTraceContext returns ctx parented to the span of the proxied request, if one is known.
+func usageTraceContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil || ginCtx.Request == nil {
+		return ctx
+	}
+	if sc := trace.SpanContextFromContext(ginCtx.Request.Context()); sc.IsValid() {
+		return trace.ContextWithRemoteSpanContext(ctx, sc)
+	}
+	return tracing.Extract(ctx, ginCtx.Request.Header)
 }
 
 func requestIDFromContext(ctx context.Context) string {
@@ -166,39 +535,74 @@ func requestIDFromContext(ctx context.Context) string {
 	return strings.TrimSpace(logging.GetRequestID(ctx))
 }
 
-// resolveAuthRecordID looks up the auth record ID by key.
-func resolveAuthRecordID(ctx context.Context, db *gorm.DB, authKey string) *uint64 {
+// resolveAuthRecord looks up the auth record ID and proxy URL by key.
+func resolveAuthRecord(ctx context.Context, db *gorm.DB, authKey string) (*uint64, string) {
 	authKey = strings.TrimSpace(authKey)
 	if authKey == "" || db == nil {
-		return nil
+		return nil, ""
 	}
 
-	// row holds the auth ID lookup result.
+	// row holds the auth lookup result.
 	var row struct {
-		ID uint64
+		ID       uint64
+		ProxyURL *string
 	}
 	errFirst := db.WithContext(ctx).Model(&models.Auth{}).
-		Select("id").
-		Where("key = ?", authKey).
+		Select("id", "proxy_url").
+		Where("? = ?", clause.Column{Name: "key"}, authKey).
 		Take(&row).Error
 	if errFirst != nil {
 		if errors.Is(errFirst, gorm.ErrRecordNotFound) {
-			return nil
+			return nil, ""
 		}
-		return nil
+		return nil, ""
 	}
 	if row.ID == 0 {
-		return nil
+		return nil, ""
 	}
 	id := row.ID
-	return &id
+	proxyURL := ""
+	if row.ProxyURL != nil {
+		proxyURL = *row.ProxyURL
+	}
+	return &id, proxyURL
 }
 
 // billQuotaEpsilon defines a tolerance for quota comparisons.
 const billQuotaEpsilon = 0.000001
 
-// deductBillBalance deducts usage from active bills and updates quotas.
-func deductBillBalance(ctx context.Context, tx *gorm.DB, userID uint64, userGroupID *uint64, amount float64, costMicros int64) (bool, error) {
+// billQuotaThreshold is the used share of a bill's quota that raises a threshold event.
+const billQuotaThreshold = 0.8
+
+// billQuotaCrossing records a bill whose quota crossed the threshold or ran out during a
+// deduction; events are published once the deduction commits.
+type billQuotaCrossing struct {
+	userID    uint64
+	billID    uint64
+	total     float64
+	left      float64
+	exhausted bool
+}
+
+// deductBillBalance deducts usage from active bills, updates quotas and records each bill
+// debit in the ledger under ref.
+func deductBillBalance(ctx context.Context, tx *gorm.DB, userID uint64, userGroupID *uint64, amount float64, costMicros int64, ref billing.LedgerRef) (bool, error) {
+	return deductBillBalanceTracked(ctx, tx, userID, userGroupID, amount, costMicros, ref, nil)
+}
+
+// deductBillBalanceTracked is deductBillBalance that also appends quota crossings to crossings
+// when it is not nil.
+func deductBillBalanceTracked(ctx context.Context, tx *gorm.DB, userID uint64, userGroupID *uint64, amount float64, costMicros int64, ref billing.LedgerRef, crossings *[]billQuotaCrossing) (deducted bool, err error) {
+	ctx, span := tracing.Start(ctx, "billing.deductBillBalance",
+		attribute.Int64("cpab.user_id", int64(userID)),
+		attribute.Float64("cpab.amount", amount),
+	)
+	defer func() {
+		span.SetAttributes(attribute.Bool("cpab.deducted", deducted))
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
 	if tx == nil {
 		return false, errors.New("nil tx")
 	}
@@ -282,6 +686,19 @@ func deductBillBalance(ctx context.Context, tx *gorm.DB, userID uint64, userGrou
 		if res.Error != nil {
 			return false, res.Error
 		}
+		leftAfter := bill.LeftQuota - deduct
+		if _, errRecord := billing.RecordBillTransaction(ctx, tx, userID, bill.ID, -deduct, leftAfter, ref); errRecord != nil {
+			return false, errRecord
+		}
+		if crossings != nil && bill.TotalQuota > 0 {
+			thresholdLeft := bill.TotalQuota * (1 - billQuotaThreshold)
+			switch {
+			case leftAfter <= billQuotaEpsilon:
+				*crossings = append(*crossings, billQuotaCrossing{userID: userID, billID: bill.ID, total: bill.TotalQuota, exhausted: true})
+			case bill.LeftQuota > thresholdLeft && leftAfter <= thresholdLeft:
+				*crossings = append(*crossings, billQuotaCrossing{userID: userID, billID: bill.ID, total: bill.TotalQuota, left: leftAfter})
+			}
+		}
 		remaining -= deduct
 	}
 	if remaining > billQuotaEpsilon {
@@ -333,8 +750,18 @@ func refreshBillUserGroupIDs(ctx context.Context, tx *gorm.DB, userID uint64) er
 		Update("bill_user_group_id", merged.Clean()).Error
 }
 
-// deductPrepaidBalance deducts usage from prepaid cards in priority order.
-func deductPrepaidBalance(ctx context.Context, tx *gorm.DB, userID uint64, userGroupID *uint64, amount float64) error {
+// deductPrepaidBalance deducts usage from prepaid cards in priority order and records each
+// card debit in the ledger under ref.
+func deductPrepaidBalance(ctx context.Context, tx *gorm.DB, userID uint64, userGroupID *uint64, amount float64, ref billing.LedgerRef) (err error) {
+	ctx, span := tracing.Start(ctx, "billing.deductPrepaidBalance",
+		attribute.Int64("cpab.user_id", int64(userID)),
+		attribute.Float64("cpab.amount", amount),
+	)
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
 	if tx == nil {
 		return errors.New("nil tx")
 	}
@@ -347,7 +774,9 @@ func deductPrepaidBalance(ctx context.Context, tx *gorm.DB, userID uint64, userG
 		Clauses(clause.Locking{Strength: "UPDATE"}).
 		Where("redeemed_user_id = ? AND is_enabled = ? AND balance > 0 AND redeemed_at IS NOT NULL", userID, true).
 		Where("(expires_at IS NULL OR expires_at >= ?)", now).
-		Order("expires_at ASC NULLS LAST, redeemed_at ASC NULLS LAST, id ASC").
+		Order(dbutil.NullsLastOrder(tx, "expires_at", "ASC")).
+		Order(dbutil.NullsLastOrder(tx, "redeemed_at", "ASC")).
+		Order("id ASC").
 		Model(&models.PrepaidCard{})
 	if userGroupID != nil && *userGroupID != 0 {
 		q = q.Where("user_group_id = ?", *userGroupID)
@@ -375,6 +804,9 @@ func deductPrepaidBalance(ctx context.Context, tx *gorm.DB, userID uint64, userG
 		if res.Error != nil {
 			return res.Error
 		}
+		if _, errRecord := billing.RecordPrepaidTransaction(ctx, tx, userID, card.ID, -deduct, card.Balance-deduct, ref); errRecord != nil {
+			return errRecord
+		}
 		remaining -= deduct
 	}
 
@@ -428,16 +860,23 @@ func accessMetadataFromContext(ctx context.Context) map[string]string {
 }
 
 type usageErrorDetail struct {
-	StatusCode   int    `json:"status_code"`
-	Message      string `json:"message"`
-	ResponseBody any    `json:"response_body,omitempty"`
+	StatusCode        int    `json:"status_code"`
+	Code              string `json:"code"`
+	Message           string `json:"message"`
+	RetryAfterSeconds int    `json:"retry_after_seconds,omitempty"`
+	ResponseBody      any    `json:"response_body,omitempty"`
+	// RateLimitHeaders keeps the upstream quota and rate-limit headers of a 429 so the auth
+	// cooldown scheduler can derive the back-off window.
+	RateLimitHeaders map[string]string `json:"rate_limit_headers,omitempty"`
 }
 
-func buildUsageErrorDetail(ctx context.Context, record coreusage.Record) (*int, datatypes.JSON) {
-	statusCode, hasStatus, responseBody := extractUsageErrorContext(ctx)
+// buildUsageErrorDetail returns the error status, structured detail, canonical error code
+// and, for 429 responses, the sanitized Retry-After seconds sent to the client.
+func buildUsageErrorDetail(ctx context.Context, record coreusage.Record) (*int, datatypes.JSON, string, int) {
+	statusCode, hasStatus, responseBody, header := extractUsageErrorContext(ctx)
 	failed := record.Failed
 	if !failed && (!hasStatus || statusCode < http.StatusBadRequest) {
-		return nil, nil
+		return nil, nil, "", 0
 	}
 	if !hasStatus || statusCode == 0 {
 		statusCode = http.StatusInternalServerError
@@ -450,8 +889,15 @@ func buildUsageErrorDetail(ctx context.Context, record coreusage.Record) (*int,
 
 	detail := usageErrorDetail{
 		StatusCode: statusCode,
+		Code:       usageerror.Classify(statusCode, message, responseBody),
 		Message:    message,
 	}
+	if statusCode == http.StatusTooManyRequests {
+		if seconds, ok := retryafter.Resolve(header.Get(retryafter.Header), responseBody, time.Now()); ok {
+			detail.RetryAfterSeconds = seconds
+		}
+		detail.RateLimitHeaders = retryafter.RateLimitHeaders(header)
+	}
 	if len(responseBody) > 0 {
 		if json.Valid(responseBody) {
 			detail.ResponseBody = json.RawMessage(responseBody)
@@ -462,25 +908,26 @@ func buildUsageErrorDetail(ctx context.Context, record coreusage.Record) (*int,
 
 	payload, errMarshal := json.Marshal(detail)
 	if errMarshal != nil {
-		return nil, nil
+		return nil, nil, "", 0
 	}
 	statusValue := statusCode
-	return &statusValue, datatypes.JSON(payload)
+	return &statusValue, datatypes.JSON(payload), detail.Code, detail.RetryAfterSeconds
 }
 
-func extractUsageErrorContext(ctx context.Context) (int, bool, []byte) {
+func extractUsageErrorContext(ctx context.Context) (int, bool, []byte, http.Header) {
 	if ctx == nil {
-		return 0, false, nil
+		return 0, false, nil, http.Header{}
 	}
 	ginCtx, ok := ctx.Value("gin").(*gin.Context)
 	if !ok || ginCtx == nil {
-		return 0, false, nil
+		return 0, false, nil, http.Header{}
 	}
+	header := ginCtx.Writer.Header().Clone()
 	statusCode := ginCtx.Writer.Status()
 	if statusCode == 0 {
-		return 0, false, extractAPIResponse(ginCtx)
+		return 0, false, extractAPIResponse(ginCtx), header
 	}
-	return statusCode, true, extractAPIResponse(ginCtx)
+	return statusCode, true, extractAPIResponse(ginCtx), header
 }
 
 func extractAPIResponse(ctx *gin.Context) []byte {
@@ -527,6 +974,19 @@ func extractErrorMessage(responseBody []byte) string {
 	return ""
 }
 
+// cacheCreationTokens reads the cache-write token count from a usage detail. Only newer
+// SDK builds report it, so the field is looked up by name and read as zero when absent.
+func cacheCreationTokens(detail coreusage.Detail) int64 {
+	field := reflect.ValueOf(detail).FieldByName("CacheCreationTokens")
+	if !field.IsValid() || !field.CanInt() {
+		return 0
+	}
+	if tokens := field.Int(); tokens > 0 {
+		return tokens
+	}
+	return 0
+}
+
 // normalizeTime returns a UTC timestamp, defaulting to now if zero.
 func normalizeTime(t time.Time) time.Time {
 	if t.IsZero() {
@@ -535,156 +995,46 @@ func normalizeTime(t time.Time) time.Time {
 	return t.UTC()
 }
 
-// calculateCost computes usage cost in micros based on billing rules.
-func calculateCost(ctx context.Context, db *gorm.DB, apiKeyID, userID, authID, billingUserGroupID *uint64, record coreusage.Record) int64 {
+// calculateCost computes usage cost in micros, the ID of the billing rule that priced it
+// and the footprint estimated from that rule's factors.
+func calculateCost(ctx context.Context, db *gorm.DB, apiKeyID, userID, authID, billingUserGroupID *uint64, record coreusage.Record) (int64, *uint64, billing.Footprint) {
 	if db == nil {
-		return 0
-	}
-	if record.Failed {
-		return 0
-	}
-
-	provider := strings.TrimSpace(record.Provider)
-	model := strings.TrimSpace(record.Model)
-	if provider == "" || model == "" {
-		return 0
-	}
-	providerLower := strings.ToLower(provider)
-
-	var authGroupID *uint64
-	if authID != nil {
-		var auth models.Auth
-		if errFindAuth := db.WithContext(ctx).Select("auth_group_id").First(&auth, *authID).Error; errFindAuth == nil {
-			authGroupID = auth.AuthGroupID.Primary()
-		}
-	}
-
-	userGroupID := billingUserGroupID
-	if userGroupID == nil && apiKeyID != nil {
-		var apiKey models.APIKey
-		if errFindAPIKey := db.WithContext(ctx).Select("user_id").First(&apiKey, *apiKeyID).Error; errFindAPIKey == nil && apiKey.UserID != nil {
-			var user models.User
-			if errFindUser := db.WithContext(ctx).Select("user_group_id").First(&user, *apiKey.UserID).Error; errFindUser == nil {
-				userGroupID = user.UserGroupID.Primary()
-			}
-		}
-	}
-	if userGroupID == nil && userID != nil {
-		var user models.User
-		if errFindUser := db.WithContext(ctx).Select("user_group_id").First(&user, *userID).Error; errFindUser == nil {
-			userGroupID = user.UserGroupID.Primary()
-		}
-	}
-
-	costFromRule := func(rule *models.BillingRule) int64 {
-		if rule == nil {
-			return 0
-		}
-
-		switch rule.BillingType {
-		case models.BillingTypePerRequest:
-			if rule.PricePerRequest == nil {
-				return 0
-			}
-			return int64(math.Round(*rule.PricePerRequest * 1_000_000))
-		case models.BillingTypePerToken:
-			var total float64
-			// Many upstream providers (e.g. OpenAI usage format) report CachedTokens as a subset of
-			// InputTokens (input_tokens_details.cached_tokens). If we charge InputTokens in full AND
-			// charge CachedTokens again as cache-read, cache hit tokens would be double-charged.
-			//
-			// To make per-token billing consistent across providers, we treat billable input tokens as:
-			//   billableInput = max(InputTokens - CachedTokens, 0)
-			// and charge CachedTokens separately via PriceCacheReadToken.
-			billableInputTokens := record.Detail.InputTokens
-			if record.Detail.CachedTokens > 0 && record.Detail.CachedTokens <= billableInputTokens {
-				billableInputTokens -= record.Detail.CachedTokens
-			}
-			if rule.PriceInputToken != nil {
-				total += float64(billableInputTokens) * (*rule.PriceInputToken)
-			}
-			if rule.PriceOutputToken != nil {
-				total += float64(record.Detail.OutputTokens) * (*rule.PriceOutputToken)
-			}
-			if rule.PriceCacheCreateToken != nil {
-				total += float64(0) * (*rule.PriceCacheCreateToken)
-			}
-			if rule.PriceCacheReadToken != nil {
-				total += float64(record.Detail.CachedTokens) * (*rule.PriceCacheReadToken)
-			}
-			// Token prices are per 1,000,000 tokens, so micros = price_per_million * tokens
-			return int64(math.Round(total))
-		default:
-			return 0
-		}
-	}
-
-	loadCandidateRules := func(primaryAuthGroupID, primaryUserGroupID, defaultAuthGroupID, defaultUserGroupID uint64) ([]models.BillingRule, error) {
-		q := db.WithContext(ctx).Model(&models.BillingRule{}).Where("is_enabled = true")
-		if defaultAuthGroupID != 0 && defaultUserGroupID != 0 && (defaultAuthGroupID != primaryAuthGroupID || defaultUserGroupID != primaryUserGroupID) {
-			q = q.Where("(auth_group_id = ? AND user_group_id = ?) OR (auth_group_id = ? AND user_group_id = ?)", primaryAuthGroupID, primaryUserGroupID, defaultAuthGroupID, defaultUserGroupID)
-		} else {
-			q = q.Where("auth_group_id = ? AND user_group_id = ?", primaryAuthGroupID, primaryUserGroupID)
-		}
-		q = q.Where("((LOWER(provider) = ? AND model = ?) OR (provider = '' AND model = ''))", providerLower, model)
-
-		var rules []models.BillingRule
-		if errFindRules := q.Find(&rules).Error; errFindRules != nil {
-			return nil, errFindRules
-		}
-		return rules, nil
-	}
+		return 0, nil, billing.Footprint{}
+	}
+	ctx, span := tracing.Start(ctx, "billing.calculateCost",
+		attribute.String("cpab.provider", record.Provider),
+		attribute.String("cpab.model", record.Model),
+	)
+	defer span.End()
+	explanation, errExplain := billing.ExplainCost(ctx, db, billing.CostInput{
+		Provider:        record.Provider,
+		Model:           record.Model,
+		APIKeyID:        apiKeyID,
+		UserID:          userID,
+		AuthID:          authID,
+		UserGroupID:     billingUserGroupID,
+		Failed:          record.Failed,
+		InputTokens:     record.Detail.InputTokens,
+		OutputTokens:    record.Detail.OutputTokens,
+		ReasoningTokens: record.Detail.ReasoningTokens,
+		CachedTokens:    record.Detail.CachedTokens,
 
-	if authGroupID != nil && userGroupID != nil {
-		rulesPrimary, errPrimary := loadCandidateRules(*authGroupID, *userGroupID, 0, 0)
-		if errPrimary != nil {
-			return 0
-		}
-		if rule := billing.SelectBillingRule(rulesPrimary, *authGroupID, *userGroupID, 0, 0, provider, model); rule != nil {
-			return costFromRule(rule)
+		CacheCreationTokens: cacheCreationTokens(record.Detail),
+	})
+	if errExplain != nil || explanation == nil {
+		tracing.RecordError(span, errExplain)
+		if errors.Is(errExplain, billing.ErrExchangeRateMissing) {
+			log.WithError(errExplain).Warn("usage plugin: request left unpriced")
 		}
+		return 0, nil, billing.Footprint{}
 	}
-
-	defaultAuthGroupID, errDefaultAuthGroup := billing.ResolveDefaultAuthGroupID(ctx, db)
-	if errDefaultAuthGroup != nil {
-		return 0
-	}
-	defaultUserGroupID, errDefaultUserGroup := billing.ResolveDefaultUserGroupID(ctx, db)
-	if errDefaultUserGroup != nil {
-		return 0
-	}
-
-	primaryAuthGroupID := authGroupID
-	if primaryAuthGroupID == nil {
-		primaryAuthGroupID = defaultAuthGroupID
+	span.SetAttributes(attribute.Int64("cpab.cost_micros", explanation.TotalMicros))
+	if explanation.Rule == nil {
+		return explanation.TotalMicros, nil, explanation.Footprint
 	}
-	primaryUserGroupID := userGroupID
-	if primaryUserGroupID == nil {
-		primaryUserGroupID = defaultUserGroupID
-	}
-	if primaryAuthGroupID == nil || primaryUserGroupID == nil {
-		return 0
-	}
-
-	primaryAuthGroupIDValue := *primaryAuthGroupID
-	primaryUserGroupIDValue := *primaryUserGroupID
-
-	var defaultAuthGroupIDValue uint64
-	if defaultAuthGroupID != nil {
-		defaultAuthGroupIDValue = *defaultAuthGroupID
-	}
-	var defaultUserGroupIDValue uint64
-	if defaultUserGroupID != nil {
-		defaultUserGroupIDValue = *defaultUserGroupID
-	}
-
-	rules, errRules := loadCandidateRules(primaryAuthGroupIDValue, primaryUserGroupIDValue, defaultAuthGroupIDValue, defaultUserGroupIDValue)
-	if errRules != nil {
-		return 0
-	}
-
-	rule := billing.SelectBillingRule(rules, primaryAuthGroupIDValue, primaryUserGroupIDValue, defaultAuthGroupIDValue, defaultUserGroupIDValue, provider, model)
-	return costFromRule(rule)
+	ruleID := explanation.Rule.ID
+	span.SetAttributes(attribute.Int64("cpab.billing_rule_id", int64(ruleID)))
+	return explanation.TotalMicros, &ruleID, explanation.Footprint
 }
 
 // Ensure GormUsagePlugin implements coreusage.Plugin.
@@ -12,6 +12,7 @@ import (
 	"github.com/gin-gonic/gin"
 	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/usageerror"
 )
 
 func TestUsageErrorDetailCaptured(t *testing.T) {
@@ -43,9 +44,10 @@ func TestUsageErrorDetailCaptured(t *testing.T) {
 	var row struct {
 		ErrorStatusCode sql.NullInt64 `gorm:"column:error_status_code"`
 		ErrorDetail     []byte        `gorm:"column:error_detail"`
+		ErrorCode       string        `gorm:"column:error_code"`
 	}
 	if errFind := conn.Table("usages").
-		Select("error_status_code, error_detail").
+		Select("error_status_code, error_detail, error_code").
 		Order("id DESC").
 		Take(&row).Error; errFind != nil {
 		t.Fatalf("query error detail: %v", errFind)
@@ -55,6 +57,10 @@ func TestUsageErrorDetailCaptured(t *testing.T) {
 		t.Fatalf("expected error_status_code=502, got %v", row.ErrorStatusCode.Int64)
 	}
 
+	if row.ErrorCode != usageerror.CodeUpstream5xx {
+		t.Fatalf("expected error_code=%s, got %q", usageerror.CodeUpstream5xx, row.ErrorCode)
+	}
+
 	var payload map[string]any
 	if errUnmarshal := json.Unmarshal(row.ErrorDetail, &payload); errUnmarshal != nil {
 		t.Fatalf("unmarshal error detail: %v", errUnmarshal)
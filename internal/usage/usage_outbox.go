@@ -0,0 +1,310 @@
+package usage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	"gorm.io/datatypes"
+)
+
+// outboxCompactAfter is how many completed records accumulate before the journal is rewritten
+// with only the pending ones.
+const outboxCompactAfter = 10000
+
+// usageOutbox is an append-only JSONL journal of usage records that are queued or failed to
+// persist. Each record is journaled before it is queued and marked done once its row and
+// balance deduction commit, so records still pending after a crash are replayed on the next
+// start instead of being lost. Writes reach the OS on every append; Sync fsyncs them.
+type usageOutbox struct {
+	path string
+
+	mu        sync.Mutex
+	file      *os.File
+	nextSeq   uint64
+	pending   map[uint64][]byte // Journal lines of records not yet persisted, by sequence.
+	doneCount int               // Done markers written since the last compaction.
+}
+
+// outboxLine is one journal line: either a record or the sequences of persisted records.
+type outboxLine struct {
+	Seq   uint64       `json:"seq,omitempty"`
+	Entry *outboxEntry `json:"entry,omitempty"`
+	Done  []uint64     `json:"done,omitempty"`
+}
+
+// outboxEntry is the serialized form of a usageEntry.
+type outboxEntry struct {
+	Record             coreusage.Record `json:"record"`
+	Provider           string           `json:"provider"`
+	Model              string           `json:"model"`
+	APIKeyID           *uint64          `json:"api_key_id,omitempty"`
+	UserID             *uint64          `json:"user_id,omitempty"`
+	BillingUserGroupID *uint64          `json:"billing_user_group_id,omitempty"`
+	TeamID             *uint64          `json:"team_id,omitempty"`
+	TeamMemberID       *uint64          `json:"team_member_id,omitempty"`
+	ProviderAPIKeyID   *uint64          `json:"provider_api_key_id,omitempty"`
+	AuthKey            string           `json:"auth_key,omitempty"`
+	RequestID          string           `json:"request_id,omitempty"`
+	ErrorStatusCode    *int             `json:"error_status_code,omitempty"`
+	ErrorDetail        json.RawMessage  `json:"error_detail,omitempty"`
+	ErrorCode          string           `json:"error_code,omitempty"`
+	RetryAfterSeconds  int              `json:"retry_after_seconds,omitempty"`
+	IdempotencyKey     string           `json:"idempotency_key,omitempty"`
+	CompletedAt        time.Time        `json:"completed_at"`
+}
+
+// openUsageOutbox opens the journal at path and returns the records left pending by an
+// earlier run, rewriting the journal so it holds only those.
+func openUsageOutbox(path string) (*usageOutbox, []*usageEntry, error) {
+	if errDir := os.MkdirAll(filepath.Dir(path), 0o700); errDir != nil {
+		return nil, nil, fmt.Errorf("usage outbox: create dir: %w", errDir)
+	}
+	o := &usageOutbox{path: path, nextSeq: 1, pending: make(map[uint64][]byte)}
+	if errLoad := o.load(); errLoad != nil {
+		return nil, nil, errLoad
+	}
+	if errRewrite := o.rewrite(); errRewrite != nil {
+		return nil, nil, errRewrite
+	}
+
+	seqs := o.pendingSeqs()
+	recovered := make([]*usageEntry, 0, len(seqs))
+	for _, seq := range seqs {
+		var line outboxLine
+		if json.Unmarshal(o.pending[seq], &line) != nil || line.Entry == nil {
+			continue
+		}
+		entry := line.Entry.toUsageEntry()
+		entry.seq = seq
+		recovered = append(recovered, entry)
+	}
+	return o, recovered, nil
+}
+
+// load reads the journal, keeping records without a done marker.
+func (o *usageOutbox) load() error {
+	file, errOpen := os.Open(o.path)
+	if errors.Is(errOpen, os.ErrNotExist) {
+		return nil
+	}
+	if errOpen != nil {
+		return fmt.Errorf("usage outbox: open: %w", errOpen)
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		raw := append([]byte(nil), scanner.Bytes()...)
+		var line outboxLine
+		if json.Unmarshal(raw, &line) != nil {
+			// A torn last line from a crash mid-write; the record was never acknowledged.
+			continue
+		}
+		if line.Seq >= o.nextSeq {
+			o.nextSeq = line.Seq + 1
+		}
+		if line.Entry != nil && line.Seq != 0 {
+			o.pending[line.Seq] = raw
+		}
+		for _, seq := range line.Done {
+			delete(o.pending, seq)
+		}
+	}
+	if errScan := scanner.Err(); errScan != nil {
+		return fmt.Errorf("usage outbox: read: %w", errScan)
+	}
+	return nil
+}
+
+// rewrite atomically replaces the journal with the pending records and reopens it for append.
+// Callers other than openUsageOutbox hold o.mu.
+func (o *usageOutbox) rewrite() error {
+	tmpPath := o.path + ".tmp"
+	tmp, errCreate := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if errCreate != nil {
+		return fmt.Errorf("usage outbox: create: %w", errCreate)
+	}
+	writer := bufio.NewWriter(tmp)
+	for _, seq := range o.pendingSeqs() {
+		_, _ = writer.Write(o.pending[seq])
+		_ = writer.WriteByte('\n')
+	}
+	errWrite := writer.Flush()
+	if errWrite == nil {
+		errWrite = tmp.Sync()
+	}
+	if errClose := tmp.Close(); errWrite == nil {
+		errWrite = errClose
+	}
+	if errWrite != nil {
+		return fmt.Errorf("usage outbox: write: %w", errWrite)
+	}
+	if o.file != nil {
+		_ = o.file.Close()
+		o.file = nil
+	}
+	if errRename := os.Rename(tmpPath, o.path); errRename != nil {
+		return fmt.Errorf("usage outbox: replace: %w", errRename)
+	}
+	file, errOpen := os.OpenFile(o.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if errOpen != nil {
+		return fmt.Errorf("usage outbox: reopen: %w", errOpen)
+	}
+	o.file = file
+	o.doneCount = 0
+	return nil
+}
+
+// pendingSeqs returns the pending sequences in journal order.
+func (o *usageOutbox) pendingSeqs() []uint64 {
+	seqs := make([]uint64, 0, len(o.pending))
+	for seq := range o.pending {
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	return seqs
+}
+
+// Append journals entry and assigns its sequence.
+func (o *usageOutbox) Append(entry *usageEntry) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.file == nil {
+		return errors.New("usage outbox: closed")
+	}
+	seq := o.nextSeq
+	raw, errMarshal := json.Marshal(outboxLine{Seq: seq, Entry: newOutboxEntry(entry)})
+	if errMarshal != nil {
+		return fmt.Errorf("usage outbox: encode: %w", errMarshal)
+	}
+	if _, errWrite := o.file.Write(append(raw, '\n')); errWrite != nil {
+		return fmt.Errorf("usage outbox: append: %w", errWrite)
+	}
+	o.nextSeq++
+	o.pending[seq] = raw
+	entry.seq = seq
+	return nil
+}
+
+// Done marks the persisted entries, compacting the journal once enough have completed.
+func (o *usageOutbox) Done(entries []*usageEntry) error {
+	seqs := make([]uint64, 0, len(entries))
+	for _, entry := range entries {
+		if entry.seq != 0 {
+			seqs = append(seqs, entry.seq)
+		}
+	}
+	if len(seqs) == 0 {
+		return nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.file == nil {
+		return errors.New("usage outbox: closed")
+	}
+	for _, seq := range seqs {
+		delete(o.pending, seq)
+	}
+	o.doneCount += len(seqs)
+	if len(o.pending) == 0 || o.doneCount >= outboxCompactAfter {
+		return o.rewrite()
+	}
+	raw, errMarshal := json.Marshal(outboxLine{Done: seqs})
+	if errMarshal != nil {
+		return fmt.Errorf("usage outbox: encode: %w", errMarshal)
+	}
+	if _, errWrite := o.file.Write(append(raw, '\n')); errWrite != nil {
+		return fmt.Errorf("usage outbox: append: %w", errWrite)
+	}
+	return nil
+}
+
+// Sync flushes journal writes to stable storage.
+func (o *usageOutbox) Sync() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.file == nil {
+		return nil
+	}
+	return o.file.Sync()
+}
+
+// Pending returns the number of journaled records not yet persisted.
+func (o *usageOutbox) Pending() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.pending)
+}
+
+// Close syncs and closes the journal; pending records are replayed on the next open.
+func (o *usageOutbox) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.file == nil {
+		return nil
+	}
+	errSync := o.file.Sync()
+	errClose := o.file.Close()
+	o.file = nil
+	return errors.Join(errSync, errClose)
+}
+
+// newOutboxEntry serializes entry. The client's API key is not journaled; the entry already
+// carries the key's ID.
+func newOutboxEntry(entry *usageEntry) *outboxEntry {
+	record := entry.record
+	record.APIKey = ""
+	return &outboxEntry{
+		Record:             record,
+		Provider:           entry.provider,
+		Model:              entry.model,
+		APIKeyID:           entry.apiKeyID,
+		UserID:             entry.userID,
+		BillingUserGroupID: entry.billingUserGroupID,
+		TeamID:             entry.teamID,
+		TeamMemberID:       entry.teamMemberID,
+		ProviderAPIKeyID:   entry.providerAPIKeyID,
+		AuthKey:            entry.authKey,
+		RequestID:          entry.requestID,
+		ErrorStatusCode:    entry.errorStatusCode,
+		ErrorDetail:        json.RawMessage(entry.errorDetail),
+		ErrorCode:          entry.errorCode,
+		RetryAfterSeconds:  entry.retryAfterSeconds,
+		IdempotencyKey:     entry.idempotencyKey,
+		CompletedAt:        entry.completedAt,
+	}
+}
+
+// toUsageEntry restores a journaled entry. The request's trace context is not journaled.
+func (e *outboxEntry) toUsageEntry() *usageEntry {
+	return &usageEntry{
+		ctx:                context.Background(),
+		record:             e.Record,
+		provider:           e.Provider,
+		model:              e.Model,
+		apiKeyID:           e.APIKeyID,
+		userID:             e.UserID,
+		billingUserGroupID: e.BillingUserGroupID,
+		teamID:             e.TeamID,
+		teamMemberID:       e.TeamMemberID,
+		providerAPIKeyID:   e.ProviderAPIKeyID,
+		authKey:            e.AuthKey,
+		requestID:          e.RequestID,
+		errorStatusCode:    e.ErrorStatusCode,
+		errorDetail:        datatypes.JSON(e.ErrorDetail),
+		errorCode:          e.ErrorCode,
+		retryAfterSeconds:  e.RetryAfterSeconds,
+		idempotencyKey:     e.IdempotencyKey,
+		completedAt:        e.CompletedAt,
+	}
+}
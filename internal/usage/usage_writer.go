@@ -0,0 +1,321 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// AsyncOptions tunes the buffered usage writer.
+type AsyncOptions struct {
+	QueueSize     int           // Buffered records; HandleUsage persists synchronously when full.
+	BatchSize     int           // Records written per transaction.
+	FlushInterval time.Duration // Longest a queued record waits for its batch.
+	MaxAttempts   int           // Immediate attempts per record before it waits for the next retry round.
+	RetryBackoff  time.Duration // Base delay between attempts; grows linearly.
+	RetryInterval time.Duration // Delay between retry rounds of records that exhausted their attempts.
+	OutboxPath    string        // Journal of queued and failed records replayed on start; empty keeps them in memory only.
+}
+
+// DefaultAsyncOptions returns the writer settings used by the server.
+func DefaultAsyncOptions() AsyncOptions {
+	return AsyncOptions{
+		QueueSize:     10000,
+		BatchSize:     100,
+		FlushInterval: 200 * time.Millisecond,
+		MaxAttempts:   5,
+		RetryBackoff:  500 * time.Millisecond,
+		RetryInterval: 30 * time.Second,
+	}
+}
+
+// MaxUsageCommitDelay bounds how long after its created_at a usage row can still commit: rows
+// take created_at inside the insert transaction, which is cancelled after its timeout.
+func MaxUsageCommitDelay(batchSize int) time.Duration {
+	if batchSize <= 0 {
+		batchSize = DefaultAsyncOptions().BatchSize
+	}
+	return persistTimeout(batchSize)
+}
+
+// usageWriter batches queued usage entries into transactions off the request path.
+type usageWriter struct {
+	plugin *GormUsagePlugin
+	opts   AsyncOptions
+	outbox *usageOutbox // Nil without OutboxPath.
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan *usageEntry
+	done   chan struct{}
+
+	strandMu    sync.Mutex
+	stranded    []*usageEntry // Records that failed every attempt; retried every RetryInterval.
+	nextRetryAt time.Time
+}
+
+// StartAsync switches the plugin to buffered, batched persistence. Records are written
+// at least once: each is journaled to the outbox before it is queued and stays there until
+// its row commits, so records failing every attempt are retried until the database accepts
+// them, and records pending at a crash are replayed on the next start.
+// Call Close on shutdown to flush queued records.
+func (p *GormUsagePlugin) StartAsync(opts AsyncOptions) {
+	if p == nil || p.db == nil {
+		return
+	}
+	defaults := DefaultAsyncOptions()
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaults.QueueSize
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaults.BatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = defaults.FlushInterval
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaults.MaxAttempts
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = defaults.RetryBackoff
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = defaults.RetryInterval
+	}
+	w := &usageWriter{
+		plugin: p,
+		opts:   opts,
+		queue:  make(chan *usageEntry, opts.QueueSize),
+		done:   make(chan struct{}),
+	}
+	if opts.OutboxPath != "" {
+		outbox, recovered, errOutbox := openUsageOutbox(opts.OutboxPath)
+		if errOutbox != nil {
+			log.WithError(errOutbox).Error("usage writer: outbox unavailable; queued records will not survive a crash")
+		} else {
+			w.outbox = outbox
+			w.stranded = recovered
+			if len(recovered) > 0 {
+				log.Infof("usage writer: replaying %d usage records from %s", len(recovered), opts.OutboxPath)
+			}
+		}
+	}
+	if !p.writer.CompareAndSwap(nil, w) {
+		if w.outbox != nil {
+			_ = w.outbox.Close()
+		}
+		return
+	}
+	go w.run()
+	log.Infof("usage writer started (batch=%d interval=%s queue=%d)", opts.BatchSize, opts.FlushInterval, opts.QueueSize)
+}
+
+// Close stops accepting queued records and waits until every queued record is persisted
+// or ctx expires. Records the database still rejects stay in the outbox for the next start.
+// Later records are persisted synchronously.
+func (p *GormUsagePlugin) Close(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	w := p.writer.Swap(nil)
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return errors.Join(errors.New("usage writer: flush timed out"), ctx.Err())
+	}
+}
+
+// QueueLength returns the number of usage records waiting to be written.
+func (p *GormUsagePlugin) QueueLength() int {
+	if p == nil {
+		return 0
+	}
+	w := p.writer.Load()
+	if w == nil {
+		return 0
+	}
+	return len(w.queue)
+}
+
+// enqueue journals and queues entry without blocking; it reports false when the writer is
+// closed or full. A full queue leaves the entry journaled for the caller to settle.
+func (w *usageWriter) enqueue(entry *usageEntry) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return false
+	}
+	if w.outbox != nil {
+		if errAppend := w.outbox.Append(entry); errAppend != nil {
+			log.WithError(errAppend).Warn("usage writer: journal usage record failed")
+		}
+	}
+	select {
+	case w.queue <- entry:
+		return true
+	default:
+		return false
+	}
+}
+
+// settle records the outcome of a synchronous write of entry.
+func (w *usageWriter) settle(entry *usageEntry, errPersist error) {
+	if errPersist == nil {
+		w.markDone([]*usageEntry{entry})
+		return
+	}
+	w.strand(entry, errPersist)
+}
+
+func (w *usageWriter) run() {
+	defer close(w.done)
+	batch := make([]*usageEntry, 0, w.opts.BatchSize)
+	timer := time.NewTimer(w.opts.FlushInterval)
+	defer timer.Stop()
+	for {
+		select {
+		case entry, ok := <-w.queue:
+			if !ok {
+				w.flush(batch)
+				w.retryStranded(true)
+				w.closeOutbox()
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) < w.opts.BatchSize {
+				continue
+			}
+		case <-timer.C:
+			timer.Reset(w.opts.FlushInterval)
+			w.retryStranded(false)
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		w.flush(batch)
+		batch = batch[:0]
+	}
+}
+
+// flush writes a batch in one transaction, falling back to per-entry retries so a single
+// bad record cannot hold back the rest of the batch.
+func (w *usageWriter) flush(batch []*usageEntry) {
+	if len(batch) == 0 {
+		return
+	}
+	errBatch := w.plugin.persistUsageEntries(batch)
+	if errBatch == nil {
+		w.markDone(batch)
+		return
+	}
+	log.WithError(errBatch).Warnf("usage writer: batch of %d failed, retrying individually", len(batch))
+	for _, entry := range batch {
+		w.persistWithRetry(entry)
+	}
+	if w.outbox != nil {
+		if errSync := w.outbox.Sync(); errSync != nil {
+			log.WithError(errSync).Warn("usage writer: sync outbox failed")
+		}
+	}
+}
+
+// persistWithRetry writes entry, keeping it for the next retry round when every attempt fails.
+func (w *usageWriter) persistWithRetry(entry *usageEntry) {
+	var errPersist error
+	for attempt := 1; attempt <= w.opts.MaxAttempts; attempt++ {
+		errPersist = w.plugin.persistUsageEntries([]*usageEntry{entry})
+		if errPersist == nil {
+			w.markDone([]*usageEntry{entry})
+			return
+		}
+		if attempt < w.opts.MaxAttempts {
+			time.Sleep(time.Duration(attempt) * w.opts.RetryBackoff)
+		}
+	}
+	w.strand(entry, errPersist)
+}
+
+// strand keeps a failed entry for the next retry round.
+func (w *usageWriter) strand(entry *usageEntry, errPersist error) {
+	w.strandMu.Lock()
+	w.stranded = append(w.stranded, entry)
+	if w.nextRetryAt.IsZero() {
+		w.nextRetryAt = time.Now().Add(w.opts.RetryInterval)
+	}
+	w.strandMu.Unlock()
+	log.WithError(errPersist).Warnf("usage writer: usage record kept for retry (request_id=%s)", entry.requestID)
+}
+
+// retryStranded writes stranded entries once their retry round is due, or now when force is set.
+func (w *usageWriter) retryStranded(force bool) {
+	w.strandMu.Lock()
+	if len(w.stranded) == 0 || (!force && time.Now().Before(w.nextRetryAt)) {
+		w.strandMu.Unlock()
+		return
+	}
+	pending := w.stranded
+	w.stranded = nil
+	w.nextRetryAt = time.Time{}
+	w.strandMu.Unlock()
+
+	for start := 0; start < len(pending); start += w.opts.BatchSize {
+		chunk := pending[start:min(start+w.opts.BatchSize, len(pending))]
+		if errPersist := w.plugin.persistUsageEntries(chunk); errPersist == nil {
+			w.markDone(chunk)
+			continue
+		}
+		for _, entry := range chunk {
+			errPersist := w.plugin.persistUsageEntries([]*usageEntry{entry})
+			if errPersist == nil {
+				w.markDone([]*usageEntry{entry})
+				continue
+			}
+			w.strand(entry, errPersist)
+		}
+	}
+}
+
+// markDone removes persisted entries from the outbox.
+func (w *usageWriter) markDone(entries []*usageEntry) {
+	if w.outbox == nil {
+		return
+	}
+	if errDone := w.outbox.Done(entries); errDone != nil {
+		log.WithError(errDone).Warn("usage writer: mark usage records done in outbox failed; they may be replayed")
+	}
+}
+
+// closeOutbox closes the journal, reporting records left for the next start.
+func (w *usageWriter) closeOutbox() {
+	w.strandMu.Lock()
+	left := len(w.stranded)
+	w.strandMu.Unlock()
+	if w.outbox == nil {
+		if left > 0 {
+			log.Errorf("usage writer: %d usage records could not be persisted and are lost (no outbox configured)", left)
+		}
+		return
+	}
+	if left > 0 {
+		log.Warnf("usage writer: %d usage records left in the outbox for the next start", left)
+	}
+	if errClose := w.outbox.Close(); errClose != nil {
+		log.WithError(errClose).Warn("usage writer: close outbox failed")
+	}
+}
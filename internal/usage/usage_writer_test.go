@@ -0,0 +1,282 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+)
+
+func TestAsyncWriterFlushesQueuedUsageOnClose(t *testing.T) {
+	conn, errOpen := db.Open(":memory:")
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+
+	plugin := NewGormUsagePlugin(conn)
+	plugin.StartAsync(AsyncOptions{BatchSize: 4, FlushInterval: time.Hour})
+	for i := 0; i < 10; i++ {
+		plugin.HandleUsage(context.Background(), coreusage.Record{
+			Provider:    "openai",
+			Model:       "gpt-4",
+			RequestedAt: time.Now().UTC(),
+			Detail:      coreusage.Detail{InputTokens: 1, TotalTokens: 1},
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if errClose := plugin.Close(ctx); errClose != nil {
+		t.Fatalf("Close: %v", errClose)
+	}
+
+	var count int64
+	if errCount := conn.Model(&models.Usage{}).Count(&count).Error; errCount != nil {
+		t.Fatalf("count usages: %v", errCount)
+	}
+	if count != 10 {
+		t.Fatalf("expected 10 usage rows after flush, got %d", count)
+	}
+
+	plugin.HandleUsage(context.Background(), coreusage.Record{Provider: "openai", Model: "gpt-4", RequestedAt: time.Now().UTC()})
+	if errCount := conn.Model(&models.Usage{}).Count(&count).Error; errCount != nil {
+		t.Fatalf("count usages: %v", errCount)
+	}
+	if count != 11 {
+		t.Fatalf("expected records after Close to persist synchronously, got %d", count)
+	}
+}
+
+func TestPendingTodayMicrosCountsLaterRowsOfSameUser(t *testing.T) {
+	now := time.Now()
+	userA, userB := uint64(1), uint64(2)
+	rows := []models.Usage{
+		{UserID: &userA, CostMicros: 100, RequestedAt: now},
+		{UserID: &userB, CostMicros: 50, RequestedAt: now},
+		{UserID: &userA, CostMicros: 30, RequestedAt: now},
+		{UserID: &userA, CostMicros: 7, RequestedAt: now.AddDate(0, 0, -2)},
+	}
+	pending := pendingTodayMicros(rows, now)
+	want := []int64{130, 50, 30, 7}
+	for i := range want {
+		if pending[i] != want[i] {
+			t.Fatalf("pending[%d] = %d, want %d (all: %v)", i, pending[i], want[i], pending)
+		}
+	}
+}
+
+func TestUsageOutboxReplaysPendingRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	outbox, recovered, errOpen := openUsageOutbox(path)
+	if errOpen != nil || len(recovered) != 0 {
+		t.Fatalf("openUsageOutbox: %v (recovered %d)", errOpen, len(recovered))
+	}
+	userID := uint64(7)
+	entries := make([]*usageEntry, 3)
+	for i := range entries {
+		entries[i] = &usageEntry{
+			record:      coreusage.Record{Provider: "openai", Model: "gpt-4", APIKey: "sk-client-secret", RequestedAt: time.Now().UTC()},
+			provider:    "openai",
+			model:       "gpt-4",
+			userID:      &userID,
+			requestID:   fmt.Sprintf("req-%d", i),
+			completedAt: time.Now().UTC(),
+		}
+		if errAppend := outbox.Append(entries[i]); errAppend != nil {
+			t.Fatalf("Append: %v", errAppend)
+		}
+	}
+	if errDone := outbox.Done(entries[1:2]); errDone != nil {
+		t.Fatalf("Done: %v", errDone)
+	}
+	if errClose := outbox.Close(); errClose != nil {
+		t.Fatalf("Close: %v", errClose)
+	}
+	journal, errRead := os.ReadFile(path)
+	if errRead != nil {
+		t.Fatalf("read journal: %v", errRead)
+	}
+	if strings.Contains(string(journal), "sk-client-secret") {
+		t.Fatalf("expected the client API key to stay out of the journal")
+	}
+
+	reopened, recovered, errReopen := openUsageOutbox(path)
+	if errReopen != nil {
+		t.Fatalf("reopen: %v", errReopen)
+	}
+	if len(recovered) != 2 || recovered[0].requestID != "req-0" || recovered[1].requestID != "req-2" {
+		t.Fatalf("expected req-0 and req-2 to be replayed, got %+v", recovered)
+	}
+	if recovered[0].userID == nil || *recovered[0].userID != userID {
+		t.Fatalf("expected entry fields to survive the journal, got %+v", recovered[0])
+	}
+	if errDone := reopened.Done(recovered); errDone != nil {
+		t.Fatalf("Done: %v", errDone)
+	}
+	if reopened.Pending() != 0 {
+		t.Fatalf("expected an empty outbox, got %d pending", reopened.Pending())
+	}
+	_ = reopened.Close()
+	if info, errStat := os.Stat(path); errStat != nil || info.Size() != 0 {
+		t.Fatalf("expected the journal to be compacted, got %v %v", info, errStat)
+	}
+}
+
+func TestAsyncWriterKeepsFailedRecordsInOutbox(t *testing.T) {
+	conn, errOpen := db.Open(":memory:")
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+
+	broken := NewGormUsagePlugin(conn)
+	if errDrop := conn.Migrator().RenameTable(&models.Usage{}, "usages_offline"); errDrop != nil {
+		t.Fatalf("rename usages: %v", errDrop)
+	}
+	broken.StartAsync(AsyncOptions{BatchSize: 2, FlushInterval: time.Hour, MaxAttempts: 1, RetryBackoff: time.Millisecond, OutboxPath: path})
+	for i := 0; i < 3; i++ {
+		broken.HandleUsage(context.Background(), coreusage.Record{Provider: "openai", Model: "gpt-4", RequestedAt: time.Now().UTC()})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if errClose := broken.Close(ctx); errClose != nil {
+		t.Fatalf("Close: %v", errClose)
+	}
+
+	if errRestore := conn.Migrator().RenameTable("usages_offline", &models.Usage{}); errRestore != nil {
+		t.Fatalf("restore usages: %v", errRestore)
+	}
+	recovered := NewGormUsagePlugin(conn)
+	recovered.StartAsync(AsyncOptions{FlushInterval: time.Millisecond, OutboxPath: path})
+	if errClose := recovered.Close(ctx); errClose != nil {
+		t.Fatalf("Close: %v", errClose)
+	}
+	var count int64
+	conn.Model(&models.Usage{}).Count(&count)
+	if count != 3 {
+		t.Fatalf("expected the 3 failed records to be replayed, got %d", count)
+	}
+}
+
+func TestUsageOutboxReplayChargesOnce(t *testing.T) {
+	conn, errOpen := db.Open(":memory:")
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	now := time.Now().UTC()
+
+	authGroup := models.AuthGroup{Name: "replay-auth-group"}
+	userGroup := models.UserGroup{Name: "replay-user-group", CreatedAt: now, UpdatedAt: now}
+	if errCreate := conn.Create(&authGroup).Error; errCreate != nil {
+		t.Fatalf("create auth group: %v", errCreate)
+	}
+	if errCreate := conn.Create(&userGroup).Error; errCreate != nil {
+		t.Fatalf("create user group: %v", errCreate)
+	}
+	authGroupID, userGroupID := authGroup.ID, userGroup.ID
+	auth := models.Auth{Key: "replay-auth", Content: datatypes.JSON(`{"type":"codex"}`), AuthGroupID: models.AuthGroupIDs{&authGroupID}}
+	if errCreate := conn.Create(&auth).Error; errCreate != nil {
+		t.Fatalf("create auth: %v", errCreate)
+	}
+	price := 1.0
+	rule := models.BillingRule{AuthGroupID: authGroupID, UserGroupID: userGroupID, BillingType: models.BillingTypePerToken, PriceInputToken: &price, IsEnabled: true}
+	if errCreate := conn.Create(&rule).Error; errCreate != nil {
+		t.Fatalf("create rule: %v", errCreate)
+	}
+	user := models.User{Username: "u1", Password: "x", CreatedAt: now, UpdatedAt: now}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+	redeemedAt := now.Add(-time.Minute)
+	card := models.PrepaidCard{
+		Name:           "c1",
+		CardSN:         "sn1",
+		Password:       "p1",
+		Amount:         10,
+		Balance:        10,
+		IsEnabled:      true,
+		RedeemedUserID: &user.ID,
+		RedeemedAt:     &redeemedAt,
+		UserGroupID:    &userGroupID,
+		CreatedAt:      now,
+	}
+	if errCreate := conn.Create(&card).Error; errCreate != nil {
+		t.Fatalf("create card: %v", errCreate)
+	}
+
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	outbox, _, errOutbox := openUsageOutbox(path)
+	if errOutbox != nil {
+		t.Fatalf("open outbox: %v", errOutbox)
+	}
+	userID := user.ID
+	entry := &usageEntry{
+		ctx:                context.Background(),
+		record:             coreusage.Record{Provider: "openai", Model: "gpt-5", AuthID: auth.Key, RequestedAt: now, Detail: coreusage.Detail{InputTokens: 1_000_000}},
+		provider:           "openai",
+		model:              "gpt-5",
+		userID:             &userID,
+		billingUserGroupID: &userGroupID,
+		authKey:            auth.Key,
+		idempotencyKey:     newUsageIdempotencyKey(),
+		completedAt:        now,
+	}
+	if errAppend := outbox.Append(entry); errAppend != nil {
+		t.Fatalf("append: %v", errAppend)
+	}
+	plugin := NewGormUsagePlugin(conn)
+	if errPersist := plugin.persistUsageEntries([]*usageEntry{entry}); errPersist != nil {
+		t.Fatalf("persist: %v", errPersist)
+	}
+	// The process stops before the done marker is written.
+	if errClose := outbox.Close(); errClose != nil {
+		t.Fatalf("close outbox: %v", errClose)
+	}
+
+	reopened, recovered, errReopen := openUsageOutbox(path)
+	if errReopen != nil {
+		t.Fatalf("reopen outbox: %v", errReopen)
+	}
+	defer func() { _ = reopened.Close() }()
+	if len(recovered) != 1 || recovered[0].idempotencyKey != entry.idempotencyKey {
+		t.Fatalf("expected the record replayed with its key, got %+v", recovered)
+	}
+	if errPersist := plugin.persistUsageEntries(recovered); errPersist != nil {
+		t.Fatalf("persist replay: %v", errPersist)
+	}
+
+	var count int64
+	conn.Model(&models.Usage{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("expected one usage row, got %d", count)
+	}
+	var updated models.PrepaidCard
+	if errFind := conn.First(&updated, card.ID).Error; errFind != nil {
+		t.Fatalf("load card: %v", errFind)
+	}
+	if updated.Balance != 9 {
+		t.Fatalf("expected one charge leaving balance 9, got %v", updated.Balance)
+	}
+	var ledger int64
+	conn.Model(&models.BalanceTransaction{}).Where("user_id = ?", user.ID).Count(&ledger)
+	if ledger != 1 {
+		t.Fatalf("expected one ledger debit, got %d", ledger)
+	}
+}
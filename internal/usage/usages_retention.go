@@ -9,21 +9,21 @@ import (
 	"time"
 
 	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/usagerollup"
 	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
 const (
 	defaultUsagesRetentionInterval = 6 * time.Hour
-	defaultUsagesDeleteBatchSize   = 5000
-	maxDeleteBatchesPerRun         = 2000
 )
 
-// UsagesRetentionCleaner periodically deletes old rows from the usages table.
+// UsagesRetentionCleaner periodically rolls up old rows from the usages table into
+// usage_daily, archiving them first when an archive directory is configured.
 type UsagesRetentionCleaner struct {
-	db        *gorm.DB
-	interval  time.Duration
-	batchSize int
+	db         *gorm.DB
+	interval   time.Duration
+	archiveDir string
 }
 
 func NewUsagesRetentionCleaner(db *gorm.DB) *UsagesRetentionCleaner {
@@ -31,12 +31,19 @@ func NewUsagesRetentionCleaner(db *gorm.DB) *UsagesRetentionCleaner {
 		return nil
 	}
 	return &UsagesRetentionCleaner{
-		db:        db,
-		interval:  defaultUsagesRetentionInterval,
-		batchSize: defaultUsagesDeleteBatchSize,
+		db:       db,
+		interval: defaultUsagesRetentionInterval,
 	}
 }
 
+// SetArchiveDir enables exporting rows as gzip-compressed JSONL into dir before they are deleted.
+func (c *UsagesRetentionCleaner) SetArchiveDir(dir string) {
+	if c == nil {
+		return
+	}
+	c.archiveDir = strings.TrimSpace(dir)
+}
+
 // Start launches the cleanup loop in a background goroutine.
 func (c *UsagesRetentionCleaner) Start(ctx context.Context) {
 	if c == nil {
@@ -90,53 +97,20 @@ func (c *UsagesRetentionCleaner) cleanupOnce(ctx context.Context) {
 
 	cutoff := time.Now().UTC().AddDate(0, 0, -retentionDays)
 
-	deletedTotal := int64(0)
-	for i := 0; i < maxDeleteBatchesPerRun; i++ {
-		if ctx != nil && ctx.Err() != nil {
-			return
-		}
-		n, err := c.deleteBatch(ctx, cutoff)
-		if err != nil {
-			log.WithError(err).Warn("usages retention cleaner: delete batch failed")
-			break
-		}
-		if n <= 0 {
-			break
-		}
-		deletedTotal += n
-	}
-
-	if deletedTotal > 0 {
-		log.Infof("usages retention cleaner: deleted %d rows (cutoff=%s retention_days=%d)", deletedTotal, cutoff.Format(time.RFC3339), retentionDays)
+	results, errRollup := usagerollup.RollupBefore(ctx, c.db, cutoff, c.archiveDir)
+	if errRollup != nil && ctx.Err() == nil {
+		log.WithError(errRollup).Warn("usages retention cleaner: rollup failed")
 	}
-}
-
-func (c *UsagesRetentionCleaner) deleteBatch(ctx context.Context, cutoff time.Time) (int64, error) {
-	if c == nil || c.db == nil {
-		return 0, nil
-	}
-	if ctx == nil {
-		ctx = context.Background()
+	rolledUp := int64(0)
+	for _, result := range results {
+		rolledUp += result.Rows
+		if result.ArchivePath != "" {
+			log.Infof("usages retention cleaner: archived %d rows of %s to %s", result.Rows, result.Day.Format(time.DateOnly), result.ArchivePath)
+		}
 	}
-	limit := c.batchSize
-	if limit <= 0 {
-		limit = defaultUsagesDeleteBatchSize
+	if rolledUp > 0 {
+		log.Infof("usages retention cleaner: rolled up %d rows from %d days into usage_daily (cutoff=%s retention_days=%d)", rolledUp, len(results), cutoff.Format(time.RFC3339), retentionDays)
 	}
-
-	// Use a limited subquery to avoid long-running transactions and table locks.
-	res := c.db.WithContext(ctx).Exec(`
-		DELETE FROM usages
-		WHERE id IN (
-			SELECT id FROM usages
-			WHERE requested_at < ?
-			ORDER BY requested_at ASC
-			LIMIT ?
-		)
-	`, cutoff, limit)
-	if res.Error != nil {
-		return 0, res.Error
-	}
-	return res.RowsAffected, nil
 }
 
 func parseDBConfigInt(raw json.RawMessage) (int, bool) {
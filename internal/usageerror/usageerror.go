@@ -0,0 +1,127 @@
+// Package usageerror classifies failed proxy requests into a small set of canonical error
+// codes, so dashboards can break failures down by cause instead of raw status codes.
+package usageerror
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Canonical error codes stored in usages.error_code.
+const (
+	// CodeRateLimited marks requests the upstream throttled or rejected for exhausted quota.
+	CodeRateLimited = "rate_limited"
+	// CodeAuthExpired marks requests whose upstream credential was expired, revoked or invalid.
+	CodeAuthExpired = "auth_expired"
+	// CodeContentFiltered marks requests refused by an upstream safety or moderation filter.
+	CodeContentFiltered = "content_filtered"
+	// CodeUpstream5xx marks upstream server errors.
+	CodeUpstream5xx = "upstream_5xx"
+	// CodeTimeout marks requests that timed out or were cut off waiting for the upstream.
+	CodeTimeout = "timeout"
+	// CodeInvalidRequest marks other client errors, such as malformed requests or unknown models.
+	CodeInvalidRequest = "invalid_request"
+	// CodeUnknown marks failures that fit no other code, including rows recorded before
+	// classification existed.
+	CodeUnknown = "unknown"
+)
+
+// Codes lists every code in display order.
+var Codes = []string{
+	CodeRateLimited,
+	CodeAuthExpired,
+	CodeContentFiltered,
+	CodeUpstream5xx,
+	CodeTimeout,
+	CodeInvalidRequest,
+	CodeUnknown,
+}
+
+var (
+	timeoutHints = []string{"timeout", "timed out", "deadline exceeded"}
+	contentHints = []string{
+		"content_filter", "content filter", "content policy", "content_policy", "safety",
+		"moderation", "flagged", "responsible ai", "prohibited_content", "blocked by",
+	}
+	rateLimitHints = []string{
+		"rate limit", "rate_limit", "ratelimit", "too many requests", "resource_exhausted",
+		"resource exhausted", "quota exceeded", "quota_exceeded", "exceeded your current quota",
+	}
+	authHints = []string{
+		"token expired", "token has expired", "expired token", "invalid_grant", "invalid api key",
+		"invalid_api_key", "invalid x-api-key", "unauthenticated", "unauthorized", "authentication",
+		"credential", "refresh token", "permission_denied", "access token",
+	}
+)
+
+// Classify maps a failed request's status code and upstream error text to a canonical code.
+// Text hints win over the status because upstreams report filters and quota errors under
+// varying statuses.
+func Classify(statusCode int, message string, body []byte) string {
+	text := strings.ToLower(message + "\n" + string(body))
+	switch {
+	case statusCode == http.StatusRequestTimeout || statusCode == http.StatusGatewayTimeout || statusCode == 524 ||
+		containsAny(text, timeoutHints):
+		return CodeTimeout
+	case containsAny(text, contentHints) && statusCode < http.StatusInternalServerError:
+		return CodeContentFiltered
+	case statusCode == http.StatusTooManyRequests || containsAny(text, rateLimitHints):
+		return CodeRateLimited
+	case statusCode == http.StatusUnauthorized ||
+		(statusCode == http.StatusForbidden && containsAny(text, authHints)):
+		return CodeAuthExpired
+	case statusCode >= http.StatusInternalServerError:
+		return CodeUpstream5xx
+	case statusCode >= http.StatusBadRequest:
+		return CodeInvalidRequest
+	default:
+		return CodeUnknown
+	}
+}
+
+// Normalize returns code when it is canonical and CodeUnknown otherwise.
+func Normalize(code string) string {
+	code = strings.TrimSpace(code)
+	for _, known := range Codes {
+		if code == known {
+			return code
+		}
+	}
+	return CodeUnknown
+}
+
+func containsAny(text string, hints []string) bool {
+	for _, hint := range hints {
+		if strings.Contains(text, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// CodeCount is the number of failures of one code.
+type CodeCount struct {
+	Code  string  `json:"code"`
+	Count int64   `json:"count"`
+	Share float64 `json:"share"` // Fraction of all counted failures.
+}
+
+// Breakdown orders per-code failure counts like Codes, listing every code so charts keep a
+// stable shape. Unrecognized codes count as CodeUnknown.
+func Breakdown(counts map[string]int64) []CodeCount {
+	merged := make(map[string]int64, len(Codes))
+	var total int64
+	for code, count := range counts {
+		merged[Normalize(code)] += count
+		total += count
+	}
+	out := make([]CodeCount, 0, len(Codes))
+	for _, code := range Codes {
+		item := CodeCount{Code: code, Count: merged[code]}
+		if total > 0 {
+			item.Share = float64(item.Count) / float64(total)
+		}
+		out = append(out, item)
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+package usageerror
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		name    string
+		status  int
+		message string
+		body    string
+		want    string
+	}{
+		{"throttled", 429, "Too Many Requests", "", CodeRateLimited},
+		{"quota under 403", 403, "", `{"error":{"status":"RESOURCE_EXHAUSTED"}}`, CodeRateLimited},
+		{"unauthorized", 401, "invalid x-api-key", "", CodeAuthExpired},
+		{"expired token under 403", 403, "OAuth token has expired", "", CodeAuthExpired},
+		{"content filter", 400, "", `{"error":{"code":"content_filter"}}`, CodeContentFiltered},
+		{"gateway timeout", 504, "Gateway Timeout", "", CodeTimeout},
+		{"timeout text", 500, "upstream request timed out", "", CodeTimeout},
+		{"server error", 502, "bad gateway", "", CodeUpstream5xx},
+		{"safety text on 5xx", 500, "safety system unavailable", "", CodeUpstream5xx},
+		{"unknown model", 404, "model not found", "", CodeInvalidRequest},
+		{"plain 403", 403, "forbidden", "", CodeInvalidRequest},
+		{"no status", 0, "", "", CodeUnknown},
+	}
+	for _, tc := range cases {
+		if got := Classify(tc.status, tc.message, []byte(tc.body)); got != tc.want {
+			t.Errorf("%s: Classify() = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if got := Normalize(" timeout "); got != CodeTimeout {
+		t.Fatalf("Normalize() = %q", got)
+	}
+	if got := Normalize(""); got != CodeUnknown {
+		t.Fatalf("Normalize(empty) = %q", got)
+	}
+}
+
+func TestBreakdownMergesUnknownCodes(t *testing.T) {
+	breakdown := Breakdown(map[string]int64{CodeTimeout: 2, "": 1, "legacy": 1})
+	if len(breakdown) != len(Codes) {
+		t.Fatalf("expected every code, got %+v", breakdown)
+	}
+	byCode := make(map[string]CodeCount, len(breakdown))
+	for _, item := range breakdown {
+		byCode[item.Code] = item
+	}
+	if byCode[CodeTimeout].Count != 2 || byCode[CodeTimeout].Share != 0.5 {
+		t.Fatalf("unexpected timeout entry %+v", byCode[CodeTimeout])
+	}
+	if byCode[CodeUnknown].Count != 2 || byCode[CodeRateLimited].Count != 0 {
+		t.Fatalf("unexpected breakdown %+v", breakdown)
+	}
+}
@@ -0,0 +1,160 @@
+package usagerollup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// Totals sums the usage of one breakdown bucket.
+type Totals struct {
+	Requests       int64 `json:"requests"`
+	FailedRequests int64 `json:"failed_requests"`
+	InputTokens    int64 `json:"input_tokens"`
+	OutputTokens   int64 `json:"output_tokens"`
+	CachedTokens   int64 `json:"cached_tokens"`
+	TotalTokens    int64 `json:"total_tokens"`
+	CostMicros     int64 `json:"cost_micros"`
+}
+
+func (t *Totals) add(o Totals) {
+	t.Requests += o.Requests
+	t.FailedRequests += o.FailedRequests
+	t.InputTokens += o.InputTokens
+	t.OutputTokens += o.OutputTokens
+	t.CachedTokens += o.CachedTokens
+	t.TotalTokens += o.TotalTokens
+	t.CostMicros += o.CostMicros
+}
+
+// BreakdownRow is the usage of one model on one local day.
+type BreakdownRow struct {
+	Day      string `json:"day"` // Local date, YYYY-MM-DD.
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Totals
+}
+
+// DayTotals is the usage of one local day across models.
+type DayTotals struct {
+	Day string `json:"day"` // Local date, YYYY-MM-DD.
+	Totals
+}
+
+// ModelTotals is the usage of one model across the range.
+type ModelTotals struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Totals
+}
+
+// Breakdown is the usage of one API key per day and per model.
+type Breakdown struct {
+	From   string         `json:"from"`   // First local date, inclusive.
+	To     string         `json:"to"`     // Last local date, inclusive.
+	Total  Totals         `json:"total"`  // Whole range.
+	Days   []DayTotals    `json:"days"`   // Every day of the range, zero-filled.
+	Models []ModelTotals  `json:"models"` // Models by descending cost.
+	Rows   []BreakdownRow `json:"rows"`   // Day and model buckets, by day then model.
+}
+
+// breakdownBucket is one grouped sum read from usages or usage_daily.
+type breakdownBucket struct {
+	Provider string
+	Model    string
+	Totals
+}
+
+const breakdownSums = "provider, model, %s AS requests, " +
+	"COALESCE(SUM(%s), 0) AS failed_requests, " +
+	"COALESCE(SUM(input_tokens), 0) AS input_tokens, COALESCE(SUM(output_tokens), 0) AS output_tokens, " +
+	"COALESCE(SUM(cached_tokens), 0) AS cached_tokens, COALESCE(SUM(total_tokens), 0) AS total_tokens, " +
+	"COALESCE(SUM(cost_micros), 0) AS cost_micros"
+
+// APIKeyBreakdown sums the usage of apiKeyID for every local day from the day containing
+// from through the day containing to. Days already rolled up are read from usage_daily.
+func APIKeyBreakdown(ctx context.Context, db *gorm.DB, apiKeyID uint64, from, to time.Time) (*Breakdown, error) {
+	if db == nil {
+		return nil, errors.New("usage breakdown: nil db")
+	}
+	first, last := DayStart(from), DayStart(to)
+	if last.Before(first) {
+		return nil, errors.New("usage breakdown: to before from")
+	}
+	out := &Breakdown{
+		From:   first.Format(time.DateOnly),
+		To:     last.Format(time.DateOnly),
+		Days:   make([]DayTotals, 0),
+		Models: make([]ModelTotals, 0),
+		Rows:   make([]BreakdownRow, 0),
+	}
+	byModel := make(map[string]*ModelTotals)
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		label := day.Format(time.DateOnly)
+		var live []breakdownBucket
+		if errLive := db.WithContext(ctx).Model(&models.Usage{}).
+			Select(fmt.Sprintf(breakdownSums, "COUNT(*)", "CASE WHEN failed THEN 1 ELSE 0 END")).
+			Where("api_key_id = ? AND requested_at >= ? AND requested_at < ?", apiKeyID, day.UTC(), day.AddDate(0, 0, 1).UTC()).
+			Group("provider, model").
+			Scan(&live).Error; errLive != nil {
+			return nil, fmt.Errorf("usage breakdown %s: aggregate usages: %w", label, errLive)
+		}
+		var rolled []breakdownBucket
+		if errDaily := db.WithContext(ctx).Model(&models.UsageDaily{}).
+			Select(fmt.Sprintf(breakdownSums, "COALESCE(SUM(requests), 0)", "failed_requests")).
+			Where("api_key_id = ? AND day >= ? AND day < ?", apiKeyID, day, day.AddDate(0, 0, 1)).
+			Group("provider, model").
+			Scan(&rolled).Error; errDaily != nil {
+			return nil, fmt.Errorf("usage breakdown %s: aggregate usage_daily: %w", label, errDaily)
+		}
+
+		dayTotals := DayTotals{Day: label}
+		merged := make(map[string]*BreakdownRow)
+		keys := make([]string, 0)
+		for _, bucket := range append(live, rolled...) {
+			key := bucket.Provider + "\x00" + bucket.Model
+			row, ok := merged[key]
+			if !ok {
+				row = &BreakdownRow{Day: label, Provider: bucket.Provider, Model: bucket.Model}
+				merged[key] = row
+				keys = append(keys, key)
+			}
+			row.add(bucket.Totals)
+			dayTotals.add(bucket.Totals)
+
+			total, ok := byModel[key]
+			if !ok {
+				total = &ModelTotals{Provider: bucket.Provider, Model: bucket.Model}
+				byModel[key] = total
+			}
+			total.add(bucket.Totals)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			out.Rows = append(out.Rows, *merged[key])
+		}
+		out.Days = append(out.Days, dayTotals)
+		out.Total.add(dayTotals.Totals)
+	}
+	for _, total := range byModel {
+		out.Models = append(out.Models, *total)
+	}
+	sort.Slice(out.Models, func(i, j int) bool {
+		if out.Models[i].CostMicros != out.Models[j].CostMicros {
+			return out.Models[i].CostMicros > out.Models[j].CostMicros
+		}
+		if out.Models[i].Provider != out.Models[j].Provider {
+			return out.Models[i].Provider < out.Models[j].Provider
+		}
+		return out.Models[i].Model < out.Models[j].Model
+	})
+	return out, nil
+}
@@ -0,0 +1,268 @@
+// Package usagerollup folds usages rows that left the retention window into the
+// usage_daily aggregate table, optionally archiving the raw rows first.
+package usagerollup
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// archiveBatchSize bounds the usages rows loaded at once while archiving.
+const archiveBatchSize = 1000
+
+// Result summarizes the rollup of one day.
+type Result struct {
+	Day         time.Time // Local midnight that starts the day.
+	Rows        int64     // Raw usages rows folded and deleted.
+	Buckets     int       // usage_daily rows created or updated.
+	ArchivePath string    // Archive file written, if any.
+}
+
+// DayStart returns the local midnight that starts the day containing t.
+func DayStart(t time.Time) time.Time {
+	local := t.In(time.Local)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// RollupBefore folds every usages row requested before the local day containing cutoff,
+// one day per transaction, oldest first. Rows from the cutoff day itself are kept until
+// the whole day has aged out.
+func RollupBefore(ctx context.Context, db *gorm.DB, cutoff time.Time, archiveDir string) ([]Result, error) {
+	if db == nil {
+		return nil, errors.New("usage rollup: nil db")
+	}
+	boundary := DayStart(cutoff)
+	var results []Result
+	for {
+		if errCtx := ctx.Err(); errCtx != nil {
+			return results, errCtx
+		}
+		var oldest models.Usage
+		errFind := db.WithContext(ctx).
+			Select("id", "requested_at").
+			Where("requested_at < ?", boundary.UTC()).
+			Order("requested_at ASC").
+			Take(&oldest).Error
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return results, nil
+		}
+		if errFind != nil {
+			return results, fmt.Errorf("usage rollup: find oldest row: %w", errFind)
+		}
+		result, errDay := RollupDay(ctx, db, oldest.RequestedAt, archiveDir)
+		if errDay != nil {
+			return results, errDay
+		}
+		if result.Rows == 0 {
+			// Nothing matched the day window; stop rather than spin on a clock-skewed row.
+			return results, nil
+		}
+		results = append(results, result)
+	}
+}
+
+// bucket is one aggregated usage_daily row before upsert.
+type bucket struct {
+	Provider        string
+	Model           string
+	UserID          uint64
+	APIKeyID        uint64
+	Requests        int64
+	FailedRequests  int64
+	InputTokens     int64
+	OutputTokens    int64
+	ReasoningTokens int64
+	CachedTokens    int64
+	TotalTokens     int64
+	CostMicros      int64
+
+	EnergyMilliWh    int64
+	CarbonMilligrams int64
+}
+
+// RollupDay folds the usages rows of the local day containing day into usage_daily and
+// deletes them in one transaction. Buckets already present are incremented, so a day can
+// be rolled up again when late rows arrive. When archiveDir is set the rows are first
+// written to a gzip-compressed JSONL file that is only kept if the transaction commits.
+func RollupDay(ctx context.Context, db *gorm.DB, day time.Time, archiveDir string) (Result, error) {
+	start := DayStart(day)
+	end := start.AddDate(0, 0, 1)
+	result := Result{Day: start}
+	label := start.Format(time.DateOnly)
+
+	var maxID struct {
+		ID *uint64
+	}
+	if errScan := db.WithContext(ctx).
+		Model(&models.Usage{}).
+		Select("MAX(id) AS id").
+		Where("requested_at >= ? AND requested_at < ?", start.UTC(), end.UTC()).
+		Scan(&maxID).Error; errScan != nil {
+		return result, fmt.Errorf("usage rollup %s: find rows: %w", label, errScan)
+	}
+	if maxID.ID == nil {
+		return result, nil
+	}
+	// Rows inserted after this point are left for the next run.
+	scope := func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("requested_at >= ? AND requested_at < ? AND id <= ?", start.UTC(), end.UTC(), *maxID.ID)
+	}
+
+	var tmpPath string
+	if archiveDir != "" {
+		path, errArchive := archiveRows(ctx, db, scope, archiveDir, fmt.Sprintf("usages-%s-%d.jsonl.gz", label, *maxID.ID))
+		if errArchive != nil {
+			return result, fmt.Errorf("usage rollup %s: archive: %w", label, errArchive)
+		}
+		tmpPath = path
+		defer func() {
+			if tmpPath != "" {
+				_ = os.Remove(tmpPath)
+			}
+		}()
+	}
+
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var buckets []bucket
+		if errScan := scope(tx.Model(&models.Usage{})).
+			Select(`
+				provider,
+				model,
+				COALESCE(user_id, 0) AS user_id,
+				COALESCE(api_key_id, 0) AS api_key_id,
+				COUNT(*) AS requests,
+				COALESCE(SUM(CASE WHEN failed THEN 1 ELSE 0 END), 0) AS failed_requests,
+				COALESCE(SUM(input_tokens), 0) AS input_tokens,
+				COALESCE(SUM(output_tokens), 0) AS output_tokens,
+				COALESCE(SUM(reasoning_tokens), 0) AS reasoning_tokens,
+				COALESCE(SUM(cached_tokens), 0) AS cached_tokens,
+				COALESCE(SUM(total_tokens), 0) AS total_tokens,
+				COALESCE(SUM(cost_micros), 0) AS cost_micros,
+				COALESCE(SUM(energy_milli_wh), 0) AS energy_milli_wh,
+				COALESCE(SUM(carbon_milligrams), 0) AS carbon_milligrams
+			`).
+			Group("provider, model, COALESCE(user_id, 0), COALESCE(api_key_id, 0)").
+			Scan(&buckets).Error; errScan != nil {
+			return fmt.Errorf("aggregate: %w", errScan)
+		}
+
+		now := time.Now().UTC()
+		for _, b := range buckets {
+			row := models.UsageDaily{
+				Day:             start,
+				Provider:        b.Provider,
+				Model:           b.Model,
+				UserID:          b.UserID,
+				APIKeyID:        b.APIKeyID,
+				Requests:        b.Requests,
+				FailedRequests:  b.FailedRequests,
+				InputTokens:     b.InputTokens,
+				OutputTokens:    b.OutputTokens,
+				ReasoningTokens: b.ReasoningTokens,
+				CachedTokens:    b.CachedTokens,
+				TotalTokens:     b.TotalTokens,
+				CostMicros:      b.CostMicros,
+
+				EnergyMilliWh:    b.EnergyMilliWh,
+				CarbonMilligrams: b.CarbonMilligrams,
+
+				CreatedAt: now,
+				UpdatedAt: now,
+			}
+			if errUpsert := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "day"}, {Name: "provider"}, {Name: "model"}, {Name: "user_id"}, {Name: "api_key_id"}},
+				DoUpdates: clause.Assignments(map[string]any{
+					"requests":          gorm.Expr("usage_daily.requests + excluded.requests"),
+					"failed_requests":   gorm.Expr("usage_daily.failed_requests + excluded.failed_requests"),
+					"input_tokens":      gorm.Expr("usage_daily.input_tokens + excluded.input_tokens"),
+					"output_tokens":     gorm.Expr("usage_daily.output_tokens + excluded.output_tokens"),
+					"reasoning_tokens":  gorm.Expr("usage_daily.reasoning_tokens + excluded.reasoning_tokens"),
+					"cached_tokens":     gorm.Expr("usage_daily.cached_tokens + excluded.cached_tokens"),
+					"total_tokens":      gorm.Expr("usage_daily.total_tokens + excluded.total_tokens"),
+					"cost_micros":       gorm.Expr("usage_daily.cost_micros + excluded.cost_micros"),
+					"energy_milli_wh":   gorm.Expr("usage_daily.energy_milli_wh + excluded.energy_milli_wh"),
+					"carbon_milligrams": gorm.Expr("usage_daily.carbon_milligrams + excluded.carbon_milligrams"),
+					"updated_at":        now,
+				}),
+			}).Create(&row).Error; errUpsert != nil {
+				return fmt.Errorf("upsert bucket: %w", errUpsert)
+			}
+		}
+
+		res := scope(tx).Delete(&models.Usage{})
+		if res.Error != nil {
+			return fmt.Errorf("delete rows: %w", res.Error)
+		}
+		result.Rows = res.RowsAffected
+		result.Buckets = len(buckets)
+		return nil
+	})
+	if errTx != nil {
+		return Result{Day: start}, fmt.Errorf("usage rollup %s: %w", label, errTx)
+	}
+
+	if tmpPath != "" {
+		finalPath := filepath.Join(archiveDir, fmt.Sprintf("usages-%s-%d.jsonl.gz", label, *maxID.ID))
+		if errRename := os.Rename(tmpPath, finalPath); errRename != nil {
+			return result, fmt.Errorf("usage rollup %s: finalize archive: %w", label, errRename)
+		}
+		tmpPath = ""
+		result.ArchivePath = finalPath
+	}
+	return result, nil
+}
+
+// archiveRows writes the scoped usages rows as gzip-compressed JSONL to a temporary file in
+// dir and returns its path. The caller renames it once the rows are deleted.
+func archiveRows(ctx context.Context, db *gorm.DB, scope func(*gorm.DB) *gorm.DB, dir, name string) (path string, err error) {
+	if errMkdir := os.MkdirAll(dir, 0o700); errMkdir != nil {
+		return "", errMkdir
+	}
+	file, errCreate := os.CreateTemp(dir, "."+name+".*.tmp")
+	if errCreate != nil {
+		return "", errCreate
+	}
+	defer func() {
+		if err != nil {
+			_ = file.Close()
+			_ = os.Remove(file.Name())
+		}
+	}()
+
+	zw := gzip.NewWriter(file)
+	enc := json.NewEncoder(zw)
+	var rows []models.Usage
+	errBatches := scope(db.WithContext(ctx).Model(&models.Usage{})).
+		Order("id ASC").
+		FindInBatches(&rows, archiveBatchSize, func(_ *gorm.DB, _ int) error {
+			for i := range rows {
+				if errEncode := enc.Encode(&rows[i]); errEncode != nil {
+					return errEncode
+				}
+			}
+			return nil
+		}).Error
+	if errBatches != nil {
+		return "", errBatches
+	}
+	if errClose := zw.Close(); errClose != nil {
+		return "", errClose
+	}
+	if errSync := file.Sync(); errSync != nil {
+		return "", errSync
+	}
+	if errClose := file.Close(); errClose != nil {
+		return "", errClose
+	}
+	return file.Name(), nil
+}
@@ -0,0 +1,165 @@
+package usagerollup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func setupRollupDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:usage_rollup_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func TestRollupBeforeFoldsAgedRowsAndArchivesThem(t *testing.T) {
+	conn := setupRollupDB(t)
+	ctx := context.Background()
+	today := DayStart(time.Now())
+	old := today.AddDate(0, 0, -100)
+	older := today.AddDate(0, 0, -101)
+
+	userA, keyA := uint64(1), uint64(7)
+	rows := []models.Usage{
+		{Provider: "codex", Model: "gpt-5", UserID: &userA, APIKeyID: &keyA, RequestedAt: old.Add(time.Hour), InputTokens: 10, OutputTokens: 5, TotalTokens: 15, CostMicros: 100},
+		{Provider: "codex", Model: "gpt-5", UserID: &userA, APIKeyID: &keyA, RequestedAt: old.Add(2 * time.Hour), Failed: true, TotalTokens: 1, CostMicros: 0},
+		{Provider: "claude", Model: "sonnet", RequestedAt: older.Add(time.Hour), TotalTokens: 7, CostMicros: 70},
+		{Provider: "codex", Model: "gpt-5", UserID: &userA, RequestedAt: today.Add(time.Hour), TotalTokens: 99, CostMicros: 999},
+	}
+	if errCreate := conn.Create(&rows).Error; errCreate != nil {
+		t.Fatalf("create usage: %v", errCreate)
+	}
+
+	archiveDir := filepath.Join(t.TempDir(), "archive")
+	results, errRollup := RollupBefore(ctx, conn, today.AddDate(0, 0, -90), archiveDir)
+	if errRollup != nil {
+		t.Fatalf("RollupBefore: %v", errRollup)
+	}
+	if len(results) != 2 || !results[0].Day.Equal(older) || results[1].Rows != 2 {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+
+	var remaining int64
+	if errCount := conn.Model(&models.Usage{}).Count(&remaining).Error; errCount != nil {
+		t.Fatalf("count usage: %v", errCount)
+	}
+	if remaining != 1 {
+		t.Fatalf("expected only the recent row to remain, got %d", remaining)
+	}
+
+	var bucket models.UsageDaily
+	if errFind := conn.Where("provider = ? AND model = ? AND user_id = ? AND api_key_id = ?", "codex", "gpt-5", userA, keyA).First(&bucket).Error; errFind != nil {
+		t.Fatalf("find bucket: %v", errFind)
+	}
+	if bucket.Requests != 2 || bucket.FailedRequests != 1 || bucket.TotalTokens != 16 || bucket.CostMicros != 100 {
+		t.Fatalf("unexpected bucket: %+v", bucket)
+	}
+
+	archived := countArchivedLines(t, results[1].ArchivePath)
+	if archived != 2 {
+		t.Fatalf("expected 2 archived rows, got %d", archived)
+	}
+
+	// Late rows for an already rolled-up day are added to the existing bucket.
+	late := models.Usage{Provider: "codex", Model: "gpt-5", UserID: &userA, APIKeyID: &keyA, RequestedAt: old.Add(3 * time.Hour), TotalTokens: 4, CostMicros: 40}
+	if errCreate := conn.Create(&late).Error; errCreate != nil {
+		t.Fatalf("create late usage: %v", errCreate)
+	}
+	if _, errRollup = RollupBefore(ctx, conn, today.AddDate(0, 0, -90), ""); errRollup != nil {
+		t.Fatalf("RollupBefore late: %v", errRollup)
+	}
+	if errFind := conn.First(&bucket, bucket.ID).Error; errFind != nil {
+		t.Fatalf("reload bucket: %v", errFind)
+	}
+	if bucket.Requests != 3 || bucket.TotalTokens != 20 || bucket.CostMicros != 140 {
+		t.Fatalf("expected late row folded into bucket, got %+v", bucket)
+	}
+}
+
+func countArchivedLines(t *testing.T, path string) int {
+	t.Helper()
+	if path == "" {
+		t.Fatalf("expected an archive path")
+	}
+	file, errOpen := os.Open(path)
+	if errOpen != nil {
+		t.Fatalf("open archive: %v", errOpen)
+	}
+	defer func() { _ = file.Close() }()
+	zr, errGzip := gzip.NewReader(file)
+	if errGzip != nil {
+		t.Fatalf("gzip reader: %v", errGzip)
+	}
+	count := 0
+	scanner := bufio.NewScanner(zr)
+	for scanner.Scan() {
+		var row models.Usage
+		if errDecode := json.Unmarshal(scanner.Bytes(), &row); errDecode != nil {
+			t.Fatalf("decode archived row: %v", errDecode)
+		}
+		count++
+	}
+	return count
+}
+
+func TestAPIKeyBreakdownMergesRolledUpDays(t *testing.T) {
+	conn := setupRollupDB(t)
+	ctx := context.Background()
+	today := DayStart(time.Now())
+	old := today.AddDate(0, 0, -100)
+
+	userID, keyID, otherKey := uint64(1), uint64(7), uint64(8)
+	rows := []models.Usage{
+		{Provider: "codex", Model: "gpt-5", UserID: &userID, APIKeyID: &keyID, RequestedAt: old.Add(time.Hour), TotalTokens: 15, CostMicros: 100},
+		{Provider: "codex", Model: "gpt-5", UserID: &userID, APIKeyID: &keyID, RequestedAt: today.Add(time.Hour), TotalTokens: 20, CostMicros: 300},
+		{Provider: "claude", Model: "sonnet", UserID: &userID, APIKeyID: &keyID, RequestedAt: today.Add(2 * time.Hour), Failed: true, TotalTokens: 5, CostMicros: 50},
+		{Provider: "codex", Model: "gpt-5", UserID: &userID, APIKeyID: &otherKey, RequestedAt: today.Add(time.Hour), TotalTokens: 999, CostMicros: 999},
+	}
+	if errCreate := conn.Create(&rows).Error; errCreate != nil {
+		t.Fatalf("create usage: %v", errCreate)
+	}
+	if _, errRollup := RollupBefore(ctx, conn, today.AddDate(0, 0, -90), ""); errRollup != nil {
+		t.Fatalf("RollupBefore: %v", errRollup)
+	}
+
+	breakdown, errBreakdown := APIKeyBreakdown(ctx, conn, keyID, old, today)
+	if errBreakdown != nil {
+		t.Fatalf("APIKeyBreakdown: %v", errBreakdown)
+	}
+	if len(breakdown.Days) != 101 {
+		t.Fatalf("days = %d, want 101", len(breakdown.Days))
+	}
+	if first := breakdown.Days[0]; first.Requests != 1 || first.CostMicros != 100 {
+		t.Fatalf("rolled up day = %+v", first)
+	}
+	if last := breakdown.Days[100]; last.Requests != 2 || last.FailedRequests != 1 || last.CostMicros != 350 {
+		t.Fatalf("live day = %+v", last)
+	}
+	if breakdown.Total.Requests != 3 || breakdown.Total.TotalTokens != 40 {
+		t.Fatalf("total = %+v", breakdown.Total)
+	}
+	if len(breakdown.Models) != 2 || breakdown.Models[0].Model != "gpt-5" || breakdown.Models[0].CostMicros != 400 {
+		t.Fatalf("models = %+v", breakdown.Models)
+	}
+	if len(breakdown.Rows) != 3 {
+		t.Fatalf("rows = %+v", breakdown.Rows)
+	}
+}
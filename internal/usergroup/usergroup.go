@@ -0,0 +1,169 @@
+// Package usergroup resolves nested user groups: a group inherits every policy it leaves at
+// zero from its nearest ancestor that sets it, and falls back to its ancestors' billing rules.
+package usergroup
+
+import (
+	"context"
+	"errors"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// MaxDepth bounds how many ancestors are followed, so a corrupt hierarchy cannot loop.
+const MaxDepth = 8
+
+// Hierarchy errors.
+var (
+	// ErrParentNotFound is returned when the parent group does not exist.
+	ErrParentNotFound = errors.New("parent user group not found")
+	// ErrParentCycle is returned when the parent would make a group its own ancestor.
+	ErrParentCycle = errors.New("parent user group would create a cycle")
+	// ErrTooDeep is returned when the parent would nest groups deeper than MaxDepth.
+	ErrTooDeep = errors.New("user group nesting is too deep")
+)
+
+// Policy holds a group's effective settings after inheritance.
+type Policy struct {
+	RateLimit             int
+	RPMLimit              int
+	TPMLimit              int
+	MaxConcurrentRequests int
+	DailySpendLimit       float64
+	MonthlySpendLimit     float64
+	// BillingRuleGroupIDs lists the groups whose billing rules are tried in order: each group
+	// of the chain, followed by its billing rule group.
+	BillingRuleGroupIDs []uint64
+	// RequestLog reports whether the group or an ancestor captures request bodies.
+	RequestLog bool
+}
+
+// Chain returns the group and its ancestors, nearest first. A missing group yields an empty
+// chain; a missing parent or a cycle ends the chain.
+func Chain(ctx context.Context, db *gorm.DB, groupID uint64) ([]models.UserGroup, error) {
+	if db == nil || groupID == 0 {
+		return nil, nil
+	}
+	var chain []models.UserGroup
+	seen := make(map[uint64]struct{})
+	next := groupID
+	for len(chain) <= MaxDepth {
+		if _, ok := seen[next]; ok {
+			break
+		}
+		seen[next] = struct{}{}
+		var group models.UserGroup
+		if errFind := db.WithContext(ctx).
+			Select("id", "parent_id", "rate_limit", "rpm_limit", "tpm_limit", "max_concurrent_requests",
+				"daily_spend_limit", "monthly_spend_limit", "billing_rule_group_id", "request_log_enabled").
+			Where("id = ?", next).
+			Take(&group).Error; errFind != nil {
+			if errors.Is(errFind, gorm.ErrRecordNotFound) {
+				break
+			}
+			return nil, errFind
+		}
+		chain = append(chain, group)
+		if group.ParentID == nil || *group.ParentID == 0 {
+			break
+		}
+		next = *group.ParentID
+	}
+	return chain, nil
+}
+
+// Effective merges a chain into the policy of its first group.
+func Effective(chain []models.UserGroup) Policy {
+	var policy Policy
+	seen := make(map[uint64]struct{})
+	addRuleGroup := func(id *uint64) {
+		if id == nil || *id == 0 {
+			return
+		}
+		if _, ok := seen[*id]; ok {
+			return
+		}
+		seen[*id] = struct{}{}
+		policy.BillingRuleGroupIDs = append(policy.BillingRuleGroupIDs, *id)
+	}
+	for i := range chain {
+		group := &chain[i]
+		policy.RateLimit = inheritInt(policy.RateLimit, group.RateLimit)
+		policy.RPMLimit = inheritInt(policy.RPMLimit, group.RPMLimit)
+		policy.TPMLimit = inheritInt(policy.TPMLimit, group.TPMLimit)
+		policy.MaxConcurrentRequests = inheritInt(policy.MaxConcurrentRequests, group.MaxConcurrentRequests)
+		if policy.DailySpendLimit <= 0 {
+			policy.DailySpendLimit = group.DailySpendLimit
+		}
+		if policy.MonthlySpendLimit <= 0 {
+			policy.MonthlySpendLimit = group.MonthlySpendLimit
+		}
+		policy.RequestLog = policy.RequestLog || group.RequestLogEnabled
+		addRuleGroup(&group.ID)
+		addRuleGroup(group.BillingRuleGroupID)
+	}
+	return policy
+}
+
+func inheritInt(current, candidate int) int {
+	if current > 0 {
+		return current
+	}
+	return candidate
+}
+
+// Resolve loads a group's effective policy.
+func Resolve(ctx context.Context, db *gorm.DB, groupID uint64) (Policy, error) {
+	chain, errChain := Chain(ctx, db, groupID)
+	if errChain != nil {
+		return Policy{}, errChain
+	}
+	return Effective(chain), nil
+}
+
+// ValidateParent checks that parentID may become the parent of groupID; groupID is zero for
+// a group that does not exist yet.
+func ValidateParent(ctx context.Context, db *gorm.DB, groupID, parentID uint64) error {
+	if parentID == 0 {
+		return nil
+	}
+	if parentID == groupID {
+		return ErrParentCycle
+	}
+	chain, errChain := Chain(ctx, db, parentID)
+	if errChain != nil {
+		return errChain
+	}
+	if len(chain) == 0 {
+		return ErrParentNotFound
+	}
+	for _, ancestor := range chain {
+		if ancestor.ID == groupID {
+			return ErrParentCycle
+		}
+	}
+	if len(chain)+1+subtreeDepth(ctx, db, groupID) > MaxDepth {
+		return ErrTooDeep
+	}
+	return nil
+}
+
+// subtreeDepth returns how many levels of descendants hang below groupID.
+func subtreeDepth(ctx context.Context, db *gorm.DB, groupID uint64) int {
+	if groupID == 0 {
+		return 0
+	}
+	depth := 0
+	level := []uint64{groupID}
+	for len(level) > 0 && depth <= MaxDepth {
+		var children []uint64
+		if errFind := db.WithContext(ctx).Model(&models.UserGroup{}).
+			Where("parent_id IN ?", level).
+			Pluck("id", &children).Error; errFind != nil || len(children) == 0 {
+			break
+		}
+		depth++
+		level = children
+	}
+	return depth
+}
@@ -0,0 +1,106 @@
+package usergroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func setupUserGroupDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:usergroup_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func createGroup(t *testing.T, conn *gorm.DB, group models.UserGroup) models.UserGroup {
+	t.Helper()
+	if errCreate := conn.Create(&group).Error; errCreate != nil {
+		t.Fatalf("create group %q: %v", group.Name, errCreate)
+	}
+	return group
+}
+
+func TestResolveInheritsFromParents(t *testing.T) {
+	conn := setupUserGroupDB(t)
+	ctx := context.Background()
+	pricing := createGroup(t, conn, models.UserGroup{Name: "pricing"})
+	root := createGroup(t, conn, models.UserGroup{Name: "root", RateLimit: 10, RPMLimit: 60, DailySpendLimit: 5, MonthlySpendLimit: 100})
+	team := createGroup(t, conn, models.UserGroup{Name: "team", ParentID: &root.ID, RPMLimit: 30, BillingRuleGroupID: &pricing.ID})
+	member := createGroup(t, conn, models.UserGroup{Name: "member", ParentID: &team.ID, DailySpendLimit: 2})
+
+	policy, errResolve := Resolve(ctx, conn, member.ID)
+	if errResolve != nil {
+		t.Fatalf("resolve: %v", errResolve)
+	}
+	if policy.RateLimit != 10 || policy.RPMLimit != 30 || policy.DailySpendLimit != 2 || policy.MonthlySpendLimit != 100 {
+		t.Fatalf("unexpected policy %+v", policy)
+	}
+	wantRules := []uint64{member.ID, team.ID, pricing.ID, root.ID}
+	if !reflect.DeepEqual(policy.BillingRuleGroupIDs, wantRules) {
+		t.Fatalf("billing rule groups = %v, want %v", policy.BillingRuleGroupIDs, wantRules)
+	}
+
+	missing, errMissing := Resolve(ctx, conn, 9999)
+	if errMissing != nil || !reflect.DeepEqual(missing, Policy{}) {
+		t.Fatalf("missing group: policy %+v err %v", missing, errMissing)
+	}
+}
+
+func TestResolveStopsOnCycle(t *testing.T) {
+	conn := setupUserGroupDB(t)
+	a := createGroup(t, conn, models.UserGroup{Name: "a"})
+	b := createGroup(t, conn, models.UserGroup{Name: "b", ParentID: &a.ID, TPMLimit: 500})
+	if errUpdate := conn.Model(&models.UserGroup{}).Where("id = ?", a.ID).Update("parent_id", b.ID).Error; errUpdate != nil {
+		t.Fatalf("update parent: %v", errUpdate)
+	}
+	chain, errChain := Chain(context.Background(), conn, a.ID)
+	if errChain != nil {
+		t.Fatalf("chain: %v", errChain)
+	}
+	if len(chain) != 2 || Effective(chain).TPMLimit != 500 {
+		t.Fatalf("unexpected chain %+v", chain)
+	}
+}
+
+func TestValidateParent(t *testing.T) {
+	conn := setupUserGroupDB(t)
+	ctx := context.Background()
+	root := createGroup(t, conn, models.UserGroup{Name: "root"})
+	child := createGroup(t, conn, models.UserGroup{Name: "child", ParentID: &root.ID})
+
+	if errValid := ValidateParent(ctx, conn, 0, child.ID); errValid != nil {
+		t.Fatalf("new group under child: %v", errValid)
+	}
+	if errSelf := ValidateParent(ctx, conn, root.ID, root.ID); !errors.Is(errSelf, ErrParentCycle) {
+		t.Fatalf("self parent: %v", errSelf)
+	}
+	if errCycle := ValidateParent(ctx, conn, root.ID, child.ID); !errors.Is(errCycle, ErrParentCycle) {
+		t.Fatalf("cycle: %v", errCycle)
+	}
+	if errMissing := ValidateParent(ctx, conn, child.ID, 9999); !errors.Is(errMissing, ErrParentNotFound) {
+		t.Fatalf("missing parent: %v", errMissing)
+	}
+
+	parent := child
+	for i := 0; i < MaxDepth-2; i++ {
+		parent = createGroup(t, conn, models.UserGroup{Name: fmt.Sprintf("level-%d", i), ParentID: &parent.ID})
+	}
+	if errDeep := ValidateParent(ctx, conn, 0, parent.ID); !errors.Is(errDeep, ErrTooDeep) {
+		t.Fatalf("deep nesting: %v", errDeep)
+	}
+}
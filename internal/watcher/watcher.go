@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -21,13 +22,22 @@ import (
 	sdkcliproxy "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/authschedule"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/canary"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/chaos"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/cluster"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
+	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/environments"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelmapping"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/providerkeys"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/retrypolicy"
 	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
 	log "github.com/sirupsen/logrus"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Default timings and buffer sizes for the watcher loop.
@@ -89,6 +99,9 @@ type dbWatcher struct {
 	authDir    string
 	reload     func(*sdkconfig.Config)
 
+	// environment is the tag of the credentials served by this instance; empty serves all.
+	environment string
+
 	pollInterval time.Duration
 
 	// config polling
@@ -97,6 +110,11 @@ type dbWatcher struct {
 	cfgHash   string
 	forceAuth bool
 
+	// cluster resync requests from peer instances
+	resyncConfig   bool
+	resyncSettings bool
+	resync         chan struct{}
+
 	// auth snapshot
 	authMu       sync.RWMutex
 	authStates   map[string]authState
@@ -119,6 +137,14 @@ type dbWatcher struct {
 	mappingLatestID  uint64
 	mappingHasLatest bool
 
+	// retry policy snapshot; the file values apply while no default policy exists
+	retryLatestAt        time.Time
+	retryLatestID        uint64
+	retryCount           int
+	retryHasSnapshot     bool
+	fileRequestRetry     int
+	fileMaxRetryInterval int
+
 	// provider key snapshot (stored in ProviderAPIKey + ModelMapping tables)
 	providerLatestAt  time.Time
 	providerLatestID  uint64
@@ -144,14 +170,20 @@ type dbWatcher struct {
 // NewDatabaseWatcherFactory builds a watcher factory backed by database polling.
 func NewDatabaseWatcherFactory(db *gorm.DB) sdkcliproxy.WatcherFactory {
 	return func(configPath, authDir string, reload func(*sdkconfig.Config)) (*sdkcliproxy.WatcherWrapper, error) {
+		envCfg, errEnv := config.LoadEnvironmentsConfig(config.ResolveConfigPath(configPath))
+		if errEnv != nil {
+			return nil, errEnv
+		}
 		w := &dbWatcher{
 			db:           db,
 			configPath:   strings.TrimSpace(configPath),
 			authDir:      strings.TrimSpace(authDir),
 			reload:       reload,
+			environment:  envCfg.Current,
 			pollInterval: defaultPollInterval,
 			authStates:   make(map[string]authState),
 			pending:      make(map[string]authUpdate, defaultDispatchBuffer),
+			resync:       make(chan struct{}, 1),
 		}
 		w.dispatchCond = sync.NewCond(&w.dispatchMu)
 		return buildWatcherWrapper(w)
@@ -178,9 +210,11 @@ func (w *dbWatcher) Start(ctx context.Context) error {
 	}
 	w.dispatchMu.Unlock()
 
+	unsubscribe := cluster.Subscribe(w.handleClusterEvent)
 	w.wg.Add(1)
 	go func() {
 		defer w.wg.Done()
+		defer unsubscribe()
 		w.run(ctx)
 	}()
 
@@ -265,6 +299,7 @@ func (w *dbWatcher) run(ctx context.Context) {
 	w.pollAuth(ctx, true)
 	w.pollSettings(ctx, true)
 	w.pollPayloadRules(ctx, true)
+	w.pollRetryPolicies(ctx, true)
 
 	ticker := time.NewTicker(w.pollInterval)
 	defer ticker.Stop()
@@ -274,15 +309,59 @@ func (w *dbWatcher) run(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
+			if chaos.ConfigSyncFault() {
+				log.Warn("watcher: config sync skipped by chaos fault")
+				continue
+			}
 			w.pollConfig(ctx)
 			w.pollProviderKeys(ctx, false)
 			w.pollAuth(ctx, w.consumeForceAuth())
 			w.pollSettings(ctx, false)
 			w.pollPayloadRules(ctx, false)
+			w.pollRetryPolicies(ctx, false)
+		case <-w.resync:
+			forceConfig, forceSettings := w.consumeResync()
+			if forceConfig {
+				w.pollProviderKeys(ctx, true)
+				w.pollAuth(ctx, true)
+				w.pollPayloadRules(ctx, true)
+				w.pollRetryPolicies(ctx, true)
+			}
+			if forceSettings {
+				w.pollSettings(ctx, true)
+			}
 		}
 	}
 }
 
+// handleClusterEvent queues a forced resync for changes made on other instances.
+func (w *dbWatcher) handleClusterEvent(event cluster.Event) {
+	if event.InstanceID == cluster.InstanceID() {
+		return
+	}
+	w.cfgMu.Lock()
+	switch event.Kind {
+	case cluster.KindConfigChanged:
+		w.resyncConfig = true
+	case cluster.KindSettingsChanged:
+		w.resyncSettings = true
+	}
+	w.cfgMu.Unlock()
+	select {
+	case w.resync <- struct{}{}:
+	default:
+	}
+}
+
+// consumeResync returns and clears the pending cluster resync flags.
+func (w *dbWatcher) consumeResync() (bool, bool) {
+	w.cfgMu.Lock()
+	forceConfig, forceSettings := w.resyncConfig, w.resyncSettings
+	w.resyncConfig, w.resyncSettings = false, false
+	w.cfgMu.Unlock()
+	return forceConfig, forceSettings
+}
+
 // pollConfig reloads the config file when its contents change.
 func (w *dbWatcher) pollConfig(ctx context.Context) {
 	if w == nil || strings.TrimSpace(w.configPath) == "" {
@@ -310,6 +389,9 @@ func (w *dbWatcher) pollConfig(ctx context.Context) {
 	}
 	cfg.RemoteManagement.DisableControlPanel = true
 	cfg.AuthDir, _ = os.Getwd()
+	w.fileRequestRetry = cfg.RequestRetry
+	w.fileMaxRetryInterval = cfg.MaxRetryInterval
+	w.applyRetryDefaults(cfg)
 
 	w.cfgMu.Lock()
 	w.cfg = cfg
@@ -434,7 +516,9 @@ func (w *dbWatcher) pollProviderKeys(ctx context.Context, force bool) {
 		baseCfg = &sdkconfig.Config{}
 	}
 	next := *baseCfg
-	providerkeys.ApplyToConfig(&next, providerRows, mappingRows)
+	servedRows := environments.FilterProviderKeys(providerRows, w.environment)
+	providerkeys.ApplyToConfig(&next, servedRows, mappingRows)
+	canary.StoreKeys(servedRows)
 
 	w.cfgMu.Lock()
 	w.cfg = &next
@@ -536,7 +620,7 @@ func (w *dbWatcher) pollAuth(ctx context.Context, force bool) {
 
 	var rows []models.Auth
 	if errFind := w.db.WithContext(qctx).
-		Select("key", "proxy_url", "content", "priority", "token_invalid", "created_at", "updated_at", "excluded_models").
+		Select("key", "proxy_url", "content", "priority", "token_invalid", "created_at", "updated_at", "excluded_models", "environments", "auth_group_id").
 		Where("is_available = ?", true).
 		Order("id ASC").
 		Find(&rows).Error; errFind != nil {
@@ -546,6 +630,8 @@ func (w *dbWatcher) pollAuth(ctx context.Context, force bool) {
 		log.WithError(errFind).Warn("db watcher: query auth records failed")
 		return
 	}
+	rows = environments.FilterAuths(rows, w.environment)
+	rows = authschedule.FilterAuths(qctx, w.db, rows)
 
 	nextStates := make(map[string]authState, len(rows))
 	nextAuths := make([]*coreauth.Auth, 0, len(rows))
@@ -571,15 +657,18 @@ func (w *dbWatcher) pollAuth(ctx context.Context, force bool) {
 	cfgSnapshot := w.cfg
 	w.cfgMu.RUnlock()
 	configAuths := synthesizeConfigAuths(cfgSnapshot)
+	apiKeyByAuthID := make(map[string]string, len(configAuths))
 	for _, auth := range configAuths {
 		if auth == nil || auth.ID == "" {
 			continue
 		}
+		apiKeyByAuthID[auth.ID] = auth.Attributes["api_key"]
 		key := auth.ID
 		nextStates[key] = authState{hash: hashAuth(auth), updatedAt: time.Time{}}
 		nextAuths = append(nextAuths, auth)
 		nextAuthByID[key] = auth
 	}
+	canary.StoreAuths(apiKeyByAuthID)
 
 	for id, st := range nextStates {
 		prev, ok := prevStates[id]
@@ -628,7 +717,8 @@ func (w *dbWatcher) pollSettings(ctx context.Context, force bool) {
 	errLatest := w.db.WithContext(qctx).
 		Model(&models.Setting{}).
 		Select("key", "updated_at").
-		Order("updated_at DESC NULLS LAST, key DESC").
+		Order(dbutil.NullsLastOrder(w.db, "updated_at", "DESC")).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "key"}, Desc: true}).
 		Limit(1).
 		Take(&latest).Error
 	if errLatest != nil {
@@ -666,7 +756,7 @@ func (w *dbWatcher) pollSettings(ctx context.Context, force bool) {
 	var rows []models.Setting
 	if errFind := w.db.WithContext(qctx).
 		Select("key", "value", "updated_at").
-		Order("key ASC").
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "key"}}).
 		Find(&rows).Error; errFind != nil {
 		if errors.Is(errFind, context.Canceled) {
 			return
@@ -876,6 +966,80 @@ func (w *dbWatcher) pollPayloadRules(ctx context.Context, force bool) {
 	w.mappingHasLatest = mappingHasLatest
 }
 
+// pollRetryPolicies reloads retry policies when they change and applies the default policy to
+// the SDK request-retry settings.
+func (w *dbWatcher) pollRetryPolicies(ctx context.Context, force bool) {
+	if w == nil || w.db == nil {
+		return
+	}
+	qctx, cancel := context.WithTimeout(ctx, defaultQueryTimeout)
+	defer cancel()
+
+	var rows []models.RetryPolicy
+	if errFind := w.db.WithContext(qctx).Order("id ASC").Find(&rows).Error; errFind != nil {
+		if errors.Is(errFind, context.Canceled) {
+			return
+		}
+		log.WithError(errFind).Warn("db watcher: query retry policies failed")
+		return
+	}
+	latestAt, latestID := time.Time{}, uint64(0)
+	for _, row := range rows {
+		updatedAt := row.UpdatedAt.UTC()
+		if updatedAt.After(latestAt) || (updatedAt.Equal(latestAt) && row.ID > latestID) {
+			latestAt, latestID = updatedAt, row.ID
+		}
+	}
+	if !force && w.retryHasSnapshot && len(rows) == w.retryCount && latestAt.Equal(w.retryLatestAt) && latestID == w.retryLatestID {
+		return
+	}
+
+	log.Infof("db watcher: retry policies changed, reloading (count=%d latest_updated_at=%s)", len(rows), latestAt.Format(time.RFC3339Nano))
+
+	policies := make([]retrypolicy.Policy, 0, len(rows))
+	for _, row := range rows {
+		if policy, ok := retrypolicy.FromModel(row); ok {
+			policies = append(policies, policy)
+		}
+	}
+	retrypolicy.Store(policies)
+
+	w.cfgMu.RLock()
+	cfg := w.cfg
+	w.cfgMu.RUnlock()
+	if cfg != nil {
+		next := *cfg
+		w.applyRetryDefaults(&next)
+		if next.RequestRetry != cfg.RequestRetry || next.MaxRetryInterval != cfg.MaxRetryInterval {
+			w.cfgMu.Lock()
+			w.cfg = &next
+			w.cfgMu.Unlock()
+			if w.reload != nil {
+				w.reload(&next)
+			}
+		}
+	}
+
+	w.retryLatestAt = latestAt
+	w.retryLatestID = latestID
+	w.retryCount = len(rows)
+	w.retryHasSnapshot = true
+}
+
+// applyRetryDefaults sets the SDK request-retry settings from the default retry policy, or
+// back to the config file values when there is none.
+func (w *dbWatcher) applyRetryDefaults(cfg *sdkconfig.Config) {
+	requestRetry, maxRetryInterval := w.fileRequestRetry, w.fileMaxRetryInterval
+	if policy, ok := retrypolicy.Default(); ok {
+		requestRetry = policy.MaxRetries
+		if policy.BackoffMax > 0 {
+			maxRetryInterval = int(math.Ceil(policy.BackoffMax.Seconds()))
+		}
+	}
+	cfg.RequestRetry = requestRetry
+	cfg.MaxRetryInterval = maxRetryInterval
+}
+
 // buildPayloadConfig converts payload rule rows into SDK payload configuration.
 func buildPayloadConfig(rows []payloadRuleRow) sdkconfig.PayloadConfig {
 	defaultRules := make([]sdkconfig.PayloadRule, 0)
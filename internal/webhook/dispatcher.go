@@ -0,0 +1,240 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultDispatchInterval = 15 * time.Second
+	defaultRequestTimeout   = 10 * time.Second
+	// MaxAttempts bounds how often one delivery is tried before it is marked failed.
+	MaxAttempts = 6
+	// baseBackoff is the delay before the first retry; it doubles on every further attempt.
+	baseBackoff = 30 * time.Second
+	// claimLease hides a claimed delivery from other dispatchers while it is being sent.
+	claimLease = 2 * time.Minute
+	batchSize  = 50
+	// maxErrorBytes caps how much of a failed response is kept in the delivery log.
+	maxErrorBytes = 512
+)
+
+// Backoff returns the delay after the given number of failed attempts.
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	return baseBackoff << (attempts - 1)
+}
+
+// Dispatcher sends due webhook deliveries.
+type Dispatcher struct {
+	db       *gorm.DB
+	client   *http.Client
+	interval time.Duration
+	now      func() time.Time
+	wake     chan struct{} // Signals newly queued deliveries.
+}
+
+// NewDispatcher constructs a webhook dispatcher; returns nil when db is nil.
+func NewDispatcher(db *gorm.DB) *Dispatcher {
+	if db == nil {
+		return nil
+	}
+	return &Dispatcher{
+		db:       db,
+		client:   &http.Client{Timeout: defaultRequestTimeout},
+		interval: defaultDispatchInterval,
+		now:      time.Now,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+var defaultDispatcher atomic.Pointer[Dispatcher]
+
+// SetDefault installs the process-wide dispatcher woken by admin actions.
+func SetDefault(d *Dispatcher) {
+	defaultDispatcher.Store(d)
+}
+
+// Default returns the process-wide dispatcher, or nil when none is running.
+func Default() *Dispatcher {
+	return defaultDispatcher.Load()
+}
+
+// Start launches the dispatch loop in a background goroutine.
+func (d *Dispatcher) Start(ctx context.Context) {
+	if d == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go d.run(ctx)
+	log.Infof("webhook dispatcher started (interval=%s)", d.interval)
+}
+
+// Wake asks the dispatcher to look for due deliveries without waiting for the next tick.
+func (d *Dispatcher) Wake() {
+	if d == nil {
+		return
+	}
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dispatcher) run(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, errRun := d.RunOnce(ctx); errRun != nil {
+			log.WithError(errRun).Warn("webhook dispatcher: run failed")
+		}
+		timer := time.NewTimer(d.interval)
+		select {
+		case <-ctx.Done():
+			if !timer.Stop() {
+				<-timer.C
+			}
+			return
+		case <-d.wake:
+			if !timer.Stop() {
+				<-timer.C
+			}
+		case <-timer.C:
+		}
+	}
+}
+
+// RunOnce sends every due pending delivery and returns how many were attempted.
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	if d == nil || d.db == nil {
+		return 0, nil
+	}
+	attempted := 0
+	for {
+		now := d.now().UTC()
+		var due []models.WebhookDelivery
+		if errFind := d.db.WithContext(ctx).
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryStatusPending, now).
+			Order("next_attempt_at ASC, id ASC").
+			Limit(batchSize).
+			Find(&due).Error; errFind != nil {
+			return attempted, fmt.Errorf("webhook: load due deliveries: %w", errFind)
+		}
+		if len(due) == 0 {
+			return attempted, nil
+		}
+		for i := range due {
+			if ctx.Err() != nil {
+				return attempted, ctx.Err()
+			}
+			claimed, errClaim := d.claim(ctx, &due[i], now)
+			if errClaim != nil {
+				return attempted, errClaim
+			}
+			if !claimed {
+				continue
+			}
+			d.deliver(ctx, &due[i])
+			attempted++
+		}
+		if len(due) < batchSize {
+			return attempted, nil
+		}
+	}
+}
+
+// claim pushes the delivery's next attempt past the lease so concurrent dispatchers skip it.
+func (d *Dispatcher) claim(ctx context.Context, row *models.WebhookDelivery, now time.Time) (bool, error) {
+	res := d.db.WithContext(ctx).Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", row.ID, models.WebhookDeliveryStatusPending, now).
+		Update("next_attempt_at", now.Add(claimLease))
+	if res.Error != nil {
+		return false, fmt.Errorf("webhook: claim delivery: %w", res.Error)
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// deliver performs one attempt and records its outcome.
+func (d *Dispatcher) deliver(ctx context.Context, row *models.WebhookDelivery) {
+	attemptAt := d.now().UTC()
+	updates := map[string]any{
+		"attempts":        row.Attempts + 1,
+		"last_attempt_at": attemptAt,
+	}
+
+	var hook models.Webhook
+	errFind := d.db.WithContext(ctx).First(&hook, row.WebhookID).Error
+	var statusCode int
+	var errSend error
+	switch {
+	case errors.Is(errFind, gorm.ErrRecordNotFound):
+		errSend = errors.New("webhook deleted")
+		updates["attempts"] = MaxAttempts
+	case errFind != nil:
+		errSend = fmt.Errorf("load webhook: %w", errFind)
+	default:
+		statusCode, errSend = d.send(ctx, &hook, row, attemptAt)
+	}
+	updates["last_status_code"] = statusCode
+
+	attempts := updates["attempts"].(int)
+	switch {
+	case errSend == nil:
+		updates["status"] = models.WebhookDeliveryStatusSucceeded
+		updates["last_error"] = ""
+		updates["delivered_at"] = attemptAt
+		updates["next_attempt_at"] = nil
+	case attempts >= MaxAttempts:
+		updates["status"] = models.WebhookDeliveryStatusFailed
+		updates["last_error"] = errSend.Error()
+		updates["next_attempt_at"] = nil
+	default:
+		updates["last_error"] = errSend.Error()
+		updates["next_attempt_at"] = attemptAt.Add(Backoff(attempts))
+	}
+	if errUpdate := d.db.WithContext(ctx).Model(&models.WebhookDelivery{}).Where("id = ?", row.ID).Updates(updates).Error; errUpdate != nil {
+		log.WithError(errUpdate).Warnf("webhook dispatcher: record delivery %d failed", row.ID)
+	}
+}
+
+// send posts the signed payload; a non-2xx status is an error.
+func (d *Dispatcher) send(ctx context.Context, hook *models.Webhook, row *models.WebhookDelivery, at time.Time) (int, error) {
+	body := []byte(row.Payload)
+	req, errReq := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if errReq != nil {
+		return 0, fmt.Errorf("build request: %w", errReq)
+	}
+	timestamp := at.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, timestamp, body))
+	req.Header.Set(HeaderEvent, row.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(row.ID, 10))
+	resp, errDo := d.client.Do(req)
+	if errDo != nil {
+		return 0, errDo
+	}
+	defer func() { _ = resp.Body.Close() }()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBytes))
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,241 @@
+// Package webhook delivers bus events to admin-configured HTTP endpoints. Each matching
+// event is logged as a delivery row, signed with the webhook's secret and retried with
+// exponential backoff until the endpoint answers 2xx or the attempts run out.
+//
+// Receivers verify a delivery by recomputing the signature:
+//
+//	X-Webhook-Signature: sha256=hex(HMAC-SHA256(secret, X-Webhook-Timestamp + "." + body))
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// Delivery headers.
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+)
+
+// AllEvents subscribes a webhook to every event type.
+const AllEvents = "*"
+
+// ErrInvalid is returned when a webhook definition is malformed.
+var ErrInvalid = errors.New("webhook: invalid definition")
+
+// Sign returns the signature header value for body sent at timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateSecret returns a random signing secret.
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, errRead := rand.Read(buf); errRead != nil {
+		return "", fmt.Errorf("webhook: random: %w", errRead)
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+// NormalizeURL validates an http(s) delivery URL.
+func NormalizeURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	parsed, errParse := url.Parse(raw)
+	if errParse != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return "", fmt.Errorf("%w: url must be an http or https URL", ErrInvalid)
+	}
+	return raw, nil
+}
+
+// NormalizeEventTypes trims, dedupes and validates an event filter.
+func NormalizeEventTypes(types []string) ([]string, error) {
+	out := make([]string, 0, len(types))
+	seen := make(map[string]struct{}, len(types))
+	for _, t := range types {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		out = append(out, t)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%w: at least one event type is required, or %q for all", ErrInvalid, AllEvents)
+	}
+	return out, nil
+}
+
+// EventTypes decodes a webhook's event filter.
+func EventTypes(hook *models.Webhook) []string {
+	var types []string
+	if len(hook.EventTypes) > 0 {
+		_ = json.Unmarshal(hook.EventTypes, &types)
+	}
+	return types
+}
+
+// Matches reports whether the webhook subscribes to eventType.
+func Matches(hook *models.Webhook, eventType events.Type) bool {
+	for _, t := range EventTypes(hook) {
+		if t == AllEvents || t == string(eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// payload is the JSON body posted to webhooks.
+type payload struct {
+	ID         string         `json:"id"`
+	Type       events.Type    `json:"type"`
+	Severity   string         `json:"severity"`
+	Subject    string         `json:"subject,omitempty"`
+	Message    string         `json:"message,omitempty"`
+	Data       map[string]any `json:"data,omitempty"`
+	OccurredAt time.Time      `json:"occurred_at"`
+}
+
+// Enqueue logs a pending delivery of event for every enabled webhook subscribed to it and
+// returns how many were queued.
+func Enqueue(ctx context.Context, db *gorm.DB, event events.Event, now time.Time) (int, error) {
+	var hooks []models.Webhook
+	if errFind := db.WithContext(ctx).Where("is_enabled = ?", true).Order("id ASC").Find(&hooks).Error; errFind != nil {
+		return 0, fmt.Errorf("webhook: load webhooks: %w", errFind)
+	}
+	var body []byte
+	queued := 0
+	for i := range hooks {
+		if !Matches(&hooks[i], event.Type) {
+			continue
+		}
+		if body == nil {
+			encoded, errMarshal := json.Marshal(payload{
+				ID:         event.ID,
+				Type:       event.Type,
+				Severity:   string(event.Severity),
+				Subject:    event.Subject,
+				Message:    event.Message,
+				Data:       event.Data,
+				OccurredAt: event.OccurredAt,
+			})
+			if errMarshal != nil {
+				return 0, fmt.Errorf("webhook: marshal payload: %w", errMarshal)
+			}
+			body = encoded
+		}
+		if _, errQueue := queueDelivery(ctx, db, hooks[i].ID, event.ID, string(event.Type), string(body), now); errQueue != nil {
+			return queued, errQueue
+		}
+		queued++
+	}
+	return queued, nil
+}
+
+func queueDelivery(ctx context.Context, db *gorm.DB, webhookID uint64, eventID, eventType, body string, now time.Time) (*models.WebhookDelivery, error) {
+	next := now.UTC()
+	row := models.WebhookDelivery{
+		WebhookID:     webhookID,
+		EventID:       eventID,
+		EventType:     eventType,
+		Payload:       body,
+		Status:        models.WebhookDeliveryStatusPending,
+		NextAttemptAt: &next,
+	}
+	if errCreate := db.WithContext(ctx).Create(&row).Error; errCreate != nil {
+		return nil, fmt.Errorf("webhook: queue delivery: %w", errCreate)
+	}
+	return &row, nil
+}
+
+// QueuePing logs a ping delivery to hook regardless of its event filter or enabled flag, so
+// admins can check an endpoint and its signature verification.
+func QueuePing(ctx context.Context, db *gorm.DB, hook *models.Webhook, actor string, now time.Time) (*models.WebhookDelivery, error) {
+	eventID := "ping_" + strconv.FormatInt(now.UnixNano(), 36)
+	body, errMarshal := json.Marshal(payload{
+		ID:       eventID,
+		Type:     events.TypeWebhookPing,
+		Severity: string(events.SeverityInfo),
+		Subject:  "webhook:" + strconv.FormatUint(hook.ID, 10),
+		Message:  "test delivery",
+		Data: map[string]any{
+			"webhook_id": hook.ID,
+			"actor":      actor,
+		},
+		OccurredAt: now.UTC(),
+	})
+	if errMarshal != nil {
+		return nil, fmt.Errorf("webhook: marshal payload: %w", errMarshal)
+	}
+	return queueDelivery(ctx, db, hook.ID, eventID, string(events.TypeWebhookPing), string(body), now)
+}
+
+// Retry requeues a finished or failed delivery for an immediate attempt, keeping its log.
+func Retry(ctx context.Context, db *gorm.DB, deliveryID uint64, now time.Time) (*models.WebhookDelivery, error) {
+	var row models.WebhookDelivery
+	if errFind := db.WithContext(ctx).First(&row, deliveryID).Error; errFind != nil {
+		return nil, errFind
+	}
+	next := now.UTC()
+	if errUpdate := db.WithContext(ctx).Model(&row).Updates(map[string]any{
+		"status":          models.WebhookDeliveryStatusPending,
+		"next_attempt_at": next,
+	}).Error; errUpdate != nil {
+		return nil, fmt.Errorf("webhook: retry delivery: %w", errUpdate)
+	}
+	row.Status = models.WebhookDeliveryStatusPending
+	row.NextAttemptAt = &next
+	return &row, nil
+}
+
+// Subscriber queues bus events for webhook delivery and wakes the dispatcher.
+type Subscriber struct {
+	db         *gorm.DB
+	dispatcher *Dispatcher
+}
+
+// NewSubscriber constructs a webhook bus subscriber; returns nil when db is nil.
+func NewSubscriber(db *gorm.DB, dispatcher *Dispatcher) *Subscriber {
+	if db == nil {
+		return nil
+	}
+	return &Subscriber{db: db, dispatcher: dispatcher}
+}
+
+// Name returns the subscriber name.
+func (s *Subscriber) Name() string { return "webhook_delivery" }
+
+// Handle queues the event for every subscribed webhook.
+func (s *Subscriber) Handle(ctx context.Context, event events.Event) error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	queued, errEnqueue := Enqueue(ctx, s.db, event, time.Now())
+	if queued > 0 {
+		s.dispatcher.Wake()
+	}
+	return errEnqueue
+}
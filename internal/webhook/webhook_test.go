@@ -0,0 +1,185 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/events"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func setupWebhookDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:webhook_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, errOpen := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if errOpen != nil {
+		t.Fatalf("open sqlite: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func createHook(t *testing.T, conn *gorm.DB, url string, types string) *models.Webhook {
+	t.Helper()
+	hook := models.Webhook{Name: "hook", URL: url, Secret: "whsec_test", EventTypes: datatypes.JSON(types), IsEnabled: true}
+	if errCreate := conn.Create(&hook).Error; errCreate != nil {
+		t.Fatalf("create webhook: %v", errCreate)
+	}
+	return &hook
+}
+
+func loadDelivery(t *testing.T, conn *gorm.DB) models.WebhookDelivery {
+	t.Helper()
+	var row models.WebhookDelivery
+	if errFind := conn.Order("id DESC").First(&row).Error; errFind != nil {
+		t.Fatalf("load delivery: %v", errFind)
+	}
+	return row
+}
+
+func TestMatchesAndNormalizeEventTypes(t *testing.T) {
+	t.Parallel()
+
+	types, errTypes := NormalizeEventTypes([]string{" bill.exhausted ", "", "bill.exhausted", "usage.anomaly"})
+	if errTypes != nil || len(types) != 2 {
+		t.Fatalf("NormalizeEventTypes = %v, %v", types, errTypes)
+	}
+	if _, errEmpty := NormalizeEventTypes([]string{" "}); errEmpty == nil {
+		t.Fatal("expected empty filter to be rejected")
+	}
+	if _, errURL := NormalizeURL("ftp://example.com"); errURL == nil {
+		t.Fatal("expected non-http url to be rejected")
+	}
+
+	hook := &models.Webhook{EventTypes: datatypes.JSON(`["bill.exhausted"]`)}
+	if !Matches(hook, events.TypeBillExhausted) || Matches(hook, events.TypeUsageAnomaly) {
+		t.Fatal("unexpected match result for explicit filter")
+	}
+	all := &models.Webhook{EventTypes: datatypes.JSON(`["*"]`)}
+	if !Matches(all, events.TypeUsageAnomaly) {
+		t.Fatal("expected wildcard filter to match")
+	}
+}
+
+func TestDeliverySignedAndRetried(t *testing.T) {
+	t.Parallel()
+
+	conn := setupWebhookDB(t)
+	var mu sync.Mutex
+	calls := 0
+	var verified bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		verified = r.Header.Get(HeaderSignature) == Sign("whsec_test", ts, body) &&
+			r.Header.Get(HeaderEvent) == string(events.TypeBillExhausted)
+		if calls == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	createHook(t, conn, server.URL, `["bill.exhausted"]`)
+	createHook(t, conn, server.URL, `["usage.anomaly"]`)
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	queued, errEnqueue := Enqueue(context.Background(), conn, events.Event{
+		ID:         "evt_1",
+		Type:       events.TypeBillExhausted,
+		Severity:   events.SeverityWarning,
+		OccurredAt: now,
+	}, now)
+	if errEnqueue != nil || queued != 1 {
+		t.Fatalf("Enqueue = %d, %v; want 1 delivery", queued, errEnqueue)
+	}
+
+	d := NewDispatcher(conn)
+	d.now = func() time.Time { return now }
+	if attempted, errRun := d.RunOnce(context.Background()); errRun != nil || attempted != 1 {
+		t.Fatalf("RunOnce = %d, %v", attempted, errRun)
+	}
+	row := loadDelivery(t, conn)
+	if row.Status != models.WebhookDeliveryStatusPending || row.Attempts != 1 || row.LastStatusCode != http.StatusInternalServerError {
+		t.Fatalf("unexpected delivery after failure: %+v", row)
+	}
+	if row.NextAttemptAt == nil || !row.NextAttemptAt.Equal(now.Add(Backoff(1))) {
+		t.Fatalf("next attempt = %v, want %v", row.NextAttemptAt, now.Add(Backoff(1)))
+	}
+
+	if attempted, _ := d.RunOnce(context.Background()); attempted != 0 {
+		t.Fatalf("expected no attempt before backoff elapses, got %d", attempted)
+	}
+
+	now = now.Add(Backoff(1))
+	if attempted, errRun := d.RunOnce(context.Background()); errRun != nil || attempted != 1 {
+		t.Fatalf("RunOnce after backoff = %d, %v", attempted, errRun)
+	}
+	row = loadDelivery(t, conn)
+	if row.Status != models.WebhookDeliveryStatusSucceeded || row.Attempts != 2 || row.DeliveredAt == nil {
+		t.Fatalf("unexpected delivery after success: %+v", row)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !verified {
+		t.Fatal("expected signature and event headers to verify")
+	}
+}
+
+func TestDeliveryFailsAfterMaxAttemptsAndRetries(t *testing.T) {
+	t.Parallel()
+
+	conn := setupWebhookDB(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	hook := createHook(t, conn, server.URL, `["*"]`)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	if _, errQueue := QueuePing(context.Background(), conn, hook, "admin:root", now); errQueue != nil {
+		t.Fatalf("QueuePing: %v", errQueue)
+	}
+
+	d := NewDispatcher(conn)
+	d.now = func() time.Time { return now }
+	for i := 1; i <= MaxAttempts; i++ {
+		if attempted, errRun := d.RunOnce(context.Background()); errRun != nil || attempted != 1 {
+			t.Fatalf("attempt %d: RunOnce = %d, %v", i, attempted, errRun)
+		}
+		now = now.Add(Backoff(i))
+	}
+	row := loadDelivery(t, conn)
+	if row.Status != models.WebhookDeliveryStatusFailed || row.Attempts != MaxAttempts || row.NextAttemptAt != nil {
+		t.Fatalf("unexpected delivery after max attempts: %+v", row)
+	}
+
+	retried, errRetry := Retry(context.Background(), conn, row.ID, now)
+	if errRetry != nil || retried.Status != models.WebhookDeliveryStatusPending {
+		t.Fatalf("Retry = %+v, %v", retried, errRetry)
+	}
+	if attempted, _ := d.RunOnce(context.Background()); attempted != 1 {
+		t.Fatalf("expected retried delivery to be attempted, got %d", attempted)
+	}
+	row = loadDelivery(t, conn)
+	if row.Status != models.WebhookDeliveryStatusFailed || row.Attempts != MaxAttempts+1 {
+		t.Fatalf("unexpected delivery after manual retry: %+v", row)
+	}
+}